@@ -0,0 +1,208 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/access"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/kube"
+)
+
+// AccessOptions holds options shared by the access subcommands.
+type AccessOptions struct {
+	Context string
+	Tenant  string
+}
+
+// NewAccessCommand creates the parent access command.
+func NewAccessCommand() *cobra.Command {
+	opts := &AccessOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "access",
+		Short: "Debug document permissions and permission sync",
+		Long: `Debug document permissions and permission sync.
+
+A user sees a document in search when any entry of the user's access list
+(their email, their Onyx groups, the external groups permission sync put
+them in, or PUBLIC) is also in the document's. "check" compares the two and
+says which entry grants access, or why none does. "sync-status" shows the
+latest permission sync of every connector that copies permissions from its
+source.
+
+On a single-tenant deployment omit --tenant.
+
+Requires: AWS SSO login, kubectl access to the EKS cluster.
+
+Examples:
+  ods access check --tenant tenant_abcd1234 --user ann@example.com --doc 'https://drive.google.com/file/d/1abc'
+  ods access sync-status --tenant tenant_abcd1234 -c staging`,
+	}
+
+	cmd.PersistentFlags().StringVarP(&opts.Context, "context", "c", "data_plane", "cluster context name (maps to KUBE_CTX_<NAME> env var)")
+	cmd.PersistentFlags().StringVar(&opts.Tenant, "tenant", "", "Tenant schema (omit on single-tenant deployments)")
+
+	cmd.AddCommand(newAccessCheckCommand(opts))
+	cmd.AddCommand(newAccessSyncStatusCommand(opts))
+
+	return cmd
+}
+
+func newAccessCheckCommand(opts *AccessOptions) *cobra.Command {
+	var user, doc string
+
+	cmd := &cobra.Command{
+		Use:   "check --user <email> --doc <document id>",
+		Short: "Explain whether a user can see a document",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			runAccessCheck(opts, user, doc)
+		},
+	}
+
+	cmd.Flags().StringVar(&user, "user", "", "Email of the user")
+	cmd.Flags().StringVar(&doc, "doc", "", "Document ID (see ods doc to find it from a URL)")
+	_ = cmd.MarkFlagRequired("user")
+	_ = cmd.MarkFlagRequired("doc")
+
+	return cmd
+}
+
+func newAccessSyncStatusCommand(opts *AccessOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "sync-status",
+		Short: "Show the health of permission sync per connector",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			runAccessSyncStatus(opts)
+		},
+	}
+}
+
+func accessPod(opts *AccessOptions) (*kube.Cluster, string) {
+	if opts.Tenant != "" {
+		validateTenantArg(opts.Tenant)
+	}
+	c := clusterFromEnv(opts.Context)
+	if err := c.EnsureContext(); err != nil {
+		log.Fatalf("Failed to ensure cluster context: %v", err)
+	}
+	pod, err := c.FindPod("api-server")
+	if err != nil {
+		log.Fatalf("Failed to find api-server pod: %v", err)
+	}
+	return c, pod
+}
+
+func runAccessCheck(opts *AccessOptions, user, doc string) {
+	c, pod := accessPod(opts)
+	check, err := access.CheckAccess(c, pod, opts.Tenant, user, doc)
+	if err != nil {
+		log.Fatalf("Failed to check access: %v", err)
+	}
+	d := check.Evaluate()
+
+	fmt.Printf("User:      %s (%s)\n", check.User.Email, check.User.Role)
+	fmt.Printf("Document:  %s (%s)\n", check.Document.ID, check.Document.SemanticID)
+	fmt.Println()
+
+	fmt.Printf("User entries (%d):\n", len(check.User.ACL))
+	for _, entry := range check.User.ACL {
+		fmt.Printf("  %s\n", entry)
+	}
+	if len(check.ExternalGroups) > 0 {
+		fmt.Println()
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "EXTERNAL GROUP\tCC PAIR\tSTALE")
+		_, _ = fmt.Fprintln(w, "--------------\t-------\t-----")
+		for _, g := range check.ExternalGroups {
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%t\n", g.ID, g.CCPair, g.Stale)
+		}
+		_ = w.Flush()
+	}
+	fmt.Println()
+	fmt.Printf("Document entries (%d):\n", len(check.Document.ACL))
+	for _, entry := range check.Document.ACL {
+		fmt.Printf("  %s\n", entry)
+	}
+	fmt.Println()
+
+	if d.Allowed {
+		fmt.Println("GRANTED by:")
+		for _, g := range d.Grants {
+			fmt.Printf("  %s: %s\n", g.Entry, g.Reason)
+		}
+	} else {
+		fmt.Println("DENIED: the user shares no entry with the document")
+	}
+	if len(d.Hints) > 0 {
+		fmt.Println()
+		for _, h := range d.Hints {
+			fmt.Printf("  - %s\n", h)
+		}
+	}
+}
+
+func runAccessSyncStatus(opts *AccessOptions) {
+	c, pod := accessPod(opts)
+	status, err := access.Status(c, pod, opts.Tenant)
+	if err != nil {
+		log.Fatalf("Failed to read permission sync status: %v", err)
+	}
+	if len(status.Pairs) == 0 {
+		log.Info("No connector syncs permissions from its source")
+		return
+	}
+
+	now := time.Now()
+	unhealthy := 0
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "CC PAIR\tNAME\tSOURCE\tSTATUS\tDOC SYNC\tGROUP SYNC\tPROBLEMS")
+	_, _ = fmt.Fprintln(w, "-------\t----\t------\t------\t--------\t----------\t--------")
+	for _, p := range status.Pairs {
+		problems := p.Problems(now)
+		if len(problems) > 0 {
+			unhealthy++
+		}
+		_, _ = fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			p.CCPairID, p.Name, p.Source, p.Status,
+			syncSummary(p.LastDocSync, p.DocAttempt), syncSummary(p.LastGroupSync, p.GroupAttempt),
+			strings.Join(problems, "; "))
+	}
+	_ = w.Flush()
+
+	if g := status.GlobalGroupSync; g != nil {
+		fmt.Printf("\nLatest global group sync: %s %s ago\n", g.Status, attemptAge(g))
+	}
+	if unhealthy > 0 {
+		log.Warnf("%d of %d connector(s) have permission sync problems", unhealthy, len(status.Pairs))
+	}
+}
+
+// syncSummary renders when a sync last completed and how its latest attempt
+// went, e.g. "3h ago (success)".
+func syncSummary(last *time.Time, a *access.Attempt) string {
+	s := "never"
+	if last != nil {
+		s = formatEventAge(*last) + " ago"
+	}
+	if a != nil {
+		s += " (" + a.Status + ")"
+	}
+	return s
+}
+
+func attemptAge(a *access.Attempt) string {
+	for _, t := range []*time.Time{a.Finished, a.Started, a.Created} {
+		if t != nil {
+			return formatEventAge(*t)
+		}
+	}
+	return "-"
+}
@@ -0,0 +1,218 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/alias"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/config"
+)
+
+// aliasGroup is the help group user-defined aliases are listed under.
+const aliasGroup = "aliases"
+
+// NewAliasCommand creates the alias command.
+func NewAliasCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "alias",
+		Short: "Define your own ods commands",
+		Long: `Define your own ods commands, kept in the ods config file.
+
+An alias of one step stands for an ods command line; arguments are appended:
+
+  ods alias add wp "whois --context prod"
+  ods wp alice@example.com
+
+A macro runs several steps in order, stopping at the first that fails.
+$1..$9 in a step are replaced by the arguments, and $@ by the rest:
+
+  ods alias add release-gate "audit --fail-on high" "scan images \$1" "upgrade-check \$1"
+  ods release-gate v2.10.4
+
+Aliases cannot shadow built-in commands, and steps must start with one.`,
+	}
+
+	cmd.AddCommand(newAliasAddCommand())
+	cmd.AddCommand(newAliasRemoveCommand())
+	cmd.AddCommand(newAliasListCommand())
+
+	return cmd
+}
+
+func newAliasAddCommand() *cobra.Command {
+	var description string
+	var force bool
+
+	cmd := &cobra.Command{
+		Use:   "add <name> <step>...",
+		Short: "Add an alias, or a macro of several steps",
+		Args:  cobra.MinimumNArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			runAliasAdd(cmd.Root(), args[0], args[1:], description, force)
+		},
+	}
+
+	cmd.Flags().StringVarP(&description, "description", "d", "", "What the alias does, shown in help")
+	cmd.Flags().BoolVarP(&force, "force", "f", false, "Replace an existing alias of that name")
+
+	return cmd
+}
+
+func newAliasRemoveCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "remove <name>",
+		Short: "Remove an alias",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			cfg := loadConfigOrDie()
+			if _, ok := cfg.Aliases[args[0]]; !ok {
+				log.Fatalf("No alias named %s", args[0])
+			}
+			delete(cfg.Aliases, args[0])
+			if err := config.Save(cfg); err != nil {
+				log.Fatalf("Failed to save ods config: %v", err)
+			}
+			log.Infof("Removed %s", args[0])
+		},
+	}
+}
+
+func newAliasListCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List aliases and macros",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			cfg := loadConfigOrDie()
+			if len(cfg.Aliases) == 0 {
+				log.Info("No aliases defined; add one with: ods alias add <name> <step>...")
+				return
+			}
+			names := make([]string, 0, len(cfg.Aliases))
+			for name := range cfg.Aliases {
+				names = append(names, name)
+			}
+			sort.Strings(names)
+			w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+			_, _ = fmt.Fprintln(w, "NAME\tSTEPS\tDESCRIPTION")
+			for _, name := range names {
+				a := cfg.Aliases[name]
+				_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", name, strings.Join(a.Steps, " && "), a.Description)
+			}
+			_ = w.Flush()
+		},
+	}
+}
+
+func loadConfigOrDie() *config.Config {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load ods config: %v", err)
+	}
+	return cfg
+}
+
+func runAliasAdd(root *cobra.Command, name string, steps []string, description string, force bool) {
+	if !alias.ValidName(name) {
+		log.Fatalf("Invalid alias name %q (lowercase letters, digits and dashes)", name)
+	}
+	if c, _, err := root.Find([]string{name}); err == nil && c != root && c.GroupID != aliasGroup {
+		log.Fatalf("%s is a built-in command", name)
+	}
+	for _, step := range steps {
+		words, err := alias.Split(step)
+		if err != nil {
+			log.Fatalf("Invalid step %q: %v", step, err)
+		}
+		if len(words) == 0 {
+			log.Fatalf("Empty step")
+		}
+		if words[0] == "ods" {
+			log.Fatalf("Steps are ods command lines without the leading \"ods\": %q", step)
+		}
+		if c, _, err := root.Find(words[:1]); err != nil || c == root || c.GroupID == aliasGroup {
+			log.Fatalf("Step %q does not start with a built-in ods command", step)
+		}
+	}
+
+	cfg := loadConfigOrDie()
+	if _, exists := cfg.Aliases[name]; exists && !force {
+		log.Fatalf("Alias %s exists; pass --force to replace it", name)
+	}
+	if cfg.Aliases == nil {
+		cfg.Aliases = map[string]config.AliasConfig{}
+	}
+	cfg.Aliases[name] = config.AliasConfig{Steps: steps, Description: description}
+	if err := config.Save(cfg); err != nil {
+		log.Fatalf("Failed to save ods config: %v", err)
+	}
+	log.Infof("Added %s: ods %s", name, strings.Join(steps, " && ods "))
+}
+
+// addAliasCommands registers the configured aliases on root, skipping any
+// that a built-in command has since taken the name of.
+func addAliasCommands(root *cobra.Command) {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Debugf("Not loading aliases: %v", err)
+		return
+	}
+	if len(cfg.Aliases) == 0 {
+		return
+	}
+	root.AddGroup(&cobra.Group{ID: aliasGroup, Title: "Aliases:"})
+	for name, a := range cfg.Aliases {
+		if c, _, err := root.Find([]string{name}); err == nil && c != root {
+			log.Debugf("Alias %s is shadowed by a built-in command", name)
+			continue
+		}
+		short := a.Description
+		if short == "" {
+			short = "ods " + strings.Join(a.Steps, " && ods ")
+		}
+		root.AddCommand(&cobra.Command{
+			Use:                name,
+			Short:              short,
+			GroupID:            aliasGroup,
+			DisableFlagParsing: true,
+			Run: func(cmd *cobra.Command, args []string) {
+				runAlias(name, a, args)
+			},
+		})
+	}
+}
+
+// runAlias runs each step as its own ods process, exiting with the status
+// of the first that fails.
+func runAlias(name string, a config.AliasConfig, args []string) {
+	argvs, err := alias.Expand(a.Steps, args)
+	if err != nil {
+		log.Fatalf("%s %v", name, err)
+	}
+	self, err := os.Executable()
+	if err != nil {
+		log.Fatalf("Failed to find the ods binary: %v", err)
+	}
+	for _, argv := range argvs {
+		if len(argvs) > 1 {
+			log.Infof("==> ods %s", strings.Join(argv, " "))
+		}
+		c := exec.Command(self, argv...)
+		c.Stdin, c.Stdout, c.Stderr = os.Stdin, os.Stdout, os.Stderr
+		if err := c.Run(); err != nil {
+			var exitErr *exec.ExitError
+			if errors.As(err, &exitErr) {
+				exitWith(exitErr.ExitCode())
+			}
+			log.Fatalf("Failed to run ods %s: %v", strings.Join(argv, " "), err)
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/anonymize"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/docker"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/postgres"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/prompt"
+)
+
+// AnonymizeOptions holds options for the anonymize command.
+type AnonymizeOptions struct {
+	Schema string
+	Rules  string
+	Salt   string
+	DryRun bool
+	Yes    bool
+}
+
+// NewAnonymizeCommand creates the anonymize command.
+func NewAnonymizeCommand() *cobra.Command {
+	opts := &AnonymizeOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "anonymize",
+		Short: "Anonymize a restored database copy in place",
+		Long: `Anonymize a restored database copy in place.
+
+Rewrites personal data and secrets in the local PostgreSQL database (the one
+` + "`ods db restore`" + ` restores into) so a copy of production data is safe to
+work with: emails are hashed, names replaced with fake ones, chat messages,
+queries and memories redacted, and credentials, API keys and tokens wiped.
+Run it before sharing any production-derived data with developers.
+
+The built-in rules cover Onyx's tables; --rules replaces them with a YAML
+file of the same format (see --dry-run for what each rule does). Rules for
+columns a schema does not have are skipped. Everything runs in one
+transaction.
+
+Hashes are salted with --salt, or a random salt printed at the start, so
+they cannot be reversed by hashing guessed emails; pass the same salt to get
+the same output from two copies.
+
+Examples:
+  ods anonymize --schema tenant_abcd1234
+  ods anonymize                                  # every schema
+  ods anonymize --schema public --rules my-rules.yaml
+  ods anonymize --dry-run > anonymize.sql`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			runAnonymize(opts)
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.Schema, "schema", "", "Schema to anonymize (default: every schema)")
+	cmd.Flags().StringVar(&opts.Rules, "rules", "", "YAML rules file to use instead of the built-in rules")
+	cmd.Flags().StringVar(&opts.Salt, "salt", "", "Salt for hashes (default: random)")
+	cmd.Flags().BoolVar(&opts.DryRun, "dry-run", false, "Print the SQL instead of running it")
+	cmd.Flags().BoolVar(&opts.Yes, "yes", false, "Skip the confirmation prompt")
+
+	return cmd
+}
+
+func runAnonymize(opts *AnonymizeOptions) {
+	script, err := anonymizeScript(opts.Schema, opts.Rules, opts.Salt)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	if opts.DryRun {
+		fmt.Print(script)
+		return
+	}
+
+	container, err := docker.FindPostgresContainer(docker.ProjectName())
+	if err != nil {
+		log.Fatalf("Failed to find PostgreSQL container: %v", err)
+	}
+	config := postgres.NewConfigFromEnv()
+
+	target := "every schema"
+	if opts.Schema != "" {
+		target = "schema '" + opts.Schema + "'"
+	}
+	if !opts.Yes && !prompt.Confirm(fmt.Sprintf("This will irreversibly anonymize %s in database '%s' (%s). Continue? (yes/no): ",
+		target, config.Database, container)) {
+		log.Info("Aborted.")
+		return
+	}
+
+	if err := runAnonymizeScript(container, config, script); err != nil {
+		log.Fatalf("Failed to anonymize: %v", err)
+	}
+	log.Infof("Anonymized %s in database '%s'", target, config.Database)
+}
+
+// anonymizeScript builds the anonymization SQL from the rules file (or the
+// built-in rules) and salt, generating a random salt if none is given.
+func anonymizeScript(schema, rulesFile, salt string) (string, error) {
+	rules := anonymize.DefaultRules()
+	if rulesFile != "" {
+		var err error
+		if rules, err = anonymize.LoadRules(rulesFile); err != nil {
+			return "", fmt.Errorf("failed to load rules: %w", err)
+		}
+	}
+	if salt == "" {
+		b := make([]byte, 16)
+		if _, err := rand.Read(b); err != nil {
+			return "", fmt.Errorf("failed to generate salt: %w", err)
+		}
+		salt = hex.EncodeToString(b)
+		log.Infof("Hash salt: %s (pass --salt to reproduce)", salt)
+	}
+	return anonymize.SQL(rules, anonymize.Options{Schema: schema, Salt: salt})
+}
+
+// runAnonymizeScript runs script with psql against config's database in the
+// PostgreSQL container, stopping at the first error.
+func runAnonymizeScript(container string, config *postgres.Config, script string) error {
+	f, err := os.CreateTemp("", "ods-anonymize-*.sql")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = os.Remove(f.Name()) }()
+	if _, err := f.WriteString(script); err != nil {
+		_ = f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	const containerFile = "/tmp/ods_anonymize.sql"
+	if err := docker.CopyToContainer(container, f.Name(), containerFile); err != nil {
+		return fmt.Errorf("failed to copy the script into %s: %w", container, err)
+	}
+	defer func() { _ = docker.Exec(container, "rm", "-f", containerFile) }()
+
+	args := append([]string{"psql"}, config.PsqlArgs()...)
+	args = append(args, "-v", "ON_ERROR_STOP=1", "-q", "-f", containerFile)
+	return docker.ExecWithEnv(container, config.Env(), args...)
+}
@@ -0,0 +1,128 @@
+package cmd
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/apigen"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/openapi"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/paths"
+)
+
+// apiPackageDir is the typed client package, relative to the git root.
+const apiPackageDir = "tools/ods/internal/api"
+
+// NewAPICommand creates the parent api command.
+func NewAPICommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "api",
+		Short: "Maintain the typed API client used by ods",
+		Long: `Maintain the typed API client used by ods.
+
+Commands that call the API server use the client in internal/api, which is
+generated from the backend's OpenAPI spec for the operations listed in
+internal/api/operations.txt.`,
+	}
+
+	cmd.AddCommand(newAPIRegenCommand())
+
+	return cmd
+}
+
+func newAPIRegenCommand() *cobra.Command {
+	var specPath string
+	var check bool
+
+	cmd := &cobra.Command{
+		Use:   "regen",
+		Short: "Regenerate the typed API client from the OpenAPI spec",
+		Long: `Regenerate the typed API client from the OpenAPI spec.
+
+Generates the backend's OpenAPI spec (as ` + "`ods openapi schema`" + ` does, which
+needs the backend venv) unless --spec points at an existing one, then
+rewrites internal/api/zz_generated.go with a method for every operation in
+internal/api/operations.txt and the types they use.
+
+--check leaves the file alone and fails if it is out of date, for CI.
+
+Examples:
+  ods api regen
+  ods api regen --spec backend/generated/openapi.json
+  ods api regen --check`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			runAPIRegen(specPath, check)
+		},
+	}
+
+	cmd.Flags().StringVar(&specPath, "spec", "", "Existing OpenAPI spec to generate from (default: generate it)")
+	cmd.Flags().BoolVar(&check, "check", false, "Fail if the generated client is out of date instead of writing it")
+
+	return cmd
+}
+
+func runAPIRegen(specPath string, check bool) {
+	root, err := paths.GitRoot()
+	if err != nil {
+		log.Fatalf("Failed to find git root: %v", err)
+	}
+	pkgDir := filepath.Join(root, apiPackageDir)
+
+	if specPath == "" {
+		tmp, err := os.MkdirTemp("", "ods-openapi-")
+		if err != nil {
+			log.Fatalf("Failed to create temp dir: %v", err)
+		}
+		defer func() { _ = os.RemoveAll(tmp) }()
+		specPath = filepath.Join(tmp, "openapi.json")
+		log.Info("Generating the OpenAPI spec...")
+		if err := openapi.GenerateSchema(specPath); err != nil {
+			log.Fatalf("Failed to generate the OpenAPI spec (or pass --spec): %v", err)
+		}
+	}
+	data, err := os.ReadFile(specPath)
+	if err != nil {
+		log.Fatalf("Failed to read %s: %v", specPath, err)
+	}
+	spec, err := apigen.ParseSpec(data)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	opsPath := filepath.Join(pkgDir, "operations.txt")
+	opsFile, err := os.Open(opsPath)
+	if err != nil {
+		log.Fatalf("Failed to open %s: %v", opsPath, err)
+	}
+	ops, err := apigen.ParseOperations(opsFile)
+	_ = opsFile.Close()
+	if err != nil {
+		log.Fatalf("Invalid %s: %v", opsPath, err)
+	}
+
+	src, err := apigen.Generate(spec, ops, "api")
+	if err != nil {
+		log.Fatalf("Failed to generate the client: %v", err)
+	}
+
+	outPath := filepath.Join(pkgDir, "zz_generated.go")
+	current, err := os.ReadFile(outPath)
+	if err != nil && !os.IsNotExist(err) {
+		log.Fatalf("Failed to read %s: %v", outPath, err)
+	}
+	if bytes.Equal(current, src) {
+		log.Infof("%s is up to date (%d operations)", relToRoot(root, outPath), len(ops))
+		return
+	}
+	if check {
+		log.Fatalf("%s is out of date; run `ods api regen`", relToRoot(root, outPath))
+	}
+	if err := os.WriteFile(outPath, src, 0644); err != nil {
+		log.Fatalf("Failed to write %s: %v", outPath, err)
+	}
+	log.Infof("Wrote %s (%d operations)", relToRoot(root, outPath), len(ops))
+}
@@ -0,0 +1,141 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/apiclient"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/auditlog"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/impersonate"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/portforward"
+)
+
+// localContext selects the local compose stack instead of a cluster.
+const localContext = "local"
+
+// APISessionOptions selects the API server a command talks to and how it
+// authenticates.
+type APISessionOptions struct {
+	Context string
+	Tenant  string
+	As      string
+	Reason  string
+	// APIKey replaces $ONYX_API_KEY, for commands that talk to several
+	// servers at once.
+	APIKey string
+}
+
+// addAPISessionFlags registers the flags of APISessionOptions on cmd.
+func addAPISessionFlags(cmd *cobra.Command, opts *APISessionOptions) {
+	cmd.Flags().StringVarP(&opts.Context, "context", "c", localContext, `cluster context name (maps to KUBE_CTX_<NAME> env var), or "local" for the compose stack`)
+	cmd.Flags().StringVar(&opts.Tenant, "tenant", "", "Tenant to act in, as its first admin unless --as is set (remote only)")
+	cmd.Flags().StringVar(&opts.As, "as", "", "Email of the user to impersonate (remote only)")
+	cmd.Flags().StringVar(&opts.Reason, "reason", "", "Ticket or justification recorded in the audit log (required to impersonate)")
+}
+
+// apiSession is an authenticated client for an API server. Close it to stop
+// the port-forward and log out any impersonated session.
+type apiSession struct {
+	Client *apiclient.Client
+	// Target names the server and identity for log lines.
+	Target string
+
+	pf    *portforward.Forward
+	token string
+}
+
+// openAPISession connects to the API server opts selects. Locally that is
+// the compose stack ($ONYX_API_URL, default http://localhost:8080). Remotely
+// it port-forwards to an api-server pod and authenticates as a tenant user
+// via impersonation, or with $ONYX_API_KEY when no tenant or user is given.
+// Exits on failure.
+func openAPISession(opts *APISessionOptions, action string) *apiSession {
+	apiKey := opts.APIKey
+	if apiKey == "" {
+		apiKey = os.Getenv("ONYX_API_KEY")
+	}
+	if opts.Context == localContext {
+		if opts.Tenant != "" || opts.As != "" {
+			log.Fatal("--tenant and --as need a cluster context (-c); locally, set ONYX_API_KEY to a key of the user to act as")
+		}
+		client := apiclient.New(envOrDefault("ONYX_API_URL", defaultBackendURL))
+		client.APIKey = apiKey
+		return &apiSession{Client: client, Target: client.BaseURL + " (" + client.Auth() + ")"}
+	}
+
+	impersonating := opts.Tenant != "" || opts.As != ""
+	var creds *impersonate.Credentials
+	if impersonating {
+		if opts.Reason == "" {
+			log.Fatal("--reason is required to act as a tenant user")
+		}
+		var err error
+		if creds, err = impersonate.CredentialsFromEnv(); err != nil {
+			log.Fatalf("%v", err)
+		}
+	} else if apiKey == "" {
+		log.Fatal("Pass --tenant or --as to impersonate a user, or set ONYX_API_KEY")
+	}
+
+	c := clusterFromEnv(opts.Context)
+	if err := c.EnsureContext(); err != nil {
+		log.Fatalf("Failed to ensure cluster context: %v", err)
+	}
+	log.Info("Finding api-server pod...")
+	pod, err := c.FindPod("api-server")
+	if err != nil {
+		log.Fatalf("Failed to find api-server pod: %v", err)
+	}
+
+	s := &apiSession{pf: forwardAPIServer(c, pod, 0)}
+	s.Client = apiclient.New(s.pf.URL())
+	if !impersonating {
+		s.Client.APIKey = apiKey
+		s.Target = c.Name + " (API key)"
+		return s
+	}
+
+	email := opts.As
+	if email == "" {
+		admins := tenantAdminEmails(c, pod, opts.Tenant)
+		if len(admins) == 0 {
+			s.pf.Stop()
+			log.Fatalf("No admin users found for %s; pass --as <email>", opts.Tenant)
+		}
+		email = admins[0]
+	}
+	if err := auditlog.Record(auditlog.Entry{
+		Action:  action,
+		Context: c.Name + "/" + c.Namespace,
+		Target:  email,
+		Detail:  fmt.Sprintf("tenant=%s reason=%s", opts.Tenant, opts.Reason),
+	}); err != nil {
+		s.pf.Stop()
+		log.Fatalf("Refusing to impersonate without an audit record: %v", err)
+	}
+	token, err := impersonate.Mint(s.pf.URL(), email, creds)
+	if err != nil {
+		s.pf.Stop()
+		log.Fatalf("Failed to mint impersonation session for %s: %v", email, err)
+	}
+	s.token = token
+	s.Client.SessionToken = token
+	s.Target = c.Name + " as " + email
+	return s
+}
+
+// Close logs out the impersonated session, if any, and stops the
+// port-forward.
+func (s *apiSession) Close() {
+	if s.token != "" {
+		if err := impersonate.Revoke(s.pf.URL(), s.token); err != nil {
+			log.Warnf("Failed to log out the impersonated session (it will expire on its own): %v", err)
+		}
+	}
+	if s.pf != nil {
+		s.pf.Stop()
+	}
+}
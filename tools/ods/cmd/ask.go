@@ -0,0 +1,140 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/ask"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/render"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/runner"
+)
+
+// AskOptions holds options for the ask command.
+type AskOptions struct {
+	Context    string
+	Model      string
+	URL        string
+	MaxRounds  int
+	ShowOutput bool
+	JSON       bool
+}
+
+// NewAskCommand creates the ask command.
+func NewAskCommand() *cobra.Command {
+	opts := &AskOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "ask <question>",
+		Short: "Answer a question about a deployment by running read-only ods commands",
+		Long: `Answer a question about a deployment in plain language.
+
+An LLM plans which ods commands answer the question, ods runs them, and the
+LLM reads their output and either runs more or answers. Every command is
+shown as it runs. Only read-only commands can run, with a fixed set of
+flags each:
+  whois, health, pause-tenant-indexing --status, access sync-status,
+  credentials check, migrate status, events, domains list, usage tokens
+Commands outside that set are refused and never run, so a question cannot
+change an environment. The commands run as you, with your cluster access.
+
+The LLM is any OpenAI-compatible chat completions API: the "ask" section of
+the ods config (url, model, context) or --url and --model, with
+$ODS_ASK_API_KEY, or $OPENAI_API_KEY. Questions and command output (emails,
+tenant IDs, connector names) are sent to it.
+
+Requires: an LLM API key, and the access the commands it runs need.
+
+Examples:
+  ods ask "which tenant does jane@acme.com belong to and is indexing healthy?"
+  ods ask -c prod "are any connector credentials about to expire for tenant_abcd1234?"
+  ods ask --show-output "is the staging data plane healthy?"`,
+		Args: cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			runAsk(strings.Join(args, " "), opts)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.Context, "context", "c", "", "cluster context name (maps to KUBE_CTX_<NAME> env var) commands run against (default: each command's own)")
+	cmd.Flags().StringVar(&opts.Model, "model", "", "Chat model that plans and answers (default: config, or "+ask.DefaultModel+")")
+	cmd.Flags().StringVar(&opts.URL, "url", "", "OpenAI-compatible API base URL (default: config, or OpenAI)")
+	cmd.Flags().IntVar(&opts.MaxRounds, "max-rounds", 5, "How many times the LLM may run commands before it must answer")
+	cmd.Flags().BoolVar(&opts.ShowOutput, "show-output", false, "Also print the output of each command")
+	cmd.Flags().BoolVar(&opts.JSON, "json", false, "Print the answer and every command with its output as JSON")
+
+	return cmd
+}
+
+func runAsk(question string, opts *AskOptions) {
+	cfg := loadConfigOrDie().Ask
+	url := firstNonEmpty(opts.URL, cfg.URL, ask.DefaultURL)
+	model := firstNonEmpty(opts.Model, cfg.Model, ask.DefaultModel)
+	context := firstNonEmpty(opts.Context, cfg.Context)
+	key := envOrDefault("ODS_ASK_API_KEY", os.Getenv("OPENAI_API_KEY"))
+	if key == "" && url == ask.DefaultURL {
+		log.Fatal("Set ODS_ASK_API_KEY or OPENAI_API_KEY, or configure an API that needs no key")
+	}
+	if opts.MaxRounds < 1 {
+		log.Fatal("--max-rounds must be at least 1")
+	}
+	self, err := os.Executable()
+	if err != nil {
+		log.Fatalf("Failed to find the ods binary: %v", err)
+	}
+
+	llm := ask.NewLLM(url, key, model)
+	res, err := ask.Ask(question, ask.Options{
+		Chat: llm.Chat,
+		Run: func(argv []string) (string, error) {
+			out, err := runner.CombinedOutput(nil, runner.Cmd{
+				Name: self,
+				Args: argv,
+				Env:  append(os.Environ(), "NO_COLOR=1"),
+			})
+			if opts.ShowOutput && !opts.JSON {
+				_, _ = os.Stderr.Write(out)
+			}
+			return string(out), err
+		},
+		Context:   context,
+		MaxRounds: opts.MaxRounds,
+		OnStep: func(argv []string) {
+			log.Infof("$ ods %s", strings.Join(argv, " "))
+		},
+	})
+	for _, s := range res.Steps {
+		if s.Refused {
+			log.Warnf("Refused ods %s: %s", strings.Join(s.Command, " "), s.Error)
+		}
+	}
+	if err != nil {
+		log.Fatalf("Failed to answer: %v", err)
+	}
+
+	if opts.JSON {
+		if err := render.JSON(os.Stdout, res); err != nil {
+			log.Fatalf("Failed to marshal the answer: %v", err)
+		}
+		return
+	}
+	fmt.Println(res.Answer)
+	ran := 0
+	for _, s := range res.Steps {
+		if !s.Refused {
+			ran++
+		}
+	}
+	fmt.Printf("\n(%d command(s) run with %s)\n", ran, model)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, v := range values {
+		if v != "" {
+			return v
+		}
+	}
+	return ""
+}
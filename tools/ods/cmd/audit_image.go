@@ -74,6 +74,6 @@ func runAuditImage(ref string, opts *AuditImageOptions) {
 
 	if len(result.Blocking) > 0 {
 		log.Errorf("%d finding(s) at or above %s severity must be resolved or suppressed", len(result.Blocking), failOn)
-		os.Exit(1)
+		exitWith(1)
 	}
 }
@@ -147,7 +147,7 @@ func runBackendService(name, module, port string, opts *BackendOptions) {
 		var exitErr *exec.ExitError
 		if errors.As(err, &exitErr) {
 			if code := exitErr.ExitCode(); code != -1 {
-				os.Exit(code)
+				exitWith(code)
 			}
 		}
 		log.Fatalf("Failed to run %s: %v", name, err)
@@ -0,0 +1,308 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/auditlog"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/controlplane"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/kube"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/portforward"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/portutil"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/prompt"
+)
+
+// BillingOptions holds options shared by the billing subcommands.
+type BillingOptions struct {
+	Context          string
+	DataPlaneContext string
+	URL              string
+	Pod              string
+}
+
+// NewBillingCommand creates the parent billing command.
+func NewBillingCommand() *cobra.Command {
+	opts := &BillingOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "billing",
+		Short: "Inspect tenant billing state in the control plane",
+		Long: `Inspect tenant billing state in the control plane.
+
+Answers the standard "why is this customer locked out" questions: subscription
+status, purchased vs. used seats, and re-syncing the license from Stripe.
+
+The control-plane API is reached through a port-forward to its pod in the
+control_plane context (or directly with --url) and authenticated with a
+short-lived JWT signed with DATA_PLANE_SECRET, which must be set.
+
+Requires: AWS SSO login, kubectl access to the EKS clusters.
+
+Examples:
+  ods billing status tenant_abcd1234
+  ods billing seats tenant_abcd1234
+  ods billing sync tenant_abcd1234`,
+	}
+
+	cmd.PersistentFlags().StringVarP(&opts.Context, "context", "c", "control_plane", "control-plane cluster context name (maps to KUBE_CTX_<NAME> env var)")
+	cmd.PersistentFlags().StringVar(&opts.DataPlaneContext, "data-plane-context", "data_plane", "data-plane cluster context used to count seats in use")
+	cmd.PersistentFlags().StringVar(&opts.URL, "url", "", "Control-plane API base URL (skips the port-forward)")
+	cmd.PersistentFlags().StringVar(&opts.Pod, "pod", "control-plane", "Substring of the control-plane API pod name")
+
+	cmd.AddCommand(newBillingStatusCommand(opts))
+	cmd.AddCommand(newBillingSeatsCommand(opts))
+	cmd.AddCommand(newBillingSyncCommand(opts))
+
+	return cmd
+}
+
+func newBillingStatusCommand(opts *BillingOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "status <tenant-id>",
+		Short: "Show a tenant's subscription and billing information",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			runBillingStatus(opts, args[0])
+		},
+	}
+}
+
+func newBillingSeatsCommand(opts *BillingOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "seats <tenant-id>",
+		Short: "Compare purchased seats with active users in the data plane",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			runBillingSeats(opts, args[0])
+		},
+	}
+}
+
+func newBillingSyncCommand(opts *BillingOptions) *cobra.Command {
+	var yes bool
+
+	cmd := &cobra.Command{
+		Use:   "sync <tenant-id>",
+		Short: "Re-sync a tenant's license from its Stripe subscription",
+		Long: `Re-sync a tenant's license from its Stripe subscription.
+
+Re-submits the tenant's current purchased seat count to the control plane,
+which re-reads the subscription from Stripe and pushes a fresh license to the
+data plane. The seat count itself is not changed.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			runBillingSync(opts, args[0], yes)
+		},
+	}
+
+	cmd.Flags().BoolVar(&yes, "yes", false, "Skip the confirmation prompt")
+
+	return cmd
+}
+
+// controlPlaneClient returns a client for the control-plane API and a cleanup
+// function that tears down any port-forward it opened.
+func controlPlaneClient(opts *BillingOptions) (*controlplane.Client, func()) {
+	return dialControlPlane(opts.Context, opts.URL, opts.Pod)
+}
+
+// dialControlPlane returns a client for the control-plane API at baseURL, or,
+// when it is empty, through a port-forward to the pod matching podName in the
+// cluster context; the cleanup function tears down the port-forward.
+func dialControlPlane(contextName, baseURL, podName string) (*controlplane.Client, func()) {
+	secret := os.Getenv("DATA_PLANE_SECRET")
+	if secret == "" {
+		log.Fatal("DATA_PLANE_SECRET is not set (it signs requests to the control plane)")
+	}
+
+	if baseURL != "" {
+		return controlplane.NewClient(baseURL, secret), func() {}
+	}
+
+	c := clusterFromEnv(contextName)
+	if err := c.EnsureContext(); err != nil {
+		log.Fatalf("Failed to ensure cluster context: %v", err)
+	}
+	pod, err := c.FindPod(podName)
+	if err != nil {
+		log.Fatalf("Failed to find control-plane pod: %v", err)
+	}
+	localPort, err := portutil.FindAvailable(18082, 100, nil)
+	if err != nil {
+		log.Fatalf("Failed to find a port for the port-forward: %v", err)
+	}
+	pf, err := portforward.Start(c, portforward.Spec{
+		Name:   "control-plane",
+		Target: "pod/" + pod,
+		Resolve: func() (string, error) {
+			pod, err := c.FindPod(podName)
+			return "pod/" + pod, err
+		},
+		LocalPort:  localPort,
+		RemotePort: controlplane.DefaultPort,
+	})
+	if err != nil {
+		log.Fatalf("Failed to port-forward to %s: %v", pod, err)
+	}
+	return controlplane.NewClient(pf.URL(), secret), pf.Stop
+}
+
+func fetchBillingInfo(client *controlplane.Client, tenantID string) map[string]any {
+	var info map[string]any
+	if err := client.Get("/billing-information", url.Values{"tenant_id": {tenantID}}, &info); err != nil {
+		log.Fatalf("Failed to fetch billing information for %s: %v", tenantID, err)
+	}
+	return info
+}
+
+func runBillingStatus(opts *BillingOptions, tenantID string) {
+	validateTenantArg(tenantID)
+	client, cleanup := controlPlaneClient(opts)
+	defer cleanup()
+
+	info := fetchBillingInfo(client, tenantID)
+	if subscribed, ok := info["subscribed"].(bool); ok && !subscribed && len(info) == 1 {
+		fmt.Printf("%s has no subscription.\n", tenantID)
+		return
+	}
+
+	keys := make([]string, 0, len(info))
+	for k := range info {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "FIELD\tVALUE")
+	_, _ = fmt.Fprintln(w, "-----\t-----")
+	for _, k := range keys {
+		_, _ = fmt.Fprintf(w, "%s\t%s\n", k, formatBillingValue(info[k]))
+	}
+	_ = w.Flush()
+}
+
+func runBillingSeats(opts *BillingOptions, tenantID string) {
+	validateTenantArg(tenantID)
+	client, cleanup := controlPlaneClient(opts)
+	defer cleanup()
+
+	info := fetchBillingInfo(client, tenantID)
+	purchased, ok := billingSeatCount(info)
+	if !ok {
+		log.Fatalf("Control plane returned no seat count for %s (is it subscribed? try `ods billing status`)", tenantID)
+	}
+
+	dp := clusterFromEnv(opts.DataPlaneContext)
+	if err := dp.EnsureContext(); err != nil {
+		log.Fatalf("Failed to ensure cluster context: %v", err)
+	}
+	used := tenantActiveUserCount(dp, tenantID)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "PURCHASED\tIN USE\tAVAILABLE")
+	_, _ = fmt.Fprintln(w, "---------\t------\t---------")
+	_, _ = fmt.Fprintf(w, "%d\t%d\t%d\n", purchased, used, purchased-used)
+	_ = w.Flush()
+
+	if used > purchased {
+		fmt.Println()
+		fmt.Printf("%s is over its seat limit by %d; new logins and invites will be blocked.\n", tenantID, used-purchased)
+	}
+}
+
+func runBillingSync(opts *BillingOptions, tenantID string, yes bool) {
+	validateTenantArg(tenantID)
+	client, cleanup := controlPlaneClient(opts)
+	defer cleanup()
+
+	info := fetchBillingInfo(client, tenantID)
+	seats, ok := billingSeatCount(info)
+	if !ok {
+		log.Fatalf("Control plane returned no seat count for %s; nothing to sync", tenantID)
+	}
+
+	if !yes && !prompt.Confirm(fmt.Sprintf("Re-sync the license for %s (%d seats) from Stripe? (yes/no): ", tenantID, seats)) {
+		log.Info("Aborted.")
+		return
+	}
+
+	if err := auditlog.Record(auditlog.Entry{
+		Action: "billing.sync",
+		Target: tenantID,
+		Detail: fmt.Sprintf("seats=%d", seats),
+	}); err != nil {
+		log.Fatalf("Refusing to sync without an audit record: %v", err)
+	}
+
+	var resp map[string]any
+	if err := client.Post("/seats/update", map[string]any{"tenant_id": tenantID, "new_seat_count": seats}, &resp); err != nil {
+		log.Fatalf("Failed to sync %s: %v", tenantID, err)
+	}
+	if msg, ok := resp["message"].(string); ok && msg != "" {
+		log.Info(msg)
+	}
+	log.Infof("License for %s re-synced", tenantID)
+}
+
+// billingSeatCount extracts the purchased seat count from a billing-information
+// response, which reports it as "seats".
+func billingSeatCount(info map[string]any) (int, bool) {
+	switch v := info["seats"].(type) {
+	case float64:
+		return int(v), true
+	case string:
+		n, err := strconv.Atoi(v)
+		return n, err == nil
+	}
+	return 0, false
+}
+
+func formatBillingValue(v any) string {
+	switch t := v.(type) {
+	case nil:
+		return "-"
+	case string:
+		return t
+	case float64:
+		return strconv.FormatFloat(t, 'f', -1, 64)
+	case bool:
+		return strconv.FormatBool(t)
+	default:
+		data, err := json.Marshal(t)
+		if err != nil {
+			return fmt.Sprint(t)
+		}
+		return string(data)
+	}
+}
+
+// tenantActiveUserCount counts active, non-API-key users in a tenant schema,
+// which is what seat enforcement counts.
+func tenantActiveUserCount(c *kube.Cluster, tenantID string) int {
+	pod, err := c.FindPod("api-server")
+	if err != nil {
+		log.Fatalf("Failed to find api-server pod: %v", err)
+	}
+	sql := fmt.Sprintf(
+		`SELECT count(*) FROM "%s"."user" WHERE is_active = true AND email NOT LIKE 'api_key__%%';`,
+		tenantID,
+	)
+	lines := queryPod(c, pod, sql)
+	if len(lines) == 0 {
+		log.Fatalf("No result counting users for %s", tenantID)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(lines[0]))
+	if err != nil {
+		log.Fatalf("Unexpected user count %q for %s", lines[0], tenantID)
+	}
+	return n
+}
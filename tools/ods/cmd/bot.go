@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"context"
+	"os"
+	"os/signal"
+	"syscall"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/auditlog"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/bot"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/serve"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/slack"
+)
+
+// slackAppTokenEnv holds the Slack app-level token ods bot connects with.
+const slackAppTokenEnv = "ODS_SLACK_APP_TOKEN"
+
+// BotOptions holds options for the bot command.
+type BotOptions struct {
+	Contexts []string
+	Channels []string
+	Users    []string
+}
+
+// NewBotCommand creates the bot command.
+func NewBotCommand() *cobra.Command {
+	opts := &BotOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "bot",
+		Short: "Answer /ods slash commands in Slack until interrupted",
+		Long: `Answer /ods slash commands in Slack until interrupted, so support can run
+lookups without terminal access.
+
+Connects in Socket Mode, so no public endpoint is needed: create a Slack app
+with Socket Mode enabled and an /ods slash command, and export its app-level
+token (xapp-…, with the connections:write scope) as ` + slackAppTokenEnv + `.
+
+Commands (answered in the channel, behind a line showing who asked):
+  /ods whois <email-fragment|tenant-id> [context]
+  /ods health [context]
+  /ods stats <report> [since] [context]
+  /ods logs <pod> <text> [context]        matching lines from the last hour
+  /ods help
+
+Every command is read-only. --channel and --user restrict who may use the
+bot, and each command is recorded in the ods audit log (as slack:<user>)
+before it runs; a command that cannot be recorded is refused.
+
+The first --context is the default; the others are answered when a command
+ends with their name. Lookups use your cluster credentials, so run the bot
+somewhere they stay valid.
+
+Examples:
+  ods bot --channel support-escalations
+  ods bot -c data_plane -c prod_eu --channel C0123ABCD --user U0456EFGH`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			runBot(opts)
+		},
+	}
+
+	cmd.Flags().StringSliceVarP(&opts.Contexts, "context", "c", []string{"data_plane"}, "cluster context names to answer for (maps to KUBE_CTX_<NAME> env vars)")
+	cmd.Flags().StringSliceVar(&opts.Channels, "channel", nil, "Only answer in these channels (IDs or names; default: any channel the app is in)")
+	cmd.Flags().StringSliceVar(&opts.Users, "user", nil, "Only answer these users (IDs or names; default: anyone)")
+
+	return cmd
+}
+
+func runBot(opts *BotOptions) {
+	client, err := slack.NewClient(os.Getenv(slackAppTokenEnv))
+	if err != nil {
+		log.Fatalf("%v (set %s)", err, slackAppTokenEnv)
+	}
+	if len(opts.Contexts) == 0 {
+		log.Fatal("Name at least one --context")
+	}
+
+	b := &bot.Bot{
+		Backends:       map[string]serve.Backend{},
+		DefaultContext: opts.Contexts[0],
+		Channels:       opts.Channels,
+		Users:          opts.Users,
+		Audit:          auditlog.Record,
+	}
+	for _, name := range opts.Contexts {
+		c := clusterFromEnv(name)
+		if err := c.EnsureContext(); err != nil {
+			log.Fatalf("Failed to ensure cluster context %s: %v", name, err)
+		}
+		b.Backends[name] = &clusterBackend{c: c, context: name}
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	log.Infof("Answering /ods commands for %v", opts.Contexts)
+	if err := client.Run(ctx, b.Handle); err != nil {
+		log.Fatalf("Slack bot failed: %v", err)
+	}
+}
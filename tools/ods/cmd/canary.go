@@ -0,0 +1,197 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/api"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/apiclient"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/canary"
+)
+
+// CanaryOptions holds options for the canary command.
+type CanaryOptions struct {
+	APISessionOptions
+	URL           string
+	Interval      time.Duration
+	Once          bool
+	CCPairID      int
+	Persona       int
+	SearchTimeout time.Duration
+	ExpectAnswer  bool
+	MetricsAddr   string
+}
+
+// NewCanaryCommand creates the canary command.
+func NewCanaryCommand() *cobra.Command {
+	opts := &CanaryOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "canary",
+		Short: "Continuously run a synthetic end-to-end flow against an environment",
+		Long: `Continuously run a synthetic end-to-end flow against an environment.
+
+Every --interval, runs these stages and reports each one's outcome:
+  login   GET /me
+  ingest  upsert a document with a unique code word through the ingestion API
+  search  find the document by its code word (admin search), waiting up to
+          --search-timeout for it to become searchable
+  chat    ask about the code word and read the answer stream to the end
+  delete  delete the document and chat session, even if a stage failed
+
+The chat stage passes when the stream ends with an answer and no error;
+--expect-answer also requires the answer to repeat the code word, which makes
+the canary sensitive to LLM quality.
+
+The server and auth are chosen as for ` + "`ods curl`" + `, or with --url the API is
+reached directly (for example through the public ingress) with
+$ONYX_API_KEY, which needs no cluster access. The API key must belong to an
+admin or curator.
+
+--metrics-addr serves Prometheus metrics (ods_canary_up,
+ods_canary_stage_up, ods_canary_stage_duration_seconds, ...) for alerting.
+--once runs a single time and exits non-zero if it failed.
+
+Examples:
+  ods canary --once
+  ods canary --url https://cloud.onyx.app/api --interval 5m --metrics-addr :9464
+  ods canary -c staging --tenant tenant_abcd1234 --reason OPS-123 --interval 1m`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			runCanary(opts)
+		},
+	}
+
+	addAPISessionFlags(cmd, &opts.APISessionOptions)
+	cmd.Flags().StringVar(&opts.URL, "url", "", "API URL to probe directly with $ONYX_API_KEY, instead of a context")
+	cmd.Flags().DurationVar(&opts.Interval, "interval", 5*time.Minute, "Time between runs")
+	cmd.Flags().BoolVar(&opts.Once, "once", false, "Run once and exit non-zero on failure")
+	cmd.Flags().IntVar(&opts.CCPairID, "cc-pair-id", 0, "Connector-credential pair for the canary document (default: the ingestion API's)")
+	cmd.Flags().IntVar(&opts.Persona, "persona", 0, "Persona (assistant) ID to chat with")
+	cmd.Flags().DurationVar(&opts.SearchTimeout, "search-timeout", 30*time.Second, "How long to wait for the document to become searchable")
+	cmd.Flags().BoolVar(&opts.ExpectAnswer, "expect-answer", false, "Fail the chat stage unless the answer repeats the code word")
+	cmd.Flags().StringVar(&opts.MetricsAddr, "metrics-addr", "", "Serve Prometheus metrics on this address (e.g. :9464)")
+
+	return cmd
+}
+
+func runCanary(opts *CanaryOptions) {
+	if !opts.Once && opts.Interval <= 0 {
+		log.Fatalf("--interval must be positive")
+	}
+	canaryOpts := canary.Options{
+		CCPairID:      opts.CCPairID,
+		Persona:       opts.Persona,
+		SearchTimeout: opts.SearchTimeout,
+		ExpectAnswer:  opts.ExpectAnswer,
+	}
+
+	var client *apiclient.Client
+	closeSession := func() {}
+	env := opts.Context
+	if opts.URL != "" {
+		client = apiclient.New(opts.URL)
+		client.APIKey = os.Getenv("ONYX_API_KEY")
+		if client.APIKey == "" {
+			log.Fatal("--url needs ONYX_API_KEY set to an admin's or curator's API key")
+		}
+		env = client.BaseURL
+	} else {
+		session := openAPISession(&opts.APISessionOptions, "canary.impersonate")
+		closeSession = session.Close
+		defer closeSession()
+		client = session.Client
+		log.Infof("Running the canary against %s", session.Target)
+	}
+
+	if opts.Once {
+		result := canary.Run(api.New(client), canaryOpts)
+		printCanaryStages(result)
+		if f := result.FirstFailure(); f != nil {
+			closeSession()
+			log.Fatalf("Canary failed at %s: %s", f.Stage, f.Detail)
+		}
+		return
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	metrics := canary.NewMetrics(env)
+	if opts.MetricsAddr != "" {
+		mux := http.NewServeMux()
+		mux.Handle("/metrics", metrics)
+		server := &http.Server{Addr: opts.MetricsAddr, Handler: mux, ReadHeaderTimeout: 30 * time.Second}
+		go func() {
+			if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+				log.Errorf("Metrics server failed: %v", err)
+			}
+		}()
+		defer func() { _ = server.Close() }()
+		log.Infof("Serving Prometheus metrics on %s/metrics", opts.MetricsAddr)
+	}
+
+	ticker := time.NewTicker(opts.Interval)
+	defer ticker.Stop()
+	for {
+		result := canary.Run(api.New(client), canaryOpts)
+		metrics.Record(result)
+		logCanaryResult(result)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// logCanaryResult logs a one-line summary of a run, plus the detail of
+// every stage that did not pass.
+func logCanaryResult(result canary.Result) {
+	timings := make([]string, 0, len(result.Stages))
+	for _, s := range result.Stages {
+		if !s.Skipped {
+			timings = append(timings, fmt.Sprintf("%s %s", s.Stage, s.Duration.Round(time.Millisecond)))
+		}
+	}
+	summary := strings.Join(timings, ", ")
+	if result.OK() {
+		log.Infof("Canary passed in %s (%s)", result.Duration().Round(time.Millisecond), summary)
+		return
+	}
+	log.Errorf("Canary FAILED (%s)", summary)
+	for _, s := range result.Stages {
+		if !s.OK {
+			log.Errorf("  %s: %s", s.Stage, s.Detail)
+		}
+	}
+}
+
+func printCanaryStages(result canary.Result) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "STAGE\tSTATUS\tDURATION\tDETAIL")
+	_, _ = fmt.Fprintln(w, "-----\t------\t--------\t------")
+	for _, s := range result.Stages {
+		status, duration := "PASS", s.Duration.Round(time.Millisecond).String()
+		switch {
+		case s.Skipped:
+			status, duration = "SKIP", "-"
+		case !s.OK:
+			status = "FAIL"
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", s.Stage, status, duration, s.Detail)
+	}
+	_ = w.Flush()
+}
@@ -0,0 +1,210 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/docker"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/notify"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/queues"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/watch"
+)
+
+// CeleryWatchOptions holds options for the celery watch command.
+type CeleryWatchOptions struct {
+	Context        string
+	Interval       time.Duration
+	AlertThreshold int
+	MaxAge         time.Duration
+	Oldest         int
+	Notify         string
+	NoBeep         bool
+}
+
+// NewCeleryCommand creates the parent celery command.
+func NewCeleryCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "celery",
+		Short: "Inspect Celery queues",
+	}
+
+	cmd.AddCommand(newCeleryWatchCommand())
+
+	return cmd
+}
+
+func newCeleryWatchCommand() *cobra.Command {
+	opts := &CeleryWatchOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "watch",
+		Short: "Watch queue depths and task ages, alarming on backlogs",
+		Long: `Watch the depth of every Celery queue and the age of its oldest tasks, and
+raise an alarm when a queue passes --alert-threshold waiting tasks or its
+oldest task passes --max-age.
+
+The broker is read from an api-server pod (or, with -c local, the compose
+stack's api_server container) every --interval. Unacknowledged tasks, those
+a worker holds prefetched or is running, are aged from when they were
+delivered. Queued tasks carry no enqueue time, so they are aged from when
+this watch first saw them, which understates how long they waited before.
+
+When a queue is alarmed the terminal beeps, --notify posts to Slack, and
+its oldest tasks are shown with their arguments. A queue is alarmed again
+only after it has recovered.
+
+Examples:
+  ods celery watch -c prod --alert-threshold 1000
+  ods celery watch -c prod --max-age 15m --notify slack:#oncall
+  ods celery watch --interval 5s`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			runCeleryWatch(opts)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.Context, "context", "c", localContext, `cluster context name (maps to KUBE_CTX_<NAME> env var), or "local" for the compose stack`)
+	cmd.Flags().DurationVar(&opts.Interval, "interval", 15*time.Second, "How often to sample the queues")
+	cmd.Flags().IntVar(&opts.AlertThreshold, "alert-threshold", 1000, "Alarm when a queue has this many waiting tasks (0 disables)")
+	cmd.Flags().DurationVar(&opts.MaxAge, "max-age", 30*time.Minute, "Alarm when a queue's oldest task is this old (0 disables)")
+	cmd.Flags().IntVar(&opts.Oldest, "oldest", 5, "Oldest tasks to show for each alarmed queue")
+	cmd.Flags().StringVar(&opts.Notify, "notify", "", "Post raised alarms to Slack, e.g. slack:#oncall")
+	cmd.Flags().BoolVar(&opts.NoBeep, "no-beep", false, "Do not ring the terminal bell on alarms")
+
+	return cmd
+}
+
+func runCeleryWatch(opts *CeleryWatchOptions) {
+	if opts.Interval <= 0 {
+		log.Fatal("--interval must be positive")
+	}
+	if opts.AlertThreshold < 0 || opts.MaxAge < 0 || opts.Oldest < 0 {
+		log.Fatal("--alert-threshold, --max-age and --oldest cannot be negative")
+	}
+
+	var target notify.Target
+	var webhook string
+	if opts.Notify != "" {
+		var err error
+		if target, err = notify.ParseTarget(opts.Notify); err != nil {
+			log.Fatal(err)
+		}
+		if webhook, err = notify.WebhookURL(target, loadODSConfig().Notify); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	var run queues.Runner
+	where := localContext
+	if opts.Context == localContext {
+		run = queues.ContainerRunner(fmt.Sprintf("%s-api_server-1", docker.ProjectName()))
+	} else {
+		c := clusterFromEnv(opts.Context)
+		if err := c.EnsureContext(); err != nil {
+			log.Fatalf("Failed to ensure cluster context: %v", err)
+		}
+		pod, err := c.FindPod("api-server")
+		if err != nil {
+			log.Fatalf("Failed to find api-server pod: %v", err)
+		}
+		run = queues.PodRunner(c, pod)
+		where = c.Name + "/" + c.Namespace
+	}
+
+	tracker := queues.NewTracker(queues.Thresholds{Depth: opts.AlertThreshold, Age: opts.MaxAge})
+	title := "ods " + strings.Join(os.Args[1:], " ")
+	err := watch.Run(opts.Interval, title, func() error {
+		s, err := queues.Take(run, opts.Oldest)
+		if err != nil {
+			return fmt.Errorf("failed to sample the queues: %w", err)
+		}
+		alarms, raised := tracker.Observe(s, time.Now())
+		printQueues(s, alarms, opts.Oldest)
+
+		if len(raised) > 0 {
+			if !opts.NoBeep {
+				_, _ = fmt.Fprint(os.Stderr, "\a")
+			}
+			if webhook != "" {
+				if err := notify.SendText(webhook, target, celeryAlarmText(where, raised)); err != nil {
+					log.Warnf("Failed to post to %s: %v", target, err)
+				}
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		log.Fatalf("Watch failed: %v", err)
+	}
+}
+
+func printQueues(s *queues.Sample, alarms []queues.Alarm, oldest int) {
+	alarmed := map[string]bool{}
+	for _, a := range alarms {
+		alarmed[a.Queue] = true
+	}
+
+	busy := s.Busy()
+	if len(busy) == 0 {
+		fmt.Println("Every queue is empty.")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "QUEUE\tWAITING\tUNACKED\tOLDEST\t")
+	for _, q := range busy {
+		age := "-"
+		if d := queues.OldestAge(q, s.Tasks); d > 0 {
+			age = queues.FormatAge(d)
+		}
+		mark := ""
+		if alarmed[q.Name] {
+			mark = "ALARM"
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%s\n", q.Name, q.Depth, q.Unacked, age, mark)
+	}
+	_ = w.Flush()
+
+	if len(alarms) == 0 {
+		return
+	}
+	fmt.Println()
+	for _, a := range alarms {
+		fmt.Printf("ALARM %s: %s\n", a.Queue, a.Message)
+	}
+	tasks := s.Stuck(alarmed)
+	if len(tasks) == 0 || oldest == 0 {
+		return
+	}
+	fmt.Println("\nOldest tasks:")
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "QUEUE\tSTATE\tAGE\tTASK\tID\tARGS")
+	for _, t := range tasks {
+		age := "-"
+		if t.AgeSeconds != nil {
+			age = queues.FormatAge(time.Duration(*t.AgeSeconds) * time.Second)
+		}
+		args := strings.TrimSpace(strings.Trim(t.Args, "()") + " " + t.Kwargs)
+		if t.ETA != nil {
+			args += " eta=" + *t.ETA
+		}
+		if t.Retries > 0 {
+			args += fmt.Sprintf(" retries=%d", t.Retries)
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", t.Queue, t.State, age, t.Name, t.ID, args)
+	}
+	_ = w.Flush()
+}
+
+func celeryAlarmText(where string, raised []queues.Alarm) string {
+	lines := []string{fmt.Sprintf(":rotating_light: Celery queue backlog in `%s`", where)}
+	for _, a := range raised {
+		lines = append(lines, fmt.Sprintf("• `%s`: %s", a.Queue, a.Message))
+	}
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,371 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/auditlog"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/chaos"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/docker"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/kube"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/paths"
+)
+
+// chaosServiceAliases maps the names people use for the infrastructure
+// services to their docker compose service names.
+var chaosServiceAliases = map[string]string{
+	"postgres": "relational_db",
+	"redis":    "cache",
+	"api":      "api_server",
+	"web":      "web_server",
+}
+
+// ChaosOptions holds options shared by the chaos subcommands.
+type ChaosOptions struct {
+	Context  string
+	Duration time.Duration
+	Image    string
+}
+
+// NewChaosCommand creates the parent chaos command.
+func NewChaosCommand() *cobra.Command {
+	opts := &ChaosOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "chaos",
+		Short: "Inject failures into the local stack to test resiliency",
+		Long: `Inject failures into services to see how the rest of Onyx copes.
+
+By default faults target the local docker compose stack, where services are
+named as in docker-compose.yml (postgres, redis, api and web are accepted
+for relational_db, cache, api_server and web_server). With -c they target a
+non-production cluster instead, where the service is matched against pod
+names; production contexts are refused.
+
+latency and partition hold the fault for --duration (0: until interrupted)
+and then undo it. If ods is killed first, ` + "`ods chaos heal`" + ` undoes the
+faults it left in the local stack.`,
+	}
+
+	cmd.PersistentFlags().StringVarP(&opts.Context, "context", "c", localContext, `cluster context name (maps to KUBE_CTX_<NAME> env var), or "local" for the compose stack`)
+
+	cmd.AddCommand(newChaosKillCommand(opts))
+	cmd.AddCommand(newChaosLatencyCommand(opts))
+	cmd.AddCommand(newChaosPartitionCommand(opts))
+	cmd.AddCommand(newChaosHealCommand(opts))
+
+	return cmd
+}
+
+func newChaosKillCommand(opts *ChaosOptions) *cobra.Command {
+	var signalName string
+
+	cmd := &cobra.Command{
+		Use:   "kill <service>",
+		Short: "Kill a service's container or pod",
+		Long: `Kill a service's container, or on a cluster one of its pods.
+
+Locally the container's main process gets --signal (KILL by default); compose
+restarts it or not according to the service's restart policy. On a cluster
+the first matching pod is deleted without a grace period, as if its node had
+died, and its deployment replaces it.
+
+Examples:
+  ods chaos kill background
+  ods chaos kill redis --signal TERM
+  ods chaos kill celery-worker-docprocessing -c staging`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			runChaosKill(opts, args[0], signalName)
+		},
+	}
+
+	cmd.Flags().StringVar(&signalName, "signal", "KILL", "Signal to send to a local container")
+
+	return cmd
+}
+
+func newChaosLatencyCommand(opts *ChaosOptions) *cobra.Command {
+	var jitter time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "latency <service> <delay>",
+		Short: "Delay a service's network traffic",
+		Long: `Delay every packet a service sends by <delay>, optionally varied by --jitter.
+
+Runs tc netem from a sidecar (--image) in the service's network namespace:
+locally a throwaway container, on a cluster an ephemeral debug container,
+which stays in the pod spec, terminated, until the pod is replaced. Replies
+to the service are delayed as well, so calls to and from it slow down.
+
+Examples:
+  ods chaos latency postgres 500ms
+  ods chaos latency api_server 2s --jitter 500ms --duration 10m
+  ods chaos latency api-server 300ms -c staging`,
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			delay, err := time.ParseDuration(args[1])
+			if err != nil {
+				log.Fatalf("Invalid delay %q: %v", args[1], err)
+			}
+			runChaosLatency(opts, args[0], delay, jitter)
+		},
+	}
+
+	cmd.Flags().DurationVar(&jitter, "jitter", 0, "Vary the delay by up to this much")
+	addChaosHoldFlags(cmd, opts)
+
+	return cmd
+}
+
+func newChaosPartitionCommand(opts *ChaosOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "partition <service>",
+		Short: "Cut a local service off the network",
+		Long: `Cut a service of the local stack off the network.
+
+Disconnects the service's container from its docker networks, so the other
+services cannot resolve or reach it while it keeps running, then reconnects
+it with its aliases. Only the local stack is supported.
+
+Examples:
+  ods chaos partition postgres
+  ods chaos partition redis --duration 2m`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			runChaosPartition(opts, args[0])
+		},
+	}
+
+	addChaosHoldFlags(cmd, opts)
+
+	return cmd
+}
+
+func newChaosHealCommand(opts *ChaosOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "heal",
+		Short: "Undo faults left in the local stack",
+		Long: `Undo the latency and partitions an interrupted ods chaos left in the local
+stack.
+
+Examples:
+  ods chaos heal`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			runChaosHeal(opts)
+		},
+	}
+}
+
+func addChaosHoldFlags(cmd *cobra.Command, opts *ChaosOptions) {
+	cmd.Flags().DurationVar(&opts.Duration, "duration", 5*time.Minute, "How long to hold the fault (0: until interrupted)")
+	cmd.Flags().StringVar(&opts.Image, "image", chaos.DefaultImage, "Sidecar image providing tc")
+}
+
+// chaosCluster returns the cluster faults target, or nil for the local
+// stack. Production contexts are refused.
+func chaosCluster(opts *ChaosOptions) *kube.Cluster {
+	if opts.Context == localContext {
+		return nil
+	}
+	if isProductionContext(opts.Context) {
+		log.Fatalf("Refusing to inject failures into %s; chaos runs against the local stack or dev, staging and test contexts", opts.Context)
+	}
+	c := clusterFromEnv(opts.Context)
+	if err := c.EnsureContext(); err != nil {
+		log.Fatalf("Failed to ensure cluster context: %v", err)
+	}
+	return c
+}
+
+// chaosContainer returns the local compose container of service.
+func chaosContainer(service string) string {
+	if s, ok := chaosServiceAliases[service]; ok {
+		service = s
+	}
+	return fmt.Sprintf("%s-%s-1", docker.ProjectName(), service)
+}
+
+// chaosPod returns the first pod of service, recording the fault about to be
+// injected into it in the audit log.
+func chaosPod(c *kube.Cluster, service, action, detail string) string {
+	pod, err := c.FindPod(service)
+	if err != nil {
+		log.Fatalf("Failed to find %s pod: %v", service, err)
+	}
+	if err := auditlog.Record(auditlog.Entry{
+		Action:  "chaos." + action,
+		Context: c.Name + "/" + c.Namespace,
+		Target:  pod,
+		Detail:  detail,
+	}); err != nil {
+		log.Fatalf("Refusing to inject a failure without an audit record: %v", err)
+	}
+	return pod
+}
+
+func loadChaosState() *chaos.State {
+	s, err := chaos.LoadState(paths.ChaosStatePath())
+	if err != nil {
+		log.Fatalf("Failed to read the chaos state: %v", err)
+	}
+	return s
+}
+
+func saveChaosState(s *chaos.State) {
+	if err := s.Save(); err != nil {
+		log.Errorf("Failed to save the chaos state: %v", err)
+	}
+}
+
+// holdFault waits for the fault's duration or an interrupt.
+func holdFault(what string, d time.Duration) {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	if d > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d)
+		defer cancel()
+		log.Infof("Holding %s for %s (Ctrl-C to undo it now)...", what, d)
+	} else {
+		log.Infof("Holding %s until interrupted (Ctrl-C to undo it)...", what)
+	}
+	<-ctx.Done()
+}
+
+func runChaosKill(opts *ChaosOptions, service, signalName string) {
+	c := chaosCluster(opts)
+	if c != nil {
+		pod := chaosPod(c, service, "kill", "")
+		if err := c.KillPod(pod); err != nil {
+			log.Fatalf("Failed to kill %s: %v", pod, err)
+		}
+		log.Infof("Killed %s; watch it come back with: ods health -c %s", pod, opts.Context)
+		return
+	}
+
+	container := chaosContainer(service)
+	if err := docker.Kill(container, signalName); err != nil {
+		log.Fatalf("Failed to kill %s: %v", container, err)
+	}
+	log.Infof("Sent SIG%s to %s", signalName, container)
+}
+
+func runChaosLatency(opts *ChaosOptions, service string, delay, jitter time.Duration) {
+	script, err := chaos.LatencyScript(delay, jitter)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	what := fmt.Sprintf("%s of latency on %s", delay, service)
+
+	if c := chaosCluster(opts); c != nil {
+		pod := chaosPod(c, service, "latency", delay.String())
+		if err := c.RunDebugContainer(pod, opts.Image, nil, os.Stdout, "sh", "-c", script); err != nil {
+			log.Fatalf("Failed to add latency to %s: %v", pod, err)
+		}
+		holdFault(what, opts.Duration)
+		if err := c.RunDebugContainer(pod, opts.Image, nil, os.Stdout, "sh", "-c", chaos.ClearScript()); err != nil {
+			log.Fatalf("Failed to remove the latency from %s: %v; the pod keeps it until replaced", pod, err)
+		}
+		log.Infof("Removed the latency from %s", pod)
+		return
+	}
+
+	container := chaosContainer(service)
+	if err := docker.RunInNetNamespace(container, opts.Image, "sh", "-c", script); err != nil {
+		log.Fatalf("Failed to add latency to %s: %v", container, err)
+	}
+	state := loadChaosState()
+	state.AddLatency(container)
+	saveChaosState(state)
+
+	holdFault(what, opts.Duration)
+	healLatency(state, container, opts.Image)
+	saveChaosState(state)
+}
+
+func runChaosPartition(opts *ChaosOptions, service string) {
+	if chaosCluster(opts) != nil {
+		log.Fatal("partition only supports the local stack")
+	}
+
+	container := chaosContainer(service)
+	networks, err := docker.ContainerNetworks(container)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	if len(networks) == 0 {
+		log.Fatalf("%s is not attached to any network; partitioned already? (ods chaos heal)", container)
+	}
+	state := loadChaosState()
+	state.AddPartition(container, networks)
+	saveChaosState(state)
+	for _, n := range networks {
+		if err := docker.DisconnectNetwork(container, n.Network); err != nil {
+			log.Errorf("Failed to disconnect %s from %s: %v", container, n.Network, err)
+		}
+	}
+	log.Infof("Disconnected %s from %d network(s)", container, len(networks))
+
+	holdFault(fmt.Sprintf("the partition of %s", service), opts.Duration)
+	healPartition(state, container)
+	saveChaosState(state)
+}
+
+func runChaosHeal(opts *ChaosOptions) {
+	if opts.Context != localContext {
+		log.Fatal("heal only supports the local stack; cluster faults end when their pod is replaced")
+	}
+	state := loadChaosState()
+	if state.Empty() {
+		log.Info("No faults recorded; nothing to heal")
+		return
+	}
+	for container := range state.Partitions {
+		healPartition(state, container)
+	}
+	for _, container := range append([]string(nil), state.Latency...) {
+		healLatency(state, container, chaos.DefaultImage)
+	}
+	saveChaosState(state)
+	if !state.Empty() {
+		log.Fatal("Some faults could not be undone; see the errors above")
+	}
+}
+
+// healLatency removes the latency injected into container, forgetting it
+// in state once removed.
+func healLatency(state *chaos.State, container, image string) {
+	if err := docker.RunInNetNamespace(container, image, "sh", "-c", chaos.ClearScript()); err != nil {
+		log.Errorf("Failed to remove the latency from %s: %v", container, err)
+		return
+	}
+	state.RemoveLatency(container)
+	log.Infof("Removed the latency from %s", container)
+}
+
+// healPartition reconnects container to the networks recorded in state,
+// keeping only those it could not rejoin.
+func healPartition(state *chaos.State, container string) {
+	var failed []docker.NetworkAttachment
+	for _, n := range state.Partitions[container] {
+		if err := docker.ConnectNetwork(container, n); err != nil {
+			log.Errorf("Failed to reconnect %s to %s: %v", container, n.Network, err)
+			failed = append(failed, n)
+		}
+	}
+	if len(failed) > 0 {
+		state.Partitions[container] = failed
+		return
+	}
+	delete(state.Partitions, container)
+	log.Infof("Reconnected %s", container)
+}
@@ -60,7 +60,7 @@ func runCheckLazyImports(providedPaths []string) {
 
 		violatedModulesStr := lazyimports.FormatViolatedModules(allViolatedModules)
 		fmt.Fprintf(os.Stderr, "\nFound eager imports of %s. You must import them only when needed.\n", violatedModulesStr)
-		os.Exit(1)
+		exitWith(1)
 	}
 
 	log.Info("✅ All lazy modules are properly imported!")
@@ -0,0 +1,192 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/chunks"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/render"
+)
+
+// ChunksOptions holds options for the chunks command.
+type ChunksOptions struct {
+	Context        string
+	Tenant         string
+	RechunkPreview bool
+	JSON           bool
+}
+
+// NewChunksCommand creates the chunks command.
+func NewChunksCommand() *cobra.Command {
+	opts := &ChunksOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "chunks <document-id>",
+		Short: "Show how a document was chunked in Vespa",
+		Long: `Show how a document was chunked, straight from Vespa.
+
+For each chunk: its size in characters and tokens (with the index's
+tokenizer, as cut and as embedded with title prefix and metadata suffix),
+whether it continues a section, its links, which embeddings it carries, and
+its first and last characters so the boundaries can be checked. Problems
+(missing chunk IDs, empty chunks, chunks over the token limit, missing
+embeddings) are listed at the end. Large chunks are not shown.
+
+--rechunk-preview rebuilds the document's text from the stored chunks and
+cuts it again with the current chunker settings, showing what re-indexing
+would produce. The rebuilt text lacks anything the connector dropped before
+chunking, so treat small differences with care.
+
+Look up a document's ID by link with ods doc. On a single-tenant deployment
+omit --tenant.
+
+Requires: AWS SSO login, kubectl access to the EKS cluster.
+
+Examples:
+  ods chunks 'FILE_CONNECTOR__1b2c3d' --tenant tenant_abcd1234
+  ods chunks https://docs.example.com/setup --tenant tenant_abcd1234 --rechunk-preview
+  ods chunks 'FILE_CONNECTOR__1b2c3d' --tenant tenant_abcd1234 --json | jq '.chunks[].tokens'`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			runChunks(opts, args[0])
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.Context, "context", "c", "data_plane", "cluster context name (maps to KUBE_CTX_<NAME> env var)")
+	cmd.Flags().StringVar(&opts.Tenant, "tenant", "", "Tenant schema (omit on single-tenant deployments)")
+	cmd.Flags().BoolVar(&opts.RechunkPreview, "rechunk-preview", false, "Also show how the current chunker settings would cut the document")
+	cmd.Flags().BoolVar(&opts.JSON, "json", false, "Print the chunks as JSON")
+
+	return cmd
+}
+
+func runChunks(opts *ChunksOptions, docID string) {
+	if opts.Tenant != "" {
+		validateTenantArg(opts.Tenant)
+	}
+	c := clusterFromEnv(opts.Context)
+	if err := c.EnsureContext(); err != nil {
+		log.Fatalf("Failed to ensure cluster context: %v", err)
+	}
+	log.Info("Finding api-server pod...")
+	pod, err := c.FindPod("api-server")
+	if err != nil {
+		log.Fatalf("Failed to find api-server pod: %v", err)
+	}
+
+	r, err := chunks.Inspect(c, pod, opts.Tenant, docID, opts.RechunkPreview)
+	if err != nil {
+		log.Fatalf("Failed to inspect chunks of %s: %v", docID, err)
+	}
+	if opts.JSON {
+		if err := render.JSON(os.Stdout, r); err != nil {
+			log.Fatalf("Failed to marshal chunks: %v", err)
+		}
+		return
+	}
+	printChunks(r)
+}
+
+func printChunks(r *chunks.Report) {
+	fmt.Printf("Document %s in %s: %d chunk(s), tokenizer %s, limit %d tokens\n\n",
+		r.DocumentID, r.IndexName, len(r.Chunks), r.Tokenizer, r.ChunkTokenLimit)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "CHUNK\tCHARS\tTOKENS\tEMBEDDED TOKENS\tCONT\tEMBEDDINGS\tTITLE EMB\tCONTEXT\tLINKS")
+	_, _ = fmt.Fprintln(w, "-----\t-----\t------\t---------------\t----\t----------\t---------\t-------\t-----")
+	for _, ch := range r.Chunks {
+		_, _ = fmt.Fprintf(w, "%d\t%d\t%d\t%d\t%t\t%s\t%t\t%t\t%d\n",
+			ch.ChunkID, ch.Chars, ch.Tokens, ch.IndexedTokens, ch.SectionContinuation,
+			embeddingSummary(ch.Embeddings), ch.TitleEmbedding, ch.HasContext, len(ch.Links))
+	}
+	_ = w.Flush()
+
+	if len(r.Chunks) > 0 {
+		if t := r.Chunks[0].Title; t != "" {
+			fmt.Printf("\nTitle: %s\n", t)
+		}
+		if m := r.Chunks[0].MetadataSuffix; strings.TrimSpace(m) != "" {
+			fmt.Printf("Metadata suffix: %s\n", strings.TrimSpace(m))
+		}
+	}
+
+	fmt.Println("\nBoundaries:")
+	printChunkEdges(r.Chunks)
+
+	if r.Preview != nil {
+		fmt.Printf("\nRe-chunked with the current settings: %d chunk(s)\n", len(r.Preview))
+		w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "CHUNK\tCHARS\tTOKENS\tSTORED CHARS\tSTORED TOKENS")
+		_, _ = fmt.Fprintln(w, "-----\t-----\t------\t------------\t-------------")
+		for i, ch := range r.Preview {
+			storedChars, storedTokens := "-", "-"
+			if i < len(r.Chunks) {
+				storedChars, storedTokens = strconv.Itoa(r.Chunks[i].Chars), strconv.Itoa(r.Chunks[i].Tokens)
+			}
+			_, _ = fmt.Fprintf(w, "%d\t%d\t%d\t%s\t%s\n", ch.ChunkID, ch.Chars, ch.Tokens, storedChars, storedTokens)
+		}
+		_ = w.Flush()
+		fmt.Println("\nPreview boundaries:")
+		printChunkEdges(r.Preview)
+	}
+
+	fmt.Println()
+	findings := r.Findings()
+	if len(findings) == 0 {
+		fmt.Println("Nothing stands out.")
+		return
+	}
+	fmt.Println("Findings:")
+	for _, f := range findings {
+		fmt.Printf("  - %s\n", f)
+	}
+}
+
+// printChunkEdges prints the start and end of each chunk, one line each.
+func printChunkEdges(list []chunks.Chunk) {
+	for _, ch := range list {
+		if ch.Tail == "" {
+			fmt.Printf("  #%d  %q\n", ch.ChunkID, ch.Head)
+			continue
+		}
+		fmt.Printf("  #%d  %q ... %q\n", ch.ChunkID, ch.Head, ch.Tail)
+	}
+}
+
+// embeddingSummary renders embedding names and dimensions compactly, e.g.
+// "full_chunk:768 +3 mini".
+func embeddingSummary(embeddings map[string]int) string {
+	if len(embeddings) == 0 {
+		return "none"
+	}
+	names := make([]string, 0, len(embeddings))
+	mini := 0
+	for name := range embeddings {
+		if strings.HasPrefix(name, "mini_chunk") {
+			mini++
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	parts := make([]string, len(names))
+	for i, name := range names {
+		if name == "" {
+			parts[i] = strconv.Itoa(embeddings[name])
+			continue
+		}
+		parts[i] = fmt.Sprintf("%s:%d", name, embeddings[name])
+	}
+	s := strings.Join(parts, ",")
+	if mini > 0 {
+		s += fmt.Sprintf(" +%d mini", mini)
+	}
+	return s
+}
@@ -0,0 +1,230 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/api"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/compare"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/render"
+)
+
+// CompareOptions holds options for the compare command.
+type CompareOptions struct {
+	EnvA      string
+	EnvB      string
+	TenantA   string
+	TenantB   string
+	As        string
+	Reason    string
+	Queries   string
+	K         int
+	Chat      bool
+	Persona   int
+	Parallel  int
+	OnlyDiffs bool
+	JSON      bool
+}
+
+// NewCompareCommand creates the compare command.
+func NewCompareCommand() *cobra.Command {
+	opts := &CompareOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "compare --env-a <context> --env-b <context> --queries <file>",
+		Short: "Send the same queries to two environments and diff the results",
+		Long: `Send the same queries to two environments and diff the results.
+
+Each query in --queries (one per line; blank lines and lines starting with #
+are ignored) goes through the admin search of both environments at the same
+time, and with --chat through a new chat session (deleted afterwards). For
+every query the top --k documents are shown side by side, each marked with
+its rank on the other side ("-" where the other side does not have it),
+together with both latencies and, with --chat, both answers and how much
+their wording overlaps. The summary gives the mean overlap of the document
+sets, how many queries returned identical rankings and p50/p95 latencies.
+
+Use it to validate an index rebuild or model upgrade: point one side at the
+environment before the change and the other at the one after, or run it
+against the same environment before and after with --json and compare.
+
+Each side connects as for ` + "`ods curl`" + ` with its context and tenant
+(--tenant-a, --tenant-b). Without a tenant or --as, the API key comes from
+$ONYX_API_KEY_A / $ONYX_API_KEY_B, falling back to $ONYX_API_KEY. The user
+needs admin search access on both sides.
+
+Examples:
+  ods compare --env-a staging --env-b prod --queries queries.txt
+  ods compare --env-a staging --env-b prod --queries queries.txt --chat --only-diffs
+  ods compare --env-a local --env-b staging --tenant-b tenant_abcd1234 --reason OPS-123 --queries queries.txt --json`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			runEnvCompare(opts)
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.EnvA, "env-a", "", `Context of side A, or "local" for the compose stack (required)`)
+	cmd.Flags().StringVar(&opts.EnvB, "env-b", "", `Context of side B, or "local" for the compose stack (required)`)
+	cmd.Flags().StringVar(&opts.TenantA, "tenant-a", "", "Tenant to act in on side A (remote only)")
+	cmd.Flags().StringVar(&opts.TenantB, "tenant-b", "", "Tenant to act in on side B (remote only)")
+	cmd.Flags().StringVar(&opts.As, "as", "", "Email of the user to impersonate on both sides (remote only)")
+	cmd.Flags().StringVar(&opts.Reason, "reason", "", "Ticket or justification recorded in the audit log (required to impersonate)")
+	cmd.Flags().StringVar(&opts.Queries, "queries", "", "File of queries, one per line (required)")
+	cmd.Flags().IntVar(&opts.K, "k", 10, "Number of top documents to compare")
+	cmd.Flags().BoolVar(&opts.Chat, "chat", false, "Also compare chat answers")
+	cmd.Flags().IntVar(&opts.Persona, "persona", 0, "Persona (assistant) ID to chat with")
+	cmd.Flags().IntVar(&opts.Parallel, "parallel", 2, "Number of queries to run at once")
+	cmd.Flags().BoolVar(&opts.OnlyDiffs, "only-diffs", false, "Only show queries whose rankings differ")
+	cmd.Flags().BoolVar(&opts.JSON, "json", false, "Print the results as JSON")
+	_ = cmd.MarkFlagRequired("env-a")
+	_ = cmd.MarkFlagRequired("env-b")
+	_ = cmd.MarkFlagRequired("queries")
+
+	return cmd
+}
+
+func runEnvCompare(opts *CompareOptions) {
+	if opts.K < 1 {
+		log.Fatalf("--k must be positive")
+	}
+	queries, err := compare.LoadQueries(opts.Queries)
+	if err != nil {
+		log.Fatalf("Failed to load queries: %v", err)
+	}
+
+	sideA := openAPISession(&APISessionOptions{
+		Context: opts.EnvA, Tenant: opts.TenantA, As: opts.As, Reason: opts.Reason,
+		APIKey: os.Getenv("ONYX_API_KEY_A"),
+	}, "compare.impersonate")
+	defer sideA.Close()
+	sideB := openAPISession(&APISessionOptions{
+		Context: opts.EnvB, Tenant: opts.TenantB, As: opts.As, Reason: opts.Reason,
+		APIKey: os.Getenv("ONYX_API_KEY_B"),
+	}, "compare.impersonate")
+	defer sideB.Close()
+	log.Infof("Comparing %d queries: A is %s, B is %s", len(queries), sideA.Target, sideB.Target)
+
+	done := 0
+	results := compare.Run(api.New(sideA.Client), api.New(sideB.Client), queries, compare.Options{
+		K:        opts.K,
+		Chat:     opts.Chat,
+		Persona:  opts.Persona,
+		Parallel: opts.Parallel,
+		OnResult: func(compare.Result) {
+			done++
+			log.Debugf("[%d/%d] done", done, len(queries))
+		},
+	})
+	summary := compare.Summarize(results)
+
+	if opts.JSON {
+		if err := render.JSON(os.Stdout, map[string]any{
+			"env_a":   opts.EnvA,
+			"env_b":   opts.EnvB,
+			"results": results,
+			"summary": summary,
+		}); err != nil {
+			log.Fatalf("Failed to marshal results: %v", err)
+		}
+		return
+	}
+
+	for i, r := range results {
+		if opts.OnlyDiffs && r.Same() && r.A.Error == "" && r.B.Error == "" {
+			continue
+		}
+		printCompareResult(i+1, r, opts)
+	}
+	printCompareSummary(summary, opts)
+}
+
+func printCompareResult(n int, r compare.Result, opts *CompareOptions) {
+	fmt.Printf("Query %d: %s\n", n, r.Query)
+	for _, side := range []struct {
+		name string
+		s    compare.Side
+	}{{"A", r.A}, {"B", r.B}} {
+		if side.s.Error != "" {
+			fmt.Printf("  %s failed: %s\n", side.name, side.s.Error)
+		}
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintf(w, "  #\tA (%s, %dms)\tIN B\tB (%s, %dms)\tIN A\n", opts.EnvA, r.A.SearchMS, opts.EnvB, r.B.SearchMS)
+	for _, row := range r.Rows() {
+		_, _ = fmt.Fprintf(w, "  %d\t%s\t%s\t%s\t%s\n", row.Rank,
+			shortDocID(row.A), compareRank(row.A, row.AInB), shortDocID(row.B), compareRank(row.B, row.BInA))
+	}
+	_ = w.Flush()
+	fmt.Printf("  overlap %.2f", r.Overlap)
+	if r.Same() {
+		fmt.Print(" (identical ranking)")
+	}
+	fmt.Println()
+
+	if opts.Chat {
+		fmt.Printf("  A answer (%dms): %s\n", r.A.ChatMS, compareAnswer(r.A.Answer))
+		fmt.Printf("  B answer (%dms): %s\n", r.B.ChatMS, compareAnswer(r.B.Answer))
+		if r.AnswerSimilarity != nil {
+			fmt.Printf("  answer word overlap %.2f\n", *r.AnswerSimilarity)
+		}
+	}
+	fmt.Println()
+}
+
+func printCompareSummary(s compare.Summary, opts *CompareOptions) {
+	fmt.Printf("%d queries: %d identical ranking(s), mean top-%d overlap %.2f\n", s.Queries, s.Identical, opts.K, s.MeanOverlap)
+	if s.MeanAnswerSimilarity != nil {
+		fmt.Printf("Mean answer word overlap %.2f\n", *s.MeanAnswerSimilarity)
+	}
+	if s.ErrorsA > 0 || s.ErrorsB > 0 {
+		fmt.Printf("Failed queries: %d on A, %d on B\n", s.ErrorsA, s.ErrorsB)
+	}
+
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "LATENCY\tA P50\tA P95\tB P50\tB P95")
+	_, _ = fmt.Fprintln(w, "-------\t-----\t-----\t-----\t-----")
+	_, _ = fmt.Fprintf(w, "search\t%dms\t%dms\t%dms\t%dms\n", s.SearchA.P50, s.SearchA.P95, s.SearchB.P50, s.SearchB.P95)
+	if s.ChatA != nil && s.ChatB != nil {
+		_, _ = fmt.Fprintf(w, "chat\t%dms\t%dms\t%dms\t%dms\n", s.ChatA.P50, s.ChatA.P95, s.ChatB.P50, s.ChatB.P95)
+	}
+	_ = w.Flush()
+}
+
+// compareRank renders where a document ranks on the other side.
+func compareRank(doc string, rank int) string {
+	switch {
+	case doc == "":
+		return ""
+	case rank == 0:
+		return "-"
+	default:
+		return fmt.Sprintf("#%d", rank)
+	}
+}
+
+// shortDocID keeps long document IDs (often URLs) to one table column.
+func shortDocID(id string) string {
+	if len(id) <= 60 {
+		return id
+	}
+	return id[:28] + "…" + id[len(id)-28:]
+}
+
+func compareAnswer(answer string) string {
+	answer = strings.Join(strings.Fields(answer), " ")
+	switch {
+	case answer == "":
+		return "(none)"
+	case len(answer) > 400:
+		return answer[:400] + "…"
+	default:
+		return answer
+	}
+}
@@ -3,8 +3,8 @@ package cmd
 import (
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
+	"slices"
 	"strings"
 
 	log "github.com/sirupsen/logrus"
@@ -12,6 +12,7 @@ import (
 
 	"github.com/onyx-dot-app/onyx/tools/ods/internal/docker"
 	"github.com/onyx-dot-app/onyx/tools/ods/internal/paths"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/runner"
 )
 
 var validProfiles = []string{"dev", "multitenant"}
@@ -23,6 +24,14 @@ type ComposeOptions struct {
 	Tag           string
 	NoEE          bool
 	Infra         bool
+	Deps          bool
+	Resources     string
+	MockLLM       bool
+	MockOAuth     bool
+	SmartBuild    bool
+	TLS           bool
+	Notify        string
+	Volumes       bool
 }
 
 // NewComposeCommand creates a new compose command for launching docker
@@ -68,8 +77,41 @@ Examples:
   # Start only infrastructure containers (no api_server, background, etc.)
   ods compose dev --infra
 
+  # Start only Postgres, Redis, OpenSearch and MinIO for native development
+  ods compose deps
+
+  # Cap memory/CPU of the search index, Postgres and model servers
+  ods compose dev --resources small
+
+  # Send LLM calls to the ods mock-llm stub on the host
+  ods compose dev --mock-llm
+
+  # Sign in through the ods mock-oauth OIDC provider on the host
+  ods compose dev --mock-oauth
+
+  # Serve https://onyx.localhost with the certificate from ods tls setup
+  ods compose dev --tls
+
   # Use a specific image tag
-  ods compose --tag edge`,
+  ods compose --tag edge
+
+  # Rebuild the backend/web/model server images whose sources changed
+  ods compose --smart-build
+
+  # Post to Slack once the stack is up (or failed to start)
+  ods compose --tag edge --notify slack:#dev-env
+
+  # Run a second, isolated stack next to the main one
+  ods compose dev --project feature-x
+  ods compose list-projects
+  ods compose --project feature-x --down --volumes
+
+Projects: every stack is a docker compose project, named by --project or
+after the git working tree (so each worktree gets its own). Containers and
+volumes are per project. Projects other than the main checkout's ("onyx")
+get free host ports picked for them, starting from the usual ones (port 80
+moves to 3080 and up), and ods remembers each project's ports so a stack
+gets the same ones back when restarted.`,
 		Args:      cobra.MaximumNArgs(1),
 		ValidArgs: validProfiles,
 		Run: func(cmd *cobra.Command, args []string) {
@@ -81,12 +123,22 @@ Examples:
 		},
 	}
 
+	cmd.AddCommand(newComposeDepsCommand())
+	cmd.AddCommand(newComposeListProjectsCommand())
+
 	cmd.Flags().BoolVar(&opts.Down, "down", false, "Stop running containers instead of starting them")
+	cmd.Flags().BoolVar(&opts.Volumes, "volumes", false, "With --down, also delete the project's volumes and forget its ports")
 	cmd.Flags().BoolVar(&opts.Wait, "wait", true, "Wait for services to be healthy before returning")
 	cmd.Flags().BoolVar(&opts.ForceRecreate, "force-recreate", false, "Force recreate containers even if unchanged")
 	cmd.Flags().StringVar(&opts.Tag, "tag", "", "Set the IMAGE_TAG for docker compose (e.g. edge, v2.10.4)")
 	cmd.Flags().BoolVar(&opts.NoEE, "no-ee", false, "Disable Enterprise Edition features (enabled by default)")
 	cmd.Flags().BoolVar(&opts.Infra, "infra", false, "Start only infrastructure containers (db, cache, search, model servers)")
+	addNotifyFlag(cmd, &opts.Notify)
+	cmd.Flags().StringVar(&opts.Resources, "resources", "", "Apply a resource limit preset: "+strings.Join(docker.ResourcePresetNames(), ", "))
+	cmd.Flags().BoolVar(&opts.MockLLM, "mock-llm", false, "Send LLM calls to the ods mock-llm stub on the host (adds "+mockLLMComposeFile+")")
+	cmd.Flags().BoolVar(&opts.MockOAuth, "mock-oauth", false, "Sign in through the ods mock-oauth provider on the host (adds "+mockOAuthComposeFile+")")
+	cmd.Flags().BoolVar(&opts.TLS, "tls", false, "Serve HTTPS with the certificate from ods tls setup (adds "+tlsComposeFile+")")
+	cmd.Flags().BoolVar(&opts.SmartBuild, "smart-build", false, "Build images locally, skipping those whose sources have not changed since their last build")
 
 	return cmd
 }
@@ -148,16 +200,12 @@ func profileLabel(profile string) string {
 func execDockerCompose(args []string, extraEnv []string) {
 	log.Debugf("Running: docker %v", args)
 
-	dockerCmd := exec.Command("docker", args...)
-	dockerCmd.Dir = composeDir()
-	dockerCmd.Stdout = os.Stdout
-	dockerCmd.Stderr = os.Stderr
-	dockerCmd.Stdin = os.Stdin
+	dockerCmd := runner.Cmd{Name: "docker", Args: args, Dir: composeDir(), Stdin: os.Stdin, Stdout: os.Stdout, Stderr: os.Stderr}
 	if len(extraEnv) > 0 {
 		dockerCmd.Env = append(os.Environ(), extraEnv...)
 	}
 
-	if err := dockerCmd.Run(); err != nil {
+	if err := runner.Or(docker.Runner).Run(dockerCmd); err != nil {
 		log.Fatalf("Docker compose failed: %v", err)
 	}
 }
@@ -173,16 +221,14 @@ func runningServiceNames() []string {
 
 	args := []string{"compose", "-p", docker.ProjectName(), "ps", "--services"}
 
-	cmd := exec.Command("docker", args...)
-	cmd.Dir = filepath.Join(gitRoot, "deployment", "docker_compose")
-	out, err := cmd.Output()
+	out, err := runner.Output(docker.Runner, runner.Cmd{Name: "docker", Args: args, Dir: filepath.Join(gitRoot, "deployment", "docker_compose")})
 	if err != nil {
 		return nil
 	}
 
 	var services []string
 	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
-		if line != "" {
+		if line = strings.TrimSpace(line); line != "" {
 			services = append(services, line)
 		}
 	}
@@ -228,7 +274,12 @@ func setEnvValue(key, value string) {
 		return
 	}
 
-	lines := strings.Split(string(data), "\n")
+	// Keep Windows line endings if the file already uses them.
+	eol := "\n"
+	if strings.Contains(string(data), "\r\n") {
+		eol = "\r\n"
+	}
+	lines := strings.Split(string(data), eol)
 	found := false
 	for i, line := range lines {
 		if strings.HasPrefix(line, prefix) {
@@ -248,7 +299,7 @@ func setEnvValue(key, value string) {
 		}
 	}
 
-	if err := os.WriteFile(envPath, []byte(strings.Join(lines, "\n")), 0644); err != nil {
+	if err := os.WriteFile(envPath, []byte(strings.Join(lines, eol)), 0644); err != nil {
 		log.Fatalf("Failed to write %s: %v", envPath, err)
 	}
 }
@@ -260,7 +311,21 @@ func setEnvValue(key, value string) {
 func runCompose(profile string, opts *ComposeOptions) {
 	validateProfile(profile)
 
+	var preset docker.ResourcePreset
+	if opts.Resources != "" {
+		var ok bool
+		if preset, ok = docker.FindResourcePreset(opts.Resources); !ok {
+			log.Fatalf("Invalid resource preset %q. Valid presets: %s", opts.Resources, strings.Join(docker.ResourcePresetNames(), ", "))
+		}
+	}
+
 	if !opts.Down {
+		// The memory presets are sized for the model servers, which deps
+		// does not start.
+		if !opts.Deps {
+			checkHostMemory(opts.Resources, preset)
+		}
+
 		eeValue := "true"
 		if opts.NoEE {
 			eeValue = "false"
@@ -270,24 +335,49 @@ func runCompose(profile string, opts *ComposeOptions) {
 			setEnvValue("LICENSE_ENFORCEMENT_ENABLED", "false")
 		}
 
-		if profile == "dev" || profile == "multitenant" {
-			ports, err := docker.FindAvailablePorts()
-			if err != nil {
-				log.Fatalf("Failed to find available ports: %v", err)
-			}
-			for k, v := range ports.ComposeEnv() {
-				setEnvValue(k, v)
-			}
-		}
+	}
+	if opts.Volumes && !opts.Down {
+		log.Fatalf("--volumes only applies with --down")
+	}
+
+	projName := docker.ProjectName()
+	env := envForTag(opts.Tag)
+	if !opts.Down && (profile == "dev" || profile == "multitenant" || !docker.IsDefaultProject(projName)) {
+		env = append(env, resolveProjectPorts(projName, profile)...)
 	}
 
 	args := baseArgs(profile)
+	if opts.Resources != "" && !opts.Down {
+		override, err := preset.WriteOverride()
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		args = append(args, "-f", override)
+		log.Infof("Applying %q resource limits", preset.Name)
+	}
+	if opts.MockLLM && !opts.Down {
+		args = append(args, "-f", mockLLMComposeFile)
+		log.Info("Sending LLM calls to the mock-llm stub (start it with `ods mock-llm serve`)")
+	}
+	if opts.MockOAuth && !opts.Down {
+		args = append(args, "-f", mockOAuthComposeFile)
+		log.Info("Signing in through the mock OIDC provider (start it with `ods mock-oauth serve`)")
+	}
+
+	if opts.TLS && !opts.Down {
+		args = append(args, "-f", tlsComposeFile)
+		log.Info("Serving HTTPS with the ods tls setup certificate")
+	}
+	if opts.SmartBuild && !opts.Down {
+		smartBuild(args, composeServices(opts), env)
+	}
 
 	if opts.Down {
 		args = append(args, "down")
-		if opts.Infra {
-			args = append(args, docker.InfraServiceNames()...)
+		if opts.Volumes {
+			args = append(args, "--volumes")
 		}
+		args = append(args, composeServices(opts)...)
 	} else {
 		args = append(args, "up", "-d")
 		if opts.Wait {
@@ -296,12 +386,9 @@ func runCompose(profile string, opts *ComposeOptions) {
 		if opts.ForceRecreate {
 			args = append(args, "--force-recreate")
 		}
-		if opts.Infra {
-			args = append(args, docker.InfraServiceNames()...)
-		}
+		args = append(args, composeServices(opts)...)
 	}
 
-	projName := docker.ProjectName()
 	action := "Starting"
 	if opts.Down {
 		action = "Stopping"
@@ -310,11 +397,135 @@ func runCompose(profile string, opts *ComposeOptions) {
 	if !opts.Down && !opts.NoEE {
 		log.Info("Enterprise Edition features enabled (use --no-ee to disable)")
 	}
-	execDockerCompose(args, envForTag(opts.Tag))
+	notifier := startNotifier(opts.Notify)
+	execDockerCompose(args, env)
 
 	if opts.Down {
 		log.Info("Containers stopped successfully")
+		if opts.Volumes && composeServices(opts) == nil {
+			forgetProject(projName)
+		}
 	} else {
 		log.Info("Containers started successfully")
+		if opts.Deps {
+			log.Info("Run `ods env` to point natively-run services at these containers")
+		}
+		notifier.Done(fmt.Sprintf("Project %q started with %s configuration", projName, profileLabel(profile)))
+	}
+}
+
+// smartBuild builds the images of the compose project described by
+// baseArgs whose sources changed since they were last built, comparing a
+// hash of each image's inputs with the label on the local image.
+func smartBuild(baseArgs, services, extraEnv []string) {
+	if err := buildChangedImages(composeDir(), baseArgs, services, extraEnv); err != nil {
+		log.Fatalf("%v", err)
+	}
+}
+
+// buildChangedImages is smartBuild with the compose directory given and
+// failures returned, running docker with docker.Runner.
+func buildChangedImages(dir string, baseArgs, services, extraEnv []string) error {
+	config, err := runner.Output(docker.Runner, runner.Cmd{
+		Name: "docker",
+		Args: append(slices.Clone(baseArgs), "config", "--format", "json"),
+		Dir:  dir,
+		Env:  append(os.Environ(), extraEnv...),
+	})
+	if err != nil {
+		return fmt.Errorf("failed to read the compose config: %w", err)
+	}
+	specs, err := docker.ComposeBuilds(config, services)
+	if err != nil {
+		return err
+	}
+
+	for _, spec := range specs {
+		hash, err := spec.SourceHash()
+		if err != nil {
+			return fmt.Errorf("failed to hash the sources of %s: %w", spec.Image, err)
+		}
+		if docker.ImageSourceHash(spec.Image) == hash {
+			log.Infof("%s is up to date (%s)", spec.Image, strings.Join(spec.Services, ", "))
+			continue
+		}
+		log.Infof("Building %s for %s...", spec.Image, strings.Join(spec.Services, ", "))
+		build := runner.Cmd{Name: "docker", Args: spec.BuildCommandArgs(hash), Stdout: os.Stdout, Stderr: os.Stderr}
+		if err := runner.Or(docker.Runner).Run(build); err != nil {
+			return fmt.Errorf("failed to build %s: %w", spec.Image, err)
+		}
+	}
+	return nil
+}
+
+// composeServices returns the services to limit up/down to, or nil for all.
+func composeServices(opts *ComposeOptions) []string {
+	switch {
+	case opts.Deps:
+		return docker.DependencyServiceNames
+	case opts.Infra:
+		return docker.InfraServiceNames()
+	default:
+		return nil
+	}
+}
+
+// newComposeDepsCommand creates the compose deps subcommand, which starts
+// only the containers a natively-run backend and frontend depend on.
+func newComposeDepsCommand() *cobra.Command {
+	opts := &ComposeOptions{Deps: true}
+
+	cmd := &cobra.Command{
+		Use:   "deps",
+		Short: "Start only Postgres, Redis, OpenSearch and MinIO",
+		Long: `Start only the infrastructure a natively-run backend and frontend depend on:
+Postgres, Redis, OpenSearch and MinIO. No api_server, web_server, background
+workers or model servers are started.
+
+Uses the dev configuration so service ports are exposed on the host. After
+starting, run "ods env" to write the ports to .vscode/.env, then run the API
+server, workers and web app from your editor or terminal.
+
+Examples:
+  # Start the dependencies
+  ods compose deps
+
+  # Stop them again
+  ods compose deps --down`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			runCompose("dev", opts)
+		},
+	}
+
+	cmd.Flags().BoolVar(&opts.Down, "down", false, "Stop the dependency containers instead of starting them")
+	cmd.Flags().BoolVar(&opts.Wait, "wait", true, "Wait for services to be healthy before returning")
+	cmd.Flags().BoolVar(&opts.ForceRecreate, "force-recreate", false, "Force recreate containers even if unchanged")
+	cmd.Flags().StringVar(&opts.Resources, "resources", "", "Apply a resource limit preset: "+strings.Join(docker.ResourcePresetNames(), ", "))
+
+	return cmd
+}
+
+// checkHostMemory warns when the memory available to Docker is below what the
+// chosen preset (or, with none chosen, the uncapped default stack) needs.
+func checkHostMemory(name string, preset docker.ResourcePreset) {
+	hostMB, err := docker.HostMemoryMB()
+	if err != nil {
+		log.Debugf("Skipping host memory check: %v", err)
+		return
+	}
+	if name == "" {
+		preset, _ = docker.FindResourcePreset("default")
+	}
+	if hostMB >= preset.HostMB {
+		return
+	}
+
+	log.Warnf("Docker has %.1fGB of memory but the %s stack needs about %.0fGB; containers may be OOM-killed",
+		float64(hostMB)/1024, profileLabel(name), float64(preset.HostMB)/1024)
+	if fit, ok := docker.LargestPresetFor(hostMB); ok && fit.Name != name {
+		log.Warnf("Try: ods compose --resources %s", fit.Name)
+	} else if !ok {
+		log.Warn("Give Docker more memory (Docker Desktop: Settings > Resources) or start fewer services with --infra")
 	}
 }
@@ -0,0 +1,188 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/docker"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/paths"
+)
+
+// resolveProjectPorts picks the host ports of projName's stack, records them
+// in the project registry and returns them as environment for docker
+// compose. The process environment outranks the shared compose .env file,
+// so side-by-side projects do not overwrite each other's ports; the main
+// project's are still written there for plain docker compose runs.
+func resolveProjectPorts(projName, profile string) []string {
+	root, _ := paths.GitRoot()
+	ports := reserveProjectPorts(projName, root, profile)
+
+	composeEnv := ports.ComposeEnv()
+	env := make([]string, 0, len(composeEnv))
+	for k, v := range composeEnv {
+		env = append(env, k+"="+v)
+		if docker.IsDefaultProject(projName) {
+			setEnvValue(k, v)
+		}
+	}
+	sort.Strings(env)
+	if !docker.IsDefaultProject(projName) {
+		log.Infof("Project %q: web on http://localhost:%s, API on %s, Postgres on %s",
+			projName, composeEnv["HOST_PORT"], composeEnv["API_SERVER_HOST_PORT"], composeEnv["POSTGRES_HOST_PORT"])
+	}
+	return env
+}
+
+// reserveProjectPorts picks the host ports of projName's stack, started from
+// the working tree at dir, and records them in the project registry.
+func reserveProjectPorts(projName, dir, profile string) *docker.ResolvedPorts {
+	reg, err := docker.LoadRegistry(paths.ComposeProjectsPath())
+	if err != nil {
+		log.Fatalf("Failed to load the compose project registry: %v", err)
+	}
+	var recorded map[string]int
+	if rec := reg.Get(projName); rec != nil {
+		recorded = rec.Ports
+	}
+	ports, err := docker.FindAvailablePorts(recorded, reg.ClaimedPorts(projName))
+	if err != nil {
+		log.Fatalf("Failed to find available ports: %v", err)
+	}
+
+	reg.Put(docker.ProjectRecord{
+		Name:    projName,
+		Dir:     dir,
+		Profile: profileLabel(profile),
+		Ports:   ports.Ports(),
+		Updated: time.Now().UTC(),
+	})
+	if err := reg.Save(); err != nil {
+		log.Warnf("Failed to save the compose project registry: %v", err)
+	}
+
+	return ports
+}
+
+// forgetProject drops projName from the registry, freeing its ports for
+// other projects.
+func forgetProject(projName string) {
+	path := paths.ComposeProjectsPath()
+	reg, err := docker.LoadRegistry(path)
+	if err != nil {
+		log.Warnf("Failed to load the compose project registry: %v", err)
+		return
+	}
+	if reg.Get(projName) == nil {
+		return
+	}
+	reg.Remove(projName)
+	if err := reg.Save(); err != nil {
+		log.Warnf("Failed to save the compose project registry: %v", err)
+	}
+}
+
+func newComposeListProjectsCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list-projects",
+		Short: "List the Onyx compose projects on this machine",
+		Long: `List the Onyx compose projects on this machine: those with containers
+(running or stopped), those ods compose has recorded ports for, and those
+whose volumes are all that is left. For each, its state, web and Postgres
+ports, volume count and the working tree it was started from.
+
+Remove a project, volumes included, with:
+  ods compose --project <name> --down --volumes`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			runComposeListProjects()
+		},
+	}
+}
+
+// composeProjectRow is one line of ods compose list-projects.
+type composeProjectRow struct {
+	name    string
+	status  string
+	record  *docker.ProjectRecord
+	volumes int
+}
+
+func runComposeListProjects() {
+	reg, err := docker.LoadRegistry(paths.ComposeProjectsPath())
+	if err != nil {
+		log.Fatalf("Failed to load the compose project registry: %v", err)
+	}
+	projects, err := docker.ComposeProjects()
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	volumes, err := docker.ProjectVolumes()
+	if err != nil {
+		log.Warnf("Failed to list volumes: %v", err)
+	}
+
+	rows := map[string]*composeProjectRow{}
+	row := func(name string) *composeProjectRow {
+		if rows[name] == nil {
+			rows[name] = &composeProjectRow{name: name, status: "no containers", record: reg.Get(name), volumes: len(volumes[name])}
+		}
+		return rows[name]
+	}
+	for _, p := range projects {
+		if p.IsOnyx() || reg.Get(p.Name) != nil {
+			row(p.Name).status = p.Status
+		}
+	}
+	for _, rec := range reg.Projects {
+		row(rec.Name)
+	}
+	for name, vols := range volumes {
+		if slices.ContainsFunc(vols, func(v string) bool { return strings.HasSuffix(v, "_db_volume") }) {
+			row(name)
+		}
+	}
+	if len(rows) == 0 {
+		log.Info("No Onyx compose projects")
+		return
+	}
+
+	names := make([]string, 0, len(rows))
+	for name := range rows {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	current := docker.ProjectName()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "PROJECT\tSTATUS\tPROFILE\tWEB\tPOSTGRES\tVOLUMES\tDIR")
+	_, _ = fmt.Fprintln(w, "-------\t------\t-------\t---\t--------\t-------\t---")
+	for _, name := range names {
+		r := rows[name]
+		label := name
+		if name == current {
+			label += " *"
+		}
+		profile, web, pg, dir := "-", "-", "-", "-"
+		if rec := r.record; rec != nil {
+			profile, dir = rec.Profile, rec.Dir
+			if p := rec.Ports["HOST_PORT"]; p != 0 {
+				web = "http://localhost:" + strconv.Itoa(p)
+			}
+			if p := rec.Ports["POSTGRES_HOST_PORT"]; p != 0 && rec.Profile != "default" {
+				pg = strconv.Itoa(p)
+			}
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%d\t%s\n", label, r.status, profile, web, pg, r.volumes, dir)
+	}
+	_ = w.Flush()
+	fmt.Println("\n* the project ods compose uses here (override with --project)")
+}
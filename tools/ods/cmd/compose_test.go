@@ -0,0 +1,64 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/docker"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/runner"
+)
+
+// useDockerFake makes docker.Runner answer from a runner.Fake for the test.
+func useDockerFake(t *testing.T) *runner.Fake {
+	t.Helper()
+	f := &runner.Fake{}
+	docker.Runner = f
+	t.Cleanup(func() { docker.Runner = nil })
+	return f
+}
+
+func TestBuildChangedImages(t *testing.T) {
+	src := t.TempDir()
+	if err := os.WriteFile(filepath.Join(src, "Dockerfile"), []byte("FROM python\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	config := `{"services": {"api_server": {"image": "onyxdotapp/onyx-backend:latest", "build": {"context": ` + strings.ReplaceAll(`"`+src+`"`, `\`, `\\`) + `}}}}`
+	base := []string{"compose", "-p", "onyx", "-f", "docker-compose.yml"}
+
+	f := useDockerFake(t).
+		On("docker compose -p onyx -f docker-compose.yml config --format json", runner.Response{Stdout: config}).
+		On("git", runner.Response{}).
+		On("docker image inspect", runner.Response{Stdout: "<no value>\n"}).
+		On("docker build", runner.Response{})
+	if err := buildChangedImages("/r/deployment/docker_compose", base, nil, []string{"IMAGE_TAG=edge"}); err != nil {
+		t.Fatal(err)
+	}
+	calls := f.Calls()
+	if calls[0].Dir != "/r/deployment/docker_compose" || !strings.Contains(strings.Join(calls[0].Env, "\n"), "IMAGE_TAG=edge") {
+		t.Errorf("compose config ran in %q without the extra env", calls[0].Dir)
+	}
+	if last := calls[len(calls)-1].String(); !strings.HasPrefix(last, "docker build -t onyxdotapp/onyx-backend:latest") {
+		t.Errorf("expected a changed image to be built, last command %q", last)
+	}
+
+	// An image labelled with the current hash is skipped.
+	specs, _ := docker.ComposeBuilds([]byte(config), nil)
+	hash, err := specs[0].SourceHash()
+	if err != nil {
+		t.Fatal(err)
+	}
+	f = useDockerFake(t).
+		On("docker compose", runner.Response{Stdout: config}).
+		On("git", runner.Response{}).
+		On("docker image inspect", runner.Response{Stdout: hash + "\n"})
+	if err := buildChangedImages("/r/deployment/docker_compose", base, nil, nil); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range f.Lines() {
+		if strings.HasPrefix(line, "docker build") {
+			t.Errorf("unexpected rebuild of an up-to-date image: %q", line)
+		}
+	}
+}
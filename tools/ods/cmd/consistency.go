@@ -0,0 +1,225 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/consistency"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/render"
+)
+
+// ConsistencyCheckOptions holds options for the consistency check command.
+type ConsistencyCheckOptions struct {
+	CPContext   string
+	CPPod       string
+	DataPlanes  []string
+	RouteColumn string
+	Grace       time.Duration
+	JSON        bool
+}
+
+// NewConsistencyCommand creates the parent consistency command.
+func NewConsistencyCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "consistency",
+		Short: "Cross-check the control plane against the data planes",
+	}
+
+	cmd.AddCommand(newConsistencyCheckCommand())
+
+	return cmd
+}
+
+func newConsistencyCheckCommand() *cobra.Command {
+	opts := &ConsistencyCheckOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "check",
+		Short: "Find tenants orphaned between the control plane and the data planes",
+		Long: `Find tenants orphaned between the control plane and the data planes.
+
+Lists the tenants in the control plane's tenant table with the data plane
+each is routed to (--route-column), lists the tenant schemas and
+user_tenant_mapping rows in each --data-plane, and reports:
+
+  orphan-schema   a tenant schema the control plane has no tenant for
+  stale-copy      a schema in a data plane other than the one the tenant is
+                  routed to, which also has it (e.g. the source of a
+                  finished ` + "`ods tenant migrate`" + `)
+  misrouted       a tenant routed to a data plane without its schema while
+                  another data plane has it
+  missing-schema  a tenant with no schema in any checked data plane
+  orphan-users    user mappings for a tenant with no schema in that data
+                  plane, which the tenant is not routed to
+
+Each --data-plane is a cluster context, optionally with the value that
+selects it in --route-column (ctx=value; default: the context name, as in
+` + "`ods tenant migrate`" + `). Tenants routed to a data plane that is not checked
+are counted but not reported. Tenants created within --grace are skipped
+while they may still be provisioning.
+
+Nothing is changed. Findings with a cleanup list the ` + "`ods tenant delete`" + `
+command that removes the orphaned data; it archives the schema first and
+refuses tenants that look active. Misrouted and missing-schema tenants need
+their control-plane route fixed by hand. Exits non-zero when anything is
+found, so the check can run on a schedule.
+
+Requires: AWS SSO login, kubectl access to the control-plane and data-plane
+clusters.
+
+Examples:
+  ods consistency check
+  ods consistency check --data-plane us --data-plane eu
+  ods consistency check --data-plane us=us-east-1 --data-plane eu=eu-west-1 --json`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			runConsistencyCheck(opts)
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.CPContext, "cp-context", "control_plane", "Control-plane cluster context")
+	cmd.Flags().StringVar(&opts.CPPod, "cp-pod", "control-plane", "Substring of the control-plane pod name")
+	cmd.Flags().StringSliceVar(&opts.DataPlanes, "data-plane", []string{"data_plane"}, "Data-plane context to check, as ctx or ctx=route-value (repeatable)")
+	cmd.Flags().StringVar(&opts.RouteColumn, "route-column", "data_plane", "Column of the control plane's tenant table that selects the data plane")
+	cmd.Flags().DurationVar(&opts.Grace, "grace", time.Hour, "Skip tenants created more recently than this")
+	cmd.Flags().BoolVar(&opts.JSON, "json", false, "Print the report as JSON")
+
+	return cmd
+}
+
+// parseDataPlanes parses --data-plane values into contexts and their route
+// values.
+func parseDataPlanes(values []string) ([][2]string, error) {
+	var planes [][2]string
+	seen := map[string]bool{}
+	for _, v := range values {
+		ctx, route, found := strings.Cut(v, "=")
+		if !found {
+			route = ctx
+		}
+		if ctx == "" || route == "" {
+			return nil, fmt.Errorf("invalid --data-plane %q: want ctx or ctx=route-value", v)
+		}
+		if seen["ctx:"+ctx] || seen["route:"+route] {
+			return nil, fmt.Errorf("--data-plane %q repeats a context or route value", v)
+		}
+		seen["ctx:"+ctx], seen["route:"+route] = true, true
+		planes = append(planes, [2]string{ctx, route})
+	}
+	if len(planes) == 0 {
+		return nil, fmt.Errorf("pass at least one --data-plane")
+	}
+	return planes, nil
+}
+
+func runConsistencyCheck(opts *ConsistencyCheckOptions) {
+	planes, err := parseDataPlanes(opts.DataPlanes)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if opts.Grace < 0 {
+		log.Fatal("--grace must not be negative")
+	}
+
+	cp := clusterFromEnv(opts.CPContext)
+	if err := cp.EnsureContext(); err != nil {
+		log.Fatalf("Failed to ensure cluster context %s: %v", opts.CPContext, err)
+	}
+	cpPod, err := cp.FindPod(opts.CPPod)
+	if err != nil {
+		log.Fatalf("Failed to find %s pod in %s: %v", opts.CPPod, opts.CPContext, err)
+	}
+	log.Infof("Listing control-plane tenants in %s...", opts.CPContext)
+	tenants, err := consistency.ControlPlaneTenants(cp, cpPod, opts.RouteColumn)
+	if err != nil {
+		log.Fatalf("Failed to list control-plane tenants: %v", err)
+	}
+
+	var dataPlanes []*consistency.DataPlane
+	for _, p := range planes {
+		ctx, route := p[0], p[1]
+		c := clusterFromEnv(ctx)
+		if err := c.EnsureContext(); err != nil {
+			log.Fatalf("Failed to ensure cluster context %s: %v", ctx, err)
+		}
+		pod, err := c.FindPod("api-server")
+		if err != nil {
+			log.Fatalf("Failed to find api-server pod in %s: %v", ctx, err)
+		}
+		log.Infof("Listing tenant schemas in %s...", ctx)
+		lines, err := tryQueryPod(c, pod, consistency.DataPlaneSQL)
+		if err != nil {
+			log.Fatalf("Failed to list tenant schemas in %s: %v", ctx, err)
+		}
+		dp, err := consistency.ParseDataPlane(ctx, route, lines)
+		if err != nil {
+			log.Fatalf("Failed to list tenant schemas in %s: %v", ctx, err)
+		}
+		dataPlanes = append(dataPlanes, dp)
+	}
+
+	report := consistency.Check(tenants, dataPlanes, time.Now().Add(-opts.Grace))
+	if opts.JSON {
+		if err := render.JSON(os.Stdout, report); err != nil {
+			log.Fatalf("Failed to marshal the report: %v", err)
+		}
+	} else {
+		printConsistencyReport(os.Stdout, opts.CPContext, report)
+	}
+	if len(report.Findings) > 0 {
+		exitWith(1)
+	}
+}
+
+func printConsistencyReport(w io.Writer, cpContext string, r *consistency.Report) {
+	_, _ = fmt.Fprintf(w, "Control plane %s: %d tenant(s)", cpContext, r.ControlPlaneTenants)
+	var notes []string
+	if r.Unchecked > 0 {
+		notes = append(notes, fmt.Sprintf("%d routed to unchecked data planes", r.Unchecked))
+	}
+	if r.Recent > 0 {
+		notes = append(notes, fmt.Sprintf("%d created within the grace period", r.Recent))
+	}
+	if len(notes) > 0 {
+		_, _ = fmt.Fprintf(w, " (%s)", strings.Join(notes, ", "))
+	}
+	_, _ = fmt.Fprintln(w)
+
+	planes := render.NewTable("DATA PLANE", "ROUTE", "SCHEMAS", "ROUTED TENANTS")
+	for _, p := range r.DataPlanes {
+		planes.Row(p.Context, p.Route, p.Schemas, p.Routed)
+	}
+	_ = planes.Write(w)
+
+	_, _ = fmt.Fprintln(w)
+	if len(r.Findings) == 0 {
+		_, _ = fmt.Fprintln(w, "No inconsistencies found.")
+		return
+	}
+	findings := render.NewTable("KIND", "TENANT", "DATA PLANE", "DETAIL")
+	var cleanup []string
+	for _, f := range r.Findings {
+		plane := f.DataPlane
+		if plane == "" {
+			plane = "-"
+		}
+		findings.Row(f.Kind, f.Tenant, plane, f.Detail)
+		if f.Cleanup != "" {
+			cleanup = append(cleanup, f.Cleanup)
+		}
+	}
+	_ = findings.Write(w)
+
+	if len(cleanup) > 0 {
+		_, _ = fmt.Fprintln(w, "\nCleanup (review each first; nothing has been changed):")
+		for _, c := range cleanup {
+			_, _ = fmt.Fprintf(w, "  %s\n", c)
+		}
+	}
+}
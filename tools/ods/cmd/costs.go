@@ -0,0 +1,276 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/costs"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/kube"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/render"
+)
+
+// CostsOptions holds options for the costs command.
+type CostsOptions struct {
+	Context    string
+	Month      string
+	SplitBy    string
+	Top        int
+	NodePrices map[string]string
+	Rates      costs.Rates
+	JSON       bool
+}
+
+// NewCostsCommand creates the costs command.
+func NewCostsCommand() *cobra.Command {
+	opts := &CostsOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "costs",
+		Short: "Estimate an environment's monthly cost and attribute it to tenants",
+		Long: `Estimate an environment's monthly cost and attribute it to tenants.
+
+The estimate has three parts:
+  compute  each workload's share of the nodes its pods run on, from the pods'
+           CPU and memory requests and the nodes' on-demand prices (looked up
+           with the AWS Price List API; spot nodes get --spot-discount)
+  storage  persistent volume sizes times --storage-usd-per-gib
+  llm      the month's chat message tokens times the per-million token rates
+
+Pods are priced as they are running now, as if they had run all month.
+Token counts come from stored chat messages and exclude the retrieved context
+and system prompts sent to the LLM, so the LLM cost is a lower bound; use the
+provider's bill for exact figures.
+
+Compute and storage are shared, and are split between tenants by --split-by:
+documents (their current document count), tokens (their token usage in the
+month) or even. Each tenant then adds its own LLM cost.
+
+Requires kubectl access and AWS credentials allowed to call pricing:GetProducts
+(or --node-price for every instance type).
+
+Examples:
+  ods costs
+  ods costs -c prod_eu --month 2026-09 --split-by tokens --top 50
+  ods costs --node-price m5.2xlarge=0.384 --llm-input-usd-per-1m 2.5 --json`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			runCosts(opts)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.Context, "context", "c", "data_plane", "cluster context name (maps to KUBE_CTX_<NAME> env var)")
+	cmd.Flags().StringVar(&opts.Month, "month", "", "Month to estimate, as YYYY-MM (default: last month)")
+	cmd.Flags().StringVar(&opts.SplitBy, "split-by", costs.SplitDocuments, "How to split shared cost between tenants: "+strings.Join(costs.Splits, ", "))
+	cmd.Flags().IntVar(&opts.Top, "top", 20, "Number of tenants to show (0 for all)")
+	cmd.Flags().StringToStringVar(&opts.NodePrices, "node-price", nil, "Hourly USD price of an instance type, instead of looking it up (e.g. m5.xlarge=0.192)")
+	cmd.Flags().Float64Var(&opts.Rates.SpotDiscount, "spot-discount", 0.6, "Fraction spot nodes cost less than on-demand")
+	cmd.Flags().Float64Var(&opts.Rates.StoragePerGiB, "storage-usd-per-gib", 0.08, "Monthly USD price of a GiB of volume storage")
+	cmd.Flags().Float64Var(&opts.Rates.LLMInputPer1M, "llm-input-usd-per-1m", 3, "USD price of a million input tokens")
+	cmd.Flags().Float64Var(&opts.Rates.LLMOutputPer1M, "llm-output-usd-per-1m", 15, "USD price of a million output tokens")
+	cmd.Flags().BoolVar(&opts.JSON, "json", false, "Print the estimate as JSON")
+
+	return cmd
+}
+
+// costsReport is the JSON form of an estimate.
+type costsReport struct {
+	Context  string             `json:"context"`
+	Month    string             `json:"month"`
+	SplitBy  string             `json:"split_by"`
+	Lines    []costs.Line       `json:"lines"`
+	TotalUSD float64            `json:"total_usd"`
+	Tenants  []costs.TenantCost `json:"tenants"`
+	Warnings []string           `json:"warnings,omitempty"`
+}
+
+func runCosts(opts *CostsOptions) {
+	month := costs.PreviousMonth(time.Now())
+	if opts.Month != "" {
+		var err error
+		if month, err = costs.ParseMonth(opts.Month); err != nil {
+			log.Fatal(err)
+		}
+	}
+	if !slices.Contains(costs.Splits, opts.SplitBy) {
+		log.Fatalf("--split-by must be one of %s", strings.Join(costs.Splits, ", "))
+	}
+
+	c := clusterFromEnv(opts.Context)
+	if err := c.EnsureContext(); err != nil {
+		log.Fatalf("Failed to ensure cluster context: %v", err)
+	}
+
+	log.Info("Reading nodes, pods and volumes...")
+	nodes, err := c.ListNodes()
+	if err != nil {
+		log.Fatalf("Failed to list nodes: %v", err)
+	}
+	pods, err := c.ListPodRequests()
+	if err != nil {
+		log.Fatalf("Failed to list pods: %v", err)
+	}
+	pvcs, err := c.ListPVCs()
+	if err != nil {
+		log.Fatalf("Failed to list volumes: %v", err)
+	}
+	prices := nodePrices(c, nodes, opts.NodePrices)
+
+	report := costsReport{Context: opts.Context, Month: month.String(), SplitBy: opts.SplitBy}
+	report.Lines, report.Warnings = costs.Infra(nodes, pods, pvcs, prices, opts.Rates, month)
+	infraUSD := costs.Total(report.Lines)
+
+	log.Infof("Reading tenant usage for %s...", month)
+	usage := tenantUsage(c, month)
+	report.Lines = append(report.Lines, costs.LLM(usage, opts.Rates))
+	report.TotalUSD = costs.Total(report.Lines)
+	report.Tenants, err = costs.Attribute(usage, infraUSD, opts.Rates, opts.SplitBy)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if opts.JSON {
+		if err := render.JSON(os.Stdout, report); err != nil {
+			log.Fatalf("Failed to encode report: %v", err)
+		}
+		return
+	}
+	for _, w := range report.Warnings {
+		log.Warn(w)
+	}
+	printCostLines(report)
+	fmt.Println()
+	printTenantCosts(report, opts.Top)
+}
+
+// nodePrices returns the hourly price of each node's instance type, taking
+// overrides first and looking the rest up in the cluster's region.
+func nodePrices(c *kube.Cluster, nodes []*kube.Node, overrides map[string]string) map[string]float64 {
+	prices := map[string]float64{}
+	for instanceType, v := range overrides {
+		price, err := strconv.ParseFloat(v, 64)
+		if err != nil || price < 0 {
+			log.Fatalf("Invalid --node-price %s=%s", instanceType, v)
+		}
+		prices[instanceType] = price
+	}
+
+	var lookup []string
+	for _, n := range nodes {
+		if _, ok := prices[n.InstanceType]; !ok && n.InstanceType != "" {
+			lookup = append(lookup, n.InstanceType)
+		}
+	}
+	if len(lookup) == 0 {
+		return prices
+	}
+	log.Infof("Looking up on-demand prices in %s...", c.Region)
+	env, err := c.AWSEnv()
+	if err != nil {
+		log.Warnf("Failed to look up node prices (pass --node-price to set them): %v", err)
+		return prices
+	}
+	found, err := costs.EC2HourlyPrices(c.Runner, env, c.Region, lookup)
+	if err != nil {
+		log.Warnf("Failed to look up node prices (pass --node-price to set them): %v", err)
+	}
+	for instanceType, price := range found {
+		prices[instanceType] = price
+	}
+	return prices
+}
+
+// tenantUsage queries every tenant schema's token usage and document count.
+func tenantUsage(c *kube.Cluster, month costs.Month) []costs.TenantUsage {
+	pod, err := c.FindPod("api-server")
+	if err != nil {
+		log.Fatalf("Failed to find api-server pod: %v", err)
+	}
+
+	schemas, err := tenantSchemas(c, pod)
+	if err != nil {
+		log.Fatalf("Failed to list tenant schemas: %v", err)
+	}
+	lines, err := querySchemas(c, pod, schemas, func(batch []string) string {
+		return costs.UsageSQL(batch, month)
+	})
+	if err != nil {
+		log.Fatalf("Failed to query tenant usage: %v", err)
+	}
+	usage, err := costs.ParseUsage(lines)
+	if err != nil {
+		log.Fatalf("Failed to read tenant usage: %v", err)
+	}
+	return usage
+}
+
+func printCostLines(report costsReport) {
+	fmt.Printf("Estimated cost of %s for %s\n\n", report.Context, report.Month)
+	// Keep the categories in report order, most expensive items first.
+	rank := map[string]int{}
+	for _, l := range report.Lines {
+		if _, ok := rank[l.Category]; !ok {
+			rank[l.Category] = len(rank)
+		}
+	}
+	lines := append([]costs.Line(nil), report.Lines...)
+	sort.SliceStable(lines, func(i, j int) bool {
+		if lines[i].Category != lines[j].Category {
+			return rank[lines[i].Category] < rank[lines[j].Category]
+		}
+		return lines[i].USD > lines[j].USD
+	})
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "CATEGORY\tITEM\tDETAIL\tUSD/MONTH")
+	_, _ = fmt.Fprintln(w, "--------\t----\t------\t---------")
+	subtotal, category := 0.0, ""
+	flush := func() {
+		if category != "" {
+			_, _ = fmt.Fprintf(w, "%s\t\tsubtotal\t%.2f\n", category, subtotal)
+		}
+	}
+	for _, l := range lines {
+		if l.Category != category {
+			flush()
+			category, subtotal = l.Category, 0
+		}
+		subtotal += l.USD
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%.2f\n", l.Category, l.Item, l.Detail, l.USD)
+	}
+	flush()
+	_, _ = fmt.Fprintf(w, "TOTAL\t\t\t%.2f\n", report.TotalUSD)
+	_ = w.Flush()
+}
+
+func printTenantCosts(report costsReport, top int) {
+	tenants := report.Tenants
+	fmt.Printf("Tenants (shared cost split by %s)\n\n", report.SplitBy)
+	if len(tenants) == 0 {
+		fmt.Println("No tenants found.")
+		return
+	}
+	if top > 0 && len(tenants) > top {
+		tenants = tenants[:top]
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "TENANT\tDOCS\tTOKENS IN\tTOKENS OUT\tSHARE\tINFRA USD\tLLM USD\tTOTAL USD")
+	_, _ = fmt.Fprintln(w, "------\t----\t---------\t----------\t-----\t---------\t-------\t---------")
+	for _, t := range tenants {
+		_, _ = fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%.1f%%\t%.2f\t%.2f\t%.2f\n",
+			t.Tenant, t.Documents, t.InputTokens, t.OutputTokens, t.Share*100, t.Infra, t.LLM, t.Total())
+	}
+	_ = w.Flush()
+	if len(tenants) < len(report.Tenants) {
+		fmt.Printf("\n... and %d more tenants (use --top 0 to show all)\n", len(report.Tenants)-len(tenants))
+	}
+}
@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/credentials"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/render"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/report"
+)
+
+// CredentialsCheckOptions holds options for the credentials check command.
+type CredentialsCheckOptions struct {
+	Context    string
+	Tenant     string
+	AllTenants bool
+	Within     string
+	Timeout    time.Duration
+	JSON       bool
+}
+
+// NewCredentialsCommand creates the parent credentials command.
+func NewCredentialsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "credentials",
+		Short: "Inspect stored connector credentials",
+	}
+
+	cmd.AddCommand(newCredentialsCheckCommand())
+
+	return cmd
+}
+
+func newCredentialsCheckCommand() *cobra.Command {
+	opts := &CredentialsCheckOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "check",
+		Short: "Test connector credentials and report those that fail or will soon",
+		Long: `Test the stored credential of every active connector without running a sync.
+
+Each connector is built from its stored credential the way the indexing
+worker builds it, and its own settings validation is run on an api-server
+pod, which for most connectors is a cheap authenticated request that checks
+the token and its permissions. The stored credential is also searched for
+expiry dates of tokens that nothing refreshes. Credential values are never
+shown.
+
+States:
+  failing   the token is rejected, expired or lacks permissions
+  expiring  a token expires within --within
+  unknown   the check errored or timed out, which may be transient
+  ok
+
+Exits non-zero when any credential is failing or expiring, so the check can
+run on a schedule. On a single-tenant deployment omit --tenant.
+
+Requires: AWS SSO login, kubectl access to the EKS cluster.
+
+Examples:
+  ods credentials check --tenant tenant_abcd1234
+  ods credentials check --all-tenants -c data_plane --within 14d
+  ods credentials check -c staging --json`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			runCredentialsCheck(opts)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.Context, "context", "c", "data_plane", "cluster context name (maps to KUBE_CTX_<NAME> env var)")
+	cmd.Flags().StringVar(&opts.Tenant, "tenant", "", "Tenant schema (omit on single-tenant deployments)")
+	cmd.Flags().BoolVar(&opts.AllTenants, "all-tenants", false, "Check every tenant schema")
+	cmd.Flags().StringVar(&opts.Within, "within", "7d", "Report tokens expiring within this long, e.g. 14d or 12h")
+	cmd.Flags().DurationVar(&opts.Timeout, "timeout", 20*time.Second, "Timeout for each credential's validation")
+	cmd.Flags().BoolVar(&opts.JSON, "json", false, "Print results as JSON")
+	cmd.MarkFlagsMutuallyExclusive("tenant", "all-tenants")
+
+	return cmd
+}
+
+func runCredentialsCheck(opts *CredentialsCheckOptions) {
+	if opts.Tenant != "" {
+		validateTenantArg(opts.Tenant)
+	}
+	within, err := report.ParseLookback(opts.Within)
+	if err != nil {
+		log.Fatalf("Invalid --within: %v", err)
+	}
+	if opts.Timeout <= 0 {
+		log.Fatal("--timeout must be positive")
+	}
+
+	c := clusterFromEnv(opts.Context)
+	if err := c.EnsureContext(); err != nil {
+		log.Fatalf("Failed to ensure cluster context: %v", err)
+	}
+	pod, err := c.FindPod("api-server")
+	if err != nil {
+		log.Fatalf("Failed to find api-server pod: %v", err)
+	}
+
+	var results []credentials.Result
+	if opts.AllTenants {
+		log.Info("Checking connector credentials of every tenant...")
+		results, err = credentials.CheckAll(c, pod, opts.Timeout)
+	} else {
+		log.Info("Checking connector credentials...")
+		results, err = credentials.Check(c, pod, opts.Tenant, opts.Timeout)
+	}
+	if err != nil {
+		log.Fatalf("Failed to check credentials: %v", err)
+	}
+	credentials.Classify(results, time.Now(), within)
+
+	if opts.JSON {
+		if err := render.JSON(os.Stdout, results); err != nil {
+			log.Fatalf("Failed to marshal results: %v", err)
+		}
+	} else {
+		printCredentialResults(results, opts.AllTenants)
+	}
+
+	counts := credentials.Counts(results)
+	if len(results) == 0 {
+		log.Info("No active connectors")
+		return
+	}
+	if !opts.JSON {
+		log.Infof("%d checked: %d failing, %d expiring, %d unknown, %d ok",
+			len(results), counts[credentials.StateFailing], counts[credentials.StateExpiring],
+			counts[credentials.StateUnknown], counts[credentials.StateOK])
+	}
+	if counts[credentials.StateFailing]+counts[credentials.StateExpiring] > 0 {
+		exitWith(1)
+	}
+}
+
+func printCredentialResults(results []credentials.Result, withTenant bool) {
+	if len(results) == 0 {
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	header := "STATE\tCC PAIR\tSOURCE\tNAME\tCREDENTIAL\tEXPIRES\tDETAIL"
+	if withTenant {
+		header = "TENANT\t" + header
+	}
+	_, _ = fmt.Fprintln(w, header)
+	for _, r := range results {
+		expires := "-"
+		if r.ExpiresAt != nil {
+			expires = r.ExpiresAt.Local().Format("2006-01-02 15:04")
+		}
+		detail := r.Detail
+		if detail == "" && r.Validation != credentials.ValidationOK {
+			detail = r.Validation
+		}
+		line := fmt.Sprintf("%s\t%d\t%s\t%s\t%d\t%s\t%s", r.State, r.CCPairID, r.Source, r.Name, r.CredentialID, expires, detail)
+		if withTenant {
+			line = r.Tenant + "\t" + line
+		}
+		_, _ = fmt.Fprintln(w, line)
+	}
+	_ = w.Flush()
+}
@@ -0,0 +1,298 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/auditlog"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/cron"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/jobs"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/kube"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/prompt"
+)
+
+// CronOptions holds options shared by the cron subcommands.
+type CronOptions struct {
+	Context string
+}
+
+// NewCronCommand creates the parent cron command.
+func NewCronCommand() *cobra.Command {
+	opts := &CronOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "cron",
+		Short: "Show and trigger scheduled tasks",
+		Long: `Show and trigger scheduled tasks.
+
+Covers both kinds of periodic work in a deployment: the Celery beat schedule
+(indexing and pruning checks, permission syncs, cleanup, monitoring), read
+from the celery-beat pod, and Kubernetes CronJobs in the namespace.
+
+Beat only records when it last sent each task, not how the task went, and
+loses that record when its pod is replaced; an entry is "late" when it is
+overdue by more than its interval, which usually means beat is down or stuck.
+
+Requires: AWS SSO login, kubectl access to the EKS cluster.
+
+Examples:
+  ods cron list
+  ods cron last-runs pruning
+  ods cron trigger check-for-pruning --tenant tenant_abcd1234
+  ods cron trigger vespa-backup -c staging`,
+	}
+
+	cmd.PersistentFlags().StringVarP(&opts.Context, "context", "c", "data_plane", "cluster context name (maps to KUBE_CTX_<NAME> env var)")
+
+	cmd.AddCommand(newCronListCommand(opts))
+	cmd.AddCommand(newCronLastRunsCommand(opts))
+	cmd.AddCommand(newCronTriggerCommand(opts))
+
+	return cmd
+}
+
+func newCronListCommand(opts *CronOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "list [filter]",
+		Short: "List beat entries and cronjobs with their schedules",
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			runCronList(opts, firstArg(args))
+		},
+	}
+}
+
+func newCronLastRunsCommand(opts *CronOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "last-runs [filter]",
+		Short: "Show when each scheduled task last ran and whether it is late",
+		Args:  cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			runCronLastRuns(opts, firstArg(args))
+		},
+	}
+}
+
+func newCronTriggerCommand(opts *CronOptions) *cobra.Command {
+	var tenant string
+	var yes bool
+
+	cmd := &cobra.Command{
+		Use:   "trigger <name>",
+		Short: "Run a beat entry or cronjob now",
+		Long: `Run a beat entry or cronjob now, without waiting for its schedule.
+
+<name> is a cronjob or beat entry name from ` + "`ods cron list`" + `. Beat
+entries may be named without their tenant suffix (check-for-pruning rather
+than check-for-pruning-public). With --tenant, a beat task is sent for that
+tenant only instead of fanning out to every tenant.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			runCronTrigger(opts, args[0], tenant, yes)
+		},
+	}
+
+	cmd.Flags().StringVar(&tenant, "tenant", "", "Send a beat task for this tenant only")
+	cmd.Flags().BoolVar(&yes, "yes", false, "Skip the confirmation prompt")
+
+	return cmd
+}
+
+func firstArg(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+	return args[0]
+}
+
+// cronSchedule is what the cron subcommands read from a cluster. Beat is
+// nil when the schedule could not be read, e.g. there is no celery-beat pod.
+type cronSchedule struct {
+	Beat     []cron.Entry
+	CronJobs []*kube.CronJob
+}
+
+func loadCronSchedule(c *kube.Cluster, filter string) *cronSchedule {
+	s := &cronSchedule{}
+	if pod, err := c.FindPod("celery-beat"); err != nil {
+		log.Warnf("Skipping the beat schedule: %v", err)
+	} else if entries, err := cron.List(c, pod); err != nil {
+		log.Warnf("Failed to read the beat schedule from %s: %v", pod, err)
+	} else {
+		for _, e := range entries {
+			if e.Matches(filter) {
+				s.Beat = append(s.Beat, e)
+			}
+		}
+		sort.Slice(s.Beat, func(i, j int) bool { return s.Beat[i].Name < s.Beat[j].Name })
+	}
+
+	cronJobs, err := c.ListCronJobs()
+	if err != nil {
+		log.Fatalf("Failed to list cronjobs: %v", err)
+	}
+	for _, cj := range cronJobs {
+		if strings.Contains(cj.Name, filter) {
+			s.CronJobs = append(s.CronJobs, cj)
+		}
+	}
+	return s
+}
+
+func cronCluster(opts *CronOptions) *kube.Cluster {
+	c := clusterFromEnv(opts.Context)
+	if err := c.EnsureContext(); err != nil {
+		log.Fatalf("Failed to ensure cluster context: %v", err)
+	}
+	return c
+}
+
+func runCronList(opts *CronOptions, filter string) {
+	s := loadCronSchedule(cronCluster(opts), filter)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "SOURCE\tNAME\tSCHEDULE\tTASK")
+	_, _ = fmt.Fprintln(w, "------\t----\t--------\t----")
+	for _, e := range s.Beat {
+		task := e.Task
+		if e.Queue != "" {
+			task += " (" + e.Queue + ")"
+		}
+		_, _ = fmt.Fprintf(w, "beat\t%s\t%s\t%s\n", e.Name, e.Schedule, task)
+	}
+	for _, cj := range s.CronJobs {
+		schedule := cj.Schedule
+		if cj.Suspend {
+			schedule += " (suspended)"
+		}
+		_, _ = fmt.Fprintf(w, "cronjob\t%s\t%s\t-\n", cj.Name, schedule)
+	}
+	_ = w.Flush()
+}
+
+func runCronLastRuns(opts *CronOptions, filter string) {
+	c := cronCluster(opts)
+	s := loadCronSchedule(c, filter)
+
+	// The newest job of each cronjob tells how its last run went.
+	lastJob := map[string]*kube.Job{}
+	if len(s.CronJobs) > 0 {
+		jobList, err := c.ListJobs()
+		if err != nil {
+			log.Fatalf("Failed to list jobs: %v", err)
+		}
+		for _, j := range jobList {
+			if j.CronJob != "" && lastJob[j.CronJob] == nil {
+				lastJob[j.CronJob] = j
+			}
+		}
+	}
+
+	late := 0
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "SOURCE\tNAME\tLAST RUN\tRUNS\tSTATUS")
+	_, _ = fmt.Fprintln(w, "------\t----\t--------\t----\t------")
+	for _, e := range s.Beat {
+		lastRun, status := "-", "never ran"
+		if e.LastRun != nil {
+			lastRun = formatEventAge(*e.LastRun) + " ago"
+			status = "ok"
+			if e.Late() {
+				status = "LATE"
+				late++
+			}
+		}
+		_, _ = fmt.Fprintf(w, "beat\t%s\t%s\t%d\t%s\n", e.Name, lastRun, e.TotalRunCount, status)
+	}
+	for _, cj := range s.CronJobs {
+		lastRun, status := "-", "never ran"
+		if !cj.LastSchedule.IsZero() {
+			lastRun = formatEventAge(cj.LastSchedule) + " ago"
+		}
+		if j := lastJob[cj.Name]; j != nil {
+			status = strings.ToLower(j.Status())
+			if j.Reason != "" {
+				status += ": " + j.Reason
+			}
+		} else if !cj.LastSuccessful.IsZero() {
+			status = "last succeeded " + formatEventAge(cj.LastSuccessful) + " ago"
+		}
+		if cj.Suspend {
+			status += " (suspended)"
+		}
+		_, _ = fmt.Fprintf(w, "cronjob\t%s\t%s\t-\t%s\n", cj.Name, lastRun, status)
+	}
+	_ = w.Flush()
+
+	if late > 0 {
+		log.Warnf("%d beat entr(ies) are late; check the celery-beat pod (ods logs -c %s celery-beat)", late, opts.Context)
+	}
+}
+
+func runCronTrigger(opts *CronOptions, name, tenant string, yes bool) {
+	if tenant != "" {
+		validateTenantArg(tenant)
+	}
+	c := cronCluster(opts)
+	auditCtx := c.Name + "/" + c.Namespace
+
+	cronJobs, err := c.ListCronJobs()
+	if err != nil {
+		log.Fatalf("Failed to list cronjobs: %v", err)
+	}
+	isCronJob := false
+	for _, cj := range cronJobs {
+		if cj.Name == name {
+			isCronJob = true
+		}
+	}
+	if isCronJob && tenant != "" {
+		log.Fatalf("%s is a cronjob; --tenant only applies to beat entries", name)
+	}
+
+	target := name
+	if tenant != "" {
+		target += " for " + tenant
+	}
+	if !yes && isProductionContext(opts.Context) {
+		if !prompt.Confirm(fmt.Sprintf("Trigger %s in %s now? (yes/no): ", target, auditCtx)) {
+			log.Info("Aborted.")
+			return
+		}
+	}
+
+	if err := auditlog.Record(auditlog.Entry{
+		Action:  "cron.trigger",
+		Context: auditCtx,
+		Target:  name,
+		Detail:  tenant,
+	}); err != nil {
+		log.Fatalf("Refusing to trigger a task without an audit record: %v", err)
+	}
+
+	if isCronJob {
+		jobName := jobs.JobName(name, time.Now().UTC().Format("20060102-150405"))
+		if err := c.TriggerCronJob(name, jobName); err != nil {
+			log.Fatalf("Failed to trigger %s: %v", name, err)
+		}
+		log.Infof("Started job %s from cronjob %s (follow it with: ods logs -c %s %s)", jobName, name, opts.Context, jobName)
+		return
+	}
+
+	pod, err := c.FindPod("celery-beat")
+	if err != nil {
+		log.Fatalf("Failed to find celery-beat pod: %v", err)
+	}
+	entry, taskID, err := cron.Trigger(c, pod, name, tenant)
+	if err != nil {
+		log.Fatalf("Failed to trigger %s: %v", name, err)
+	}
+	log.Infof("Sent %s as task %s", entry, taskID)
+}
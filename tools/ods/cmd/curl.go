@@ -0,0 +1,143 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/apiclient"
+)
+
+// CurlOptions holds options for the curl command.
+type CurlOptions struct {
+	APISessionOptions
+	Method  string
+	Data    string
+	Headers []string
+	Include bool
+	Raw     bool
+}
+
+// NewCurlCommand creates the curl command for calling the API server.
+func NewCurlCommand() *cobra.Command {
+	opts := &CurlOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "curl <path>",
+		Short: "Call the API server with auth attached",
+		Long: `Call the API server with auth attached and pretty-print the response.
+
+Locally (the default, -c local) requests go to the compose stack at
+$ONYX_API_URL (default http://localhost:8080), with $ONYX_API_KEY as a
+bearer token if it is set.
+
+With a cluster context, ods port-forwards to an api-server pod. --tenant
+and/or --as mint a short-lived impersonation session for the call, as
+` + "`ods impersonate`" + ` does (needs --reason, SUPER_CLOUD_API_KEY and
+ODS_SUPERUSER_SESSION, and is recorded in the audit log); otherwise
+$ONYX_API_KEY is used.
+
+JSON responses are indented unless --raw is set. The status line goes to
+stderr, so the body can be piped; a 4xx/5xx status exits non-zero.
+
+Examples:
+  ods curl /me
+  ods curl /manage/admin/connector/indexing-status -X POST --data '{}'
+  ods curl /persona -c data_plane --tenant tenant_abcd1234 --reason SUP-1234
+  ods curl /chat/send-message -X POST --data @message.json -H 'Accept: text/event-stream'`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			runCurl(opts, args[0])
+		},
+	}
+
+	addAPISessionFlags(cmd, &opts.APISessionOptions)
+	cmd.Flags().StringVarP(&opts.Method, "method", "X", "", "HTTP method (default GET, or POST with --data)")
+	cmd.Flags().StringVarP(&opts.Data, "data", "d", "", "Request body: literal JSON, @file or @- for stdin")
+	cmd.Flags().StringArrayVarP(&opts.Headers, "header", "H", nil, "Extra request header, e.g. 'Accept: text/event-stream' (repeatable)")
+	cmd.Flags().BoolVarP(&opts.Include, "include", "i", false, "Print response headers")
+	cmd.Flags().BoolVar(&opts.Raw, "raw", false, "Print the body as received")
+
+	return cmd
+}
+
+func runCurl(opts *CurlOptions, path string) {
+	var body []byte
+	if opts.Data != "" {
+		var err error
+		if body, err = apiclient.ReadBody(opts.Data, os.Stdin); err != nil {
+			log.Fatalf("%v", err)
+		}
+	}
+	method := strings.ToUpper(opts.Method)
+	if method == "" {
+		method = http.MethodGet
+		if body != nil {
+			method = http.MethodPost
+		}
+	}
+
+	session := openAPISession(&opts.APISessionOptions, "curl.impersonate")
+	defer session.Close()
+
+	req, err := session.Client.NewRequest(method, path, body)
+	if err != nil {
+		session.Close()
+		log.Fatalf("Invalid request: %v", err)
+	}
+	for _, h := range opts.Headers {
+		name, value, ok := strings.Cut(h, ":")
+		if !ok {
+			session.Close()
+			log.Fatalf("Invalid header %q: expected 'Name: value'", h)
+		}
+		req.Header.Set(strings.TrimSpace(name), strings.TrimSpace(value))
+	}
+
+	log.Debugf("%s %s via %s", method, req.URL, session.Target)
+	resp, err := session.Client.Do(req)
+	if err != nil {
+		session.Close()
+		log.Fatalf("%v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	fmt.Fprintf(os.Stderr, "%s %s\n", resp.Proto, resp.Status)
+	if opts.Include {
+		names := make([]string, 0, len(resp.Header))
+		for name := range resp.Header {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			for _, v := range resp.Header.Values(name) {
+				fmt.Fprintf(os.Stderr, "%s: %s\n", name, v)
+			}
+		}
+		fmt.Fprintln(os.Stderr)
+	}
+
+	// Streamed and non-JSON responses are copied as they arrive.
+	if opts.Raw || !apiclient.IsJSON(resp.Header.Get("Content-Type")) {
+		_, err = io.Copy(os.Stdout, resp.Body)
+	} else {
+		var data []byte
+		if data, err = io.ReadAll(resp.Body); err == nil {
+			_, err = os.Stdout.Write(apiclient.Pretty(data))
+		}
+	}
+	if err != nil {
+		session.Close()
+		log.Fatalf("Failed to read response: %v", err)
+	}
+	if resp.StatusCode >= 400 {
+		session.Close()
+		exitWith(1)
+	}
+}
@@ -16,9 +16,12 @@ import (
 
 // DBDumpOptions holds options for the db dump command.
 type DBDumpOptions struct {
-	Format string
-	Schema string
-	Output string
+	Format    string
+	Schema    string
+	Output    string
+	Anonymize bool
+	Rules     string
+	Salt      string
 }
 
 // NewDBDumpCommand creates the db dump command.
@@ -33,11 +36,16 @@ func NewDBDumpCommand() *cobra.Command {
 The snapshot is saved to the specified output file, or to the default
 snapshots directory (~/.local/share/onyx-dev/snapshots/) if no file is specified.
 
+With --anonymize the database is first copied to a scratch database, which
+is anonymized as by ` + "`ods anonymize`" + ` (--rules and --salt apply), dumped and
+dropped; the source database is not modified.
+
 Examples:
   ods db dump                           # Creates onyx_<timestamp>.dump in snapshots dir
   ods db dump mybackup.dump             # Creates mybackup.dump in snapshots dir
   ods db dump /path/to/backup.sql       # Creates backup.sql at specified path
-  ods db dump --format sql              # Creates SQL format instead of custom format`,
+  ods db dump --format sql              # Creates SQL format instead of custom format
+  ods db dump shareable.dump --anonymize # Dumps an anonymized copy`,
 		Args: cobra.MaximumNArgs(1),
 		Run: func(cmd *cobra.Command, args []string) {
 			if len(args) > 0 {
@@ -49,6 +57,9 @@ Examples:
 
 	cmd.Flags().StringVar(&opts.Format, "format", "custom", "Output format: 'custom' (pg_dump -Fc) or 'sql' (plain SQL)")
 	cmd.Flags().StringVar(&opts.Schema, "schema", "", "Dump only a specific schema")
+	cmd.Flags().BoolVar(&opts.Anonymize, "anonymize", false, "Dump an anonymized copy of the database")
+	cmd.Flags().StringVar(&opts.Rules, "rules", "", "With --anonymize, a YAML rules file to use instead of the built-in rules")
+	cmd.Flags().StringVar(&opts.Salt, "salt", "", "With --anonymize, the salt for hashes (default: random)")
 
 	return cmd
 }
@@ -63,6 +74,19 @@ func runDBDump(opts *DBDumpOptions) {
 
 	config := postgres.NewConfigFromEnv()
 
+	if opts.Anonymize {
+		script, err := anonymizeScript(opts.Schema, opts.Rules, opts.Salt)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		scratch, cleanup, err := anonymizedCopy(container, config, opts.Schema, script)
+		if err != nil {
+			log.Fatalf("Failed to create an anonymized copy: %v", err)
+		}
+		defer cleanup()
+		config = scratch
+	}
+
 	// Determine output file path.
 	outputPath := determineOutputPath(opts.Output, opts.Format)
 
@@ -107,6 +131,56 @@ func runDBDump(opts *DBDumpOptions) {
 	}
 }
 
+// anonymizedCopy copies config's database (or one schema of it) to a scratch
+// database in the same container and anonymizes it with script. It returns
+// the scratch database's config and a function that drops it.
+func anonymizedCopy(container string, config *postgres.Config, schema, script string) (*postgres.Config, func(), error) {
+	scratch := *config
+	scratch.Database = config.Database + "_ods_anonymized"
+	if !validIdentifier.MatchString(scratch.Database) {
+		return nil, nil, fmt.Errorf("invalid database name: %s", config.Database)
+	}
+	env := config.Env()
+	maintenance := func(sql string) error {
+		return docker.ExecWithEnv(container, env, "psql", "-U", config.User, "-d", "template1", "-q", "-c", sql)
+	}
+	drop := func() {
+		if err := maintenance(fmt.Sprintf("DROP DATABASE IF EXISTS %s;", scratch.Database)); err != nil {
+			log.Warnf("Failed to drop scratch database %s: %v", scratch.Database, err)
+		}
+	}
+
+	log.Infof("Copying '%s' to scratch database '%s'...", config.Database, scratch.Database)
+	drop()
+	if err := maintenance(fmt.Sprintf("CREATE DATABASE %s;", scratch.Database)); err != nil {
+		return nil, nil, fmt.Errorf("failed to create scratch database: %w", err)
+	}
+
+	const copyFile = "/tmp/onyx_anonymize_src"
+	defer func() { _ = docker.Exec(container, "rm", "-f", copyFile) }()
+	dumpArgs := append([]string{"pg_dump"}, config.PgDumpArgs("custom")...)
+	if schema != "" {
+		dumpArgs = append(dumpArgs, "-n", schema)
+	}
+	dumpArgs = append(dumpArgs, "-f", copyFile)
+	restoreArgs := append(append([]string{"pg_restore"}, scratch.PgRestoreArgs()...), "--no-owner", "--clean", "--if-exists", copyFile)
+	if err := docker.ExecWithEnv(container, env, dumpArgs...); err != nil {
+		drop()
+		return nil, nil, fmt.Errorf("failed to dump '%s': %w", config.Database, err)
+	}
+	if err := docker.ExecWithEnv(container, env, restoreArgs...); err != nil {
+		drop()
+		return nil, nil, fmt.Errorf("failed to restore into '%s': %w", scratch.Database, err)
+	}
+
+	log.Info("Anonymizing the copy...")
+	if err := runAnonymizeScript(container, &scratch, script); err != nil {
+		drop()
+		return nil, nil, err
+	}
+	return &scratch, drop, nil
+}
+
 // determineOutputPath determines the output file path based on options.
 func determineOutputPath(output string, format string) string {
 	ext := ".dump"
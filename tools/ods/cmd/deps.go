@@ -0,0 +1,223 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/audit"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/deps"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/paths"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/render"
+)
+
+// DepsOutdatedOptions holds options for the deps outdated subcommand.
+type DepsOutdatedOptions struct {
+	Ecosystems   []string
+	NoAdvisories bool
+	Vulnerable   bool
+	JSON         bool
+}
+
+// NewDepsCommand creates the deps command.
+func NewDepsCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "deps",
+		Short: "Inspect dependencies across the Python, Node and Go projects",
+	}
+
+	cmd.AddCommand(newDepsOutdatedCommand())
+	cmd.AddCommand(newDepsGraphCommand())
+
+	return cmd
+}
+
+func newDepsOutdatedCommand() *cobra.Command {
+	opts := &DepsOutdatedOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "outdated",
+		Short: "List outdated dependencies with their known advisories",
+		Long: `List outdated dependencies across the monorepo in one table: Python (the root
+venv, via uv pip list), Node (each JS project, via npm outdated) and Go (the
+direct requirements of each module, via go list -m -u).
+
+Each row is annotated with the advisories osv-scanner knows against the
+installed version, as ods audit finds them, so upgrades that fix a CVE stand
+out. Unlike ods audit no allowlist is applied and nothing gates.
+
+Reads what is installed: run uv sync and bun install first.
+
+Examples:
+  ods deps outdated
+  ods deps outdated --ecosystem python --vulnerable
+  ods deps outdated --json > outdated.json`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			runDepsOutdated(opts)
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&opts.Ecosystems, "ecosystem", nil, "Limit to ecosystems: python, node, go (repeatable)")
+	cmd.Flags().BoolVar(&opts.NoAdvisories, "no-advisories", false, "Skip the advisory lookup (which queries OSV.dev)")
+	cmd.Flags().BoolVar(&opts.Vulnerable, "vulnerable", false, "Only list dependencies with known advisories")
+	cmd.Flags().BoolVar(&opts.JSON, "json", false, "Print the dependencies as JSON")
+
+	return cmd
+}
+
+func newDepsGraphCommand() *cobra.Command {
+	var ecosystems []string
+
+	cmd := &cobra.Command{
+		Use:   "graph <package>",
+		Short: "Show what pulls a dependency in",
+		Long: `Show what pulls a dependency in, in every project that has it: the inverted
+tree from uv tree for Python, npm ls for each JS project and go mod why for
+each Go module.
+
+Examples:
+  ods deps graph urllib3
+  ods deps graph semver --ecosystem node
+  ods deps graph golang.org/x/net`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			runDepsGraph(ecosystems, args[0])
+		},
+	}
+
+	cmd.Flags().StringSliceVar(&ecosystems, "ecosystem", nil, "Limit to ecosystems: python, node, go (repeatable)")
+
+	return cmd
+}
+
+// depsProjects returns the repo root and its projects in the given
+// ecosystems (all when none are given).
+func depsProjects(ecosystems []string) (string, []deps.Project) {
+	root, err := paths.GitRoot()
+	if err != nil {
+		log.Fatalf("Failed to find git root: %v", err)
+	}
+	wanted := map[deps.Ecosystem]bool{}
+	for _, name := range ecosystems {
+		eco, ok := deps.ParseEcosystem(name)
+		if !ok {
+			log.Fatalf("Invalid --ecosystem %q (want python, node or go)", name)
+		}
+		wanted[eco] = true
+	}
+	var projects []deps.Project
+	for _, p := range deps.Projects(root) {
+		if len(wanted) == 0 || wanted[p.Ecosystem] {
+			projects = append(projects, p)
+		}
+	}
+	if len(projects) == 0 {
+		log.Fatalf("No projects found under %s", root)
+	}
+	return root, projects
+}
+
+func runDepsOutdated(opts *DepsOutdatedOptions) {
+	root, projects := depsProjects(opts.Ecosystems)
+
+	var outdated []deps.Outdated
+	for _, p := range projects {
+		log.Infof("Checking %s dependencies in %s...", p.Ecosystem, p.Dir)
+		found, err := deps.ListOutdated(root, p)
+		if err != nil {
+			log.Warnf("Skipping %s: %v", p.Dir, err)
+			continue
+		}
+		outdated = append(outdated, found...)
+	}
+
+	if !opts.NoAdvisories {
+		log.Info("Looking up advisories...")
+		findings, err := audit.ScanLockfiles(deps.Lockfiles(root, projects))
+		if err != nil {
+			log.Warnf("Failed to look up advisories: %v", err)
+		}
+		deps.Annotate(outdated, findings)
+	}
+	if opts.Vulnerable {
+		kept := outdated[:0]
+		for _, o := range outdated {
+			if len(o.Advisories) > 0 {
+				kept = append(kept, o)
+			}
+		}
+		outdated = kept
+	}
+
+	if opts.JSON {
+		if outdated == nil {
+			outdated = []deps.Outdated{}
+		}
+		if err := render.JSON(os.Stdout, outdated); err != nil {
+			log.Fatalf("Failed to marshal dependencies: %v", err)
+		}
+		return
+	}
+	if len(outdated) == 0 {
+		log.Info("Everything is up to date")
+		return
+	}
+
+	vulnerable := 0
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "ECOSYSTEM\tPROJECT\tPACKAGE\tCURRENT\tLATEST\tADVISORIES")
+	_, _ = fmt.Fprintln(w, "---------\t-------\t-------\t-------\t------\t----------")
+	for _, o := range outdated {
+		latest := o.Latest
+		if o.Wanted != "" && o.Wanted != o.Current && o.Wanted != o.Latest {
+			latest = fmt.Sprintf("%s (in range: %s)", o.Latest, o.Wanted)
+		}
+		advisories := "-"
+		if len(o.Advisories) > 0 {
+			vulnerable++
+			advisories = fmt.Sprintf("%s: %s", o.Severity(), advisoryIDs(o.Advisories))
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\n", o.Ecosystem, o.Project, o.Package, o.Current, latest, advisories)
+	}
+	_ = w.Flush()
+	fmt.Printf("\n%d outdated, %d with known advisories\n", len(outdated), vulnerable)
+}
+
+// advisoryIDs lists up to three advisory IDs, then a count of the rest.
+func advisoryIDs(findings []audit.Finding) string {
+	ids := make([]string, 0, len(findings))
+	for _, f := range findings {
+		ids = append(ids, f.ID)
+	}
+	if len(ids) > 3 {
+		return fmt.Sprintf("%s +%d", strings.Join(ids[:3], ", "), len(ids)-3)
+	}
+	return strings.Join(ids, ", ")
+}
+
+func runDepsGraph(ecosystems []string, pkg string) {
+	root, projects := depsProjects(ecosystems)
+
+	found := false
+	for _, p := range projects {
+		out, ok, err := deps.Why(root, p, pkg)
+		if err != nil {
+			log.Warnf("Skipping %s: %v", p.Dir, err)
+			continue
+		}
+		if !ok {
+			log.Debugf("%s does not depend on %s", p.Dir, pkg)
+			continue
+		}
+		found = true
+		fmt.Printf("== %s (%s)\n%s\n\n", p.Ecosystem, p.Dir, out)
+	}
+	if !found {
+		log.Fatalf("No project depends on %s", pkg)
+	}
+}
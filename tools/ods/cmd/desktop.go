@@ -56,9 +56,10 @@ func runDesktopScript(args []string) {
 		log.Fatalf("Failed to find repo root: %v", err)
 	}
 	rootNodeModules := filepath.Join(root, "node_modules")
-	if needsInstall, reason := nodeModulesNeedsInstall(rootNodeModules); needsInstall {
+	rootLockfile := filepath.Join(root, "bun.lock")
+	if needsInstall, reason := nodeModulesNeedsInstall(rootNodeModules, rootLockfile); needsInstall {
 		log.Infof("%s, running bun install --frozen-lockfile...", reason)
-		installCmd := exec.Command("bun", "install", "--frozen-lockfile")
+		installCmd := exec.Command(paths.Executable("bun"), "install", "--frozen-lockfile")
 		installCmd.Dir = root
 		installCmd.Stdout = os.Stdout
 		installCmd.Stderr = os.Stderr
@@ -66,6 +67,9 @@ func runDesktopScript(args []string) {
 		if err := installCmd.Run(); err != nil {
 			log.Fatalf("Failed to run bun install: %v", err)
 		}
+		if err := stampLockfileHash(rootNodeModules, rootLockfile); err != nil {
+			log.Debugf("Failed to record lockfile hash: %v", err)
+		}
 	}
 
 	scriptName := args[0]
@@ -82,7 +86,7 @@ func runDesktopScript(args []string) {
 	}
 	log.Debugf("Running in %s: npm %v", desktopDir, npmArgs)
 
-	desktopCmd := exec.Command("npm", npmArgs...)
+	desktopCmd := exec.Command(paths.Executable("npm"), npmArgs...)
 	desktopCmd.Dir = desktopDir
 	desktopCmd.Stdout = os.Stdout
 	desktopCmd.Stderr = os.Stderr
@@ -94,7 +98,7 @@ func runDesktopScript(args []string) {
 		var exitErr *exec.ExitError
 		if errors.As(err, &exitErr) {
 			if code := exitErr.ExitCode(); code != -1 {
-				os.Exit(code)
+				exitWith(code)
 			}
 		}
 		log.Fatalf("Failed to run npm: %v", err)
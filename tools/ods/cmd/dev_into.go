@@ -42,7 +42,7 @@ func runDevExec(command []string) {
 
 	log.Debugf("Running: devcontainer %v", args)
 
-	c := exec.Command("devcontainer", args...)
+	c := exec.Command(paths.Executable("devcontainer"), args...)
 	c.Stdout = os.Stdout
 	c.Stderr = os.Stderr
 	c.Stdin = os.Stdin
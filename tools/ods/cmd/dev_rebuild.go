@@ -6,6 +6,8 @@ import (
 
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/paths"
 )
 
 func newDevRebuildCommand() *cobra.Command {
@@ -30,7 +32,7 @@ func runDevRebuild() {
 	image := devcontainerImage()
 
 	log.Infof("Pulling %s...", image)
-	pull := exec.Command("docker", "pull", image)
+	pull := exec.Command(paths.Executable("docker"), "pull", image)
 	pull.Stdout = os.Stdout
 	pull.Stderr = os.Stderr
 	if err := pull.Run(); err != nil {
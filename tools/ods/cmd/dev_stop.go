@@ -34,7 +34,7 @@ func runDevStop() {
 
 	// Find the container by the devcontainer label
 	out, err := exec.Command(
-		"docker", "ps", "-q",
+		paths.Executable("docker"), "ps", "-q",
 		"--filter", "label=devcontainer.local_folder="+root,
 	).Output()
 	if err != nil {
@@ -48,7 +48,7 @@ func runDevStop() {
 	}
 
 	log.Infof("Stopping devcontainer %s...", containerID)
-	c := exec.Command("docker", "stop", containerID)
+	c := exec.Command(paths.Executable("docker"), "stop", containerID)
 	if err := c.Run(); err != nil {
 		log.Fatalf("Failed to stop devcontainer: %v", err)
 	}
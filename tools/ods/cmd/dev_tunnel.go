@@ -84,7 +84,7 @@ func runDevTunnel(hostPort, containerPort int) {
 	}
 
 	out, err := exec.Command(
-		"docker", "ps", "-q",
+		paths.Executable("docker"), "ps", "-q",
 		"--filter", "label=devcontainer.local_folder="+root,
 	).Output()
 	if err != nil {
@@ -57,7 +57,7 @@ func devcontainerImage() string {
 
 // checkDevcontainerCLI ensures the devcontainer CLI is installed.
 func checkDevcontainerCLI() {
-	if _, err := exec.LookPath("devcontainer"); err != nil {
+	if _, err := exec.LookPath(paths.Executable("devcontainer")); err != nil {
 		log.Fatal("devcontainer CLI is not installed. Install it with: bun install -g @devcontainers/cli")
 	}
 }
@@ -241,7 +241,7 @@ func runDevcontainer(action string, extraArgs []string) {
 
 	log.Debugf("Running: devcontainer %v", args)
 
-	c := exec.Command("devcontainer", args...)
+	c := exec.Command(paths.Executable("devcontainer"), args...)
 	c.Stdout = os.Stdout
 	c.Stderr = os.Stderr
 	c.Stdin = os.Stdin
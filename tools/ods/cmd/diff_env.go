@@ -0,0 +1,145 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/envdiff"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/render"
+)
+
+// DiffEnvOptions holds options for the diff-env command.
+type DiffEnvOptions struct {
+	Tenant  string
+	NoFlags bool
+	Only    []string
+}
+
+// NewDiffEnvCommand creates the diff-env command.
+func NewDiffEnvCommand() *cobra.Command {
+	opts := &DiffEnvOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "diff-env <context-a> <context-b>",
+		Short: "Show how the configuration of two environments differs",
+		Long: `Show how the configuration of two environments differs.
+
+Compares, between two cluster contexts (each maps to a KUBE_CTX_<NAME> env
+var):
+  image     the image of every deployment's containers
+  replicas  desired replica counts ("autoscaled" for deployments behind an
+            autoscaler, whose counts move with load)
+  env       every container's environment variables, resolved from the pod
+            template, ConfigMaps and Secrets
+  flag      feature flags (workspace settings) of --tenant, or of the
+            default schema on single-tenant deployments
+
+Values from Secrets, and literal values of variables named like secrets,
+are shown as hashes keyed for this run only: equal hashes mean equal values,
+but nothing about a value can be learned from its hash.
+
+Lines starting with ~ differ, - exist only in context-a and + only in
+context-b. Color is used when stdout is a terminal.
+
+Requires: AWS SSO login, kubectl access to both EKS clusters.
+
+Examples:
+  ods diff-env staging prod
+  ods diff-env staging prod --only image,env
+  ods diff-env staging prod --tenant tenant_abcd1234`,
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			runDiffEnv(args[0], args[1], opts)
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.Tenant, "tenant", "", "Tenant whose flags to compare (omit on single-tenant deployments)")
+	cmd.Flags().BoolVar(&opts.NoFlags, "no-flags", false, "Skip feature flags, which need an api-server pod in each context")
+	cmd.Flags().StringSliceVar(&opts.Only, "only", nil, "Compare only these sections: "+strings.Join(envdiff.Sections, ", "))
+
+	return cmd
+}
+
+func runDiffEnv(contextA, contextB string, opts *DiffEnvOptions) {
+	for _, section := range opts.Only {
+		if !slices.Contains(envdiff.Sections, section) {
+			log.Fatalf("Unknown section %q (must be one of %s)", section, strings.Join(envdiff.Sections, ", "))
+		}
+	}
+	if opts.Tenant != "" {
+		validateTenantArg(opts.Tenant)
+	}
+	withFlags := !opts.NoFlags && (len(opts.Only) == 0 || slices.Contains(opts.Only, envdiff.SectionFlag))
+
+	hasher, err := envdiff.NewHasher()
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	a := snapshotContext(contextA, opts.Tenant, withFlags, hasher)
+	b := snapshotContext(contextB, opts.Tenant, withFlags, hasher)
+	for _, s := range []*envdiff.Snapshot{a, b} {
+		for _, w := range s.Warnings {
+			log.Warnf("%s: %s", s.Context, w)
+		}
+	}
+
+	changes := envdiff.Diff(a, b, opts.Only...)
+	if len(changes) == 0 {
+		log.Infof("No differences between %s and %s", contextA, contextB)
+		return
+	}
+	printEnvDiff(contextA, contextB, changes, render.ColorEnabled(os.Stdout))
+}
+
+func snapshotContext(name, tenant string, withFlags bool, hasher *envdiff.Hasher) *envdiff.Snapshot {
+	c := clusterFromEnv(name)
+	if err := c.EnsureContext(); err != nil {
+		log.Fatalf("Failed to ensure cluster context %s: %v", name, err)
+	}
+	pod := ""
+	if withFlags {
+		var err error
+		if pod, err = c.FindPod("api-server"); err != nil {
+			log.Warnf("%s: no api-server pod, flags not compared: %v", name, err)
+			pod = ""
+		}
+	}
+	log.Infof("Reading configuration of %s...", name)
+	s, err := envdiff.Collect(c, pod, tenant, hasher)
+	if err != nil {
+		log.Fatalf("Failed to read %s: %v", name, err)
+	}
+	s.Context = name
+	return s
+}
+
+func printEnvDiff(contextA, contextB string, changes []envdiff.Change, color bool) {
+	paint := func(style, s string) string { return render.Paint(color, style, s) }
+	width := 0
+	for _, c := range changes {
+		width = max(width, len(c.Key))
+	}
+
+	fmt.Printf("%s  %s  %s\n", paint(render.Red, "- only in "+contextA), paint(render.Green, "+ only in "+contextB), paint(render.Yellow, "~ differs ("+contextA+" → "+contextB+")"))
+	section := ""
+	for _, c := range changes {
+		if c.Section != section {
+			section = c.Section
+			fmt.Printf("\n%s\n", paint(render.Bold, strings.ToUpper(section)))
+		}
+		switch {
+		case !c.InB:
+			fmt.Println(paint(render.Red, fmt.Sprintf("- %-*s  %s", width, c.Key, c.A)))
+		case !c.InA:
+			fmt.Println(paint(render.Green, fmt.Sprintf("+ %-*s  %s", width, c.Key, c.B)))
+		default:
+			fmt.Println(paint(render.Yellow, fmt.Sprintf("~ %-*s  %s → %s", width, c.Key, c.A, c.B)))
+		}
+	}
+	fmt.Printf("\n%d differences\n", len(changes))
+}
@@ -0,0 +1,230 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/auditlog"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/docker"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/paths"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/prompt"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/render"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/vespaapp"
+)
+
+// DiffSchemaVespaOptions holds options for the diff-schema-vespa command.
+type DiffSchemaVespaOptions struct {
+	Context string
+	Deploy  bool
+	Allow   []string
+	Yes     bool
+	JSON    bool
+}
+
+// NewDiffSchemaVespaCommand creates the diff-schema-vespa command.
+func NewDiffSchemaVespaCommand() *cobra.Command {
+	opts := &DiffSchemaVespaOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "diff-schema-vespa",
+		Short: "Compare the deployed Vespa application package with the repo's, and deploy it",
+		Long: `Compare the Vespa application package deployed in an environment with the
+one the repo's templates (` + vespaapp.AppConfigPath + `) render.
+
+The deployed package is fetched from the Vespa config server through an
+api-server pod (or the local api_server container). The repo's templates are
+rendered with the values the deployed package was rendered with (index
+names, embedding dimension and precision, multi-tenancy, ngrams and search
+threads), so only changes to the templates show up. Differences are listed
+per field, rank-profile, fieldset and document-summary:
+  - only deployed; deploying the repo's package removes it
+  + only in the repo; deploying adds it
+  ~ differs, with the changed lines
+
+--deploy prepares and activates the repo's package. When Vespa refuses a
+change that needs a validation override (e.g. indexing-change on a field),
+each override it asks for is shown and must be allowed, at the prompt or up
+front with --allow, before the package is deployed again with the override
+added for one day. Deploys ask for confirmation on production contexts
+unless --yes is passed, and are recorded in the local audit log.
+
+Requires, for a cluster context: AWS SSO login, kubectl access to the EKS
+cluster.
+
+Examples:
+  ods diff-schema-vespa
+  ods diff-schema-vespa -c staging
+  ods diff-schema-vespa -c staging --deploy
+  ods diff-schema-vespa -c prod --deploy --allow indexing-change`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			runDiffSchemaVespa(opts)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.Context, "context", "c", localContext, `cluster context name (maps to KUBE_CTX_<NAME> env var), or "local" for the compose stack`)
+	cmd.Flags().BoolVar(&opts.Deploy, "deploy", false, "Deploy the repo's package if it differs")
+	cmd.Flags().StringSliceVar(&opts.Allow, "allow", nil, "Validation overrides to allow without asking (e.g. indexing-change)")
+	cmd.Flags().BoolVar(&opts.Yes, "yes", false, "Skip the deploy confirmation prompt")
+	cmd.Flags().BoolVar(&opts.JSON, "json", false, "Print differences as JSON")
+
+	return cmd
+}
+
+func runDiffSchemaVespa(opts *DiffSchemaVespaOptions) {
+	if opts.JSON && opts.Deploy {
+		log.Fatal("--json cannot be combined with --deploy")
+	}
+
+	var run vespaapp.Runner
+	target := "local"
+	if opts.Context == localContext {
+		run = vespaapp.ContainerRunner(fmt.Sprintf("%s-api_server-1", docker.ProjectName()))
+	} else {
+		c := clusterFromEnv(opts.Context)
+		if err := c.EnsureContext(); err != nil {
+			log.Fatalf("Failed to ensure cluster context: %v", err)
+		}
+		target = c.Name + "/" + c.Namespace
+		log.Info("Finding api-server pod...")
+		pod, err := c.FindPod("api-server")
+		if err != nil {
+			log.Fatalf("Failed to find api-server pod: %v", err)
+		}
+		run = vespaapp.PodRunner(c, pod)
+	}
+
+	log.Info("Fetching the deployed application package...")
+	deployed, err := vespaapp.Fetch(run)
+	if err != nil {
+		log.Fatalf("Failed to fetch the deployed application package: %v", err)
+	}
+	renderOpts, err := vespaapp.InferOptions(deployed)
+	if err != nil {
+		log.Fatalf("Failed to read the deployed application package: %v", err)
+	}
+	root, err := paths.GitRoot()
+	if err != nil {
+		log.Fatalf("Failed to find the git root: %v", err)
+	}
+	repo, err := vespaapp.Render(filepath.Join(root, vespaapp.AppConfigPath), renderOpts)
+	if err != nil {
+		log.Fatalf("Failed to render the repo's application package: %v", err)
+	}
+
+	changes := vespaapp.Diff(deployed, repo)
+	if opts.JSON {
+		if err := render.JSON(os.Stdout, changes); err != nil {
+			log.Fatalf("Failed to marshal differences: %v", err)
+		}
+		return
+	}
+	if len(changes) == 0 {
+		log.Infof("The application package in %s matches the repo", target)
+		return
+	}
+	printSchemaDiff(changes, render.ColorEnabled(os.Stdout))
+	if !opts.Deploy {
+		return
+	}
+
+	if !opts.Yes && isProductionContext(opts.Context) {
+		if !prompt.Confirm(fmt.Sprintf("Deploy the repo's Vespa application package to %s? (yes/no): ", target)) {
+			log.Info("Aborted.")
+			return
+		}
+	}
+	if err := auditlog.Record(auditlog.Entry{
+		Action:  "vespa.deploy",
+		Context: target,
+		Target:  strings.Join(deployed.Schemas(), ","),
+	}); err != nil {
+		log.Fatalf("Refusing to deploy without an audit record: %v", err)
+	}
+	deployVespaPackage(run, repo, target, opts.Allow)
+}
+
+// deployVespaPackage deploys pkg, adding the validation overrides Vespa
+// asks for once the user allows them.
+func deployVespaPackage(run vespaapp.Runner, pkg vespaapp.Package, target string, preallowed []string) {
+	var added []string
+	for {
+		log.Infof("Deploying the application package to %s...", target)
+		res, err := vespaapp.Deploy(run, pkg)
+		if err != nil {
+			log.Fatalf("Failed to deploy: %v", err)
+		}
+		if res.OK() {
+			log.Infof("Deployed: %s", res.Message())
+			return
+		}
+
+		ids := vespaapp.RequiredOverrides(res.Message())
+		if len(ids) == 0 {
+			log.Fatalf("Vespa rejected the package (HTTP %d): %s", res.Code, res.Message())
+		}
+		var needed []string
+		for _, id := range ids {
+			if slices.Contains(added, id) {
+				log.Fatalf("Vespa still requires %s after it was allowed: %s", id, res.Message())
+			}
+			needed = append(needed, id)
+		}
+
+		fmt.Printf("\nVespa needs validation overrides to apply this package:\n%s\n\n", res.Message())
+		for _, id := range needed {
+			if slices.Contains(preallowed, id) {
+				log.Infof("Allowing %s (--allow)", id)
+				continue
+			}
+			if !render.IsTerminal(os.Stdin) {
+				log.Fatalf("Validation override %s is required; pass --allow %s to allow it", id, id)
+			}
+			if !prompt.Confirm(fmt.Sprintf("Allow %s? (yes/no): ", id)) {
+				log.Fatalf("Not deploying without %s; nothing was changed", id)
+			}
+		}
+		added = append(added, needed...)
+		pkg = vespaapp.WithOverrides(pkg, needed, time.Now().AddDate(0, 0, 1), "Allowed with ods diff-schema-vespa")
+	}
+}
+
+func printSchemaDiff(changes []vespaapp.Change, color bool) {
+	paint := func(style, s string) string { return render.Paint(color, style, s) }
+
+	fmt.Printf("%s  %s  %s\n", paint(render.Red, "- only deployed"), paint(render.Green, "+ only in the repo"), paint(render.Yellow, "~ differs"))
+	file := ""
+	for _, c := range changes {
+		if c.File != file {
+			file = c.File
+			fmt.Printf("\n%s\n", paint(render.Bold, file))
+		}
+		label := c.Kind + " " + c.Name
+		if c.Kind == vespaapp.KindFile {
+			label = c.Name
+		}
+		switch {
+		case !c.InRepo:
+			fmt.Println(paint(render.Red, "- "+label))
+		case !c.InDeployed:
+			fmt.Println(paint(render.Green, "+ "+label))
+		default:
+			fmt.Println(paint(render.Yellow, "~ "+label))
+			for _, line := range c.Lines {
+				code := render.Green
+				if strings.HasPrefix(line, "-") {
+					code = render.Red
+				}
+				fmt.Println("    " + paint(code, line))
+			}
+		}
+	}
+	fmt.Printf("\n%d differences\n", len(changes))
+}
@@ -0,0 +1,169 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/dist"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/git"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/paths"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/runner"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/s3"
+)
+
+const odsTagPrefix = "ods/"
+
+// DistOptions holds options for the dist command.
+type DistOptions struct {
+	Version       string
+	Targets       string
+	OutDir        string
+	S3URL         string
+	GitHubRelease string
+	Repo          string
+}
+
+// NewDistCommand creates the dist command for building prebuilt ods binaries.
+func NewDistCommand() *cobra.Command {
+	opts := &DistOptions{}
+
+	defaultTargets := make([]string, len(dist.DefaultTargets))
+	for i, t := range dist.DefaultTargets {
+		defaultTargets[i] = t.String()
+	}
+
+	cmd := &cobra.Command{
+		Use:   "dist",
+		Short: "Cross-compile ods release binaries",
+		Long: `Cross-compile the ods binary for each supported platform.
+
+Each binary is stamped with the version and commit (see ` + "`ods --version`" + `) and a
+SHA256SUMS manifest is written alongside them. The version defaults to the
+latest ods/* tag reachable from HEAD.
+
+Artifacts can optionally be uploaded to an S3 prefix (requires AWS credentials)
+or attached to an existing GitHub release (requires gh).
+
+Examples:
+  ods dist
+  ods dist --targets linux/amd64,darwin/arm64
+  ods dist --s3 s3://my-bucket/ods/
+  ods dist --github-release ods/v0.7.0`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			runDist(opts)
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.Version, "version", "", "Version to stamp into the binaries (default: latest ods/* tag)")
+	cmd.Flags().StringVar(&opts.Targets, "targets", strings.Join(defaultTargets, ","), "Comma-separated os/arch pairs to build")
+	cmd.Flags().StringVar(&opts.OutDir, "out", "", "Output directory (default: tools/ods/dist)")
+	cmd.Flags().StringVar(&opts.S3URL, "s3", "", "S3 prefix to upload artifacts to (e.g. s3://bucket/ods/)")
+	cmd.Flags().StringVar(&opts.GitHubRelease, "github-release", "", "Tag of an existing GitHub release to attach artifacts to")
+	cmd.Flags().StringVar(&opts.Repo, "repo", "onyx-dot-app/onyx", "GitHub repo (owner/name) for --github-release")
+
+	return cmd
+}
+
+func runDist(opts *DistOptions) {
+	targets, err := dist.ParseTargets(opts.Targets)
+	if err != nil {
+		log.Fatalf("Invalid --targets: %v", err)
+	}
+	if opts.GitHubRelease != "" {
+		git.CheckGitHubCLI()
+	}
+
+	root, err := paths.GitRoot()
+	if err != nil {
+		log.Fatalf("Failed to find git root: %v", err)
+	}
+	srcDir := filepath.Join(root, "tools", "ods")
+
+	outDir := opts.OutDir
+	if outDir == "" {
+		outDir = filepath.Join(srcDir, "dist")
+	}
+	if err := os.MkdirAll(outDir, 0755); err != nil {
+		log.Fatalf("Failed to create %s: %v", outDir, err)
+	}
+
+	version := opts.Version
+	if version == "" {
+		version = latestOdsVersion(nil)
+	}
+	commit := headCommit(nil)
+	log.Infof("Building ods %s (commit %s) for %d target(s)", version, commit, len(targets))
+
+	var artifacts []string
+	for _, t := range targets {
+		out, err := dist.Build(nil, srcDir, outDir, version, commit, t)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		artifacts = append(artifacts, out)
+	}
+
+	sums, err := dist.WriteChecksums(outDir, artifacts)
+	if err != nil {
+		log.Fatalf("Failed to write checksums: %v", err)
+	}
+	artifacts = append(artifacts, sums)
+
+	if opts.S3URL != "" {
+		prefix := strings.TrimSuffix(opts.S3URL, "/") + "/" + version + "/"
+		for _, a := range artifacts {
+			if err := s3.PutFile(a, prefix+filepath.Base(a)); err != nil {
+				log.Fatalf("Failed to upload %s: %v", a, err)
+			}
+		}
+		log.Infof("Uploaded artifacts to %s", prefix)
+	}
+
+	if opts.GitHubRelease != "" {
+		if err := uploadReleaseAssets(nil, opts.GitHubRelease, opts.Repo, artifacts); err != nil {
+			log.Fatalf("Failed to upload to GitHub release %s: %v", opts.GitHubRelease, err)
+		}
+		log.Infof("Attached artifacts to GitHub release %s", opts.GitHubRelease)
+	}
+
+	fmt.Println()
+	for _, a := range artifacts {
+		fmt.Println(a)
+	}
+}
+
+// uploadReleaseAssets attaches artifacts to the GitHub release tag of repo,
+// replacing assets of the same name.
+func uploadReleaseAssets(r runner.Runner, tag, repo string, artifacts []string) error {
+	return runner.Or(r).Run(runner.Cmd{
+		Name:   "gh",
+		Args:   append([]string{"release", "upload", tag, "-R", repo, "--clobber"}, artifacts...),
+		Stdout: os.Stderr,
+		Stderr: os.Stderr,
+	})
+}
+
+// latestOdsVersion returns the most recent ods/* tag reachable from HEAD with
+// the prefix stripped, falling back to "dev" when there is none.
+func latestOdsVersion(r runner.Runner) string {
+	out, err := runner.Output(r, runner.Cmd{Name: "git", Args: []string{"describe", "--tags", "--abbrev=0", "--match", odsTagPrefix + "*"}})
+	if err != nil {
+		log.Warn("No ods/* tag found; stamping version as \"dev\" (pass --version to override)")
+		return "dev"
+	}
+	return strings.TrimPrefix(strings.TrimSpace(string(out)), odsTagPrefix)
+}
+
+func headCommit(r runner.Runner) string {
+	out, err := runner.Output(r, runner.Cmd{Name: "git", Args: []string{"rev-parse", "HEAD"}})
+	if err != nil {
+		return "none"
+	}
+	return strings.TrimSpace(string(out))
+}
@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/runner"
+)
+
+func TestDistGitMetadata(t *testing.T) {
+	f := (&runner.Fake{}).
+		On("git describe --tags --abbrev=0 --match ods/*", runner.Response{Stdout: "ods/0.4.1\n"}).
+		On("git rev-parse HEAD", runner.Response{Stdout: "0123abcd\n"})
+	if got := latestOdsVersion(f); got != "0.4.1" {
+		t.Errorf("latestOdsVersion() = %q, want 0.4.1", got)
+	}
+	if got := headCommit(f); got != "0123abcd" {
+		t.Errorf("headCommit() = %q", got)
+	}
+
+	f = (&runner.Fake{}).On("git", runner.Response{Err: errors.New("exit status 128")})
+	if got := latestOdsVersion(f); got != "dev" {
+		t.Errorf("latestOdsVersion() without a tag = %q, want dev", got)
+	}
+	if got := headCommit(f); got != "none" {
+		t.Errorf("headCommit() outside a repo = %q, want none", got)
+	}
+}
+
+func TestUploadReleaseAssets(t *testing.T) {
+	f := (&runner.Fake{}).On("gh release upload", runner.Response{})
+	if err := uploadReleaseAssets(f, "ods/0.4.1", "onyx-dot-app/onyx", []string{"dist/ods_0.4.1_linux_amd64", "dist/SHA256SUMS"}); err != nil {
+		t.Fatal(err)
+	}
+	want := "gh release upload ods/0.4.1 -R onyx-dot-app/onyx --clobber dist/ods_0.4.1_linux_amd64 dist/SHA256SUMS"
+	if got := f.Lines(); len(got) != 1 || got[0] != want {
+		t.Errorf("commands = %q, want %q", got, want)
+	}
+}
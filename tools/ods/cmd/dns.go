@@ -0,0 +1,280 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/domains"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/kube"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/render"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/trace"
+)
+
+// DNSOptions holds options for the dns command.
+type DNSOptions struct {
+	Context string
+	Timeout time.Duration
+	JSON    bool
+}
+
+// NewDNSCommand creates the dns command.
+func NewDNSCommand() *cobra.Command {
+	opts := &DNSOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "dns <host | tenant_id>",
+		Short: "Trace the request path of a host or tenant and report where it breaks",
+		Long: `Trace the path a request for a host takes into the cluster and report where
+it breaks, for "the site is down" triage.
+
+Each hop is checked in order:
+  dns            the host resolves, to the ingress's load balancer
+  load-balancer  the load balancer accepts connections on 443 (80 without TLS)
+  ingress        an ingress has a rule for the host, and where it routes it
+  service        each backend service exists and has the port the rule uses
+  pods           the service selects pods, and they are ready
+  http           a request to the host gets an answer other than a 5xx
+
+For a tenant ID, the hosts of the tenant's custom domains (ingresses labelled
+` + domains.TenantLabel + `) are traced, or the deployment's own hosts when it
+has none. A host may also be given as a URL. Certificates are checked in depth
+by ` + "`ods domains check`" + `.
+
+Exits non-zero when any hop fails.
+
+Requires: AWS SSO login, kubectl access to the EKS cluster.
+
+Examples:
+  ods dns cloud.onyx.app
+  ods dns https://onyx.acme.com/chat -c data_plane
+  ods dns tenant_abcd1234 --json`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			runDNS(opts, args[0])
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.Context, "context", "c", "data_plane", "cluster context name (maps to KUBE_CTX_<NAME> env var)")
+	cmd.Flags().DurationVar(&opts.Timeout, "timeout", 5*time.Second, "Timeout for each DNS lookup, connection and request")
+	cmd.Flags().BoolVar(&opts.JSON, "json", false, "Print the traces as JSON")
+
+	return cmd
+}
+
+func runDNS(opts *DNSOptions, target string) {
+	if opts.Timeout <= 0 {
+		log.Fatal("--timeout must be positive")
+	}
+	c := clusterFromEnv(opts.Context)
+	if err := c.EnsureContext(); err != nil {
+		log.Fatalf("Failed to ensure cluster context: %v", err)
+	}
+	ingresses, err := c.ListIngresses()
+	if err != nil {
+		log.Fatalf("Failed to list ingresses: %v", err)
+	}
+
+	var tenantID string
+	var hosts []string
+	if strings.HasPrefix(target, "tenant_") {
+		validateTenantArg(target)
+		tenantID = target
+		hosts = dnsTenantHosts(ingresses, tenantID)
+		if len(hosts) == 0 {
+			log.Fatal("No ingress serves any host")
+		}
+	} else {
+		hosts = []string{trace.NormalizeHost(target)}
+	}
+
+	p := &dnsProbe{cluster: c, timeout: opts.Timeout, services: map[string]*kube.Service{}, pods: map[string][]*kube.Pod{}}
+	var traces []*trace.Trace
+	broken := 0
+	for _, host := range hosts {
+		log.Infof("Tracing %s...", host)
+		t := p.trace(ingresses, host)
+		t.Tenant = tenantID
+		if t.Break() != nil {
+			broken++
+		}
+		traces = append(traces, t)
+	}
+
+	if opts.JSON {
+		if err := render.JSON(os.Stdout, traces); err != nil {
+			log.Fatalf("Failed to marshal the traces: %v", err)
+		}
+	} else {
+		for i, t := range traces {
+			if i > 0 {
+				fmt.Println()
+			}
+			printTrace(os.Stdout, t)
+		}
+	}
+	if broken > 0 {
+		exitWith(1)
+	}
+}
+
+// dnsTenantHosts returns the hosts of the tenant's custom domains, or the
+// deployment's own hosts when it has none.
+func dnsTenantHosts(ingresses []*kube.Ingress, tenantID string) []string {
+	var own, custom []string
+	for _, d := range domains.Collect(ingresses, nil) {
+		switch d.Tenant {
+		case tenantID:
+			custom = append(custom, d.Host)
+		case "":
+			own = append(own, d.Host)
+		}
+	}
+	if len(custom) > 0 {
+		return custom
+	}
+	log.Infof("%s has no custom domain; tracing the deployment's own hosts", tenantID)
+	return own
+}
+
+// dnsProbe checks the hops of request paths, fetching each service and its
+// pods once.
+type dnsProbe struct {
+	cluster  *kube.Cluster
+	timeout  time.Duration
+	services map[string]*kube.Service
+	pods     map[string][]*kube.Pod
+}
+
+func (p *dnsProbe) trace(ingresses []*kube.Ingress, host string) *trace.Trace {
+	t := &trace.Trace{Host: host}
+	ing := trace.FindIngress(ingresses, host)
+	var lb []string
+	tls := false
+	if ing != nil {
+		lb = ing.LoadBalancer
+		tls = ing.TLSSecret(host) != ""
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), p.timeout)
+	dns := domains.Resolve(ctx, net.DefaultResolver, host, lb)
+	cancel()
+	t.Hops = append(t.Hops, trace.DNSHop(host, dns))
+
+	port := "80"
+	if tls {
+		port = "443"
+	}
+	if ing == nil {
+		t.Hops = append(t.Hops, trace.Skipped("load-balancer", "-", "no ingress serves the host"))
+	} else {
+		var dialErr error
+		if len(lb) > 0 {
+			conn, err := net.DialTimeout("tcp", net.JoinHostPort(lb[0], port), p.timeout)
+			if err == nil {
+				_ = conn.Close()
+			}
+			dialErr = err
+		}
+		t.Hops = append(t.Hops, trace.LoadBalancerHop(lb, port, dialErr))
+	}
+
+	t.Hops = append(t.Hops, trace.IngressHop(ing, host))
+	if ing != nil {
+		for _, b := range ing.BackendsFor(host) {
+			if b.Service == "" {
+				continue
+			}
+			t.Hops = append(t.Hops, p.backendHops(b)...)
+		}
+	}
+
+	scheme := "http"
+	if tls {
+		scheme = "https"
+	}
+	url := scheme + "://" + host + "/"
+	if dns.Error != "" {
+		t.Hops = append(t.Hops, trace.Skipped("http", url, "the host does not resolve"))
+	} else {
+		status, err := p.get(url)
+		t.Hops = append(t.Hops, trace.HTTPHop(url, status, err))
+	}
+	return t
+}
+
+// backendHops checks the service an ingress backend routes to and the pods
+// it selects.
+func (p *dnsProbe) backendHops(b kube.IngressBackend) []trace.Hop {
+	svc, ok := p.services[b.Service]
+	var err error
+	if !ok {
+		svc, err = p.cluster.GetService(b.Service)
+		if err != nil {
+			log.Debugf("Failed to get service %s: %v", b.Service, err)
+			svc = nil
+		}
+		p.services[b.Service] = svc
+	}
+	hops := []trace.Hop{trace.ServiceHop(b, svc, err)}
+	if svc == nil || len(svc.Selector) == 0 {
+		return hops
+	}
+
+	selector := trace.Selector(svc)
+	pods, ok := p.pods[selector]
+	if !ok {
+		pods, err = p.cluster.ListPodsWithSelector(selector)
+		if err != nil {
+			return append(hops, trace.Hop{Name: "pods", Target: selector, State: trace.StateFail,
+				Detail: fmt.Sprintf("failed to list pods: %v", err)})
+		}
+		p.pods[selector] = pods
+	}
+	return append(hops, trace.PodsHop(b.Service, selector, pods))
+}
+
+// get requests url without following redirects and returns the status.
+func (p *dnsProbe) get(url string) (int, error) {
+	client := &http.Client{
+		Timeout: p.timeout,
+		CheckRedirect: func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		},
+	}
+	resp, err := client.Get(url)
+	if err != nil {
+		return 0, err
+	}
+	_ = resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+func printTrace(w io.Writer, t *trace.Trace) {
+	_, _ = fmt.Fprint(w, t.Host)
+	if t.Tenant != "" {
+		_, _ = fmt.Fprintf(w, " (tenant %s)", t.Tenant)
+	}
+	_, _ = fmt.Fprintln(w)
+
+	table := render.NewTable("HOP", "STATE", "TARGET", "DETAIL")
+	table.Indent = "  "
+	for _, h := range t.Hops {
+		table.Row(h.Name, h.State, h.Target, h.Detail)
+	}
+	_ = table.Write(w)
+
+	if b := t.Break(); b != nil {
+		_, _ = fmt.Fprintf(w, "Breaks at %s (%s): %s\n", b.Name, b.Target, b.Detail)
+	} else {
+		_, _ = fmt.Fprintln(w, "No hop failed.")
+	}
+}
@@ -0,0 +1,166 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/document"
+)
+
+// DocOptions holds options for the doc command.
+type DocOptions struct {
+	Context string
+	Tenant  string
+}
+
+// NewDocCommand creates the doc command for looking up a document.
+func NewDocCommand() *cobra.Command {
+	opts := &DocOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "doc <document-id or URL>",
+		Short: "Look up a document in Postgres and Vespa",
+		Long: `Look up a document in Postgres and Vespa and explain why it may not show up
+in search.
+
+Finds the document by ID or, failing that, by link, and prints its row
+(boost, hidden flag, chunk count, last update and last sync to Vespa), the
+connectors that index it, the access list the backend computes for it and
+the chunks Vespa holds for it. Differences between the two stores, and
+anything else that would hide the document or rank it down, are listed at
+the end.
+
+On a single-tenant deployment omit --tenant.
+
+Requires: AWS SSO login, kubectl access to the EKS cluster.
+
+Examples:
+  ods doc https://docs.example.com/setup --tenant tenant_abcd1234
+  ods doc 'FILE_CONNECTOR__1b2c3d' --tenant tenant_abcd1234 -c staging`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			runDoc(opts, args[0])
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.Context, "context", "c", "data_plane", "cluster context name (maps to KUBE_CTX_<NAME> env var)")
+	cmd.Flags().StringVar(&opts.Tenant, "tenant", "", "Tenant schema (omit on single-tenant deployments)")
+
+	return cmd
+}
+
+func runDoc(opts *DocOptions, key string) {
+	if opts.Tenant != "" {
+		validateTenantArg(opts.Tenant)
+	}
+	c := clusterFromEnv(opts.Context)
+	if err := c.EnsureContext(); err != nil {
+		log.Fatalf("Failed to ensure cluster context: %v", err)
+	}
+	log.Info("Finding api-server pod...")
+	pod, err := c.FindPod("api-server")
+	if err != nil {
+		log.Fatalf("Failed to find api-server pod: %v", err)
+	}
+
+	l, err := document.Find(c, pod, opts.Tenant, key)
+	if err != nil {
+		log.Fatalf("Failed to look up %s: %v", key, err)
+	}
+	if len(l.Candidates) > 0 {
+		fmt.Printf("%d documents link to %s; look one up by ID:\n", len(l.Candidates), key)
+		for _, cand := range l.Candidates {
+			fmt.Printf("  %s  (%s)\n", cand.ID, cand.SemanticID)
+		}
+		return
+	}
+	printDocLookup(l)
+}
+
+func printDocLookup(l *document.Lookup) {
+	d := l.Document
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintf(w, "ID:\t%s\n", d.ID)
+	_, _ = fmt.Fprintf(w, "Title:\t%s\n", d.SemanticID)
+	if d.Link != "" {
+		_, _ = fmt.Fprintf(w, "Link:\t%s\n", d.Link)
+	}
+	_, _ = fmt.Fprintf(w, "Boost:\t%d\n", d.Boost)
+	_, _ = fmt.Fprintf(w, "Hidden:\t%t\n", d.Hidden)
+	_, _ = fmt.Fprintf(w, "Chunks:\t%s\n", docChunkCount(d.ChunkCount))
+	_, _ = fmt.Fprintf(w, "Updated at source:\t%s\n", docTime(d.DocUpdatedAt))
+	_, _ = fmt.Fprintf(w, "Last modified:\t%s\n", docTime(d.LastModified))
+	_, _ = fmt.Fprintf(w, "Last synced to Vespa:\t%s\n", docTime(d.LastSynced))
+	if len(l.DocumentSets) > 0 {
+		_, _ = fmt.Fprintf(w, "Document sets:\t%s\n", strings.Join(l.DocumentSets, ", "))
+	}
+	_ = w.Flush()
+
+	fmt.Println()
+	if len(l.Connectors) == 0 {
+		fmt.Println("Connectors: none")
+	} else {
+		w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "CC PAIR\tCONNECTOR\tSOURCE\tSTATUS\tACCESS\tINDEXED\tLAST INDEXED")
+		_, _ = fmt.Fprintln(w, "-------\t---------\t------\t------\t------\t-------\t------------")
+		for _, cc := range l.Connectors {
+			_, _ = fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%t\t%s\n",
+				cc.CCPairID, cc.Name, cc.Source, cc.Status, cc.AccessType, cc.HasBeenIndexed, docTime(cc.LastSuccessfulIndex))
+		}
+		_ = w.Flush()
+	}
+
+	fmt.Println()
+	fmt.Printf("Access list (%d):\n", len(l.ACL))
+	for _, entry := range l.ACL {
+		fmt.Printf("  %s\n", entry)
+	}
+
+	fmt.Println()
+	switch {
+	case l.IndexError != "":
+		fmt.Printf("Vespa: could not fetch chunks: %s\n", l.IndexError)
+	case len(l.Chunks) == 0:
+		fmt.Println("Vespa: no chunks")
+	default:
+		w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "CHUNK\tBOOST\tHIDDEN\tACL\tCHARS\tUPDATED")
+		_, _ = fmt.Fprintln(w, "-----\t-----\t------\t---\t-----\t-------")
+		for _, ch := range l.Chunks {
+			_, _ = fmt.Fprintf(w, "%d\t%g\t%t\t%d entries\t%d\t%s\n",
+				ch.ChunkID, ch.Boost, ch.Hidden, len(ch.ACL), ch.ContentChars, docTime(ch.UpdatedAt))
+		}
+		_ = w.Flush()
+	}
+
+	fmt.Println()
+	findings := l.Diagnose()
+	if len(findings) == 0 {
+		fmt.Println("Nothing stands out: the document is indexed, in sync and visible to its access list.")
+		return
+	}
+	fmt.Println("Findings:")
+	for _, f := range findings {
+		fmt.Printf("  - %s\n", f)
+	}
+}
+
+func docChunkCount(n *int) string {
+	if n == nil {
+		return "unknown"
+	}
+	return fmt.Sprint(*n)
+}
+
+func docTime(t *time.Time) string {
+	if t == nil {
+		return "-"
+	}
+	return t.Local().Format("2006-01-02 15:04:05 MST")
+}
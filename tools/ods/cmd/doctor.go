@@ -0,0 +1,151 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"text/tabwriter"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/paths"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/runner"
+)
+
+// doctorResult is the outcome of one environment check.
+type doctorResult struct {
+	name   string
+	status string // "ok", "warn" or "fail"
+	detail string
+}
+
+// NewDoctorCommand creates the doctor command.
+func NewDoctorCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "doctor",
+		Short: "Check that the tools ods relies on are installed and working",
+		Long: `Check that the tools ods relies on are installed and working: git, Docker
+and Docker Compose, plus the optional kubectl, aws, gh and bun.
+
+Under WSL it also checks that Docker Desktop's WSL integration is enabled for
+this distro, that the checkout is not on a mounted Windows drive, and that git
+is not converting shell scripts to CRLF.
+
+Exits non-zero if a required check fails.
+
+Examples:
+  ods doctor`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			runDoctor()
+		},
+	}
+}
+
+func runDoctor() {
+	wsl := paths.IsWSL()
+	platform := runtime.GOOS + "/" + runtime.GOARCH
+	if wsl {
+		platform += " (WSL)"
+	}
+	fmt.Printf("Platform: %s\n\n", platform)
+
+	r := runner.Default
+	results := []doctorResult{checkGit()}
+	if wsl {
+		results = append(results, checkWSLCheckout(), checkAutoCRLF(r))
+	}
+	results = append(results, checkDocker(r, wsl)...)
+	for _, tool := range []string{"kubectl", "aws", "gh", "bun"} {
+		results = append(results, checkOptionalTool(tool))
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	failed := 0
+	for _, r := range results {
+		if r.status == "fail" {
+			failed++
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", strings.ToUpper(r.status), r.name, r.detail)
+	}
+	_ = w.Flush()
+
+	if failed > 0 {
+		fmt.Println()
+		log.Fatalf("%d check(s) failed", failed)
+	}
+}
+
+func checkGit() doctorResult {
+	root, err := paths.GitRoot()
+	if err != nil {
+		return doctorResult{"git", "fail", "not in an Onyx checkout, or git is not installed"}
+	}
+	return doctorResult{"git", "ok", root}
+}
+
+func checkWSLCheckout() doctorResult {
+	root, err := paths.GitRoot()
+	if err == nil && paths.OnWindowsDrive(root) {
+		return doctorResult{"checkout", "warn", "on a Windows drive; clone into the Linux filesystem (e.g. ~/onyx) for fast bind mounts and hot reload"}
+	}
+	return doctorResult{"checkout", "ok", "on the Linux filesystem"}
+}
+
+func checkAutoCRLF(r runner.Runner) doctorResult {
+	out, _ := runner.Output(r, runner.Cmd{Name: "git", Args: []string{"config", "--get", "core.autocrlf"}})
+	if strings.TrimSpace(string(out)) == "true" {
+		return doctorResult{"line endings", "warn", "core.autocrlf=true checks scripts out with CRLF, which breaks them in containers; run: git config core.autocrlf input"}
+	}
+	return doctorResult{"line endings", "ok", "git keeps LF line endings"}
+}
+
+func checkDocker(r runner.Runner, wsl bool) []doctorResult {
+	docker := paths.Executable("docker")
+	if _, err := exec.LookPath(docker); err != nil {
+		detail := "docker is not on PATH; install Docker Desktop or Docker Engine"
+		if wsl {
+			detail = "docker is not on PATH; enable WSL integration for this distro in Docker Desktop (Settings > Resources > WSL integration)"
+		}
+		return []doctorResult{{"docker", "fail", detail}}
+	}
+
+	var results []doctorResult
+	if wsl && strings.HasSuffix(strings.ToLower(filepath.Base(docker)), ".exe") {
+		results = append(results, doctorResult{"docker", "warn", "using the Windows docker.exe; enable WSL integration for this distro in Docker Desktop so bind mounts use Linux paths"})
+	}
+	return append(results, checkDockerDaemon(r, wsl)...)
+}
+
+// checkDockerDaemon checks that the daemon answers and the compose plugin is
+// installed.
+func checkDockerDaemon(r runner.Runner, wsl bool) []doctorResult {
+	var results []doctorResult
+	out, err := runner.Output(r, runner.Cmd{Name: "docker", Args: []string{"info", "--format", "{{.ServerVersion}}"}})
+	if err != nil {
+		detail := "the Docker daemon is not reachable; start Docker"
+		if wsl {
+			detail += " and check Docker Desktop's WSL integration for this distro"
+		}
+		return append(results, doctorResult{"docker daemon", "fail", detail})
+	}
+	results = append(results, doctorResult{"docker daemon", "ok", "server " + strings.TrimSpace(string(out))})
+
+	out, err = runner.Output(r, runner.Cmd{Name: "docker", Args: []string{"compose", "version", "--short"}})
+	if err != nil {
+		return append(results, doctorResult{"docker compose", "fail", "the compose plugin is not installed"})
+	}
+	return append(results, doctorResult{"docker compose", "ok", strings.TrimSpace(string(out))})
+}
+
+func checkOptionalTool(name string) doctorResult {
+	path, err := exec.LookPath(paths.Executable(name))
+	if err != nil {
+		return doctorResult{name, "warn", "not on PATH (only needed for some commands)"}
+	}
+	return doctorResult{name, "ok", path}
+}
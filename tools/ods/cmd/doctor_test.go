@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/runner"
+)
+
+func TestCheckDockerDaemon(t *testing.T) {
+	f := (&runner.Fake{}).
+		On("docker info", runner.Response{Stdout: "27.3.1\n"}).
+		On("docker compose version --short", runner.Response{Err: errors.New("exit status 1")})
+	got := checkDockerDaemon(f, false)
+	want := []doctorResult{
+		{"docker daemon", "ok", "server 27.3.1"},
+		{"docker compose", "fail", "the compose plugin is not installed"},
+	}
+	if len(got) != len(want) || got[0] != want[0] || got[1] != want[1] {
+		t.Errorf("checkDockerDaemon() = %+v, want %+v", got, want)
+	}
+
+	down := (&runner.Fake{}).On("docker info", runner.Response{Err: errors.New("exit status 1")})
+	if got := checkDockerDaemon(down, true); len(got) != 1 || got[0].status != "fail" || len(down.Calls()) != 1 {
+		t.Errorf("expected a single failure when the daemon is down, got %+v", got)
+	}
+}
+
+func TestCheckAutoCRLF(t *testing.T) {
+	f := (&runner.Fake{}).On("git config --get core.autocrlf", runner.Response{Stdout: "true\n"})
+	if got := checkAutoCRLF(f); got.status != "warn" {
+		t.Errorf("expected autocrlf=true to warn, got %+v", got)
+	}
+	if got := checkAutoCRLF(&runner.Fake{}); got.status != "ok" {
+		t.Errorf("expected an unset autocrlf to pass, got %+v", got)
+	}
+}
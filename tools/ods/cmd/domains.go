@@ -0,0 +1,381 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/auditlog"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/domains"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/kube"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/prompt"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/render"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/report"
+)
+
+// DomainsOptions holds options shared by the domains subcommands.
+type DomainsOptions struct {
+	Context string
+	Tenant  string
+}
+
+// NewDomainsCommand creates the parent domains command.
+func NewDomainsCommand() *cobra.Command {
+	opts := &DomainsOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "domains",
+		Short: "List custom domains and check their DNS and certificates",
+		Long: `List the hosts the cluster's ingresses serve, tenants' custom domains among
+them, check their DNS records and certificates, and have cert-manager issue
+certificates again.
+
+A custom domain belongs to the tenant in its ingress's ` + domains.TenantLabel + `
+label; hosts without one are the deployment's own.
+
+Requires: AWS SSO login, kubectl access to the EKS cluster.`,
+	}
+
+	cmd.PersistentFlags().StringVarP(&opts.Context, "context", "c", "data_plane", "cluster context name (maps to KUBE_CTX_<NAME> env var)")
+	cmd.PersistentFlags().StringVar(&opts.Tenant, "tenant", "", "Only the custom domains of this tenant")
+
+	cmd.AddCommand(newDomainsListCommand(opts))
+	cmd.AddCommand(newDomainsCheckCommand(opts))
+	cmd.AddCommand(newDomainsRenewCommand(opts))
+
+	return cmd
+}
+
+func newDomainsListCommand(opts *DomainsOptions) *cobra.Command {
+	var jsonOut bool
+
+	cmd := &cobra.Command{
+		Use:   "list",
+		Short: "List the served hosts with their certificate's expiry",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			runDomainsList(opts, jsonOut)
+		},
+	}
+
+	cmd.Flags().BoolVar(&jsonOut, "json", false, "Print the domains as JSON")
+
+	return cmd
+}
+
+func newDomainsCheckCommand(opts *DomainsOptions) *cobra.Command {
+	var within string
+	var timeout time.Duration
+	var jsonOut bool
+
+	cmd := &cobra.Command{
+		Use:   "check [host...]",
+		Short: "Check the DNS records and certificates of the served hosts",
+		Long: `Check the DNS records and certificates of the served hosts, or only the named ones.
+
+For each host:
+  - the certificate in its TLS secret is read and checked for expiry and
+    whether it covers the host;
+  - the cert-manager Certificate writing that secret is checked for
+    readiness and failed issuances;
+  - the host is resolved and compared with the ingress's load balancer,
+    since a record pointing elsewhere breaks both users and HTTP-01
+    renewals;
+  - https://<host> is fetched to see the certificate actually served, which
+    catches an ingress controller still serving an old or fallback one.
+
+Exits non-zero when anything is found, so the check can run on a schedule.
+
+Examples:
+  ods domains check
+  ods domains check --tenant tenant_abcd1234 --within 30d
+  ods domains check onyx.acme.com --json`,
+		Run: func(cmd *cobra.Command, args []string) {
+			runDomainsCheck(opts, args, within, timeout, jsonOut)
+		},
+	}
+
+	cmd.Flags().StringVar(&within, "within", "21d", "Report certificates expiring within this long, e.g. 30d")
+	cmd.Flags().DurationVar(&timeout, "timeout", 5*time.Second, "Timeout for each DNS lookup and TLS connection")
+	cmd.Flags().BoolVar(&jsonOut, "json", false, "Print the domains as JSON")
+
+	return cmd
+}
+
+func newDomainsRenewCommand(opts *DomainsOptions) *cobra.Command {
+	var yes bool
+
+	cmd := &cobra.Command{
+		Use:   "renew <host>...",
+		Short: "Have cert-manager issue the certificate of hosts again",
+		Long: `Have cert-manager issue the certificate of the named hosts again now, the way
+cmctl renew does, instead of waiting for its renewal time. The current
+certificate keeps being served until the new one is issued. Follow the
+issuance with ods domains check <host>.
+
+Examples:
+  ods domains renew onyx.acme.com
+  ods domains renew onyx.acme.com docs.acme.com -c data_plane --yes`,
+		Args: cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			runDomainsRenew(opts, args, yes)
+		},
+	}
+
+	cmd.Flags().BoolVar(&yes, "yes", false, "Skip the confirmation prompt")
+
+	return cmd
+}
+
+// loadDomains fetches the ingresses and cert-manager Certificates and
+// returns the domains opts and hosts select.
+func loadDomains(opts *DomainsOptions, hosts []string) (*kube.Cluster, []domains.Domain) {
+	if opts.Tenant != "" {
+		validateTenantArg(opts.Tenant)
+	}
+	c := clusterFromEnv(opts.Context)
+	if err := c.EnsureContext(); err != nil {
+		log.Fatalf("Failed to ensure cluster context: %v", err)
+	}
+
+	ingresses, err := c.ListIngresses()
+	if err != nil {
+		log.Fatalf("Failed to list ingresses: %v", err)
+	}
+	certs, err := c.ListCertificates()
+	if err != nil {
+		log.Debugf("Not reading cert-manager Certificates: %v", err)
+		certs = nil
+	}
+
+	wanted := map[string]bool{}
+	for _, h := range hosts {
+		wanted[strings.ToLower(strings.TrimSuffix(h, "."))] = true
+	}
+	var selected []domains.Domain
+	for _, d := range domains.Collect(ingresses, certs) {
+		if opts.Tenant != "" && d.Tenant != opts.Tenant {
+			continue
+		}
+		if len(wanted) > 0 && !wanted[strings.ToLower(d.Host)] {
+			continue
+		}
+		delete(wanted, strings.ToLower(d.Host))
+		selected = append(selected, d)
+	}
+	for h := range wanted {
+		log.Fatalf("No ingress serves %s", h)
+	}
+	return c, selected
+}
+
+// loadStoredCerts reads the certificate in each domain's TLS secret.
+func loadStoredCerts(c *kube.Cluster, list []domains.Domain) {
+	secrets := map[string]*domains.Cert{}
+	for i := range list {
+		name := list[i].SecretName
+		if name == "" {
+			continue
+		}
+		if _, ok := secrets[name]; !ok {
+			secret, err := c.GetSecret(name)
+			if err != nil {
+				secrets[name] = &domains.Cert{Error: fmt.Sprintf("secret %s could not be read", name)}
+				log.Debugf("Failed to read secret %s: %v", name, err)
+			} else {
+				secrets[name] = domains.ParseStored(secret)
+			}
+		}
+		list[i].Stored = secrets[name]
+	}
+}
+
+func runDomainsList(opts *DomainsOptions, jsonOut bool) {
+	c, list := loadDomains(opts, nil)
+	loadStoredCerts(c, list)
+
+	if jsonOut {
+		printDomainsJSON(os.Stdout, list)
+		return
+	}
+	if len(list) == 0 {
+		log.Info("No ingress hosts")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "HOST\tTENANT\tINGRESS\tCERTIFICATE\tEXPIRES")
+	for _, d := range list {
+		tenant := d.Tenant
+		if tenant == "" {
+			tenant = "-"
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", d.Host, tenant, d.Ingress, domainCertState(&d), domainExpiry(&d))
+	}
+	_ = w.Flush()
+}
+
+func runDomainsCheck(opts *DomainsOptions, hosts []string, within string, timeout time.Duration, jsonOut bool) {
+	withinDur, err := report.ParseLookback(within)
+	if err != nil {
+		log.Fatalf("Invalid --within: %v", err)
+	}
+	if timeout <= 0 {
+		log.Fatal("--timeout must be positive")
+	}
+
+	c, list := loadDomains(opts, hosts)
+	if len(list) == 0 {
+		log.Info("No ingress hosts")
+		return
+	}
+	loadStoredCerts(c, list)
+
+	log.Infof("Checking DNS and served certificates of %d host(s)...", len(list))
+	var wg sync.WaitGroup
+	for i := range list {
+		wg.Add(1)
+		go func(d *domains.Domain) {
+			defer wg.Done()
+			ctx, cancel := context.WithTimeout(context.Background(), timeout)
+			defer cancel()
+			d.DNS = domains.Resolve(ctx, net.DefaultResolver, d.Host, d.LoadBalancer)
+			if d.SecretName != "" && d.DNS.Error == "" {
+				d.Served = domains.FetchServed(d.Host, timeout)
+			}
+		}(&list[i])
+	}
+	wg.Wait()
+
+	if jsonOut {
+		printDomainsJSON(os.Stdout, list)
+	}
+
+	now := time.Now()
+	found := 0
+	for i := range list {
+		d := &list[i]
+		findings := d.Findings(now, withinDur)
+		found += len(findings)
+		if jsonOut {
+			continue
+		}
+		printDomain(d)
+		printFindings(findings)
+	}
+	if found > 0 {
+		exitWith(1)
+	}
+}
+
+func runDomainsRenew(opts *DomainsOptions, hosts []string, yes bool) {
+	c, list := loadDomains(opts, hosts)
+	auditCtx := c.Name + "/" + c.Namespace
+
+	// Hosts sharing a certificate renew it once.
+	var certs []string
+	seen := map[string]bool{}
+	for _, d := range list {
+		if d.CertManager == nil {
+			log.Fatalf("%s is not served with a cert-manager Certificate, so it cannot be renewed here", d.Host)
+		}
+		if !seen[d.CertManager.Name] {
+			seen[d.CertManager.Name] = true
+			certs = append(certs, d.CertManager.Name)
+		}
+	}
+
+	if !yes && isProductionContext(opts.Context) {
+		if !prompt.Confirm(fmt.Sprintf("Re-issue certificate(s) %s in %s? (yes/no): ", strings.Join(certs, ", "), auditCtx)) {
+			log.Info("Aborted.")
+			return
+		}
+	}
+
+	for _, name := range certs {
+		if err := auditlog.Record(auditlog.Entry{
+			Action:  "domains.renew",
+			Context: auditCtx,
+			Target:  name,
+			Detail:  strings.Join(hosts, ","),
+		}); err != nil {
+			log.Fatalf("Refusing to renew a certificate without an audit record: %v", err)
+		}
+		if err := c.RenewCertificate(name); err != nil {
+			log.Fatalf("Failed to renew %s: %v", name, err)
+		}
+		log.Infof("Triggered re-issuance of Certificate %s", name)
+	}
+	log.Infof("Follow it with: ods domains check -c %s %s", opts.Context, strings.Join(hosts, " "))
+}
+
+func printDomainsJSON(w io.Writer, list []domains.Domain) {
+	if list == nil {
+		list = []domains.Domain{}
+	}
+	if err := render.JSON(w, list); err != nil {
+		log.Fatalf("Failed to marshal domains: %v", err)
+	}
+}
+
+func printDomain(d *domains.Domain) {
+	fmt.Printf("\n%s (ingress %s", d.Host, d.Ingress)
+	if d.Tenant != "" {
+		fmt.Printf(", tenant %s", d.Tenant)
+	}
+	fmt.Println(")")
+	if dns := d.DNS; dns != nil && dns.Error == "" {
+		target := strings.Join(dns.Addresses, ", ")
+		if dns.CNAME != "" {
+			target = dns.CNAME + " -> " + target
+		}
+		fmt.Printf("  DNS:              %s\n", target)
+	}
+	if len(d.LoadBalancer) > 0 {
+		fmt.Printf("  Load balancer:    %s\n", strings.Join(d.LoadBalancer, ", "))
+	}
+	if d.SecretName != "" {
+		fmt.Printf("  TLS secret:       %s\n", d.SecretName)
+	}
+	if cm := d.CertManager; cm != nil {
+		fmt.Printf("  Certificate:      %s (%s), %s\n", cm.Name, cm.Issuer, domainCertState(d))
+		if !cm.RenewalTime.IsZero() {
+			fmt.Printf("  Renews:           %s\n", cm.RenewalTime.Local().Format("2006-01-02 15:04"))
+		}
+	}
+	if c := d.Stored; c != nil && c.Error == "" {
+		fmt.Printf("  Expires:          %s (issuer %s)\n", c.NotAfter.Local().Format("2006-01-02 15:04"), c.Issuer)
+	}
+}
+
+func domainCertState(d *domains.Domain) string {
+	switch cm := d.CertManager; {
+	case d.SecretName == "":
+		return "none"
+	case cm == nil:
+		return "unmanaged"
+	case cm.Issuing:
+		return "issuing"
+	case cm.Ready:
+		return "ready"
+	}
+	return "not ready"
+}
+
+func domainExpiry(d *domains.Domain) string {
+	if d.Stored != nil && d.Stored.Error != "" {
+		return "unreadable"
+	}
+	if t := d.Expires(); !t.IsZero() {
+		return t.Local().Format("2006-01-02")
+	}
+	return "-"
+}
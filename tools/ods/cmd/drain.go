@@ -0,0 +1,321 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/auditlog"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/drain"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/kube"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/prompt"
+)
+
+// DrainOptions holds options shared by the drain subcommands.
+type DrainOptions struct {
+	Context string
+}
+
+// DrainWorkerOptions holds options for the drain worker command.
+type DrainWorkerOptions struct {
+	Timeout  time.Duration
+	Interval time.Duration
+	Then     string
+	Resume   bool
+	Yes      bool
+	Notify   string
+}
+
+// NewDrainCommand creates the parent drain command.
+func NewDrainCommand() *cobra.Command {
+	opts := &DrainOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "drain",
+		Short: "Take work off pods before maintenance",
+		Long: `Take work off pods before maintenance, so that restarting them does not
+kill work in flight.
+
+Requires: AWS SSO login, kubectl access to the EKS cluster.`,
+	}
+
+	cmd.PersistentFlags().StringVarP(&opts.Context, "context", "c", "data_plane", "cluster context name (maps to KUBE_CTX_<NAME> env var)")
+
+	cmd.AddCommand(newDrainWorkerCommand(opts))
+
+	return cmd
+}
+
+func newDrainWorkerCommand(parent *DrainOptions) *cobra.Command {
+	opts := &DrainWorkerOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "worker <pod|deployment>",
+		Short: "Stop Celery workers taking tasks and wait for running ones",
+		Long: `Stop Celery workers taking new tasks and wait for their running tasks.
+
+Cancels every queue the Celery workers of the pod (or of every pod of the
+deployment, e.g. celery-worker-docfetching) consume, so the broker hands
+their work to other replicas, then lists the tasks they are still running or
+hold prefetched until none are left. A restart at that point interrupts no
+index attempt. The drained queues are recorded on each pod, so --resume can
+put the workers back to work if the maintenance is called off.
+
+With --then restart, a drained pod is deleted (its deployment replaces it),
+and a drained deployment is rollout-restarted and watched as ods restart
+does. Without it the pods stay drained, taking no tasks, until restarted or
+resumed.
+
+If tasks are still running after --timeout the command fails and leaves the
+pods drained; run it again to keep waiting.
+
+Asks for confirmation on production contexts unless --yes is passed, and
+records the drain in the local audit log.
+
+Examples:
+  ods drain worker celery-worker-docprocessing-6d9f7c8b4-x2x7q
+  ods drain worker celery-worker-docfetching -c prod --then restart
+  ods drain worker celery-worker-docfetching --timeout 2h --notify slack:#oncall
+  ods drain worker celery-worker-docfetching --resume`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			runDrainWorker(parent, opts, args[0])
+		},
+	}
+
+	cmd.Flags().DurationVar(&opts.Timeout, "timeout", 30*time.Minute, "How long to wait for running tasks")
+	cmd.Flags().DurationVar(&opts.Interval, "interval", 15*time.Second, "How often to list running tasks")
+	cmd.Flags().StringVar(&opts.Then, "then", "", "What to do once drained: restart (default: leave the pods drained)")
+	cmd.Flags().BoolVar(&opts.Resume, "resume", false, "Put drained workers back to work instead")
+	cmd.Flags().BoolVar(&opts.Yes, "yes", false, "Skip the confirmation prompt")
+	addNotifyFlag(cmd, &opts.Notify)
+
+	return cmd
+}
+
+func runDrainWorker(parent *DrainOptions, opts *DrainWorkerOptions, target string) {
+	if opts.Then != "" && opts.Then != "restart" {
+		log.Fatalf("Unknown --then %q; expected restart", opts.Then)
+	}
+	if opts.Resume && opts.Then != "" {
+		log.Fatal("--then cannot be combined with --resume")
+	}
+	if opts.Interval < time.Second {
+		log.Fatal("--interval must be at least 1s")
+	}
+	if !strings.Contains(target, "celery-worker") {
+		log.Fatalf("%s is not a Celery worker; expected a celery-worker-* pod or deployment", target)
+	}
+
+	c := clusterFromEnv(parent.Context)
+	if err := c.EnsureContext(); err != nil {
+		log.Fatalf("Failed to ensure cluster context: %v", err)
+	}
+	auditCtx := c.Name + "/" + c.Namespace
+
+	pods, deployment := drainTargets(c, target)
+	if opts.Resume {
+		resumeWorkers(c, auditCtx, target, pods)
+		return
+	}
+
+	fmt.Printf("Draining in %s:\n", auditCtx)
+	for _, p := range pods {
+		fmt.Printf("  %s\n", p.Name)
+	}
+	if !opts.Yes && isProductionContext(parent.Context) {
+		if !prompt.Confirm(fmt.Sprintf("Stop %d pod(s) in %s taking new tasks? (yes/no): ", len(pods), auditCtx)) {
+			log.Info("Aborted.")
+			return
+		}
+	}
+
+	notifier := startNotifier(opts.Notify)
+
+	if err := auditlog.Record(auditlog.Entry{
+		Action:  "drain.worker",
+		Context: auditCtx,
+		Target:  target,
+		Detail:  opts.Then,
+	}); err != nil {
+		log.Fatalf("Refusing to drain without an audit record: %v", err)
+	}
+
+	for _, p := range pods {
+		queues := p.Annotations[drain.QueuesAnnotation]
+		s, err := drain.Stop(c, p.Name)
+		if err != nil {
+			log.Fatalf("Failed to drain %s: %v", p.Name, err)
+		}
+		// Draining a drained pod finds no queues; keep the ones recorded
+		// the first time.
+		if len(s.Queues) > 0 {
+			queues = strings.Join(s.Queues, ",")
+			if err := c.AnnotatePod(p.Name, drain.QueuesAnnotation, queues); err != nil {
+				log.Fatalf("Failed to record the drained queues of %s: %v", p.Name, err)
+			}
+		}
+		log.Infof("%s: %s stopped taking %s", p.Name, strings.Join(s.Workers, ", "), queues)
+	}
+
+	start := time.Now()
+	if err := waitForDrain(c, pods, opts.Timeout, opts.Interval); err != nil {
+		log.Fatalf("%v; the pods stay drained (ods drain worker %s --resume to undo)", err, target)
+	}
+	log.Infof("%d pod(s) drained in %s", len(pods), formatElapsed(time.Since(start)))
+
+	if opts.Then == "restart" {
+		restartDrained(c, pods, deployment)
+	}
+	notifier.Done(fmt.Sprintf("Drained %s in %s", target, auditCtx))
+}
+
+// drainTargets resolves target to a pod, or to the pods of a deployment
+// together with the deployment's name.
+func drainTargets(c *kube.Cluster, target string) ([]*kube.Pod, string) {
+	all, err := c.ListPods()
+	if err != nil {
+		log.Fatalf("Failed to list pods: %v", err)
+	}
+	for _, p := range all {
+		if p.Name == target {
+			return []*kube.Pod{p}, ""
+		}
+	}
+
+	deployments, err := c.ListDeployments()
+	if err != nil {
+		log.Fatalf("Failed to list deployments: %v", err)
+	}
+	names := make([]string, 0, len(deployments))
+	for _, d := range deployments {
+		names = append(names, d.Name)
+	}
+	name, err := resolveDeployment(names, target)
+	if err != nil {
+		log.Fatalf("%s is neither a pod nor a deployment: %v", target, err)
+	}
+	var pods []*kube.Pod
+	for _, p := range all {
+		if podDeployment(p.Name, []string{name}) != "" && p.Phase == "Running" {
+			pods = append(pods, p)
+		}
+	}
+	if len(pods) == 0 {
+		log.Fatalf("%s has no running pods", name)
+	}
+	return pods, name
+}
+
+// waitForDrain lists the tasks the pods' workers still hold until there
+// are none, printing the list whenever it changes.
+func waitForDrain(c *kube.Cluster, pods []*kube.Pod, timeout, interval time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	last := ""
+	for {
+		var tasks []drain.Task
+		reserved := 0
+		for _, p := range pods {
+			s, err := drain.Status(c, p.Name)
+			if err != nil {
+				return fmt.Errorf("failed to list the tasks of %s: %w", p.Name, err)
+			}
+			tasks = append(tasks, s.Active...)
+			tasks = append(tasks, s.Reserved...)
+			reserved += len(s.Reserved)
+		}
+		if len(tasks) == 0 {
+			return nil
+		}
+
+		ids := make([]string, len(tasks))
+		for i, t := range tasks {
+			ids[i] = t.ID
+		}
+		if key := strings.Join(ids, ","); key != last {
+			last = key
+			printDrainTasks(tasks, reserved)
+		}
+
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%d task(s) still running after %s", len(tasks), timeout)
+		}
+		time.Sleep(interval)
+	}
+}
+
+func printDrainTasks(tasks []drain.Task, reserved int) {
+	fmt.Printf("\n[%s] waiting for %d task(s), %d not started yet:\n", time.Now().Format("15:04:05"), len(tasks), reserved)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "WORKER\tTASK\tID\tRUNNING")
+	for _, t := range tasks {
+		running := "-"
+		if t.RuntimeSeconds > 0 {
+			running = formatElapsed(time.Duration(t.RuntimeSeconds) * time.Second)
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", t.Worker, t.Name, t.ID, running)
+	}
+	_ = w.Flush()
+}
+
+// restartDrained replaces drained pods: the whole deployment when one was
+// drained, otherwise each pod.
+func restartDrained(c *kube.Cluster, pods []*kube.Pod, deployment string) {
+	if deployment == "" {
+		for _, p := range pods {
+			if err := c.DeletePod(p.Name); err != nil {
+				log.Fatalf("Failed to delete %s: %v", p.Name, err)
+			}
+			log.Infof("Deleted %s; its deployment will replace it", p.Name)
+		}
+		return
+	}
+
+	oldPods := make(map[string]bool, len(pods))
+	for _, p := range pods {
+		oldPods[p.Name] = true
+	}
+	if err := c.RolloutRestart(deployment); err != nil {
+		log.Fatalf("Failed to restart %s: %v", deployment, err)
+	}
+	log.Infof("Restarting %s, watching rollout...", deployment)
+	if err := watchRollout(c, []string{deployment}, oldPods, 10*time.Minute); err != nil {
+		log.Fatalf("%v", err)
+	}
+	log.Infof("%s rolled out", deployment)
+}
+
+// resumeWorkers adds back the queues recorded on drained pods.
+func resumeWorkers(c *kube.Cluster, auditCtx, target string, pods []*kube.Pod) {
+	if err := auditlog.Record(auditlog.Entry{
+		Action:  "drain.resume",
+		Context: auditCtx,
+		Target:  target,
+	}); err != nil {
+		log.Fatalf("Refusing to resume without an audit record: %v", err)
+	}
+
+	resumed := 0
+	for _, p := range pods {
+		recorded := p.Annotations[drain.QueuesAnnotation]
+		if recorded == "" {
+			log.Infof("%s is not drained", p.Name)
+			continue
+		}
+		s, err := drain.Resume(c, p.Name, strings.Split(recorded, ","))
+		if err != nil {
+			log.Fatalf("Failed to resume %s: %v", p.Name, err)
+		}
+		if err := c.AnnotatePod(p.Name, drain.QueuesAnnotation, ""); err != nil {
+			log.Warnf("Failed to clear the drained queues of %s: %v", p.Name, err)
+		}
+		log.Infof("%s: %s taking %s again", p.Name, strings.Join(s.Workers, ", "), strings.Join(s.Queues, ", "))
+		resumed++
+	}
+	log.Infof("Resumed %d pod(s)", resumed)
+}
@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/auditlog"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/embeddings"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/kube"
+)
+
+// EmbeddingsOptions holds options shared by the embeddings subcommands.
+type EmbeddingsOptions struct {
+	Context string
+}
+
+// EmbeddingsExportOptions holds options for the embeddings export command.
+type EmbeddingsExportOptions struct {
+	Tenant string
+	Sample int
+	Format string
+	UMAP   bool
+	Output string
+}
+
+// NewEmbeddingsCommand creates the parent embeddings command.
+func NewEmbeddingsCommand() *cobra.Command {
+	opts := &EmbeddingsOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "embeddings",
+		Short: "Work with the embedding vectors stored in Vespa",
+		Long: `Work with the embedding vectors stored in Vespa.
+
+Requires: AWS SSO login, kubectl access to the EKS cluster.`,
+	}
+
+	cmd.PersistentFlags().StringVarP(&opts.Context, "context", "c", "data_plane", "cluster context name (maps to KUBE_CTX_<NAME> env var)")
+
+	cmd.AddCommand(newEmbeddingsExportCommand(opts))
+
+	return cmd
+}
+
+func newEmbeddingsExportCommand(parent *EmbeddingsOptions) *cobra.Command {
+	opts := &EmbeddingsExportOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "export",
+		Short: "Export a sample of a tenant's chunk embeddings",
+		Long: `Export a sample of a tenant's chunk embeddings for offline analysis.
+
+Visits the IDs of every chunk Vespa holds for the tenant (large chunks
+excluded), picks --sample of them uniformly at random, and fetches their
+full-chunk embeddings. Each row has document_id, chunk_id, source_type and
+embedding; --umap adds umap_x and umap_y, a 2D UMAP projection (cosine
+metric) of the sampled vectors for plotting.
+
+The file is written on an api-server pod and copied to --output (default:
+embeddings-<tenant>-<yyyymmdd-hhmm>.<format> in the current directory).
+parquet needs pyarrow and --umap needs umap-learn in the pod's environment;
+where they are missing, use --format jsonl and project the vectors offline.
+
+Embeddings are derived from customer content: keep exports off shared
+drives and delete them when the investigation is done. Each export is
+recorded in the audit log.
+
+On a single-tenant deployment omit --tenant.
+
+Requires: AWS SSO login, kubectl access to the EKS cluster.
+
+Examples:
+  ods embeddings export --tenant tenant_abcd1234
+  ods embeddings export --tenant tenant_abcd1234 --sample 20000 --umap
+  ods embeddings export --tenant tenant_abcd1234 --format jsonl -o vectors.jsonl`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			runEmbeddingsExport(parent, opts)
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.Tenant, "tenant", "", "Tenant schema (omit on single-tenant deployments)")
+	cmd.Flags().IntVar(&opts.Sample, "sample", 5000, "Number of chunks to export, 0 for all")
+	cmd.Flags().StringVar(&opts.Format, "format", "parquet", "Output format: "+strings.Join(embeddings.Formats, ", "))
+	cmd.Flags().BoolVar(&opts.UMAP, "umap", false, "Add 2D UMAP coordinates to each row")
+	cmd.Flags().StringVarP(&opts.Output, "output", "o", "", "Local file to write (default: embeddings-<tenant>-<time>.<format>)")
+
+	return cmd
+}
+
+func runEmbeddingsExport(parent *EmbeddingsOptions, opts *EmbeddingsExportOptions) {
+	if opts.Tenant != "" {
+		validateTenantArg(opts.Tenant)
+	}
+	if opts.Sample < 0 {
+		log.Fatalf("--sample must be 0 or more")
+	}
+	c := clusterFromEnv(parent.Context)
+	if err := c.EnsureContext(); err != nil {
+		log.Fatalf("Failed to ensure cluster context: %v", err)
+	}
+	log.Info("Finding api-server pod...")
+	pod, err := c.FindPod("api-server")
+	if err != nil {
+		log.Fatalf("Failed to find api-server pod: %v", err)
+	}
+
+	target := opts.Tenant
+	if target == "" {
+		target = "public"
+	}
+	if err := auditlog.Record(auditlog.Entry{
+		Action:  "vespa.embeddings.export",
+		Context: c.Name + "/" + c.Namespace,
+		Target:  target,
+		Detail:  fmt.Sprintf("sample=%d format=%s", opts.Sample, opts.Format),
+	}); err != nil {
+		log.Fatalf("Refusing to export without an audit record: %v", err)
+	}
+
+	log.Infof("Exporting embeddings of %s (this visits every chunk of the tenant)...", target)
+	r, err := embeddings.Export(c, pod, embeddings.Options{
+		Schema: opts.Tenant,
+		Sample: opts.Sample,
+		Format: opts.Format,
+		UMAP:   opts.UMAP,
+	})
+	if err != nil {
+		log.Fatalf("Failed to export embeddings: %v", err)
+	}
+	output := opts.Output
+	if output == "" {
+		output = embeddings.FileName(opts.Tenant, opts.Format, time.Now())
+	}
+	err = saveEmbeddingsExport(c, pod, r.Output, output)
+	if _, rmErr := c.ExecOnPod(pod, "rm", "-f", r.Output); rmErr != nil {
+		log.Warnf("Failed to remove %s from %s: %v", r.Output, pod, rmErr)
+	}
+	if err != nil {
+		log.Fatalf("Failed to download the export: %v", err)
+	}
+
+	log.Infof("Wrote %s chunk(s) of %s (%d-dim %s, index %s) to %s",
+		formatCount(r.Rows), formatCount(r.Visited), r.Dimensions, r.ModelName, r.IndexName, output)
+}
+
+// saveEmbeddingsExport copies remote on pod to the new local file output,
+// removing it again if the copy fails.
+func saveEmbeddingsExport(c *kube.Cluster, pod, remote, output string) error {
+	f, err := os.OpenFile(output, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	err = c.CopyFromPod(pod, remote, f)
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		_ = os.Remove(output)
+	}
+	return err
+}
@@ -122,9 +122,14 @@ func setEnvValues(envPath string, values map[string]string) error {
 		return fmt.Errorf("read %s: %w", envPath, err)
 	}
 
+	// Keep Windows line endings if the file already uses them.
+	eol := "\n"
+	if strings.Contains(string(data), "\r\n") {
+		eol = "\r\n"
+	}
 	lines := []string{""}
 	if len(data) > 0 {
-		lines = strings.Split(string(data), "\n")
+		lines = strings.Split(string(data), eol)
 	}
 
 	remaining := make(map[string]string, len(values))
@@ -152,5 +157,5 @@ func setEnvValues(envPath string, values map[string]string) error {
 		}
 	}
 
-	return os.WriteFile(envPath, []byte(strings.Join(lines, "\n")), 0644)
+	return os.WriteFile(envPath, []byte(strings.Join(lines, eol)), 0644)
 }
@@ -178,3 +178,24 @@ func TestSetEnvValues_overwritesWithNewValue(t *testing.T) {
 		t.Errorf("expected exactly 1 PORT= line, got:\n%s", content)
 	}
 }
+
+func TestSetEnvValues_preservesCRLF(t *testing.T) {
+	dir := t.TempDir()
+	envPath := filepath.Join(dir, ".env")
+
+	if err := os.WriteFile(envPath, []byte("FOO=old\r\nOTHER=keep\r\n"), 0644); err != nil {
+		t.Fatalf("failed to write initial file: %v", err)
+	}
+	if err := setEnvValues(envPath, map[string]string{"FOO": "new", "BAR": "baz"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data, err := os.ReadFile(envPath)
+	if err != nil {
+		t.Fatalf("failed to read file: %v", err)
+	}
+	want := "FOO=new\r\nOTHER=keep\r\nBAR=baz\r\n"
+	if string(data) != want {
+		t.Errorf("expected %q, got %q", want, string(data))
+	}
+}
@@ -0,0 +1,273 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/api"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/eval"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/paths"
+)
+
+// EvalRunOptions holds options for the eval run command.
+type EvalRunOptions struct {
+	APISessionOptions
+	Dataset    string
+	K          []int
+	Persona    int
+	Parallel   int
+	NoAnswers  bool
+	JudgeModel string
+	JudgeURL   string
+	Baseline   string
+	MaxDrop    float64
+	Output     string
+}
+
+// NewEvalCommand creates the parent eval command.
+func NewEvalCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "eval",
+		Short: "Evaluate retrieval and answer quality against a labeled query set",
+		Long: `Evaluate retrieval and answer quality against a labeled query set.
+
+A dataset is a JSONL file with one case per line:
+
+  {"id": "pw-reset", "query": "How do I reset my password?",
+   "relevant_document_ids": ["https://docs.example.com/reset"],
+   "expected_answer": "Use the Forgot password link on the login page."}
+
+id is optional (default: the line number) but keeps cases comparable when
+the file is edited. Cases with relevant_document_ids are scored for
+retrieval, cases with expected_answer for answers; at least one is needed.
+Lines starting with # are ignored.`,
+	}
+
+	cmd.AddCommand(newEvalRunCommand())
+	cmd.AddCommand(newEvalDiffCommand())
+
+	return cmd
+}
+
+func newEvalRunCommand() *cobra.Command {
+	opts := &EvalRunOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "run --dataset <file>",
+		Short: "Run a dataset through search and chat and score it",
+		Long: `Run a dataset through search and chat and score it.
+
+Each case's query goes through the admin search; recall@k (for each --k) and
+the reciprocal rank of the first relevant document are computed from the
+returned document IDs. Cases with an expected answer are also asked in a new
+chat session (deleted afterwards), and an LLM judge grades the answer against
+the expected one as correct (1), partly correct (0.5) or wrong (0). The
+summary reports mean recall@k, MRR and mean answer score.
+
+The judge is any OpenAI-compatible chat completions API: --judge-url
+(default: OpenAI) with $ODS_JUDGE_API_KEY, or $OPENAI_API_KEY. --no-answers
+skips chat and the judge, scoring retrieval only.
+
+Every run is saved to ~/.local/share/onyx-dev/eval-runs/<env>-<time>.json (or
+--output). With --baseline, the run is compared with an earlier one: summary
+metric deltas and the cases that got worse. --max-drop makes the command exit
+non-zero when any summary metric falls by more than that, for release
+gating. Grades vary a little between runs; keep --max-drop above the noise.
+
+The server and auth are chosen as for ` + "`ods curl`" + `; the user needs admin
+search access.
+
+Examples:
+  ods eval run --dataset queries.jsonl -c staging
+  ods eval run --dataset queries.jsonl -c staging --baseline ~/.local/share/onyx-dev/eval-runs/staging-20261001-090000.json --max-drop 0.02
+  ods eval run --dataset queries.jsonl --no-answers --k 1,3,10`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			runEval(opts)
+		},
+	}
+
+	addAPISessionFlags(cmd, &opts.APISessionOptions)
+	cmd.Flags().StringVar(&opts.Dataset, "dataset", "", "JSONL file of labeled queries (required)")
+	cmd.Flags().IntSliceVar(&opts.K, "k", []int{1, 5, 10}, "Cutoffs to compute recall at")
+	cmd.Flags().IntVar(&opts.Persona, "persona", 0, "Persona (assistant) ID to chat with")
+	cmd.Flags().IntVar(&opts.Parallel, "parallel", 4, "Number of cases to run at once")
+	cmd.Flags().BoolVar(&opts.NoAnswers, "no-answers", false, "Score retrieval only; skip chat and the judge")
+	cmd.Flags().StringVar(&opts.JudgeModel, "judge-model", "gpt-4.1", "Model that grades answers")
+	cmd.Flags().StringVar(&opts.JudgeURL, "judge-url", eval.DefaultJudgeURL, "OpenAI-compatible API base URL of the judge")
+	cmd.Flags().StringVar(&opts.Baseline, "baseline", "", "Saved run to compare with")
+	cmd.Flags().Float64Var(&opts.MaxDrop, "max-drop", 0, "Exit non-zero if a summary metric falls by more than this vs --baseline (0: never)")
+	cmd.Flags().StringVarP(&opts.Output, "output", "o", "", "Where to save the run (default: the eval-runs directory)")
+	_ = cmd.MarkFlagRequired("dataset")
+
+	return cmd
+}
+
+func newEvalDiffCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "diff <baseline.json> <run.json>",
+		Short: "Compare two saved eval runs",
+		Long: `Compare two saved eval runs: summary metric deltas and the cases whose
+scores changed.
+
+Examples:
+  ods eval diff staging-20261001-090000.json staging-20261015-140200.json`,
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			baseline := loadEvalReport(args[0])
+			current := loadEvalReport(args[1])
+			printEvalDiff(eval.Compare(baseline, current), true)
+		},
+	}
+}
+
+func runEval(opts *EvalRunOptions) {
+	for _, k := range opts.K {
+		if k < 1 {
+			log.Fatalf("--k values must be positive, got %d", k)
+		}
+	}
+	cases, err := eval.LoadDataset(opts.Dataset)
+	if err != nil {
+		log.Fatalf("Failed to load dataset: %v", err)
+	}
+	var baseline *eval.Report
+	if opts.Baseline != "" {
+		baseline = loadEvalReport(opts.Baseline)
+	} else if opts.MaxDrop > 0 {
+		log.Fatal("--max-drop needs --baseline")
+	}
+
+	var judge *eval.Judge
+	if !opts.NoAnswers {
+		key := envOrDefault("ODS_JUDGE_API_KEY", os.Getenv("OPENAI_API_KEY"))
+		if key == "" && opts.JudgeURL == eval.DefaultJudgeURL {
+			log.Fatal("Set ODS_JUDGE_API_KEY or OPENAI_API_KEY for the judge, or pass --no-answers")
+		}
+		judge = eval.NewJudge(opts.JudgeURL, key, opts.JudgeModel)
+	}
+
+	session := openAPISession(&opts.APISessionOptions, "eval.impersonate")
+	defer session.Close()
+	log.Infof("Running %d case(s) from %s against %s", len(cases), opts.Dataset, session.Target)
+
+	done := 0
+	report := eval.Run(api.New(session.Client), cases, eval.Options{
+		K:        opts.K,
+		Persona:  opts.Persona,
+		Judge:    judge,
+		Parallel: opts.Parallel,
+		OnCase: func(res eval.CaseResult) {
+			done++
+			if res.Error != "" {
+				log.Warnf("[%d/%d] %s: %s", done, len(cases), res.ID, res.Error)
+				return
+			}
+			log.Debugf("[%d/%d] %s done", done, len(cases), res.ID)
+		},
+	})
+	report.Env = opts.Context
+	report.Dataset = opts.Dataset
+
+	output := opts.Output
+	if output == "" {
+		output = filepath.Join(paths.EvalRunsDir(), eval.FileName(report.Env, report.Start))
+	}
+	if err := report.Save(output); err != nil {
+		log.Fatalf("Failed to save the run: %v", err)
+	}
+
+	printEvalSummary(report)
+	log.Infof("Saved run to %s", output)
+
+	if baseline == nil {
+		return
+	}
+	diff := eval.Compare(baseline, report)
+	fmt.Printf("\nCompared with %s (%s):\n", opts.Baseline, baseline.Start.Format("2006-01-02 15:04"))
+	printEvalDiff(diff, false)
+	if drop, name := diff.WorstDrop(); opts.MaxDrop > 0 && drop > opts.MaxDrop {
+		session.Close()
+		log.Fatalf("%s fell by %.3f, more than --max-drop %.3f", name, drop, opts.MaxDrop)
+	}
+}
+
+func loadEvalReport(path string) *eval.Report {
+	r, err := eval.LoadReport(path)
+	if err != nil {
+		log.Fatalf("Failed to load run: %v", err)
+	}
+	return r
+}
+
+func printEvalSummary(r *eval.Report) {
+	s := r.Summary
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "METRIC\tVALUE\tCASES")
+	_, _ = fmt.Fprintln(w, "------\t-----\t-----")
+	if s.Retrieval > 0 {
+		for _, k := range r.K {
+			_, _ = fmt.Fprintf(w, "recall@%d\t%.3f\t%d\n", k, s.Recall[k], s.Retrieval)
+		}
+		_, _ = fmt.Fprintf(w, "MRR\t%.3f\t%d\n", s.MRR, s.Retrieval)
+	}
+	if s.Graded > 0 {
+		_, _ = fmt.Fprintf(w, "answer score\t%.3f\t%d\n", s.AnswerScore, s.Graded)
+	}
+	_ = w.Flush()
+	if s.Errors > 0 {
+		fmt.Printf("\n%d case(s) failed and are left out of the metrics they could not be scored for.\n", s.Errors)
+	}
+}
+
+// printEvalDiff prints metric deltas and changed cases; improvements are
+// only listed with all.
+func printEvalDiff(d *eval.Diff, all bool) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "METRIC\tBASELINE\tCURRENT\tDELTA")
+	_, _ = fmt.Fprintln(w, "------\t--------\t-------\t-----")
+	for _, m := range d.Metrics {
+		_, _ = fmt.Fprintf(w, "%s\t%.3f\t%.3f\t%+.3f\n", m.Name, m.Baseline, m.Current, m.Delta())
+	}
+	_ = w.Flush()
+
+	if d.Added > 0 || d.Removed > 0 {
+		fmt.Printf("\n%d case(s) only in the current run, %d only in the baseline.\n", d.Added, d.Removed)
+	}
+	printEvalChanges("Regressions", d.Regressions)
+	if all {
+		printEvalChanges("Improvements", d.Improvements)
+	} else if len(d.Improvements) > 0 {
+		fmt.Printf("\n%d case score(s) improved.\n", len(d.Improvements))
+	}
+}
+
+func printEvalChanges(title string, changes []eval.CaseChange) {
+	if len(changes) == 0 {
+		return
+	}
+	fmt.Printf("\n%s:\n", title)
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "  CASE\tMETRIC\tBASELINE\tCURRENT\tQUERY")
+	for _, c := range changes {
+		_, _ = fmt.Fprintf(w, "  %s\t%s\t%s\t%s\t%s\n", c.ID, c.Metric,
+			strconv.FormatFloat(c.Baseline, 'g', 3, 64), strconv.FormatFloat(c.Current, 'g', 3, 64), truncateQuery(c.Query))
+	}
+	_ = w.Flush()
+}
+
+func truncateQuery(q string) string {
+	q = strings.Join(strings.Fields(q), " ")
+	if len(q) <= 60 {
+		return q
+	}
+	return q[:59] + "…"
+}
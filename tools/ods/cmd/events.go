@@ -0,0 +1,242 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/kube"
+)
+
+// EventsOptions holds options for the events command.
+type EventsOptions struct {
+	Context string
+	Since   time.Duration
+	Pod     string
+}
+
+// eventExplanation is a human-readable reading of a warning event.
+type eventExplanation struct {
+	Summary string
+	Hint    string
+}
+
+// NewEventsCommand creates the events command for surfacing warning events.
+func NewEventsCommand() *cobra.Command {
+	opts := &EventsOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "events",
+		Short: "Summarise recent Kubernetes warning events",
+		Long: `Summarise recent Kubernetes warning events in the namespace.
+
+Warning events are grouped by the object they are about, translated into a
+short summary (crash loop, image pull failure, unschedulable, ...) and paired
+with a suggested next step. Containers whose last exit was an OOM kill are
+included even though Kubernetes doesn't emit an event for them.
+
+Requires: AWS SSO login, kubectl access to the EKS cluster.
+
+Examples:
+  ods events
+  ods events --since 15m
+  ods events --pod celery-worker-heavy -c data_plane_eu`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			runEvents(opts)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.Context, "context", "c", "data_plane", "cluster context name (maps to KUBE_CTX_<NAME> env var)")
+	cmd.Flags().DurationVar(&opts.Since, "since", time.Hour, "Only show events seen within this window")
+	cmd.Flags().StringVar(&opts.Pod, "pod", "", "Only show events for objects whose name contains this substring")
+
+	return cmd
+}
+
+func runEvents(opts *EventsOptions) {
+	c := clusterFromEnv(opts.Context)
+	if err := c.EnsureContext(); err != nil {
+		log.Fatalf("Failed to ensure cluster context: %v", err)
+	}
+
+	events, err := c.ListWarningEvents()
+	if err != nil {
+		log.Fatalf("Failed to list events: %v", err)
+	}
+	if pods, err := c.ListPods(); err != nil {
+		log.Debugf("Failed to list pods: %v", err)
+	} else {
+		events = append(events, oomKillEvents(pods)...)
+	}
+
+	cutoff := time.Now().Add(-opts.Since)
+	groups := groupEvents(events, func(e *kube.Event) bool {
+		// OOM kills synthesised from pod status carry no timestamp.
+		if !e.LastSeen.IsZero() && e.LastSeen.Before(cutoff) {
+			return false
+		}
+		return opts.Pod == "" || strings.Contains(e.Object, opts.Pod)
+	})
+	if len(groups) == 0 {
+		fmt.Printf("No warning events in %s/%s in the last %s.\n", c.Name, c.Namespace, opts.Since)
+		return
+	}
+
+	for i, g := range groups {
+		if i > 0 {
+			fmt.Println()
+		}
+		fmt.Printf("%s/%s\n", g.Kind, g.Object)
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		var hints []string
+		for _, e := range g.Events {
+			ex := explainEvent(e)
+			_, _ = fmt.Fprintf(w, "  %s\t%s\tx%d\t%s\n", formatEventAge(e.LastSeen), e.Reason, e.Count, ex.Summary)
+			if ex.Hint != "" && !slices.Contains(hints, ex.Hint) {
+				hints = append(hints, ex.Hint)
+			}
+		}
+		_ = w.Flush()
+		for _, h := range hints {
+			fmt.Printf("  → %s\n", h)
+		}
+	}
+}
+
+// eventGroup is the set of events about one object.
+type eventGroup struct {
+	Kind   string
+	Object string
+	Events []*kube.Event
+}
+
+// groupEvents groups the events that pass keep by object, ordering groups by
+// their most recent event.
+func groupEvents(events []*kube.Event, keep func(*kube.Event) bool) []*eventGroup {
+	byObject := make(map[string]*eventGroup)
+	var groups []*eventGroup
+	for _, e := range events {
+		if !keep(e) {
+			continue
+		}
+		key := e.Kind + "/" + e.Object
+		g, ok := byObject[key]
+		if !ok {
+			g = &eventGroup{Kind: e.Kind, Object: e.Object}
+			byObject[key] = g
+			groups = append(groups, g)
+		}
+		g.Events = append(g.Events, e)
+	}
+
+	latest := func(g *eventGroup) time.Time {
+		var t time.Time
+		for _, e := range g.Events {
+			if e.LastSeen.After(t) {
+				t = e.LastSeen
+			}
+		}
+		return t
+	}
+	sort.SliceStable(groups, func(i, j int) bool { return latest(groups[i]).After(latest(groups[j])) })
+	return groups
+}
+
+// oomKillEvents synthesises events for containers whose last exit was an OOM
+// kill, which the kubelet only records in the pod status.
+func oomKillEvents(pods []*kube.Pod) []*kube.Event {
+	var events []*kube.Event
+	for _, p := range pods {
+		for _, cs := range p.Containers {
+			if cs.LastTerminationReason != "OOMKilled" {
+				continue
+			}
+			events = append(events, &kube.Event{
+				Kind:    "Pod",
+				Object:  p.Name,
+				Type:    "Warning",
+				Reason:  "OOMKilled",
+				Message: fmt.Sprintf("container %s was OOM killed (%d restarts)", cs.Name, cs.RestartCount),
+				Count:   1,
+			})
+		}
+	}
+	return events
+}
+
+// explainEvent turns a warning event into a one-line summary and a suggested
+// next step. Unknown reasons fall back to the event message.
+func explainEvent(e *kube.Event) eventExplanation {
+	msg := e.Message
+	switch {
+	case e.Reason == "OOMKilled" || e.Reason == "OOMKilling":
+		return eventExplanation{
+			Summary: "OOMKilled: " + msg,
+			Hint:    "Container exceeded its memory limit; check memory usage before raising resources.limits.memory",
+		}
+	case e.Reason == "BackOff" && strings.Contains(msg, "pulling image"),
+		strings.Contains(msg, "ImagePullBackOff"), strings.Contains(msg, "ErrImagePull"):
+		return eventExplanation{
+			Summary: "ImagePullBackOff: " + msg,
+			Hint:    "Check that the image tag exists and the node can pull from the registry",
+		}
+	case e.Reason == "BackOff":
+		return eventExplanation{
+			Summary: "CrashLoopBackOff: container keeps exiting",
+			Hint:    fmt.Sprintf("Read the crash output with `kubectl logs %s --previous`", e.Object),
+		}
+	case e.Reason == "FailedScheduling":
+		return eventExplanation{
+			Summary: "FailedScheduling: " + msg,
+			Hint:    "No node fits the pod; check requests vs. node capacity, taints and the cluster autoscaler",
+		}
+	case e.Reason == "Unhealthy":
+		return eventExplanation{
+			Summary: "Probe failing: " + msg,
+			Hint:    "The app is up but not answering its probe; check startup time and the probe path",
+		}
+	case e.Reason == "FailedMount" || e.Reason == "FailedAttachVolume":
+		return eventExplanation{
+			Summary: "Volume not mounted: " + msg,
+			Hint:    "Check the referenced Secret/ConfigMap/PVC exists and the volume isn't attached elsewhere",
+		}
+	case e.Reason == "Evicted":
+		return eventExplanation{
+			Summary: "Evicted: " + msg,
+			Hint:    "The node ran short of resources; check node pressure and pod requests",
+		}
+	case strings.HasPrefix(e.Reason, "FailedGetResourceMetric") || strings.HasPrefix(e.Reason, "FailedComputeMetricsReplicas"):
+		return eventExplanation{
+			Summary: "Autoscaler can't read metrics: " + msg,
+			Hint:    "Check metrics-server / KEDA and that the target pods set resource requests",
+		}
+	}
+	return eventExplanation{Summary: msg}
+}
+
+// formatEventAge renders how long ago an event fired, e.g. "5m".
+func formatEventAge(t time.Time) string {
+	if t.IsZero() {
+		return "-"
+	}
+	d := time.Since(t)
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 48*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	default:
+		return fmt.Sprintf("%dd", int(d.Hours()/24))
+	}
+}
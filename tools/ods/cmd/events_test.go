@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/kube"
+)
+
+func TestExplainEvent(t *testing.T) {
+	tests := []struct {
+		event   kube.Event
+		summary string
+	}{
+		{kube.Event{Reason: "BackOff", Message: "Back-off restarting failed container api"}, "CrashLoopBackOff"},
+		{kube.Event{Reason: "BackOff", Message: "Back-off pulling image \"onyx/backend:v9\""}, "ImagePullBackOff"},
+		{kube.Event{Reason: "Failed", Message: "Error: ErrImagePull"}, "ImagePullBackOff"},
+		{kube.Event{Reason: "FailedScheduling", Message: "0/3 nodes are available"}, "FailedScheduling"},
+		{kube.Event{Reason: "OOMKilled", Message: "container api was OOM killed"}, "OOMKilled"},
+		{kube.Event{Reason: "SomethingNew", Message: "raw message"}, "raw message"},
+	}
+	for _, tt := range tests {
+		got := explainEvent(&tt.event)
+		if !strings.HasPrefix(got.Summary, tt.summary) {
+			t.Errorf("explainEvent(%s: %s).Summary = %q, want prefix %q", tt.event.Reason, tt.event.Message, got.Summary, tt.summary)
+		}
+	}
+}
+
+func TestGroupEvents(t *testing.T) {
+	now := time.Now()
+	events := []*kube.Event{
+		{Kind: "Pod", Object: "api-0", Reason: "BackOff", LastSeen: now.Add(-time.Hour)},
+		{Kind: "Pod", Object: "web-0", Reason: "Unhealthy", LastSeen: now.Add(-time.Minute)},
+		{Kind: "Pod", Object: "api-0", Reason: "Unhealthy", LastSeen: now.Add(-2 * time.Hour)},
+		{Kind: "Pod", Object: "old-0", Reason: "BackOff", LastSeen: now.Add(-48 * time.Hour)},
+	}
+	cutoff := now.Add(-3 * time.Hour)
+
+	groups := groupEvents(events, func(e *kube.Event) bool { return e.LastSeen.After(cutoff) })
+	if len(groups) != 2 {
+		t.Fatalf("expected 2 groups, got %d", len(groups))
+	}
+	if groups[0].Object != "web-0" || groups[1].Object != "api-0" {
+		t.Errorf("groups not ordered by most recent event: %s, %s", groups[0].Object, groups[1].Object)
+	}
+	if len(groups[1].Events) != 2 {
+		t.Errorf("expected both api-0 events grouped, got %d", len(groups[1].Events))
+	}
+}
+
+func TestOOMKillEvents(t *testing.T) {
+	pods := []*kube.Pod{
+		{Name: "api-0", Containers: []kube.ContainerStatus{{Name: "api", LastTerminationReason: "OOMKilled", RestartCount: 2}}},
+		{Name: "web-0", Containers: []kube.ContainerStatus{{Name: "web", LastTerminationReason: "Completed"}}},
+	}
+	events := oomKillEvents(pods)
+	if len(events) != 1 || events[0].Object != "api-0" || events[0].Reason != "OOMKilled" {
+		t.Errorf("oomKillEvents() = %+v", events)
+	}
+}
@@ -0,0 +1,223 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/ask"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/explain"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/history"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/kube"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/paths"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/render"
+)
+
+// ExplainOptions holds options for the explain command.
+type ExplainOptions struct {
+	Last  bool
+	LLM   bool
+	NoLLM bool
+	JSON  bool
+}
+
+// NewExplainCommand creates the explain command.
+func NewExplainCommand() *cobra.Command {
+	opts := &ExplainOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "explain [--last | <error message>]",
+		Short: "Diagnose a failed ods command and suggest what to run next",
+		Long: `Diagnose a failed ods command and suggest what to run next.
+
+ods keeps a history of its recent runs: the command line, whether it failed,
+the error and the end of what it logged, redacted of credentials and
+emails. --last explains the most recent failure in it; an error message
+can also be passed instead.
+
+Known error signatures (an expired AWS SSO session, a missing KUBE_CTX_*
+variable, Docker not running, a port taken by a leaked port-forward, no
+ready pod, ...) are diagnosed locally. Other failures are sent to the LLM of
+` + "`ods ask`" + ` when one is configured, with the redacted command, error, log
+output and kubectl context; --llm asks it even when a signature matched,
+--no-llm never does.
+
+Runs that exit without an error message (e.g. a health check exiting
+non-zero) are not recorded as failures.
+
+Examples:
+  ods explain --last
+  ods explain --last --llm
+  ods explain "dial tcp 127.0.0.1:8080: connect: connection refused"`,
+		Run: func(cmd *cobra.Command, args []string) {
+			runExplain(opts, args)
+		},
+	}
+
+	cmd.Flags().BoolVar(&opts.Last, "last", false, "Explain the last failed ods command")
+	cmd.Flags().BoolVar(&opts.LLM, "llm", false, "Ask the LLM even when a known error signature matched")
+	cmd.Flags().BoolVar(&opts.NoLLM, "no-llm", false, "Only use the built-in error signatures")
+	cmd.Flags().BoolVar(&opts.JSON, "json", false, "Print the diagnoses as JSON")
+
+	return cmd
+}
+
+func runExplain(opts *ExplainOptions, args []string) {
+	if opts.LLM && opts.NoLLM {
+		log.Fatal("--llm and --no-llm are mutually exclusive")
+	}
+	var e *history.Entry
+	switch {
+	case opts.Last && len(args) > 0:
+		log.Fatal("--last and an error message are mutually exclusive")
+	case opts.Last:
+		entries, err := history.Read(paths.HistoryPath())
+		if err != nil {
+			log.Fatalf("Failed to read the history: %v", err)
+		}
+		if e = history.LastFailed(entries); e == nil {
+			log.Fatalf("No failed ods command in the history (%s)", paths.HistoryPath())
+		}
+	case len(args) > 0:
+		e = &history.Entry{Error: strings.Join(args, " ")}
+	default:
+		log.Fatal("Pass --last or an error message to explain")
+	}
+
+	diagnoses := explain.Heuristics(e)
+	if !opts.NoLLM && (opts.LLM || len(diagnoses) == 0) {
+		chat := explainLLM(opts.LLM)
+		if chat != nil {
+			env := explain.Env{KubeContext: kube.CurrentContext(), OS: runtime.GOOS + "/" + runtime.GOARCH}
+			d, err := explain.WithLLM(chat, e, env, diagnoses)
+			if err != nil {
+				log.Warnf("Failed to ask the LLM: %v", err)
+			} else {
+				diagnoses = append([]explain.Diagnosis{*d}, diagnoses...)
+			}
+		}
+	}
+
+	if opts.JSON {
+		if diagnoses == nil {
+			diagnoses = []explain.Diagnosis{}
+		}
+		if err := render.JSON(os.Stdout, diagnoses); err != nil {
+			log.Fatalf("Failed to marshal the diagnoses: %v", err)
+		}
+		return
+	}
+	printExplanation(os.Stdout, e, diagnoses)
+}
+
+// explainLLM returns the chat of the configured ods ask LLM, or nil when
+// none is configured; with required it is an error not to have one.
+func explainLLM(required bool) func([]ask.Message) (string, error) {
+	cfg := loadConfigOrDie().Ask
+	key := envOrDefault("ODS_ASK_API_KEY", os.Getenv("OPENAI_API_KEY"))
+	if key == "" && cfg.URL == "" {
+		if required {
+			log.Fatal("--llm needs an LLM: set ODS_ASK_API_KEY or OPENAI_API_KEY, or ask.url in the ods config")
+		}
+		log.Debug("No LLM configured")
+		return nil
+	}
+	url := firstNonEmpty(cfg.URL, ask.DefaultURL)
+	model := firstNonEmpty(cfg.Model, ask.DefaultModel)
+	log.Infof("Asking %s for a diagnosis", model)
+	return ask.NewLLM(url, key, model).Chat
+}
+
+func printExplanation(w io.Writer, e *history.Entry, diagnoses []explain.Diagnosis) {
+	if len(e.Args) > 0 {
+		_, _ = fmt.Fprintf(w, "Command: %s (failed %s ago)\n", e.Command(), formatEventAge(e.Time))
+	}
+	if e.Error != "" {
+		_, _ = fmt.Fprintf(w, "Error:   %s\n", strings.ReplaceAll(strings.TrimSpace(e.Error), "\n", "\n         "))
+	}
+
+	if len(diagnoses) == 0 {
+		_, _ = fmt.Fprintln(w, "\nNo known error signature matched.")
+		_, _ = fmt.Fprintln(w, "Next:")
+		if len(e.Args) > 0 {
+			_, _ = fmt.Fprintf(w, "  %s --debug\n", e.Command())
+		}
+		_, _ = fmt.Fprintln(w, "  ods doctor")
+		return
+	}
+	for _, d := range diagnoses {
+		source := "known error " + d.Source
+		if d.Source == explain.SourceLLM {
+			source = "LLM"
+		}
+		_, _ = fmt.Fprintf(w, "\nCause (%s): %s\n", source, d.Cause)
+		if len(d.Next) > 0 {
+			_, _ = fmt.Fprintln(w, "Next:")
+			for _, n := range d.Next {
+				_, _ = fmt.Fprintf(w, "  %s\n", n)
+			}
+		}
+	}
+}
+
+// historyRecorder records the run in the history when it finishes,
+// successfully or through log.Fatal. A nil recorder does nothing.
+type historyRecorder struct {
+	entry   history.Entry
+	start   time.Time
+	tail    *history.Tail
+	failure *fatalCapture
+}
+
+// startHistory starts keeping the tail of what the run logs, to record it
+// with the run.
+func startHistory(cmd *cobra.Command) *historyRecorder {
+	switch cmd.Name() {
+	case "explain", "help", "completion", cobra.ShellCompRequestCmd, cobra.ShellCompNoDescRequestCmd:
+		return nil
+	}
+	dir, _ := os.Getwd()
+	r := &historyRecorder{
+		entry: history.Entry{
+			Args:    os.Args[1:],
+			Dir:     dir,
+			Version: Version,
+		},
+		start:   time.Now(),
+		tail:    history.NewTail(history.TailSize),
+		failure: &fatalCapture{},
+	}
+	log.SetOutput(io.MultiWriter(log.StandardLogger().Out, r.tail))
+	log.AddHook(r.failure)
+	log.RegisterExitHandler(func() {
+		r.record(false, r.failure.message)
+	})
+	return r
+}
+
+// Done records a successful run.
+func (r *historyRecorder) Done() {
+	if r == nil {
+		return
+	}
+	r.record(true, "")
+}
+
+func (r *historyRecorder) record(ok bool, failure string) {
+	e := r.entry
+	e.OK = ok
+	e.DurationMS = time.Since(r.start).Milliseconds()
+	e.Error = failure
+	if !ok {
+		e.Output = r.tail.String()
+	}
+	if err := history.Record(e); err != nil {
+		log.Debugf("Failed to record the run in the history: %v", err)
+	}
+}
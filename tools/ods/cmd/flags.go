@@ -0,0 +1,189 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"text/tabwriter"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/auditlog"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/flags"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/kube"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/prompt"
+)
+
+// FlagsOptions holds options shared by the flags subcommands.
+type FlagsOptions struct {
+	Context string
+	Tenant  string
+}
+
+// NewFlagsCommand creates the parent flags command.
+func NewFlagsCommand() *cobra.Command {
+	opts := &FlagsOptions{}
+
+	cmd := &cobra.Command{
+		Use:     "flags",
+		Aliases: []string{"feature-flags"},
+		Short:   "View and toggle feature flags of a tenant",
+		Long: `View and toggle feature flags of a tenant.
+
+Flags are the on/off workspace settings of the admin settings page
+(deep_research_enabled, search_ui_enabled, invite_only_enabled, ...), stored
+per tenant schema. Changes go through the backend's own settings store on an
+api-server pod, so cached copies are updated too, and are recorded in the
+local audit log.
+
+On a single-tenant deployment omit --tenant.
+
+Requires: AWS SSO login, kubectl access to the EKS cluster.
+
+Examples:
+  ods flags list --tenant tenant_abcd1234
+  ods flags set deep_research_enabled on --tenant tenant_abcd1234
+  ods flags set multi_model_chat_enabled off --all-tenants -c staging`,
+	}
+
+	cmd.PersistentFlags().StringVarP(&opts.Context, "context", "c", "data_plane", "cluster context name (maps to KUBE_CTX_<NAME> env var)")
+	cmd.PersistentFlags().StringVar(&opts.Tenant, "tenant", "", "Tenant schema (omit on single-tenant deployments)")
+
+	cmd.AddCommand(newFlagsListCommand(opts))
+	cmd.AddCommand(newFlagsSetCommand(opts))
+
+	return cmd
+}
+
+func newFlagsListCommand(opts *FlagsOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List flags and where their values come from",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			runFlagsList(opts)
+		},
+	}
+}
+
+func newFlagsSetCommand(opts *FlagsOptions) *cobra.Command {
+	var allTenants, yes bool
+
+	cmd := &cobra.Command{
+		Use:   "set <name> <on|off>",
+		Short: "Turn a flag on or off for a tenant or every tenant",
+		Long: `Turn a flag on or off for a tenant or every tenant.
+
+Flags overridden by the deployment's environment (source "env" in
+` + "`ods flags list`" + `) are stored but keep their environment value until
+the override is removed.`,
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			if allTenants && opts.Tenant != "" {
+				log.Fatal("--all-tenants and --tenant are mutually exclusive")
+			}
+			runFlagsSet(opts, args[0], args[1], allTenants, yes)
+		},
+	}
+
+	cmd.Flags().BoolVar(&allTenants, "all-tenants", false, "Set the flag in every tenant schema")
+	cmd.Flags().BoolVar(&yes, "yes", false, "Skip the confirmation prompt")
+
+	return cmd
+}
+
+func flagsPod(opts *FlagsOptions) (*kube.Cluster, string) {
+	if opts.Tenant != "" {
+		validateTenantArg(opts.Tenant)
+	}
+	c := clusterFromEnv(opts.Context)
+	if err := c.EnsureContext(); err != nil {
+		log.Fatalf("Failed to ensure cluster context: %v", err)
+	}
+	pod, err := c.FindPod("api-server")
+	if err != nil {
+		log.Fatalf("Failed to find api-server pod: %v", err)
+	}
+	return c, pod
+}
+
+func runFlagsList(opts *FlagsOptions) {
+	c, pod := flagsPod(opts)
+	list, err := flags.List(c, pod, opts.Tenant)
+	if err != nil {
+		log.Fatalf("Failed to list flags: %v", err)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "FLAG\tVALUE\tSOURCE")
+	_, _ = fmt.Fprintln(w, "----\t-----\t------")
+	for _, f := range list {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", f.Name, flags.FormatValue(f.Value), f.Source)
+	}
+	_ = w.Flush()
+}
+
+func runFlagsSet(opts *FlagsOptions, name, raw string, allTenants, yes bool) {
+	value, err := flags.ParseValue(raw)
+	if err != nil {
+		log.Fatal(err)
+	}
+	c, pod := flagsPod(opts)
+	auditCtx := c.Name + "/" + c.Namespace
+
+	target := opts.Tenant
+	switch {
+	case allTenants:
+		target = "all-tenants"
+	case target == "":
+		target = "default"
+	}
+
+	if !yes && (allTenants || isProductionContext(opts.Context)) {
+		if !prompt.Confirm(fmt.Sprintf("Turn %s %s for %s in %s? (yes/no): ", name, flags.FormatValue(&value), target, auditCtx)) {
+			log.Info("Aborted.")
+			return
+		}
+	}
+
+	if err := auditlog.Record(auditlog.Entry{
+		Action:  "flags.set",
+		Context: auditCtx,
+		Target:  target,
+		Detail:  fmt.Sprintf("%s=%s", name, flags.FormatValue(&value)),
+	}); err != nil {
+		log.Fatalf("Refusing to change a flag without an audit record: %v", err)
+	}
+
+	if allTenants {
+		bulk, err := flags.SetAll(c, pod, name, value)
+		if err != nil {
+			log.Fatalf("Failed to set %s: %v", name, err)
+		}
+		log.Infof("Set %s %s: %d tenant(s) changed, %d already set", name, flags.FormatValue(&value), bulk.Changed, bulk.Unchanged)
+		if len(bulk.Failed) > 0 {
+			tenants := make([]string, 0, len(bulk.Failed))
+			for t := range bulk.Failed {
+				tenants = append(tenants, t)
+			}
+			sort.Strings(tenants)
+			for _, t := range tenants {
+				log.Errorf("%s: %s", t, bulk.Failed[t])
+			}
+			log.Fatalf("Failed to set %s in %d tenant(s)", name, len(bulk.Failed))
+		}
+		return
+	}
+
+	previous, err := flags.Set(c, pod, opts.Tenant, name, value)
+	if err != nil {
+		log.Fatalf("Failed to set %s: %v", name, err)
+	}
+	if previous != nil && *previous == value {
+		log.Infof("%s was already %s for %s", name, flags.FormatValue(&value), target)
+		return
+	}
+	log.Infof("%s for %s: %s -> %s", name, target, flags.FormatValue(previous), flags.FormatValue(&value))
+}
@@ -0,0 +1,256 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/auditlog"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/gdpr"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/kube"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/prompt"
+)
+
+// GDPROptions holds options shared by the gdpr subcommands.
+type GDPROptions struct {
+	Context string
+	Tenant  string
+	Reason  string
+	Out     string
+}
+
+// NewGDPRCommand creates the parent gdpr command.
+func NewGDPRCommand() *cobra.Command {
+	opts := &GDPROptions{}
+
+	cmd := &cobra.Command{
+		Use:   "gdpr",
+		Short: "Fulfil data subject requests (export or delete a user's data)",
+		Long: `Fulfil data subject requests (DSRs) for a single user.
+
+export collects the user's profile, chat sessions and messages, feedback,
+memories, search queries and uploaded-file metadata. delete purges the same
+data, including uploaded files from the file store and document index, and
+then removes the user.
+
+Both run a bundled script on an api-server pod using the backend's own models
+and deletion paths, and write a report signed with your DSR signing key
+(` + "`ods gdpr verify`" + ` checks one). Set ` + gdpr.SigningKeyEnv + ` to sign
+with a shared team key instead of the one generated under your config dir.
+Every request is recorded in the local audit log.
+
+Requires: AWS SSO login, kubectl access to the EKS cluster.
+
+Examples:
+  ods gdpr export jane@customer.com --reason DSR-42
+  ods gdpr delete jane@customer.com --tenant tenant_abcd1234 --reason DSR-42
+  ods gdpr verify dsr-delete-jane@customer.com-20260102.json`,
+	}
+
+	cmd.PersistentFlags().StringVarP(&opts.Context, "context", "c", "data_plane", "cluster context name (maps to KUBE_CTX_<NAME> env var)")
+
+	cmd.AddCommand(newGDPRExportCommand(opts))
+	cmd.AddCommand(newGDPRDeleteCommand(opts))
+	cmd.AddCommand(newGDPRVerifyCommand())
+
+	return cmd
+}
+
+func newGDPRExportCommand(opts *GDPROptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "export <email>",
+		Short: "Export a user's personal data to a signed report",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			runGDPR(opts, gdpr.ActionExport, args[0], true)
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.Tenant, "tenant", "", "Tenant ID (default: looked up from the email)")
+	cmd.Flags().StringVar(&opts.Reason, "reason", "", "DSR ticket or justification recorded in the report (required)")
+	cmd.Flags().StringVarP(&opts.Out, "out", "o", "", "Report path (default: dsr-export-<email>-<date>.json)")
+	_ = cmd.MarkFlagRequired("reason")
+
+	return cmd
+}
+
+func newGDPRDeleteCommand(opts *GDPROptions) *cobra.Command {
+	var yes bool
+
+	cmd := &cobra.Command{
+		Use:   "delete <email>",
+		Short: "Purge a user's personal data and write a signed report",
+		Long: `Purge a user's personal data and write a signed report.
+
+Shows what will be deleted, asks for confirmation (unless --yes), then deletes
+feedback, search queries, chat sessions and their files, uploaded files, and
+finally the user and their tenant mapping. The report records counts only.
+This cannot be undone.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			runGDPR(opts, gdpr.ActionDelete, args[0], yes)
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.Tenant, "tenant", "", "Tenant ID the user belongs to (required)")
+	cmd.Flags().StringVar(&opts.Reason, "reason", "", "DSR ticket or justification recorded in the report (required)")
+	cmd.Flags().StringVarP(&opts.Out, "out", "o", "", "Report path (default: dsr-delete-<email>-<date>.json)")
+	cmd.Flags().BoolVar(&yes, "yes", false, "Skip the confirmation prompt")
+	_ = cmd.MarkFlagRequired("tenant")
+	_ = cmd.MarkFlagRequired("reason")
+
+	return cmd
+}
+
+func newGDPRVerifyCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "verify <report>",
+		Short: "Check a DSR report's signature",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			r, err := gdpr.ReadReport(args[0])
+			if err != nil {
+				log.Fatalf("%v", err)
+			}
+			if err := r.Verify(); err != nil {
+				log.Fatalf("Report %s is NOT valid: %v", args[0], err)
+			}
+			fmt.Printf("Valid %s report for %s (%s) by %s at %s\n", r.Action, r.Email, r.TenantID, r.Actor, r.Time.Format(time.RFC3339))
+			fmt.Printf("Signed by key %s\n", r.PublicKey)
+		},
+	}
+}
+
+func runGDPR(opts *GDPROptions, action gdpr.Action, email string, yes bool) {
+	email = strings.ToLower(strings.TrimSpace(email))
+	if !isPlainEmail(email) {
+		log.Fatalf("%q does not look like an email address", email)
+	}
+	if strings.TrimSpace(opts.Reason) == "" {
+		log.Fatal("--reason must not be empty")
+	}
+	if opts.Tenant != "" {
+		validateTenantArg(opts.Tenant)
+	}
+
+	key, err := gdpr.LoadSigningKey(gdpr.SigningKeyPath())
+	if err != nil {
+		log.Fatalf("Failed to load DSR signing key: %v", err)
+	}
+
+	c := clusterFromEnv(opts.Context)
+	if err := c.EnsureContext(); err != nil {
+		log.Fatalf("Failed to ensure cluster context: %v", err)
+	}
+	auditCtx := c.Name + "/" + c.Namespace
+
+	log.Info("Finding api-server pod...")
+	pod, err := c.FindPod("api-server")
+	if err != nil {
+		log.Fatalf("Failed to find api-server pod: %v", err)
+	}
+
+	tenantID := opts.Tenant
+	if tenantID == "" {
+		tenantID = tenantForEmail(c, pod, email)
+	}
+
+	if action == gdpr.ActionDelete {
+		// Dry run first so the operator sees exactly what will go.
+		preview, err := gdpr.Run(c, pod, gdpr.ActionExport, email, tenantID)
+		if err != nil {
+			log.Fatalf("Failed to collect data for %s: %v", email, err)
+		}
+		fmt.Printf("Personal data for %s in %s (%s):\n", email, tenantID, auditCtx)
+		printDSRCounts(preview.Counts)
+		if !yes && !prompt.Confirm(fmt.Sprintf("Permanently delete %s and all of the above? (yes/no): ", email)) {
+			log.Info("Aborted.")
+			return
+		}
+	}
+
+	if err := auditlog.Record(auditlog.Entry{
+		Action:  "gdpr." + string(action),
+		Context: auditCtx,
+		Target:  email + " in " + tenantID,
+		Detail:  "reason=" + opts.Reason,
+	}); err != nil {
+		log.Fatalf("Refusing to process a DSR without an audit record: %v", err)
+	}
+
+	log.Infof("Running DSR %s for %s in %s...", action, email, tenantID)
+	result, err := gdpr.Run(c, pod, action, email, tenantID)
+	if err != nil {
+		log.Fatalf("DSR %s failed for %s: %v", action, email, err)
+	}
+
+	report := &gdpr.Report{
+		Action:   action,
+		Email:    email,
+		TenantID: tenantID,
+		Context:  auditCtx,
+		Reason:   opts.Reason,
+		Actor:    auditlog.Actor(),
+		Time:     time.Now().UTC(),
+		Counts:   result.Counts,
+		Data:     result.Data,
+	}
+	if err := report.Sign(key); err != nil {
+		log.Fatalf("Failed to sign report: %v", err)
+	}
+
+	out := opts.Out
+	if out == "" {
+		out = fmt.Sprintf("dsr-%s-%s-%s.json", action, email, report.Time.Format("20060102"))
+	}
+	if err := gdpr.WriteReport(out, report); err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	if action == gdpr.ActionExport {
+		printDSRCounts(result.Counts)
+	}
+	log.Infof("DSR %s for %s complete; signed report written to %s", action, email, out)
+}
+
+// tenantForEmail returns the single tenant an email is mapped to, exiting if
+// there are none or several.
+func tenantForEmail(c *kube.Cluster, pod, email string) string {
+	sql := fmt.Sprintf(`SELECT tenant_id FROM public.user_tenant_mapping WHERE email = '%s' ORDER BY tenant_id;`, email)
+	tenants := queryPod(c, pod, sql)
+	switch len(tenants) {
+	case 0:
+		log.Fatalf("%s is not mapped to any tenant; pass --tenant", email)
+	case 1:
+		return tenants[0]
+	}
+	log.Fatalf("%s belongs to several tenants (%s); pass --tenant", email, strings.Join(tenants, ", "))
+	return ""
+}
+
+// isPlainEmail reports whether s is an email address safe to interpolate into
+// a quoted SQL literal.
+func isPlainEmail(s string) bool {
+	at := strings.Index(s, "@")
+	return at > 0 && at < len(s)-1 && !strings.ContainsAny(s, "'\"\\; \t\n")
+}
+
+func printDSRCounts(counts map[string]int) {
+	keys := make([]string, 0, len(counts))
+	for k := range counts {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, k := range keys {
+		_, _ = fmt.Fprintf(w, "  %s\t%d\n", strings.ReplaceAll(k, "_", " "), counts[k])
+	}
+	_ = w.Flush()
+}
@@ -0,0 +1,20 @@
+package cmd
+
+import "testing"
+
+func TestIsPlainEmail(t *testing.T) {
+	for email, want := range map[string]bool{
+		"jane@customer.com":        true,
+		"jane+dsr@sub.customer.io": true,
+		"jane":                     false,
+		"@customer.com":            false,
+		"jane@":                    false,
+		"jane'--@customer.com":     false,
+		"jane@customer.com; DROP":  false,
+		`jane\@customer.com`:       false,
+	} {
+		if got := isPlainEmail(email); got != want {
+			t.Errorf("isPlainEmail(%q) = %v, want %v", email, got, want)
+		}
+	}
+}
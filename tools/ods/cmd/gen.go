@@ -0,0 +1,162 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/gen"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/paths"
+)
+
+// GenOptions holds options shared by the gen subcommands.
+type GenOptions struct {
+	DryRun bool
+}
+
+// NewGenCommand creates the parent gen command.
+func NewGenCommand() *cobra.Command {
+	opts := &GenOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "gen",
+		Short: "Scaffold connectors, migrations and API endpoints",
+		Long: `Scaffold new code in the Onyx repository, already wired into the registries
+it needs, so new contributors start from something that runs.
+
+Every generator checks the files it edits before writing anything: if one
+has changed shape so its insertion point cannot be found, nothing is written.`,
+	}
+
+	cmd.PersistentFlags().BoolVar(&opts.DryRun, "dry-run", false, "Show the files that would be created or edited without writing them")
+
+	cmd.AddCommand(newGenConnectorCommand(opts))
+	cmd.AddCommand(newGenMigrationCommand(opts))
+	cmd.AddCommand(newGenAPIEndpointCommand(opts))
+
+	return cmd
+}
+
+func newGenConnectorCommand(opts *GenOptions) *cobra.Command {
+	var description, category string
+
+	cmd := &cobra.Command{
+		Use:   "connector <name>",
+		Short: "Scaffold a connector with its tests, registration and form stubs",
+		Long: `Scaffold a connector: a LoadConnector/PollConnector module under
+backend/onyx/connectors/<name> with a unit test, a DocumentSource member and
+description, a connector factory registration, and the frontend source entry,
+connector config form and credential form (an API token) stubs.
+
+The name may be given as words ("Acme Wiki"), PascalCase or snake_case.
+
+Examples:
+  ods gen connector "Acme Wiki"
+  ods gen connector Zammad --category TicketingAndTaskManagement
+  ods gen connector AcmeWiki --description "Wiki pages and comments" --dry-run`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			name, err := gen.ParseName(args[0])
+			if err != nil {
+				log.Fatalf("%v", err)
+			}
+			runGen(opts, func(root string) (*gen.Plan, error) {
+				return gen.Connector(root, gen.ConnectorOptions{Name: name, Description: description, Category: category})
+			})
+		},
+	}
+
+	cmd.Flags().StringVar(&description, "description", "", `One-line summary of what the source holds (default "Documents from <name>")`)
+	cmd.Flags().StringVar(&category, "category", "Other", "Add Connector page category: "+strings.Join(gen.SourceCategories, ", "))
+
+	return cmd
+}
+
+func newGenMigrationCommand(opts *GenOptions) *cobra.Command {
+	var schema string
+
+	cmd := &cobra.Command{
+		Use:   "migration <message>",
+		Short: "Scaffold an Alembic migration on top of the current head",
+		Long: `Scaffold an empty Alembic migration revising the current head, named the way
+alembic revision names it. Fails if the migrations have more than one head.
+
+Unlike alembic revision, this needs neither a database nor a Python
+environment.
+
+Examples:
+  ods gen migration "Add retention policy to chat sessions"
+  ods gen migration "Add tenant region" --schema private`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			runGen(opts, func(root string) (*gen.Plan, error) {
+				return gen.Migration(root, gen.MigrationOptions{Message: args[0], Schema: schema, Now: time.Now()})
+			})
+		},
+	}
+
+	cmd.Flags().StringVar(&schema, "schema", "default", "Schema to migrate: 'default' or 'private' (multi-tenant)")
+
+	return cmd
+}
+
+func newGenAPIEndpointCommand(opts *GenOptions) *cobra.Command {
+	var admin bool
+
+	cmd := &cobra.Command{
+		Use:   "api-endpoint <area>",
+		Short: "Scaffold an API router with a response model and test",
+		Long: `Scaffold a FastAPI router under backend/onyx/server/<area> with one GET
+endpoint, its Pydantic response model and a unit test, and include it in the
+app in backend/onyx/main.py.
+
+The area is a snake_case package path; its last part names the route.
+
+Examples:
+  ods gen api-endpoint reading_list              # GET /reading-list
+  ods gen api-endpoint features/retention --admin  # GET /admin/retention`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			runGen(opts, func(root string) (*gen.Plan, error) {
+				return gen.APIEndpoint(root, gen.EndpointOptions{Area: args[0], Admin: admin})
+			})
+		},
+	}
+
+	cmd.Flags().BoolVar(&admin, "admin", false, "Serve under /admin and require full admin access (default: any signed-in user)")
+
+	return cmd
+}
+
+func runGen(opts *GenOptions, plan func(root string) (*gen.Plan, error)) {
+	root, err := paths.GitRoot()
+	if err != nil {
+		log.Fatalf("Failed to find git root: %v", err)
+	}
+	p, err := plan(root)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	for _, c := range p.Changes {
+		verb := "edit  "
+		if c.Created {
+			verb = "create"
+		}
+		fmt.Printf("%s %s\n", verb, c.Path)
+	}
+	if opts.DryRun {
+		return
+	}
+	if err := p.Apply(); err != nil {
+		log.Fatalf("Failed to write changes: %v", err)
+	}
+
+	fmt.Println("\nNext:")
+	for _, step := range p.Next {
+		fmt.Printf("  - %s\n", step)
+	}
+}
@@ -0,0 +1,125 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/docker"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/health"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/render"
+)
+
+// HealthOptions holds options for the health command.
+type HealthOptions struct {
+	Context string
+	Timeout time.Duration
+	JSON    bool
+	Watch   time.Duration
+}
+
+// NewHealthCommand creates the health command.
+func NewHealthCommand() *cobra.Command {
+	opts := &HealthOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "health",
+		Short: "Probe every service a deployment depends on",
+		Long: `Probe every service a deployment depends on.
+
+From an api-server pod (or, with -c local, the compose stack's api_server
+container), concurrently probes:
+  api_server             its /health endpoint
+  postgres               a connection and query
+  redis                  PING
+  vespa                  the search container's health state
+  model_server           its /api/health endpoint (and the indexing model
+                         server's, when it is a separate one)
+  celery                 a ping answered by at least one worker
+
+Each probe runs with the backend's own configuration and is bounded by
+--timeout. Prints one pass/fail table with latencies and exits non-zero if
+any probe fails, so it can gate scripts.
+
+Examples:
+  ods health
+  ods health -c prod
+  ods health -c prod --json | jq '.[] | select(.ok | not)'
+  ods health -c staging --watch`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			runHealth(opts)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.Context, "context", "c", localContext, `cluster context name (maps to KUBE_CTX_<NAME> env var), or "local" for the compose stack`)
+	cmd.Flags().DurationVar(&opts.Timeout, "timeout", 5*time.Second, "Timeout for each probe")
+	cmd.Flags().BoolVar(&opts.JSON, "json", false, "Print results as JSON")
+	addWatchFlag(cmd, &opts.Watch)
+
+	return cmd
+}
+
+func runHealth(opts *HealthOptions) {
+	if opts.Timeout <= 0 {
+		log.Fatalf("--timeout must be positive")
+	}
+
+	var probe func() ([]health.Result, error)
+	if opts.Context == localContext {
+		container := fmt.Sprintf("%s-api_server-1", docker.ProjectName())
+		log.Debugf("Probing from %s", container)
+		probe = func() ([]health.Result, error) { return health.ProbeLocal(container, opts.Timeout) }
+	} else {
+		c := clusterFromEnv(opts.Context)
+		if err := c.EnsureContext(); err != nil {
+			log.Fatalf("Failed to ensure cluster context: %v", err)
+		}
+		pod, err := c.FindPod("api-server")
+		if err != nil {
+			log.Fatalf("Failed to find api-server pod: %v", err)
+		}
+		log.Debugf("Probing from %s", pod)
+		probe = func() ([]health.Result, error) { return health.ProbeCluster(c, pod, opts.Timeout) }
+	}
+
+	runWatchable(opts.Watch, func() error {
+		results, err := probe()
+		if err != nil {
+			return fmt.Errorf("failed to run health probes: %w", err)
+		}
+		printHealth(results, opts.JSON)
+		if failed := health.Failed(results); failed > 0 {
+			return fmt.Errorf("%d of %d health checks failed", failed, len(results))
+		}
+		return nil
+	})
+}
+
+func printHealth(results []health.Result, asJSON bool) {
+	if asJSON {
+		if err := render.JSON(os.Stdout, results); err != nil {
+			log.Fatalf("Failed to marshal results: %v", err)
+		}
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "CHECK\tSTATUS\tLATENCY\tDETAIL")
+	_, _ = fmt.Fprintln(w, "-----\t------\t-------\t------")
+	for _, r := range results {
+		status := "PASS"
+		switch {
+		case r.Skipped:
+			status = "SKIP"
+		case !r.OK:
+			status = "FAIL"
+		}
+		latency := time.Duration(r.Latency * float64(time.Millisecond)).Round(time.Millisecond)
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", r.Name, status, latency, r.Detail)
+	}
+	_ = w.Flush()
+}
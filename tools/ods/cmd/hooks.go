@@ -0,0 +1,167 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/hooks"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/paths"
+)
+
+// NewHooksCommand creates the parent hooks command.
+func NewHooksCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "hooks",
+		Short: "Install git hooks that lint and test what a change touches",
+		Long: `Install git hooks that run ods lint before each commit and ods test before
+each push, only for the areas (backend, web, tools) the change touches.
+A commit that touches none of them costs a git diff and nothing more.
+
+Skip the hooks once with git's --no-verify, or with ` + hooks.SkipEnv + `=1;
+` + hooks.SkipEnv + `=web,backend skips just those areas.`,
+	}
+
+	cmd.AddCommand(newHooksInstallCommand())
+	cmd.AddCommand(newHooksUninstallCommand())
+	cmd.AddCommand(newHooksRunCommand())
+
+	return cmd
+}
+
+func newHooksInstallCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "install [hook...]",
+		Short: "Install the pre-commit and pre-push hooks",
+		Long: `Install the pre-commit (lint staged files) and pre-push (test pushed
+changes) hooks, or just the ones named. Re-running updates them.
+
+A hook ods did not write, such as the pre-commit framework's, is kept as
+<hook>.pre-ods and runs first; uninstall puts it back.
+
+Examples:
+  ods hooks install
+  ods hooks install pre-commit`,
+		ValidArgs: hooks.HookNames,
+		Run: func(cmd *cobra.Command, args []string) {
+			dir := hooksDir()
+			for _, hook := range hookArgs(args) {
+				chained, err := hooks.Install(dir, hook)
+				if err != nil {
+					log.Fatalf("Failed to install %s: %v", hook, err)
+				}
+				if chained {
+					log.Infof("Installed %s; the existing hook runs first", hook)
+				} else {
+					log.Infof("Installed %s", hook)
+				}
+			}
+		},
+	}
+
+	return cmd
+}
+
+func newHooksUninstallCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "uninstall [hook...]",
+		Short: "Remove the hooks ods installed",
+		Long: `Remove the hooks ods installed, restoring any hook they displaced.
+
+Examples:
+  ods hooks uninstall`,
+		ValidArgs: hooks.HookNames,
+		Run: func(cmd *cobra.Command, args []string) {
+			dir := hooksDir()
+			for _, hook := range hookArgs(args) {
+				removed, err := hooks.Uninstall(dir, hook)
+				if err != nil {
+					log.Fatalf("Failed to uninstall %s: %v", hook, err)
+				}
+				if removed {
+					log.Infof("Removed %s", hook)
+				}
+			}
+		},
+	}
+
+	return cmd
+}
+
+func newHooksRunCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "run <hook>",
+		Short: "Run a hook's checks (called by the installed hooks)",
+		Long: `Run a hook's checks as git would: pre-commit lints the staged files, and
+pre-push tests the changes in the refs git passes on stdin.
+
+Examples:
+  ods hooks run pre-commit
+  echo "refs/heads/me $(git rev-parse HEAD) refs/heads/me 0000000000000000000000000000000000000000" | ods hooks run pre-push`,
+		Args:      cobra.MinimumNArgs(1),
+		ValidArgs: hooks.HookNames,
+		Run: func(cmd *cobra.Command, args []string) {
+			runHook(args[0])
+		},
+	}
+
+	return cmd
+}
+
+func hooksDir() string {
+	root, err := paths.GitRoot()
+	if err != nil {
+		log.Fatalf("Failed to find git root: %v", err)
+	}
+	dir, err := hooks.Dir(root)
+	if err != nil {
+		log.Fatalf("Failed to find the hooks directory: %v", err)
+	}
+	return dir
+}
+
+func hookArgs(args []string) []string {
+	if len(args) == 0 {
+		return hooks.HookNames
+	}
+	for _, hook := range args {
+		if !slices.Contains(hooks.HookNames, hook) {
+			log.Fatalf("Unknown hook %q; choose from %s", hook, strings.Join(hooks.HookNames, ", "))
+		}
+	}
+	return args
+}
+
+func runHook(hook string) {
+	kind, ok := hooks.Hooks[hook]
+	if !ok {
+		log.Fatalf("Unknown hook %q; choose from %s", hook, strings.Join(hooks.HookNames, ", "))
+	}
+	areas := hooks.SkipFromEnv(hooks.Areas)
+	if len(areas) == 0 {
+		return
+	}
+	root, err := paths.GitRoot()
+	if err != nil {
+		log.Fatalf("Failed to find git root: %v", err)
+	}
+
+	var changed []string
+	if hook == "pre-push" {
+		changed, err = hooks.PushedFiles(root, os.Stdin)
+	} else {
+		changed, err = hooks.StagedFiles(root)
+	}
+	if err != nil {
+		log.Fatalf("Failed to list changed files: %v", err)
+	}
+	cmds := hooks.Plan(areas, kind, changed)
+	if len(cmds) > 0 {
+		fmt.Fprintf(os.Stderr, "ods %s: %s (skip with --no-verify or %s=1)\n", hook, kind, hooks.SkipEnv)
+	}
+	runPlan(root, cmds, kind)
+}
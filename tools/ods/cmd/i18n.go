@@ -0,0 +1,301 @@
+package cmd
+
+import (
+	"fmt"
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/config"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/i18n"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/paths"
+)
+
+// I18nOptions holds options shared by the i18n subcommands.
+type I18nOptions struct {
+	LocalesDir string
+	SourceDir  string
+	Source     string
+}
+
+// NewI18nCommand creates the parent i18n command.
+func NewI18nCommand() *cobra.Command {
+	opts := &I18nOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "i18n",
+		Short: "Keep web translations in step with the code",
+		Long: `Keep the web app's translations in step with its code.
+
+Messages live in one JSON file per locale under ` + i18n.DefaultLocalesDir + ` (en.json,
+de.json, ...), nested by key segment, and the code reads them with
+t("settings.title") or <Trans i18nKey="settings.title">. English is the
+source locale: every other locale translates its keys.
+
+  extract   add the keys the code uses to the source locale
+  check     report undefined, unused, missing and extra keys (for CI)
+  sync      push the source locale to the translation service and pull
+            the translations back`,
+	}
+
+	cmd.PersistentFlags().StringVar(&opts.LocalesDir, "locales", i18n.DefaultLocalesDir, "Locale file directory, relative to the repository root")
+	cmd.PersistentFlags().StringVar(&opts.SourceDir, "src", i18n.DefaultSourceDir, "Source directory to scan, relative to the repository root")
+	cmd.PersistentFlags().StringVar(&opts.Source, "source-locale", i18n.DefaultSource, "Locale the code's messages are written in")
+
+	cmd.AddCommand(newI18nExtractCommand(opts))
+	cmd.AddCommand(newI18nCheckCommand(opts))
+	cmd.AddCommand(newI18nSyncCommand(opts))
+
+	return cmd
+}
+
+func newI18nExtractCommand(opts *I18nOptions) *cobra.Command {
+	var prune, literals bool
+
+	cmd := &cobra.Command{
+		Use:   "extract",
+		Short: "Add the message keys the code uses to the source locale",
+		Long: `Add the message keys the code uses but the source locale lacks, with empty
+messages to fill in. --prune also removes keys the code no longer uses from
+every locale; keys under a prefix the code builds at run time, as in
+` + "t(`connectors.${source}`)" + `, are kept.
+
+--literals instead lists user-facing text written straight into JSX (text
+nodes and placeholder, title, label, alt and aria-label props), to find what
+still needs moving into messages. It writes nothing.
+
+Examples:
+  ods i18n extract
+  ods i18n extract --prune
+  ods i18n extract --literals`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			runI18nExtract(opts, prune, literals)
+		},
+	}
+
+	cmd.Flags().BoolVar(&prune, "prune", false, "Remove keys the code no longer uses")
+	cmd.Flags().BoolVar(&literals, "literals", false, "List hardcoded user-facing text instead of extracting keys")
+
+	return cmd
+}
+
+func newI18nCheckCommand(opts *I18nOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "check",
+		Short: "Report drift between the code and the locale files",
+		Long: `Report drift between the code and the locale files, exiting non-zero if
+there is any:
+
+  undefined      keys the code uses that the source locale lacks
+  unused         source keys the code never uses
+  missing        keys a locale has no translation for (absent or empty)
+  extra          keys a locale has that the source locale does not
+  placeholders   translations interpolating different {arguments} than
+                 the source message
+
+Examples:
+  ods i18n check
+  ods i18n check --locales web/src/locales`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			runI18nCheck(opts)
+		},
+	}
+
+	return cmd
+}
+
+func newI18nSyncCommand(opts *I18nOptions) *cobra.Command {
+	var push, pull bool
+	var locales []string
+
+	cmd := &cobra.Command{
+		Use:   "sync",
+		Short: "Push the source locale to the translation service and pull translations",
+		Long: `Push the source locale to the translation service, so translators see new
+and changed messages, and pull the translations back into the locale files.
+With neither --push nor --pull, does both.
+
+Pulls every locale that has a file; --locale adds new ones. Pulled files are
+written sorted, like extract writes them.
+
+The service is set in the ods config:
+
+  "i18n": {"provider": "crowdin", "crowdin_project_id": 123456}
+
+with a personal access token in ` + i18n.CrowdinTokenEnv + `.
+
+Examples:
+  ods i18n sync
+  ods i18n sync --pull --locale ja`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if !push && !pull {
+				push, pull = true, true
+			}
+			runI18nSync(opts, push, pull, locales)
+		},
+	}
+
+	cmd.Flags().BoolVar(&push, "push", false, "Upload the source locale")
+	cmd.Flags().BoolVar(&pull, "pull", false, "Download translations")
+	cmd.Flags().StringSliceVar(&locales, "locale", nil, "Also pull these locales (e.g. ja, pt-BR)")
+
+	return cmd
+}
+
+// i18nDirs resolves the locale and source directories against the
+// repository root.
+func i18nDirs(opts *I18nOptions) (string, string) {
+	root, err := paths.GitRoot()
+	if err != nil {
+		log.Fatalf("Failed to find git root: %v", err)
+	}
+	return filepath.Join(root, filepath.FromSlash(opts.LocalesDir)), filepath.Join(root, filepath.FromSlash(opts.SourceDir))
+}
+
+func loadI18n(opts *I18nOptions, literals bool) (string, *i18n.Scan, map[string]i18n.Catalog) {
+	localesDir, srcDir := i18nDirs(opts)
+	scan, err := i18n.ScanSource(srcDir, literals)
+	if err != nil {
+		log.Fatalf("Failed to scan %s: %v", opts.SourceDir, err)
+	}
+	locales, err := i18n.LoadLocales(localesDir)
+	if err != nil {
+		log.Fatalf("Failed to read locale files: %v", err)
+	}
+	if _, ok := locales[opts.Source]; !ok {
+		locales[opts.Source] = i18n.Catalog{}
+	}
+	return localesDir, scan, locales
+}
+
+func runI18nExtract(opts *I18nOptions, prune, literals bool) {
+	localesDir, scan, locales := loadI18n(opts, literals)
+
+	if literals {
+		for _, l := range scan.Literals {
+			fmt.Printf("%s/%s:%d: %s\n", opts.SourceDir, l.File, l.Line, l.Text)
+		}
+		log.Infof("Found %d hardcoded string(s)", len(scan.Literals))
+		return
+	}
+
+	src := locales[opts.Source]
+	added, removed := i18n.Extract(scan, src, prune)
+	if len(added) == 0 && len(removed) == 0 {
+		log.Infof("%s.json already has the %d key(s) the code uses", opts.Source, len(src))
+		return
+	}
+	for _, key := range added {
+		fmt.Printf("+ %s\n", key)
+	}
+	for _, key := range removed {
+		fmt.Printf("- %s\n", key)
+	}
+
+	for locale, c := range locales {
+		if locale != opts.Source && (!prune || len(i18n.Prune(c, src)) == 0) {
+			continue
+		}
+		if err := i18n.SaveCatalog(i18n.LocalePath(localesDir, locale), c); err != nil {
+			log.Fatalf("Failed to write %s.json: %v", locale, err)
+		}
+	}
+	log.Infof("Added %d and removed %d key(s); fill in the new messages in %s", len(added), len(removed), filepath.Join(opts.LocalesDir, opts.Source+".json"))
+}
+
+func runI18nCheck(opts *I18nOptions) {
+	_, scan, locales := loadI18n(opts, false)
+	r := i18n.Check(scan, locales, opts.Source)
+
+	for _, u := range r.Undefined {
+		fmt.Printf("undefined     %s (%s/%s:%d)\n", u.Key, opts.SourceDir, u.File, u.Line)
+	}
+	for _, key := range r.Unused {
+		fmt.Printf("unused        %s\n", key)
+	}
+	for _, locale := range r.Locales() {
+		for _, key := range r.Missing[locale] {
+			fmt.Printf("missing       %s: %s\n", locale, key)
+		}
+		for _, key := range r.Extra[locale] {
+			fmt.Printf("extra         %s: %s\n", locale, key)
+		}
+		for _, key := range r.Placeholders[locale] {
+			fmt.Printf("placeholders  %s: %s\n", locale, key)
+		}
+	}
+	if n := r.Problems(); n > 0 {
+		log.Fatalf("Found %d translation issue(s); run ods i18n extract to add undefined keys", n)
+	}
+	log.Infof("%d key(s) used, %d locale(s) up to date", len(locales[opts.Source]), len(locales))
+}
+
+func runI18nSync(opts *I18nOptions, push, pull bool, extra []string) {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	service, err := i18n.NewService(cfg.I18n)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	localesDir, _ := i18nDirs(opts)
+	sourceName := opts.Source + ".json"
+
+	if push {
+		data, err := os.ReadFile(i18n.LocalePath(localesDir, opts.Source))
+		if err != nil {
+			log.Fatalf("Failed to read the source locale: %v", err)
+		}
+		if err := service.Push(sourceName, data); err != nil {
+			log.Fatalf("Failed to push %s: %v", sourceName, err)
+		}
+		log.Infof("Pushed %s", sourceName)
+	}
+	if !pull {
+		return
+	}
+
+	locales, err := i18n.LoadLocales(localesDir)
+	if err != nil {
+		log.Fatalf("Failed to read locale files: %v", err)
+	}
+	targets := map[string]bool{}
+	for locale := range locales {
+		targets[locale] = locale != opts.Source
+	}
+	for _, locale := range extra {
+		targets[locale] = true
+	}
+	var pulled []string
+	for _, locale := range slices.Sorted(maps.Keys(targets)) {
+		if !targets[locale] {
+			continue
+		}
+		data, err := service.Pull(sourceName, locale)
+		if err != nil {
+			log.Fatalf("Failed to pull %s: %v", locale, err)
+		}
+		c, err := i18n.ParseCatalog(data)
+		if err != nil {
+			log.Fatalf("The %s translation is not a locale file: %v", locale, err)
+		}
+		if err := i18n.SaveCatalog(i18n.LocalePath(localesDir, locale), c); err != nil {
+			log.Fatalf("Failed to write %s.json: %v", locale, err)
+		}
+		pulled = append(pulled, locale)
+	}
+	if len(pulled) == 0 {
+		log.Infof("No locales to pull; add one with --locale")
+		return
+	}
+	log.Infof("Pulled %s", strings.Join(pulled, ", "))
+}
@@ -0,0 +1,172 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/auditlog"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/impersonate"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/kube"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/portforward"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/portutil"
+)
+
+const (
+	apiServerContainerPort = 8080
+	maxImpersonateTTL      = time.Hour
+)
+
+// ImpersonateOptions holds options for the impersonate command.
+type ImpersonateOptions struct {
+	Context string
+	URL     string
+	Pod     string
+	Reason  string
+	TTL     time.Duration
+}
+
+// NewImpersonateCommand creates the impersonate command for minting a
+// short-lived support session as another user.
+func NewImpersonateCommand() *cobra.Command {
+	opts := &ImpersonateOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "impersonate <email>",
+		Short: "Mint a short-lived session as another user for support debugging",
+		Long: `Mint a short-lived session as another user for support debugging.
+
+Asks the control-plane API to mint a session as the user that the server
+expires after --ttl, records the action (with --reason) in the local audit
+log, and prints a one-time login URL for it. The control plane records the
+reason too.
+
+The command stays running until --ttl elapses so the session can be ended
+early: Ctrl-C, a kill or closing the terminal revokes it. If ods is stopped
+any other way, the session still expires on the server at --ttl.
+
+The control-plane API is reached through a port-forward to its pod in the
+control_plane context (or directly with --url) and authenticated with a
+short-lived JWT signed with DATA_PLANE_SECRET, which must be set.
+
+Requires: AWS SSO login, kubectl access to the control-plane cluster.
+
+Examples:
+  ods impersonate jane@customer.com --reason SUP-1234
+  ods impersonate jane@customer.com --reason SUP-1234 --ttl 5m`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			runImpersonate(args[0], opts)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.Context, "context", "c", "control_plane", "control-plane cluster context name (maps to KUBE_CTX_<NAME> env var)")
+	cmd.Flags().StringVar(&opts.URL, "url", "", "Control-plane API base URL (skips the port-forward)")
+	cmd.Flags().StringVar(&opts.Pod, "pod", "control-plane", "Substring of the control-plane API pod name")
+	cmd.Flags().StringVar(&opts.Reason, "reason", "", "Ticket or justification recorded in the audit log (required)")
+	cmd.Flags().DurationVar(&opts.TTL, "ttl", 15*time.Minute, "How long the session stays valid (max 1h)")
+	_ = cmd.MarkFlagRequired("reason")
+
+	return cmd
+}
+
+func runImpersonate(email string, opts *ImpersonateOptions) {
+	if !strings.Contains(email, "@") {
+		log.Fatalf("%q does not look like an email address", email)
+	}
+	reason := strings.TrimSpace(opts.Reason)
+	if reason == "" {
+		log.Fatal("--reason must not be empty")
+	}
+	if opts.TTL < time.Minute || opts.TTL > maxImpersonateTTL {
+		log.Fatalf("--ttl must be between 1m and %s, got %s", maxImpersonateTTL, opts.TTL)
+	}
+
+	client, cleanup := dialControlPlane(opts.Context, opts.URL, opts.Pod)
+	defer cleanup()
+
+	auditCtx := opts.Context
+	if opts.URL != "" {
+		auditCtx = opts.URL
+	}
+	if err := auditlog.Record(auditlog.Entry{
+		Action:  "impersonate.start",
+		Context: auditCtx,
+		Target:  email,
+		Detail:  fmt.Sprintf("reason=%s ttl=%s", reason, opts.TTL),
+	}); err != nil {
+		log.Fatalf("Refusing to impersonate without an audit record: %v", err)
+	}
+
+	login, err := impersonate.MintLogin(client, email, reason, auditlog.Actor(), opts.TTL)
+	if err != nil {
+		log.Fatalf("Failed to mint impersonation session for %s: %v", email, err)
+	}
+
+	fmt.Println()
+	fmt.Printf("Session for %s (expires %s). Open in a private window:\n\n", email, login.ExpiresAt.Local().Format(time.Kitchen))
+	fmt.Printf("  %s\n\n", login.LoginURL)
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	defer stop()
+
+	log.Info("Keeping the session open until it expires (Ctrl-C to end early)...")
+	end := "ended"
+	if expired, err := impersonate.Hold(ctx, client, login); expired {
+		end = "expired"
+	} else if err != nil {
+		log.Warnf("Failed to revoke the session (it expires on the server at %s): %v", login.ExpiresAt.Local().Format(time.Kitchen), err)
+	}
+	if err := auditlog.Record(auditlog.Entry{
+		Action:  "impersonate.end",
+		Context: auditCtx,
+		Target:  email,
+		Detail:  end,
+	}); err != nil {
+		log.Warnf("Failed to record end of session: %v", err)
+	}
+	log.Infof("Session %s", end)
+}
+
+// forwardAPIServer port-forwards a free local port to the api-server pod,
+// avoiding the reserved port (pass 0 for none). The forward moves to another
+// api-server pod if this one goes away. Exits on failure.
+func forwardAPIServer(c *kube.Cluster, pod string, reserved int) *portforward.Forward {
+	claimed := map[int]bool{}
+	if reserved != 0 {
+		claimed[reserved] = true
+	}
+	forwardPort, err := portutil.FindAvailable(18080, 100, claimed)
+	if err != nil {
+		log.Fatalf("Failed to find a port for the port-forward: %v", err)
+	}
+	pf, err := portforward.Start(c, portforward.Spec{
+		Name:   "api-server",
+		Target: "pod/" + pod,
+		Resolve: func() (string, error) {
+			pod, err := c.FindPod("api-server")
+			return "pod/" + pod, err
+		},
+		LocalPort:  forwardPort,
+		RemotePort: apiServerContainerPort,
+	})
+	if err != nil {
+		log.Fatalf("Failed to port-forward to %s: %v", pod, err)
+	}
+	return pf
+}
+
+// envOrDefault returns the environment variable value or a default.
+func envOrDefault(key, defaultValue string) string {
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return defaultValue
+}
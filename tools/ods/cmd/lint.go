@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"os"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/hooks"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/paths"
+)
+
+// ChecksOptions holds the options shared by ods lint and ods test.
+type ChecksOptions struct {
+	Staged bool
+	Since  string
+	Skip   []string
+}
+
+// NewLintCommand creates the lint command.
+func NewLintCommand() *cobra.Command {
+	opts := &ChecksOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "lint [area...]",
+		Short: "Run the linters for the backend, web app or ods",
+		Long: `Run the linters for an area of the repository:
+
+  backend   ruff check and ruff format --check
+  web       oxlint and oxfmt --check
+  tools     gofmt and go vet (tools/ods)
+
+With no areas, lints all of them. --staged or --since limit the run to
+areas with changed files, and the file-aware linters to just those files;
+if nothing relevant changed, nothing runs.
+
+Examples:
+  ods lint
+  ods lint web
+  ods lint --staged
+  ods lint --since origin/main`,
+		ValidArgs: hooks.AreaNames(),
+		Run: func(cmd *cobra.Command, args []string) {
+			runChecks(opts, "lint", args)
+		},
+	}
+
+	addChecksFlags(cmd, opts)
+
+	return cmd
+}
+
+func addChecksFlags(cmd *cobra.Command, opts *ChecksOptions) {
+	cmd.Flags().BoolVar(&opts.Staged, "staged", false, "Only check areas and files staged for commit")
+	cmd.Flags().StringVar(&opts.Since, "since", "", "Only check areas and files changed since this ref's merge base")
+	cmd.Flags().StringSliceVar(&opts.Skip, "skip", nil, "Areas to skip (also read from "+hooks.SkipEnv+")")
+	cmd.MarkFlagsMutuallyExclusive("staged", "since")
+}
+
+func runChecks(opts *ChecksOptions, kind string, names []string) {
+	root, err := paths.GitRoot()
+	if err != nil {
+		log.Fatalf("Failed to find git root: %v", err)
+	}
+	areas, err := hooks.Select(names)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	areas = hooks.Without(areas, opts.Skip)
+
+	var changed []string
+	switch {
+	case opts.Staged:
+		changed, err = hooks.StagedFiles(root)
+	case opts.Since != "":
+		changed, err = hooks.FilesSince(root, opts.Since)
+	}
+	if err != nil {
+		log.Fatalf("Failed to list changed files: %v", err)
+	}
+	runPlan(root, hooks.Plan(areas, kind, changed), kind)
+}
+
+// runPlan runs the planned checks, exiting non-zero if any fail.
+func runPlan(root string, cmds []hooks.Command, kind string) {
+	if len(cmds) == 0 {
+		log.Debugf("No relevant changes; nothing to %s", kind)
+		return
+	}
+	if err := hooks.Run(root, cmds, os.Stderr); err != nil {
+		log.Fatalf("ods %s: %v", kind, err)
+	}
+}
@@ -1,14 +1,36 @@
 package cmd
 
 import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/docker"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/kube"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/redact"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/runner"
 )
 
 // LogsOptions holds options for the logs command.
 type LogsOptions struct {
-	Follow bool
-	Tail   string
+	Follow   bool
+	Tail     string
+	NoRedact bool
+
+	// Cluster mode, used when Context is set.
+	Context    string
+	Since      time.Duration
+	Container  string
+	Previous   bool
+	Timestamps bool
 }
 
 // NewLogsCommand creates a new logs command for viewing docker container logs
@@ -17,12 +39,22 @@ func NewLogsCommand() *cobra.Command {
 
 	cmd := &cobra.Command{
 		Use:   "logs [service...]",
-		Short: "View logs from Onyx docker containers",
+		Short: "View logs from Onyx docker containers or cluster pods",
 		Long: `View logs from running Onyx docker containers.
 
 All arguments are treated as service names to filter logs.
 If no services are specified, logs from all services are shown.
 
+With --context, shows logs from pods in that cluster instead. Each argument
+is a pod name substring (e.g. api-server); every matching pod is included,
+whether or not it is ready, with lines prefixed by the pod name when more
+than one matches.
+
+Lines are redacted before they are printed: credentials, tokens, email
+addresses, card numbers and SSNs are replaced with placeholders, so output
+can be pasted into tickets. --no-redact shows them as logged; for a cluster
+it needs an active session (ods session start).
+
 Examples:
   # View logs from all services (follow mode)
   ods logs
@@ -37,18 +69,42 @@ Examples:
   ods logs --tail 100 api_server
 
   # View logs without following
-  ods logs --follow=false`,
+  ods logs --follow=false
+
+  # Last 10 minutes of api-server pod logs in the data plane
+  ods logs -c data_plane api-server --since 10m --follow=false
+
+  # Why did the previous instance of a crashing pod exit?
+  ods logs -c staging celery-worker-docfetching --previous`,
 		Args: cobra.ArbitraryArgs,
 		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 			return runningServiceNames(), cobra.ShellCompDirectiveNoFileComp
 		},
 		Run: func(cmd *cobra.Command, args []string) {
+			if opts.NoRedact {
+				allowUnredacted(opts.Context)
+			}
+			if opts.Context != "" {
+				runKubeLogs(args, opts)
+				return
+			}
+			for _, name := range []string{"since", "container", "previous", "timestamps"} {
+				if cmd.Flags().Changed(name) {
+					log.Fatalf("--%s requires --context", name)
+				}
+			}
 			runComposeLogs(args, opts)
 		},
 	}
 
 	cmd.Flags().BoolVar(&opts.Follow, "follow", true, "Follow log output")
 	cmd.Flags().StringVar(&opts.Tail, "tail", "", "Number of lines to show from the end of the logs (e.g. 100)")
+	cmd.Flags().StringVarP(&opts.Context, "context", "c", "", "Show pod logs from this cluster context (maps to KUBE_CTX_<NAME> env var)")
+	cmd.Flags().DurationVar(&opts.Since, "since", 0, "Only show lines newer than this (e.g. 10m); requires --context")
+	cmd.Flags().StringVar(&opts.Container, "container", "", "Container to show (default: all containers of the pod); requires --context")
+	cmd.Flags().BoolVar(&opts.Previous, "previous", false, "Show the previous, terminated instance of the container; requires --context")
+	cmd.Flags().BoolVar(&opts.Timestamps, "timestamps", false, "Prefix lines with their timestamp; requires --context")
+	addNoRedactFlag(cmd, &opts.NoRedact)
 
 	return cmd
 }
@@ -65,5 +121,134 @@ func runComposeLogs(services []string, opts *LogsOptions) {
 	args = append(args, services...)
 
 	log.Info("Viewing container logs...")
-	execDockerCompose(args, nil)
+	if opts.NoRedact {
+		execDockerCompose(args, nil)
+		return
+	}
+	if err := redactedComposeLogs(composeDir(), args, os.Stdout); err != nil {
+		log.Fatalf("Docker compose failed: %v", err)
+	}
+}
+
+// redactedComposeLogs runs docker with args in dir, writing its output to w
+// with secrets redacted.
+func redactedComposeLogs(dir string, args []string, w io.Writer) error {
+	rw := redact.NewWriter(w)
+	err := runner.Or(docker.Runner).Run(runner.Cmd{Name: "docker", Args: args, Dir: dir, Stdout: rw, Stderr: rw})
+	_ = rw.Flush()
+	return err
+}
+
+// logsOutput is where log lines go: stdout, through redaction unless
+// noRedact.
+func logsOutput(noRedact bool) (io.Writer, func()) {
+	if noRedact {
+		return os.Stdout, func() {}
+	}
+	w := redact.NewWriter(os.Stdout)
+	return w, func() { _ = w.Flush() }
+}
+
+func runKubeLogs(substrings []string, opts *LogsOptions) {
+	if len(substrings) == 0 {
+		log.Fatal("Name at least one pod (substring) to show logs for")
+	}
+	logOpts := kube.LogOptions{
+		Container:     opts.Container,
+		AllContainers: opts.Container == "",
+		Follow:        opts.Follow && !opts.Previous,
+		Since:         opts.Since,
+		Previous:      opts.Previous,
+		Timestamps:    opts.Timestamps,
+	}
+	if opts.Tail != "" {
+		n, err := strconv.Atoi(opts.Tail)
+		if err != nil || n < 0 {
+			log.Fatalf("Invalid --tail %q: expected a number of lines", opts.Tail)
+		}
+		logOpts.Tail = n
+	}
+
+	c := clusterFromEnv(opts.Context)
+	if err := c.EnsureContext(); err != nil {
+		log.Fatalf("Failed to ensure cluster context: %v", err)
+	}
+	pods, err := c.ListPods()
+	if err != nil {
+		log.Fatalf("Failed to list pods: %v", err)
+	}
+	matched := kube.MatchPods(pods, substrings)
+	if len(matched) == 0 {
+		log.Fatalf("No pods in %s/%s match %s", c.Name, c.Namespace, strings.Join(substrings, ", "))
+	}
+
+	out, flush := logsOutput(opts.NoRedact)
+	if len(matched) == 1 {
+		err := c.Logs(matched[0], logOpts, out)
+		flush()
+		if err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
+	log.Infof("Showing logs from %d pods: %s", len(matched), strings.Join(matched, ", "))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	failed := 0
+	for _, pod := range matched {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := &prefixWriter{prefix: "[" + pod + "] ", mu: &mu, out: out}
+			err := c.Logs(pod, logOpts, w)
+			w.Flush()
+			if err != nil {
+				mu.Lock()
+				failed++
+				mu.Unlock()
+				log.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	flush()
+	if failed > 0 {
+		log.Fatalf("Failed to get logs from %d of %d pod(s)", failed, len(matched))
+	}
+}
+
+// prefixWriter writes each complete line to out with prefix, holding mu while
+// writing so lines from concurrent writers don't interleave.
+type prefixWriter struct {
+	prefix string
+	mu     *sync.Mutex
+	out    io.Writer
+	buf    []byte
+}
+
+func (w *prefixWriter) Write(p []byte) (int, error) {
+	w.buf = append(w.buf, p...)
+	for {
+		i := bytes.IndexByte(w.buf, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		w.writeLine(w.buf[:i+1])
+		w.buf = w.buf[i+1:]
+	}
+}
+
+// Flush writes any trailing partial line.
+func (w *prefixWriter) Flush() {
+	if len(w.buf) > 0 {
+		w.writeLine(append(w.buf, '\n'))
+		w.buf = nil
+	}
+}
+
+func (w *prefixWriter) writeLine(line []byte) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	_, _ = fmt.Fprintf(w.out, "%s%s", w.prefix, line)
 }
@@ -0,0 +1,22 @@
+package cmd
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/redact"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/runner"
+)
+
+func TestRedactedComposeLogs(t *testing.T) {
+	useDockerFake(t).On("docker compose -p onyx logs", runner.Response{Stdout: "api_server-1  | calling with sk-proj-abcdefghijklmnopqrstu failed\n"})
+
+	var out bytes.Buffer
+	if err := redactedComposeLogs("/r/deployment/docker_compose", []string{"compose", "-p", "onyx", "logs", "api_server"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(out.String(), "sk-proj-") || !strings.Contains(out.String(), redact.Placeholder) {
+		t.Errorf("expected the key to be redacted, got %q", out.String())
+	}
+}
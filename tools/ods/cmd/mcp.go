@@ -0,0 +1,94 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/auditlog"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/mcp"
+)
+
+// MCPOptions holds options for the mcp command.
+type MCPOptions struct {
+	Context string
+	Allow   []string
+}
+
+// NewMCPCommand creates the mcp command.
+func NewMCPCommand() *cobra.Command {
+	opts := &MCPOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "mcp",
+		Short: "Serve read-only operations tools to AI assistants over MCP",
+		Long: `Serve read-only operations tools to AI assistants over the Model Context
+Protocol, on stdin and stdout, so an assistant can answer operational
+questions with the same lookups as ods serve.
+
+Tools (only those named with --allow are offered):
+  whois        users by email fragment, or a tenant's admins
+  tenant_info  a tenant's admins and 30-day activity, documents and indexing
+  health       dependency probes from an api-server pod
+  search_logs  recent pod log lines containing some text
+  stats        an operations report (weekly-ops, tenant-growth, connector-health)
+
+Every tool call is recorded in the ods audit log, with the client's name,
+before it runs; a call that cannot be recorded is refused.
+
+Register it with your assistant as a stdio server, e.g. in its MCP config:
+  {"command": "ods", "args": ["mcp", "-c", "data_plane", "--allow", "whois,tenant_info,health"]}
+
+Requires: AWS SSO login, kubectl access to the EKS cluster.
+
+Examples:
+  ods mcp --allow whois,tenant_info,health,search_logs,stats
+  ods mcp -c prod_eu --allow health,stats`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			runMCP(opts)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.Context, "context", "c", "data_plane", "cluster context name (maps to KUBE_CTX_<NAME> env var)")
+	cmd.Flags().StringSliceVar(&opts.Allow, "allow", nil, "Tools to offer: "+strings.Join(mcp.ToolNames, ", "))
+	_ = cmd.MarkFlagRequired("allow")
+
+	return cmd
+}
+
+func runMCP(opts *MCPOptions) {
+	c := clusterFromEnv(opts.Context)
+	backend := &clusterBackend{c: c, context: opts.Context}
+	tools, err := mcp.OpsTools(backend, opts.Allow)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := c.EnsureContext(); err != nil {
+		log.Fatalf("Failed to ensure cluster context: %v", err)
+	}
+
+	server := mcp.NewServer("ods", Version, tools)
+	server.Audit = func(tool string, args json.RawMessage, client string) error {
+		if client == "" {
+			client = "unknown"
+		}
+		return auditlog.Record(auditlog.Entry{
+			Action:  "mcp." + tool,
+			Context: opts.Context,
+			Target:  string(args),
+			Detail:  "MCP client " + client,
+		})
+	}
+
+	// stdout carries the protocol; logs go to stderr. The client ends the
+	// session by closing stdin.
+	log.Infof("Serving %s over MCP for %s", strings.Join(opts.Allow, ", "), opts.Context)
+	if err := server.Serve(context.Background(), os.Stdin, os.Stdout); err != nil {
+		log.Fatalf("MCP server failed: %v", err)
+	}
+}
@@ -0,0 +1,226 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/alembic"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/auditlog"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/prompt"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/render"
+)
+
+// maxListedSchemas caps how many lagging schemas are listed individually.
+const maxListedSchemas = 50
+
+// MigrateStatusOptions holds options for the migrate status command.
+type MigrateStatusOptions struct {
+	Context    string
+	AllTenants bool
+	Tenant     string
+	Fix        bool
+	Jobs       int
+	BatchSize  int
+	Yes        bool
+	Notify     string
+	Watch      time.Duration
+}
+
+// NewMigrateCommand creates the parent migrate command for deployed
+// environments (`ods db` covers the local database).
+func NewMigrateCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "migrate",
+		Short: "Inspect and repair schema migrations on a cluster",
+	}
+
+	cmd.AddCommand(newMigrateStatusCommand())
+
+	return cmd
+}
+
+func newMigrateStatusCommand() *cobra.Command {
+	opts := &MigrateStatusOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "status",
+		Short: "Report schemas lagging behind the Alembic head",
+		Long: `Report which schemas are behind the Alembic head revision on a cluster.
+
+Runs on an api-server pod, so "head" is the revision of the deployed image.
+With --all-tenants every tenant schema is checked using the backend's own
+lagging-schema query; otherwise only --tenant, or the default schema of a
+single-tenant deployment. Schemas that have never been migrated show as
+"(none)", which usually means tenant provisioning failed part-way.
+
+--fix re-runs migrations for the lagging schemas only, in parallel batches
+(--jobs, --batch-size) via the backend's multi-tenant migration runner, then
+checks again. It asks for confirmation on production contexts unless --yes
+is passed, and is recorded in the local audit log.
+
+Requires: AWS SSO login, kubectl access to the EKS cluster.
+
+Examples:
+  ods migrate status --all-tenants --context data_plane
+  ods migrate status --tenant tenant_abcd1234
+  ods migrate status --all-tenants --fix --jobs 4 --notify slack:#oncall
+  ods migrate status --all-tenants --watch=30s`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			runMigrateStatus(opts)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.Context, "context", "c", "data_plane", "cluster context name (maps to KUBE_CTX_<NAME> env var)")
+	cmd.Flags().BoolVar(&opts.AllTenants, "all-tenants", false, "Check every tenant schema")
+	cmd.Flags().StringVar(&opts.Tenant, "tenant", "", "Check a single tenant schema")
+	cmd.Flags().BoolVar(&opts.Fix, "fix", false, "Re-run migrations for lagging schemas")
+	cmd.Flags().IntVarP(&opts.Jobs, "jobs", "j", 4, "Parallel migration processes for --fix")
+	cmd.Flags().IntVarP(&opts.BatchSize, "batch-size", "b", 50, "Schemas per migration process for --fix")
+	cmd.Flags().BoolVar(&opts.Yes, "yes", false, "Skip the confirmation prompt")
+	addNotifyFlag(cmd, &opts.Notify)
+	addWatchFlag(cmd, &opts.Watch)
+	cmd.MarkFlagsMutuallyExclusive("all-tenants", "tenant")
+	cmd.MarkFlagsMutuallyExclusive("watch", "fix")
+
+	return cmd
+}
+
+func runMigrateStatus(opts *MigrateStatusOptions) {
+	if opts.Tenant != "" {
+		validateTenantArg(opts.Tenant)
+	}
+	if opts.Jobs < 1 || opts.Jobs > 16 {
+		log.Fatalf("--jobs must be between 1 and 16, got %d", opts.Jobs)
+	}
+	if opts.BatchSize < 1 {
+		log.Fatalf("--batch-size must be at least 1, got %d", opts.BatchSize)
+	}
+
+	c := clusterFromEnv(opts.Context)
+	if err := c.EnsureContext(); err != nil {
+		log.Fatalf("Failed to ensure cluster context: %v", err)
+	}
+	auditCtx := c.Name + "/" + c.Namespace
+
+	log.Info("Finding api-server pod...")
+	pod, err := c.FindPod("api-server")
+	if err != nil {
+		log.Fatalf("Failed to find api-server pod: %v", err)
+	}
+
+	if opts.Watch > 0 {
+		runWatchable(opts.Watch, func() error {
+			status, err := alembic.RemoteStatus(c, pod, opts.AllTenants, opts.Tenant)
+			if err != nil {
+				return fmt.Errorf("failed to read schema revisions: %w", err)
+			}
+			printMigrateStatus(os.Stdout, auditCtx, status)
+			return nil
+		})
+		return
+	}
+
+	status, err := alembic.RemoteStatus(c, pod, opts.AllTenants, opts.Tenant)
+	if err != nil {
+		log.Fatalf("Failed to read schema revisions: %v", err)
+	}
+	printMigrateStatus(os.Stdout, auditCtx, status)
+
+	if len(status.Lagging) == 0 {
+		return
+	}
+	if !opts.Fix {
+		log.Fatalf("%d schema(s) are behind head; pass --fix to migrate them", len(status.Lagging))
+	}
+
+	if !opts.Yes && isProductionContext(opts.Context) {
+		if !prompt.Confirm(fmt.Sprintf("Migrate %d lagging schema(s) in %s to %s? (yes/no): ", len(status.Lagging), auditCtx, status.Head)) {
+			log.Info("Aborted.")
+			return
+		}
+	}
+
+	target := "default schema"
+	switch {
+	case opts.AllTenants:
+		target = "all tenants"
+	case opts.Tenant != "":
+		target = opts.Tenant
+	}
+	if err := auditlog.Record(auditlog.Entry{
+		Action:  "migrate.upgrade",
+		Context: auditCtx,
+		Target:  target,
+		Detail:  fmt.Sprintf("lagging=%d head=%s", len(status.Lagging), status.Head),
+	}); err != nil {
+		log.Fatalf("Refusing to migrate without an audit record: %v", err)
+	}
+
+	notifier := startNotifier(opts.Notify)
+	command := alembic.RemoteUpgradeCommand(status, opts.AllTenants, opts.Tenant, opts.Jobs, opts.BatchSize)
+	log.Infof("Running migrations on %s...", pod)
+	if err := c.ExecOnPodStreaming(pod, command...); err != nil {
+		log.Warnf("Migrations reported failures: %v", err)
+	}
+
+	after, err := alembic.RemoteStatus(c, pod, opts.AllTenants, opts.Tenant)
+	if err != nil {
+		log.Fatalf("Failed to re-check schema revisions: %v", err)
+	}
+	if len(after.Lagging) > 0 {
+		printMigrateStatus(os.Stdout, auditCtx, after)
+		log.Fatalf("%d schema(s) are still behind head", len(after.Lagging))
+	}
+	summary := fmt.Sprintf("%s: all %d schema(s) are at head (%s)", auditCtx, after.Total, after.Head)
+	log.Info(summary)
+	notifier.Done(summary)
+}
+
+func printMigrateStatus(w io.Writer, auditCtx string, status *alembic.SchemaStatus) {
+	atHead := status.Total - len(status.Lagging)
+	_, _ = fmt.Fprintf(w, "%s: %d/%d schema(s) at head %s\n", auditCtx, atHead, status.Total, status.Head)
+	if len(status.Lagging) == 0 {
+		return
+	}
+
+	counts := status.RevisionCounts()
+	revs := make([]string, 0, len(counts))
+	for rev := range counts {
+		revs = append(revs, rev)
+	}
+	sort.Slice(revs, func(i, j int) bool { return counts[revs[i]] > counts[revs[j]] })
+
+	_, _ = fmt.Fprintln(w, "\nLagging schemas by revision:")
+	byRev := render.NewTable()
+	byRev.Indent = "  "
+	for _, rev := range revs {
+		byRev.Row(revisionLabel(rev), counts[rev])
+	}
+	_ = byRev.Write(w)
+
+	names := status.LaggingSchemas()
+	_, _ = fmt.Fprintln(w)
+	schemas := render.NewTable("SCHEMA", "REVISION")
+	for i, name := range names {
+		if i == maxListedSchemas {
+			schemas.Row(fmt.Sprintf("... and %d more", len(names)-maxListedSchemas), "")
+			break
+		}
+		schemas.Row(name, revisionLabel(status.Lagging[name]))
+	}
+	_ = schemas.Write(w)
+}
+
+func revisionLabel(rev string) string {
+	if rev == "" {
+		return "(none)"
+	}
+	return rev
+}
@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/mockllm"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/portutil"
+)
+
+// mockLLMComposeFile is the compose override that points the stack at the
+// stub; `ods compose --mock-llm` adds it.
+const mockLLMComposeFile = "docker-compose.mock-llm.yml"
+
+// MockLLMServeOptions holds options for the mock-llm serve command.
+type MockLLMServeOptions struct {
+	Port        int
+	Host        string
+	Latency     time.Duration
+	ChunkDelay  time.Duration
+	FailureRate float64
+	Responses   string
+}
+
+// NewMockLLMCommand creates the parent mock-llm command.
+func NewMockLLMCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "mock-llm",
+		Short: "Run an OpenAI-compatible stub LLM for local development",
+		Long: `Run an OpenAI-compatible stub LLM for local development.
+
+The stub answers /v1/chat/completions (streamed or not) with canned
+responses, so the backend and web app can be developed without provider keys
+or spend. It can add latency and fail a fraction of requests to exercise
+retries and error handling.
+
+To wire a compose stack to it, start the stack with --mock-llm, which adds
+` + mockLLMComposeFile + `. That sets GEN_AI_API_KEY, so the API server
+creates a default OpenAI provider when none exists, and OPENAI_BASE_URL,
+which sends its requests to the stub. A natively-run backend needs the same
+two variables, with OPENAI_BASE_URL=http://localhost:<port>/v1.
+
+Examples:
+  ods mock-llm serve
+  ods compose dev --mock-llm`,
+	}
+
+	cmd.AddCommand(newMockLLMServeCommand())
+
+	return cmd
+}
+
+func newMockLLMServeCommand() *cobra.Command {
+	opts := &MockLLMServeOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve the stub until interrupted",
+		Long: `Serve the stub until interrupted.
+
+Replies come from --responses, a YAML list of rules tried in order against
+the last user message; the first whose regular expression matches wins:
+
+  - match: (?i)pto|vacation
+    reply: Employees get 25 days of PTO per year.
+  - match: (?i)^hello
+    reply: Hi! How can I help?
+
+Without a match the reply echoes the message. Failed requests alternate
+between a 500 and a 429.
+
+The stub listens on 127.0.0.1 by default. Docker Desktop forwards
+host.docker.internal to the host's loopback; with Docker Engine on Linux,
+containers reach the host through its bridge address instead, so pass
+--host 0.0.0.0 (or that address).
+
+Examples:
+  ods mock-llm serve
+  ods mock-llm serve --port 8001 --latency 200ms --failure-rate 0.1
+  ods mock-llm serve --responses ./mock-responses.yml --chunk-delay 50ms
+  ods mock-llm serve --host 0.0.0.0   # compose stack on Linux`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			runMockLLMServe(opts)
+		},
+	}
+
+	cmd.Flags().IntVar(&opts.Port, "port", 8001, "Port to listen on (the compose override reads MOCK_LLM_PORT)")
+	cmd.Flags().StringVar(&opts.Host, "host", "127.0.0.1", "Address to listen on")
+	cmd.Flags().DurationVar(&opts.Latency, "latency", 0, "Delay before each response")
+	cmd.Flags().DurationVar(&opts.ChunkDelay, "chunk-delay", 20*time.Millisecond, "Delay between streamed chunks")
+	cmd.Flags().Float64Var(&opts.FailureRate, "failure-rate", 0, "Fraction of requests (0-1) to fail")
+	cmd.Flags().StringVar(&opts.Responses, "responses", "", "YAML file of canned responses")
+
+	return cmd
+}
+
+func runMockLLMServe(opts *MockLLMServeOptions) {
+	cfg := mockllm.Config{
+		Latency:     opts.Latency,
+		ChunkDelay:  opts.ChunkDelay,
+		FailureRate: opts.FailureRate,
+	}
+	if opts.Responses != "" {
+		rules, err := mockllm.LoadRules(opts.Responses)
+		if err != nil {
+			log.Fatalf("Failed to load responses: %v", err)
+		}
+		cfg.Rules = rules
+	}
+	stub, err := mockllm.New(cfg)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	if !portutil.IsAvailable(opts.Port) {
+		log.Fatalf("Port %d is in use by %s; pick another with --port", opts.Port, portutil.ProcessOnPort(opts.Port))
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	server := &http.Server{
+		Addr:              fmt.Sprintf("%s:%d", opts.Host, opts.Port),
+		Handler:           logRequests(stub),
+		ReadHeaderTimeout: 30 * time.Second,
+	}
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- server.ListenAndServe() }()
+
+	log.Infof("Mock LLM listening on http://%s/v1 (%d rule(s), latency %s, failure rate %.0f%%)",
+		server.Addr, len(cfg.Rules), opts.Latency, opts.FailureRate*100)
+	if opts.Port != 8001 {
+		log.Infof("Set MOCK_LLM_PORT=%d for ods compose --mock-llm", opts.Port)
+	}
+
+	select {
+	case <-ctx.Done():
+	case err := <-serveErr:
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Mock LLM server failed: %v", err)
+		}
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_ = server.Shutdown(shutdownCtx)
+	log.Infof("Served %d completion(s)", stub.Requests())
+}
+
+// statusRecorder captures the status code written by a handler while still
+// letting streamed responses flush.
+type statusRecorder struct {
+	http.ResponseWriter
+	status int
+}
+
+func (r *statusRecorder) WriteHeader(status int) {
+	r.status = status
+	r.ResponseWriter.WriteHeader(status)
+}
+
+func (r *statusRecorder) Flush() {
+	if f, ok := r.ResponseWriter.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+// logRequests logs one line per request with its status and duration.
+func logRequests(h http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		rec := &statusRecorder{ResponseWriter: w, status: http.StatusOK}
+		h.ServeHTTP(rec, r)
+		log.Infof("%s %s %d (%s)", r.Method, r.URL.Path, rec.status, time.Since(start).Round(time.Millisecond))
+	})
+}
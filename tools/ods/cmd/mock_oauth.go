@@ -0,0 +1,231 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/apiclient"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/mockoauth"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/portutil"
+)
+
+const (
+	// mockOAuthComposeFile is the compose override that signs the stack in
+	// through the mock provider; `ods compose --mock-oauth` adds it.
+	mockOAuthComposeFile = "docker-compose.mock-oauth.yml"
+
+	defaultMockOAuthPort         = 8002
+	defaultMockOAuthClientID     = "ods-mock-oauth"
+	defaultMockOAuthClientSecret = "ods-mock-oauth-secret"
+)
+
+// MockOAuthOptions holds options shared by the mock-oauth subcommands.
+type MockOAuthOptions struct {
+	Port         int
+	ClientID     string
+	ClientSecret string
+}
+
+// NewMockOAuthCommand creates the parent mock-oauth command.
+func NewMockOAuthCommand() *cobra.Command {
+	opts := &MockOAuthOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "mock-oauth",
+		Short: "Run a mock OIDC identity provider for local SSO testing",
+		Long: `Run a mock OpenID Connect identity provider for local SSO testing.
+
+The provider signs in whichever email is picked on its login page, without a
+password, and supports what Onyx's OIDC login uses: discovery, the
+authorization code flow with PKCE, refresh tokens, userinfo and signed ID
+tokens. Only OIDC is mocked; SAML logins still need a real IdP.
+
+To sign a compose stack in through it, start the stack with --mock-oauth,
+which adds ` + mockOAuthComposeFile + `. Its settings seed the SSO
+provider only on a fresh database; on an existing one run "register", which
+adds the provider through the admin API.
+
+Examples:
+  ods mock-oauth serve
+  ods compose dev --mock-oauth
+  ods mock-oauth register`,
+	}
+
+	cmd.PersistentFlags().IntVar(&opts.Port, "port", defaultMockOAuthPort, "Port the provider listens on (the compose override reads MOCK_OAUTH_PORT)")
+	cmd.PersistentFlags().StringVar(&opts.ClientID, "client-id", defaultMockOAuthClientID, "OAuth client ID Onyx authenticates with")
+	cmd.PersistentFlags().StringVar(&opts.ClientSecret, "client-secret", defaultMockOAuthClientSecret, "OAuth client secret Onyx authenticates with")
+
+	cmd.AddCommand(newMockOAuthServeCommand(opts))
+	cmd.AddCommand(newMockOAuthRegisterCommand(opts))
+
+	return cmd
+}
+
+func newMockOAuthServeCommand(opts *MockOAuthOptions) *cobra.Command {
+	var host, publicURL string
+	var users []string
+
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve the provider until interrupted",
+		Long: `Serve the provider until interrupted.
+
+The login page offers a button per --user and a field for any other email.
+Redirect URIs are not checked against an allowlist.
+
+Endpoints other than the login page are advertised under whatever host the
+discovery document is fetched from, which Onyx requires to match its
+issuer: a containerized backend uses host.docker.internal, while the browser
+is sent to --public-url.
+
+The provider listens on 127.0.0.1 by default, since it signs in anyone who
+can reach it. Docker Desktop forwards host.docker.internal to the host's
+loopback; with Docker Engine on Linux, containers reach the host through its
+bridge address instead, so pass --host 0.0.0.0 (or that address).
+
+Examples:
+  ods mock-oauth serve
+  ods mock-oauth serve --user admin@example.com --user viewer@example.com
+  ods mock-oauth serve --host 0.0.0.0   # compose stack on Linux`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			runMockOAuthServe(opts, host, publicURL, users)
+		},
+	}
+
+	cmd.Flags().StringVar(&host, "host", "127.0.0.1", "Address to listen on")
+	cmd.Flags().StringVar(&publicURL, "public-url", "", "URL the browser reaches the provider at (default http://localhost:<port>)")
+	cmd.Flags().StringArrayVar(&users, "user", []string{"admin@example.com", "user@example.com"}, "Email offered on the login page (repeatable)")
+
+	return cmd
+}
+
+func newMockOAuthRegisterCommand(opts *MockOAuthOptions) *cobra.Command {
+	var name, backendHost string
+
+	cmd := &cobra.Command{
+		Use:   "register",
+		Short: "Add the provider to a running stack as an SSO provider",
+		Long: `Add the mock provider to a running stack as an OIDC SSO provider.
+
+Calls the local admin API (POST /admin/sso/provider) at $ONYX_API_URL
+(default ` + defaultBackendURL + `) with $ONYX_API_KEY, which must belong to
+an admin. The provider's discovery URL uses --backend-host, the host the API
+server reaches this machine at.
+
+Examples:
+  ods mock-oauth register
+  ods mock-oauth register --backend-host localhost   # natively-run backend`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			runMockOAuthRegister(opts, name, backendHost)
+		},
+	}
+
+	cmd.Flags().StringVar(&name, "name", "mock-oauth", "Provider name (the login URL path segment)")
+	cmd.Flags().StringVar(&backendHost, "backend-host", "host.docker.internal", "Host the API server reaches the provider at")
+
+	return cmd
+}
+
+func runMockOAuthServe(opts *MockOAuthOptions, host, publicURL string, users []string) {
+	if publicURL == "" {
+		publicURL = fmt.Sprintf("http://localhost:%d", opts.Port)
+	}
+	provider, err := mockoauth.New(mockoauth.Config{
+		ClientID:     opts.ClientID,
+		ClientSecret: opts.ClientSecret,
+		PublicURL:    publicURL,
+		Users:        users,
+	})
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	if !portutil.IsAvailable(opts.Port) {
+		log.Fatalf("Port %d is in use by %s; pick another with --port", opts.Port, portutil.ProcessOnPort(opts.Port))
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	server := &http.Server{
+		Addr:              fmt.Sprintf("%s:%d", host, opts.Port),
+		Handler:           logRequests(provider),
+		ReadHeaderTimeout: 30 * time.Second,
+	}
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- server.ListenAndServe() }()
+
+	log.Infof("Mock OIDC provider listening on %s", server.Addr)
+	log.Infof("Discovery URL (compose): %s", mockoauth.ConfigURL(fmt.Sprintf("http://host.docker.internal:%d", opts.Port)))
+	log.Infof("Discovery URL (native):  %s", mockoauth.ConfigURL(fmt.Sprintf("http://localhost:%d", opts.Port)))
+	log.Infof("Client ID %q, secret %q", opts.ClientID, opts.ClientSecret)
+
+	select {
+	case <-ctx.Done():
+	case err := <-serveErr:
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("Mock OIDC provider failed: %v", err)
+		}
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_ = server.Shutdown(shutdownCtx)
+}
+
+func runMockOAuthRegister(opts *MockOAuthOptions, name, backendHost string) {
+	body, err := json.Marshal(map[string]any{
+		"name":          name,
+		"display_name":  "Mock SSO (ods)",
+		"provider_type": "OIDC",
+		"config": map[string]any{
+			"client_id":         opts.ClientID,
+			"client_secret":     opts.ClientSecret,
+			"openid_config_url": mockoauth.ConfigURL(fmt.Sprintf("http://%s:%d", backendHost, opts.Port)),
+		},
+		"allowed_email_domains": []string{},
+	})
+	if err != nil {
+		log.Fatalf("Failed to marshal request: %v", err)
+	}
+
+	client := apiclient.New(envOrDefault("ONYX_API_URL", defaultBackendURL))
+	client.APIKey = os.Getenv("ONYX_API_KEY")
+	if client.APIKey == "" {
+		log.Fatal("Set ONYX_API_KEY to an admin's API key")
+	}
+	req, err := client.NewRequest(http.MethodPost, "/admin/sso/provider", body)
+	if err != nil {
+		log.Fatalf("Invalid request: %v", err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode >= 300 {
+		log.Fatalf("Failed to register the provider (%s): %s", resp.Status, apiclient.Pretty(data))
+	}
+
+	var created struct {
+		ID          int    `json:"id"`
+		RedirectURI string `json:"redirect_uri"`
+	}
+	_ = json.Unmarshal(data, &created)
+	log.Infof("Registered SSO provider %q (id %d) on %s", name, created.ID, client.BaseURL)
+	if created.RedirectURI != "" {
+		log.Infof("Login callback: %s", created.RedirectURI)
+	}
+}
@@ -0,0 +1,332 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/docker"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/kube"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/nginx"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/runner"
+)
+
+// NginxOptions holds options shared by the nginx subcommands.
+type NginxOptions struct {
+	// Context selects the ingress-nginx controller of a cluster instead of
+	// the compose stack's nginx.
+	Context string
+}
+
+// NewNginxCommand creates the parent nginx command.
+func NewNginxCommand() *cobra.Command {
+	opts := &NginxOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "nginx",
+		Short: "Inspect, validate, tail and reload the reverse proxy",
+		Long: `Debug the nginx reverse proxy in front of the api and web servers: the
+usual suspect for 413s, 502s and 504s.
+
+Works on the compose stack's nginx container by default, or with --context
+on the ingress-nginx controller pods of a cluster.`,
+	}
+
+	cmd.PersistentFlags().StringVarP(&opts.Context, "context", "c", "", "Use the ingress-nginx controller in this cluster context (maps to KUBE_CTX_<NAME> env var)")
+
+	cmd.AddCommand(newNginxConfigCommand(opts))
+	cmd.AddCommand(newNginxValidateCommand(opts))
+	cmd.AddCommand(newNginxLogsCommand(opts))
+	cmd.AddCommand(newNginxReloadCommand(opts))
+
+	return cmd
+}
+
+func newNginxConfigCommand(opts *NginxOptions) *cobra.Command {
+	var summary bool
+
+	cmd := &cobra.Command{
+		Use:   "config",
+		Short: "Show the effective nginx configuration",
+		Long: `Show the configuration nginx is running with, as rendered from the
+templates for the current profile (nginx -T), with every included file.
+
+--summary lists just the directives behind most proxy errors, with where
+they are set: client_max_body_size (413), proxy timeouts (504), buffering,
+listen ports, server names and the upstreams requests are routed to.
+
+Examples:
+  ods nginx config
+  ods nginx config --summary
+  ods nginx config -c data_plane --summary`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			t := resolveNginxTarget(opts)
+			out, err := t.exec(t.pods[0], "nginx", "-T")
+			if err != nil {
+				log.Fatalf("nginx -T failed on %s: %v\n%s", t.pods[0], err, out)
+			}
+			if !summary {
+				fmt.Print(out)
+				return
+			}
+			for _, d := range nginx.Summarize(out) {
+				fmt.Println(d)
+			}
+		},
+	}
+
+	cmd.Flags().BoolVar(&summary, "summary", false, "Show only limits, timeouts and routing")
+
+	return cmd
+}
+
+func newNginxValidateCommand(opts *NginxOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "validate",
+		Short: "Check the nginx configuration (nginx -t)",
+		Long: `Check the configuration on disk with nginx -t, e.g. after editing a
+template or the onyx-nginx-conf ConfigMap and before reloading.
+
+Examples:
+  ods nginx validate
+  ods nginx validate -c staging`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			t := resolveNginxTarget(opts)
+			if failed := t.validate(); failed > 0 {
+				log.Fatalf("nginx configuration is invalid on %d of %d target(s)", failed, len(t.pods))
+			}
+		},
+	}
+}
+
+func newNginxLogsCommand(opts *NginxOptions) *cobra.Command {
+	var statuses, path, since string
+	var slowerThan time.Duration
+	var errors, follow, noRedact bool
+	var tail int
+
+	cmd := &cobra.Command{
+		Use:   "logs",
+		Short: "Tail nginx access and error logs, filtered",
+		Long: `Tail nginx's access and error logs, keeping only the lines that match
+the filters. Access filters (--status, --path, --slower-than) must all
+match; --errors adds error log lines of warn severity and above, such as
+"upstream timed out" or "client intended to send too large body".
+Lines are redacted as by ods logs unless --no-redact is given.
+
+Examples:
+  # Follow failing requests
+  ods nginx logs --status 5xx,413 --errors -f
+
+  # Slow chat requests in the last hour
+  ods nginx logs --path /api/chat --slower-than 10s --since 1h
+
+  # 404s from the staging ingress controller
+  ods nginx logs -c staging --status 404 --tail 1000`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			filter := nginx.Filter{Path: path, SlowerThan: slowerThan, Errors: errors}
+			if statuses != "" {
+				var err error
+				if filter.Statuses, err = nginx.ParseStatuses(statuses); err != nil {
+					log.Fatalf("Invalid --status: %v", err)
+				}
+			}
+			var sinceDur time.Duration
+			if since != "" {
+				var err error
+				if sinceDur, err = time.ParseDuration(since); err != nil {
+					log.Fatalf("Invalid --since %q: %v", since, err)
+				}
+			}
+			if noRedact {
+				allowUnredacted(opts.Context)
+			}
+			t := resolveNginxTarget(opts)
+			t.logs(filter, follow, tail, sinceDur, noRedact)
+		},
+	}
+
+	cmd.Flags().StringVar(&statuses, "status", "", "Keep requests with these statuses or classes (e.g. 5xx,404)")
+	cmd.Flags().StringVar(&path, "path", "", "Keep requests whose path contains this")
+	cmd.Flags().DurationVar(&slowerThan, "slower-than", 0, "Keep requests that took longer than this (e.g. 5s)")
+	cmd.Flags().BoolVar(&errors, "errors", false, "Keep error log lines of warn severity and above")
+	cmd.Flags().BoolVarP(&follow, "follow", "f", false, "Follow log output")
+	cmd.Flags().IntVar(&tail, "tail", 0, "Number of lines to read from the end of the logs before filtering (0 for all)")
+	cmd.Flags().StringVar(&since, "since", "", "Only read lines newer than this (e.g. 10m, 1h)")
+	addNoRedactFlag(cmd, &noRedact)
+
+	return cmd
+}
+
+func newNginxReloadCommand(opts *NginxOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "reload",
+		Short: "Validate and reload the nginx configuration",
+		Long: `Validate the configuration with nginx -t and, if it passes everywhere,
+reload it with nginx -s reload. Open connections finish on the old
+configuration.
+
+In a cluster this picks up an edited onyx-nginx-conf ConfigMap without
+restarting the controller, once the kubelet has synced the mounted volume
+(up to a minute after the edit).
+
+Examples:
+  ods nginx reload
+  ods nginx reload -c data_plane`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			t := resolveNginxTarget(opts)
+			if failed := t.validate(); failed > 0 {
+				log.Fatalf("Not reloading: nginx configuration is invalid on %d of %d target(s)", failed, len(t.pods))
+			}
+			for _, pod := range t.pods {
+				if out, err := t.exec(pod, "nginx", "-s", "reload"); err != nil {
+					log.Fatalf("Failed to reload nginx on %s: %v\n%s", pod, err, out)
+				}
+				log.Infof("Reloaded nginx on %s", pod)
+			}
+		},
+	}
+}
+
+// nginxTarget is where nginx runs: the compose stack's nginx container, or
+// a cluster's ingress-nginx controller pods.
+type nginxTarget struct {
+	// cluster is nil for the compose stack.
+	cluster *kube.Cluster
+	// pods are the controller pods, or the nginx container's name.
+	pods []string
+}
+
+// ingressControllerSelector matches ingress-nginx controller pods; the chart
+// names them <release>-nginx-controller-*.
+const ingressControllerSelector = "app.kubernetes.io/component=controller"
+
+func resolveNginxTarget(opts *NginxOptions) *nginxTarget {
+	if opts.Context == "" {
+		container := fmt.Sprintf("%s-nginx-1", docker.ProjectName())
+		out, err := runner.Output(docker.Runner, runner.Cmd{Name: "docker", Args: []string{"inspect", "-f", "{{.State.Running}}", container}})
+		if err != nil || strings.TrimSpace(string(out)) != "true" {
+			log.Fatalf("nginx is not running in the %s compose project; start the stack with: ods compose", docker.ProjectName())
+		}
+		return &nginxTarget{pods: []string{container}}
+	}
+
+	c := clusterFromEnv(opts.Context)
+	if err := c.EnsureContext(); err != nil {
+		log.Fatalf("Failed to ensure cluster context: %v", err)
+	}
+	pods, err := c.ListPodsWithSelector(ingressControllerSelector)
+	if err != nil {
+		log.Fatalf("Failed to list ingress controller pods: %v", err)
+	}
+	t := &nginxTarget{cluster: c}
+	for _, p := range pods {
+		if p.Phase == "Running" && strings.Contains(p.Name, "nginx") {
+			t.pods = append(t.pods, p.Name)
+		}
+	}
+	if len(t.pods) == 0 {
+		log.Fatalf("No running ingress-nginx controller pods in %s/%s", c.Name, c.Namespace)
+	}
+	return t
+}
+
+// exec runs a command in the nginx container or pod, returning its stdout
+// and stderr together, as nginx reports on stderr.
+func (t *nginxTarget) exec(pod string, command ...string) (string, error) {
+	if t.cluster != nil {
+		return t.cluster.ExecOnPodCombined(pod, command...)
+	}
+	out, err := runner.CombinedOutput(docker.Runner, runner.Cmd{Name: "docker", Args: append([]string{"exec", pod}, command...)})
+	return string(out), err
+}
+
+// validate runs nginx -t everywhere, printing the results, and returns the
+// number of targets where it failed.
+func (t *nginxTarget) validate() int {
+	failed := 0
+	for _, pod := range t.pods {
+		out, err := t.exec(pod, "nginx", "-t")
+		if err != nil {
+			failed++
+			log.Errorf("%s:\n%s", pod, strings.TrimSpace(out))
+			continue
+		}
+		log.Infof("%s: configuration is valid", pod)
+	}
+	return failed
+}
+
+// dockerLogsArgs returns the docker logs arguments for container.
+func dockerLogsArgs(container string, follow bool, tail int, since time.Duration) []string {
+	args := []string{"logs"}
+	if follow {
+		args = append(args, "--follow")
+	}
+	if tail > 0 {
+		args = append(args, "--tail", strconv.Itoa(tail))
+	}
+	if since > 0 {
+		args = append(args, "--since", since.String())
+	}
+	return append(args, container)
+}
+
+// logs streams the nginx logs through filter, prefixing lines with the pod
+// name when there is more than one controller.
+func (t *nginxTarget) logs(filter nginx.Filter, follow bool, tail int, since time.Duration, noRedact bool) {
+	out, flush := logsOutput(noRedact)
+	if t.cluster == nil {
+		// nginx logs requests to stdout and errors to stderr; both go
+		// through the same filter.
+		w := nginx.NewFilterWriter(out, filter)
+		cmd := runner.Cmd{Name: "docker", Args: dockerLogsArgs(t.pods[0], follow, tail, since), Stdout: w, Stderr: w}
+		err := runner.Or(docker.Runner).Run(cmd)
+		_ = w.Flush()
+		flush()
+		if err != nil {
+			log.Fatalf("docker logs failed: %v", err)
+		}
+		return
+	}
+
+	logOpts := kube.LogOptions{Follow: follow, Tail: tail, Since: since}
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	failed := 0
+	for _, pod := range t.pods {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			prefix := ""
+			if len(t.pods) > 1 {
+				prefix = "[" + pod + "] "
+			}
+			pw := &prefixWriter{prefix: prefix, mu: &mu, out: out}
+			w := nginx.NewFilterWriter(pw, filter)
+			err := t.cluster.Logs(pod, logOpts, w)
+			_ = w.Flush()
+			pw.Flush()
+			if err != nil {
+				mu.Lock()
+				failed++
+				mu.Unlock()
+				log.Error(err)
+			}
+		}()
+	}
+	wg.Wait()
+	flush()
+	if failed > 0 {
+		log.Fatalf("Failed to get logs from %d of %d pod(s)", failed, len(t.pods))
+	}
+}
@@ -0,0 +1,33 @@
+package cmd
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/runner"
+)
+
+func TestComposeNginxTarget(t *testing.T) {
+	f := useDockerFake(t).
+		On("docker exec onyx-nginx-1 nginx -t", runner.Response{Stderr: "nginx: configuration file /etc/nginx/nginx.conf test failed\n", Err: errors.New("exit status 1")})
+	target := &nginxTarget{pods: []string{"onyx-nginx-1"}}
+	if failed := target.validate(); failed != 1 {
+		t.Errorf("validate() = %d failures, want 1", failed)
+	}
+	if got := f.Lines(); len(got) != 1 || got[0] != "docker exec onyx-nginx-1 nginx -t" {
+		t.Errorf("commands = %q", got)
+	}
+}
+
+func TestDockerLogsArgs(t *testing.T) {
+	got := dockerLogsArgs("onyx-nginx-1", true, 100, 10*time.Minute)
+	want := []string{"logs", "--follow", "--tail", "100", "--since", "10m0s", "onyx-nginx-1"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("dockerLogsArgs() = %q, want %q", got, want)
+	}
+	if got := dockerLogsArgs("onyx-nginx-1", false, 0, 0); !reflect.DeepEqual(got, []string{"logs", "onyx-nginx-1"}) {
+		t.Errorf("dockerLogsArgs() without options = %q", got)
+	}
+}
@@ -0,0 +1,105 @@
+package cmd
+
+import (
+	"os"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/auditlog"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/config"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/notify"
+)
+
+// addNotifyFlag registers --notify on a long-running command.
+func addNotifyFlag(cmd *cobra.Command, spec *string) {
+	cmd.Flags().StringVar(spec, "notify", "", "Post the result when done, e.g. slack:#oncall (default: notify.default in the ods config)")
+}
+
+// runNotifier posts a command's outcome when it finishes. A nil runNotifier
+// does nothing, so callers need not check whether notifications are on.
+type runNotifier struct {
+	target  notify.Target
+	url     string
+	command string
+	start   time.Time
+	failure *fatalCapture
+}
+
+// startNotifier resolves spec (or the configured default) and arranges for
+// a failure notice if the command exits through log.Fatal. It returns nil
+// when notifications are off.
+func startNotifier(spec string) *runNotifier {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Warnf("Failed to load ods config: %v", err)
+		cfg = &config.Config{}
+	}
+	if spec == "" {
+		spec = cfg.Notify.Default
+	}
+	if spec == "" {
+		return nil
+	}
+
+	target, err := notify.ParseTarget(spec)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	url, err := notify.WebhookURL(target, cfg.Notify)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	n := &runNotifier{
+		target:  target,
+		url:     url,
+		command: "ods " + strings.Join(os.Args[1:], " "),
+		start:   time.Now(),
+		failure: &fatalCapture{},
+	}
+	log.AddHook(n.failure)
+	log.RegisterExitHandler(func() {
+		n.send(false, n.failure.message)
+	})
+	return n
+}
+
+// Done posts a success notice with summary.
+func (n *runNotifier) Done(summary string) {
+	if n == nil {
+		return
+	}
+	n.send(true, summary)
+}
+
+func (n *runNotifier) send(ok bool, summary string) {
+	msg := notify.Message{
+		Command:  n.command,
+		OK:       ok,
+		Duration: time.Since(n.start),
+		Summary:  summary,
+		Actor:    auditlog.Actor(),
+	}
+	if err := notify.Send(n.url, n.target, msg); err != nil {
+		log.Warnf("Failed to notify %s: %v", n.target, err)
+		return
+	}
+	log.Debugf("Notified %s", n.target)
+}
+
+// fatalCapture is a logrus hook remembering the message that ended the run.
+type fatalCapture struct {
+	message string
+}
+
+func (f *fatalCapture) Levels() []log.Level {
+	return []log.Level{log.FatalLevel, log.PanicLevel}
+}
+
+func (f *fatalCapture) Fire(e *log.Entry) error {
+	f.message = e.Message
+	return nil
+}
@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/oauthcheck"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/render"
+)
+
+// OAuthCheckOptions holds options for the oauth check command.
+type OAuthCheckOptions struct {
+	Context string
+	Tenant  string
+	JSON    bool
+}
+
+// NewOAuthCommand creates the parent oauth command.
+func NewOAuthCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "oauth",
+		Short: "Inspect the OAuth apps a deployment is configured with",
+	}
+
+	cmd.AddCommand(newOAuthCheckCommand())
+
+	return cmd
+}
+
+func newOAuthCheckCommand() *cobra.Command {
+	opts := &OAuthCheckOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "check [provider]",
+		Short: "Validate OAuth client IDs, secrets, redirect URIs and scopes",
+		Long: `Validate the OAuth apps of a deployment against WEB_DOMAIN and their providers.
+
+Covered are the Google login app (google-login, from OAUTH_CLIENT_ID), the
+Linear connector app (linear-connector, from LINEAR_CLIENT_ID) and the
+external apps (slack, google_drive, gmail, google_calendar, linear, github,
+hubspot, notion), whether their client comes from the EXT_APP_* settings or
+an admin-configured app. Pass a provider to check only its apps. SSO
+providers are checked by ods sso-debug.
+
+For each app the configuration is fetched from an api-server pod, with the
+secret reduced to whether it is set, and checked against WEB_DOMAIN and the
+provider's client ID format. The provider is then probed from the
+api-server's network, without anyone signing in:
+
+  authorize  the request a login would make is sent; Google, Slack and
+             others refuse an unregistered redirect URI, an unknown client
+             or an unknown scope before showing a login page.
+  token      a made-up authorization code is exchanged; providers check the
+             client ID and secret, and most the redirect URI, before the
+             code, so an invalid-code error means the rest is right.
+
+The command exits non-zero when anything is found. On a single-tenant
+deployment omit --tenant; the tenant only matters for external apps.
+
+Requires: AWS SSO login, kubectl access to the EKS cluster.
+
+Examples:
+  ods oauth check
+  ods oauth check slack --tenant tenant_abcd1234
+  ods oauth check google-login -c staging --json`,
+		Args: cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			provider := ""
+			if len(args) == 1 {
+				provider = args[0]
+			}
+			runOAuthCheck(opts, provider)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.Context, "context", "c", "data_plane", "cluster context name (maps to KUBE_CTX_<NAME> env var)")
+	cmd.Flags().StringVar(&opts.Tenant, "tenant", "", "Tenant schema (omit on single-tenant deployments)")
+	cmd.Flags().BoolVar(&opts.JSON, "json", false, "Print the apps as JSON")
+
+	return cmd
+}
+
+func runOAuthCheck(opts *OAuthCheckOptions, provider string) {
+	if opts.Tenant != "" {
+		validateTenantArg(opts.Tenant)
+	}
+	provider = strings.ToLower(provider)
+
+	c := clusterFromEnv(opts.Context)
+	if err := c.EnsureContext(); err != nil {
+		log.Fatalf("Failed to ensure cluster context: %v", err)
+	}
+	log.Info("Finding api-server pod...")
+	pod, err := c.FindPod("api-server")
+	if err != nil {
+		log.Fatalf("Failed to find api-server pod: %v", err)
+	}
+
+	log.Info("Fetching and probing OAuth apps...")
+	r, err := oauthcheck.Inspect(c, pod, opts.Tenant, provider)
+	if err != nil {
+		log.Fatalf("Failed to inspect OAuth apps: %v", err)
+	}
+	if len(r.Apps) == 0 {
+		if provider != "" {
+			log.Fatalf("No OAuth app for provider %q; see ods oauth check --help for the provider names", provider)
+		}
+		log.Fatal("No OAuth apps are configured")
+	}
+	if opts.JSON {
+		if err := render.JSON(os.Stdout, r); err != nil {
+			log.Fatalf("Failed to marshal apps: %v", err)
+		}
+		return
+	}
+
+	fmt.Printf("WEB_DOMAIN: %s\n", r.WebDomain)
+	found := 0
+	if f := r.DomainFindings(); len(f) > 0 {
+		found += printFindings(f)
+	}
+	for i := range r.Apps {
+		a := &r.Apps[i]
+		printOAuthApp(a)
+		found += printFindings(a.Findings(r.WebDomain))
+	}
+
+	if found > 0 {
+		exitWith(1)
+	}
+}
+
+func printOAuthApp(a *oauthcheck.App) {
+	fmt.Printf("\n%s (%s, %s)\n", a.Label, a.Provider, a.Source)
+	if a.ClientID == "" {
+		return
+	}
+	secret := "set"
+	if !a.HasClientSecret {
+		secret = "not set"
+	}
+	fmt.Printf("  Client ID:        %s (secret %s)\n", a.ClientID, secret)
+	fmt.Printf("  Redirect URI:     %s\n", a.RedirectURI)
+	if a.Scope != "" {
+		fmt.Printf("  Scope:            %s\n", a.Scope)
+	}
+	fmt.Printf("  Authorize URL:    %s\n", a.AuthorizeURL)
+	fmt.Printf("  Token URL:        %s\n", a.TokenURL)
+	if v := a.AuthorizeVerdict(); strings.HasPrefix(v, "ok") {
+		fmt.Printf("  Authorize check:  %s\n", strings.TrimPrefix(v, "ok: "))
+	}
+	if v := a.TokenVerdict(); strings.HasPrefix(v, "ok") {
+		fmt.Printf("  Token check:      %s\n", strings.TrimPrefix(v, "ok: "))
+	}
+}
@@ -0,0 +1,137 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/alembic"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/consistency"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/domains"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/golden"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/tenant"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/trace"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/whois"
+)
+
+func TestWhoisOutput(t *testing.T) {
+	tests := []struct {
+		name string
+		kind string
+		rows []string
+	}{
+		{"whois_email", whois.KindEmail, []string{
+			"alice@example.com\ttenant_1b2c3d\ttrue",
+			"bob@example.org\ttenant_9f8e7d6c5b\tfalse",
+		}},
+		{"whois_tenant", whois.KindTenant, []string{"admin@example.com", "ops@example.com"}},
+		{"whois_empty", whois.KindEmail, nil},
+	}
+	for _, tt := range tests {
+		var buf bytes.Buffer
+		printWhoisResult(&buf, tt.kind, tt.rows)
+		golden.Assert(t, tt.name, buf.Bytes())
+	}
+}
+
+func TestTenantCreatedOutput(t *testing.T) {
+	var buf bytes.Buffer
+	printTenantCreated(&buf, &tenant.Created{
+		TenantID:  "tenant_1b2c3d",
+		Email:     "admin@example.com",
+		Role:      "admin",
+		Password:  "s3cret-Passw0rd",
+		WebDomain: "https://cloud.example.com/",
+	})
+	golden.Assert(t, "tenant_create", buf.Bytes())
+}
+
+func TestMigrateStatusOutput(t *testing.T) {
+	var buf bytes.Buffer
+	printMigrateStatus(&buf, "prod-us-east", &alembic.SchemaStatus{
+		Head:        "a1b2c3d4e5f6",
+		MultiTenant: true,
+		Total:       5,
+		Lagging: map[string]string{
+			"tenant_aaa":   "0f9e8d7c6b5a",
+			"tenant_bbb":   "0f9e8d7c6b5a",
+			"tenant_fresh": "",
+		},
+	})
+	golden.Assert(t, "migrate_status", buf.Bytes())
+
+	lagging := map[string]string{}
+	for i := 0; i < maxListedSchemas+3; i++ {
+		lagging[fmt.Sprintf("tenant_%03d", i)] = "0f9e8d7c6b5a"
+	}
+	buf.Reset()
+	printMigrateStatus(&buf, "prod-us-east", &alembic.SchemaStatus{Head: "a1b2c3d4e5f6", Total: 60, Lagging: lagging})
+	golden.Assert(t, "migrate_status_truncated", buf.Bytes())
+}
+
+func TestDomainsJSONOutput(t *testing.T) {
+	var buf bytes.Buffer
+	printDomainsJSON(&buf, nil)
+	golden.Assert(t, "domains_empty_json", buf.Bytes())
+
+	buf.Reset()
+	notAfter := time.Date(2026, 12, 1, 0, 0, 0, 0, time.UTC)
+	printDomainsJSON(&buf, []domains.Domain{{
+		Host:         "chat.acme.example.com",
+		Tenant:       "tenant_1b2c3d",
+		Ingress:      "custom-domain-acme",
+		LoadBalancer: []string{"lb-123.elb.amazonaws.com"},
+		SecretName:   "acme-tls",
+		CertManager: &domains.CertManager{
+			Name:        "acme-tls",
+			Issuer:      "letsencrypt-prod",
+			Ready:       true,
+			RenewalTime: notAfter.Add(-30 * 24 * time.Hour),
+		},
+		Stored: &domains.Cert{NotAfter: notAfter},
+	}})
+	golden.Assert(t, "domains_json", buf.Bytes())
+}
+
+func TestConsistencyReportOutput(t *testing.T) {
+	var buf bytes.Buffer
+	printConsistencyReport(&buf, "control_plane", &consistency.Report{
+		ControlPlaneTenants: 42,
+		Unchecked:           3,
+		DataPlanes: []consistency.PlaneSummary{
+			{Context: "us", Route: "us-east-1", Schemas: 30, Routed: 29},
+			{Context: "eu", Route: "eu-west-1", Schemas: 11, Routed: 10},
+		},
+		Findings: []consistency.Finding{
+			{Kind: consistency.KindMissingSchema, Tenant: "tenant_1b2c3d", Detail: "has no route and no schema in any checked data plane"},
+			{Kind: consistency.KindStaleCopy, Tenant: "tenant_9f8e7d", DataPlane: "us", Detail: "has a schema here but is routed to eu",
+				Cleanup: "ods tenant delete tenant_9f8e7d -c us"},
+		},
+	})
+	golden.Assert(t, "consistency_report", buf.Bytes())
+
+	buf.Reset()
+	printConsistencyReport(&buf, "control_plane", &consistency.Report{
+		ControlPlaneTenants: 1,
+		DataPlanes:          []consistency.PlaneSummary{{Context: "data_plane", Route: "data_plane", Schemas: 1, Routed: 1}},
+	})
+	golden.Assert(t, "consistency_report_clean", buf.Bytes())
+}
+
+func TestTraceOutput(t *testing.T) {
+	var buf bytes.Buffer
+	printTrace(&buf, &trace.Trace{
+		Host:   "onyx.acme.com",
+		Tenant: "tenant_1b2c3d",
+		Hops: []trace.Hop{
+			{Name: "dns", Target: "onyx.acme.com", State: trace.StateOK, Detail: "abc.elb.amazonaws.com -> 203.0.113.7"},
+			{Name: "load-balancer", Target: "abc.elb.amazonaws.com", State: trace.StateOK, Detail: "accepts connections on port 443"},
+			{Name: "ingress", Target: "acme-domain", State: trace.StateOK, Detail: "class nginx; / -> web-server:3000"},
+			{Name: "service", Target: "web-server:3000", State: trace.StateOK, Detail: "ClusterIP, port 3000 -> target port 3000"},
+			{Name: "pods", Target: "app=web-server", State: trace.StateFail, Detail: "0/2 ready; web-server-7d9f-abcde (web-server: CrashLoopBackOff)"},
+			{Name: "http", Target: "https://onyx.acme.com/", State: trace.StateFail, Detail: "503: the ingress controller could not get an answer from the backend"},
+		},
+	})
+	golden.Assert(t, "dns_trace", buf.Bytes())
+}
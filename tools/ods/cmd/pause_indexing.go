@@ -0,0 +1,284 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/auditlog"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/kube"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/pauseindexing"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/prompt"
+)
+
+const pauseIndexingPollInterval = 10 * time.Second
+
+// PauseIndexingOptions holds options for the pause-tenant-indexing and
+// resume-tenant-indexing commands.
+type PauseIndexingOptions struct {
+	Context    string
+	AllTenants bool
+	Reason     string
+	Wait       time.Duration
+	Status     bool
+	Yes        bool
+}
+
+// NewPauseTenantIndexingCommand creates the pause-tenant-indexing command.
+func NewPauseTenantIndexingCommand() *cobra.Command {
+	opts := &PauseIndexingOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "pause-tenant-indexing [tenant...]",
+		Short: "Pause indexing for tenants or every tenant",
+		Long: `Pause indexing for the given tenants, or every tenant with --all-tenants,
+e.g. during database maintenance, without scaling the workers down.
+
+A tenant is paused the way the admin UI pauses a connector: its active
+connectors are set to PAUSED, so no new indexing attempts are scheduled for
+them, and running attempts stop at their next batch with their checkpoint
+saved, to carry on from there after resuming. The connectors paused are
+recorded in the tenant's Redis with --reason, so ` + "`ods resume-tenant-indexing`" + `
+restores exactly those and leaves connectors an admin paused alone.
+
+The command then waits up to --wait for the workers to stop every running
+attempt of the paused connectors. Attempts for a pending embedding model
+switch are not stopped by a pause.
+
+With --status, only report what is paused and what is still running.
+On a single-tenant deployment name no tenants.
+
+Requires: AWS SSO login, kubectl access to the EKS cluster.
+
+Examples:
+  ods pause-tenant-indexing tenant_abcd1234 --reason "pg upgrade" -c prod
+  ods pause-tenant-indexing --all-tenants --reason "pg upgrade" -c prod
+  ods pause-tenant-indexing --status --all-tenants -c prod`,
+		Run: func(cmd *cobra.Command, args []string) {
+			if opts.Status {
+				runIndexingStatus(opts, args)
+				return
+			}
+			runPauseIndexing(opts, args)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.Context, "context", "c", "data_plane", "cluster context name (maps to KUBE_CTX_<NAME> env var)")
+	cmd.Flags().BoolVar(&opts.AllTenants, "all-tenants", false, "Pause every tenant")
+	cmd.Flags().StringVar(&opts.Reason, "reason", "", "Why indexing is paused, recorded with the pause (required)")
+	cmd.Flags().DurationVar(&opts.Wait, "wait", 10*time.Minute, "How long to wait for running attempts to stop (0 to not wait)")
+	cmd.Flags().BoolVar(&opts.Status, "status", false, "Only report paused tenants and their running attempts")
+	cmd.Flags().BoolVar(&opts.Yes, "yes", false, "Skip the confirmation prompt")
+
+	return cmd
+}
+
+// NewResumeTenantIndexingCommand creates the resume-tenant-indexing command.
+func NewResumeTenantIndexingCommand() *cobra.Command {
+	opts := &PauseIndexingOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "resume-tenant-indexing [tenant...]",
+		Short: "Resume indexing paused by pause-tenant-indexing",
+		Long: `Resume indexing for tenants paused by ` + "`ods pause-tenant-indexing`" + `, or every
+paused tenant with --all-tenants.
+
+Only the connectors the pause recorded are restored, to the status they had,
+and only if they are still paused; connectors changed since are left alone.
+Interrupted attempts carry on from their checkpoint.
+
+On a single-tenant deployment name no tenants.
+
+Examples:
+  ods resume-tenant-indexing tenant_abcd1234 -c prod
+  ods resume-tenant-indexing --all-tenants -c prod`,
+		Run: func(cmd *cobra.Command, args []string) {
+			runResumeIndexing(opts, args)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.Context, "context", "c", "data_plane", "cluster context name (maps to KUBE_CTX_<NAME> env var)")
+	cmd.Flags().BoolVar(&opts.AllTenants, "all-tenants", false, "Resume every paused tenant")
+	cmd.Flags().BoolVar(&opts.Yes, "yes", false, "Skip the confirmation prompt")
+
+	return cmd
+}
+
+// indexingTargets validates the tenant arguments and returns the schemas to
+// pass to the script and how to name them.
+func indexingTargets(opts *PauseIndexingOptions, args []string) ([]string, string) {
+	switch {
+	case opts.AllTenants && len(args) > 0:
+		log.Fatal("--all-tenants and tenant arguments are mutually exclusive")
+	case opts.AllTenants:
+		return nil, "all tenants"
+	case len(args) == 0:
+		return []string{""}, "default"
+	}
+	for _, t := range args {
+		validateTenantArg(t)
+	}
+	return args, strings.Join(args, ", ")
+}
+
+func indexingPod(opts *PauseIndexingOptions) (*kube.Cluster, string) {
+	c := clusterFromEnv(opts.Context)
+	if err := c.EnsureContext(); err != nil {
+		log.Fatalf("Failed to ensure cluster context: %v", err)
+	}
+	pod, err := c.FindPod("api-server")
+	if err != nil {
+		log.Fatalf("Failed to find api-server pod: %v", err)
+	}
+	return c, pod
+}
+
+func runPauseIndexing(opts *PauseIndexingOptions, args []string) {
+	schemas, target := indexingTargets(opts, args)
+	if strings.TrimSpace(opts.Reason) == "" {
+		log.Fatal("--reason is required")
+	}
+	if opts.Wait < 0 {
+		log.Fatal("--wait cannot be negative")
+	}
+	c, pod := indexingPod(opts)
+	auditCtx := c.Name + "/" + c.Namespace
+
+	if !opts.Yes && (opts.AllTenants || isProductionContext(opts.Context)) {
+		if !prompt.Confirm(fmt.Sprintf("Pause indexing for %s in %s? (yes/no): ", target, auditCtx)) {
+			log.Info("Aborted.")
+			return
+		}
+	}
+	if err := auditlog.Record(auditlog.Entry{
+		Action:  "indexing.pause",
+		Context: auditCtx,
+		Target:  target,
+		Detail:  opts.Reason,
+	}); err != nil {
+		log.Fatalf("Refusing to pause indexing without an audit record: %v", err)
+	}
+
+	tenants, err := pauseindexing.Pause(c, pod, opts.Reason, auditlog.Actor(), schemas, opts.AllTenants)
+	if err != nil {
+		log.Fatalf("Failed to pause indexing: %v", err)
+	}
+	changed := 0
+	for _, t := range tenants {
+		changed += t.Changed
+	}
+	log.Infof("Paused %d connector(s) across %d tenant(s)", changed, len(tenants)-len(pauseindexing.Failed(tenants)))
+	failed := reportIndexingFailures(tenants)
+
+	if opts.Wait > 0 {
+		waitForIndexingStop(c, pod, schemas, opts.AllTenants, opts.Wait)
+	}
+	if failed > 0 {
+		log.Fatalf("Failed to pause %d tenant(s)", failed)
+	}
+}
+
+// waitForIndexingStop polls until no attempt of a paused connector is in
+// progress, showing that the workers picked up the pause.
+func waitForIndexingStop(c *kube.Cluster, pod string, schemas []string, all bool, wait time.Duration) {
+	deadline := time.Now().Add(wait)
+	for {
+		tenants, err := pauseindexing.Status(c, pod, schemas, all)
+		if err != nil {
+			log.Fatalf("Failed to check running attempts: %v", err)
+		}
+		running := pauseindexing.Running(tenants)
+		if running == 0 {
+			log.Info("No indexing attempts running; the workers have stopped")
+			return
+		}
+		if time.Now().After(deadline) {
+			printIndexingStatus(tenants)
+			log.Fatalf("%d indexing attempt(s) still running after %s", running, wait)
+		}
+		log.Infof("Waiting for %d running indexing attempt(s) to reach their next batch...", running)
+		time.Sleep(pauseIndexingPollInterval)
+	}
+}
+
+func runResumeIndexing(opts *PauseIndexingOptions, args []string) {
+	schemas, target := indexingTargets(opts, args)
+	c, pod := indexingPod(opts)
+	auditCtx := c.Name + "/" + c.Namespace
+
+	if !opts.Yes && (opts.AllTenants || isProductionContext(opts.Context)) {
+		if !prompt.Confirm(fmt.Sprintf("Resume indexing for %s in %s? (yes/no): ", target, auditCtx)) {
+			log.Info("Aborted.")
+			return
+		}
+	}
+	if err := auditlog.Record(auditlog.Entry{
+		Action:  "indexing.resume",
+		Context: auditCtx,
+		Target:  target,
+	}); err != nil {
+		log.Fatalf("Refusing to resume indexing without an audit record: %v", err)
+	}
+
+	tenants, err := pauseindexing.Resume(c, pod, schemas, opts.AllTenants)
+	if err != nil {
+		log.Fatalf("Failed to resume indexing: %v", err)
+	}
+	changed := 0
+	for _, t := range tenants {
+		if t.Error == "" && t.Paused == 0 {
+			log.Warnf("%s was not paused by ods", t.Tenant)
+		}
+		changed += t.Changed
+	}
+	log.Infof("Resumed %d connector(s) across %d tenant(s)", changed, len(tenants)-len(pauseindexing.Failed(tenants)))
+	if failed := reportIndexingFailures(tenants); failed > 0 {
+		log.Fatalf("Failed to resume %d tenant(s)", failed)
+	}
+}
+
+func runIndexingStatus(opts *PauseIndexingOptions, args []string) {
+	schemas, _ := indexingTargets(opts, args)
+	c, pod := indexingPod(opts)
+	tenants, err := pauseindexing.Status(c, pod, schemas, opts.AllTenants)
+	if err != nil {
+		log.Fatalf("Failed to read the pause state: %v", err)
+	}
+	if len(tenants) == 0 {
+		fmt.Println("No tenants are paused.")
+		return
+	}
+	printIndexingStatus(tenants)
+	if failed := reportIndexingFailures(tenants); failed > 0 {
+		log.Fatalf("Failed to read %d tenant(s)", failed)
+	}
+}
+
+func printIndexingStatus(tenants []pauseindexing.Tenant) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "TENANT\tPAUSED\tRUNNING\tSINCE\tBY\tREASON")
+	for _, t := range tenants {
+		if t.Error != "" {
+			continue
+		}
+		since := "-"
+		if t.PausedAt != nil {
+			since = *t.PausedAt
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%d\t%d\t%s\t%s\t%s\n", t.Tenant, t.Paused, t.Running, since, t.Actor, t.Reason)
+	}
+	_ = w.Flush()
+}
+
+func reportIndexingFailures(tenants []pauseindexing.Tenant) int {
+	failed := pauseindexing.Failed(tenants)
+	for _, t := range failed {
+		log.Errorf("%s: %s", t.Tenant, t.Error)
+	}
+	return len(failed)
+}
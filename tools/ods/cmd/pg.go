@@ -0,0 +1,321 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/auditlog"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/docker"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/pgdiag"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/postgres"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/prompt"
+)
+
+// PGOptions holds options shared by the pg subcommands.
+type PGOptions struct {
+	Context string
+	Local   bool
+	Watch   time.Duration
+}
+
+// pgTarget runs SQL against the selected environment's Postgres.
+type pgTarget struct {
+	label string
+	query func(sql string) []string
+}
+
+// NewPGCommand creates the parent pg command.
+func NewPGCommand() *cobra.Command {
+	opts := &PGOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "pg",
+		Short: "Postgres diagnostics and maintenance",
+		Long: `Run curated Postgres diagnostics against a cluster (via an api-server pod)
+or, with --local, the docker compose database.
+
+Rows that cross a threshold are marked with "!" in the first column; the
+thresholds are printed under each table.
+
+Requires: AWS SSO login, kubectl access to the EKS cluster (unless --local).
+
+Examples:
+  ods pg connections
+  ods pg connections --watch
+  ods pg locks -c data_plane_eu
+  ods pg slow-queries --min 10s
+  ods pg bloat --local
+  ods pg vacuum tenant_abcd1234.document`,
+	}
+
+	cmd.PersistentFlags().StringVarP(&opts.Context, "context", "c", "data_plane", "cluster context name (maps to KUBE_CTX_<NAME> env var)")
+	cmd.PersistentFlags().BoolVar(&opts.Local, "local", false, "Use the local docker compose database instead of a cluster")
+
+	cmd.AddCommand(newPGConnectionsCommand(opts))
+	cmd.AddCommand(newPGLocksCommand(opts))
+	cmd.AddCommand(newPGSlowQueriesCommand(opts))
+	cmd.AddCommand(newPGBloatCommand(opts))
+	cmd.AddCommand(newPGVacuumCommand(opts))
+
+	return cmd
+}
+
+func newPGConnectionsCommand(opts *PGOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "connections",
+		Short: "Show connections by state, user and application",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			t := openPGTarget(opts)
+			runWatchable(opts.Watch, func() error {
+				used, limit, err := pgdiag.ConnectionUsage(t.query(pgdiag.ConnectionSummarySQL))
+				if err != nil {
+					return err
+				}
+				rows, err := pgdiag.Connections(t.query(pgdiag.ConnectionsSQL))
+				if err != nil {
+					return err
+				}
+
+				flag := ""
+				if float64(used) > float64(limit)*pgdiag.ConnectionUsageLimit {
+					flag = " !"
+				}
+				fmt.Printf("%s: %d of %d connections in use%s\n\n", t.label, used, limit, flag)
+				printPGRows([]string{"STATE", "USER", "APPLICATION", "COUNT", "LONGEST"}, rows)
+				fmt.Printf("\n! idle in transaction for over %s, or over %.0f%% of max_connections in use\n",
+					pgdiag.FormatDuration(pgdiag.IdleInTransactionLimit), pgdiag.ConnectionUsageLimit*100)
+				return nil
+			})
+		},
+	}
+	addWatchFlag(cmd, &opts.Watch)
+	return cmd
+}
+
+func newPGLocksCommand(opts *PGOptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "locks",
+		Short: "Show sessions waiting on locks and who is blocking them",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			t := openPGTarget(opts)
+			runWatchable(opts.Watch, func() error {
+				rows, err := pgdiag.Locks(t.query(pgdiag.LocksSQL))
+				if err != nil {
+					return err
+				}
+				if len(rows) == 0 {
+					fmt.Printf("%s: no sessions are waiting on locks\n", t.label)
+					return nil
+				}
+				printPGRows([]string{"PID", "BLOCKED BY", "WAITING", "WAIT EVENT", "QUERY"}, rows)
+				fmt.Printf("\n! waiting for over %s\n", pgdiag.FormatDuration(pgdiag.LockWaitLimit))
+				return nil
+			})
+		},
+	}
+	addWatchFlag(cmd, &opts.Watch)
+	return cmd
+}
+
+func newPGSlowQueriesCommand(opts *PGOptions) *cobra.Command {
+	var minAge time.Duration
+
+	cmd := &cobra.Command{
+		Use:   "slow-queries",
+		Short: "Show queries that have been running for a while",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if minAge < 0 {
+				log.Fatal("--min must not be negative")
+			}
+			t := openPGTarget(opts)
+			runWatchable(opts.Watch, func() error {
+				rows, err := pgdiag.SlowQueries(t.query(pgdiag.SlowQueriesSQL(minAge)))
+				if err != nil {
+					return err
+				}
+				if len(rows) == 0 {
+					fmt.Printf("%s: no queries running longer than %s\n", t.label, minAge)
+					return nil
+				}
+				printPGRows([]string{"PID", "USER", "STATE", "RUNNING", "QUERY"}, rows)
+				fmt.Printf("\n! running for over %s\n", pgdiag.FormatDuration(pgdiag.SlowQueryLimit))
+				return nil
+			})
+		},
+	}
+
+	cmd.Flags().DurationVar(&minAge, "min", 5*time.Second, "Only show queries running longer than this")
+	addWatchFlag(cmd, &opts.Watch)
+
+	return cmd
+}
+
+func newPGBloatCommand(opts *PGOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "bloat",
+		Short: "Show the tables with the most dead tuples",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			t := openPGTarget(opts)
+			rows, err := pgdiag.Bloat(t.query(pgdiag.AllBloatSQL()))
+			if err != nil {
+				log.Fatalf("%v", err)
+			}
+			printPGRows([]string{"TABLE", "LIVE", "DEAD", "DEAD %", "LAST VACUUM"}, rows)
+			fmt.Printf("\n! at least %d dead tuples and over %.0f%% dead; try `ods pg vacuum <schema.table>`\n",
+				pgdiag.BloatMinDeadTuples, pgdiag.BloatDeadRatioLimit*100)
+		},
+	}
+}
+
+func newPGVacuumCommand(opts *PGOptions) *cobra.Command {
+	var yes bool
+
+	cmd := &cobra.Command{
+		Use:   "vacuum <[schema.]table>",
+		Short: "Vacuum and analyze a table",
+		Long: `Vacuum and analyze a table, showing its dead tuples before and after.
+
+VACUUM does not lock out reads or writes but adds I/O load, so it asks for
+confirmation on production contexts (unless --yes) and is recorded in the
+local audit log. The schema defaults to public.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			runPGVacuum(opts, args[0], yes)
+		},
+	}
+
+	cmd.Flags().BoolVar(&yes, "yes", false, "Skip the confirmation prompt")
+
+	return cmd
+}
+
+func runPGVacuum(opts *PGOptions, arg string, yes bool) {
+	schema, table, err := parseTableName(arg)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	t := openPGTarget(opts)
+	before, err := pgdiag.Bloat(t.query(pgdiag.TableBloatSQL(schema, table)))
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	if len(before) == 0 {
+		log.Fatalf("Table %s.%s not found or has no dead tuples in %s", schema, table, t.label)
+	}
+	headers := []string{"TABLE", "LIVE", "DEAD", "DEAD %", "LAST VACUUM"}
+	printPGRows(headers, before)
+
+	if !opts.Local {
+		if !yes && isProductionContext(opts.Context) {
+			if !prompt.Confirm(fmt.Sprintf("Vacuum %s.%s in %s? (yes/no): ", schema, table, t.label)) {
+				log.Info("Aborted.")
+				return
+			}
+		}
+		if err := auditlog.Record(auditlog.Entry{
+			Action:  "pg.vacuum",
+			Context: t.label,
+			Target:  schema + "." + table,
+		}); err != nil {
+			log.Fatalf("Refusing to vacuum without an audit record: %v", err)
+		}
+	}
+
+	log.Infof("Vacuuming %s.%s...", schema, table)
+	start := time.Now()
+	t.query(pgdiag.VacuumSQL(schema, table))
+	log.Infof("Vacuumed %s.%s in %s", schema, table, pgdiag.FormatDuration(time.Since(start)))
+
+	after, err := pgdiag.Bloat(t.query(pgdiag.TableBloatSQL(schema, table)))
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	if len(after) == 0 {
+		fmt.Println("No dead tuples remain.")
+		return
+	}
+	printPGRows(headers, after)
+}
+
+// parseTableName splits "[schema.]table", defaulting to the public schema.
+func parseTableName(arg string) (schema, table string, err error) {
+	schema, table = "public", arg
+	if i := strings.Index(arg, "."); i >= 0 {
+		schema, table = arg[:i], arg[i+1:]
+	}
+	if !safeIdentifier.MatchString(schema) || !validIdentifier.MatchString(table) {
+		return "", "", fmt.Errorf("invalid table name %q (expected [schema.]table)", arg)
+	}
+	return schema, table, nil
+}
+
+// openPGTarget connects to the selected environment's Postgres.
+func openPGTarget(opts *PGOptions) *pgTarget {
+	if opts.Local {
+		container, err := docker.FindPostgresContainer(docker.ProjectName())
+		if err != nil {
+			log.Fatalf("Failed to find PostgreSQL container: %v", err)
+		}
+		config := postgres.NewConfigFromEnv()
+		args := append([]string{"psql"}, config.PsqlArgs()...)
+		args = append(args, "-A", "-t", "-F", "\t", "-c")
+		return &pgTarget{
+			label: "local (" + container + ")",
+			query: func(sql string) []string {
+				out, err := docker.ExecOutput(container, append(args, sql)...)
+				if err != nil {
+					log.Fatalf("Query failed: %v", err)
+				}
+				return nonEmptyLines(out)
+			},
+		}
+	}
+
+	c := clusterFromEnv(opts.Context)
+	if err := c.EnsureContext(); err != nil {
+		log.Fatalf("Failed to ensure cluster context: %v", err)
+	}
+	log.Info("Finding api-server pod...")
+	pod, err := c.FindPod("api-server")
+	if err != nil {
+		log.Fatalf("Failed to find api-server pod: %v", err)
+	}
+	return &pgTarget{
+		label: c.Name + "/" + c.Namespace,
+		query: func(sql string) []string { return queryPod(c, pod, sql) },
+	}
+}
+
+func nonEmptyLines(s string) []string {
+	var lines []string
+	for _, line := range strings.Split(s, "\n") {
+		if strings.TrimSpace(line) != "" {
+			lines = append(lines, strings.TrimRight(line, "\r"))
+		}
+	}
+	return lines
+}
+
+func printPGRows(headers []string, rows []pgdiag.Row) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintf(w, " \t%s\n", strings.Join(headers, "\t"))
+	for _, r := range rows {
+		mark := " "
+		if r.Flag {
+			mark = "!"
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\n", mark, strings.Join(r.Cells, "\t"))
+	}
+	_ = w.Flush()
+}
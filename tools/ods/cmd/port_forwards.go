@@ -0,0 +1,75 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/portforward"
+)
+
+// NewPortForwardsCommand creates the port-forwards command.
+func NewPortForwardsCommand() *cobra.Command {
+	var clean bool
+
+	cmd := &cobra.Command{
+		Use:   "port-forwards",
+		Short: "List the kubectl port-forwards ods has running",
+		Long: `List the kubectl port-forwards started by ods commands (proxy, curl,
+impersonate, billing, ...) that are still running, from the registry ods
+keeps of them.
+
+A forward is orphaned when the ods run that started it is gone, e.g. after
+it was killed; --clean stops those.
+
+Examples:
+  ods port-forwards
+  ods port-forwards --clean`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			runPortForwards(clean)
+		},
+	}
+
+	cmd.Flags().BoolVar(&clean, "clean", false, "Stop orphaned port-forwards")
+
+	return cmd
+}
+
+func runPortForwards(clean bool) {
+	if clean {
+		cleaned, err := portforward.Clean()
+		if err != nil {
+			log.Fatalf("Failed to clean port-forwards: %v", err)
+		}
+		for _, e := range cleaned {
+			log.Infof("Stopped orphaned port-forward %s (localhost:%d -> %s)", e.Name, e.LocalPort, e.Target)
+		}
+		log.Infof("Stopped %d orphaned port-forward(s)", len(cleaned))
+		return
+	}
+
+	entries, err := portforward.List()
+	if err != nil {
+		log.Fatalf("Failed to read %s: %v", portforward.RegistryPath(), err)
+	}
+	if len(entries) == 0 {
+		fmt.Println("No port-forwards running.")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "NAME\tLOCAL\tTARGET\tCONTEXT\tUPTIME\tPID\tOWNER")
+	for _, e := range entries {
+		owner := fmt.Sprint(e.Owner)
+		if e.Orphaned() {
+			owner += " (orphaned)"
+		}
+		_, _ = fmt.Fprintf(w, "%s\tlocalhost:%d\t%s:%d\t%s/%s\t%s\t%d\t%s\n",
+			e.Name, e.LocalPort, e.Target, e.RemotePort, e.Context, e.Namespace,
+			formatEventAge(e.StartedAt), e.PID, owner)
+	}
+	_ = w.Flush()
+}
@@ -0,0 +1,288 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/config"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/kube"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/paths"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/portutil"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/preview"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/runner"
+)
+
+// PROptions holds options shared by the pr subcommands.
+type PROptions struct {
+	Local   bool
+	Context string
+}
+
+// NewPRCommand creates the parent pr command.
+func NewPRCommand() *cobra.Command {
+	opts := &PROptions{}
+
+	cmd := &cobra.Command{
+		Use:   "pr",
+		Short: "Deploy pull requests to preview environments",
+		Long: `Deploy a pull request's full stack so reviewers can try it.
+
+A preview builds the backend and web server images from the PR's head commit
+(in a temporary worktree, leaving your checkout alone) and runs them with
+released model server and dependency images, either
+
+  in the dev cluster   an onyx-pr-<number> namespace installed from the PR's
+                       helm chart and served at https://onyx-pr-<number>.<domain>
+  locally (--local)    an onyx-pr-<number> compose project with its own
+                       volumes, on a free port next to your own stack
+
+Cluster previews need the ods config's preview section:
+
+  "preview": {"registry": "<account>.dkr.ecr.<region>.amazonaws.com/onyx-preview",
+              "domain": "preview.onyx.app"}
+
+and the cluster in KUBE_CTX_DEV (or the context set with --context).`,
+	}
+
+	cmd.PersistentFlags().BoolVar(&opts.Local, "local", false, "Use a local docker compose project instead of the dev cluster")
+	cmd.PersistentFlags().StringVarP(&opts.Context, "context", "c", "", `Cluster context name (maps to KUBE_CTX_<NAME>; default "dev")`)
+
+	cmd.AddCommand(newPRDeployCommand(opts))
+	cmd.AddCommand(newPRDestroyCommand(opts))
+
+	return cmd
+}
+
+func newPRDeployCommand(opts *PROptions) *cobra.Command {
+	var skipBuild bool
+	var notify string
+
+	cmd := &cobra.Command{
+		Use:   "deploy <number>",
+		Short: "Build a PR's images and deploy its preview",
+		Long: `Build a PR's backend and web server images and deploy its preview, printing
+its URL once it is healthy. Re-running updates the preview to the PR's latest
+commit; its data is kept.
+
+Examples:
+  ods pr deploy 4821
+  ods pr deploy 4821 --local
+  ods pr deploy 4821 --skip-build --notify slack:#reviews`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			runPRDeploy(opts, parsePRNumber(args[0]), skipBuild, notify)
+		},
+	}
+
+	cmd.Flags().BoolVar(&skipBuild, "skip-build", false, "Reuse images already built for the PR's head commit")
+	addNotifyFlag(cmd, &notify)
+
+	return cmd
+}
+
+func newPRDestroyCommand(opts *PROptions) *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "destroy <number>",
+		Short: "Tear down a PR's preview and its data",
+		Long: `Tear down a PR's preview: uninstall the release and delete its namespace,
+or with --local stop the compose project and remove its volumes and images.
+
+Examples:
+  ods pr destroy 4821
+  ods pr destroy 4821 --local`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			runPRDestroy(opts, parsePRNumber(args[0]))
+		},
+	}
+
+	return cmd
+}
+
+func parsePRNumber(arg string) int {
+	n, err := strconv.Atoi(strings.TrimPrefix(arg, "#"))
+	if err != nil || n <= 0 {
+		log.Fatalf("Invalid PR number %q", arg)
+	}
+	return n
+}
+
+// previewCluster returns the cluster previews deploy to, targeting the PR's
+// namespace.
+func previewCluster(opts *PROptions, cfg *config.Config, number int) *kube.Cluster {
+	name := opts.Context
+	if name == "" {
+		name = cfg.Preview.Context
+	}
+	if name == "" {
+		name = "dev"
+	}
+	c := clusterFromEnv(name)
+	if err := c.EnsureContext(); err != nil {
+		log.Fatalf("Failed to set up the cluster context: %v", err)
+	}
+	c.Namespace = preview.Name(number)
+	return c
+}
+
+// runPRStep runs a command with its output streamed, exiting on failure.
+func runPRStep(r runner.Runner, cmd runner.Cmd, what string) {
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := runner.Or(r).Run(cmd); err != nil {
+		log.Fatalf("Failed to %s: %v", what, err)
+	}
+}
+
+func runPRDeploy(opts *PROptions, number int, skipBuild bool, notify string) {
+	root, err := paths.GitRoot()
+	if err != nil {
+		log.Fatalf("Failed to find git root: %v", err)
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	if !opts.Local && (cfg.Preview.Registry == "" || cfg.Preview.Domain == "") {
+		log.Fatalf("Cluster previews need preview.registry and preview.domain in the ods config; use --local to run one locally")
+	}
+
+	pr, err := preview.LookupPR(number)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	if pr.State != "OPEN" {
+		log.Warnf("PR #%d is %s", number, strings.ToLower(pr.State))
+	}
+	log.Infof("Deploying PR #%d %q (%s at %.7s)", number, pr.Title, pr.HeadRef, pr.HeadSHA)
+
+	// Resolve the cluster before the long build, so a missing context fails
+	// fast.
+	var cluster *kube.Cluster
+	if !opts.Local {
+		cluster = previewCluster(opts, cfg, number)
+	}
+
+	dir, cleanup, err := preview.Checkout(root, pr)
+	if err != nil {
+		log.Fatalf("Failed to check out PR #%d: %v", number, err)
+	}
+	defer cleanup()
+
+	notifier := startNotifier(notify)
+	tag := pr.Tag()
+	registry := preview.LocalRegistry
+	if !opts.Local {
+		registry = cfg.Preview.Registry
+	}
+	if !skipBuild {
+		for _, image := range preview.Images {
+			ref := image.Ref(registry, tag)
+			log.Infof("Building %s", ref)
+			runPRStep(nil, runner.Cmd{Name: "docker", Args: image.BuildArgs(dir, ref, !opts.Local)}, "build "+ref)
+		}
+	}
+
+	var url string
+	if opts.Local {
+		url = deployLocalPreview(root, dir, pr, tag)
+	} else {
+		url = deployClusterPreview(cluster, cfg.Preview, dir, pr, tag)
+	}
+
+	log.Infof("PR #%d is up at %s", number, url)
+	fmt.Println(url)
+	notifier.Done(fmt.Sprintf("Preview of PR #%d %q is up at %s", number, pr.Title, url))
+}
+
+// deployLocalPreview starts the PR's compose file as its own project and
+// returns the URL it is served at.
+func deployLocalPreview(root, dir string, pr *preview.PR, tag string) string {
+	claimed := map[int]bool{}
+	port, err := portutil.FindAvailable(3100, 100, claimed)
+	if err != nil {
+		log.Fatalf("Failed to find a free port: %v", err)
+	}
+	claimed[port] = true
+	port80, err := portutil.FindAvailable(8100, 100, claimed)
+	if err != nil {
+		log.Fatalf("Failed to find a free port: %v", err)
+	}
+
+	composeDir := filepath.Join(dir, "deployment", "docker_compose")
+	// Carry over your compose settings (LLM keys, auth), which are not in git.
+	if data, err := os.ReadFile(filepath.Join(root, "deployment", "docker_compose", ".env")); err == nil {
+		if err := os.WriteFile(filepath.Join(composeDir, ".env"), data, 0o600); err != nil {
+			log.Fatalf("Failed to copy the compose .env: %v", err)
+		}
+	}
+
+	name := preview.Name(pr.Number)
+	log.Infof("Starting compose project %q", name)
+	cmd := runner.Cmd{
+		Name: "docker",
+		Args: []string{"compose", "-p", name, "-f", "docker-compose.yml", "up", "-d", "--wait"},
+		Dir:  composeDir,
+		Env:  append(os.Environ(), preview.ComposeEnv(tag, port, port80)...),
+	}
+	runPRStep(nil, cmd, "start "+name)
+	return fmt.Sprintf("http://localhost:%d", port)
+}
+
+// deployClusterPreview installs the PR's chart into its namespace and
+// returns the URL it is served at.
+func deployClusterPreview(c *kube.Cluster, cfg config.PreviewConfig, dir string, pr *preview.PR, tag string) string {
+	if err := c.EnsureNamespace(map[string]string{preview.LabelKey: strconv.Itoa(pr.Number)}); err != nil {
+		log.Fatalf("Failed to create namespace %s: %v", c.Namespace, err)
+	}
+
+	chart := filepath.Join(dir, "deployment", "helm", "charts", "onyx")
+	runPRStep(c.Runner, runner.Cmd{Name: "helm", Args: []string{"dependency", "build", chart}}, "fetch chart dependencies")
+
+	host := preview.Host(pr.Number, cfg.Domain)
+	args := []string{"upgrade", "--install", c.Namespace, chart, "--wait", "--timeout", "20m"}
+	for _, f := range cfg.ValuesFiles {
+		args = append(args, "-f", filepath.Join(dir, filepath.FromSlash(f)))
+	}
+	args = append(args, preview.HelmSetArgs(cfg.Registry, tag, host, cfg.IngressClass)...)
+	log.Infof("Installing release %s in namespace %s", c.Namespace, c.Namespace)
+	runPRStep(c.Runner, c.Helm(args...), "install the chart")
+	return "https://" + host
+}
+
+func runPRDestroy(opts *PROptions, number int) {
+	name := preview.Name(number)
+	if opts.Local {
+		runPRStep(nil, runner.Cmd{Name: "docker", Args: []string{"compose", "-p", name, "down", "--volumes", "--remove-orphans"}}, "stop "+name)
+
+		out, err := runner.Output(nil, runner.Cmd{Name: "docker", Args: []string{"image", "ls", "-q",
+			"--filter", fmt.Sprintf("reference=%s/*:pr-%d-*", preview.LocalRegistry, number)}})
+		if err == nil && len(strings.Fields(string(out))) > 0 {
+			rm := runner.Cmd{Name: "docker", Args: append([]string{"image", "rm", "--force"}, strings.Fields(string(out))...)}
+			if err := runner.Or(nil).Run(rm); err != nil {
+				log.Warnf("Failed to remove PR #%d's images: %v", number, err)
+			}
+		}
+		log.Infof("Removed the local preview of PR #%d", number)
+		return
+	}
+
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load config: %v", err)
+	}
+	c := previewCluster(opts, cfg, number)
+	if out, err := runner.CombinedOutput(c.Runner, c.Helm("uninstall", name)); err != nil && !strings.Contains(string(out), "not found") {
+		log.Fatalf("Failed to uninstall %s: %v\n%s", name, err, out)
+	}
+	if err := c.DeleteNamespace(); err != nil {
+		log.Fatalf("Failed to delete namespace %s: %v", name, err)
+	}
+	log.Infof("Removed the preview of PR #%d; namespace %s is being deleted", number, name)
+}
@@ -0,0 +1,192 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/docker"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/profile"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/runner"
+)
+
+// ProfileOptions holds options for the profile command.
+type ProfileOptions struct {
+	Context  string
+	Local    bool
+	Pod      string
+	Type     string
+	Format   string
+	Duration time.Duration
+	Rate     int
+	Native   bool
+	Image    string
+	Out      string
+	NoOpen   bool
+}
+
+// NewProfileCommand creates the profile command for capturing py-spy
+// profiles of backend processes.
+func NewProfileCommand() *cobra.Command {
+	opts := &ProfileOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "profile <api|celery-worker-...>",
+		Short: "Capture a py-spy profile of the api-server or a worker",
+		Long: `Capture a py-spy profile of the api-server or a celery worker and open it.
+
+py-spy runs in a sidecar that shares the target's process namespace, so the
+backend image needs no changes: on a cluster it is an ephemeral debug
+container on the pod, locally (--local) a throwaway container next to the
+docker compose service. The sidecar installs py-spy with pip unless --image
+already provides it, and profiles the main Python process with its
+subprocesses.
+
+Types:
+  cpu   sample stacks for --duration (flamegraph, speedscope or raw output)
+  dump  print every thread's current stack once (for hangs and deadlocks)
+
+Flamegraphs open in your browser; load speedscope output at
+https://www.speedscope.app. On a cluster, each capture leaves a terminated
+ephemeral container in the pod spec until the pod is replaced.
+
+Requires: AWS SSO login, kubectl access to the EKS cluster (unless --local).
+
+Examples:
+  ods profile api --type cpu --duration 30s
+  ods profile celery-worker-docprocessing --format speedscope -c staging
+  ods profile celery-worker-heavy --type dump
+  ods profile api --local`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			runProfile(opts, args[0])
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.Context, "context", "c", "data_plane", "cluster context name (maps to KUBE_CTX_<NAME> env var)")
+	cmd.Flags().BoolVar(&opts.Local, "local", false, "Profile the local docker compose stack instead of a cluster")
+	cmd.Flags().StringVar(&opts.Pod, "pod", "", "Profile this pod instead of the first ready one")
+	cmd.Flags().StringVar(&opts.Type, "type", string(profile.TypeCPU), "Profile type: cpu or dump")
+	cmd.Flags().StringVar(&opts.Format, "format", "flamegraph", "Output format for cpu profiles: flamegraph, speedscope or raw")
+	cmd.Flags().DurationVar(&opts.Duration, "duration", 30*time.Second, "How long to sample for cpu profiles")
+	cmd.Flags().IntVar(&opts.Rate, "rate", 100, "Samples per second for cpu profiles")
+	cmd.Flags().BoolVar(&opts.Native, "native", false, "Include native (C extension) frames")
+	cmd.Flags().StringVar(&opts.Image, "image", profile.DefaultImage, "Sidecar image to run py-spy in")
+	cmd.Flags().StringVarP(&opts.Out, "out", "o", "", "Output path (default: profile-<target>-<time>.<ext>)")
+	cmd.Flags().BoolVar(&opts.NoOpen, "no-open", false, "Don't open the profile when done")
+
+	return cmd
+}
+
+func runProfile(opts *ProfileOptions, target string) {
+	component, ok := profileComponent(target)
+	if !ok {
+		log.Fatalf("Cannot profile %q; expected api or a celery worker (e.g. celery-worker-heavy)", target)
+	}
+
+	popts := profile.Options{
+		Type:     profile.Type(opts.Type),
+		Format:   opts.Format,
+		Duration: opts.Duration,
+		Rate:     opts.Rate,
+		Native:   opts.Native,
+	}
+	if err := popts.Validate(); err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	out := opts.Out
+	if out == "" {
+		out = fmt.Sprintf("profile-%s-%s.%s", component, time.Now().Format("20060102-150405"), popts.Extension())
+	}
+	f, err := os.Create(out)
+	if err != nil {
+		log.Fatalf("Failed to create %s: %v", out, err)
+	}
+
+	script := []string{"sh", "-c", popts.Script()}
+	if opts.Local {
+		container := profileContainer(component)
+		log.Infof("Profiling %s (%s)...", container, popts.Type)
+		err = docker.RunInPIDNamespace(container, opts.Image, popts.Env(), f, script...)
+	} else {
+		c := clusterFromEnv(opts.Context)
+		if err := c.EnsureContext(); err != nil {
+			log.Fatalf("Failed to ensure cluster context: %v", err)
+		}
+		pod := opts.Pod
+		if pod == "" {
+			if pod, err = c.FindPod(component); err != nil {
+				log.Fatalf("Failed to find %s pod: %v", component, err)
+			}
+		}
+		log.Infof("Profiling %s (%s)...", pod, popts.Type)
+		err = c.RunDebugContainer(pod, opts.Image, popts.Env(), f, script...)
+	}
+	if cerr := f.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		_ = os.Remove(out)
+		log.Fatalf("Profiling failed: %v", err)
+	}
+
+	log.Infof("Profile written to %s", out)
+	switch {
+	case popts.Type == profile.TypeDump:
+		data, _ := os.ReadFile(out)
+		fmt.Print(string(data))
+	case opts.NoOpen:
+	case popts.Format == "flamegraph":
+		if err := openFile(nil, out); err != nil {
+			log.Warnf("Failed to open %s: %v", out, err)
+		}
+	case popts.Format == "speedscope":
+		log.Infof("Load it at https://www.speedscope.app")
+	}
+}
+
+// profileComponent maps a profile target to the deployment component whose
+// pods run it.
+func profileComponent(target string) (string, bool) {
+	if target == "api" || target == "api-server" {
+		return "api-server", true
+	}
+	comp, ok := onyxDeploymentComponent(target)
+	if !ok || !strings.HasPrefix(comp, "celery-") {
+		return "", false
+	}
+	return comp, true
+}
+
+// profileContainer returns the local docker compose container running a
+// component; all celery processes share the background container.
+func profileContainer(component string) string {
+	service := "background"
+	if component == "api-server" {
+		service = "api_server"
+	}
+	return fmt.Sprintf("%s-%s-1", docker.ProjectName(), service)
+}
+
+// openFile opens path with the platform's default application.
+func openFile(r runner.Runner, path string) error {
+	return runner.Or(r).Run(openCommand(runtime.GOOS, path))
+}
+
+// openCommand returns the command that opens path on goos.
+func openCommand(goos, path string) runner.Cmd {
+	switch goos {
+	case "darwin":
+		return runner.Cmd{Name: "open", Args: []string{path}}
+	case "windows":
+		return runner.Cmd{Name: "cmd", Args: []string{"/c", "start", "", path}}
+	default:
+		return runner.Cmd{Name: "xdg-open", Args: []string{path}}
+	}
+}
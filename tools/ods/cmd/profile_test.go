@@ -0,0 +1,18 @@
+package cmd
+
+import (
+	"testing"
+)
+
+func TestOpenCommand(t *testing.T) {
+	tests := map[string]string{
+		"darwin":  "open /tmp/profile.svg",
+		"windows": "cmd /c start  /tmp/profile.svg",
+		"linux":   "xdg-open /tmp/profile.svg",
+	}
+	for goos, want := range tests {
+		if got := openCommand(goos, "/tmp/profile.svg").String(); got != want {
+			t.Errorf("openCommand(%q) = %q, want %q", goos, got, want)
+		}
+	}
+}
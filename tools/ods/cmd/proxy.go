@@ -0,0 +1,255 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httputil"
+	"net/url"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/auditlog"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/impersonate"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/portforward"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/portutil"
+)
+
+const maxProxyTTL = 2 * time.Hour
+
+// ProxyOptions holds options for the proxy command.
+type ProxyOptions struct {
+	Context string
+	Tenant  string
+	As      string
+	Reason  string
+	TTL     time.Duration
+	Port    int
+	Origins []string
+}
+
+// NewProxyCommand creates the proxy command for pointing a local frontend at a
+// remote tenant.
+func NewProxyCommand() *cobra.Command {
+	opts := &ProxyOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "proxy",
+		Short: "Proxy a remote API server locally, authenticated as a tenant user",
+		Long: `Proxy a remote API server to localhost, authenticated as a user of a tenant.
+
+Port-forwards to an api-server pod in the selected cluster, mints an ephemeral
+impersonation session (by default for the tenant's first admin), and serves a
+local reverse proxy that attaches that session to every request. Point a local
+web UI at it to debug against real data:
+
+  ods proxy -c prod --tenant tenant_abcd1234 --reason SUP-123
+  ods web dev --with-backend --backend-url http://localhost:8080
+
+The session ends after --ttl (or Ctrl-C, or closing the terminal): the proxy
+stops, the impersonated session is logged out and the start/end are recorded
+in the local audit log.
+
+Only requests addressed to localhost:<port> are proxied, and browser requests
+only from the proxy's own origin or an --allow-origin (the local web UI), so
+other pages and DNS rebinding cannot ride the session.
+
+Requires: AWS SSO login, kubectl access to the EKS cluster, and superuser
+credentials in SUPER_CLOUD_API_KEY and ODS_SUPERUSER_SESSION. The API server
+must run with IMPERSONATION_ENABLED.`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			runProxy(opts)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.Context, "context", "c", "data_plane", "cluster context name (maps to KUBE_CTX_<NAME> env var)")
+	cmd.Flags().StringVar(&opts.Tenant, "tenant", "", "Tenant ID to act as (required)")
+	cmd.Flags().StringVar(&opts.As, "as", "", "Email of the user to impersonate (default: the tenant's first admin)")
+	cmd.Flags().StringVar(&opts.Reason, "reason", "", "Ticket or justification recorded in the audit log (required)")
+	cmd.Flags().DurationVar(&opts.TTL, "ttl", 30*time.Minute, "How long the session stays open (max 2h)")
+	cmd.Flags().IntVar(&opts.Port, "port", 8080, "Local port for the proxy")
+	cmd.Flags().StringSliceVar(&opts.Origins, "allow-origin", []string{"http://localhost:3000"}, "Browser origins allowed to use the proxy besides its own")
+	_ = cmd.MarkFlagRequired("tenant")
+	_ = cmd.MarkFlagRequired("reason")
+
+	return cmd
+}
+
+func runProxy(opts *ProxyOptions) {
+	if opts.TTL <= 0 || opts.TTL > maxProxyTTL {
+		log.Fatalf("--ttl must be between 0 and %s, got %s", maxProxyTTL, opts.TTL)
+	}
+	validateTenantArg(opts.Tenant)
+	creds, err := impersonate.CredentialsFromEnv()
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	if !portutil.IsAvailable(opts.Port) {
+		log.Fatalf("Port %d is in use by %s; pick another with --port", opts.Port, portutil.ProcessOnPort(opts.Port))
+	}
+
+	c := clusterFromEnv(opts.Context)
+	if err := c.EnsureContext(); err != nil {
+		log.Fatalf("Failed to ensure cluster context: %v", err)
+	}
+
+	log.Info("Finding api-server pod...")
+	pod, err := c.FindPod("api-server")
+	if err != nil {
+		log.Fatalf("Failed to find api-server pod: %v", err)
+	}
+
+	email := opts.As
+	if email == "" {
+		admins := tenantAdminEmails(c, pod, opts.Tenant)
+		if len(admins) == 0 {
+			log.Fatalf("No admin users found for %s; pass --as <email>", opts.Tenant)
+		}
+		email = admins[0]
+	}
+
+	pf := forwardAPIServer(c, pod, opts.Port)
+	defer pf.Stop()
+
+	auditCtx := c.Name + "/" + c.Namespace
+	if err := auditlog.Record(auditlog.Entry{
+		Action:  "proxy.start",
+		Context: auditCtx,
+		Target:  opts.Tenant + " as " + email,
+		Detail:  fmt.Sprintf("reason=%s ttl=%s", opts.Reason, opts.TTL),
+	}); err != nil {
+		log.Fatalf("Refusing to impersonate without an audit record: %v", err)
+	}
+
+	token, err := impersonate.Mint(pf.URL(), email, creds)
+	if err != nil {
+		log.Fatalf("Failed to mint impersonation session for %s: %v", email, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), opts.TTL)
+	defer cancel()
+	ctx, stop := signal.NotifyContext(ctx, os.Interrupt, syscall.SIGTERM, syscall.SIGHUP)
+	defer stop()
+
+	server := &http.Server{
+		Addr:              fmt.Sprintf("127.0.0.1:%d", opts.Port),
+		Handler:           localOnly(opts.Port, opts.Origins, newImpersonatingProxy(pf, token)),
+		ReadHeaderTimeout: 30 * time.Second,
+	}
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- server.ListenAndServe() }()
+
+	expires := time.Now().Add(opts.TTL)
+	log.Infof("Proxying http://localhost:%d -> %s (%s) as %s", opts.Port, pod, opts.Tenant, email)
+	log.Infof("Session expires at %s (Ctrl-C to end early)", expires.Format(time.Kitchen))
+	log.Infof("Start the UI with: ods web dev --with-backend --backend-url http://localhost:%d", opts.Port)
+
+	reason := "ended"
+	select {
+	case <-ctx.Done():
+		if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+			reason = "expired"
+		}
+	case err := <-serveErr:
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Errorf("Proxy server failed: %v", err)
+			reason = "failed"
+		}
+	}
+
+	shutdownCtx, shutdownCancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer shutdownCancel()
+	_ = server.Shutdown(shutdownCtx)
+
+	if err := impersonate.Revoke(pf.URL(), token); err != nil {
+		log.Warnf("Failed to log out the impersonated session (it will expire on its own): %v", err)
+	}
+	if err := auditlog.Record(auditlog.Entry{
+		Action:  "proxy.end",
+		Context: auditCtx,
+		Target:  opts.Tenant + " as " + email,
+		Detail:  reason,
+	}); err != nil {
+		log.Warnf("Failed to record end of session: %v", err)
+	}
+	log.Infof("Session %s", reason)
+}
+
+// localOnly rejects requests that did not come from this machine's browser
+// or tools: a Host other than localhost:port (DNS rebinding) or an Origin
+// other than the proxy's own or one of origins (any other page).
+func localOnly(port int, origins []string, next http.Handler) http.Handler {
+	hosts := map[string]bool{}
+	allowed := map[string]bool{}
+	for _, h := range []string{"localhost", "127.0.0.1"} {
+		host := fmt.Sprintf("%s:%d", h, port)
+		hosts[host] = true
+		allowed["http://"+host] = true
+	}
+	for _, o := range origins {
+		allowed[strings.TrimSuffix(o, "/")] = true
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !hosts[strings.ToLower(r.Host)] {
+			log.Warnf("Rejected a request for host %q", r.Host)
+			http.Error(w, "ods proxy only serves localhost", http.StatusForbidden)
+			return
+		}
+		if origin := r.Header.Get("Origin"); origin != "" && !allowed[origin] {
+			log.Warnf("Rejected a request from origin %q", origin)
+			http.Error(w, "ods proxy does not serve cross-origin requests", http.StatusForbidden)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// newImpersonatingProxy returns a reverse proxy to the port-forward that
+// replaces any session cookie on incoming requests with the impersonation
+// token and strips session cookies from responses, so the token never reaches
+// the browser.
+func newImpersonatingProxy(pf *portforward.Forward, token string) http.Handler {
+	target, _ := url.Parse(pf.URL())
+	proxy := httputil.NewSingleHostReverseProxy(target)
+
+	director := proxy.Director
+	proxy.Director = func(r *http.Request) {
+		director(r)
+		setSessionCookie(r, token)
+	}
+	proxy.ModifyResponse = func(resp *http.Response) error {
+		var kept []string
+		for _, v := range resp.Header.Values("Set-Cookie") {
+			if !strings.HasPrefix(v, impersonate.SessionCookieName+"=") {
+				kept = append(kept, v)
+			}
+		}
+		resp.Header.Del("Set-Cookie")
+		for _, v := range kept {
+			resp.Header.Add("Set-Cookie", v)
+		}
+		return nil
+	}
+	return proxy
+}
+
+// setSessionCookie rewrites the Cookie header so it carries exactly one
+// session cookie: the given token.
+func setSessionCookie(r *http.Request, token string) {
+	cookies := r.Cookies()
+	r.Header.Del("Cookie")
+	for _, c := range cookies {
+		if c.Name != impersonate.SessionCookieName {
+			r.AddCookie(c)
+		}
+	}
+	r.AddCookie(&http.Cookie{Name: impersonate.SessionCookieName, Value: token})
+}
@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestLocalOnly(t *testing.T) {
+	h := localOnly(8080, []string{"http://localhost:3000/"}, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		host, origin string
+		want         int
+	}{
+		{"localhost:8080", "", http.StatusNoContent},
+		{"127.0.0.1:8080", "", http.StatusNoContent},
+		{"localhost:8080", "http://localhost:8080", http.StatusNoContent},
+		{"localhost:8080", "http://localhost:3000", http.StatusNoContent},
+		{"attacker.example:8080", "", http.StatusForbidden},
+		{"localhost:9090", "", http.StatusForbidden},
+		{"localhost:8080", "http://attacker.example", http.StatusForbidden},
+		{"localhost:8080", "http://localhost:5173", http.StatusForbidden},
+		{"localhost:8080", "null", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		r := httptest.NewRequest(http.MethodGet, "/api/me", nil)
+		r.Host = tt.host
+		if tt.origin != "" {
+			r.Header.Set("Origin", tt.origin)
+		}
+		rec := httptest.NewRecorder()
+		h.ServeHTTP(rec, r)
+		if rec.Code != tt.want {
+			t.Errorf("host %q origin %q: status %d, want %d", tt.host, tt.origin, rec.Code, tt.want)
+		}
+	}
+}
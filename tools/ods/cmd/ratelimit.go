@@ -0,0 +1,164 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/auditlog"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/prompt"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/ratelimit"
+)
+
+// RateLimitOptions holds options shared by the ratelimit subcommands.
+type RateLimitOptions struct {
+	Context string
+	Tenant  string
+}
+
+// NewRateLimitCommand creates the parent ratelimit command.
+func NewRateLimitCommand() *cobra.Command {
+	opts := &RateLimitOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "ratelimit",
+		Short: "Inspect and reset rate limits",
+		Long: `Inspect and reset rate limits.
+
+"show" prints the api-server's request rate-limit settings, the tenant's
+token and cost budgets with the usage in their current window, and the
+counters the rate limiters keep in Redis. Invite counters live in the
+tenant's namespace; auth, feedback and signup buckets are shared by the whole
+deployment and keyed by client IP or user.
+
+--reset deletes one counter, named as "show" lists it, after confirmation.
+Only rate-limit keys are accepted. Token and cost budgets are computed from
+recorded usage and cannot be reset; raise or disable the limit instead.
+
+On a single-tenant deployment omit --tenant.
+
+Requires: AWS SSO login, kubectl access to the EKS cluster.
+
+Examples:
+  ods ratelimit show --tenant tenant_abcd1234
+  ods ratelimit show --tenant tenant_abcd1234 --reset ratelimit:invite_put:tenant:tenant_abcd1234:day
+  ods ratelimit show -c staging --reset 'fastapi-limiter:10.0.0.1-curl/8.4.0:/auth/login:0:0' --yes`,
+	}
+
+	cmd.PersistentFlags().StringVarP(&opts.Context, "context", "c", "data_plane", "cluster context name (maps to KUBE_CTX_<NAME> env var)")
+	cmd.PersistentFlags().StringVar(&opts.Tenant, "tenant", "", "Tenant schema (omit on single-tenant deployments)")
+
+	cmd.AddCommand(newRateLimitShowCommand(opts))
+
+	return cmd
+}
+
+func newRateLimitShowCommand(opts *RateLimitOptions) *cobra.Command {
+	var reset string
+	var yes bool
+
+	cmd := &cobra.Command{
+		Use:   "show",
+		Short: "Show rate-limit settings, budgets and Redis buckets",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			if reset != "" {
+				runRateLimitReset(opts, reset, yes)
+				return
+			}
+			runRateLimitShow(opts)
+		},
+	}
+
+	cmd.Flags().StringVar(&reset, "reset", "", "Delete this bucket instead of showing")
+	cmd.Flags().BoolVar(&yes, "yes", false, "Skip the confirmation prompt")
+
+	return cmd
+}
+
+func runRateLimitShow(opts *RateLimitOptions) {
+	c, pod := accessPod(&AccessOptions{Context: opts.Context, Tenant: opts.Tenant})
+	r, err := ratelimit.Show(c, pod, opts.Tenant)
+	if err != nil {
+		log.Fatalf("Failed to read rate limits: %v", err)
+	}
+
+	s := r.Settings
+	fmt.Printf("Auth rate limit:      %s\n", requestLimit(s.AuthEnabled, s.AuthMaxRequests, s.AuthWindowSeconds))
+	fmt.Printf("Feedback rate limit:  %s\n", requestLimit(s.FeedbackEnabled, &s.FeedbackMaxRequests, &s.FeedbackWindowSeconds))
+	fmt.Println()
+
+	if len(r.TokenLimits) == 0 {
+		fmt.Println("No tenant-wide token or cost budgets")
+	} else {
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "BUDGET\tENABLED\tPERIOD\tTOKENS\tCOST\tEXCEEDED")
+		_, _ = fmt.Fprintln(w, "------\t-------\t------\t------\t----\t--------")
+		for _, l := range r.TokenLimits {
+			tokens := "-"
+			if l.TokenBudget != nil {
+				tokens = fmt.Sprintf("%d / %d", l.TokensUsed, *l.TokenBudget)
+			}
+			cost := "-"
+			if l.CostBudgetCents != nil {
+				cost = fmt.Sprintf("$%.2f / $%.2f", l.CostUsedCents/100, *l.CostBudgetCents/100)
+			}
+			_, _ = fmt.Fprintf(w, "%d\t%t\t%dh\t%s\t%s\t%t\n", l.ID, l.Enabled, l.PeriodHours, tokens, cost, l.Exceeded())
+		}
+		_ = w.Flush()
+	}
+	fmt.Println()
+
+	if len(r.Buckets) == 0 {
+		fmt.Println("No rate-limit buckets in Redis")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "KEY\tLIMITER\tSCOPE\tCOUNT\tTTL")
+	_, _ = fmt.Fprintln(w, "---\t-------\t-----\t-----\t---")
+	for _, b := range r.Buckets {
+		scope := "tenant"
+		if b.Shared {
+			scope = "shared"
+		}
+		count := "-"
+		if b.Value != nil {
+			count = *b.Value
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", b.Key, b.Limiter(), scope, count, b.TTL())
+	}
+	_ = w.Flush()
+}
+
+// requestLimit renders a request rate limit, e.g. "10 requests / 60s".
+func requestLimit(enabled bool, maxRequests, window *int) string {
+	if !enabled || maxRequests == nil || window == nil {
+		return "disabled"
+	}
+	return fmt.Sprintf("%d requests / %ds", *maxRequests, *window)
+}
+
+func runRateLimitReset(opts *RateLimitOptions, key string, yes bool) {
+	c, pod := accessPod(&AccessOptions{Context: opts.Context, Tenant: opts.Tenant})
+	auditCtx := c.Name + "/" + c.Namespace
+
+	if !yes && !prompt.Confirm(fmt.Sprintf("Delete rate-limit bucket %s in %s? (yes/no): ", key, auditCtx)) {
+		log.Info("Aborted.")
+		return
+	}
+	if err := auditlog.Record(auditlog.Entry{
+		Action:  "ratelimit.reset",
+		Context: auditCtx,
+		Target:  key,
+		Detail:  opts.Tenant,
+	}); err != nil {
+		log.Fatalf("Refusing to reset a rate limit without an audit record: %v", err)
+	}
+	if err := ratelimit.Reset(c, pod, opts.Tenant, key); err != nil {
+		log.Fatalf("Failed to reset %s: %v", key, err)
+	}
+	log.Infof("Deleted %s", key)
+}
@@ -0,0 +1,195 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/auditlog"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/kube"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/prompt"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/reconcile"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/reindex"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/render"
+)
+
+// ReconcileOptions holds options for the reconcile command.
+type ReconcileOptions struct {
+	Context string
+	Tenant  string
+	Sample  int
+	JSON    bool
+	Resync  bool
+	Yes     bool
+}
+
+// NewReconcileCommand creates the reconcile command.
+func NewReconcileCommand() *cobra.Command {
+	opts := &ReconcileOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "reconcile",
+		Short: "Compare a tenant's document counts in Postgres and Vespa",
+		Long: `Compare a tenant's documents and chunks in Postgres with Vespa, per
+connector, to find index drift before users report missing search results.
+
+Visits every chunk Vespa holds for the tenant and, for each connector, counts
+the documents Postgres says it indexed, how many of them Vespa has chunks
+for, and how many have a different number of chunks than Postgres expects.
+Also lists orphans: documents Vespa has that Postgres does not (search can
+return them, nothing will update them), and documents no connector has
+indexed (pruning should delete them). Look one up with ods doc.
+
+--resync marks the connectors with missing or mismatched documents for a
+from-scratch reindex, as ods vespa reindex does; the workers pick it up like
+an admin re-index. Orphans are only reported. Asks for confirmation on
+production contexts unless --yes is passed, and records the resync in the
+local audit log.
+
+Visiting takes about a minute per million chunks. On a single-tenant
+deployment omit --tenant. Exits 1 when drift or orphans are found.
+
+Requires: AWS SSO login, kubectl access to the EKS cluster.
+
+Examples:
+  ods reconcile --tenant tenant_abcd1234
+  ods reconcile --tenant tenant_abcd1234 --json > drift.json
+  ods reconcile --tenant tenant_abcd1234 --resync -c staging`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			runReconcile(opts)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.Context, "context", "c", "data_plane", "cluster context name (maps to KUBE_CTX_<NAME> env var)")
+	cmd.Flags().StringVar(&opts.Tenant, "tenant", "", "Tenant schema (omit on single-tenant deployments)")
+	cmd.Flags().IntVar(&opts.Sample, "sample", 10, "Document IDs to list per kind of drift")
+	cmd.Flags().BoolVar(&opts.JSON, "json", false, "Print the report as JSON")
+	cmd.Flags().BoolVar(&opts.Resync, "resync", false, "Reindex connectors with missing or mismatched documents")
+	cmd.Flags().BoolVar(&opts.Yes, "yes", false, "Skip the confirmation prompt for --resync")
+
+	return cmd
+}
+
+func runReconcile(opts *ReconcileOptions) {
+	if opts.Tenant != "" {
+		validateTenantArg(opts.Tenant)
+	}
+	if opts.Sample < 0 {
+		log.Fatalf("Invalid --sample %d", opts.Sample)
+	}
+
+	c := clusterFromEnv(opts.Context)
+	if err := c.EnsureContext(); err != nil {
+		log.Fatalf("Failed to ensure cluster context: %v", err)
+	}
+	auditCtx := c.Name + "/" + c.Namespace
+
+	log.Info("Finding api-server pod...")
+	pod, err := c.FindPod("api-server")
+	if err != nil {
+		log.Fatalf("Failed to find api-server pod: %v", err)
+	}
+
+	log.Info("Reconciling Postgres with Vespa (this visits every chunk of the tenant)...")
+	r, err := reconcile.Run(c, pod, opts.Tenant, opts.Sample)
+	if err != nil {
+		log.Fatalf("Failed to reconcile: %v", err)
+	}
+
+	if opts.JSON {
+		if err := render.JSON(os.Stdout, r); err != nil {
+			log.Fatalf("Failed to marshal report: %v", err)
+		}
+	} else {
+		printReconcileReport(r)
+	}
+
+	if opts.Resync {
+		ids := r.ResyncConnectors()
+		if len(ids) == 0 {
+			log.Info("No connectors to resync")
+		} else {
+			resyncConnectors(c, pod, auditCtx, opts, r.Schema, ids)
+		}
+	}
+	if !r.Clean() {
+		exitWith(1)
+	}
+}
+
+func printReconcileReport(r *reconcile.Report) {
+	fmt.Printf("Schema %s, index %s: Vespa holds %d document(s) in %d chunk(s)\n\n", r.Schema, r.IndexName, r.VespaDocs, r.VespaChunks)
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "CC PAIR\tCONNECTOR\tSOURCE\tSTATUS\tPG DOCS\tVESPA DOCS\tPG CHUNKS\tVESPA CHUNKS\tMISSING\tMISMATCHED")
+	_, _ = fmt.Fprintln(w, "-------\t---------\t------\t------\t-------\t----------\t---------\t------------\t-------\t----------")
+	for _, cc := range r.Connectors {
+		_, _ = fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%d\t%d\t%d\t%d\t%d\t%d\n",
+			cc.CCPairID, cc.Name, cc.Source, cc.Status, cc.PostgresDocs, cc.VespaDocs,
+			cc.PostgresChunks, cc.VespaChunks, cc.Missing, cc.ChunkMismatch)
+	}
+	_ = w.Flush()
+
+	for _, cc := range r.Drifted() {
+		fmt.Printf("\n%s (cc pair %d):\n", cc.Name, cc.CCPairID)
+		if cc.Missing > 0 {
+			fmt.Printf("  %d document(s) missing from Vespa: %s\n", cc.Missing, sampleList(cc.MissingSample, cc.Missing))
+		}
+		if cc.ChunkMismatch > 0 {
+			fmt.Printf("  %d document(s) with a different chunk count: %s\n", cc.ChunkMismatch, sampleList(cc.ChunkMismatchSample, cc.ChunkMismatch))
+		}
+	}
+	if r.VespaOrphans.Count > 0 {
+		fmt.Printf("\n%d document(s) in Vespa but not in Postgres: %s\n", r.VespaOrphans.Count, sampleList(r.VespaOrphans.Sample, r.VespaOrphans.Count))
+	}
+	if r.PostgresOrphans.Count > 0 {
+		fmt.Printf("\n%d document(s) in Postgres that no connector has indexed: %s\n", r.PostgresOrphans.Count, sampleList(r.PostgresOrphans.Sample, r.PostgresOrphans.Count))
+	}
+	if r.Clean() {
+		fmt.Println("\nPostgres and Vespa agree.")
+	}
+}
+
+// sampleList joins sample, noting how many of total it leaves out.
+func sampleList(sample []string, total int) string {
+	s := strings.Join(sample, ", ")
+	if more := total - len(sample); more > 0 {
+		s += fmt.Sprintf(" (and %d more)", more)
+	}
+	return s
+}
+
+// resyncConnectors marks connectorIDs of schema for a from-scratch reindex.
+func resyncConnectors(c *kube.Cluster, pod, auditCtx string, opts *ReconcileOptions, schema string, connectorIDs []int) {
+	ids := make([]string, len(connectorIDs))
+	for i, id := range connectorIDs {
+		ids[i] = fmt.Sprint(id)
+	}
+	target := fmt.Sprintf("%s connector(s) %s", schema, strings.Join(ids, ", "))
+	if !opts.Yes && isProductionContext(opts.Context) {
+		if !prompt.Confirm(fmt.Sprintf("Reindex %s in %s from scratch? (yes/no): ", target, auditCtx)) {
+			log.Info("Aborted.")
+			return
+		}
+	}
+	if err := auditlog.Record(auditlog.Entry{
+		Action:  "vespa.reconcile.resync",
+		Context: auditCtx,
+		Target:  target,
+	}); err != nil {
+		log.Fatalf("Refusing to reindex without an audit record: %v", err)
+	}
+	for _, id := range connectorIDs {
+		tr, err := reindex.Start(c, pod, schema, id)
+		if err != nil {
+			log.Errorf("Failed to trigger reindex of connector %d: %v", id, err)
+			continue
+		}
+		log.Infof("Marked %d connector/credential pair(s) of connector %d for reindexing", len(tr.CCPairIDs), id)
+	}
+}
@@ -0,0 +1,21 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+)
+
+// addNoRedactFlag registers --no-redact on a command that prints or
+// collects logs.
+func addNoRedactFlag(cmd *cobra.Command, noRedact *bool) {
+	cmd.Flags().BoolVar(noRedact, "no-redact", false, "Show credentials, emails and other personal data unredacted (needs an active session for a cluster context)")
+}
+
+// allowUnredacted gates --no-redact: logs from a cluster are customer data,
+// so seeing them unredacted needs an active session for the context, which
+// is recorded in the audit log. The local compose stack needs none.
+func allowUnredacted(context string) {
+	if context == "" || context == localContext {
+		return
+	}
+	requireProdSession(context)
+}
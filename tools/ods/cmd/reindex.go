@@ -0,0 +1,363 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/auditlog"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/kube"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/paths"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/prompt"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/reindex"
+)
+
+// ReindexOptions holds options shared by the reindex subcommands.
+type ReindexOptions struct {
+	Context string
+}
+
+// ReindexAllOptions holds options for the reindex all command.
+type ReindexAllOptions struct {
+	MaxParallelTenants int
+	MaxAttempts        int
+	MaxDocsPerMinute   float64
+	PauseOnError       bool
+	Interval           time.Duration
+	Checkpoint         string
+	RetryFailed        bool
+	Restart            bool
+	Yes                bool
+	Notify             string
+}
+
+// NewReindexCommand creates the parent reindex command.
+func NewReindexCommand() *cobra.Command {
+	opts := &ReindexOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "reindex",
+		Short: "Re-embed and reindex documents across a data plane",
+		Long: `Re-embed and reindex documents across a data plane.
+
+To reindex a single tenant use ` + "`ods vespa reindex`" + `.
+
+Requires: AWS SSO login, kubectl access to the EKS cluster.`,
+	}
+
+	cmd.PersistentFlags().StringVarP(&opts.Context, "context", "c", "data_plane", "cluster context name (maps to KUBE_CTX_<NAME> env var)")
+
+	cmd.AddCommand(newReindexAllCommand(opts))
+
+	return cmd
+}
+
+func newReindexAllCommand(parent *ReindexOptions) *cobra.Command {
+	opts := &ReindexAllOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "all",
+		Short: "Reindex every tenant of a data plane a few at a time",
+		Long: `Reindex every tenant of a data plane a few at a time.
+
+Walks the data plane's tenants in order, triggering a from-scratch reindex of
+each one's active connectors (as ods vespa reindex does) and following the
+resulting index attempts. A new tenant starts only while all of these hold:
+
+  - fewer than --max-parallel-tenants tenants are reindexing
+  - fewer than --max-attempts index attempts are running across them
+  - the combined indexing rate is below --max-docs-per-minute
+
+A tenant whose attempts fail is marked failed and the walk moves on; with
+--pause-on-error no new tenants start after a failure, and the command exits
+once the running ones finish. Tenants without active connectors are skipped.
+
+Progress is checkpointed to a file under the ods data directory (one per
+context, or --checkpoint) after every change. Interrupting only stops the
+walk: reindexes already triggered carry on in the workers, and re-running the
+same command resumes from the checkpoint, picking up the running tenants and
+any tenants created since. --retry-failed starts the failed tenants over and
+--restart discards the checkpoint.
+
+Asks for confirmation on production contexts unless --yes is passed, and
+records the run in the local audit log.
+
+Examples:
+  ods reindex all -c prod --max-parallel-tenants 3 --pause-on-error
+  ods reindex all -c prod --max-attempts 20 --max-docs-per-minute 50000
+  ods reindex all -c prod --retry-failed
+  ods reindex all -c staging --restart --notify slack:#oncall`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			runReindexAll(parent, opts)
+		},
+	}
+
+	cmd.Flags().IntVar(&opts.MaxParallelTenants, "max-parallel-tenants", 2, "Most tenants to reindex at once")
+	cmd.Flags().IntVar(&opts.MaxAttempts, "max-attempts", 0, "Most index attempts running across tenants before holding off (0: no limit)")
+	cmd.Flags().Float64Var(&opts.MaxDocsPerMinute, "max-docs-per-minute", 0, "Combined indexing rate above which no new tenant starts (0: no limit)")
+	cmd.Flags().BoolVar(&opts.PauseOnError, "pause-on-error", false, "Start no new tenants after a tenant fails")
+	cmd.Flags().DurationVar(&opts.Interval, "interval", 30*time.Second, "How often to poll progress")
+	cmd.Flags().StringVar(&opts.Checkpoint, "checkpoint", "", "Checkpoint file (default: reindex-runs/<context>.json in the ods data directory)")
+	cmd.Flags().BoolVar(&opts.RetryFailed, "retry-failed", false, "Reindex the tenants that failed in the checkpointed run again")
+	cmd.Flags().BoolVar(&opts.Restart, "restart", false, "Discard the checkpoint and start over")
+	cmd.Flags().BoolVar(&opts.Yes, "yes", false, "Skip the confirmation prompt")
+	addNotifyFlag(cmd, &opts.Notify)
+
+	return cmd
+}
+
+func runReindexAll(parent *ReindexOptions, opts *ReindexAllOptions) {
+	if opts.MaxParallelTenants < 1 {
+		log.Fatal("--max-parallel-tenants must be positive")
+	}
+	if opts.MaxAttempts < 0 || opts.MaxDocsPerMinute < 0 {
+		log.Fatal("--max-attempts and --max-docs-per-minute must not be negative")
+	}
+	if opts.Interval < time.Second {
+		log.Fatal("--interval must be at least 1s")
+	}
+	path := opts.Checkpoint
+	if path == "" {
+		path = filepath.Join(paths.ReindexRunsDir(), parent.Context+".json")
+	}
+	if opts.Restart {
+		if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+			log.Fatalf("Failed to discard the checkpoint: %v", err)
+		}
+	}
+
+	c := clusterFromEnv(parent.Context)
+	if err := c.EnsureContext(); err != nil {
+		log.Fatalf("Failed to ensure cluster context: %v", err)
+	}
+	auditCtx := c.Name + "/" + c.Namespace
+
+	log.Info("Finding api-server pod...")
+	pod, err := c.FindPod("api-server")
+	if err != nil {
+		log.Fatalf("Failed to find api-server pod: %v", err)
+	}
+	tenants, err := tenantSchemas(c, pod)
+	if err != nil {
+		log.Fatalf("Failed to list tenants: %v", err)
+	}
+
+	cp, err := reindex.LoadCheckpoint(path)
+	if err != nil {
+		log.Fatalf("%v (or pass --restart)", err)
+	}
+	if cp == nil {
+		cp = reindex.NewCheckpoint(path, parent.Context, tenants)
+		log.Infof("Reindexing %d tenant(s) of %s; checkpoint at %s", len(cp.Tenants), auditCtx, path)
+	} else {
+		if cp.Context != parent.Context {
+			log.Fatalf("%s belongs to a run against %s, not %s", path, cp.Context, parent.Context)
+		}
+		added := cp.AddTenants(tenants)
+		retried := 0
+		if opts.RetryFailed {
+			retried = cp.RetryFailed()
+		}
+		counts := cp.Counts()
+		log.Infof("Resuming the reindex of %s started %s: %d done, %d running, %d pending (%d new, %d retried), %d failed, %d skipped",
+			auditCtx, cp.Created.Local().Format(time.RFC3339), counts[reindex.StateDone], counts[reindex.StateRunning],
+			counts[reindex.StatePending], added, retried, counts[reindex.StateFailed], counts[reindex.StateSkipped])
+	}
+	if err := cp.Save(); err != nil {
+		log.Fatalf("Failed to save the checkpoint: %v", err)
+	}
+
+	pending := len(cp.InState(reindex.StatePending))
+	if pending == 0 && len(cp.InState(reindex.StateRunning)) == 0 {
+		printReindexAllSummary(cp)
+		log.Info("Nothing left to reindex; pass --retry-failed or --restart to go again")
+		return
+	}
+	if pending > 0 && !opts.Yes && isProductionContext(parent.Context) {
+		if !prompt.Confirm(fmt.Sprintf("Reindex %d tenant(s) in %s from scratch, %d at a time? (yes/no): ", pending, auditCtx, opts.MaxParallelTenants)) {
+			log.Info("Aborted.")
+			return
+		}
+	}
+
+	notifier := startNotifier(opts.Notify)
+
+	if err := auditlog.Record(auditlog.Entry{
+		Action:  "reindex.all",
+		Context: auditCtx,
+		Target:  "all-tenants",
+		Detail:  fmt.Sprintf("%d pending, max %d parallel", pending, opts.MaxParallelTenants),
+	}); err != nil {
+		log.Fatalf("Refusing to reindex without an audit record: %v", err)
+	}
+
+	w := &fleetReindex{
+		c:      c,
+		pod:    pod,
+		cp:     cp,
+		budget: reindex.Budget{MaxTenants: opts.MaxParallelTenants, MaxAttempts: opts.MaxAttempts, MaxDocsPerMinute: opts.MaxDocsPerMinute},
+		polled: make(map[string]time.Time),
+	}
+	start := time.Now()
+	paused := w.run(opts.Interval, opts.PauseOnError)
+
+	printReindexAllSummary(cp)
+	counts := cp.Counts()
+	switch {
+	case paused != nil:
+		log.Fatalf("Paused after %s failed: %s; fix the cause and re-run (with --retry-failed to retry it)", paused.Tenant, paused.Error)
+	case counts[reindex.StateFailed] > 0:
+		log.Fatalf("%d tenant(s) failed to reindex; see `ods events` and the docprocessing worker logs, then re-run with --retry-failed", counts[reindex.StateFailed])
+	}
+	summary := fmt.Sprintf("Reindex of %s complete: %d tenant(s) done, %d skipped in %s", auditCtx, counts[reindex.StateDone], counts[reindex.StateSkipped], formatElapsed(time.Since(start)))
+	log.Info(summary)
+	notifier.Done(summary)
+}
+
+// fleetReindex drives the tenants of a checkpoint through their reindexes.
+type fleetReindex struct {
+	c      *kube.Cluster
+	pod    string
+	cp     *reindex.Checkpoint
+	budget reindex.Budget
+	// polled is when each running tenant's progress was last read, for its
+	// indexing rate.
+	polled map[string]time.Time
+}
+
+// run polls and starts tenants until none are pending or running. With
+// pauseOnError it stops starting tenants after the first failure and
+// returns the failed tenant once the running ones finish.
+func (w *fleetReindex) run(interval time.Duration, pauseOnError bool) *reindex.TenantRun {
+	var paused *reindex.TenantRun
+	for {
+		rate := 0.0
+		for _, t := range w.cp.InState(reindex.StateRunning) {
+			rate += w.poll(t)
+			if t.State == reindex.StateFailed && pauseOnError && paused == nil {
+				paused = t
+			}
+		}
+
+		for _, t := range w.cp.InState(reindex.StatePending) {
+			if paused != nil {
+				break
+			}
+			if ok, why := w.budget.CanStart(w.cp.InState(reindex.StateRunning), rate); !ok {
+				log.Debugf("Holding off new tenants: %s", why)
+				break
+			}
+			w.start(t)
+			if t.State == reindex.StateFailed && pauseOnError {
+				paused = t
+			}
+		}
+		w.save()
+
+		running := w.cp.InState(reindex.StateRunning)
+		counts := w.cp.Counts()
+		fmt.Printf("[%s] %d running, %d pending, %d done, %d failed, %d skipped | %.0f docs/min\n",
+			time.Now().Format("15:04:05"), len(running), counts[reindex.StatePending], counts[reindex.StateDone],
+			counts[reindex.StateFailed], counts[reindex.StateSkipped], rate)
+		if len(running) == 0 && (paused != nil || counts[reindex.StatePending] == 0) {
+			return paused
+		}
+		time.Sleep(interval)
+	}
+}
+
+// start triggers the reindex of a pending tenant.
+func (w *fleetReindex) start(t *reindex.TenantRun) {
+	log.Infof("Triggering reindex of %s...", t.Tenant)
+	tr, err := reindex.Start(w.c, w.pod, t.Tenant, 0)
+	var none *reindex.NoConnectorsError
+	switch {
+	case errors.As(err, &none):
+		log.Infof("%s has no active connectors; skipping", t.Tenant)
+		t.State = reindex.StateSkipped
+		return
+	case err != nil:
+		log.Errorf("Failed to trigger reindex of %s: %v", t.Tenant, err)
+		t.State, t.Error = reindex.StateFailed, err.Error()
+		return
+	case !reindex.ValidStartedAt(tr.StartedAt):
+		log.Errorf("Unexpected start time %q from reindex script for %s", tr.StartedAt, t.Tenant)
+		t.State, t.Error = reindex.StateFailed, "unexpected start time from reindex script"
+		return
+	}
+	log.Infof("Marked %d connector/credential pair(s) of %s for reindexing", len(tr.CCPairIDs), t.Tenant)
+	t.State, t.CCPairIDs, t.StartedAt = reindex.StateRunning, tr.CCPairIDs, tr.StartedAt
+	w.polled[t.Tenant] = time.Now()
+	w.save()
+}
+
+// poll reads a running tenant's progress, finishing it once its attempts
+// are done, and returns its indexing rate since the previous poll.
+func (w *fleetReindex) poll(t *reindex.TenantRun) float64 {
+	if !reindex.ValidStartedAt(t.StartedAt) {
+		t.State, t.Error = reindex.StateFailed, "invalid start time in checkpoint"
+		return 0
+	}
+	rows, err := tryQueryPod(w.c, w.pod, reindex.ProgressSQL(t.Tenant, t.CCPairIDs, t.StartedAt))
+	if err != nil {
+		log.Warnf("Failed to read progress of %s: %v", t.Tenant, err)
+		return 0
+	}
+	p, err := reindex.ParseProgress(rows)
+	if err != nil {
+		log.Warnf("Failed to read progress of %s: %v", t.Tenant, err)
+		return 0
+	}
+
+	now := time.Now()
+	rate := 0.0
+	if last, ok := w.polled[t.Tenant]; ok {
+		rate = reindex.Throughput(t.DocsIndexed, p.DocsIndexed, now.Sub(last))
+	}
+	w.polled[t.Tenant] = now
+	t.DocsIndexed, t.Running = p.DocsIndexed, p.Running()
+
+	if p.Total() > 0 && p.Running() == 0 {
+		t.Finish(p, now)
+		delete(w.polled, t.Tenant)
+		if t.State == reindex.StateFailed {
+			log.Errorf("Reindex of %s failed: %s", t.Tenant, t.Error)
+		} else {
+			log.Infof("Reindex of %s complete: %d docs", t.Tenant, t.DocsIndexed)
+		}
+	}
+	return rate
+}
+
+func (w *fleetReindex) save() {
+	if err := w.cp.Save(); err != nil {
+		log.Fatalf("Failed to save the checkpoint: %v", err)
+	}
+}
+
+func printReindexAllSummary(cp *reindex.Checkpoint) {
+	counts := cp.Counts()
+	fmt.Println()
+	fmt.Printf("%d tenant(s): %d done, %d running, %d pending, %d failed, %d skipped\n", len(cp.Tenants),
+		counts[reindex.StateDone], counts[reindex.StateRunning], counts[reindex.StatePending],
+		counts[reindex.StateFailed], counts[reindex.StateSkipped])
+
+	failed := cp.InState(reindex.StateFailed)
+	if len(failed) == 0 {
+		return
+	}
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "FAILED TENANT\tDOCS\tERROR")
+	for _, t := range failed {
+		_, _ = fmt.Fprintf(w, "%s\t%d\t%s\n", t.Tenant, t.DocsIndexed, t.Error)
+	}
+	_ = w.Flush()
+}
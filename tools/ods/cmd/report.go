@@ -0,0 +1,296 @@
+package cmd
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/kube"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/notify"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/report"
+)
+
+// ReportOptions holds options shared by the report subcommands.
+type ReportOptions struct {
+	Context string
+	Format  string
+	Since   string
+}
+
+// ReportGenerateOptions holds options for the report generate command.
+type ReportGenerateOptions struct {
+	Output string
+}
+
+// ReportScheduleOptions holds options for the report schedule command.
+type ReportScheduleOptions struct {
+	Cron   string
+	OutDir string
+	Notify string
+}
+
+// NewReportCommand creates the parent report command.
+func NewReportCommand() *cobra.Command {
+	opts := &ReportOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "report",
+		Short: "Generate operations reports from data plane statistics",
+		Long: `Generate operations reports from data plane statistics.
+
+Reports:
+  weekly-ops        chat activity, indexing failures and failing connectors
+                    across tenants (default period: 7d)
+  tenant-growth     tenants, users and messages, with the fastest growing and
+                    declining tenants against the previous period (default: 30d)
+  connector-health  every connector classified as failing, flaky, stale, ...
+                    with its last error (default: 7d)
+
+Statistics are read from every tenant schema (or public, for a single-tenant
+deployment) through an api-server pod. Reports render as Markdown or as a
+self-contained HTML page.
+
+Requires: AWS SSO login, kubectl access to the EKS cluster.`,
+	}
+
+	cmd.PersistentFlags().StringVarP(&opts.Context, "context", "c", "data_plane", "cluster context name (maps to KUBE_CTX_<NAME> env var)")
+	cmd.PersistentFlags().StringVar(&opts.Format, "format", report.FormatMarkdown, "Output format: md or html")
+	cmd.PersistentFlags().StringVar(&opts.Since, "since", "", "Period the report covers, e.g. 7d (default: the report's own)")
+
+	cmd.AddCommand(newReportGenerateCommand(opts))
+	cmd.AddCommand(newReportScheduleCommand(opts))
+
+	return cmd
+}
+
+func newReportGenerateCommand(parent *ReportOptions) *cobra.Command {
+	opts := &ReportGenerateOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "generate <report>",
+		Short: "Generate a report once",
+		Long: `Generate a report once, printing it or writing it to --output.
+
+Examples:
+  ods report generate weekly-ops
+  ods report generate tenant-growth --since 90d --format html -o growth.html
+  ods report generate connector-health -c prod_eu`,
+		Args:      cobra.ExactArgs(1),
+		ValidArgs: report.KindNames(),
+		Run: func(cmd *cobra.Command, args []string) {
+			runReportGenerate(parent, opts, args[0])
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.Output, "output", "o", "", "Write the report to this file instead of stdout")
+
+	return cmd
+}
+
+func newReportScheduleCommand(parent *ReportOptions) *cobra.Command {
+	opts := &ReportScheduleOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "schedule <report>...",
+		Short: "Generate reports on a cron schedule until interrupted",
+		Long: `Generate reports on a cron schedule until interrupted.
+
+Runs in the foreground (run it under systemd, a tmux session or a pod) and,
+each time --cron fires, generates every given report into --out-dir as
+<report>-<date>.<format>. With --notify, each report's headline numbers are
+posted to Slack as well.
+
+--cron is a standard five-field expression (minute hour day-of-month month
+day-of-week) in the local time zone. A failed run is logged and retried at
+the next scheduled time.
+
+Examples:
+  ods report schedule weekly-ops connector-health --cron "0 9 * * MON" --notify slack:#ops
+  ods report schedule tenant-growth --cron "0 8 1 * *" --format html --out-dir /srv/reports`,
+		Args: cobra.MinimumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			runReportSchedule(parent, opts, args)
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.Cron, "cron", "", "When to generate the reports, e.g. \"0 9 * * MON\"")
+	cmd.Flags().StringVar(&opts.OutDir, "out-dir", ".", "Directory to write reports to")
+	cmd.Flags().StringVar(&opts.Notify, "notify", "", "Post each report's summary, e.g. slack:#ops")
+	_ = cmd.MarkFlagRequired("cron")
+
+	return cmd
+}
+
+// reportPeriod returns the period kind covers, per --since or its default.
+func reportPeriod(opts *ReportOptions, kind report.Kind, now time.Time) report.Period {
+	d := kind.DefaultPeriod
+	if opts.Since != "" {
+		var err error
+		if d, err = report.ParseLookback(opts.Since); err != nil {
+			log.Fatalf("Invalid --since: %v", err)
+		}
+	}
+	return report.Last(d, now)
+}
+
+func validateReportArgs(opts *ReportOptions, names []string) []report.Kind {
+	if opts.Format != report.FormatMarkdown && opts.Format != report.FormatHTML {
+		log.Fatalf("--format must be %s or %s", report.FormatMarkdown, report.FormatHTML)
+	}
+	kinds := make([]report.Kind, len(names))
+	for i, name := range names {
+		kind, err := report.LookupKind(name)
+		if err != nil {
+			log.Fatal(err)
+		}
+		kinds[i] = kind
+	}
+	// Catch a bad --since before connecting to anything.
+	reportPeriod(opts, kinds[0], time.Now())
+	return kinds
+}
+
+func runReportGenerate(opts *ReportOptions, genOpts *ReportGenerateOptions, name string) {
+	kind := validateReportArgs(opts, []string{name})[0]
+
+	c := clusterFromEnv(opts.Context)
+	if err := c.EnsureContext(); err != nil {
+		log.Fatalf("Failed to ensure cluster context: %v", err)
+	}
+
+	now := time.Now()
+	r, err := buildReport(c, opts.Context, kind, reportPeriod(opts, kind, now), now)
+	if err != nil {
+		log.Fatalf("Failed to build %s report: %v", kind.Name, err)
+	}
+
+	if genOpts.Output == "" {
+		if err := report.Render(os.Stdout, r, opts.Format); err != nil {
+			log.Fatalf("Failed to render report: %v", err)
+		}
+		return
+	}
+	if err := writeReport(genOpts.Output, r, opts.Format); err != nil {
+		log.Fatalf("Failed to write report: %v", err)
+	}
+	log.Infof("Wrote %s", genOpts.Output)
+}
+
+func runReportSchedule(opts *ReportOptions, schedOpts *ReportScheduleOptions, names []string) {
+	kinds := validateReportArgs(opts, names)
+	schedule, err := report.ParseSchedule(schedOpts.Cron)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if schedule.Next(time.Now()).IsZero() {
+		log.Fatalf("--cron %q never fires", schedOpts.Cron)
+	}
+	if err := os.MkdirAll(schedOpts.OutDir, 0755); err != nil {
+		log.Fatalf("Failed to create %s: %v", schedOpts.OutDir, err)
+	}
+
+	var target notify.Target
+	var webhook string
+	if schedOpts.Notify != "" {
+		if target, err = notify.ParseTarget(schedOpts.Notify); err != nil {
+			log.Fatal(err)
+		}
+		if webhook, err = notify.WebhookURL(target, loadODSConfig().Notify); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	c := clusterFromEnv(opts.Context)
+	if err := c.EnsureContext(); err != nil {
+		log.Fatalf("Failed to ensure cluster context: %v", err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	for {
+		next := schedule.Next(time.Now())
+		log.Infof("Next run of %s at %s", strings.Join(names, ", "), next.Format("2006-01-02 15:04 MST"))
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+		if err := checkProdSession(opts.Context); err != nil {
+			log.Fatalf("Stopping the schedule: %v", err)
+		}
+
+		for _, kind := range kinds {
+			now := time.Now()
+			r, err := buildReport(c, opts.Context, kind, reportPeriod(opts, kind, now), now)
+			if err != nil {
+				log.Errorf("Failed to build %s report: %v", kind.Name, err)
+				continue
+			}
+			path := filepath.Join(schedOpts.OutDir, fmt.Sprintf("%s-%s.%s", kind.Name, now.Format("2006-01-02"), opts.Format))
+			if err := writeReport(path, r, opts.Format); err != nil {
+				log.Errorf("Failed to write %s: %v", path, err)
+				continue
+			}
+			log.Infof("Wrote %s", path)
+			if webhook != "" {
+				if err := notify.SendText(webhook, target, report.SlackSummary(r)); err != nil {
+					log.Warnf("Failed to post to %s: %v", target, err)
+				}
+			}
+		}
+	}
+}
+
+func writeReport(path string, r *report.Report, format string) error {
+	var buf bytes.Buffer
+	if err := report.Render(&buf, r, format); err != nil {
+		return err
+	}
+	return os.WriteFile(path, buf.Bytes(), 0644)
+}
+
+// buildReport gathers the statistics kind needs for p and builds it.
+func buildReport(c *kube.Cluster, contextName string, kind report.Kind, p report.Period, now time.Time) (*report.Report, error) {
+	pod, err := c.FindPod("api-server")
+	if err != nil {
+		return nil, fmt.Errorf("failed to find api-server pod: %w", err)
+	}
+	schemas, err := tenantSchemas(c, pod)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tenant schemas: %w", err)
+	}
+	log.Infof("Reading %s statistics from %d schemas...", kind.Name, len(schemas))
+
+	d := report.Data{Context: contextName, Period: p, Generated: now}
+	if kind.Tenants {
+		lines, err := querySchemas(c, pod, schemas, func(batch []string) string { return report.TenantStatsSQL(batch, p) })
+		if err != nil {
+			return nil, fmt.Errorf("failed to query tenant statistics: %w", err)
+		}
+		if d.Tenants, err = report.ParseTenantStats(lines); err != nil {
+			return nil, err
+		}
+	}
+	if kind.Connectors {
+		lines, err := querySchemas(c, pod, schemas, func(batch []string) string { return report.ConnectorStatsSQL(batch, p) })
+		if err != nil {
+			return nil, fmt.Errorf("failed to query connector statistics: %w", err)
+		}
+		if d.Connectors, err = report.ParseConnectorStats(lines); err != nil {
+			return nil, err
+		}
+	}
+	return kind.Build(d), nil
+}
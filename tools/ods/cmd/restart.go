@@ -0,0 +1,240 @@
+package cmd
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/auditlog"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/kube"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/prompt"
+)
+
+const restartPollInterval = 3 * time.Second
+
+// restartGroups maps the groups accepted by `ods restart` to deployment
+// components. Components not deployed in the namespace are skipped.
+var restartGroups = map[string][]string{
+	"api": {"api-server"},
+	"web": {"web-server"},
+	"workers": {
+		"celery-beat",
+		"celery-worker-primary",
+		"celery-worker-light",
+		"celery-worker-heavy",
+		"celery-worker-docfetching",
+		"celery-worker-docprocessing",
+		"celery-worker-monitoring",
+		"celery-worker-scheduled-tasks",
+		"celery-worker-user-file-processing",
+	},
+	"all": onyxDeployments,
+}
+
+// RestartOptions holds options for the restart command.
+type RestartOptions struct {
+	Context string
+	Timeout time.Duration
+	Yes     bool
+	Notify  string
+}
+
+// NewRestartCommand creates the restart command for rolling restarts.
+func NewRestartCommand() *cobra.Command {
+	opts := &RestartOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "restart <api|workers|web|all>",
+		Short: "Rollout-restart Onyx deployments and watch them come back",
+		Long: `Rollout-restart Onyx deployments and watch the rollout to completion.
+
+Groups:
+  api       the API server
+  web       the web server
+  workers   celery beat and every celery worker
+  all       every Onyx deployment, including model servers
+
+After triggering the restart, progress is printed until every deployment has
+rolled out. The command fails as soon as a new pod crash-loops or can't pull
+its image, or when --timeout elapses.
+
+Restarting asks for confirmation unless --yes is passed or the context is a
+non-production one (a "_" or "-" separated word of its name is dev,
+staging, test or local, and none is prod).
+
+Requires: AWS SSO login, kubectl access to the EKS cluster.
+
+Examples:
+  ods restart api -c staging
+  ods restart workers --timeout 20m
+  ods restart all -c data_plane_eu --yes
+  ods restart workers --notify slack:#oncall`,
+		Args:      cobra.ExactArgs(1),
+		ValidArgs: []string{"api", "workers", "web", "all"},
+		Run: func(cmd *cobra.Command, args []string) {
+			runRestart(opts, args[0])
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.Context, "context", "c", "data_plane", "cluster context name (maps to KUBE_CTX_<NAME> env var)")
+	cmd.Flags().DurationVar(&opts.Timeout, "timeout", 10*time.Minute, "How long to wait for the rollout")
+	cmd.Flags().BoolVar(&opts.Yes, "yes", false, "Skip the confirmation prompt")
+	addNotifyFlag(cmd, &opts.Notify)
+
+	return cmd
+}
+
+func runRestart(opts *RestartOptions, group string) {
+	components, ok := restartGroups[group]
+	if !ok {
+		log.Fatalf("Unknown group %q; expected api, workers, web or all", group)
+	}
+
+	c := clusterFromEnv(opts.Context)
+	if err := c.EnsureContext(); err != nil {
+		log.Fatalf("Failed to ensure cluster context: %v", err)
+	}
+	auditCtx := c.Name + "/" + c.Namespace
+
+	deployments, err := c.ListDeployments()
+	if err != nil {
+		log.Fatalf("Failed to list deployments: %v", err)
+	}
+	names := make([]string, 0, len(deployments))
+	for _, d := range deployments {
+		names = append(names, d.Name)
+	}
+	var targets []string
+	for _, comp := range components {
+		name, err := resolveDeployment(names, comp)
+		if err != nil {
+			log.Debugf("Skipping %s: %v", comp, err)
+			continue
+		}
+		targets = append(targets, name)
+	}
+	if len(targets) == 0 {
+		log.Fatalf("No %s deployments found in %s", group, auditCtx)
+	}
+
+	fmt.Printf("Restarting in %s:\n", auditCtx)
+	for _, t := range targets {
+		fmt.Printf("  %s\n", t)
+	}
+	if !opts.Yes && isProductionContext(opts.Context) {
+		if !prompt.Confirm(fmt.Sprintf("Restart %d deployment(s) in %s? (yes/no): ", len(targets), opts.Context)) {
+			log.Info("Aborted.")
+			return
+		}
+	}
+
+	notifier := startNotifier(opts.Notify)
+
+	// Pods that exist before the restart are being replaced; only new pods
+	// count when looking for crash loops.
+	before, err := c.ListPods()
+	if err != nil {
+		log.Fatalf("Failed to list pods: %v", err)
+	}
+	oldPods := make(map[string]bool, len(before))
+	for _, p := range before {
+		oldPods[p.Name] = true
+	}
+
+	if err := auditlog.Record(auditlog.Entry{
+		Action:  "restart",
+		Context: auditCtx,
+		Target:  group,
+		Detail:  strings.Join(targets, ","),
+	}); err != nil {
+		log.Fatalf("Refusing to restart without an audit record: %v", err)
+	}
+
+	if err := c.RolloutRestart(targets...); err != nil {
+		log.Fatalf("Failed to restart: %v", err)
+	}
+	log.Info("Restart triggered, watching rollout...")
+
+	if err := watchRollout(c, targets, oldPods, opts.Timeout); err != nil {
+		log.Fatalf("%v", err)
+	}
+	log.Infof("All %d deployment(s) rolled out", len(targets))
+	notifier.Done(fmt.Sprintf("Restarted in %s:\n%s", auditCtx, strings.Join(targets, "\n")))
+}
+
+// watchRollout polls the deployments until each has rolled out, printing a
+// line whenever a deployment's progress changes. It returns an error if a new
+// pod is failing or the timeout elapses.
+func watchRollout(c *kube.Cluster, targets []string, oldPods map[string]bool, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	last := make(map[string]string, len(targets))
+	done := make(map[string]bool, len(targets))
+
+	for {
+		for _, name := range targets {
+			if done[name] {
+				continue
+			}
+			d, err := c.GetDeployment(name)
+			if err != nil {
+				return fmt.Errorf("failed to get deployment %s: %w", name, err)
+			}
+			progress := fmt.Sprintf("%d/%d updated, %d/%d ready", d.UpdatedReplicas, d.Replicas, d.ReadyReplicas, d.Replicas)
+			if d.RolloutComplete() {
+				done[name] = true
+				progress = "rolled out"
+			}
+			if progress != last[name] {
+				fmt.Printf("  %-45s %s\n", name, progress)
+				last[name] = progress
+			}
+		}
+		if len(done) == len(targets) {
+			return nil
+		}
+
+		pods, err := c.ListPods()
+		if err != nil {
+			log.Debugf("Failed to list pods: %v", err)
+		}
+		for _, p := range pods {
+			if oldPods[p.Name] {
+				continue
+			}
+			owner := podDeployment(p.Name, targets)
+			if owner == "" {
+				continue
+			}
+			if reason := p.FailureReason(); reason != "" {
+				return fmt.Errorf("rollout of %s is failing: pod %s is %s\n\nInspect it with:\n  kubectl --context %s -n %s describe pod %s\n  kubectl --context %s -n %s logs %s --previous",
+					owner, p.Name, reason, c.Name, c.Namespace, p.Name, c.Name, c.Namespace, p.Name)
+			}
+		}
+
+		if time.Now().After(deadline) {
+			var pending []string
+			for _, name := range targets {
+				if !done[name] {
+					pending = append(pending, name)
+				}
+			}
+			return fmt.Errorf("rollout not finished after %s: %s still in progress", timeout, strings.Join(pending, ", "))
+		}
+		time.Sleep(restartPollInterval)
+	}
+}
+
+// podDeployment returns the deployment among names that owns pod, based on the
+// <deployment>-<replicaset-hash>-<suffix> naming of deployment pods.
+func podDeployment(pod string, names []string) string {
+	for _, n := range names {
+		rest, ok := strings.CutPrefix(pod, n+"-")
+		if ok && strings.Count(rest, "-") == 1 {
+			return n
+		}
+	}
+	return ""
+}
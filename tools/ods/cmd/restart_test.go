@@ -0,0 +1,18 @@
+package cmd
+
+import "testing"
+
+func TestPodDeployment(t *testing.T) {
+	names := []string{"onyx-api-server", "onyx-celery-worker-heavy"}
+	tests := map[string]string{
+		"onyx-api-server-7d9f8b6c5d-x2k4p":          "onyx-api-server",
+		"onyx-celery-worker-heavy-5c6d7e8f9a-abcde": "onyx-celery-worker-heavy",
+		"onyx-api-server-x2k4p":                     "",
+		"onyx-web-server-7d9f8b6c5d-x2k4p":          "",
+	}
+	for pod, want := range tests {
+		if got := podDeployment(pod, names); got != want {
+			t.Errorf("podDeployment(%q) = %q, want %q", pod, got, want)
+		}
+	}
+}
@@ -0,0 +1,414 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/alembic"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/auditlog"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/backups"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/config"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/kube"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/prompt"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/schemadiff"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/tenant"
+)
+
+// RestoreOptions holds options shared by the restore subcommands.
+type RestoreOptions struct {
+	Context string
+}
+
+// RestoreTenantOptions holds options for the restore tenant command.
+type RestoreTenantOptions struct {
+	To            string
+	Target        string
+	Source        string
+	InstanceClass string
+	KeepInstance  bool
+	Promote       bool
+	Yes           bool
+}
+
+// NewRestoreCommand creates the parent restore command.
+func NewRestoreCommand() *cobra.Command {
+	opts := &RestoreOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "restore",
+		Short: "Recover data from an environment's backups",
+		Long: `Recover data from an environment's backups.
+
+Backups are the rds sources configured for the context under "backups" in
+the ods config (see ods verify-backups --help).
+
+Requires: AWS SSO login, kubectl access to the EKS cluster.`,
+	}
+
+	cmd.PersistentFlags().StringVarP(&opts.Context, "context", "c", "data_plane", "cluster context name (maps to KUBE_CTX_<NAME> env var)")
+
+	cmd.AddCommand(newRestoreTenantCommand(opts))
+
+	return cmd
+}
+
+func newRestoreTenantCommand(parent *RestoreOptions) *cobra.Command {
+	opts := &RestoreTenantOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "tenant <tenant_id> --to <time>",
+		Short: "Restore a tenant's schema as it was at a point in time",
+		Long: `Restore a tenant's schema as it was at a point in time, next to the live one.
+
+  1. restore   point-in-time restore the database (--source, default: the
+               context's first rds backup source) to a scratch RDS instance
+  2. copy      rename the tenant's schema in the scratch instance to --target
+               and pg_dump it into the live database, from an api-server pod
+  3. cleanup   delete the scratch instance (unless --keep-instance)
+  4. compare   print the tables whose row counts differ between the live
+               schema and the restored one
+
+--to is UTC unless it carries an offset, and must be within the instance's
+backup retention. --target defaults to "scratch", which names the schema
+<tenant_id>_pitr_<yymmddhhmm>. The live schema is not touched; inspect the
+restored one with pginto on an api-server pod, copy rows across by hand, or
+drop it when done.
+
+--promote then swaps the restored schema in: in one transaction the live
+schema is renamed to <tenant_id>_pre_<yymmddhhmm> (kept, not dropped) and the
+restored one takes its name. It requires typing the tenant ID back. With a
+--target that already exists, --promote skips the restore and promotes that
+schema, so a restore can be inspected before it goes live. Only restores of
+the tenant (<tenant_id>_pitr_<yymmddhhmm>) can be promoted, and only when
+their Alembic revision matches the live schema's (or, with no live schema,
+the deployed code's head); migrate an older restore first.
+
+Only the tenant schema is restored: the tenant's rows in public tables
+(user_tenant_mapping etc.), file-store objects and search indexes are not.
+Restores take 10-30 minutes and the scratch instance is billed while it runs.
+Every step is recorded in the local audit log.
+
+Examples:
+  ods restore tenant tenant_abcd1234 --to "2026-05-01 12:00"
+  ods restore tenant tenant_abcd1234 --to "2026-05-01T14:00:00+02:00" -c prod
+  ods restore tenant tenant_abcd1234 --target tenant_abcd1234_pitr_2605011200 --promote`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			runRestoreTenant(parent, opts, args[0])
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.To, "to", "", "Time to restore the tenant to (UTC unless an offset is given)")
+	cmd.Flags().StringVar(&opts.Target, "target", "scratch", `Schema to restore into ("scratch" names one after the tenant and --to)`)
+	cmd.Flags().StringVar(&opts.Source, "source", "", "rds backup source to restore from (default: the first one configured)")
+	cmd.Flags().StringVar(&opts.InstanceClass, "instance-class", "", "Instance class of the scratch instance (default: the source's)")
+	cmd.Flags().BoolVar(&opts.KeepInstance, "keep-instance", false, "Keep the scratch RDS instance after copying the schema")
+	cmd.Flags().BoolVar(&opts.Promote, "promote", false, "Swap the restored schema in for the live one")
+	cmd.Flags().BoolVar(&opts.Yes, "yes", false, "Skip the confirmation prompt for the restore (not for --promote)")
+
+	return cmd
+}
+
+func runRestoreTenant(parent *RestoreOptions, opts *RestoreTenantOptions, tenantID string) {
+	validateTenantArg(tenantID)
+	if !strings.HasPrefix(tenantID, "tenant_") {
+		log.Fatalf("Refusing to restore %q: tenant IDs start with tenant_", tenantID)
+	}
+
+	var at time.Time
+	if opts.To != "" {
+		var err error
+		if at, err = tenant.ParseRestoreTime(opts.To); err != nil {
+			log.Fatalf("Invalid --to: %v", err)
+		}
+		if !at.Before(time.Now()) {
+			log.Fatalf("--to %s is in the future", at.Format(time.RFC3339))
+		}
+	}
+	target := opts.Target
+	if target == "scratch" {
+		if at.IsZero() {
+			log.Fatal("--to is required")
+		}
+		target = tenant.ScratchSchema(tenantID, at)
+	}
+	validateTenantArg(target)
+	if target == tenantID || target == "public" {
+		log.Fatalf("--target must not be %s: the restore goes next to the live schema", target)
+	}
+	if opts.Promote && !tenant.IsScratchSchema(tenantID, target) {
+		log.Fatalf("Refusing to promote %s: only restores of %s (%s) can be promoted", target, tenantID, tenant.ScratchSchema(tenantID, time.Now()))
+	}
+
+	c := clusterFromEnv(parent.Context)
+	if err := c.EnsureContext(); err != nil {
+		log.Fatalf("Failed to ensure cluster context: %v", err)
+	}
+	auditCtx := c.Name + "/" + c.Namespace
+
+	log.Info("Finding api-server pod...")
+	pod, err := c.FindPod("api-server")
+	if err != nil {
+		log.Fatalf("Failed to find api-server pod: %v", err)
+	}
+
+	liveExists := schemaExists(c, pod, tenantID)
+	if !liveExists {
+		log.Warnf("%s has no live schema in %s; the restore will be its only copy", tenantID, auditCtx)
+	}
+
+	if schemaExists(c, pod, target) {
+		if !opts.Promote {
+			log.Fatalf("%s already exists in %s; drop it, pick another --target, or pass --promote to swap it in", target, auditCtx)
+		}
+		log.Infof("%s already exists; promoting it without restoring again", target)
+	} else {
+		if at.IsZero() {
+			log.Fatal("--to is required")
+		}
+		if !restoreTenantToSchema(c, pod, parent.Context, tenantID, target, at, opts) {
+			return
+		}
+	}
+
+	printRestoreComparison(c, pod, tenantID, target, liveExists)
+
+	if !opts.Promote {
+		fmt.Println()
+		fmt.Println("Next steps:")
+		fmt.Printf("  - inspect the restore: kubectl exec -it -n %s %s -- pginto, then SET search_path TO \"%s\";\n", c.Namespace, pod, target)
+		fmt.Printf("  - swap it in: ods restore tenant %s --target %s --promote -c %s\n", tenantID, target, parent.Context)
+		fmt.Printf("  - or discard it: DROP SCHEMA \"%s\" CASCADE\n", target)
+		return
+	}
+	checkRestoredRevision(c, pod, tenantID, target, liveExists)
+	promoteRestoredSchema(c, pod, auditCtx, tenantID, target, liveExists)
+}
+
+// checkRestoredRevision exits unless target is at the Alembic revision of
+// the live schema or, without one, of the deployed code, so a restore taken
+// before a migration is not swapped in behind code that expects it.
+func checkRestoredRevision(c *kube.Cluster, pod, tenantID, target string, liveExists bool) {
+	revision := func(schema string) string {
+		lines, err := tryQueryPod(c, pod, schemadiff.RevisionSQL(schema))
+		if err != nil {
+			log.Fatalf("Failed to read the Alembic revision of %s: %v", schema, err)
+		}
+		if len(lines) == 0 {
+			return ""
+		}
+		return lines[0]
+	}
+	restored := revision(target)
+
+	want, of := "", ""
+	if liveExists {
+		want, of = revision(tenantID), tenantID
+	} else {
+		status, err := alembic.RemoteStatus(c, pod, false, target)
+		if err != nil {
+			log.Fatalf("Failed to read the Alembic head: %v", err)
+		}
+		want, of = status.Head, "the deployed code's head"
+	}
+	if restored != want {
+		log.Fatalf("Refusing to promote %s: it is at Alembic revision %q but %s is at %q. Migrate it first (ods migrate status --tenant %s --fix), then promote again.",
+			target, restored, of, want, target)
+	}
+}
+
+// restoreTenantToSchema restores the database to a scratch instance as it was
+// at, and copies the tenant's schema from it into the live database as
+// target. It returns false when the restore is declined.
+func restoreTenantToSchema(c *kube.Cluster, pod, context, tenantID, target string, at time.Time, opts *RestoreTenantOptions) bool {
+	src := rdsBackupSource(context, opts.Source)
+	run := awsRunner(c)
+	source, err := backups.DescribeInstance(run, src.ID)
+	if err != nil {
+		log.Fatalf("Failed to describe %s: %v", src.ID, err)
+	}
+	if source.LatestRestorable.IsZero() {
+		log.Fatalf("%s has no automated backups to restore from", src.ID)
+	}
+	if at.After(source.LatestRestorable) {
+		log.Fatalf("%s can be restored up to %s at the latest", src.ID, source.LatestRestorable.Format(time.RFC3339))
+	}
+
+	scratch := backups.ScratchID("pitr", src.ID, time.Now())
+	auditCtx := c.Name + "/" + c.Namespace
+	fmt.Printf("Restore %s as of %s\n", tenantID, at.Format(time.RFC3339))
+	fmt.Printf("  from     %s (%s)\n", src.ID, auditCtx)
+	fmt.Printf("  via      scratch instance %s\n", scratch)
+	fmt.Printf("  into     schema %s (the live schema is not touched)\n", target)
+	fmt.Println()
+	if !opts.Yes && !prompt.Confirm("Start the restore (the scratch instance is billed until deleted)? (yes/no): ") {
+		log.Info("Aborted.")
+		return false
+	}
+
+	if err := auditlog.Record(auditlog.Entry{
+		Action:  "tenant.restore",
+		Context: auditCtx,
+		Target:  tenantID,
+		Detail:  fmt.Sprintf("to=%s target=%s scratch=%s", at.Format(time.RFC3339), target, scratch),
+	}); err != nil {
+		log.Fatalf("Refusing to restore a tenant without an audit record: %v", err)
+	}
+
+	log.Infof("Restoring %s as of %s to %s...", src.ID, at.Format(time.RFC3339), scratch)
+	restored, cleanup, err := createScratchInstance(run, scratch, backups.PointInTimeArgs(source, scratch, at, opts.InstanceClass), opts.KeepInstance)
+	if err == nil {
+		err = copyRestoredSchema(c, pod, restored, tenantID, target)
+	}
+	if cleanup != nil {
+		cleanup()
+	}
+	if err != nil {
+		log.Fatalf("Failed to restore %s: %v", tenantID, err)
+	}
+	log.Infof("Restored %s as of %s into %s", tenantID, at.Format(time.RFC3339), target)
+	return true
+}
+
+// rdsBackupSource returns the rds backup source of context named name, or
+// the first one.
+func rdsBackupSource(context, name string) config.BackupSource {
+	for _, src := range loadODSConfig().Backups.Environments[context] {
+		if src.Type == backups.TypeRDS && (name == "" || src.Name == name) {
+			return src
+		}
+	}
+	if name != "" {
+		log.Fatalf("No rds backup source named %q for %s in the ods config (see ods verify-backups --help)", name, context)
+	}
+	log.Fatalf("No rds backup source for %s in the ods config (see ods verify-backups --help)", context)
+	return config.BackupSource{}
+}
+
+// copyRestoredSchema renames the tenant's schema in the restored instance to
+// target, so it cannot clash with the live one, and copies it into the live
+// database with pg_dump and psql on pod.
+func copyRestoredSchema(c *kube.Cluster, pod string, restored *backups.Instance, tenantID, target string) error {
+	// pginto connects to PGINTO_HOST instead of the live database; the
+	// restore keeps the source's users, passwords and IAM auth.
+	restoredEnv := []string{"env", "PGINTO_HOST=" + restored.Endpoint, "POSTGRES_PORT=" + strconv.Itoa(restored.Port)}
+	remote := "/tmp/" + target + ".restore.dump"
+	defer func() {
+		if _, err := c.ExecOnPod(pod, "rm", "-f", remote, remote+".sql"); err != nil {
+			log.Warnf("Failed to remove %s from %s: %v", remote, pod, err)
+		}
+	}()
+
+	log.Infof("Renaming %s to %s in %s...", tenantID, target, restored.ID)
+	if _, err := c.ExecOnPod(pod, append(restoredEnv, "pginto", "-v", "ON_ERROR_STOP=1", "-c", tenant.RenameSchemaSQL(tenantID, target))...); err != nil {
+		return fmt.Errorf("failed to rename %s in %s (did it exist at that time?): %w", tenantID, restored.ID, err)
+	}
+	log.Infof("Dumping %s...", target)
+	if _, err := c.ExecOnPod(pod, append(restoredEnv, "PGINTO_PSQL_BIN=pg_dump", "pginto", "-Fc", "-n", target, "-f", remote)...); err != nil {
+		return fmt.Errorf("failed to dump %s: %w", target, err)
+	}
+	// As in tenant migrate: one transaction, so a failure leaves no
+	// half-restored schema behind.
+	log.Infof("Copying %s into the live database...", target)
+	if _, err := c.ExecOnPod(pod, "sh", "-c",
+		`pg_restore --no-owner --no-privileges -f "$1.sql" "$1" && pginto -q -v ON_ERROR_STOP=1 --single-transaction -f "$1.sql"`,
+		"sh", remote); err != nil {
+		return fmt.Errorf("failed to copy %s into the live database: %w", target, err)
+	}
+	return nil
+}
+
+func schemaExists(c *kube.Cluster, pod, schema string) bool {
+	lines := queryPod(c, pod, tenant.SchemaExistsSQL(schema))
+	return len(lines) == 1 && lines[0] == "1"
+}
+
+// printRestoreComparison prints the tables whose row counts differ between
+// the live and restored schemas.
+func printRestoreComparison(c *kube.Cluster, pod, tenantID, target string, liveExists bool) {
+	counts := func(schema string) map[string]int {
+		tables := queryPod(c, pod, tenant.TablesSQL(schema))
+		if len(tables) == 0 {
+			return map[string]int{}
+		}
+		n, err := tenant.ParseCounts(queryPod(c, pod, tenant.CountsSQL(schema, tables)))
+		if err != nil {
+			log.Fatalf("Failed to count rows in %s: %v", schema, err)
+		}
+		return n
+	}
+	live := map[string]int{}
+	if liveExists {
+		live = counts(tenantID)
+	}
+	restored := counts(target)
+	if len(restored) == 0 {
+		log.Fatalf("%s has no tables", target)
+	}
+
+	var liveRows, restoredRows int
+	for _, n := range live {
+		liveRows += n
+	}
+	for _, n := range restored {
+		restoredRows += n
+	}
+	fmt.Println()
+	fmt.Printf("%s (live) vs %s (restored): %d vs %d table(s), %d vs %d row(s)\n",
+		tenantID, target, len(live), len(restored), liveRows, restoredRows)
+
+	mismatches := tenant.CompareCounts(live, restored)
+	if len(mismatches) == 0 {
+		fmt.Println("Row counts match in every table.")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "TABLE\tLIVE\tRESTORED\tDIFF")
+	_, _ = fmt.Fprintln(w, "-----\t----\t--------\t----")
+	for _, mm := range mismatches {
+		diff := ""
+		if mm.Source >= 0 && mm.Target >= 0 {
+			diff = fmt.Sprintf("%+d", mm.Target-mm.Source)
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", mm.Table, formatCount(mm.Source), formatCount(mm.Target), diff)
+	}
+	_ = w.Flush()
+}
+
+// promoteRestoredSchema swaps target in for the tenant's live schema.
+func promoteRestoredSchema(c *kube.Cluster, pod, auditCtx, tenantID, target string, liveExists bool) {
+	replaced := tenant.ReplacedSchema(tenantID, time.Now())
+	fmt.Println()
+	if liveExists {
+		fmt.Printf("Promoting renames %s to %s and %s to %s.\n", tenantID, replaced, target, tenantID)
+	} else {
+		fmt.Printf("Promoting renames %s to %s.\n", target, tenantID)
+	}
+	if typed := prompt.String(fmt.Sprintf("Type %s to replace its live schema: ", tenantID)); typed != tenantID {
+		log.Info("Tenant ID did not match. Aborted.")
+		return
+	}
+
+	if err := auditlog.Record(auditlog.Entry{
+		Action:  "tenant.restore.promote",
+		Context: auditCtx,
+		Target:  tenantID,
+		Detail:  fmt.Sprintf("restored=%s replaced=%s", target, replaced),
+	}); err != nil {
+		log.Fatalf("Refusing to promote a restore without an audit record: %v", err)
+	}
+	queryPod(c, pod, tenant.PromoteSQL(tenantID, target, replaced, liveExists))
+
+	log.Infof("%s now serves the restored data", tenantID)
+	if liveExists {
+		fmt.Printf("The previous schema is kept as %s; once confirmed, drop it with DROP SCHEMA \"%s\" CASCADE\n", replaced, replaced)
+	}
+	fmt.Println("Re-index the tenant's connectors so search matches the restored data.")
+}
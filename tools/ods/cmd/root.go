@@ -7,6 +7,7 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/onyx-dot-app/onyx/tools/ods/internal/docker"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/render"
 )
 
 var (
@@ -17,12 +18,17 @@ var (
 // RootOptions holds options for the root command.
 type RootOptions struct {
 	Debug   bool
+	NoColor bool
 	Project string
+	Ticket  string
 }
 
 // NewRootCommand creates the root command.
 func NewRootCommand() *cobra.Command {
 	opts := &RootOptions{}
+	var reporter *ticketReporter
+	var recorder *telemetryRecorder
+	var runs *historyRecorder
 
 	cmd := &cobra.Command{
 		Use:   "ods ",
@@ -34,39 +40,137 @@ func NewRootCommand() *cobra.Command {
 			} else {
 				log.SetLevel(log.InfoLevel)
 			}
+			render.SetNoColor(opts.NoColor)
 			log.SetFormatter(&log.TextFormatter{
 				DisableTimestamp: true,
+				DisableColors:    render.NoColor(),
 			})
 			docker.SetProjectFlags(opts.Project)
+			reporter = startTicketReporter(opts.Ticket)
+			recorder = startTelemetry(cmd)
+			runs = startHistory(cmd)
+		},
+		PersistentPostRun: func(cmd *cobra.Command, args []string) {
+			reporter.Done()
+			recorder.Done()
+			runs.Done()
 		},
 		Version: fmt.Sprintf("%s\ncommit %s", Version, Commit),
 	}
 
 	cmd.PersistentFlags().BoolVar(&opts.Debug, "debug", false, "run in debug mode")
+	cmd.PersistentFlags().BoolVar(&opts.NoColor, "no-color", false, "Disable colored output (also set by NO_COLOR)")
 	cmd.PersistentFlags().StringVar(&opts.Project, "project", "", "Docker Compose project name (default: basename of git root)")
+	cmd.PersistentFlags().StringVar(&opts.Ticket, "ticket", "", "Jira/Linear issue (e.g. OPS-123) to record with audited actions and comment on")
 
 	// Add subcommands
+	cmd.AddCommand(NewAccessCommand())
+	cmd.AddCommand(NewAliasCommand())
+	cmd.AddCommand(NewAPICommand())
+	cmd.AddCommand(NewAnonymizeCommand())
+	cmd.AddCommand(NewAskCommand())
 	cmd.AddCommand(NewAuditCommand())
+	cmd.AddCommand(NewDepsCommand())
+	cmd.AddCommand(NewSBOMCommand())
+	cmd.AddCommand(NewScanCommand())
 	cmd.AddCommand(NewBackendCommand())
+	cmd.AddCommand(NewBillingCommand())
+	cmd.AddCommand(NewCostsCommand())
+	cmd.AddCommand(NewCanaryCommand())
+	cmd.AddCommand(NewCeleryCommand())
+	cmd.AddCommand(NewChaosCommand())
 	cmd.AddCommand(NewCheckLazyImportsCommand())
 	cmd.AddCommand(NewCherryPickCommand())
+	cmd.AddCommand(NewChunksCommand())
+	cmd.AddCommand(NewCompareCommand())
+	cmd.AddCommand(NewConnectorCommand())
+	cmd.AddCommand(NewConsistencyCommand())
+	cmd.AddCommand(NewCredentialsCommand())
 	cmd.AddCommand(NewDBCommand())
 	cmd.AddCommand(NewDeployCommand())
+	cmd.AddCommand(NewDistCommand())
+	cmd.AddCommand(NewDNSCommand())
+	cmd.AddCommand(NewDocCommand())
+	cmd.AddCommand(NewDoctorCommand())
+	cmd.AddCommand(NewDomainsCommand())
+	cmd.AddCommand(NewDrainCommand())
 	cmd.AddCommand(NewOpenAPICommand())
 	cmd.AddCommand(NewComposeCommand())
+	cmd.AddCommand(NewCronCommand())
+	cmd.AddCommand(NewCurlCommand())
+	cmd.AddCommand(NewEmbeddingsCommand())
 	cmd.AddCommand(NewEnvCommand())
+	cmd.AddCommand(NewEvalCommand())
+	cmd.AddCommand(NewEventsCommand())
+	cmd.AddCommand(NewExplainCommand())
+	cmd.AddCommand(NewFixturesCommand())
+	cmd.AddCommand(NewFlagsCommand())
+	cmd.AddCommand(NewGenCommand())
+	cmd.AddCommand(NewHealthCommand())
+	cmd.AddCommand(NewHooksCommand())
+	cmd.AddCommand(NewI18nCommand())
 	cmd.AddCommand(NewLogsCommand())
+	cmd.AddCommand(NewMCPCommand())
+	cmd.AddCommand(NewMigrateCommand())
+	cmd.AddCommand(NewMockLLMCommand())
+	cmd.AddCommand(NewMockOAuthCommand())
+	cmd.AddCommand(NewNginxCommand())
+	cmd.AddCommand(NewOAuthCommand())
+	cmd.AddCommand(NewPauseTenantIndexingCommand())
+	cmd.AddCommand(NewPortForwardsCommand())
+	cmd.AddCommand(NewPGCommand())
+	cmd.AddCommand(NewPRCommand())
+	cmd.AddCommand(NewProfileCommand())
+	cmd.AddCommand(NewProxyCommand())
 	cmd.AddCommand(NewPullCommand())
+	cmd.AddCommand(NewRateLimitCommand())
+	cmd.AddCommand(NewReconcileCommand())
+	cmd.AddCommand(NewReindexCommand())
+	cmd.AddCommand(NewReportCommand())
+	cmd.AddCommand(NewRestoreCommand())
+	cmd.AddCommand(NewRestartCommand())
+	cmd.AddCommand(NewResumeTenantIndexingCommand())
+	cmd.AddCommand(NewRunCommand())
 	cmd.AddCommand(NewRunCICommand())
+	cmd.AddCommand(NewRunJobCommand())
+	cmd.AddCommand(NewScaleCommand())
+	cmd.AddCommand(NewSchemaCommand())
 	cmd.AddCommand(NewScreenshotDiffCommand())
+	cmd.AddCommand(NewServeCommand())
+	cmd.AddCommand(NewSupportBundleCommand())
 	cmd.AddCommand(NewDesktopCommand())
 	cmd.AddCommand(NewDevCommand())
+	cmd.AddCommand(NewDiffEnvCommand())
+	cmd.AddCommand(NewDiffSchemaVespaCommand())
 	cmd.AddCommand(NewWebCommand())
+	cmd.AddCommand(NewWSCommand())
+	cmd.AddCommand(NewBotCommand())
 	cmd.AddCommand(NewLatestStableTagCommand())
+	cmd.AddCommand(NewLintCommand())
 	cmd.AddCommand(NewWhoisCommand())
+	cmd.AddCommand(NewWhoamiCommand())
+	cmd.AddCommand(NewTenantCommand())
+	cmd.AddCommand(NewTLSCommand())
+	cmd.AddCommand(NewTokenCommand())
+	cmd.AddCommand(NewTelemetryCommand())
+	cmd.AddCommand(NewTestCommand())
+	cmd.AddCommand(NewUsageCommand())
+	cmd.AddCommand(NewUpgradeCheckCommand())
 	cmd.AddCommand(NewTraceCommand())
+	cmd.AddCommand(NewValidateCommand())
+	cmd.AddCommand(NewVerifyBackupsCommand())
+	cmd.AddCommand(NewVespaCommand())
+	cmd.AddCommand(NewWarmCommand())
+	cmd.AddCommand(NewWorkspaceCommand())
+	cmd.AddCommand(NewGDPRCommand())
+	cmd.AddCommand(NewImpersonateCommand())
 	cmd.AddCommand(NewInstallSkillCommand())
 	cmd.AddCommand(NewReleaseCommand())
+	cmd.AddCommand(NewSecretsCommand())
+	cmd.AddCommand(NewSessionCommand())
+	cmd.AddCommand(NewSSODebugCommand())
+
+	addAliasCommands(cmd)
 
 	return cmd
 }
@@ -74,3 +178,10 @@ func NewRootCommand() *cobra.Command {
 func rootCmd(cmd *cobra.Command, args []string) {
 	_ = cmd.Help()
 }
+
+// exitWith exits with a non-zero code the way log.Fatal does, running the
+// exit handlers first so history, telemetry, --notify and --ticket record the
+// failure. Commands use it instead of os.Exit, which skips them.
+func exitWith(code int) {
+	log.StandardLogger().Exit(code)
+}
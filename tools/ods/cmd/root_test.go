@@ -0,0 +1,26 @@
+package cmd
+
+import (
+	"testing"
+
+	log "github.com/sirupsen/logrus"
+)
+
+func TestExitWithRunsExitHandlers(t *testing.T) {
+	logger := log.StandardLogger()
+	exit := logger.ExitFunc
+	t.Cleanup(func() { logger.ExitFunc = exit })
+
+	code := -1
+	logger.ExitFunc = func(c int) { code = c }
+	ran := false
+	log.RegisterExitHandler(func() { ran = true })
+
+	exitWith(3)
+	if !ran {
+		t.Error("expected the exit handlers to run, so the failure is recorded")
+	}
+	if code != 3 {
+		t.Errorf("exit code = %d, want 3", code)
+	}
+}
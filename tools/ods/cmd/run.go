@@ -0,0 +1,191 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/alias"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/jobs"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/playbook"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/prompt"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/render"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/runner"
+)
+
+// RunOptions holds options for the run command.
+type RunOptions struct {
+	File   string
+	Yes    bool
+	DryRun bool
+}
+
+// NewRunCommand creates the run command for playbooks.
+func NewRunCommand() *cobra.Command {
+	opts := &RunOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "run -f <playbook.yaml> [PARAM=value...]",
+		Short: "Run a playbook of ods steps",
+		Long: `Run a playbook: a YAML runbook of ods command lines, kept with the code so a
+procedure can be reviewed and run instead of copied from a wiki.
+
+  description: Triage a customer report that the site is down
+  params:
+    - name: HOST
+      required: true
+    - name: CONTEXT
+      default: data_plane
+  steps:
+    - name: trace
+      run: dns ${HOST} -c ${CONTEXT}
+      allow_failure: true
+    - name: events
+      run: events -c ${CONTEXT} --since 30m
+      if: trace.failed
+    - name: restart
+      run: restart web -c ${CONTEXT}
+      if: trace.exit == 1
+      confirm: true
+
+Each step's run is an ods command line without the leading "ods", run as its
+own ods process with the terminal attached, so its prompts work as usual.
+${PARAM} placeholders are filled from PARAM=value arguments or defaults; a
+value always stays one argument. if: runs the step only when a condition on
+an earlier step holds: <step>.ok, <step>.failed, <step>.skipped, or
+<step>.exit == N (or != N).
+
+The playbook stops at the first step that fails, with its exit code, unless
+the step has allow_failure: true. Steps with confirm: true ask before they
+run, for destructive actions; --yes skips those questions (the commands' own
+production prompts still apply). --dry-run prints the resolved steps only.
+
+Examples:
+  ods run -f playbooks/site-down.yaml HOST=onyx.acme.com
+  ods run -f playbooks/site-down.yaml HOST=onyx.acme.com CONTEXT=staging --dry-run`,
+		Args: cobra.ArbitraryArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			runPlaybook(cmd.Root(), opts, args)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.File, "file", "f", "", "Playbook to run (required)")
+	cmd.Flags().BoolVar(&opts.Yes, "yes", false, "Run steps marked confirm without asking")
+	cmd.Flags().BoolVar(&opts.DryRun, "dry-run", false, "Print the resolved steps instead of running them")
+	_ = cmd.MarkFlagRequired("file")
+
+	return cmd
+}
+
+func runPlaybook(root *cobra.Command, opts *RunOptions, args []string) {
+	p, err := playbook.Load(opts.File)
+	if err != nil {
+		log.Fatalf("Failed to load playbook: %v", err)
+	}
+	for _, s := range p.Steps {
+		words, _ := alias.Split(s.Run)
+		if c, _, err := root.Find(words[:1]); err != nil || c == root || c.GroupID == aliasGroup {
+			log.Fatalf("%s: step %s does not start with a built-in ods command", p.Source, s.Name)
+		}
+	}
+	values, err := jobs.ParseAssignments(args)
+	if err != nil {
+		log.Fatal(err)
+	}
+	params, err := p.Resolve(values)
+	if err != nil {
+		log.Fatalf("%s: %v", p.Source, err)
+	}
+
+	if opts.DryRun {
+		printPlaybook(os.Stdout, p, params)
+		return
+	}
+
+	self, err := os.Executable()
+	if err != nil {
+		log.Fatalf("Failed to find the ods binary: %v", err)
+	}
+	if p.Description != "" {
+		log.Infof("%s: %s", p.Source, p.Description)
+	}
+	results, err := playbook.Execute(p, playbook.Options{
+		Params: params,
+		Run:    playbookStepRunner(nil, self),
+		Confirm: func(s *playbook.Step, argv []string) bool {
+			if opts.Yes {
+				return true
+			}
+			return prompt.Confirm(fmt.Sprintf("Run step %s: ods %s? (yes/no): ", s.Name, alias.Join(argv)))
+		},
+		OnStep: func(i int, s *playbook.Step, argv []string) {
+			log.Infof("==> [%d/%d] %s: ods %s", i+1, len(p.Steps), s.Name, alias.Join(argv))
+		},
+	})
+
+	_, _ = fmt.Fprintln(os.Stdout)
+	printPlaybookResults(os.Stdout, results)
+
+	var failed *playbook.FailedError
+	switch {
+	case errors.As(err, &failed) && failed.Declined:
+		log.Infof("Stopped: step %s was declined", failed.Step)
+		exitWith(1)
+	case errors.As(err, &failed):
+		log.Errorf("Stopped: %v", failed)
+		exitWith(failed.Exit)
+	case err != nil:
+		log.Fatalf("Failed to run the playbook: %v", err)
+	}
+}
+
+// playbookStepRunner returns the playbook.Options.Run that runs each step as
+// its own ods process, self, with the terminal attached.
+func playbookStepRunner(r runner.Runner, self string) func([]string) (int, error) {
+	return func(argv []string) (int, error) {
+		err := runner.Or(r).Run(runner.Cmd{Name: self, Args: argv, Stdin: os.Stdin, Stdout: os.Stdout, Stderr: os.Stderr})
+		if code, ok := runner.ExitCode(err); ok {
+			return code, nil
+		}
+		return 0, err
+	}
+}
+
+func printPlaybook(w io.Writer, p *playbook.Playbook, params map[string]string) {
+	if p.Description != "" {
+		_, _ = fmt.Fprintf(w, "%s\n\n", p.Description)
+	}
+	table := render.NewTable("STEP", "COMMAND", "IF", "NOTES")
+	for _, s := range p.Steps {
+		var notes []string
+		if s.Confirm {
+			notes = append(notes, "confirm")
+		}
+		if s.AllowFailure {
+			notes = append(notes, "allow failure")
+		}
+		cond := s.If
+		if cond == "" {
+			cond = "-"
+		}
+		table.Row(s.Name, "ods "+alias.Join(s.Argv(params)), cond, strings.Join(notes, ", "))
+	}
+	_ = table.Write(w)
+}
+
+func printPlaybookResults(w io.Writer, results []playbook.Result) {
+	table := render.NewTable("STEP", "STATE", "EXIT", "COMMAND")
+	for _, r := range results {
+		exit := "-"
+		if r.State == playbook.StateOK || r.State == playbook.StateFailed {
+			exit = fmt.Sprint(r.Exit)
+		}
+		table.Row(r.Step, r.State, exit, "ods "+alias.Join(r.Command))
+	}
+	_ = table.Write(w)
+}
@@ -0,0 +1,286 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"sort"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/auditlog"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/jobs"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/kube"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/paths"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/prompt"
+)
+
+const jobPollInterval = 2 * time.Second
+
+// RunJobOptions holds options for the run-job command.
+type RunJobOptions struct {
+	Context string
+	List    bool
+	DryRun  bool
+	Keep    bool
+	Yes     bool
+	Notify  string
+}
+
+// NewRunJobCommand creates the run-job command for one-off Kubernetes jobs.
+func NewRunJobCommand() *cobra.Command {
+	opts := &RunJobOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "run-job <template> [PARAM=value...]",
+		Short: "Run a one-off Kubernetes job from a template",
+		Long: `Run a one-off Kubernetes job from a named template, stream its logs, wait
+for it to finish and delete it.
+
+Templates are YAML files with a description, parameters and a Job manifest
+using ${PARAM} placeholders; JOB_NAME and NAMESPACE are always available.
+Templates with a base deployment (usually api-server) run with that
+deployment's image, environment and volumes, so scripts see the same
+database, secrets and config as the backend. ods ships a few templates;
+files in ` + "~/.config/onyx-dev/jobs/<name>.yaml" + ` add to or override them.
+
+The job is deleted when it finishes or the command is interrupted, unless
+--keep is passed; jobs are also garbage-collected a day after finishing in
+case ods is killed. Running a job asks for confirmation in production
+contexts unless --yes is passed, and is recorded in the local audit log.
+
+Requires: AWS SSO login, kubectl access to the EKS cluster.
+
+Examples:
+  ods run-job --list
+  ods run-job shell COMMAND='python scripts/debugging/onyx_list_tenants.py' -c staging
+  ods run-job force-delete-connector CONNECTOR_ID=42
+  ods run-job alembic-upgrade REVISION=head --dry-run`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if opts.List {
+				return cobra.NoArgs(cmd, args)
+			}
+			return cobra.MinimumNArgs(1)(cmd, args)
+		},
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			if len(args) > 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			templates, _ := jobs.List(paths.JobTemplatesDir())
+			names := make([]string, 0, len(templates))
+			for _, t := range templates {
+				names = append(names, t.Name+"\t"+t.Description)
+			}
+			return names, cobra.ShellCompDirectiveNoFileComp
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			if opts.List {
+				listJobTemplates()
+				return
+			}
+			runJob(opts, args[0], args[1:])
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.Context, "context", "c", "data_plane", "cluster context name (maps to KUBE_CTX_<NAME> env var)")
+	cmd.Flags().BoolVarP(&opts.List, "list", "l", false, "List templates and their parameters")
+	cmd.Flags().BoolVar(&opts.DryRun, "dry-run", false, "Print the job manifest instead of running it")
+	cmd.Flags().BoolVar(&opts.Keep, "keep", false, "Keep the job and its pods after it finishes")
+	cmd.Flags().BoolVar(&opts.Yes, "yes", false, "Skip the confirmation prompt")
+	addNotifyFlag(cmd, &opts.Notify)
+
+	return cmd
+}
+
+func listJobTemplates() {
+	templates, err := jobs.List(paths.JobTemplatesDir())
+	if err != nil {
+		log.Fatalf("Failed to load job templates: %v", err)
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, t := range templates {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t(%s)\n", t.Name, t.Description, t.Source)
+		for _, p := range t.Params {
+			detail := p.Description
+			switch {
+			case p.Required:
+				detail += " (required)"
+			case p.Default != "":
+				detail += fmt.Sprintf(" (default %q)", p.Default)
+			}
+			_, _ = fmt.Fprintf(w, "  %s\t%s\t\n", p.Name, strings.TrimSpace(detail))
+		}
+	}
+	_ = w.Flush()
+}
+
+func runJob(opts *RunJobOptions, name string, assignments []string) {
+	tmpl, err := jobs.Load(name, paths.JobTemplatesDir())
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	values, err := jobs.ParseAssignments(assignments)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	params, err := tmpl.Resolve(values)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	c := clusterFromEnv(opts.Context)
+	if err := c.EnsureContext(); err != nil {
+		log.Fatalf("Failed to ensure cluster context: %v", err)
+	}
+	auditCtx := c.Name + "/" + c.Namespace
+
+	var base map[string]any
+	if tmpl.Base != "" {
+		deployments, err := c.ListDeployments()
+		if err != nil {
+			log.Fatalf("Failed to list deployments: %v", err)
+		}
+		names := make([]string, 0, len(deployments))
+		for _, d := range deployments {
+			names = append(names, d.Name)
+		}
+		deployment, err := resolveDeployment(names, tmpl.Base)
+		if err != nil {
+			log.Fatalf("Failed to find the %s base deployment: %v", tmpl.Base, err)
+		}
+		if base, err = c.DeploymentPodSpec(deployment); err != nil {
+			log.Fatalf("Failed to read deployment %s: %v", deployment, err)
+		}
+	}
+
+	jobName := jobs.JobName(tmpl.Name, time.Now().UTC().Format("20060102-150405"))
+	manifest, err := tmpl.Render(jobName, c.Namespace, params, base)
+	if err != nil {
+		log.Fatalf("Failed to render %s: %v", tmpl.Name, err)
+	}
+	if opts.DryRun {
+		fmt.Println(string(manifest))
+		return
+	}
+
+	summary := jobParamSummary(params)
+	fmt.Printf("Running %s in %s as job %s\n", tmpl.Name, auditCtx, jobName)
+	if summary != "" {
+		fmt.Printf("  %s\n", summary)
+	}
+	if !opts.Yes && isProductionContext(opts.Context) {
+		if !prompt.Confirm(fmt.Sprintf("Run %s in %s? (yes/no): ", tmpl.Name, opts.Context)) {
+			log.Info("Aborted.")
+			return
+		}
+	}
+
+	notifier := startNotifier(opts.Notify)
+
+	if err := auditlog.Record(auditlog.Entry{
+		Action:  "job.run",
+		Context: auditCtx,
+		Target:  jobName,
+		Detail:  strings.TrimSpace(tmpl.Name + " " + summary),
+	}); err != nil {
+		log.Fatalf("Refusing to run a job without an audit record: %v", err)
+	}
+
+	if err := c.CreateJob(manifest); err != nil {
+		log.Fatalf("Failed to create job: %v", err)
+	}
+	cleanup := func() {
+		if opts.Keep {
+			log.Infof("Keeping job %s (delete it with: kubectl delete job %s)", jobName, jobName)
+			return
+		}
+		if err := c.DeleteJob(jobName); err != nil {
+			log.Warnf("Failed to delete job %s: %v", jobName, err)
+			return
+		}
+		log.Infof("Deleted job %s", jobName)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	job, err := followJob(ctx, c, jobName)
+	cleanup()
+	switch {
+	case err != nil:
+		log.Fatalf("%v", err)
+	case job.Condition == "Failed":
+		log.Fatalf("Job %s failed: %s", jobName, job.Reason)
+	}
+	log.Infof("Job %s completed", jobName)
+	notifier.Done(fmt.Sprintf("%s completed in %s (job %s)", tmpl.Name, auditCtx, jobName))
+}
+
+// followJob streams the logs of each of the job's pods in turn until the job
+// finishes. It gives up when a pod can't start or ctx is cancelled.
+func followJob(ctx context.Context, c *kube.Cluster, jobName string) (*kube.Job, error) {
+	streamed := map[string]bool{}
+	waiting := ""
+	for {
+		if ctx.Err() != nil {
+			return nil, fmt.Errorf("interrupted while waiting for job %s", jobName)
+		}
+		job, err := c.GetJob(jobName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get job %s: %w", jobName, err)
+		}
+		pods, err := c.ListPodsWithSelector("job-name=" + jobName)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list pods of job %s: %w", jobName, err)
+		}
+		sort.Slice(pods, func(i, j int) bool { return pods[i].Created.Before(pods[j].Created) })
+
+		for _, p := range pods {
+			if streamed[p.Name] {
+				continue
+			}
+			if reason := p.FailureReason(); reason != "" {
+				return nil, fmt.Errorf("pod %s can't start: %s", p.Name, reason)
+			}
+			if p.Phase == "Pending" {
+				if waiting != p.Name {
+					log.Infof("Waiting for pod %s to start...", p.Name)
+					waiting = p.Name
+				}
+				continue
+			}
+			streamed[p.Name] = true
+			if len(pods) > 1 {
+				log.Infof("Logs of pod %s:", p.Name)
+			}
+			if err := c.Logs(p.Name, kube.LogOptions{AllContainers: true, Follow: true}, os.Stdout); err != nil && ctx.Err() == nil {
+				log.Warnf("Log stream of %s ended: %v", p.Name, err)
+			}
+		}
+
+		if job.Finished() {
+			return job, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, fmt.Errorf("interrupted while waiting for job %s", jobName)
+		case <-time.After(jobPollInterval):
+		}
+	}
+}
+
+// jobParamSummary renders params as sorted NAME=value pairs.
+func jobParamSummary(params map[string]string) string {
+	pairs := make([]string, 0, len(params))
+	for k, v := range params {
+		pairs = append(pairs, fmt.Sprintf("%s=%q", k, v))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, " ")
+}
@@ -0,0 +1,29 @@
+package cmd
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/runner"
+)
+
+func TestPlaybookStepRunner(t *testing.T) {
+	f := (&runner.Fake{}).
+		On("/usr/local/bin/ods dns onyx.acme.com", runner.Response{Exit: 3}).
+		On("/usr/local/bin/ods events", runner.Response{}).
+		On("/usr/local/bin/ods restart", runner.Response{Err: errors.New("fork/exec: permission denied")})
+	run := playbookStepRunner(f, "/usr/local/bin/ods")
+
+	if code, err := run([]string{"dns", "onyx.acme.com"}); code != 3 || err != nil {
+		t.Errorf("failing step = %d, %v; want its exit code and no error", code, err)
+	}
+	if code, err := run([]string{"events", "--since", "30m"}); code != 0 || err != nil {
+		t.Errorf("passing step = %d, %v", code, err)
+	}
+	if _, err := run([]string{"restart", "web"}); err == nil {
+		t.Error("expected a step that could not start to return an error")
+	}
+	if got := f.Lines()[1]; got != "/usr/local/bin/ods events --since 30m" {
+		t.Errorf("unexpected command %q", got)
+	}
+}
@@ -0,0 +1,209 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/paths"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/sbom"
+)
+
+// onyxImages are the images a release ships, as ods sbom --tag names them.
+var onyxImages = []string{"onyxdotapp/onyx-backend", "onyxdotapp/onyx-web-server", "onyxdotapp/onyx-model-server"}
+
+// SBOMOptions holds options for the sbom command.
+type SBOMOptions struct {
+	Format  string
+	Output  string
+	Backend bool
+	Web     bool
+	Images  []string
+	Tag     string
+	FailOn  string
+}
+
+// NewSBOMCommand creates the sbom command.
+func NewSBOMCommand() *cobra.Command {
+	opts := &SBOMOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "sbom",
+		Short: "Produce a software bill of materials and check its licenses",
+		Long: `Produce one software bill of materials, as CycloneDX 1.5 or SPDX 2.3 JSON,
+merging the backend (uv.lock), the web app (bun.lock) and, with --image or
+--tag, built container images, OS packages included. Packages found in more
+than one place are listed once, with every place they were found.
+
+Licenses come from deps.dev and are checked against the license policy:
+permissive licenses are allowed, strong copyleft (GPL, AGPL, SSPL, ...) is
+incompatible, and weak copyleft or licenses the policy does not know need
+review. OS packages in images are listed but not checked. The findings go
+to stderr and the command exits non-zero on any at or above --fail-on.
+
+With no selector flags, the backend and web app are included.
+
+Examples:
+  # The SBOM of a release, images included, for a customer
+  ods sbom --tag v2.10.4 --format spdx -o onyx-v2.10.4.spdx.json
+
+  # Check the lockfiles' licenses only
+  ods sbom -o /dev/null --fail-on review`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			runSBOM(opts, cmd.Root().Version)
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.Format, "format", "cyclonedx", "SBOM format: "+strings.Join(sbom.Formats, ", "))
+	cmd.Flags().StringVarP(&opts.Output, "output", "o", "", "Write the SBOM to this file (default: stdout)")
+	cmd.Flags().BoolVar(&opts.Backend, "backend", false, "Include the backend's Python dependencies (uv.lock)")
+	cmd.Flags().BoolVar(&opts.Web, "web", false, "Include the web app's JS dependencies (bun.lock)")
+	cmd.Flags().StringArrayVar(&opts.Images, "image", nil, "Include a container image (repeatable)")
+	cmd.Flags().StringVar(&opts.Tag, "tag", "", "Include the backend, web server and model server images at this tag, and record it as the version")
+	cmd.Flags().StringVar(&opts.FailOn, "fail-on", string(sbom.VerdictIncompatible), "Fail on licenses at or above: incompatible, unknown, review, or none")
+
+	return cmd
+}
+
+// sbomFailVerdicts maps --fail-on to the verdicts that fail the command.
+var sbomFailVerdicts = map[string][]sbom.Verdict{
+	"incompatible": {sbom.VerdictIncompatible},
+	"unknown":      {sbom.VerdictIncompatible, sbom.VerdictUnknown},
+	"review":       {sbom.VerdictIncompatible, sbom.VerdictUnknown, sbom.VerdictReview},
+	"none":         nil,
+}
+
+func runSBOM(opts *SBOMOptions, toolVersion string) {
+	failVerdicts, ok := sbomFailVerdicts[opts.FailOn]
+	if !ok {
+		log.Fatalf("Invalid --fail-on %q (want incompatible, unknown, review, or none)", opts.FailOn)
+	}
+	if !slices.Contains(sbom.Formats, opts.Format) {
+		log.Fatalf("Invalid --format %q (want %s)", opts.Format, strings.Join(sbom.Formats, " or "))
+	}
+	root, err := paths.GitRoot()
+	if err != nil {
+		log.Fatalf("Failed to find git root: %v", err)
+	}
+
+	images := opts.Images
+	if opts.Tag != "" {
+		for _, image := range onyxImages {
+			images = append(images, image+":"+opts.Tag)
+		}
+	}
+	includeAll := !opts.Backend && !opts.Web && len(images) == 0
+
+	// Name lockfiles repo-relative in the SBOM, not by this machine's path.
+	sources := map[string]string{}
+	var lockfiles []string
+	if includeAll || opts.Backend {
+		lockfiles = append(lockfiles, filepath.Join(root, "uv.lock"))
+	}
+	if includeAll || opts.Web {
+		lockfiles = append(lockfiles, filepath.Join(root, "web", "bun.lock"), filepath.Join(root, "bun.lock"))
+	}
+	var existing []string
+	for _, lf := range lockfiles {
+		if _, err := os.Stat(lf); err != nil {
+			log.Debugf("Skipping missing lockfile %s", lf)
+			continue
+		}
+		rel, _ := filepath.Rel(root, lf)
+		sources[filepath.ToSlash(lf)] = filepath.ToSlash(rel)
+		existing = append(existing, lf)
+	}
+
+	var lists [][]sbom.Component
+	if len(existing) > 0 {
+		log.Infof("Listing the packages in %d lockfile(s)...", len(existing))
+		components, err := sbom.ScanLockfiles(existing, sources)
+		if err != nil {
+			log.Fatalf("Failed to scan lockfiles: %v", err)
+		}
+		lists = append(lists, components)
+	}
+	for _, image := range images {
+		log.Infof("Listing the packages in %s...", image)
+		components, err := sbom.ScanImage(image)
+		if err != nil {
+			log.Fatalf("Failed to scan image: %v", err)
+		}
+		lists = append(lists, components)
+	}
+	components := sbom.Merge(lists...)
+
+	version := opts.Tag
+	if version == "" {
+		out, err := exec.Command("git", "-C", root, "describe", "--tags", "--always").Output()
+		if err != nil {
+			log.Fatalf("Failed to describe the checkout: %v", err)
+		}
+		version = strings.TrimSpace(string(out))
+	}
+	doc := sbom.Document{Name: "onyx", Version: version, Tool: toolVersion, Created: time.Now()}
+
+	out := os.Stdout
+	if opts.Output != "" {
+		f, err := os.Create(opts.Output)
+		if err != nil {
+			log.Fatalf("Failed to create %s: %v", opts.Output, err)
+		}
+		out = f
+	}
+	if err := sbom.Write(out, opts.Format, doc, components); err != nil {
+		log.Fatalf("Failed to write the SBOM: %v", err)
+	}
+	if opts.Output != "" {
+		if err := out.Close(); err != nil {
+			log.Fatalf("Failed to write %s: %v", opts.Output, err)
+		}
+		log.Infof("Wrote %d packages to %s", len(components), opts.Output)
+	}
+
+	if failed := printLicenseFindings(components, failVerdicts); failed > 0 {
+		log.Errorf("%d package(s) fail the license policy at --fail-on %s", failed, opts.FailOn)
+		exitWith(1)
+	}
+}
+
+// printLicenseFindings writes the packages the license policy flags to
+// stderr, keeping stdout for the SBOM, and returns how many fail.
+func printLicenseFindings(components []sbom.Component, failVerdicts []sbom.Verdict) int {
+	byVerdict := map[sbom.Verdict][]sbom.Component{}
+	for _, c := range components {
+		v := c.Verdict()
+		byVerdict[v] = append(byVerdict[v], c)
+	}
+	_, _ = fmt.Fprintf(os.Stderr, "\nLicenses: %d allowed, %d need review, %d unknown, %d incompatible, %d not checked (OS packages)\n",
+		len(byVerdict[sbom.VerdictAllowed]), len(byVerdict[sbom.VerdictReview]), len(byVerdict[sbom.VerdictUnknown]),
+		len(byVerdict[sbom.VerdictIncompatible]), len(byVerdict[sbom.VerdictNotChecked]))
+
+	for _, v := range []sbom.Verdict{sbom.VerdictIncompatible, sbom.VerdictReview, sbom.VerdictUnknown} {
+		if len(byVerdict[v]) == 0 {
+			continue
+		}
+		_, _ = fmt.Fprintf(os.Stderr, "\n%s:\n", strings.ToUpper(string(v)))
+		for _, c := range byVerdict[v] {
+			license := sbom.LicenseExpression(c.Licenses)
+			if license == "" {
+				license = "no license found"
+			}
+			_, _ = fmt.Fprintf(os.Stderr, "  %s %s (%s) - %s, in %s\n", c.Name, c.Version, c.Ecosystem, license, strings.Join(c.Sources, ", "))
+		}
+	}
+
+	failed := 0
+	for _, v := range failVerdicts {
+		failed += len(byVerdict[v])
+	}
+	return failed
+}
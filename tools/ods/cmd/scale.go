@@ -0,0 +1,243 @@
+package cmd
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/auditlog"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/paths"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/prompt"
+)
+
+const maxScaleReplicas = 50
+
+// onyxDeployments are the deployment components the Onyx helm chart creates.
+// Actual deployment names carry the release prefix (e.g. "onyx-api-server").
+var onyxDeployments = []string{
+	"api-server",
+	"web-server",
+	"inference-model",
+	"indexing-model",
+	"mcp-server",
+	"celery-beat",
+	"celery-worker-primary",
+	"celery-worker-light",
+	"celery-worker-heavy",
+	"celery-worker-docfetching",
+	"celery-worker-docprocessing",
+	"celery-worker-monitoring",
+	"celery-worker-scheduled-tasks",
+	"celery-worker-user-file-processing",
+}
+
+// ScaleOptions holds options for the scale command.
+type ScaleOptions struct {
+	Context string
+	Restore bool
+	Yes     bool
+}
+
+// NewScaleCommand creates the scale command for changing deployment replica
+// counts.
+func NewScaleCommand() *cobra.Command {
+	opts := &ScaleOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "scale <deployment> [replicas]",
+		Short: "Scale an Onyx deployment",
+		Long: `Scale an Onyx deployment to a number of replicas.
+
+The deployment may be given by component (e.g. celery-worker-heavy) or by its
+full name; only deployments the Onyx helm chart creates are accepted. Every
+scale is recorded in the local audit log with the previous replica count, and
+--restore returns the deployment to the count it had before its most recent
+` + "`ods scale`" + `.
+
+Scaling asks for confirmation unless --yes is passed or the context is a
+non-production one (a "_" or "-" separated word of its name is dev,
+staging, test or local, and none is prod).
+
+Deployments managed by an autoscaler will drift back to the autoscaler's
+target; you are warned when that applies.
+
+Requires: AWS SSO login, kubectl access to the EKS cluster.
+
+Examples:
+  ods scale celery-worker-docprocessing 8 -c staging
+  ods scale celery-worker-docprocessing --restore -c staging
+  ods scale api-server 0 -c data_plane_eu`,
+		Args: cobra.RangeArgs(1, 2),
+		Run: func(cmd *cobra.Command, args []string) {
+			runScale(opts, args)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.Context, "context", "c", "data_plane", "cluster context name (maps to KUBE_CTX_<NAME> env var)")
+	cmd.Flags().BoolVar(&opts.Restore, "restore", false, "Return to the replica count recorded before the last scale")
+	cmd.Flags().BoolVar(&opts.Yes, "yes", false, "Skip the confirmation prompt")
+
+	return cmd
+}
+
+func runScale(opts *ScaleOptions, args []string) {
+	component, ok := onyxDeploymentComponent(args[0])
+	if !ok {
+		log.Fatalf("%q is not a known Onyx deployment; expected one of:\n  %s", args[0], strings.Join(onyxDeployments, "\n  "))
+	}
+	if opts.Restore == (len(args) == 2) {
+		log.Fatal("Pass either a replica count or --restore")
+	}
+
+	c := clusterFromEnv(opts.Context)
+	if err := c.EnsureContext(); err != nil {
+		log.Fatalf("Failed to ensure cluster context: %v", err)
+	}
+	auditCtx := c.Name + "/" + c.Namespace
+
+	deployments, err := c.ListDeployments()
+	if err != nil {
+		log.Fatalf("Failed to list deployments: %v", err)
+	}
+	names := make([]string, 0, len(deployments))
+	for _, d := range deployments {
+		names = append(names, d.Name)
+	}
+	name := args[0]
+	if !slices.Contains(names, name) {
+		if name, err = resolveDeployment(names, component); err != nil {
+			log.Fatalf("%v", err)
+		}
+	}
+
+	current, err := c.GetDeployment(name)
+	if err != nil {
+		log.Fatalf("Failed to get deployment %s: %v", name, err)
+	}
+
+	var replicas int
+	if opts.Restore {
+		entries, err := auditlog.Read(paths.AuditLogPath())
+		if err != nil {
+			log.Fatalf("Failed to read audit log: %v", err)
+		}
+		prev, ok := previousReplicas(entries, auditCtx, name)
+		if !ok {
+			log.Fatalf("No previous scale of %s in %s found in the audit log", name, auditCtx)
+		}
+		replicas = prev
+	} else {
+		replicas, err = strconv.Atoi(args[1])
+		if err != nil || replicas < 0 || replicas > maxScaleReplicas {
+			log.Fatalf("Replicas must be a number between 0 and %d, got %q", maxScaleReplicas, args[1])
+		}
+	}
+
+	if replicas == current.Replicas {
+		log.Infof("%s already has %d replicas", name, replicas)
+		return
+	}
+
+	if autoscaled, err := c.AutoscaledDeployments(); err != nil {
+		log.Debugf("Failed to list autoscalers: %v", err)
+	} else if autoscaled[name] {
+		log.Warnf("%s is managed by an autoscaler, which will override a manual replica count", name)
+	}
+
+	fmt.Printf("%s (%s): %d -> %d replicas\n", name, auditCtx, current.Replicas, replicas)
+	if !opts.Yes && isProductionContext(opts.Context) {
+		if !prompt.Confirm(fmt.Sprintf("Scale %s in %s? (yes/no): ", name, opts.Context)) {
+			log.Info("Aborted.")
+			return
+		}
+	}
+
+	if err := auditlog.Record(auditlog.Entry{
+		Action:  "scale",
+		Context: auditCtx,
+		Target:  name,
+		Detail:  fmt.Sprintf("from=%d to=%d", current.Replicas, replicas),
+	}); err != nil {
+		log.Fatalf("Refusing to scale without an audit record: %v", err)
+	}
+
+	if err := c.ScaleDeployment(name, replicas); err != nil {
+		log.Fatalf("Failed to scale %s: %v", name, err)
+	}
+	log.Infof("Scaled %s to %d replicas", name, replicas)
+}
+
+// onyxDeploymentComponent maps a deployment component or full deployment name
+// to its component, preferring the longest match.
+func onyxDeploymentComponent(arg string) (string, bool) {
+	best := ""
+	for _, comp := range onyxDeployments {
+		if (arg == comp || strings.HasSuffix(arg, "-"+comp)) && len(comp) > len(best) {
+			best = comp
+		}
+	}
+	return best, best != ""
+}
+
+// resolveDeployment finds the deployment for a component among names: an
+// exact match, or the single name ending in "-<component>".
+func resolveDeployment(names []string, component string) (string, error) {
+	var matches []string
+	for _, n := range names {
+		if n == component {
+			return n, nil
+		}
+		if strings.HasSuffix(n, "-"+component) {
+			matches = append(matches, n)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("no %s deployment found in this namespace", component)
+	case 1:
+		return matches[0], nil
+	default:
+		return "", fmt.Errorf("multiple %s deployments found (%s); pass the full name", component, strings.Join(matches, ", "))
+	}
+}
+
+// previousReplicas returns the replica count recorded before the most recent
+// scale of deployment in auditCtx.
+func previousReplicas(entries []auditlog.Entry, auditCtx, deployment string) (int, bool) {
+	for i := len(entries) - 1; i >= 0; i-- {
+		e := entries[i]
+		if e.Action != "scale" || e.Context != auditCtx || e.Target != deployment {
+			continue
+		}
+		var from, to int
+		if _, err := fmt.Sscanf(e.Detail, "from=%d to=%d", &from, &to); err != nil {
+			continue
+		}
+		return from, true
+	}
+	return 0, false
+}
+
+// isProductionContext reports whether a cluster context should be treated as
+// production. Unless a whole word of its name (split at "_" and "-") marks
+// it as a dev, staging, test or local environment, assume it is; a name
+// that also says prod is production either way.
+func isProductionContext(name string) bool {
+	words := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return r == '_' || r == '-'
+	})
+	nonProd := false
+	for _, w := range words {
+		switch w {
+		case "prod", "production":
+			return true
+		case "dev", "staging", "test", "local":
+			nonProd = true
+		}
+	}
+	return !nonProd
+}
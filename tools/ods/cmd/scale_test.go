@@ -0,0 +1,79 @@
+package cmd
+
+import (
+	"testing"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/auditlog"
+)
+
+func TestOnyxDeploymentComponent(t *testing.T) {
+	tests := []struct {
+		arg  string
+		want string
+		ok   bool
+	}{
+		{"api-server", "api-server", true},
+		{"onyx-api-server", "api-server", true},
+		{"onyx-celery-worker-heavy", "celery-worker-heavy", true},
+		{"celery-worker-user-file-processing", "celery-worker-user-file-processing", true},
+		{"postgres", "", false},
+		{"heavy", "", false},
+	}
+	for _, tt := range tests {
+		got, ok := onyxDeploymentComponent(tt.arg)
+		if got != tt.want || ok != tt.ok {
+			t.Errorf("onyxDeploymentComponent(%q) = %q, %v; want %q, %v", tt.arg, got, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestResolveDeployment(t *testing.T) {
+	names := []string{"onyx-api-server", "onyx-celery-worker-heavy", "a-web-server", "b-web-server"}
+
+	if got, err := resolveDeployment(names, "api-server"); err != nil || got != "onyx-api-server" {
+		t.Errorf("resolveDeployment(api-server) = %q, %v", got, err)
+	}
+	if _, err := resolveDeployment(names, "celery-beat"); err == nil {
+		t.Error("expected an error for a missing deployment")
+	}
+	if _, err := resolveDeployment(names, "web-server"); err == nil {
+		t.Error("expected an error for an ambiguous deployment")
+	}
+}
+
+func TestPreviousReplicas(t *testing.T) {
+	entries := []auditlog.Entry{
+		{Action: "scale", Context: "dp/onyx", Target: "onyx-api-server", Detail: "from=2 to=4"},
+		{Action: "scale", Context: "dp/onyx", Target: "onyx-api-server", Detail: "from=4 to=6"},
+		{Action: "scale", Context: "other/onyx", Target: "onyx-api-server", Detail: "from=1 to=9"},
+		{Action: "secrets.rotate", Context: "dp/onyx", Target: "onyx-api-server"},
+	}
+
+	if got, ok := previousReplicas(entries, "dp/onyx", "onyx-api-server"); !ok || got != 4 {
+		t.Errorf("previousReplicas() = %d, %v; want 4, true", got, ok)
+	}
+	if _, ok := previousReplicas(entries, "dp/onyx", "onyx-web-server"); ok {
+		t.Error("expected no previous scale for an unscaled deployment")
+	}
+}
+
+func TestIsProductionContext(t *testing.T) {
+	for name, want := range map[string]bool{
+		"data_plane":    true,
+		"prod_eu":       true,
+		"staging":       false,
+		"dev_cluster":   false,
+		"LOCAL":         false,
+		"control_plane": true,
+		"prod_latest":   true,
+		"devops_prod":   true,
+		"testing-eu":    true,
+		"prod_test":     true,
+		"eu-staging":    false,
+		"dev-us":        false,
+	} {
+		if got := isProductionContext(name); got != want {
+			t.Errorf("isProductionContext(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
@@ -0,0 +1,156 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/audit"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/docker"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/paths"
+)
+
+// ScanImagesOptions holds options for the scan images command.
+type ScanImagesOptions struct {
+	Scanner   string
+	Images    []string
+	Format    string
+	FailOn    string
+	IgnoreURL string
+}
+
+// NewScanCommand creates the scan command.
+func NewScanCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "scan",
+		Short: "Scan release artifacts with external scanners",
+	}
+
+	cmd.AddCommand(newScanImagesCommand())
+
+	return cmd
+}
+
+func newScanImagesCommand() *cobra.Command {
+	opts := &ScanImagesOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "images [tag]",
+		Short: "Scan the Onyx images with trivy or grype",
+		Long: `Scan the Onyx images with trivy or grype before promoting a release.
+
+The images are the onyxdotapp/ images the services of
+deployment/docker_compose/docker-compose.yml run, with IMAGE_TAG set to the
+given tag (or taken from the environment, as docker compose would), unless
+--image names them explicitly. An advisory found in several images (most OS
+findings, as they share a base layer) is reported once, listing every image.
+
+Accepted advisories are suppressed via the same S3 allowlist 'ods audit' uses.
+The report is JSON by default; see 'ods audit --help' for combining formats.
+Exits non-zero when an unignored finding at or above --fail-on remains.
+
+Examples:
+  # Gate promoting v2.10.4
+  ods scan images v2.10.4 > scan.json
+
+  # Scan a locally built backend image with grype
+  ods scan images --image onyxdotapp/onyx-backend:dev --scanner grype --format text`,
+		Args: cobra.MaximumNArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			tag := ""
+			if len(args) == 1 {
+				tag = args[0]
+			}
+			runScanImages(tag, opts)
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.Scanner, "scanner", "", "Scanner to run: trivy or grype (default: whichever is installed)")
+	cmd.Flags().StringArrayVar(&opts.Images, "image", nil, "Scan this image instead of the compose file's (repeatable)")
+	cmd.Flags().StringVar(&opts.Format, "format", "json", "Output format(s), comma-separated: text, json, sarif (e.g. json,text)")
+	cmd.Flags().StringVar(&opts.FailOn, "fail-on", "critical", "Minimum severity that fails the scan: critical, high, moderate, or low")
+	cmd.Flags().StringVar(&opts.IgnoreURL, "ignore-url", audit.DefaultIgnoreURL, "S3 URL of the advisory allowlist")
+
+	return cmd
+}
+
+func runScanImages(tag string, opts *ScanImagesOptions) {
+	failOn := audit.ParseSeverity(opts.FailOn)
+	if failOn == audit.SeverityUnknown {
+		log.Fatalf("Invalid --fail-on %q (want critical, high, moderate, or low)", opts.FailOn)
+	}
+	scanner := opts.Scanner
+	switch scanner {
+	case "":
+		var err error
+		if scanner, err = audit.DetectScanner(); err != nil {
+			log.Fatalf("%v", err)
+		}
+	case audit.ScannerTrivy, audit.ScannerGrype:
+	default:
+		log.Fatalf("Invalid --scanner %q (want trivy or grype)", scanner)
+	}
+
+	images := opts.Images
+	if len(images) == 0 {
+		var err error
+		if images, err = onyxComposeImages(tag); err != nil {
+			log.Fatalf("Failed to list the compose images: %v", err)
+		}
+		if len(images) == 0 {
+			log.Fatalf("No onyxdotapp/ images found in the compose file")
+		}
+	} else if tag != "" {
+		log.Fatalf("Pass either a tag or --image, not both")
+	}
+
+	result, err := audit.RunImages(audit.ImagesOptions{
+		Images:    images,
+		Scanner:   scanner,
+		Format:    opts.Format,
+		FailOn:    failOn,
+		IgnoreURL: opts.IgnoreURL,
+		Stdout:    os.Stdout,
+		Stderr:    os.Stderr,
+	})
+	if err != nil {
+		log.Fatalf("Scan failed: %v", err)
+	}
+
+	if len(result.Blocking) > 0 {
+		log.Errorf("%d finding(s) at or above %s severity must be resolved or suppressed", len(result.Blocking), failOn)
+		exitWith(1)
+	}
+}
+
+// onyxComposeImages returns the onyxdotapp/ images of the default compose
+// file, at tag when given.
+func onyxComposeImages(tag string) ([]string, error) {
+	root, err := paths.GitRoot()
+	if err != nil {
+		return nil, err
+	}
+	env := map[string]string{}
+	for _, kv := range os.Environ() {
+		if k, v, ok := strings.Cut(kv, "="); ok {
+			env[k] = v
+		}
+	}
+	if tag != "" {
+		env["IMAGE_TAG"] = tag
+	}
+	all, err := docker.ComposeImages([]string{filepath.Join(root, "deployment", "docker_compose", "docker-compose.yml")}, env)
+	if err != nil {
+		return nil, err
+	}
+	var images []string
+	for _, image := range all {
+		if strings.HasPrefix(image, docker.OnyxImagePrefix) {
+			images = append(images, image)
+		}
+	}
+	return images, nil
+}
@@ -0,0 +1,149 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/schemadiff"
+)
+
+// SchemaDiffOptions holds options for the schema diff command.
+type SchemaDiffOptions struct {
+	Tables []string
+}
+
+// schemaSide is one side of a schema diff.
+type schemaSide struct {
+	label    string
+	snapshot schemadiff.Snapshot
+	revision string
+}
+
+// NewSchemaCommand creates the parent schema command.
+func NewSchemaCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "schema",
+		Short: "Inspect live database schemas",
+	}
+
+	cmd.AddCommand(newSchemaDiffCommand())
+
+	return cmd
+}
+
+func newSchemaDiffCommand() *cobra.Command {
+	opts := &SchemaDiffOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "diff <a> <b>",
+		Short: "Diff the live schema of two environments",
+		Long: `Diff the tables, columns and indexes of two live database schemas and report
+what is missing or different on either side.
+
+Each side is a cluster context name, "local" for the docker compose database,
+or "head" for the local database's public schema as the canonical migration
+head (run "ods db upgrade" first). Append ":<schema>" to compare a tenant
+schema instead of public.
+
+The Alembic revision of each side is printed first; differences between
+schemas at different revisions are expected. Exits non-zero when the schemas
+differ.
+
+Requires: AWS SSO login, kubectl access to the EKS cluster (for cluster sides).
+
+Examples:
+  ods schema diff data_plane staging
+  ods schema diff data_plane:tenant_abcd1234 head
+  ods schema diff local data_plane --table document --table chat_message`,
+		Args: cobra.ExactArgs(2),
+		Run: func(cmd *cobra.Command, args []string) {
+			runSchemaDiff(args[0], args[1], opts)
+		},
+	}
+
+	cmd.Flags().StringArrayVar(&opts.Tables, "table", nil, "Only compare this table (repeatable)")
+
+	return cmd
+}
+
+func runSchemaDiff(specA, specB string, opts *SchemaDiffOptions) {
+	a := loadSchemaSide(specA)
+	b := loadSchemaSide(specB)
+	for _, s := range []*schemaSide{a, b} {
+		rev := s.revision
+		if rev == "" {
+			rev = "(no alembic_version)"
+		}
+		fmt.Printf("%s: %s\n", s.label, rev)
+	}
+	fmt.Println()
+
+	changes := schemadiff.Diff(a.snapshot, b.snapshot)
+	if len(opts.Tables) > 0 {
+		var filtered []schemadiff.Change
+		for _, c := range changes {
+			for _, t := range opts.Tables {
+				if c.Table == t {
+					filtered = append(filtered, c)
+				}
+			}
+		}
+		changes = filtered
+	}
+	if len(changes) == 0 {
+		fmt.Println("No differences.")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintf(w, "KIND\tOBJECT\tSTATUS\t%s\t%s\n", strings.ToUpper(a.label), strings.ToUpper(b.label))
+	for _, c := range changes {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", c.Kind, c.Object, c.Status(a.label, b.label), c.A, c.B)
+	}
+	_ = w.Flush()
+	fmt.Println()
+	log.Fatalf("%d difference(s) between %s and %s", len(changes), a.label, b.label)
+}
+
+// loadSchemaSide snapshots the schema named by spec: "local", "head" or a
+// cluster context, optionally followed by ":<schema>".
+func loadSchemaSide(spec string) *schemaSide {
+	env, schema, _ := strings.Cut(spec, ":")
+	if schema == "" {
+		schema = "public"
+	}
+	if !schemadiff.ValidSchema(schema) {
+		log.Fatalf("Invalid schema name %q", schema)
+	}
+
+	pgOpts := &PGOptions{Context: env}
+	if env == "local" || env == "head" {
+		pgOpts.Local = true
+	}
+	if env == "head" && schema != "public" {
+		log.Fatalf("%q: the migration head is always the local public schema", spec)
+	}
+	t := openPGTarget(pgOpts)
+
+	log.Infof("Reading schema %s from %s...", schema, t.label)
+	snapshot, err := schemadiff.Parse(schema, t.query(schemadiff.SnapshotSQL(schema)))
+	if err != nil {
+		log.Fatalf("Failed to read schema %s from %s: %v", schema, t.label, err)
+	}
+	if len(snapshot.Objects) == 0 {
+		log.Fatalf("Schema %s does not exist or is empty in %s", schema, t.label)
+	}
+
+	s := &schemaSide{label: spec, snapshot: snapshot}
+	if snapshot.HasTable("alembic_version") {
+		if rows := t.query(schemadiff.RevisionSQL(schema)); len(rows) > 0 {
+			s.revision = strings.TrimSpace(rows[0])
+		}
+	}
+	return s
+}
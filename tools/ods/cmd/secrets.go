@@ -0,0 +1,352 @@
+package cmd
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+	"math/big"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/auditlog"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/kube"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/prompt"
+)
+
+const generatedSecretAlphabet = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// SecretsOptions holds options shared by the secrets subcommands.
+type SecretsOptions struct {
+	Context string
+	Reveal  bool
+}
+
+// SecretsRotateOptions holds options for the secrets rotate command.
+type SecretsRotateOptions struct {
+	Set      []string
+	Generate []string
+	Length   int
+	Yes      bool
+}
+
+// NewSecretsCommand creates the parent secrets command.
+func NewSecretsCommand() *cobra.Command {
+	opts := &SecretsOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "secrets",
+		Short: "Inspect and rotate Kubernetes secrets",
+		Long: `Inspect and rotate Kubernetes secrets in the configured namespace.
+
+Requires: AWS SSO login, kubectl access to the EKS cluster.
+
+Values are redacted unless --reveal is passed. Every reveal and rotation is
+appended to the local audit log.
+
+Cluster connection is configured via KUBE_CTX_* environment variables (see
+` + "`ods whois --help`" + `). Use -c to select which context (default: data_plane).
+
+Examples:
+  ods secrets list
+  ods secrets get onyx-postgres
+  ods secrets get onyx-postgres --reveal
+  ods secrets rotate onyx-postgres --generate POSTGRES_PASSWORD
+  ods secrets rotate oauth --set OAUTH_CLIENT_SECRET
+  ods secrets rotate oauth --set OAUTH_CLIENT_SECRET=@client-secret.txt`,
+	}
+
+	cmd.PersistentFlags().StringVarP(&opts.Context, "context", "c", "data_plane", "cluster context name (maps to KUBE_CTX_<NAME> env var)")
+	cmd.PersistentFlags().BoolVar(&opts.Reveal, "reveal", false, "Print secret values in plaintext")
+
+	cmd.AddCommand(newSecretsListCommand(opts))
+	cmd.AddCommand(newSecretsGetCommand(opts))
+	cmd.AddCommand(newSecretsRotateCommand(opts))
+
+	return cmd
+}
+
+func newSecretsListCommand(opts *SecretsOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List secrets and their keys",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			runSecretsList(opts)
+		},
+	}
+}
+
+func newSecretsGetCommand(opts *SecretsOptions) *cobra.Command {
+	return &cobra.Command{
+		Use:   "get <name>",
+		Short: "Show the keys (and optionally values) of a secret",
+		Args:  cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			runSecretsGet(opts, args[0])
+		},
+	}
+}
+
+func newSecretsRotateCommand(opts *SecretsOptions) *cobra.Command {
+	rotateOpts := &SecretsRotateOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "rotate <name>",
+		Short: "Update keys in a secret after showing a diff",
+		Long: `Update keys in a secret after showing a diff of the change.
+
+Pass --set to set an explicit value, or --generate KEY to generate a random
+alphanumeric value. Both flags may be repeated. Keys that are not named are
+left untouched. --set takes the value from:
+
+  KEY          a prompt that does not echo it
+  KEY=@path    the file at path (a trailing newline is dropped)
+  KEY=-        stdin (for one key)
+  KEY=VALUE    the argument itself, which leaves the value in shell history
+               and in ods's process arguments; ods warns when it is used
+
+ Generated values are printed once after the secret is
+updated, so they can be stored wherever else they are needed; later,
+` + "`ods secrets get <name> --reveal`" + ` shows them.
+
+Pods only pick up new secret values on restart, so follow a rotation with a
+rollout restart of the deployments that consume the secret.`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			runSecretsRotate(opts, rotateOpts, args[0])
+		},
+	}
+
+	cmd.Flags().StringArrayVar(&rotateOpts.Set, "set", nil, "KEY, KEY=@file, KEY=- or KEY=VALUE to write into the secret (repeatable)")
+	cmd.Flags().StringArrayVar(&rotateOpts.Generate, "generate", nil, "KEY to fill with a random value (repeatable)")
+	cmd.Flags().IntVar(&rotateOpts.Length, "length", 32, "Length of generated values")
+	cmd.Flags().BoolVar(&rotateOpts.Yes, "yes", false, "Skip the confirmation prompt")
+
+	return cmd
+}
+
+func secretsCluster(opts *SecretsOptions) *kube.Cluster {
+	c := clusterFromEnv(opts.Context)
+	if err := c.EnsureContext(); err != nil {
+		log.Fatalf("Failed to ensure cluster context: %v", err)
+	}
+	return c
+}
+
+func displaySecretValue(v string, reveal bool) string {
+	if reveal {
+		return v
+	}
+	return kube.RedactValue(v)
+}
+
+func runSecretsList(opts *SecretsOptions) {
+	c := secretsCluster(opts)
+
+	secrets, err := c.ListSecrets()
+	if err != nil {
+		log.Fatalf("Failed to list secrets: %v", err)
+	}
+	if len(secrets) == 0 {
+		fmt.Printf("No secrets found in namespace %s.\n", c.Namespace)
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "NAME\tTYPE\tKEYS")
+	_, _ = fmt.Fprintln(w, "----\t----\t----")
+	for _, s := range secrets {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", s.Name, s.Type, strings.Join(s.Keys(), ","))
+	}
+	_ = w.Flush()
+}
+
+func runSecretsGet(opts *SecretsOptions, name string) {
+	c := secretsCluster(opts)
+
+	secret, err := c.GetSecret(name)
+	if err != nil {
+		log.Fatalf("Failed to get secret %s: %v", name, err)
+	}
+
+	if opts.Reveal {
+		if err := auditlog.Record(auditlog.Entry{
+			Action:  "secrets.reveal",
+			Context: c.Name + "/" + c.Namespace,
+			Target:  name,
+		}); err != nil {
+			log.Fatalf("Refusing to reveal without an audit record: %v", err)
+		}
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "KEY\tVALUE")
+	_, _ = fmt.Fprintln(w, "---\t-----")
+	for _, k := range secret.Keys() {
+		_, _ = fmt.Fprintf(w, "%s\t%s\n", k, displaySecretValue(secret.Data[k], opts.Reveal))
+	}
+	_ = w.Flush()
+}
+
+func runSecretsRotate(opts *SecretsOptions, rotateOpts *SecretsRotateOptions, name string) {
+	if len(rotateOpts.Set) == 0 && len(rotateOpts.Generate) == 0 {
+		log.Fatal("Nothing to rotate: pass --set KEY and/or --generate KEY")
+	}
+	if rotateOpts.Length < 16 {
+		log.Fatalf("--length must be at least 16, got %d", rotateOpts.Length)
+	}
+
+	updates, err := secretSetValues(rotateOpts.Set, os.Stdin, prompt.Secret)
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, key := range rotateOpts.Generate {
+		if _, dup := updates[key]; dup {
+			log.Fatalf("Key %s passed to both --set and --generate", key)
+		}
+		value, err := generateSecretValue(rotateOpts.Length)
+		if err != nil {
+			log.Fatalf("Failed to generate value for %s: %v", key, err)
+		}
+		updates[key] = value
+	}
+
+	c := secretsCluster(opts)
+	current, err := c.GetSecret(name)
+	if err != nil {
+		log.Fatalf("Failed to get secret %s: %v", name, err)
+	}
+
+	proposed := make(map[string]string, len(current.Data)+len(updates))
+	for k, v := range current.Data {
+		proposed[k] = v
+	}
+	for k, v := range updates {
+		proposed[k] = v
+	}
+
+	changes := kube.DiffSecretData(current.Data, proposed)
+	if len(changes) == 0 {
+		log.Info("No changes: the secret already has these values.")
+		return
+	}
+
+	fmt.Printf("Changes to secret %s in %s/%s:\n", name, c.Name, c.Namespace)
+	for _, ch := range changes {
+		switch ch.Op {
+		case "add":
+			fmt.Printf("  + %s: %s\n", ch.Key, displaySecretValue(ch.New, opts.Reveal))
+		case "change":
+			fmt.Printf("  ~ %s: %s -> %s\n", ch.Key, displaySecretValue(ch.Old, opts.Reveal), displaySecretValue(ch.New, opts.Reveal))
+		}
+	}
+
+	if !rotateOpts.Yes {
+		if !prompt.Confirm("Apply these changes? (yes/no): ") {
+			log.Info("Aborted.")
+			return
+		}
+	}
+
+	keys := make([]string, 0, len(changes))
+	for _, ch := range changes {
+		keys = append(keys, ch.Key)
+	}
+	if err := auditlog.Record(auditlog.Entry{
+		Action:  "secrets.rotate",
+		Context: c.Name + "/" + c.Namespace,
+		Target:  name,
+		Detail:  "keys: " + strings.Join(keys, ","),
+	}); err != nil {
+		log.Fatalf("Refusing to rotate without an audit record: %v", err)
+	}
+
+	if err := c.PatchSecret(name, updates); err != nil {
+		log.Fatalf("Failed to update secret %s: %v", name, err)
+	}
+
+	log.Infof("Secret %s updated (%s)", name, strings.Join(keys, ", "))
+	if len(rotateOpts.Generate) > 0 && !opts.Reveal {
+		fmt.Println("Generated values (shown once):")
+		for _, key := range rotateOpts.Generate {
+			fmt.Printf("  %s: %s\n", key, updates[key])
+		}
+	}
+	log.Info("Restart the deployments that consume this secret for the new values to take effect.")
+}
+
+// secretSetValues resolves --set arguments to secret values, reading
+// KEY=@path from the file, KEY=- from stdin and a bare KEY from ask. Literal
+// KEY=VALUE arguments are accepted with a warning, since the value is visible
+// to anyone who can list processes.
+func secretSetValues(sets []string, stdin io.Reader, ask func(prompt string) (string, error)) (map[string]string, error) {
+	updates := make(map[string]string)
+	readStdin := false
+	for _, kv := range sets {
+		key, spec, hasValue := strings.Cut(kv, "=")
+		if key == "" {
+			return nil, fmt.Errorf("invalid --set %q: expected KEY, KEY=@file, KEY=- or KEY=VALUE", kv)
+		}
+		if _, dup := updates[key]; dup {
+			return nil, fmt.Errorf("key %s passed to --set more than once", key)
+		}
+		var value string
+		switch {
+		case !hasValue:
+			v, err := ask(fmt.Sprintf("Value for %s: ", key))
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s (pass %s=@file or %s=- instead): %w", key, key, key, err)
+			}
+			value = v
+		case spec == "-":
+			if readStdin {
+				return nil, fmt.Errorf("only one --set can read stdin, got a second for %s", key)
+			}
+			readStdin = true
+			data, err := io.ReadAll(stdin)
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s from stdin: %w", key, err)
+			}
+			value = trimNewline(string(data))
+		case strings.HasPrefix(spec, "@"):
+			data, err := os.ReadFile(spec[1:])
+			if err != nil {
+				return nil, fmt.Errorf("failed to read %s: %w", key, err)
+			}
+			value = trimNewline(string(data))
+		default:
+			log.Warnf("The value of %s was passed on the command line, where shell history and process listings can see it; prefer --set %s to be prompted, or %s=@file", key, key, key)
+			updates[key] = spec
+			continue
+		}
+		if value == "" {
+			return nil, fmt.Errorf("empty value for %s", key)
+		}
+		updates[key] = value
+	}
+	return updates, nil
+}
+
+// trimNewline drops the one trailing newline that files and echo add.
+func trimNewline(s string) string {
+	s = strings.TrimSuffix(s, "\n")
+	return strings.TrimSuffix(s, "\r")
+}
+
+// generateSecretValue returns a cryptographically random alphanumeric string.
+func generateSecretValue(length int) (string, error) {
+	limit := big.NewInt(int64(len(generatedSecretAlphabet)))
+	var b strings.Builder
+	for i := 0; i < length; i++ {
+		n, err := rand.Int(rand.Reader, limit)
+		if err != nil {
+			return "", err
+		}
+		b.WriteByte(generatedSecretAlphabet[n.Int64()])
+	}
+	return b.String(), nil
+}
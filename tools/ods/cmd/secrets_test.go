@@ -0,0 +1,62 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSecretSetValues(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "client-secret.txt")
+	if err := os.WriteFile(file, []byte("from-file\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	var prompted []string
+	ask := func(p string) (string, error) {
+		prompted = append(prompted, p)
+		return "typed", nil
+	}
+
+	got, err := secretSetValues(
+		[]string{"PROMPTED", "FILE=@" + file, "STDIN=-", "LITERAL=plain=text"},
+		strings.NewReader("from-stdin\r\n"), ask)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]string{"PROMPTED": "typed", "FILE": "from-file", "STDIN": "from-stdin", "LITERAL": "plain=text"}
+	for k, v := range want {
+		if got[k] != v {
+			t.Errorf("%s = %q, want %q", k, got[k], v)
+		}
+	}
+	if len(prompted) != 1 || prompted[0] != "Value for PROMPTED: " {
+		t.Errorf("prompts = %q", prompted)
+	}
+}
+
+func TestSecretSetValuesErrors(t *testing.T) {
+	noTTY := func(string) (string, error) { return "", errors.New("stdin is not a terminal") }
+	tests := []struct {
+		name  string
+		sets  []string
+		stdin string
+		want  string
+	}{
+		{"missing key", []string{"=value"}, "", "invalid --set"},
+		{"duplicate", []string{"A=1", "A=2"}, "", "more than once"},
+		{"two stdin", []string{"A=-", "B=-"}, "secret", "only one --set can read stdin"},
+		{"missing file", []string{"A=@/nonexistent/secret"}, "", "failed to read A"},
+		{"no terminal", []string{"A"}, "", "pass A=@file or A=- instead"},
+		{"empty stdin", []string{"A=-"}, "", "empty value for A"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := secretSetValues(tt.sets, strings.NewReader(tt.stdin), noTTY)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("error = %v, want it to mention %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,254 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/signal"
+	"slices"
+	"strings"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/health"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/kube"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/redact"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/report"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/serve"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/whois"
+)
+
+// ServeOptions holds options for the serve command.
+type ServeOptions struct {
+	Listen      string
+	Context     string
+	TokenFile   string
+	AllowRemote bool
+}
+
+// NewServeCommand creates the serve command.
+func NewServeCommand() *cobra.Command {
+	opts := &ServeOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "serve",
+		Short: "Serve whois, health, stats and log search over a local HTTP API",
+		Long: `Serve whois, health, stats and log search over a local HTTP API until
+interrupted, so internal dashboards and chatops bots can call them without
+shelling out to ods.
+
+Endpoints (JSON unless noted):
+  GET /healthz                                  liveness, unauthenticated
+  GET /v1/whois?q=<email-fragment|tenant-id>    users matching an email, or a tenant's admins
+  GET /v1/health[?timeout=5s]                   dependency probes from an api-server pod
+  GET /v1/stats/<report>[?since=7d&format=md]   a report (weekly-ops, tenant-growth,
+                                                connector-health) as JSON, md or html
+  GET /v1/logs/search?pod=api-server&q=<text>[&since=1h&limit=200]
+                                                matching log lines; pod is repeatable
+
+Every /v1 request needs "Authorization: Bearer <token>". The token is read
+from ODS_SERVE_TOKEN or --token-file; if neither exists, a random token is
+generated into --token-file (readable only by you) and its path is logged.
+
+The API answers with your cluster credentials, so it only listens on loopback
+addresses unless --allow-remote is given.
+
+Requires: AWS SSO login, kubectl access to the EKS cluster.
+
+Examples:
+  ods serve
+  ods serve --listen 127.0.0.1:8080 -c prod_eu
+  curl -H "Authorization: Bearer $(cat ~/.local/share/onyx-dev/serve-token)" \
+    "http://127.0.0.1:7777/v1/whois?q=chris"`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			runServe(opts)
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.Listen, "listen", "127.0.0.1:7777", "Address to listen on")
+	cmd.Flags().StringVarP(&opts.Context, "context", "c", "data_plane", "cluster context name (maps to KUBE_CTX_<NAME> env var)")
+	cmd.Flags().StringVar(&opts.TokenFile, "token-file", "", "File holding the API token, created if missing (default: serve-token in the ods data directory)")
+	cmd.Flags().BoolVar(&opts.AllowRemote, "allow-remote", false, "Allow listening on a non-loopback address")
+
+	return cmd
+}
+
+func runServe(opts *ServeOptions) {
+	if !opts.AllowRemote {
+		if err := requireLoopback(opts.Listen); err != nil {
+			log.Fatal(err)
+		}
+	}
+
+	token := os.Getenv("ODS_SERVE_TOKEN")
+	if token == "" {
+		if opts.TokenFile == "" {
+			opts.TokenFile = serve.DefaultTokenPath()
+		}
+		var created bool
+		var err error
+		if token, created, err = serve.LoadOrCreateToken(opts.TokenFile); err != nil {
+			log.Fatalf("Failed to load the API token: %v", err)
+		}
+		if created {
+			log.Infof("Generated an API token in %s", opts.TokenFile)
+		}
+	}
+
+	c := clusterFromEnv(opts.Context)
+	if err := c.EnsureContext(); err != nil {
+		log.Fatalf("Failed to ensure cluster context: %v", err)
+	}
+
+	api, err := serve.New(token, &clusterBackend{c: c, context: opts.Context})
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	server := &http.Server{
+		Addr:              opts.Listen,
+		Handler:           logRequests(api),
+		ReadHeaderTimeout: 30 * time.Second,
+	}
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- server.ListenAndServe() }()
+
+	log.Infof("ods API for %s listening on http://%s", opts.Context, opts.Listen)
+
+	select {
+	case <-ctx.Done():
+	case err := <-serveErr:
+		if err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatalf("ods API failed: %v", err)
+		}
+	}
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	_ = server.Shutdown(shutdownCtx)
+}
+
+// requireLoopback returns an error unless addr only listens on loopback.
+func requireLoopback(addr string) error {
+	host, _, err := net.SplitHostPort(addr)
+	if err != nil {
+		return fmt.Errorf("invalid --listen %q: %v", addr, err)
+	}
+	if host == "localhost" {
+		return nil
+	}
+	if ip := net.ParseIP(host); ip != nil && ip.IsLoopback() {
+		return nil
+	}
+	return fmt.Errorf("--listen %s is not a loopback address; pass --allow-remote to expose the API beyond this machine", addr)
+}
+
+// clusterBackend answers API requests against a cluster, finding a ready
+// api-server pod for each request so it survives rollouts. Every request
+// first checks that the context's production session is still active, so a
+// long-running server stops answering once it lapses.
+type clusterBackend struct {
+	c       *kube.Cluster
+	context string
+}
+
+// checkSession returns a ForbiddenError once the production session for
+// the backend's context has ended.
+func (b *clusterBackend) checkSession() error {
+	if err := checkProdSession(b.context); err != nil {
+		return &serve.ForbiddenError{Message: err.Error()}
+	}
+	return nil
+}
+
+func (b *clusterBackend) apiServerPod() (string, error) {
+	if err := b.checkSession(); err != nil {
+		return "", err
+	}
+	pod, err := b.c.FindPod("api-server")
+	if err != nil {
+		return "", fmt.Errorf("failed to find api-server pod: %w", err)
+	}
+	return pod, nil
+}
+
+func (b *clusterBackend) Whois(query string) (*whois.Result, error) {
+	pod, err := b.apiServerPod()
+	if err != nil {
+		return nil, err
+	}
+	return whois.Lookup(func(sql string) ([]string, error) { return tryQueryPod(b.c, pod, sql) }, query)
+}
+
+func (b *clusterBackend) Health(timeout time.Duration) ([]health.Result, error) {
+	pod, err := b.apiServerPod()
+	if err != nil {
+		return nil, err
+	}
+	return health.ProbeCluster(b.c, pod, timeout)
+}
+
+func (b *clusterBackend) Stats(kind report.Kind, p report.Period) (*report.Report, error) {
+	if err := b.checkSession(); err != nil {
+		return nil, err
+	}
+	return buildReport(b.c, b.context, kind, p, time.Now())
+}
+
+func (b *clusterBackend) SearchLogs(substrings []string, text string, since time.Duration, limit int) ([]kube.LogMatch, error) {
+	if err := b.checkSession(); err != nil {
+		return nil, err
+	}
+	pods, err := b.c.ListPods()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list pods: %w", err)
+	}
+	matched := kube.MatchPods(pods, substrings)
+	if len(matched) == 0 {
+		return nil, &serve.NotFoundError{Message: fmt.Sprintf("no pods in %s/%s match %s", b.c.Name, b.c.Namespace, strings.Join(substrings, ", "))}
+	}
+	matches, err := b.c.SearchLogs(matched, text, kube.LogOptions{AllContainers: true, Since: since}, limit)
+	if err != nil {
+		return nil, err
+	}
+	// Results go to Slack and MCP clients, so they are always redacted.
+	for i := range matches {
+		matches[i].Line = redact.Line(matches[i].Line)
+	}
+	return matches, nil
+}
+
+func (b *clusterBackend) TenantStats(tenantID string, p report.Period) (*report.TenantStats, error) {
+	pod, err := b.apiServerPod()
+	if err != nil {
+		return nil, err
+	}
+	schemas, err := tenantSchemas(b.c, pod)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list tenant schemas: %w", err)
+	}
+	if !slices.Contains(schemas, tenantID) {
+		return nil, &serve.NotFoundError{Message: fmt.Sprintf("tenant %s not found in %s", tenantID, b.context)}
+	}
+	lines, err := tryQueryPod(b.c, pod, report.TenantStatsSQL([]string{tenantID}, p))
+	if err != nil {
+		return nil, err
+	}
+	stats, err := report.ParseTenantStats(lines)
+	if err != nil {
+		return nil, err
+	}
+	if len(stats) != 1 {
+		return nil, fmt.Errorf("expected one row of statistics for %s, got %d", tenantID, len(stats))
+	}
+	return &stats[0], nil
+}
@@ -0,0 +1,315 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/auditlog"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/config"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/notify"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/paths"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/prodaccess"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/tickets"
+)
+
+// SessionStartOptions holds options for the session start subcommand.
+type SessionStartOptions struct {
+	Env    string
+	TTL    time.Duration
+	Reason string
+}
+
+// NewSessionCommand creates the session command.
+func NewSessionCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "session",
+		Short: "Manage time-boxed production access sessions",
+		Long: `Manage time-boxed production access sessions.
+
+Commands against a production context (any context whose name does not mark
+it as dev, staging, test or local) refuse to run outside an active session
+for that context. Sessions are started with a reason, last at most
+sessions.max_ttl from the ods config (default 4h), and end on their own.
+Long-running commands (serve, bot, mcp, report schedule) check the session
+again before each request or scheduled run, and stop answering once it ends.
+
+Session starts and ends, and every command run against a production context,
+are recorded in the audit log and, when sessions.notify is set in the ods
+config (e.g. "slack:#security"), posted there.
+
+ods session show is the odd one out: it looks up a user's Onyx login session,
+for "I keep getting logged out" tickets.
+
+Examples:
+  ods session start --env prod --ttl 1h --reason OPS-123
+  ods session status
+  ods session end
+  ods session show 'Xk2f...' -c prod`,
+	}
+
+	cmd.AddCommand(newSessionStartCommand())
+	cmd.AddCommand(newSessionEndCommand())
+	cmd.AddCommand(newSessionStatusCommand())
+	cmd.AddCommand(newSessionShowCommand())
+
+	return cmd
+}
+
+func newSessionStartCommand() *cobra.Command {
+	opts := &SessionStartOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "start",
+		Short: "Start a production access session",
+		Long: `Start a production access session.
+
+Unlocks --env (a cluster context, mapping to KUBE_CTX_<NAME>) for --ttl.
+Starting a session for a context that already has one replaces it.
+
+Examples:
+  ods session start --env prod --ttl 1h --reason OPS-123
+  ods session start --env control_plane --ttl 30m --reason "INC-42 billing outage"`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			runSessionStart(opts)
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.Env, "env", "", "Cluster context to unlock (required)")
+	cmd.Flags().DurationVar(&opts.TTL, "ttl", time.Hour, "How long the session lasts")
+	cmd.Flags().StringVar(&opts.Reason, "reason", "", "Why access is needed, ideally a ticket like OPS-123 (required)")
+	_ = cmd.MarkFlagRequired("env")
+	_ = cmd.MarkFlagRequired("reason")
+
+	return cmd
+}
+
+func newSessionEndCommand() *cobra.Command {
+	var env string
+
+	cmd := &cobra.Command{
+		Use:   "end",
+		Short: "End production access sessions",
+		Long: `End production access sessions: every active one, or only --env's.
+
+Examples:
+  ods session end
+  ods session end --env prod`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			runSessionEnd(env)
+		},
+	}
+
+	cmd.Flags().StringVar(&env, "env", "", "Cluster context whose session to end (default: all)")
+
+	return cmd
+}
+
+func newSessionStatusCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "status",
+		Short: "Show active production access sessions",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			runSessionStatus()
+		},
+	}
+}
+
+func runSessionStart(opts *SessionStartOptions) {
+	if !isProductionContext(opts.Env) {
+		log.Fatalf("%s is not a production context; commands against it need no session", opts.Env)
+	}
+	reason := strings.TrimSpace(opts.Reason)
+	if reason == "" {
+		log.Fatalf("--reason must not be empty")
+	}
+	cfg := loadODSConfig()
+	maxTTL := prodaccess.DefaultMaxTTL
+	if cfg.Sessions.MaxTTL != "" {
+		var err error
+		if maxTTL, err = time.ParseDuration(cfg.Sessions.MaxTTL); err != nil {
+			log.Fatalf("Invalid sessions.max_ttl %q in the ods config: %v", cfg.Sessions.MaxTTL, err)
+		}
+	}
+	if err := prodaccess.ValidateTTL(opts.TTL, maxTTL); err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	now := time.Now().UTC()
+	s := prodaccess.Session{
+		Context: opts.Env,
+		Actor:   auditlog.Actor(),
+		Reason:  reason,
+		Started: now,
+		Expires: now.Add(opts.TTL),
+	}
+	if err := recordSessionEvent(s, "session.start", fmt.Sprintf("ttl=%s reason=%s", opts.TTL, reason)); err != nil {
+		log.Fatalf("Refusing to start a session without an audit record: %v", err)
+	}
+
+	path := paths.ProdSessionsPath()
+	sessions, err := prodaccess.Load(path)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	if err := prodaccess.Save(path, prodaccess.Start(sessions, s), now); err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	postSessionEvent(cfg, fmt.Sprintf(":unlock: %s started a %s production session on `%s`: %s", s.Actor, opts.TTL, s.Context, reason))
+	log.Infof("Production access to %s granted until %s", s.Context, s.Expires.Local().Format(time.Kitchen))
+}
+
+func runSessionEnd(env string) {
+	path := paths.ProdSessionsPath()
+	sessions, err := prodaccess.Load(path)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	now := time.Now().UTC()
+	kept, ended := prodaccess.End(sessions, env)
+	if err := prodaccess.Save(path, kept, now); err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	cfg := loadODSConfig()
+	count := 0
+	for _, s := range ended {
+		if !s.Active(now) {
+			continue
+		}
+		count++
+		if err := recordSessionEvent(s, "session.end", fmt.Sprintf("remaining=%s", s.Remaining(now))); err != nil {
+			log.Warnf("Failed to record the end of the %s session: %v", s.Context, err)
+		}
+		postSessionEvent(cfg, fmt.Sprintf(":lock: %s ended the production session on `%s` (%s)", s.Actor, s.Context, s.Reason))
+		log.Infof("Ended the production session on %s", s.Context)
+	}
+	if count == 0 {
+		log.Info("No active production sessions")
+	}
+}
+
+func runSessionStatus() {
+	sessions, err := prodaccess.Load(paths.ProdSessionsPath())
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	now := time.Now()
+	var active []prodaccess.Session
+	for _, s := range sessions {
+		if s.Active(now) {
+			active = append(active, s)
+		}
+	}
+	if len(active) == 0 {
+		log.Info("No active production sessions")
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "CONTEXT\tEXPIRES\tREMAINING\tREASON")
+	_, _ = fmt.Fprintln(w, "-------\t-------\t---------\t------")
+	for _, s := range active {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", s.Context, s.Expires.Local().Format(time.Kitchen), s.Remaining(now), s.Reason)
+	}
+	_ = w.Flush()
+}
+
+// sessionCommandsLogged holds the production contexts this run has already
+// recorded its command for.
+var sessionCommandsLogged = map[string]bool{}
+
+// requireProdSession exits unless an active session covers the production
+// context name, then records the running command against it.
+func requireProdSession(name string) {
+	if sessionCommandsLogged[name] {
+		return
+	}
+	sessions, err := prodaccess.Load(paths.ProdSessionsPath())
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	s, err := prodaccess.Check(sessions, name, time.Now())
+	if errors.Is(err, prodaccess.ErrNoSession) {
+		log.Fatalf("%s is a production context and there is no active session for it.\n\nStart one with:\n  ods session start --env %s --ttl 1h --reason <ticket>", name, name)
+	}
+	if err != nil {
+		log.Fatalf("%v.\n\nStart a new one with:\n  ods session start --env %s --ttl 1h --reason <ticket>", err, name)
+	}
+
+	command := "ods " + strings.Join(os.Args[1:], " ")
+	if err := recordSessionEvent(*s, "session.command", command); err != nil {
+		log.Fatalf("Refusing to run against %s without an audit record: %v", name, err)
+	}
+	sessionCommandsLogged[name] = true
+	postSessionEvent(loadODSConfig(), fmt.Sprintf(":computer: %s ran `%s` on `%s` (%s)", s.Actor, command, name, s.Reason))
+	log.Debugf("Running under the %s session (%s left)", name, s.Remaining(time.Now()))
+}
+
+// checkProdSession returns an error once no active session covers the
+// production context name any more. requireProdSession checks once per
+// process, so long-running commands call this before each request or job.
+func checkProdSession(name string) error {
+	if !isProductionContext(name) {
+		return nil
+	}
+	sessions, err := prodaccess.Load(paths.ProdSessionsPath())
+	if err != nil {
+		return err
+	}
+	_, err = prodaccess.Check(sessions, name, time.Now())
+	if errors.Is(err, prodaccess.ErrNoSession) {
+		return fmt.Errorf("the production access session for %s has ended", name)
+	}
+	return err
+}
+
+// recordSessionEvent writes an audit entry for s, attaching its reason as
+// the ticket when it is one and no --ticket was given.
+func recordSessionEvent(s prodaccess.Session, action, detail string) error {
+	e := auditlog.Entry{Action: action, Context: s.Context, Detail: detail}
+	if tickets.ValidKey(s.Reason) {
+		e.Ticket = s.Reason
+	}
+	return auditlog.Record(e)
+}
+
+func loadODSConfig() *config.Config {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Warnf("Failed to load ods config: %v", err)
+		return &config.Config{}
+	}
+	return cfg
+}
+
+// postSessionEvent posts text to sessions.notify, if configured. Failures are
+// warnings: the audit log remains the record.
+func postSessionEvent(cfg *config.Config, text string) {
+	if cfg.Sessions.Notify == "" {
+		return
+	}
+	target, err := notify.ParseTarget(cfg.Sessions.Notify)
+	if err != nil {
+		log.Warnf("Invalid sessions.notify: %v", err)
+		return
+	}
+	url, err := notify.WebhookURL(target, cfg.Notify)
+	if err != nil {
+		log.Warnf("Not posting to %s: %v", target, err)
+		return
+	}
+	if err := notify.SendText(url, target, text); err != nil {
+		log.Warnf("Failed to post to %s: %v", target, err)
+	}
+}
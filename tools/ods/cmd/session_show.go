@@ -0,0 +1,153 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/apiclient"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/authsession"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/render"
+)
+
+// SessionShowOptions holds options for the session show subcommand.
+type SessionShowOptions struct {
+	Context string
+	Tenant  string
+	JSON    bool
+}
+
+func newSessionShowCommand() *cobra.Command {
+	opts := &SessionShowOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "show <session-id>",
+		Short: "Look up an Onyx login session",
+		Long: `Look up an Onyx login session, for "I keep getting logged out" tickets.
+
+Unlike the other session commands this is about a user's login session, not
+production access. The session ID is the value of the user's fastapiusersauth
+cookie (or the bearer token of the mobile app), given literally, as @file or
+as @- for stdin.
+
+Shows the auth backend and session lifetime, whether the api-server would
+accept the session and if not why (expired, signed out, unknown), when it
+was issued and expires, and its user. With the postgres auth backend the
+user's other sessions are listed too. A JWT (the JWT auth backend) is decoded
+and verified as by ods token decode --verify.
+
+With the redis auth backend the session names its tenant; otherwise pass
+--tenant, omitting it on single-tenant deployments.
+
+Requires: AWS SSO login, kubectl access to the EKS cluster.
+
+Examples:
+  ods session show 'Xk2f...' -c prod
+  pbpaste | ods session show @- --tenant tenant_abcd1234`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			runSessionShow(opts, args[0])
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.Context, "context", "c", "data_plane", "cluster context name (maps to KUBE_CTX_<NAME> env var)")
+	cmd.Flags().StringVar(&opts.Tenant, "tenant", "", "Tenant schema (omit on single-tenant deployments)")
+	cmd.Flags().BoolVar(&opts.JSON, "json", false, "Print the session as JSON")
+
+	return cmd
+}
+
+func runSessionShow(opts *SessionShowOptions, spec string) {
+	if opts.Tenant != "" {
+		validateTenantArg(opts.Tenant)
+	}
+	data, err := apiclient.ReadBody(spec, os.Stdin)
+	if err != nil {
+		log.Fatalf("Failed to read the session ID: %v", err)
+	}
+	id := strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(string(data)), "fastapiusersauth="))
+	if strings.Count(id, ".") == 2 {
+		log.Info("The session ID is a JWT; decoding it")
+		runTokenDecode(&TokenDecodeOptions{Context: opts.Context, Verify: true, JSON: opts.JSON}, id)
+		return
+	}
+
+	c := clusterFromEnv(opts.Context)
+	if err := c.EnsureContext(); err != nil {
+		log.Fatalf("Failed to ensure cluster context: %v", err)
+	}
+	log.Info("Finding api-server pod...")
+	pod, err := c.FindPod("api-server")
+	if err != nil {
+		log.Fatalf("Failed to find api-server pod: %v", err)
+	}
+
+	s, err := authsession.Show(c, pod, opts.Tenant, id)
+	if err != nil {
+		log.Fatalf("Failed to look up the session: %v", err)
+	}
+	if opts.JSON {
+		if err := render.JSON(os.Stdout, s); err != nil {
+			log.Fatalf("Failed to marshal session: %v", err)
+		}
+		return
+	}
+	printLoginSession(s)
+}
+
+func printLoginSession(s *authsession.Session) {
+	now := time.Now()
+	fmt.Printf("Auth backend:  %s, sessions last %s idle\n", s.Backend, time.Duration(s.LifetimeSeconds)*time.Second)
+	fmt.Printf("State:         %s\n", s.Explain(now))
+	if s.IssuedAt != "" {
+		fmt.Printf("Issued:        %s\n", s.IssuedAt)
+	}
+	if s.ExpiresAt != "" {
+		fmt.Printf("Expires:       %s\n", s.ExpiresAt)
+	}
+	if s.LoggedOutAt != "" {
+		fmt.Printf("Signed out:    %s\n", s.LoggedOutAt)
+	}
+	if s.Backend == "redis" && s.TTLSeconds > 0 {
+		fmt.Printf("Redis TTL:     %s\n", time.Duration(s.TTLSeconds)*time.Second)
+	}
+	if s.TenantID != "" {
+		fmt.Printf("Tenant:        %s\n", s.TenantID)
+	}
+	if u := s.User; u != nil {
+		fmt.Printf("User:          %s (%s, role %s, active %t, verified %t)\n", u.Email, u.ID, u.Role, u.IsActive, u.IsVerified)
+	} else if s.UserID != "" {
+		fmt.Printf("User:          %s\n", s.UserID)
+	}
+
+	if len(s.Sessions) > 0 {
+		fmt.Println("\nThe user's sessions, newest first:")
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "ID\tISSUED\tEXPIRES\t")
+		_, _ = fmt.Fprintln(w, "--\t------\t-------\t")
+		for _, r := range s.Sessions {
+			mark := ""
+			if r.Current {
+				mark = "(this one)"
+			}
+			_, _ = fmt.Fprintf(w, "%s…\t%s\t%s\t%s\n", r.ID, r.IssuedAt, r.ExpiresAt, mark)
+		}
+		_ = w.Flush()
+	}
+
+	fmt.Println()
+	findings := s.Findings()
+	if len(findings) == 0 {
+		fmt.Println("Nothing stands out.")
+		return
+	}
+	fmt.Println("Findings:")
+	for _, f := range findings {
+		fmt.Printf("  - %s\n", f)
+	}
+}
@@ -0,0 +1,38 @@
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/paths"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/prodaccess"
+)
+
+func TestCheckProdSession(t *testing.T) {
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+	t.Setenv("LOCALAPPDATA", t.TempDir())
+
+	if err := checkProdSession("staging"); err != nil {
+		t.Errorf("checkProdSession(staging) = %v; a non-production context needs no session", err)
+	}
+	if err := checkProdSession("prod"); err == nil {
+		t.Error("expected an error without a session")
+	}
+
+	now := time.Now()
+	sessions := []prodaccess.Session{{Context: "prod", Reason: "OPS-1", Started: now, Expires: now.Add(time.Hour)}}
+	if err := prodaccess.Save(paths.ProdSessionsPath(), sessions, now); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkProdSession("prod"); err != nil {
+		t.Errorf("checkProdSession(prod) = %v during an active session", err)
+	}
+
+	// Ending the session must stop a process that checked it earlier.
+	if err := prodaccess.Save(paths.ProdSessionsPath(), nil, now); err != nil {
+		t.Fatal(err)
+	}
+	if err := checkProdSession("prod"); err == nil {
+		t.Error("expected an error once the session has ended")
+	}
+}
@@ -0,0 +1,335 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/apiclient"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/render"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/sso"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/token"
+)
+
+// SSODebugOptions holds options for the sso-debug command.
+type SSODebugOptions struct {
+	Context      string
+	Tenant       string
+	Provider     string
+	SAMLResponse string
+	IDToken      string
+	JSON         bool
+}
+
+// NewSSODebugCommand creates the sso-debug command.
+func NewSSODebugCommand() *cobra.Command {
+	opts := &SSODebugOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "sso-debug",
+		Short: "Check a tenant's SSO providers and decode what their IdP sends",
+		Long: `Check a tenant's SSO providers (Google, OIDC, SAML) and point out why logins fail.
+
+For each provider the configuration is fetched from the api-server, with
+secrets reduced to whether they are set, and its IdP is probed from the
+api-server's network:
+
+  OIDC/Google  the discovery document and JWKS are fetched, and a
+               client_credentials request is sent to the token endpoint.
+               IdPs authenticate the client before looking at the grant, so
+               the answer tells whether the client ID and secret are still
+               accepted without needing a user to log in.
+  SAML         the IdP SSO URL is requested and the IdP and SP certificates
+               are checked for expiry.
+
+Pass what the IdP sent during a failed login to compare it with the
+configuration: --saml-response takes the SAMLResponse form value from the
+browser's network tab (base64, URL-encoded or the XML itself), --id-token an
+ID token. Both accept @file or @- for stdin. Issuer, audience, ACS URL,
+signing certificate, email attribute, email_verified and allowed domains are
+checked. Nothing pasted leaves this machine, and signatures are not verified.
+
+The command exits non-zero when anything is found. On a single-tenant
+deployment omit --tenant.
+
+Requires: AWS SSO login, kubectl access to the EKS cluster.
+
+Examples:
+  ods sso-debug --tenant tenant_abcd1234
+  ods sso-debug --tenant tenant_abcd1234 --provider okta --id-token @token.txt
+  pbpaste | ods sso-debug --tenant tenant_abcd1234 --saml-response @-`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			runSSODebug(opts)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.Context, "context", "c", "data_plane", "cluster context name (maps to KUBE_CTX_<NAME> env var)")
+	cmd.Flags().StringVar(&opts.Tenant, "tenant", "", "Tenant schema (omit on single-tenant deployments)")
+	cmd.Flags().StringVar(&opts.Provider, "provider", "", "Only check the provider with this name")
+	cmd.Flags().StringVar(&opts.SAMLResponse, "saml-response", "", "SAML response to decode and check: literal, @file or @- for stdin")
+	cmd.Flags().StringVar(&opts.IDToken, "id-token", "", "ID token to decode and check: literal, @file or @- for stdin")
+	cmd.Flags().BoolVar(&opts.JSON, "json", false, "Print the providers as JSON")
+	cmd.MarkFlagsMutuallyExclusive("saml-response", "id-token")
+
+	return cmd
+}
+
+func runSSODebug(opts *SSODebugOptions) {
+	if opts.Tenant != "" {
+		validateTenantArg(opts.Tenant)
+	}
+
+	// Decode what was pasted before touching the cluster, so a bad copy
+	// fails fast.
+	var samlResp *sso.SAMLResponse
+	var idToken *token.JWT
+	if opts.SAMLResponse != "" {
+		data, err := apiclient.ReadBody(opts.SAMLResponse, os.Stdin)
+		if err != nil {
+			log.Fatalf("Failed to read the SAML response: %v", err)
+		}
+		if samlResp, err = sso.DecodeSAMLResponse(string(data)); err != nil {
+			log.Fatalf("Failed to decode the SAML response: %v", err)
+		}
+	}
+	if opts.IDToken != "" {
+		data, err := apiclient.ReadBody(opts.IDToken, os.Stdin)
+		if err != nil {
+			log.Fatalf("Failed to read the ID token: %v", err)
+		}
+		if idToken, err = token.Decode(string(data)); err != nil {
+			log.Fatalf("Failed to decode the ID token: %v", err)
+		}
+	}
+
+	c := clusterFromEnv(opts.Context)
+	if err := c.EnsureContext(); err != nil {
+		log.Fatalf("Failed to ensure cluster context: %v", err)
+	}
+	log.Info("Finding api-server pod...")
+	pod, err := c.FindPod("api-server")
+	if err != nil {
+		log.Fatalf("Failed to find api-server pod: %v", err)
+	}
+
+	log.Info("Fetching and probing SSO providers...")
+	r, err := sso.Inspect(c, pod, opts.Tenant, opts.Provider)
+	if err != nil {
+		log.Fatalf("Failed to inspect SSO providers: %v", err)
+	}
+	if len(r.Providers) == 0 {
+		log.Fatalf("No SSO providers are configured; logins use email and password")
+	}
+	if opts.JSON {
+		if err := render.JSON(os.Stdout, r); err != nil {
+			log.Fatalf("Failed to marshal providers: %v", err)
+		}
+		return
+	}
+
+	now := time.Now()
+	found := 0
+	for i := range r.Providers {
+		p := &r.Providers[i]
+		printSSOProvider(p)
+		found += printFindings(p.Findings(now))
+	}
+
+	if samlResp != nil {
+		p := matchSAMLProvider(r.Providers, samlResp)
+		printSAMLResponse(samlResp)
+		if p == nil {
+			fmt.Printf("\nNo SAML provider has the IdP entity ID %q, so Onyx cannot tell which provider the response is for\n", samlResp.Issuer)
+			found++
+		} else {
+			fmt.Printf("\nAgainst provider %s:\n", p.Name)
+			found += printFindings(samlResp.Check(p))
+		}
+	}
+	if idToken != nil {
+		printIDToken(idToken)
+		p := matchOIDCProvider(r.Providers, idToken)
+		if p == nil {
+			fmt.Println("\nNo OIDC or Google provider to check the token against; name one with --provider")
+			found++
+		} else {
+			fmt.Printf("\nAgainst provider %s:\n", p.Name)
+			found += printFindings(sso.CheckIDToken(idToken, p, now))
+		}
+	}
+
+	if found > 0 {
+		exitWith(1)
+	}
+}
+
+func printSSOProvider(p *sso.Provider) {
+	state := "enabled"
+	if !p.Enabled {
+		state = "disabled"
+	}
+	fmt.Printf("\n%s (%s, %s)\n", p.Name, p.Type, state)
+	if p.DisplayName != "" && p.DisplayName != p.Name {
+		fmt.Printf("  Display name:     %s\n", p.DisplayName)
+	}
+	fmt.Printf("  Callback URL:     %s\n", p.CallbackURL)
+	if len(p.AllowedEmailDomains) > 0 {
+		fmt.Printf("  Allowed domains:  %s\n", strings.Join(p.AllowedEmailDomains, ", "))
+	}
+	if p.Updated != "" {
+		fmt.Printf("  Updated:          %s\n", p.Updated)
+	}
+
+	if p.Type == sso.TypeSAML {
+		fmt.Printf("  IdP entity ID:    %s\n", p.IdPEntityID)
+		fmt.Printf("  IdP SSO URL:      %s%s\n", p.IdPSSOURL, urlProbeSuffix(p.IdPSSOProbe))
+		fmt.Printf("  SP entity ID:     %s\n", p.SPEntityID)
+		if p.EmailAttribute != "" {
+			fmt.Printf("  Email attribute:  %s\n", p.EmailAttribute)
+		}
+		printSSOCert("IdP certificate", p.IdPCert)
+		printSSOCert("SP certificate", p.SPCert)
+		return
+	}
+
+	secret := "set"
+	if !p.HasClientSecret {
+		secret = "not set"
+	}
+	fmt.Printf("  Client ID:        %s (secret %s)\n", p.ClientID, secret)
+	o := p.OIDC
+	if o == nil {
+		return
+	}
+	fmt.Printf("  Discovery URL:    %s\n", o.DiscoveryURL)
+	if o.DiscoveryError != "" {
+		return
+	}
+	fmt.Printf("  Issuer:           %s\n", o.Issuer)
+	fmt.Printf("  Token endpoint:   %s\n", o.TokenEndpoint)
+	fmt.Printf("  JWKS:             %s (%d key(s))\n", o.JWKSURI, o.JWKSKeys)
+	if v := sso.TokenVerdict(o); strings.HasPrefix(v, "ok") {
+		fmt.Printf("  Client check:     %s\n", strings.TrimPrefix(v, "ok: "))
+	}
+}
+
+func urlProbeSuffix(probe *sso.URLProbe) string {
+	if probe == nil || probe.Error != "" {
+		return ""
+	}
+	return fmt.Sprintf(" (HTTP %d)", probe.Status)
+}
+
+func printSSOCert(label, raw string) {
+	if raw == "" {
+		return
+	}
+	cert, err := sso.ParseCertificate(raw)
+	if err != nil {
+		return
+	}
+	fmt.Printf("  %-17s %s, expires %s\n", label+":", cert.Subject.String(), cert.NotAfter.Format(time.DateOnly))
+	fmt.Printf("  %-17s SHA-256 %s\n", "", sso.Fingerprint(cert))
+}
+
+// printFindings prints findings under the last heading and returns how many
+// there were.
+func printFindings(findings []string) int {
+	if len(findings) == 0 {
+		fmt.Println("  Nothing stands out.")
+		return 0
+	}
+	fmt.Println("  Findings:")
+	for _, f := range findings {
+		fmt.Printf("    - %s\n", f)
+	}
+	return len(findings)
+}
+
+// matchSAMLProvider finds the provider a SAML response is for the way the
+// callback does, by issuer, falling back to the only SAML provider.
+func matchSAMLProvider(providers []sso.Provider, r *sso.SAMLResponse) *sso.Provider {
+	var saml []*sso.Provider
+	for i := range providers {
+		p := &providers[i]
+		if p.Type != sso.TypeSAML {
+			continue
+		}
+		if p.IdPEntityID == r.Issuer {
+			return p
+		}
+		saml = append(saml, p)
+	}
+	if len(saml) == 1 {
+		return saml[0]
+	}
+	return nil
+}
+
+// matchOIDCProvider finds the provider an ID token was issued to by client
+// ID, falling back to the only OIDC or Google provider.
+func matchOIDCProvider(providers []sso.Provider, t *token.JWT) *sso.Provider {
+	var oidc []*sso.Provider
+	for i := range providers {
+		p := &providers[i]
+		if p.Type == sso.TypeSAML {
+			continue
+		}
+		for _, aud := range t.Audiences() {
+			if aud == p.ClientID {
+				return p
+			}
+		}
+		oidc = append(oidc, p)
+	}
+	if len(oidc) == 1 {
+		return oidc[0]
+	}
+	return nil
+}
+
+func printSAMLResponse(r *sso.SAMLResponse) {
+	fmt.Println("\nSAML response")
+	fmt.Printf("  Issuer:           %s\n", r.Issuer)
+	fmt.Printf("  Destination:      %s\n", r.Destination)
+	fmt.Printf("  Issued:           %s\n", r.IssueInstant)
+	fmt.Printf("  Status:           %s\n", r.Status)
+	if cert, err := sso.ParseCertificate(r.SigningCert); err == nil {
+		fmt.Printf("  Signed with:      %s, expires %s\n", cert.Subject.String(), cert.NotAfter.Format(time.DateOnly))
+		fmt.Printf("  %-17s SHA-256 %s\n", "", sso.Fingerprint(cert))
+	}
+	if r.Encrypted {
+		fmt.Println("  Assertion:        encrypted")
+		return
+	}
+	a := r.Assertion
+	if a == nil {
+		return
+	}
+	fmt.Printf("  NameID:           %s (%s)\n", a.NameID, a.NameIDFormat)
+	fmt.Printf("  Audience:         %s\n", strings.Join(a.Audiences, ", "))
+	fmt.Printf("  Recipient:        %s\n", a.Recipient)
+	fmt.Printf("  Valid:            %s to %s\n", a.NotBefore, a.NotOnOrAfter)
+	if len(a.AttributeNames) > 0 {
+		fmt.Println("  Attributes:")
+		for _, name := range a.AttributeNames {
+			fmt.Printf("    %s = %s\n", name, strings.Join(a.Attributes[name], ", "))
+		}
+	}
+}
+
+func printIDToken(t *token.JWT) {
+	fmt.Println("\nID token")
+	fmt.Printf("  Algorithm:        %s", t.Algorithm())
+	if kid := t.KeyID(); kid != "" {
+		fmt.Printf(" (key %s)", kid)
+	}
+	fmt.Println()
+	fmt.Println("  Claims:")
+	printTokenClaims(t, "    ")
+}
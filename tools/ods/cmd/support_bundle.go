@@ -0,0 +1,383 @@
+package cmd
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/alembic"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/docker"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/health"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/kube"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/runner"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/supportbundle"
+)
+
+// SupportBundleOptions holds options for the support-bundle command.
+type SupportBundleOptions struct {
+	Context  string
+	Output   string
+	Since    time.Duration
+	Tail     int
+	NoRedact bool
+}
+
+// NewSupportBundleCommand creates the support-bundle command.
+func NewSupportBundleCommand() *cobra.Command {
+	opts := &SupportBundleOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "support-bundle",
+		Short: "Collect a redacted diagnostics tarball to attach to an issue",
+		Long: `Collect a deployment's diagnostics into one tarball to attach to a GitHub
+issue or support request:
+
+  versions.txt      ods, docker/helm and the images every service runs
+  config/           the effective compose configuration and .env, or the
+                    helm values of each release
+  status/           containers or pods with restarts, and warning events
+  logs/             recent logs of every service (and of the previous
+                    instance of a restarted pod)
+  migrations.txt    the database's alembic revision against head
+  health.json       the result of ods health
+
+Every file is redacted before it is written: values of keys such as
+*_PASSWORD, *_SECRET, *_API_KEY and *_TOKEN, API keys, tokens and
+passwords in connection URLs are replaced with [REDACTED], and email
+addresses, card numbers and SSNs with placeholders. Logs can still hold
+document text; look through the bundle before attaching it publicly.
+--no-redact keeps everything, for sharing privately; for a cluster it
+needs an active session.
+
+Steps that fail (e.g. health checks against a stopped stack) are listed in
+errors.txt rather than aborting the bundle.
+
+Examples:
+  # The local compose deployment
+  ods support-bundle
+
+  # A cluster, with the last 6 hours of logs
+  ods support-bundle -c prod --since 6h -o /tmp/onyx-support.tar.gz`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			runSupportBundle(opts)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.Context, "context", "c", localContext, `cluster context name (maps to KUBE_CTX_<NAME> env var), or "local" for the compose stack`)
+	cmd.Flags().StringVarP(&opts.Output, "output", "o", "", "Path of the tarball (default: onyx-support-<timestamp>.tar.gz)")
+	cmd.Flags().DurationVar(&opts.Since, "since", 24*time.Hour, "Collect logs newer than this")
+	cmd.Flags().IntVar(&opts.Tail, "tail", 5000, "Collect at most this many log lines per container (0 for all)")
+	addNoRedactFlag(cmd, &opts.NoRedact)
+
+	return cmd
+}
+
+func runSupportBundle(opts *SupportBundleOptions) {
+	if opts.Since <= 0 {
+		log.Fatalf("--since must be positive")
+	}
+	if opts.Tail < 0 {
+		log.Fatalf("--tail must not be negative")
+	}
+	if opts.NoRedact {
+		allowUnredacted(opts.Context)
+	}
+	name := "onyx-support-" + time.Now().Format("20060102-150405")
+	if opts.Output == "" {
+		opts.Output = name + ".tar.gz"
+	}
+
+	f, err := os.OpenFile(opts.Output, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0o600)
+	if err != nil {
+		log.Fatalf("Failed to create %s: %v", opts.Output, err)
+	}
+	b := supportbundle.New(f, name)
+	b.Raw = opts.NoRedact
+
+	var summary string
+	if opts.Context == localContext {
+		summary = collectComposeBundle(b, opts)
+	} else {
+		summary = collectClusterBundle(b, opts)
+	}
+
+	if err := b.Close(summary); err != nil {
+		log.Fatalf("Failed to write %s: %v", opts.Output, err)
+	}
+	if err := f.Close(); err != nil {
+		log.Fatalf("Failed to write %s: %v", opts.Output, err)
+	}
+	for _, e := range b.Errors() {
+		log.Warnf("Not collected: %s", e)
+	}
+	log.Infof("Wrote %s; review it before sharing", opts.Output)
+	fmt.Println(opts.Output)
+}
+
+// bundleStep adds the output of collect to the bundle as name, recording a
+// failure instead if it fails.
+func bundleStep(b *supportbundle.Bundle, name string, collect func() ([]byte, error)) {
+	log.Infof("Collecting %s", name)
+	data, err := collect()
+	if err != nil {
+		b.Fail(name, err)
+		if len(data) == 0 {
+			return
+		}
+	}
+	if err := b.Add(name, data); err != nil {
+		log.Fatalf("Failed to write %s to the bundle: %v", name, err)
+	}
+}
+
+// commandOutput runs a command with r and returns its stdout, with stderr in
+// the error if it fails. What it printed before failing is returned too.
+func commandOutput(r runner.Runner, name string, args ...string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	err := runner.Or(r).Run(runner.Cmd{Name: name, Args: args, Stdout: &stdout, Stderr: &stderr})
+	if err != nil {
+		return stdout.Bytes(), fmt.Errorf("%s %s failed: %w\n%s", name, args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+// odsVersion is the first line of versions.txt.
+func odsVersion() string {
+	return fmt.Sprintf("ods %s (commit %s) %s/%s\n", Version, Commit, runtime.GOOS, runtime.GOARCH)
+}
+
+func collectComposeBundle(b *supportbundle.Bundle, opts *SupportBundleOptions) string {
+	project := docker.ProjectName()
+	apiServer := fmt.Sprintf("%s-api_server-1", project)
+	compose := func(args ...string) ([]byte, error) {
+		return commandOutput(docker.Runner, "docker", append([]string{"compose", "-p", project}, args...)...)
+	}
+
+	bundleStep(b, "versions.txt", func() ([]byte, error) {
+		var out strings.Builder
+		out.WriteString(odsVersion())
+		for _, args := range [][]string{
+			{"version", "--format", "docker {{.Server.Version}} ({{.Server.Os}}/{{.Server.Arch}})"},
+			{"compose", "version"},
+		} {
+			v, err := commandOutput(docker.Runner, "docker", args...)
+			if err != nil {
+				return []byte(out.String()), err
+			}
+			out.Write(v)
+		}
+		images, err := compose("images")
+		out.WriteString("\n")
+		out.Write(images)
+		return []byte(out.String()), err
+	})
+
+	// The project's labels say which compose files and directory it was
+	// started from, whichever deployment method was used.
+	labels := func(key string) string {
+		out, err := commandOutput(docker.Runner, "docker", "inspect", "--format", fmt.Sprintf(`{{index .Config.Labels %q}}`, key), apiServer)
+		if err != nil {
+			return ""
+		}
+		return strings.TrimSpace(string(out))
+	}
+	bundleStep(b, "config/compose.yaml", func() ([]byte, error) {
+		files := labels("com.docker.compose.project.config_files")
+		if files == "" {
+			return nil, fmt.Errorf("%s is not running, so its compose files are unknown", apiServer)
+		}
+		args := []string{}
+		for _, f := range strings.Split(files, ",") {
+			args = append(args, "-f", f)
+		}
+		return compose(append(args, "config")...)
+	})
+	bundleStep(b, "config/env", func() ([]byte, error) {
+		dir := labels("com.docker.compose.project.working_dir")
+		if dir == "" {
+			return nil, fmt.Errorf("%s is not running, so its .env is unknown", apiServer)
+		}
+		return os.ReadFile(filepath.Join(dir, ".env"))
+	})
+
+	bundleStep(b, "status/containers.txt", func() ([]byte, error) {
+		return compose("ps", "--all")
+	})
+
+	services, err := compose("ps", "--all", "--services")
+	if err != nil {
+		b.Fail("logs", err)
+	}
+	for _, svc := range strings.Fields(string(services)) {
+		bundleStep(b, "logs/"+svc+".log", func() ([]byte, error) {
+			args := []string{"logs", "--no-color", "--timestamps", "--since", opts.Since.String()}
+			if opts.Tail > 0 {
+				args = append(args, "--tail", fmt.Sprint(opts.Tail))
+			}
+			return composeLogs(project, append(args, svc))
+		})
+	}
+
+	bundleStep(b, "migrations.txt", func() ([]byte, error) {
+		var out bytes.Buffer
+		for _, sub := range []string{"current", "heads"} {
+			fmt.Fprintf(&out, "$ alembic %s\n", sub)
+			v, err := commandOutput(docker.Runner, "docker", "exec", apiServer, "alembic", sub)
+			if err != nil {
+				return out.Bytes(), err
+			}
+			out.Write(v)
+		}
+		return out.Bytes(), nil
+	})
+
+	bundleStep(b, "health.json", func() ([]byte, error) {
+		results, err := health.ProbeLocal(apiServer, 5*time.Second)
+		if err != nil {
+			return nil, err
+		}
+		return json.MarshalIndent(results, "", "  ")
+	})
+
+	return fmt.Sprintf("Deployment: docker compose project %q.", project)
+}
+
+// composeLogs returns a service's logs with stdout and stderr interleaved,
+// as its error output is the interesting part.
+func composeLogs(project string, args []string) ([]byte, error) {
+	out, err := runner.CombinedOutput(docker.Runner, runner.Cmd{Name: "docker", Args: append([]string{"compose", "-p", project}, args...)})
+	if err != nil {
+		return out, fmt.Errorf("docker compose logs failed: %w", err)
+	}
+	return out, nil
+}
+
+func collectClusterBundle(b *supportbundle.Bundle, opts *SupportBundleOptions) string {
+	c := clusterFromEnv(opts.Context)
+	if err := c.EnsureContext(); err != nil {
+		log.Fatalf("Failed to ensure cluster context: %v", err)
+	}
+
+	bundleStep(b, "versions.txt", func() ([]byte, error) {
+		var out bytes.Buffer
+		out.WriteString(odsVersion())
+		if v, err := commandOutput(c.Runner, "helm", "version", "--short"); err == nil {
+			out.WriteString("helm ")
+			out.Write(v)
+		}
+		deployments, err := c.ListDeployments()
+		if err != nil {
+			return out.Bytes(), err
+		}
+		out.WriteString("\n")
+		w := tabwriter.NewWriter(&out, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "DEPLOYMENT\tCONTAINER\tIMAGE")
+		for _, d := range deployments {
+			for _, ctr := range d.Containers {
+				_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", d.Name, ctr.Name, ctr.Image)
+			}
+		}
+		_ = w.Flush()
+		return out.Bytes(), nil
+	})
+
+	releases, err := runner.Output(c.Runner, c.Helm("list", "--short"))
+	if err != nil {
+		b.Fail("config", fmt.Errorf("helm list failed: %w", err))
+	}
+	for _, release := range strings.Fields(string(releases)) {
+		bundleStep(b, "config/"+release+"-values.yaml", func() ([]byte, error) {
+			out, err := runner.Output(c.Runner, c.Helm("get", "values", release, "--output", "yaml"))
+			if err != nil {
+				return nil, fmt.Errorf("helm get values %s failed: %w", release, err)
+			}
+			return out, nil
+		})
+	}
+
+	pods, podsErr := c.ListPods()
+	bundleStep(b, "status/pods.txt", func() ([]byte, error) {
+		if podsErr != nil {
+			return nil, podsErr
+		}
+		var out bytes.Buffer
+		w := tabwriter.NewWriter(&out, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "POD\tPHASE\tREADY\tRESTARTS\tAGE\tPROBLEM")
+		for _, p := range pods {
+			restarts := 0
+			for _, cs := range p.Containers {
+				restarts += cs.RestartCount
+			}
+			_, _ = fmt.Fprintf(w, "%s\t%s\t%v\t%d\t%s\t%s\n", p.Name, p.Phase, p.Ready, restarts,
+				time.Since(p.Created).Round(time.Minute), p.FailureReason())
+		}
+		_ = w.Flush()
+		return out.Bytes(), nil
+	})
+	bundleStep(b, "status/events.txt", func() ([]byte, error) {
+		events, err := c.ListWarningEvents()
+		if err != nil {
+			return nil, err
+		}
+		var out bytes.Buffer
+		for _, e := range events {
+			fmt.Fprintf(&out, "%s  %s/%s  %s (x%d)  %s\n", e.LastSeen.UTC().Format(time.RFC3339), e.Kind, e.Object, e.Reason, e.Count, e.Message)
+		}
+		return out.Bytes(), nil
+	})
+
+	for _, p := range pods {
+		logOpts := kube.LogOptions{AllContainers: true, Since: opts.Since, Tail: opts.Tail, Timestamps: true}
+		bundleStep(b, "logs/"+p.Name+".log", func() ([]byte, error) {
+			var out bytes.Buffer
+			err := c.Logs(p.Name, logOpts, &out)
+			return out.Bytes(), err
+		})
+		restarted := false
+		for _, cs := range p.Containers {
+			restarted = restarted || cs.RestartCount > 0
+		}
+		if restarted {
+			logOpts.Previous, logOpts.Since = true, 0
+			bundleStep(b, "logs/"+p.Name+".previous.log", func() ([]byte, error) {
+				var out bytes.Buffer
+				err := c.Logs(p.Name, logOpts, &out)
+				return out.Bytes(), err
+			})
+		}
+	}
+
+	apiPod, apiErr := c.FindPod("api-server")
+	bundleStep(b, "migrations.txt", func() ([]byte, error) {
+		if apiErr != nil {
+			return nil, apiErr
+		}
+		status, err := alembic.RemoteStatus(c, apiPod, false, "")
+		if err != nil {
+			return nil, err
+		}
+		return fmt.Appendf(nil, "head %s\nschemas %d, behind head %d\n", status.Head, status.Total, len(status.Lagging)), nil
+	})
+	bundleStep(b, "health.json", func() ([]byte, error) {
+		if apiErr != nil {
+			return nil, apiErr
+		}
+		results, err := health.ProbeCluster(c, apiPod, 5*time.Second)
+		if err != nil {
+			return nil, err
+		}
+		return json.MarshalIndent(results, "", "  ")
+	})
+
+	return fmt.Sprintf("Deployment: Kubernetes namespace %s.", c.Namespace)
+}
@@ -0,0 +1,37 @@
+package cmd
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/runner"
+)
+
+func TestCommandOutput(t *testing.T) {
+	f := (&runner.Fake{}).
+		On("helm version --short", runner.Response{Stdout: "v3.16.2+g13654a5\n"}).
+		On("docker exec onyx-api_server-1 alembic current", runner.Response{Stdout: "partial\n", Stderr: "boom\n", Err: errors.New("exit status 1")})
+
+	if out, err := commandOutput(f, "helm", "version", "--short"); err != nil || string(out) != "v3.16.2+g13654a5\n" {
+		t.Errorf("commandOutput() = %q, %v", out, err)
+	}
+	out, err := commandOutput(f, "docker", "exec", "onyx-api_server-1", "alembic", "current")
+	if err == nil || !strings.Contains(err.Error(), "docker exec failed") || !strings.Contains(err.Error(), "boom") {
+		t.Errorf("expected the command and its stderr in the error, got %v", err)
+	}
+	if string(out) != "partial\n" {
+		t.Errorf("expected the output before the failure to be kept, got %q", out)
+	}
+}
+
+func TestComposeLogs(t *testing.T) {
+	f := useDockerFake(t).On("docker compose -p onyx logs --no-color api_server", runner.Response{Stdout: "started\n", Stderr: "Traceback\n"})
+	out, err := composeLogs("onyx", []string{"logs", "--no-color", "api_server"})
+	if err != nil || !strings.Contains(string(out), "started") || !strings.Contains(string(out), "Traceback") {
+		t.Errorf("composeLogs() = %q, %v; want stdout and stderr", out, err)
+	}
+	if len(f.Lines()) != 1 {
+		t.Errorf("commands = %q", f.Lines())
+	}
+}
@@ -0,0 +1,181 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"runtime"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/config"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/telemetry"
+)
+
+// NewTelemetryCommand creates the telemetry command.
+func NewTelemetryCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "telemetry",
+		Short: "Opt in to or out of anonymous ods usage telemetry",
+		Long: `Opt in to or out of anonymous usage telemetry for ods itself, which tells
+the maintainers which commands matter and where people hit failures.
+
+Telemetry is off until you run 'ods telemetry on'. Each run then sends the
+command's name (e.g. "compose list-projects", never its arguments), how long
+it took, whether it succeeded and, if not, a coarse error category such as
+"network" or "auth" (never the message), with the ods version, OS and a
+random installation ID.
+
+DO_NOT_TRACK=1 or ODS_TELEMETRY=0 turn it off regardless. Only the released
+onyx-devtools wheels have an endpoint; binaries built from source have no
+telemetry at all.`,
+	}
+
+	cmd.AddCommand(&cobra.Command{
+		Use:   "on",
+		Short: "Send anonymous usage telemetry",
+		Args:  cobra.NoArgs,
+		Run:   func(cmd *cobra.Command, args []string) { setTelemetry(true) },
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:   "off",
+		Short: "Stop sending usage telemetry",
+		Args:  cobra.NoArgs,
+		Run:   func(cmd *cobra.Command, args []string) { setTelemetry(false) },
+	})
+	cmd.AddCommand(&cobra.Command{
+		Use:   "status",
+		Short: "Show whether usage telemetry is sent",
+		Args:  cobra.NoArgs,
+		Run:   func(cmd *cobra.Command, args []string) { runTelemetryStatus() },
+	})
+
+	return cmd
+}
+
+func setTelemetry(enabled bool) {
+	if enabled && !telemetry.Available() {
+		log.Fatalf("This build of ods has no telemetry")
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load ods config: %v", err)
+	}
+	cfg.Telemetry.Enabled = enabled
+	if enabled && cfg.Telemetry.InstallID == "" {
+		cfg.Telemetry.InstallID = telemetry.NewInstallID()
+	}
+	if !enabled {
+		// A later opt-in starts a new, unlinked installation.
+		cfg.Telemetry.InstallID = ""
+	}
+	if err := config.Save(cfg); err != nil {
+		log.Fatalf("Failed to save ods config: %v", err)
+	}
+	if enabled {
+		log.Info("Telemetry is on. Thanks! Turn it off any time with: ods telemetry off")
+		if telemetry.EnvDisabled() {
+			log.Warn("DO_NOT_TRACK or ODS_TELEMETRY=0 is set, so nothing is sent from this shell")
+		}
+	} else {
+		log.Info("Telemetry is off")
+	}
+}
+
+func runTelemetryStatus() {
+	if !telemetry.Available() {
+		fmt.Println("Telemetry: not built into this binary")
+		return
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatalf("Failed to load ods config: %v", err)
+	}
+	state := "off"
+	switch {
+	case cfg.Telemetry.Enabled && telemetry.EnvDisabled():
+		state = "on, but disabled by DO_NOT_TRACK or ODS_TELEMETRY=0"
+	case cfg.Telemetry.Enabled:
+		state = "on"
+	}
+	fmt.Printf("Telemetry:    %s\n", state)
+	if cfg.Telemetry.Enabled {
+		fmt.Printf("Endpoint:     %s\n", telemetry.Endpoint(cfg.Telemetry))
+		fmt.Printf("Install ID:   %s\n", cfg.Telemetry.InstallID)
+	}
+}
+
+// telemetryRecorder sends one event for the run when it finishes,
+// successfully or through log.Fatal. A nil recorder does nothing.
+type telemetryRecorder struct {
+	sink    telemetry.Sink
+	event   telemetry.Event
+	start   time.Time
+	failure *fatalCapture
+}
+
+// startTelemetry returns a recorder for cmd when the user has opted in.
+func startTelemetry(cmd *cobra.Command) *telemetryRecorder {
+	command := strings.TrimSpace(strings.TrimPrefix(cmd.CommandPath(), cmd.Root().Name()))
+	if command == "telemetry" || strings.HasPrefix(command, "telemetry ") {
+		return nil
+	}
+	if cmd.GroupID == aliasGroup {
+		// Alias names are the user's own; each step is recorded by the
+		// ods process that runs it.
+		command = "(alias)"
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		return nil
+	}
+	sink, err := telemetry.NewSink(cfg.Telemetry)
+	if errors.Is(err, telemetry.ErrDisabled) {
+		return nil
+	}
+	if err != nil {
+		log.Debugf("Telemetry: %v", err)
+		return nil
+	}
+
+	r := &telemetryRecorder{
+		sink: sink,
+		event: telemetry.Event{
+			InstallID: cfg.Telemetry.InstallID,
+			Command:   command,
+			Version:   Version,
+			OS:        runtime.GOOS,
+			Arch:      runtime.GOARCH,
+		},
+		start:   time.Now(),
+		failure: &fatalCapture{},
+	}
+	log.AddHook(r.failure)
+	log.RegisterExitHandler(func() {
+		r.send(false, r.failure.message)
+	})
+	return r
+}
+
+// Done records a successful run.
+func (r *telemetryRecorder) Done() {
+	if r == nil {
+		return
+	}
+	r.send(true, "")
+}
+
+func (r *telemetryRecorder) send(ok bool, failure string) {
+	e := r.event
+	e.OK = ok
+	e.DurationMS = time.Since(r.start).Milliseconds()
+	e.Time = time.Now().UTC()
+	if !ok {
+		e.Error = telemetry.Categorize(failure)
+	}
+	if err := r.sink.Send(e); err != nil {
+		log.Debugf("Failed to send telemetry: %v", err)
+	}
+}
@@ -0,0 +1,371 @@
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/alembic"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/auditlog"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/kube"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/paths"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/prompt"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/s3"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/tenant"
+)
+
+// TenantOptions holds options shared by the tenant subcommands.
+type TenantOptions struct {
+	Context string
+}
+
+// TenantCreateOptions holds options for the tenant create command.
+type TenantCreateOptions struct {
+	Email   string
+	Timeout time.Duration
+	Yes     bool
+}
+
+// TenantDeleteOptions holds options for the tenant delete command.
+type TenantDeleteOptions struct {
+	Idle        time.Duration
+	Archive     string
+	AllowActive bool
+}
+
+// NewTenantCommand creates the parent tenant command.
+func NewTenantCommand() *cobra.Command {
+	opts := &TenantOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "tenant",
+		Short: "Provision and manage tenants of a multi-tenant data plane",
+		Long: `Provision and manage tenants of a multi-tenant data plane.
+
+Requires: AWS SSO login, kubectl access to the EKS cluster.`,
+	}
+
+	cmd.PersistentFlags().StringVarP(&opts.Context, "context", "c", "data_plane", "cluster context name (maps to KUBE_CTX_<NAME> env var)")
+
+	cmd.AddCommand(newTenantCreateCommand(opts))
+	cmd.AddCommand(newTenantDeleteCommand(opts))
+	cmd.AddCommand(newTenantMigrateCommand(opts))
+
+	return cmd
+}
+
+func newTenantCreateCommand(parent *TenantOptions) *cobra.Command {
+	opts := &TenantCreateOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "create",
+		Short: "Provision a tenant with a ready-to-use admin account",
+		Long: `Provision a tenant whose first user, and admin, is --email.
+
+Runs the same signup flow as the web app on an api-server pod, so the tenant
+is provisioned (or taken from the pre-provisioned pool), migrated and seeded
+like a customer's. The admin is created verified with a random password.
+Waits until the tenant schema is at the migration head, then prints the login
+details.
+
+Asks for confirmation on production contexts unless --yes is passed, and
+records the new tenant in the local audit log.
+
+Examples:
+  ods tenant create --email qa+0415@onyx.app -c staging
+  ods tenant create --email qa+0415@onyx.app -c staging --timeout 20m`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			runTenantCreate(parent, opts)
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.Email, "email", "", "Email of the tenant's admin (required)")
+	cmd.Flags().DurationVar(&opts.Timeout, "timeout", 10*time.Minute, "How long to wait for the tenant schema to reach the migration head")
+	cmd.Flags().BoolVar(&opts.Yes, "yes", false, "Skip the confirmation prompt")
+	_ = cmd.MarkFlagRequired("email")
+
+	return cmd
+}
+
+func runTenantCreate(parent *TenantOptions, opts *TenantCreateOptions) {
+	if !tenant.ValidEmail(opts.Email) {
+		log.Fatalf("Invalid email %q", opts.Email)
+	}
+
+	c := clusterFromEnv(parent.Context)
+	if err := c.EnsureContext(); err != nil {
+		log.Fatalf("Failed to ensure cluster context: %v", err)
+	}
+	auditCtx := c.Name + "/" + c.Namespace
+
+	if !opts.Yes && isProductionContext(parent.Context) {
+		if !prompt.Confirm(fmt.Sprintf("Provision a tenant for %s in %s? (yes/no): ", opts.Email, auditCtx)) {
+			log.Info("Aborted.")
+			return
+		}
+	}
+
+	log.Info("Finding api-server pod...")
+	pod, err := c.FindPod("api-server")
+	if err != nil {
+		log.Fatalf("Failed to find api-server pod: %v", err)
+	}
+
+	if err := auditlog.Record(auditlog.Entry{
+		Action:  "tenant.create",
+		Context: auditCtx,
+		Target:  opts.Email,
+	}); err != nil {
+		log.Fatalf("Refusing to provision a tenant without an audit record: %v", err)
+	}
+
+	log.Infof("Provisioning a tenant for %s (this can take a few minutes)...", opts.Email)
+	created, err := tenant.Create(c, pod, opts.Email)
+	if err != nil {
+		log.Fatalf("Failed to provision a tenant for %s: %v", opts.Email, err)
+	}
+	log.Infof("Provisioned %s", created.TenantID)
+
+	waitForTenantMigrations(c, pod, created.TenantID, opts.Timeout)
+	printTenantCreated(os.Stdout, created)
+}
+
+func printTenantCreated(w io.Writer, created *tenant.Created) {
+	_, _ = fmt.Fprintln(w)
+	_, _ = fmt.Fprintf(w, "Tenant:   %s\n", created.TenantID)
+	_, _ = fmt.Fprintf(w, "Login:    %s\n", created.LoginURL())
+	_, _ = fmt.Fprintf(w, "Email:    %s (%s)\n", created.Email, created.Role)
+	_, _ = fmt.Fprintf(w, "Password: %s\n", created.Password)
+}
+
+// waitForTenantMigrations polls until schema is at the migration head.
+func waitForTenantMigrations(c *kube.Cluster, pod, schema string, timeout time.Duration) {
+	deadline := time.Now().Add(timeout)
+	for {
+		status, err := alembic.RemoteStatus(c, pod, false, schema)
+		if err != nil {
+			log.Fatalf("Failed to check migrations of %s: %v", schema, err)
+		}
+		rev, lagging := status.Lagging[schema]
+		if !lagging {
+			log.Infof("%s is at migration head %s", schema, status.Head)
+			return
+		}
+		if time.Now().After(deadline) {
+			log.Fatalf("%s is still at revision %q (head %s) after %s; check `ods migrate status --tenant %s`", schema, rev, status.Head, timeout, schema)
+		}
+		log.Infof("Waiting for %s to reach migration head %s (at %q)...", schema, status.Head, rev)
+		time.Sleep(10 * time.Second)
+	}
+}
+
+func newTenantDeleteCommand(parent *TenantOptions) *cobra.Command {
+	opts := &TenantDeleteOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "delete <tenant_id>",
+		Short: "Archive and delete an inactive tenant",
+		Long: `Archive and delete an inactive tenant.
+
+Refuses tenants with active users or chat activity within --idle (on
+non-production contexts, --allow-active skips this check). Shows what will be
+removed and requires typing the tenant ID back. Then it:
+
+  1. Snapshots the tenant schema with pg_dump into the local ods data
+     directory (and uploads it under --archive on S3, if given)
+  2. Deletes the tenant's rows from every public table keyed by tenant_id
+     (user_tenant_mapping etc.) and drops the schema, in one transaction
+
+Both steps are recorded in the local audit log. Restore a snapshot with
+pg_restore --schema <tenant_id>.
+
+Examples:
+  ods tenant delete tenant_abcd1234 -c staging
+  ods tenant delete tenant_abcd1234 --archive s3://onyx-tenant-archives/prod/`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			runTenantDelete(parent, opts, args[0])
+		},
+	}
+
+	cmd.Flags().DurationVar(&opts.Idle, "idle", 30*24*time.Hour, "Refuse tenants with chat activity more recent than this")
+	cmd.Flags().StringVar(&opts.Archive, "archive", "", "Also upload the schema snapshot under this S3 prefix")
+	cmd.Flags().BoolVar(&opts.AllowActive, "allow-active", false, "Delete even if the tenant looks active (non-production contexts only)")
+
+	return cmd
+}
+
+func runTenantDelete(parent *TenantOptions, opts *TenantDeleteOptions, tenantID string) {
+	validateTenantArg(tenantID)
+	if !strings.HasPrefix(tenantID, "tenant_") {
+		log.Fatalf("Refusing to delete %q: tenant IDs start with tenant_", tenantID)
+	}
+	if opts.AllowActive && isProductionContext(parent.Context) {
+		log.Fatal("--allow-active is not allowed on production contexts")
+	}
+	archiveURL := ""
+	if opts.Archive != "" {
+		archiveURL = strings.TrimSuffix(opts.Archive, "/") + "/"
+		if _, err := s3.ParseS3URL(archiveURL); err != nil {
+			log.Fatalf("Invalid --archive: %v", err)
+		}
+	}
+
+	c := clusterFromEnv(parent.Context)
+	if err := c.EnsureContext(); err != nil {
+		log.Fatalf("Failed to ensure cluster context: %v", err)
+	}
+	auditCtx := c.Name + "/" + c.Namespace
+
+	log.Info("Finding api-server pod...")
+	pod, err := c.FindPod("api-server")
+	if err != nil {
+		log.Fatalf("Failed to find api-server pod: %v", err)
+	}
+
+	usage, err := tenant.ParseUsage(queryPod(c, pod, tenant.UsageSQL(tenantID)))
+	if err != nil {
+		log.Fatalf("Failed to inspect %s: %v", tenantID, err)
+	}
+	if usage.HasChats {
+		if usage.LastActivity, err = tenant.ParseLastActivity(queryPod(c, pod, tenant.LastActivitySQL(tenantID))); err != nil {
+			log.Fatalf("Failed to inspect %s: %v", tenantID, err)
+		}
+	}
+	tables := queryPod(c, pod, tenant.PublicTablesSQL)
+	if len(tables) > 0 {
+		if usage.PublicRows, err = tenant.ParsePublicRows(queryPod(c, pod, tenant.PublicRowsSQL(tenantID, tables))); err != nil {
+			log.Fatalf("Failed to inspect %s: %v", tenantID, err)
+		}
+	}
+
+	leftovers := 0
+	for _, n := range usage.PublicRows {
+		leftovers += n
+	}
+	if !usage.SchemaExists && leftovers == 0 {
+		log.Fatalf("Tenant %s not found in %s", tenantID, auditCtx)
+	}
+
+	if inactive, reason := usage.Inactive(opts.Idle, time.Now()); !inactive {
+		if !opts.AllowActive {
+			log.Fatalf("Refusing to delete %s: it has %s", tenantID, reason)
+		}
+		log.Warnf("%s has %s; deleting anyway because of --allow-active", tenantID, reason)
+	}
+
+	fmt.Printf("Tenant %s in %s:\n", tenantID, auditCtx)
+	if usage.SchemaExists {
+		fmt.Printf("  schema %s (will be archived, then dropped)\n", tenantID)
+	} else {
+		fmt.Println("  no schema (only public rows remain)")
+	}
+	fmt.Printf("  %d user(s), %d active\n", usage.Users, usage.ActiveUsers)
+	if !usage.LastActivity.IsZero() {
+		fmt.Printf("  last chat activity %s\n", usage.LastActivity.Format(time.RFC3339))
+	}
+	names := make([]string, 0, len(usage.PublicRows))
+	for t, n := range usage.PublicRows {
+		if n > 0 {
+			names = append(names, t)
+		}
+	}
+	sort.Strings(names)
+	for _, t := range names {
+		fmt.Printf("  %d row(s) in public.%s\n", usage.PublicRows[t], t)
+	}
+	fmt.Println()
+
+	if typed := prompt.String(fmt.Sprintf("Type %s to delete it permanently: ", tenantID)); typed != tenantID {
+		log.Info("Tenant ID did not match. Aborted.")
+		return
+	}
+
+	detail := "no schema"
+	if usage.SchemaExists {
+		detail = archiveTenantSchema(c, pod, tenantID, archiveURL, auditCtx)
+	}
+
+	if err := auditlog.Record(auditlog.Entry{
+		Action:  "tenant.delete",
+		Context: auditCtx,
+		Target:  tenantID,
+		Detail:  detail,
+	}); err != nil {
+		log.Fatalf("Refusing to delete a tenant without an audit record: %v", err)
+	}
+
+	log.Infof("Deleting %s...", tenantID)
+	queryPod(c, pod, tenant.DeleteSQL(tenantID, tables))
+	log.Infof("Deleted %s (archive: %s)", tenantID, detail)
+}
+
+// archiveTenantSchema dumps schema on pod to the local archive directory and,
+// with archiveURL, S3, and returns where the archive went.
+func archiveTenantSchema(c *kube.Cluster, pod, schema, archiveURL, auditCtx string) string {
+	name := tenant.ArchiveName(schema, time.Now())
+	if err := os.MkdirAll(paths.TenantArchivesDir(), 0700); err != nil {
+		log.Fatalf("Failed to create archive directory: %v", err)
+	}
+	local := filepath.Join(paths.TenantArchivesDir(), name)
+	f, err := os.OpenFile(local, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+	if err != nil {
+		log.Fatalf("Failed to create %s: %v", local, err)
+	}
+	dumpTenantSchema(c, pod, schema, f)
+	log.Infof("Saved snapshot to %s", local)
+
+	where := local
+	if archiveURL != "" {
+		if err := s3.PutFile(local, archiveURL+name); err != nil {
+			log.Fatalf("Failed to upload the snapshot of %s: %v", schema, err)
+		}
+		where = archiveURL + name
+	}
+
+	if err := auditlog.Record(auditlog.Entry{
+		Action:  "tenant.archive",
+		Context: auditCtx,
+		Target:  schema,
+		Detail:  where,
+	}); err != nil {
+		log.Fatalf("Refusing to continue without an audit record: %v", err)
+	}
+	return where
+}
+
+// dumpTenantSchema pg_dumps schema on pod into f and closes it.
+func dumpTenantSchema(c *kube.Cluster, pod, schema string, f *os.File) {
+	remote := "/tmp/" + tenant.ArchiveName(schema, time.Now())
+
+	log.Infof("Snapshotting schema %s...", schema)
+	// pginto execs psql with the connection flags; pointing it at pg_dump
+	// reuses its credential handling (including RDS IAM auth).
+	if _, err := c.ExecOnPod(pod, "env", "PGINTO_PSQL_BIN=pg_dump", "pginto", "-Fc", "-n", schema, "-f", remote); err != nil {
+		log.Fatalf("Failed to snapshot %s: %v", schema, err)
+	}
+	defer func() {
+		if _, err := c.ExecOnPod(pod, "rm", "-f", remote); err != nil {
+			log.Warnf("Failed to remove %s from %s: %v", remote, pod, err)
+		}
+	}()
+
+	if err := c.CopyFromPod(pod, remote, f); err != nil {
+		_ = f.Close()
+		log.Fatalf("Failed to download the snapshot of %s: %v", schema, err)
+	}
+	if err := f.Close(); err != nil {
+		log.Fatalf("Failed to write %s: %v", f.Name(), err)
+	}
+	if info, err := os.Stat(f.Name()); err != nil || info.Size() == 0 {
+		log.Fatalf("Snapshot %s of %s is empty", f.Name(), schema)
+	}
+}
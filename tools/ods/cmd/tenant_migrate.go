@@ -0,0 +1,489 @@
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/alembic"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/auditlog"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/kube"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/paths"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/prompt"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/s3"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/tenant"
+)
+
+// TenantMigrateOptions holds options for the tenant migrate command.
+type TenantMigrateOptions struct {
+	From        string
+	To          string
+	CPContext   string
+	CPPod       string
+	RouteColumn string
+	RouteValue  string
+	Restart     bool
+	SkipFiles   bool
+	SkipRouting bool
+}
+
+func newTenantMigrateCommand(parent *TenantOptions) *cobra.Command {
+	opts := &TenantMigrateOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "migrate <tenant_id> --to <context>",
+		Short: "Move a tenant to another data plane",
+		Long: `Move a tenant to another data plane.
+
+Copies the tenant from the --from data plane (default: --context) to the --to
+data plane, then points the control plane at the new one:
+
+  1. dump          pg_dump the tenant schema on the source
+  2. export        copy the tenant's rows out of public tables keyed by
+                   tenant_id (user_tenant_mapping etc.)
+  3. restore       restore the schema on the target
+  4. import        copy the public rows into the target
+  5. files         aws s3 sync the tenant's file-store objects between the
+                   data planes' buckets (needs AWS access to both)
+  6. file-records  point the tenant's file_record rows at the target bucket
+  7. validate      compare every table's row count between source and target
+  8. route         set the tenant's --route-column in the control plane's
+                   tenant table to --route-value (default: the --to context)
+
+Progress is kept under the ods data directory. If a step fails, fix the cause
+and re-run the same command: completed steps are skipped. --restart discards
+the saved progress (it does not undo anything done on the target).
+
+Both data planes must be at the same migration head. Writes to the tenant
+after the dump are not copied, so schedule the move with the customer; they
+show up as mismatches in the validate step. The source is left untouched:
+remove it with ` + "`ods tenant delete`" + ` once the move is confirmed. Search
+indexes are not copied; re-index the tenant's connectors on the target.
+
+Examples:
+  ods tenant migrate tenant_abcd1234 --from us --to eu
+  ods tenant migrate tenant_abcd1234 --from us --to eu --route-value eu-west-1
+  ods tenant migrate tenant_abcd1234 --from us --to eu --restart`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			runTenantMigrate(parent, opts, args[0])
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.From, "from", "", "Source data-plane context (default: --context)")
+	cmd.Flags().StringVar(&opts.To, "to", "", "Target data-plane context (required)")
+	cmd.Flags().StringVar(&opts.CPContext, "cp-context", "control_plane", "Control-plane cluster context")
+	cmd.Flags().StringVar(&opts.CPPod, "cp-pod", "control-plane", "Substring of the control-plane pod name")
+	cmd.Flags().StringVar(&opts.RouteColumn, "route-column", "data_plane", "Column of the control plane's tenant table that selects the data plane")
+	cmd.Flags().StringVar(&opts.RouteValue, "route-value", "", "Value identifying the target data plane in --route-column (default: --to)")
+	cmd.Flags().BoolVar(&opts.Restart, "restart", false, "Discard saved progress and start over")
+	cmd.Flags().BoolVar(&opts.SkipFiles, "skip-files", false, "Do not copy file-store objects (the buckets are shared)")
+	cmd.Flags().BoolVar(&opts.SkipRouting, "skip-routing", false, "Do not update the control plane (route the tenant by hand)")
+	_ = cmd.MarkFlagRequired("to")
+
+	return cmd
+}
+
+// migrationSide is one data plane of a tenant migration.
+type migrationSide struct {
+	cluster *kube.Cluster
+	pod     string
+	name    string
+}
+
+func openMigrationSide(ctx string) *migrationSide {
+	c := clusterFromEnv(ctx)
+	if err := c.EnsureContext(); err != nil {
+		log.Fatalf("Failed to ensure cluster context %s: %v", ctx, err)
+	}
+	pod, err := c.FindPod("api-server")
+	if err != nil {
+		log.Fatalf("Failed to find api-server pod in %s: %v", ctx, err)
+	}
+	return &migrationSide{cluster: c, pod: pod, name: c.Name + "/" + c.Namespace}
+}
+
+func runTenantMigrate(parent *TenantOptions, opts *TenantMigrateOptions, tenantID string) {
+	validateTenantArg(tenantID)
+	if !strings.HasPrefix(tenantID, "tenant_") {
+		log.Fatalf("Refusing to migrate %q: tenant IDs start with tenant_", tenantID)
+	}
+	if opts.From == "" {
+		opts.From = parent.Context
+	}
+	if opts.From == opts.To {
+		log.Fatalf("--from and --to are both %s", opts.From)
+	}
+	if opts.RouteValue == "" {
+		opts.RouteValue = opts.To
+	}
+
+	dir := tenant.MigrationDir(paths.TenantMigrationsDir(), tenantID)
+	if opts.Restart {
+		if err := os.RemoveAll(dir); err != nil {
+			log.Fatalf("Failed to discard saved progress: %v", err)
+		}
+	}
+	m, err := tenant.LoadMigration(dir, tenantID, opts.From, opts.To)
+	if err != nil {
+		log.Fatalf("%v (or pass --restart)", err)
+	}
+	if m.Finished() {
+		log.Infof("%s was already migrated from %s to %s; pass --restart to migrate it again", tenantID, opts.From, opts.To)
+		return
+	}
+
+	src := openMigrationSide(opts.From)
+	dst := openMigrationSide(opts.To)
+	checkMigrationHeads(src, dst, tenantID)
+
+	if m.Resuming() {
+		var done []string
+		for _, s := range tenant.MigrationSteps {
+			if m.Done[s] {
+				done = append(done, s)
+			}
+		}
+		fmt.Printf("Resuming migration of %s from %s to %s started %s (done: %s)\n",
+			tenantID, src.name, dst.name, m.StartedAt.Format(time.RFC3339), strings.Join(done, ", "))
+	} else {
+		preflightTenantMigration(src, dst, tenantID)
+	}
+	fmt.Println()
+
+	if typed := prompt.String(fmt.Sprintf("Type %s to migrate it to %s: ", tenantID, dst.name)); typed != tenantID {
+		log.Info("Tenant ID did not match. Aborted.")
+		return
+	}
+
+	if err := auditlog.Record(auditlog.Entry{
+		Action:  "tenant.migrate",
+		Context: src.name,
+		Target:  tenantID,
+		Detail:  "to " + dst.name,
+	}); err != nil {
+		log.Fatalf("Refusing to migrate a tenant without an audit record: %v", err)
+	}
+	if err := m.Save(); err != nil {
+		log.Fatalf("Failed to save migration progress: %v", err)
+	}
+
+	steps := map[string]func(){
+		"dump":         func() { migrateDump(src, m) },
+		"export":       func() { migrateExport(src, m) },
+		"restore":      func() { migrateRestore(dst, m) },
+		"import":       func() { migrateImport(dst, m) },
+		"files":        func() { migrateFiles(src, dst, m, opts.SkipFiles) },
+		"file-records": func() { migrateFileRecords(src, dst, m, opts.SkipFiles) },
+		"validate":     func() { migrateValidate(src, dst, m) },
+		"route":        func() { migrateRoute(dst, m, opts) },
+	}
+	for i, step := range tenant.MigrationSteps {
+		if m.Done[step] {
+			continue
+		}
+		log.Infof("[%d/%d] %s", i+1, len(tenant.MigrationSteps), step)
+		steps[step]()
+		if err := m.MarkDone(step); err != nil {
+			log.Fatalf("Failed to save migration progress: %v", err)
+		}
+	}
+
+	log.Infof("Migrated %s from %s to %s", tenantID, src.name, dst.name)
+	fmt.Println()
+	fmt.Println("Next steps:")
+	fmt.Printf("  - re-index the tenant's connectors in %s\n", dst.name)
+	fmt.Printf("  - once confirmed and idle on the source, remove it there: ods tenant delete %s -c %s\n", tenantID, opts.From)
+}
+
+// checkMigrationHeads requires both data planes to run the same migration
+// head and the tenant to be at it, so the dump restores as-is.
+func checkMigrationHeads(src, dst *migrationSide, tenantID string) {
+	srcStatus, err := alembic.RemoteStatus(src.cluster, src.pod, false, tenantID)
+	if err != nil {
+		log.Fatalf("Failed to check migrations in %s: %v", src.name, err)
+	}
+	dstStatus, err := alembic.RemoteStatus(dst.cluster, dst.pod, false, "public")
+	if err != nil {
+		log.Fatalf("Failed to check migrations in %s: %v", dst.name, err)
+	}
+	if srcStatus.Head != dstStatus.Head {
+		log.Fatalf("%s is at migration head %s but %s is at %s; deploy the same version to both first", src.name, srcStatus.Head, dst.name, dstStatus.Head)
+	}
+	if rev, lagging := srcStatus.Lagging[tenantID]; lagging {
+		log.Fatalf("%s is at revision %q, not head %s; run `ods migrate up --tenant %s -c ...` first", tenantID, rev, srcStatus.Head, tenantID)
+	}
+}
+
+// preflightTenantMigration checks the tenant exists on the source and not on
+// the target, and prints what will be moved.
+func preflightTenantMigration(src, dst *migrationSide, tenantID string) {
+	usage, err := tenant.ParseUsage(queryPod(src.cluster, src.pod, tenant.UsageSQL(tenantID)))
+	if err != nil {
+		log.Fatalf("Failed to inspect %s in %s: %v", tenantID, src.name, err)
+	}
+	if !usage.SchemaExists {
+		log.Fatalf("Tenant %s not found in %s", tenantID, src.name)
+	}
+
+	existing, err := tenant.ParseUsage(queryPod(dst.cluster, dst.pod, tenant.UsageSQL(tenantID)))
+	if err != nil {
+		log.Fatalf("Failed to inspect %s in %s: %v", tenantID, dst.name, err)
+	}
+	if existing.SchemaExists {
+		log.Fatalf("%s already has a schema %s; delete it first if it is a leftover", dst.name, tenantID)
+	}
+	if rows := publicTenantRows(dst, tenantID); len(rows) > 0 {
+		log.Fatalf("%s already has rows for %s in public tables (%s); delete them first if they are leftovers", dst.name, tenantID, strings.Join(sortedKeys(rows), ", "))
+	}
+
+	fmt.Printf("Tenant %s: %d user(s), %d active\n", tenantID, usage.Users, usage.ActiveUsers)
+	fmt.Printf("  from %s\n", src.name)
+	fmt.Printf("  to   %s\n", dst.name)
+}
+
+// publicTenantRows returns the public tables holding rows of the tenant, with
+// their counts.
+func publicTenantRows(side *migrationSide, tenantID string) map[string]int {
+	tables := queryPod(side.cluster, side.pod, tenant.PublicTablesSQL)
+	if len(tables) == 0 {
+		return nil
+	}
+	counts, err := tenant.ParsePublicRows(queryPod(side.cluster, side.pod, tenant.PublicRowsSQL(tenantID, tables)))
+	if err != nil {
+		log.Fatalf("Failed to inspect public tables in %s: %v", side.name, err)
+	}
+	rows := map[string]int{}
+	for t, n := range counts {
+		if n > 0 {
+			rows[t] = n
+		}
+	}
+	return rows
+}
+
+func migrateDump(src *migrationSide, m *tenant.Migration) {
+	if err := os.MkdirAll(filepath.Dir(m.Path("schema.dump")), 0700); err != nil {
+		log.Fatalf("Failed to create %s: %v", filepath.Dir(m.Path("schema.dump")), err)
+	}
+	f, err := os.OpenFile(m.Path("schema.dump"), os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0600)
+	if err != nil {
+		log.Fatalf("Failed to create %s: %v", m.Path("schema.dump"), err)
+	}
+	dumpTenantSchema(src.cluster, src.pod, m.TenantID, f)
+}
+
+func migrateExport(src *migrationSide, m *tenant.Migration) {
+	m.PublicTables = sortedKeys(publicTenantRows(src, m.TenantID))
+	for _, table := range m.PublicTables {
+		out, err := src.cluster.ExecOnPod(src.pod, "pginto", "-c", tenant.CopyOutSQL(table, m.TenantID))
+		if err != nil {
+			log.Fatalf("Failed to export public.%s: %v", table, err)
+		}
+		if err := os.WriteFile(m.Path("public."+table+".csv"), []byte(stripPginto(out)), 0600); err != nil {
+			log.Fatalf("Failed to save public.%s: %v", table, err)
+		}
+	}
+	log.Infof("Exported rows from %d public table(s)", len(m.PublicTables))
+}
+
+// stripPginto removes the connection banner pginto prints before psql runs.
+func stripPginto(out string) string {
+	for strings.HasPrefix(out, "Connecting to ") {
+		_, out, _ = strings.Cut(out, "\n")
+	}
+	return out
+}
+
+func migrateRestore(dst *migrationSide, m *tenant.Migration) {
+	f, err := os.Open(m.Path("schema.dump"))
+	if err != nil {
+		log.Fatalf("Failed to open the dump: %v", err)
+	}
+	defer func() { _ = f.Close() }()
+
+	remote := "/tmp/" + m.TenantID + ".migrate.dump"
+	if _, err := dst.cluster.ExecOnPodWithStdin(dst.pod, f, "sh", "-c", `cat > "$1"`, "sh", remote); err != nil {
+		log.Fatalf("Failed to upload the dump to %s: %v", dst.pod, err)
+	}
+	defer func() {
+		if _, err := dst.cluster.ExecOnPod(dst.pod, "rm", "-f", remote, remote+".sql"); err != nil {
+			log.Warnf("Failed to remove %s from %s: %v", remote, dst.pod, err)
+		}
+	}()
+
+	// pg_restore renders the dump as SQL, which psql (through pginto, for its
+	// connection handling) applies in one transaction so a failure leaves no
+	// half-restored schema behind.
+	log.Infof("Restoring %s in %s...", m.TenantID, dst.name)
+	if _, err := dst.cluster.ExecOnPod(dst.pod, "sh", "-c",
+		`pg_restore --no-owner --no-privileges -f "$1.sql" "$1" && pginto -q -v ON_ERROR_STOP=1 --single-transaction -f "$1.sql"`,
+		"sh", remote); err != nil {
+		log.Fatalf("Failed to restore %s: %v", m.TenantID, err)
+	}
+}
+
+func migrateImport(dst *migrationSide, m *tenant.Migration) {
+	for _, table := range m.PublicTables {
+		data, err := os.ReadFile(m.Path("public." + table + ".csv"))
+		if err != nil {
+			log.Fatalf("Failed to read the export of public.%s: %v", table, err)
+		}
+		header, _, _ := strings.Cut(string(data), "\n")
+		sql, err := tenant.CopyInSQL(table, header)
+		if err != nil {
+			log.Fatal(err)
+		}
+		// Clearing first in the same transaction makes a re-run after a
+		// partial import safe; preflight made sure the target had no rows.
+		if _, err := dst.cluster.ExecOnPodWithStdin(dst.pod, bytes.NewReader(data), "pginto",
+			"-v", "ON_ERROR_STOP=1", "--single-transaction",
+			"-c", tenant.ClearPublicSQL(table, m.TenantID), "-c", sql); err != nil {
+			log.Fatalf("Failed to import public.%s: %v", table, err)
+		}
+	}
+	log.Infof("Imported rows into %d public table(s)", len(m.PublicTables))
+}
+
+// migrationFileStore reads where a data plane keeps files from its api-server
+// environment, with the backend's defaults.
+func migrationFileStore(side *migrationSide) tenant.FileStore {
+	env := func(name, def string) string {
+		out, err := side.cluster.ExecOnPod(side.pod, "printenv", name)
+		if v := strings.TrimSpace(out); err == nil && v != "" {
+			return v
+		}
+		return def
+	}
+	if backend := env("FILE_STORE_BACKEND", "s3"); backend != "s3" {
+		log.Fatalf("%s uses the %s file store; only s3 is supported", side.name, backend)
+	}
+	return tenant.FileStore{
+		Bucket: env("S3_FILE_STORE_BUCKET_NAME", "onyx-file-store-bucket"),
+		Prefix: env("S3_FILE_STORE_PREFIX", "onyx-files"),
+	}
+}
+
+func migrateFiles(src, dst *migrationSide, m *tenant.Migration, skip bool) {
+	if skip {
+		log.Info("Skipping file-store objects (--skip-files)")
+		return
+	}
+	from, to := migrationFileStore(src), migrationFileStore(dst)
+	if from == to {
+		log.Info("Both data planes share a file store; nothing to copy")
+		return
+	}
+	// The copy runs as the target data plane, which needs read access to the
+	// source bucket when the two are in different accounts.
+	env, err := dst.cluster.AWSEnv()
+	if err != nil {
+		log.Fatalf("Failed to get AWS credentials for %s: %v", dst.name, err)
+	}
+	if err := s3.SyncBuckets(env, from.TenantURL(m.TenantID), to.TenantURL(m.TenantID)); err != nil {
+		log.Fatalf("Failed to copy file-store objects: %v", err)
+	}
+}
+
+func migrateFileRecords(src, dst *migrationSide, m *tenant.Migration, skip bool) {
+	if skip {
+		return
+	}
+	sql := tenant.FileRecordsSQL(m.TenantID, migrationFileStore(src), migrationFileStore(dst))
+	if sql == "" {
+		return
+	}
+	queryPod(dst.cluster, dst.pod, sql)
+}
+
+func migrateValidate(src, dst *migrationSide, m *tenant.Migration) {
+	tables := queryPod(src.cluster, src.pod, tenant.TablesSQL(m.TenantID))
+	if len(tables) == 0 {
+		log.Fatalf("No tables found in %s on %s", m.TenantID, src.name)
+	}
+	counts := func(side *migrationSide) map[string]int {
+		schema, err := tenant.ParseCounts(queryPod(side.cluster, side.pod, tenant.CountsSQL(m.TenantID, tables)))
+		if err != nil {
+			log.Fatalf("Failed to count rows in %s: %v", side.name, err)
+		}
+		if len(m.PublicTables) > 0 {
+			public, err := tenant.ParseCounts(queryPod(side.cluster, side.pod, tenant.PublicRowsSQL(m.TenantID, m.PublicTables)))
+			if err != nil {
+				log.Fatalf("Failed to count rows in %s: %v", side.name, err)
+			}
+			for t, n := range public {
+				schema["public."+t] = n
+			}
+		}
+		return schema
+	}
+
+	mismatches := tenant.CompareCounts(counts(src), counts(dst))
+	if len(mismatches) == 0 {
+		log.Infof("Row counts match across %d table(s)", len(tables)+len(m.PublicTables))
+		return
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "TABLE\tSOURCE\tTARGET")
+	_, _ = fmt.Fprintln(w, "-----\t------\t------")
+	for _, mm := range mismatches {
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\n", mm.Table, formatCount(mm.Source), formatCount(mm.Target))
+	}
+	_ = w.Flush()
+	log.Fatalf("Row counts differ in %d table(s); if the tenant was written to during the move, re-run with --restart after dropping %s in %s", len(mismatches), m.TenantID, dst.name)
+}
+
+func formatCount(n int) string {
+	if n < 0 {
+		return "missing"
+	}
+	return fmt.Sprint(n)
+}
+
+func migrateRoute(dst *migrationSide, m *tenant.Migration, opts *TenantMigrateOptions) {
+	if opts.SkipRouting {
+		log.Warnf("Not updating the control plane (--skip-routing); route %s to %s by hand", m.TenantID, dst.name)
+		return
+	}
+
+	cp := clusterFromEnv(opts.CPContext)
+	if err := cp.EnsureContext(); err != nil {
+		log.Fatalf("Failed to ensure cluster context: %v", err)
+	}
+	pod, err := cp.FindPod(opts.CPPod)
+	if err != nil {
+		log.Fatalf("Failed to find control-plane pod: %v", err)
+	}
+
+	if err := auditlog.Record(auditlog.Entry{
+		Action:  "tenant.route",
+		Context: cp.Name + "/" + cp.Namespace,
+		Target:  m.TenantID,
+		Detail:  fmt.Sprintf("%s=%s", opts.RouteColumn, opts.RouteValue),
+	}); err != nil {
+		log.Fatalf("Refusing to route a tenant without an audit record: %v", err)
+	}
+	previous, err := tenant.Route(cp, pod, m.TenantID, opts.RouteColumn, opts.RouteValue)
+	if err != nil {
+		log.Fatalf("Failed to route %s in the control plane: %v", m.TenantID, err)
+	}
+	log.Infof("Control plane now routes %s to %s (was %q)", m.TenantID, opts.RouteValue, previous)
+}
+
+func sortedKeys(m map[string]int) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
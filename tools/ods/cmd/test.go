@@ -0,0 +1,40 @@
+package cmd
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/hooks"
+)
+
+// NewTestCommand creates the test command.
+func NewTestCommand() *cobra.Command {
+	opts := &ChecksOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "test [area...]",
+		Short: "Run the unit tests for the backend, web app or ods",
+		Long: `Run the unit tests for an area of the repository:
+
+  backend   pytest tests/unit
+  web       jest (with --staged or --since, only tests related to the
+            changed files)
+  tools     go test ./... (tools/ods)
+
+With no areas, tests all of them. --staged or --since limit the run to
+areas with changed files; if nothing relevant changed, nothing runs.
+Integration and end-to-end tests need running services and are left to CI.
+
+Examples:
+  ods test
+  ods test backend
+  ods test --since origin/main --skip web`,
+		ValidArgs: hooks.AreaNames(),
+		Run: func(cmd *cobra.Command, args []string) {
+			runChecks(opts, "test", args)
+		},
+	}
+
+	addChecksFlags(cmd, opts)
+
+	return cmd
+}
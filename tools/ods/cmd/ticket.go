@@ -0,0 +1,84 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/auditlog"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/config"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/tickets"
+)
+
+// ticketReporter comments on the --ticket issue once a command that wrote
+// audit entries finishes, successfully or through log.Fatal.
+type ticketReporter struct {
+	key       string
+	commenter tickets.Commenter
+	failure   *fatalCapture
+}
+
+// startTicketReporter attaches ticket to this run's audit entries and, when a
+// ticket provider is configured, arranges for the comment. Runs that record
+// nothing leave the ticket alone.
+func startTicketReporter(ticket string) *ticketReporter {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Warnf("Failed to load ods config: %v", err)
+		cfg = &config.Config{}
+	}
+	auditlog.SetTicket(ticket, cfg.Tickets.Require)
+	if ticket == "" {
+		return nil
+	}
+	if !tickets.ValidKey(ticket) {
+		log.Fatalf("Invalid ticket %q (expected an issue key like OPS-123)", ticket)
+	}
+
+	commenter, err := tickets.NewCommenter(cfg.Tickets)
+	if errors.Is(err, tickets.ErrNotConfigured) {
+		log.Debugf("No ticket provider configured; %s is only recorded in the audit log", ticket)
+		return nil
+	}
+	if err != nil {
+		log.Warnf("Not commenting on %s: %v", ticket, err)
+		return nil
+	}
+
+	r := &ticketReporter{key: ticket, commenter: commenter, failure: &fatalCapture{}}
+	log.AddHook(r.failure)
+	log.RegisterExitHandler(func() {
+		r.post(false, r.failure.message)
+	})
+	return r
+}
+
+// Done comments on the ticket after a successful run. A nil reporter does
+// nothing.
+func (r *ticketReporter) Done() {
+	if r == nil {
+		return
+	}
+	r.post(true, "")
+}
+
+func (r *ticketReporter) post(ok bool, summary string) {
+	actions := auditlog.Recorded()
+	if len(actions) == 0 {
+		return
+	}
+	report := tickets.Report{
+		Command: "ods " + strings.Join(os.Args[1:], " "),
+		Actor:   auditlog.Actor(),
+		OK:      ok,
+		Summary: summary,
+		Actions: actions,
+	}
+	if err := r.commenter.Comment(r.key, report.Body()); err != nil {
+		log.Warnf("Failed to comment on %s: %v", r.key, err)
+		return
+	}
+	log.Infof("Commented on %s", r.key)
+}
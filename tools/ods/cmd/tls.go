@@ -0,0 +1,117 @@
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/devtls"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/paths"
+)
+
+// tlsComposeFile is the compose override that serves the stack over HTTPS;
+// `ods compose --tls` adds it.
+const tlsComposeFile = "docker-compose.tls.yml"
+
+// NewTLSCommand creates the parent tls command.
+func NewTLSCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "tls",
+		Short: "Serve the local dev stack over HTTPS",
+		Long: `Serve the local dev stack over HTTPS, as OAuth providers and secure
+cookies increasingly require even in development.`,
+	}
+
+	cmd.AddCommand(newTLSSetupCommand())
+
+	return cmd
+}
+
+func newTLSSetupCommand() *cobra.Command {
+	var domain string
+	var port int
+	var noMkcert bool
+
+	cmd := &cobra.Command{
+		Use:   "setup",
+		Short: "Make a trusted certificate and point the compose stack at it",
+		Long: `Make a certificate for the dev stack and configure compose to serve it:
+
+  1. With mkcert on PATH, installs its CA into the system and browser trust
+     stores and issues the certificate from it. Otherwise ods makes a local
+     CA of its own (kept in ~/.local/share/onyx-dev/tls) and prints how to
+     trust it.
+  2. Issues a certificate for the domain, its subdomains and localhost.
+  3. Writes the domain, certificate directory and HTTPS port to the compose
+     .env, which ` + tlsComposeFile + ` reads to serve https on nginx and
+     set WEB_DOMAIN for the api and web servers (login redirects, cookies).
+
+Then start the stack with ods compose --tls. onyx.localhost resolves to this
+machine without a hosts entry; a custom --domain needs one.
+
+Re-running reissues the certificate, e.g. for a new domain.
+
+Examples:
+  ods tls setup
+  ods tls setup --domain onyx.test --port 8443`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			runTLSSetup(domain, port, noMkcert)
+		},
+	}
+
+	cmd.Flags().StringVar(&domain, "domain", devtls.DefaultDomain, "Domain to serve the stack at")
+	cmd.Flags().IntVar(&port, "port", 443, "Host port for HTTPS")
+	cmd.Flags().BoolVar(&noMkcert, "no-mkcert", false, "Use ods's own CA even if mkcert is installed")
+
+	return cmd
+}
+
+func runTLSSetup(domain string, port int, noMkcert bool) {
+	domain = strings.ToLower(strings.TrimSuffix(strings.TrimSpace(domain), "."))
+	if domain == "" || strings.ContainsAny(domain, "/: ") {
+		log.Fatalf("Invalid domain %q", domain)
+	}
+	if port <= 0 || port > 65535 {
+		log.Fatalf("Invalid port %d", port)
+	}
+
+	dir := filepath.Join(paths.TLSDir(), domain)
+	names := devtls.Names(domain)
+	if devtls.HasMkcert() && !noMkcert {
+		log.Info("Issuing the certificate with mkcert")
+		if err := devtls.IssueWithMkcert(nil, dir, names); err != nil {
+			log.Fatalf("%v", err)
+		}
+	} else {
+		ca, created, err := devtls.LoadOrCreateCA(paths.TLSDir())
+		if err != nil {
+			log.Fatalf("Failed to set up the local CA: %v", err)
+		}
+		if err := ca.Issue(dir, names); err != nil {
+			log.Fatalf("Failed to issue the certificate: %v", err)
+		}
+		if created {
+			log.Infof("Created a local CA; trust it once so browsers accept the certificate:\n\n  %s\n", devtls.TrustInstructions(ca.Path, runtime.GOOS))
+		} else {
+			log.Infof("Issued the certificate from the local CA at %s", ca.Path)
+		}
+	}
+
+	webDomain := devtls.WebDomain(domain, port)
+	setEnvValue("TLS_DOMAIN", domain)
+	setEnvValue("TLS_WEB_DOMAIN", webDomain)
+	setEnvValue("TLS_CERT_DIR", dir)
+	setEnvValue("HOST_HTTPS_PORT", strconv.Itoa(port))
+
+	if !devtls.Resolves(domain) {
+		log.Warnf("%s does not resolve to this machine; add it to your hosts file:\n\n  127.0.0.1 %s\n", domain, domain)
+	}
+	log.Infof("Start the stack with `ods compose --tls` (or `ods compose dev --tls`)")
+	fmt.Println(webDomain)
+}
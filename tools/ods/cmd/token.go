@@ -0,0 +1,159 @@
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/apiclient"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/render"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/token"
+)
+
+// TokenDecodeOptions holds options for the token decode subcommand.
+type TokenDecodeOptions struct {
+	Context string
+	Verify  bool
+	JSON    bool
+}
+
+// NewTokenCommand creates the token command.
+func NewTokenCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "token",
+		Short: "Inspect JSON Web Tokens",
+	}
+
+	cmd.AddCommand(newTokenDecodeCommand())
+
+	return cmd
+}
+
+func newTokenDecodeCommand() *cobra.Command {
+	opts := &TokenDecodeOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "decode <jwt>",
+		Short: "Decode a JWT and say why it would be refused",
+		Long: `Decode a JWT: its header, its claims with timestamps spelled out, what issued
+it (session, password reset, email verification or an external issuer, by
+audience), and whether it has expired or is not valid yet.
+
+The token may be given literally, as @file or as @- for stdin, with or without
+a "Bearer " prefix. Decoding happens locally; nothing is sent anywhere.
+
+--verify also checks the signature with the keys of the deployment behind -c:
+USER_AUTH_SECRET for HS256 tokens, the key at JWT_PUBLIC_KEY_URL for RS256
+ones. The check runs on an api-server pod, so the secret stays there, but
+the token is passed to the pod.
+
+The command exits non-zero when the token would be refused.
+
+Examples:
+  ods token decode eyJhbGciOi...
+  pbpaste | ods token decode @-
+  ods token decode @token.txt --verify -c prod`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			runTokenDecode(opts, args[0])
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.Context, "context", "c", "data_plane", "cluster context name for --verify (maps to KUBE_CTX_<NAME> env var)")
+	cmd.Flags().BoolVar(&opts.Verify, "verify", false, "Check the signature with the deployment's keys")
+	cmd.Flags().BoolVar(&opts.JSON, "json", false, "Print the header and claims as JSON")
+
+	return cmd
+}
+
+func runTokenDecode(opts *TokenDecodeOptions, spec string) {
+	data, err := apiclient.ReadBody(spec, os.Stdin)
+	if err != nil {
+		log.Fatalf("Failed to read the token: %v", err)
+	}
+	raw := string(data)
+	t, err := token.Decode(raw)
+	if err != nil {
+		log.Fatalf("Failed to decode the token: %v", err)
+	}
+
+	var v *token.Verification
+	if opts.Verify {
+		c := clusterFromEnv(opts.Context)
+		if err := c.EnsureContext(); err != nil {
+			log.Fatalf("Failed to ensure cluster context: %v", err)
+		}
+		log.Info("Finding api-server pod...")
+		pod, err := c.FindPod("api-server")
+		if err != nil {
+			log.Fatalf("Failed to find api-server pod: %v", err)
+		}
+		if v, err = token.Verify(c, pod, raw); err != nil {
+			log.Fatalf("Failed to verify the token: %v", err)
+		}
+	}
+
+	if opts.JSON {
+		if err := render.JSON(os.Stdout, map[string]any{"header": t.Header, "claims": t.Claims, "verification": v}); err != nil {
+			log.Fatalf("Failed to marshal token: %v", err)
+		}
+		return
+	}
+
+	findings := t.Findings(time.Now())
+	fmt.Printf("Kind:       %s\n", t.Kind())
+	fmt.Printf("Algorithm:  %s", t.Algorithm())
+	if kid := t.KeyID(); kid != "" {
+		fmt.Printf(" (key %s)", kid)
+	}
+	fmt.Println()
+	if v != nil {
+		switch {
+		case v.Valid:
+			fmt.Printf("Signature:  valid (%s)\n", v.Key)
+		case v.Key != "":
+			fmt.Printf("Signature:  INVALID against %s: %s\n", v.Key, v.Error)
+			findings = append(findings, "The signature does not verify with "+v.Key+"; the token is from another environment or the key was rotated")
+		default:
+			fmt.Printf("Signature:  not checked: %s\n", v.Error)
+		}
+	}
+
+	fmt.Println("\nClaims:")
+	printTokenClaims(t, "  ")
+
+	fmt.Println()
+	if len(findings) == 0 {
+		fmt.Println("Nothing stands out.")
+		return
+	}
+	fmt.Println("Findings:")
+	for _, f := range findings {
+		fmt.Printf("  - %s\n", f)
+	}
+	exitWith(1)
+}
+
+// tokenTimeClaims are the NumericDate claims printed with their time.
+var tokenTimeClaims = map[string]bool{"exp": true, "iat": true, "nbf": true, "auth_time": true}
+
+func printTokenClaims(t *token.JWT, indent string) {
+	names := make([]string, 0, len(t.Claims))
+	for name := range t.Claims {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		value, _ := json.Marshal(t.Claims[name])
+		if ts, ok := t.Time(name); ok && tokenTimeClaims[name] {
+			fmt.Printf("%s%s = %s (%s)\n", indent, name, value, ts.UTC().Format(time.RFC3339))
+			continue
+		}
+		fmt.Printf("%s%s = %s\n", indent, name, value)
+	}
+}
@@ -524,7 +524,7 @@ func openTraces(traces []traceInfo) {
 	args := append([]string{"playwright", "show-trace"}, tracePaths...)
 
 	log.Infof("Opening %d trace(s) with playwright show-trace...", len(traces))
-	cmd := exec.Command("bunx", args...)
+	cmd := exec.Command(paths.Executable("bunx"), args...)
 
 	// Run from web/ to pick up the locally-installed Playwright version
 	if root, err := paths.GitRoot(); err == nil {
@@ -0,0 +1,234 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/docker"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/paths"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/pgdiag"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/postgres"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/render"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/upgrade"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/version"
+)
+
+// UpgradeCheckOptions holds options for the upgrade-check command.
+type UpgradeCheckOptions struct {
+	From    string
+	EnvFile string
+	JSON    bool
+}
+
+// upgradeReport is what upgrade-check found, as --json prints it.
+type upgradeReport struct {
+	From       string                   `json:"from,omitempty"`
+	To         string                   `json:"to"`
+	Releases   []upgrade.Release        `json:"releases"`
+	Env        []upgrade.EnvIssue       `json:"env"`
+	Revisions  []string                 `json:"current_revisions,omitempty"`
+	Pending    []string                 `json:"pending_migrations"`
+	Estimate   time.Duration            `json:"estimate_ns"`
+	Heavy      []upgrade.HeavyMigration `json:"heavy_migrations,omitempty"`
+	NoDatabase bool                     `json:"no_database,omitempty"`
+}
+
+// NewUpgradeCheckCommand creates the upgrade-check command.
+func NewUpgradeCheckCommand() *cobra.Command {
+	opts := &UpgradeCheckOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "upgrade-check <version>",
+		Short: "Check a self-hosted instance before upgrading to a release",
+		Long: `Check a docker compose deployment of Onyx before upgrading it to a release.
+
+Reads the upgrade metadata packaged with the target release (` + upgrade.MetadataPath + `)
+and reports, for every release between the current version and the target:
+
+  - breaking changes
+  - environment variables to add or rename, checked against the deployment's
+    .env file
+  - the migrations the database has still to run, from its current Alembic
+    revision, and how long they should take on this instance's data
+
+The current version is --from, or IMAGE_TAG in the .env file. The database is
+the running compose project's; without one the migration sections are skipped.
+
+Exits non-zero when the .env file needs changing before the upgrade.
+
+Examples:
+  ods upgrade-check v2.10.4
+  ods upgrade-check v2.10.4 --from v2.8.0 --json`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			runUpgradeCheck(args[0], opts)
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.From, "from", "", "Version the instance runs (default: IMAGE_TAG in the .env file)")
+	cmd.Flags().StringVar(&opts.EnvFile, "env-file", "", "The deployment's .env file (default: deployment/docker_compose/.env)")
+	cmd.Flags().BoolVar(&opts.JSON, "json", false, "Print the report as JSON")
+
+	return cmd
+}
+
+func runUpgradeCheck(target string, opts *UpgradeCheckOptions) {
+	root, err := paths.GitRoot()
+	if err != nil {
+		log.Fatalf("Failed to find git root: %v", err)
+	}
+	ref, err := upgrade.ResolveRef(root, target)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	envFile := opts.EnvFile
+	if envFile == "" {
+		envFile = filepath.Join(root, "deployment", "docker_compose", ".env")
+	}
+	env := map[string]string{}
+	if _, err := os.Stat(envFile); err == nil {
+		for _, kv := range loadBackendEnvFile(envFile) {
+			k, v, _ := strings.Cut(kv, "=")
+			env[k] = v
+		}
+	} else {
+		log.Warnf("No env file at %s; every required variable will be reported as missing", envFile)
+	}
+
+	report := upgradeReport{From: opts.From, To: target}
+	if report.From == "" && version.IsSemverish(env["IMAGE_TAG"]) {
+		report.From = env["IMAGE_TAG"]
+	}
+	if report.From == "" {
+		log.Warnf("Current version unknown (IMAGE_TAG is %q); only %s's notes are shown, pass --from for every release in between", env["IMAGE_TAG"], target)
+	}
+
+	meta, err := upgrade.LoadMetadata(root, ref)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	if meta == nil {
+		log.Warnf("%s has no upgrade metadata (%s); breaking changes and env changes are not known", target, upgrade.MetadataPath)
+		meta = &upgrade.Metadata{}
+	}
+	report.Releases = meta.Between(report.From, target)
+	report.Env = upgrade.CheckEnv(report.Releases, env)
+
+	graph, err := upgrade.LoadRevisions(root, ref)
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	if query := localDBQuery(); query == nil {
+		report.NoDatabase = true
+	} else {
+		report.Revisions = query("SELECT version_num FROM alembic_version")
+		if report.Pending, err = graph.Pending(report.Revisions...); err != nil {
+			log.Fatalf("%v", err)
+		}
+		rows := map[string]int64{}
+		for _, line := range query("SELECT relname, GREATEST(reltuples, 0)::bigint FROM pg_class WHERE relkind = 'r' AND relnamespace = 'public'::regnamespace") {
+			name, count, _ := strings.Cut(line, "\t")
+			rows[name], _ = strconv.ParseInt(count, 10, 64)
+		}
+		report.Estimate, report.Heavy = upgrade.Estimate(report.Pending, meta.Migrations, rows)
+	}
+
+	if opts.JSON {
+		if err := render.JSON(os.Stdout, report); err != nil {
+			log.Fatalf("Failed to encode report: %v", err)
+		}
+	} else {
+		printUpgradeReport(&report, envFile)
+	}
+
+	actions := 0
+	for _, issue := range report.Env {
+		if issue.Action != "" {
+			actions++
+		}
+	}
+	if actions > 0 {
+		log.Errorf("%d change(s) to %s are needed before upgrading", actions, envFile)
+		exitWith(1)
+	}
+}
+
+// localDBQuery returns a query function for the compose project's Postgres,
+// or nil when it is not running.
+func localDBQuery() func(sql string) []string {
+	container, err := docker.FindPostgresContainer(docker.ProjectName())
+	if err != nil {
+		log.Warnf("No running database (%v); skipping the migration checks", err)
+		return nil
+	}
+	args := append([]string{"psql"}, postgres.NewConfigFromEnv().PsqlArgs()...)
+	args = append(args, "-A", "-t", "-F", "\t", "-c")
+	return func(sql string) []string {
+		out, err := docker.ExecOutput(container, append(args, sql)...)
+		if err != nil {
+			log.Fatalf("Query failed: %v", err)
+		}
+		return nonEmptyLines(out)
+	}
+}
+
+func printUpgradeReport(r *upgradeReport, envFile string) {
+	from := r.From
+	if from == "" {
+		from = "unknown version"
+	}
+	fmt.Printf("Upgrade check: %s -> %s\n", from, r.To)
+
+	var breaking int
+	for _, rel := range r.Releases {
+		breaking += len(rel.Breaking)
+	}
+	fmt.Printf("\nBreaking changes (%d):\n", breaking)
+	for _, rel := range r.Releases {
+		for _, b := range rel.Breaking {
+			fmt.Printf("  %-10s %s\n", rel.Version, b)
+		}
+	}
+
+	fmt.Printf("\nEnvironment (%s):\n", envFile)
+	if len(r.Env) == 0 {
+		fmt.Println("  nothing to change")
+	}
+	for _, issue := range r.Env {
+		mark, what := "!", issue.Action
+		if what == "" {
+			mark, what = "+", "new optional "+issue.Name
+		}
+		line := fmt.Sprintf("  %s %-10s %s", mark, issue.Release, what)
+		if issue.Note != "" {
+			line += " - " + issue.Note
+		}
+		fmt.Println(line)
+	}
+
+	fmt.Println("\nMigrations:")
+	if r.NoDatabase {
+		fmt.Println("  skipped, no running database")
+		return
+	}
+	current := strings.Join(r.Revisions, ", ")
+	if current == "" {
+		current = "an unmigrated database"
+	}
+	fmt.Printf("  %d pending from %s, estimated %s on this instance's data\n", len(r.Pending), current, pgdiag.FormatDuration(r.Estimate))
+	for _, h := range r.Heavy {
+		line := fmt.Sprintf("    %s  %s (%d rows)  ~%s", h.Revision, h.Table, h.Rows, pgdiag.FormatDuration(h.Estimate))
+		if h.Note != "" {
+			line += "  " + h.Note
+		}
+		fmt.Println(line)
+	}
+}
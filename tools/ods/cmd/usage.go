@@ -0,0 +1,216 @@
+package cmd
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/report"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/tenant"
+)
+
+// UsageOptions holds options shared by the usage subcommands.
+type UsageOptions struct {
+	Context string
+}
+
+// UsageTokensOptions holds options for the usage tokens command.
+type UsageTokensOptions struct {
+	Tenant string
+	Since  string
+	By     string
+	CSV    bool
+}
+
+// NewUsageCommand creates the parent usage command.
+func NewUsageCommand() *cobra.Command {
+	opts := &UsageOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "usage",
+		Short: "Report a tenant's usage from the data plane database",
+		Long: `Report a tenant's usage from the data plane database.
+
+Requires: AWS SSO login, kubectl access to the EKS cluster.`,
+	}
+
+	cmd.PersistentFlags().StringVarP(&opts.Context, "context", "c", "data_plane", "cluster context name (maps to KUBE_CTX_<NAME> env var)")
+
+	cmd.AddCommand(newUsageTokensCommand(opts))
+
+	return cmd
+}
+
+func newUsageTokensCommand(parent *UsageOptions) *cobra.Command {
+	opts := &UsageTokensOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "tokens",
+		Short: "Summarize a tenant's token consumption by model and assistant",
+		Long: `Summarize a tenant's token consumption by model and assistant.
+
+By model: LLM, embedding and rerank tokens and their cost, from the per-user
+usage rollup the backend records for every model call (including indexing).
+
+By assistant: chat sessions, user messages and the token counts of stored
+chat messages. These counts exclude retrieved context and prompts, so they
+are smaller than what the LLM was sent; use the model breakdown for spend.
+
+--since takes a duration such as 30d, 12h or 90m. Windows in the usage rollup
+are daily, so the model breakdown starts at the beginning of a day.
+
+Use --by to show one breakdown, and --csv to print it as CSV.
+
+Examples:
+  ods usage tokens --tenant tenant_abcd1234
+  ods usage tokens --tenant tenant_abcd1234 --since 7d --by assistant
+  ods usage tokens -c prod_eu --tenant tenant_abcd1234 --by model --csv > usage.csv`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			runUsageTokens(parent, opts)
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.Tenant, "tenant", "public", "Tenant ID (public for a single-tenant deployment)")
+	cmd.Flags().StringVar(&opts.Since, "since", "30d", "How far back to look (e.g. 30d, 12h)")
+	cmd.Flags().StringVar(&opts.By, "by", "", "Show only one breakdown: model or assistant (required with --csv)")
+	cmd.Flags().BoolVar(&opts.CSV, "csv", false, "Print the breakdown as CSV")
+
+	return cmd
+}
+
+func runUsageTokens(parent *UsageOptions, opts *UsageTokensOptions) {
+	validateTenantArg(opts.Tenant)
+	lookback, err := report.ParseLookback(opts.Since)
+	if err != nil {
+		log.Fatalf("Invalid --since: %v", err)
+	}
+	switch opts.By {
+	case "", "model", "assistant":
+	default:
+		log.Fatalf("--by must be model or assistant")
+	}
+	if opts.CSV && opts.By == "" {
+		log.Fatal("--csv needs --by model or --by assistant")
+	}
+	since := time.Now().Add(-lookback)
+
+	c := clusterFromEnv(parent.Context)
+	if err := c.EnsureContext(); err != nil {
+		log.Fatalf("Failed to ensure cluster context: %v", err)
+	}
+	pod, err := c.FindPod("api-server")
+	if err != nil {
+		log.Fatalf("Failed to find api-server pod: %v", err)
+	}
+
+	var models []tenant.ModelTokens
+	if opts.By != "assistant" {
+		if models, err = tenant.ParseModelTokens(queryPod(c, pod, tenant.ModelTokensSQL(opts.Tenant, since))); err != nil {
+			log.Fatalf("Failed to read model usage: %v", err)
+		}
+	}
+	var assistants []tenant.AssistantTokens
+	if opts.By != "model" {
+		if assistants, err = tenant.ParseAssistantTokens(queryPod(c, pod, tenant.AssistantTokensSQL(opts.Tenant, since))); err != nil {
+			log.Fatalf("Failed to read assistant usage: %v", err)
+		}
+	}
+
+	if opts.CSV {
+		if opts.By == "model" {
+			writeModelTokensCSV(models)
+		} else {
+			writeAssistantTokensCSV(assistants)
+		}
+		return
+	}
+
+	fmt.Printf("Token usage of %s since %s\n", opts.Tenant, since.UTC().Format("2006-01-02 15:04 MST"))
+	if opts.By != "assistant" {
+		fmt.Println()
+		printModelTokens(models)
+	}
+	if opts.By != "model" {
+		fmt.Println()
+		printAssistantTokens(assistants)
+	}
+}
+
+func printModelTokens(rows []tenant.ModelTokens) {
+	if len(rows) == 0 {
+		fmt.Println("No model usage recorded.")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "MODEL\tPROVIDER\tKIND\tINPUT\tOUTPUT\tCACHE READ\tUSERS\tCOST USD")
+	_, _ = fmt.Fprintln(w, "-----\t--------\t----\t-----\t------\t----------\t-----\t--------")
+	var in, out, cached int64
+	var cents float64
+	for _, r := range rows {
+		provider := r.Provider
+		if provider == "" {
+			provider = "-"
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%d\t%d\t%d\t%.2f\n",
+			r.Model, provider, r.Kind, r.InputTokens, r.OutputTokens, r.CacheReadTokens, r.Users, r.CostCents/100)
+		in, out, cached, cents = in+r.InputTokens, out+r.OutputTokens, cached+r.CacheReadTokens, cents+r.CostCents
+	}
+	_, _ = fmt.Fprintf(w, "TOTAL\t\t\t%d\t%d\t%d\t\t%.2f\n", in, out, cached, cents/100)
+	_ = w.Flush()
+}
+
+func printAssistantTokens(rows []tenant.AssistantTokens) {
+	if len(rows) == 0 {
+		fmt.Println("No chat messages.")
+		return
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "ASSISTANT\tCHATS\tMESSAGES\tINPUT\tOUTPUT")
+	_, _ = fmt.Fprintln(w, "---------\t-----\t--------\t-----\t------")
+	var chats, messages int
+	var in, out int64
+	for _, r := range rows {
+		_, _ = fmt.Fprintf(w, "%s\t%d\t%d\t%d\t%d\n", r.Assistant, r.ChatSessions, r.Messages, r.InputTokens, r.OutputTokens)
+		chats, messages, in, out = chats+r.ChatSessions, messages+r.Messages, in+r.InputTokens, out+r.OutputTokens
+	}
+	_, _ = fmt.Fprintf(w, "TOTAL\t%d\t%d\t%d\t%d\n", chats, messages, in, out)
+	_ = w.Flush()
+}
+
+func writeModelTokensCSV(rows []tenant.ModelTokens) {
+	w := csv.NewWriter(os.Stdout)
+	_ = w.Write([]string{"model", "provider", "kind", "input_tokens", "output_tokens", "cache_read_tokens", "users", "cost_usd"})
+	for _, r := range rows {
+		_ = w.Write([]string{
+			r.Model, r.Provider, r.Kind,
+			strconv.FormatInt(r.InputTokens, 10), strconv.FormatInt(r.OutputTokens, 10), strconv.FormatInt(r.CacheReadTokens, 10),
+			strconv.Itoa(r.Users), strconv.FormatFloat(r.CostCents/100, 'f', 6, 64),
+		})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		log.Fatalf("Failed to write CSV: %v", err)
+	}
+}
+
+func writeAssistantTokensCSV(rows []tenant.AssistantTokens) {
+	w := csv.NewWriter(os.Stdout)
+	_ = w.Write([]string{"assistant", "chat_sessions", "messages", "input_tokens", "output_tokens"})
+	for _, r := range rows {
+		_ = w.Write([]string{
+			r.Assistant, strconv.Itoa(r.ChatSessions), strconv.Itoa(r.Messages),
+			strconv.FormatInt(r.InputTokens, 10), strconv.FormatInt(r.OutputTokens, 10),
+		})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		log.Fatalf("Failed to write CSV: %v", err)
+	}
+}
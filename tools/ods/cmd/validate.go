@@ -0,0 +1,265 @@
+package cmd
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/paths"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/validate"
+)
+
+// ValidateOptions holds options for the validate command.
+type ValidateOptions struct {
+	Offline bool
+}
+
+// NewValidateCommand creates the validate command.
+func NewValidateCommand() *cobra.Command {
+	opts := &ValidateOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "validate [compose|helm|env]",
+		Short: "Statically check deployment manifests",
+		Long: `Statically check deployment manifests.
+
+Checks the repo's deployment configuration without deploying it:
+
+  compose  docker compose files: variables with no default that no env
+           template documents, host ports published twice in a file (or in
+           docker-compose.yml + docker-compose.dev.yml, as ods compose dev
+           runs them) and images missing from their registry
+  helm     charts: .Values paths templates use but values.yaml does not
+           define, keys in values-*.yaml overrides that values.yaml does not
+           define, and images missing from their registry
+  env      env templates: malformed lines, variables set twice and values
+           referencing variables the template does not document
+
+With no argument, runs all three. Registry lookups use docker's
+credentials; --offline skips them.
+
+Examples:
+  ods validate
+  ods validate compose
+  ods validate helm --offline`,
+		Args:      cobra.MaximumNArgs(1),
+		ValidArgs: []string{"compose", "helm", "env"},
+		Run: func(cmd *cobra.Command, args []string) {
+			runValidate(opts, args)
+		},
+	}
+
+	cmd.Flags().BoolVar(&opts.Offline, "offline", false, "Skip checking that images exist in their registries")
+
+	return cmd
+}
+
+func runValidate(opts *ValidateOptions, args []string) {
+	root, err := paths.GitRoot()
+	if err != nil {
+		log.Fatalf("Failed to find git root: %v", err)
+	}
+	composeDir := filepath.Join(root, "deployment", "docker_compose")
+	chartsDir := filepath.Join(root, "deployment", "helm", "charts")
+
+	targets := []string{"compose", "helm", "env"}
+	if len(args) == 1 {
+		targets = args
+	}
+
+	var issues []validate.Issue
+	var images []validate.Image
+	for _, target := range targets {
+		switch target {
+		case "compose":
+			found, imgs := validateCompose(composeDir)
+			issues = append(issues, found...)
+			images = append(images, imgs...)
+		case "helm":
+			found, imgs := validateHelm(chartsDir)
+			issues = append(issues, found...)
+			images = append(images, imgs...)
+		case "env":
+			_, found := loadEnvTemplates(composeDir)
+			issues = append(issues, found...)
+		default:
+			log.Fatalf("Unknown target %q (expected compose, helm or env)", target)
+		}
+	}
+
+	if !opts.Offline {
+		issues = append(issues, checkImages(images)...)
+	}
+
+	validate.Sort(issues)
+	for _, issue := range issues {
+		issue.File = relToRoot(root, issue.File)
+		fmt.Println(issue)
+	}
+	if len(issues) > 0 {
+		log.Fatalf("Found %d issue(s)", len(issues))
+	}
+	log.Infof("No issues found in %s", strings.Join(targets, ", "))
+}
+
+func relToRoot(root, path string) string {
+	if rel, err := filepath.Rel(root, path); err == nil {
+		return filepath.ToSlash(rel)
+	}
+	return path
+}
+
+// loadEnvTemplates parses every env template in dir.
+func loadEnvTemplates(dir string) ([]*validate.EnvTemplate, []validate.Issue) {
+	files, err := filepath.Glob(filepath.Join(dir, "env*.template"))
+	if err != nil {
+		log.Fatalf("Failed to list env templates: %v", err)
+	}
+	var templates []*validate.EnvTemplate
+	var issues []validate.Issue
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			log.Fatalf("Failed to read %s: %v", f, err)
+		}
+		t, found := validate.ParseEnvTemplate(f, data)
+		templates = append(templates, t)
+		issues = append(issues, found...)
+	}
+	return templates, issues
+}
+
+func validateCompose(dir string) ([]validate.Issue, []validate.Image) {
+	templates, _ := loadEnvTemplates(dir)
+	defined := map[string]bool{}
+	for _, v := range validate.HostVars {
+		defined[v] = true
+	}
+	for _, t := range templates {
+		for k := range t.Keys {
+			defined[k] = true
+		}
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "docker-compose*.yml"))
+	if err != nil {
+		log.Fatalf("Failed to list compose files: %v", err)
+	}
+	var issues []validate.Issue
+	var images []validate.Image
+	parsed := map[string]*validate.ComposeFile{}
+	for _, f := range files {
+		data, err := os.ReadFile(f)
+		if err != nil {
+			log.Fatalf("Failed to read %s: %v", f, err)
+		}
+		c, found := validate.ParseCompose(f, data)
+		issues = append(issues, found...)
+		if c == nil {
+			continue
+		}
+		parsed[filepath.Base(f)] = c
+		issues = append(issues, c.UndefinedVars(defined)...)
+		issues = append(issues, validate.PortConflicts(c)...)
+		images = append(images, c.Images()...)
+	}
+
+	// The stacks ods compose runs, beyond single files.
+	for _, stack := range [][]string{composeFiles("dev")} {
+		var files []*validate.ComposeFile
+		for _, name := range stack {
+			if c := parsed[name]; c != nil {
+				files = append(files, c)
+			}
+		}
+		if len(files) == len(stack) {
+			issues = append(issues, validate.PortConflicts(files...)...)
+		}
+	}
+	return issues, images
+}
+
+func validateHelm(dir string) ([]validate.Issue, []validate.Image) {
+	charts, err := filepath.Glob(filepath.Join(dir, "*", "Chart.yaml"))
+	if err != nil {
+		log.Fatalf("Failed to list charts: %v", err)
+	}
+	var issues []validate.Issue
+	var images []validate.Image
+	for _, chartFile := range charts {
+		chartDir := filepath.Dir(chartFile)
+		valuesFile := filepath.Join(chartDir, "values.yaml")
+		chartData, err := os.ReadFile(chartFile)
+		if err != nil {
+			log.Fatalf("Failed to read %s: %v", chartFile, err)
+		}
+		valuesData, err := os.ReadFile(valuesFile)
+		if os.IsNotExist(err) {
+			continue
+		}
+		if err != nil {
+			log.Fatalf("Failed to read %s: %v", valuesFile, err)
+		}
+
+		h, found := validate.ParseHelmChart(chartFile, chartData, valuesFile, valuesData)
+		issues = append(issues, found...)
+		if h == nil {
+			continue
+		}
+		images = append(images, h.Images()...)
+
+		templatesDir := filepath.Join(chartDir, "templates")
+		_ = filepath.WalkDir(templatesDir, func(path string, d fs.DirEntry, err error) error {
+			if err != nil || d.IsDir() {
+				return err
+			}
+			data, err := os.ReadFile(path)
+			if err != nil {
+				return err
+			}
+			issues = append(issues, h.CheckTemplate(path, data)...)
+			return nil
+		})
+
+		overrides, _ := filepath.Glob(filepath.Join(chartDir, "values-*.yaml"))
+		ci, _ := filepath.Glob(filepath.Join(chartDir, "ci", "*.yaml"))
+		for _, f := range append(overrides, ci...) {
+			data, err := os.ReadFile(f)
+			if err != nil {
+				log.Fatalf("Failed to read %s: %v", f, err)
+			}
+			issues = append(issues, h.UnknownOverrides(f, data)...)
+		}
+	}
+	return issues, images
+}
+
+// checkImages looks up every distinct image in its registry.
+func checkImages(images []validate.Image) []validate.Issue {
+	unique := validate.UniqueImages(images)
+	log.Infof("Checking %d image(s) in their registries...", len(unique))
+
+	var issues []validate.Issue
+	var unreachable []string
+	for _, img := range unique {
+		ok, err := validate.ImageExists(img.Ref)
+		switch {
+		case err != nil:
+			log.Debugf("Could not look up %s: %v", img.Ref, err)
+			unreachable = append(unreachable, img.Ref)
+		case !ok:
+			issues = append(issues, validate.Issue{File: img.File, Line: img.Line, Kind: validate.KindMissingImage, Message: img.Ref + " does not exist in its registry"})
+		}
+	}
+	if len(unreachable) > 0 {
+		sort.Strings(unreachable)
+		log.Warnf("Could not check %d image(s) (offline or not logged in?): %s", len(unreachable), strings.Join(unreachable, ", "))
+	}
+	return issues
+}
@@ -0,0 +1,309 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/auditlog"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/backups"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/kube"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/prompt"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/render"
+)
+
+// VerifyBackupsOptions holds options for the verify-backups command.
+type VerifyBackupsOptions struct {
+	Context string
+	Limit   int
+	JSON    bool
+
+	// TestRestore is the schema to test-restore; empty skips the restore.
+	TestRestore   string
+	Source        string
+	InstanceClass string
+	Keep          bool
+	Yes           bool
+}
+
+// NewVerifyBackupsCommand creates the verify-backups command.
+func NewVerifyBackupsCommand() *cobra.Command {
+	opts := &VerifyBackupsOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "verify-backups",
+		Short: "Check an environment's backups are recent and restorable",
+		Long: `List an environment's recent backups, check the newest of each source is
+within the recency policy, and optionally prove a database snapshot can be
+restored.
+
+Sources are configured per context under "backups" in the ods config
+(~/.config/onyx-dev/config.json):
+
+  "backups": {
+    "max_age": "26h",
+    "environments": {
+      "prod": [
+        {"name": "postgres", "type": "rds", "id": "onyx-prod"},
+        {"name": "vespa", "type": "ebs", "id": "app=vespa"},
+        {"name": "file-store", "type": "s3", "id": "s3://onyx-prod-backups/file-store/", "max_age": "48h"}
+      ]
+    }
+  }
+
+Types: rds (DB instance snapshots), rds-cluster (Aurora cluster snapshots),
+ebs (volume snapshots with a tag=value) and s3 (objects under a prefix,
+one backup per top-level folder). AWS calls use the context's region and
+profile.
+
+--test-restore restores the newest snapshot of an rds source to a scratch
+instance in the same subnets and security groups, then compares a schema
+(public, or a tenant's) in the restore with the live one from an api-server
+pod: every table present, rows restored, an alembic revision. The scratch
+instance is deleted afterwards unless --keep is given. Restores take 10-30
+minutes and are billed while they run.
+
+Exits non-zero if any source is stale or the restore fails.
+
+Examples:
+  ods verify-backups -c prod
+  ods verify-backups -c prod --json
+  ods verify-backups -c prod --test-restore public
+  ods verify-backups -c data_plane --test-restore tenant_1234abcd --instance-class db.t4g.medium`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			runVerifyBackups(opts)
+		},
+	}
+
+	cmd.Flags().StringVarP(&opts.Context, "context", "c", "data_plane", "cluster context name (maps to KUBE_CTX_<NAME> env var)")
+	cmd.Flags().IntVar(&opts.Limit, "limit", 5, "Recent backups to list per source")
+	cmd.Flags().BoolVar(&opts.JSON, "json", false, "Print the checks as JSON")
+	cmd.Flags().StringVar(&opts.TestRestore, "test-restore", "", "Restore the newest database snapshot to a scratch instance and verify this schema")
+	cmd.Flags().StringVar(&opts.Source, "source", "", "rds source to test-restore (default: the first one configured)")
+	cmd.Flags().StringVar(&opts.InstanceClass, "instance-class", "", "Instance class of the scratch instance (default: the source's)")
+	cmd.Flags().BoolVar(&opts.Keep, "keep", false, "Keep the scratch instance after the test restore")
+	cmd.Flags().BoolVar(&opts.Yes, "yes", false, "Skip the confirmation prompt for the test restore")
+
+	return cmd
+}
+
+func runVerifyBackups(opts *VerifyBackupsOptions) {
+	if opts.TestRestore != "" && opts.TestRestore != "public" {
+		validateTenantArg(opts.TestRestore)
+	}
+	cfg := loadODSConfig()
+	sources := cfg.Backups.Environments[opts.Context]
+	if len(sources) == 0 {
+		log.Fatalf("No backup sources are configured for %s; add them under backups.environments.%s in the ods config (see ods verify-backups --help)", opts.Context, opts.Context)
+	}
+
+	c := clusterFromEnv(opts.Context)
+	run := awsRunner(c)
+
+	now := time.Now()
+	var checks []backups.Check
+	for _, src := range sources {
+		maxAge, err := backups.MaxAge(cfg.Backups, src)
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		log.Infof("Listing %s backups (%s %s)...", src.Name, src.Type, src.ID)
+		list, err := backups.List(run, src)
+		check := backups.Evaluate(src, list, maxAge, now)
+		if err != nil {
+			check.Detail = err.Error()
+		}
+		checks = append(checks, check)
+	}
+
+	if opts.JSON {
+		if err := render.JSON(os.Stdout, checks); err != nil {
+			log.Fatalf("Failed to marshal checks: %v", err)
+		}
+	} else {
+		printBackupChecks(checks, opts.Limit, now)
+	}
+
+	failed := 0
+	for _, check := range checks {
+		if !check.OK {
+			failed++
+		}
+	}
+
+	if opts.TestRestore != "" {
+		if err := testRestore(c, run, checks, opts); err != nil {
+			log.Errorf("Test restore failed: %v", err)
+			failed++
+		}
+	}
+	if failed > 0 {
+		log.Fatalf("%d backup check(s) failed", failed)
+	}
+}
+
+// awsRunner runs the aws CLI in the cluster's region and profile.
+func awsRunner(c *kube.Cluster) backups.Runner {
+	return c.AWS
+}
+
+func printBackupChecks(checks []backups.Check, limit int, now time.Time) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "SOURCE\tTYPE\tPOLICY\tSTATUS\tDETAIL")
+	for _, c := range checks {
+		status := "PASS"
+		if !c.OK {
+			status = "FAIL"
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", c.Source.Name, c.Source.Type, c.MaxAge, status, c.Detail)
+	}
+	_ = w.Flush()
+
+	for _, c := range checks {
+		if len(c.Backups) == 0 || limit <= 0 {
+			continue
+		}
+		fmt.Printf("\n%s (%d backups):\n", c.Source.Name, len(c.Backups))
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		for i, b := range c.Backups {
+			if i == limit {
+				break
+			}
+			size := ""
+			if b.SizeBytes > 0 {
+				size = fmt.Sprintf("%.1f GiB", float64(b.SizeBytes)/(1<<30))
+			}
+			_, _ = fmt.Fprintf(w, "  %s\t%s\t%s ago\t%s\t%s\n", b.ID, b.Created.UTC().Format(time.RFC3339),
+				now.Sub(b.Created).Round(time.Minute), b.Status, size)
+		}
+		_ = w.Flush()
+	}
+}
+
+// testRestore restores the newest snapshot of an rds source to a scratch
+// instance and compares a schema in it with the live one.
+func testRestore(c *kube.Cluster, run backups.Runner, checks []backups.Check, opts *VerifyBackupsOptions) error {
+	var check *backups.Check
+	for i := range checks {
+		src := checks[i].Source
+		if src.Type == backups.TypeRDS && (opts.Source == "" || opts.Source == src.Name) {
+			check = &checks[i]
+			break
+		}
+	}
+	if check == nil {
+		if opts.Source != "" {
+			return fmt.Errorf("no rds source named %q (test restores of %s sources are not supported)", opts.Source, backups.TypeRDSCluster)
+		}
+		return fmt.Errorf("no rds source to restore from (test restores of %s sources are not supported)", backups.TypeRDSCluster)
+	}
+	if check.Latest == nil {
+		return fmt.Errorf("%s has no complete snapshot to restore", check.Source.Name)
+	}
+	snapshot := check.Latest.ID
+	scratch := backups.ScratchID("verify", check.Source.ID, time.Now())
+	auditCtx := c.Name + "/" + c.Namespace
+
+	if !opts.Yes && !prompt.Confirm(fmt.Sprintf("Restore %s to a new RDS instance %s (billed until deleted)? (yes/no): ", snapshot, scratch)) {
+		return fmt.Errorf("aborted")
+	}
+	if err := auditlog.Record(auditlog.Entry{
+		Action:  "backups.test-restore",
+		Context: auditCtx,
+		Target:  snapshot,
+		Detail:  fmt.Sprintf("scratch=%s schema=%s", scratch, opts.TestRestore),
+	}); err != nil {
+		return fmt.Errorf("refusing to restore without an audit record: %w", err)
+	}
+
+	source, err := backups.DescribeInstance(run, check.Source.ID)
+	if err != nil {
+		return fmt.Errorf("failed to describe %s: %w", check.Source.ID, err)
+	}
+	log.Infof("Restoring %s to %s...", snapshot, scratch)
+	restored, cleanup, err := createScratchInstance(run, scratch, backups.RestoreArgs(snapshot, scratch, source, opts.InstanceClass), opts.Keep)
+	if cleanup != nil {
+		defer cleanup()
+	}
+	if err != nil {
+		return err
+	}
+
+	pod, err := c.FindPod("api-server")
+	if err != nil {
+		return fmt.Errorf("failed to find api-server pod: %w", err)
+	}
+	log.Infof("Comparing %s in the restore with the live database from %s...", opts.TestRestore, pod)
+	out, err := c.RunPython(pod, backups.VerifyRestoreScript, restored.Endpoint, strconv.Itoa(restored.Port), opts.TestRestore)
+	if err != nil {
+		return err
+	}
+	report, err := backups.ParseRestoreReport(out)
+	if err != nil {
+		return err
+	}
+
+	var restoredRows, liveRows int64
+	for _, t := range report.Tables {
+		restoredRows += t.Restored
+		liveRows += t.Live
+	}
+	fmt.Printf("\nTest restore of %s from %s (%s old):\n", report.Schema, snapshot, time.Since(check.Latest.Created).Round(time.Minute))
+	fmt.Printf("  revision  %s (live: %s)\n", revisionLabel(report.Revision), revisionLabel(report.LiveRevision))
+	fmt.Printf("  tables    %d\n", len(report.Tables))
+	fmt.Printf("  rows      %d (live: %d)\n", restoredRows, liveRows)
+	if problems := report.Problems(); len(problems) > 0 {
+		for _, p := range problems {
+			log.Error(p)
+		}
+		return fmt.Errorf("the restore of %s is incomplete", report.Schema)
+	}
+	log.Infof("Restore of %s verified", report.Schema)
+	return nil
+}
+
+// createScratchInstance creates the RDS instance scratch with the aws
+// arguments args and waits until it is available. Once the instance exists
+// the returned cleanup is non-nil, even with an error: it deletes the
+// instance or, with keep, says how to.
+func createScratchInstance(run backups.Runner, scratch string, args []string, keep bool) (*backups.Instance, func(), error) {
+	if _, err := run(args...); err != nil {
+		return nil, nil, err
+	}
+	cleanup := func() {
+		log.Infof("Deleting %s...", scratch)
+		if _, err := run(backups.DeleteArgs(scratch)...); err != nil {
+			log.Errorf("Failed to delete the scratch instance %s, delete it by hand: %v", scratch, err)
+		}
+	}
+	if keep {
+		cleanup = func() {
+			log.Infof("Kept %s; delete it with: aws rds delete-db-instance --db-instance-identifier %s --skip-final-snapshot", scratch, scratch)
+		}
+	}
+
+	// The aws waiter gives up after 30 minutes; large restores take longer.
+	start := time.Now()
+	for attempt := 1; ; attempt++ {
+		log.Infof("Waiting for %s to become available (%s so far)...", scratch, time.Since(start).Round(time.Minute))
+		_, err := run(backups.WaitArgs(scratch)...)
+		if err == nil {
+			break
+		}
+		if attempt == 3 {
+			return nil, cleanup, fmt.Errorf("%s did not become available: %w", scratch, err)
+		}
+	}
+	inst, err := backups.DescribeInstance(run, scratch)
+	if err != nil {
+		return nil, cleanup, err
+	}
+	log.Infof("%s is available after %s", scratch, time.Since(start).Round(time.Second))
+	return inst, cleanup, nil
+}
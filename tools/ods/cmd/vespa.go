@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/auditlog"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/prompt"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/reindex"
+)
+
+// VespaOptions holds options shared by the vespa subcommands.
+type VespaOptions struct {
+	Context string
+}
+
+// VespaReindexOptions holds options for the vespa reindex command.
+type VespaReindexOptions struct {
+	Tenant    string
+	Connector int
+	Interval  time.Duration
+	NoWait    bool
+	Yes       bool
+	Notify    string
+}
+
+// NewVespaCommand creates the parent vespa command.
+func NewVespaCommand() *cobra.Command {
+	opts := &VespaOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "vespa",
+		Short: "Operate on the Vespa document index",
+		Long: `Operate on the Vespa document index of a deployed data plane.
+
+Requires: AWS SSO login, kubectl access to the EKS cluster.`,
+	}
+
+	cmd.PersistentFlags().StringVarP(&opts.Context, "context", "c", "data_plane", "cluster context name (maps to KUBE_CTX_<NAME> env var)")
+
+	cmd.AddCommand(newVespaReindexCommand(opts))
+
+	return cmd
+}
+
+func newVespaReindexCommand(parent *VespaOptions) *cobra.Command {
+	opts := &VespaReindexOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "reindex",
+		Short: "Re-embed and reindex a tenant's documents",
+		Long: `Re-embed and reindex a tenant's documents from scratch.
+
+Marks every active connector of the tenant (or only --connector) for a full
+reindex, the same as the admin "Re-index" button, then follows the resulting
+index attempts and prints documents indexed and throughput until they finish.
+Interrupting only stops watching; indexing carries on in the workers.
+
+Asks for confirmation on production contexts unless --yes is passed, and
+records the reindex in the local audit log.
+
+Examples:
+  ods vespa reindex --tenant tenant_abcd1234
+  ods vespa reindex --tenant tenant_abcd1234 --connector 12 -c staging
+  ods vespa reindex --tenant tenant_abcd1234 --no-wait
+  ods vespa reindex --tenant tenant_abcd1234 --notify slack:#oncall`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			runVespaReindex(parent, opts)
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.Tenant, "tenant", "", "Tenant ID to reindex (required)")
+	cmd.Flags().IntVar(&opts.Connector, "connector", 0, "Only reindex this connector ID")
+	cmd.Flags().DurationVar(&opts.Interval, "interval", 15*time.Second, "How often to poll progress")
+	cmd.Flags().BoolVar(&opts.NoWait, "no-wait", false, "Trigger the reindex and exit without watching progress")
+	cmd.Flags().BoolVar(&opts.Yes, "yes", false, "Skip the confirmation prompt")
+	addNotifyFlag(cmd, &opts.Notify)
+	_ = cmd.MarkFlagRequired("tenant")
+
+	return cmd
+}
+
+func runVespaReindex(parent *VespaOptions, opts *VespaReindexOptions) {
+	validateTenantArg(opts.Tenant)
+	if opts.Connector < 0 {
+		log.Fatalf("Invalid connector ID %d", opts.Connector)
+	}
+	if opts.Interval < time.Second {
+		log.Fatal("--interval must be at least 1s")
+	}
+
+	c := clusterFromEnv(parent.Context)
+	if err := c.EnsureContext(); err != nil {
+		log.Fatalf("Failed to ensure cluster context: %v", err)
+	}
+	auditCtx := c.Name + "/" + c.Namespace
+
+	target := opts.Tenant
+	if opts.Connector != 0 {
+		target += " connector " + strconv.Itoa(opts.Connector)
+	}
+	if !opts.Yes && isProductionContext(parent.Context) {
+		if !prompt.Confirm(fmt.Sprintf("Reindex %s in %s from scratch? (yes/no): ", target, auditCtx)) {
+			log.Info("Aborted.")
+			return
+		}
+	}
+
+	var notifier *runNotifier
+	if !opts.NoWait {
+		notifier = startNotifier(opts.Notify)
+	}
+
+	log.Info("Finding api-server pod...")
+	pod, err := c.FindPod("api-server")
+	if err != nil {
+		log.Fatalf("Failed to find api-server pod: %v", err)
+	}
+
+	if err := auditlog.Record(auditlog.Entry{
+		Action:  "vespa.reindex",
+		Context: auditCtx,
+		Target:  target,
+	}); err != nil {
+		log.Fatalf("Refusing to reindex without an audit record: %v", err)
+	}
+
+	log.Infof("Triggering reindex of %s...", target)
+	tr, err := reindex.Start(c, pod, opts.Tenant, opts.Connector)
+	if err != nil {
+		log.Fatalf("Failed to trigger reindex of %s: %v", target, err)
+	}
+	log.Infof("Marked %d connector/credential pair(s) for reindexing", len(tr.CCPairIDs))
+
+	if opts.NoWait {
+		return
+	}
+	if !reindex.ValidStartedAt(tr.StartedAt) {
+		log.Fatalf("Unexpected start time %q from reindex script", tr.StartedAt)
+	}
+
+	sql := reindex.ProgressSQL(opts.Tenant, tr.CCPairIDs, tr.StartedAt)
+	start := time.Now()
+	lastDocs, lastPoll := 0, start
+	for {
+		time.Sleep(opts.Interval)
+
+		p, err := reindex.ParseProgress(queryPod(c, pod, sql))
+		if err != nil {
+			log.Fatalf("Failed to read progress: %v", err)
+		}
+		now := time.Now()
+		rate := reindex.Throughput(lastDocs, p.DocsIndexed, now.Sub(lastPoll))
+		lastDocs, lastPoll = p.DocsIndexed, now
+
+		if p.Total() == 0 {
+			fmt.Printf("[%s] waiting for index attempts to be scheduled...\n", formatElapsed(now.Sub(start)))
+			continue
+		}
+		fmt.Printf("[%s] attempts %d/%d done, %d failed | batches %d/%d | %d docs | %.0f docs/min\n",
+			formatElapsed(now.Sub(start)), p.Total()-p.Running(), p.Total(), p.Failed(),
+			p.BatchesDone, p.BatchesExpected, p.DocsIndexed, rate)
+
+		if p.Running() == 0 {
+			overall := reindex.Throughput(0, p.DocsIndexed, now.Sub(start))
+			if p.Failed() > 0 {
+				log.Fatalf("Reindex of %s finished with %d failed attempt(s); see `ods events` and the docprocessing worker logs", target, p.Failed())
+			}
+			summary := fmt.Sprintf("Reindex of %s in %s complete: %d docs in %s (%.0f docs/min)", target, auditCtx, p.DocsIndexed, formatElapsed(now.Sub(start)), overall)
+			log.Info(summary)
+			notifier.Done(summary)
+			return
+		}
+	}
+}
+
+func formatElapsed(d time.Duration) string {
+	return d.Truncate(time.Second).String()
+}
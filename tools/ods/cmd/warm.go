@@ -0,0 +1,175 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/api"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/report"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/warm"
+)
+
+// WarmOptions holds options for the warm command.
+type WarmOptions struct {
+	APISessionOptions
+	From        string
+	Top         int
+	Since       string
+	Chat        bool
+	Persona     int
+	Parallel    int
+	ShowQueries bool
+}
+
+// NewWarmCommand creates the warm command.
+func NewWarmCommand() *cobra.Command {
+	opts := &WarmOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "warm",
+		Short: "Replay a tenant's top recent queries to warm caches after a deploy",
+		Long: `Replay a tenant's top recent queries to warm caches after a deploy.
+
+Reads the --top most frequent user chat messages of the last --since from the
+tenant's query history (on --from, default: the same context), then sends
+each through the admin search of the environment -c points at, so that
+model servers, Vespa and caches are warm and errors surface before users hit
+them. --chat also asks each one in a new chat session (deleted afterwards),
+which warms the LLM path but spends tokens.
+
+Prints a line per failed query, searches that returned nothing (after a
+reindex often a sign of missing documents), the slowest queries and p50/p95
+latencies, and exits non-zero if any query failed. Query text is customer
+content and is only printed with --show-queries.
+
+The server and auth are chosen as for ` + "`ods curl`" + `: with --tenant the
+queries run as the tenant's first admin (--reason required). On a
+single-tenant deployment omit --tenant; history comes from the public schema
+and $ONYX_API_KEY authenticates.
+
+Examples:
+  ods warm -c staging --tenant tenant_abcd1234 --reason DEPLOY-42
+  ods warm -c prod --tenant tenant_abcd1234 --reason DEPLOY-42 --top 100 --since 3d --chat
+  ods warm -c canary --from prod --tenant tenant_abcd1234 --reason DEPLOY-42`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			runWarm(opts)
+		},
+	}
+
+	addAPISessionFlags(cmd, &opts.APISessionOptions)
+	cmd.Flags().StringVar(&opts.From, "from", "", "Context to read the query history from (default: --context)")
+	cmd.Flags().IntVar(&opts.Top, "top", 50, "Number of most frequent queries to replay")
+	cmd.Flags().StringVar(&opts.Since, "since", "7d", "How far back to look in the query history (e.g. 7d, 12h)")
+	cmd.Flags().BoolVar(&opts.Chat, "chat", false, "Also ask each query in chat (spends LLM tokens)")
+	cmd.Flags().IntVar(&opts.Persona, "persona", 0, "Persona (assistant) ID to chat with")
+	cmd.Flags().IntVar(&opts.Parallel, "parallel", 4, "Number of queries to replay at once")
+	cmd.Flags().BoolVar(&opts.ShowQueries, "show-queries", false, "Print query text (customer content)")
+
+	return cmd
+}
+
+func runWarm(opts *WarmOptions) {
+	if opts.Top < 1 {
+		log.Fatalf("--top must be positive")
+	}
+	lookback, err := report.ParseLookback(opts.Since)
+	if err != nil {
+		log.Fatalf("Invalid --since: %v", err)
+	}
+	schema := "public"
+	if opts.Tenant != "" {
+		validateTenantArg(opts.Tenant)
+		schema = opts.Tenant
+	}
+
+	from := opts.From
+	if from == "" {
+		from = opts.Context
+	}
+	if from == localContext {
+		log.Fatal("The query history is read from a cluster; pass --from <context> to warm the local stack")
+	}
+	hc := clusterFromEnv(from)
+	if err := hc.EnsureContext(); err != nil {
+		log.Fatalf("Failed to ensure cluster context: %v", err)
+	}
+	log.Info("Finding api-server pod...")
+	pod, err := hc.FindPod("api-server")
+	if err != nil {
+		log.Fatalf("Failed to find api-server pod: %v", err)
+	}
+	log.Infof("Reading the top %d queries of %s since %s from %s...", opts.Top, schema, opts.Since, from)
+	queries, err := warm.ParseQueries(queryPod(hc, pod, warm.TopQueriesSQL(schema, time.Now().Add(-lookback), opts.Top)))
+	if err != nil {
+		log.Fatalf("Failed to read the query history: %v", err)
+	}
+	if len(queries) == 0 {
+		log.Infof("%s has no queries since %s; nothing to warm", schema, opts.Since)
+		return
+	}
+
+	session := openAPISession(&opts.APISessionOptions, "warm.impersonate")
+	defer session.Close()
+	log.Infof("Replaying %d queries against %s", len(queries), session.Target)
+
+	results := warm.Run(api.New(session.Client), queries, warm.Options{
+		Chat:     opts.Chat,
+		Persona:  opts.Persona,
+		Parallel: opts.Parallel,
+		OnResult: func(r warm.Result) {
+			if r.Error != "" {
+				log.Warnf("Query %s failed: %s", warmQueryLabel(r, opts.ShowQueries), r.Error)
+			}
+		},
+	})
+
+	s := warm.Summarize(results)
+	printWarmSummary(s, results, opts)
+	if s.Failed > 0 {
+		session.Close()
+		log.Fatalf("%d of %d queries failed", s.Failed, s.Queries)
+	}
+}
+
+func printWarmSummary(s warm.Summary, results []warm.Result, opts *WarmOptions) {
+	fmt.Println()
+	fmt.Printf("Replayed %d queries: %d failed, %d returned no documents\n", s.Queries, s.Failed, s.Empty)
+	fmt.Printf("Search latency: p50 %dms, p95 %dms\n", s.SearchP50, s.SearchP95)
+	if opts.Chat {
+		fmt.Printf("Chat latency:   p50 %dms, p95 %dms\n", s.ChatP50, s.ChatP95)
+	}
+
+	if s.Empty > 0 {
+		var empty []string
+		for _, r := range results {
+			if r.Error == "" && r.Docs == 0 {
+				empty = append(empty, warmQueryLabel(r, opts.ShowQueries))
+			}
+		}
+		fmt.Printf("\nNo documents for: %s\n", strings.Join(empty, ", "))
+	}
+
+	fmt.Println("\nSlowest searches:")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "  QUERY\tASKED\tSEARCH\tDOCS")
+	for _, r := range s.Slowest {
+		_, _ = fmt.Fprintf(w, "  %s\t%d\t%dms\t%d\n", warmQueryLabel(r, opts.ShowQueries), r.Query.Count, r.SearchMS, r.Docs)
+	}
+	_ = w.Flush()
+}
+
+// warmQueryLabel names a replayed query by its rank in the history, with
+// its text only when asked for.
+func warmQueryLabel(r warm.Result, showText bool) string {
+	if !showText {
+		return fmt.Sprintf("#%d", r.Index)
+	}
+	return fmt.Sprintf("#%d %q", r.Index, truncateQuery(r.Query.Text))
+}
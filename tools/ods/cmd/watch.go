@@ -0,0 +1,36 @@
+package cmd
+
+import (
+	"os"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/watch"
+)
+
+// defaultWatchInterval is the refresh interval of a bare --watch.
+const defaultWatchInterval = 2 * time.Second
+
+// addWatchFlag registers --watch on a status-like command.
+func addWatchFlag(cmd *cobra.Command, interval *time.Duration) {
+	cmd.Flags().DurationVar(interval, "watch", 0, "Refresh the output in place every interval, highlighting changes, until interrupted (--watch alone: every 2s, or e.g. --watch=10s)")
+	cmd.Flags().Lookup("watch").NoOptDefVal = defaultWatchInterval.String()
+}
+
+// runWatchable prints frame once, ending through log.Fatal if it fails, or
+// with a --watch interval keeps redrawing it, showing failures in place.
+func runWatchable(interval time.Duration, frame func() error) {
+	if interval <= 0 {
+		if err := frame(); err != nil {
+			log.Fatalf("%v", err)
+		}
+		return
+	}
+	title := "ods " + strings.Join(os.Args[1:], " ")
+	if err := watch.Run(interval, title, frame); err != nil {
+		log.Fatalf("Watch failed: %v", err)
+	}
+}
@@ -2,10 +2,8 @@ package cmd
 
 import (
 	"encoding/json"
-	"errors"
 	"fmt"
 	"os"
-	"os/exec"
 	"path/filepath"
 	"sort"
 	"strings"
@@ -14,14 +12,24 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/onyx-dot-app/onyx/tools/ods/internal/paths"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/runner"
 )
 
 type webPackageJSON struct {
 	Scripts map[string]string `json:"scripts"`
 }
 
+// WebOptions holds options for the web command.
+type WebOptions struct {
+	NoInstall   bool
+	WithBackend bool
+	BackendURL  string
+}
+
 // NewWebCommand creates a command that runs bun scripts from the web directory.
 func NewWebCommand() *cobra.Command {
+	opts := &WebOptions{}
+
 	cmd := &cobra.Command{
 		Use:   "web <script> [args...]",
 		Short: "Run web/package.json bun scripts",
@@ -34,39 +42,57 @@ func NewWebCommand() *cobra.Command {
 			return webScriptNames(), cobra.ShellCompDirectiveNoFileComp
 		},
 		Run: func(cmd *cobra.Command, args []string) {
-			runWebScript(args)
+			runWebScript(args, opts)
 		},
 	}
 	cmd.Flags().SetInterspersed(false)
+	cmd.Flags().BoolVar(&opts.NoInstall, "no-install", false, "Don't install dependencies when node_modules is missing or stale")
+	cmd.Flags().BoolVar(&opts.WithBackend, "with-backend", false, "Ensure the API server is reachable (starting it via compose if local) and point the script at it")
+	cmd.Flags().StringVar(&opts.BackendURL, "backend-url", defaultBackendURL, "API server URL used with --with-backend (e.g. a port-forwarded remote)")
 
 	return cmd
 }
 
-func runWebScript(args []string) {
+func runWebScript(args []string, opts *WebOptions) {
 	webDir, err := webDir()
 	if err != nil {
 		log.Fatalf("Failed to find web directory: %v", err)
 	}
 
+	wrapper := resolveNodeWrapper(nil, webDir)
+
 	nodeModules := filepath.Join(webDir, "node_modules")
-	if needsInstall, reason := nodeModulesNeedsInstall(nodeModules); needsInstall {
-		log.Infof("%s, running bun install --frozen-lockfile...", reason)
-		installCmd := exec.Command("bun", "install", "--frozen-lockfile")
-		installCmd.Dir = webDir
-		installCmd.Stdout = os.Stdout
-		installCmd.Stderr = os.Stderr
-		installCmd.Stdin = os.Stdin
-		if err := installCmd.Run(); err != nil {
-			log.Fatalf("Failed to run bun install: %v", err)
+	lockfile := filepath.Join(webDir, "bun.lock")
+	if needsInstall, reason := nodeModulesNeedsInstall(nodeModules, lockfile); needsInstall {
+		if opts.NoInstall {
+			log.Warnf("%s; skipping install because --no-install was passed", reason)
+		} else {
+			log.Infof("%s, running bun install --frozen-lockfile...", reason)
+			installCmd := wrapCommand(wrapper, "bun", "install", "--frozen-lockfile")
+			installCmd.Dir = webDir
+			installCmd.Stdout = os.Stdout
+			installCmd.Stderr = os.Stderr
+			installCmd.Stdin = os.Stdin
+			if err := runner.Default.Run(installCmd); err != nil {
+				log.Fatalf("Failed to run bun install: %v", err)
+			}
+			if err := stampLockfileHash(nodeModules, lockfile); err != nil {
+				log.Debugf("Failed to record lockfile hash: %v", err)
+			}
 		}
 	}
 
 	scriptName := args[0]
-	scriptArgs := args[1:]
+	scriptArgs := extractWebBackendFlags(args[1:], opts)
 	if len(scriptArgs) > 0 && scriptArgs[0] == "--" {
 		scriptArgs = scriptArgs[1:]
 	}
 
+	var backendEnv []string
+	if opts.WithBackend {
+		backendEnv = ensureWebBackend(opts.BackendURL)
+	}
+
 	bunArgs := []string{"run", scriptName}
 	if len(scriptArgs) > 0 {
 		// bun requires "--" to forward flags to the underlying script.
@@ -75,44 +101,25 @@ func runWebScript(args []string) {
 	}
 	log.Debugf("Running in %s: bun %v", webDir, bunArgs)
 
-	webCmd := exec.Command("bun", bunArgs...)
+	webCmd := wrapCommand(wrapper, "bun", bunArgs...)
 	webCmd.Dir = webDir
 	webCmd.Stdout = os.Stdout
 	webCmd.Stderr = os.Stderr
 	webCmd.Stdin = os.Stdin
+	if len(backendEnv) > 0 {
+		webCmd.Env = append(os.Environ(), backendEnv...)
+	}
 
-	if err := webCmd.Run(); err != nil {
+	if err := runner.Default.Run(webCmd); err != nil {
 		// For wrapped commands, preserve the child process's exit code and
 		// avoid duplicating already-printed stderr output.
-		var exitErr *exec.ExitError
-		if errors.As(err, &exitErr) {
-			if code := exitErr.ExitCode(); code != -1 {
-				os.Exit(code)
-			}
+		if code, ok := runner.ExitCode(err); ok && code != -1 {
+			exitWith(code)
 		}
 		log.Fatalf("Failed to run bun: %v", err)
 	}
 }
 
-// nodeModulesNeedsInstall reports whether bun install should be run, along with
-// a human-readable reason. Install is needed when node_modules is missing or
-// exists but is empty.
-func nodeModulesNeedsInstall(nodeModules string) (bool, string) {
-	entries, err := os.ReadDir(nodeModules)
-	if errors.Is(err, os.ErrNotExist) {
-		return true, "node_modules not found"
-	}
-	if err != nil {
-		// Couldn't read the directory for some other reason; let bun install
-		// attempt to sort it out rather than silently skipping.
-		return true, fmt.Sprintf("could not read node_modules (%v)", err)
-	}
-	if len(entries) == 0 {
-		return true, "node_modules is empty"
-	}
-	return false, ""
-}
-
 func webScriptNames() []string {
 	scripts, err := loadWebScripts()
 	if err != nil {
@@ -130,10 +137,18 @@ func webScriptNames() []string {
 func webHelpDescription() string {
 	description := `Run bun scripts from web/package.json.
 
+Before running, the installed Node version is checked against web/.nvmrc (or
+package.json engines.node), and dependencies are installed when node_modules is
+missing or was installed from a different bun.lock. Pass --no-install before the
+script name to skip the install.
+
 Examples:
   ods web dev
   ods web lint
-  ods web test --watch`
+  ods web test --watch
+  ods web --no-install lint
+  ods web dev --with-backend
+  ods web dev --with-backend --backend-url http://localhost:18080`
 
 	scripts := webScriptNames()
 	if len(scripts) == 0 {
@@ -0,0 +1,114 @@
+package cmd
+
+import (
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/health"
+)
+
+const (
+	defaultBackendURL       = "http://localhost:8080"
+	backendStartupTimeout   = 3 * time.Minute
+	backendStartupInterval  = 2 * time.Second
+	composeAPIServerService = "api_server"
+)
+
+// extractWebBackendFlags pulls --with-backend and --backend-url out of the
+// script arguments so `ods web dev --with-backend` works even though flag
+// parsing stops at the script name. Anything after a literal "--" is left for
+// the script.
+func extractWebBackendFlags(scriptArgs []string, opts *WebOptions) []string {
+	var rest []string
+	for i := 0; i < len(scriptArgs); i++ {
+		arg := scriptArgs[i]
+		switch {
+		case arg == "--":
+			return append(rest, scriptArgs[i:]...)
+		case arg == "--with-backend":
+			opts.WithBackend = true
+		case arg == "--backend-url" && i+1 < len(scriptArgs):
+			opts.BackendURL = scriptArgs[i+1]
+			i++
+		case strings.HasPrefix(arg, "--backend-url="):
+			opts.BackendURL = strings.TrimPrefix(arg, "--backend-url=")
+		default:
+			rest = append(rest, arg)
+		}
+	}
+	return rest
+}
+
+// isLocalURL reports whether u points at this machine, i.e. a backend that
+// `ods compose` could start for us.
+func isLocalURL(u *url.URL) bool {
+	switch u.Hostname() {
+	case "localhost", "127.0.0.1", "::1":
+		return true
+	}
+	return false
+}
+
+// ensureWebBackend makes sure the API server at backendURL is reachable,
+// starting the local compose api_server if needed, and returns the env vars
+// that point the Next.js dev server at it.
+func ensureWebBackend(backendURL string) []string {
+	u, err := url.Parse(backendURL)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		log.Fatalf("Invalid --backend-url %q: expected e.g. http://localhost:8080", backendURL)
+	}
+	healthURL := strings.TrimSuffix(backendURL, "/") + "/health"
+
+	if err := health.Probe(healthURL, health.DefaultTimeout); err == nil {
+		log.Infof("API server is reachable at %s", backendURL)
+	} else {
+		if !isLocalURL(u) {
+			log.Fatalf("API server at %s is not reachable (%v).\n\nIf this is a port-forwarded remote, make sure the port-forward is running.", backendURL, err)
+		}
+
+		log.Infof("API server at %s is not reachable, starting %s via docker compose...", backendURL, composeAPIServerService)
+		args := append(baseArgs("dev"), "up", "-d", composeAPIServerService)
+		execDockerCompose(args, nil)
+
+		log.Infof("Waiting for %s to become healthy...", healthURL)
+		if err := health.WaitFor(healthURL, backendStartupTimeout, backendStartupInterval); err != nil {
+			log.Fatalf("API server did not come up: %v\n\nCheck its logs with: ods logs %s", err, composeAPIServerService)
+		}
+		log.Infof("API server is up at %s", backendURL)
+	}
+
+	env := []string{"INTERNAL_URL=" + strings.TrimSuffix(backendURL, "/")}
+	env = append(env, composeNextPublicEnv()...)
+	for _, e := range env {
+		log.Debugf("  %s", e)
+	}
+	return env
+}
+
+// composeNextPublicEnv returns NEXT_PUBLIC_* settings from the compose .env so
+// the dev server is built with the same feature flags as the backend it talks
+// to. Values already exported in the shell win.
+func composeNextPublicEnv() []string {
+	envPath := filepath.Join(composeDir(), ".env")
+	if _, err := os.Stat(envPath); err != nil {
+		return nil
+	}
+
+	var env []string
+	for _, entry := range loadBackendEnvFile(envPath) {
+		key, _, _ := strings.Cut(entry, "=")
+		if !strings.HasPrefix(key, "NEXT_PUBLIC_") {
+			continue
+		}
+		if _, set := os.LookupEnv(key); set {
+			continue
+		}
+		env = append(env, entry)
+	}
+	return env
+}
@@ -0,0 +1,28 @@
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestExtractWebBackendFlags(t *testing.T) {
+	opts := &WebOptions{BackendURL: defaultBackendURL}
+	rest := extractWebBackendFlags([]string{"--turbo", "--with-backend", "--backend-url", "http://localhost:9999", "--", "--with-backend"}, opts)
+
+	if !opts.WithBackend {
+		t.Error("expected WithBackend to be set")
+	}
+	if opts.BackendURL != "http://localhost:9999" {
+		t.Errorf("unexpected BackendURL %q", opts.BackendURL)
+	}
+	want := []string{"--turbo", "--", "--with-backend"}
+	if !reflect.DeepEqual(rest, want) {
+		t.Errorf("got %v, want %v", rest, want)
+	}
+
+	opts = &WebOptions{}
+	rest = extractWebBackendFlags([]string{"--backend-url=http://127.0.0.1:8081"}, opts)
+	if opts.BackendURL != "http://127.0.0.1:8081" || len(rest) != 0 {
+		t.Errorf("unexpected result: opts=%+v rest=%v", opts, rest)
+	}
+}
@@ -0,0 +1,209 @@
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/paths"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/prompt"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/runner"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/version"
+)
+
+// lockfileStampName is written into node_modules after a successful install
+// and records the hash of the lockfile that install was made from.
+const lockfileStampName = ".ods-lockfile-hash"
+
+// nodeRequirement is the Node version the web app expects and where it was
+// declared.
+type nodeRequirement struct {
+	Constraint string
+	Source     string
+	// Pinned is true for version files (.nvmrc, .node-version), whose value
+	// can be handed straight to a version manager. engines ranges cannot.
+	Pinned bool
+}
+
+// findNodeRequirement looks for the expected Node version in, in order,
+// web/.nvmrc, web/.node-version, <root>/.nvmrc, <root>/.node-version and the
+// engines.node field of web/package.json. Returns nil when none is declared.
+func findNodeRequirement(webDir string) *nodeRequirement {
+	candidates := []string{
+		filepath.Join(webDir, ".nvmrc"),
+		filepath.Join(webDir, ".node-version"),
+	}
+	if root, err := paths.GitRoot(); err == nil {
+		candidates = append(candidates,
+			filepath.Join(root, ".nvmrc"),
+			filepath.Join(root, ".node-version"),
+		)
+	}
+	for _, path := range candidates {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		if v := strings.TrimSpace(string(data)); v != "" {
+			return &nodeRequirement{Constraint: v, Source: path, Pinned: true}
+		}
+	}
+
+	packageJSONPath := filepath.Join(webDir, "package.json")
+	data, err := os.ReadFile(packageJSONPath)
+	if err != nil {
+		return nil
+	}
+	var pkg struct {
+		Engines map[string]string `json:"engines"`
+	}
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		return nil
+	}
+	if v := strings.TrimSpace(pkg.Engines["node"]); v != "" {
+		return &nodeRequirement{Constraint: v, Source: packageJSONPath + " (engines.node)"}
+	}
+	return nil
+}
+
+// resolveNodeWrapper checks the installed Node against the web app's declared
+// requirement. On a mismatch it offers to run scripts through fnm or nvm with
+// the required version, returning the argv prefix to wrap commands with. An
+// empty prefix means "run commands directly".
+func resolveNodeWrapper(r runner.Runner, webDir string) []string {
+	req := findNodeRequirement(webDir)
+	if req == nil {
+		log.Debug("No Node version requirement declared, skipping version check")
+		return nil
+	}
+	if nodeSatisfies(r, req) {
+		return nil
+	}
+	return versionManagerWrapper(req)
+}
+
+// nodeSatisfies reports whether the installed node meets req. A constraint
+// that cannot be checked is let through with a warning.
+func nodeSatisfies(r runner.Runner, req *nodeRequirement) bool {
+	out, err := runner.Output(r, runner.Cmd{Name: "node", Args: []string{"--version"}})
+	if err != nil {
+		log.Warnf("node is not installed; %s expects %s", req.Source, req.Constraint)
+		return false
+	}
+	current := strings.TrimSpace(string(out))
+
+	ok, err := version.Satisfies(current, req.Constraint)
+	if err != nil {
+		log.Warnf("Could not check Node %s against %q from %s: %v", current, req.Constraint, req.Source, err)
+		return true
+	}
+	if ok {
+		log.Debugf("Node %s satisfies %q from %s", current, req.Constraint, req.Source)
+		return true
+	}
+
+	log.Warnf("Node %s does not satisfy %q (from %s)", current, req.Constraint, req.Source)
+	return false
+}
+
+// versionManagerWrapper offers to run through fnm or nvm when the requirement
+// is a pinned version. Returns nil (after printing guidance) if neither is
+// available or the user declines.
+func versionManagerWrapper(req *nodeRequirement) []string {
+	if !req.Pinned {
+		log.Warn("Install a matching Node version and re-run; continuing with the current one.")
+		return nil
+	}
+
+	if _, err := exec.LookPath("fnm"); err == nil {
+		if prompt.Confirm(fmt.Sprintf("Run with Node %s via fnm? (Y/n): ", req.Constraint)) {
+			return []string{"fnm", "exec", "--using=" + req.Constraint, "--"}
+		}
+		return nil
+	}
+
+	if nvmDir := os.Getenv("NVM_DIR"); nvmDir != "" {
+		nvmScript := filepath.Join(nvmDir, "nvm.sh")
+		if _, err := os.Stat(nvmScript); err == nil {
+			if prompt.Confirm(fmt.Sprintf("Run with Node %s via nvm? (Y/n): ", req.Constraint)) {
+				// nvm is a shell function, so it has to be sourced into a shell
+				// that then execs the real command ("$@").
+				script := fmt.Sprintf(`. %q && nvm exec --silent %q "$@"`, nvmScript, req.Constraint)
+				return []string{"bash", "-c", script, "nvm-exec"}
+			}
+			return nil
+		}
+	}
+
+	log.Warnf("Install Node %s (e.g. with fnm or nvm) and re-run; continuing with the current one.", req.Constraint)
+	return nil
+}
+
+// wrapCommand builds a runner.Cmd for name/args, prefixed by wrapper if set.
+func wrapCommand(wrapper []string, name string, args ...string) runner.Cmd {
+	if len(wrapper) == 0 {
+		return runner.Cmd{Name: name, Args: args}
+	}
+	argv := append(append(append([]string{}, wrapper[1:]...), name), args...)
+	return runner.Cmd{Name: wrapper[0], Args: argv}
+}
+
+// nodeModulesNeedsInstall reports whether bun install should be run, along with
+// a human-readable reason. Install is needed when node_modules is missing,
+// exists but is empty, or was installed from a different lockfile than the
+// one currently checked out.
+func nodeModulesNeedsInstall(nodeModules, lockfile string) (bool, string) {
+	entries, err := os.ReadDir(nodeModules)
+	if errors.Is(err, os.ErrNotExist) {
+		return true, "node_modules not found"
+	}
+	if err != nil {
+		// Couldn't read the directory for some other reason; let bun install
+		// attempt to sort it out rather than silently skipping.
+		return true, fmt.Sprintf("could not read node_modules (%v)", err)
+	}
+	if len(entries) == 0 {
+		return true, "node_modules is empty"
+	}
+
+	want, err := lockfileHash(lockfile)
+	if err != nil {
+		log.Debugf("Skipping lockfile staleness check: %v", err)
+		return false, ""
+	}
+	have, err := os.ReadFile(filepath.Join(nodeModules, lockfileStampName))
+	if err != nil {
+		return true, "node_modules has no record of the lockfile it was installed from"
+	}
+	if strings.TrimSpace(string(have)) != want {
+		return true, fmt.Sprintf("%s changed since the last install", filepath.Base(lockfile))
+	}
+	return false, ""
+}
+
+// stampLockfileHash records the lockfile hash in node_modules so the next run
+// can tell whether dependencies are stale.
+func stampLockfileHash(nodeModules, lockfile string) error {
+	hash, err := lockfileHash(lockfile)
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(nodeModules, lockfileStampName), []byte(hash+"\n"), 0644)
+}
+
+func lockfileHash(lockfile string) (string, error) {
+	data, err := os.ReadFile(lockfile)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
@@ -0,0 +1,119 @@
+package cmd
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/runner"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/version"
+)
+
+func TestNodeModulesNeedsInstall(t *testing.T) {
+	dir := t.TempDir()
+	nodeModules := filepath.Join(dir, "node_modules")
+	lockfile := filepath.Join(dir, "bun.lock")
+	if err := os.WriteFile(lockfile, []byte("v1"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if need, _ := nodeModulesNeedsInstall(nodeModules, lockfile); !need {
+		t.Error("expected install when node_modules is missing")
+	}
+
+	if err := os.MkdirAll(filepath.Join(nodeModules, "react"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if need, _ := nodeModulesNeedsInstall(nodeModules, lockfile); !need {
+		t.Error("expected install when no lockfile hash has been recorded")
+	}
+
+	if err := stampLockfileHash(nodeModules, lockfile); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if need, reason := nodeModulesNeedsInstall(nodeModules, lockfile); need {
+		t.Errorf("expected no install after stamping, got %q", reason)
+	}
+
+	if err := os.WriteFile(lockfile, []byte("v2"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if need, _ := nodeModulesNeedsInstall(nodeModules, lockfile); !need {
+		t.Error("expected install after the lockfile changed")
+	}
+}
+
+func TestFindNodeRequirement_engines(t *testing.T) {
+	dir := t.TempDir()
+	pkg := `{"engines": {"node": ">=22.0.0"}}`
+	if err := os.WriteFile(filepath.Join(dir, "package.json"), []byte(pkg), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	req := findNodeRequirement(dir)
+	if req == nil {
+		t.Fatal("expected a requirement from engines.node")
+	}
+	if req.Constraint != ">=22.0.0" || req.Pinned {
+		t.Errorf("unexpected requirement %+v", req)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, ".nvmrc"), []byte("22.11.0\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	req = findNodeRequirement(dir)
+	if req == nil || req.Constraint != "22.11.0" || !req.Pinned {
+		t.Errorf(".nvmrc should take precedence, got %+v", req)
+	}
+}
+
+func TestWebDeclaresNodeVersion(t *testing.T) {
+	dir, err := webDir()
+	if err != nil {
+		t.Skipf("not in a git checkout: %v", err)
+	}
+	// Without a declared version the check in ods web never runs.
+	req := findNodeRequirement(dir)
+	if req == nil || !req.Pinned {
+		t.Fatalf("expected web/.nvmrc to pin the Node version, got %+v", req)
+	}
+	if ok, err := version.Satisfies("v24.0.0", req.Constraint); err != nil || !ok {
+		t.Errorf("%s pins %q; keep it on the Node major of web/Dockerfile (24)", req.Source, req.Constraint)
+	}
+}
+
+func TestWrapCommand(t *testing.T) {
+	c := wrapCommand(nil, "bun", "run", "dev")
+	if got := c.String(); got != "bun run dev" {
+		t.Errorf("unexpected command %q", got)
+	}
+
+	c = wrapCommand([]string{"fnm", "exec", "--using=22", "--"}, "bun", "run", "dev")
+	if got, want := c.String(), "fnm exec --using=22 -- bun run dev"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestNodeSatisfies(t *testing.T) {
+	req := &nodeRequirement{Constraint: ">=22.0.0", Source: "web/package.json (engines.node)"}
+	tests := []struct {
+		name string
+		resp runner.Response
+		want bool
+	}{
+		{"matching", runner.Response{Stdout: "v22.11.0\n"}, true},
+		{"too old", runner.Response{Stdout: "v20.18.1\n"}, false},
+		{"not installed", runner.Response{Err: errors.New("executable file not found in $PATH")}, false},
+		{"unparseable", runner.Response{Stdout: "nightly\n"}, true},
+	}
+	for _, tt := range tests {
+		f := (&runner.Fake{}).On("node --version", tt.resp)
+		if got := nodeSatisfies(f, req); got != tt.want {
+			t.Errorf("%s: nodeSatisfies() = %v, want %v", tt.name, got, tt.want)
+		}
+		if lines := f.Lines(); len(lines) != 1 || lines[0] != "node --version" {
+			t.Errorf("%s: unexpected commands %q", tt.name, lines)
+		}
+	}
+}
@@ -0,0 +1,259 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"text/tabwriter"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/config"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/gdpr"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/kube"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/notify"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/paths"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/prodaccess"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/runner"
+)
+
+// whoamiCredentials are the credentials ods reads from the environment, each
+// with what it is used for.
+var whoamiCredentials = []struct {
+	env     []string
+	purpose string
+}{
+	{[]string{"ONYX_API_KEY"}, "API calls without --tenant/--as"},
+	{[]string{"SUPER_CLOUD_API_KEY", "ODS_SUPERUSER_SESSION"}, "ods proxy, --tenant/--as on API commands"},
+	{[]string{"DATA_PLANE_SECRET"}, "control-plane calls (ods impersonate, ods billing)"},
+	{[]string{"JIRA_EMAIL", "JIRA_API_TOKEN"}, "--ticket comments (Jira)"},
+	{[]string{"LINEAR_API_KEY"}, "--ticket comments (Linear)"},
+	{[]string{notify.WebhookEnv}, "--notify"},
+	{[]string{gdpr.SigningKeyEnv}, "signing DSR exports"},
+	{[]string{"ODS_ASK_API_KEY"}, "ods ask, ods explain --llm"},
+}
+
+// WhoamiOptions holds options for the whoami command.
+type WhoamiOptions struct {
+	Timeout time.Duration
+}
+
+// NewWhoamiCommand creates the whoami command.
+func NewWhoamiCommand() *cobra.Command {
+	opts := &WhoamiOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "whoami",
+		Short: "Show who ods is authenticated as, and where",
+		Long: `Show who ods is authenticated as, and where.
+
+Reports:
+  - the AWS identity (sts get-caller-identity) of the shell's default
+    credentials and of every AWS profile a cluster context uses
+  - kubectl's current context
+  - every cluster context configured through KUBE_CTX_<NAME>, whether it is
+    in kubeconfig, and the user its API server authenticates you as
+  - which API tokens and webhooks ods would use (never their values)
+  - active production access sessions
+
+Nothing is changed: contexts missing from kubeconfig are reported rather
+than fetched, and no production access session is needed.
+
+Examples:
+  ods whoami
+  ods whoami --timeout 10s`,
+		Args: cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			runWhoami(opts)
+		},
+	}
+
+	cmd.Flags().DurationVar(&opts.Timeout, "timeout", 5*time.Second, "How long to wait for each cluster")
+
+	return cmd
+}
+
+// whoamiContext is a KUBE_CTX_<NAME> context and what checking it found.
+type whoamiContext struct {
+	name    string
+	cluster *kube.Cluster
+	status  string
+}
+
+func runWhoami(opts *WhoamiOptions) {
+	contexts := configuredContexts()
+
+	profiles := []string{""}
+	for _, ctx := range contexts {
+		if ctx.cluster != nil && ctx.cluster.Profile != "" && !slices.Contains(profiles, ctx.cluster.Profile) {
+			profiles = append(profiles, ctx.cluster.Profile)
+		}
+	}
+	identities := make([]string, len(profiles))
+
+	var wg sync.WaitGroup
+	for i, profile := range profiles {
+		wg.Go(func() {
+			id, err := kube.GetCallerIdentity(profile)
+			if err != nil {
+				identities[i] = "error: " + errorSummary(err)
+				return
+			}
+			identities[i] = fmt.Sprintf("%s\t%s", id.Account, id.Arn)
+		})
+	}
+	for _, ctx := range contexts {
+		if ctx.cluster == nil {
+			continue
+		}
+		wg.Go(func() {
+			ctx.status = contextStatus(ctx.cluster, opts.Timeout)
+		})
+	}
+	wg.Wait()
+
+	fmt.Println("AWS")
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "PROFILE\tACCOUNT\tARN")
+	_, _ = fmt.Fprintln(w, "-------\t-------\t---")
+	for i, profile := range profiles {
+		if profile == "" {
+			profile = "(default)"
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\n", profile, identities[i])
+	}
+	_ = w.Flush()
+
+	current := kube.CurrentContext()
+	if current == "" {
+		current = "(none)"
+	}
+	fmt.Printf("\nKubernetes\nkubectl current context: %s\n\n", current)
+	if len(contexts) == 0 {
+		fmt.Println("No ods contexts configured (set KUBE_CTX_<NAME>)")
+	} else {
+		w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		_, _ = fmt.Fprintln(w, "CONTEXT\tCLUSTER\tNAMESPACE\tPROFILE\tSTATUS")
+		_, _ = fmt.Fprintln(w, "-------\t-------\t---------\t-------\t------")
+		for _, ctx := range contexts {
+			_, _ = fmt.Fprintln(w, ctx.row())
+		}
+		_ = w.Flush()
+	}
+
+	fmt.Println("\nCredentials")
+	w = tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, cred := range whoamiCredentials {
+		_, _ = fmt.Fprintln(w, credentialRow(cred.env, cred.purpose))
+	}
+	cfg, err := config.Load()
+	if err != nil {
+		log.Warnf("Failed to load ods config: %v", err)
+		cfg = &config.Config{}
+	}
+	_, _ = fmt.Fprintf(w, "notify.slack_webhooks\t%d configured\t--notify, session notices\n", len(cfg.Notify.SlackWebhooks))
+	_, _ = fmt.Fprintf(w, "gh\t%s\tdeploys, releases, CI\n", githubLogin(nil))
+	_ = w.Flush()
+
+	fmt.Println("\nProduction access sessions")
+	sessions, err := prodaccess.Load(paths.ProdSessionsPath())
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+	now := time.Now()
+	active := 0
+	for _, s := range sessions {
+		if s.Active(now) {
+			active++
+			fmt.Printf("%s until %s (%s left): %s\n", s.Context, s.Expires.Local().Format(time.Kitchen), s.Remaining(now), s.Reason)
+		}
+	}
+	if active == 0 {
+		fmt.Println("None (start one with `ods session start`)")
+	}
+}
+
+// row is the context's line of the Kubernetes table.
+func (ctx *whoamiContext) row() string {
+	cluster, namespace, profile := "-", "-", "-"
+	if c := ctx.cluster; c != nil {
+		cluster, namespace = c.Name, c.Namespace
+		if c.Profile != "" {
+			profile = c.Profile
+		}
+	}
+	return strings.Join([]string{ctx.name, cluster, namespace, profile, ctx.status}, "\t")
+}
+
+// credentialRow is the line of the credentials table for variables that are
+// used together: "set" only when all of them are.
+func credentialRow(env []string, purpose string) string {
+	status := "set"
+	for _, name := range env {
+		if os.Getenv(name) == "" {
+			status = "not set"
+		}
+	}
+	return strings.Join([]string{strings.Join(env, " + "), status, purpose}, "\t")
+}
+
+// configuredContexts returns the contexts defined by KUBE_CTX_<NAME>
+// variables, ordered by name. Unparseable ones have a nil cluster and the
+// parse error as status.
+func configuredContexts() []*whoamiContext {
+	var contexts []*whoamiContext
+	for _, kv := range os.Environ() {
+		key, value, _ := strings.Cut(kv, "=")
+		suffix, ok := strings.CutPrefix(key, "KUBE_CTX_")
+		if !ok || suffix == "" {
+			continue
+		}
+		ctx := &whoamiContext{name: strings.ToLower(suffix)}
+		c, err := kube.ParseClusterSpec(value)
+		if err != nil {
+			ctx.status = "invalid: " + err.Error()
+		} else {
+			ctx.cluster = c
+		}
+		contexts = append(contexts, ctx)
+	}
+	sort.Slice(contexts, func(i, j int) bool { return contexts[i].name < contexts[j].name })
+	return contexts
+}
+
+// contextStatus describes whether c is reachable and who it authenticates
+// as.
+func contextStatus(c *kube.Cluster, timeout time.Duration) string {
+	if !c.InKubeconfig() {
+		return "not in kubeconfig (any command using it will fetch it)"
+	}
+	user, err := c.WhoAmI(timeout)
+	if err != nil {
+		return "unreachable: " + errorSummary(err)
+	}
+	return "ok, as " + user
+}
+
+// githubLogin returns the account gh is logged in as, or why it is not.
+func githubLogin(r runner.Runner) string {
+	out, err := runner.Output(r, runner.Cmd{Name: "gh", Args: []string{"api", "user", "--jq", ".login"}})
+	if err != nil {
+		if _, ran := runner.ExitCode(err); !ran {
+			return "not installed"
+		}
+		return "not logged in (gh auth login)"
+	}
+	return "logged in as " + strings.TrimSpace(string(out))
+}
+
+// errorSummary returns the last line of err, which for failed aws and
+// kubectl runs is the tool's own explanation.
+func errorSummary(err error) string {
+	lines := strings.Split(strings.TrimSpace(err.Error()), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
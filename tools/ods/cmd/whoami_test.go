@@ -0,0 +1,93 @@
+package cmd
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/runner"
+)
+
+func TestGithubLogin(t *testing.T) {
+	tests := []struct {
+		name string
+		resp runner.Response
+		want string
+	}{
+		{"logged in", runner.Response{Stdout: "octocat\n"}, "logged in as octocat"},
+		{"logged out", runner.Response{Exit: 1}, "not logged in (gh auth login)"},
+		{"missing", runner.Response{Err: errors.New(`exec: "gh": executable file not found in $PATH`)}, "not installed"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			f := (&runner.Fake{}).On("gh api user --jq .login", tt.resp)
+			if got := githubLogin(f); got != tt.want {
+				t.Errorf("githubLogin() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCredentialRows(t *testing.T) {
+	tests := []struct {
+		env     map[string]string
+		names   []string
+		purpose string
+		want    string
+	}{
+		{
+			env:   map[string]string{"DATA_PLANE_SECRET": "s"},
+			names: []string{"DATA_PLANE_SECRET"}, purpose: "control-plane calls",
+			want: "DATA_PLANE_SECRET\tset\tcontrol-plane calls",
+		},
+		{
+			env:   map[string]string{"SUPER_CLOUD_API_KEY": "k", "ODS_SUPERUSER_SESSION": ""},
+			names: []string{"SUPER_CLOUD_API_KEY", "ODS_SUPERUSER_SESSION"}, purpose: "ods proxy",
+			want: "SUPER_CLOUD_API_KEY + ODS_SUPERUSER_SESSION\tnot set\tods proxy",
+		},
+	}
+	for _, tt := range tests {
+		for k, v := range tt.env {
+			t.Setenv(k, v)
+		}
+		if got := credentialRow(tt.names, tt.purpose); got != tt.want {
+			t.Errorf("credentialRow(%v) = %q, want %q", tt.names, got, tt.want)
+		}
+	}
+
+	var names []string
+	for _, cred := range whoamiCredentials {
+		names = append(names, cred.env...)
+	}
+	for _, want := range []string{"DATA_PLANE_SECRET", "SUPER_CLOUD_API_KEY", "ODS_SUPERUSER_SESSION", "ONYX_API_KEY"} {
+		if !strings.Contains(strings.Join(names, " "), want) {
+			t.Errorf("whoami does not report %s", want)
+		}
+	}
+}
+
+func TestContextRows(t *testing.T) {
+	t.Setenv("KUBE_CTX_WHOAMI_TEST_DP", "dp us-east-2 onyx prod")
+	t.Setenv("KUBE_CTX_WHOAMI_TEST_BAD", "dp us-east-2")
+	t.Setenv("KUBE_CTX_WHOAMI_TEST_SHELL", "cp us-west-2 control")
+
+	tests := map[string]string{
+		"whoami_test_dp":    "whoami_test_dp\tdp\tonyx\tprod\t",
+		"whoami_test_bad":   "whoami_test_bad\t-\t-\t-\tinvalid: expected 3 to 5 space-separated values",
+		"whoami_test_shell": "whoami_test_shell\tcp\tcontrol\t-\t",
+	}
+	found := 0
+	for _, ctx := range configuredContexts() {
+		want, ok := tests[ctx.name]
+		if !ok {
+			continue
+		}
+		found++
+		if got := ctx.row(); !strings.HasPrefix(got, want) {
+			t.Errorf("row for %s = %q, want prefix %q", ctx.name, got, want)
+		}
+	}
+	if found != len(tests) {
+		t.Errorf("found %d of the %d test contexts", found, len(tests))
+	}
+}
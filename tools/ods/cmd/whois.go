@@ -2,22 +2,37 @@ package cmd
 
 import (
 	"fmt"
+	"io"
 	"os"
 	"regexp"
 	"strings"
-	"text/tabwriter"
+	"time"
 
 	log "github.com/sirupsen/logrus"
 	"github.com/spf13/cobra"
 
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/config"
 	"github.com/onyx-dot-app/onyx/tools/ods/internal/kube"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/lookupcache"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/render"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/tenant"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/whois"
 )
 
 var safeIdentifier = regexp.MustCompile(`^[a-zA-Z0-9_\-]+$`)
 
+// WhoisOptions holds options for the whois command.
+type WhoisOptions struct {
+	Context    string
+	Cache      bool
+	Offline    bool
+	MaxAge     time.Duration
+	ClearCache bool
+}
+
 // NewWhoisCommand creates the whois command for looking up users/tenants.
 func NewWhoisCommand() *cobra.Command {
-	var ctx string
+	opts := &WhoisOptions{}
 
 	cmd := &cobra.Command{
 		Use:   "whois <email-fragment or tenant-id>",
@@ -37,20 +52,42 @@ Two modes (auto-detected):
     → Lists all admin emails in that tenant
 
 Cluster connection is configured via KUBE_CTX_* environment variables.
-Each variable is a space-separated tuple: "cluster region namespace"
+Each variable is a space-separated tuple: "cluster region namespace", optionally
+followed by the AWS profile to authenticate with and an IAM role ARN to assume
+(use "-" for the profile to keep the shell's default):
 
   export KUBE_CTX_DATA_PLANE="<cluster> <region> <namespace>"
-  export KUBE_CTX_CONTROL_PLANE="<cluster> <region> <namespace>"
+  export KUBE_CTX_CONTROL_PLANE="<cluster> <region> <namespace> <aws-profile>"
+  export KUBE_CTX_PROD_EU="<cluster> <region> <namespace> <aws-profile> <role-arn>"
   etc...
 
-Use -c to select which context (default: data_plane).`,
-		Args: cobra.ExactArgs(1),
+Use -c to select which context (default: data_plane).
+
+Caching (opt-in with --cache, or "whois": {"cache": true} in the ods config):
+results are kept in a local cache encrypted at rest. Lookups younger than
+--max-age are answered from it without touching the cluster, and --offline
+answers from it regardless of age, e.g. when writing an incident timeline after
+your access window has closed. Cached answers are labelled with their age.`,
+		Args: func(cmd *cobra.Command, args []string) error {
+			if opts.ClearCache {
+				return cobra.NoArgs(cmd, args)
+			}
+			return cobra.ExactArgs(1)(cmd, args)
+		},
 		Run: func(cmd *cobra.Command, args []string) {
-			runWhois(args[0], ctx)
+			if opts.ClearCache {
+				clearWhoisCache()
+				return
+			}
+			runWhois(args[0], opts)
 		},
 	}
 
-	cmd.Flags().StringVarP(&ctx, "context", "c", "data_plane", "cluster context name (maps to KUBE_CTX_<NAME> env var)")
+	cmd.Flags().StringVarP(&opts.Context, "context", "c", "data_plane", "cluster context name (maps to KUBE_CTX_<NAME> env var)")
+	cmd.Flags().BoolVar(&opts.Cache, "cache", false, "Use the encrypted local lookup cache")
+	cmd.Flags().BoolVar(&opts.Offline, "offline", false, "Answer from the lookup cache only, without cluster access")
+	cmd.Flags().DurationVar(&opts.MaxAge, "max-age", time.Hour, "Reuse cached results younger than this")
+	cmd.Flags().BoolVar(&opts.ClearCache, "clear-cache", false, "Delete the local lookup cache and exit")
 
 	return cmd
 }
@@ -59,23 +96,42 @@ func clusterFromEnv(name string) *kube.Cluster {
 	envKey := "KUBE_CTX_" + strings.ToUpper(name)
 	val := os.Getenv(envKey)
 	if val == "" {
-		log.Fatalf("Environment variable %s is not set.\n\nSet it as a space-separated tuple:\n  export %s=\"<cluster> <region> <namespace>\"", envKey, envKey)
+		log.Fatalf("Environment variable %s is not set.\n\nSet it as a space-separated tuple:\n  export %s=\"<cluster> <region> <namespace> [<aws-profile> [<role-arn>]]\"", envKey, envKey)
 	}
 
-	parts := strings.Fields(val)
-	if len(parts) != 3 {
-		log.Fatalf("%s must be a space-separated tuple of 3 values (cluster region namespace), got: %q", envKey, val)
+	c, err := kube.ParseClusterSpec(val)
+	if err != nil {
+		log.Fatalf("Invalid %s=%q: %v", envKey, val, err)
 	}
+	if isProductionContext(name) {
+		requireProdSession(name)
+	}
+	return c
+}
 
-	return &kube.Cluster{Name: parts[0], Region: parts[1], Namespace: parts[2]}
+// validateTenantArg exits if tenantID could not be safely interpolated into a
+// schema-qualified SQL identifier.
+func validateTenantArg(tenantID string) {
+	if err := whois.ValidateTenantID(tenantID); err != nil {
+		log.Fatalf("%v", err)
+	}
 }
 
 // queryPod runs a SQL query via pginto on the given pod and returns cleaned output lines.
 func queryPod(c *kube.Cluster, pod, sql string) []string {
-	raw, err := c.ExecOnPod(pod, "pginto", "-A", "-t", "-F", "\t", "-c", sql)
+	lines, err := tryQueryPod(c, pod, sql)
 	if err != nil {
 		log.Fatalf("Query failed: %v", err)
 	}
+	return lines
+}
+
+// tryQueryPod is queryPod for callers that must survive a failed query.
+func tryQueryPod(c *kube.Cluster, pod, sql string) ([]string, error) {
+	raw, err := c.ExecOnPod(pod, "pginto", "-A", "-t", "-F", "\t", "-c", sql)
+	if err != nil {
+		return nil, err
+	}
 
 	var lines []string
 	for _, line := range strings.Split(strings.TrimSpace(raw), "\n") {
@@ -84,11 +140,69 @@ func queryPod(c *kube.Cluster, pod, sql string) []string {
 			lines = append(lines, line)
 		}
 	}
-	return lines
+	return lines, nil
 }
 
-func runWhois(query string, ctx string) {
-	c := clusterFromEnv(ctx)
+// schemaBatch is how many tenant schemas one querySchemas query covers.
+const schemaBatch = 200
+
+// tenantSchemas lists the schemas holding tenant data, skipping any whose
+// name could not be safely interpolated into SQL.
+func tenantSchemas(c *kube.Cluster, pod string) ([]string, error) {
+	lines, err := tryQueryPod(c, pod, tenant.SchemasSQL)
+	if err != nil {
+		return nil, err
+	}
+	var schemas []string
+	for _, s := range lines {
+		if !safeIdentifier.MatchString(s) {
+			log.Warnf("Skipping schema with an unexpected name: %q", s)
+			continue
+		}
+		schemas = append(schemas, s)
+	}
+	return schemas, nil
+}
+
+// querySchemas runs the query sqlFor builds for schemas in batches of
+// schemaBatch and returns the output lines of all of them.
+func querySchemas(c *kube.Cluster, pod string, schemas []string, sqlFor func(batch []string) string) ([]string, error) {
+	var lines []string
+	for start := 0; start < len(schemas); start += schemaBatch {
+		batch := schemas[start:min(start+schemaBatch, len(schemas))]
+		out, err := tryQueryPod(c, pod, sqlFor(batch))
+		if err != nil {
+			return nil, err
+		}
+		lines = append(lines, out...)
+	}
+	return lines, nil
+}
+
+func runWhois(query string, opts *WhoisOptions) {
+	kind := whois.KindOf(query)
+	if kind == whois.KindTenant {
+		validateTenantArg(query)
+	}
+
+	cache := openWhoisCache(opts)
+	cacheKey := lookupcache.Key(opts.Context, kind, query)
+	if cache != nil {
+		if e, ok := cache.Get(cacheKey); ok && (opts.Offline || e.Age() < opts.MaxAge) {
+			label := fmt.Sprintf("cached %s ago", e.Age().Round(time.Second))
+			if e.Age() >= opts.MaxAge {
+				label = fmt.Sprintf("STALE: cached %s ago at %s", e.Age().Round(time.Minute), e.Fetched.Local().Format(time.RFC3339))
+			}
+			log.Infof("Answering from the lookup cache (%s)", label)
+			printWhoisResult(os.Stdout, kind, e.Rows)
+			return
+		}
+		if opts.Offline {
+			log.Fatalf("No cached result for %q in context %s", query, opts.Context)
+		}
+	}
+
+	c := clusterFromEnv(opts.Context)
 
 	if err := c.EnsureContext(); err != nil {
 		log.Fatalf("Failed to ensure cluster context: %v", err)
@@ -101,59 +215,99 @@ func runWhois(query string, ctx string) {
 	}
 	log.Debugf("Using pod: %s", pod)
 
-	if strings.HasPrefix(query, "tenant_") {
-		findAdminsByTenant(c, pod, query)
+	if kind == whois.KindTenant {
+		log.Infof("Fetching admin emails for %s...", query)
 	} else {
-		findByEmail(c, pod, query)
+		log.Infof("Searching for emails matching '%%%s%%'...", whois.EscapeFragment(query))
 	}
-}
-
-func findByEmail(c *kube.Cluster, pod, fragment string) {
-	fragment = strings.NewReplacer("'", "", `"`, "", `;`, "", `\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(fragment)
+	result, err := whois.Lookup(func(sql string) ([]string, error) { return tryQueryPod(c, pod, sql) }, query)
+	if err != nil {
+		log.Fatalf("Query failed: %v", err)
+	}
+	rows := result.Rows
 
-	sql := fmt.Sprintf(
-		`SELECT email, tenant_id, active FROM public.user_tenant_mapping WHERE email LIKE '%%%s%%' ORDER BY email;`,
-		fragment,
-	)
+	if cache != nil {
+		if err := cache.Put(cacheKey, rows); err != nil {
+			log.Warnf("Failed to update the lookup cache: %v", err)
+		}
+	}
+	printWhoisResult(os.Stdout, kind, rows)
+}
 
-	log.Infof("Searching for emails matching '%%%s%%'...", fragment)
-	lines := queryPod(c, pod, sql)
-	if len(lines) == 0 {
-		fmt.Println("No results found.")
-		return
+// openWhoisCache returns the lookup cache if caching is enabled by flag or
+// config, or nil otherwise. --offline implies --cache.
+func openWhoisCache(opts *WhoisOptions) *lookupcache.Cache {
+	enabled := opts.Cache || opts.Offline
+	if !enabled {
+		cfg, err := config.Load()
+		if err != nil {
+			log.Warnf("Failed to load ods config: %v", err)
+		} else {
+			enabled = cfg.Whois.Cache
+		}
+	}
+	if !enabled {
+		return nil
 	}
 
-	fmt.Println()
-	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
-	_, _ = fmt.Fprintln(w, "EMAIL\tTENANT ID\tACTIVE")
-	_, _ = fmt.Fprintln(w, "-----\t---------\t------")
-	for _, line := range lines {
-		_, _ = fmt.Fprintln(w, line)
+	cache, err := lookupcache.Open(lookupcache.DefaultPaths())
+	if err != nil {
+		if opts.Offline {
+			log.Fatalf("Failed to open the lookup cache: %v", err)
+		}
+		log.Warnf("Ignoring the lookup cache: %v", err)
+		return nil
 	}
-	_ = w.Flush()
+	return cache
 }
 
-func findAdminsByTenant(c *kube.Cluster, pod, tenantID string) {
-	if !safeIdentifier.MatchString(tenantID) {
-		log.Fatalf("Invalid tenant ID: %q (must be alphanumeric, hyphens, underscores only)", tenantID)
+func clearWhoisCache() {
+	cache, err := lookupcache.Open(lookupcache.DefaultPaths())
+	if err != nil {
+		// Unreadable (e.g. the key was lost); remove the file directly.
+		cachePath, _ := lookupcache.DefaultPaths()
+		if err := os.Remove(cachePath); err != nil && !os.IsNotExist(err) {
+			log.Fatalf("Failed to remove the lookup cache: %v", err)
+		}
+	} else if err := cache.Clear(); err != nil {
+		log.Fatalf("%v", err)
 	}
+	log.Info("Lookup cache cleared")
+}
 
-	sql := fmt.Sprintf(
-		`SELECT email FROM "%s"."user" WHERE role = 'ADMIN' AND is_active = true AND email NOT LIKE 'api_key__%%' ORDER BY email;`,
-		tenantID,
-	)
-
-	log.Infof("Fetching admin emails for %s...", tenantID)
-	lines := queryPod(c, pod, sql)
-	if len(lines) == 0 {
-		fmt.Println("No admin users found for this tenant.")
+// printWhoisResult writes the rows of a lookup, each tab-separated as psql
+// returned it, as a table.
+func printWhoisResult(w io.Writer, kind string, rows []string) {
+	if len(rows) == 0 {
+		if kind == whois.KindTenant {
+			_, _ = fmt.Fprintln(w, "No admin users found for this tenant.")
+		} else {
+			_, _ = fmt.Fprintln(w, "No results found.")
+		}
 		return
 	}
 
-	fmt.Println()
-	fmt.Println("EMAIL")
-	fmt.Println("-----")
-	for _, line := range lines {
-		fmt.Println(line)
+	t := render.NewTable("EMAIL", "TENANT ID", "ACTIVE")
+	if kind == whois.KindTenant {
+		t = render.NewTable("EMAIL")
+	}
+	t.Underline = true
+	for _, line := range rows {
+		var cells []any
+		for _, cell := range strings.Split(line, "\t") {
+			cells = append(cells, cell)
+		}
+		t.Row(cells...)
+	}
+	_, _ = fmt.Fprintln(w)
+	_ = t.Write(w)
+}
+
+// tenantAdminEmails returns the active, non-API-key admin emails of a tenant.
+func tenantAdminEmails(c *kube.Cluster, pod, tenantID string) []string {
+	sql, err := whois.AdminsSQL(tenantID)
+	if err != nil {
+		log.Fatalf("%v", err)
 	}
+	return queryPod(c, pod, sql)
 }
@@ -0,0 +1,485 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"text/tabwriter"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/alembic"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/docker"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/git"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/paths"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/prompt"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/workspace"
+)
+
+// WorkspaceCreateOptions holds options for the workspace create subcommand.
+type WorkspaceCreateOptions struct {
+	From    string
+	Dir     string
+	Start   bool
+	Migrate bool
+}
+
+// WorkspaceSwitchOptions holds options for the workspace switch subcommand.
+type WorkspaceSwitchOptions struct {
+	Start      bool
+	StopOthers bool
+}
+
+// WorkspaceRemoveOptions holds options for the workspace remove subcommand.
+type WorkspaceRemoveOptions struct {
+	KeepWorktree bool
+	Force        bool
+	Yes          bool
+}
+
+// NewWorkspaceCommand creates the workspace command.
+func NewWorkspaceCommand() *cobra.Command {
+	cmd := &cobra.Command{
+		Use:   "workspace",
+		Short: "Give each branch its own worktree, dependency stack and database",
+		Long: `Give each branch its own worktree, dependency stack and database.
+
+A workspace is a git worktree of one branch beside the main checkout (e.g.
+~/src/onyx-feature-x for ~/src/onyx) with its own compose project, named
+after the worktree. The project has its own Postgres volume, so migrations
+run on a branch stay in that branch's database and switching branches does
+not mean re-migrating, or downgrading, the shared local database. Its ports
+are picked so it can run beside the main stack, and written to the
+worktree's .vscode/.env.
+
+To cd into a workspace as you switch, add to your shell profile:
+  ows() { cd "$(ods workspace switch "$@")"; }`,
+	}
+
+	cmd.AddCommand(newWorkspaceCreateCommand())
+	cmd.AddCommand(newWorkspaceSwitchCommand())
+	cmd.AddCommand(newWorkspaceListCommand())
+	cmd.AddCommand(newWorkspaceRemoveCommand())
+
+	return cmd
+}
+
+func newWorkspaceCreateCommand() *cobra.Command {
+	opts := &WorkspaceCreateOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "create <branch>",
+		Short: "Create a workspace for a branch",
+		Long: `Create a workspace for a branch: a worktree with the branch checked out, its
+.vscode/.env (copied from the main checkout's, with the workspace's ports),
+and its dependency stack (Postgres, Redis, OpenSearch and MinIO) with a
+fresh database migrated to the branch's head.
+
+The branch is checked out if it exists locally or on origin, and otherwise
+created from --from. A worktree that already has the branch checked out is
+adopted as it is.
+
+Examples:
+  ods workspace create feature-x
+  ods workspace create jane/sso-fix --from origin/main
+  ods workspace create feature-x --start=false`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			runWorkspaceCreate(opts, args[0])
+		},
+	}
+
+	cmd.Flags().StringVar(&opts.From, "from", "HEAD", "Commit to start a new branch from")
+	cmd.Flags().StringVar(&opts.Dir, "dir", "", "Where to put the worktree (default: beside the main checkout)")
+	cmd.Flags().BoolVar(&opts.Start, "start", true, "Start the workspace's dependency stack")
+	cmd.Flags().BoolVar(&opts.Migrate, "migrate", true, "Migrate the workspace's database once started")
+
+	return cmd
+}
+
+func newWorkspaceSwitchCommand() *cobra.Command {
+	opts := &WorkspaceSwitchOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "switch <workspace>",
+		Short: "Start a workspace's stack and print its directory",
+		Long: `Start a workspace's dependency stack, refresh the ports in its .vscode/.env and
+print its directory, the only thing written to stdout, for cd "$(...)".
+The workspace is given by name or by branch.
+
+--stop-others stops the stacks of the other workspaces, keeping their
+volumes, to free memory.
+
+Examples:
+  cd "$(ods workspace switch feature-x)"
+  ods workspace switch jane/sso-fix --stop-others`,
+		Args: cobra.ExactArgs(1),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return workspaceNames(), cobra.ShellCompDirectiveNoFileComp
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			runWorkspaceSwitch(opts, args[0])
+		},
+	}
+
+	cmd.Flags().BoolVar(&opts.Start, "start", true, "Start the workspace's dependency stack")
+	cmd.Flags().BoolVar(&opts.StopOthers, "stop-others", false, "Stop the other workspaces' stacks")
+
+	return cmd
+}
+
+func newWorkspaceListCommand() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List workspaces",
+		Args:  cobra.NoArgs,
+		Run: func(cmd *cobra.Command, args []string) {
+			runWorkspaceList()
+		},
+	}
+}
+
+func newWorkspaceRemoveCommand() *cobra.Command {
+	opts := &WorkspaceRemoveOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "remove <workspace>",
+		Short: "Remove a workspace, its stack and its database",
+		Long: `Remove a workspace: its containers and volumes, database included, and its
+worktree. The branch itself is kept.
+
+git refuses to remove a worktree with uncommitted changes unless --force is
+given.`,
+		Args: cobra.ExactArgs(1),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+			return workspaceNames(), cobra.ShellCompDirectiveNoFileComp
+		},
+		Run: func(cmd *cobra.Command, args []string) {
+			runWorkspaceRemove(opts, args[0])
+		},
+	}
+
+	cmd.Flags().BoolVar(&opts.KeepWorktree, "keep-worktree", false, "Keep the worktree, removing only the stack and database")
+	cmd.Flags().BoolVar(&opts.Force, "force", false, "Remove the worktree even with uncommitted changes")
+	cmd.Flags().BoolVar(&opts.Yes, "yes", false, "Skip the confirmation prompt")
+
+	return cmd
+}
+
+func loadWorkspaces() *workspace.Store {
+	store, err := workspace.LoadStore(paths.WorkspacesPath())
+	if err != nil {
+		log.Fatalf("Failed to load workspaces: %v", err)
+	}
+	return store
+}
+
+func findWorkspace(store *workspace.Store, ref string) *workspace.Workspace {
+	ws := store.Find(ref)
+	if ws == nil {
+		ws = store.Find(workspace.NameForBranch(ref))
+	}
+	if ws == nil {
+		log.Fatalf("No workspace %q; see ods workspace list, or make one with ods workspace create", ref)
+	}
+	return ws
+}
+
+func workspaceNames() []string {
+	store, err := workspace.LoadStore(paths.WorkspacesPath())
+	if err != nil {
+		return nil
+	}
+	names := make([]string, 0, len(store.Workspaces))
+	for _, ws := range store.Workspaces {
+		names = append(names, ws.Name)
+	}
+	return names
+}
+
+func runWorkspaceCreate(opts *WorkspaceCreateOptions, branch string) {
+	name := workspace.NameForBranch(branch)
+	if name == "" {
+		log.Fatalf("Cannot name a workspace after branch %q", branch)
+	}
+	store := loadWorkspaces()
+	if ws := store.Find(name); ws != nil {
+		log.Fatalf("Workspace %q already exists at %s; use ods workspace switch %s", name, ws.Dir, name)
+	}
+
+	mainRoot, err := git.MainWorktreeRoot()
+	if err != nil {
+		log.Fatalf("Failed to find the main checkout: %v", err)
+	}
+	worktrees, err := git.ListWorktrees()
+	if err != nil {
+		log.Fatalf("%v", err)
+	}
+
+	dir := ""
+	for _, wt := range worktrees {
+		if wt.Branch == branch {
+			dir = wt.Path
+		}
+	}
+	switch {
+	case dir == mainRoot:
+		log.Fatalf("%s is checked out in the main checkout; check out another branch there first", branch)
+	case dir != "":
+		log.Infof("Using the existing worktree of %s at %s", branch, dir)
+	default:
+		dir = opts.Dir
+		if dir == "" {
+			dir = workspace.DefaultDir(mainRoot, name)
+		}
+		if dir, err = filepath.Abs(dir); err != nil {
+			log.Fatalf("Failed to resolve %s: %v", opts.Dir, err)
+		}
+		if _, err := os.Stat(dir); err == nil {
+			log.Fatalf("%s already exists; pass --dir to put the worktree elsewhere", dir)
+		}
+		args := []string{"-C", mainRoot, "worktree", "add"}
+		switch {
+		case git.BranchExists(branch):
+			args = append(args, dir, branch)
+		case git.RemoteBranchExists(branch):
+			args = append(args, "--track", "-b", branch, dir, "origin/"+branch)
+		default:
+			log.Infof("Creating branch %s from %s", branch, opts.From)
+			args = append(args, "-b", branch, dir, opts.From)
+		}
+		log.Infof("Adding a worktree for %s at %s...", branch, dir)
+		if err := git.RunCommand(args...); err != nil {
+			log.Fatalf("Failed to add the worktree: %v", err)
+		}
+	}
+
+	ws := workspace.Workspace{
+		Name:    name,
+		Branch:  branch,
+		Dir:     dir,
+		Project: docker.ProjectNameForDir(dir),
+		Created: time.Now().UTC(),
+	}
+	if docker.IsDefaultProject(ws.Project) {
+		log.Fatalf("The worktree at %s would share the main checkout's compose project; pass --dir with another directory name", dir)
+	}
+	store.Put(ws)
+	if err := store.Save(); err != nil {
+		log.Fatalf("Failed to save workspaces: %v", err)
+	}
+
+	copyWorkspaceEnv(mainRoot, dir)
+	// Write the ports now, so the copied .env never points at the main
+	// checkout's database even before the stack is started.
+	writeWorkspaceEnv(&ws, reserveProjectPorts(ws.Project, dir, "dev").AppEnv())
+
+	if opts.Start {
+		startWorkspace(&ws)
+		if opts.Migrate {
+			migrateWorkspace(&ws)
+		}
+	}
+
+	log.Infof("Workspace %q is ready at %s", name, dir)
+	if !opts.Start {
+		log.Infof("Start its stack with: ods workspace switch %s", name)
+	}
+}
+
+// copyWorkspaceEnv seeds a new worktree's .vscode/.env with the main
+// checkout's, which holds the API keys and auth settings a worktree lacks.
+func copyWorkspaceEnv(mainRoot, dir string) {
+	dst := filepath.Join(dir, ".vscode", ".env")
+	if _, err := os.Stat(dst); err == nil {
+		return
+	}
+	data, err := os.ReadFile(filepath.Join(mainRoot, ".vscode", ".env"))
+	if errors.Is(err, fs.ErrNotExist) {
+		log.Warnf("The main checkout has no .vscode/.env; the workspace's will have its ports only")
+		return
+	}
+	if err != nil {
+		log.Fatalf("Failed to read the main checkout's .vscode/.env: %v", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		log.Fatalf("Failed to create %s: %v", filepath.Dir(dst), err)
+	}
+	if err := os.WriteFile(dst, data, 0644); err != nil {
+		log.Fatalf("Failed to write %s: %v", dst, err)
+	}
+}
+
+func writeWorkspaceEnv(ws *workspace.Workspace, env map[string]string) {
+	envPath := filepath.Join(ws.Dir, ".vscode", ".env")
+	if err := os.MkdirAll(filepath.Dir(envPath), 0755); err != nil {
+		log.Fatalf("Failed to create %s: %v", filepath.Dir(envPath), err)
+	}
+	if err := setEnvValues(envPath, env); err != nil {
+		log.Fatalf("Failed to update %s: %v", envPath, err)
+	}
+	log.Infof("Updated %s", envPath)
+}
+
+// useWorkspace points ods compose at the workspace's worktree and project.
+func useWorkspace(ws *workspace.Workspace) {
+	if err := os.Chdir(ws.Dir); err != nil {
+		log.Fatalf("Failed to enter workspace %q: %v", ws.Name, err)
+	}
+	docker.SetProjectFlags(ws.Project)
+}
+
+// startWorkspace starts the workspace's dependency stack and writes the
+// ports it got to its .vscode/.env.
+func startWorkspace(ws *workspace.Workspace) {
+	useWorkspace(ws)
+	runCompose("dev", &ComposeOptions{Deps: true, Wait: true})
+	writeWorkspaceEnv(ws, queryContainerPorts(ws.Project).AppEnv())
+}
+
+// migrateWorkspace upgrades the workspace's fresh database to the branch's
+// head. Failing that is not fatal: the worktree may lack a venv.
+func migrateWorkspace(ws *workspace.Workspace) {
+	useWorkspace(ws)
+	env := queryContainerPorts(ws.Project).AppEnv()
+	_ = os.Setenv("POSTGRES_HOST", "localhost")
+	_ = os.Setenv("POSTGRES_PORT", env["POSTGRES_PORT"])
+	log.Infof("Migrating the database of workspace %q...", ws.Name)
+	if err := alembic.Upgrade("head", alembic.SchemaDefault); err != nil {
+		log.Warnf("Failed to migrate the workspace's database: %v", err)
+		log.Warnf("Run `ods db upgrade` in %s once its venv is set up", ws.Dir)
+	}
+}
+
+func runWorkspaceSwitch(opts *WorkspaceSwitchOptions, ref string) {
+	store := loadWorkspaces()
+	ws := findWorkspace(store, ref)
+	if _, err := os.Stat(ws.Dir); err != nil {
+		log.Fatalf("The worktree of workspace %q is gone (%v); remove it with ods workspace remove %s", ws.Name, err, ws.Name)
+	}
+
+	// Keep stdout for the directory: docker compose's output goes to stderr.
+	stdout := os.Stdout
+	os.Stdout = os.Stderr
+	if opts.StopOthers {
+		for i := range store.Workspaces {
+			other := &store.Workspaces[i]
+			if other.Name == ws.Name {
+				continue
+			}
+			if _, err := os.Stat(other.Dir); err != nil {
+				continue
+			}
+			useWorkspace(other)
+			runCompose("dev", &ComposeOptions{Down: true})
+		}
+	}
+	if opts.Start {
+		startWorkspace(ws)
+	}
+	os.Stdout = stdout
+
+	fmt.Println(ws.Dir)
+}
+
+func runWorkspaceList() {
+	store := loadWorkspaces()
+	if len(store.Workspaces) == 0 {
+		log.Info("No workspaces; make one with ods workspace create <branch>")
+		return
+	}
+	reg, err := docker.LoadRegistry(paths.ComposeProjectsPath())
+	if err != nil {
+		log.Fatalf("Failed to load the compose project registry: %v", err)
+	}
+	status := map[string]string{}
+	if projects, err := docker.ComposeProjects(); err != nil {
+		log.Warnf("%v", err)
+	} else {
+		for _, p := range projects {
+			status[p.Name] = p.Status
+		}
+	}
+
+	current, _ := paths.GitRoot()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "WORKSPACE\tBRANCH\tSTACK\tPOSTGRES\tDIR")
+	_, _ = fmt.Fprintln(w, "---------\t------\t-----\t--------\t---")
+	for _, ws := range store.Workspaces {
+		label := ws.Name
+		if ws.Dir == current {
+			label += " *"
+		}
+		stack := status[ws.Project]
+		if stack == "" {
+			stack = "stopped"
+		}
+		if _, err := os.Stat(ws.Dir); err != nil {
+			stack = "worktree missing"
+		}
+		pg := "-"
+		if rec := reg.Get(ws.Project); rec != nil && rec.Ports["POSTGRES_HOST_PORT"] != 0 {
+			pg = strconv.Itoa(rec.Ports["POSTGRES_HOST_PORT"])
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", label, ws.Branch, stack, pg, ws.Dir)
+	}
+	_ = w.Flush()
+}
+
+func runWorkspaceRemove(opts *WorkspaceRemoveOptions, ref string) {
+	store := loadWorkspaces()
+	ws := findWorkspace(store, ref)
+
+	if !opts.Yes {
+		what := "its containers, volumes and worktree"
+		if opts.KeepWorktree {
+			what = "its containers and volumes"
+		}
+		if !prompt.Confirm(fmt.Sprintf("Remove workspace %q with %s? (yes/no): ", ws.Name, what)) {
+			log.Info("Aborted.")
+			return
+		}
+	}
+
+	mainRoot, err := git.MainWorktreeRoot()
+	if err != nil {
+		log.Fatalf("Failed to find the main checkout: %v", err)
+	}
+	if _, err := os.Stat(ws.Dir); err == nil {
+		useWorkspace(ws)
+	} else {
+		// The compose files are the same in any checkout.
+		if err := os.Chdir(mainRoot); err != nil {
+			log.Fatalf("%v", err)
+		}
+		docker.SetProjectFlags(ws.Project)
+	}
+	runCompose("dev", &ComposeOptions{Down: true, Volumes: true})
+
+	if !opts.KeepWorktree {
+		if err := os.Chdir(mainRoot); err != nil {
+			log.Fatalf("%v", err)
+		}
+		args := []string{"worktree", "remove", ws.Dir}
+		if opts.Force {
+			args = append(args, "--force")
+		}
+		if _, err := os.Stat(ws.Dir); err != nil {
+			args = []string{"worktree", "prune"}
+		}
+		if err := git.RunCommand(args...); err != nil {
+			log.Fatalf("Failed to remove the worktree (pass --force to discard its changes): %v", err)
+		}
+	}
+
+	store.Remove(ws.Name)
+	if err := store.Save(); err != nil {
+		log.Fatalf("Failed to save workspaces: %v", err)
+	}
+	log.Infof("Removed workspace %q; branch %s is kept", ws.Name, ws.Branch)
+}
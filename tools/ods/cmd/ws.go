@@ -0,0 +1,183 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/api"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/chatstream"
+)
+
+// WSOptions holds options for the ws command.
+type WSOptions struct {
+	APISessionOptions
+	Persona      int
+	Session      string
+	DeepResearch bool
+	Raw          bool
+	Heartbeats   bool
+	Timeout      time.Duration
+}
+
+// NewWSCommand creates the ws command for exercising the chat stream.
+func NewWSCommand() *cobra.Command {
+	opts := &WSOptions{}
+
+	cmd := &cobra.Command{
+		Use:   "ws <message>",
+		Short: "Send a chat message and render the streamed answer with timings",
+		Long: `Send a chat message and render the streamed answer with timings.
+
+Posts the message to /chat/send-chat-message and reads the answer stream
+(newline-delimited JSON packets) as it arrives. Each change of packet type is
+annotated with the time since the request and the packet's turn; answer and
+reasoning text is printed as it streams. A table of per-type counts and
+first/last arrival times follows, with the time to the first answer token.
+
+--raw prints every frame as received, prefixed with its arrival time.
+
+The API server and auth are chosen as for ` + "`ods curl`" + `. A new chat session
+is created for the message unless --session is given.
+
+Examples:
+  ods ws "What is our PTO policy?"
+  ods ws "Summarize the Q3 roadmap" --persona 3 --raw
+  ods ws "hello" -c staging --tenant tenant_abcd1234 --reason SUP-1234`,
+		Args: cobra.ExactArgs(1),
+		Run: func(cmd *cobra.Command, args []string) {
+			runWS(opts, args[0])
+		},
+	}
+
+	addAPISessionFlags(cmd, &opts.APISessionOptions)
+	cmd.Flags().IntVar(&opts.Persona, "persona", 0, "Persona (assistant) ID for a new chat session")
+	cmd.Flags().StringVar(&opts.Session, "session", "", "Existing chat session ID to continue")
+	cmd.Flags().BoolVar(&opts.DeepResearch, "deep-research", false, "Answer with deep research")
+	cmd.Flags().BoolVar(&opts.Raw, "raw", false, "Print every frame as received")
+	cmd.Flags().BoolVar(&opts.Heartbeats, "heartbeats", false, "Show heartbeat packets (always shown with --raw)")
+	cmd.Flags().DurationVar(&opts.Timeout, "timeout", 10*time.Minute, "Give up if the stream has not finished by then")
+
+	return cmd
+}
+
+func runWS(opts *WSOptions, message string) {
+	req := api.SendMessageRequest{Message: message, DeepResearch: &opts.DeepResearch}
+	if opts.Session != "" {
+		req.ChatSessionID = &opts.Session
+	} else {
+		req.ChatSessionInfo = &api.ChatSessionCreationRequest{PersonaID: &opts.Persona}
+	}
+
+	session := openAPISession(&opts.APISessionOptions, "ws.impersonate")
+	defer session.Close()
+	session.Client.HTTP.Timeout = opts.Timeout
+
+	log.Infof("Sending to %s...", session.Target)
+	start := time.Now()
+	stream, err := api.New(session.Client).StreamChatMessage(req)
+	if err != nil {
+		session.Close()
+		log.Fatalf("%v", err)
+	}
+	defer func() { _ = stream.Close() }()
+	log.Infof("Response headers after %s", formatOffset(time.Since(start)))
+
+	var stats chatstream.Stats
+	r := &streamRenderer{raw: opts.Raw, heartbeats: opts.Heartbeats || opts.Raw}
+	readErr := chatstream.Read(stream, start, func(f chatstream.Frame) error {
+		stats.Add(f)
+		r.render(f)
+		return nil
+	})
+	r.endText()
+	total := time.Since(start)
+
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, "TYPE\tCOUNT\tFIRST\tLAST\tCHARS")
+	_, _ = fmt.Fprintln(w, "----\t-----\t-----\t----\t-----")
+	for _, t := range stats.Types() {
+		chars := "-"
+		if t.Chars > 0 {
+			chars = fmt.Sprint(t.Chars)
+		}
+		_, _ = fmt.Fprintf(w, "%s\t%d\t%s\t%s\t%s\n", t.Type, t.Count, formatOffset(t.First), formatOffset(t.Last), chars)
+	}
+	_ = w.Flush()
+	fmt.Println()
+	if ttft, ok := stats.FirstToken(); ok {
+		fmt.Printf("First answer token: %s\n", formatOffset(ttft))
+	}
+	fmt.Printf("Total:              %s\n", formatOffset(total))
+
+	if readErr != nil {
+		session.Close()
+		log.Fatalf("Stream broke off after %s: %v", formatOffset(total), readErr)
+	}
+	if r.errors > 0 {
+		session.Close()
+		log.Fatalf("The stream reported %d error(s)", r.errors)
+	}
+}
+
+// streamRenderer prints frames as they arrive: an annotation per change of
+// packet type and the text of delta packets inline.
+type streamRenderer struct {
+	raw        bool
+	heartbeats bool
+
+	lastType string
+	inText   bool
+	errors   int
+}
+
+func (r *streamRenderer) render(f chatstream.Frame) {
+	if f.Type == chatstream.TypeError {
+		r.errors++
+	}
+	if r.raw {
+		fmt.Printf("[%s] %s\n", formatOffset(f.At), f.Raw)
+		return
+	}
+	if f.Type == "chat_heartbeat" && !r.heartbeats {
+		return
+	}
+	if f.IsDelta() && f.Type == r.lastType {
+		fmt.Print(f.Text)
+		return
+	}
+
+	r.endText()
+	annotation := fmt.Sprintf("[%s] %s", formatOffset(f.At), f.Type)
+	if f.Turn != nil {
+		annotation += fmt.Sprintf(" (turn %d)", *f.Turn)
+	}
+	switch {
+	case f.IsDelta():
+		fmt.Println(annotation)
+		fmt.Print(f.Text)
+		r.inText = true
+	case f.Text != "":
+		fmt.Printf("%s: %s\n", annotation, f.Text)
+	default:
+		fmt.Println(annotation)
+	}
+	r.lastType = f.Type
+}
+
+func (r *streamRenderer) endText() {
+	if r.inText {
+		fmt.Println()
+		r.inText = false
+	}
+}
+
+// formatOffset renders a duration since the request, e.g. "+1.234s".
+func formatOffset(d time.Duration) string {
+	return fmt.Sprintf("+%.3fs", d.Seconds())
+}
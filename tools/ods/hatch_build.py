@@ -34,6 +34,14 @@ class CustomBuildHook(BuildHookInterface):
         if not os.path.exists(binary_name):
             print(f"Building Go binary '{binary_name}'...")
             ldflags = f"-X main.version={tag} -X main.commit={commit} -s -w"
+            # Only the release pipeline sets an endpoint; other builds ship
+            # without telemetry.
+            endpoint = os.getenv("ODS_TELEMETRY_ENDPOINT", "")
+            if endpoint:
+                ldflags += (
+                    " -X github.com/onyx-dot-app/onyx/tools/ods/internal/telemetry"
+                    f".defaultEndpoint={endpoint}"
+                )
             subprocess.check_call(  # noqa: S603
                 ["go", "build", f"-ldflags={ldflags}", "-o", binary_name],
             )
@@ -0,0 +1,351 @@
+// Package access explains whether a user can see a document and reports the
+// health of permission sync, which copies access rules from connector
+// sources (Google Drive shares, Confluence spaces, ...) into Onyx.
+package access
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/kube"
+)
+
+//go:embed access.py
+var accessScript string
+
+// ACL entry prefixes, as built by onyx.access.utils.
+const (
+	publicEntry         = "PUBLIC"
+	userEmailPrefix     = "user_email:"
+	groupPrefix         = "group:"
+	externalGroupPrefix = "external_group:"
+)
+
+// staleAfter is how old a connector's last permission sync may get before
+// sync-status flags it. Sync frequencies are per source, but none of the
+// defaults is longer than a day.
+const staleAfter = 24 * time.Hour
+
+// stuckAfter is how long an attempt may stay in progress before it counts
+// as stuck.
+const stuckAfter = 6 * time.Hour
+
+// User is the user whose access is checked.
+type User struct {
+	Email    string `json:"email"`
+	Role     string `json:"role"`
+	IsActive bool   `json:"is_active"`
+	// ACL is every entry the user holds, as the backend matches it at query
+	// time.
+	ACL []string `json:"acl"`
+}
+
+// Document is the document whose access is checked.
+type Document struct {
+	ID                   string   `json:"id"`
+	SemanticID           string   `json:"semantic_id"`
+	IsPublic             bool     `json:"is_public"`
+	ExternalUserEmails   []string `json:"external_user_emails"`
+	ExternalUserGroupIDs []string `json:"external_user_group_ids"`
+	// ACL is the access list the backend computes for the document.
+	ACL        []string    `json:"acl"`
+	Connectors []Connector `json:"connectors"`
+}
+
+// Connector is a connector/credential pair that indexes the document.
+type Connector struct {
+	CCPairID   int    `json:"cc_pair_id"`
+	Name       string `json:"name"`
+	Source     string `json:"source"`
+	AccessType string `json:"access_type"`
+}
+
+// ExternalGroup is a user's membership of a group synced from a source.
+type ExternalGroup struct {
+	ID       string `json:"id"`
+	CCPairID int    `json:"cc_pair_id"`
+	CCPair   string `json:"cc_pair"`
+	// Stale memberships were not seen by the latest group sync and are
+	// removed when it finishes.
+	Stale bool `json:"stale"`
+}
+
+// Check is everything needed to decide whether a user can see a document.
+type Check struct {
+	User           User            `json:"user"`
+	Document       Document        `json:"document"`
+	Groups         []string        `json:"groups"`
+	ExternalGroups []ExternalGroup `json:"external_groups"`
+	// PublicExternalGroups are groups a source marks as open to everyone
+	// ("anyone with the link").
+	PublicExternalGroups []string `json:"public_external_groups"`
+}
+
+// Rule is one ACL entry that grants access, with why the user holds it.
+type Rule struct {
+	Entry  string
+	Reason string
+}
+
+// Decision is the outcome of a Check.
+type Decision struct {
+	Allowed bool
+	// Grants lists every entry the user and document share. Access needs
+	// only one.
+	Grants []Rule
+	// Hints explain a denial, or caveats of a grant, most likely cause
+	// first.
+	Hints []string
+}
+
+// CheckAccess gathers what is needed to decide whether the user with email
+// can see the document with ID docID in schema ("" for the default schema of
+// a single-tenant deployment).
+func CheckAccess(c *kube.Cluster, pod, schema, email, docID string) (*Check, error) {
+	var r Check
+	if err := run(c, pod, &r, "check", schema, email, docID); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// Evaluate matches the user's entries against the document's access list,
+// as search does: the user sees the document if any entry is in both.
+func (c *Check) Evaluate() Decision {
+	var d Decision
+	for _, entry := range c.Document.ACL {
+		if slices.Contains(c.User.ACL, entry) {
+			d.Grants = append(d.Grants, Rule{Entry: entry, Reason: c.reason(entry)})
+		}
+	}
+	d.Allowed = len(d.Grants) > 0
+
+	if !c.User.IsActive {
+		d.Hints = append(d.Hints, "The user is deactivated and cannot sign in")
+	}
+	if d.Allowed {
+		for _, g := range d.Grants {
+			if ext := c.externalGroup(g.Entry); ext != nil && ext.Stale {
+				d.Hints = append(d.Hints, fmt.Sprintf("%s is stale and goes away when the running group sync of %q finishes", g.Entry, ext.CCPair))
+			}
+		}
+		return d
+	}
+	return c.denialHints(d)
+}
+
+func (c *Check) denialHints(d Decision) Decision {
+	if len(c.Document.ACL) == 0 {
+		d.Hints = append(d.Hints, "The document's access list is empty; no user can see it")
+		return d
+	}
+
+	email := strings.ToLower(c.User.Email)
+	for _, entry := range c.Document.ACL {
+		if strings.HasPrefix(entry, userEmailPrefix) && strings.ToLower(strings.TrimPrefix(entry, userEmailPrefix)) == email {
+			d.Hints = append(d.Hints, fmt.Sprintf("The document lists %s, which differs from the user's email only in case", entry))
+		}
+	}
+
+	memberships := map[int]int{}
+	for _, g := range c.ExternalGroups {
+		memberships[g.CCPairID]++
+	}
+	for _, cc := range c.Document.Connectors {
+		if cc.AccessType != "sync" {
+			continue
+		}
+		if memberships[cc.CCPairID] == 0 {
+			d.Hints = append(d.Hints, fmt.Sprintf("Group sync of %q (%s) gave the user no external groups; it may not have run, or the user's email at the source differs from %s", cc.Name, cc.Source, c.User.Email))
+		}
+	}
+
+	var wantGroups []string
+	for _, entry := range c.Document.ACL {
+		if strings.HasPrefix(entry, externalGroupPrefix) {
+			wantGroups = append(wantGroups, strings.TrimPrefix(entry, externalGroupPrefix))
+		}
+	}
+	if len(wantGroups) > 0 {
+		d.Hints = append(d.Hints, fmt.Sprintf("The user is in none of the document's %d external group(s): %s", len(wantGroups), strings.Join(wantGroups, ", ")))
+	}
+	for _, id := range c.Document.ExternalUserGroupIDs {
+		if slices.Contains(c.PublicExternalGroups, id) && !c.Document.IsPublic {
+			d.Hints = append(d.Hints, fmt.Sprintf("External group %s is public at the source but the document is not marked public; its permissions need a re-sync", id))
+		}
+	}
+
+	onyxGroups := 0
+	for _, entry := range c.Document.ACL {
+		if strings.HasPrefix(entry, groupPrefix) {
+			onyxGroups++
+		}
+	}
+	if onyxGroups > 0 && !slices.ContainsFunc(c.User.ACL, func(e string) bool { return strings.HasPrefix(e, groupPrefix) }) && len(c.Groups) > 0 {
+		d.Hints = append(d.Hints, "The user is in Onyx groups but holds no group entries; this deployment may not run the enterprise edition")
+	}
+	if len(d.Hints) == 0 {
+		d.Hints = append(d.Hints, "The user shares no entry with the document's access list")
+	}
+	return d
+}
+
+// reason explains why the user holds entry.
+func (c *Check) reason(entry string) string {
+	switch {
+	case entry == publicEntry:
+		return "the document is public"
+	case strings.HasPrefix(entry, userEmailPrefix):
+		return "the document lists the user by email"
+	case strings.HasPrefix(entry, groupPrefix):
+		return fmt.Sprintf("the user is in Onyx group %q", strings.TrimPrefix(entry, groupPrefix))
+	case strings.HasPrefix(entry, externalGroupPrefix):
+		if ext := c.externalGroup(entry); ext != nil {
+			return fmt.Sprintf("group sync of %q put the user in external group %s", ext.CCPair, ext.ID)
+		}
+		return "the user is in external group " + strings.TrimPrefix(entry, externalGroupPrefix)
+	}
+	return "the user holds this entry"
+}
+
+// externalGroup returns the membership behind an external_group: entry.
+func (c *Check) externalGroup(entry string) *ExternalGroup {
+	id, ok := strings.CutPrefix(entry, externalGroupPrefix)
+	if !ok {
+		return nil
+	}
+	for i := range c.ExternalGroups {
+		if c.ExternalGroups[i].ID == id {
+			return &c.ExternalGroups[i]
+		}
+	}
+	return nil
+}
+
+// Attempt is a permission sync attempt.
+type Attempt struct {
+	ID       int        `json:"id"`
+	Status   string     `json:"status"`
+	Created  *time.Time `json:"created"`
+	Started  *time.Time `json:"started"`
+	Finished *time.Time `json:"finished"`
+	Error    string     `json:"error"`
+	// Set for document syncs.
+	DocsSynced     int `json:"docs_synced"`
+	DocsWithErrors int `json:"docs_with_errors"`
+	// Set for group syncs.
+	UsersProcessed  int `json:"users_processed"`
+	GroupsProcessed int `json:"groups_processed"`
+}
+
+// Failed reports whether the attempt ended without syncing everything.
+func (a *Attempt) Failed() bool {
+	return a != nil && (a.Status == "failed" || a.Status == "completed_with_errors")
+}
+
+// Stuck reports whether the attempt has been in progress for longer than
+// stuckAfter as of now.
+func (a *Attempt) Stuck(now time.Time) bool {
+	return a != nil && a.Status == "in_progress" && a.Started != nil && now.Sub(*a.Started) > stuckAfter
+}
+
+// SyncPair is a connector/credential pair that syncs permissions.
+type SyncPair struct {
+	CCPairID      int        `json:"cc_pair_id"`
+	Name          string     `json:"name"`
+	Source        string     `json:"source"`
+	Status        string     `json:"status"`
+	LastDocSync   *time.Time `json:"last_doc_sync"`
+	LastGroupSync *time.Time `json:"last_group_sync"`
+	DocAttempt    *Attempt   `json:"doc_attempt"`
+	GroupAttempt  *Attempt   `json:"group_attempt"`
+}
+
+// Problems lists what is wrong with the pair's permission sync as of now.
+// Paused or deleting pairs are not expected to sync.
+func (p SyncPair) Problems(now time.Time) []string {
+	if p.Status == "PAUSED" || p.Status == "DELETING" {
+		return nil
+	}
+	var problems []string
+	for _, s := range []struct {
+		kind    string
+		last    *time.Time
+		attempt *Attempt
+	}{
+		{"document", p.LastDocSync, p.DocAttempt},
+		{"group", p.LastGroupSync, p.GroupAttempt},
+	} {
+		switch {
+		case s.attempt.Failed():
+			msg := fmt.Sprintf("last %s sync %s", s.kind, strings.ReplaceAll(s.attempt.Status, "_", " "))
+			if s.attempt.Error != "" {
+				msg += ": " + firstLine(s.attempt.Error)
+			}
+			problems = append(problems, msg)
+		case s.attempt.Stuck(now):
+			problems = append(problems, fmt.Sprintf("%s sync in progress since %s", s.kind, s.attempt.Started.UTC().Format(time.RFC3339)))
+		}
+		switch {
+		case s.last == nil:
+			problems = append(problems, fmt.Sprintf("%s permissions never synced", s.kind))
+		case now.Sub(*s.last) > staleAfter:
+			problems = append(problems, fmt.Sprintf("%s permissions last synced %s ago", s.kind, now.Sub(*s.last).Round(time.Hour)))
+		}
+	}
+	if p.DocAttempt != nil && p.DocAttempt.DocsWithErrors > 0 && !p.DocAttempt.Failed() {
+		problems = append(problems, fmt.Sprintf("%d document(s) failed to sync permissions", p.DocAttempt.DocsWithErrors))
+	}
+	return problems
+}
+
+// SyncStatus is the permission sync state of a tenant.
+type SyncStatus struct {
+	Pairs []SyncPair `json:"pairs"`
+	// GlobalGroupSync is the latest group sync not tied to a connector, if
+	// any.
+	GlobalGroupSync *Attempt `json:"global_group_sync"`
+}
+
+// Status reports the permission sync state of schema ("" for the default
+// schema of a single-tenant deployment).
+func Status(c *kube.Cluster, pod, schema string) (*SyncStatus, error) {
+	var r SyncStatus
+	if err := run(c, pod, &r, "sync-status", schema); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	return line
+}
+
+func run(c *kube.Cluster, pod string, out any, args ...string) error {
+	stdout, err := c.RunPython(pod, accessScript, args...)
+	if err != nil {
+		return err
+	}
+	return parseResult(stdout, out)
+}
+
+func parseResult(stdout string, out any) error {
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	last := strings.TrimSpace(lines[len(lines)-1])
+	var r struct {
+		Status  string `json:"status"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal([]byte(last), &r); err != nil {
+		return fmt.Errorf("unexpected output from access script: %q", last)
+	}
+	if r.Status != "success" {
+		return fmt.Errorf("%s", r.Message)
+	}
+	return json.Unmarshal([]byte(last), out)
+}
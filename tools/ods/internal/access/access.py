@@ -0,0 +1,277 @@
+"""Explain a user's access to a document, or report permission sync health.
+
+Bundled with ods and piped into `python -` on an api-server pod by
+`ods access`. `check` gathers the access list the backend computes for a
+document and the entries a user holds (their email, Onyx groups and the
+external groups permission sync attached them to), so the two can be
+compared. `sync-status` reports every connector that syncs permissions
+from its source, with its latest document and group sync attempts.
+
+Usage:
+    python - check <schema> <email> <document id>
+    python - sync-status <schema>
+
+An empty <schema> means the default schema of a single-tenant deployment.
+
+Progress goes to stderr; the last line on stdout is a JSON object with
+"status" and "user", "document", "groups" and "external_groups" (check) or
+"pairs" and "global_group_sync" (sync-status).
+"""
+
+from __future__ import annotations
+
+import json
+import sys
+from datetime import datetime
+from typing import Any
+
+
+def iso(dt: datetime | None) -> str | None:
+    return dt.isoformat() if dt is not None else None
+
+
+def use_schema(schema: str) -> str:
+    from onyx.db.engine.tenant_utils import validate_tenant_id
+    from shared_configs.configs import MULTI_TENANT
+    from shared_configs.configs import POSTGRES_DEFAULT_SCHEMA
+    from shared_configs.contextvars import CURRENT_TENANT_ID_CONTEXTVAR
+
+    if not schema:
+        if MULTI_TENANT:
+            raise ValueError("This deployment is multi-tenant; pass --tenant")
+        schema = POSTGRES_DEFAULT_SCHEMA
+    elif schema != POSTGRES_DEFAULT_SCHEMA and not validate_tenant_id(schema):
+        raise ValueError(f"Invalid schema {schema!r}")
+    CURRENT_TENANT_ID_CONTEXTVAR.set(schema)
+    return schema
+
+
+def onyx_groups(db_session: Any, user_id: Any) -> list[str]:
+    from sqlalchemy import select
+
+    from onyx.db.models import User__UserGroup
+    from onyx.db.models import UserGroup
+
+    return sorted(
+        db_session.scalars(
+            select(UserGroup.name)
+            .join(User__UserGroup, User__UserGroup.user_group_id == UserGroup.id)
+            .where(User__UserGroup.user_id == user_id)
+        )
+    )
+
+
+def external_groups(db_session: Any, user_id: Any) -> list[dict[str, Any]]:
+    from sqlalchemy import select
+
+    from onyx.db.models import ConnectorCredentialPair
+    from onyx.db.models import User__ExternalUserGroupId
+
+    rows = db_session.execute(
+        select(User__ExternalUserGroupId, ConnectorCredentialPair)
+        .join(
+            ConnectorCredentialPair,
+            ConnectorCredentialPair.id == User__ExternalUserGroupId.cc_pair_id,
+        )
+        .where(User__ExternalUserGroupId.user_id == user_id)
+    ).all()
+    return sorted(
+        (
+            {
+                "id": membership.external_user_group_id,
+                "cc_pair_id": cc_pair.id,
+                "cc_pair": cc_pair.name,
+                "stale": bool(membership.stale),
+            }
+            for membership, cc_pair in rows
+        ),
+        key=lambda g: (g["cc_pair_id"], g["id"]),
+    )
+
+
+def public_groups(db_session: Any) -> list[str]:
+    from sqlalchemy import select
+
+    from onyx.db.models import PublicExternalUserGroup
+
+    return sorted(
+        set(db_session.scalars(select(PublicExternalUserGroup.external_user_group_id)))
+    )
+
+
+def sync_pairs(db_session: Any, doc_id: str) -> list[dict[str, Any]]:
+    from sqlalchemy import and_
+    from sqlalchemy import select
+
+    from onyx.db.models import Connector
+    from onyx.db.models import ConnectorCredentialPair
+    from onyx.db.models import DocumentByConnectorCredentialPair as DocByCC
+
+    rows = db_session.execute(
+        select(ConnectorCredentialPair, Connector)
+        .join(
+            DocByCC,
+            and_(
+                ConnectorCredentialPair.connector_id == DocByCC.connector_id,
+                ConnectorCredentialPair.credential_id == DocByCC.credential_id,
+            ),
+        )
+        .join(Connector, Connector.id == ConnectorCredentialPair.connector_id)
+        .where(DocByCC.id == doc_id)
+    ).all()
+    return [
+        {
+            "cc_pair_id": cc_pair.id,
+            "name": cc_pair.name,
+            "source": connector.source.value,
+            "access_type": cc_pair.access_type.value,
+        }
+        for cc_pair, connector in rows
+    ]
+
+
+def check(schema: str, email: str, doc_id: str) -> dict[str, Any]:
+    from onyx.access.access import get_access_for_documents
+    from onyx.access.access import get_acl_for_user
+    from onyx.db.engine.sql_engine import get_session_with_current_tenant
+    from onyx.db.models import Document
+    from onyx.db.users import get_user_by_email
+
+    use_schema(schema)
+    with get_session_with_current_tenant() as db_session:
+        user = get_user_by_email(email, db_session)
+        if user is None:
+            return {"status": "error", "message": f"No user with email {email!r}"}
+        doc = db_session.get(Document, doc_id)
+        if doc is None:
+            return {"status": "error", "message": f"No document with ID {doc_id!r}"}
+        access = get_access_for_documents([doc.id], db_session).get(doc.id)
+
+        return {
+            "status": "success",
+            "user": {
+                "email": user.email,
+                "role": user.role.value,
+                "is_active": bool(user.is_active),
+                "acl": sorted(get_acl_for_user(user, db_session)),
+            },
+            "document": {
+                "id": doc.id,
+                "semantic_id": doc.semantic_id,
+                "is_public": bool(doc.is_public),
+                "external_user_emails": sorted(doc.external_user_emails or []),
+                "external_user_group_ids": sorted(doc.external_user_group_ids or []),
+                "acl": sorted(access.to_acl()) if access is not None else [],
+                "connectors": sync_pairs(db_session, doc.id),
+            },
+            "groups": onyx_groups(db_session, user.id),
+            "external_groups": external_groups(db_session, user.id),
+            "public_external_groups": public_groups(db_session),
+        }
+
+
+def attempt(row: Any, **counts: int | None) -> dict[str, Any]:
+    return {
+        "id": row.id,
+        "status": row.status.value,
+        "created": iso(row.time_created),
+        "started": iso(row.time_started),
+        "finished": iso(row.time_finished),
+        "error": row.error_message or "",
+        **{k: v or 0 for k, v in counts.items()},
+    }
+
+
+def sync_status(schema: str) -> dict[str, Any]:
+    from sqlalchemy import select
+
+    from onyx.db.engine.sql_engine import get_session_with_current_tenant
+    from onyx.db.enums import AccessType
+    from onyx.db.models import ConnectorCredentialPair
+    from onyx.db.models import DocPermissionSyncAttempt as DocAttempt
+    from onyx.db.models import ExternalGroupPermissionSyncAttempt as GroupAttempt
+
+    def latest_doc(cc_pair_id: int) -> dict[str, Any] | None:
+        row = db_session.scalars(
+            select(DocAttempt)
+            .where(DocAttempt.connector_credential_pair_id == cc_pair_id)
+            .order_by(DocAttempt.time_created.desc())
+            .limit(1)
+        ).first()
+        if row is None:
+            return None
+        return attempt(
+            row,
+            docs_synced=row.total_docs_synced,
+            docs_with_errors=row.docs_with_permission_errors,
+        )
+
+    def latest_group(cc_pair_id: int | None) -> dict[str, Any] | None:
+        # Group syncs not tied to a connector have no cc_pair_id.
+        column = GroupAttempt.connector_credential_pair_id
+        row = db_session.scalars(
+            select(GroupAttempt)
+            .where(column.is_(None) if cc_pair_id is None else column == cc_pair_id)
+            .order_by(GroupAttempt.time_created.desc())
+            .limit(1)
+        ).first()
+        if row is None:
+            return None
+        return attempt(
+            row,
+            users_processed=row.total_users_processed,
+            groups_processed=row.total_groups_processed,
+        )
+
+    use_schema(schema)
+    with get_session_with_current_tenant() as db_session:
+        cc_pairs = db_session.scalars(
+            select(ConnectorCredentialPair)
+            .where(ConnectorCredentialPair.access_type == AccessType.SYNC)
+            .order_by(ConnectorCredentialPair.id)
+        ).all()
+        pairs = [
+            {
+                "cc_pair_id": cc_pair.id,
+                "name": cc_pair.name,
+                "source": cc_pair.connector.source.value,
+                "status": cc_pair.status.value,
+                "last_doc_sync": iso(cc_pair.last_time_perm_sync),
+                "last_group_sync": iso(cc_pair.last_time_external_group_sync),
+                "doc_attempt": latest_doc(cc_pair.id),
+                "group_attempt": latest_group(cc_pair.id),
+            }
+            for cc_pair in cc_pairs
+        ]
+        return {
+            "status": "success",
+            "pairs": pairs,
+            "global_group_sync": latest_group(None),
+        }
+
+
+def main() -> None:
+    usage = "Usage: python - check <schema> <email> <document id> | sync-status <schema>"
+    args = sys.argv[1:]
+    arity = {"check": 4, "sync-status": 2}
+    if not args or arity.get(args[0]) != len(args):
+        print(json.dumps({"status": "error", "message": usage}))
+        sys.exit(1)
+
+    from onyx.db.engine.sql_engine import SqlEngine
+
+    SqlEngine.init_engine(pool_size=5, max_overflow=2)
+
+    try:
+        if args[0] == "check":
+            result = check(args[1], args[2], args[3])
+        else:
+            result = sync_status(args[1])
+    except Exception as e:
+        print(f"Error: {e}", file=sys.stderr)
+        result = {"status": "error", "message": str(e)}
+    print(json.dumps(result))
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,115 @@
+package access
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestEvaluateGranted(t *testing.T) {
+	c := Check{
+		User: User{Email: "ann@example.com", IsActive: true, ACL: []string{
+			"PUBLIC", "user_email:ann@example.com", "external_group:confluence_eng", "group:Support",
+		}},
+		Document: Document{ACL: []string{"external_group:confluence_eng", "group:Support", "user_email:bob@example.com"}},
+		ExternalGroups: []ExternalGroup{
+			{ID: "confluence_eng", CCPairID: 3, CCPair: "Confluence", Stale: true},
+		},
+	}
+	d := c.Evaluate()
+	if !d.Allowed {
+		t.Fatal("expected access through a shared entry")
+	}
+	if len(d.Grants) != 2 {
+		t.Fatalf("expected 2 grants, got %+v", d.Grants)
+	}
+	if !strings.Contains(d.Grants[0].Reason, `"Confluence"`) || !strings.Contains(d.Grants[1].Reason, `"Support"`) {
+		t.Errorf("unexpected reasons %+v", d.Grants)
+	}
+	if len(d.Hints) != 1 || !strings.Contains(d.Hints[0], "stale") {
+		t.Errorf("expected a stale membership hint, got %v", d.Hints)
+	}
+}
+
+func TestEvaluateDenied(t *testing.T) {
+	c := Check{
+		User: User{Email: "Ann@example.com", IsActive: true, ACL: []string{"PUBLIC", "user_email:Ann@example.com"}},
+		Document: Document{
+			ACL: []string{"external_group:google_drive_eng", "user_email:ann@example.com"},
+			Connectors: []Connector{
+				{CCPairID: 4, Name: "Drive", Source: "google_drive", AccessType: "sync"},
+				{CCPairID: 5, Name: "Web", Source: "web", AccessType: "public"},
+			},
+		},
+	}
+	d := c.Evaluate()
+	if d.Allowed || len(d.Grants) != 0 {
+		t.Fatalf("expected no access, got %+v", d)
+	}
+	want := []string{"only in case", `"Drive"`, "google_drive_eng"}
+	if len(d.Hints) != len(want) {
+		t.Fatalf("expected %d hints, got %v", len(want), d.Hints)
+	}
+	for i, w := range want {
+		if !strings.Contains(d.Hints[i], w) {
+			t.Errorf("hint %d = %q, want it to mention %s", i, d.Hints[i], w)
+		}
+	}
+
+	c.Document.ACL = nil
+	if d := c.Evaluate(); len(d.Hints) != 1 || !strings.Contains(d.Hints[0], "empty") {
+		t.Errorf("expected an empty ACL hint, got %v", d.Hints)
+	}
+}
+
+func TestSyncPairProblems(t *testing.T) {
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	at := func(ago time.Duration) *time.Time { t := now.Add(-ago); return &t }
+
+	healthy := SyncPair{
+		Status:        "ACTIVE",
+		LastDocSync:   at(time.Hour),
+		LastGroupSync: at(2 * time.Hour),
+		DocAttempt:    &Attempt{Status: "success"},
+		GroupAttempt:  &Attempt{Status: "in_progress", Started: at(time.Hour)},
+	}
+	if got := healthy.Problems(now); len(got) != 0 {
+		t.Errorf("expected no problems, got %v", got)
+	}
+
+	broken := SyncPair{
+		Status:       "ACTIVE",
+		LastDocSync:  at(48 * time.Hour),
+		DocAttempt:   &Attempt{Status: "failed", Error: "403 Forbidden\nTraceback ..."},
+		GroupAttempt: &Attempt{Status: "in_progress", Started: at(7 * time.Hour)},
+	}
+	want := []string{
+		"last document sync failed: 403 Forbidden",
+		"document permissions last synced 48h0m0s ago",
+		"group sync in progress since 2026-03-01T05:00:00Z",
+		"group permissions never synced",
+	}
+	got := broken.Problems(now)
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("Problems() = %q, want %q", got, want)
+	}
+
+	broken.Status = "PAUSED"
+	if got := broken.Problems(now); len(got) != 0 {
+		t.Errorf("paused pairs should not be flagged, got %v", got)
+	}
+}
+
+func TestParseResult(t *testing.T) {
+	out := "Connecting...\n" + `{"status": "success", "pairs": [{"cc_pair_id": 2, "name": "Drive", "last_doc_sync": "2026-01-02T03:04:05+00:00", "doc_attempt": {"id": 9, "status": "success", "docs_synced": 40}, "group_attempt": null}], "global_group_sync": null}`
+	var s SyncStatus
+	if err := parseResult(out, &s); err != nil {
+		t.Fatalf("parseResult() error: %v", err)
+	}
+	if len(s.Pairs) != 1 || s.Pairs[0].DocAttempt.DocsSynced != 40 || s.Pairs[0].GroupAttempt != nil {
+		t.Errorf("unexpected status %+v", s)
+	}
+	if err := parseResult(`{"status": "error", "message": "No user with email 'x'"}`, &s); err == nil || err.Error() != "No user with email 'x'" {
+		t.Errorf("expected the script's error, got %v", err)
+	}
+}
@@ -13,8 +13,13 @@ import (
 	"github.com/onyx-dot-app/onyx/tools/ods/internal/docker"
 	"github.com/onyx-dot-app/onyx/tools/ods/internal/paths"
 	"github.com/onyx-dot-app/onyx/tools/ods/internal/postgres"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/runner"
 )
 
+// Runner runs alembic and docker; nil means runner.Default. Tests set a
+// runner.Fake.
+var Runner runner.Runner
+
 // Schema represents an Alembic schema configuration.
 type Schema string
 
@@ -97,16 +102,16 @@ func runLocally(args []string, schema Schema) error {
 	}
 	cmdArgs = append(cmdArgs, args...)
 
-	cmd := exec.Command(alembic, cmdArgs...)
-	cmd.Dir = backendDir
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	cmd.Stdin = os.Stdin
-
-	// Pass through POSTGRES_* environment variables.
-	cmd.Env = buildAlembicEnv()
-
-	return cmd.Run()
+	return runner.Or(Runner).Run(runner.Cmd{
+		Name: alembic,
+		Args: cmdArgs,
+		Dir:  backendDir,
+		// Pass through POSTGRES_* environment variables.
+		Env:    buildAlembicEnv(),
+		Stdin:  os.Stdin,
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	})
 }
 
 // runViaDockerExec runs alembic inside a Docker container that has network
@@ -128,24 +133,25 @@ func runViaDockerExec(args []string, schema Schema) error {
 
 	log.Infof("Running alembic via docker exec on container: %s", container)
 
-	// Build the alembic command.
-	var alembicArgs []string
-	if schema == SchemaPrivate {
-		alembicArgs = append(alembicArgs, "-n", "schema_private")
-	}
-	alembicArgs = append(alembicArgs, args...)
-
 	// Run alembic inside the container.
 	// The container should have the correct env vars and network access.
-	dockerArgs := []string{"exec", "-i", container, "alembic"}
-	dockerArgs = append(dockerArgs, alembicArgs...)
-
-	cmd := exec.Command("docker", dockerArgs...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	cmd.Stdin = os.Stdin
+	return runner.Or(Runner).Run(runner.Cmd{
+		Name:   "docker",
+		Args:   dockerExecArgs(container, args, schema),
+		Stdin:  os.Stdin,
+		Stdout: os.Stdout,
+		Stderr: os.Stderr,
+	})
+}
 
-	return cmd.Run()
+// dockerExecArgs builds the docker arguments that run alembic inside
+// container.
+func dockerExecArgs(container string, args []string, schema Schema) []string {
+	dockerArgs := []string{"exec", "-i", container, "alembic"}
+	if schema == SchemaPrivate {
+		dockerArgs = append(dockerArgs, "-n", "schema_private")
+	}
+	return append(dockerArgs, args...)
 }
 
 // legacyAlembicContainerNames are fallback names tried after the
@@ -212,8 +218,10 @@ func findAlembicContainer() (string, error) {
 
 // isContainerRunning checks if a container is running.
 func isContainerRunning(name string) bool {
-	cmd := exec.Command("docker", "inspect", "-f", "{{.State.Running}}", name)
-	output, err := cmd.Output()
+	output, err := runner.Output(Runner, runner.Cmd{
+		Name: "docker",
+		Args: []string{"inspect", "-f", "{{.State.Running}}", name},
+	})
 	if err != nil {
 		return false
 	}
@@ -0,0 +1,39 @@
+package alembic
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/runner"
+)
+
+func TestDockerExecArgs(t *testing.T) {
+	got := dockerExecArgs("onyx-api_server-1", []string{"upgrade", "head"}, SchemaPrivate)
+	want := []string{"exec", "-i", "onyx-api_server-1", "alembic", "-n", "schema_private", "upgrade", "head"}
+	if !slices.Equal(got, want) {
+		t.Errorf("dockerExecArgs() = %q, want %q", got, want)
+	}
+	got = dockerExecArgs("api_server", []string{"current"}, SchemaDefault)
+	if want := []string{"exec", "-i", "api_server", "alembic", "current"}; !slices.Equal(got, want) {
+		t.Errorf("dockerExecArgs() = %q, want %q", got, want)
+	}
+}
+
+func TestFindAlembicContainerFallsBackToLegacyNames(t *testing.T) {
+	f := (&runner.Fake{}).
+		On("docker inspect", runner.Response{Stdout: "false\n"}).
+		On("docker inspect -f {{.State.Running}} onyx-stack-api_server-1", runner.Response{Stdout: "true\n"})
+	Runner = f
+	t.Cleanup(func() { Runner = nil })
+
+	name, err := findAlembicContainer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if name != "onyx-stack-api_server-1" {
+		t.Errorf("findAlembicContainer() = %q", name)
+	}
+	if n := len(f.Calls()); n != 3 {
+		t.Errorf("expected the project name and two legacy names to be tried, got %q", f.Lines())
+	}
+}
@@ -0,0 +1,116 @@
+"""Report the Alembic revision of a deployment's schemas against head.
+
+Bundled with ods and piped into `python -` on an api-server pod (working
+directory /app, next to alembic.ini) by `ods migrate status`. Lagging tenant
+schemas are found with the backend's own get_schemas_needing_migration, which
+reads versions one schema at a time rather than with one huge query.
+
+Usage:
+    python - all              # every tenant schema
+    python - schema [<name>]  # one schema (default: the default schema)
+
+Progress goes to stderr; the last line on stdout is a JSON object with
+"status", "head", "multi_tenant", "total" and "lagging" (schema -> revision,
+"" when the schema has no alembic_version yet).
+"""
+
+from __future__ import annotations
+
+import json
+import sys
+from typing import Any
+
+
+def schema_revision(schema: str) -> str:
+    from sqlalchemy import text
+
+    from onyx.db.engine.sql_engine import SqlEngine
+
+    with SqlEngine.get_engine().connect() as conn:
+        try:
+            return (
+                conn.execute(
+                    text(f'SELECT version_num FROM "{schema}".alembic_version')  # noqa: S608
+                ).scalar()
+                or ""
+            )
+        except Exception:
+            return ""
+
+
+def status(scope: str, schema: str | None) -> dict[str, Any]:
+    from alembic.config import Config
+    from alembic.script import ScriptDirectory
+
+    from onyx.db.engine.tenant_utils import get_all_tenant_ids
+    from onyx.db.engine.tenant_utils import get_schemas_needing_migration
+    from onyx.db.engine.tenant_utils import validate_tenant_id
+    from shared_configs.configs import MULTI_TENANT
+    from shared_configs.configs import POSTGRES_DEFAULT_SCHEMA
+    from shared_configs.configs import TENANT_ID_PREFIX
+
+    heads = ScriptDirectory.from_config(Config("alembic.ini")).get_heads()
+    if len(heads) != 1:
+        return {
+            "status": "error",
+            "message": f"Expected one head revision, found {len(heads)}: {heads}",
+        }
+    head = heads[0]
+
+    if scope == "all":
+        if not MULTI_TENANT:
+            return {
+                "status": "error",
+                "message": "This deployment is not multi-tenant; drop --all-tenants",
+            }
+        schemas = [
+            tid for tid in get_all_tenant_ids() if tid.startswith(TENANT_ID_PREFIX)
+        ]
+    elif schema is None and MULTI_TENANT:
+        return {
+            "status": "error",
+            "message": "This deployment is multi-tenant; pass --all-tenants or --tenant",
+        }
+    else:
+        schema = schema or POSTGRES_DEFAULT_SCHEMA
+        if schema != POSTGRES_DEFAULT_SCHEMA and not validate_tenant_id(schema):
+            return {"status": "error", "message": f"Invalid schema {schema!r}"}
+        schemas = [schema]
+
+    print(f"Checking {len(schemas)} schema(s) against {head}...", file=sys.stderr)
+    lagging = get_schemas_needing_migration(schemas, head)
+    return {
+        "status": "success",
+        "head": head,
+        "multi_tenant": MULTI_TENANT,
+        "total": len(schemas),
+        "lagging": {s: schema_revision(s) for s in lagging},
+    }
+
+
+def main() -> None:
+    if len(sys.argv) < 2 or sys.argv[1] not in ("all", "schema"):
+        print(
+            json.dumps(
+                {
+                    "status": "error",
+                    "message": "Usage: python - all | schema [<name>]",
+                }
+            )
+        )
+        sys.exit(1)
+
+    from onyx.db.engine.sql_engine import SqlEngine
+
+    SqlEngine.init_engine(pool_size=5, max_overflow=2)
+
+    try:
+        result = status(sys.argv[1], sys.argv[2] if len(sys.argv) > 2 else None)
+    except Exception as e:
+        print(f"Error: {e}", file=sys.stderr)
+        result = {"status": "error", "message": str(e)}
+    print(json.dumps(result))
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,94 @@
+package alembic
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/kube"
+)
+
+//go:embed schema_status.py
+var schemaStatusScript string
+
+// SchemaStatus is the migration state of a deployment's schemas.
+type SchemaStatus struct {
+	Head        string `json:"head"`
+	MultiTenant bool   `json:"multi_tenant"`
+	Total       int    `json:"total"`
+	// Lagging maps each schema not at Head to its revision, which is empty
+	// when the schema has never been migrated.
+	Lagging map[string]string `json:"lagging"`
+}
+
+// LaggingSchemas returns the lagging schema names, sorted.
+func (s *SchemaStatus) LaggingSchemas() []string {
+	names := make([]string, 0, len(s.Lagging))
+	for name := range s.Lagging {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// RevisionCounts returns how many lagging schemas are at each revision.
+func (s *SchemaStatus) RevisionCounts() map[string]int {
+	counts := make(map[string]int)
+	for _, rev := range s.Lagging {
+		counts[rev]++
+	}
+	return counts
+}
+
+// RemoteStatus reports schema revisions on a cluster by running a bundled
+// script on pod: every tenant schema with allTenants, otherwise schema or,
+// when empty, the default schema of a single-tenant deployment.
+func RemoteStatus(c *kube.Cluster, pod string, allTenants bool, schema string) (*SchemaStatus, error) {
+	args := []string{"schema"}
+	switch {
+	case allTenants:
+		args = []string{"all"}
+	case schema != "":
+		args = append(args, schema)
+	}
+	out, err := c.RunPython(pod, schemaStatusScript, args...)
+	if err != nil {
+		return nil, err
+	}
+	return parseSchemaStatus(out)
+}
+
+func parseSchemaStatus(stdout string) (*SchemaStatus, error) {
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	last := strings.TrimSpace(lines[len(lines)-1])
+	var r struct {
+		Status  string `json:"status"`
+		Message string `json:"message"`
+		SchemaStatus
+	}
+	if err := json.Unmarshal([]byte(last), &r); err != nil {
+		return nil, fmt.Errorf("unexpected output from schema status script: %q", last)
+	}
+	if r.Status != "success" {
+		return nil, fmt.Errorf("%s", r.Message)
+	}
+	return &r.SchemaStatus, nil
+}
+
+// RemoteUpgradeCommand returns the command, run on an api-server pod, that
+// brings lagging schemas to head. For every tenant this is the backend's
+// parallel runner, which itself only migrates schemas not at head.
+func RemoteUpgradeCommand(status *SchemaStatus, allTenants bool, schema string, jobs, batchSize int) []string {
+	switch {
+	case allTenants:
+		return []string{"python", "alembic/run_multitenant_migrations.py",
+			"-j", strconv.Itoa(jobs), "-b", strconv.Itoa(batchSize)}
+	case status.MultiTenant:
+		return []string{"alembic", "-x", "schemas=" + schema, "upgrade", "head"}
+	default:
+		return []string{"alembic", "upgrade", "head"}
+	}
+}
@@ -0,0 +1,41 @@
+package alembic
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseSchemaStatus(t *testing.T) {
+	out := "INFO import noise\n" +
+		`{"status": "success", "head": "abc123", "multi_tenant": true, "total": 5, "lagging": {"tenant_b": "old1", "tenant_a": "", "tenant_c": "old1"}}`
+	s, err := parseSchemaStatus(out)
+	if err != nil {
+		t.Fatalf("parseSchemaStatus() error: %v", err)
+	}
+	if s.Head != "abc123" || !s.MultiTenant || s.Total != 5 {
+		t.Errorf("unexpected status %+v", s)
+	}
+	if got := s.LaggingSchemas(); !slices.Equal(got, []string{"tenant_a", "tenant_b", "tenant_c"}) {
+		t.Errorf("LaggingSchemas() = %v", got)
+	}
+	if counts := s.RevisionCounts(); counts["old1"] != 2 || counts[""] != 1 {
+		t.Errorf("RevisionCounts() = %v", counts)
+	}
+
+	if _, err := parseSchemaStatus(`{"status": "error", "message": "Expected one head revision"}`); err == nil || err.Error() != "Expected one head revision" {
+		t.Errorf("expected script error message, got %v", err)
+	}
+}
+
+func TestRemoteUpgradeCommand(t *testing.T) {
+	mt := &SchemaStatus{MultiTenant: true}
+	if got := RemoteUpgradeCommand(mt, true, "", 4, 20); !slices.Equal(got, []string{"python", "alembic/run_multitenant_migrations.py", "-j", "4", "-b", "20"}) {
+		t.Errorf("all tenants: %v", got)
+	}
+	if got := RemoteUpgradeCommand(mt, false, "tenant_a", 4, 20); !slices.Equal(got, []string{"alembic", "-x", "schemas=tenant_a", "upgrade", "head"}) {
+		t.Errorf("one tenant: %v", got)
+	}
+	if got := RemoteUpgradeCommand(&SchemaStatus{}, false, "", 4, 20); !slices.Equal(got, []string{"alembic", "upgrade", "head"}) {
+		t.Errorf("single tenant: %v", got)
+	}
+}
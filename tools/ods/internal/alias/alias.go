@@ -0,0 +1,147 @@
+// Package alias expands user-defined ods aliases and macros: named
+// sequences of ods command lines with positional parameters.
+package alias
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var namePattern = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+
+// ValidName reports whether name can name an alias.
+func ValidName(name string) bool {
+	return namePattern.MatchString(name)
+}
+
+// paramPattern matches $1..$9 and $@.
+var paramPattern = regexp.MustCompile(`\$([1-9@])`)
+
+// Arity returns the highest positional parameter the steps use.
+func Arity(steps []string) int {
+	n := 0
+	for _, s := range steps {
+		for _, m := range paramPattern.FindAllStringSubmatch(s, -1) {
+			if i, err := strconv.Atoi(m[1]); err == nil && i > n {
+				n = i
+			}
+		}
+	}
+	return n
+}
+
+// Expand turns steps into the argument lists to run, substituting args for
+// $1..$9 (one argument each) and $@ (the arguments after the highest $N
+// used). An alias of one step without parameters gets args appended, so
+// it behaves like the command it stands for.
+func Expand(steps []string, args []string) ([][]string, error) {
+	arity := Arity(steps)
+	if len(args) < arity {
+		return nil, fmt.Errorf("needs %d argument(s), got %d", arity, len(args))
+	}
+	rest := args[arity:]
+	usesRest := false
+	for _, s := range steps {
+		if strings.Contains(s, "$@") {
+			usesRest = true
+		}
+	}
+
+	var out [][]string
+	for _, s := range steps {
+		words, err := Split(s)
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", s, err)
+		}
+		var argv []string
+		for _, w := range words {
+			if w == "$@" {
+				argv = append(argv, rest...)
+				continue
+			}
+			argv = append(argv, paramPattern.ReplaceAllStringFunc(w, func(p string) string {
+				if p == "$@" {
+					return strings.Join(rest, " ")
+				}
+				i, _ := strconv.Atoi(p[1:])
+				return args[i-1]
+			}))
+		}
+		out = append(out, argv)
+	}
+	if len(steps) == 1 && arity == 0 && !usesRest {
+		out[0] = append(out[0], args...)
+	} else if !usesRest && len(rest) > 0 {
+		return nil, fmt.Errorf("takes %d argument(s), got %d", arity, len(args))
+	}
+	return out, nil
+}
+
+// Split splits a command line into words as a POSIX shell would, honouring
+// single quotes, double quotes and backslash escapes, without expanding
+// anything.
+func Split(line string) ([]string, error) {
+	var words []string
+	var cur strings.Builder
+	inWord := false
+	var quote rune
+	escaped := false
+	for _, r := range line {
+		switch {
+		case escaped:
+			cur.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				cur.WriteRune(r)
+			}
+		case quote == '"':
+			switch r {
+			case '"':
+				quote = 0
+			case '\\':
+				escaped = true
+			default:
+				cur.WriteRune(r)
+			}
+		case r == '\\':
+			escaped, inWord = true, true
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				words = append(words, cur.String())
+				cur.Reset()
+				inWord = false
+			}
+		default:
+			cur.WriteRune(r)
+			inWord = true
+		}
+	}
+	if quote != 0 || escaped {
+		return nil, fmt.Errorf("unterminated quote or escape")
+	}
+	if inWord {
+		words = append(words, cur.String())
+	}
+	return words, nil
+}
+
+// Join is the inverse of Split: it renders words as a command line,
+// single-quoting those a shell would otherwise split or expand.
+func Join(words []string) string {
+	quoted := make([]string, len(words))
+	for i, w := range words {
+		if w != "" && !strings.ContainsAny(w, " \t\n'\"\\$`|&;<>()*?[]{}~#!") {
+			quoted[i] = w
+			continue
+		}
+		quoted[i] = "'" + strings.ReplaceAll(w, "'", `'\''`) + "'"
+	}
+	return strings.Join(quoted, " ")
+}
@@ -0,0 +1,73 @@
+package alias
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestSplit(t *testing.T) {
+	tests := map[string][]string{
+		`whois --context prod`:          {"whois", "--context", "prod"},
+		`  logs   api_server  `:         {"logs", "api_server"},
+		`tenant show "acme corp" 'a b'`: {"tenant", "show", "acme corp", "a b"},
+		`curl -d "{\"a\": 1}"`:          {"curl", "-d", `{"a": 1}`},
+		`db dump ""`:                    {"db", "dump", ""},
+	}
+	for line, want := range tests {
+		got, err := Split(line)
+		if err != nil {
+			t.Fatalf("Split(%q): %v", line, err)
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("Split(%q) = %q, want %q", line, got, want)
+		}
+	}
+	if _, err := Split(`whois "unterminated`); err == nil {
+		t.Error("Split of an unterminated quote should fail")
+	}
+}
+
+func TestExpand(t *testing.T) {
+	tests := []struct {
+		name  string
+		steps []string
+		args  []string
+		want  [][]string
+	}{
+		{"alias appends", []string{"whois --context prod"}, []string{"alice@example.com"},
+			[][]string{{"whois", "--context", "prod", "alice@example.com"}}},
+		{"positional", []string{"audit --fail-on high", "scan images $1", "upgrade-check $1"}, []string{"v2.10.4"},
+			[][]string{{"audit", "--fail-on", "high"}, {"scan", "images", "v2.10.4"}, {"upgrade-check", "v2.10.4"}}},
+		{"rest", []string{"logs $1 $@"}, []string{"api_server", "-f", "--tail", "10"},
+			[][]string{{"logs", "api_server", "-f", "--tail", "10"}}},
+		{"inside a word", []string{"tenant show --tenant=tenant_$1"}, []string{"abcd"},
+			[][]string{{"tenant", "show", "--tenant=tenant_abcd"}}},
+	}
+	for _, tt := range tests {
+		got, err := Expand(tt.steps, tt.args)
+		if err != nil {
+			t.Fatalf("%s: %v", tt.name, err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: Expand() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	if _, err := Expand([]string{"scan images $1"}, nil); err == nil {
+		t.Error("a missing argument should fail")
+	}
+	if _, err := Expand([]string{"audit", "scan images $1"}, []string{"v1", "extra"}); err == nil {
+		t.Error("an unused argument to a macro should fail")
+	}
+}
+
+func TestJoin(t *testing.T) {
+	words := []string{"explain", "no space left", "--tenant=tenant_abcd", "it's", ""}
+	line := Join(words)
+	if line != `explain 'no space left' --tenant=tenant_abcd 'it'\''s' ''` {
+		t.Errorf("Join() = %s", line)
+	}
+	if got, err := Split(line); err != nil || !reflect.DeepEqual(got, words) {
+		t.Errorf("Split(Join()) = %q, %v", got, err)
+	}
+}
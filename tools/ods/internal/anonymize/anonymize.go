@@ -0,0 +1,183 @@
+// Package anonymize rewrites personal data and secrets in an Onyx database
+// so a production-derived copy can be shared with developers. Rules name a
+// column and what to replace its values with; the package turns them into
+// one SQL script that applies them in every schema holding the column.
+package anonymize
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed rules.yaml
+var defaultRules []byte
+
+// Actions are the supported rule actions; rules.yaml describes each.
+var Actions = []string{"hash_email", "fake_name", "hash", "redact", "null", "set", "scrub_secret"}
+
+// identifier matches table and column names rules may use.
+var identifier = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+// Rule rewrites one column.
+type Rule struct {
+	Table  string  `yaml:"table"`
+	Column string  `yaml:"column"`
+	Action string  `yaml:"action"`
+	Value  *string `yaml:"value"` // for "set"
+}
+
+func (r Rule) validate() error {
+	if !identifier.MatchString(r.Table) || !identifier.MatchString(r.Column) {
+		return fmt.Errorf("invalid table or column in rule %s.%s", r.Table, r.Column)
+	}
+	valid := false
+	for _, a := range Actions {
+		valid = valid || a == r.Action
+	}
+	if !valid {
+		return fmt.Errorf("unknown action %q for %s.%s (must be one of %s)", r.Action, r.Table, r.Column, strings.Join(Actions, ", "))
+	}
+	if (r.Action == "set") != (r.Value != nil) {
+		return fmt.Errorf("rule for %s.%s: value is required with set and only allowed with set", r.Table, r.Column)
+	}
+	return nil
+}
+
+// DefaultRules returns the built-in rules.
+func DefaultRules() []Rule {
+	rules, err := parseRules(defaultRules)
+	if err != nil {
+		panic(fmt.Sprintf("invalid embedded anonymization rules: %v", err))
+	}
+	return rules
+}
+
+// LoadRules reads rules from a YAML file in the format of the built-in
+// rules.yaml.
+func LoadRules(path string) ([]Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	rules, err := parseRules(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return rules, nil
+}
+
+func parseRules(data []byte) ([]Rule, error) {
+	var rules []Rule
+	if err := yaml.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse rules: %w", err)
+	}
+	for _, r := range rules {
+		if err := r.validate(); err != nil {
+			return nil, err
+		}
+	}
+	return rules, nil
+}
+
+// Options configures the generated script.
+type Options struct {
+	// Schema limits the script to one schema; empty means every schema.
+	Schema string
+	// Salt is mixed into hashes so they cannot be reversed by hashing
+	// guessed values. Use a fresh random salt per copy.
+	Salt string
+}
+
+// SQL returns a script that applies rules in one transaction. Columns that
+// do not exist are skipped, so one rule set works across schema versions.
+// Each rewrite is reported as a NOTICE with its row count.
+func SQL(rules []Rule, opts Options) (string, error) {
+	if opts.Schema != "" && !identifier.MatchString(opts.Schema) {
+		return "", fmt.Errorf("invalid schema name %q", opts.Schema)
+	}
+	schemaFilter := "table_schema NOT IN ('pg_catalog', 'information_schema')"
+	if opts.Schema != "" {
+		schemaFilter = "table_schema = " + quoteLiteral(opts.Schema)
+	}
+
+	var b strings.Builder
+	b.WriteString("BEGIN;\n")
+	for _, r := range rules {
+		if err := r.validate(); err != nil {
+			return "", err
+		}
+		col := quoteIdent(r.Column)
+		// The statement is a format() template with the schema as %I, so
+		// any other % has to be doubled.
+		update := strings.ReplaceAll(fmt.Sprintf("UPDATE %%I.%s SET %s = %s WHERE %s IS NOT NULL",
+			quoteIdent(r.Table), col, expression(r, col, opts.Salt), col), "%", "%%")
+		update = strings.Replace(update, "%%I", "%I", 1)
+
+		fmt.Fprintf(&b, `DO $ods$
+DECLARE
+  s text;
+  n bigint;
+BEGIN
+  FOR s IN SELECT table_schema FROM information_schema.columns
+    WHERE table_name = %s AND column_name = %s AND %s
+  LOOP
+    EXECUTE format(%s, s);
+    GET DIAGNOSTICS n = ROW_COUNT;
+    RAISE NOTICE '%%.%s.%s: %s (%% rows)', s, n;
+  END LOOP;
+END
+$ods$;
+`, quoteLiteral(r.Table), quoteLiteral(r.Column), schemaFilter, quoteLiteral(update), r.Table, r.Column, r.Action)
+	}
+	b.WriteString("COMMIT;\n")
+	return b.String(), nil
+}
+
+// expression returns the SQL the column is set to.
+func expression(r Rule, col, salt string) string {
+	hash := func(of string) string { return fmt.Sprintf("md5(%s || %s)", quoteLiteral(salt), of) }
+	switch r.Action {
+	case "hash_email":
+		return fmt.Sprintf("'user-' || left(%s, 12) || '@example.invalid'", hash("lower("+col+")"))
+	case "fake_name":
+		digest := fmt.Sprintf("decode(%s, 'hex')", hash(col+"::text"))
+		return fmt.Sprintf("(%s)[1 + get_byte(%s, 0) %% %d] || ' ' || (%s)[1 + get_byte(%s, 1) %% %d]",
+			sqlArray(firstNames), digest, len(firstNames), sqlArray(lastNames), digest, len(lastNames))
+	case "hash":
+		return fmt.Sprintf("left(%s, 16)", hash(col+"::text"))
+	case "redact":
+		return fmt.Sprintf("'[redacted ' || length(%s) || ' chars]'", col)
+	case "null":
+		return "NULL"
+	case "set":
+		return quoteLiteral(*r.Value)
+	case "scrub_secret":
+		return fmt.Sprintf("convert_to('redacted-' || left(md5(%s), 12), 'UTF8')", col)
+	}
+	panic("unreachable: rule was validated")
+}
+
+func quoteIdent(s string) string {
+	return `"` + strings.ReplaceAll(s, `"`, `""`) + `"`
+}
+
+func quoteLiteral(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+func sqlArray(values []string) string {
+	quoted := make([]string, len(values))
+	for i, v := range values {
+		quoted[i] = quoteLiteral(v)
+	}
+	return "ARRAY[" + strings.Join(quoted, ", ") + "]"
+}
+
+var firstNames = []string{"Ada", "Bo", "Chidi", "Dana", "Elif", "Femi", "Grace", "Hugo", "Ines", "Jun", "Kofi", "Lena", "Mateo", "Nia", "Omar", "Priya"}
+
+var lastNames = []string{"Adams", "Brandt", "Costa", "Diaz", "Eriksen", "Fischer", "Gupta", "Hale", "Ito", "Jensen", "Kim", "Larsen", "Moreau", "Novak", "Okafor", "Park"}
@@ -0,0 +1,96 @@
+package anonymize
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestDefaultRules(t *testing.T) {
+	rules := DefaultRules()
+	if len(rules) == 0 {
+		t.Fatal("no default rules")
+	}
+	seen := map[string]bool{}
+	for _, r := range rules {
+		key := r.Table + "." + r.Column
+		if seen[key] {
+			t.Errorf("duplicate rule for %s", key)
+		}
+		seen[key] = true
+	}
+	for _, must := range []string{"user.email", "chat_message.message", "credential.credential_json"} {
+		if !seen[must] {
+			t.Errorf("default rules do not cover %s", must)
+		}
+	}
+}
+
+func TestSQL(t *testing.T) {
+	value := "it's 100%"
+	script, err := SQL([]Rule{
+		{Table: "user", Column: "email", Action: "hash_email"},
+		{Table: "user", Column: "personal_name", Action: "fake_name"},
+		{Table: "chat_message", Column: "message", Action: "set", Value: &value},
+	}, Options{Schema: "tenant_abc", Salt: "pepper"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(script, "BEGIN;\n") || !strings.HasSuffix(script, "COMMIT;\n") {
+		t.Error("script is not one transaction")
+	}
+	for _, want := range []string{
+		// The schema is the only format() placeholder; other % are doubled.
+		`'UPDATE %I."user" SET "email" = ''user-'' || left(md5(''pepper'' || lower("email")), 12) || ''@example.invalid'' WHERE "email" IS NOT NULL'`,
+		`get_byte(decode(md5(''pepper'' || "personal_name"::text), ''hex''), 0) %% 16`,
+		`SET "message" = ''it''''s 100%%''`,
+		"table_schema = 'tenant_abc'",
+		"RAISE NOTICE '%.chat_message.message: set (% rows)', s, n;",
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("script missing %s\n%s", want, script)
+		}
+	}
+
+	all, err := SQL(DefaultRules(), Options{Salt: "x"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(all, "table_schema NOT IN ('pg_catalog', 'information_schema')") {
+		t.Error("without a schema the script should cover every schema")
+	}
+}
+
+func TestSQLRejectsBadInput(t *testing.T) {
+	if _, err := SQL(nil, Options{Schema: "public; DROP TABLE x"}); err == nil {
+		t.Error("expected an error for an invalid schema")
+	}
+	for _, r := range []Rule{
+		{Table: `user"`, Column: "email", Action: "hash"},
+		{Table: "user", Column: "email", Action: "shuffle"},
+		{Table: "user", Column: "email", Action: "set"},
+	} {
+		if _, err := SQL([]Rule{r}, Options{}); err == nil {
+			t.Errorf("expected an error for %+v", r)
+		}
+	}
+}
+
+func TestLoadRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rules.yaml")
+	if err := os.WriteFile(path, []byte("- {table: user, column: email, action: \"null\"}\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	rules, err := LoadRules(path)
+	if err != nil || len(rules) != 1 || rules[0].Action != "null" {
+		t.Fatalf("got %+v, %v", rules, err)
+	}
+
+	if err := os.WriteFile(path, []byte("- {table: user, column: email, action: null}\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadRules(path); err == nil {
+		t.Error("expected an error for an unquoted null action")
+	}
+}
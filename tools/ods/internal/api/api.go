@@ -0,0 +1,96 @@
+// Package api is a typed client for the Onyx API server.
+//
+// The methods and types in zz_generated.go are generated from the backend's
+// OpenAPI spec by `ods api regen`, for the operations listed in
+// operations.txt. Add an operation there and regenerate rather than calling
+// endpoints by hand, so the client follows the backend as it changes.
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/apiclient"
+)
+
+// Client calls the API server through an authenticated apiclient.Client.
+type Client struct {
+	*apiclient.Client
+}
+
+// New wraps an authenticated client.
+func New(c *apiclient.Client) *Client {
+	return &Client{Client: c}
+}
+
+// Error is a non-2xx response.
+type Error struct {
+	Method     string
+	Path       string
+	StatusCode int
+	Status     string
+	// Detail is the backend's error message (FastAPI's "detail"), or the
+	// raw body when it has none.
+	Detail string
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("%s %s returned %s: %s", e.Method, e.Path, e.Status, e.Detail)
+}
+
+func (c *Client) do(method, path string, query url.Values, body, out any) error {
+	if len(query) > 0 {
+		path += "?" + query.Encode()
+	}
+	var data []byte
+	if body != nil {
+		var err error
+		if data, err = json.Marshal(body); err != nil {
+			return fmt.Errorf("failed to marshal request body: %w", err)
+		}
+	}
+	req, err := c.NewRequest(method, path, data)
+	if err != nil {
+		return err
+	}
+	resp, err := c.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return &Error{Method: method, Path: req.URL.Path, StatusCode: resp.StatusCode, Status: resp.Status, Detail: errorDetail(respBody)}
+	}
+	if out == nil || resp.StatusCode == http.StatusNoContent {
+		return nil
+	}
+	if err := json.Unmarshal(respBody, out); err != nil {
+		return fmt.Errorf("failed to parse response from %s: %w", req.URL.Path, err)
+	}
+	return nil
+}
+
+// errorDetail extracts FastAPI's {"detail": ...} message from an error
+// body.
+func errorDetail(body []byte) string {
+	var r struct {
+		Detail json.RawMessage `json:"detail"`
+	}
+	if json.Unmarshal(body, &r) == nil && len(r.Detail) > 0 {
+		var msg string
+		if json.Unmarshal(r.Detail, &msg) == nil {
+			return msg
+		}
+		return string(r.Detail)
+	}
+	return strings.TrimSpace(string(body))
+}
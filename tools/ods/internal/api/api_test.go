@@ -0,0 +1,133 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/apiclient"
+)
+
+func TestDo(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/echo":
+			if r.Header.Get("Authorization") != "Bearer key" {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			body, _ := io.ReadAll(r.Body)
+			_, _ = w.Write([]byte(`{"query": "` + r.URL.RawQuery + `", "body": ` + string(body) + `}`))
+		case "/missing":
+			w.WriteHeader(http.StatusNotFound)
+			_, _ = w.Write([]byte(`{"detail": "Chat session not found"}`))
+		default:
+			w.WriteHeader(http.StatusInternalServerError)
+			_, _ = w.Write([]byte("boom"))
+		}
+	}))
+	defer srv.Close()
+
+	raw := apiclient.New(srv.URL)
+	raw.APIKey = "key"
+	c := New(raw)
+
+	var out struct {
+		Query string         `json:"query"`
+		Body  map[string]int `json:"body"`
+	}
+	if err := c.do(http.MethodPost, "/echo", url.Values{"a": {"1"}}, map[string]int{"x": 2}, &out); err != nil {
+		t.Fatalf("do() error: %v", err)
+	}
+	if out.Query != "a=1" || out.Body["x"] != 2 {
+		t.Errorf("unexpected response %+v", out)
+	}
+
+	err := c.do(http.MethodGet, "/missing", nil, nil, &out)
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusNotFound || apiErr.Detail != "Chat session not found" {
+		t.Errorf("expected a 404 with the backend's detail, got %v", err)
+	}
+	if err := c.do(http.MethodGet, "/other", nil, nil, nil); err == nil || !errors.As(err, &apiErr) || apiErr.Detail != "boom" {
+		t.Errorf("expected the raw body as detail, got %v", err)
+	}
+}
+
+func TestGeneratedMethods(t *testing.T) {
+	var got []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got = append(got, r.Method+" "+r.URL.RequestURI())
+		switch r.URL.Path {
+		case "/me":
+			_, _ = w.Write([]byte(`{"id": "u1", "email": "dev@example.com", "role": "admin", "preferences": {}}`))
+		case "/admin/search":
+			body, _ := io.ReadAll(r.Body)
+			if string(body) != `{"filters":{},"query":"pricing"}` {
+				t.Errorf("search body = %s", body)
+			}
+			_, _ = w.Write([]byte(`{"documents": [{"document_id": "doc-1", "source_type": "web"}]}`))
+		}
+	}))
+	defer srv.Close()
+	c := New(apiclient.New(srv.URL))
+
+	me, err := c.GetMe()
+	if err != nil || me.Email != "dev@example.com" || me.Role != UserRoleAdmin {
+		t.Fatalf("GetMe() = %+v, %v", me, err)
+	}
+	res, err := c.AdminSearch(AdminSearchRequest{Query: "pricing"})
+	if err != nil || len(res.Documents) != 1 || res.Documents[0].SourceType != DocumentSourceWeb {
+		t.Fatalf("AdminSearch() = %+v, %v", res, err)
+	}
+	hard := true
+	if err := c.DeleteChatSession("5f0c/x", DeleteChatSessionParams{HardDelete: &hard}); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"GET /me", "POST /admin/search", "DELETE /chat/delete-chat-session/5f0c%2Fx?hard_delete=true"}
+	if len(got) != len(want) {
+		t.Fatalf("requests = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("request %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+}
+
+func TestStreamChatMessage(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req SendMessageRequest
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		if req.Stream == nil || !*req.Stream {
+			t.Error("expected stream to be set")
+		}
+		if req.Message == "fail" {
+			w.WriteHeader(http.StatusTooManyRequests)
+			_, _ = w.Write([]byte(`{"detail": "Token budget exceeded"}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"chat_session_id": "s1"}` + "\n"))
+	}))
+	defer srv.Close()
+	c := New(apiclient.New(srv.URL))
+
+	stream, err := c.StreamChatMessage(SendMessageRequest{Message: "hi"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(stream)
+	_ = stream.Close()
+	if string(data) != `{"chat_session_id": "s1"}`+"\n" {
+		t.Errorf("stream = %q", data)
+	}
+
+	_, err = c.StreamChatMessage(SendMessageRequest{Message: "fail"})
+	var apiErr *Error
+	if !errors.As(err, &apiErr) || apiErr.StatusCode != http.StatusTooManyRequests || apiErr.Detail != "Token budget exceeded" {
+		t.Errorf("expected a 429 with the backend's detail, got %v", err)
+	}
+}
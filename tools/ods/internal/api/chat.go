@@ -0,0 +1,35 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+)
+
+// StreamChatMessage posts body to /chat/send-chat-message with stream set and
+// returns the answer stream, for chatstream.Read; the caller closes it.
+// SendChatMessage is the generated non-streaming form, which the generator
+// cannot produce for a text/event-stream response.
+func (c *Client) StreamChatMessage(body SendMessageRequest) (io.ReadCloser, error) {
+	stream := true
+	body.Stream = &stream
+	data, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request body: %w", err)
+	}
+	req, err := c.NewRequest(http.MethodPost, "/chat/send-chat-message", data)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := c.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		defer func() { _ = resp.Body.Close() }()
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, &Error{Method: http.MethodPost, Path: req.URL.Path, StatusCode: resp.StatusCode, Status: resp.Status, Detail: errorDetail(respBody)}
+	}
+	return resp.Body, nil
+}
@@ -0,0 +1,449 @@
+// Code generated by ods api regen from the backend's OpenAPI spec. DO NOT EDIT.
+
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// GetMe calls GET /me. Verify User Logged In.
+func (c *Client) GetMe() (UserInfo, error) {
+	var out UserInfo
+	err := c.do(http.MethodGet, "/me", nil, nil, &out)
+	return out, err
+}
+
+// IngestDocument calls POST /onyx-api/ingestion. Upsert Ingestion Doc.
+func (c *Client) IngestDocument(body IngestionDocument) (IngestionResult, error) {
+	var out IngestionResult
+	err := c.do(http.MethodPost, "/onyx-api/ingestion", nil, body, &out)
+	return out, err
+}
+
+// DeleteIngestedDocument calls DELETE /onyx-api/ingestion/{document_id}.
+// Delete Ingestion Doc.
+func (c *Client) DeleteIngestedDocument(documentID string) error {
+	return c.do(http.MethodDelete, fmt.Sprintf("/onyx-api/ingestion/%s", url.PathEscape(fmt.Sprint(documentID))), nil, nil, nil)
+}
+
+// AdminSearch calls POST /admin/search. Admin Search.
+func (c *Client) AdminSearch(body AdminSearchRequest) (AdminSearchResponse, error) {
+	var out AdminSearchResponse
+	err := c.do(http.MethodPost, "/admin/search", nil, body, &out)
+	return out, err
+}
+
+// SendChatMessage calls POST /chat/send-chat-message. Handle Send Chat
+// Message.
+func (c *Client) SendChatMessage(body SendMessageRequest) (ChatFullResponse, error) {
+	var out ChatFullResponse
+	err := c.do(http.MethodPost, "/chat/send-chat-message", nil, body, &out)
+	return out, err
+}
+
+// DeleteChatSession calls DELETE /chat/delete-chat-session/{session_id}.
+// Delete Chat Session By Id.
+func (c *Client) DeleteChatSession(sessionID string, params DeleteChatSessionParams) error {
+	return c.do(http.MethodDelete, fmt.Sprintf("/chat/delete-chat-session/%s", url.PathEscape(fmt.Sprint(sessionID))), params.values(), nil, nil)
+}
+
+// DeleteChatSessionParams holds the query parameters of DeleteChatSession.
+type DeleteChatSessionParams struct {
+	HardDelete *bool
+}
+
+func (p DeleteChatSessionParams) values() url.Values {
+	q := url.Values{}
+	if p.HardDelete != nil {
+		q.Set("hard_delete", fmt.Sprint(*p.HardDelete))
+	}
+	return q
+}
+
+type UserInfo struct {
+	Email              string               `json:"email"`
+	ID                 string               `json:"id"`
+	IsActive           bool                 `json:"is_active"`
+	IsAnonymousUser    *bool                `json:"is_anonymous_user,omitempty"`
+	IsCloudSuperuser   *bool                `json:"is_cloud_superuser,omitempty"`
+	IsSuperuser        bool                 `json:"is_superuser"`
+	IsVerified         bool                 `json:"is_verified"`
+	PasswordConfigured *bool                `json:"password_configured,omitempty"`
+	Personalization    *UserPersonalization `json:"personalization,omitempty"`
+	Preferences        UserPreferences      `json:"preferences"`
+	Role               UserRole             `json:"role"`
+	TeamName           *string              `json:"team_name,omitempty"`
+	TenantInfo         *TenantInfo          `json:"tenant_info,omitempty"`
+	TokenExpiresAt     *time.Time           `json:"token_expires_at,omitempty"`
+}
+
+type IngestionDocument struct {
+	CcPairID *int         `json:"cc_pair_id,omitempty"`
+	Document DocumentBase `json:"document"`
+}
+
+type IngestionResult struct {
+	AlreadyExisted bool   `json:"already_existed"`
+	DocumentID     string `json:"document_id"`
+}
+
+type AdminSearchRequest struct {
+	Filters BaseFilters `json:"filters"`
+	Query   string      `json:"query"`
+}
+
+type AdminSearchResponse struct {
+	Documents []SearchDoc `json:"documents"`
+}
+
+type SendMessageRequest struct {
+	AdditionalContext     *string                     `json:"additional_context,omitempty"`
+	AllowedToolIDs        []int                       `json:"allowed_tool_ids,omitempty"`
+	ChatSessionID         *string                     `json:"chat_session_id,omitempty"`
+	ChatSessionInfo       *ChatSessionCreationRequest `json:"chat_session_info,omitempty"`
+	DeepResearch          *bool                       `json:"deep_research,omitempty"`
+	FileDescriptors       []FileDescriptor            `json:"file_descriptors,omitempty"`
+	ForcedToolID          *int                        `json:"forced_tool_id,omitempty"`
+	IncludeCitations      *bool                       `json:"include_citations,omitempty"`
+	InternalSearchFilters *BaseFilters                `json:"internal_search_filters,omitempty"`
+	LLMOverride           *LLMOverride                `json:"llm_override,omitempty"`
+	LLMOverrides          []LLMOverride               `json:"llm_overrides,omitempty"`
+	McpHeaders            map[string]string           `json:"mcp_headers,omitempty"`
+	Message               string                      `json:"message"`
+	MockLLMResponse       *string                     `json:"mock_llm_response,omitempty"`
+	Origin                *MessageOrigin              `json:"origin,omitempty"`
+	ParentMessageID       *int                        `json:"parent_message_id,omitempty"`
+	Stream                *bool                       `json:"stream,omitempty"`
+}
+
+// ChatFullResponse: Complete non-streaming response with all available data.
+// NOTE: This model is used for the core flow of the Onyx application, any
+// changes to it should be reviewed and approved by an experienced team
+// member. It is very important to 1. avoid bloat and 2. that this remains
+// backwards compatible across versions.
+type ChatFullResponse struct {
+	Answer             string             `json:"answer"`
+	AnswerCitationless string             `json:"answer_citationless"`
+	ChatSessionID      *string            `json:"chat_session_id,omitempty"`
+	CitationInfo       []CitationInfo     `json:"citation_info"`
+	ErrorMsg           *string            `json:"error_msg,omitempty"`
+	MessageID          int                `json:"message_id"`
+	PreAnswerReasoning *string            `json:"pre_answer_reasoning,omitempty"`
+	ToolCalls          []ToolCallResponse `json:"tool_calls,omitempty"`
+	TopDocuments       []SearchDoc        `json:"top_documents"`
+}
+
+type UserPersonalization struct {
+	EnableMemoryTool *bool        `json:"enable_memory_tool,omitempty"`
+	Memories         []MemoryItem `json:"memories,omitempty"`
+	Name             *string      `json:"name,omitempty"`
+	Role             *string      `json:"role,omitempty"`
+	UseMemories      *bool        `json:"use_memories,omitempty"`
+	UserPreferences  *string      `json:"user_preferences,omitempty"`
+}
+
+type UserPreferences struct {
+	AssistantSpecificConfigs   map[string]UserSpecificAssistantPreference `json:"assistant_specific_configs,omitempty"`
+	AutoScroll                 *bool                                      `json:"auto_scroll,omitempty"`
+	ChatBackground             *string                                    `json:"chat_background,omitempty"`
+	ChosenAssistants           []int                                      `json:"chosen_assistants,omitempty"`
+	DefaultAppMode             *DefaultAppMode                            `json:"default_app_mode,omitempty"`
+	DefaultModel               *string                                    `json:"default_model,omitempty"`
+	HiddenAssistants           []int                                      `json:"hidden_assistants,omitempty"`
+	Language                   *string                                    `json:"language,omitempty"`
+	PasteAsTile                *bool                                      `json:"paste_as_tile,omitempty"`
+	PinnedAssistants           []int                                      `json:"pinned_assistants,omitempty"`
+	ShortcutEnabled            *bool                                      `json:"shortcut_enabled,omitempty"`
+	TemperatureOverrideEnabled *bool                                      `json:"temperature_override_enabled,omitempty"`
+	ThemePreference            *ThemePreference                           `json:"theme_preference,omitempty"`
+	VisibleAssistants          []int                                      `json:"visible_assistants,omitempty"`
+	VoiceAutoPlayback          *bool                                      `json:"voice_auto_playback,omitempty"`
+	VoiceAutoSend              *bool                                      `json:"voice_auto_send,omitempty"`
+	VoicePlaybackSpeed         *float64                                   `json:"voice_playback_speed,omitempty"`
+}
+
+// UserRole: User roles - Basic can't perform any admin actions - Admin can
+// perform all admin actions - Curator can perform admin actions for groups
+// they are curators of - Global Curator can perform admin actions for all
+// groups they are a member of - Limited can access a limited set of basic api
+// endpoints - Slack are users that have used onyx via slack but dont have a
+// web login - External permissioned users that have been picked up during the
+// external permissions sync process but don't have a web login
+type UserRole string
+
+const (
+	UserRoleLimited       UserRole = "limited"
+	UserRoleBasic         UserRole = "basic"
+	UserRoleAdmin         UserRole = "admin"
+	UserRoleCurator       UserRole = "curator"
+	UserRoleGlobalCurator UserRole = "global_curator"
+	UserRoleSlackUser     UserRole = "slack_user"
+	UserRoleExtPermUser   UserRole = "ext_perm_user"
+)
+
+type TenantInfo struct {
+	Invitation *TenantSnapshot `json:"invitation,omitempty"`
+	NewTenant  *TenantSnapshot `json:"new_tenant,omitempty"`
+}
+
+// DocumentBase: Used for Onyx ingestion api, the ID is inferred before use if
+// not provided
+type DocumentBase struct {
+	AdditionalInfo           json.RawMessage            `json:"additional_info,omitempty"`
+	ChunkCount               *int                       `json:"chunk_count,omitempty"`
+	DocCreatedAt             *time.Time                 `json:"doc_created_at,omitempty"`
+	DocMetadata              map[string]any             `json:"doc_metadata,omitempty"`
+	DocUpdatedAt             *time.Time                 `json:"doc_updated_at,omitempty"`
+	ExternalAccess           *ExternalAccess            `json:"external_access,omitempty"`
+	FileID                   *string                    `json:"file_id,omitempty"`
+	FromIngestionAPI         *bool                      `json:"from_ingestion_api,omitempty"`
+	ID                       *string                    `json:"id,omitempty"`
+	Metadata                 map[string]json.RawMessage `json:"metadata"`
+	ParentHierarchyNodeID    *int                       `json:"parent_hierarchy_node_id,omitempty"`
+	ParentHierarchyRawNodeID *string                    `json:"parent_hierarchy_raw_node_id,omitempty"`
+	PrimaryOwners            []BasicExpertInfo          `json:"primary_owners,omitempty"`
+	SecondaryOwners          []BasicExpertInfo          `json:"secondary_owners,omitempty"`
+	Sections                 []json.RawMessage          `json:"sections"`
+	SemanticIdentifier       string                     `json:"semantic_identifier"`
+	Source                   *DocumentSource            `json:"source,omitempty"`
+	Title                    *string                    `json:"title,omitempty"`
+}
+
+type BaseFilters struct {
+	CreatedAtRange *TimeRange       `json:"created_at_range,omitempty"`
+	DocumentSet    []string         `json:"document_set,omitempty"`
+	SourceType     []DocumentSource `json:"source_type,omitempty"`
+	Tags           []Tag            `json:"tags,omitempty"`
+	TimeCutoff     *time.Time       `json:"time_cutoff,omitempty"`
+	UpdatedAtRange *TimeRange       `json:"updated_at_range,omitempty"`
+}
+
+type SearchDoc struct {
+	Blurb                string                     `json:"blurb"`
+	Boost                int                        `json:"boost"`
+	ChunkInd             int                        `json:"chunk_ind"`
+	DocumentID           string                     `json:"document_id"`
+	FileID               *string                    `json:"file_id,omitempty"`
+	Hidden               bool                       `json:"hidden"`
+	IsInternet           *bool                      `json:"is_internet,omitempty"`
+	IsRelevant           *bool                      `json:"is_relevant,omitempty"`
+	Link                 *string                    `json:"link,omitempty"`
+	MatchHighlights      []string                   `json:"match_highlights"`
+	Metadata             map[string]json.RawMessage `json:"metadata"`
+	PrimaryOwners        []string                   `json:"primary_owners,omitempty"`
+	RelevanceExplanation *string                    `json:"relevance_explanation,omitempty"`
+	Score                *float64                   `json:"score,omitempty"`
+	SecondaryOwners      []string                   `json:"secondary_owners,omitempty"`
+	SemanticIdentifier   string                     `json:"semantic_identifier"`
+	SourceType           DocumentSource             `json:"source_type"`
+	UpdatedAt            *time.Time                 `json:"updated_at,omitempty"`
+}
+
+type ChatSessionCreationRequest struct {
+	Description *string `json:"description,omitempty"`
+	PersonaID   *int    `json:"persona_id,omitempty"`
+	ProjectID   *int    `json:"project_id,omitempty"`
+}
+
+// FileDescriptor: NOTE: is a `TypedDict` so it can be used as a type hint for
+// a JSONB column in Postgres
+type FileDescriptor struct {
+	ID         string       `json:"id"`
+	Name       *string      `json:"name,omitempty"`
+	Type       ChatFileType `json:"type"`
+	UserFileID *string      `json:"user_file_id,omitempty"`
+}
+
+// LLMOverride: Per-request LLM settings that override persona defaults. All
+// fields are optional — only the fields that differ from the persona's
+// configured LLM need to be supplied. Used both over the wire (API requests)
+// and for multi-model comparison, where one override is supplied per model.
+// Attributes: model_provider: LLM provider slug (e.g. “"openai"“,
+// “"anthropic"“). When “None“, the persona's default provider is used.
+// model_version: Specific model version string (e.g. “"gpt-4o"“). When
+// “None“, the persona's default model is used. temperature: Sampling
+// temperature in “[0, 2]“. When “None“, the persona's default temperature
+// is used. display_name: Human-readable label shown in the UI for this model,
+// e.g. “"GPT-4 Turbo"“. Optional; falls back to “model_version“ when not
+// set.
+type LLMOverride struct {
+	DisplayName   *string  `json:"display_name,omitempty"`
+	ModelProvider *string  `json:"model_provider,omitempty"`
+	ModelVersion  *string  `json:"model_version,omitempty"`
+	Temperature   *float64 `json:"temperature,omitempty"`
+}
+
+// MessageOrigin: Origin of a chat message for telemetry tracking.
+type MessageOrigin string
+
+const (
+	MessageOriginWebapp          MessageOrigin = "webapp"
+	MessageOriginChromeExtension MessageOrigin = "chrome_extension"
+	MessageOriginAPI             MessageOrigin = "api"
+	MessageOriginSlackbot        MessageOrigin = "slackbot"
+	MessageOriginWidget          MessageOrigin = "widget"
+	MessageOriginDiscordbot      MessageOrigin = "discordbot"
+	MessageOriginMobile          MessageOrigin = "mobile"
+	MessageOriginUnknown         MessageOrigin = "unknown"
+	MessageOriginUnset           MessageOrigin = "unset"
+)
+
+type CitationInfo struct {
+	CitationNumber int     `json:"citation_number"`
+	DocumentID     string  `json:"document_id"`
+	Type           *string `json:"type,omitempty"`
+}
+
+// ToolCallResponse: Tool call with full details for non-streaming response.
+type ToolCallResponse struct {
+	GeneratedImages []GeneratedImage `json:"generated_images,omitempty"`
+	PreReasoning    *string          `json:"pre_reasoning,omitempty"`
+	SearchDocs      []SearchDoc      `json:"search_docs,omitempty"`
+	ToolArguments   map[string]any   `json:"tool_arguments"`
+	ToolName        string           `json:"tool_name"`
+	ToolResult      string           `json:"tool_result"`
+}
+
+type MemoryItem struct {
+	Content string `json:"content"`
+	ID      *int   `json:"id,omitempty"`
+}
+
+type UserSpecificAssistantPreference struct {
+	DisabledToolIDs []int `json:"disabled_tool_ids"`
+}
+
+type DefaultAppMode string
+
+const (
+	DefaultAppModeAUTO   DefaultAppMode = "AUTO"
+	DefaultAppModeCHAT   DefaultAppMode = "CHAT"
+	DefaultAppModeSEARCH DefaultAppMode = "SEARCH"
+)
+
+type ThemePreference string
+
+const (
+	ThemePreferenceLight  ThemePreference = "light"
+	ThemePreferenceDark   ThemePreference = "dark"
+	ThemePreferenceSystem ThemePreference = "system"
+)
+
+type TenantSnapshot struct {
+	NumberOfUsers int    `json:"number_of_users"`
+	TenantID      string `json:"tenant_id"`
+}
+
+type ExternalAccess struct {
+	ExternalUserEmails   []string `json:"external_user_emails"`
+	ExternalUserGroupIDs []string `json:"external_user_group_ids"`
+	IsPublic             bool     `json:"is_public"`
+}
+
+// BasicExpertInfo: Basic Information for the owner of a document, any of the
+// fields can be left as None Display fallback goes as follows: - first_name +
+// (optional middle_initial) + last_name - display_name - email - first_name
+type BasicExpertInfo struct {
+	DisplayName   *string `json:"display_name,omitempty"`
+	Email         *string `json:"email,omitempty"`
+	FirstName     *string `json:"first_name,omitempty"`
+	LastName      *string `json:"last_name,omitempty"`
+	MiddleInitial *string `json:"middle_initial,omitempty"`
+}
+
+type DocumentSource string
+
+const (
+	DocumentSourceIngestionAPI       DocumentSource = "ingestion_api"
+	DocumentSourceSlack              DocumentSource = "slack"
+	DocumentSourceWeb                DocumentSource = "web"
+	DocumentSourceGoogleDrive        DocumentSource = "google_drive"
+	DocumentSourceGmail              DocumentSource = "gmail"
+	DocumentSourceGithub             DocumentSource = "github"
+	DocumentSourceGitbook            DocumentSource = "gitbook"
+	DocumentSourceGitlab             DocumentSource = "gitlab"
+	DocumentSourceGuru               DocumentSource = "guru"
+	DocumentSourceBookstack          DocumentSource = "bookstack"
+	DocumentSourceOutline            DocumentSource = "outline"
+	DocumentSourceConfluence         DocumentSource = "confluence"
+	DocumentSourceJira               DocumentSource = "jira"
+	DocumentSourceSlab               DocumentSource = "slab"
+	DocumentSourceProductboard       DocumentSource = "productboard"
+	DocumentSourceFile               DocumentSource = "file"
+	DocumentSourceCoda               DocumentSource = "coda"
+	DocumentSourceCanvas             DocumentSource = "canvas"
+	DocumentSourceNotion             DocumentSource = "notion"
+	DocumentSourceZulip              DocumentSource = "zulip"
+	DocumentSourceLinear             DocumentSource = "linear"
+	DocumentSourceHubspot            DocumentSource = "hubspot"
+	DocumentSourceDocument360        DocumentSource = "document360"
+	DocumentSourceGong               DocumentSource = "gong"
+	DocumentSourceGoogleSites        DocumentSource = "google_sites"
+	DocumentSourceZendesk            DocumentSource = "zendesk"
+	DocumentSourceLoopio             DocumentSource = "loopio"
+	DocumentSourceBox                DocumentSource = "box"
+	DocumentSourceDropbox            DocumentSource = "dropbox"
+	DocumentSourceSharepoint         DocumentSource = "sharepoint"
+	DocumentSourceTeams              DocumentSource = "teams"
+	DocumentSourceSalesforce         DocumentSource = "salesforce"
+	DocumentSourceDiscourse          DocumentSource = "discourse"
+	DocumentSourceAxero              DocumentSource = "axero"
+	DocumentSourceClickup            DocumentSource = "clickup"
+	DocumentSourceMediawiki          DocumentSource = "mediawiki"
+	DocumentSourceWikipedia          DocumentSource = "wikipedia"
+	DocumentSourceAsana              DocumentSource = "asana"
+	DocumentSourceS3                 DocumentSource = "s3"
+	DocumentSourceR2                 DocumentSource = "r2"
+	DocumentSourceGoogleCloudStorage DocumentSource = "google_cloud_storage"
+	DocumentSourceOciStorage         DocumentSource = "oci_storage"
+	DocumentSourceXenforo            DocumentSource = "xenforo"
+	DocumentSourceNotApplicable      DocumentSource = "not_applicable"
+	DocumentSourceDiscord            DocumentSource = "discord"
+	DocumentSourceFreshdesk          DocumentSource = "freshdesk"
+	DocumentSourceFireflies          DocumentSource = "fireflies"
+	DocumentSourceEgnyte             DocumentSource = "egnyte"
+	DocumentSourceAirtable           DocumentSource = "airtable"
+	DocumentSourceHighspot           DocumentSource = "highspot"
+	DocumentSourceDrupalWiki         DocumentSource = "drupal_wiki"
+	DocumentSourceImap               DocumentSource = "imap"
+	DocumentSourceBitbucket          DocumentSource = "bitbucket"
+	DocumentSourceTestrail           DocumentSource = "testrail"
+	DocumentSourceBraintrust         DocumentSource = "braintrust"
+	DocumentSourceLumapps            DocumentSource = "lumapps"
+	DocumentSourceMockConnector      DocumentSource = "mock_connector"
+	DocumentSourceUserFile           DocumentSource = "user_file"
+	DocumentSourceCraftFile          DocumentSource = "craft_file"
+)
+
+// TimeRange: An inclusive [start, end] window; either bound may be None
+// (open). Naive (timezone-less) bounds are treated as UTC.
+type TimeRange struct {
+	End   *time.Time `json:"end,omitempty"`
+	Start *time.Time `json:"start,omitempty"`
+}
+
+type Tag struct {
+	TagKey   string `json:"tag_key"`
+	TagValue string `json:"tag_value"`
+}
+
+type ChatFileType string
+
+const (
+	ChatFileTypeImage     ChatFileType = "image"
+	ChatFileTypeDocument  ChatFileType = "document"
+	ChatFileTypePlainText ChatFileType = "plain_text"
+	ChatFileTypeTabular   ChatFileType = "tabular"
+)
+
+// GeneratedImage: Represents an image generated by an image generation tool.
+type GeneratedImage struct {
+	FileID        string  `json:"file_id"`
+	RevisedPrompt string  `json:"revised_prompt"`
+	Shape         *string `json:"shape,omitempty"`
+	URL           string  `json:"url"`
+}
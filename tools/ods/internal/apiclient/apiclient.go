@@ -0,0 +1,126 @@
+// Package apiclient makes authenticated requests to an Onyx API server, with
+// either an API key or a session token (for example an impersonated one).
+package apiclient
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/impersonate"
+)
+
+const requestTimeout = 5 * time.Minute
+
+// Client talks to the API server at BaseURL. At most one of APIKey and
+// SessionToken is normally set; with neither, requests are anonymous.
+type Client struct {
+	BaseURL      string
+	APIKey       string
+	SessionToken string
+
+	HTTP *http.Client
+}
+
+// New returns a client for the API server at baseURL.
+func New(baseURL string) *Client {
+	return &Client{
+		BaseURL: strings.TrimSuffix(baseURL, "/"),
+		HTTP:    &http.Client{Timeout: requestTimeout},
+	}
+}
+
+// Auth describes how the client authenticates, for log lines.
+func (c *Client) Auth() string {
+	switch {
+	case c.SessionToken != "":
+		return "session"
+	case c.APIKey != "":
+		return "API key"
+	default:
+		return "anonymous"
+	}
+}
+
+// URL joins path, which may carry a query string, onto BaseURL.
+func (c *Client) URL(path string) string {
+	if !strings.HasPrefix(path, "/") {
+		path = "/" + path
+	}
+	return c.BaseURL + path
+}
+
+// NewRequest builds an authenticated request for path. A non-nil body is
+// sent as JSON unless the caller overrides Content-Type.
+func (c *Client) NewRequest(method, path string, body []byte) (*http.Request, error) {
+	var r io.Reader
+	if body != nil {
+		r = bytes.NewReader(body)
+	}
+	req, err := http.NewRequest(method, c.URL(path), r)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	switch {
+	case c.SessionToken != "":
+		req.AddCookie(&http.Cookie{Name: impersonate.SessionCookieName, Value: c.SessionToken})
+	case c.APIKey != "":
+		req.Header.Set("Authorization", "Bearer "+c.APIKey)
+	}
+	return req, nil
+}
+
+// Do sends req.
+func (c *Client) Do(req *http.Request) (*http.Response, error) {
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s failed: %w", req.Method, req.URL.Path, err)
+	}
+	return resp, nil
+}
+
+// ReadBody resolves a curl-style --data value: "@file" reads the file, "@-"
+// reads stdin and anything else is the body itself.
+func ReadBody(spec string, stdin io.Reader) ([]byte, error) {
+	name, ok := strings.CutPrefix(spec, "@")
+	if !ok {
+		return []byte(spec), nil
+	}
+	if name == "-" {
+		return io.ReadAll(stdin)
+	}
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read request body: %w", err)
+	}
+	return data, nil
+}
+
+// IsJSON reports whether contentType is a JSON media type.
+func IsJSON(contentType string) bool {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return false
+	}
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// Pretty indents body when it is valid JSON and returns it unchanged
+// otherwise.
+func Pretty(body []byte) []byte {
+	var out bytes.Buffer
+	if err := json.Indent(&out, bytes.TrimSpace(body), "", "  "); err != nil {
+		return body
+	}
+	out.WriteByte('\n')
+	return out.Bytes()
+}
@@ -0,0 +1,84 @@
+package apiclient
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/impersonate"
+)
+
+func TestNewRequestAuth(t *testing.T) {
+	c := New("http://localhost:8080/")
+	c.APIKey = "on_key"
+	req, err := c.NewRequest("GET", "manage/admin/connector?x=1", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := req.URL.String(); got != "http://localhost:8080/manage/admin/connector?x=1" {
+		t.Errorf("URL = %s", got)
+	}
+	if got := req.Header.Get("Authorization"); got != "Bearer on_key" {
+		t.Errorf("Authorization = %q", got)
+	}
+	if req.Header.Get("Content-Type") != "" {
+		t.Error("a request without a body should not set Content-Type")
+	}
+
+	c.SessionToken = "tok"
+	req, err = c.NewRequest("POST", "/chat/create-chat-session", []byte(`{}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.Header.Get("Authorization") != "" {
+		t.Error("a session token should take precedence over the API key")
+	}
+	if cookie, err := req.Cookie(impersonate.SessionCookieName); err != nil || cookie.Value != "tok" {
+		t.Errorf("expected the session cookie, got %v (%v)", cookie, err)
+	}
+	if req.Header.Get("Content-Type") != "application/json" {
+		t.Error("a request with a body should default to JSON")
+	}
+}
+
+func TestReadBody(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "body.json")
+	if err := os.WriteFile(path, []byte(`{"a":1}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct{ spec, want string }{
+		{`{"b":2}`, `{"b":2}`},
+		{"@" + path, `{"a":1}`},
+		{"@-", "from stdin"},
+	}
+	for _, tt := range tests {
+		got, err := ReadBody(tt.spec, strings.NewReader("from stdin"))
+		if err != nil || string(got) != tt.want {
+			t.Errorf("ReadBody(%q) = %q, %v; want %q", tt.spec, got, err, tt.want)
+		}
+	}
+	if _, err := ReadBody("@"+filepath.Join(t.TempDir(), "missing"), nil); err == nil {
+		t.Error("expected an error for a missing file")
+	}
+}
+
+func TestPretty(t *testing.T) {
+	if got := string(Pretty([]byte(`{"a":[1,2]}`))); got != "{\n  \"a\": [\n    1,\n    2\n  ]\n}\n" {
+		t.Errorf("Pretty() = %q", got)
+	}
+	if got := string(Pretty([]byte("not json"))); got != "not json" {
+		t.Errorf("Pretty() should pass non-JSON through, got %q", got)
+	}
+	for ct, want := range map[string]bool{
+		"application/json":                true,
+		"application/json; charset=utf-8": true,
+		"application/problem+json":        true,
+		"text/event-stream":               false,
+		"":                                false,
+	} {
+		if got := IsJSON(ct); got != want {
+			t.Errorf("IsJSON(%q) = %v, want %v", ct, got, want)
+		}
+	}
+}
@@ -0,0 +1,559 @@
+// Package apigen generates the typed Go client in internal/api from the
+// backend's OpenAPI spec. Only the operations listed in an operations file
+// are generated, along with the schemas they reference, so the client stays
+// small and its method names stay stable when FastAPI's operation IDs change.
+package apigen
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"go/token"
+	"io"
+	"net/http"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Header starts every generated file.
+const Header = "// Code generated by ods api regen from the backend's OpenAPI spec. DO NOT EDIT.\n"
+
+// Operation is an entry of the operations file: the Go method name to
+// generate for an HTTP method and path of the spec.
+type Operation struct {
+	Name   string
+	Method string
+	Path   string
+}
+
+// ParseOperations reads an operations file: one "<Name> <METHOD> <path>"
+// per line, with blank lines and #-comments ignored.
+func ParseOperations(r io.Reader) ([]Operation, error) {
+	var ops []Operation
+	seen := map[string]bool{}
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		fields := strings.Fields(line)
+		if len(fields) != 3 {
+			return nil, fmt.Errorf("line %d: expected <Name> <METHOD> <path>, got %q", n, line)
+		}
+		op := Operation{Name: fields[0], Method: strings.ToUpper(fields[1]), Path: fields[2]}
+		if !isExported(op.Name) {
+			return nil, fmt.Errorf("line %d: %q is not an exported Go name", n, op.Name)
+		}
+		if seen[op.Name] {
+			return nil, fmt.Errorf("line %d: %s is listed twice", n, op.Name)
+		}
+		seen[op.Name] = true
+		ops = append(ops, op)
+	}
+	return ops, scanner.Err()
+}
+
+// Spec is the subset of an OpenAPI 3 document the generator reads.
+type Spec struct {
+	Paths      map[string]map[string]*specOperation `json:"paths"`
+	Components struct {
+		Schemas map[string]*Schema `json:"schemas"`
+	} `json:"components"`
+}
+
+type specOperation struct {
+	Summary     string      `json:"summary"`
+	Parameters  []parameter `json:"parameters"`
+	RequestBody *struct {
+		Content map[string]struct {
+			Schema *Schema `json:"schema"`
+		} `json:"content"`
+	} `json:"requestBody"`
+	Responses map[string]struct {
+		Content map[string]struct {
+			Schema *Schema `json:"schema"`
+		} `json:"content"`
+	} `json:"responses"`
+}
+
+type parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+// Schema is the subset of a JSON schema the generator maps to Go types.
+type Schema struct {
+	Ref                  string             `json:"$ref"`
+	Type                 any                `json:"type"`
+	Format               string             `json:"format"`
+	Description          string             `json:"description"`
+	Properties           map[string]*Schema `json:"properties"`
+	Required             []string           `json:"required"`
+	Items                *Schema            `json:"items"`
+	AdditionalProperties json.RawMessage    `json:"additionalProperties"`
+	Enum                 []any              `json:"enum"`
+	AnyOf                []*Schema          `json:"anyOf"`
+	OneOf                []*Schema          `json:"oneOf"`
+	AllOf                []*Schema          `json:"allOf"`
+}
+
+// types returns the schema's JSON types; OpenAPI 3.1 allows a list.
+func (s *Schema) types() []string {
+	switch t := s.Type.(type) {
+	case string:
+		return []string{t}
+	case []any:
+		var out []string
+		for _, v := range t {
+			if str, ok := v.(string); ok {
+				out = append(out, str)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+func (s *Schema) isNull() bool {
+	t := s.types()
+	return len(t) == 1 && t[0] == "null"
+}
+
+// ParseSpec decodes an OpenAPI document.
+func ParseSpec(data []byte) (*Spec, error) {
+	var spec Spec
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return nil, fmt.Errorf("failed to parse OpenAPI spec: %w", err)
+	}
+	return &spec, nil
+}
+
+// Generate renders the client methods for ops and the types they reference
+// as the gofmt'ed source of a file in package pkg.
+func Generate(spec *Spec, ops []Operation, pkg string) ([]byte, error) {
+	g := &generator{spec: spec, named: map[string]bool{}}
+	var methods bytes.Buffer
+	for _, op := range ops {
+		if err := g.operation(&methods, op); err != nil {
+			return nil, err
+		}
+	}
+	// Rendering a type can reference more, so drain the queue.
+	var types bytes.Buffer
+	for i := 0; i < len(g.queue); i++ {
+		if err := g.namedType(&types, g.queue[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	body := methods.String() + types.String()
+	var out bytes.Buffer
+	out.WriteString(Header + "\npackage " + pkg + "\n\n")
+	var imports []string
+	for _, imp := range []struct{ pkg, use string }{
+		{"encoding/json", "json."},
+		{"fmt", "fmt."},
+		{"net/http", "http."},
+		{"net/url", "url."},
+		{"time", "time."},
+	} {
+		if strings.Contains(body, imp.use) {
+			imports = append(imports, imp.pkg)
+		}
+	}
+	if len(imports) > 0 {
+		out.WriteString("import (\n")
+		for _, imp := range imports {
+			fmt.Fprintf(&out, "\t%q\n", imp)
+		}
+		out.WriteString(")\n\n")
+	}
+	out.WriteString(body)
+
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("generated code does not parse: %w", err)
+	}
+	return src, nil
+}
+
+type generator struct {
+	spec *Spec
+	// queue holds the component schemas to render, in first-use order.
+	queue []string
+	named map[string]bool
+}
+
+func (g *generator) ref(ref string) (string, error) {
+	name, ok := strings.CutPrefix(ref, "#/components/schemas/")
+	if !ok {
+		return "", fmt.Errorf("unsupported $ref %q", ref)
+	}
+	if g.spec.Components.Schemas[name] == nil {
+		return "", fmt.Errorf("$ref to undefined schema %q", name)
+	}
+	if !g.named[name] {
+		g.named[name] = true
+		g.queue = append(g.queue, name)
+	}
+	return goName(name), nil
+}
+
+// goType maps s to a Go type. nullable reports whether the schema admits
+// null, so callers can choose a pointer.
+func (g *generator) goType(s *Schema) (typ string, nullable bool, err error) {
+	if s == nil {
+		return "json.RawMessage", false, nil
+	}
+	if s.Ref != "" {
+		t, err := g.ref(s.Ref)
+		return t, false, err
+	}
+	if variants := append(slices.Clone(s.AnyOf), s.OneOf...); len(variants) > 0 {
+		var rest []*Schema
+		for _, v := range variants {
+			if v.isNull() {
+				nullable = true
+			} else {
+				rest = append(rest, v)
+			}
+		}
+		if len(rest) != 1 {
+			return "json.RawMessage", nullable, nil
+		}
+		typ, inner, err := g.goType(rest[0])
+		return typ, nullable || inner, err
+	}
+	if len(s.AllOf) == 1 {
+		return g.goType(s.AllOf[0])
+	}
+
+	types := s.types()
+	if slices.Contains(types, "null") {
+		nullable = true
+		types = slices.DeleteFunc(slices.Clone(types), func(t string) bool { return t == "null" })
+	}
+	if len(types) != 1 {
+		return "json.RawMessage", nullable, nil
+	}
+	switch types[0] {
+	case "string":
+		if s.Format == "date-time" {
+			return "time.Time", nullable, nil
+		}
+		return "string", nullable, nil
+	case "integer":
+		return "int", nullable, nil
+	case "number":
+		return "float64", nullable, nil
+	case "boolean":
+		return "bool", nullable, nil
+	case "array":
+		item, _, err := g.goType(s.Items)
+		return "[]" + item, nullable, err
+	case "object":
+		if len(s.Properties) > 0 {
+			// Inline objects are rare in FastAPI specs; keep them raw.
+			return "json.RawMessage", nullable, nil
+		}
+		var additional Schema
+		if len(s.AdditionalProperties) > 0 && json.Unmarshal(s.AdditionalProperties, &additional) == nil {
+			value, _, err := g.goType(&additional)
+			return "map[string]" + value, nullable, err
+		}
+		return "map[string]any", nullable, nil
+	}
+	return "json.RawMessage", nullable, nil
+}
+
+// fieldType is goType for a struct field or parameter, using a pointer when
+// the value may be absent or null and its zero value would be ambiguous.
+func (g *generator) fieldType(s *Schema, required bool) (string, error) {
+	typ, nullable, err := g.goType(s)
+	if err != nil {
+		return "", err
+	}
+	if (nullable || !required) && !strings.HasPrefix(typ, "[]") && !strings.HasPrefix(typ, "map[") && typ != "json.RawMessage" {
+		typ = "*" + typ
+	}
+	return typ, nil
+}
+
+func (g *generator) namedType(w *bytes.Buffer, name string) error {
+	s := g.spec.Components.Schemas[name]
+	typeName := goName(name)
+	writeDoc(w, typeName, s.Description)
+
+	if len(s.Enum) > 0 && slices.Equal(s.types(), []string{"string"}) {
+		fmt.Fprintf(w, "type %s string\n\n", typeName)
+		w.WriteString("const (\n")
+		for _, v := range s.Enum {
+			str, _ := v.(string)
+			fmt.Fprintf(w, "\t%s%s %s = %q\n", typeName, goName(str), typeName, str)
+		}
+		w.WriteString(")\n\n")
+		return nil
+	}
+
+	if len(s.Properties) == 0 || len(s.AnyOf)+len(s.OneOf) > 0 {
+		typ, _, err := g.goType(s)
+		if err != nil {
+			return fmt.Errorf("schema %s: %w", name, err)
+		}
+		fmt.Fprintf(w, "type %s = %s\n\n", typeName, typ)
+		return nil
+	}
+
+	fmt.Fprintf(w, "type %s struct {\n", typeName)
+	props := make([]string, 0, len(s.Properties))
+	for p := range s.Properties {
+		props = append(props, p)
+	}
+	sort.Strings(props)
+	for _, p := range props {
+		required := slices.Contains(s.Required, p)
+		typ, err := g.fieldType(s.Properties[p], required)
+		if err != nil {
+			return fmt.Errorf("schema %s, property %s: %w", name, p, err)
+		}
+		tag := p
+		if !required {
+			tag += ",omitempty"
+		}
+		fmt.Fprintf(w, "\t%s %s `json:%q`\n", goName(p), typ, tag)
+	}
+	w.WriteString("}\n\n")
+	return nil
+}
+
+var pathParamRe = regexp.MustCompile(`\{([^}]+)\}`)
+
+func (g *generator) operation(w *bytes.Buffer, op Operation) error {
+	spec := g.spec.Paths[op.Path][strings.ToLower(op.Method)]
+	if spec == nil {
+		return fmt.Errorf("%s: %s %s is not in the spec", op.Name, op.Method, op.Path)
+	}
+
+	byName := map[string]parameter{}
+	var query []parameter
+	for _, p := range spec.Parameters {
+		switch p.In {
+		case "path":
+			byName[p.Name] = p
+		case "query":
+			query = append(query, p)
+		}
+	}
+
+	var args []string
+	pathExpr := fmt.Sprintf("%q", op.Path)
+	if params := pathParamRe.FindAllStringSubmatch(op.Path, -1); len(params) > 0 {
+		format := pathParamRe.ReplaceAllString(op.Path, "%s")
+		var values []string
+		for _, m := range params {
+			p, ok := byName[m[1]]
+			if !ok {
+				return fmt.Errorf("%s: path parameter %s is not declared", op.Name, m[1])
+			}
+			typ, _, err := g.goType(p.Schema)
+			if err != nil {
+				return fmt.Errorf("%s: %w", op.Name, err)
+			}
+			arg := goArg(p.Name)
+			args = append(args, arg+" "+typ)
+			values = append(values, fmt.Sprintf("url.PathEscape(fmt.Sprint(%s))", arg))
+		}
+		pathExpr = fmt.Sprintf("fmt.Sprintf(%q, %s)", format, strings.Join(values, ", "))
+	}
+
+	var paramsType string
+	if len(query) > 0 {
+		paramsType = op.Name + "Params"
+		args = append(args, "params "+paramsType)
+	}
+
+	bodyExpr := "nil"
+	if spec.RequestBody != nil {
+		content, ok := spec.RequestBody.Content["application/json"]
+		if !ok {
+			return fmt.Errorf("%s: only JSON request bodies are supported", op.Name)
+		}
+		typ, _, err := g.goType(content.Schema)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op.Name, err)
+		}
+		args = append(args, "body "+typ)
+		bodyExpr = "body"
+	}
+
+	var resultType string
+	for _, code := range []string{"200", "201", "202"} {
+		if content, ok := spec.Responses[code].Content["application/json"]; ok && content.Schema != nil && (content.Schema.Ref != "" || content.Schema.Type != nil || len(content.Schema.AnyOf) > 0) {
+			typ, _, err := g.goType(content.Schema)
+			if err != nil {
+				return fmt.Errorf("%s: %w", op.Name, err)
+			}
+			resultType = typ
+			break
+		}
+	}
+
+	doc := fmt.Sprintf("%s calls %s %s.", op.Name, op.Method, op.Path)
+	if spec.Summary != "" {
+		doc += " " + strings.TrimSuffix(spec.Summary, ".") + "."
+	}
+	writeDoc(w, "", doc)
+
+	queryExpr := "nil"
+	if paramsType != "" {
+		queryExpr = "params.values()"
+	}
+	method := httpMethodConst(op.Method)
+	if resultType == "" {
+		fmt.Fprintf(w, "func (c *Client) %s(%s) error {\n", op.Name, strings.Join(args, ", "))
+		fmt.Fprintf(w, "\treturn c.do(%s, %s, %s, %s, nil)\n}\n\n", method, pathExpr, queryExpr, bodyExpr)
+	} else {
+		fmt.Fprintf(w, "func (c *Client) %s(%s) (%s, error) {\n", op.Name, strings.Join(args, ", "), resultType)
+		fmt.Fprintf(w, "\tvar out %s\n", resultType)
+		fmt.Fprintf(w, "\terr := c.do(%s, %s, %s, %s, &out)\n", method, pathExpr, queryExpr, bodyExpr)
+		w.WriteString("\treturn out, err\n}\n\n")
+	}
+
+	if paramsType != "" {
+		return g.queryParams(w, op, paramsType, query)
+	}
+	return nil
+}
+
+func (g *generator) queryParams(w *bytes.Buffer, op Operation, typeName string, query []parameter) error {
+	writeDoc(w, "", fmt.Sprintf("%s holds the query parameters of %s.", typeName, op.Name))
+	fmt.Fprintf(w, "type %s struct {\n", typeName)
+	for _, p := range query {
+		typ, err := g.fieldType(p.Schema, false)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op.Name, err)
+		}
+		fmt.Fprintf(w, "\t%s %s\n", goName(p.Name), typ)
+	}
+	w.WriteString("}\n\n")
+
+	fmt.Fprintf(w, "func (p %s) values() url.Values {\n\tq := url.Values{}\n", typeName)
+	for _, p := range query {
+		field := "p." + goName(p.Name)
+		typ, _ := g.fieldType(p.Schema, false)
+		switch {
+		case strings.HasPrefix(typ, "[]"):
+			fmt.Fprintf(w, "\tfor _, v := range %s {\n\t\tq.Add(%q, fmt.Sprint(v))\n\t}\n", field, p.Name)
+		case strings.HasPrefix(typ, "*"):
+			fmt.Fprintf(w, "\tif %s != nil {\n\t\tq.Set(%q, fmt.Sprint(*%s))\n\t}\n", field, p.Name, field)
+		default:
+			fmt.Fprintf(w, "\tq.Set(%q, fmt.Sprint(%s))\n", p.Name, field)
+		}
+	}
+	w.WriteString("\treturn q\n}\n\n")
+	return nil
+}
+
+func httpMethodConst(method string) string {
+	switch method {
+	case http.MethodGet:
+		return "http.MethodGet"
+	case http.MethodPost:
+		return "http.MethodPost"
+	case http.MethodPut:
+		return "http.MethodPut"
+	case http.MethodPatch:
+		return "http.MethodPatch"
+	case http.MethodDelete:
+		return "http.MethodDelete"
+	}
+	return fmt.Sprintf("%q", method)
+}
+
+func writeDoc(w *bytes.Buffer, name, text string) {
+	text = strings.Join(strings.Fields(text), " ")
+	if text == "" {
+		return
+	}
+	if name != "" && !strings.HasPrefix(text, name+" ") {
+		text = name + ": " + text
+	}
+	line := "//"
+	for _, word := range strings.Fields(text) {
+		if len(line)+1+len(word) > 78 && line != "//" {
+			w.WriteString(line + "\n")
+			line = "//"
+		}
+		line += " " + word
+	}
+	w.WriteString(line + "\n")
+}
+
+// initialisms are upper-cased whole in Go names.
+var initialisms = map[string]bool{
+	"API": true, "ID": true, "IDS": true, "URL": true, "URI": true, "HTTP": true,
+	"JSON": true, "LLM": true, "SSO": true, "UI": true, "UUID": true,
+}
+
+// goName turns snake_case, kebab-case or PascalCase into an exported Go
+// name.
+func goName(s string) string {
+	parts := strings.FieldsFunc(s, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+	var b strings.Builder
+	for _, p := range parts {
+		if up := strings.ToUpper(p); initialisms[up] {
+			if up == "IDS" {
+				up = "IDs"
+			}
+			b.WriteString(up)
+			continue
+		}
+		b.WriteString(strings.ToUpper(p[:1]) + p[1:])
+	}
+	name := b.String()
+	if name == "" || unicode.IsDigit(rune(name[0])) {
+		name = "X" + name
+	}
+	return name
+}
+
+// goArg turns a parameter name into an unexported Go identifier.
+func goArg(s string) string {
+	name := goName(s)
+	arg := strings.ToLower(name)
+	for i, r := range name {
+		if unicode.IsLower(r) {
+			if i > 1 {
+				i--
+			}
+			arg = strings.ToLower(name[:i]) + name[i:]
+			break
+		}
+	}
+	if token.IsKeyword(arg) {
+		arg += "_"
+	}
+	return arg
+}
+
+func isExported(s string) bool {
+	if s == "" || !unicode.IsUpper(rune(s[0])) {
+		return false
+	}
+	for _, r := range s {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '_' {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,152 @@
+package apigen
+
+import (
+	"strings"
+	"testing"
+)
+
+const testSpec = `{
+  "openapi": "3.1.0",
+  "paths": {
+    "/chat/get-chat-session/{session_id}": {
+      "get": {
+        "summary": "Get Chat Session",
+        "parameters": [
+          {"name": "session_id", "in": "path", "required": true, "schema": {"type": "string", "format": "uuid"}},
+          {"name": "is_shared", "in": "query", "required": false, "schema": {"type": "boolean", "default": false}}
+        ],
+        "responses": {"200": {"content": {"application/json": {"schema": {"$ref": "#/components/schemas/ChatSessionDetail"}}}}}
+      }
+    },
+    "/chat/create-chat-session": {
+      "post": {
+        "requestBody": {"content": {"application/json": {"schema": {"$ref": "#/components/schemas/CreateChatSessionRequest"}}}},
+        "responses": {"200": {"content": {"application/json": {"schema": {"$ref": "#/components/schemas/CreateChatSessionID"}}}}}
+      }
+    },
+    "/auth/logout": {
+      "post": {"responses": {"204": {"description": "No Content"}}}
+    }
+  },
+  "components": {
+    "schemas": {
+      "ChatSessionDetail": {
+        "type": "object",
+        "description": "A chat session and its messages.",
+        "required": ["chat_session_id", "time_created", "shared_status"],
+        "properties": {
+          "chat_session_id": {"type": "string", "format": "uuid"},
+          "description": {"anyOf": [{"type": "string"}, {"type": "null"}]},
+          "time_created": {"type": "string", "format": "date-time"},
+          "shared_status": {"$ref": "#/components/schemas/ChatSessionSharedStatus"},
+          "persona_ids": {"type": "array", "items": {"type": "integer"}},
+          "metadata": {"type": "object", "additionalProperties": {"type": "string"}},
+          "packets": {"anyOf": [{"type": "string"}, {"type": "integer"}]}
+        }
+      },
+      "ChatSessionSharedStatus": {"type": "string", "enum": ["public", "private"]},
+      "CreateChatSessionRequest": {
+        "type": "object",
+        "required": ["persona_id"],
+        "properties": {"persona_id": {"type": "integer"}}
+      },
+      "CreateChatSessionID": {
+        "type": "object",
+        "required": ["chat_session_id"],
+        "properties": {"chat_session_id": {"type": "string"}}
+      }
+    }
+  }
+}`
+
+func TestGenerate(t *testing.T) {
+	spec, err := ParseSpec([]byte(testSpec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ops, err := ParseOperations(strings.NewReader(`
+# comment
+GetChatSession GET /chat/get-chat-session/{session_id}
+CreateChatSession post /chat/create-chat-session
+Logout POST /auth/logout
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err := Generate(spec, ops, "api")
+	if err != nil {
+		t.Fatalf("Generate() error: %v", err)
+	}
+	got := string(src)
+	for _, want := range []string{
+		Header,
+		`"net/http"`,
+		`"time"`,
+		"func (c *Client) GetChatSession(sessionID string, params GetChatSessionParams) (ChatSessionDetail, error) {",
+		`err := c.do(http.MethodGet, fmt.Sprintf("/chat/get-chat-session/%s", url.PathEscape(fmt.Sprint(sessionID))), params.values(), nil, &out)`,
+		"// GetChatSession calls GET /chat/get-chat-session/{session_id}. Get Chat\n// Session.",
+		"IsShared *bool",
+		`q.Set("is_shared", fmt.Sprint(*p.IsShared))`,
+		"func (c *Client) CreateChatSession(body CreateChatSessionRequest) (CreateChatSessionID, error) {",
+		"func (c *Client) Logout() error {",
+		"// ChatSessionDetail: A chat session and its messages.",
+		"ChatSessionID string `json:\"chat_session_id\"`",
+		"Description *string `json:\"description,omitempty\"`",
+		"TimeCreated time.Time `json:\"time_created\"`",
+		"SharedStatus ChatSessionSharedStatus `json:\"shared_status\"`",
+		"PersonaIDs []int `json:\"persona_ids,omitempty\"`",
+		"Metadata map[string]string `json:\"metadata,omitempty\"`",
+		"Packets json.RawMessage `json:\"packets,omitempty\"`",
+		"ChatSessionSharedStatusPublic ChatSessionSharedStatus = \"public\"",
+		"PersonaID int `json:\"persona_id\"`",
+	} {
+		if !strings.Contains(strings.Join(strings.Fields(got), " "), strings.Join(strings.Fields(want), " ")) {
+			t.Errorf("generated code is missing %q\n%s", want, got)
+		}
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	spec, err := ParseSpec([]byte(testSpec))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Generate(spec, []Operation{{Name: "Missing", Method: "GET", Path: "/nope"}}, "api"); err == nil || !strings.Contains(err.Error(), "not in the spec") {
+		t.Errorf("expected a missing operation error, got %v", err)
+	}
+
+	for _, input := range []string{
+		"GetMe GET",
+		"getMe GET /me",
+		"GetMe GET /me\nGetMe GET /me",
+	} {
+		if _, err := ParseOperations(strings.NewReader(input)); err == nil {
+			t.Errorf("ParseOperations(%q) should fail", input)
+		}
+	}
+}
+
+func TestGoNames(t *testing.T) {
+	for in, want := range map[string]string{
+		"chat_session_id": "ChatSessionID",
+		"persona_ids":     "PersonaIDs",
+		"api_key":         "APIKey",
+		"UserInfo":        "UserInfo",
+		"not-found":       "NotFound",
+		"2fa":             "X2fa",
+	} {
+		if got := goName(in); got != want {
+			t.Errorf("goName(%q) = %q, want %q", in, got, want)
+		}
+	}
+	for in, want := range map[string]string{
+		"session_id": "sessionID",
+		"id":         "id",
+		"url_path":   "urlPath",
+		"type":       "type_",
+	} {
+		if got := goArg(in); got != want {
+			t.Errorf("goArg(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
@@ -0,0 +1,280 @@
+// Package ask answers questions about Onyx deployments in plain language:
+// an LLM plans read-only ods commands, ods runs them, and the LLM summarizes
+// their output.
+package ask
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/alias"
+)
+
+// DefaultURL is the OpenAI API; any OpenAI-compatible server works.
+const DefaultURL = "https://api.openai.com/v1"
+
+// DefaultModel plans and answers when none is configured.
+const DefaultModel = "gpt-4.1"
+
+// maxOutput caps how much of a command's output the model is shown.
+const maxOutput = 16 << 10
+
+const systemPrompt = `You help support staff answer questions about Onyx deployments by running
+read-only ods commands and reading their output.
+
+Commands you may run, with the flags each allows:
+%s
+%s
+Reply with only a JSON object, either
+  {"commands": ["whois jane@acme.com"]}
+to run commands (without the leading "ods"; you will be sent their output),
+or
+  {"answer": "..."}
+once you can answer. Run only the commands the question needs, a few at a
+time, and use what earlier commands returned (e.g. a tenant ID from whois)
+in later ones. Base the answer only on the output: name tenants by ID, give
+the facts that answer the question, and say what you could not find out.
+Keep the answer to a few sentences.`
+
+// Message is one message of a chat.
+type Message struct {
+	Role    string `json:"role"`
+	Content string `json:"content"`
+}
+
+// LLM is a chat model behind an OpenAI-compatible chat completions API.
+type LLM struct {
+	URL    string
+	APIKey string
+	Model  string
+
+	HTTP *http.Client
+}
+
+// NewLLM returns model at the API under url.
+func NewLLM(url, apiKey, model string) *LLM {
+	return &LLM{
+		URL:    strings.TrimSuffix(url, "/"),
+		APIKey: apiKey,
+		Model:  model,
+		HTTP:   &http.Client{Timeout: 2 * time.Minute},
+	}
+}
+
+// Chat sends messages and returns the model's reply.
+func (l *LLM) Chat(messages []Message) (string, error) {
+	body, err := json.Marshal(map[string]any{
+		"model":       l.Model,
+		"temperature": 0,
+		"messages":    messages,
+	})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequest(http.MethodPost, l.URL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if l.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+l.APIKey)
+	}
+	resp, err := l.HTTP.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("LLM request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", fmt.Errorf("failed to read the LLM's response: %w", err)
+	}
+	if resp.StatusCode >= 300 {
+		return "", fmt.Errorf("LLM returned %s: %s", resp.Status, truncate(string(bytes.TrimSpace(data)), 300))
+	}
+	var completion struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal(data, &completion); err != nil || len(completion.Choices) == 0 {
+		return "", fmt.Errorf("unexpected response from the LLM: %s", truncate(string(data), 300))
+	}
+	return completion.Choices[0].Message.Content, nil
+}
+
+// Step is a command the model asked for.
+type Step struct {
+	Command []string `json:"command"`
+	Output  string   `json:"output,omitempty"`
+	// Error is why the command failed, or why it was refused without
+	// running.
+	Error string `json:"error,omitempty"`
+	// Refused is set when the command is not a read-only tool and did not
+	// run.
+	Refused bool `json:"refused,omitempty"`
+}
+
+// Result is the answer to a question and how it was found.
+type Result struct {
+	Question string `json:"question"`
+	Answer   string `json:"answer"`
+	Steps    []Step `json:"steps"`
+}
+
+// Options configures Ask.
+type Options struct {
+	// Chat sends the conversation to the model and returns its reply.
+	Chat func(messages []Message) (string, error)
+	// Run runs an ods command line, without the leading "ods", and returns
+	// its output.
+	Run func(argv []string) (string, error)
+	// Context is passed as -c to commands that do not name one; "" leaves
+	// each command's default.
+	Context string
+	// MaxRounds caps how many times the model may run commands before it
+	// must answer.
+	MaxRounds int
+	// OnStep, if set, is called before each command runs.
+	OnStep func(argv []string)
+}
+
+// plan is a reply of the model.
+type plan struct {
+	Commands []string `json:"commands"`
+	Answer   string   `json:"answer"`
+}
+
+// Ask answers question, running the commands the model plans.
+func Ask(question string, opts Options) (*Result, error) {
+	res := &Result{Question: question}
+	messages := []Message{
+		{Role: "system", Content: Prompt(opts.Context)},
+		{Role: "user", Content: question},
+	}
+	for round := 0; ; round++ {
+		reply, err := opts.Chat(messages)
+		if err != nil {
+			return res, err
+		}
+		p, err := parsePlan(reply)
+		if err != nil {
+			return res, err
+		}
+		messages = append(messages, Message{Role: "assistant", Content: reply})
+		if len(p.Commands) == 0 {
+			if p.Answer == "" {
+				return res, fmt.Errorf("LLM replied with neither commands nor an answer")
+			}
+			res.Answer = p.Answer
+			return res, nil
+		}
+		if round >= opts.MaxRounds {
+			if round > opts.MaxRounds {
+				return res, fmt.Errorf("no answer after running commands %d times", opts.MaxRounds)
+			}
+			messages = append(messages, Message{Role: "user", Content: `You may not run more commands. Answer now with {"answer": "..."}.`})
+			continue
+		}
+
+		var out strings.Builder
+		for _, line := range p.Commands {
+			step := runStep(line, opts)
+			res.Steps = append(res.Steps, step)
+			fmt.Fprintf(&out, "$ ods %s\n", strings.Join(step.Command, " "))
+			if step.Output != "" {
+				out.WriteString(truncate(step.Output, maxOutput))
+				if !strings.HasSuffix(step.Output, "\n") {
+					out.WriteString("\n")
+				}
+			}
+			if step.Error != "" {
+				fmt.Fprintf(&out, "(failed: %s)\n", step.Error)
+			}
+			out.WriteString("\n")
+		}
+		messages = append(messages, Message{Role: "user", Content: out.String()})
+	}
+}
+
+// runStep checks and runs one command line the model asked for.
+func runStep(line string, opts Options) Step {
+	argv, err := alias.Split(line)
+	if err == nil && len(argv) > 0 && argv[0] == "ods" {
+		argv = argv[1:]
+	}
+	if err != nil {
+		return Step{Command: []string{line}, Error: err.Error(), Refused: true}
+	}
+	if _, err := Check(argv); err != nil {
+		return Step{Command: argv, Error: err.Error(), Refused: true}
+	}
+	if opts.Context != "" && !namesContext(argv) {
+		argv = append(argv, "-c", opts.Context)
+	}
+	if opts.OnStep != nil {
+		opts.OnStep(argv)
+	}
+	step := Step{Command: argv}
+	step.Output, err = opts.Run(argv)
+	if err != nil {
+		step.Error = err.Error()
+	}
+	return step
+}
+
+func namesContext(argv []string) bool {
+	for _, a := range argv {
+		if a == "-c" || a == "--context" || strings.HasPrefix(a, "-c=") || strings.HasPrefix(a, "--context=") {
+			return true
+		}
+	}
+	return false
+}
+
+// Prompt returns the system prompt listing the tools.
+func Prompt(context string) string {
+	var tools strings.Builder
+	for i := range Tools {
+		t := &Tools[i]
+		var flags []string
+		for f := range t.Flags {
+			flags = append(flags, f)
+		}
+		sort.Strings(flags)
+		fmt.Fprintf(&tools, "  %s  [%s]\n      %s\n", t.Usage(), strings.Join(flags, ", "), t.Help)
+	}
+	var ctx string
+	if context != "" {
+		ctx = fmt.Sprintf("\nCommands run against the %q cluster context unless you pass -c <context>.\n", context)
+	}
+	return fmt.Sprintf(systemPrompt, tools.String(), ctx)
+}
+
+// parsePlan reads the model's JSON reply, tolerating a code fence or text
+// around it.
+func parsePlan(content string) (*plan, error) {
+	start, end := strings.Index(content, "{"), strings.LastIndex(content, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("LLM did not reply with JSON: %q", truncate(content, 200))
+	}
+	var p plan
+	if err := json.Unmarshal([]byte(content[start:end+1]), &p); err != nil {
+		return nil, fmt.Errorf("LLM did not reply with JSON: %q", truncate(content, 200))
+	}
+	return &p, nil
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "…"
+}
@@ -0,0 +1,107 @@
+package ask
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	allowed := [][]string{
+		{"whois", "jane@acme.com"},
+		{"whois", "jane@acme.com", "-c", "prod"},
+		{"pause-tenant-indexing", "tenant_abcd", "--status", "--context=prod"},
+		{"access", "sync-status", "--tenant", "tenant_abcd"},
+		{"health"},
+	}
+	for _, argv := range allowed {
+		if _, err := Check(argv); err != nil {
+			t.Errorf("Check(%q): %v", argv, err)
+		}
+	}
+
+	refused := [][]string{
+		{"tenant", "delete", "tenant_abcd"},
+		{"pause-tenant-indexing", "tenant_abcd"},
+		{"pause-tenant-indexing", "--all-tenants", "--reason", "x", "--status"},
+		{"pause-tenant-indexing", "tenant_abcd", "--status=false"},
+		{"pause-tenant-indexing", "tenant_abcd", "--status=true"},
+		{"credentials", "check", "--all-tenants=false"},
+		{"migrate", "status", "--fix"},
+		{"health", "--watch"},
+		{"health", "extra"},
+		{"usage"},
+	}
+	for _, argv := range refused {
+		if _, err := Check(argv); err == nil {
+			t.Errorf("Check(%q) should refuse", argv)
+		}
+	}
+}
+
+func TestAsk(t *testing.T) {
+	replies := []string{
+		`{"commands": ["whois jane@acme.com", "tenant delete tenant_abcd"]}`,
+		"```json\n" + `{"commands": ["ods pause-tenant-indexing tenant_abcd --status"]}` + "\n```",
+		`{"answer": "jane@acme.com is in tenant_abcd; indexing is running."}`,
+	}
+	var sent [][]Message
+	var ran [][]string
+	res, err := Ask("which tenant is jane@acme.com in and is indexing healthy?", Options{
+		Chat: func(messages []Message) (string, error) {
+			sent = append(sent, messages)
+			reply := replies[0]
+			replies = replies[1:]
+			return reply, nil
+		},
+		Run: func(argv []string) (string, error) {
+			ran = append(ran, argv)
+			if argv[0] == "whois" {
+				return "EMAIL          TENANT ID    ACTIVE\njane@acme.com  tenant_abcd  true\n", nil
+			}
+			return "", fmt.Errorf("exit status 1")
+		},
+		Context:   "prod",
+		MaxRounds: 5,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(res.Answer, "jane@acme.com is in tenant_abcd") {
+		t.Errorf("Answer = %q", res.Answer)
+	}
+
+	want := [][]string{
+		{"whois", "jane@acme.com", "-c", "prod"},
+		{"pause-tenant-indexing", "tenant_abcd", "--status", "-c", "prod"},
+	}
+	if !reflect.DeepEqual(ran, want) {
+		t.Errorf("ran %q, want %q", ran, want)
+	}
+	if len(res.Steps) != 3 || !res.Steps[1].Refused || res.Steps[2].Error == "" {
+		t.Errorf("unexpected steps: %+v", res.Steps)
+	}
+
+	fed := sent[1][len(sent[1])-1].Content
+	for _, s := range []string{"$ ods whois jane@acme.com -c prod", "tenant_abcd  true", "not a read-only command"} {
+		if !strings.Contains(fed, s) {
+			t.Errorf("command output sent to the model lacks %q:\n%s", s, fed)
+		}
+	}
+}
+
+func TestAskMaxRounds(t *testing.T) {
+	rounds := 0
+	_, err := Ask("loop", Options{
+		Chat: func(messages []Message) (string, error) {
+			rounds++
+			return `{"commands": ["health"]}`, nil
+		},
+		Run:       func(argv []string) (string, error) { return "ok\n", nil },
+		MaxRounds: 2,
+	})
+	if err == nil || rounds != 4 {
+		t.Errorf("expected to give up after 2 rounds and a last call for an answer, got %d chats, err %v", rounds, err)
+	}
+}
@@ -0,0 +1,148 @@
+package ask
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Tool is an ods command the planner may run. Only read-only commands are
+// tools, and only with the flags listed, so nothing the planner asks for
+// can change an environment.
+type Tool struct {
+	// Path is the command, e.g. ["access", "sync-status"].
+	Path []string
+	// Args names the positional arguments, for the prompt; "" takes none.
+	Args string
+	// Flags maps the flags allowed to whether they take a value.
+	Flags map[string]bool
+	// Require are flags that must be passed, e.g. --status to make
+	// pause-tenant-indexing only report.
+	Require []string
+	// Help says what the command answers.
+	Help string
+}
+
+// contextFlags are the flags every cluster command takes.
+var contextFlags = map[string]bool{"-c": true, "--context": true}
+
+// Tools are the commands ods ask may run.
+var Tools = []Tool{
+	{
+		Path:  []string{"whois"},
+		Args:  "<email-fragment or tenant-id>",
+		Flags: contextFlags,
+		Help:  "Find the tenant of a user by email fragment, or the admins of a tenant by tenant ID.",
+	},
+	{
+		Path:  []string{"health"},
+		Flags: with(contextFlags, map[string]bool{"--timeout": true}),
+		Help:  "Probe the api server, postgres, redis, vespa, model servers and celery of a deployment.",
+	},
+	{
+		Path:    []string{"pause-tenant-indexing"},
+		Args:    "[tenant-id...]",
+		Flags:   with(contextFlags, map[string]bool{"--status": false, "--all-tenants": false}),
+		Require: []string{"--status"},
+		Help:    "With --status: per tenant, the connectors whose indexing is paused, running and failed.",
+	},
+	{
+		Path:  []string{"access", "sync-status"},
+		Flags: with(contextFlags, map[string]bool{"--tenant": true}),
+		Help:  "The health of permission sync per connector of a tenant.",
+	},
+	{
+		Path:  []string{"credentials", "check"},
+		Flags: with(contextFlags, map[string]bool{"--tenant": true, "--all-tenants": false, "--within": true}),
+		Help:  "Test the stored connector credentials of a tenant and report failing or expiring ones.",
+	},
+	{
+		Path:  []string{"migrate", "status"},
+		Flags: with(contextFlags, map[string]bool{"--tenant": true, "--all-tenants": false}),
+		Help:  "Whether tenant schemas are at the latest database migration.",
+	},
+	{
+		Path:  []string{"events"},
+		Flags: with(contextFlags, map[string]bool{"--since": true, "--pod": true}),
+		Help:  "Recent Kubernetes warning events (crash loops, OOM kills, scheduling failures).",
+	},
+	{
+		Path:  []string{"domains", "list"},
+		Flags: with(contextFlags, map[string]bool{"--tenant": true}),
+		Help:  "Custom domains of tenants and the state of their certificates.",
+	},
+	{
+		Path:  []string{"usage", "tokens"},
+		Flags: with(contextFlags, map[string]bool{"--tenant": true, "--since": true, "--by": true}),
+		Help:  "A tenant's LLM token consumption by model and assistant.",
+	},
+}
+
+func with(a, b map[string]bool) map[string]bool {
+	out := make(map[string]bool, len(a)+len(b))
+	for k, v := range a {
+		out[k] = v
+	}
+	for k, v := range b {
+		out[k] = v
+	}
+	return out
+}
+
+// Usage returns the tool's usage line, e.g. "pause-tenant-indexing
+// [tenant-id...] --status".
+func (t *Tool) Usage() string {
+	parts := append([]string{}, t.Path...)
+	if t.Args != "" {
+		parts = append(parts, t.Args)
+	}
+	parts = append(parts, t.Require...)
+	return strings.Join(parts, " ")
+}
+
+// Check returns the tool argv runs, or an error when argv is not a tool or
+// passes a flag the tool does not allow.
+func Check(argv []string) (*Tool, error) {
+	var tool *Tool
+	for i := range Tools {
+		t := &Tools[i]
+		if len(argv) >= len(t.Path) && strings.Join(argv[:len(t.Path)], " ") == strings.Join(t.Path, " ") {
+			tool = t
+			break
+		}
+	}
+	if tool == nil {
+		return nil, fmt.Errorf("ods %s is not a read-only command ods ask may run", strings.Join(argv, " "))
+	}
+
+	passed := map[string]bool{}
+	rest := argv[len(tool.Path):]
+	for i := 0; i < len(rest); i++ {
+		arg := rest[i]
+		if !strings.HasPrefix(arg, "-") {
+			if tool.Args == "" {
+				return nil, fmt.Errorf("ods %s takes no arguments, got %q", strings.Join(tool.Path, " "), arg)
+			}
+			continue
+		}
+		name, _, hasValue := strings.Cut(arg, "=")
+		takesValue, ok := tool.Flags[name]
+		if !ok {
+			return nil, fmt.Errorf("ods ask may not pass %s to ods %s", name, strings.Join(tool.Path, " "))
+		}
+		// A switch given a value could turn it off (--status=false), so
+		// switches are only accepted bare.
+		if !takesValue && hasValue {
+			return nil, fmt.Errorf("ods ask may only pass %s without a value", name)
+		}
+		if takesValue && !hasValue {
+			i++
+		}
+		passed[name] = true
+	}
+	for _, r := range tool.Require {
+		if !passed[r] {
+			return nil, fmt.Errorf("ods %s must be run with %s", strings.Join(tool.Path, " "), r)
+		}
+	}
+	return tool, nil
+}
@@ -106,6 +106,8 @@ type Finding struct {
 	// Manifest is the repo-relative lockfile/manifest the finding came from,
 	// used for SARIF locations. May be empty.
 	Manifest string `json:"manifest,omitempty"`
+	// Images lists every image an ods scan images finding was found in.
+	Images []string `json:"images,omitempty"`
 }
 
 // Options configures an audit run.
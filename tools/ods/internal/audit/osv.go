@@ -24,6 +24,12 @@ func init() {
 // osvBaseURL is the canonical OSV.dev vulnerability page prefix.
 const osvBaseURL = "https://osv.dev/vulnerability/"
 
+// ScanLockfiles runs osv-scanner over the given lockfiles (or go.mod files)
+// and returns the raw findings, with no allowlist applied.
+func ScanLockfiles(lockfiles []string) ([]Finding, error) {
+	return scanLockfiles(lockfiles)
+}
+
 // scanLockfiles runs osv-scanner (as a library) over the given lockfiles and
 // maps the results into Findings. Returns nil when there are no lockfiles.
 func scanLockfiles(lockfiles []string) ([]Finding, error) {
@@ -119,6 +119,9 @@ func renderText(w io.Writer, res *Result) error {
 		if f.Source == SourceDependabot {
 			_, _ = fmt.Fprint(w, " [dependabot]")
 		}
+		if len(f.Images) > 1 {
+			_, _ = fmt.Fprintf(w, " [%d images]", len(f.Images))
+		}
 		_, _ = fmt.Fprintln(w)
 	}
 
@@ -0,0 +1,249 @@
+package audit
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os/exec"
+	"slices"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// The external image scanners RunImages can drive.
+const (
+	ScannerTrivy = "trivy"
+	ScannerGrype = "grype"
+)
+
+// DetectScanner returns the first of trivy and grype on PATH.
+func DetectScanner() (string, error) {
+	for _, s := range []string{ScannerTrivy, ScannerGrype} {
+		if _, err := exec.LookPath(s); err == nil {
+			return s, nil
+		}
+	}
+	return "", fmt.Errorf("neither trivy nor grype is installed (brew install trivy, or see https://trivy.dev)")
+}
+
+// ImagesOptions configures a multi-image scan with an external scanner.
+type ImagesOptions struct {
+	Images    []string
+	Scanner   string // trivy or grype
+	Format    string // comma-separated list of text|json|sarif
+	FailOn    Severity
+	IgnoreURL string
+	// Stdout/Stderr route the requested formats; see Options and renderReport.
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// RunImages scans each image with trivy or grype, merges the findings so an
+// advisory shared by several images is reported once with every image it is
+// in, applies the S3 allowlist used by Run, and renders a report. Findings at
+// or above opts.FailOn are reported as Blocking.
+func RunImages(opts ImagesOptions) (*Result, error) {
+	var findings []Finding
+	for _, ref := range opts.Images {
+		log.Infof("Scanning %s with %s...", ref, opts.Scanner)
+		fs, err := scanImageWith(opts.Scanner, ref)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", ref, err)
+		}
+		findings = append(findings, fs...)
+	}
+
+	ignores, err := FetchIgnores(opts.IgnoreURL)
+	if err != nil {
+		// Err toward blocking, as Run does.
+		log.Warnf("Could not fetch allowlist from %s: %v (continuing with no suppressions)", opts.IgnoreURL, err)
+		ignores = nil
+	}
+
+	findings = mergeImageFindings(findings)
+
+	kept, suppressed := applyIgnores(findings, ignores, time.Now())
+	sortFindings(kept)
+	sortFindings(suppressed)
+
+	result := &Result{
+		Findings: kept,
+		Ignored:  suppressed,
+		Blocking: blockingFindings(kept, opts.FailOn),
+	}
+
+	if err := renderReport(opts.Stdout, opts.Stderr, opts.Format, result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+func scanImageWith(scanner, ref string) ([]Finding, error) {
+	var args []string
+	switch scanner {
+	case ScannerTrivy:
+		args = []string{"image", "--format", "json", "--quiet", "--scanners", "vuln", ref}
+	case ScannerGrype:
+		args = []string{ref, "-o", "json", "-q"}
+	default:
+		return nil, fmt.Errorf("unknown scanner %q (want trivy or grype)", scanner)
+	}
+	cmd := exec.Command(scanner, args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("%s failed: %w: %s", scanner, err, strings.TrimSpace(stderr.String()))
+	}
+
+	var findings []Finding
+	if scanner == ScannerTrivy {
+		findings, err = parseTrivy(out)
+	} else {
+		findings, err = parseGrype(out)
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i := range findings {
+		findings[i].Source = scanner
+		findings[i].Manifest = ref
+		findings[i].Images = []string{ref}
+	}
+	return findings, nil
+}
+
+// scannerEcosystems maps trivy result types and grype artifact types to the
+// OSV ecosystem names the rest of the audit uses.
+var scannerEcosystems = map[string]string{
+	"debian": "Debian", "deb": "Debian",
+	"ubuntu": "Ubuntu",
+	"alpine": "Alpine", "apk": "Alpine",
+	"python-pkg": "PyPI", "pip": "PyPI", "pipenv": "PyPI", "poetry": "PyPI", "uv": "PyPI", "python": "PyPI",
+	"node-pkg": "npm", "npm": "npm", "yarn": "npm", "pnpm": "npm", "bun": "npm",
+	"gobinary": "Go", "gomod": "Go", "go-module": "Go",
+	"jar": "Maven", "pom": "Maven", "java-archive": "Maven",
+	"rust-binary": "crates.io", "cargo": "crates.io", "rust-crate": "crates.io",
+}
+
+func scannerEcosystem(kind string) string {
+	if eco, ok := scannerEcosystems[strings.ToLower(kind)]; ok {
+		return eco
+	}
+	return kind
+}
+
+// parseTrivy maps trivy image --format json output into Findings.
+func parseTrivy(data []byte) ([]Finding, error) {
+	var report struct {
+		Results []struct {
+			Type            string
+			Vulnerabilities []struct {
+				VulnerabilityID  string
+				PkgName          string
+				InstalledVersion string
+				FixedVersion     string
+				Severity         string
+				Title            string
+				PrimaryURL       string
+			}
+		}
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse trivy output: %w", err)
+	}
+	var findings []Finding
+	for _, res := range report.Results {
+		for _, v := range res.Vulnerabilities {
+			findings = append(findings, Finding{
+				ID:        v.VulnerabilityID,
+				Ecosystem: scannerEcosystem(res.Type),
+				Package:   v.PkgName,
+				Version:   v.InstalledVersion,
+				Severity:  ParseSeverity(v.Severity),
+				Title:     v.Title,
+				URL:       v.PrimaryURL,
+				FixedIn:   v.FixedVersion,
+			})
+		}
+	}
+	return findings, nil
+}
+
+// parseGrype maps grype -o json output into Findings.
+func parseGrype(data []byte) ([]Finding, error) {
+	var report struct {
+		Matches []struct {
+			Vulnerability struct {
+				ID          string
+				Severity    string
+				DataSource  string
+				Description string
+				Fix         struct {
+					Versions []string
+				}
+			}
+			RelatedVulnerabilities []struct {
+				ID string
+			}
+			Artifact struct {
+				Name    string
+				Version string
+				Type    string
+			}
+		}
+	}
+	if err := json.Unmarshal(data, &report); err != nil {
+		return nil, fmt.Errorf("failed to parse grype output: %w", err)
+	}
+	var findings []Finding
+	for _, m := range report.Matches {
+		f := Finding{
+			ID:        m.Vulnerability.ID,
+			Ecosystem: scannerEcosystem(m.Artifact.Type),
+			Package:   m.Artifact.Name,
+			Version:   m.Artifact.Version,
+			Severity:  ParseSeverity(m.Vulnerability.Severity),
+			Title:     firstLine(m.Vulnerability.Description),
+			URL:       m.Vulnerability.DataSource,
+			FixedIn:   strings.Join(m.Vulnerability.Fix.Versions, ", "),
+		}
+		for _, r := range m.RelatedVulnerabilities {
+			if r.ID != f.ID {
+				f.Aliases = append(f.Aliases, r.ID)
+			}
+		}
+		findings = append(findings, f)
+	}
+	return findings, nil
+}
+
+func firstLine(s string) string {
+	line, _, _ := strings.Cut(strings.TrimSpace(s), "\n")
+	return strings.TrimSpace(line)
+}
+
+// mergeImageFindings collapses findings of the same advisory, package and
+// version from different images into one, listing every image in Images.
+// The same base layer makes most OS findings show up in every Onyx image.
+func mergeImageFindings(findings []Finding) []Finding {
+	index := make(map[string]int, len(findings))
+	var out []Finding
+	for _, f := range findings {
+		key := strings.ToLower(f.ID) + "\x00" + strings.ToLower(f.Ecosystem) + "\x00" + f.Package + "\x00" + f.Version
+		if i, ok := index[key]; ok {
+			for _, image := range f.Images {
+				if !slices.Contains(out[i].Images, image) {
+					out[i].Images = append(out[i].Images, image)
+				}
+			}
+			continue
+		}
+		index[key] = len(out)
+		out = append(out, f)
+	}
+	return out
+}
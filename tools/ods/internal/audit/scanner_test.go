@@ -0,0 +1,69 @@
+package audit
+
+import (
+	"testing"
+)
+
+func TestParseTrivy(t *testing.T) {
+	data := []byte(`{"Results": [
+  {"Target": "onyxdotapp/onyx-backend:v1 (debian 12.5)", "Type": "debian", "Vulnerabilities": [
+    {"VulnerabilityID": "CVE-2023-45853", "PkgName": "zlib1g", "InstalledVersion": "1:1.2.13.dfsg-1", "Severity": "CRITICAL", "Title": "MiniZip integer overflow", "PrimaryURL": "https://avd.aquasec.com/nvd/cve-2023-45853"}
+  ]},
+  {"Target": "Python", "Type": "python-pkg", "Vulnerabilities": [
+    {"VulnerabilityID": "CVE-2024-1", "PkgName": "requests", "InstalledVersion": "2.31.0", "FixedVersion": "2.32.0", "Severity": "MEDIUM"}
+  ]},
+  {"Target": "app", "Type": "gobinary"}
+]}`)
+	findings, err := parseTrivy(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(findings) != 2 {
+		t.Fatalf("got %d findings, want 2", len(findings))
+	}
+	if f := findings[0]; f.Ecosystem != "Debian" || f.Severity != SeverityCritical || f.Package != "zlib1g" {
+		t.Errorf("unexpected finding %+v", f)
+	}
+	if f := findings[1]; f.Ecosystem != "PyPI" || f.Severity != SeverityModerate || f.FixedIn != "2.32.0" {
+		t.Errorf("unexpected finding %+v", f)
+	}
+}
+
+func TestParseGrype(t *testing.T) {
+	data := []byte(`{"matches": [
+  {"vulnerability": {"id": "GHSA-9wx4-h78v-vm56", "severity": "High", "dataSource": "https://github.com/advisories/GHSA-9wx4-h78v-vm56",
+    "description": "Requests leaks credentials\nmore detail", "fix": {"versions": ["2.32.0"], "state": "fixed"}},
+   "relatedVulnerabilities": [{"id": "CVE-2024-35195"}],
+   "artifact": {"name": "requests", "version": "2.31.0", "type": "python"}}
+]}`)
+	findings, err := parseGrype(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(findings) != 1 {
+		t.Fatalf("got %d findings, want 1", len(findings))
+	}
+	f := findings[0]
+	if f.Ecosystem != "PyPI" || f.Severity != SeverityHigh || f.Title != "Requests leaks credentials" {
+		t.Errorf("unexpected finding %+v", f)
+	}
+	if len(f.Aliases) != 1 || f.Aliases[0] != "CVE-2024-35195" || f.FixedIn != "2.32.0" {
+		t.Errorf("unexpected aliases or fix %+v", f)
+	}
+}
+
+func TestMergeImageFindings(t *testing.T) {
+	findings := []Finding{
+		{ID: "CVE-1", Ecosystem: "Debian", Package: "zlib1g", Version: "1", Images: []string{"backend:v1"}},
+		{ID: "CVE-1", Ecosystem: "Debian", Package: "zlib1g", Version: "1", Images: []string{"model-server:v1"}},
+		{ID: "CVE-1", Ecosystem: "Debian", Package: "zlib1g", Version: "1", Images: []string{"backend:v1"}},
+		{ID: "CVE-1", Ecosystem: "Debian", Package: "zlib1g", Version: "2", Images: []string{"web-server:v1"}},
+	}
+	merged := mergeImageFindings(findings)
+	if len(merged) != 2 {
+		t.Fatalf("got %d findings, want 2", len(merged))
+	}
+	if got := merged[0].Images; len(got) != 2 || got[0] != "backend:v1" || got[1] != "model-server:v1" {
+		t.Errorf("images = %v", got)
+	}
+}
@@ -0,0 +1,135 @@
+// Package auditlog records actions ods takes against shared environments
+// (secret rotation, impersonation, etc.) to an append-only JSON-lines file so
+// there is a local trail of who did what, where, and when.
+package auditlog
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/paths"
+)
+
+// Entry is a single audit record.
+type Entry struct {
+	Time    time.Time `json:"time"`
+	Actor   string    `json:"actor"`
+	Action  string    `json:"action"`
+	Context string    `json:"context,omitempty"`
+	Target  string    `json:"target,omitempty"`
+	Detail  string    `json:"detail,omitempty"`
+	Ticket  string    `json:"ticket,omitempty"`
+}
+
+// ErrTicketRequired is returned by Record when tickets are required and none
+// was given.
+var ErrTicketRequired = errors.New("a --ticket is required for actions on shared environments")
+
+var (
+	ticket        string
+	requireTicket bool
+	recorded      []Entry
+)
+
+// SetTicket sets the ticket attached to every entry recorded by this process.
+// With required, Record refuses to write entries without one.
+func SetTicket(t string, required bool) {
+	ticket, requireTicket = t, required
+}
+
+// Recorded returns the entries this process has recorded, oldest first.
+func Recorded() []Entry {
+	return recorded
+}
+
+// Record appends an entry to the audit log at paths.AuditLogPath(). Time and
+// Actor are filled in when left empty.
+func Record(e Entry) error {
+	return RecordTo(paths.AuditLogPath(), e)
+}
+
+// RecordTo appends an entry to the audit log at path.
+func RecordTo(path string, e Entry) error {
+	if e.Ticket == "" {
+		e.Ticket = ticket
+	}
+	if e.Ticket == "" && requireTicket {
+		return ErrTicketRequired
+	}
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	if e.Actor == "" {
+		e.Actor = Actor()
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create audit log directory: %w", err)
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to marshal audit entry: %w", err)
+	}
+
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open audit log %s: %w", path, err)
+	}
+	defer func() { _ = f.Close() }()
+
+	if _, err := f.Write(append(data, '\n')); err != nil {
+		return fmt.Errorf("failed to write audit log %s: %w", path, err)
+	}
+	recorded = append(recorded, e)
+	return nil
+}
+
+// Read returns every entry in the audit log at path, oldest first. A missing
+// file yields no entries.
+func Read(path string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open audit log %s: %w", path, err)
+	}
+	defer func() { _ = f.Close() }()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			return nil, fmt.Errorf("failed to parse audit log %s: %w", path, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+// Actor identifies the person running ods, preferring the git author email
+// since that is what teammates recognise, then the OS username.
+func Actor() string {
+	if out, err := exec.Command("git", "config", "user.email").Output(); err == nil {
+		if email := strings.TrimSpace(string(out)); email != "" {
+			return email
+		}
+	}
+	if u, err := user.Current(); err == nil && u.Username != "" {
+		return u.Username
+	}
+	return "unknown"
+}
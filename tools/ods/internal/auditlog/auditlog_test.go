@@ -0,0 +1,67 @@
+package auditlog
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestRecordToAndRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "audit.log")
+
+	if err := RecordTo(path, Entry{Actor: "a@example.com", Action: "secrets.rotate", Target: "db"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := RecordTo(path, Entry{Actor: "b@example.com", Action: "secrets.reveal", Target: "oauth"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	entries, err := Read(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+	if entries[0].Action != "secrets.rotate" || entries[1].Target != "oauth" {
+		t.Errorf("entries out of order or wrong: %+v", entries)
+	}
+	if entries[0].Time.IsZero() {
+		t.Error("expected Time to be filled in")
+	}
+}
+
+func TestRead_missingFile(t *testing.T) {
+	entries, err := Read(filepath.Join(t.TempDir(), "missing.log"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 0 {
+		t.Errorf("expected no entries, got %d", len(entries))
+	}
+}
+
+func TestRecordTo_ticket(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "audit.log")
+	t.Cleanup(func() { SetTicket("", false) })
+
+	SetTicket("", true)
+	if err := RecordTo(path, Entry{Action: "scale"}); !errors.Is(err, ErrTicketRequired) {
+		t.Fatalf("expected ErrTicketRequired, got %v", err)
+	}
+
+	SetTicket("OPS-42", true)
+	if err := RecordTo(path, Entry{Action: "scale"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	entries, err := Read(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Ticket != "OPS-42" {
+		t.Errorf("expected one entry with the ticket, got %+v", entries)
+	}
+	if got := Recorded(); len(got) == 0 || got[len(got)-1].Ticket != "OPS-42" {
+		t.Errorf("Recorded() = %+v", got)
+	}
+}
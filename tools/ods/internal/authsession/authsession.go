@@ -0,0 +1,138 @@
+// Package authsession looks up Onyx login sessions, the server side of the
+// fastapiusersauth cookie.
+package authsession
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/kube"
+)
+
+//go:embed session_show.py
+var showScript string
+
+// Session states, as the api-server classifies a presented session.
+const (
+	StateLive       = "live"
+	StateExpired    = "expired"
+	StateTerminated = "terminated"
+	StateNotFound   = "not_found"
+	StateMalformed  = "malformed"
+	// StateStateless is reported by the JWT auth backend, whose sessions
+	// are the tokens themselves.
+	StateStateless = "stateless"
+)
+
+// Session is what the deployment knows about one login session.
+type Session struct {
+	Backend         string `json:"backend"`
+	LifetimeSeconds int    `json:"lifetime_seconds"`
+	GraceSeconds    int    `json:"grace_seconds"`
+	Found           bool   `json:"found"`
+	State           string `json:"state"`
+	// TTLSeconds is the redis key's remaining lifetime, -2 when absent.
+	TTLSeconds  int    `json:"ttl_seconds"`
+	UserID      string `json:"user_id"`
+	TenantID    string `json:"tenant_id"`
+	IssuedAt    string `json:"issued_at"`
+	ExpiresAt   string `json:"expires_at"`
+	LoggedOutAt string `json:"logged_out_at"`
+	User        *User  `json:"user"`
+	UserError   string `json:"user_error"`
+	// Sessions are the user's sessions, newest first, postgres backend only.
+	Sessions []Row `json:"sessions"`
+}
+
+// User is the session's user.
+type User struct {
+	ID         string `json:"id"`
+	Email      string `json:"email"`
+	Role       string `json:"role"`
+	IsActive   bool   `json:"is_active"`
+	IsVerified bool   `json:"is_verified"`
+}
+
+// Row is one of a user's sessions, identified by a token prefix.
+type Row struct {
+	ID        string `json:"id"`
+	Current   bool   `json:"current"`
+	IssuedAt  string `json:"issued_at"`
+	ExpiresAt string `json:"expires_at"`
+}
+
+// Show looks up the session id on pod. schema ("" on single-tenant
+// deployments) is where the user is looked up when the session does not
+// name its tenant.
+func Show(c *kube.Cluster, pod, schema, id string) (*Session, error) {
+	out, err := c.RunPython(pod, showScript, schema, id)
+	if err != nil {
+		return nil, err
+	}
+	return parseSession(out)
+}
+
+func parseSession(stdout string) (*Session, error) {
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	last := strings.TrimSpace(lines[len(lines)-1])
+	var r struct {
+		Status  string `json:"status"`
+		Message string `json:"message"`
+		Session
+	}
+	if err := json.Unmarshal([]byte(last), &r); err != nil {
+		return nil, fmt.Errorf("unexpected output from session script: %q", last)
+	}
+	if r.Status != "success" {
+		return nil, fmt.Errorf("%s", r.Message)
+	}
+	return &r.Session, nil
+}
+
+// Explain says in a sentence why the api-server accepts or refuses the
+// session, as of now.
+func (s *Session) Explain(now time.Time) string {
+	switch s.State {
+	case StateLive:
+		if exp, err := time.Parse(time.RFC3339, s.ExpiresAt); err == nil {
+			return fmt.Sprintf("Live; it expires in %s unless the web app refreshes it first", exp.Sub(now).Truncate(time.Minute))
+		}
+		return "Live"
+	case StateExpired:
+		return "Expired: the user was idle past the session lifetime and was sent back to login"
+	case StateTerminated:
+		return "Signed out: a logout (perhaps in another tab) ended the session"
+	case StateNotFound:
+		if s.Backend == "redis" {
+			return fmt.Sprintf("Unknown: the session ended more than %s ago, was never issued here, or redis lost it (restart or eviction)", time.Duration(s.GraceSeconds)*time.Second)
+		}
+		return "Unknown: the session was deleted or never issued here"
+	case StateMalformed:
+		return "Unreadable: the stored session value does not parse"
+	case StateStateless:
+		return "This deployment uses the JWT auth backend; the cookie is the session, decode it with ods token decode"
+	}
+	return s.State
+}
+
+// Findings lists what about the session or its user would log the user out
+// or keep them out.
+func (s *Session) Findings() []string {
+	var findings []string
+	if s.LifetimeSeconds > 0 && s.LifetimeSeconds < 24*60*60 {
+		findings = append(findings, fmt.Sprintf("SESSION_EXPIRE_TIME_SECONDS is %s; idle users are logged out after that", time.Duration(s.LifetimeSeconds)*time.Second))
+	}
+	if s.UserError != "" {
+		findings = append(findings, "The session's user could not be looked up: "+s.UserError)
+	}
+	if s.UserID != "" && s.User == nil && s.UserError == "" {
+		findings = append(findings, fmt.Sprintf("The session's user %s no longer exists in %s", s.UserID, s.TenantID))
+	}
+	if u := s.User; u != nil && !u.IsActive {
+		findings = append(findings, fmt.Sprintf("%s is deactivated, so every session is refused", u.Email))
+	}
+	return findings
+}
@@ -0,0 +1,38 @@
+package authsession
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseSession(t *testing.T) {
+	out := "Looking up user 3f0c...\n" + `{"status": "success", "backend": "redis", "lifetime_seconds": 3600, "grace_seconds": 3600, "found": true, "state": "live", "ttl_seconds": 5400, "user_id": "3f0c", "tenant_id": "tenant_abc", "issued_at": "2026-06-01T10:00:00+00:00", "expires_at": "2026-06-01T12:30:00+00:00", "user": {"id": "3f0c", "email": "jane@acme.com", "role": "basic", "is_active": false, "is_verified": true}}`
+	s, err := parseSession(out)
+	if err != nil {
+		t.Fatalf("parseSession() error: %v", err)
+	}
+	if s.User == nil || s.User.Email != "jane@acme.com" || s.TTLSeconds != 5400 {
+		t.Fatalf("unexpected session %+v", s)
+	}
+
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	if got := s.Explain(now); got != "Live; it expires in 30m0s unless the web app refreshes it first" {
+		t.Errorf("Explain() = %q", got)
+	}
+	f := strings.Join(s.Findings(), "\n")
+	for _, want := range []string{"SESSION_EXPIRE_TIME_SECONDS is 1h0m0s", "jane@acme.com is deactivated"} {
+		if !strings.Contains(f, want) {
+			t.Errorf("Findings() missing %q:\n%s", want, f)
+		}
+	}
+
+	s = &Session{Backend: "redis", State: StateNotFound, GraceSeconds: 3600}
+	if got := s.Explain(now); !strings.Contains(got, "more than 1h0m0s ago") {
+		t.Errorf("Explain() = %q", got)
+	}
+
+	if _, err := parseSession(`{"status": "error", "message": "This deployment is multi-tenant; pass --tenant"}`); err == nil || !strings.Contains(err.Error(), "pass --tenant") {
+		t.Errorf("expected the script's message as error, got %v", err)
+	}
+}
@@ -0,0 +1,196 @@
+"""Look up an Onyx login session.
+
+Bundled with ods and piped into `python -` on an api-server pod by `ods
+session show`. The session ID is the value of the fastapiusersauth cookie (or
+the mobile bearer token). With the redis auth backend the entry under
+fastapi_users_token:<id> is read and classified the way the api-server does;
+with the postgres backend the accesstoken row is read and the user's other
+sessions listed. The session's user is looked up in the session's tenant.
+
+Usage:
+    python - <schema> <session id>
+
+The last line on stdout is a JSON object with "status", "backend", "state"
+and what is known about the session and its user.
+"""
+
+from __future__ import annotations
+
+import json
+import sys
+from datetime import datetime
+from datetime import timedelta
+from datetime import timezone
+from typing import Any
+
+MAX_SESSIONS = 20
+
+
+def use_schema(schema: str) -> str:
+    from onyx.db.engine.tenant_utils import validate_tenant_id
+    from shared_configs.configs import MULTI_TENANT
+    from shared_configs.configs import POSTGRES_DEFAULT_SCHEMA
+    from shared_configs.contextvars import CURRENT_TENANT_ID_CONTEXTVAR
+
+    if not schema:
+        if MULTI_TENANT:
+            raise ValueError("This deployment is multi-tenant; pass --tenant")
+        schema = POSTGRES_DEFAULT_SCHEMA
+    elif schema != POSTGRES_DEFAULT_SCHEMA and not validate_tenant_id(schema):
+        raise ValueError(f"Invalid schema {schema!r}")
+    CURRENT_TENANT_ID_CONTEXTVAR.set(schema)
+    return schema
+
+
+def iso(dt: datetime | None) -> str:
+    return dt.isoformat() if dt else ""
+
+
+def redis_session(token: str) -> dict[str, Any]:
+    from pydantic import ValidationError
+
+    from onyx.auth.session_tokens import SessionRejection
+    from onyx.auth.session_tokens import SessionTokenValue
+    from onyx.auth.session_tokens import classify_session_token_value
+    from onyx.configs.app_configs import REDIS_AUTH_KEY_PREFIX
+    from onyx.redis.redis_pool import get_raw_redis_client
+
+    r = get_raw_redis_client()
+    key = f"{REDIS_AUTH_KEY_PREFIX}{token}"
+    raw = r.get(key)
+    out: dict[str, Any] = {"found": raw is not None, "ttl_seconds": r.ttl(key)}
+
+    result = classify_session_token_value(raw)
+    value = result.token_value if isinstance(result, SessionRejection) else result
+    out["state"] = result.reason.value if isinstance(result, SessionRejection) else "live"
+    if value is None and raw is not None:
+        try:
+            value = SessionTokenValue.model_validate_json(raw)
+        except ValidationError:
+            value = None
+    if value is not None:
+        out.update(
+            user_id=value.sub or "",
+            tenant_id=value.tenant_id or "",
+            issued_at=iso(value.issued_at),
+            expires_at=iso(value.expires_at),
+            logged_out_at=iso(value.logged_out_at),
+        )
+    return out
+
+
+def postgres_session(token: str, schema: str, lifetime: int) -> dict[str, Any]:
+    from sqlalchemy import select
+
+    from onyx.db.engine.sql_engine import get_session_with_tenant
+    from onyx.db.models import AccessToken
+
+    with get_session_with_tenant(tenant_id=schema) as db_session:
+        row = db_session.get(AccessToken, token)
+        if row is None:
+            return {"found": False, "state": "not_found"}
+        expires_at = row.created_at + timedelta(seconds=lifetime)
+        out: dict[str, Any] = {
+            "found": True,
+            "state": "live" if expires_at > datetime.now(timezone.utc) else "expired",
+            "user_id": str(row.user_id),
+            "tenant_id": schema,
+            "issued_at": iso(row.created_at),
+            "expires_at": iso(expires_at),
+        }
+        others = db_session.scalars(
+            select(AccessToken)
+            .where(AccessToken.user_id == row.user_id)
+            .order_by(AccessToken.created_at.desc())
+            .limit(MAX_SESSIONS)
+        ).all()
+        out["sessions"] = [
+            {
+                # Enough of the token to tell sessions apart, not to use one.
+                "id": o.token[:6],
+                "current": o.token == token,
+                "issued_at": iso(o.created_at),
+                "expires_at": iso(o.created_at + timedelta(seconds=lifetime)),
+            }
+            for o in others
+        ]
+        return out
+
+
+def lookup_user(schema: str, user_id: str) -> dict[str, Any] | None:
+    from uuid import UUID
+
+    from onyx.db.engine.sql_engine import get_session_with_tenant
+    from onyx.db.models import User
+    from shared_configs.contextvars import CURRENT_TENANT_ID_CONTEXTVAR
+
+    CURRENT_TENANT_ID_CONTEXTVAR.set(schema)
+    with get_session_with_tenant(tenant_id=schema) as db_session:
+        user = db_session.get(User, UUID(user_id))
+        if user is None:
+            return None
+        return {
+            "id": str(user.id),
+            "email": user.email,
+            "role": user.role.value,
+            "is_active": user.is_active,
+            "is_verified": user.is_verified,
+        }
+
+
+def show(schema: str, token: str) -> dict[str, Any]:
+    from onyx.auth.schemas import AuthBackend
+    from onyx.auth.session_tokens import SESSION_TOKEN_GRACE_PERIOD_SECONDS
+    from onyx.configs.app_configs import AUTH_BACKEND
+    from onyx.configs.app_configs import SESSION_EXPIRE_TIME_SECONDS
+
+    out: dict[str, Any] = {
+        "status": "success",
+        "backend": AUTH_BACKEND.value,
+        "lifetime_seconds": SESSION_EXPIRE_TIME_SECONDS,
+        "grace_seconds": SESSION_TOKEN_GRACE_PERIOD_SECONDS,
+    }
+    if AUTH_BACKEND == AuthBackend.REDIS:
+        out.update(redis_session(token))
+        # The entry names its tenant; --tenant is only a fallback for
+        # entries written before tenants were recorded.
+        if not out.get("tenant_id"):
+            out["tenant_id"] = use_schema(schema)
+    elif AUTH_BACKEND == AuthBackend.POSTGRES:
+        out.update(postgres_session(token, use_schema(schema), SESSION_EXPIRE_TIME_SECONDS))
+    else:
+        out.update(found=False, state="stateless")
+        return out
+
+    if out.get("user_id"):
+        print(f"Looking up user {out['user_id']}...", file=sys.stderr)
+        try:
+            out["user"] = lookup_user(use_schema(out["tenant_id"]), out["user_id"])
+        except Exception as e:
+            out["user_error"] = str(e)
+    return out
+
+
+def main() -> None:
+    if len(sys.argv) != 3:
+        print(
+            json.dumps(
+                {"status": "error", "message": "Usage: python - <schema> <session id>"}
+            )
+        )
+        sys.exit(1)
+
+    from onyx.db.engine.sql_engine import SqlEngine
+
+    SqlEngine.init_engine(pool_size=5, max_overflow=2)
+
+    try:
+        result = show(sys.argv[1], sys.argv[2])
+    except Exception as e:
+        print(f"Error: {e}", file=sys.stderr)
+        result = {"status": "error", "message": str(e)}
+    print(json.dumps(result))
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,228 @@
+// Package backups lists an environment's RDS, EBS and S3 backups, checks
+// them against a recency policy, and test-restores a database snapshot to
+// prove it can be restored.
+package backups
+
+import (
+	"encoding/json"
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/config"
+)
+
+// Source types, as config.BackupSource.Type.
+const (
+	TypeRDS        = "rds"
+	TypeRDSCluster = "rds-cluster"
+	TypeEBS        = "ebs"
+	TypeS3         = "s3"
+)
+
+// Types lists the supported source types.
+var Types = []string{TypeRDS, TypeRDSCluster, TypeEBS, TypeS3}
+
+// DefaultMaxAge is the recency policy when none is configured.
+const DefaultMaxAge = 26 * time.Hour
+
+// Runner runs the aws CLI with args (with JSON output) and returns stdout.
+type Runner func(args ...string) ([]byte, error)
+
+// Backup is one backup of a source.
+type Backup struct {
+	ID      string    `json:"id"`
+	Created time.Time `json:"created"`
+	// Status is the provider's status, e.g. "available" or "completed";
+	// only Complete backups count.
+	Status   string `json:"status"`
+	Complete bool   `json:"complete"`
+	// SizeBytes is 0 when unknown.
+	SizeBytes int64 `json:"size_bytes,omitempty"`
+}
+
+// List returns the backups of a source, newest first.
+func List(run Runner, src config.BackupSource) ([]Backup, error) {
+	var out []byte
+	var err error
+	switch src.Type {
+	case TypeRDS:
+		out, err = run("rds", "describe-db-snapshots", "--db-instance-identifier", src.ID)
+	case TypeRDSCluster:
+		out, err = run("rds", "describe-db-cluster-snapshots", "--db-cluster-identifier", src.ID)
+	case TypeEBS:
+		key, value, ok := strings.Cut(src.ID, "=")
+		if !ok {
+			return nil, fmt.Errorf("ebs source %s: id must be tag=value, got %q", src.Name, src.ID)
+		}
+		out, err = run("ec2", "describe-snapshots", "--owner-ids", "self", "--filters", fmt.Sprintf("Name=tag:%s,Values=%s", key, value))
+	case TypeS3:
+		bucket, prefix, ok := parseS3URL(src.ID)
+		if !ok {
+			return nil, fmt.Errorf("s3 source %s: id must be s3://bucket/prefix, got %q", src.Name, src.ID)
+		}
+		out, err = run("s3api", "list-objects-v2", "--bucket", bucket, "--prefix", prefix)
+		if err != nil {
+			return nil, err
+		}
+		return parseS3Objects(out, prefix)
+	default:
+		return nil, fmt.Errorf("source %s: unknown type %q (expected one of %s)", src.Name, src.Type, strings.Join(Types, ", "))
+	}
+	if err != nil {
+		return nil, err
+	}
+	return parseSnapshots(src.Type, out)
+}
+
+func parseS3URL(url string) (bucket, prefix string, ok bool) {
+	rest, ok := strings.CutPrefix(url, "s3://")
+	if !ok {
+		return "", "", false
+	}
+	bucket, prefix, _ = strings.Cut(rest, "/")
+	return bucket, prefix, bucket != ""
+}
+
+func parseSnapshots(typ string, data []byte) ([]Backup, error) {
+	var resp struct {
+		DBSnapshots []struct {
+			ID               string    `json:"DBSnapshotIdentifier"`
+			Created          time.Time `json:"SnapshotCreateTime"`
+			Status           string    `json:"Status"`
+			AllocatedStorage int64     `json:"AllocatedStorage"`
+		} `json:"DBSnapshots"`
+		DBClusterSnapshots []struct {
+			ID               string    `json:"DBClusterSnapshotIdentifier"`
+			Created          time.Time `json:"SnapshotCreateTime"`
+			Status           string    `json:"Status"`
+			AllocatedStorage int64     `json:"AllocatedStorage"`
+		} `json:"DBClusterSnapshots"`
+		Snapshots []struct {
+			ID         string    `json:"SnapshotId"`
+			Created    time.Time `json:"StartTime"`
+			State      string    `json:"State"`
+			VolumeSize int64     `json:"VolumeSize"`
+		} `json:"Snapshots"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse aws output: %w", err)
+	}
+	const gib = 1 << 30
+	var out []Backup
+	switch typ {
+	case TypeRDS:
+		for _, s := range resp.DBSnapshots {
+			out = append(out, Backup{ID: s.ID, Created: s.Created, Status: s.Status, Complete: s.Status == "available", SizeBytes: s.AllocatedStorage * gib})
+		}
+	case TypeRDSCluster:
+		for _, s := range resp.DBClusterSnapshots {
+			out = append(out, Backup{ID: s.ID, Created: s.Created, Status: s.Status, Complete: s.Status == "available", SizeBytes: s.AllocatedStorage * gib})
+		}
+	case TypeEBS:
+		for _, s := range resp.Snapshots {
+			out = append(out, Backup{ID: s.ID, Created: s.Created, Status: s.State, Complete: s.State == "completed", SizeBytes: s.VolumeSize * gib})
+		}
+	}
+	sortNewestFirst(out)
+	return out, nil
+}
+
+// parseS3Objects groups objects into backups by the first path segment
+// under prefix (e.g. prefix/2026-10-15/...), dated by their newest object.
+// Objects directly under prefix are backups of their own.
+func parseS3Objects(data []byte, prefix string) ([]Backup, error) {
+	var resp struct {
+		Contents []struct {
+			Key          string    `json:"Key"`
+			LastModified time.Time `json:"LastModified"`
+			Size         int64     `json:"Size"`
+		} `json:"Contents"`
+	}
+	if len(strings.TrimSpace(string(data))) > 0 {
+		if err := json.Unmarshal(data, &resp); err != nil {
+			return nil, fmt.Errorf("failed to parse aws output: %w", err)
+		}
+	}
+	groups := map[string]*Backup{}
+	for _, o := range resp.Contents {
+		rel := strings.TrimPrefix(strings.TrimPrefix(o.Key, prefix), "/")
+		if rel == "" {
+			continue
+		}
+		id, _, _ := strings.Cut(rel, "/")
+		b, ok := groups[id]
+		if !ok {
+			b = &Backup{ID: path.Join(prefix, id), Status: "stored", Complete: true}
+			groups[id] = b
+		}
+		b.SizeBytes += o.Size
+		if o.LastModified.After(b.Created) {
+			b.Created = o.LastModified
+		}
+	}
+	out := make([]Backup, 0, len(groups))
+	for _, b := range groups {
+		out = append(out, *b)
+	}
+	sortNewestFirst(out)
+	return out, nil
+}
+
+func sortNewestFirst(backups []Backup) {
+	sort.SliceStable(backups, func(i, j int) bool { return backups[i].Created.After(backups[j].Created) })
+}
+
+// MaxAge returns the recency policy of a source.
+func MaxAge(cfg config.BackupsConfig, src config.BackupSource) (time.Duration, error) {
+	for _, s := range []string{src.MaxAge, cfg.MaxAge} {
+		if s == "" {
+			continue
+		}
+		d, err := time.ParseDuration(s)
+		if err != nil || d <= 0 {
+			return 0, fmt.Errorf("invalid max_age %q for %s", s, src.Name)
+		}
+		return d, nil
+	}
+	return DefaultMaxAge, nil
+}
+
+// Check is the verdict on one source.
+type Check struct {
+	Source config.BackupSource `json:"source"`
+	MaxAge time.Duration       `json:"max_age"`
+	// Latest is the newest complete backup, if any.
+	Latest *Backup `json:"latest,omitempty"`
+	// Backups are all backups, newest first.
+	Backups []Backup `json:"backups"`
+	OK      bool     `json:"ok"`
+	Detail  string   `json:"detail"`
+}
+
+// Evaluate checks that the newest complete backup is younger than maxAge.
+func Evaluate(src config.BackupSource, backups []Backup, maxAge time.Duration, now time.Time) Check {
+	c := Check{Source: src, MaxAge: maxAge, Backups: backups}
+	for i := range backups {
+		if backups[i].Complete {
+			c.Latest = &backups[i]
+			break
+		}
+	}
+	switch {
+	case len(backups) == 0:
+		c.Detail = "no backups found"
+	case c.Latest == nil:
+		c.Detail = fmt.Sprintf("no complete backups among %d (newest is %s)", len(backups), backups[0].Status)
+	default:
+		age := now.Sub(c.Latest.Created)
+		c.OK = age <= maxAge
+		c.Detail = fmt.Sprintf("newest is %s old", age.Round(time.Minute))
+		if !c.OK {
+			c.Detail += fmt.Sprintf(", older than the %s policy", maxAge)
+		}
+	}
+	return c
+}
@@ -0,0 +1,128 @@
+package backups
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/config"
+)
+
+func TestListRDS(t *testing.T) {
+	var gotArgs []string
+	run := func(args ...string) ([]byte, error) {
+		gotArgs = args
+		return []byte(`{"DBSnapshots": [
+			{"DBSnapshotIdentifier": "rds:onyx-2026-10-14-03-10", "SnapshotCreateTime": "2026-10-14T03:10:00Z", "Status": "available", "AllocatedStorage": 100},
+			{"DBSnapshotIdentifier": "rds:onyx-2026-10-15-03-10", "SnapshotCreateTime": "2026-10-15T03:10:00Z", "Status": "creating", "AllocatedStorage": 100}
+		]}`), nil
+	}
+	src := config.BackupSource{Name: "postgres", Type: TypeRDS, ID: "onyx"}
+	backups, err := List(run, src)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(gotArgs, " ") != "rds describe-db-snapshots --db-instance-identifier onyx" {
+		t.Errorf("args = %q", gotArgs)
+	}
+	if len(backups) != 2 || backups[0].Status != "creating" || backups[1].SizeBytes != 100<<30 {
+		t.Fatalf("backups = %+v", backups)
+	}
+
+	now := time.Date(2026, 10, 15, 4, 0, 0, 0, time.UTC)
+	c := Evaluate(src, backups, 26*time.Hour, now)
+	if !c.OK || c.Latest.ID != "rds:onyx-2026-10-14-03-10" || c.Detail != "newest is 24h50m0s old" {
+		t.Errorf("Evaluate() = %+v", c)
+	}
+	if c := Evaluate(src, backups, 12*time.Hour, now); c.OK || !strings.Contains(c.Detail, "older than the 12h0m0s policy") {
+		t.Errorf("Evaluate(12h) = %+v", c)
+	}
+	if c := Evaluate(src, nil, time.Hour, now); c.OK || c.Detail != "no backups found" {
+		t.Errorf("Evaluate(none) = %+v", c)
+	}
+}
+
+func TestListS3GroupsByFolder(t *testing.T) {
+	run := func(args ...string) ([]byte, error) {
+		if strings.Join(args, " ") != "s3api list-objects-v2 --bucket backups --prefix vespa/" {
+			t.Errorf("args = %q", args)
+		}
+		return []byte(`{"Contents": [
+			{"Key": "vespa/2026-10-14/a.tar", "LastModified": "2026-10-14T02:00:00Z", "Size": 10},
+			{"Key": "vespa/2026-10-15/a.tar", "LastModified": "2026-10-15T02:00:00Z", "Size": 10},
+			{"Key": "vespa/2026-10-15/b.tar", "LastModified": "2026-10-15T02:30:00Z", "Size": 5},
+			{"Key": "vespa/manifest.json", "LastModified": "2026-10-13T00:00:00Z", "Size": 1}
+		]}`), nil
+	}
+	backups, err := List(run, config.BackupSource{Name: "vespa", Type: TypeS3, ID: "s3://backups/vespa/"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(backups) != 3 || backups[0].ID != "vespa/2026-10-15" || backups[0].SizeBytes != 15 ||
+		!backups[0].Created.Equal(time.Date(2026, 10, 15, 2, 30, 0, 0, time.UTC)) {
+		t.Errorf("backups = %+v", backups)
+	}
+
+	if _, err := List(run, config.BackupSource{Name: "x", Type: TypeEBS, ID: "no-equals"}); err == nil {
+		t.Error("List accepted an ebs id without tag=value")
+	}
+}
+
+func TestMaxAge(t *testing.T) {
+	cfg := config.BackupsConfig{MaxAge: "48h"}
+	if d, _ := MaxAge(cfg, config.BackupSource{}); d != 48*time.Hour {
+		t.Errorf("MaxAge(default) = %s", d)
+	}
+	if d, _ := MaxAge(cfg, config.BackupSource{MaxAge: "2h"}); d != 2*time.Hour {
+		t.Errorf("MaxAge(override) = %s", d)
+	}
+	if d, _ := MaxAge(config.BackupsConfig{}, config.BackupSource{}); d != DefaultMaxAge {
+		t.Errorf("MaxAge(unset) = %s", d)
+	}
+	if _, err := MaxAge(cfg, config.BackupSource{Name: "x", MaxAge: "daily"}); err == nil {
+		t.Error("MaxAge accepted an invalid duration")
+	}
+}
+
+func TestScratchID(t *testing.T) {
+	now := time.Date(2026, 10, 15, 4, 5, 0, 0, time.UTC)
+	if got := ScratchID("verify", "onyx-prod", now); got != "ods-verify-onyx-prod-2610150405" {
+		t.Errorf("ScratchID() = %q", got)
+	}
+	if got := ScratchID("verify", strings.Repeat("very-long_Name", 6), now); len(got) > 63 || strings.Contains(got, "--") {
+		t.Errorf("ScratchID(long) = %q", got)
+	}
+}
+
+func TestRestoreReport(t *testing.T) {
+	r, err := ParseRestoreReport("Counting...\n" + `{"status": "success", "schema": "public", "revision": "abc", "live_revision": "abd", "tables": [{"name": "user", "restored": 3, "live": 4}], "missing_tables": ["new_table"]}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if p := r.Problems(); len(p) != 1 || p[0] != "tables missing from the restore: new_table" {
+		t.Errorf("Problems() = %q", p)
+	}
+	if _, err := ParseRestoreReport(`{"status": "error", "message": "connection refused"}`); err == nil || err.Error() != "connection refused" {
+		t.Errorf("ParseRestoreReport(error) = %v", err)
+	}
+}
+
+func TestPointInTimeArgs(t *testing.T) {
+	inst, err := parseInstance([]byte(`{"DBInstances": [{"DBInstanceIdentifier": "onyx", "DBInstanceClass": "db.r6g.large",
+		"DBSubnetGroup": {"DBSubnetGroupName": "private"}, "VpcSecurityGroups": [{"VpcSecurityGroupId": "sg-1"}],
+		"IAMDatabaseAuthenticationEnabled": true, "LatestRestorableTime": "2026-10-15T03:55:00Z"}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !inst.LatestRestorable.Equal(time.Date(2026, 10, 15, 3, 55, 0, 0, time.UTC)) {
+		t.Errorf("LatestRestorable = %s", inst.LatestRestorable)
+	}
+	at := time.Date(2026, 10, 14, 12, 0, 0, 0, time.UTC)
+	got := strings.Join(PointInTimeArgs(inst, "ods-pitr-onyx-2610150405", at, ""), " ")
+	want := "rds restore-db-instance-to-point-in-time --source-db-instance-identifier onyx --target-db-instance-identifier ods-pitr-onyx-2610150405" +
+		" --restore-time 2026-10-14T12:00:00Z --db-instance-class db.r6g.large --db-subnet-group-name private --no-publicly-accessible" +
+		" --no-multi-az --tags Key=created-by,Value=ods-restore --enable-iam-database-authentication --vpc-security-group-ids sg-1"
+	if got != want {
+		t.Errorf("PointInTimeArgs() =\n%s\nwant\n%s", got, want)
+	}
+}
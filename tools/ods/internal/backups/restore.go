@@ -0,0 +1,214 @@
+package backups
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+)
+
+//go:embed verify_restore.py
+var VerifyRestoreScript string
+
+// Instance is the placement of an RDS instance a scratch copy reuses, so
+// pods that reach the source can reach the copy.
+type Instance struct {
+	ID             string
+	Class          string
+	SubnetGroup    string
+	SecurityGroups []string
+	Endpoint       string
+	Port           int
+	Status         string
+	IAMAuth        bool
+	// LatestRestorable is the newest point-in-time restore target; zero when
+	// automated backups are off.
+	LatestRestorable time.Time
+}
+
+// DescribeInstance looks up an RDS instance.
+func DescribeInstance(run Runner, id string) (*Instance, error) {
+	out, err := run("rds", "describe-db-instances", "--db-instance-identifier", id)
+	if err != nil {
+		return nil, err
+	}
+	return parseInstance(out)
+}
+
+func parseInstance(data []byte) (*Instance, error) {
+	var resp struct {
+		DBInstances []struct {
+			ID                string `json:"DBInstanceIdentifier"`
+			Class             string `json:"DBInstanceClass"`
+			Status            string `json:"DBInstanceStatus"`
+			DBSubnetGroup     struct{ DBSubnetGroupName string }
+			IAMAuth           bool      `json:"IAMDatabaseAuthenticationEnabled"`
+			LatestRestorable  time.Time `json:"LatestRestorableTime"`
+			VpcSecurityGroups []struct {
+				VpcSecurityGroupID string `json:"VpcSecurityGroupId"`
+			}
+			Endpoint *struct {
+				Address string
+				Port    int
+			}
+		} `json:"DBInstances"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse aws output: %w", err)
+	}
+	if len(resp.DBInstances) == 0 {
+		return nil, fmt.Errorf("no such DB instance")
+	}
+	d := resp.DBInstances[0]
+	inst := &Instance{
+		ID:               d.ID,
+		Class:            d.Class,
+		SubnetGroup:      d.DBSubnetGroup.DBSubnetGroupName,
+		Status:           d.Status,
+		IAMAuth:          d.IAMAuth,
+		LatestRestorable: d.LatestRestorable,
+	}
+	for _, sg := range d.VpcSecurityGroups {
+		inst.SecurityGroups = append(inst.SecurityGroups, sg.VpcSecurityGroupID)
+	}
+	if d.Endpoint != nil {
+		inst.Endpoint, inst.Port = d.Endpoint.Address, d.Endpoint.Port
+	}
+	return inst, nil
+}
+
+// nonAlnum runs are replaced by a hyphen in scratch instance names.
+var nonAlnum = regexp.MustCompile(`[^a-z0-9]+`)
+
+// ScratchID names a scratch instance restored from source for purpose
+// ("verify", "pitr"): ods-<purpose>-<source>-<time>, within RDS's 63
+// characters.
+func ScratchID(purpose, source string, now time.Time) string {
+	prefix := "ods-" + purpose + "-"
+	suffix := now.UTC().Format("0601021504")
+	base := strings.Trim(nonAlnum.ReplaceAllString(strings.ToLower(source), "-"), "-")
+	if max := 63 - len(prefix) - 1 - len(suffix); len(base) > max {
+		base = strings.TrimRight(base[:max], "-")
+	}
+	return prefix + base + "-" + suffix
+}
+
+// RestoreArgs are the aws arguments that restore snapshot to a new scratch
+// instance placed like source, private and without backups of its own.
+func RestoreArgs(snapshot, scratch string, source *Instance, class string) []string {
+	args := []string{
+		"rds", "restore-db-instance-from-db-snapshot",
+		"--db-instance-identifier", scratch,
+		"--db-snapshot-identifier", snapshot,
+	}
+	return append(args, scratchPlacement(source, class, "ods-verify-backups")...)
+}
+
+// PointInTimeArgs are the aws arguments that restore source as it was at a
+// point in time to a new scratch instance placed like it.
+func PointInTimeArgs(source *Instance, scratch string, at time.Time, class string) []string {
+	args := []string{
+		"rds", "restore-db-instance-to-point-in-time",
+		"--source-db-instance-identifier", source.ID,
+		"--target-db-instance-identifier", scratch,
+		"--restore-time", at.UTC().Format(time.RFC3339),
+	}
+	return append(args, scratchPlacement(source, class, "ods-restore")...)
+}
+
+// scratchPlacement places a scratch instance in source's subnets and
+// security groups, private and single-AZ, tagged with the command that
+// created it.
+func scratchPlacement(source *Instance, class, createdBy string) []string {
+	if class == "" {
+		class = source.Class
+	}
+	args := []string{
+		"--db-instance-class", class,
+		"--db-subnet-group-name", source.SubnetGroup,
+		"--no-publicly-accessible",
+		"--no-multi-az",
+		"--tags", "Key=created-by,Value=" + createdBy,
+	}
+	if source.IAMAuth {
+		// Pods that log in with IAM tokens can then reach the copy too.
+		args = append(args, "--enable-iam-database-authentication")
+	}
+	if len(source.SecurityGroups) > 0 {
+		args = append(args, "--vpc-security-group-ids")
+		args = append(args, source.SecurityGroups...)
+	}
+	return args
+}
+
+// WaitArgs wait until a scratch instance is available.
+func WaitArgs(scratch string) []string {
+	return []string{"rds", "wait", "db-instance-available", "--db-instance-identifier", scratch}
+}
+
+// DeleteArgs delete a scratch instance without a final snapshot.
+func DeleteArgs(scratch string) []string {
+	return []string{"rds", "delete-db-instance", "--db-instance-identifier", scratch,
+		"--skip-final-snapshot", "--delete-automated-backups"}
+}
+
+// RestoreReport is what verify_restore.py found in the restored schema,
+// against the live one.
+type RestoreReport struct {
+	Schema        string       `json:"schema"`
+	Revision      string       `json:"revision"`
+	LiveRevision  string       `json:"live_revision"`
+	Tables        []TableCount `json:"tables"`
+	MissingTables []string     `json:"missing_tables"`
+}
+
+// TableCount is a table's row count in the restored and live schema.
+type TableCount struct {
+	Name     string `json:"name"`
+	Restored int64  `json:"restored"`
+	Live     int64  `json:"live"`
+}
+
+// ParseRestoreReport parses the last line of verify_restore.py's output.
+func ParseRestoreReport(stdout string) (*RestoreReport, error) {
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	last := strings.TrimSpace(lines[len(lines)-1])
+	var r struct {
+		Status  string `json:"status"`
+		Message string `json:"message"`
+		RestoreReport
+	}
+	if err := json.Unmarshal([]byte(last), &r); err != nil {
+		return nil, fmt.Errorf("unexpected output from restore check script: %q", last)
+	}
+	if r.Status != "success" {
+		return nil, fmt.Errorf("%s", r.Message)
+	}
+	return &r.RestoreReport, nil
+}
+
+// Problems lists why the restored schema cannot be trusted; none means the
+// restore is proven.
+func (r *RestoreReport) Problems() []string {
+	var problems []string
+	if len(r.Tables) == 0 {
+		problems = append(problems, fmt.Sprintf("schema %s has no tables in the restore", r.Schema))
+	}
+	if r.Revision == "" {
+		problems = append(problems, "the restore has no alembic revision")
+	}
+	if len(r.MissingTables) > 0 {
+		problems = append(problems, fmt.Sprintf("tables missing from the restore: %s", strings.Join(r.MissingTables, ", ")))
+	}
+	var rows, liveRows int64
+	for _, t := range r.Tables {
+		rows += t.Restored
+		liveRows += t.Live
+	}
+	if rows == 0 && liveRows > 0 {
+		problems = append(problems, "the restore has no rows")
+	}
+	return problems
+}
@@ -0,0 +1,120 @@
+"""Compare a schema restored from a backup with the live one.
+
+Bundled with ods and piped into `python -` on an api-server pod by
+`ods verify-backups --test-restore`, so it connects to both databases with
+the backend's own credentials: the live database through the backend's
+engine, and the scratch instance restored from a snapshot (which keeps the
+source's users and passwords) at the given host.
+
+Usage:
+    python - <restored-host> <port> <schema>
+
+Progress goes to stderr; the last line on stdout is a JSON object with
+"status", "schema", "revision", "live_revision", "tables" (name, restored and
+live row counts) and "missing_tables" (tables of the live schema the restore
+lacks).
+"""
+
+from __future__ import annotations
+
+import json
+import os
+import sys
+from typing import Any
+
+
+def table_counts(conn: Any, schema: str) -> dict[str, int]:
+    from sqlalchemy import text
+
+    tables = conn.execute(
+        text(
+            "SELECT table_name FROM information_schema.tables "
+            "WHERE table_schema = :schema AND table_type = 'BASE TABLE' "
+            "ORDER BY table_name"
+        ),
+        {"schema": schema},
+    ).scalars()
+    counts = {}
+    for table in list(tables):
+        counts[table] = conn.execute(
+            text(f'SELECT count(*) FROM "{schema}"."{table}"')  # noqa: S608
+        ).scalar()
+    return counts
+
+
+def revision(conn: Any, schema: str) -> str:
+    from sqlalchemy import text
+
+    try:
+        return (
+            conn.execute(
+                text(f'SELECT version_num FROM "{schema}".alembic_version')  # noqa: S608
+            ).scalar()
+            or ""
+        )
+    except Exception:
+        return ""
+
+
+def check(host: str, port: int, schema: str) -> dict[str, Any]:
+    from sqlalchemy import create_engine
+    from sqlalchemy.engine import URL
+
+    from onyx.configs.app_configs import POSTGRES_DB
+    from onyx.configs.app_configs import POSTGRES_USER
+    from onyx.db.engine.sql_engine import SqlEngine
+
+    # app_configs URL-quotes the password for its own connection string;
+    # URL.create wants it raw.
+    restored_engine = create_engine(
+        URL.create(
+            "postgresql+psycopg2",
+            username=POSTGRES_USER,
+            password=os.environ.get("POSTGRES_PASSWORD") or "password",
+            host=host,
+            port=port,
+            database=POSTGRES_DB,
+        ),
+        connect_args={"connect_timeout": 10},
+    )
+    print(f"Counting rows of {schema} in the restore...", file=sys.stderr)
+    with restored_engine.connect() as conn:
+        restored = table_counts(conn, schema)
+        restored_revision = revision(conn, schema)
+    print(f"Counting rows of {schema} in the live database...", file=sys.stderr)
+    with SqlEngine.get_engine().connect() as conn:
+        live = table_counts(conn, schema)
+        live_revision = revision(conn, schema)
+
+    return {
+        "status": "success",
+        "schema": schema,
+        "revision": restored_revision,
+        "live_revision": live_revision,
+        "tables": [
+            {"name": name, "restored": count, "live": live.get(name, 0)}
+            for name, count in sorted(restored.items())
+        ],
+        "missing_tables": sorted(set(live) - set(restored)),
+    }
+
+
+def main() -> None:
+    if len(sys.argv) != 4:
+        result: dict[str, Any] = {
+            "status": "error",
+            "message": "usage: python - <restored-host> <port> <schema>",
+        }
+    else:
+        try:
+            from onyx.db.engine.sql_engine import SqlEngine
+
+            SqlEngine.init_engine(pool_size=2, max_overflow=0)
+            result = check(sys.argv[1], int(sys.argv[2]), sys.argv[3])
+        except Exception as e:
+            result = {"status": "error", "message": f"{type(e).__name__}: {e}"}
+    print(json.dumps(result))
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,284 @@
+// Package bot answers ods chat commands such as "/ods whois chris" or
+// "/ods health prod" with the same lookups ods serve exposes. Every command
+// is read-only, restricted to allowed channels and users, and recorded in
+// the audit log before it runs.
+package bot
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/auditlog"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/health"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/report"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/serve"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/slack"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/whois"
+)
+
+// Limits that keep replies readable in a channel.
+const (
+	maxRows      = 25
+	maxLogLines  = 20
+	maxReplyLen  = 3500
+	logsLookback = time.Hour
+)
+
+// Bot answers slash commands against one backend per cluster context.
+type Bot struct {
+	// Backends maps context names to the backends answering for them.
+	Backends map[string]serve.Backend
+	// DefaultContext is used when a command names no context.
+	DefaultContext string
+	// Channels and Users restrict who may use the bot, by ID or name. Empty
+	// allows everyone.
+	Channels []string
+	Users    []string
+	// Audit records each command before it runs; a command whose record
+	// fails is refused.
+	Audit func(auditlog.Entry) error
+}
+
+type command struct {
+	usage   string
+	minArgs int
+	run     func(b serve.Backend, args []string) slack.Reply
+}
+
+var commands = map[string]command{
+	"whois":  {usage: "whois <email-fragment|tenant-id> [context]", minArgs: 1, run: runWhois},
+	"health": {usage: "health [context]", minArgs: 0, run: runHealth},
+	"stats":  {usage: "stats <report> [since] [context]", minArgs: 1, run: runStats},
+	"logs":   {usage: "logs <pod> <text> [context]", minArgs: 2, run: runLogs},
+}
+
+// Handle answers cmd. It matches slack.Handler.
+func (b *Bot) Handle(_ context.Context, cmd slack.SlashCommand) slack.Reply {
+	fields := strings.Fields(cmd.Text)
+	if len(fields) == 0 || fields[0] == "help" {
+		return slack.Reply{Text: b.help(cmd.Command), Ephemeral: true}
+	}
+	verb, args := fields[0], fields[1:]
+
+	if !allowed(b.Channels, cmd.ChannelID, cmd.ChannelName) {
+		_ = b.record(cmd, "bot.denied", b.DefaultContext, "channel not allowed")
+		return slack.Reply{Text: "Sorry, ods lookups are not enabled in this channel.", Ephemeral: true}
+	}
+	if !allowed(b.Users, cmd.UserID, cmd.UserName) {
+		_ = b.record(cmd, "bot.denied", b.DefaultContext, "user not allowed")
+		return slack.Reply{Text: "Sorry, you are not allowed to run ods lookups.", Ephemeral: true}
+	}
+
+	c, ok := commands[verb]
+	if !ok {
+		return slack.Reply{Text: fmt.Sprintf("Unknown command `%s`.\n%s", verb, b.help(cmd.Command)), Ephemeral: true}
+	}
+	contextName := b.DefaultContext
+	if len(args) > c.minArgs {
+		if _, ok := b.Backends[args[len(args)-1]]; ok {
+			contextName, args = args[len(args)-1], args[:len(args)-1]
+		}
+	}
+	if len(args) < c.minArgs {
+		return slack.Reply{Text: fmt.Sprintf("Usage: `%s %s`", cmd.Command, c.usage), Ephemeral: true}
+	}
+	backend, ok := b.Backends[contextName]
+	if !ok {
+		return slack.Reply{Text: fmt.Sprintf("Context `%s` is not served by this bot.", contextName), Ephemeral: true}
+	}
+
+	if err := b.record(cmd, "bot."+verb, contextName, ""); err != nil {
+		return slack.Reply{Text: fmt.Sprintf(":warning: Refusing to run an unaudited lookup: %v", err), Ephemeral: true}
+	}
+	reply := c.run(backend, args)
+	reply.Text = truncate(fmt.Sprintf("*%s* · `%s`\n%s", verb, contextName, reply.Text))
+	return reply
+}
+
+func (b *Bot) help(slash string) string {
+	if slash == "" {
+		slash = "/ods"
+	}
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	var s strings.Builder
+	s.WriteString("Read-only ods lookups:\n")
+	for _, name := range names {
+		fmt.Fprintf(&s, "• `%s %s`\n", slash, commands[name].usage)
+	}
+	contexts := make([]string, 0, len(b.Backends))
+	for name := range b.Backends {
+		contexts = append(contexts, name)
+	}
+	slices.Sort(contexts)
+	fmt.Fprintf(&s, "Contexts: %s (default `%s`). Reports: %s.", strings.Join(contexts, ", "), b.DefaultContext, strings.Join(report.KindNames(), ", "))
+	return s.String()
+}
+
+func (b *Bot) record(cmd slack.SlashCommand, action, contextName, detail string) error {
+	if b.Audit == nil {
+		return fmt.Errorf("no audit log configured")
+	}
+	channel := cmd.ChannelName
+	if channel == "" {
+		channel = cmd.ChannelID
+	}
+	if detail != "" {
+		detail = "; " + detail
+	}
+	return b.Audit(auditlog.Entry{
+		Actor:   "slack:" + cmd.UserName,
+		Action:  action,
+		Context: contextName,
+		Target:  cmd.Text,
+		Detail:  fmt.Sprintf("user %s in #%s%s", cmd.UserID, channel, detail),
+	})
+}
+
+func allowed(list []string, id, name string) bool {
+	if len(list) == 0 {
+		return true
+	}
+	for _, a := range list {
+		a = strings.TrimPrefix(a, "#")
+		if a == id || (name != "" && a == name) {
+			return true
+		}
+	}
+	return false
+}
+
+func failed(err error) slack.Reply {
+	return slack.Reply{Text: fmt.Sprintf(":warning: %v", err)}
+}
+
+func runWhois(b serve.Backend, args []string) slack.Reply {
+	query := strings.Join(args, " ")
+	if whois.KindOf(query) == whois.KindTenant {
+		if err := whois.ValidateTenantID(query); err != nil {
+			return slack.Reply{Text: err.Error(), Ephemeral: true}
+		}
+	}
+	r, err := b.Whois(query)
+	if err != nil {
+		return failed(err)
+	}
+	if r.Kind == whois.KindTenant {
+		if len(r.Rows) == 0 {
+			return slack.Reply{Text: "No admin users found for this tenant."}
+		}
+		return slack.Reply{Text: codeBlock(limitRows(r.Rows, maxRows))}
+	}
+	matches := r.Matches()
+	if len(matches) == 0 {
+		return slack.Reply{Text: "No results found."}
+	}
+	rows := make([]string, len(matches))
+	for i, m := range matches {
+		rows[i] = fmt.Sprintf("%s\t%s\t%t", m.Email, m.TenantID, m.Active)
+	}
+	return slack.Reply{Text: codeBlock(table("EMAIL\tTENANT ID\tACTIVE", limitRows(rows, maxRows)))}
+}
+
+func runHealth(b serve.Backend, _ []string) slack.Reply {
+	results, err := b.Health(serve.DefaultHealthTimeout)
+	if err != nil {
+		return failed(err)
+	}
+	var s strings.Builder
+	if n := health.Failed(results); n > 0 {
+		fmt.Fprintf(&s, ":red_circle: %d of %d checks failing\n", n, len(results))
+	} else {
+		fmt.Fprintf(&s, ":large_green_circle: all %d checks passing\n", len(results))
+	}
+	for _, r := range results {
+		switch {
+		case r.Skipped:
+			fmt.Fprintf(&s, "• %s: skipped", r.Name)
+		case r.OK:
+			fmt.Fprintf(&s, "• %s: ok (%.0fms)", r.Name, r.Latency)
+		default:
+			fmt.Fprintf(&s, "• *%s: FAILED*", r.Name)
+		}
+		if r.Detail != "" && !r.OK {
+			fmt.Fprintf(&s, " – %s", r.Detail)
+		}
+		s.WriteByte('\n')
+	}
+	return slack.Reply{Text: strings.TrimSuffix(s.String(), "\n")}
+}
+
+func runStats(b serve.Backend, args []string) slack.Reply {
+	kind, err := report.LookupKind(args[0])
+	if err != nil {
+		return slack.Reply{Text: err.Error(), Ephemeral: true}
+	}
+	d := kind.DefaultPeriod
+	if len(args) > 1 {
+		if d, err = report.ParseLookback(args[1]); err != nil || d > serve.MaxStatsPeriod {
+			return slack.Reply{Text: fmt.Sprintf("Invalid period %q: expected e.g. 7d, at most %s", args[1], serve.MaxStatsPeriod), Ephemeral: true}
+		}
+	}
+	r, err := b.Stats(kind, report.Last(d, time.Now()))
+	if err != nil {
+		return failed(err)
+	}
+	return slack.Reply{Text: report.SlackSummary(r)}
+}
+
+func runLogs(b serve.Backend, args []string) slack.Reply {
+	matches, err := b.SearchLogs([]string{args[0]}, strings.Join(args[1:], " "), logsLookback, maxLogLines)
+	if err != nil {
+		return failed(err)
+	}
+	if len(matches) == 0 {
+		return slack.Reply{Text: fmt.Sprintf("No matching lines in the last %s.", logsLookback)}
+	}
+	lines := make([]string, len(matches))
+	for i, m := range matches {
+		lines[i] = fmt.Sprintf("[%s] %s", m.Pod, m.Line)
+	}
+	return slack.Reply{Text: codeBlock(limitRows(lines, maxLogLines))}
+}
+
+func table(header string, rows []string) []string {
+	var b strings.Builder
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	_, _ = fmt.Fprintln(w, header)
+	for _, row := range rows {
+		_, _ = fmt.Fprintln(w, row)
+	}
+	_ = w.Flush()
+	return strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+}
+
+func limitRows(rows []string, limit int) []string {
+	if len(rows) <= limit {
+		return rows
+	}
+	return append(rows[:limit:limit], fmt.Sprintf("… and %d more", len(rows)-limit))
+}
+
+func codeBlock(lines []string) string {
+	return "```\n" + strings.ReplaceAll(strings.Join(lines, "\n"), "```", "'''") + "\n```"
+}
+
+// truncate keeps a reply under Slack's message size, closing an open code
+// block.
+func truncate(s string) string {
+	if len(s) <= maxReplyLen {
+		return s
+	}
+	s = strings.ToValidUTF8(s[:maxReplyLen], "") + "\n… (truncated)"
+	if strings.Count(s, "```")%2 == 1 {
+		s += "\n```"
+	}
+	return s
+}
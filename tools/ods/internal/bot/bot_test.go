@@ -0,0 +1,135 @@
+package bot
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/auditlog"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/health"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/kube"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/report"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/serve"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/slack"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/whois"
+)
+
+type fakeBackend struct {
+	name  string
+	query string
+}
+
+func (b *fakeBackend) Whois(query string) (*whois.Result, error) {
+	b.query = query
+	return &whois.Result{Kind: whois.KindOf(query), Rows: []string{"chris@acme.com\ttenant_a\tt"}}, nil
+}
+
+func (b *fakeBackend) Health(time.Duration) ([]health.Result, error) {
+	return []health.Result{{Name: "postgres", OK: true, Latency: 4}, {Name: "redis", Detail: "connection refused"}}, nil
+}
+
+func (b *fakeBackend) Stats(kind report.Kind, p report.Period) (*report.Report, error) {
+	return kind.Build(report.Data{Context: b.name, Period: p, Generated: p.To}), nil
+}
+
+func (b *fakeBackend) SearchLogs(pods []string, text string, since time.Duration, limit int) ([]kube.LogMatch, error) {
+	return nil, errors.New("no pods match " + pods[0])
+}
+
+func newTestBot() (*Bot, map[string]*fakeBackend, *[]auditlog.Entry) {
+	fakes := map[string]*fakeBackend{"data_plane": {name: "data_plane"}, "prod": {name: "prod"}}
+	var entries []auditlog.Entry
+	b := &Bot{
+		Backends:       map[string]serve.Backend{"data_plane": fakes["data_plane"], "prod": fakes["prod"]},
+		DefaultContext: "data_plane",
+		Channels:       []string{"#support"},
+		Audit: func(e auditlog.Entry) error {
+			entries = append(entries, e)
+			return nil
+		},
+	}
+	return b, fakes, &entries
+}
+
+func run(b *Bot, text string) slack.Reply {
+	return b.Handle(context.Background(), slack.SlashCommand{
+		Command: "/ods", Text: text, UserID: "U1", UserName: "sam", ChannelID: "C1", ChannelName: "support",
+	})
+}
+
+func TestHandle(t *testing.T) {
+	b, fakes, entries := newTestBot()
+
+	r := run(b, "whois chris")
+	if r.Ephemeral || !strings.Contains(r.Text, "`data_plane`") || !strings.Contains(r.Text, "chris@acme.com  tenant_a   true") {
+		t.Errorf("whois reply = %+v", r)
+	}
+	if fakes["data_plane"].query != "chris" {
+		t.Errorf("whois query = %q", fakes["data_plane"].query)
+	}
+
+	r = run(b, "health prod")
+	if !strings.Contains(r.Text, "`prod`") || !strings.Contains(r.Text, "1 of 2 checks failing") || !strings.Contains(r.Text, "*redis: FAILED* – connection refused") {
+		t.Errorf("health reply = %+v", r)
+	}
+
+	// A lone argument that names a context is the query, not the context.
+	run(b, "whois prod")
+	if fakes["data_plane"].query != "prod" {
+		t.Errorf("whois prod went to %+v", fakes)
+	}
+
+	if r = run(b, "stats weekly-ops 30d prod"); !strings.Contains(r.Text, "*Weekly operations* (prod") {
+		t.Errorf("stats reply = %+v", r)
+	}
+	if r = run(b, "logs api-server timeout"); !strings.Contains(r.Text, ":warning: no pods match api-server") {
+		t.Errorf("logs reply = %+v", r)
+	}
+
+	if len(*entries) != 5 || (*entries)[1] != (auditlog.Entry{Actor: "slack:sam", Action: "bot.health", Context: "prod", Target: "health prod", Detail: "user U1 in #support"}) {
+		t.Errorf("audit entries = %+v", *entries)
+	}
+	if r = run(b, "stats daily"); !r.Ephemeral || !strings.Contains(r.Text, "daily") {
+		t.Errorf("unknown report reply = %+v", r)
+	}
+}
+
+func TestHandleGuardrails(t *testing.T) {
+	b, _, entries := newTestBot()
+
+	for _, text := range []string{"", "help", "rotate-secrets", "whois"} {
+		if r := run(b, text); !r.Ephemeral {
+			t.Errorf("%q: reply %+v should be ephemeral", text, r)
+		}
+	}
+	if len(*entries) != 0 {
+		t.Errorf("unexpected audit entries %+v", *entries)
+	}
+
+	b.Users = []string{"U2"}
+	if r := run(b, "health"); !strings.Contains(r.Text, "not allowed") {
+		t.Errorf("disallowed user got %+v", r)
+	}
+	b.Users, b.Channels = nil, []string{"C9"}
+	if r := run(b, "health"); !strings.Contains(r.Text, "not enabled in this channel") {
+		t.Errorf("disallowed channel got %+v", r)
+	}
+	if len(*entries) != 2 || (*entries)[0].Action != "bot.denied" {
+		t.Errorf("denials not audited: %+v", *entries)
+	}
+
+	b.Channels = nil
+	b.Audit = func(auditlog.Entry) error { return errors.New("disk full") }
+	if r := run(b, "health"); !strings.Contains(r.Text, "Refusing to run an unaudited lookup: disk full") {
+		t.Errorf("unaudited lookup got %+v", r)
+	}
+}
+
+func TestTruncate(t *testing.T) {
+	s := truncate("```\n" + strings.Repeat("x", maxReplyLen))
+	if len(s) > maxReplyLen+40 || !strings.HasSuffix(s, "(truncated)\n```") {
+		t.Errorf("truncate() = …%q", s[len(s)-30:])
+	}
+}
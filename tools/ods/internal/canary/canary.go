@@ -0,0 +1,293 @@
+// Package canary runs a synthetic end-to-end flow against an Onyx API
+// server — log in, ingest a document, find it through search, chat about it
+// and delete it — and reports the outcome of each stage.
+package canary
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/api"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/chatstream"
+)
+
+// Stages of a run, in order.
+const (
+	StageLogin  = "login"
+	StageIngest = "ingest"
+	StageSearch = "search"
+	StageChat   = "chat"
+	StageDelete = "delete"
+)
+
+// Stages lists every stage in run order.
+var Stages = []string{StageLogin, StageIngest, StageSearch, StageChat, StageDelete}
+
+// searchRetryWait is the pause between searches for the new document.
+const searchRetryWait = 2 * time.Second
+
+// Options configures a run.
+type Options struct {
+	// CCPairID attaches the document to a connector-credential pair; 0
+	// uses the ingestion API's default.
+	CCPairID int
+	// Persona is the assistant the chat stage talks to.
+	Persona int
+	// SearchTimeout bounds how long the search stage waits for the
+	// document to become searchable.
+	SearchTimeout time.Duration
+	// ExpectAnswer fails the chat stage unless the answer repeats the
+	// document's code word, which makes it depend on the LLM's quality.
+	ExpectAnswer bool
+}
+
+// StageResult is the outcome of one stage.
+type StageResult struct {
+	Stage string
+	OK    bool
+	// Skipped is set when an earlier stage failed.
+	Skipped  bool
+	Duration time.Duration
+	Detail   string
+}
+
+// Result is the outcome of one run.
+type Result struct {
+	Start  time.Time
+	Stages []StageResult
+}
+
+// OK reports whether every stage passed.
+func (r Result) OK() bool {
+	for _, s := range r.Stages {
+		if !s.OK {
+			return false
+		}
+	}
+	return true
+}
+
+// FirstFailure returns the stage that failed first, or nil.
+func (r Result) FirstFailure() *StageResult {
+	for i := range r.Stages {
+		if !r.Stages[i].OK && !r.Stages[i].Skipped {
+			return &r.Stages[i]
+		}
+	}
+	return nil
+}
+
+// Duration is the time from the first stage's start to the last's end.
+func (r Result) Duration() time.Duration {
+	var d time.Duration
+	for _, s := range r.Stages {
+		d += s.Duration
+	}
+	return d
+}
+
+type runner struct {
+	client *api.Client
+	opts   Options
+
+	word        string
+	docID       string
+	chatSession string
+}
+
+// Run performs one canary run. Once a stage fails the rest are skipped,
+// except delete, which still cleans up whatever was created.
+func Run(client *api.Client, opts Options) Result {
+	suffix := randomHex()
+	r := &runner{
+		client: client,
+		opts:   opts,
+		word:   "canary" + suffix,
+		docID:  "ods-canary-" + suffix,
+	}
+	result := Result{Start: time.Now()}
+
+	steps := []struct {
+		stage string
+		fn    func() (string, error)
+	}{
+		{StageLogin, r.login},
+		{StageIngest, r.ingest},
+		{StageSearch, r.search},
+		{StageChat, r.chat},
+	}
+	failed, ingested := false, false
+	for _, step := range steps {
+		if failed {
+			result.Stages = append(result.Stages, StageResult{Stage: step.stage, Skipped: true, Detail: "skipped"})
+			continue
+		}
+		s := timed(step.stage, step.fn)
+		failed = !s.OK
+		ingested = ingested || (step.stage == StageIngest && s.OK)
+		result.Stages = append(result.Stages, s)
+	}
+
+	if ingested || r.chatSession != "" {
+		result.Stages = append(result.Stages, timed(StageDelete, r.cleanup))
+	} else {
+		result.Stages = append(result.Stages, StageResult{Stage: StageDelete, Skipped: true, Detail: "nothing to delete"})
+	}
+	return result
+}
+
+func timed(stage string, fn func() (string, error)) StageResult {
+	start := time.Now()
+	detail, err := fn()
+	s := StageResult{Stage: stage, OK: err == nil, Duration: time.Since(start), Detail: detail}
+	if err != nil {
+		s.Detail = err.Error()
+	}
+	return s
+}
+
+func (r *runner) login() (string, error) {
+	me, err := r.client.GetMe()
+	if err != nil {
+		return "", err
+	}
+	return "as " + me.Email, nil
+}
+
+func (r *runner) ingest() (string, error) {
+	now := time.Now().UTC()
+	section, err := json.Marshal(map[string]string{
+		"text": fmt.Sprintf("This document was created by the ods canary to check that Onyx works end to end. "+
+			"The canary code word is %s. The canary deletes this document within minutes.", r.word),
+	})
+	if err != nil {
+		return "", err
+	}
+	title := "ods canary " + r.word
+	source := api.DocumentSourceIngestionAPI
+	doc := api.IngestionDocument{
+		Document: api.DocumentBase{
+			ID:                 &r.docID,
+			SemanticIdentifier: title,
+			Title:              &title,
+			Source:             &source,
+			Sections:           []json.RawMessage{section},
+			Metadata:           map[string]json.RawMessage{"ods_canary": json.RawMessage(`"true"`)},
+			DocUpdatedAt:       &now,
+			DocCreatedAt:       &now,
+		},
+	}
+	if r.opts.CCPairID != 0 {
+		doc.CcPairID = &r.opts.CCPairID
+	}
+	res, err := r.client.IngestDocument(doc)
+	if err != nil {
+		return "", err
+	}
+	return "document " + res.DocumentID, nil
+}
+
+func (r *runner) search() (string, error) {
+	deadline := time.Now().Add(r.opts.SearchTimeout)
+	for attempt := 1; ; attempt++ {
+		res, err := r.client.AdminSearch(api.AdminSearchRequest{Query: r.word})
+		if err != nil {
+			return "", err
+		}
+		for _, d := range res.Documents {
+			if d.DocumentID == r.docID {
+				return fmt.Sprintf("found after %d attempt(s)", attempt), nil
+			}
+		}
+		if time.Now().Add(searchRetryWait).After(deadline) {
+			return "", fmt.Errorf("document not found by search after %d attempt(s) (%d other results)", attempt, len(res.Documents))
+		}
+		time.Sleep(searchRetryWait)
+	}
+}
+
+func (r *runner) chat() (string, error) {
+	start := time.Now()
+	stream, err := r.client.StreamChatMessage(api.SendMessageRequest{
+		Message:         "What is the canary code word in the ods canary document " + r.word + "?",
+		ChatSessionInfo: &api.ChatSessionCreationRequest{PersonaID: &r.opts.Persona},
+	})
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = stream.Close() }()
+
+	var answer strings.Builder
+	var streamErrors []string
+	var firstToken time.Duration
+	readErr := chatstream.Read(stream, start, func(f chatstream.Frame) error {
+		switch {
+		case f.Type == chatstream.TypeSession:
+			r.chatSession = f.Text
+		case f.Type == chatstream.TypeError:
+			streamErrors = append(streamErrors, f.Text)
+		case f.Type == "message_delta":
+			if answer.Len() == 0 {
+				firstToken = f.At
+			}
+			answer.WriteString(f.Text)
+		}
+		return nil
+	})
+	switch {
+	case readErr != nil:
+		return "", fmt.Errorf("stream broke off: %w", readErr)
+	case len(streamErrors) > 0:
+		return "", fmt.Errorf("stream reported an error: %s", streamErrors[0])
+	case answer.Len() == 0:
+		return "", fmt.Errorf("stream ended without an answer")
+	}
+
+	recalled := strings.Contains(strings.ToLower(answer.String()), r.word)
+	if r.opts.ExpectAnswer && !recalled {
+		return "", fmt.Errorf("answer does not mention the code word: %q", truncate(answer.String(), 200))
+	}
+	detail := fmt.Sprintf("first token after %s", firstToken.Round(time.Millisecond))
+	if !recalled {
+		detail += ", answer does not mention the code word"
+	}
+	return detail, nil
+}
+
+// cleanup deletes the document and chat session, reporting every failure.
+func (r *runner) cleanup() (string, error) {
+	var deleted, problems []string
+	if err := r.client.DeleteIngestedDocument(r.docID); err != nil {
+		problems = append(problems, "document: "+err.Error())
+	} else {
+		deleted = append(deleted, "document")
+	}
+	if r.chatSession != "" {
+		if err := r.client.DeleteChatSession(r.chatSession, api.DeleteChatSessionParams{}); err != nil {
+			problems = append(problems, "chat session: "+err.Error())
+		} else {
+			deleted = append(deleted, "chat session")
+		}
+	}
+	if len(problems) > 0 {
+		return "", fmt.Errorf("%s", strings.Join(problems, "; "))
+	}
+	return "deleted " + strings.Join(deleted, " and "), nil
+}
+
+func randomHex() string {
+	b := make([]byte, 6)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "…"
+}
@@ -0,0 +1,156 @@
+package canary
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/api"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/apiclient"
+)
+
+// fakeServer implements the endpoints the canary uses, remembering the
+// ingested document so search can find it.
+type fakeServer struct {
+	mu          sync.Mutex
+	docID       string
+	searchable  bool
+	deletedDoc  bool
+	deletedChat bool
+}
+
+func (f *fakeServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	switch {
+	case r.Method == http.MethodGet && r.URL.Path == "/me":
+		_ = json.NewEncoder(w).Encode(map[string]string{"email": "canary@example.com"})
+	case r.Method == http.MethodPost && r.URL.Path == "/onyx-api/ingestion":
+		var req struct {
+			Document struct {
+				ID string `json:"id"`
+			} `json:"document"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		f.docID = req.Document.ID
+		_ = json.NewEncoder(w).Encode(map[string]any{"document_id": f.docID, "already_existed": false})
+	case r.Method == http.MethodPost && r.URL.Path == "/admin/search":
+		docs := []map[string]string{{"document_id": "other"}}
+		if f.searchable {
+			docs = append(docs, map[string]string{"document_id": f.docID})
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"documents": docs})
+	case r.Method == http.MethodPost && r.URL.Path == "/chat/send-chat-message":
+		var req struct {
+			Message string `json:"message"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		_, _ = w.Write([]byte(`{"chat_session_id": "chat-1"}` + "\n"))
+		answer, _ := json.Marshal(map[string]any{
+			"placement": map[string]int{"turn_index": 0},
+			"obj":       map[string]string{"type": "message_delta", "content": "You asked: " + req.Message},
+		})
+		_, _ = w.Write(append(answer, '\n'))
+	case r.Method == http.MethodDelete && r.URL.Path == "/onyx-api/ingestion/"+f.docID:
+		f.deletedDoc = true
+	case r.Method == http.MethodDelete && r.URL.Path == "/chat/delete-chat-session/chat-1":
+		f.deletedChat = true
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func TestRunPasses(t *testing.T) {
+	fake := &fakeServer{searchable: true}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	result := Run(api.New(apiclient.New(srv.URL)), Options{SearchTimeout: time.Second, ExpectAnswer: true})
+	if !result.OK() {
+		t.Fatalf("expected a passing run, got %+v", result.Stages)
+	}
+	if len(result.Stages) != len(Stages) {
+		t.Fatalf("got %d stages, want %d", len(result.Stages), len(Stages))
+	}
+	for i, s := range result.Stages {
+		if s.Stage != Stages[i] {
+			t.Errorf("stage %d = %s, want %s", i, s.Stage, Stages[i])
+		}
+	}
+	if !strings.HasPrefix(fake.docID, "ods-canary-") || !fake.deletedDoc || !fake.deletedChat {
+		t.Errorf("expected the document %q and chat session to be deleted", fake.docID)
+	}
+}
+
+func TestRunSearchFailureStillCleansUp(t *testing.T) {
+	fake := &fakeServer{searchable: false}
+	srv := httptest.NewServer(fake)
+	defer srv.Close()
+
+	result := Run(api.New(apiclient.New(srv.URL)), Options{})
+	if result.OK() {
+		t.Fatal("expected a failing run")
+	}
+	failure := result.FirstFailure()
+	if failure == nil || failure.Stage != StageSearch || !strings.Contains(failure.Detail, "not found") {
+		t.Fatalf("FirstFailure = %+v, want the search stage", failure)
+	}
+	if chat := result.Stages[3]; !chat.Skipped {
+		t.Errorf("chat stage = %+v, want skipped", chat)
+	}
+	if del := result.Stages[4]; !del.OK || !fake.deletedDoc {
+		t.Errorf("delete stage = %+v, want the document deleted", del)
+	}
+}
+
+func TestRunLoginFailure(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+	}))
+	defer srv.Close()
+
+	result := Run(api.New(apiclient.New(srv.URL)), Options{})
+	if f := result.FirstFailure(); f == nil || f.Stage != StageLogin || !strings.Contains(f.Detail, "401") {
+		t.Fatalf("FirstFailure = %+v, want login with a 401", f)
+	}
+	if del := result.Stages[4]; !del.Skipped {
+		t.Errorf("delete stage = %+v, want skipped when nothing was created", del)
+	}
+}
+
+func TestMetrics(t *testing.T) {
+	m := NewMetrics("prod")
+	var b strings.Builder
+	m.Write(&b)
+	if !strings.Contains(b.String(), `ods_canary_runs_total{env="prod",result="success"} 0`) {
+		t.Errorf("missing zero run counter:\n%s", b.String())
+	}
+
+	m.Record(Result{Start: time.Unix(1700000000, 0), Stages: []StageResult{
+		{Stage: StageLogin, OK: true, Duration: 250 * time.Millisecond},
+		{Stage: StageIngest, Duration: time.Second, Detail: "500"},
+		{Stage: StageSearch, Skipped: true},
+	}})
+	b.Reset()
+	m.Write(&b)
+	for _, want := range []string{
+		`ods_canary_runs_total{env="prod",result="failure"} 1`,
+		`ods_canary_stage_failures_total{env="prod",stage="ingest"} 1`,
+		`ods_canary_stage_failures_total{env="prod",stage="search"} 0`,
+		`ods_canary_stage_up{env="prod",stage="login"} 1`,
+		`ods_canary_stage_duration_seconds{env="prod",stage="login"} 0.25`,
+		`ods_canary_up{env="prod"} 0`,
+		`ods_canary_last_run_timestamp_seconds{env="prod"} 1700000000`,
+	} {
+		if !strings.Contains(b.String(), want) {
+			t.Errorf("missing %q in:\n%s", want, b.String())
+		}
+	}
+	if strings.Contains(b.String(), `ods_canary_stage_duration_seconds{env="prod",stage="search"}`) {
+		t.Error("skipped stages should have no duration")
+	}
+}
@@ -0,0 +1,93 @@
+package canary
+
+import (
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"sync"
+)
+
+// Metrics exports canary results in the Prometheus text format.
+type Metrics struct {
+	env string
+
+	mu            sync.Mutex
+	last          *Result
+	runs          map[bool]int
+	stageFailures map[string]int
+}
+
+// NewMetrics returns Metrics whose series carry env as a label.
+func NewMetrics(env string) *Metrics {
+	return &Metrics{env: env, runs: map[bool]int{}, stageFailures: map[string]int{}}
+}
+
+// Record adds a run's result.
+func (m *Metrics) Record(r Result) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.last = &r
+	m.runs[r.OK()]++
+	for _, s := range r.Stages {
+		if !s.OK && !s.Skipped {
+			m.stageFailures[s.Stage]++
+		}
+	}
+}
+
+// ServeHTTP serves the metrics.
+func (m *Metrics) ServeHTTP(w http.ResponseWriter, _ *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4")
+	m.Write(w)
+}
+
+// Write writes the metrics to w.
+func (m *Metrics) Write(w io.Writer) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	env := strconv.Quote(m.env)
+
+	header := func(name, kind, help string) {
+		_, _ = fmt.Fprintf(w, "# HELP %s %s\n# TYPE %s %s\n", name, help, name, kind)
+	}
+
+	header("ods_canary_runs_total", "counter", "Canary runs by result.")
+	for _, ok := range []bool{true, false} {
+		result := "success"
+		if !ok {
+			result = "failure"
+		}
+		_, _ = fmt.Fprintf(w, "ods_canary_runs_total{env=%s,result=%q} %d\n", env, result, m.runs[ok])
+	}
+
+	header("ods_canary_stage_failures_total", "counter", "Canary stage failures.")
+	for _, stage := range Stages {
+		_, _ = fmt.Fprintf(w, "ods_canary_stage_failures_total{env=%s,stage=%q} %d\n", env, stage, m.stageFailures[stage])
+	}
+
+	if m.last == nil {
+		return
+	}
+	header("ods_canary_stage_up", "gauge", "Whether the stage passed in the last run.")
+	for _, s := range m.last.Stages {
+		_, _ = fmt.Fprintf(w, "ods_canary_stage_up{env=%s,stage=%q} %d\n", env, s.Stage, boolToInt(s.OK))
+	}
+	header("ods_canary_stage_duration_seconds", "gauge", "How long the stage took in the last run.")
+	for _, s := range m.last.Stages {
+		if !s.Skipped {
+			_, _ = fmt.Fprintf(w, "ods_canary_stage_duration_seconds{env=%s,stage=%q} %g\n", env, s.Stage, s.Duration.Seconds())
+		}
+	}
+	header("ods_canary_up", "gauge", "Whether the last run passed.")
+	_, _ = fmt.Fprintf(w, "ods_canary_up{env=%s} %d\n", env, boolToInt(m.last.OK()))
+	header("ods_canary_last_run_timestamp_seconds", "gauge", "When the last run started.")
+	_, _ = fmt.Fprintf(w, "ods_canary_last_run_timestamp_seconds{env=%s} %d\n", env, m.last.Start.Unix())
+}
+
+func boolToInt(b bool) int {
+	if b {
+		return 1
+	}
+	return 0
+}
@@ -0,0 +1,124 @@
+// Package chaos injects failures into Onyx services to exercise their retry
+// and degradation paths: added network latency and network partitions.
+package chaos
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/docker"
+)
+
+// DefaultImage provides tc for latency injection.
+const DefaultImage = "nicolaka/netshoot:v0.13"
+
+// Interface is the network interface faults are applied to; it is the only
+// one in compose containers and pods.
+const Interface = "eth0"
+
+// LatencyScript returns the shell command that delays every packet leaving
+// Interface by delay, varied by up to jitter.
+func LatencyScript(delay, jitter time.Duration) (string, error) {
+	if delay <= 0 {
+		return "", fmt.Errorf("latency must be positive, got %s", delay)
+	}
+	if jitter < 0 || jitter > delay {
+		return "", fmt.Errorf("jitter must be between 0 and the latency (%s), got %s", delay, jitter)
+	}
+	script := fmt.Sprintf("tc qdisc replace dev %s root netem delay %dms", Interface, delay.Milliseconds())
+	if jitter > 0 {
+		script += fmt.Sprintf(" %dms distribution normal", jitter.Milliseconds())
+	}
+	return script, nil
+}
+
+// ClearScript returns the shell command that removes injected latency.
+// Removing the root qdisc restores the default and is a no-op (apart from
+// its exit status) when none was added.
+func ClearScript() string {
+	return fmt.Sprintf("tc qdisc del dev %s root 2>/dev/null || true", Interface)
+}
+
+// State records the faults left in place, so `ods chaos heal` can undo them
+// after an interrupted run.
+type State struct {
+	// Partitions maps a partitioned container to the networks it was
+	// disconnected from.
+	Partitions map[string][]docker.NetworkAttachment `json:"partitions,omitempty"`
+	// Latency lists the containers with injected latency.
+	Latency []string `json:"latency,omitempty"`
+
+	path string
+}
+
+// LoadState reads the state at path; a missing file is an empty state.
+func LoadState(path string) (*State, error) {
+	s := &State{path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Empty reports whether no faults are recorded.
+func (s *State) Empty() bool {
+	return len(s.Partitions) == 0 && len(s.Latency) == 0
+}
+
+// AddLatency records latency injected into container.
+func (s *State) AddLatency(container string) {
+	for _, c := range s.Latency {
+		if c == container {
+			return
+		}
+	}
+	s.Latency = append(s.Latency, container)
+}
+
+// RemoveLatency forgets the latency of container.
+func (s *State) RemoveLatency(container string) {
+	for i, c := range s.Latency {
+		if c == container {
+			s.Latency = append(s.Latency[:i], s.Latency[i+1:]...)
+			return
+		}
+	}
+}
+
+// AddPartition records that container was disconnected from networks.
+func (s *State) AddPartition(container string, networks []docker.NetworkAttachment) {
+	if s.Partitions == nil {
+		s.Partitions = make(map[string][]docker.NetworkAttachment)
+	}
+	s.Partitions[container] = networks
+}
+
+// Save writes the state, removing the file once no faults are left.
+func (s *State) Save() error {
+	if s.Empty() {
+		if err := os.Remove(s.path); err != nil && !errors.Is(err, fs.ErrNotExist) {
+			return err
+		}
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, append(data, '\n'), 0644)
+}
@@ -0,0 +1,58 @@
+package chaos
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/docker"
+)
+
+func TestLatencyScript(t *testing.T) {
+	got, err := LatencyScript(500*time.Millisecond, 0)
+	if err != nil || got != "tc qdisc replace dev eth0 root netem delay 500ms" {
+		t.Errorf("LatencyScript(500ms, 0) = %q, %v", got, err)
+	}
+	got, err = LatencyScript(time.Second, 200*time.Millisecond)
+	if err != nil || got != "tc qdisc replace dev eth0 root netem delay 1000ms 200ms distribution normal" {
+		t.Errorf("LatencyScript(1s, 200ms) = %q, %v", got, err)
+	}
+	if _, err := LatencyScript(0, 0); err == nil {
+		t.Error("expected zero latency to be rejected")
+	}
+	if _, err := LatencyScript(100*time.Millisecond, time.Second); err == nil {
+		t.Error("expected jitter above the latency to be rejected")
+	}
+}
+
+func TestState(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "chaos.json")
+	s, err := LoadState(path)
+	if err != nil || !s.Empty() {
+		t.Fatalf("LoadState() of a missing file = %+v, %v", s, err)
+	}
+
+	s.AddLatency("onyx-api_server-1")
+	s.AddLatency("onyx-api_server-1")
+	s.AddPartition("onyx-relational_db-1", []docker.NetworkAttachment{{Network: "onyx_default", Aliases: []string{"relational_db"}}})
+	if err := s.Save(); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+	got, err := LoadState(path)
+	if err != nil {
+		t.Fatalf("LoadState() error: %v", err)
+	}
+	if len(got.Latency) != 1 || got.Partitions["onyx-relational_db-1"][0].Aliases[0] != "relational_db" {
+		t.Errorf("unexpected state %+v", got)
+	}
+
+	got.RemoveLatency("onyx-api_server-1")
+	delete(got.Partitions, "onyx-relational_db-1")
+	if err := got.Save(); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("expected an empty state to remove %s, got %v", path, err)
+	}
+}
@@ -0,0 +1,177 @@
+// Package chatstream reads the chat answer stream of the API server: the
+// newline-delimited JSON packets /chat/send-chat-message returns when
+// streaming, and timing statistics per packet type.
+package chatstream
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"time"
+)
+
+// maxFrameSize bounds a single packet; search results with many documents
+// can be large.
+const maxFrameSize = 16 << 20
+
+// Frame types for the stream parts that are not packets.
+const (
+	TypeSession    = "chat_session_id"
+	TypeMessageIDs = "message_ids"
+	TypeError      = "error"
+	TypeUnknown    = "unknown"
+)
+
+// Frame is one part of the answer stream.
+type Frame struct {
+	// At is when the frame arrived, relative to the request.
+	At time.Duration
+	// Type is the packet's obj.type (message_delta, search_tool_start, ...)
+	// or one of the Type constants for other parts.
+	Type string
+	// Turn is the packet's placement.turn_index, nil for other parts.
+	Turn *int
+	// Text is the streamed text of delta packets, or the error message.
+	Text string
+	Raw  json.RawMessage
+}
+
+// IsDelta reports whether the frame carries streamed answer or reasoning
+// text.
+func (f Frame) IsDelta() bool {
+	return f.Type == "message_delta" || f.Type == "reasoning_delta"
+}
+
+// ParseFrame classifies one line of the stream.
+func ParseFrame(line []byte) (Frame, error) {
+	var part struct {
+		Placement *struct {
+			TurnIndex int `json:"turn_index"`
+		} `json:"placement"`
+		Obj *struct {
+			Type      string `json:"type"`
+			Content   string `json:"content"`
+			Reasoning string `json:"reasoning"`
+			Error     string `json:"error"`
+		} `json:"obj"`
+		ChatSessionID     string          `json:"chat_session_id"`
+		ReservedMessageID *int            `json:"reserved_assistant_message_id"`
+		Responses         json.RawMessage `json:"responses"`
+		Error             string          `json:"error"`
+	}
+	if err := json.Unmarshal(line, &part); err != nil {
+		return Frame{}, fmt.Errorf("invalid stream frame %q: %w", truncate(string(line), 200), err)
+	}
+	f := Frame{Raw: append(json.RawMessage(nil), line...)}
+	switch {
+	case part.Obj != nil:
+		f.Type = part.Obj.Type
+		if part.Placement != nil {
+			turn := part.Placement.TurnIndex
+			f.Turn = &turn
+		}
+		switch {
+		case part.Obj.Content != "":
+			f.Text = part.Obj.Content
+		case part.Obj.Reasoning != "":
+			f.Text = part.Obj.Reasoning
+		default:
+			f.Text = part.Obj.Error
+		}
+	case part.Error != "":
+		f.Type, f.Text = TypeError, part.Error
+	case part.ChatSessionID != "":
+		f.Type, f.Text = TypeSession, part.ChatSessionID
+	case part.ReservedMessageID != nil || len(part.Responses) > 0:
+		f.Type = TypeMessageIDs
+	default:
+		f.Type = TypeUnknown
+	}
+	return f, nil
+}
+
+// Read parses frames from r as they arrive, stamping each with the time
+// since start, and calls fn for each. It stops at the end of the stream or
+// the first error from fn.
+func Read(r io.Reader, start time.Time, fn func(Frame) error) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxFrameSize)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		// Tolerate SSE framing in case a proxy or future backend adds it.
+		if len(line) > 5 && string(line[:5]) == "data:" {
+			line = line[5:]
+		}
+		f, err := ParseFrame(line)
+		if err != nil {
+			return err
+		}
+		f.At = time.Since(start)
+		if err := fn(f); err != nil {
+			return err
+		}
+	}
+	return scanner.Err()
+}
+
+// TypeStats summarizes the frames of one type.
+type TypeStats struct {
+	Type  string
+	Count int
+	First time.Duration
+	Last  time.Duration
+	// Chars is the streamed text length of delta frames.
+	Chars int
+}
+
+// Stats accumulates TypeStats in first-seen order.
+type Stats struct {
+	types []*TypeStats
+	index map[string]*TypeStats
+}
+
+// Add records f.
+func (s *Stats) Add(f Frame) {
+	if s.index == nil {
+		s.index = map[string]*TypeStats{}
+	}
+	t := s.index[f.Type]
+	if t == nil {
+		t = &TypeStats{Type: f.Type, First: f.At}
+		s.index[f.Type] = t
+		s.types = append(s.types, t)
+	}
+	t.Count++
+	t.Last = f.At
+	if f.IsDelta() {
+		t.Chars += len(f.Text)
+	}
+}
+
+// Types returns the stats per type, in the order types first appeared.
+func (s *Stats) Types() []TypeStats {
+	out := make([]TypeStats, len(s.types))
+	for i, t := range s.types {
+		out[i] = *t
+	}
+	return out
+}
+
+// FirstToken returns when the first answer text arrived.
+func (s *Stats) FirstToken() (time.Duration, bool) {
+	if t := s.index["message_delta"]; t != nil {
+		return t.First, true
+	}
+	return 0, false
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "..."
+}
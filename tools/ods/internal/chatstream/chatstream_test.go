@@ -0,0 +1,71 @@
+package chatstream
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseFrame(t *testing.T) {
+	tests := []struct {
+		line, typ, text string
+		turn            int
+	}{
+		{`{"chat_session_id": "5f0c"}`, TypeSession, "5f0c", -1},
+		{`{"user_message_id": 1, "reserved_assistant_message_id": 2}`, TypeMessageIDs, "", -1},
+		{`{"placement": {"turn_index": 0}, "obj": {"type": "message_start", "final_documents": null}}`, "message_start", "", 0},
+		{`{"placement": {"turn_index": 2}, "obj": {"type": "message_delta", "content": "Hel"}}`, "message_delta", "Hel", 2},
+		{`{"placement": {"turn_index": 1}, "obj": {"type": "reasoning_delta", "reasoning": "hmm"}}`, "reasoning_delta", "hmm", 1},
+		{`{"error": "rate limited"}`, TypeError, "rate limited", -1},
+		{`{"something": "else"}`, TypeUnknown, "", -1},
+	}
+	for _, tt := range tests {
+		f, err := ParseFrame([]byte(tt.line))
+		if err != nil {
+			t.Fatalf("ParseFrame(%s) error: %v", tt.line, err)
+		}
+		if f.Type != tt.typ || f.Text != tt.text {
+			t.Errorf("ParseFrame(%s) = %s %q, want %s %q", tt.line, f.Type, f.Text, tt.typ, tt.text)
+		}
+		if (tt.turn < 0) != (f.Turn == nil) || (f.Turn != nil && *f.Turn != tt.turn) {
+			t.Errorf("ParseFrame(%s) turn = %v, want %d", tt.line, f.Turn, tt.turn)
+		}
+	}
+	if _, err := ParseFrame([]byte("not json")); err == nil {
+		t.Error("expected an error for a non-JSON frame")
+	}
+}
+
+func TestReadAndStats(t *testing.T) {
+	stream := strings.Join([]string{
+		`{"chat_session_id": "5f0c"}`,
+		``,
+		`{"placement": {"turn_index": 0}, "obj": {"type": "message_delta", "content": "Hello"}}`,
+		`data: {"placement": {"turn_index": 0}, "obj": {"type": "message_delta", "content": " world"}}`,
+		`{"placement": {"turn_index": 0}, "obj": {"type": "stop"}}`,
+	}, "\n")
+	var s Stats
+	var text strings.Builder
+	if err := Read(strings.NewReader(stream), time.Now(), func(f Frame) error {
+		s.Add(f)
+		if f.IsDelta() {
+			text.WriteString(f.Text)
+		}
+		return nil
+	}); err != nil {
+		t.Fatalf("Read() error: %v", err)
+	}
+	if text.String() != "Hello world" {
+		t.Errorf("streamed text = %q", text.String())
+	}
+	types := s.Types()
+	if len(types) != 3 || types[0].Type != TypeSession || types[1].Type != "message_delta" || types[2].Type != "stop" {
+		t.Fatalf("unexpected types %+v", types)
+	}
+	if types[1].Count != 2 || types[1].Chars != 11 || types[1].Last < types[1].First {
+		t.Errorf("unexpected delta stats %+v", types[1])
+	}
+	if _, ok := s.FirstToken(); !ok {
+		t.Error("expected a first token time")
+	}
+}
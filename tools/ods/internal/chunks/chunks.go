@@ -0,0 +1,136 @@
+// Package chunks shows how a document was chunked in Vespa and what the
+// current chunker settings would make of it.
+package chunks
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/kube"
+)
+
+//go:embed inspect_chunks.py
+var inspectScript string
+
+// Chunk is a chunk as stored in Vespa, or as the chunker would cut it now.
+// The stored-only fields are empty in a preview.
+type Chunk struct {
+	ChunkID             int      `json:"chunk_id"`
+	Chars               int      `json:"chars"`
+	Tokens              int      `json:"tokens"`
+	Title               string   `json:"title"`
+	SectionContinuation bool     `json:"section_continuation"`
+	Links               []string `json:"links"`
+	// Head and Tail are the first and last characters of the chunk; Tail is
+	// empty when Head covers it all.
+	Head string `json:"head"`
+	Tail string `json:"tail"`
+
+	// IndexedTokens counts the chunk as embedded: with title prefix and
+	// metadata suffix.
+	IndexedTokens int `json:"indexed_tokens"`
+	// Embeddings maps each embedding (full_chunk, mini_chunk_N) to its
+	// dimensions.
+	Embeddings     map[string]int `json:"embeddings"`
+	TitleEmbedding bool           `json:"title_embedding"`
+	HasContext     bool           `json:"has_context"`
+	MetadataSuffix string         `json:"metadata_suffix"`
+	Blurb          string         `json:"blurb"`
+}
+
+// Report is a document's chunks.
+type Report struct {
+	DocumentID      string  `json:"document_id"`
+	IndexName       string  `json:"index_name"`
+	Tokenizer       string  `json:"tokenizer"`
+	ChunkTokenLimit int     `json:"chunk_token_limit"`
+	Chunks          []Chunk `json:"chunks"`
+	// Preview is what the current settings would produce from the text of
+	// the stored chunks, when asked for.
+	Preview      []Chunk `json:"preview,omitempty"`
+	PreviewError string  `json:"preview_error,omitempty"`
+}
+
+// Inspect fetches the chunks of docID in schema ("" for the default schema
+// of a single-tenant deployment) on pod, re-chunking it too with preview.
+func Inspect(c *kube.Cluster, pod, schema, docID string, preview bool) (*Report, error) {
+	args := []string{schema, docID}
+	if preview {
+		args = append(args, "preview")
+	}
+	out, err := c.RunPython(pod, inspectScript, args...)
+	if err != nil {
+		return nil, err
+	}
+	return parseReport(out)
+}
+
+func parseReport(stdout string) (*Report, error) {
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	last := strings.TrimSpace(lines[len(lines)-1])
+	var r struct {
+		Status  string `json:"status"`
+		Message string `json:"message"`
+		Report
+	}
+	if err := json.Unmarshal([]byte(last), &r); err != nil {
+		return nil, fmt.Errorf("unexpected output from chunks script: %q", last)
+	}
+	if r.Status != "success" {
+		return nil, fmt.Errorf("%s", r.Message)
+	}
+	return &r.Report, nil
+}
+
+// Findings lists what looks wrong with the stored chunks, and how a
+// re-chunk would differ. An empty list means nothing stands out.
+func (r *Report) Findings() []string {
+	var findings []string
+	var gaps, empty, over, unembedded []string
+	for i, ch := range r.Chunks {
+		id := fmt.Sprint(ch.ChunkID)
+		if ch.ChunkID != i {
+			gaps = append(gaps, id)
+		}
+		if strings.TrimSpace(ch.Head) == "" {
+			empty = append(empty, id)
+		}
+		if r.ChunkTokenLimit > 0 && ch.IndexedTokens > r.ChunkTokenLimit {
+			over = append(over, id)
+		}
+		if len(ch.Embeddings) == 0 {
+			unembedded = append(unembedded, id)
+		}
+	}
+	if len(gaps) > 0 {
+		findings = append(findings, fmt.Sprintf("Chunk IDs are not consecutive from 0 (at %s); chunks are missing from Vespa", strings.Join(gaps, ", ")))
+	}
+	if len(empty) > 0 {
+		findings = append(findings, fmt.Sprintf("Chunk(s) %s have no text", strings.Join(empty, ", ")))
+	}
+	if len(over) > 0 {
+		findings = append(findings, fmt.Sprintf("Chunk(s) %s exceed the %d-token limit with title and metadata, so their embeddings may be truncated", strings.Join(over, ", "), r.ChunkTokenLimit))
+	}
+	if len(unembedded) > 0 {
+		findings = append(findings, fmt.Sprintf("Chunk(s) %s have no embeddings, so only keyword search can find them", strings.Join(unembedded, ", ")))
+	}
+
+	if r.PreviewError != "" {
+		findings = append(findings, "Could not re-chunk the document: "+r.PreviewError)
+	} else if r.Preview != nil {
+		switch {
+		case len(r.Preview) != len(r.Chunks):
+			findings = append(findings, fmt.Sprintf("The current settings would cut %d chunk(s) instead of %d", len(r.Preview), len(r.Chunks)))
+		default:
+			for i := range r.Preview {
+				if r.Preview[i].Chars != r.Chunks[i].Chars {
+					findings = append(findings, fmt.Sprintf("The current settings would cut the same number of chunks, with boundaries moving from chunk %d", i))
+					break
+				}
+			}
+		}
+	}
+	return findings
+}
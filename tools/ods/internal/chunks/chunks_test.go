@@ -0,0 +1,45 @@
+package chunks
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseReport(t *testing.T) {
+	out := "Fetching chunks...\n" + `{"status": "success", "document_id": "doc", "index_name": "danswer_chunk", "tokenizer": "nomic", "chunk_token_limit": 512, "chunks": [{"chunk_id": 0, "chars": 10, "tokens": 3, "indexed_tokens": 5, "head": "Hello", "embeddings": {"full_chunk": 768}}, {"chunk_id": 2, "chars": 4, "indexed_tokens": 600, "head": "  "}], "preview": [{"chunk_id": 0, "chars": 14}]}`
+	r, err := parseReport(out)
+	if err != nil {
+		t.Fatalf("parseReport() error: %v", err)
+	}
+	if len(r.Chunks) != 2 || r.Chunks[0].Embeddings["full_chunk"] != 768 {
+		t.Errorf("unexpected report %+v", r)
+	}
+
+	findings := strings.Join(r.Findings(), "\n")
+	for _, want := range []string{
+		"not consecutive from 0 (at 2)",
+		"Chunk(s) 2 have no text",
+		"Chunk(s) 2 exceed the 512-token limit",
+		"Chunk(s) 2 have no embeddings",
+		"would cut 1 chunk(s) instead of 2",
+	} {
+		if !strings.Contains(findings, want) {
+			t.Errorf("Findings() missing %q:\n%s", want, findings)
+		}
+	}
+
+	if _, err := parseReport(`{"status": "error", "message": "Vespa has no chunks for 'doc'"}`); err == nil || !strings.Contains(err.Error(), "no chunks") {
+		t.Errorf("expected the script's message as error, got %v", err)
+	}
+}
+
+func TestFindingsClean(t *testing.T) {
+	r := &Report{
+		ChunkTokenLimit: 512,
+		Chunks:          []Chunk{{ChunkID: 0, Chars: 5, Head: "Hello", IndexedTokens: 10, Embeddings: map[string]int{"full_chunk": 768}}},
+		Preview:         []Chunk{{ChunkID: 0, Chars: 5}},
+	}
+	if f := r.Findings(); len(f) != 0 {
+		t.Errorf("Findings() = %q, want none", f)
+	}
+}
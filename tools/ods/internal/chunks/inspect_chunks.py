@@ -0,0 +1,248 @@
+"""Show how a document was chunked, straight from Vespa.
+
+Bundled with ods and piped into `python -` on an api-server pod by
+`ods chunks`. Fetches every regular (not large) chunk Vespa holds for the
+document and reports each chunk's size and edges, token counts with the
+index's tokenizer, title, links and which embeddings it carries. With
+"preview", the document's text is rebuilt from the stored chunks and run
+through the current chunker settings, to show what re-indexing would produce.
+
+Usage:
+    python - <schema> <document id> [preview]
+
+An empty <schema> means the default schema of a single-tenant deployment.
+
+Progress goes to stderr; the last line on stdout is a JSON object with
+"status", "document_id", "index_name", "tokenizer", "chunk_token_limit",
+"chunks" and, with preview, "preview" or "preview_error".
+"""
+
+from __future__ import annotations
+
+import json
+import sys
+from typing import Any
+
+# How much of each end of a chunk to show, to make boundaries visible.
+EDGE_CHARS = 80
+
+
+def use_schema(schema: str) -> str:
+    from onyx.db.engine.tenant_utils import validate_tenant_id
+    from shared_configs.configs import MULTI_TENANT
+    from shared_configs.configs import POSTGRES_DEFAULT_SCHEMA
+    from shared_configs.contextvars import CURRENT_TENANT_ID_CONTEXTVAR
+
+    if not schema:
+        if MULTI_TENANT:
+            raise ValueError("This deployment is multi-tenant; pass --tenant")
+        schema = POSTGRES_DEFAULT_SCHEMA
+    elif schema != POSTGRES_DEFAULT_SCHEMA and not validate_tenant_id(schema):
+        raise ValueError(f"Invalid schema {schema!r}")
+    CURRENT_TENANT_ID_CONTEXTVAR.set(schema)
+    return schema
+
+
+def edges(text: str) -> tuple[str, str]:
+    return text[:EDGE_CHARS], text[-EDGE_CHARS:] if len(text) > EDGE_CHARS else ""
+
+
+def embedding_dims(value: Any) -> dict[str, int]:
+    # short-value tensors: a mapped tensor is {label: [floats]}, an indexed
+    # one a plain list.
+    if isinstance(value, dict):
+        return {
+            str(k): len(v) if isinstance(v, list) else 0 for k, v in value.items()
+        }
+    if isinstance(value, list):
+        return {"": len(value)}
+    return {}
+
+
+def links_of(raw: Any) -> dict[int, str]:
+    if isinstance(raw, str):
+        raw = json.loads(raw or "null")
+    return {int(k): v for k, v in (raw or {}).items()}
+
+
+def chunk_text(fields: dict[str, Any]) -> str:
+    # content_summary is the chunk as cut; content adds the title prefix and
+    # metadata suffix that were embedded with it.
+    return fields.get("content_summary") or fields.get("content") or ""
+
+
+def stored_chunks(
+    fields_list: list[dict[str, Any]], tokenizer: Any
+) -> list[dict[str, Any]]:
+    chunks = []
+    for fields in sorted(fields_list, key=lambda f: f.get("chunk_id") or 0):
+        content = chunk_text(fields)
+        head, tail = edges(content)
+        chunks.append(
+            {
+                "chunk_id": fields.get("chunk_id"),
+                "chars": len(content),
+                "tokens": len(tokenizer.encode(content)),
+                "indexed_tokens": len(tokenizer.encode(fields.get("content") or "")),
+                "title": fields.get("title") or "",
+                "section_continuation": bool(fields.get("section_continuation")),
+                "links": sorted(set(links_of(fields.get("source_links")).values())),
+                "embeddings": embedding_dims(fields.get("embeddings")),
+                "title_embedding": bool(fields.get("title_embedding")),
+                "has_context": bool(
+                    fields.get("chunk_context") or fields.get("doc_summary")
+                ),
+                "metadata_suffix": fields.get("metadata_suffix") or "",
+                "blurb": fields.get("blurb") or "",
+                "head": head,
+                "tail": tail,
+            }
+        )
+    return chunks
+
+
+def rebuild_sections(fields_list: list[dict[str, Any]]) -> list[Any]:
+    from onyx.connectors.models import TextSection
+
+    sections: list[TextSection] = []
+    for fields in sorted(fields_list, key=lambda f: f.get("chunk_id") or 0):
+        content = chunk_text(fields)
+        links = links_of(fields.get("source_links"))
+        offsets = sorted(links) or [0]
+        if offsets[0] != 0:
+            offsets.insert(0, 0)
+        for i, offset in enumerate(offsets):
+            end = offsets[i + 1] if i + 1 < len(offsets) else len(content)
+            part = content[offset:end]
+            link = links.get(offset)
+            continues = i == 0 and fields.get("section_continuation") and sections
+            if continues:
+                sections[-1].text += part
+            elif part.strip():
+                sections.append(TextSection(text=part, link=link))
+    return sections
+
+
+def preview(
+    fields_list: list[dict[str, Any]],
+    doc_id: str,
+    multipass: bool,
+    tokenizer: Any,
+) -> list[dict[str, Any]]:
+    from onyx.configs.constants import DocumentSource
+    from onyx.connectors.models import IndexingDocument
+    from onyx.indexing.chunker import Chunker
+
+    first = min(fields_list, key=lambda f: f.get("chunk_id") or 0)
+    metadata = first.get("metadata") or "{}"
+    if isinstance(metadata, str):
+        metadata = json.loads(metadata)
+    sections = rebuild_sections(fields_list)
+    document = IndexingDocument(
+        id=doc_id,
+        source=DocumentSource(first.get("source_type")),
+        semantic_identifier=first.get("semantic_identifier") or doc_id,
+        title=first.get("title"),
+        metadata=metadata,
+        sections=sections,
+        processed_sections=sections,
+    )
+    chunker = Chunker(
+        tokenizer=tokenizer,
+        enable_multipass=multipass,
+        enable_large_chunks=False,
+        enable_contextual_rag=False,
+    )
+    out = []
+    for chunk in chunker.chunk([document]):
+        head, tail = edges(chunk.content)
+        out.append(
+            {
+                "chunk_id": chunk.chunk_id,
+                "chars": len(chunk.content),
+                "tokens": len(tokenizer.encode(chunk.content)),
+                "title": chunk.title_prefix.strip(),
+                "section_continuation": chunk.section_continuation,
+                "links": sorted(set((chunk.source_links or {}).values())),
+                "head": head,
+                "tail": tail,
+            }
+        )
+    return out
+
+
+def inspect(schema: str, doc_id: str, with_preview: bool) -> dict[str, Any]:
+    from onyx.configs.app_configs import ONYX_DISABLE_VESPA
+    from onyx.db.engine.sql_engine import get_session_with_current_tenant
+    from onyx.db.search_settings import get_current_search_settings
+    from onyx.document_index.document_index_utils import get_multipass_config
+    from onyx.document_index.interfaces_new import TenantState
+    from onyx.document_index.vespa.vespa_document_index import VespaDocumentIndex
+    from onyx.indexing.chunker import Chunker
+    from onyx.natural_language_processing.utils import get_tokenizer
+    from shared_configs.configs import MULTI_TENANT
+
+    if ONYX_DISABLE_VESPA:
+        raise RuntimeError("Vespa is disabled in this deployment")
+    schema = use_schema(schema)
+    with get_session_with_current_tenant() as db_session:
+        search_settings = get_current_search_settings(db_session)
+        index_name = search_settings.index_name
+        model_name = search_settings.model_name
+        tokenizer = get_tokenizer(model_name, search_settings.provider_type)
+        multipass = get_multipass_config(search_settings)
+    index = VespaDocumentIndex(
+        index_name=index_name,
+        tenant_state=TenantState(tenant_id=schema, multitenant=MULTI_TENANT),
+        large_chunks_enabled=multipass.enable_large_chunks,
+    )
+    print(f"Fetching chunks of {doc_id} from Vespa...", file=sys.stderr)
+    fields_list = index.get_raw_document_chunks(doc_id)
+    if not fields_list:
+        return {"status": "error", "message": f"Vespa has no chunks for {doc_id!r}"}
+
+    result: dict[str, Any] = {
+        "status": "success",
+        "document_id": doc_id,
+        "index_name": index_name,
+        "tokenizer": model_name,
+        "chunk_token_limit": Chunker(tokenizer=tokenizer).chunk_token_limit,
+        "chunks": stored_chunks(fields_list, tokenizer),
+    }
+    if with_preview:
+        print("Re-chunking with the current settings...", file=sys.stderr)
+        try:
+            result["preview"] = preview(
+                fields_list, doc_id, multipass.multipass_indexing, tokenizer
+            )
+        except Exception as e:
+            result["preview_error"] = f"{type(e).__name__}: {e}"
+    return result
+
+
+def main() -> None:
+    if len(sys.argv) not in (3, 4):
+        print(
+            json.dumps(
+                {
+                    "status": "error",
+                    "message": "Usage: python - <schema> <document id> [preview]",
+                }
+            )
+        )
+        sys.exit(1)
+
+    from onyx.db.engine.sql_engine import SqlEngine
+
+    SqlEngine.init_engine(pool_size=5, max_overflow=2)
+
+    try:
+        result = inspect(sys.argv[1], sys.argv[2], len(sys.argv) == 4)
+    except Exception as e:
+        print(f"Error: {e}", file=sys.stderr)
+        result = {"status": "error", "message": str(e)}
+    print(json.dumps(result))
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,298 @@
+// Package compare sends the same search and chat requests to two Onyx API
+// servers and measures how their results, latencies and answers differ, to
+// validate index rebuilds and model upgrades.
+package compare
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/api"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/eval"
+)
+
+// Options configures a comparison.
+type Options struct {
+	// K is how many of each side's top documents are compared.
+	K int
+	// Chat also asks each query in chat and compares the answers.
+	Chat    bool
+	Persona int
+	// Parallel is the number of queries run at once.
+	Parallel int
+	// OnResult, if set, is called as each query finishes.
+	OnResult func(Result)
+}
+
+// Side is one server's response to a query.
+type Side struct {
+	// Docs are the top K document IDs, best first.
+	Docs     []string `json:"docs"`
+	SearchMS int64    `json:"search_ms"`
+	Answer   string   `json:"answer,omitempty"`
+	ChatMS   int64    `json:"chat_ms,omitempty"`
+	Error    string   `json:"error,omitempty"`
+}
+
+// Result is a query and both servers' responses.
+type Result struct {
+	Query string `json:"query"`
+	A     Side   `json:"a"`
+	B     Side   `json:"b"`
+	// Overlap is the Jaccard similarity of the two top-K document sets.
+	Overlap float64 `json:"overlap"`
+	// AnswerSimilarity is the Jaccard similarity of the answers' word
+	// sets, unset without chat or when either side has no answer.
+	AnswerSimilarity *float64 `json:"answer_similarity,omitempty"`
+}
+
+// Same reports whether both sides returned the same documents in the same
+// order.
+func (r Result) Same() bool {
+	return slices.Equal(r.A.Docs, r.B.Docs)
+}
+
+// LoadQueries reads one query per line, ignoring blank lines and lines
+// starting with #.
+func LoadQueries(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	var queries []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line != "" && !strings.HasPrefix(line, "#") {
+			queries = append(queries, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if len(queries) == 0 {
+		return nil, fmt.Errorf("%s has no queries", path)
+	}
+	return queries, nil
+}
+
+// Run sends every query to a and b, both sides at the same time so that
+// load on one does not skew the other's latency.
+func Run(a, b *api.Client, queries []string, opts Options) []Result {
+	if opts.K < 1 {
+		opts.K = 10
+	}
+	if opts.Parallel < 1 {
+		opts.Parallel = 1
+	}
+	results := make([]Result, len(queries))
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, opts.Parallel)
+	for i, q := range queries {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			res := Result{Query: q}
+			var sides sync.WaitGroup
+			sides.Add(2)
+			go func() { defer sides.Done(); res.A = query(a, q, opts) }()
+			go func() { defer sides.Done(); res.B = query(b, q, opts) }()
+			sides.Wait()
+
+			res.Overlap = Overlap(res.A.Docs, res.B.Docs)
+			if res.A.Answer != "" && res.B.Answer != "" {
+				sim := TextSimilarity(res.A.Answer, res.B.Answer)
+				res.AnswerSimilarity = &sim
+			}
+			results[i] = res
+			if opts.OnResult != nil {
+				mu.Lock()
+				opts.OnResult(res)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+func query(client *api.Client, q string, opts Options) Side {
+	var s Side
+	var problems []string
+	start := time.Now()
+	docs, err := eval.Search(client, q)
+	s.SearchMS = time.Since(start).Milliseconds()
+	if err != nil {
+		problems = append(problems, "search: "+err.Error())
+	}
+	s.Docs = docs[:min(opts.K, len(docs))]
+
+	if opts.Chat {
+		start := time.Now()
+		s.Answer, err = eval.Chat(client, q, opts.Persona)
+		s.ChatMS = time.Since(start).Milliseconds()
+		if err != nil {
+			problems = append(problems, "chat: "+err.Error())
+		}
+	}
+	s.Error = strings.Join(problems, "; ")
+	return s
+}
+
+// Overlap is |a ∩ b| / |a ∪ b|, 1 when both are empty.
+func Overlap(a, b []string) float64 {
+	union := map[string]bool{}
+	for _, id := range a {
+		union[id] = true
+	}
+	shared := 0
+	for _, id := range b {
+		if union[id] {
+			shared++
+		} else {
+			union[id] = true
+		}
+	}
+	if len(union) == 0 {
+		return 1
+	}
+	return float64(shared) / float64(len(union))
+}
+
+// TextSimilarity is the Overlap of the lowercased word sets of a and b, a
+// rough measure of whether two answers say the same thing.
+func TextSimilarity(a, b string) float64 {
+	words := func(s string) []string {
+		var out []string
+		for _, w := range strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+			return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9' || r > 127)
+		}) {
+			if !slices.Contains(out, w) {
+				out = append(out, w)
+			}
+		}
+		return out
+	}
+	return Overlap(words(a), words(b))
+}
+
+// Row is one rank of the side-by-side document lists.
+type Row struct {
+	Rank int
+	A, B string
+	// AInB and BInA are the rank of the document in the other list, 0 if
+	// it is not there.
+	AInB, BInA int
+}
+
+// Rows lines up the two sides' documents by rank.
+func (r Result) Rows() []Row {
+	rows := make([]Row, max(len(r.A.Docs), len(r.B.Docs)))
+	for i := range rows {
+		rows[i].Rank = i + 1
+		if i < len(r.A.Docs) {
+			rows[i].A = r.A.Docs[i]
+			rows[i].AInB = slices.Index(r.B.Docs, r.A.Docs[i]) + 1
+		}
+		if i < len(r.B.Docs) {
+			rows[i].B = r.B.Docs[i]
+			rows[i].BInA = slices.Index(r.A.Docs, r.B.Docs[i]) + 1
+		}
+	}
+	return rows
+}
+
+// Latency is a latency distribution in milliseconds.
+type Latency struct {
+	P50 int64 `json:"p50_ms"`
+	P95 int64 `json:"p95_ms"`
+}
+
+// Summary aggregates a comparison.
+type Summary struct {
+	Queries   int `json:"queries"`
+	Identical int `json:"identical"`
+	// ErrorsA and ErrorsB count queries that failed on each side.
+	ErrorsA int `json:"errors_a"`
+	ErrorsB int `json:"errors_b"`
+	// MeanOverlap and MeanAnswerSimilarity are averaged over queries both
+	// sides answered.
+	MeanOverlap          float64  `json:"mean_overlap"`
+	MeanAnswerSimilarity *float64 `json:"mean_answer_similarity,omitempty"`
+	SearchA              Latency  `json:"search_a"`
+	SearchB              Latency  `json:"search_b"`
+	ChatA                *Latency `json:"chat_a,omitempty"`
+	ChatB                *Latency `json:"chat_b,omitempty"`
+}
+
+// Summarize aggregates results.
+func Summarize(results []Result) Summary {
+	s := Summary{Queries: len(results)}
+	var searchA, searchB, chatA, chatB []int64
+	compared, answered := 0, 0
+	var answerSim float64
+	for _, r := range results {
+		if r.A.Error != "" {
+			s.ErrorsA++
+		}
+		if r.B.Error != "" {
+			s.ErrorsB++
+		}
+		searchA, searchB = append(searchA, r.A.SearchMS), append(searchB, r.B.SearchMS)
+		if r.A.ChatMS > 0 {
+			chatA = append(chatA, r.A.ChatMS)
+		}
+		if r.B.ChatMS > 0 {
+			chatB = append(chatB, r.B.ChatMS)
+		}
+		if r.A.Error == "" && r.B.Error == "" {
+			compared++
+			s.MeanOverlap += r.Overlap
+			if r.Same() {
+				s.Identical++
+			}
+		}
+		if r.AnswerSimilarity != nil {
+			answered++
+			answerSim += *r.AnswerSimilarity
+		}
+	}
+	if compared > 0 {
+		s.MeanOverlap /= float64(compared)
+	}
+	if answered > 0 {
+		mean := answerSim / float64(answered)
+		s.MeanAnswerSimilarity = &mean
+	}
+	s.SearchA, s.SearchB = latency(searchA), latency(searchB)
+	if len(chatA) > 0 {
+		l := latency(chatA)
+		s.ChatA = &l
+	}
+	if len(chatB) > 0 {
+		l := latency(chatB)
+		s.ChatB = &l
+	}
+	return s
+}
+
+func latency(ms []int64) Latency {
+	if len(ms) == 0 {
+		return Latency{}
+	}
+	slices.Sort(ms)
+	at := func(p float64) int64 {
+		return ms[min(len(ms)-1, int(p*float64(len(ms))))]
+	}
+	return Latency{P50: at(0.5), P95: at(0.95)}
+}
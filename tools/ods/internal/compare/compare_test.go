@@ -0,0 +1,62 @@
+package compare
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestOverlap(t *testing.T) {
+	if got := Overlap([]string{"a", "b", "c"}, []string{"b", "c", "d"}); got != 0.5 {
+		t.Errorf("Overlap() = %v, want 0.5", got)
+	}
+	if got := Overlap(nil, nil); got != 1 {
+		t.Errorf("Overlap(nil, nil) = %v, want 1", got)
+	}
+	if got := TextSimilarity("Use the Forgot password link.", "use the forgot-password link"); got != 1 {
+		t.Errorf("TextSimilarity() = %v, want 1", got)
+	}
+}
+
+func TestRows(t *testing.T) {
+	r := Result{A: Side{Docs: []string{"a", "b", "c"}}, B: Side{Docs: []string{"b", "a"}}}
+	rows := r.Rows()
+	if len(rows) != 3 {
+		t.Fatalf("got %d rows, want 3", len(rows))
+	}
+	if rows[0].AInB != 2 || rows[0].BInA != 2 || rows[2].B != "" || rows[2].AInB != 0 {
+		t.Errorf("unexpected rows %+v", rows)
+	}
+	if r.Same() {
+		t.Error("Same() = true for reordered results")
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	sim := 0.5
+	s := Summarize([]Result{
+		{A: Side{Docs: []string{"a"}, SearchMS: 100}, B: Side{Docs: []string{"a"}, SearchMS: 300}, Overlap: 1},
+		{A: Side{Docs: []string{"a"}, SearchMS: 200}, B: Side{Docs: []string{"b"}, SearchMS: 400}, Overlap: 0, AnswerSimilarity: &sim},
+		{A: Side{SearchMS: 50, Error: "search: 500"}, B: Side{SearchMS: 500}},
+	})
+	if s.Queries != 3 || s.Identical != 1 || s.ErrorsA != 1 || s.MeanOverlap != 0.5 {
+		t.Errorf("unexpected summary %+v", s)
+	}
+	if s.SearchA.P50 != 100 || s.SearchB.P95 != 500 || s.ChatA != nil {
+		t.Errorf("unexpected latencies %+v %+v", s.SearchA, s.SearchB)
+	}
+	if s.MeanAnswerSimilarity == nil || *s.MeanAnswerSimilarity != 0.5 {
+		t.Errorf("MeanAnswerSimilarity = %v", s.MeanAnswerSimilarity)
+	}
+}
+
+func TestLoadQueries(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queries.txt")
+	if err := os.WriteFile(path, []byte("# smoke set\nreset password\n\n  sso setup  \n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	queries, err := LoadQueries(path)
+	if err != nil || len(queries) != 2 || queries[1] != "sso setup" {
+		t.Errorf("LoadQueries() = %q, %v", queries, err)
+	}
+}
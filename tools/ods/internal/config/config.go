@@ -22,12 +22,152 @@ type DeployCommandConfig struct {
 	TargetWorkflow string `json:"target_workflow,omitempty"`
 }
 
+// WhoisConfig holds settings for `ods whois`.
+type WhoisConfig struct {
+	// Cache enables the encrypted local lookup cache without passing --cache.
+	Cache bool `json:"cache,omitempty"`
+}
+
+// NotifyConfig holds settings for --notify on long-running commands.
+type NotifyConfig struct {
+	// Default is the target used when --notify is not passed (e.g.
+	// "slack:#oncall"); empty disables notifications by default.
+	Default string `json:"default,omitempty"`
+	// SlackWebhooks maps channels (e.g. "#oncall") to incoming webhook URLs.
+	// The "default" key is used for channels without their own webhook.
+	SlackWebhooks map[string]string `json:"slack_webhooks,omitempty"`
+}
+
+// TicketsConfig holds settings for --ticket on commands that touch shared
+// environments. Credentials come from the environment: JIRA_EMAIL and
+// JIRA_API_TOKEN, or LINEAR_API_KEY.
+type TicketsConfig struct {
+	// Provider is "jira" or "linear"; empty records tickets in the audit log
+	// without commenting on them.
+	Provider string `json:"provider,omitempty"`
+	// JiraURL is the Jira site, e.g. https://onyx.atlassian.net.
+	JiraURL string `json:"jira_url,omitempty"`
+	// Require refuses audited actions that have no --ticket.
+	Require bool `json:"require,omitempty"`
+}
+
+// SessionsConfig holds settings for time-boxed production access sessions
+// (`ods session`).
+type SessionsConfig struct {
+	// MaxTTL caps --ttl, as a Go duration (e.g. "4h"); empty means 4h.
+	MaxTTL string `json:"max_ttl,omitempty"`
+	// Notify is where session starts, ends and the commands run in them are
+	// posted (e.g. "slack:#security"); empty posts nothing.
+	Notify string `json:"notify,omitempty"`
+}
+
+// I18nConfig holds settings for `ods i18n sync`. The Crowdin token comes
+// from CROWDIN_API_TOKEN.
+type I18nConfig struct {
+	// Provider is "crowdin"; empty disables sync.
+	Provider string `json:"provider,omitempty"`
+	// CrowdinProjectID is the numeric ID of the Crowdin project.
+	CrowdinProjectID int `json:"crowdin_project_id,omitempty"`
+	// CrowdinURL overrides the API base URL, for Crowdin Enterprise.
+	CrowdinURL string `json:"crowdin_url,omitempty"`
+}
+
+// PreviewConfig holds settings for `ods pr deploy`, which deploys pull
+// requests to ephemeral namespaces in the dev cluster.
+type PreviewConfig struct {
+	// Context is the KUBE_CTX_<NAME> cluster previews deploy to; empty
+	// means "dev".
+	Context string `json:"context,omitempty"`
+	// Registry is where preview images are pushed, e.g.
+	// 123456789012.dkr.ecr.us-east-2.amazonaws.com/onyx-preview.
+	Registry string `json:"registry,omitempty"`
+	// Domain is the wildcard DNS zone previews are served from: PR 123 is
+	// at https://onyx-pr-123.<domain>.
+	Domain string `json:"domain,omitempty"`
+	// IngressClass is the cluster's ingress class; empty uses the chart's
+	// default (nginx).
+	IngressClass string `json:"ingress_class,omitempty"`
+	// ValuesFiles are extra helm values files, relative to the repository
+	// root, e.g. to size previews down.
+	ValuesFiles []string `json:"values_files,omitempty"`
+}
+
+// BackupsConfig holds settings for `ods verify-backups`.
+type BackupsConfig struct {
+	// MaxAge is the default recency policy, as a Go duration: the newest
+	// backup of every source must be younger. Empty means 26h, a daily
+	// backup with some slack.
+	MaxAge string `json:"max_age,omitempty"`
+	// Environments maps a KUBE_CTX_<NAME> context name to its backup
+	// sources.
+	Environments map[string][]BackupSource `json:"environments,omitempty"`
+}
+
+// BackupSource is where one kind of backup of an environment is kept.
+type BackupSource struct {
+	// Name labels the source, e.g. "postgres", "vespa" or "file-store".
+	Name string `json:"name"`
+	// Type is "rds" (snapshots of a DB instance), "rds-cluster" (of an
+	// Aurora cluster), "ebs" (volume snapshots) or "s3" (objects under a
+	// prefix).
+	Type string `json:"type"`
+	// ID is the DB instance or cluster identifier, the tag=value EBS
+	// snapshots are tagged with, or the s3://bucket/prefix.
+	ID string `json:"id"`
+	// MaxAge overrides the default recency policy for this source.
+	MaxAge string `json:"max_age,omitempty"`
+}
+
+// TelemetryConfig holds the opt-in for anonymous ods usage telemetry
+// (`ods telemetry`).
+type TelemetryConfig struct {
+	// Enabled is set by `ods telemetry on`; telemetry is off by default.
+	Enabled bool `json:"enabled,omitempty"`
+	// InstallID is a random ID grouping one installation's events. It is
+	// not derived from anything about the user or machine.
+	InstallID string `json:"install_id,omitempty"`
+	// Endpoint overrides where events are sent.
+	Endpoint string `json:"endpoint,omitempty"`
+}
+
+// AskConfig holds the LLM `ods ask` plans with. The API key comes from
+// ODS_ASK_API_KEY or OPENAI_API_KEY.
+type AskConfig struct {
+	// URL is the base URL of an OpenAI-compatible API; empty means OpenAI.
+	URL string `json:"url,omitempty"`
+	// Model is the chat model; empty means gpt-4.1.
+	Model string `json:"model,omitempty"`
+	// Context is the KUBE_CTX_<NAME> cluster questions are about by
+	// default; empty leaves each command's default.
+	Context string `json:"context,omitempty"`
+}
+
+// AliasConfig is a user-defined command (`ods alias`): an alias when it has
+// one step, a macro when it has several.
+type AliasConfig struct {
+	// Steps are ods command lines without the leading "ods", e.g.
+	// "whois --context prod", run in order. $1..$9 and $@ are replaced by
+	// the arguments the alias is run with.
+	Steps       []string `json:"steps"`
+	Description string   `json:"description,omitempty"`
+}
+
 // Config is the top-level on-disk schema for ~/.config/onyx-dev/config.json.
 // New per-command sections should be added as additional fields.
 type Config struct {
-	Deploy     DeployConfig        `json:"deploy,omitempty"`
-	DeployEdge DeployCommandConfig `json:"deploy_edge,omitempty"`
-	DeployWiki DeployCommandConfig `json:"deploy_wiki,omitempty"`
+	Deploy     DeployConfig           `json:"deploy,omitempty"`
+	DeployEdge DeployCommandConfig    `json:"deploy_edge,omitempty"`
+	DeployWiki DeployCommandConfig    `json:"deploy_wiki,omitempty"`
+	Whois      WhoisConfig            `json:"whois,omitempty"`
+	Notify     NotifyConfig           `json:"notify,omitempty"`
+	Tickets    TicketsConfig          `json:"tickets,omitempty"`
+	Sessions   SessionsConfig         `json:"sessions,omitempty"`
+	I18n       I18nConfig             `json:"i18n,omitempty"`
+	Preview    PreviewConfig          `json:"preview,omitempty"`
+	Backups    BackupsConfig          `json:"backups,omitempty"`
+	Telemetry  TelemetryConfig        `json:"telemetry,omitempty"`
+	Ask        AskConfig              `json:"ask,omitempty"`
+	Aliases    map[string]AliasConfig `json:"aliases,omitempty"`
 }
 
 // Load reads the config file. Returns a zero-valued Config if the file does
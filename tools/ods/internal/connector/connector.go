@@ -0,0 +1,178 @@
+// Package connector runs a connector's fetch logic in the local backend
+// container against supplied credentials and streams back what it extracts,
+// without creating any database state.
+package connector
+
+import (
+	"bufio"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/docker"
+)
+
+//go:embed run.py
+var runScript string
+
+// requestEnv is the environment variable the request JSON is passed in, so
+// credentials stay out of the process arguments.
+const requestEnv = "ODS_CONNECTOR_REQUEST"
+
+// Modes are the ways a connector can be driven; "auto" picks the one the
+// indexing worker would use.
+var Modes = []string{"auto", "checkpoint", "load", "poll"}
+
+// Config is a connector config file: the connector-specific settings (as
+// stored in connector_specific_config) and the credential JSON.
+type Config struct {
+	Connector   map[string]any `json:"connector"`
+	Credentials map[string]any `json:"credentials"`
+}
+
+// LoadConfig reads a Config from a JSON file.
+func LoadConfig(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var cfg Config
+	if err := json.Unmarshal(data, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	if cfg.Connector == nil {
+		cfg.Connector = map[string]any{}
+	}
+	if cfg.Credentials == nil {
+		cfg.Credentials = map[string]any{}
+	}
+	return &cfg, nil
+}
+
+// Options configures a run.
+type Options struct {
+	Source    string // DocumentSource value, e.g. "web" or "github"
+	Config    Config
+	Limit     int       // stop after this many documents; 0 for no limit
+	Mode      string    // one of Modes
+	Since     time.Time // start of the window for checkpointed and polled connectors; zero for all time
+	TextChars int       // characters of each document's text to return
+}
+
+// Item is a document, failure or hierarchy node the connector produced.
+type Item struct {
+	Type string `json:"type"` // "document", "failure" or "hierarchy_node"
+
+	// Documents and hierarchy nodes.
+	ID string `json:"id"`
+
+	// Documents.
+	SemanticIdentifier string         `json:"semantic_identifier"`
+	Title              *string        `json:"title"`
+	Link               *string        `json:"link"`
+	UpdatedAt          *string        `json:"updated_at"`
+	Sections           int            `json:"sections"`
+	Chars              int            `json:"chars"`
+	Metadata           map[string]any `json:"metadata"`
+	Text               string         `json:"text"`
+
+	// Failures.
+	DocumentID *string `json:"document_id"`
+	EntityID   *string `json:"entity_id"`
+	Message    string  `json:"message"`
+
+	// Hierarchy nodes.
+	ParentID *string `json:"parent_id"`
+	Name     string  `json:"name"`
+}
+
+// Summary is the outcome of a run.
+type Summary struct {
+	Documents      int     `json:"documents"`
+	Failures       int     `json:"failures"`
+	HierarchyNodes int     `json:"hierarchy_nodes"`
+	Truncated      bool    `json:"truncated"`
+	ElapsedSeconds float64 `json:"elapsed_seconds"`
+}
+
+// Run runs the connector in container, calling onItem for each item as it
+// is produced.
+func Run(container string, opts Options, onItem func(Item)) (*Summary, error) {
+	var start int64
+	if !opts.Since.IsZero() {
+		start = opts.Since.Unix()
+	}
+	request, err := json.Marshal(map[string]any{
+		"source":      opts.Source,
+		"connector":   opts.Config.Connector,
+		"credentials": opts.Config.Credentials,
+		"limit":       opts.Limit,
+		"mode":        opts.Mode,
+		"start":       start,
+		"text_chars":  opts.TextChars,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal request: %w", err)
+	}
+
+	pr, pw := io.Pipe()
+	runErr := make(chan error, 1)
+	go func() {
+		err := docker.RunPython(container, runScript, map[string]string{requestEnv: string(request)}, pw, "run")
+		_ = pw.Close()
+		runErr <- err
+	}()
+
+	summary, scanErr := scan(pr, onItem)
+	// Drain what is left so the script is not blocked writing.
+	_, _ = io.Copy(io.Discard, pr)
+	if err := <-runErr; err != nil && errors.Is(scanErr, errNoResult) {
+		return nil, fmt.Errorf("connector run failed: %w", err)
+	}
+	return summary, scanErr
+}
+
+var errNoResult = errors.New("connector script exited without a result")
+
+// scan reads the script's output, passing items to onItem and returning the
+// summary from the final status line.
+func scan(r io.Reader, onItem func(Item)) (*Summary, error) {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		var head struct {
+			Type    string `json:"type"`
+			Status  string `json:"status"`
+			Message string `json:"message"`
+		}
+		if err := json.Unmarshal(line, &head); err != nil {
+			// Connectors and libraries sometimes print to stdout.
+			continue
+		}
+		switch {
+		case head.Status == "success":
+			var s Summary
+			if err := json.Unmarshal(line, &s); err != nil {
+				return nil, fmt.Errorf("unexpected summary from connector script: %q", line)
+			}
+			return &s, nil
+		case head.Status != "":
+			return nil, fmt.Errorf("%s", head.Message)
+		case head.Type != "":
+			var item Item
+			if err := json.Unmarshal(line, &item); err != nil {
+				return nil, fmt.Errorf("unexpected output from connector script: %q", line)
+			}
+			onItem(item)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, errNoResult
+}
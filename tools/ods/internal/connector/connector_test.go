@@ -0,0 +1,68 @@
+package connector
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestScan(t *testing.T) {
+	out := strings.Join([]string{
+		`Downloading something...`,
+		`{"type": "hierarchy_node", "id": "space-1", "parent_id": null, "name": "Engineering"}`,
+		`{"type": "document", "id": "doc-1", "semantic_identifier": "Runbook", "title": null, "link": "https://wiki/runbook", "updated_at": "2026-01-02T03:04:05+00:00", "sections": 2, "chars": 120, "metadata": {"tags": ["ops"]}, "text": "Step one"}`,
+		`{"type": "failure", "document_id": "doc-2", "entity_id": null, "message": "403 Forbidden"}`,
+		`{"status": "success", "documents": 1, "failures": 1, "hierarchy_nodes": 1, "truncated": true, "elapsed_seconds": 1.5}`,
+	}, "\n")
+
+	var items []Item
+	summary, err := scan(strings.NewReader(out), func(item Item) { items = append(items, item) })
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(items) != 3 {
+		t.Fatalf("got %d items, want 3", len(items))
+	}
+	if items[0].Type != "hierarchy_node" || items[0].Name != "Engineering" {
+		t.Errorf("unexpected hierarchy node %+v", items[0])
+	}
+	doc := items[1]
+	if doc.ID != "doc-1" || doc.Link == nil || *doc.Link != "https://wiki/runbook" || doc.Sections != 2 || doc.Text != "Step one" {
+		t.Errorf("unexpected document %+v", doc)
+	}
+	if items[2].DocumentID == nil || *items[2].DocumentID != "doc-2" || items[2].Message != "403 Forbidden" {
+		t.Errorf("unexpected failure %+v", items[2])
+	}
+	if summary.Documents != 1 || !summary.Truncated || summary.ElapsedSeconds != 1.5 {
+		t.Errorf("unexpected summary %+v", summary)
+	}
+}
+
+func TestScanError(t *testing.T) {
+	out := `{"type": "document", "id": "doc-1"}` + "\n" + `{"status": "error", "message": "ValueError: Unknown connector type 'nope'"}`
+	_, err := scan(strings.NewReader(out), func(Item) {})
+	if err == nil || !strings.Contains(err.Error(), "Unknown connector type") {
+		t.Errorf("got %v, want the script's error", err)
+	}
+
+	_, err = scan(strings.NewReader(`{"type": "document", "id": "doc-1"}`), func(Item) {})
+	if !errors.Is(err, errNoResult) {
+		t.Errorf("got %v, want errNoResult", err)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "web.json")
+	if err := os.WriteFile(path, []byte(`{"connector": {"base_url": "https://docs.onyx.app", "web_connector_type": "single"}}`), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cfg, err := LoadConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Connector["base_url"] != "https://docs.onyx.app" || cfg.Credentials == nil {
+		t.Errorf("unexpected config %+v", cfg)
+	}
+}
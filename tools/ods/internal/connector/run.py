@@ -0,0 +1,209 @@
+"""Run a connector's fetch logic without touching the database.
+
+Bundled with ods and piped into `python -` in the local api_server container
+by `ods connector run`. The connector is built from the supplied config and
+credentials (never from a stored credential), then driven the way the
+indexing worker drives it: checkpointed connectors page through checkpoints,
+others use load_from_state or poll_source. Documents are printed, not
+indexed, and the run stops after --limit documents.
+
+Usage:
+    ODS_CONNECTOR_REQUEST='<json>' python - run
+
+The request is {"source", "connector", "credentials", "limit", "mode",
+"start", "text_chars"}; mode is "auto", "checkpoint", "load" or "poll" and
+start is a Unix timestamp for checkpointed and polled connectors.
+
+Progress goes to stderr. Each document, failure and hierarchy node is one
+JSON line on stdout ("type" is "document", "failure" or "hierarchy_node");
+the last line is a JSON object with "status" and the run's counts.
+"""
+
+from __future__ import annotations
+
+import json
+import os
+import sys
+import time
+from collections.abc import Iterator
+from typing import Any
+
+# Stop paging a checkpointed connector that keeps returning no documents.
+MAX_EMPTY_CHECKPOINTS = 50
+
+
+def emit(obj: dict[str, Any]) -> None:
+    print(json.dumps(obj, default=str), flush=True)
+
+
+def document_line(doc: Any, text_chars: int) -> dict[str, Any]:
+    text = "\n".join(
+        section.text for section in doc.sections if getattr(section, "text", None)
+    )
+    link = next((s.link for s in doc.sections if s.link), None)
+    return {
+        "type": "document",
+        "id": doc.id,
+        "semantic_identifier": doc.semantic_identifier,
+        "title": doc.title,
+        "link": link,
+        "updated_at": (
+            doc.doc_updated_at.isoformat() if doc.doc_updated_at else None
+        ),
+        "sections": len(doc.sections),
+        "chars": len(text),
+        "metadata": doc.metadata,
+        "text": text[:text_chars],
+    }
+
+
+def failure_line(failure: Any) -> dict[str, Any]:
+    document = failure.failed_document
+    entity = failure.failed_entity
+    return {
+        "type": "failure",
+        "document_id": document.document_id if document else None,
+        "entity_id": entity.entity_id if entity else None,
+        "message": failure.failure_message,
+    }
+
+
+def build_connector(request: dict[str, Any]) -> Any:
+    from onyx.configs.constants import DocumentSource
+    from onyx.connectors.credentials_provider import OnyxStaticCredentialsProvider
+    from onyx.connectors.factory import identify_connector_class
+    from onyx.connectors.interfaces import CredentialsConnector
+    from shared_configs.configs import POSTGRES_DEFAULT_SCHEMA
+    from shared_configs.contextvars import CURRENT_TENANT_ID_CONTEXTVAR
+
+    try:
+        source = DocumentSource(request["source"])
+    except ValueError:
+        known = ", ".join(sorted(s.value for s in DocumentSource))
+        raise ValueError(
+            f"Unknown connector type {request['source']!r} (known: {known})"
+        )
+
+    CURRENT_TENANT_ID_CONTEXTVAR.set(POSTGRES_DEFAULT_SCHEMA)
+    connector = identify_connector_class(source)(**request["connector"])
+    credentials = request["credentials"]
+    if isinstance(connector, CredentialsConnector):
+        connector.set_credentials_provider(
+            OnyxStaticCredentialsProvider(
+                POSTGRES_DEFAULT_SCHEMA, source.value, credentials
+            )
+        )
+    elif connector.load_credentials(credentials) is not None:
+        print(
+            "The connector refreshed its credentials; the new ones are not saved",
+            file=sys.stderr,
+        )
+    connector.set_allow_images(False)
+    return connector
+
+
+def outputs(connector: Any, mode: str, start: float) -> Iterator[Any]:
+    """Yields documents, hierarchy nodes and failures the way the indexing
+    worker would receive them."""
+    from onyx.connectors.connector_runner import CheckpointOutputWrapper
+    from onyx.connectors.interfaces import CheckpointedConnector
+    from onyx.connectors.interfaces import LoadConnector
+    from onyx.connectors.interfaces import PollConnector
+
+    end = time.time()
+    if mode == "auto":
+        if isinstance(connector, CheckpointedConnector):
+            mode = "checkpoint"
+        elif isinstance(connector, LoadConnector):
+            mode = "load"
+        else:
+            mode = "poll"
+    print(f"Running {type(connector).__name__} ({mode})", file=sys.stderr)
+
+    if mode == "checkpoint":
+        checkpoint = connector.build_dummy_checkpoint()
+        empty = 0
+        while checkpoint.has_more and empty < MAX_EMPTY_CHECKPOINTS:
+            produced = False
+            for doc, node, failure, next_checkpoint in CheckpointOutputWrapper()(
+                connector.load_from_checkpoint(start, end, checkpoint)
+            ):
+                for item in (doc, node, failure):
+                    if item is not None:
+                        produced = True
+                        yield item
+                if next_checkpoint is not None:
+                    checkpoint = next_checkpoint
+            empty = 0 if produced else empty + 1
+        return
+
+    if mode == "load":
+        if not isinstance(connector, LoadConnector):
+            raise ValueError(f"{type(connector).__name__} does not support load")
+        batches = connector.load_from_state()
+    else:
+        if not isinstance(connector, PollConnector):
+            raise ValueError(f"{type(connector).__name__} does not support poll")
+        batches = connector.poll_source(start, end)
+    for batch in batches:
+        yield from batch
+
+
+def run(request: dict[str, Any]) -> dict[str, Any]:
+    from onyx.connectors.models import ConnectorFailure
+    from onyx.connectors.models import Document
+    from onyx.connectors.models import HierarchyNode
+
+    connector = build_connector(request)
+    connector.validate_connector_settings()
+
+    counts = {"documents": 0, "failures": 0, "hierarchy_nodes": 0}
+    limit = request["limit"]
+    truncated = False
+    started = time.monotonic()
+    for item in outputs(connector, request["mode"], request["start"]):
+        if isinstance(item, Document):
+            if limit and counts["documents"] >= limit:
+                truncated = True
+                break
+            counts["documents"] += 1
+            emit(document_line(item, request["text_chars"]))
+        elif isinstance(item, ConnectorFailure):
+            counts["failures"] += 1
+            emit(failure_line(item))
+        elif isinstance(item, HierarchyNode):
+            counts["hierarchy_nodes"] += 1
+            emit(
+                {
+                    "type": "hierarchy_node",
+                    "id": item.raw_node_id,
+                    "parent_id": item.raw_parent_id,
+                    "name": item.display_name,
+                }
+            )
+    return {
+        "status": "success",
+        **counts,
+        "truncated": truncated,
+        "elapsed_seconds": round(time.monotonic() - started, 3),
+    }
+
+
+def main() -> None:
+    usage = "Usage: ODS_CONNECTOR_REQUEST='<json>' python - run"
+    args = sys.argv[1:]
+    request_json = os.environ.get("ODS_CONNECTOR_REQUEST")
+    if args != ["run"] or not request_json:
+        emit({"status": "error", "message": usage})
+        sys.exit(1)
+
+    try:
+        result = run(json.loads(request_json))
+    except Exception as e:
+        print(f"Error: {type(e).__name__}: {e}", file=sys.stderr)
+        result = {"status": "error", "message": f"{type(e).__name__}: {e}"}
+    emit(result)
+
+
+if __name__ == "__main__":
+    main()
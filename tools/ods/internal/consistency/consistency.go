@@ -0,0 +1,303 @@
+// Package consistency cross-references the tenants the control plane knows
+// about against the tenant schemas that exist in each data plane, to find
+// tenants orphaned in either direction.
+package consistency
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/kube"
+)
+
+//go:embed control_plane_tenants.py
+var tenantsScript string
+
+// Finding kinds.
+const (
+	// KindOrphanSchema is a tenant schema the control plane has no tenant for.
+	KindOrphanSchema = "orphan-schema"
+	// KindStaleCopy is a tenant schema in a data plane other than the one the
+	// tenant is routed to, which has its own copy: typically the source of a
+	// finished migration.
+	KindStaleCopy = "stale-copy"
+	// KindMisrouted is a tenant routed to a data plane without its schema
+	// while another data plane has it.
+	KindMisrouted = "misrouted"
+	// KindMissingSchema is a control-plane tenant with no schema in any
+	// checked data plane.
+	KindMissingSchema = "missing-schema"
+	// KindOrphanUsers is user_tenant_mapping rows in a data plane that has no
+	// schema for the tenant and is not where the tenant is routed.
+	KindOrphanUsers = "orphan-users"
+)
+
+// Tenant is a tenant registered in the control plane.
+type Tenant struct {
+	ID string `json:"tenant_id"`
+	// Route is the value of the route column: the data plane the tenant is
+	// served from.
+	Route string `json:"route"`
+	// Created is zero when the control plane does not record it.
+	Created time.Time `json:"created"`
+}
+
+// ControlPlaneTenants lists the control plane's tenants with the value of
+// their route column, by running a script on a control-plane pod.
+func ControlPlaneTenants(c *kube.Cluster, pod, column string) ([]Tenant, error) {
+	out, err := c.RunPython(pod, tenantsScript, column)
+	if err != nil {
+		return nil, err
+	}
+	return parseTenants(out)
+}
+
+func parseTenants(stdout string) ([]Tenant, error) {
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	last := strings.TrimSpace(lines[len(lines)-1])
+	var r struct {
+		Status  string `json:"status"`
+		Message string `json:"message"`
+		Tenants []struct {
+			ID      string `json:"tenant_id"`
+			Route   string `json:"route"`
+			Created string `json:"created"`
+		} `json:"tenants"`
+	}
+	if err := json.Unmarshal([]byte(last), &r); err != nil {
+		return nil, fmt.Errorf("unexpected output from tenants script: %q", last)
+	}
+	if r.Status != "success" {
+		return nil, fmt.Errorf("%s", r.Message)
+	}
+	tenants := make([]Tenant, 0, len(r.Tenants))
+	for _, t := range r.Tenants {
+		tenant := Tenant{ID: t.ID, Route: t.Route}
+		if t.Created != "" {
+			created, err := parseCreated(t.Created)
+			if err != nil {
+				return nil, fmt.Errorf("tenant %s: %w", t.ID, err)
+			}
+			tenant.Created = created
+		}
+		tenants = append(tenants, tenant)
+	}
+	return tenants, nil
+}
+
+// parseCreated parses a Python isoformat timestamp, which has no zone when
+// the column is a timestamp without time zone; those are taken as UTC.
+func parseCreated(s string) (time.Time, error) {
+	for _, layout := range []string{time.RFC3339Nano, "2006-01-02T15:04:05.999999999"} {
+		if t, err := time.Parse(layout, s); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("unexpected created_at %q", s)
+}
+
+// DataPlaneSQL lists a data plane's tenant schemas and how many
+// user_tenant_mapping rows each tenant ID has, one "schema\t<name>\t0" or
+// "users\t<tenant_id>\t<count>" line each.
+const DataPlaneSQL = `SELECT 'schema', nspname, 0 FROM pg_namespace WHERE nspname LIKE 'tenant\_%'
+UNION ALL
+SELECT 'users', tenant_id, count(*) FROM public.user_tenant_mapping GROUP BY tenant_id`
+
+// DataPlane is what one data plane holds.
+type DataPlane struct {
+	// Context is the data plane's cluster context.
+	Context string
+	// Route is the route-column value that selects this data plane.
+	Route string
+	// Schemas are its tenant schemas.
+	Schemas map[string]bool
+	// Users is the number of user_tenant_mapping rows per tenant ID.
+	Users map[string]int
+}
+
+// ParseDataPlane parses the output of DataPlaneSQL into a DataPlane.
+func ParseDataPlane(ctx, route string, lines []string) (*DataPlane, error) {
+	dp := &DataPlane{Context: ctx, Route: route, Schemas: map[string]bool{}, Users: map[string]int{}}
+	for _, line := range lines {
+		parts := strings.Split(line, "\t")
+		if len(parts) != 3 {
+			return nil, fmt.Errorf("unexpected data-plane line: %q", line)
+		}
+		switch parts[0] {
+		case "schema":
+			dp.Schemas[parts[1]] = true
+		case "users":
+			n, err := strconv.Atoi(parts[2])
+			if err != nil {
+				return nil, fmt.Errorf("unexpected user count in %q", line)
+			}
+			dp.Users[parts[1]] = n
+		default:
+			return nil, fmt.Errorf("unexpected data-plane line: %q", line)
+		}
+	}
+	return dp, nil
+}
+
+// Finding is one inconsistency.
+type Finding struct {
+	Kind   string `json:"kind"`
+	Tenant string `json:"tenant"`
+	// DataPlane is the context the finding is about, or "" for a tenant that
+	// is in none.
+	DataPlane string `json:"data_plane"`
+	Detail    string `json:"detail"`
+	// Cleanup is the ods command that resolves the finding, when one does.
+	Cleanup string `json:"cleanup,omitempty"`
+}
+
+// PlaneSummary is what a report saw in one data plane.
+type PlaneSummary struct {
+	Context string `json:"context"`
+	Route   string `json:"route"`
+	Schemas int    `json:"schemas"`
+	Routed  int    `json:"routed"`
+}
+
+// Report is the result of a check.
+type Report struct {
+	ControlPlaneTenants int            `json:"control_plane_tenants"`
+	DataPlanes          []PlaneSummary `json:"data_planes"`
+	// Unchecked counts tenants routed to a data plane that was not checked.
+	Unchecked int `json:"unchecked"`
+	// Recent counts tenants skipped because they were created within the
+	// grace period and may still be provisioning.
+	Recent   int       `json:"recent"`
+	Findings []Finding `json:"findings"`
+}
+
+// Check cross-references the control plane's tenants against the data
+// planes. Tenants created after since are skipped, and so are schemas and
+// user mappings of their IDs. A tenant with an empty route is expected in
+// one of the checked data planes.
+func Check(tenants []Tenant, planes []*DataPlane, since time.Time) *Report {
+	r := &Report{ControlPlaneTenants: len(tenants), Findings: []Finding{}}
+	byRoute := map[string]*DataPlane{}
+	for _, dp := range planes {
+		byRoute[dp.Route] = dp
+	}
+
+	known := map[string]*Tenant{}
+	recent := map[string]bool{}
+	routed := map[string]int{}
+	for i := range tenants {
+		t := &tenants[i]
+		known[t.ID] = t
+		if !t.Created.IsZero() && t.Created.After(since) {
+			recent[t.ID] = true
+			r.Recent++
+			continue
+		}
+		home, checked := byRoute[t.Route]
+		if !checked && t.Route != "" {
+			r.Unchecked++
+			continue
+		}
+		if checked {
+			routed[home.Context]++
+		}
+
+		var holders []*DataPlane
+		for _, dp := range planes {
+			if dp.Schemas[t.ID] {
+				holders = append(holders, dp)
+			}
+		}
+		switch {
+		case len(holders) == 0 && checked:
+			r.add(Finding{Kind: KindMissingSchema, Tenant: t.ID, DataPlane: home.Context,
+				Detail: fmt.Sprintf("routed to %s, which has no schema for it, nor does any other checked data plane", home.Context)})
+		case len(holders) == 0:
+			r.add(Finding{Kind: KindMissingSchema, Tenant: t.ID,
+				Detail: "has no route and no schema in any checked data plane"})
+		case checked && !home.Schemas[t.ID]:
+			r.add(Finding{Kind: KindMisrouted, Tenant: t.ID, DataPlane: home.Context,
+				Detail: fmt.Sprintf("routed to %s, which has no schema for it, but %s has one", home.Context, contexts(holders))})
+		case checked:
+			for _, dp := range holders {
+				if dp != home {
+					r.add(Finding{Kind: KindStaleCopy, Tenant: t.ID, DataPlane: dp.Context,
+						Detail:  fmt.Sprintf("has a schema here but is routed to %s", home.Context),
+						Cleanup: deleteCommand(t.ID, dp.Context)})
+				}
+			}
+		}
+	}
+
+	for _, dp := range planes {
+		r.DataPlanes = append(r.DataPlanes, PlaneSummary{Context: dp.Context, Route: dp.Route, Schemas: len(dp.Schemas), Routed: routed[dp.Context]})
+		for _, id := range sortedKeys(dp.Schemas) {
+			if known[id] == nil && !recent[id] {
+				r.add(Finding{Kind: KindOrphanSchema, Tenant: id, DataPlane: dp.Context,
+					Detail:  "has a schema here but no tenant in the control plane",
+					Cleanup: deleteCommand(id, dp.Context)})
+			}
+		}
+		for _, id := range sortedKeys(dp.Users) {
+			if dp.Schemas[id] || recent[id] || !strings.HasPrefix(id, "tenant_") {
+				continue
+			}
+			t := known[id]
+			if t != nil && (t.Route == dp.Route || t.Route == "") {
+				// Reported as a missing schema, if it is missing.
+				continue
+			}
+			detail := fmt.Sprintf("%d user mapping(s) here but no schema and no tenant in the control plane", dp.Users[id])
+			if t != nil {
+				detail = fmt.Sprintf("%d user mapping(s) here but no schema, and the tenant is routed to %s", dp.Users[id], t.Route)
+			}
+			r.add(Finding{Kind: KindOrphanUsers, Tenant: id, DataPlane: dp.Context, Detail: detail,
+				Cleanup: deleteCommand(id, dp.Context)})
+		}
+	}
+
+	sort.SliceStable(r.Findings, func(i, j int) bool {
+		a, b := r.Findings[i], r.Findings[j]
+		if a.Kind != b.Kind {
+			return a.Kind < b.Kind
+		}
+		if a.Tenant != b.Tenant {
+			return a.Tenant < b.Tenant
+		}
+		return a.DataPlane < b.DataPlane
+	})
+	return r
+}
+
+func (r *Report) add(f Finding) {
+	r.Findings = append(r.Findings, f)
+}
+
+// deleteCommand is the cleanup for a tenant's data in one data plane. ods
+// tenant delete archives the schema first and refuses tenants that look
+// active, so it is safe to offer for a copy that turns out to be in use.
+func deleteCommand(tenantID, ctx string) string {
+	return fmt.Sprintf("ods tenant delete %s -c %s", tenantID, ctx)
+}
+
+func contexts(planes []*DataPlane) string {
+	names := make([]string, len(planes))
+	for i, dp := range planes {
+		names[i] = dp.Context
+	}
+	return strings.Join(names, ", ")
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,92 @@
+package consistency
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseTenants(t *testing.T) {
+	out := "Connecting...\n" + `{"status": "success", "tenants": [` +
+		`{"tenant_id": "tenant_a", "route": "us", "created": "2026-03-01T12:00:00.123456"},` +
+		`{"tenant_id": "tenant_b", "route": "", "created": ""}]}`
+	tenants, err := parseTenants(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Tenant{
+		{ID: "tenant_a", Route: "us", Created: time.Date(2026, 3, 1, 12, 0, 0, 123456000, time.UTC)},
+		{ID: "tenant_b"},
+	}
+	if !reflect.DeepEqual(tenants, want) {
+		t.Errorf("parseTenants = %+v, want %+v", tenants, want)
+	}
+
+	if _, err := parseTenants(`{"status": "error", "message": "the tenant table has no column 'dp'"}`); err == nil {
+		t.Error("expected the script's error")
+	}
+}
+
+func TestParseDataPlane(t *testing.T) {
+	dp, err := ParseDataPlane("us", "us-east-1", []string{"schema\ttenant_a\t0", "users\ttenant_a\t3"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !dp.Schemas["tenant_a"] || dp.Users["tenant_a"] != 3 || dp.Route != "us-east-1" {
+		t.Errorf("unexpected data plane: %+v", dp)
+	}
+	if _, err := ParseDataPlane("us", "us", []string{"users\ttenant_a\tmany"}); err == nil {
+		t.Error("expected an error for a bad count")
+	}
+}
+
+func TestCheck(t *testing.T) {
+	now := time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)
+	old := now.Add(-48 * time.Hour)
+	tenants := []Tenant{
+		{ID: "tenant_ok", Route: "us", Created: old},
+		{ID: "tenant_moved", Route: "eu", Created: old},
+		{ID: "tenant_misrouted", Route: "eu", Created: old},
+		{ID: "tenant_missing", Route: "us", Created: old},
+		{ID: "tenant_unrouted", Created: old},
+		{ID: "tenant_new", Route: "us", Created: now.Add(-time.Minute)},
+		{ID: "tenant_elsewhere", Route: "ap", Created: old},
+	}
+	us := &DataPlane{Context: "us", Route: "us",
+		Schemas: map[string]bool{"tenant_ok": true, "tenant_moved": true, "tenant_misrouted": true, "tenant_orphan": true},
+		Users:   map[string]int{"tenant_ok": 2, "tenant_gone": 1, "tenant_missing": 1, "tenant_new": 1},
+	}
+	eu := &DataPlane{Context: "eu", Route: "eu",
+		Schemas: map[string]bool{"tenant_moved": true},
+		Users:   map[string]int{"tenant_moved": 1},
+	}
+
+	r := Check(tenants, []*DataPlane{us, eu}, now.Add(-time.Hour))
+
+	want := []Finding{
+		{Kind: KindMisrouted, Tenant: "tenant_misrouted", DataPlane: "eu"},
+		{Kind: KindMissingSchema, Tenant: "tenant_missing", DataPlane: "us"},
+		{Kind: KindMissingSchema, Tenant: "tenant_unrouted"},
+		{Kind: KindOrphanSchema, Tenant: "tenant_orphan", DataPlane: "us", Cleanup: "ods tenant delete tenant_orphan -c us"},
+		{Kind: KindOrphanUsers, Tenant: "tenant_gone", DataPlane: "us", Cleanup: "ods tenant delete tenant_gone -c us"},
+		{Kind: KindStaleCopy, Tenant: "tenant_moved", DataPlane: "us", Cleanup: "ods tenant delete tenant_moved -c us"},
+	}
+	var got []Finding
+	for _, f := range r.Findings {
+		if f.Detail == "" {
+			t.Errorf("finding without detail: %+v", f)
+		}
+		f.Detail = ""
+		got = append(got, f)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("findings:\n got %+v\nwant %+v", got, want)
+	}
+	if r.Unchecked != 1 || r.Recent != 1 || r.ControlPlaneTenants != 7 {
+		t.Errorf("unexpected counts: %+v", r)
+	}
+	wantPlanes := []PlaneSummary{{Context: "us", Route: "us", Schemas: 4, Routed: 2}, {Context: "eu", Route: "eu", Schemas: 1, Routed: 2}}
+	if !reflect.DeepEqual(r.DataPlanes, wantPlanes) {
+		t.Errorf("DataPlanes = %+v, want %+v", r.DataPlanes, wantPlanes)
+	}
+}
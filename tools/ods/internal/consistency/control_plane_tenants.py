@@ -0,0 +1,78 @@
+"""List the tenants of the control plane and the data plane each is routed to.
+
+Bundled with ods and piped into `python -` on a control-plane pod by
+`ods consistency check`. Connects with the pod's POSTGRES_* environment, as
+the tenant routing script does, and reads the control plane's tenant table.
+
+Usage:
+    python - <route_column>
+
+The last line on stdout is a JSON object with "status" and, on success,
+"tenants", one per tenant with "tenant_id", "route" (the route column's
+value) and "created" (ISO 8601, or "" when the table has no created_at).
+"""
+
+from __future__ import annotations
+
+import json
+import os
+import re
+import sys
+from typing import Any
+
+from sqlalchemy import create_engine, text
+
+
+def tenants(column: str) -> dict[str, Any]:
+    if not re.fullmatch(r"[a-z_][a-z0-9_]*", column):
+        raise ValueError(f"invalid column name {column!r}")
+
+    url = "postgresql://{user}:{password}@{host}:{port}/{db}".format(
+        user=os.environ.get("POSTGRES_USER", "postgres"),
+        password=os.environ.get("POSTGRES_PASSWORD", ""),
+        host=os.environ["POSTGRES_HOST"],
+        port=os.environ.get("POSTGRES_PORT", "5432"),
+        db=os.environ.get("POSTGRES_DB", "danswer"),
+    )
+    engine = create_engine(url)
+    with engine.connect() as conn:
+        columns = {
+            row[0]
+            for row in conn.execute(
+                text(
+                    "SELECT column_name FROM information_schema.columns "
+                    "WHERE table_name = 'tenant'"
+                )
+            )
+        }
+        if column not in columns:
+            raise ValueError(f"the tenant table has no column {column!r}")
+        created = "created_at" if "created_at" in columns else "NULL"
+        rows = conn.execute(
+            text(f"SELECT tenant_id, {column}, {created} FROM tenant")  # noqa: S608
+        ).all()
+
+    return {
+        "status": "success",
+        "tenants": [
+            {
+                "tenant_id": tenant_id,
+                "route": "" if route is None else str(route),
+                "created": created_at.isoformat() if created_at is not None else "",
+            }
+            for tenant_id, route, created_at in rows
+        ],
+    }
+
+
+def main() -> None:
+    try:
+        result = tenants(sys.argv[1])
+    except Exception as e:
+        print(f"Error: {e}", file=sys.stderr)
+        result = {"status": "error", "message": str(e)}
+    print(json.dumps(result))
+
+
+if __name__ == "__main__":
+    main()
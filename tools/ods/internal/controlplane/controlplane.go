@@ -0,0 +1,117 @@
+// Package controlplane is a minimal client for the Onyx control-plane API,
+// authenticating the same way the data plane does: a short-lived HS256 JWT
+// signed with DATA_PLANE_SECRET.
+package controlplane
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	// DefaultPort is the control-plane API's container port.
+	DefaultPort = 8082
+
+	tokenLifetime  = 5 * time.Minute
+	requestTimeout = 30 * time.Second
+)
+
+// Client talks to the control-plane API at BaseURL.
+type Client struct {
+	BaseURL string
+	Secret  string
+
+	// now is overridable for tests.
+	now func() time.Time
+}
+
+// NewClient returns a client for the control plane at baseURL.
+func NewClient(baseURL, secret string) *Client {
+	return &Client{BaseURL: strings.TrimSuffix(baseURL, "/"), Secret: secret, now: time.Now}
+}
+
+// Token returns a data-plane JWT valid for a few minutes.
+func (c *Client) Token() (string, error) {
+	now := c.now().UTC()
+	header, err := json.Marshal(map[string]string{"alg": "HS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+	payload, err := json.Marshal(map[string]any{
+		"iss":   "data_plane",
+		"iat":   now.Unix(),
+		"exp":   now.Add(tokenLifetime).Unix(),
+		"scope": "api_access",
+	})
+	if err != nil {
+		return "", err
+	}
+
+	enc := base64.RawURLEncoding
+	signingInput := enc.EncodeToString(header) + "." + enc.EncodeToString(payload)
+	mac := hmac.New(sha256.New, []byte(c.Secret))
+	mac.Write([]byte(signingInput))
+	return signingInput + "." + enc.EncodeToString(mac.Sum(nil)), nil
+}
+
+// Get issues a GET and decodes the JSON response into out.
+func (c *Client) Get(path string, params url.Values, out any) error {
+	u := c.BaseURL + path
+	if len(params) > 0 {
+		u += "?" + params.Encode()
+	}
+	return c.do(http.MethodGet, u, nil, out)
+}
+
+// Post issues a POST with a JSON body and decodes the JSON response into out.
+func (c *Client) Post(path string, body, out any) error {
+	data, err := json.Marshal(body)
+	if err != nil {
+		return fmt.Errorf("failed to marshal request body: %w", err)
+	}
+	return c.do(http.MethodPost, c.BaseURL+path, data, out)
+}
+
+func (c *Client) do(method, u string, body []byte, out any) error {
+	token, err := c.Token()
+	if err != nil {
+		return fmt.Errorf("failed to sign control-plane token: %w", err)
+	}
+
+	req, err := http.NewRequest(method, u, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", "Bearer "+token)
+	req.Header.Set("Content-Type", "application/json")
+
+	resp, err := (&http.Client{Timeout: requestTimeout}).Do(req)
+	if err != nil {
+		return fmt.Errorf("%s %s failed: %w", method, u, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s %s returned %s: %s", method, u, resp.Status, strings.TrimSpace(string(data)))
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("failed to parse response from %s: %w", u, err)
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package controlplane
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestToken(t *testing.T) {
+	c := NewClient("http://cp", "s3cret")
+	fixed := time.Unix(1700000000, 0)
+	c.now = func() time.Time { return fixed }
+
+	token, err := c.Token()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		t.Fatalf("expected 3 JWT segments, got %d", len(parts))
+	}
+
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	if want := base64.RawURLEncoding.EncodeToString(mac.Sum(nil)); parts[2] != want {
+		t.Error("signature does not verify")
+	}
+
+	raw, err := base64.RawURLEncoding.DecodeString(parts[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var claims map[string]any
+	if err := json.Unmarshal(raw, &claims); err != nil {
+		t.Fatal(err)
+	}
+	if claims["iss"] != "data_plane" || claims["scope"] != "api_access" {
+		t.Errorf("unexpected claims %v", claims)
+	}
+	if int64(claims["exp"].(float64)) != fixed.Add(tokenLifetime).Unix() {
+		t.Errorf("unexpected exp %v", claims["exp"])
+	}
+}
+
+func TestGetAndPost(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		switch r.URL.Path {
+		case "/billing-information":
+			_ = json.NewEncoder(w).Encode(map[string]any{"tenant_id": r.URL.Query().Get("tenant_id"), "seats": 10})
+		case "/seats/update":
+			var body map[string]any
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			_ = json.NewEncoder(w).Encode(map[string]any{"success": true, "current_seats": body["new_seat_count"]})
+		default:
+			http.Error(w, `{"detail":"Tenant not found"}`, http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+
+	c := NewClient(srv.URL+"/", "s")
+
+	var info map[string]any
+	if err := c.Get("/billing-information", url.Values{"tenant_id": {"t1"}}, &info); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if info["tenant_id"] != "t1" {
+		t.Errorf("unexpected response %v", info)
+	}
+
+	var update map[string]any
+	if err := c.Post("/seats/update", map[string]any{"new_seat_count": 12}, &update); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if update["current_seats"].(float64) != 12 {
+		t.Errorf("unexpected response %v", update)
+	}
+
+	err := c.Get("/missing", nil, nil)
+	if err == nil || !strings.Contains(err.Error(), "Tenant not found") {
+		t.Errorf("expected the error body to be surfaced, got %v", err)
+	}
+}
@@ -0,0 +1,304 @@
+// Package costs estimates what an Onyx deployment costs to run and
+// attributes that cost to tenants. Compute is priced from the share of each
+// node the deployment's pods request, storage from volume sizes, and LLM
+// spend from the token counts of stored chat messages.
+package costs
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/kube"
+)
+
+// Ways to split shared infrastructure cost between tenants.
+const (
+	SplitDocuments = "documents"
+	SplitTokens    = "tokens"
+	SplitEven      = "even"
+)
+
+// Splits lists the supported split methods.
+var Splits = []string{SplitDocuments, SplitTokens, SplitEven}
+
+// Rates are the prices an estimate uses besides node prices.
+type Rates struct {
+	// SpotDiscount is the fraction spot nodes cost less than on-demand.
+	SpotDiscount float64
+	// StoragePerGiB is the monthly price of a GiB of volume storage.
+	StoragePerGiB float64
+	// LLMInputPer1M and LLMOutputPer1M price a million tokens.
+	LLMInputPer1M  float64
+	LLMOutputPer1M float64
+}
+
+// Month is a calendar month in UTC.
+type Month struct {
+	Start time.Time
+}
+
+// ParseMonth parses "2006-01".
+func ParseMonth(s string) (Month, error) {
+	t, err := time.Parse("2006-01", s)
+	if err != nil {
+		return Month{}, fmt.Errorf("invalid month %q (expected YYYY-MM)", s)
+	}
+	return Month{Start: t.UTC()}, nil
+}
+
+// PreviousMonth returns the month before the one containing now.
+func PreviousMonth(now time.Time) Month {
+	now = now.UTC()
+	return Month{Start: time.Date(now.Year(), now.Month()-1, 1, 0, 0, 0, 0, time.UTC)}
+}
+
+// End returns the start of the following month.
+func (m Month) End() time.Time {
+	return m.Start.AddDate(0, 1, 0)
+}
+
+// Hours returns the month's length in hours.
+func (m Month) Hours() float64 {
+	return m.End().Sub(m.Start).Hours()
+}
+
+func (m Month) String() string {
+	return m.Start.Format("2006-01")
+}
+
+// Line is one item of an environment's cost.
+type Line struct {
+	Category string  `json:"category"` // "compute", "storage" or "llm"
+	Item     string  `json:"item"`
+	Detail   string  `json:"detail"`
+	USD      float64 `json:"usd"`
+}
+
+// Infra prices the compute the pods request and the storage of pvcs for
+// month. prices maps instance types to on-demand hourly prices; pods on
+// nodes without a price are left out with a warning.
+func Infra(nodes []*kube.Node, pods []*kube.PodRequests, pvcs []*kube.PVC, prices map[string]float64, rates Rates, month Month) ([]Line, []string) {
+	nodeCost := map[string]float64{}
+	byName := map[string]*kube.Node{}
+	var warnings []string
+	for _, n := range nodes {
+		byName[n.Name] = n
+		price, ok := prices[n.InstanceType]
+		if !ok {
+			continue
+		}
+		if n.Spot {
+			price *= 1 - rates.SpotDiscount
+		}
+		nodeCost[n.Name] = price * month.Hours()
+	}
+
+	type workload struct {
+		pods        int
+		cpu, memory float64
+		usd         float64
+	}
+	workloads := map[string]*workload{}
+	unpriced := map[string]bool{}
+	for _, p := range pods {
+		w := workloads[p.Workload]
+		if w == nil {
+			w = &workload{}
+			workloads[p.Workload] = w
+		}
+		w.pods++
+		w.cpu += p.CPU
+		w.memory += p.Memory
+
+		n, ok := byName[p.Node]
+		cost, priced := nodeCost[p.Node]
+		if !ok || !priced || n.CPU == 0 || n.Memory == 0 {
+			unpriced[p.Workload] = true
+			continue
+		}
+		// A pod pays for the share of its node it reserves, weighing CPU and
+		// memory equally.
+		w.usd += cost * (p.CPU/n.CPU + p.Memory/n.Memory) / 2
+	}
+
+	var lines []Line
+	for _, name := range sortedKeys(workloads) {
+		w := workloads[name]
+		lines = append(lines, Line{
+			Category: "compute",
+			Item:     name,
+			Detail:   fmt.Sprintf("%d pods, %.2f CPU, %s", w.pods, w.cpu, formatGiB(w.memory)),
+			USD:      w.usd,
+		})
+		if unpriced[name] {
+			warnings = append(warnings, fmt.Sprintf("%s runs on nodes without a price; its compute cost is understated", name))
+		}
+	}
+	for _, pvc := range pvcs {
+		class := pvc.StorageClass
+		if class == "" {
+			class = "default class"
+		}
+		lines = append(lines, Line{
+			Category: "storage",
+			Item:     pvc.Name,
+			Detail:   fmt.Sprintf("%s %s", formatGiB(pvc.Bytes), class),
+			USD:      pvc.Bytes / (1 << 30) * rates.StoragePerGiB,
+		})
+	}
+	return lines, warnings
+}
+
+// LLM prices the tokens of usage.
+func LLM(usage []TenantUsage, rates Rates) Line {
+	var in, out int64
+	for _, u := range usage {
+		in += u.InputTokens
+		out += u.OutputTokens
+	}
+	return Line{
+		Category: "llm",
+		Item:     "chat messages",
+		Detail:   fmt.Sprintf("%s in / %s out tokens", formatCount(in), formatCount(out)),
+		USD:      tokenCost(in, out, rates),
+	}
+}
+
+// Total sums lines.
+func Total(lines []Line) float64 {
+	total := 0.0
+	for _, l := range lines {
+		total += l.USD
+	}
+	return total
+}
+
+// TenantUsage is what one tenant used in a month.
+type TenantUsage struct {
+	Tenant       string `json:"tenant"`
+	InputTokens  int64  `json:"input_tokens"`
+	OutputTokens int64  `json:"output_tokens"`
+	// Documents counts the tenant's documents at the time of the query.
+	Documents int64 `json:"documents"`
+}
+
+// UsageSQL returns each schema's message tokens in month, split into input
+// (user) and output (assistant) tokens, and its document count.
+func UsageSQL(schemas []string, month Month) string {
+	from, to := month.Start.Format(time.RFC3339), month.End().Format(time.RFC3339)
+	parts := make([]string, len(schemas))
+	for i, s := range schemas {
+		parts[i] = fmt.Sprintf(`SELECT '%[1]s',
+  COALESCE(sum(token_count) FILTER (WHERE message_type <> 'ASSISTANT'), 0),
+  COALESCE(sum(token_count) FILTER (WHERE message_type = 'ASSISTANT'), 0),
+  (SELECT count(*) FROM "%[1]s".document)
+FROM "%[1]s".chat_message WHERE time_sent >= '%[2]s' AND time_sent < '%[3]s'`, s, from, to)
+	}
+	return strings.Join(parts, "\nUNION ALL\n")
+}
+
+// ParseUsage reads the tab-separated output of UsageSQL.
+func ParseUsage(lines []string) ([]TenantUsage, error) {
+	usage := make([]TenantUsage, 0, len(lines))
+	for _, line := range lines {
+		f := strings.Split(line, "\t")
+		if len(f) != 4 {
+			return nil, fmt.Errorf("unexpected usage row: %q", line)
+		}
+		u := TenantUsage{Tenant: f[0]}
+		for i, field := range []*int64{&u.InputTokens, &u.OutputTokens, &u.Documents} {
+			v, err := strconv.ParseInt(f[i+1], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("unexpected usage row: %q", line)
+			}
+			*field = v
+		}
+		usage = append(usage, u)
+	}
+	return usage, nil
+}
+
+// TenantCost is a tenant's share of an environment's cost.
+type TenantCost struct {
+	TenantUsage
+	// Share is the fraction of infrastructure cost attributed to the tenant.
+	Share float64 `json:"share"`
+	Infra float64 `json:"infra_usd"`
+	LLM   float64 `json:"llm_usd"`
+}
+
+// Total is the tenant's attributed cost.
+func (t TenantCost) Total() float64 {
+	return t.Infra + t.LLM
+}
+
+// Attribute splits infraUSD between tenants by split and adds each tenant's
+// own LLM cost. The result is sorted by total cost, highest first.
+func Attribute(usage []TenantUsage, infraUSD float64, rates Rates, split string) ([]TenantCost, error) {
+	weight := func(u TenantUsage) float64 {
+		switch split {
+		case SplitDocuments:
+			return float64(u.Documents)
+		case SplitTokens:
+			return float64(u.InputTokens + u.OutputTokens)
+		default:
+			return 1
+		}
+	}
+	if split != SplitDocuments && split != SplitTokens && split != SplitEven {
+		return nil, fmt.Errorf("unknown split %q (must be one of %s)", split, strings.Join(Splits, ", "))
+	}
+
+	total := 0.0
+	for _, u := range usage {
+		total += weight(u)
+	}
+	costs := make([]TenantCost, 0, len(usage))
+	for _, u := range usage {
+		c := TenantCost{TenantUsage: u, LLM: tokenCost(u.InputTokens, u.OutputTokens, rates)}
+		if total > 0 {
+			c.Share = weight(u) / total
+		}
+		c.Infra = infraUSD * c.Share
+		costs = append(costs, c)
+	}
+	sort.SliceStable(costs, func(i, j int) bool {
+		if costs[i].Total() != costs[j].Total() {
+			return costs[i].Total() > costs[j].Total()
+		}
+		return costs[i].Tenant < costs[j].Tenant
+	})
+	return costs, nil
+}
+
+func tokenCost(in, out int64, rates Rates) float64 {
+	return float64(in)/1e6*rates.LLMInputPer1M + float64(out)/1e6*rates.LLMOutputPer1M
+}
+
+func formatGiB(bytes float64) string {
+	return fmt.Sprintf("%.1f GiB", bytes/(1<<30))
+}
+
+func formatCount(n int64) string {
+	switch {
+	case n >= 1e9:
+		return fmt.Sprintf("%.1fB", float64(n)/1e9)
+	case n >= 1e6:
+		return fmt.Sprintf("%.1fM", float64(n)/1e6)
+	case n >= 1e3:
+		return fmt.Sprintf("%.1fk", float64(n)/1e3)
+	}
+	return strconv.FormatInt(n, 10)
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,152 @@
+package costs
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/kube"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/runner"
+)
+
+func approx(a, b float64) bool {
+	return math.Abs(a-b) < 1e-6
+}
+
+func TestMonth(t *testing.T) {
+	m := PreviousMonth(time.Date(2026, time.March, 15, 12, 0, 0, 0, time.UTC))
+	if m.String() != "2026-02" || m.Hours() != 28*24 {
+		t.Errorf("PreviousMonth = %s with %g hours", m, m.Hours())
+	}
+	if m := PreviousMonth(time.Date(2026, time.January, 1, 0, 0, 0, 0, time.UTC)); m.String() != "2025-12" {
+		t.Errorf("PreviousMonth in January = %s", m)
+	}
+	if _, err := ParseMonth("2026-13"); err == nil {
+		t.Error("expected an error for an invalid month")
+	}
+}
+
+func TestInfra(t *testing.T) {
+	month, _ := ParseMonth("2026-04") // 720 hours
+	nodes := []*kube.Node{
+		{Name: "a", InstanceType: "m5.xlarge", CPU: 4, Memory: 16 << 30},
+		{Name: "b", InstanceType: "m5.xlarge", Spot: true, CPU: 4, Memory: 16 << 30},
+		{Name: "c", InstanceType: "unknown", CPU: 4, Memory: 16 << 30},
+	}
+	pods := []*kube.PodRequests{
+		{Pod: "api-1", Workload: "api-server", Node: "a", CPU: 2, Memory: 8 << 30},
+		{Pod: "api-2", Workload: "api-server", Node: "b", CPU: 1, Memory: 4 << 30},
+		{Pod: "web-1", Workload: "web-server", Node: "c", CPU: 1, Memory: 1 << 30},
+	}
+	pvcs := []*kube.PVC{{Name: "vespa-0", StorageClass: "gp3", Bytes: 100 << 30}}
+	rates := Rates{SpotDiscount: 0.5, StoragePerGiB: 0.1}
+
+	lines, warnings := Infra(nodes, pods, pvcs, map[string]float64{"m5.xlarge": 0.2}, rates, month)
+	if len(lines) != 3 {
+		t.Fatalf("got %d lines: %+v", len(lines), lines)
+	}
+	// Half of node a (72) plus a quarter of the discounted node b (18).
+	if api := lines[0]; api.Item != "api-server" || !approx(api.USD, 90) || api.Detail != "2 pods, 3.00 CPU, 12.0 GiB" {
+		t.Errorf("api-server line = %+v", api)
+	}
+	if web := lines[1]; web.Item != "web-server" || web.USD != 0 {
+		t.Errorf("web-server line = %+v", web)
+	}
+	if len(warnings) != 1 || !strings.Contains(warnings[0], "web-server") {
+		t.Errorf("warnings = %v", warnings)
+	}
+	if s := lines[2]; s.Category != "storage" || !approx(s.USD, 10) {
+		t.Errorf("storage line = %+v", s)
+	}
+}
+
+func TestUsageSQLAndParse(t *testing.T) {
+	month, _ := ParseMonth("2026-04")
+	sql := UsageSQL([]string{"public", "tenant_a"}, month)
+	if strings.Count(sql, "UNION ALL") != 1 || !strings.Contains(sql, `FROM "tenant_a".chat_message`) ||
+		!strings.Contains(sql, "time_sent < '2026-05-01T00:00:00Z'") {
+		t.Errorf("unexpected SQL:\n%s", sql)
+	}
+
+	usage, err := ParseUsage([]string{"public\t1000\t500\t10", "tenant_a\t0\t0\t30"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(usage) != 2 || usage[0] != (TenantUsage{Tenant: "public", InputTokens: 1000, OutputTokens: 500, Documents: 10}) {
+		t.Errorf("usage = %+v", usage)
+	}
+	if _, err := ParseUsage([]string{"public\tx\t0\t0"}); err == nil {
+		t.Error("expected an error for a malformed row")
+	}
+}
+
+func TestAttribute(t *testing.T) {
+	usage := []TenantUsage{
+		{Tenant: "a", InputTokens: 3_000_000, OutputTokens: 1_000_000, Documents: 10},
+		{Tenant: "b", Documents: 30},
+	}
+	rates := Rates{LLMInputPer1M: 1, LLMOutputPer1M: 10}
+
+	costs, err := Attribute(usage, 100, rates, SplitDocuments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// b carries 75 of infrastructure; a carries 25 plus 13 of LLM.
+	if costs[0].Tenant != "b" || !approx(costs[0].Infra, 75) || costs[1].Tenant != "a" || !approx(costs[1].Total(), 38) {
+		t.Errorf("split by documents = %+v", costs)
+	}
+
+	costs, _ = Attribute(usage, 100, rates, SplitTokens)
+	if costs[0].Tenant != "a" || !approx(costs[0].Share, 1) || costs[1].Infra != 0 {
+		t.Errorf("split by tokens = %+v", costs)
+	}
+
+	costs, _ = Attribute(usage, 100, rates, SplitEven)
+	if !approx(costs[0].Infra, 50) || !approx(costs[1].Infra, 50) {
+		t.Errorf("even split = %+v", costs)
+	}
+
+	if _, err := Attribute(usage, 100, rates, "pods"); err == nil {
+		t.Error("expected an error for an unknown split")
+	}
+
+	if line := LLM(usage, rates); !approx(line.USD, 13) || line.Detail != "3.0M in / 1.0M out tokens" {
+		t.Errorf("LLM line = %+v", line)
+	}
+}
+
+func TestParseOnDemandPrice(t *testing.T) {
+	data := []byte(`{"PriceList": ["{\"terms\": {\"OnDemand\": {\"X.JRTCKXETXF\": {\"priceDimensions\": {\"X.JRTCKXETXF.6YS6EN2CT7\": {\"unit\": \"Hrs\", \"pricePerUnit\": {\"USD\": \"0.1920000000\"}}}}}}}"]}`)
+	price, err := parseOnDemandPrice(data)
+	if err != nil || price != 0.192 {
+		t.Errorf("parseOnDemandPrice = %g, %v", price, err)
+	}
+	if _, err := parseOnDemandPrice([]byte(`{"PriceList": []}`)); err == nil {
+		t.Error("expected an error for an empty price list")
+	}
+}
+
+func TestEC2HourlyPrices(t *testing.T) {
+	product := `{"PriceList": ["{\"terms\": {\"OnDemand\": {\"X\": {\"priceDimensions\": {\"Y\": {\"unit\": \"Hrs\", \"pricePerUnit\": {\"USD\": \"0.1920000000\"}}}}}}}"]}`
+	f := (&runner.Fake{}).On("aws pricing get-products", runner.Response{Stdout: product})
+
+	prices, err := EC2HourlyPrices(f, []string{"AWS_PROFILE=prod"}, "us-east-2", []string{"m5.xlarge", "m5.xlarge", ""})
+	if err != nil || prices["m5.xlarge"] != 0.192 {
+		t.Fatalf("EC2HourlyPrices() = %v, %v", prices, err)
+	}
+	calls := f.Calls()
+	if len(calls) != 1 {
+		t.Fatalf("expected one lookup per instance type, got %q", f.Lines())
+	}
+	line := calls[0].String()
+	for _, want := range []string{"--region us-east-1", "Field=instanceType,Value=m5.xlarge", "Field=regionCode,Value=us-east-2"} {
+		if !strings.Contains(line, want) {
+			t.Errorf("command %q lacks %q", line, want)
+		}
+	}
+	if !slices.Contains(calls[0].Env, "AWS_PROFILE=prod") {
+		t.Error("expected the cluster's AWS environment")
+	}
+}
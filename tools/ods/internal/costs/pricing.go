@@ -0,0 +1,98 @@
+package costs
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"strconv"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/runner"
+)
+
+// EC2HourlyPrices looks up the Linux on-demand hourly price of each instance
+// type in region with the AWS Price List API, running aws with r (nil for
+// runner.Default) in env, the cluster's AWS environment (see
+// kube.Cluster.AWSEnv).
+func EC2HourlyPrices(r runner.Runner, env []string, region string, instanceTypes []string) (map[string]float64, error) {
+	prices := map[string]float64{}
+	for _, t := range instanceTypes {
+		if _, ok := prices[t]; ok || t == "" {
+			continue
+		}
+		price, err := ec2HourlyPrice(r, env, region, t)
+		if err != nil {
+			return prices, err
+		}
+		prices[t] = price
+	}
+	return prices, nil
+}
+
+func ec2HourlyPrice(r runner.Runner, env []string, region, instanceType string) (float64, error) {
+	args := []string{
+		"pricing", "get-products", "--region", "us-east-1", "--service-code", "AmazonEC2", "--output", "json",
+		"--filters",
+	}
+	for _, f := range [][2]string{
+		{"instanceType", instanceType},
+		{"regionCode", region},
+		{"operatingSystem", "Linux"},
+		{"tenancy", "Shared"},
+		{"preInstalledSw", "NA"},
+		{"capacitystatus", "Used"},
+	} {
+		args = append(args, fmt.Sprintf("Type=TERM_MATCH,Field=%s,Value=%s", f[0], f[1]))
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := runner.Cmd{Name: "aws", Args: args, Env: env, Stdout: &stdout, Stderr: &stderr}
+	if err := runner.Or(r).Run(cmd); err != nil {
+		return 0, fmt.Errorf("aws pricing get-products failed for %s: %w\n%s", instanceType, err, stderr.String())
+	}
+	price, err := parseOnDemandPrice(stdout.Bytes())
+	if err != nil {
+		return 0, fmt.Errorf("%s in %s: %w", instanceType, region, err)
+	}
+	return price, nil
+}
+
+// parseOnDemandPrice returns the USD on-demand price in the output of aws
+// pricing get-products, whose PriceList holds each product as a JSON string.
+func parseOnDemandPrice(data []byte) (float64, error) {
+	var out struct {
+		PriceList []string `json:"PriceList"`
+	}
+	if err := json.Unmarshal(data, &out); err != nil {
+		return 0, fmt.Errorf("failed to parse aws output: %w", err)
+	}
+	for _, raw := range out.PriceList {
+		var product struct {
+			Terms struct {
+				OnDemand map[string]struct {
+					PriceDimensions map[string]struct {
+						Unit         string            `json:"unit"`
+						PricePerUnit map[string]string `json:"pricePerUnit"`
+					} `json:"priceDimensions"`
+				} `json:"OnDemand"`
+			} `json:"terms"`
+		}
+		if err := json.Unmarshal([]byte(raw), &product); err != nil {
+			return 0, fmt.Errorf("failed to parse price list entry: %w", err)
+		}
+		for _, term := range product.Terms.OnDemand {
+			for _, dim := range term.PriceDimensions {
+				usd, ok := dim.PricePerUnit["USD"]
+				if !ok || dim.Unit != "Hrs" {
+					continue
+				}
+				price, err := strconv.ParseFloat(usd, 64)
+				if err != nil {
+					return 0, fmt.Errorf("invalid price %q: %w", usd, err)
+				}
+				if price > 0 {
+					return price, nil
+				}
+			}
+		}
+	}
+	return 0, fmt.Errorf("no on-demand price found")
+}
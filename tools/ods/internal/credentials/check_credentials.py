@@ -0,0 +1,249 @@
+"""Test the stored credentials of a tenant's connectors without syncing.
+
+Bundled with ods and piped into `python -` on an api-server pod by
+`ods credentials check`. Each active (or invalid) connector/credential pair
+is instantiated the way the indexing worker does it and its
+validate_connector_settings() is called, which for most connectors makes a
+cheap authenticated request that checks the token and its permissions. The
+stored credential JSON is also searched for expiry dates of tokens that are
+not refreshed automatically. Credential values are never printed.
+
+Usage:
+    python - check <schema> <timeout seconds>
+    python - check-all <timeout seconds>    # every tenant schema
+
+An empty <schema> means the default schema of a single-tenant deployment.
+
+Progress goes to stderr; the last line on stdout is a JSON object with
+"status" and "results", one per pair with "tenant", "cc_pair_id", "name",
+"source", "credential_id", "validation" ("ok", "expired", "permissions",
+"invalid", "error" or "timeout"), "detail" and "expires_at" (ISO 8601 or
+null).
+"""
+
+from __future__ import annotations
+
+import json
+import os
+import sys
+from concurrent.futures import ThreadPoolExecutor
+from concurrent.futures import TimeoutError as FutureTimeout
+from datetime import datetime
+from datetime import timezone
+from typing import Any
+
+# Keys holding the expiry of a token that only matters when nothing can
+# refresh it, i.e. when there is no refresh_token beside it.
+ACCESS_EXPIRY_KEYS = {"expiry", "expires_at", "expiration", "expiry_date", "expires"}
+# Keys holding the expiry of something that cannot be refreshed.
+HARD_EXPIRY_KEYS = {
+    "refresh_token_expires_at",
+    "refresh_token_expiry",
+    "refresh_expires_at",
+    "token_expires_at",
+    "key_expires_at",
+}
+
+MAX_DETAIL = 300
+
+
+def parse_time(value: Any) -> datetime | None:
+    if isinstance(value, bool):
+        return None
+    if isinstance(value, (int, float)):
+        # Epoch seconds, or milliseconds for values past year 33658.
+        seconds = value / 1000 if value > 1e12 else value
+        try:
+            return datetime.fromtimestamp(seconds, tz=timezone.utc)
+        except (OverflowError, OSError, ValueError):
+            return None
+    if isinstance(value, str):
+        try:
+            parsed = datetime.fromisoformat(value.replace("Z", "+00:00"))
+        except ValueError:
+            return None
+        return parsed if parsed.tzinfo else parsed.replace(tzinfo=timezone.utc)
+    return None
+
+
+def earliest_expiry(value: Any) -> datetime | None:
+    """Searches a credential JSON, including JSON-encoded strings inside it
+    (such as Google's token blobs), for the earliest hard expiry."""
+    if isinstance(value, str) and value.lstrip().startswith("{"):
+        try:
+            value = json.loads(value)
+        except ValueError:
+            return None
+    found: list[datetime] = []
+    if isinstance(value, dict):
+        refreshable = "refresh_token" in value
+        for key, item in value.items():
+            key = str(key).lower()
+            if key in HARD_EXPIRY_KEYS or (key in ACCESS_EXPIRY_KEYS and not refreshable):
+                if (when := parse_time(item)) is not None:
+                    found.append(when)
+            elif (when := earliest_expiry(item)) is not None:
+                found.append(when)
+    elif isinstance(value, list):
+        found = [w for item in value if (w := earliest_expiry(item)) is not None]
+    return min(found) if found else None
+
+
+def validate(connector: Any) -> tuple[str, str]:
+    from onyx.connectors.exceptions import CredentialExpiredError
+    from onyx.connectors.exceptions import InsufficientPermissionsError
+    from onyx.connectors.exceptions import UnexpectedValidationError
+    from onyx.connectors.exceptions import ValidationError
+
+    try:
+        connector.validate_connector_settings()
+    except CredentialExpiredError as e:
+        return "expired", e.message
+    except InsufficientPermissionsError as e:
+        return "permissions", e.message
+    except UnexpectedValidationError as e:
+        return "error", e.message
+    except ValidationError as e:
+        return "invalid", e.message
+    except Exception as e:
+        return "error", f"{type(e).__name__}: {e}"
+    return "ok", ""
+
+
+def use_schema(schema: str) -> str:
+    from onyx.db.engine.tenant_utils import validate_tenant_id
+    from shared_configs.configs import MULTI_TENANT
+    from shared_configs.configs import POSTGRES_DEFAULT_SCHEMA
+    from shared_configs.contextvars import CURRENT_TENANT_ID_CONTEXTVAR
+
+    if not schema:
+        if MULTI_TENANT:
+            raise ValueError("This deployment is multi-tenant; pass --tenant")
+        schema = POSTGRES_DEFAULT_SCHEMA
+    elif schema != POSTGRES_DEFAULT_SCHEMA and not validate_tenant_id(schema):
+        raise ValueError(f"Invalid schema {schema!r}")
+    CURRENT_TENANT_ID_CONTEXTVAR.set(schema)
+    return schema
+
+
+def check(schema: str, timeout: float) -> list[dict[str, Any]]:
+    from sqlalchemy import select
+
+    from onyx.connectors.factory import instantiate_connector
+    from onyx.db.engine.sql_engine import get_session_with_tenant
+    from onyx.db.enums import ConnectorCredentialPairStatus
+    from onyx.db.models import ConnectorCredentialPair
+
+    schema = use_schema(schema)
+    statuses = ConnectorCredentialPairStatus.active_statuses() + [
+        ConnectorCredentialPairStatus.INVALID
+    ]
+    results = []
+    with get_session_with_tenant(tenant_id=schema) as db_session:
+        pairs = db_session.scalars(
+            select(ConnectorCredentialPair)
+            .where(ConnectorCredentialPair.status.in_(statuses))
+            .order_by(ConnectorCredentialPair.id)
+        ).all()
+        # Connectors are built here, where the session is, and validated
+        # concurrently so one slow API does not hold up the rest.
+        pool = ThreadPoolExecutor(max_workers=4)
+        checks = []
+        for pair in pairs:
+            credential = pair.credential
+            result = {
+                "tenant": schema,
+                "cc_pair_id": pair.id,
+                "name": pair.name,
+                "source": pair.connector.source.value,
+                "credential_id": credential.id,
+                "validation": "ok",
+                "detail": "",
+                "expires_at": None,
+            }
+            if credential.credential_json:
+                expiry = earliest_expiry(
+                    credential.credential_json.get_value(apply_mask=False)
+                )
+                if expiry:
+                    result["expires_at"] = expiry.isoformat()
+            try:
+                connector = instantiate_connector(
+                    db_session=db_session,
+                    source=pair.connector.source,
+                    input_type=pair.connector.input_type,
+                    connector_specific_config=pair.connector.connector_specific_config,
+                    credential=credential,
+                )
+            except Exception as e:
+                result["validation"] = "invalid"
+                result["detail"] = f"failed to load credential: {e}"[:MAX_DETAIL]
+                results.append(result)
+                continue
+            checks.append((result, pool.submit(validate, connector)))
+
+        for result, future in checks:
+            try:
+                result["validation"], result["detail"] = future.result(timeout=timeout)
+            except FutureTimeout:
+                result["validation"] = "timeout"
+                result["detail"] = f"no answer within {timeout:g}s"
+            result["detail"] = result["detail"][:MAX_DETAIL]
+            results.append(result)
+        pool.shutdown(wait=False, cancel_futures=True)
+    return results
+
+
+def check_all(timeout: float) -> list[dict[str, Any]]:
+    from onyx.db.engine.tenant_utils import get_all_tenant_ids
+    from shared_configs.configs import MULTI_TENANT
+    from shared_configs.configs import TENANT_ID_PREFIX
+
+    if not MULTI_TENANT:
+        raise ValueError("This deployment is not multi-tenant; drop --all-tenants")
+
+    tenants = [t for t in get_all_tenant_ids() if t.startswith(TENANT_ID_PREFIX)]
+    results = []
+    for i, tenant_id in enumerate(tenants, 1):
+        print(f"{i}/{len(tenants)} {tenant_id}", file=sys.stderr)
+        try:
+            results.extend(check(tenant_id, timeout))
+        except Exception as e:
+            results.append(
+                {
+                    "tenant": tenant_id,
+                    "validation": "error",
+                    "detail": f"failed to check tenant: {e}"[:MAX_DETAIL],
+                }
+            )
+    return results
+
+
+def main() -> None:
+    usage = "Usage: python - check <schema> <timeout> | check-all <timeout>"
+    args = sys.argv[1:]
+    arity = {"check": 3, "check-all": 2}
+    if not args or arity.get(args[0]) != len(args):
+        print(json.dumps({"status": "error", "message": usage}))
+        sys.exit(1)
+
+    from onyx.db.engine.sql_engine import SqlEngine
+
+    SqlEngine.init_engine(pool_size=5, max_overflow=2)
+
+    try:
+        if args[0] == "check":
+            results = check(args[1], float(args[2]))
+        else:
+            results = check_all(float(args[1]))
+        result = {"status": "success", "results": results}
+    except Exception as e:
+        print(f"Error: {e}", file=sys.stderr)
+        result = {"status": "error", "message": str(e)}
+    print(json.dumps(result), flush=True)
+    # Exit without joining validations abandoned after their timeout.
+    os._exit(0)
+
+
+if __name__ == "__main__":
+    main()
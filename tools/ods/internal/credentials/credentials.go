@@ -0,0 +1,132 @@
+// Package credentials tests the stored credentials of connectors, without
+// running a sync, and flags those that fail or are about to.
+package credentials
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/kube"
+)
+
+//go:embed check_credentials.py
+var checkScript string
+
+// Validation outcomes reported by the check script.
+const (
+	ValidationOK          = "ok"
+	ValidationExpired     = "expired"
+	ValidationPermissions = "permissions"
+	ValidationInvalid     = "invalid"
+	ValidationError       = "error"
+	ValidationTimeout     = "timeout"
+)
+
+// States of a checked credential, from best to worst.
+const (
+	StateOK = "ok"
+	// StateUnknown is a check that errored or timed out, which says
+	// nothing about the credential itself.
+	StateUnknown  = "unknown"
+	StateExpiring = "expiring"
+	StateFailing  = "failing"
+)
+
+var stateRank = map[string]int{StateOK: 0, StateUnknown: 1, StateExpiring: 2, StateFailing: 3}
+
+// Result is the check of one connector/credential pair.
+type Result struct {
+	Tenant       string     `json:"tenant"`
+	CCPairID     int        `json:"cc_pair_id"`
+	Name         string     `json:"name"`
+	Source       string     `json:"source"`
+	CredentialID int        `json:"credential_id"`
+	Validation   string     `json:"validation"`
+	Detail       string     `json:"detail"`
+	ExpiresAt    *time.Time `json:"expires_at"`
+	// State is set by Classify.
+	State string `json:"state"`
+}
+
+// Check tests the credentials of schema's connectors ("" for the default
+// schema of a single-tenant deployment), giving each validation timeout.
+func Check(c *kube.Cluster, pod, schema string, timeout time.Duration) ([]Result, error) {
+	return run(c, pod, "check", schema, timeoutArg(timeout))
+}
+
+// CheckAll is Check for every tenant schema.
+func CheckAll(c *kube.Cluster, pod string, timeout time.Duration) ([]Result, error) {
+	return run(c, pod, "check-all", timeoutArg(timeout))
+}
+
+func timeoutArg(timeout time.Duration) string {
+	return strconv.FormatFloat(timeout.Seconds(), 'f', -1, 64)
+}
+
+func run(c *kube.Cluster, pod string, args ...string) ([]Result, error) {
+	stdout, err := c.RunPython(pod, checkScript, args...)
+	if err != nil {
+		return nil, err
+	}
+	return parseResults(stdout)
+}
+
+func parseResults(stdout string) ([]Result, error) {
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	last := strings.TrimSpace(lines[len(lines)-1])
+	var r struct {
+		Status  string   `json:"status"`
+		Message string   `json:"message"`
+		Results []Result `json:"results"`
+	}
+	if err := json.Unmarshal([]byte(last), &r); err != nil {
+		return nil, fmt.Errorf("unexpected output from credentials script: %q", last)
+	}
+	if r.Status != "success" {
+		return nil, fmt.Errorf("%s", r.Message)
+	}
+	return r.Results, nil
+}
+
+// Classify sets each result's State, with tokens expiring before now+within
+// counted as expiring, and sorts the results worst first.
+func Classify(results []Result, now time.Time, within time.Duration) {
+	for i := range results {
+		results[i].State = state(results[i], now, within)
+	}
+	sort.SliceStable(results, func(i, j int) bool {
+		return stateRank[results[i].State] > stateRank[results[j].State]
+	})
+}
+
+func state(r Result, now time.Time, within time.Duration) string {
+	switch r.Validation {
+	case ValidationExpired, ValidationPermissions, ValidationInvalid:
+		return StateFailing
+	case ValidationError, ValidationTimeout:
+		return StateUnknown
+	}
+	switch {
+	case r.ExpiresAt == nil:
+		return StateOK
+	case !r.ExpiresAt.After(now):
+		return StateFailing
+	case r.ExpiresAt.Before(now.Add(within)):
+		return StateExpiring
+	}
+	return StateOK
+}
+
+// Counts returns the number of results in each state.
+func Counts(results []Result) map[string]int {
+	counts := map[string]int{}
+	for _, r := range results {
+		counts[r.State]++
+	}
+	return counts
+}
@@ -0,0 +1,60 @@
+package credentials
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseResults(t *testing.T) {
+	out := "loading...\n" + `{"status": "success", "results": [{"tenant": "public", "cc_pair_id": 3, "name": "Drive", "source": "google_drive", "credential_id": 7, "validation": "ok", "detail": "", "expires_at": "2026-10-20T00:00:00+00:00"}]}`
+	results, err := parseResults(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].CCPairID != 3 || results[0].ExpiresAt == nil || results[0].ExpiresAt.Day() != 20 {
+		t.Errorf("unexpected results: %+v", results)
+	}
+
+	if _, err := parseResults(`{"status": "error", "message": "This deployment is multi-tenant; pass --tenant"}`); err == nil || err.Error() != "This deployment is multi-tenant; pass --tenant" {
+		t.Errorf("expected the script's error, got %v", err)
+	}
+	if _, err := parseResults("Traceback"); err == nil {
+		t.Error("expected an error for unparseable output")
+	}
+}
+
+func TestClassify(t *testing.T) {
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	at := func(d time.Duration) *time.Time {
+		t := now.Add(d)
+		return &t
+	}
+	results := []Result{
+		{CCPairID: 1, Validation: ValidationOK},
+		{CCPairID: 2, Validation: ValidationOK, ExpiresAt: at(3 * 24 * time.Hour)},
+		{CCPairID: 3, Validation: ValidationOK, ExpiresAt: at(30 * 24 * time.Hour)},
+		{CCPairID: 4, Validation: ValidationOK, ExpiresAt: at(-time.Hour)},
+		{CCPairID: 5, Validation: ValidationTimeout},
+		{CCPairID: 6, Validation: ValidationPermissions},
+		{CCPairID: 7, Validation: ValidationError, ExpiresAt: at(time.Hour)},
+	}
+	Classify(results, now, 7*24*time.Hour)
+
+	want := []struct {
+		id    int
+		state string
+	}{
+		{4, StateFailing}, {6, StateFailing},
+		{2, StateExpiring},
+		{5, StateUnknown}, {7, StateUnknown},
+		{1, StateOK}, {3, StateOK},
+	}
+	for i, w := range want {
+		if results[i].CCPairID != w.id || results[i].State != w.state {
+			t.Errorf("results[%d] = pair %d %s, want pair %d %s", i, results[i].CCPairID, results[i].State, w.id, w.state)
+		}
+	}
+	if c := Counts(results); c[StateFailing] != 2 || c[StateExpiring] != 1 || c[StateUnknown] != 2 || c[StateOK] != 2 {
+		t.Errorf("unexpected counts: %v", c)
+	}
+}
@@ -0,0 +1,105 @@
+// Package cron reads and triggers the Celery beat schedule of a deployment,
+// which runs Onyx's periodic tasks (pruning, permission sync, cleanup, ...).
+package cron
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/kube"
+)
+
+//go:embed cron.py
+var cronScript string
+
+// lateGrace is the minimum slack before an entry counts as late. Beat
+// sleeps up to five minutes between ticks, so short schedules drift.
+const lateGrace = 5 * time.Minute
+
+// Entry is a beat schedule entry.
+type Entry struct {
+	Name            string            `json:"name"`
+	Task            string            `json:"task"`
+	Schedule        string            `json:"schedule"`
+	IntervalSeconds *float64          `json:"interval_seconds"`
+	Queue           string            `json:"queue"`
+	Kwargs          map[string]string `json:"kwargs"`
+	LastRun         *time.Time        `json:"last_run"`
+	TotalRunCount   int               `json:"total_run_count"`
+	// DueInSeconds is how long until the entry next fires, negative when it
+	// is overdue, as of when the schedule was read. Nil if it never ran.
+	DueInSeconds *float64 `json:"due_in_seconds"`
+}
+
+// Late reports whether the entry should have fired again by now: it is
+// overdue by more than its interval (and at least lateGrace). Beat being
+// down, stuck or restarted without its schedule file all show up this way.
+func (e Entry) Late() bool {
+	if e.LastRun == nil || e.DueInSeconds == nil {
+		return false
+	}
+	grace := lateGrace
+	if e.IntervalSeconds != nil {
+		if interval := time.Duration(*e.IntervalSeconds * float64(time.Second)); interval > grace {
+			grace = interval
+		}
+	}
+	return time.Duration(-*e.DueInSeconds*float64(time.Second)) > grace
+}
+
+// Matches reports whether the entry's name or task contains filter.
+func (e Entry) Matches(filter string) bool {
+	return strings.Contains(e.Name, filter) || strings.Contains(e.Task, filter)
+}
+
+// List returns the beat schedule read on pod, a celery-beat pod.
+func List(c *kube.Cluster, pod string) ([]Entry, error) {
+	var r struct {
+		Entries []Entry `json:"entries"`
+	}
+	if err := run(c, pod, &r, "entries"); err != nil {
+		return nil, err
+	}
+	return r.Entries, nil
+}
+
+// Trigger sends the task of the entry called name now, as beat would, or for
+// tenant only when tenant is set. It returns the matched entry and the
+// Celery task ID.
+func Trigger(c *kube.Cluster, pod, name, tenant string) (entry, taskID string, err error) {
+	var r struct {
+		Entry  string `json:"entry"`
+		TaskID string `json:"task_id"`
+	}
+	if err := run(c, pod, &r, "trigger", name, tenant); err != nil {
+		return "", "", err
+	}
+	return r.Entry, r.TaskID, nil
+}
+
+func run(c *kube.Cluster, pod string, out any, args ...string) error {
+	stdout, err := c.RunPython(pod, cronScript, args...)
+	if err != nil {
+		return err
+	}
+	return parseResult(stdout, out)
+}
+
+func parseResult(stdout string, out any) error {
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	last := strings.TrimSpace(lines[len(lines)-1])
+	var r struct {
+		Status  string `json:"status"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal([]byte(last), &r); err != nil {
+		return fmt.Errorf("unexpected output from cron script: %q", last)
+	}
+	if r.Status != "success" {
+		return fmt.Errorf("%s", r.Message)
+	}
+	return json.Unmarshal([]byte(last), out)
+}
@@ -0,0 +1,170 @@
+"""List or trigger the Celery beat schedule of a deployment.
+
+Bundled with ods and piped into `python -` on the celery-beat pod by
+`ods cron`. Beat persists its schedule, including when each entry last
+fired, in a shelve file in its working directory; this script reads a copy
+of it so the running beat is not disturbed.
+
+Usage:
+    python - entries
+    python - trigger <name> <tenant>
+
+An empty <tenant> triggers the entry as beat would. With a tenant, the
+entry's task is sent for that tenant only.
+
+Progress goes to stderr; the last line on stdout is a JSON object with
+"status" and "entries" (entries) or "task_id"/"entry" (trigger).
+"""
+
+from __future__ import annotations
+
+import glob
+import json
+import os
+import shelve
+import shutil
+import sys
+import tempfile
+from datetime import datetime
+from datetime import timezone
+from typing import Any
+
+
+def app() -> Any:
+    from onyx.background.celery.versioned_apps.beat import app as beat_app
+
+    return beat_app
+
+
+def load_entries() -> dict[str, Any]:
+    filename = app().conf.beat_schedule_filename or "celerybeat-schedule"
+    files = glob.glob(filename + "*")
+    if not files:
+        raise FileNotFoundError(
+            f"No beat schedule file {filename!r} in {os.getcwd()}; is this the celery-beat pod?"
+        )
+    with tempfile.TemporaryDirectory() as tmp:
+        # dbm backends may split the shelve over several files (.dir, .dat,
+        # .bak); copy them all, keeping their suffixes.
+        for f in files:
+            shutil.copy(f, os.path.join(tmp, os.path.basename(f)))
+        copy = os.path.join(tmp, os.path.basename(filename))
+        with shelve.open(copy, flag="r") as db:
+            return dict(db.get("entries", {}))
+
+
+def describe(entry: Any) -> dict[str, Any]:
+    schedule = entry.schedule
+    run_every = getattr(schedule, "run_every", None)
+    interval = run_every.total_seconds() if run_every is not None else None
+    last_run = entry.last_run_at
+    due_in = None
+    if last_run is not None:
+        if last_run.tzinfo is None:
+            last_run = last_run.replace(tzinfo=timezone.utc)
+        due_in = schedule.remaining_estimate(last_run).total_seconds()
+    return {
+        "name": entry.name,
+        "task": entry.task,
+        "schedule": str(run_every) if run_every is not None else str(schedule),
+        "interval_seconds": interval,
+        "queue": (entry.options or {}).get("queue", ""),
+        "kwargs": {k: str(v) for k, v in (entry.kwargs or {}).items()},
+        "last_run": last_run.isoformat() if last_run is not None else None,
+        "total_run_count": entry.total_run_count,
+        "due_in_seconds": due_in,
+    }
+
+
+def list_entries() -> dict[str, Any]:
+    entries = load_entries()
+    return {
+        "status": "success",
+        "now": datetime.now(timezone.utc).isoformat(),
+        "entries": [describe(e) for e in entries.values()],
+    }
+
+
+def send_options(options: dict[str, Any]) -> dict[str, Any]:
+    # expires is relative to when beat queued the message; a manual trigger
+    # should not be dropped for sitting behind a backlog.
+    return {k: v for k, v in options.items() if k in ("queue", "priority")}
+
+
+def trigger(name: str, tenant: str) -> dict[str, Any]:
+    from onyx.configs.constants import ONYX_CLOUD_CELERY_TASK_PREFIX
+    from shared_configs.configs import MULTI_TENANT
+    from shared_configs.configs import POSTGRES_DEFAULT_SCHEMA
+
+    entries = load_entries()
+    if not tenant and not MULTI_TENANT:
+        tenant = POSTGRES_DEFAULT_SCHEMA
+
+    # Self-hosted beat schedules one entry per tenant, named <name>-<tenant>.
+    for candidate in (name, f"{name}-{tenant}"):
+        entry = entries.get(candidate)
+        if entry is None:
+            continue
+        kwargs = dict(entry.kwargs or {})
+        if tenant and "tenant_id" in kwargs and kwargs["tenant_id"] != tenant:
+            continue
+        result = app().send_task(
+            entry.task,
+            args=entry.args,
+            kwargs=kwargs,
+            **send_options(entry.options or {}),
+        )
+        return {"status": "success", "task_id": result.id, "entry": candidate}
+
+    # Multi-tenant beat schedules one generator per task, named
+    # cloud_<name>, that fans out to every tenant.
+    generator = entries.get(f"{ONYX_CLOUD_CELERY_TASK_PREFIX}_{name}") or entries.get(
+        name
+    )
+    if generator is not None and tenant:
+        kwargs = dict(generator.kwargs or {})
+        task_name = kwargs.get("task_name")
+        if task_name:
+            result = app().send_task(
+                task_name,
+                kwargs={"tenant_id": tenant},
+                **send_options(kwargs),
+            )
+            return {
+                "status": "success",
+                "task_id": result.id,
+                "entry": generator.name,
+            }
+    if generator is not None:
+        result = app().send_task(
+            generator.task,
+            args=generator.args,
+            kwargs=dict(generator.kwargs or {}),
+            **send_options(generator.options or {}),
+        )
+        return {"status": "success", "task_id": result.id, "entry": generator.name}
+
+    raise ValueError(f"No beat entry {name!r}; see `ods cron list`")
+
+
+def main() -> None:
+    usage = "Usage: python - entries | trigger <name> <tenant>"
+    args = sys.argv[1:]
+    arity = {"entries": 1, "trigger": 3}
+    if not args or arity.get(args[0]) != len(args):
+        print(json.dumps({"status": "error", "message": usage}))
+        sys.exit(1)
+
+    try:
+        if args[0] == "entries":
+            result = list_entries()
+        else:
+            result = trigger(args[1], args[2])
+    except Exception as e:
+        print(f"Error: {e}", file=sys.stderr)
+        result = {"status": "error", "message": str(e)}
+    print(json.dumps(result))
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,63 @@
+package cron
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseResultEntries(t *testing.T) {
+	out := "Connecting...\n" + strings.Join(strings.Fields(`{"status": "success", "entries": [
+		{"name": "check-for-pruning-public", "task": "check_for_pruning", "schedule": "0:00:20", "interval_seconds": 20.0,
+		 "queue": "", "kwargs": {"tenant_id": "public"}, "last_run": "2026-01-02T03:04:05.123456+00:00", "total_run_count": 7, "due_in_seconds": -4000.0},
+		{"name": "scheduled-eval-pipeline", "task": "scheduled_eval_task", "schedule": "<crontab: 0 0 0 * * (m/h/dM/MY/d)>", "interval_seconds": null,
+		 "queue": "", "kwargs": {}, "last_run": null, "total_run_count": 0, "due_in_seconds": null}
+	]}`), " ")
+	var r struct {
+		Entries []Entry `json:"entries"`
+	}
+	if err := parseResult(out, &r); err != nil {
+		t.Fatalf("parseResult() error: %v", err)
+	}
+	if len(r.Entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(r.Entries))
+	}
+	pruning := r.Entries[0]
+	if pruning.LastRun == nil || pruning.LastRun.Hour() != 3 || pruning.Kwargs["tenant_id"] != "public" {
+		t.Errorf("unexpected entry %+v", pruning)
+	}
+	if !pruning.Late() {
+		t.Error("an entry overdue by over an hour should be late")
+	}
+	if r.Entries[1].Late() {
+		t.Error("an entry that never ran is not late")
+	}
+
+	if err := parseResult(`{"status": "error", "message": "no beat schedule"}`, &r); err == nil || err.Error() != "no beat schedule" {
+		t.Errorf("expected the script's error, got %v", err)
+	}
+}
+
+func TestEntryLate(t *testing.T) {
+	f := func(v float64) *float64 { return &v }
+	now := time.Now()
+	ran := Entry{LastRun: &now}
+	tests := []struct {
+		interval, dueIn *float64
+		want            bool
+	}{
+		{interval: f(20), dueIn: f(-30), want: false},     // within the minimum grace
+		{interval: f(20), dueIn: f(-400), want: true},     // past the minimum grace
+		{interval: f(3600), dueIn: f(-1800), want: false}, // within one interval
+		{interval: f(3600), dueIn: f(-4000), want: true},
+		{interval: nil, dueIn: f(-600), want: true}, // crontab
+		{interval: f(20), dueIn: f(10), want: false},
+	}
+	for i, tt := range tests {
+		e := ran
+		e.IntervalSeconds, e.DueInSeconds = tt.interval, tt.dueIn
+		if got := e.Late(); got != tt.want {
+			t.Errorf("case %d: Late() = %v, want %v", i, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,323 @@
+// Package deps reports outdated dependencies across the monorepo's Python,
+// Node and Go projects, annotated with known advisories, and explains what
+// pulls a dependency in.
+package deps
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/audit"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/paths"
+)
+
+// Ecosystem is a package ecosystem, named as OSV names it so advisories
+// match.
+type Ecosystem string
+
+const (
+	Python Ecosystem = "PyPI"
+	Node   Ecosystem = "npm"
+	Go     Ecosystem = "Go"
+)
+
+// ParseEcosystem maps a user-facing ecosystem name (python, pip, node, npm,
+// go) to an Ecosystem.
+func ParseEcosystem(s string) (Ecosystem, bool) {
+	switch strings.ToLower(s) {
+	case "python", "pip", "pypi":
+		return Python, true
+	case "node", "npm", "js":
+		return Node, true
+	case "go":
+		return Go, true
+	}
+	return "", false
+}
+
+// Project is a directory with its own dependency manifest.
+type Project struct {
+	Ecosystem Ecosystem
+	// Dir is the project's directory relative to the repo root ("." for the
+	// root itself).
+	Dir string
+}
+
+// nodeProjectDirs are the directories of the monorepo's JS projects.
+var nodeProjectDirs = []string{".", "web", "desktop", "widget", "mobile"}
+
+// Projects returns the monorepo's projects, found under root.
+func Projects(root string) []Project {
+	var projects []Project
+	if exists(filepath.Join(root, "uv.lock")) {
+		projects = append(projects, Project{Ecosystem: Python, Dir: "."})
+	}
+	for _, dir := range nodeProjectDirs {
+		if exists(filepath.Join(root, dir, "package.json")) {
+			projects = append(projects, Project{Ecosystem: Node, Dir: dir})
+		}
+	}
+	for _, pattern := range []string{"*/go.mod", "tools/*/go.mod"} {
+		matches, _ := filepath.Glob(filepath.Join(root, pattern))
+		for _, m := range matches {
+			rel, _ := filepath.Rel(root, filepath.Dir(m))
+			projects = append(projects, Project{Ecosystem: Go, Dir: filepath.ToSlash(rel)})
+		}
+	}
+	return projects
+}
+
+// Lockfiles returns the lockfiles (go.mod for Go) of the projects that
+// osv-scanner can check for advisories.
+func Lockfiles(root string, projects []Project) []string {
+	var lockfiles []string
+	seen := map[string]bool{}
+	for _, p := range projects {
+		var name string
+		switch p.Ecosystem {
+		case Python:
+			name = "uv.lock"
+		case Node:
+			name = "bun.lock"
+		case Go:
+			name = "go.mod"
+		}
+		path := filepath.Join(root, p.Dir, name)
+		if exists(path) && !seen[path] {
+			seen[path] = true
+			lockfiles = append(lockfiles, path)
+		}
+	}
+	return lockfiles
+}
+
+// Outdated is a dependency with a newer release than the one installed.
+type Outdated struct {
+	Ecosystem Ecosystem `json:"ecosystem"`
+	Project   string    `json:"project"`
+	Package   string    `json:"package"`
+	Current   string    `json:"current"`
+	// Wanted is the newest release the manifest's constraint allows, where
+	// the ecosystem tells (npm).
+	Wanted string `json:"wanted,omitempty"`
+	Latest string `json:"latest"`
+	// Advisories are the known advisories against Current.
+	Advisories []audit.Finding `json:"advisories,omitempty"`
+}
+
+// Severity returns the highest severity of the dependency's advisories, or
+// "" if it has none.
+func (o Outdated) Severity() audit.Severity {
+	var worst audit.Severity
+	for _, f := range o.Advisories {
+		if worst == "" || f.Severity.AtLeast(worst) {
+			worst = f.Severity
+		}
+	}
+	return worst
+}
+
+// ListOutdated asks the project's package manager for its outdated
+// dependencies: uv pip list (the root venv) for Python, npm outdated for
+// Node and go list -m -u for the direct requirements of a Go module.
+func ListOutdated(root string, p Project) ([]Outdated, error) {
+	dir := filepath.Join(root, p.Dir)
+	var (
+		outdated []Outdated
+		err      error
+	)
+	switch p.Ecosystem {
+	case Python:
+		var out []byte
+		if out, err = run(dir, "uv", "pip", "list", "--outdated", "--format", "json"); err == nil {
+			outdated, err = parsePipOutdated(out)
+		}
+	case Node:
+		if !exists(filepath.Join(dir, "node_modules")) {
+			return nil, fmt.Errorf("%s has no node_modules; install its dependencies first", p.Dir)
+		}
+		// npm outdated exits 1 when anything is outdated.
+		out, runErr := run(dir, paths.Executable("npm"), "outdated", "--json")
+		var exitErr *exec.ExitError
+		if runErr != nil && !errors.As(runErr, &exitErr) {
+			return nil, runErr
+		}
+		outdated, err = parseNpmOutdated(out)
+	case Go:
+		var out []byte
+		if out, err = run(dir, "go", "list", "-m", "-u", "-json", "all"); err == nil {
+			outdated, err = parseGoOutdated(out)
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+	for i := range outdated {
+		outdated[i].Ecosystem = p.Ecosystem
+		outdated[i].Project = p.Dir
+	}
+	return outdated, nil
+}
+
+func parsePipOutdated(out []byte) ([]Outdated, error) {
+	var pkgs []struct {
+		Name          string `json:"name"`
+		Version       string `json:"version"`
+		LatestVersion string `json:"latest_version"`
+	}
+	if err := json.Unmarshal(out, &pkgs); err != nil {
+		return nil, fmt.Errorf("failed to parse uv pip list output: %w", err)
+	}
+	outdated := make([]Outdated, 0, len(pkgs))
+	for _, p := range pkgs {
+		outdated = append(outdated, Outdated{Package: p.Name, Current: p.Version, Latest: p.LatestVersion})
+	}
+	sortOutdated(outdated)
+	return outdated, nil
+}
+
+func parseNpmOutdated(out []byte) ([]Outdated, error) {
+	if len(bytes.TrimSpace(out)) == 0 {
+		return nil, nil
+	}
+	var pkgs map[string]struct {
+		Current string `json:"current"`
+		Wanted  string `json:"wanted"`
+		Latest  string `json:"latest"`
+	}
+	if err := json.Unmarshal(out, &pkgs); err != nil {
+		return nil, fmt.Errorf("failed to parse npm outdated output: %w", err)
+	}
+	outdated := make([]Outdated, 0, len(pkgs))
+	for name, p := range pkgs {
+		if p.Current == "" {
+			// Declared but not installed; nothing to compare.
+			continue
+		}
+		outdated = append(outdated, Outdated{Package: name, Current: p.Current, Wanted: p.Wanted, Latest: p.Latest})
+	}
+	sortOutdated(outdated)
+	return outdated, nil
+}
+
+// parseGoOutdated reads the stream of JSON objects go list -m -u -json all
+// prints, keeping the direct requirements that have an update.
+func parseGoOutdated(out []byte) ([]Outdated, error) {
+	var outdated []Outdated
+	dec := json.NewDecoder(bytes.NewReader(out))
+	for {
+		var m struct {
+			Path     string
+			Version  string
+			Main     bool
+			Indirect bool
+			Update   *struct{ Version string }
+		}
+		if err := dec.Decode(&m); errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			return nil, fmt.Errorf("failed to parse go list output: %w", err)
+		}
+		if m.Main || m.Indirect || m.Update == nil {
+			continue
+		}
+		outdated = append(outdated, Outdated{Package: m.Path, Current: m.Version, Latest: m.Update.Version})
+	}
+	sortOutdated(outdated)
+	return outdated, nil
+}
+
+func sortOutdated(outdated []Outdated) {
+	sort.Slice(outdated, func(i, j int) bool { return outdated[i].Package < outdated[j].Package })
+}
+
+// Annotate attaches to each outdated dependency the findings against its
+// current version.
+func Annotate(outdated []Outdated, findings []audit.Finding) {
+	byPackage := map[string][]audit.Finding{}
+	for _, f := range findings {
+		key := packageKey(Ecosystem(f.Ecosystem), f.Package, f.Version)
+		byPackage[key] = append(byPackage[key], f)
+	}
+	for i, o := range outdated {
+		outdated[i].Advisories = byPackage[packageKey(o.Ecosystem, o.Package, o.Current)]
+	}
+}
+
+var pythonNameSeparators = regexp.MustCompile(`[-_.]+`)
+
+// packageKey identifies a package version across tools; Python names are
+// normalized as PEP 503 does, since pip and the lockfile spell them
+// differently.
+func packageKey(eco Ecosystem, name, version string) string {
+	if eco == Python {
+		name = pythonNameSeparators.ReplaceAllString(strings.ToLower(name), "-")
+	}
+	return string(eco) + "\x00" + name + "\x00" + strings.TrimPrefix(version, "v")
+}
+
+// Why explains what pulls pkg into the project: uv tree --invert for
+// Python, npm ls for Node and go mod why for Go. found is false when the
+// project does not depend on pkg.
+func Why(root string, p Project, pkg string) (out string, found bool, err error) {
+	dir := filepath.Join(root, p.Dir)
+	var raw []byte
+	switch p.Ecosystem {
+	case Python:
+		raw, err = run(dir, "uv", "tree", "--frozen", "--invert", "--package", pkg)
+		if err != nil && strings.Contains(err.Error(), "not found") {
+			return "", false, nil
+		}
+	case Node:
+		raw, err = run(dir, paths.Executable("npm"), "ls", pkg, "--all")
+		// npm ls exits 1 when pkg is missing, printing "(empty)".
+		if bytes.Contains(raw, []byte("(empty)")) {
+			return "", false, nil
+		}
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) {
+			err = nil
+		}
+	case Go:
+		raw, err = run(dir, "go", "mod", "why", "-m", pkg)
+		if bytes.Contains(raw, []byte("does not need module")) {
+			return "", false, nil
+		}
+	}
+	if err != nil {
+		return "", false, err
+	}
+	out = strings.TrimSpace(string(raw))
+	return out, out != "", nil
+}
+
+// run runs a package manager in dir, returning its stdout. A failure
+// carries its stderr.
+func run(dir, name string, args ...string) ([]byte, error) {
+	cmd := exec.Command(name, args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	out, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return out, fmt.Errorf("%s %s: %w: %s", filepath.Base(name), strings.Join(args, " "), err, msg)
+		}
+		return out, fmt.Errorf("%s %s: %w", filepath.Base(name), strings.Join(args, " "), err)
+	}
+	return out, nil
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
@@ -0,0 +1,104 @@
+package deps
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/audit"
+)
+
+func TestParsePipOutdated(t *testing.T) {
+	out := []byte(`[{"name":"fastapi","version":"0.110.0","latest_version":"0.115.2","latest_filetype":"wheel"},
+		{"name":"Authlib","version":"1.3.0","latest_version":"1.3.2","latest_filetype":"wheel"}]`)
+	got, err := parsePipOutdated(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].Package != "Authlib" || got[1].Latest != "0.115.2" {
+		t.Errorf("parsePipOutdated() = %+v", got)
+	}
+}
+
+func TestParseNpmOutdated(t *testing.T) {
+	out := []byte(`{
+  "next": {"current": "14.2.3", "wanted": "14.2.15", "latest": "15.0.1", "dependent": "web"},
+  "not-installed": {"wanted": "1.0.0", "latest": "1.0.0", "dependent": "web"}
+}`)
+	got, err := parseNpmOutdated(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Package != "next" || got[0].Wanted != "14.2.15" {
+		t.Errorf("parseNpmOutdated() = %+v", got)
+	}
+	if got, err := parseNpmOutdated([]byte("\n")); err != nil || got != nil {
+		t.Errorf("parseNpmOutdated(empty) = %+v, %v", got, err)
+	}
+}
+
+func TestParseGoOutdated(t *testing.T) {
+	out := []byte(`{"Path": "github.com/onyx-dot-app/onyx/tools/ods", "Main": true}
+{"Path": "github.com/spf13/cobra", "Version": "v1.8.0", "Update": {"Path": "github.com/spf13/cobra", "Version": "v1.8.1"}}
+{"Path": "golang.org/x/sys", "Version": "v0.20.0", "Indirect": true, "Update": {"Version": "v0.26.0"}}
+{"Path": "github.com/sirupsen/logrus", "Version": "v1.9.3"}
+`)
+	got, err := parseGoOutdated(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 1 || got[0].Package != "github.com/spf13/cobra" || got[0].Latest != "v1.8.1" {
+		t.Errorf("parseGoOutdated() = %+v", got)
+	}
+}
+
+func TestAnnotate(t *testing.T) {
+	outdated := []Outdated{
+		{Ecosystem: Python, Package: "python_multipart", Current: "0.0.9"},
+		{Ecosystem: Go, Package: "golang.org/x/net", Current: "v0.20.0"},
+		{Ecosystem: Node, Package: "next", Current: "14.2.3"},
+	}
+	findings := []audit.Finding{
+		{ID: "GHSA-1", Ecosystem: "PyPI", Package: "python-multipart", Version: "0.0.9", Severity: audit.SeverityHigh},
+		{ID: "GHSA-2", Ecosystem: "PyPI", Package: "python-multipart", Version: "0.0.9", Severity: audit.SeverityCritical},
+		{ID: "GO-1", Ecosystem: "Go", Package: "golang.org/x/net", Version: "0.20.0", Severity: audit.SeverityModerate},
+		{ID: "GHSA-3", Ecosystem: "npm", Package: "next", Version: "14.2.4", Severity: audit.SeverityHigh},
+	}
+	Annotate(outdated, findings)
+	if len(outdated[0].Advisories) != 2 || outdated[0].Severity() != audit.SeverityCritical {
+		t.Errorf("python advisories = %+v", outdated[0].Advisories)
+	}
+	if len(outdated[1].Advisories) != 1 {
+		t.Errorf("go advisories = %+v", outdated[1].Advisories)
+	}
+	if len(outdated[2].Advisories) != 0 || outdated[2].Severity() != "" {
+		t.Errorf("expected no advisories for another version, got %+v", outdated[2].Advisories)
+	}
+}
+
+func TestProjects(t *testing.T) {
+	root := t.TempDir()
+	for _, f := range []string{"uv.lock", "package.json", "bun.lock", "web/package.json", "cli/go.mod", "tools/ods/go.mod"} {
+		path := filepath.Join(root, f)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	projects := Projects(root)
+	want := []Project{{Python, "."}, {Node, "."}, {Node, "web"}, {Go, "cli"}, {Go, "tools/ods"}}
+	if len(projects) != len(want) {
+		t.Fatalf("Projects() = %+v", projects)
+	}
+	for i := range want {
+		if projects[i] != want[i] {
+			t.Errorf("Projects()[%d] = %+v, want %+v", i, projects[i], want[i])
+		}
+	}
+	lockfiles := Lockfiles(root, projects)
+	if len(lockfiles) != 4 {
+		t.Errorf("Lockfiles() = %v", lockfiles)
+	}
+}
@@ -0,0 +1,237 @@
+// Package devtls issues the certificates that serve the local dev stack over
+// HTTPS, from mkcert when it is installed or otherwise from a local CA of
+// its own.
+package devtls
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/runner"
+)
+
+// DefaultDomain needs no hosts entry: browsers and most resolvers send
+// *.localhost to the loopback address.
+const DefaultDomain = "onyx.localhost"
+
+// Certificate and key file names, matching the nginx template's defaults
+// (SSL_CERT_FILE_NAME, SSL_CERT_KEY_FILE_NAME).
+const (
+	CertFile = "ssl.crt"
+	KeyFile  = "ssl.key"
+)
+
+const (
+	caCertFile = "ca.pem"
+	caKeyFile  = "ca-key.pem"
+	caValidity = 10 * 365 * 24 * time.Hour
+	// Apple platforms reject TLS server certificates valid for longer.
+	leafValidity = 825 * 24 * time.Hour
+)
+
+// Names returns the names a certificate for domain covers: the domain, its
+// subdomains (for tenant hosts) and the loopback addresses.
+func Names(domain string) []string {
+	return []string{domain, "*." + domain, "localhost", "127.0.0.1", "::1"}
+}
+
+// CA is a local certificate authority.
+type CA struct {
+	Cert *x509.Certificate
+	Key  *ecdsa.PrivateKey
+	// Path is the CA certificate file, for trusting it.
+	Path string
+}
+
+// LoadOrCreateCA loads the CA kept in dir, creating one if there is none.
+// It reports whether the CA is new (and so not trusted yet).
+func LoadOrCreateCA(dir string) (*CA, bool, error) {
+	certPath, keyPath := filepath.Join(dir, caCertFile), filepath.Join(dir, caKeyFile)
+	certPEM, certErr := os.ReadFile(certPath)
+	keyPEM, keyErr := os.ReadFile(keyPath)
+	if certErr == nil && keyErr == nil {
+		ca, err := parseCA(certPEM, keyPEM)
+		if err != nil {
+			return nil, false, fmt.Errorf("invalid CA in %s: %w", dir, err)
+		}
+		ca.Path = certPath
+		return ca, false, nil
+	}
+	if !errors.Is(certErr, os.ErrNotExist) && certErr != nil {
+		return nil, false, certErr
+	}
+
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, false, err
+	}
+	user := os.Getenv("USER")
+	if host, err := os.Hostname(); err == nil {
+		user += "@" + host
+	}
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber:          serial(),
+		Subject:               pkix.Name{Organization: []string{"ods local development CA"}, CommonName: "ods dev CA " + user},
+		NotBefore:             now.Add(-time.Hour),
+		NotAfter:              now.Add(caValidity),
+		KeyUsage:              x509.KeyUsageCertSign | x509.KeyUsageCRLSign,
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+		MaxPathLenZero:        true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		return nil, false, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		return nil, false, err
+	}
+	if err := writePair(certPath, keyPath, der, key); err != nil {
+		return nil, false, err
+	}
+	return &CA{Cert: cert, Key: key, Path: certPath}, true, nil
+}
+
+func parseCA(certPEM, keyPEM []byte) (*CA, error) {
+	certBlock, _ := pem.Decode(certPEM)
+	keyBlock, _ := pem.Decode(keyPEM)
+	if certBlock == nil || keyBlock == nil {
+		return nil, errors.New("not PEM")
+	}
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	key, err := x509.ParseECPrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	return &CA{Cert: cert, Key: key}, nil
+}
+
+// Issue writes a certificate for names, signed by the CA, and its key to
+// dir as CertFile and KeyFile.
+func (ca *CA) Issue(dir string, names []string) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	tmpl := &x509.Certificate{
+		SerialNumber: serial(),
+		Subject:      pkix.Name{Organization: []string{"ods local development"}, CommonName: names[0]},
+		NotBefore:    now.Add(-time.Hour),
+		NotAfter:     now.Add(leafValidity),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	for _, name := range names {
+		if ip := net.ParseIP(name); ip != nil {
+			tmpl.IPAddresses = append(tmpl.IPAddresses, ip)
+		} else {
+			tmpl.DNSNames = append(tmpl.DNSNames, name)
+		}
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.Cert, &key.PublicKey, ca.Key)
+	if err != nil {
+		return err
+	}
+	return writePair(filepath.Join(dir, CertFile), filepath.Join(dir, KeyFile), der, key)
+}
+
+func writePair(certPath, keyPath string, der []byte, key *ecdsa.PrivateKey) error {
+	if err := os.MkdirAll(filepath.Dir(certPath), 0o700); err != nil {
+		return err
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		return err
+	}
+	return os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o644)
+}
+
+func serial() *big.Int {
+	n, _ := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 127))
+	return n
+}
+
+// HasMkcert reports whether mkcert is on PATH.
+func HasMkcert() bool {
+	_, err := exec.LookPath("mkcert")
+	return err == nil
+}
+
+// IssueWithMkcert installs mkcert's CA into the system and browser trust
+// stores (prompting for a password if needed) and writes a certificate for
+// names to dir, running mkcert with r (nil for runner.Default).
+func IssueWithMkcert(r runner.Runner, dir string, names []string) error {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return err
+	}
+	install := runner.Cmd{Name: "mkcert", Args: []string{"-install"}, Stdin: os.Stdin, Stdout: os.Stderr, Stderr: os.Stderr}
+	if err := runner.Or(r).Run(install); err != nil {
+		return fmt.Errorf("mkcert -install failed: %w", err)
+	}
+	args := append([]string{"-cert-file", filepath.Join(dir, CertFile), "-key-file", filepath.Join(dir, KeyFile)}, names...)
+	if out, err := runner.CombinedOutput(r, runner.Cmd{Name: "mkcert", Args: args}); err != nil {
+		return fmt.Errorf("mkcert failed: %w\n%s", err, out)
+	}
+	return nil
+}
+
+// TrustInstructions explains how to trust the CA at caPath on goos.
+func TrustInstructions(caPath, goos string) string {
+	switch goos {
+	case "darwin":
+		return fmt.Sprintf("sudo security add-trusted-cert -d -r trustRoot -k /Library/Keychains/System.keychain %q", caPath)
+	case "windows":
+		return fmt.Sprintf("certutil -addstore -f ROOT %q   (in an administrator prompt)", caPath)
+	default:
+		return strings.Join([]string{
+			fmt.Sprintf("sudo cp %q /usr/local/share/ca-certificates/ods-dev-ca.crt && sudo update-ca-certificates", caPath),
+			"  (Debian/Ubuntu; on Fedora copy it to /etc/pki/ca-trust/source/anchors/ and run update-ca-trust)",
+			"  Firefox and Chrome on Linux keep their own stores: import it under Settings > Certificates.",
+		}, "\n")
+	}
+}
+
+// WebDomain is the URL the stack is served at over HTTPS on port.
+func WebDomain(domain string, port int) string {
+	if port == 443 {
+		return "https://" + domain
+	}
+	return fmt.Sprintf("https://%s:%d", domain, port)
+}
+
+// Resolves reports whether domain resolves to a loopback address, as a
+// custom domain needs a hosts entry to.
+func Resolves(domain string) bool {
+	addrs, err := net.LookupHost(domain)
+	if err != nil {
+		return false
+	}
+	for _, a := range addrs {
+		if ip := net.ParseIP(a); ip != nil && ip.IsLoopback() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,79 @@
+package devtls
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/runner"
+)
+
+func TestIssueVerifiesAgainstCA(t *testing.T) {
+	dir := t.TempDir()
+	ca, created, err := LoadOrCreateCA(dir)
+	if err != nil || !created {
+		t.Fatalf("LoadOrCreateCA() = %v, %v", created, err)
+	}
+	again, created, err := LoadOrCreateCA(dir)
+	if err != nil || created || !again.Cert.Equal(ca.Cert) {
+		t.Fatalf("reloading the CA = %v, %v", created, err)
+	}
+
+	certDir := filepath.Join(dir, "onyx.localhost")
+	if err := again.Issue(certDir, Names("onyx.localhost")); err != nil {
+		t.Fatal(err)
+	}
+	pair, err := tls.LoadX509KeyPair(filepath.Join(certDir, CertFile), filepath.Join(certDir, KeyFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	leaf, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.Cert)
+	for _, name := range []string{"onyx.localhost", "acme.onyx.localhost", "localhost", "127.0.0.1"} {
+		if _, err := leaf.Verify(x509.VerifyOptions{DNSName: name, Roots: roots}); err != nil {
+			t.Errorf("Verify(%s): %v", name, err)
+		}
+	}
+	if _, err := leaf.Verify(x509.VerifyOptions{DNSName: "example.com", Roots: roots}); err == nil {
+		t.Error("certificate verified for an unrelated name")
+	}
+
+	info, err := os.Stat(filepath.Join(certDir, KeyFile))
+	if err != nil || info.Mode().Perm() != 0o600 {
+		t.Errorf("key file mode = %v, %v", info.Mode(), err)
+	}
+}
+
+func TestWebDomain(t *testing.T) {
+	if got := WebDomain("onyx.localhost", 443); got != "https://onyx.localhost" {
+		t.Errorf("WebDomain(443) = %q", got)
+	}
+	if got := WebDomain("onyx.test", 8443); got != "https://onyx.test:8443" {
+		t.Errorf("WebDomain(8443) = %q", got)
+	}
+}
+
+func TestIssueWithMkcert(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "tls")
+	f := (&runner.Fake{}).On("mkcert", runner.Response{})
+	if err := IssueWithMkcert(f, dir, []string{"onyx.localhost", "*.onyx.localhost"}); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"mkcert -install",
+		"mkcert -cert-file " + filepath.Join(dir, CertFile) + " -key-file " + filepath.Join(dir, KeyFile) + " onyx.localhost *.onyx.localhost",
+	}
+	if got := f.Lines(); !reflect.DeepEqual(got, want) {
+		t.Errorf("commands = %q, want %q", got, want)
+	}
+	if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+		t.Errorf("expected %s to be created", dir)
+	}
+}
@@ -0,0 +1,139 @@
+// Package dist builds release artifacts of the ods binary itself: one binary
+// per GOOS/GOARCH target plus a SHA256SUMS manifest.
+package dist
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/runner"
+)
+
+// ChecksumsFile is the name of the checksum manifest written next to the
+// binaries. Its format matches `sha256sum` so it can be verified with
+// `sha256sum -c SHA256SUMS`.
+const ChecksumsFile = "SHA256SUMS"
+
+// DefaultTargets are the platforms teammates run ods on.
+var DefaultTargets = []Target{
+	{OS: "darwin", Arch: "amd64"},
+	{OS: "darwin", Arch: "arm64"},
+	{OS: "linux", Arch: "amd64"},
+	{OS: "linux", Arch: "arm64"},
+}
+
+// Target is a single GOOS/GOARCH pair.
+type Target struct {
+	OS   string
+	Arch string
+}
+
+func (t Target) String() string {
+	return t.OS + "/" + t.Arch
+}
+
+// ParseTargets parses a comma-separated list of os/arch pairs
+// (e.g. "linux/amd64,darwin/arm64").
+func ParseTargets(spec string) ([]Target, error) {
+	var targets []Target
+	seen := make(map[Target]bool)
+	for _, part := range strings.Split(spec, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		goos, goarch, ok := strings.Cut(part, "/")
+		if !ok || goos == "" || goarch == "" {
+			return nil, fmt.Errorf("invalid target %q: expected os/arch", part)
+		}
+		t := Target{OS: goos, Arch: goarch}
+		if !seen[t] {
+			seen[t] = true
+			targets = append(targets, t)
+		}
+	}
+	if len(targets) == 0 {
+		return nil, fmt.Errorf("no targets given")
+	}
+	return targets, nil
+}
+
+// ArtifactName returns the file name of the binary built for a target,
+// e.g. ods_1.2.3_linux_amd64 (with .exe on windows).
+func ArtifactName(version string, t Target) string {
+	name := fmt.Sprintf("ods_%s_%s_%s", version, t.OS, t.Arch)
+	if t.OS == "windows" {
+		name += ".exe"
+	}
+	return name
+}
+
+// LDFlags returns the linker flags that stamp version metadata into main.
+func LDFlags(version, commit string) string {
+	return fmt.Sprintf("-X main.version=%s -X main.commit=%s -s -w", version, commit)
+}
+
+// Build cross-compiles the ods module at srcDir for the given target with r
+// (nil for runner.Default) and writes the binary into outDir. It returns the
+// path of the built binary.
+func Build(r runner.Runner, srcDir, outDir, version, commit string, t Target) (string, error) {
+	out := filepath.Join(outDir, ArtifactName(version, t))
+	log.Infof("Building %s -> %s", t, out)
+
+	cmd := runner.Cmd{
+		Name:   "go",
+		Args:   []string{"build", "-trimpath", "-ldflags", LDFlags(version, commit), "-o", out, "."},
+		Dir:    srcDir,
+		Env:    append(os.Environ(), "CGO_ENABLED=0", "GOOS="+t.OS, "GOARCH="+t.Arch),
+		Stdout: os.Stderr,
+		Stderr: os.Stderr,
+	}
+	if err := runner.Or(r).Run(cmd); err != nil {
+		return "", fmt.Errorf("go build for %s failed: %w", t, err)
+	}
+	return out, nil
+}
+
+// WriteChecksums writes a sha256sum-compatible manifest of files into
+// outDir/SHA256SUMS and returns its path. Entries are sorted by file name.
+func WriteChecksums(outDir string, files []string) (string, error) {
+	sorted := append([]string(nil), files...)
+	sort.Slice(sorted, func(i, j int) bool { return filepath.Base(sorted[i]) < filepath.Base(sorted[j]) })
+
+	var b strings.Builder
+	for _, f := range sorted {
+		sum, err := fileSHA256(f)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(&b, "%s  %s\n", sum, filepath.Base(f))
+	}
+
+	path := filepath.Join(outDir, ChecksumsFile)
+	if err := os.WriteFile(path, []byte(b.String()), 0644); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return path, nil
+}
+
+func fileSHA256(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to open %s: %w", path, err)
+	}
+	defer func() { _ = f.Close() }()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", fmt.Errorf("failed to hash %s: %w", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,91 @@
+package dist
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/runner"
+)
+
+func TestParseTargets(t *testing.T) {
+	got, err := ParseTargets("linux/amd64, darwin/arm64,linux/amd64")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	want := []Target{{OS: "linux", Arch: "amd64"}, {OS: "darwin", Arch: "arm64"}}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ParseTargets() = %v, want %v", got, want)
+	}
+
+	for _, bad := range []string{"", "linux", "linux/", "/amd64"} {
+		if _, err := ParseTargets(bad); err == nil {
+			t.Errorf("ParseTargets(%q) should fail", bad)
+		}
+	}
+}
+
+func TestArtifactName(t *testing.T) {
+	if got := ArtifactName("1.2.3", Target{OS: "linux", Arch: "arm64"}); got != "ods_1.2.3_linux_arm64" {
+		t.Errorf("unexpected name %q", got)
+	}
+	if got := ArtifactName("1.2.3", Target{OS: "windows", Arch: "amd64"}); got != "ods_1.2.3_windows_amd64.exe" {
+		t.Errorf("unexpected name %q", got)
+	}
+}
+
+func TestWriteChecksums(t *testing.T) {
+	dir := t.TempDir()
+	b := filepath.Join(dir, "b")
+	a := filepath.Join(dir, "a")
+	if err := os.WriteFile(a, []byte("hello\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(b, []byte(""), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	path, err := WriteChecksums(dir, []string{b, a})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "5891b5b522d5df086d0ff0b110fbd9d21bb4fc7163af34d08286a2e846f6be03  a\n" +
+		"e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855  b\n"
+	if string(data) != want {
+		t.Errorf("unexpected checksums:\n%s\nwant:\n%s", data, want)
+	}
+}
+
+func TestBuild(t *testing.T) {
+	f := (&runner.Fake{}).On("go build", runner.Response{})
+	out, err := Build(f, "/src/tools/ods", "/out", "1.2.3", "abc123", Target{OS: "windows", Arch: "amd64"})
+	if err != nil {
+		t.Fatalf("Build() error: %v", err)
+	}
+	if out != filepath.Join("/out", "ods_1.2.3_windows_amd64.exe") {
+		t.Errorf("Build() = %q", out)
+	}
+
+	calls := f.Calls()
+	if len(calls) != 1 {
+		t.Fatalf("expected one command, got %q", f.Lines())
+	}
+	want := "go build -trimpath -ldflags " + LDFlags("1.2.3", "abc123") + " -o " + out + " ."
+	if got := calls[0].String(); got != want {
+		t.Errorf("command = %q, want %q", got, want)
+	}
+	if calls[0].Dir != "/src/tools/ods" {
+		t.Errorf("dir = %q", calls[0].Dir)
+	}
+	for _, kv := range []string{"CGO_ENABLED=0", "GOOS=windows", "GOARCH=amd64"} {
+		if !slices.Contains(calls[0].Env, kv) {
+			t.Errorf("expected %s in the environment", kv)
+		}
+	}
+}
@@ -0,0 +1,316 @@
+package docker
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/runner"
+)
+
+// SourceHashLabel is the image label recording the hash of the sources an
+// image was built from, so unchanged images can be reused.
+const SourceHashLabel = "app.onyx.ods.source-hash"
+
+// BuildSpec is an image the compose project builds, with the services that
+// run it.
+type BuildSpec struct {
+	Image string
+	// Context is the absolute build context; Dockerfile is relative to it.
+	Context    string
+	Dockerfile string
+	Target     string
+	Args       map[string]string
+	Services   []string
+}
+
+type composeConfigJSON struct {
+	Services map[string]struct {
+		Image string `json:"image"`
+		Build *struct {
+			Context    string             `json:"context"`
+			Dockerfile string             `json:"dockerfile"`
+			Target     string             `json:"target"`
+			Args       map[string]*string `json:"args"`
+		} `json:"build"`
+	} `json:"services"`
+}
+
+// ComposeBuilds returns the images built by the services in the output of
+// `docker compose config --format json`, limited to services if any are
+// given. Services sharing an image share a spec.
+func ComposeBuilds(config []byte, services []string) ([]*BuildSpec, error) {
+	var cfg composeConfigJSON
+	if err := json.Unmarshal(config, &cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse compose config: %w", err)
+	}
+	names := make([]string, 0, len(cfg.Services))
+	for name := range cfg.Services {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	byImage := map[string]*BuildSpec{}
+	var specs []*BuildSpec
+	for _, name := range names {
+		svc := cfg.Services[name]
+		if svc.Build == nil || svc.Image == "" || (len(services) > 0 && !slices.Contains(services, name)) {
+			continue
+		}
+		if spec, ok := byImage[svc.Image]; ok {
+			spec.Services = append(spec.Services, name)
+			continue
+		}
+		spec := &BuildSpec{
+			Image:      svc.Image,
+			Context:    svc.Build.Context,
+			Dockerfile: svc.Build.Dockerfile,
+			Target:     svc.Build.Target,
+			Args:       map[string]string{},
+			Services:   []string{name},
+		}
+		if spec.Dockerfile == "" {
+			spec.Dockerfile = "Dockerfile"
+		}
+		for k, v := range svc.Build.Args {
+			if v != nil {
+				spec.Args[k] = *v
+			}
+		}
+		byImage[svc.Image] = spec
+		specs = append(specs, spec)
+	}
+	return specs, nil
+}
+
+// DockerfileInputs returns the context paths a Dockerfile copies from,
+// cleaned and relative to the context; "." means the whole context. Copies
+// from other stages and images are not inputs.
+func DockerfileInputs(dockerfile []byte) []string {
+	var inputs []string
+	for _, inst := range dockerfileInstructions(dockerfile) {
+		fields := strings.Fields(inst)
+		if len(fields) < 3 {
+			continue
+		}
+		cmd := strings.ToUpper(fields[0])
+		if cmd != "COPY" && cmd != "ADD" {
+			continue
+		}
+		args := fields[1:]
+		if rest := strings.TrimSpace(inst[len(fields[0]):]); strings.HasPrefix(rest, "[") {
+			var list []string
+			if json.Unmarshal([]byte(rest), &list) == nil {
+				args = list
+			}
+		}
+		var srcs []string
+		fromStage := false
+		for _, arg := range args {
+			if strings.HasPrefix(arg, "--") {
+				fromStage = fromStage || strings.HasPrefix(arg, "--from=")
+				continue
+			}
+			srcs = append(srcs, arg)
+		}
+		if fromStage || len(srcs) < 2 {
+			continue
+		}
+		for _, src := range srcs[:len(srcs)-1] {
+			if strings.Contains(src, "://") {
+				continue
+			}
+			inputs = append(inputs, path.Clean(strings.TrimPrefix(src, "/")))
+		}
+	}
+	return inputs
+}
+
+// dockerfileInstructions joins continuation lines and drops comments.
+func dockerfileInstructions(dockerfile []byte) []string {
+	var insts []string
+	var cur strings.Builder
+	scanner := bufio.NewScanner(bytes.NewReader(dockerfile))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		if strings.HasSuffix(line, "\\") {
+			cur.WriteString(strings.TrimSuffix(line, "\\") + " ")
+			continue
+		}
+		cur.WriteString(line)
+		if s := strings.TrimSpace(cur.String()); s != "" {
+			insts = append(insts, s)
+		}
+		cur.Reset()
+	}
+	if s := strings.TrimSpace(cur.String()); s != "" {
+		insts = append(insts, s)
+	}
+	return insts
+}
+
+// dockerignore is a parsed .dockerignore.
+type dockerignore []struct {
+	pattern string
+	negate  bool
+}
+
+func parseDockerignore(data []byte) dockerignore {
+	var d dockerignore
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		negate := strings.HasPrefix(line, "!")
+		line = strings.Trim(strings.TrimPrefix(line, "!"), "/")
+		d = append(d, struct {
+			pattern string
+			negate  bool
+		}{path.Clean(line), negate})
+	}
+	return d
+}
+
+// Excludes reports whether the context path name is left out of the build
+// context. The last matching pattern wins, as in docker.
+func (d dockerignore) Excludes(name string) bool {
+	excluded := false
+	for _, p := range d {
+		if ignoreMatch(p.pattern, name) {
+			excluded = !p.negate
+		}
+	}
+	return excluded
+}
+
+// ignoreMatch matches name or one of its parent directories against a
+// pattern, where a leading **/ matches at any depth.
+func ignoreMatch(pattern, name string) bool {
+	anyDepth := strings.HasPrefix(pattern, "**/")
+	pattern = strings.TrimPrefix(pattern, "**/")
+	parts := strings.Split(name, "/")
+	for start := 0; start < len(parts); start++ {
+		if start > 0 && !anyDepth {
+			break
+		}
+		for end := start + 1; end <= len(parts); end++ {
+			if ok, _ := path.Match(pattern, strings.Join(parts[start:end], "/")); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// SourceHash hashes everything that goes into the image: the Dockerfile,
+// the build target and arguments, and the files the Dockerfile copies that
+// git tracks or would track (so ignored build output does not count),
+// minus .dockerignore'd ones. Uncommitted edits count.
+func (b *BuildSpec) SourceHash() (string, error) {
+	dockerfilePath := b.Dockerfile
+	if !filepath.IsAbs(dockerfilePath) {
+		dockerfilePath = filepath.Join(b.Context, dockerfilePath)
+	}
+	dockerfile, err := os.ReadFile(dockerfilePath)
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %w", dockerfilePath, err)
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "dockerfile\x00%s\x00target\x00%s\x00", dockerfile, b.Target)
+	keys := make([]string, 0, len(b.Args))
+	for k := range b.Args {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		fmt.Fprintf(h, "arg\x00%s=%s\x00", k, b.Args[k])
+	}
+
+	inputs := DockerfileInputs(dockerfile)
+	args := []string{"-C", b.Context, "ls-files", "-z", "--cached", "--others", "--exclude-standard"}
+	if !slices.Contains(inputs, ".") {
+		if len(inputs) == 0 {
+			return hex.EncodeToString(h.Sum(nil)), nil
+		}
+		args = append(append(args, "--"), inputs...)
+	}
+	out, err := runner.Output(Runner, runner.Cmd{Name: "git", Args: args})
+	if err != nil {
+		return "", fmt.Errorf("failed to list files in %s: %w", b.Context, err)
+	}
+	ignoreData, _ := os.ReadFile(filepath.Join(b.Context, ".dockerignore"))
+	ignore := parseDockerignore(ignoreData)
+
+	files := strings.Split(strings.TrimSuffix(string(out), "\x00"), "\x00")
+	sort.Strings(files)
+	for _, name := range slices.Compact(files) {
+		if name == "" || ignore.Excludes(name) {
+			continue
+		}
+		f, err := os.Open(filepath.Join(b.Context, filepath.FromSlash(name)))
+		if err != nil {
+			continue // deleted but still in the index
+		}
+		fh := sha256.New()
+		_, err = io.Copy(fh, f)
+		_ = f.Close()
+		if err != nil {
+			continue // a directory, e.g. a submodule
+		}
+		fmt.Fprintf(h, "file\x00%s\x00%x\x00", name, fh.Sum(nil))
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// ImageSourceHash returns the source hash label of a local image, or ""
+// if the image does not exist or was not built by ods.
+func ImageSourceHash(image string) string {
+	out, err := output("image", "inspect", "--format",
+		fmt.Sprintf(`{{index .Config.Labels %q}}`, SourceHashLabel), image)
+	if err != nil {
+		return ""
+	}
+	hash := strings.TrimSpace(string(out))
+	if hash == "<no value>" {
+		return ""
+	}
+	return hash
+}
+
+// BuildCommandArgs returns the docker arguments that build the image,
+// labelled with hash.
+func (b *BuildSpec) BuildCommandArgs(hash string) []string {
+	dockerfile := b.Dockerfile
+	if !filepath.IsAbs(dockerfile) {
+		dockerfile = filepath.Join(b.Context, dockerfile)
+	}
+	args := []string{"build", "-t", b.Image, "-f", dockerfile, "--label", SourceHashLabel + "=" + hash}
+	if b.Target != "" {
+		args = append(args, "--target", b.Target)
+	}
+	keys := make([]string, 0, len(b.Args))
+	for k := range b.Args {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		args = append(args, "--build-arg", k+"="+b.Args[k])
+	}
+	return append(args, b.Context)
+}
@@ -0,0 +1,132 @@
+package docker
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDockerfileInputs(t *testing.T) {
+	dockerfile := `FROM python:3.13 AS builder
+COPY --from=ghcr.io/astral-sh/uv:0.11 /uv /uvx /bin/
+# COPY ./commented /nope
+COPY ./requirements/default.txt /tmp/requirements.txt
+COPY --from=builder /usr/local/lib /usr/local/lib
+COPY --chown=onyx:onyx ./onyx /app/onyx
+COPY a.txt \
+     b/ /app/
+ADD ["./static", "/app/static"]
+ADD https://example.com/x.tar.gz /tmp/
+RUN echo done
+`
+	got := DockerfileInputs([]byte(dockerfile))
+	want := []string{"requirements/default.txt", "onyx", "a.txt", "b", "static"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DockerfileInputs() = %q, want %q", got, want)
+	}
+	if got := DockerfileInputs([]byte("FROM node\nCOPY . .\n")); !reflect.DeepEqual(got, []string{"."}) {
+		t.Errorf("DockerfileInputs(COPY . .) = %q", got)
+	}
+}
+
+func TestDockerignore(t *testing.T) {
+	d := parseDockerignore([]byte("node_modules\n/tests/\n**/__pycache__\n*.log\nbuild\n!src/app/build\n"))
+	for name, want := range map[string]bool{
+		"node_modules/react/index.js":  true,
+		"tests/e2e/a.spec.ts":          true,
+		"src/tests/a.ts":               false,
+		"onyx/db/__pycache__/x.pyc":    true,
+		"server.log":                   true,
+		"build/out.js":                 true,
+		"src/app/build/page.tsx":       false,
+		"src/app/page.tsx":             false,
+		"lib/node_modules_notes/a.txt": false,
+	} {
+		if got := d.Excludes(name); got != want {
+			t.Errorf("Excludes(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestComposeBuilds(t *testing.T) {
+	config := `{"services": {
+		"api_server": {"image": "onyxdotapp/onyx-backend:latest", "build": {"context": "/r/backend", "dockerfile": "Dockerfile"}},
+		"background": {"image": "onyxdotapp/onyx-backend:latest", "build": {"context": "/r/backend", "dockerfile": "Dockerfile"}},
+		"web_server": {"image": "onyxdotapp/onyx-web-server:latest", "build": {"context": "/r/web", "args": {"NODE_OPTIONS": "--max-old-space-size=8192", "UNSET": null}}},
+		"cache": {"image": "redis:7.4-alpine"}
+	}}`
+	specs, err := ComposeBuilds([]byte(config), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(specs) != 2 || !reflect.DeepEqual(specs[0].Services, []string{"api_server", "background"}) {
+		t.Fatalf("specs = %+v", specs)
+	}
+	if specs[1].Dockerfile != "Dockerfile" || !reflect.DeepEqual(specs[1].Args, map[string]string{"NODE_OPTIONS": "--max-old-space-size=8192"}) {
+		t.Errorf("web spec = %+v", specs[1])
+	}
+	args := strings.Join(specs[1].BuildCommandArgs("abc"), " ")
+	if args != "build -t onyxdotapp/onyx-web-server:latest -f /r/web/Dockerfile --label "+SourceHashLabel+"=abc --build-arg NODE_OPTIONS=--max-old-space-size=8192 /r/web" {
+		t.Errorf("BuildCommandArgs() = %s", args)
+	}
+
+	specs, _ = ComposeBuilds([]byte(config), []string{"cache", "web_server"})
+	if len(specs) != 1 || specs[0].Image != "onyxdotapp/onyx-web-server:latest" {
+		t.Errorf("specs for web_server = %+v", specs)
+	}
+}
+
+func TestSourceHash(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) {
+		t.Helper()
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if out, err := exec.Command("git", "-C", dir, "init", "-q").CombinedOutput(); err != nil {
+		t.Fatalf("git init: %v\n%s", err, out)
+	}
+	write("Dockerfile", "FROM python\nCOPY ./app /app\n")
+	write(".gitignore", "app/generated.py\n")
+	write(".dockerignore", "**/__pycache__\n")
+	write("app/main.py", "print(1)\n")
+	write("tests/test_main.py", "def test(): pass\n")
+
+	spec := &BuildSpec{Image: "x", Context: dir, Dockerfile: "Dockerfile", Args: map[string]string{}}
+	hash := func() string {
+		t.Helper()
+		h, err := spec.SourceHash()
+		if err != nil {
+			t.Fatal(err)
+		}
+		return h
+	}
+	base := hash()
+
+	// Files the image does not copy, ignored build output and
+	// .dockerignore'd files do not change the hash.
+	write("tests/test_main.py", "def test(): assert True\n")
+	write("app/generated.py", "x = 1\n")
+	write("app/__pycache__/main.pyc", "bytes")
+	if got := hash(); got != base {
+		t.Error("hash changed for files outside the image's inputs")
+	}
+
+	write("app/main.py", "print(2)\n")
+	edited := hash()
+	if edited == base {
+		t.Error("hash did not change for an edited input")
+	}
+	spec.Args["MODE"] = "dev"
+	if hash() == edited {
+		t.Error("hash did not change for a new build argument")
+	}
+}
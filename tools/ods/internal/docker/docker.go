@@ -3,12 +3,29 @@ package docker
 import (
 	"bytes"
 	"fmt"
+	"io"
 	"os"
-	"os/exec"
 	"strconv"
 	"strings"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/runner"
 )
 
+// Runner runs docker and git; nil means runner.Default. Tests set a runner.Fake.
+var Runner runner.Runner
+
+// run runs docker with args.
+func run(args []string, stdin io.Reader, stdout, stderr io.Writer) error {
+	return runner.Or(Runner).Run(runner.Cmd{Name: "docker", Args: args, Stdin: stdin, Stdout: stdout, Stderr: stderr})
+}
+
+// output runs docker with args and returns its stdout.
+func output(args ...string) ([]byte, error) {
+	var stdout bytes.Buffer
+	err := run(args, nil, &stdout, nil)
+	return stdout.Bytes(), err
+}
+
 // legacyPostgresContainerNames are fallback names tried after the
 // project-specific name.
 var legacyPostgresContainerNames = []string{
@@ -37,12 +54,11 @@ func FindPostgresContainer(projectName string) (string, error) {
 	// Fall back to searching for any postgres container by image name. Try
 	// multiple filters since the image name may vary (postgres,
 	// postgres:15.2-alpine, etc.)
-	cmd := exec.Command("docker", "ps", "--format", "{{.Names}}\t{{.Image}}")
-	output, err := cmd.Output()
+	out, err := output("ps", "--format", "{{.Names}}\t{{.Image}}")
 	if err == nil {
-		lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+		lines := strings.Split(strings.TrimSpace(string(out)), "\n")
 		for _, line := range lines {
-			parts := strings.Split(line, "\t")
+			parts := strings.Split(strings.TrimSpace(line), "\t")
 			if len(parts) >= 2 {
 				name, image := parts[0], parts[1]
 				if strings.Contains(image, "postgres") {
@@ -57,22 +73,17 @@ func FindPostgresContainer(projectName string) (string, error) {
 
 // isContainerRunning checks if a container with the given name is running.
 func isContainerRunning(name string) bool {
-	cmd := exec.Command("docker", "inspect", "-f", "{{.State.Running}}", name)
-	output, err := cmd.Output()
+	out, err := output("inspect", "-f", "{{.State.Running}}", name)
 	if err != nil {
 		return false
 	}
-	return strings.TrimSpace(string(output)) == "true"
+	return strings.TrimSpace(string(out)) == "true"
 }
 
 // Exec runs a command inside a Docker container.
 func Exec(container string, args ...string) error {
 	dockerArgs := append([]string{"exec", "-i", container}, args...)
-	cmd := exec.Command("docker", dockerArgs...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	cmd.Stdin = os.Stdin
-	return cmd.Run()
+	return run(dockerArgs, os.Stdin, os.Stdout, os.Stderr)
 }
 
 // ExecWithEnv runs a command inside a Docker container with environment
@@ -84,23 +95,14 @@ func ExecWithEnv(container string, env map[string]string, args ...string) error
 	}
 	dockerArgs = append(dockerArgs, container)
 	dockerArgs = append(dockerArgs, args...)
-
-	cmd := exec.Command("docker", dockerArgs...)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	cmd.Stdin = os.Stdin
-	return cmd.Run()
+	return run(dockerArgs, os.Stdin, os.Stdout, os.Stderr)
 }
 
 // ExecOutput runs a command inside a Docker container and returns its output.
 func ExecOutput(container string, args ...string) (string, error) {
 	dockerArgs := append([]string{"exec", "-i", container}, args...)
-	cmd := exec.Command("docker", dockerArgs...)
 	var stdout, stderr bytes.Buffer
-	cmd.Stdout = &stdout
-	cmd.Stderr = &stderr
-	err := cmd.Run()
-	if err != nil {
+	if err := run(dockerArgs, nil, &stdout, &stderr); err != nil {
 		return "", fmt.Errorf("%w: %s", err, stderr.String())
 	}
 	return stdout.String(), nil
@@ -108,18 +110,12 @@ func ExecOutput(container string, args ...string) (string, error) {
 
 // CopyFromContainer copies a file from a container to the host.
 func CopyFromContainer(container, src, dst string) error {
-	cmd := exec.Command("docker", "cp", fmt.Sprintf("%s:%s", container, src), dst)
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
+	return run([]string{"cp", fmt.Sprintf("%s:%s", container, src), dst}, nil, os.Stdout, os.Stderr)
 }
 
 // CopyToContainer copies a file from the host to a container.
 func CopyToContainer(container, src, dst string) error {
-	cmd := exec.Command("docker", "cp", src, fmt.Sprintf("%s:%s", container, dst))
-	cmd.Stdout = os.Stdout
-	cmd.Stderr = os.Stderr
-	return cmd.Run()
+	return run([]string{"cp", src, fmt.Sprintf("%s:%s", container, dst)}, nil, os.Stdout, os.Stderr)
 }
 
 // GetContainerIP returns the IP address of a container. It returns the first
@@ -127,15 +123,14 @@ func CopyToContainer(container, src, dst string) error {
 func GetContainerIP(container string) (string, error) {
 	// Get IPs from the container's network settings (space-separated if
 	// multiple).
-	cmd := exec.Command("docker", "inspect", "-f",
+	out, err := output("inspect", "-f",
 		"{{range .NetworkSettings.Networks}}{{.IPAddress}} {{end}}", container)
-	output, err := cmd.Output()
 	if err != nil {
 		return "", fmt.Errorf("failed to get container IP: %w", err)
 	}
 
 	// Take the first IP if there are multiple
-	ips := strings.Fields(string(output))
+	ips := strings.Fields(string(out))
 	if len(ips) == 0 {
 		return "", fmt.Errorf("container %s has no IP address", container)
 	}
@@ -147,8 +142,7 @@ func GetContainerIP(container string) (string, error) {
 // host-side port number. Returns an error if the container is not running or the
 // port is not mapped.
 func GetHostPort(container string, containerPort int) (int, error) {
-	cmd := exec.Command("docker", "port", container, strconv.Itoa(containerPort))
-	out, err := cmd.Output()
+	out, err := output("port", container, strconv.Itoa(containerPort))
 	if err != nil {
 		return 0, fmt.Errorf("docker port %s %d: %w", container, containerPort, err)
 	}
@@ -173,3 +167,29 @@ func IsPortExposed(container string, containerPort string) bool {
 	_, err := GetHostPort(container, port)
 	return err == nil
 }
+
+// RunInPIDNamespace runs a throwaway container of image sharing the process
+// namespace of container, with the ptrace capability profilers need.
+func RunInPIDNamespace(container, image string, env map[string]string, stdout io.Writer, args ...string) error {
+	dockerArgs := []string{"run", "--rm", "-i",
+		"--pid", "container:" + container,
+		"--cap-add", "SYS_PTRACE",
+	}
+	for k, v := range env {
+		dockerArgs = append(dockerArgs, "-e", k+"="+v)
+	}
+	dockerArgs = append(append(dockerArgs, image), args...)
+	return run(dockerArgs, nil, stdout, os.Stderr)
+}
+
+// RunPython pipes script into `python -` inside container, so it runs with
+// the container's code and environment plus env, writing the script's stdout
+// to stdout.
+func RunPython(container, script string, env map[string]string, stdout io.Writer, args ...string) error {
+	dockerArgs := []string{"exec", "-i"}
+	for k, v := range env {
+		dockerArgs = append(dockerArgs, "-e", k+"="+v)
+	}
+	dockerArgs = append(append(dockerArgs, container, "python", "-"), args...)
+	return run(dockerArgs, strings.NewReader(script), stdout, os.Stderr)
+}
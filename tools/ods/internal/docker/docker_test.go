@@ -0,0 +1,44 @@
+package docker
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/runner"
+)
+
+func useFake(t *testing.T) *runner.Fake {
+	t.Helper()
+	f := &runner.Fake{}
+	Runner = f
+	t.Cleanup(func() { Runner = nil })
+	return f
+}
+
+func TestGetHostPort(t *testing.T) {
+	f := useFake(t)
+	f.On("docker port onyx-api_server-1 8080", runner.Response{Stdout: "0.0.0.0:18080\n[::]:18080\n"})
+	f.On("docker port onyx-api_server-1 5432", runner.Response{Err: errors.New("exit status 1")})
+
+	if port, err := GetHostPort("onyx-api_server-1", 8080); err != nil || port != 18080 {
+		t.Errorf("GetHostPort() = %d, %v", port, err)
+	}
+	if _, err := GetHostPort("onyx-api_server-1", 5432); err == nil {
+		t.Error("expected an unmapped port to fail")
+	}
+}
+
+func TestFindPostgresContainer(t *testing.T) {
+	f := useFake(t)
+	f.On("docker inspect", runner.Response{Err: errors.New("exit status 1")})
+	f.On("docker ps", runner.Response{Stdout: "onyx-api_server-1\tonyxdotapp/onyx-backend\nmy-db\tpostgres:15.2-alpine\n"})
+
+	name, err := FindPostgresContainer("onyx")
+	if err != nil || name != "my-db" {
+		t.Fatalf("FindPostgresContainer() = %q, %v", name, err)
+	}
+	lines := f.Lines()
+	if lines[0] != "docker inspect -f {{.State.Running}} onyx-relational_db-1" || len(lines) != len(legacyPostgresContainerNames)+2 {
+		t.Errorf("unexpected commands %q", lines)
+	}
+}
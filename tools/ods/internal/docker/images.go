@@ -0,0 +1,103 @@
+package docker
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// OnyxImagePrefix is the Docker Hub namespace of the images Onyx builds.
+const OnyxImagePrefix = "onyxdotapp/"
+
+// ComposeImages returns the images the services of the given compose files
+// run, with ${VAR:-default} references expanded from env (falling back to
+// each reference's default, as docker compose does), deduplicated and
+// sorted.
+func ComposeImages(files []string, env map[string]string) ([]string, error) {
+	seen := map[string]bool{}
+	for _, file := range files {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var compose struct {
+			Services map[string]struct {
+				Image string `yaml:"image"`
+			} `yaml:"services"`
+		}
+		if err := yaml.Unmarshal(data, &compose); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", file, err)
+		}
+		for _, svc := range compose.Services {
+			if svc.Image != "" {
+				seen[ExpandComposeVars(svc.Image, env)] = true
+			}
+		}
+	}
+	images := make([]string, 0, len(seen))
+	for image := range seen {
+		images = append(images, image)
+	}
+	sort.Strings(images)
+	return images, nil
+}
+
+// ExpandComposeVars expands the ${VAR}, ${VAR:-default} and ${VAR-default}
+// references of compose file interpolation in s, defaults included.
+func ExpandComposeVars(s string, env map[string]string) string {
+	var b strings.Builder
+	for {
+		start := strings.Index(s, "${")
+		if start < 0 {
+			b.WriteString(s)
+			return b.String()
+		}
+		b.WriteString(s[:start])
+		end := matchingBrace(s, start+2)
+		if end < 0 {
+			b.WriteString(s[start:])
+			return b.String()
+		}
+		b.WriteString(expandComposeVar(s[start+2:end], env))
+		s = s[end+1:]
+	}
+}
+
+// expandComposeVar expands the inside of one ${...}.
+func expandComposeVar(expr string, env map[string]string) string {
+	if name, def, ok := strings.Cut(expr, ":-"); ok && !strings.ContainsAny(name, "-}") {
+		if v := env[name]; v != "" {
+			return v
+		}
+		return ExpandComposeVars(def, env)
+	}
+	if name, def, ok := strings.Cut(expr, "-"); ok && !strings.Contains(name, "}") {
+		if v, set := env[name]; set {
+			return v
+		}
+		return ExpandComposeVars(def, env)
+	}
+	return env[expr]
+}
+
+// matchingBrace returns the index of the "}" closing the "${" whose body
+// starts at i, or -1.
+func matchingBrace(s string, i int) int {
+	depth := 1
+	for ; i < len(s); i++ {
+		switch {
+		case strings.HasPrefix(s[i:], "${"):
+			depth++
+			i++
+		case s[i] == '}':
+			depth--
+			if depth == 0 {
+				return i
+			}
+		}
+	}
+	return -1
+}
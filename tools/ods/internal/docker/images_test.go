@@ -0,0 +1,55 @@
+package docker
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestExpandComposeVars(t *testing.T) {
+	env := map[string]string{"IMAGE_TAG": "v2.10.4", "EMPTY": ""}
+	tests := map[string]string{
+		"${ONYX_BACKEND_IMAGE:-onyxdotapp/onyx-backend:${IMAGE_TAG:-latest}}": "onyxdotapp/onyx-backend:v2.10.4",
+		"onyxdotapp/code-interpreter:${CODE_INTERPRETER_IMAGE_TAG:-latest}":   "onyxdotapp/code-interpreter:latest",
+		"${BASE_IMAGE_REGISTRY:-docker.io}/library/postgres:15.2-alpine":      "docker.io/library/postgres:15.2-alpine",
+		"${EMPTY:-fallback}":   "fallback",
+		"${EMPTY-fallback}":    "",
+		"${UNSET-fallback}":    "fallback",
+		"plain:${IMAGE_TAG}":   "plain:v2.10.4",
+		"unterminated ${FOO":   "unterminated ${FOO",
+		"no references at all": "no references at all",
+	}
+	for in, want := range tests {
+		if got := ExpandComposeVars(in, env); got != want {
+			t.Errorf("ExpandComposeVars(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestComposeImages(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "docker-compose.yml")
+	compose := `services:
+  api_server:
+    image: ${ONYX_BACKEND_IMAGE:-onyxdotapp/onyx-backend:${IMAGE_TAG:-latest}}
+  background:
+    image: ${ONYX_BACKEND_IMAGE:-onyxdotapp/onyx-backend:${IMAGE_TAG:-latest}}
+  web_server:
+    image: ${ONYX_WEB_SERVER_IMAGE:-onyxdotapp/onyx-web-server:${IMAGE_TAG:-latest}}
+  cache:
+    image: redis:7.4-alpine
+  built_only:
+    build: .
+`
+	if err := os.WriteFile(file, []byte(compose), 0644); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ComposeImages([]string{file}, map[string]string{"IMAGE_TAG": "v1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"onyxdotapp/onyx-backend:v1", "onyxdotapp/onyx-web-server:v1", "redis:7.4-alpine"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ComposeImages() = %v, want %v", got, want)
+	}
+}
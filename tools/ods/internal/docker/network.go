@@ -0,0 +1,76 @@
+package docker
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// NetworkAttachment is a container's membership of a docker network.
+type NetworkAttachment struct {
+	Network string   `json:"network"`
+	Aliases []string `json:"aliases,omitempty"`
+}
+
+// ContainerNetworks returns the networks container is attached to, sorted by
+// name.
+func ContainerNetworks(container string) ([]NetworkAttachment, error) {
+	out, err := output("inspect", "-f", "{{json .NetworkSettings.Networks}}", container)
+	if err != nil {
+		return nil, fmt.Errorf("failed to inspect %s: %w", container, err)
+	}
+	return parseNetworks(out)
+}
+
+func parseNetworks(data []byte) ([]NetworkAttachment, error) {
+	var networks map[string]struct {
+		Aliases []string `json:"Aliases"`
+	}
+	if err := json.Unmarshal(data, &networks); err != nil {
+		return nil, fmt.Errorf("failed to parse docker inspect output: %w", err)
+	}
+	attachments := make([]NetworkAttachment, 0, len(networks))
+	for name, n := range networks {
+		attachments = append(attachments, NetworkAttachment{Network: name, Aliases: n.Aliases})
+	}
+	sort.Slice(attachments, func(i, j int) bool { return attachments[i].Network < attachments[j].Network })
+	return attachments, nil
+}
+
+// DisconnectNetwork detaches container from network.
+func DisconnectNetwork(container, network string) error {
+	return dockerRun("network", "disconnect", network, container)
+}
+
+// ConnectNetwork attaches container to the network of a, with its aliases.
+func ConnectNetwork(container string, a NetworkAttachment) error {
+	args := []string{"network", "connect"}
+	for _, alias := range a.Aliases {
+		args = append(args, "--alias", alias)
+	}
+	return dockerRun(append(args, a.Network, container)...)
+}
+
+// Kill sends signal (e.g. KILL, TERM) to the main process of container.
+func Kill(container, signal string) error {
+	return dockerRun("kill", "--signal", signal, container)
+}
+
+func dockerRun(args ...string) error {
+	var stderr bytes.Buffer
+	if err := run(args, nil, nil, &stderr); err != nil {
+		return fmt.Errorf("docker %s: %w: %s", args[0], err, bytes.TrimSpace(stderr.Bytes()))
+	}
+	return nil
+}
+
+// RunInNetNamespace runs a throwaway container of image sharing the network
+// namespace of container, with the capability to change its interfaces.
+func RunInNetNamespace(container, image string, args ...string) error {
+	return dockerRun(append([]string{"run", "--rm",
+		"--net", "container:" + container,
+		"--cap-add", "NET_ADMIN",
+		image,
+	}, args...)...)
+}
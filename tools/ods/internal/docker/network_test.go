@@ -0,0 +1,53 @@
+package docker
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/runner"
+)
+
+func TestParseNetworks(t *testing.T) {
+	got, err := parseNetworks([]byte(`{"onyx_default": {"Aliases": ["api_server", "3f2a"]}, "a_net": {"Aliases": null}}`))
+	if err != nil {
+		t.Fatalf("parseNetworks() error: %v", err)
+	}
+	if len(got) != 2 || got[0].Network != "a_net" || got[1].Network != "onyx_default" || got[1].Aliases[0] != "api_server" {
+		t.Errorf("unexpected networks %+v", got)
+	}
+	if _, err := parseNetworks([]byte("not json")); err == nil {
+		t.Error("expected invalid output to return an error")
+	}
+}
+
+func TestContainerNetworks(t *testing.T) {
+	f := useFake(t)
+	f.On("docker inspect -f {{json .NetworkSettings.Networks}} onyx-api_server-1", runner.Response{Stdout: `{"onyx_default": {"Aliases": ["api_server"]}}`})
+	f.On("docker inspect -f {{json .NetworkSettings.Networks}} gone", runner.Response{Err: errors.New("exit status 1")})
+
+	got, err := ContainerNetworks("onyx-api_server-1")
+	if err != nil || len(got) != 1 || got[0].Network != "onyx_default" {
+		t.Errorf("ContainerNetworks() = %+v, %v", got, err)
+	}
+	if _, err := ContainerNetworks("gone"); err == nil {
+		t.Error("expected a missing container to fail")
+	}
+}
+
+func TestConnectNetwork(t *testing.T) {
+	f := useFake(t)
+	f.On("docker network connect", runner.Response{})
+	f.On("docker kill", runner.Response{Stderr: "No such container: gone\n", Err: errors.New("exit status 1")})
+
+	if err := ConnectNetwork("onyx-api_server-1", NetworkAttachment{Network: "onyx_default", Aliases: []string{"api_server", "api"}}); err != nil {
+		t.Fatalf("ConnectNetwork() error: %v", err)
+	}
+	if got, want := f.Lines()[0], "docker network connect --alias api_server --alias api onyx_default onyx-api_server-1"; got != want {
+		t.Errorf("command = %q, want %q", got, want)
+	}
+	err := Kill("gone", "KILL")
+	if err == nil || !strings.Contains(err.Error(), "No such container: gone") {
+		t.Errorf("Kill() error = %v, want docker's stderr", err)
+	}
+}
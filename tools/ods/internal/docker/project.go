@@ -3,6 +3,7 @@ package docker
 import (
 	"fmt"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
 	"unicode"
@@ -23,6 +24,10 @@ type PortSpec struct {
 	ComposeVar    string // env var for docker-compose.dev.yml (e.g., "POSTGRES_HOST_PORT")
 	AppVar        string // env var for .vscode/.env (empty = not written to app env)
 	AppFormat     string // format string for AppVar value (empty = "%d")
+	// IsolatedHost is where projects other than the main one start looking
+	// for a free port when DefaultHost is privileged (below 1024): ods
+	// cannot probe those, only Docker can bind them.
+	IsolatedHost int
 }
 
 // ServiceSpec describes an infrastructure service managed by Docker Compose.
@@ -56,6 +61,19 @@ var InfraServices = []ServiceSpec{
 	}},
 }
 
+// WebServices are the application services that publish host ports in every
+// profile. They are only resolved when ods compose offsets ports; otherwise
+// the compose files' defaults apply.
+var WebServices = []ServiceSpec{
+	{Name: "nginx", Ports: []PortSpec{
+		{ContainerPort: 80, DefaultHost: 80, ComposeVar: "HOST_PORT_80", IsolatedHost: 3080},
+		{ContainerPort: 80, DefaultHost: 3000, ComposeVar: "HOST_PORT"},
+	}},
+	{Name: "api_server", Ports: []PortSpec{
+		{ContainerPort: 8080, DefaultHost: 8080, ComposeVar: "API_SERVER_HOST_PORT"},
+	}},
+}
+
 // InfraServiceNames returns the Docker Compose service names for all
 // infrastructure services.
 func InfraServiceNames() []string {
@@ -66,6 +84,11 @@ func InfraServiceNames() []string {
 	return names
 }
 
+// DependencyServiceNames are the infrastructure services a natively-run
+// backend and frontend need: Postgres, Redis, the search index and the S3
+// file store. Model servers and code-interpreter are left out.
+var DependencyServiceNames = []string{"relational_db", "cache", "opensearch", "minio"}
+
 // ResolvedPorts holds the discovered host port for each PortSpec, in the same
 // order as InfraServices and their Ports slices.
 type ResolvedPorts struct {
@@ -118,6 +141,12 @@ func SetProjectFlags(project string) {
 	flagProject = project
 }
 
+// IsDefaultProject reports whether name is the main checkout's project,
+// which keeps the compose files' default ports where it can.
+func IsDefaultProject(name string) bool {
+	return name == defaultProjectName
+}
+
 // ProjectName returns the Docker Compose project name. Uses --project if set,
 // otherwise the basename of the git working tree root (e.g. "onyx" for the main
 // checkout, "feature-x" for a worktree at .../feature-x). The result is
@@ -131,7 +160,13 @@ func ProjectName() string {
 	if err != nil {
 		return defaultProjectName
 	}
-	return normalizeProjectName(filepath.Base(root))
+	return ProjectNameForDir(root)
+}
+
+// ProjectNameForDir returns the project name ods compose uses in the working
+// tree at dir.
+func ProjectNameForDir(dir string) string {
+	return normalizeProjectName(filepath.Base(dir))
 }
 
 // normalizeProjectName converts a string into a valid Docker Compose project
@@ -150,34 +185,73 @@ func normalizeProjectName(name string) string {
 	return b.String()
 }
 
-// FindAvailablePorts resolves host ports for each port spec in InfraServices.
-// For each port it first checks whether the project's container is already
-// running with a mapped host port (via ``docker port``) and reuses it. Only
-// when the container is not running does it probe for a free port. A global
-// claimed set prevents cross-service collisions (e.g., inference_model_server
-// and minio both defaulting near port 9000).
-func FindAvailablePorts() (*ResolvedPorts, error) {
+// FindAvailablePorts resolves host ports for each port spec in InfraServices
+// and WebServices. A port recorded for the project in a previous run is kept
+// while it is free or held by the project's own containers, so a stack keeps
+// its ports across restarts. Otherwise a running container's mapped host port
+// (via ``docker port``) is reused, and only then is a free port probed for.
+// Ports in claimed (those recorded for other projects) and those already
+// handed out in this call are skipped, which prevents collisions between
+// stacks and between services (e.g., inference_model_server and minio both
+// defaulting near port 9000).
+func FindAvailablePorts(recorded map[string]int, claimed map[int]bool) (*ResolvedPorts, error) {
 	resolved := NewResolvedPorts()
-	claimed := make(map[int]bool)
+	taken := make(map[int]bool, len(claimed))
+	for p := range claimed {
+		taken[p] = true
+	}
 	projName := ProjectName()
+	own := ProjectHostPorts(projName)
 
-	for _, svc := range InfraServices {
+	services := append(slices.Clone(InfraServices), WebServices...)
+	for _, svc := range services {
 		container := fmt.Sprintf("%s-%s-1", projName, svc.Name)
 		for _, spec := range svc.Ports {
-			if hp, err := GetHostPort(container, spec.ContainerPort); err == nil {
-				claimed[hp] = true
-				resolved.Append(hp, spec)
-				continue
-			}
-
-			port, err := portutil.FindAvailable(spec.DefaultHost, maxPortScanRange, claimed)
+			port, err := resolvePort(spec, container, recorded[spec.ComposeVar], own, taken, IsDefaultProject(projName))
 			if err != nil {
 				return nil, fmt.Errorf("%s port %d: %w", svc.Name, spec.ContainerPort, err)
 			}
-			claimed[port] = true
+			taken[port] = true
 			resolved.Append(port, spec)
 		}
 	}
 
 	return resolved, nil
 }
+
+func resolvePort(spec PortSpec, container string, recorded int, own, taken map[int]bool, defaultProject bool) (int, error) {
+	if recorded != 0 && !taken[recorded] && (own[recorded] || recorded < 1024 || portutil.IsAvailable(recorded)) {
+		return recorded, nil
+	}
+	// nginx publishes container port 80 twice, so docker port cannot tell
+	// which mapping belongs to which spec; rely on the record for those.
+	if !sharedContainerPort(spec) {
+		if hp, err := GetHostPort(container, spec.ContainerPort); err == nil && !taken[hp] {
+			return hp, nil
+		}
+	}
+
+	base := spec.DefaultHost
+	if base < 1024 {
+		if defaultProject && !taken[base] {
+			return base, nil
+		}
+		base = spec.IsolatedHost
+	}
+	return portutil.FindAvailable(base, maxPortScanRange, taken)
+}
+
+func sharedContainerPort(spec PortSpec) bool {
+	for _, svc := range WebServices {
+		n := 0
+		for _, other := range svc.Ports {
+			if other.ContainerPort == spec.ContainerPort {
+				n++
+			}
+		}
+		if n > 1 && slices.Contains(svc.Ports, spec) {
+			return true
+		}
+	}
+	return false
+}
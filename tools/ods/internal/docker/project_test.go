@@ -53,6 +53,18 @@ func TestInfraServiceNames(t *testing.T) {
 	}
 }
 
+func TestDependencyServiceNames(t *testing.T) {
+	infra := map[string]bool{}
+	for _, name := range InfraServiceNames() {
+		infra[name] = true
+	}
+	for _, name := range DependencyServiceNames {
+		if !infra[name] {
+			t.Errorf("dependency service %q is not an infrastructure service", name)
+		}
+	}
+}
+
 func TestResolvedPorts_ComposeEnv(t *testing.T) {
 	resolved := NewResolvedPorts()
 	for _, svc := range InfraServices {
@@ -129,3 +141,24 @@ func TestResolvedPorts_AppEnv_emptyAppVarSkipped(t *testing.T) {
 		t.Errorf("expected empty AppEnv for spec with empty AppVar, got %v", env)
 	}
 }
+
+func TestResolvePort_privilegedAndRecorded(t *testing.T) {
+	http := WebServices[0].Ports[0]
+	web := WebServices[0].Ports[1]
+
+	if got, err := resolvePort(http, "x-nginx-1", 0, nil, map[int]bool{}, true); err != nil || got != 80 {
+		t.Errorf("default project: got %d, %v; want 80", got, err)
+	}
+	got, err := resolvePort(http, "x-nginx-1", 0, nil, map[int]bool{}, false)
+	if err != nil || got < http.IsolatedHost {
+		t.Errorf("isolated project: got %d, %v; want a port from %d", got, err, http.IsolatedHost)
+	}
+
+	own := map[int]bool{3001: true}
+	if got, err := resolvePort(web, "x-nginx-1", 3001, own, map[int]bool{}, false); err != nil || got != 3001 {
+		t.Errorf("recorded port held by the project: got %d, %v; want 3001", got, err)
+	}
+	if got, _ := resolvePort(web, "x-nginx-1", 3001, own, map[int]bool{3001: true}, false); got == 3001 {
+		t.Error("a port claimed by another project must not be reused")
+	}
+}
@@ -0,0 +1,189 @@
+package docker
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ProjectRecord is what ods compose remembers about a project it started.
+type ProjectRecord struct {
+	Name string `json:"name"`
+	// Dir is the git working tree the project was started from.
+	Dir     string `json:"dir"`
+	Profile string `json:"profile"`
+	// Ports maps the compose port variables (e.g. HOST_PORT) to the host
+	// ports the project was given.
+	Ports   map[string]int `json:"ports"`
+	Updated time.Time      `json:"updated"`
+}
+
+// Registry is the set of compose projects ods has started, kept so each
+// stack gets its ports back on restart and no two stacks share one.
+type Registry struct {
+	Projects []ProjectRecord `json:"projects"`
+	path     string
+}
+
+// LoadRegistry reads the registry at path; a missing file is an empty
+// registry.
+func LoadRegistry(path string) (*Registry, error) {
+	r := &Registry{path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return r, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, r); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return r, nil
+}
+
+// Get returns the record of the named project, or nil.
+func (r *Registry) Get(name string) *ProjectRecord {
+	for i := range r.Projects {
+		if r.Projects[i].Name == name {
+			return &r.Projects[i]
+		}
+	}
+	return nil
+}
+
+// Put adds or replaces the record of rec.Name.
+func (r *Registry) Put(rec ProjectRecord) {
+	if existing := r.Get(rec.Name); existing != nil {
+		*existing = rec
+		return
+	}
+	r.Projects = append(r.Projects, rec)
+	slices.SortFunc(r.Projects, func(a, b ProjectRecord) int { return strings.Compare(a.Name, b.Name) })
+}
+
+// Remove forgets the named project.
+func (r *Registry) Remove(name string) {
+	r.Projects = slices.DeleteFunc(r.Projects, func(p ProjectRecord) bool { return p.Name == name })
+}
+
+// ClaimedPorts returns the host ports recorded for projects other than name.
+func (r *Registry) ClaimedPorts(name string) map[int]bool {
+	claimed := make(map[int]bool)
+	for _, p := range r.Projects {
+		if p.Name == name {
+			continue
+		}
+		for _, port := range p.Ports {
+			claimed[port] = true
+		}
+	}
+	return claimed
+}
+
+// Save writes the registry back to its file.
+func (r *Registry) Save() error {
+	if err := os.MkdirAll(filepath.Dir(r.path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(r.path, append(data, '\n'), 0644)
+}
+
+// Ports returns the resolved ports keyed by compose variable, for a
+// ProjectRecord.
+func (r *ResolvedPorts) Ports() map[string]int {
+	ports := make(map[string]int, len(r.specs))
+	for i, spec := range r.specs {
+		ports[spec.ComposeVar] = r.ports[i]
+	}
+	return ports
+}
+
+// ComposeProject is a project as docker compose ls reports it.
+type ComposeProject struct {
+	Name   string `json:"Name"`
+	Status string `json:"Status"`
+	// ConfigFiles is a comma-separated list of the compose files used.
+	ConfigFiles string `json:"ConfigFiles"`
+}
+
+// IsOnyx reports whether the project was started from an Onyx checkout's
+// compose files.
+func (p ComposeProject) IsOnyx() bool {
+	return strings.Contains(filepath.ToSlash(p.ConfigFiles), "deployment/docker_compose/")
+}
+
+// ComposeProjects lists compose projects with containers, running or not.
+func ComposeProjects() ([]ComposeProject, error) {
+	out, err := output("compose", "ls", "--all", "--format", "json")
+	if err != nil {
+		return nil, fmt.Errorf("docker compose ls: %w", err)
+	}
+	var projects []ComposeProject
+	if err := json.Unmarshal(out, &projects); err != nil {
+		return nil, fmt.Errorf("failed to parse docker compose ls output: %w", err)
+	}
+	return projects, nil
+}
+
+// ProjectVolumes returns the names of the volumes of each compose project,
+// keyed by project name.
+func ProjectVolumes() (map[string][]string, error) {
+	out, err := output("volume", "ls", "--format",
+		`{{.Label "com.docker.compose.project"}}`+"\t{{.Name}}")
+	if err != nil {
+		return nil, fmt.Errorf("docker volume ls: %w", err)
+	}
+	return parseProjectVolumes(string(out)), nil
+}
+
+func parseProjectVolumes(out string) map[string][]string {
+	volumes := make(map[string][]string)
+	for _, line := range strings.Split(strings.TrimSpace(out), "\n") {
+		project, name, ok := strings.Cut(line, "\t")
+		if !ok || project == "" {
+			continue
+		}
+		volumes[project] = append(volumes[project], name)
+	}
+	return volumes
+}
+
+// ProjectHostPorts returns the host ports published by the project's
+// running containers.
+func ProjectHostPorts(project string) map[int]bool {
+	out, err := output("ps",
+		"--filter", "label=com.docker.compose.project="+project,
+		"--format", "{{.Ports}}")
+	if err != nil {
+		return nil
+	}
+	return parsePublishedPorts(string(out))
+}
+
+// parsePublishedPorts reads the host ports out of docker ps's Ports column,
+// e.g. "0.0.0.0:5432->5432/tcp, [::]:5432->5432/tcp".
+func parsePublishedPorts(out string) map[int]bool {
+	ports := make(map[int]bool)
+	for _, field := range strings.FieldsFunc(out, func(r rune) bool { return r == ',' || r == '\n' }) {
+		host, _, ok := strings.Cut(strings.TrimSpace(field), "->")
+		if !ok {
+			continue
+		}
+		if port, err := strconv.Atoi(host[strings.LastIndex(host, ":")+1:]); err == nil {
+			ports[port] = true
+		}
+	}
+	return ports
+}
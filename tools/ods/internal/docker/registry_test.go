@@ -0,0 +1,93 @@
+package docker
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/runner"
+)
+
+func TestRegistry(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "compose-projects.json")
+	reg, err := LoadRegistry(path)
+	if err != nil {
+		t.Fatalf("LoadRegistry() error: %v", err)
+	}
+	reg.Put(ProjectRecord{Name: "onyx", Ports: map[string]int{"HOST_PORT": 3000}})
+	reg.Put(ProjectRecord{Name: "feature-x", Ports: map[string]int{"HOST_PORT": 3001, "POSTGRES_HOST_PORT": 5433}})
+	reg.Put(ProjectRecord{Name: "onyx", Ports: map[string]int{"HOST_PORT": 3000, "HOST_PORT_80": 80}})
+	if err := reg.Save(); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+
+	reg, err = LoadRegistry(path)
+	if err != nil {
+		t.Fatalf("LoadRegistry() error: %v", err)
+	}
+	if len(reg.Projects) != 2 || reg.Projects[0].Name != "feature-x" {
+		t.Fatalf("unexpected projects %+v", reg.Projects)
+	}
+	if got, want := reg.ClaimedPorts("feature-x"), map[int]bool{3000: true, 80: true}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ClaimedPorts() = %v, want %v", got, want)
+	}
+	reg.Remove("onyx")
+	if reg.Get("onyx") != nil || len(reg.ClaimedPorts("feature-x")) != 0 {
+		t.Errorf("Remove() left %+v", reg.Projects)
+	}
+}
+
+func TestParsePublishedPorts(t *testing.T) {
+	out := "0.0.0.0:5433->5432/tcp, [::]:5433->5432/tcp\n\n0.0.0.0:3001->80/tcp, 0.0.0.0:3080->80/tcp, 9000/tcp\n"
+	want := map[int]bool{5433: true, 3001: true, 3080: true}
+	if got := parsePublishedPorts(out); !reflect.DeepEqual(got, want) {
+		t.Errorf("parsePublishedPorts() = %v, want %v", got, want)
+	}
+}
+
+func TestParseProjectVolumes(t *testing.T) {
+	out := "onyx\tonyx_db_volume\nfeature-x\tfeature-x_db_volume\nfeature-x\tfeature-x_minio_data\n\tdangling\n"
+	got := parseProjectVolumes(out)
+	if len(got) != 2 || len(got["feature-x"]) != 2 || got["onyx"][0] != "onyx_db_volume" {
+		t.Errorf("parseProjectVolumes() = %v", got)
+	}
+}
+
+func TestComposeProjectIsOnyx(t *testing.T) {
+	p := ComposeProject{ConfigFiles: "/src/onyx/deployment/docker_compose/docker-compose.yml,/src/onyx/deployment/docker_compose/docker-compose.dev.yml"}
+	if !p.IsOnyx() {
+		t.Error("expected an Onyx project")
+	}
+	if (ComposeProject{ConfigFiles: "/src/other/compose.yml"}).IsOnyx() {
+		t.Error("expected a foreign project")
+	}
+}
+
+func TestComposeProjects(t *testing.T) {
+	f := useFake(t)
+	f.On("docker compose ls --all --format json", runner.Response{Stdout: `[{"Name":"onyx","Status":"running(12)","ConfigFiles":"/src/onyx/deployment/docker_compose/docker-compose.yml"}]`})
+
+	projects, err := ComposeProjects()
+	if err != nil || len(projects) != 1 || projects[0].Name != "onyx" || !projects[0].IsOnyx() {
+		t.Errorf("ComposeProjects() = %+v, %v", projects, err)
+	}
+
+	useFake(t).On("docker compose ls", runner.Response{Stdout: "not json"})
+	if _, err := ComposeProjects(); err == nil {
+		t.Error("expected unparseable output to fail")
+	}
+}
+
+func TestProjectHostPorts(t *testing.T) {
+	f := useFake(t)
+	f.On("docker ps --filter label=com.docker.compose.project=onyx", runner.Response{Stdout: "0.0.0.0:5432->5432/tcp\n0.0.0.0:3000->3000/tcp\n"})
+	f.On("docker ps --filter label=com.docker.compose.project=down", runner.Response{Err: errors.New("exit status 1")})
+
+	if got := ProjectHostPorts("onyx"); !reflect.DeepEqual(got, map[int]bool{5432: true, 3000: true}) {
+		t.Errorf("ProjectHostPorts(onyx) = %v", got)
+	}
+	if got := ProjectHostPorts("down"); got != nil {
+		t.Errorf("ProjectHostPorts(down) = %v, want nil when docker fails", got)
+	}
+}
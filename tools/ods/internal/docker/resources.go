@@ -0,0 +1,151 @@
+package docker
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/paths"
+)
+
+// ServiceLimits caps a single compose service.
+type ServiceLimits struct {
+	Service  string
+	CPUs     string
+	MemoryMB int
+	// JavaHeap is set for JVM services, whose heap must fit inside MemoryMB.
+	JavaHeap string
+}
+
+// ResourcePreset is a named set of limits for the heaviest services.
+type ResourcePreset struct {
+	Name string
+	// HostMB is the host memory the whole stack needs at this preset,
+	// including services the preset does not cap.
+	HostMB int
+	Limits []ServiceLimits
+}
+
+// ResourcePresets are the presets accepted by `ods compose --resources`,
+// smallest first.
+var ResourcePresets = []ResourcePreset{
+	{Name: "small", HostMB: 8 * 1024, Limits: []ServiceLimits{
+		{Service: "opensearch", CPUs: "1", MemoryMB: 1536, JavaHeap: "768m"},
+		{Service: "relational_db", CPUs: "1", MemoryMB: 768},
+		{Service: "inference_model_server", CPUs: "2", MemoryMB: 1536},
+		{Service: "indexing_model_server", CPUs: "2", MemoryMB: 1536},
+	}},
+	{Name: "default", HostMB: 22 * 1024, Limits: []ServiceLimits{
+		{Service: "opensearch", CPUs: "2", MemoryMB: 4096, JavaHeap: "2g"},
+		{Service: "relational_db", CPUs: "2", MemoryMB: 4096},
+		{Service: "inference_model_server", CPUs: "4", MemoryMB: 5120},
+		{Service: "indexing_model_server", CPUs: "4", MemoryMB: 5120},
+	}},
+	{Name: "large", HostMB: 36 * 1024, Limits: []ServiceLimits{
+		{Service: "opensearch", CPUs: "4", MemoryMB: 8192, JavaHeap: "4g"},
+		{Service: "relational_db", CPUs: "4", MemoryMB: 8192},
+		{Service: "inference_model_server", CPUs: "6", MemoryMB: 8192},
+		{Service: "indexing_model_server", CPUs: "6", MemoryMB: 8192},
+	}},
+}
+
+// ResourcePresetNames returns the preset names, smallest first.
+func ResourcePresetNames() []string {
+	names := make([]string, len(ResourcePresets))
+	for i, p := range ResourcePresets {
+		names[i] = p.Name
+	}
+	return names
+}
+
+// FindResourcePreset returns the preset with the given name.
+func FindResourcePreset(name string) (ResourcePreset, bool) {
+	for _, p := range ResourcePresets {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return ResourcePreset{}, false
+}
+
+// LargestPresetFor returns the largest preset that fits in hostMB, or false if
+// even the smallest does not.
+func LargestPresetFor(hostMB int) (ResourcePreset, bool) {
+	for i := len(ResourcePresets) - 1; i >= 0; i-- {
+		if ResourcePresets[i].HostMB <= hostMB {
+			return ResourcePresets[i], true
+		}
+	}
+	return ResourcePreset{}, false
+}
+
+// OverrideYAML renders the preset as a docker compose override file.
+func (p ResourcePreset) OverrideYAML() ([]byte, error) {
+	type limits struct {
+		CPUs   string `yaml:"cpus"`
+		Memory string `yaml:"memory"`
+	}
+	type service struct {
+		Environment map[string]string `yaml:"environment,omitempty"`
+		Deploy      struct {
+			Resources struct {
+				Limits limits `yaml:"limits"`
+			} `yaml:"resources"`
+		} `yaml:"deploy"`
+	}
+
+	services := make(map[string]service, len(p.Limits))
+	for _, l := range p.Limits {
+		var s service
+		s.Deploy.Resources.Limits = limits{CPUs: l.CPUs, Memory: fmt.Sprintf("%dm", l.MemoryMB)}
+		if l.JavaHeap != "" {
+			s.Environment = map[string]string{
+				"OPENSEARCH_JAVA_OPTS": fmt.Sprintf("-Xms%s -Xmx%s", l.JavaHeap, l.JavaHeap),
+			}
+		}
+		services[l.Service] = s
+	}
+
+	body, err := yaml.Marshal(map[string]any{"services": services})
+	if err != nil {
+		return nil, err
+	}
+	header := fmt.Sprintf("# Generated by `ods compose --resources %s`; do not edit.\n", p.Name)
+	return append([]byte(header), body...), nil
+}
+
+// WriteOverride writes the preset's override file under the ods data
+// directory and returns its path.
+func (p ResourcePreset) WriteOverride() (string, error) {
+	data, err := p.OverrideYAML()
+	if err != nil {
+		return "", fmt.Errorf("failed to render resource override: %w", err)
+	}
+	dir := paths.DataDir()
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create %s: %w", dir, err)
+	}
+	path := filepath.Join(dir, fmt.Sprintf("docker-compose.resources-%s.yml", p.Name))
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return "", fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return path, nil
+}
+
+// HostMemoryMB returns the memory available to the Docker engine, which on
+// Docker Desktop is the VM's allocation rather than the machine's RAM.
+func HostMemoryMB() (int, error) {
+	out, err := output("info", "--format", "{{.MemTotal}}")
+	if err != nil {
+		return 0, fmt.Errorf("docker info failed: %w", err)
+	}
+	bytes, err := strconv.ParseInt(strings.TrimSpace(string(out)), 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("unexpected docker info output %q", strings.TrimSpace(string(out)))
+	}
+	return int(bytes / (1024 * 1024)), nil
+}
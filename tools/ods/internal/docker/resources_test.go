@@ -0,0 +1,91 @@
+package docker
+
+import (
+	"testing"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/runner"
+)
+
+func TestResourcePresetOverrideYAML(t *testing.T) {
+	p, ok := FindResourcePreset("small")
+	if !ok {
+		t.Fatal("small preset not found")
+	}
+	data, err := p.OverrideYAML()
+	if err != nil {
+		t.Fatalf("OverrideYAML() error: %v", err)
+	}
+
+	var parsed struct {
+		Services map[string]struct {
+			Environment map[string]string `yaml:"environment"`
+			Deploy      struct {
+				Resources struct {
+					Limits struct {
+						CPUs   string `yaml:"cpus"`
+						Memory string `yaml:"memory"`
+					} `yaml:"limits"`
+				} `yaml:"resources"`
+			} `yaml:"deploy"`
+		} `yaml:"services"`
+	}
+	if err := yaml.Unmarshal(data, &parsed); err != nil {
+		t.Fatalf("override is not valid YAML: %v", err)
+	}
+	if len(parsed.Services) != len(p.Limits) {
+		t.Errorf("got %d services, want %d", len(parsed.Services), len(p.Limits))
+	}
+	db := parsed.Services["relational_db"]
+	if db.Deploy.Resources.Limits.Memory != "768m" || db.Deploy.Resources.Limits.CPUs != "1" {
+		t.Errorf("relational_db limits = %+v", db.Deploy.Resources.Limits)
+	}
+	if got := parsed.Services["opensearch"].Environment["OPENSEARCH_JAVA_OPTS"]; got != "-Xms768m -Xmx768m" {
+		t.Errorf("OPENSEARCH_JAVA_OPTS = %q", got)
+	}
+}
+
+func TestResourcePresetsFitTheirHost(t *testing.T) {
+	for _, p := range ResourcePresets {
+		total := 0
+		for _, l := range p.Limits {
+			total += l.MemoryMB
+		}
+		if total >= p.HostMB {
+			t.Errorf("preset %s caps %dMB but only budgets %dMB for the host", p.Name, total, p.HostMB)
+		}
+	}
+}
+
+func TestLargestPresetFor(t *testing.T) {
+	tests := []struct {
+		hostMB int
+		want   string
+		ok     bool
+	}{
+		{4 * 1024, "", false},
+		{12 * 1024, "small", true},
+		{24 * 1024, "default", true},
+		{64 * 1024, "large", true},
+	}
+	for _, tt := range tests {
+		p, ok := LargestPresetFor(tt.hostMB)
+		if ok != tt.ok || p.Name != tt.want {
+			t.Errorf("LargestPresetFor(%d) = %q, %v; want %q, %v", tt.hostMB, p.Name, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestHostMemoryMB(t *testing.T) {
+	f := useFake(t)
+	f.On("docker info --format {{.MemTotal}}", runner.Response{Stdout: "8589934592\n"})
+	if mb, err := HostMemoryMB(); err != nil || mb != 8192 {
+		t.Errorf("HostMemoryMB() = %d, %v", mb, err)
+	}
+
+	useFake(t).On("docker info", runner.Response{Stdout: "unknown\n"})
+	if _, err := HostMemoryMB(); err == nil {
+		t.Error("expected unexpected output to fail")
+	}
+}
@@ -0,0 +1,182 @@
+// Package document looks a document up across Postgres and Vespa and
+// explains why it may not show up in search.
+package document
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/kube"
+)
+
+//go:embed lookup.py
+var lookupScript string
+
+// publicACL is the ACL entry of documents every user may see.
+const publicACL = "PUBLIC"
+
+// Document is a document's row in Postgres.
+type Document struct {
+	ID               string     `json:"id"`
+	SemanticID       string     `json:"semantic_id"`
+	Link             string     `json:"link"`
+	Boost            int        `json:"boost"`
+	Hidden           bool       `json:"hidden"`
+	IsPublic         bool       `json:"is_public"`
+	FromIngestionAPI bool       `json:"from_ingestion_api"`
+	ChunkCount       *int       `json:"chunk_count"`
+	DocUpdatedAt     *time.Time `json:"doc_updated_at"`
+	LastModified     *time.Time `json:"last_modified"`
+	LastSynced       *time.Time `json:"last_synced"`
+}
+
+// Connector is a connector/credential pair that indexes the document.
+type Connector struct {
+	CCPairID            int        `json:"cc_pair_id"`
+	Name                string     `json:"name"`
+	Source              string     `json:"source"`
+	Status              string     `json:"status"`
+	AccessType          string     `json:"access_type"`
+	HasBeenIndexed      bool       `json:"has_been_indexed"`
+	LastSuccessfulIndex *time.Time `json:"last_successful_index"`
+	LastPermissionSync  *time.Time `json:"last_permission_sync"`
+}
+
+// Chunk is a chunk of the document as stored in Vespa.
+type Chunk struct {
+	ChunkID      int        `json:"chunk_id"`
+	Boost        float64    `json:"boost"`
+	Hidden       bool       `json:"hidden"`
+	ACL          []string   `json:"acl"`
+	DocumentSets []string   `json:"document_sets"`
+	UpdatedAt    *time.Time `json:"updated_at"`
+	ContentChars int        `json:"content_chars"`
+}
+
+// Candidate is one of several documents whose link matched.
+type Candidate struct {
+	ID         string `json:"id"`
+	SemanticID string `json:"semantic_id"`
+}
+
+// Lookup is everything known about a document.
+type Lookup struct {
+	Document     *Document   `json:"document"`
+	Connectors   []Connector `json:"connectors"`
+	ACL          []string    `json:"acl"`
+	DocumentSets []string    `json:"document_sets"`
+	Chunks       []Chunk     `json:"chunks"`
+	// IndexError is set when Vespa could not be queried; Chunks is then
+	// unknown rather than empty.
+	IndexError string `json:"index_error"`
+	// Candidates is set instead of the rest when key matched several
+	// documents' links.
+	Candidates []Candidate `json:"candidates"`
+}
+
+// Find looks up the document with ID or link key in schema ("" for the
+// default schema of a single-tenant deployment).
+func Find(c *kube.Cluster, pod, schema, key string) (*Lookup, error) {
+	stdout, err := c.RunPython(pod, lookupScript, schema, key)
+	if err != nil {
+		return nil, err
+	}
+	return parseResult(stdout)
+}
+
+func parseResult(stdout string) (*Lookup, error) {
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	last := strings.TrimSpace(lines[len(lines)-1])
+	var r struct {
+		Status  string `json:"status"`
+		Message string `json:"message"`
+		Lookup
+	}
+	if err := json.Unmarshal([]byte(last), &r); err != nil {
+		return nil, fmt.Errorf("unexpected output from lookup script: %q", last)
+	}
+	if r.Status != "success" {
+		return nil, fmt.Errorf("%s", r.Message)
+	}
+	return &r.Lookup, nil
+}
+
+// Diagnose lists what would keep the document out of search results or make
+// it rank poorly, most decisive first. An empty list means nothing stands
+// out.
+func (l *Lookup) Diagnose() []string {
+	d := l.Document
+	var findings []string
+	if d == nil {
+		return nil
+	}
+
+	if d.Hidden {
+		findings = append(findings, "The document is hidden (an admin hid it in the document explorer)")
+	}
+
+	if len(l.Connectors) == 0 && !d.FromIngestionAPI {
+		findings = append(findings, "No connector indexes the document any more; it is an orphan that pruning should delete")
+	}
+	active := 0
+	for _, cc := range l.Connectors {
+		switch cc.Status {
+		case "ACTIVE", "SCHEDULED", "INITIAL_INDEXING":
+			active++
+		}
+		if !cc.HasBeenIndexed {
+			findings = append(findings, fmt.Sprintf("Connector %q has not finished indexing the document yet", cc.Name))
+		}
+	}
+	if len(l.Connectors) > 0 && active == 0 {
+		findings = append(findings, "Every connector indexing the document is paused, invalid or being deleted")
+	}
+
+	if l.IndexError == "" {
+		switch {
+		case len(l.Chunks) == 0:
+			findings = append(findings, "Vespa has no chunks for the document, so search cannot find it")
+		case d.ChunkCount != nil && *d.ChunkCount != len(l.Chunks):
+			findings = append(findings, fmt.Sprintf("Postgres expects %d chunk(s) but Vespa has %d", *d.ChunkCount, len(l.Chunks)))
+		}
+		if stale := l.staleChunks(); stale > 0 {
+			findings = append(findings, fmt.Sprintf("%d chunk(s) in Vespa disagree with Postgres on hidden, boost or access; the sync to Vespa is behind", stale))
+		}
+	}
+
+	if d.LastModified != nil && (d.LastSynced == nil || d.LastSynced.Before(*d.LastModified)) {
+		since := d.LastModified.UTC().Format(time.RFC3339)
+		findings = append(findings, "Changes since "+since+" have not been synced to Vespa yet (check the vespa sync task)")
+	}
+
+	if !slices.Contains(l.ACL, publicACL) {
+		switch len(l.ACL) {
+		case 0:
+			findings = append(findings, "The document's access list is empty; no user can see it")
+		default:
+			findings = append(findings, fmt.Sprintf("The document is not public; only %d user/group ACL entries can see it", len(l.ACL)))
+		}
+	}
+
+	if d.Boost < 0 {
+		findings = append(findings, fmt.Sprintf("Negative boost (%d) from user feedback lowers its ranking", d.Boost))
+	}
+	return findings
+}
+
+// staleChunks counts chunks whose index state differs from Postgres.
+func (l *Lookup) staleChunks() int {
+	want := slices.Sorted(slices.Values(l.ACL))
+	stale := 0
+	for _, ch := range l.Chunks {
+		acl := slices.Sorted(slices.Values(ch.ACL))
+		if ch.Hidden != l.Document.Hidden || int(ch.Boost) != l.Document.Boost || !slices.Equal(acl, want) {
+			stale++
+		}
+	}
+	return stale
+}
@@ -0,0 +1,68 @@
+package document
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseResult(t *testing.T) {
+	out := "Fetching chunks from Vespa...\n" + `{"status": "success", "document": {"id": "https://example.com/a", "semantic_id": "A", "link": "https://example.com/a", "boost": 0, "hidden": false, "is_public": true, "from_ingestion_api": false, "chunk_count": 2, "doc_updated_at": null, "last_modified": "2026-01-02T03:04:05+00:00", "last_synced": "2026-01-02T03:05:00+00:00"}, "connectors": [{"cc_pair_id": 3, "name": "Docs", "source": "web", "status": "ACTIVE", "access_type": "public", "has_been_indexed": true, "last_successful_index": null, "last_permission_sync": null}], "acl": ["PUBLIC"], "document_sets": [], "chunks": [{"chunk_id": 0, "boost": 0, "hidden": false, "acl": ["PUBLIC"], "document_sets": [], "updated_at": null, "content_chars": 120}, {"chunk_id": 1, "boost": 0, "hidden": false, "acl": ["PUBLIC"], "document_sets": [], "updated_at": null, "content_chars": 80}], "index_error": ""}`
+
+	l, err := parseResult(out)
+	if err != nil {
+		t.Fatalf("parseResult() error: %v", err)
+	}
+	if l.Document == nil || l.Document.SemanticID != "A" || len(l.Chunks) != 2 || l.Connectors[0].CCPairID != 3 {
+		t.Fatalf("unexpected lookup %+v", l)
+	}
+	if findings := l.Diagnose(); len(findings) != 0 {
+		t.Errorf("expected a healthy document, got %v", findings)
+	}
+
+	if _, err := parseResult(`{"status": "error", "message": "No document with ID or link 'x'"}`); err == nil || !strings.Contains(err.Error(), "No document") {
+		t.Errorf("expected the script's error, got %v", err)
+	}
+}
+
+func TestDiagnose(t *testing.T) {
+	modified := time.Date(2026, 1, 2, 3, 0, 0, 0, time.UTC)
+	synced := modified.Add(-time.Hour)
+	two := 2
+	l := &Lookup{
+		Document: &Document{ID: "d", Hidden: true, Boost: -2, ChunkCount: &two, LastModified: &modified, LastSynced: &synced},
+		Connectors: []Connector{
+			{Name: "Drive", Status: "PAUSED", HasBeenIndexed: true},
+		},
+		ACL:    []string{"user_email:a@example.com"},
+		Chunks: []Chunk{{ChunkID: 0, Hidden: false, ACL: []string{"PUBLIC"}}},
+	}
+
+	findings := strings.Join(l.Diagnose(), "\n")
+	for _, want := range []string{
+		"hidden",
+		"paused",
+		"expects 2 chunk(s) but Vespa has 1",
+		"1 chunk(s) in Vespa disagree",
+		"not been synced",
+		"not public",
+		"Negative boost (-2)",
+	} {
+		if !strings.Contains(findings, want) {
+			t.Errorf("expected a finding containing %q, got:\n%s", want, findings)
+		}
+	}
+
+	// Without Vespa results, chunk findings are withheld rather than
+	// reported as missing.
+	l.IndexError = "Vespa is disabled"
+	l.Chunks = nil
+	if findings := strings.Join(l.Diagnose(), "\n"); strings.Contains(findings, "Vespa has no chunks") {
+		t.Errorf("reported missing chunks despite an index error:\n%s", findings)
+	}
+
+	orphan := &Lookup{Document: &Document{ID: "o"}, ACL: []string{"PUBLIC"}, IndexError: "x"}
+	if findings := strings.Join(orphan.Diagnose(), "\n"); !strings.Contains(findings, "orphan") {
+		t.Errorf("expected an orphan finding, got:\n%s", findings)
+	}
+}
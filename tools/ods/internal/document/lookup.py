@@ -0,0 +1,212 @@
+"""Look up a document in Postgres and Vespa.
+
+Bundled with ods and piped into `python -` on an api-server pod by
+`ods doc`. Finds the document by ID or link, then reports its row, the
+connectors that index it, the access list the backend computes for it and
+every chunk Vespa holds for it, so the two can be compared.
+
+Usage:
+    python - <schema> <document id or URL>
+
+An empty <schema> means the default schema of a single-tenant deployment.
+
+Progress goes to stderr; the last line on stdout is a JSON object with
+"status" and "document", "connectors", "acl", "document_sets", "chunks" and
+"index_error", or "candidates" when the link matches several documents.
+"""
+
+from __future__ import annotations
+
+import json
+import sys
+from datetime import datetime
+from datetime import timezone
+from typing import Any
+
+
+def iso(dt: datetime | None) -> str | None:
+    return dt.isoformat() if dt is not None else None
+
+
+def use_schema(schema: str) -> str:
+    from onyx.db.engine.tenant_utils import validate_tenant_id
+    from shared_configs.configs import MULTI_TENANT
+    from shared_configs.configs import POSTGRES_DEFAULT_SCHEMA
+    from shared_configs.contextvars import CURRENT_TENANT_ID_CONTEXTVAR
+
+    if not schema:
+        if MULTI_TENANT:
+            raise ValueError("This deployment is multi-tenant; pass --tenant")
+        schema = POSTGRES_DEFAULT_SCHEMA
+    elif schema != POSTGRES_DEFAULT_SCHEMA and not validate_tenant_id(schema):
+        raise ValueError(f"Invalid schema {schema!r}")
+    CURRENT_TENANT_ID_CONTEXTVAR.set(schema)
+    return schema
+
+
+def find_documents(db_session: Any, key: str) -> list[Any]:
+    from sqlalchemy import select
+
+    from onyx.db.models import Document
+
+    doc = db_session.get(Document, key)
+    if doc is not None:
+        return [doc]
+    # Many connectors use the URL as the ID, but not all; fall back to the
+    # link, with and without a trailing slash.
+    links = {key, key.rstrip("/"), key.rstrip("/") + "/"}
+    return list(
+        db_session.scalars(select(Document).where(Document.link.in_(links)).limit(10))
+    )
+
+
+def connectors(db_session: Any, doc_id: str) -> list[dict[str, Any]]:
+    from sqlalchemy import and_
+    from sqlalchemy import select
+
+    from onyx.db.models import Connector
+    from onyx.db.models import ConnectorCredentialPair
+    from onyx.db.models import DocumentByConnectorCredentialPair as DocByCC
+
+    rows = db_session.execute(
+        select(DocByCC, ConnectorCredentialPair, Connector)
+        .join(
+            ConnectorCredentialPair,
+            and_(
+                ConnectorCredentialPair.connector_id == DocByCC.connector_id,
+                ConnectorCredentialPair.credential_id == DocByCC.credential_id,
+            ),
+        )
+        .join(Connector, Connector.id == DocByCC.connector_id)
+        .where(DocByCC.id == doc_id)
+    ).all()
+    return [
+        {
+            "cc_pair_id": cc_pair.id,
+            "name": cc_pair.name,
+            "source": connector.source.value,
+            "status": cc_pair.status.value,
+            "access_type": cc_pair.access_type.value,
+            "has_been_indexed": bool(link.has_been_indexed),
+            "last_successful_index": iso(cc_pair.last_successful_index_time),
+            "last_permission_sync": iso(cc_pair.last_time_perm_sync),
+        }
+        for link, cc_pair, connector in rows
+    ]
+
+
+def index_chunks(db_session: Any, schema: str, doc_id: str) -> list[dict[str, Any]]:
+    from onyx.configs.app_configs import ONYX_DISABLE_VESPA
+    from onyx.db.search_settings import get_current_search_settings
+    from onyx.document_index.document_index_utils import get_multipass_config
+    from onyx.document_index.interfaces_new import TenantState
+    from onyx.document_index.vespa.vespa_document_index import VespaDocumentIndex
+    from shared_configs.configs import MULTI_TENANT
+
+    if ONYX_DISABLE_VESPA:
+        raise RuntimeError("Vespa is disabled in this deployment")
+    search_settings = get_current_search_settings(db_session)
+    index = VespaDocumentIndex(
+        index_name=search_settings.index_name,
+        tenant_state=TenantState(tenant_id=schema, multitenant=MULTI_TENANT),
+        large_chunks_enabled=get_multipass_config(
+            search_settings
+        ).enable_large_chunks,
+    )
+    chunks = []
+    for fields in index.get_raw_document_chunks(doc_id):
+        updated = fields.get("doc_updated_at")
+        chunks.append(
+            {
+                "chunk_id": fields.get("chunk_id"),
+                "boost": fields.get("boost"),
+                "hidden": bool(fields.get("hidden", False)),
+                "acl": sorted((fields.get("access_control_list") or {}).keys()),
+                "document_sets": sorted((fields.get("document_sets") or {}).keys()),
+                "updated_at": (
+                    datetime.fromtimestamp(updated, tz=timezone.utc).isoformat()
+                    if updated
+                    else None
+                ),
+                "content_chars": len(fields.get("content") or ""),
+            }
+        )
+    chunks.sort(key=lambda c: c["chunk_id"] or 0)
+    return chunks
+
+
+def lookup(schema: str, key: str) -> dict[str, Any]:
+    from onyx.access.access import get_access_for_documents
+    from onyx.db.document_set import fetch_document_sets_for_document
+    from onyx.db.engine.sql_engine import get_session_with_current_tenant
+
+    schema = use_schema(schema)
+    with get_session_with_current_tenant() as db_session:
+        docs = find_documents(db_session, key)
+        if not docs:
+            return {"status": "error", "message": f"No document with ID or link {key!r}"}
+        if len(docs) > 1:
+            return {
+                "status": "success",
+                "candidates": [
+                    {"id": d.id, "semantic_id": d.semantic_id} for d in docs
+                ],
+            }
+        doc = docs[0]
+        access = get_access_for_documents([doc.id], db_session).get(doc.id)
+
+        result: dict[str, Any] = {
+            "status": "success",
+            "document": {
+                "id": doc.id,
+                "semantic_id": doc.semantic_id,
+                "link": doc.link,
+                "boost": doc.boost,
+                "hidden": bool(doc.hidden),
+                "is_public": bool(doc.is_public),
+                "from_ingestion_api": bool(doc.from_ingestion_api),
+                "chunk_count": doc.chunk_count,
+                "doc_updated_at": iso(doc.doc_updated_at),
+                "last_modified": iso(doc.last_modified),
+                "last_synced": iso(doc.last_synced),
+            },
+            "connectors": connectors(db_session, doc.id),
+            "acl": sorted(access.to_acl()) if access is not None else [],
+            "document_sets": sorted(fetch_document_sets_for_document(doc.id, db_session)),
+            "chunks": [],
+            "index_error": "",
+        }
+        try:
+            print("Fetching chunks from Vespa...", file=sys.stderr)
+            result["chunks"] = index_chunks(db_session, schema, doc.id)
+        except Exception as e:
+            result["index_error"] = str(e)
+        return result
+
+
+def main() -> None:
+    if len(sys.argv) != 3:
+        print(
+            json.dumps(
+                {
+                    "status": "error",
+                    "message": "Usage: python - <schema> <document id or URL>",
+                }
+            )
+        )
+        sys.exit(1)
+
+    from onyx.db.engine.sql_engine import SqlEngine
+
+    SqlEngine.init_engine(pool_size=5, max_overflow=2)
+
+    try:
+        result = lookup(sys.argv[1], sys.argv[2])
+    except Exception as e:
+        print(f"Error: {e}", file=sys.stderr)
+        result = {"status": "error", "message": str(e)}
+    print(json.dumps(result))
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,317 @@
+// Package domains gathers the hosts a deployment's ingresses serve, tenants'
+// custom domains among them, and checks their DNS records and certificates.
+package domains
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/kube"
+)
+
+// TenantLabel is the label on the ingress of a tenant's custom domain that
+// holds the tenant ID. Hosts of ingresses without it are the deployment's
+// own.
+const TenantLabel = "onyx.app/tenant-id"
+
+// Domain is a host served by an ingress, with what was found about it.
+type Domain struct {
+	Host         string   `json:"host"`
+	Tenant       string   `json:"tenant"`
+	Ingress      string   `json:"ingress"`
+	LoadBalancer []string `json:"load_balancer"`
+	// SecretName is the TLS secret the ingress serves the host with, ""
+	// when it is served over plain http.
+	SecretName  string       `json:"secret_name"`
+	CertManager *CertManager `json:"cert_manager"`
+	// Unmanaged is set when cert-manager is installed but no Certificate
+	// writes the host's TLS secret, so nothing renews it.
+	Unmanaged bool  `json:"unmanaged"`
+	Stored    *Cert `json:"stored_cert"`
+	Served    *Cert `json:"served_cert"`
+	DNS       *DNS  `json:"dns"`
+}
+
+// CertManager is the state of the cert-manager Certificate behind a host.
+type CertManager struct {
+	Name           string    `json:"name"`
+	Issuer         string    `json:"issuer"`
+	Ready          bool      `json:"ready"`
+	Message        string    `json:"message"`
+	Issuing        bool      `json:"issuing"`
+	RenewalTime    time.Time `json:"renewal_time"`
+	FailedAttempts int       `json:"failed_attempts"`
+}
+
+// Cert is a certificate as stored in a TLS secret or served on port 443.
+type Cert struct {
+	Subject  string    `json:"subject"`
+	Issuer   string    `json:"issuer"`
+	DNSNames []string  `json:"dns_names"`
+	Serial   string    `json:"serial"`
+	NotAfter time.Time `json:"not_after"`
+	// VerifyError is why a served certificate is not trusted for the host.
+	VerifyError string `json:"verify_error,omitempty"`
+	Error       string `json:"error,omitempty"`
+}
+
+// DNS is what the host and the ingress's load balancer resolve to.
+type DNS struct {
+	CNAME     string   `json:"cname"`
+	Addresses []string `json:"addresses"`
+	// LoadBalancerAddresses are the IPs of the ingress's load balancer.
+	LoadBalancerAddresses []string `json:"load_balancer_addresses"`
+	Error                 string   `json:"error,omitempty"`
+}
+
+// Collect returns a domain for every host of the ingresses, sorted by tenant
+// and host. certs are the namespace's cert-manager Certificates, nil when
+// cert-manager is not installed.
+func Collect(ingresses []*kube.Ingress, certs []*kube.Certificate) []Domain {
+	bySecret := map[string]*kube.Certificate{}
+	for _, c := range certs {
+		bySecret[c.SecretName] = c
+	}
+
+	seen := map[string]bool{}
+	var domains []Domain
+	for _, ing := range ingresses {
+		for _, host := range ing.Hosts {
+			if seen[host] {
+				continue
+			}
+			seen[host] = true
+			d := Domain{
+				Host:         host,
+				Tenant:       ing.Labels[TenantLabel],
+				Ingress:      ing.Name,
+				LoadBalancer: ing.LoadBalancer,
+				SecretName:   ing.TLSSecret(host),
+			}
+			if c := bySecret[d.SecretName]; c != nil && d.SecretName != "" {
+				d.CertManager = &CertManager{
+					Name:           c.Name,
+					Issuer:         c.Issuer,
+					Ready:          c.Ready,
+					Message:        c.ReadyMessage,
+					Issuing:        c.Issuing,
+					RenewalTime:    c.RenewalTime,
+					FailedAttempts: c.FailedIssuanceAttempts,
+				}
+			} else if certs != nil && d.SecretName != "" {
+				d.Unmanaged = true
+			}
+			domains = append(domains, d)
+		}
+	}
+	sort.Slice(domains, func(i, j int) bool {
+		if domains[i].Tenant != domains[j].Tenant {
+			return domains[i].Tenant < domains[j].Tenant
+		}
+		return domains[i].Host < domains[j].Host
+	})
+	return domains
+}
+
+// ParseStored reads the certificate of a kubernetes.io/tls secret's tls.crt.
+func ParseStored(secret *kube.Secret) *Cert {
+	block, _ := pem.Decode([]byte(secret.Data["tls.crt"]))
+	if block == nil {
+		return &Cert{Error: fmt.Sprintf("secret %s has no PEM certificate in tls.crt", secret.Name)}
+	}
+	cert, err := x509.ParseCertificate(block.Bytes)
+	if err != nil {
+		return &Cert{Error: fmt.Sprintf("secret %s: %v", secret.Name, err)}
+	}
+	return fromX509(cert)
+}
+
+// FetchServed connects to host:443 and returns the certificate it serves,
+// noting whether the system roots trust it for host.
+func FetchServed(host string, timeout time.Duration) *Cert {
+	dialer := &net.Dialer{Timeout: timeout}
+	conn, err := tls.DialWithDialer(dialer, "tcp", net.JoinHostPort(host, "443"), &tls.Config{
+		ServerName: host,
+		// Verified below, so an untrusted certificate is still described.
+		InsecureSkipVerify: true,
+	})
+	if err != nil {
+		return &Cert{Error: err.Error()}
+	}
+	defer func() { _ = conn.Close() }()
+
+	chain := conn.ConnectionState().PeerCertificates
+	if len(chain) == 0 {
+		return &Cert{Error: "no certificate was served"}
+	}
+	c := fromX509(chain[0])
+	intermediates := x509.NewCertPool()
+	for _, ic := range chain[1:] {
+		intermediates.AddCert(ic)
+	}
+	if _, err := chain[0].Verify(x509.VerifyOptions{DNSName: host, Intermediates: intermediates}); err != nil {
+		c.VerifyError = err.Error()
+	}
+	return c
+}
+
+// Resolve looks up host and the load balancer's hostnames and IPs.
+func Resolve(ctx context.Context, r *net.Resolver, host string, loadBalancer []string) *DNS {
+	d := &DNS{}
+	if cname, err := r.LookupCNAME(ctx, host); err == nil && strings.TrimSuffix(cname, ".") != host {
+		d.CNAME = strings.TrimSuffix(cname, ".")
+	}
+	addrs, err := r.LookupHost(ctx, host)
+	if err != nil {
+		d.Error = err.Error()
+	}
+	d.Addresses = addrs
+	for _, lb := range loadBalancer {
+		if net.ParseIP(lb) != nil {
+			d.LoadBalancerAddresses = append(d.LoadBalancerAddresses, lb)
+			continue
+		}
+		if lbAddrs, err := r.LookupHost(ctx, lb); err == nil {
+			d.LoadBalancerAddresses = append(d.LoadBalancerAddresses, lbAddrs...)
+		}
+	}
+	sort.Strings(d.Addresses)
+	sort.Strings(d.LoadBalancerAddresses)
+	return d
+}
+
+func fromX509(cert *x509.Certificate) *Cert {
+	return &Cert{
+		Subject:  cert.Subject.CommonName,
+		Issuer:   cert.Issuer.CommonName,
+		DNSNames: cert.DNSNames,
+		Serial:   cert.SerialNumber.Text(16),
+		NotAfter: cert.NotAfter,
+	}
+}
+
+// Expires returns when the host's certificate expires: the stored one, else
+// the served one. It is zero when neither is known.
+func (d *Domain) Expires() time.Time {
+	for _, c := range []*Cert{d.Stored, d.Served} {
+		if c != nil && c.Error == "" {
+			return c.NotAfter
+		}
+	}
+	return time.Time{}
+}
+
+// Findings lists what looks wrong with the domain as of now, with
+// certificates expiring before now+within flagged.
+func (d *Domain) Findings(now time.Time, within time.Duration) []string {
+	var findings []string
+	if d.SecretName == "" {
+		findings = append(findings, fmt.Sprintf("Ingress %s serves %s without TLS", d.Ingress, d.Host))
+	}
+	if c := d.Stored; c != nil {
+		findings = append(findings, certFindings("The certificate in secret "+d.SecretName, c, d.Host, now, within)...)
+	}
+	if cm := d.CertManager; cm != nil {
+		if !cm.Ready {
+			msg := cm.Message
+			if msg == "" {
+				msg = "no reason given"
+			}
+			findings = append(findings, fmt.Sprintf("cert-manager reports Certificate %s not ready: %s", cm.Name, msg))
+		}
+		if cm.FailedAttempts > 0 {
+			findings = append(findings, fmt.Sprintf("cert-manager has failed to issue Certificate %s %d time(s) in a row; see kubectl describe certificaterequest", cm.Name, cm.FailedAttempts))
+		}
+	}
+	if d.Unmanaged {
+		findings = append(findings, fmt.Sprintf("No cert-manager Certificate writes secret %s, so nothing renews it", d.SecretName))
+	}
+	findings = append(findings, d.dnsFindings()...)
+	if c := d.Served; c != nil {
+		switch {
+		case c.Error != "":
+			findings = append(findings, fmt.Sprintf("https://%s could not be reached: %s", d.Host, c.Error))
+		case c.VerifyError != "":
+			findings = append(findings, fmt.Sprintf("https://%s serves a certificate browsers reject (%s): %s", d.Host, c.Subject, c.VerifyError))
+		default:
+			if d.Stored == nil || d.Stored.Error != "" {
+				findings = append(findings, certFindings("The served certificate", c, d.Host, now, within)...)
+			}
+		}
+		if c.Error == "" && d.Stored != nil && d.Stored.Error == "" && c.Serial != d.Stored.Serial {
+			findings = append(findings, fmt.Sprintf("https://%s serves a different certificate (expires %s) than secret %s holds (expires %s); the ingress controller may not have picked up the new one",
+				d.Host, c.NotAfter.Format(time.DateOnly), d.SecretName, d.Stored.NotAfter.Format(time.DateOnly)))
+		}
+	}
+	return findings
+}
+
+func certFindings(what string, c *Cert, host string, now time.Time, within time.Duration) []string {
+	if c.Error != "" {
+		return []string{what + " could not be read: " + c.Error}
+	}
+	var findings []string
+	switch {
+	case !c.NotAfter.After(now):
+		findings = append(findings, fmt.Sprintf("%s expired on %s", what, c.NotAfter.Format(time.DateOnly)))
+	case c.NotAfter.Before(now.Add(within)):
+		findings = append(findings, fmt.Sprintf("%s expires on %s, in %d day(s)", what, c.NotAfter.Format(time.DateOnly), int(c.NotAfter.Sub(now).Hours()/24)))
+	}
+	if !covers(c.DNSNames, host) {
+		findings = append(findings, fmt.Sprintf("%s does not cover %s (it is for %s)", what, host, strings.Join(c.DNSNames, ", ")))
+	}
+	return findings
+}
+
+func (d *Domain) dnsFindings() []string {
+	dns := d.DNS
+	if dns == nil {
+		return nil
+	}
+	if dns.Error != "" {
+		return []string{fmt.Sprintf("%s does not resolve (%s), so users cannot reach it and HTTP-01 challenges fail", d.Host, dns.Error)}
+	}
+	if len(dns.LoadBalancerAddresses) == 0 {
+		return nil
+	}
+	lb := map[string]bool{}
+	for _, a := range dns.LoadBalancerAddresses {
+		lb[a] = true
+	}
+	for _, a := range dns.Addresses {
+		if lb[a] {
+			return nil
+		}
+	}
+	target := strings.Join(dns.Addresses, ", ")
+	if dns.CNAME != "" {
+		target = dns.CNAME + " (" + target + ")"
+	}
+	return []string{fmt.Sprintf("%s resolves to %s, not to the ingress load balancer %s; renewals over HTTP-01 will fail",
+		d.Host, target, strings.Join(d.LoadBalancer, ", "))}
+}
+
+// covers reports whether a certificate for names is valid for host,
+// allowing one wildcard label.
+func covers(names []string, host string) bool {
+	host = strings.ToLower(host)
+	for _, n := range names {
+		n = strings.ToLower(n)
+		if n == host {
+			return true
+		}
+		if rest, ok := strings.CutPrefix(n, "*."); ok {
+			if i := strings.IndexByte(host, '.'); i > 0 && host[i+1:] == rest {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,148 @@
+package domains
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/kube"
+)
+
+var now = time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+
+func TestCollect(t *testing.T) {
+	ingresses := []*kube.Ingress{
+		{
+			Name:   "acme-domain",
+			Labels: map[string]string{TenantLabel: "tenant_acme"},
+			Hosts:  []string{"onyx.acme.com"},
+			TLS:    []kube.IngressTLS{{Hosts: []string{"onyx.acme.com"}, SecretName: "acme-tls"}},
+		},
+		{
+			Name:  "onyx-ingress-webserver",
+			Hosts: []string{"cloud.onyx.app", "onyx.acme.com"},
+			TLS:   []kube.IngressTLS{{Hosts: []string{"cloud.onyx.app"}, SecretName: "webserver-tls"}},
+		},
+	}
+	certs := []*kube.Certificate{{Name: "webserver-tls", SecretName: "webserver-tls", Ready: true}}
+
+	domains := Collect(ingresses, certs)
+	if len(domains) != 2 {
+		t.Fatalf("expected 2 domains, got %+v", domains)
+	}
+	if d := domains[0]; d.Host != "cloud.onyx.app" || d.Tenant != "" || d.CertManager == nil || d.Unmanaged {
+		t.Errorf("unexpected deployment domain %+v", d)
+	}
+	if d := domains[1]; d.Host != "onyx.acme.com" || d.Tenant != "tenant_acme" || d.Ingress != "acme-domain" || d.CertManager != nil || !d.Unmanaged {
+		t.Errorf("unexpected tenant domain %+v", d)
+	}
+
+	// Without cert-manager nothing is flagged as unmanaged.
+	if d := Collect(ingresses, nil)[1]; d.Unmanaged {
+		t.Errorf("expected no unmanaged flag without cert-manager, got %+v", d)
+	}
+}
+
+func TestParseStored(t *testing.T) {
+	notAfter := now.Add(30 * 24 * time.Hour).Truncate(time.Second)
+	c := ParseStored(&kube.Secret{Name: "acme-tls", Data: map[string]string{"tls.crt": testCertPEM(t, notAfter, "onyx.acme.com")}})
+	if c.Error != "" || !c.NotAfter.Equal(notAfter) || len(c.DNSNames) != 1 || c.Serial == "" {
+		t.Errorf("unexpected cert %+v", c)
+	}
+
+	if c := ParseStored(&kube.Secret{Name: "empty"}); !strings.Contains(c.Error, "no PEM certificate") {
+		t.Errorf("expected an error for a secret without tls.crt, got %+v", c)
+	}
+}
+
+func TestFindings(t *testing.T) {
+	healthy := Domain{
+		Host:         "onyx.acme.com",
+		Ingress:      "acme-domain",
+		LoadBalancer: []string{"abc.elb.amazonaws.com"},
+		SecretName:   "acme-tls",
+		CertManager:  &CertManager{Name: "acme-tls", Ready: true},
+		Stored:       &Cert{DNSNames: []string{"*.acme.com"}, Serial: "1", NotAfter: now.Add(60 * 24 * time.Hour)},
+		Served:       &Cert{DNSNames: []string{"*.acme.com"}, Serial: "1", NotAfter: now.Add(60 * 24 * time.Hour)},
+		DNS:          &DNS{CNAME: "abc.elb.amazonaws.com", Addresses: []string{"1.2.3.4"}, LoadBalancerAddresses: []string{"1.2.3.4", "5.6.7.8"}},
+	}
+	if f := healthy.Findings(now, 21*24*time.Hour); len(f) != 0 {
+		t.Errorf("expected no findings, got %v", f)
+	}
+
+	broken := healthy
+	broken.Stored = &Cert{DNSNames: []string{"old.acme.com"}, Serial: "2", NotAfter: now.Add(5 * 24 * time.Hour)}
+	broken.CertManager = &CertManager{Name: "acme-tls", Message: "Issuing certificate", FailedAttempts: 3}
+	broken.DNS = &DNS{Addresses: []string{"9.9.9.9"}, LoadBalancerAddresses: []string{"1.2.3.4"}}
+	broken.Served = &Cert{Subject: "Kubernetes Ingress Controller Fake Certificate", Serial: "3", VerifyError: "x509: certificate signed by unknown authority"}
+	want := []string{
+		"expires on 2026-10-20, in 5 day(s)",
+		"does not cover onyx.acme.com",
+		"not ready: Issuing certificate",
+		"3 time(s) in a row",
+		"resolves to 9.9.9.9, not to the ingress load balancer",
+		"browsers reject (Kubernetes Ingress Controller Fake Certificate)",
+		"serves a different certificate",
+	}
+	f := broken.Findings(now, 21*24*time.Hour)
+	if len(f) != len(want) {
+		t.Fatalf("expected %d findings, got %d: %v", len(want), len(f), f)
+	}
+	for i, w := range want {
+		if !strings.Contains(f[i], w) {
+			t.Errorf("finding %d = %q, want it to contain %q", i, f[i], w)
+		}
+	}
+
+	plain := Domain{Host: "onyx.acme.com", Ingress: "acme-domain", DNS: &DNS{Error: "no such host"}}
+	f = plain.Findings(now, 21*24*time.Hour)
+	if len(f) != 2 || !strings.Contains(f[0], "without TLS") || !strings.Contains(f[1], "does not resolve") {
+		t.Errorf("unexpected findings %v", f)
+	}
+}
+
+func TestCovers(t *testing.T) {
+	tests := []struct {
+		names []string
+		host  string
+		want  bool
+	}{
+		{[]string{"onyx.acme.com"}, "ONYX.acme.com", true},
+		{[]string{"*.acme.com"}, "onyx.acme.com", true},
+		{[]string{"*.acme.com"}, "a.onyx.acme.com", false},
+		{[]string{"*.acme.com"}, "acme.com", false},
+		{nil, "onyx.acme.com", false},
+	}
+	for _, tt := range tests {
+		if got := covers(tt.names, tt.host); got != tt.want {
+			t.Errorf("covers(%v, %q) = %v, want %v", tt.names, tt.host, got, tt.want)
+		}
+	}
+}
+
+func testCertPEM(t *testing.T, notAfter time.Time, host string) string {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(42),
+		Subject:      pkix.Name{CommonName: host},
+		DNSNames:     []string{host},
+		NotBefore:    notAfter.Add(-90 * 24 * time.Hour),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}))
+}
@@ -0,0 +1,87 @@
+// Package drain stops the Celery workers of a pod from taking new tasks and
+// reports the tasks they still run, so the pod can be replaced without
+// killing index attempts mid-flight.
+package drain
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/kube"
+)
+
+//go:embed drain_worker.py
+var workerScript string
+
+// QueuesAnnotation marks a drained pod with the queues to resume, comma
+// separated.
+const QueuesAnnotation = "ods.onyx.app/drained-queues"
+
+// Task is a task a worker is running or holds prefetched.
+type Task struct {
+	Worker         string `json:"worker"`
+	ID             string `json:"id"`
+	Name           string `json:"name"`
+	RuntimeSeconds int    `json:"runtime_seconds"`
+}
+
+// State is what the Celery workers of a pod are doing.
+type State struct {
+	Workers []string `json:"workers"`
+	// Queues are the queues the workers consume, or for Stop and Resume the
+	// queues that were cancelled or added back.
+	Queues   []string `json:"queues"`
+	Active   []Task   `json:"active"`
+	Reserved []Task   `json:"reserved"`
+}
+
+// Busy returns the number of tasks the workers have yet to finish.
+func (s *State) Busy() int {
+	return len(s.Active) + len(s.Reserved)
+}
+
+// Status reports the workers of pod without changing them.
+func Status(c *kube.Cluster, pod string) (*State, error) {
+	return run(c, pod, "status")
+}
+
+// Stop cancels every queue the workers of pod consume. They finish the
+// tasks they hold but take no new ones.
+func Stop(c *kube.Cluster, pod string) (*State, error) {
+	return run(c, pod, "stop")
+}
+
+// Resume makes the workers of pod consume queues again.
+func Resume(c *kube.Cluster, pod string, queues []string) (*State, error) {
+	if len(queues) == 0 {
+		return nil, fmt.Errorf("no queues to resume")
+	}
+	return run(c, pod, append([]string{"resume"}, queues...)...)
+}
+
+func run(c *kube.Cluster, pod string, args ...string) (*State, error) {
+	out, err := c.RunPython(pod, workerScript, args...)
+	if err != nil {
+		return nil, err
+	}
+	return parseState(out)
+}
+
+func parseState(stdout string) (*State, error) {
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	last := strings.TrimSpace(lines[len(lines)-1])
+	var r struct {
+		Status  string `json:"status"`
+		Message string `json:"message"`
+		State
+	}
+	if err := json.Unmarshal([]byte(last), &r); err != nil {
+		return nil, fmt.Errorf("unexpected output from drain script: %q", last)
+	}
+	if r.Status != "success" {
+		return nil, fmt.Errorf("%s", r.Message)
+	}
+	return &r.State, nil
+}
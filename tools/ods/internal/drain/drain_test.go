@@ -0,0 +1,21 @@
+package drain
+
+import "testing"
+
+func TestParseState(t *testing.T) {
+	out := "Cancelling consumer of connector_doc_fetching...\n" + `{"status": "success", "workers": ["docfetching@pod-a"], "queues": ["connector_doc_fetching"], "active": [{"worker": "docfetching@pod-a", "id": "t1", "name": "docfetching_proxy_task", "runtime_seconds": 42}], "reserved": []}`
+	s, err := parseState(out)
+	if err != nil {
+		t.Fatalf("parseState() error: %v", err)
+	}
+	if len(s.Workers) != 1 || s.Queues[0] != "connector_doc_fetching" || s.Busy() != 1 || s.Active[0].RuntimeSeconds != 42 {
+		t.Errorf("unexpected state %+v", s)
+	}
+
+	if _, err := parseState(`{"status": "not_found", "message": "No Celery workers answered for pod-a"}`); err == nil || err.Error() != "No Celery workers answered for pod-a" {
+		t.Errorf("expected the script's message as error, got %v", err)
+	}
+	if _, err := parseState("Traceback (most recent call last):"); err == nil {
+		t.Error("expected non-JSON output to return an error")
+	}
+}
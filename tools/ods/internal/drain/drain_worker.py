@@ -0,0 +1,115 @@
+"""Stop, resume or inspect the Celery workers running in a pod.
+
+Bundled with ods and piped into `python -` on a worker pod by `ods drain
+worker`. Onyx workers are named <kind>@<pod hostname>, so the workers of this
+pod are those whose name ends in @<hostname>. Control commands go through the
+broker, the same way `celery control` does.
+
+Usage:
+    python - status
+    python - stop
+    python - resume <queue> [<queue> ...]
+
+stop cancels every queue the pod's workers consume, so they take no new
+tasks but finish the ones they hold; resume adds the given queues back.
+
+Progress goes to stderr; the last line on stdout is a JSON object with
+"status", the pod's "workers" and their "queues", and the tasks they are
+running ("active") or hold prefetched ("reserved").
+"""
+
+from __future__ import annotations
+
+import json
+import socket
+import sys
+import time
+from typing import Any
+
+TIMEOUT = 5.0
+
+
+def _workers(app: Any, hostname: str) -> list[str]:
+    replies = app.control.ping(timeout=TIMEOUT) or []
+    names = [name for reply in replies for name in reply]
+    return sorted(n for n in names if n.split("@", 1)[-1] == hostname)
+
+
+def _tasks(by_worker: dict[str, list[dict[str, Any]]] | None) -> list[dict[str, Any]]:
+    now = time.time()
+    tasks = []
+    for worker, entries in (by_worker or {}).items():
+        for t in entries:
+            started = t.get("time_start")
+            tasks.append(
+                {
+                    "worker": worker,
+                    "id": t.get("id", ""),
+                    "name": t.get("name", ""),
+                    "runtime_seconds": int(now - started) if started else 0,
+                }
+            )
+    return tasks
+
+
+def run(action: str, queues: list[str]) -> dict[str, Any]:
+    from onyx.background.celery.versioned_apps.client import app
+
+    hostname = socket.gethostname()
+    workers = _workers(app, hostname)
+    if not workers:
+        return {
+            "status": "not_found",
+            "message": f"No Celery workers answered for {hostname}",
+        }
+
+    inspect = app.control.inspect(destination=workers, timeout=TIMEOUT)
+    active_queues = inspect.active_queues() or {}
+    consumed = sorted({q["name"] for qs in active_queues.values() for q in qs})
+
+    if action == "stop":
+        for queue in consumed:
+            print(f"Cancelling consumer of {queue}...", file=sys.stderr)
+            app.control.cancel_consumer(queue, destination=workers, reply=True)
+        queues = consumed
+    elif action == "resume":
+        for queue in queues:
+            print(f"Adding consumer of {queue}...", file=sys.stderr)
+            app.control.add_consumer(queue, destination=workers, reply=True)
+    else:
+        queues = consumed
+
+    return {
+        "status": "success",
+        "workers": workers,
+        "queues": queues,
+        "active": _tasks(inspect.active()),
+        "reserved": _tasks(inspect.reserved()),
+    }
+
+
+def main() -> None:
+    action = sys.argv[1] if len(sys.argv) > 1 else ""
+    if action not in ("status", "stop", "resume") or (
+        action == "resume" and len(sys.argv) < 3
+    ):
+        print(
+            json.dumps(
+                {
+                    "status": "error",
+                    "message": "Usage: python - status|stop|resume [<queue> ...]",
+                }
+            )
+        )
+        sys.exit(1)
+
+    try:
+        result = run(action, sys.argv[2:])
+    except Exception as e:
+        print(f"Error: {e}", file=sys.stderr)
+        result = {"status": "error", "message": str(e)}
+    print(json.dumps(result))
+
+
+if __name__ == "__main__":
+    main()
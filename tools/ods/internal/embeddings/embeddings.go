@@ -0,0 +1,91 @@
+// Package embeddings exports chunk embedding vectors from Vespa for offline
+// analysis.
+package embeddings
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/kube"
+)
+
+//go:embed export_embeddings.py
+var exportScript string
+
+// Formats are the file formats Export can write.
+var Formats = []string{"parquet", "jsonl"}
+
+// Options selects what Export pulls and how it writes it.
+type Options struct {
+	// Schema is the tenant's schema, "" for the default schema of a
+	// single-tenant deployment.
+	Schema string
+	// Sample is the number of chunks to export, picked uniformly at random;
+	// 0 exports all of them.
+	Sample int
+	Format string
+	// UMAP adds 2D UMAP coordinates to each row.
+	UMAP bool
+}
+
+// Result describes a finished export.
+type Result struct {
+	IndexName  string `json:"index_name"`
+	ModelName  string `json:"model_name"`
+	Dimensions int    `json:"dimensions"`
+	// Visited is the number of chunks the tenant has; Rows the number
+	// written.
+	Visited int `json:"visited"`
+	Rows    int `json:"rows"`
+	// Output is the file on the pod holding the rows.
+	Output string `json:"output"`
+}
+
+// Export samples the tenant's chunk embeddings on pod and writes them to a
+// file there, named in the result. The caller copies it off and removes it.
+func Export(c *kube.Cluster, pod string, opts Options) (*Result, error) {
+	if !slices.Contains(Formats, opts.Format) {
+		return nil, fmt.Errorf("unknown format %q (want one of %s)", opts.Format, strings.Join(Formats, ", "))
+	}
+	remote := "/tmp/" + FileName(opts.Schema, opts.Format, time.Now())
+	args := []string{opts.Schema, strconv.Itoa(opts.Sample), opts.Format, remote}
+	if opts.UMAP {
+		args = append(args, "umap")
+	}
+	out, err := c.RunPython(pod, exportScript, args...)
+	if err != nil {
+		return nil, err
+	}
+	return parseResult(out)
+}
+
+// FileName names an export of schema, e.g.
+// "embeddings-tenant_abcd-20261015-1402.parquet".
+func FileName(schema, format string, now time.Time) string {
+	if schema == "" {
+		schema = "public"
+	}
+	return fmt.Sprintf("embeddings-%s-%s.%s", schema, now.UTC().Format("20060102-1504"), format)
+}
+
+func parseResult(stdout string) (*Result, error) {
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	last := strings.TrimSpace(lines[len(lines)-1])
+	var r struct {
+		Status  string `json:"status"`
+		Message string `json:"message"`
+		Result
+	}
+	if err := json.Unmarshal([]byte(last), &r); err != nil {
+		return nil, fmt.Errorf("unexpected output from export script: %q", last)
+	}
+	if r.Status != "success" {
+		return nil, fmt.Errorf("%s", r.Message)
+	}
+	return &r.Result, nil
+}
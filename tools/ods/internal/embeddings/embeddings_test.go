@@ -0,0 +1,31 @@
+package embeddings
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseResult(t *testing.T) {
+	out := "Visiting chunks...\n" + `{"status": "success", "index_name": "danswer_chunk_nomic", "model_name": "nomic-ai/nomic-embed-text-v1", "dimensions": 768, "visited": 120000, "rows": 5000, "output": "/tmp/embeddings-tenant_a-20261015-1402.parquet"}`
+	r, err := parseResult(out)
+	if err != nil {
+		t.Fatalf("parseResult() error: %v", err)
+	}
+	if r.Rows != 5000 || r.Visited != 120000 || r.Dimensions != 768 || r.Output == "" {
+		t.Errorf("unexpected result %+v", r)
+	}
+
+	if _, err := parseResult(`{"status": "error", "message": "pyarrow is not installed on this pod; use --format jsonl"}`); err == nil || err.Error() != "pyarrow is not installed on this pod; use --format jsonl" {
+		t.Errorf("expected the script's message as error, got %v", err)
+	}
+}
+
+func TestFileName(t *testing.T) {
+	now := time.Date(2026, 10, 15, 14, 2, 0, 0, time.UTC)
+	if got := FileName("tenant_a", "parquet", now); got != "embeddings-tenant_a-20261015-1402.parquet" {
+		t.Errorf("FileName() = %q", got)
+	}
+	if got := FileName("", "jsonl", now); got != "embeddings-public-20261015-1402.jsonl" {
+		t.Errorf("FileName() = %q", got)
+	}
+}
@@ -0,0 +1,243 @@
+"""Export a sample of a tenant's chunk embeddings from Vespa.
+
+Bundled with ods and piped into `python -` on an api-server pod by
+`ods embeddings export`. Visits the IDs of every regular (not large) chunk
+Vespa holds for the tenant, keeps a uniform random sample of them, then
+fetches each sampled chunk's full-chunk embedding and writes one row per
+chunk (document_id, chunk_id, source_type, embedding) to <output> on the pod.
+With "umap", each row also gets 2D UMAP coordinates (umap_x, umap_y); this
+needs umap-learn in the pod's environment.
+
+Usage:
+    python - <schema> <sample> <format> <output> [umap]
+
+An empty <schema> means the default schema of a single-tenant deployment.
+<sample> is the number of chunks to export, 0 for all of them. <format> is
+"parquet" (needs pyarrow) or "jsonl".
+
+Progress goes to stderr; the last line on stdout is a JSON object with
+"status", "index_name", "model_name", "dimensions", "visited" (chunks in the
+tenant), "rows" (chunks written) and "output".
+"""
+
+from __future__ import annotations
+
+import json
+import random
+import sys
+from concurrent.futures import ThreadPoolExecutor
+from typing import Any
+
+FETCH_WORKERS = 16
+
+
+def use_schema(schema: str) -> str:
+    from onyx.db.engine.tenant_utils import validate_tenant_id
+    from shared_configs.configs import MULTI_TENANT
+    from shared_configs.configs import POSTGRES_DEFAULT_SCHEMA
+    from shared_configs.contextvars import CURRENT_TENANT_ID_CONTEXTVAR
+
+    if not schema:
+        if MULTI_TENANT:
+            raise ValueError("This deployment is multi-tenant; pass --tenant")
+        schema = POSTGRES_DEFAULT_SCHEMA
+    elif schema != POSTGRES_DEFAULT_SCHEMA and not validate_tenant_id(schema):
+        raise ValueError(f"Invalid schema {schema!r}")
+    CURRENT_TENANT_ID_CONTEXTVAR.set(schema)
+    return schema
+
+
+def sample_chunk_ids(
+    client: Any, index_name: str, schema: str, sample: int
+) -> tuple[list[str], int]:
+    """Visits the tenant's chunk IDs and reservoir-samples them, so the sample
+    is uniform without holding every ID in memory."""
+    from onyx.document_index.vespa_constants import DOCUMENT_ID_ENDPOINT
+    from shared_configs.configs import MULTI_TENANT
+
+    selection = f"{index_name}.large_chunk_reference_ids == null"
+    if MULTI_TENANT:
+        selection += f" and {index_name}.tenant_id=='{schema}'"
+    params: dict[str, Any] = {
+        "selection": selection,
+        "wantedDocumentCount": 1_000,
+        "fieldSet": "[id]",
+    }
+
+    kept: list[str] = []
+    visited = reported = 0
+    while True:
+        response = client.get(
+            DOCUMENT_ID_ENDPOINT.format(index_name=index_name), params=params
+        )
+        response.raise_for_status()
+        data = response.json()
+        for doc in data.get("documents", []):
+            # "id:<namespace>:<doctype>::<chunk uuid>"
+            chunk_id = doc["id"].split("::", 1)[-1]
+            visited += 1
+            if sample <= 0 or len(kept) < sample:
+                kept.append(chunk_id)
+            else:
+                i = random.randrange(visited)
+                if i < sample:
+                    kept[i] = chunk_id
+        if visited - reported >= 100_000:
+            print(f"Visited {visited} chunks...", file=sys.stderr)
+            reported = visited
+        if not data.get("continuation"):
+            return kept, visited
+        params["continuation"] = data["continuation"]
+
+
+def fetch_row(client: Any, index_name: str, chunk_id: str) -> dict[str, Any] | None:
+    from onyx.document_index.vespa_constants import DOCUMENT_ID_ENDPOINT
+
+    response = client.get(
+        f"{DOCUMENT_ID_ENDPOINT.format(index_name=index_name)}/{chunk_id}",
+        params={
+            "fieldSet": f"{index_name}:document_id,chunk_id,source_type,embeddings",
+            "format.tensors": "short-value",
+        },
+    )
+    if response.status_code == 404:
+        # Deleted since the visit.
+        return None
+    response.raise_for_status()
+    fields = response.json().get("fields", {})
+    embedding = (fields.get("embeddings") or {}).get("full_chunk")
+    if not embedding:
+        return None
+    return {
+        "document_id": fields.get("document_id"),
+        "chunk_id": fields.get("chunk_id"),
+        "source_type": fields.get("source_type"),
+        "embedding": embedding,
+    }
+
+
+def add_umap(rows: list[dict[str, Any]]) -> None:
+    try:
+        import numpy as np
+        import umap  # type: ignore[import-not-found]
+    except ImportError:
+        raise RuntimeError(
+            "umap-learn is not installed on this pod; export without --umap "
+            "and project the vectors offline"
+        )
+    if len(rows) < 3:
+        raise RuntimeError(f"UMAP needs at least 3 chunks, got {len(rows)}")
+
+    print(f"Projecting {len(rows)} embeddings with UMAP...", file=sys.stderr)
+    vectors = np.array([row["embedding"] for row in rows], dtype=np.float32)
+    coords = umap.UMAP(
+        n_components=2, n_neighbors=min(15, len(rows) - 1), metric="cosine"
+    ).fit_transform(vectors)
+    for row, (x, y) in zip(rows, coords):
+        row["umap_x"] = float(x)
+        row["umap_y"] = float(y)
+
+
+def write_rows(rows: list[dict[str, Any]], fmt: str, output: str) -> None:
+    if fmt == "jsonl":
+        with open(output, "w") as f:
+            for row in rows:
+                f.write(json.dumps(row) + "\n")
+        return
+    if fmt != "parquet":
+        raise ValueError(f"Unknown format {fmt!r}")
+    try:
+        import pyarrow as pa
+        import pyarrow.parquet as pq
+    except ImportError:
+        raise RuntimeError(
+            "pyarrow is not installed on this pod; use --format jsonl"
+        )
+    columns = {key: [row.get(key) for row in rows] for key in rows[0]}
+    schema = pa.schema(
+        [
+            (key, pa.list_(pa.float32()))
+            if key == "embedding"
+            else (key, pa.array(values).type)
+            for key, values in columns.items()
+        ]
+    )
+    pq.write_table(pa.table(columns, schema=schema), output)
+
+
+def export(
+    schema: str, sample: int, fmt: str, output: str, with_umap: bool
+) -> dict[str, Any]:
+    from onyx.configs.app_configs import ONYX_DISABLE_VESPA
+    from onyx.db.engine.sql_engine import get_session_with_current_tenant
+    from onyx.db.search_settings import get_current_search_settings
+    from onyx.document_index.vespa.shared_utils.utils import get_vespa_http_client
+
+    if ONYX_DISABLE_VESPA:
+        raise RuntimeError("Vespa is disabled in this deployment")
+    schema = use_schema(schema)
+    with get_session_with_current_tenant() as db_session:
+        search_settings = get_current_search_settings(db_session)
+        index_name = search_settings.index_name
+        model_name = search_settings.model_name
+
+    with get_vespa_http_client(no_timeout=True) as client:
+        print(f"Visiting chunks of {schema} in {index_name}...", file=sys.stderr)
+        chunk_ids, visited = sample_chunk_ids(client, index_name, schema, sample)
+        if not chunk_ids:
+            return {"status": "error", "message": f"Vespa has no chunks for {schema}"}
+
+        print(f"Fetching {len(chunk_ids)} embeddings...", file=sys.stderr)
+        with ThreadPoolExecutor(max_workers=FETCH_WORKERS) as pool:
+            fetched = pool.map(lambda i: fetch_row(client, index_name, i), chunk_ids)
+            rows = [row for row in fetched if row is not None]
+    if not rows:
+        return {
+            "status": "error",
+            "message": "None of the sampled chunks has an embedding",
+        }
+    rows.sort(key=lambda row: (row["document_id"] or "", row["chunk_id"] or 0))
+
+    if with_umap:
+        add_umap(rows)
+    write_rows(rows, fmt, output)
+    return {
+        "status": "success",
+        "index_name": index_name,
+        "model_name": model_name,
+        "dimensions": len(rows[0]["embedding"]),
+        "visited": visited,
+        "rows": len(rows),
+        "output": output,
+    }
+
+
+def main() -> None:
+    if len(sys.argv) not in (5, 6):
+        print(
+            json.dumps(
+                {
+                    "status": "error",
+                    "message": "Usage: python - <schema> <sample> <format> "
+                    "<output> [umap]",
+                }
+            )
+        )
+        sys.exit(1)
+
+    from onyx.db.engine.sql_engine import SqlEngine
+
+    SqlEngine.init_engine(pool_size=5, max_overflow=2)
+
+    try:
+        result = export(
+            sys.argv[1], int(sys.argv[2]), sys.argv[3], sys.argv[4], len(sys.argv) == 6
+        )
+    except Exception as e:
+        print(f"Error: {e}", file=sys.stderr)
+        result = {"status": "error", "message": str(e)}
+    print(json.dumps(result))
+
+
+if __name__ == "__main__":
+    main()
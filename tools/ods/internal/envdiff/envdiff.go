@@ -0,0 +1,201 @@
+// Package envdiff snapshots the configuration of a deployment — images,
+// replica counts, environment variables and feature flags — and diffs two
+// snapshots. Secret values are replaced by keyed hashes, so equal values can
+// be recognized without being shown.
+package envdiff
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/flags"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/kube"
+)
+
+// Sections of a snapshot, in display order.
+const (
+	SectionImage    = "image"
+	SectionReplicas = "replicas"
+	SectionEnv      = "env"
+	SectionFlag     = "flag"
+)
+
+// Sections lists every section in display order.
+var Sections = []string{SectionImage, SectionReplicas, SectionEnv, SectionFlag}
+
+// sensitiveName matches variables whose literal values are treated as
+// secrets even when they are not read from a Secret.
+var sensitiveName = regexp.MustCompile(`(?i)(SECRET|PASSWORD|PASSWD|TOKEN|API_KEY|PRIVATE_KEY|CREDENTIAL|DSN)`)
+
+// Hasher hashes secret values with a key that exists only for one run, so
+// hashes are comparable between the snapshots of that run but cannot be
+// matched against hashes of guessed values.
+type Hasher struct {
+	key []byte
+}
+
+// NewHasher returns a Hasher with a fresh random key.
+func NewHasher() (*Hasher, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("failed to generate hash key: %w", err)
+	}
+	return &Hasher{key: key}, nil
+}
+
+// Hash returns a display form of a secret value.
+func (h *Hasher) Hash(value string) string {
+	if value == "" {
+		return "(empty)"
+	}
+	mac := hmac.New(sha256.New, h.key)
+	mac.Write([]byte(value))
+	return "secret:" + hex.EncodeToString(mac.Sum(nil))[:12]
+}
+
+// Snapshot is the configuration of one context. Each section maps a key
+// (a workload, "workload NAME" for env vars, or a flag name) to its display
+// value.
+type Snapshot struct {
+	Context  string
+	Sections map[string]map[string]string
+	// Warnings describe parts of the snapshot that could not be taken.
+	Warnings []string
+}
+
+// Collect takes a snapshot of c. Feature flags are read from tenant ("" for
+// a single-tenant deployment) through pod; with an empty pod, or if they
+// cannot be read, they are left out with a warning.
+func Collect(c *kube.Cluster, pod, tenant string, h *Hasher) (*Snapshot, error) {
+	deployments, err := c.ListDeployments()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list deployments: %w", err)
+	}
+	autoscaled, err := c.AutoscaledDeployments()
+	if err != nil {
+		return nil, fmt.Errorf("failed to list autoscalers: %w", err)
+	}
+	envs, err := c.DeploymentEnvs()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read deployment environments: %w", err)
+	}
+	var flagList []flags.Flag
+	var flagsErr error
+	if pod != "" {
+		flagList, flagsErr = flags.List(c, pod, tenant)
+	}
+	s := build(c.Name, deployments, autoscaled, envs, flagList, h)
+	switch {
+	case pod == "":
+		delete(s.Sections, SectionFlag)
+	case flagsErr != nil:
+		delete(s.Sections, SectionFlag)
+		s.Warnings = append(s.Warnings, fmt.Sprintf("flags not compared: %v", flagsErr))
+	}
+	return s, nil
+}
+
+func build(context string, deployments []*kube.Deployment, autoscaled map[string]bool, envs []kube.ContainerEnv, flagList []flags.Flag, h *Hasher) *Snapshot {
+	s := &Snapshot{Context: context, Sections: map[string]map[string]string{}}
+	for _, name := range Sections {
+		s.Sections[name] = map[string]string{}
+	}
+
+	for _, d := range deployments {
+		for _, container := range d.Containers {
+			s.Sections[SectionImage][workload(d.Name, container.Name)] = container.Image
+		}
+		replicas := strconv.Itoa(d.Replicas)
+		if autoscaled[d.Name] {
+			// The count moves with load, so only the fact of autoscaling is
+			// comparable.
+			replicas = "autoscaled"
+		}
+		s.Sections[SectionReplicas][d.Name] = replicas
+	}
+
+	for _, env := range envs {
+		w := workload(env.Deployment, env.Container)
+		for _, v := range env.Vars {
+			value := v.Value
+			switch {
+			case v.Unresolved != "":
+				value = "<" + v.Unresolved + ">"
+			case v.Secret || sensitiveName.MatchString(v.Name):
+				value = h.Hash(v.Value)
+			}
+			s.Sections[SectionEnv][w+" "+v.Name] = value
+		}
+	}
+
+	for _, f := range flagList {
+		s.Sections[SectionFlag][f.Name] = fmt.Sprintf("%s (%s)", flags.FormatValue(f.Value), f.Source)
+	}
+	return s
+}
+
+// workload names a container: by its deployment alone when the container is
+// named after it, as Onyx's are.
+func workload(deployment, container string) string {
+	if container == deployment {
+		return deployment
+	}
+	return deployment + "/" + container
+}
+
+// Change is a key whose value differs between two snapshots.
+type Change struct {
+	Section string
+	Key     string
+	A, B    string
+	// InA and InB report whether the key exists in each snapshot.
+	InA, InB bool
+}
+
+// Diff returns the differences between a and b in the given sections (all
+// when empty), ordered by section and key. Sections missing from either
+// snapshot are skipped.
+func Diff(a, b *Snapshot, sections ...string) []Change {
+	if len(sections) == 0 {
+		sections = Sections
+	}
+	var changes []Change
+	for _, section := range Sections {
+		if !slices.Contains(sections, section) {
+			continue
+		}
+		av, okA := a.Sections[section]
+		bv, okB := b.Sections[section]
+		if !okA || !okB {
+			continue
+		}
+		keys := map[string]bool{}
+		for k := range av {
+			keys[k] = true
+		}
+		for k := range bv {
+			keys[k] = true
+		}
+		sorted := make([]string, 0, len(keys))
+		for k := range keys {
+			sorted = append(sorted, k)
+		}
+		sort.Strings(sorted)
+		for _, k := range sorted {
+			x, inA := av[k]
+			y, inB := bv[k]
+			if inA && inB && x == y {
+				continue
+			}
+			changes = append(changes, Change{Section: section, Key: k, A: x, B: y, InA: inA, InB: inB})
+		}
+	}
+	return changes
+}
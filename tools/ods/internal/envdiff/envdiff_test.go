@@ -0,0 +1,94 @@
+package envdiff
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/flags"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/kube"
+)
+
+func TestHasher(t *testing.T) {
+	h, err := NewHasher()
+	if err != nil {
+		t.Fatal(err)
+	}
+	a, b := h.Hash("hunter2"), h.Hash("hunter2")
+	if a != b || strings.Contains(a, "hunter2") || !strings.HasPrefix(a, "secret:") {
+		t.Errorf("Hash gave %q and %q", a, b)
+	}
+	if h.Hash("hunter3") == a {
+		t.Error("different values hashed the same")
+	}
+	other, _ := NewHasher()
+	if other.Hash("hunter2") == a {
+		t.Error("hashes should depend on the run's key")
+	}
+	if h.Hash("") != "(empty)" {
+		t.Errorf("Hash(\"\") = %q", h.Hash(""))
+	}
+}
+
+func snapshot(t *testing.T, h *Hasher, context, image string, replicas int, autoscale bool, env []kube.EnvVar, flag bool) *Snapshot {
+	t.Helper()
+	deployments := []*kube.Deployment{{
+		Name:       "api-server",
+		Replicas:   replicas,
+		Containers: []kube.Container{{Name: "api-server", Image: image}, {Name: "proxy", Image: "envoy:1"}},
+	}}
+	envs := []kube.ContainerEnv{{Deployment: "api-server", Container: "api-server", Vars: env}}
+	return build(context, deployments, map[string]bool{"api-server": autoscale}, envs, []flags.Flag{{Name: "search_ui", Value: &flag, Source: "stored"}}, h)
+}
+
+func TestDiff(t *testing.T) {
+	h, _ := NewHasher()
+	staging := snapshot(t, h, "staging", "onyx-backend:v2", 2, false, []kube.EnvVar{
+		{Name: "LOG_LEVEL", Value: "debug"},
+		{Name: "POSTGRES_PASSWORD", Value: "a", Secret: true},
+		{Name: "OPENAI_API_KEY", Value: "same"},
+		{Name: "STAGING_ONLY", Value: "1"},
+	}, true)
+	prod := snapshot(t, h, "prod", "onyx-backend:v1", 6, true, []kube.EnvVar{
+		{Name: "LOG_LEVEL", Value: "info"},
+		{Name: "POSTGRES_PASSWORD", Value: "b", Secret: true},
+		{Name: "OPENAI_API_KEY", Value: "same"},
+	}, false)
+
+	if v := staging.Sections[SectionEnv]["api-server OPENAI_API_KEY"]; v == "same" {
+		t.Error("a literal value of a sensitive variable was not hashed")
+	}
+
+	changes := Diff(staging, prod)
+	var keys []string
+	for _, c := range changes {
+		keys = append(keys, c.Section+" "+c.Key)
+	}
+	want := []string{
+		"image api-server",
+		"replicas api-server",
+		"env api-server LOG_LEVEL",
+		"env api-server POSTGRES_PASSWORD",
+		"env api-server STAGING_ONLY",
+		"flag search_ui",
+	}
+	if !reflect.DeepEqual(keys, want) {
+		t.Fatalf("changes = %v, want %v", keys, want)
+	}
+	if changes[1].B != "autoscaled" {
+		t.Errorf("replicas of an autoscaled deployment = %q", changes[1].B)
+	}
+	if c := changes[4]; !c.InA || c.InB {
+		t.Errorf("STAGING_ONLY should only be in staging: %+v", c)
+	}
+	if c := changes[5]; c.A != "on (stored)" || c.B != "off (stored)" {
+		t.Errorf("unexpected flag change %+v", c)
+	}
+
+	if got := Diff(staging, prod, SectionImage); len(got) != 1 {
+		t.Errorf("Diff limited to images returned %d changes", len(got))
+	}
+	if got := Diff(staging, staging); len(got) != 0 {
+		t.Errorf("a snapshot differs from itself: %+v", got)
+	}
+}
@@ -0,0 +1,69 @@
+package eval
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Case is one labeled query of a dataset.
+type Case struct {
+	// ID names the case across runs; it defaults to the line number.
+	ID    string `json:"id"`
+	Query string `json:"query"`
+	// Relevant are the document IDs search should return for the query.
+	// Cases without them are not scored for retrieval.
+	Relevant []string `json:"relevant_document_ids"`
+	// Answer is a reference answer for the judge. Cases without one are
+	// not sent through chat.
+	Answer string `json:"expected_answer"`
+}
+
+// LoadDataset reads a JSONL dataset: one Case per line, blank lines and
+// lines starting with # ignored.
+func LoadDataset(path string) ([]Case, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = f.Close() }()
+
+	var cases []Case
+	seen := map[string]int{}
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4<<20)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		var c Case
+		if err := json.Unmarshal([]byte(line), &c); err != nil {
+			return nil, fmt.Errorf("%s:%d: %w", path, n, err)
+		}
+		if strings.TrimSpace(c.Query) == "" {
+			return nil, fmt.Errorf("%s:%d: query is empty", path, n)
+		}
+		if len(c.Relevant) == 0 && c.Answer == "" {
+			return nil, fmt.Errorf("%s:%d: needs relevant_document_ids, expected_answer or both", path, n)
+		}
+		if c.ID == "" {
+			c.ID = strconv.Itoa(n)
+		}
+		if prev, ok := seen[c.ID]; ok {
+			return nil, fmt.Errorf("%s:%d: id %q already used on line %d", path, n, c.ID, prev)
+		}
+		seen[c.ID] = n
+		cases = append(cases, c)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+	if len(cases) == 0 {
+		return nil, fmt.Errorf("%s has no cases", path)
+	}
+	return cases, nil
+}
@@ -0,0 +1,270 @@
+// Package eval runs a labeled set of queries through an Onyx API server's
+// search and chat, scores retrieval (recall@k, MRR) and answers (graded by an
+// LLM judge), and compares runs to catch relevance regressions.
+package eval
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/api"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/chatstream"
+)
+
+// Options configures a run.
+type Options struct {
+	// K are the cutoffs recall is computed at.
+	K []int
+	// Persona is the assistant answers come from.
+	Persona int
+	// Judge grades answers; nil skips chat entirely.
+	Judge *Judge
+	// Parallel is the number of cases run at once.
+	Parallel int
+	// OnCase, if set, is called as each case finishes.
+	OnCase func(CaseResult)
+}
+
+// CaseResult is the outcome of one case.
+type CaseResult struct {
+	ID    string `json:"id"`
+	Query string `json:"query"`
+	// Retrieved are the document IDs search returned, best first, up to
+	// the largest K.
+	Retrieved []string `json:"retrieved,omitempty"`
+	// Recall maps each K to the fraction of relevant documents in the top
+	// K; RR is the reciprocal rank of the first relevant one. Both are
+	// unset for cases without relevant documents.
+	Recall   map[int]float64 `json:"recall,omitempty"`
+	RR       *float64        `json:"rr,omitempty"`
+	SearchMS int64           `json:"search_ms,omitempty"`
+
+	Answer string `json:"answer,omitempty"`
+	Grade  *Grade `json:"grade,omitempty"`
+	ChatMS int64  `json:"chat_ms,omitempty"`
+
+	Error string `json:"error,omitempty"`
+}
+
+// Summary aggregates a run.
+type Summary struct {
+	// Retrieval is the number of cases scored for retrieval; Recall and
+	// MRR are averaged over them.
+	Retrieval int             `json:"retrieval"`
+	Recall    map[int]float64 `json:"recall"`
+	MRR       float64         `json:"mrr"`
+	// Graded is the number of answers the judge graded; AnswerScore is
+	// their mean score.
+	Graded      int     `json:"graded"`
+	AnswerScore float64 `json:"answer_score"`
+	Errors      int     `json:"errors"`
+}
+
+// Report is a finished run, as saved for later comparison.
+type Report struct {
+	Env        string       `json:"env"`
+	Dataset    string       `json:"dataset"`
+	Start      time.Time    `json:"start"`
+	K          []int        `json:"k"`
+	JudgeModel string       `json:"judge_model,omitempty"`
+	Cases      []CaseResult `json:"cases"`
+	Summary    Summary      `json:"summary"`
+}
+
+// Run sends every case through client and scores it. Failures are recorded
+// per case rather than stopping the run.
+func Run(client *api.Client, cases []Case, opts Options) *Report {
+	if len(opts.K) == 0 {
+		opts.K = []int{1, 5, 10}
+	}
+	opts.K = slices.Sorted(slices.Values(opts.K))
+	if opts.Parallel < 1 {
+		opts.Parallel = 1
+	}
+	r := &Report{Start: time.Now().UTC(), K: opts.K, Cases: make([]CaseResult, len(cases))}
+	if opts.Judge != nil {
+		r.JudgeModel = opts.Judge.Model
+	}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, opts.Parallel)
+	for i, c := range cases {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			res := runCase(client, c, opts)
+			r.Cases[i] = res
+			if opts.OnCase != nil {
+				mu.Lock()
+				opts.OnCase(res)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	r.Summary = Summarize(r.Cases, r.K)
+	return r
+}
+
+func runCase(client *api.Client, c Case, opts Options) CaseResult {
+	res := CaseResult{ID: c.ID, Query: c.Query}
+	var problems []string
+
+	if len(c.Relevant) > 0 {
+		start := time.Now()
+		docs, err := Search(client, c.Query)
+		res.SearchMS = time.Since(start).Milliseconds()
+		if err != nil {
+			problems = append(problems, "search: "+err.Error())
+		} else {
+			k := opts.K[len(opts.K)-1]
+			res.Retrieved = docs[:min(k, len(docs))]
+			res.Recall = map[int]float64{}
+			for _, k := range opts.K {
+				res.Recall[k] = RecallAt(docs, c.Relevant, k)
+			}
+			rr := ReciprocalRank(docs, c.Relevant)
+			res.RR = &rr
+		}
+	}
+
+	if c.Answer != "" && opts.Judge != nil {
+		start := time.Now()
+		answer, err := Chat(client, c.Query, opts.Persona)
+		res.ChatMS = time.Since(start).Milliseconds()
+		res.Answer = answer
+		if err != nil {
+			problems = append(problems, "chat: "+err.Error())
+		} else if res.Grade, err = opts.Judge.Grade(c.Query, c.Answer, answer); err != nil {
+			problems = append(problems, "judge: "+err.Error())
+		}
+	}
+
+	res.Error = strings.Join(problems, "; ")
+	return res
+}
+
+// RecallAt is the fraction of relevant documents among the first k
+// retrieved.
+func RecallAt(retrieved, relevant []string, k int) float64 {
+	if len(relevant) == 0 {
+		return 0
+	}
+	want := map[string]bool{}
+	for _, id := range relevant {
+		want[id] = true
+	}
+	found := 0
+	for _, id := range retrieved[:min(k, len(retrieved))] {
+		if want[id] {
+			found++
+			delete(want, id)
+		}
+	}
+	return float64(found) / float64(found+len(want))
+}
+
+// ReciprocalRank is 1/rank of the first relevant retrieved document, 0 if
+// none was retrieved.
+func ReciprocalRank(retrieved, relevant []string) float64 {
+	for i, id := range retrieved {
+		if slices.Contains(relevant, id) {
+			return 1 / float64(i+1)
+		}
+	}
+	return 0
+}
+
+// Summarize averages the metrics of cases.
+func Summarize(cases []CaseResult, k []int) Summary {
+	s := Summary{Recall: map[int]float64{}}
+	for _, c := range cases {
+		if c.Error != "" {
+			s.Errors++
+		}
+		if c.RR != nil {
+			s.Retrieval++
+			s.MRR += *c.RR
+			for _, k := range k {
+				s.Recall[k] += c.Recall[k]
+			}
+		}
+		if c.Grade != nil {
+			s.Graded++
+			s.AnswerScore += c.Grade.Score
+		}
+	}
+	if s.Retrieval > 0 {
+		s.MRR /= float64(s.Retrieval)
+		for k := range s.Recall {
+			s.Recall[k] /= float64(s.Retrieval)
+		}
+	}
+	if s.Graded > 0 {
+		s.AnswerScore /= float64(s.Graded)
+	}
+	return s
+}
+
+// Search returns the document IDs the admin search returns for query, best
+// first, each once.
+func Search(client *api.Client, query string) ([]string, error) {
+	res, err := client.AdminSearch(api.AdminSearchRequest{Query: query})
+	if err != nil {
+		return nil, err
+	}
+	var ids []string
+	for _, d := range res.Documents {
+		if !slices.Contains(ids, d.DocumentID) {
+			ids = append(ids, d.DocumentID)
+		}
+	}
+	return ids, nil
+}
+
+// Chat asks query in a new chat session, returns the streamed answer and
+// deletes the session again.
+func Chat(client *api.Client, query string, persona int) (string, error) {
+	start := time.Now()
+	stream, err := client.StreamChatMessage(api.SendMessageRequest{
+		Message:         query,
+		ChatSessionInfo: &api.ChatSessionCreationRequest{PersonaID: &persona},
+	})
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = stream.Close() }()
+
+	var answer strings.Builder
+	var session string
+	var streamErrors []string
+	readErr := chatstream.Read(stream, start, func(f chatstream.Frame) error {
+		switch {
+		case f.Type == chatstream.TypeSession:
+			session = f.Text
+		case f.Type == chatstream.TypeError:
+			streamErrors = append(streamErrors, f.Text)
+		case f.Type == "message_delta":
+			answer.WriteString(f.Text)
+		}
+		return nil
+	})
+	if session != "" {
+		// Best effort: a leftover session only clutters the user's history.
+		_ = client.DeleteChatSession(session, api.DeleteChatSessionParams{})
+	}
+	switch {
+	case readErr != nil:
+		return answer.String(), fmt.Errorf("stream broke off: %w", readErr)
+	case len(streamErrors) > 0:
+		return answer.String(), fmt.Errorf("stream reported an error: %s", streamErrors[0])
+	case answer.Len() == 0:
+		return "", fmt.Errorf("stream ended without an answer")
+	}
+	return answer.String(), nil
+}
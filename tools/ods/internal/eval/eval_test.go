@@ -0,0 +1,116 @@
+package eval
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMetrics(t *testing.T) {
+	retrieved := []string{"a", "b", "c", "d"}
+	relevant := []string{"c", "x"}
+	if got := RecallAt(retrieved, relevant, 2); got != 0 {
+		t.Errorf("RecallAt(2) = %v, want 0", got)
+	}
+	if got := RecallAt(retrieved, relevant, 10); got != 0.5 {
+		t.Errorf("RecallAt(10) = %v, want 0.5", got)
+	}
+	if got := ReciprocalRank(retrieved, relevant); math.Abs(got-1.0/3) > 1e-9 {
+		t.Errorf("ReciprocalRank() = %v, want 1/3", got)
+	}
+	if got := ReciprocalRank(retrieved, []string{"x"}); got != 0 {
+		t.Errorf("ReciprocalRank() = %v, want 0", got)
+	}
+}
+
+func TestLoadDataset(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "queries.jsonl")
+	data := `# release regression set
+{"query": "How do I reset my password?", "relevant_document_ids": ["doc1"]}
+
+{"id": "sso", "query": "Which SSO providers are supported?", "expected_answer": "Okta and Google"}
+`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	cases, err := LoadDataset(path)
+	if err != nil {
+		t.Fatalf("LoadDataset() error: %v", err)
+	}
+	if len(cases) != 2 || cases[0].ID != "2" || cases[1].ID != "sso" || cases[1].Answer != "Okta and Google" {
+		t.Errorf("unexpected cases %+v", cases)
+	}
+
+	if err := os.WriteFile(path, []byte(`{"query": "no labels"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadDataset(path); err == nil || !strings.Contains(err.Error(), ":1: needs") {
+		t.Errorf("expected a missing-labels error, got %v", err)
+	}
+}
+
+func TestParseGrade(t *testing.T) {
+	g, err := parseGrade("```json\n{\"score\": 0.5, \"reason\": \"Misses Google.\"}\n```")
+	if err != nil || g.Score != 0.5 || g.Reason != "Misses Google." {
+		t.Errorf("parseGrade() = %+v, %v", g, err)
+	}
+	if _, err := parseGrade(`{"score": 7}`); err == nil {
+		t.Error("expected an error for an out-of-range score")
+	}
+	if _, err := parseGrade("Correct."); err == nil {
+		t.Error("expected an error for a reply without JSON")
+	}
+}
+
+func TestCompare(t *testing.T) {
+	rr := func(v float64) *float64 { return &v }
+	baseline := &Report{K: []int{1, 5}, Cases: []CaseResult{
+		{ID: "a", RR: rr(1), Recall: map[int]float64{1: 1, 5: 1}},
+		{ID: "b", RR: rr(0.5), Recall: map[int]float64{1: 0, 5: 1}, Grade: &Grade{Score: 1}},
+		{ID: "gone", RR: rr(1), Recall: map[int]float64{1: 1, 5: 1}},
+	}}
+	baseline.Summary = Summarize(baseline.Cases, baseline.K)
+	current := &Report{K: []int{1, 5, 10}, Cases: []CaseResult{
+		{ID: "a", RR: rr(1), Recall: map[int]float64{1: 1, 5: 1, 10: 1}},
+		{ID: "b", RR: rr(0.2), Recall: map[int]float64{1: 0, 5: 0, 10: 1}, Grade: &Grade{Score: 0.5}},
+		{ID: "new", RR: rr(1), Recall: map[int]float64{1: 1, 5: 1, 10: 1}},
+	}}
+	current.Summary = Summarize(current.Cases, current.K)
+
+	d := Compare(baseline, current)
+	var names []string
+	for _, m := range d.Metrics {
+		names = append(names, m.Name)
+	}
+	if got := strings.Join(names, ","); got != "recall@1,recall@5,MRR,answer score" {
+		t.Errorf("metrics = %s", got)
+	}
+	if len(d.Regressions) != 3 || d.Regressions[1].Metric != "recall@5" || len(d.Improvements) != 0 {
+		t.Errorf("regressions = %+v, improvements = %+v", d.Regressions, d.Improvements)
+	}
+	if d.Added != 1 || d.Removed != 1 {
+		t.Errorf("added %d, removed %d", d.Added, d.Removed)
+	}
+	if drop, name := d.WorstDrop(); name != "answer score" || drop != 0.5 {
+		t.Errorf("WorstDrop() = %v %s", drop, name)
+	}
+}
+
+func TestReportRoundTrip(t *testing.T) {
+	rr := 0.5
+	r := &Report{Env: "staging", K: []int{5}, Cases: []CaseResult{{ID: "a", RR: &rr, Recall: map[int]float64{5: 1}}}}
+	r.Summary = Summarize(r.Cases, r.K)
+	path := filepath.Join(t.TempDir(), "runs", FileName(r.Env, r.Start))
+	if err := r.Save(path); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+	got, err := LoadReport(path)
+	if err != nil {
+		t.Fatalf("LoadReport() error: %v", err)
+	}
+	if got.Summary.Recall[5] != 1 || *got.Cases[0].RR != 0.5 {
+		t.Errorf("unexpected report %+v", got)
+	}
+}
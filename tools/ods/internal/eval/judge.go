@@ -0,0 +1,125 @@
+package eval
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// DefaultJudgeURL is the OpenAI API; any OpenAI-compatible server works.
+const DefaultJudgeURL = "https://api.openai.com/v1"
+
+const judgePrompt = `You grade answers of an enterprise search assistant against a reference answer.
+
+Question: %s
+
+Reference answer: %s
+
+Assistant's answer: %s
+
+Grade whether the assistant's answer conveys the facts of the reference
+answer. Ignore style, length and extra correct detail. Reply with only a JSON
+object: {"score": 1, "reason": "..."} where score is 1 if the answer is
+correct, 0.5 if it is partly correct or incomplete, and 0 if it is wrong, a
+refusal, or missing the key facts. Keep the reason to one sentence.`
+
+// Grade is the judge's verdict on an answer.
+type Grade struct {
+	// Score is 1 (correct), 0.5 (partly correct) or 0 (wrong).
+	Score  float64 `json:"score"`
+	Reason string  `json:"reason"`
+}
+
+// Judge grades answers with an LLM behind an OpenAI-compatible chat
+// completions API.
+type Judge struct {
+	URL    string
+	APIKey string
+	Model  string
+
+	HTTP *http.Client
+}
+
+// NewJudge returns a judge using model at the API under url.
+func NewJudge(url, apiKey, model string) *Judge {
+	return &Judge{
+		URL:    strings.TrimSuffix(url, "/"),
+		APIKey: apiKey,
+		Model:  model,
+		HTTP:   &http.Client{Timeout: 2 * time.Minute},
+	}
+}
+
+// Grade asks the judge whether answer matches the reference answer to
+// question.
+func (j *Judge) Grade(question, reference, answer string) (*Grade, error) {
+	body, err := json.Marshal(map[string]any{
+		"model":       j.Model,
+		"temperature": 0,
+		"messages": []map[string]string{
+			{"role": "user", "content": fmt.Sprintf(judgePrompt, question, reference, answer)},
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequest(http.MethodPost, j.URL+"/chat/completions", bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if j.APIKey != "" {
+		req.Header.Set("Authorization", "Bearer "+j.APIKey)
+	}
+	resp, err := j.HTTP.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("judge request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the judge's response: %w", err)
+	}
+	if resp.StatusCode >= 300 {
+		return nil, fmt.Errorf("judge returned %s: %s", resp.Status, truncate(string(bytes.TrimSpace(data)), 300))
+	}
+	var completion struct {
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+	}
+	if err := json.Unmarshal(data, &completion); err != nil || len(completion.Choices) == 0 {
+		return nil, fmt.Errorf("unexpected response from the judge: %s", truncate(string(data), 300))
+	}
+	return parseGrade(completion.Choices[0].Message.Content)
+}
+
+// parseGrade reads the judge's JSON verdict, tolerating a code fence or
+// text around it.
+func parseGrade(content string) (*Grade, error) {
+	start, end := strings.Index(content, "{"), strings.LastIndex(content, "}")
+	if start < 0 || end < start {
+		return nil, fmt.Errorf("judge did not reply with JSON: %q", truncate(content, 200))
+	}
+	var g Grade
+	if err := json.Unmarshal([]byte(content[start:end+1]), &g); err != nil {
+		return nil, fmt.Errorf("judge did not reply with JSON: %q", truncate(content, 200))
+	}
+	if g.Score != 0 && g.Score != 0.5 && g.Score != 1 {
+		return nil, fmt.Errorf("judge gave score %v, want 0, 0.5 or 1", g.Score)
+	}
+	return &g, nil
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "…"
+}
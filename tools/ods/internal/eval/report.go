@@ -0,0 +1,144 @@
+package eval
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+)
+
+// FileName names the saved report of a run against env, e.g.
+// "staging-20261015-140200.json".
+func FileName(env string, start time.Time) string {
+	return fmt.Sprintf("%s-%s.json", env, start.UTC().Format("20060102-150405"))
+}
+
+// Save writes r as indented JSON to path, creating its directory.
+func (r *Report) Save(path string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0644)
+}
+
+// LoadReport reads a report saved by Save.
+func LoadReport(path string) (*Report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var r Report
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return &r, nil
+}
+
+// MetricDelta is a summary metric in two runs. Higher is better for all of
+// them.
+type MetricDelta struct {
+	Name     string
+	Baseline float64
+	Current  float64
+}
+
+// Delta is Current - Baseline.
+func (m MetricDelta) Delta() float64 {
+	return m.Current - m.Baseline
+}
+
+// CaseChange is a case that scored differently in two runs.
+type CaseChange struct {
+	ID     string
+	Query  string
+	Metric string
+	// Baseline and Current are the case's scores for Metric.
+	Baseline float64
+	Current  float64
+}
+
+// Diff compares a run against a baseline run.
+type Diff struct {
+	Metrics []MetricDelta
+	// Regressions and Improvements are cases present in both runs whose
+	// reciprocal rank, recall at the largest shared K, or answer grade
+	// moved.
+	Regressions  []CaseChange
+	Improvements []CaseChange
+	// Added and Removed count cases only in the current or baseline run.
+	Added, Removed int
+}
+
+// Compare diffs current against baseline. Recall is compared at the K both
+// runs computed.
+func Compare(baseline, current *Report) *Diff {
+	d := &Diff{}
+	var shared []int
+	for _, k := range current.K {
+		if _, ok := baseline.Summary.Recall[k]; ok {
+			shared = append(shared, k)
+		}
+	}
+	for _, k := range shared {
+		d.Metrics = append(d.Metrics, MetricDelta{"recall@" + strconv.Itoa(k), baseline.Summary.Recall[k], current.Summary.Recall[k]})
+	}
+	if baseline.Summary.Retrieval > 0 || current.Summary.Retrieval > 0 {
+		d.Metrics = append(d.Metrics, MetricDelta{"MRR", baseline.Summary.MRR, current.Summary.MRR})
+	}
+	if baseline.Summary.Graded > 0 || current.Summary.Graded > 0 {
+		d.Metrics = append(d.Metrics, MetricDelta{"answer score", baseline.Summary.AnswerScore, current.Summary.AnswerScore})
+	}
+
+	before := map[string]CaseResult{}
+	for _, c := range baseline.Cases {
+		before[c.ID] = c
+	}
+	seen := map[string]bool{}
+	for _, cur := range current.Cases {
+		base, ok := before[cur.ID]
+		if !ok {
+			d.Added++
+			continue
+		}
+		seen[cur.ID] = true
+		var pairs []CaseChange
+		if base.RR != nil && cur.RR != nil {
+			pairs = append(pairs, CaseChange{Metric: "RR", Baseline: *base.RR, Current: *cur.RR})
+		}
+		if len(shared) > 0 && base.Recall != nil && cur.Recall != nil {
+			k := shared[len(shared)-1]
+			pairs = append(pairs, CaseChange{Metric: "recall@" + strconv.Itoa(k), Baseline: base.Recall[k], Current: cur.Recall[k]})
+		}
+		if base.Grade != nil && cur.Grade != nil {
+			pairs = append(pairs, CaseChange{Metric: "answer", Baseline: base.Grade.Score, Current: cur.Grade.Score})
+		}
+		for _, p := range pairs {
+			p.ID, p.Query = cur.ID, cur.Query
+			switch {
+			case p.Current < p.Baseline:
+				d.Regressions = append(d.Regressions, p)
+			case p.Current > p.Baseline:
+				d.Improvements = append(d.Improvements, p)
+			}
+		}
+	}
+	d.Removed = len(baseline.Cases) - len(seen)
+	return d
+}
+
+// WorstDrop is the largest fall of any summary metric, 0 if none fell.
+func (d *Diff) WorstDrop() (float64, string) {
+	worst, name := 0.0, ""
+	for _, m := range d.Metrics {
+		if -m.Delta() > worst {
+			worst, name = -m.Delta(), m.Name
+		}
+	}
+	return worst, name
+}
@@ -0,0 +1,248 @@
+// Package explain diagnoses failed ods runs: known error signatures map to a
+// cause and the commands to run next, and an LLM can be asked about the
+// rest.
+package explain
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/ask"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/history"
+)
+
+// SourceLLM is the Source of a diagnosis made by the LLM.
+const SourceLLM = "llm"
+
+// Diagnosis is a likely cause of a failure and what to run next.
+type Diagnosis struct {
+	Cause string   `json:"cause"`
+	Next  []string `json:"next"`
+	// Source is the name of the signature that matched, or SourceLLM.
+	Source string `json:"source"`
+}
+
+// signature is a known error. In next, {context} is the failed command's
+// cluster context and {command} its command line.
+type signature struct {
+	name    string
+	pattern *regexp.Regexp
+	cause   string
+	next    []string
+}
+
+// signatures are checked against the error and log output of a run, most
+// specific first.
+var signatures = []signature{
+	{
+		name:    "kube-context-unset",
+		pattern: regexp.MustCompile(`Environment variable KUBE_CTX_\w+ is not set`),
+		cause:   "The cluster context is not configured in this shell. Each -c <name> maps to a KUBE_CTX_<NAME> variable holding \"<cluster> <region> <namespace> [<aws-profile> [<role-arn>]]\".",
+		next:    []string{"ods whoami"},
+	},
+	{
+		name:    "aws-sso-expired",
+		pattern: regexp.MustCompile(`(?i)sso session.*expired|token has expired|ExpiredToken|refresh failed|run aws sso login`),
+		cause:   "Your AWS SSO session has expired, so ods cannot get cluster credentials.",
+		next:    []string{"aws sso login", "ods whoami"},
+	},
+	{
+		name:    "kube-unauthorized",
+		pattern: regexp.MustCompile(`(?i)must be logged in to the server|\(Unauthorized\)`),
+		cause:   "The cluster rejected your credentials, usually because the AWS session behind the kubeconfig expired or belongs to the wrong profile.",
+		next:    []string{"aws sso login", "ods whoami"},
+	},
+	{
+		name:    "prod-session",
+		pattern: regexp.MustCompile(`no active production access session|production access session for \S+ expired`),
+		cause:   "The command needs a production access session for this context, and there is none or it has expired.",
+		next:    []string{"ods session status", "ods session start --env {context} --ttl 1h --reason <ticket>"},
+	},
+	{
+		name:    "kube-forbidden",
+		pattern: regexp.MustCompile(`(?i)is forbidden: User`),
+		cause:   "You are authenticated to the cluster, but your role lacks the permission the command needs.",
+		next:    []string{"ods whoami", "ods session start --env {context} --ttl 1h --reason <ticket>"},
+	},
+	{
+		name:    "ticket-required",
+		pattern: regexp.MustCompile(`a --ticket is required`),
+		cause:   "Your ods config requires a ticket for actions on shared environments, and none was passed.",
+		next:    []string{"{command} --ticket <issue>"},
+	},
+	{
+		name:    "docker-daemon",
+		pattern: regexp.MustCompile(`(?i)cannot connect to the docker daemon|docker daemon is not running|error during connect`),
+		cause:   "Docker is not running, or ods cannot reach its daemon.",
+		next:    []string{"ods doctor"},
+	},
+	{
+		name:    "tool-missing",
+		pattern: regexp.MustCompile(`executable file not found in \$PATH`),
+		cause:   "A tool the command drives (docker, kubectl, aws, gh, uv, ...) is not installed or not on PATH.",
+		next:    []string{"ods doctor"},
+	},
+	{
+		name:    "port-in-use",
+		pattern: regexp.MustCompile(`(?i)address already in use`),
+		cause:   "A local port the command needs is taken, often by a port-forward left behind by an earlier ods run.",
+		next:    []string{"ods port-forwards", "ods port-forwards --clean"},
+	},
+	{
+		name:    "no-ready-pod",
+		pattern: regexp.MustCompile(`no ready pod found matching`),
+		cause:   "No pod of the service the command runs in is ready: it may be crash looping, being rescheduled or scaled to zero.",
+		next:    []string{"ods events -c {context}", "ods health -c {context}"},
+	},
+	{
+		name:    "local-connection-refused",
+		pattern: regexp.MustCompile(`(?i)(localhost|127\.0\.0\.1):\d+.*connection refused`),
+		cause:   "Nothing is listening on the local port: the compose stack is down or the port-forward to the cluster died.",
+		next:    []string{"ods health", "ods port-forwards"},
+	},
+	{
+		name:    "migrations",
+		pattern: regexp.MustCompile(`(?i)can't locate revision|multiple head revisions|target database is not up to date`),
+		cause:   "The database schema and the code disagree about migrations.",
+		next:    []string{"ods migrate status -c {context}"},
+	},
+	{
+		name:    "disk-full",
+		pattern: regexp.MustCompile(`(?i)no space left on device`),
+		cause:   "The disk is full, usually with Docker images and volumes.",
+		next:    []string{"docker system df", "docker system prune"},
+	},
+	{
+		name:    "dns",
+		pattern: regexp.MustCompile(`(?i)no such host`),
+		cause:   "A host name did not resolve, which usually means the VPN is down or the host is mistyped.",
+		next:    []string{"ods whoami"},
+	},
+	{
+		name:    "timeout",
+		pattern: regexp.MustCompile(`(?i)context deadline exceeded|i/o timeout|timed out`),
+		cause:   "A request timed out: the VPN may be down, or the service is overloaded or unhealthy.",
+		next:    []string{"ods whoami", "ods health -c {context}"},
+	},
+}
+
+// Heuristics returns the diagnoses of the known signatures in e's error and
+// output.
+func Heuristics(e *history.Entry) []Diagnosis {
+	text := e.Error + "\n" + e.Output
+	var out []Diagnosis
+	for _, s := range signatures {
+		if !s.pattern.MatchString(text) {
+			continue
+		}
+		d := Diagnosis{Cause: s.cause, Source: s.name}
+		for _, n := range s.next {
+			d.Next = append(d.Next, fill(n, e))
+		}
+		out = append(out, d)
+	}
+	return out
+}
+
+// fill substitutes {command} and {context} in a suggested command, dropping
+// "-c {context}" when the failed command named no context.
+func fill(next string, e *history.Entry) string {
+	next = strings.ReplaceAll(next, "{command}", e.Command())
+	ctx := contextOf(e.Args)
+	if ctx == "" {
+		next = strings.ReplaceAll(next, " -c {context}", "")
+		ctx = "<context>"
+	}
+	return strings.ReplaceAll(next, "{context}", ctx)
+}
+
+// contextOf returns the -c/--context argument of a command, or "".
+func contextOf(args []string) string {
+	for i, a := range args {
+		switch {
+		case (a == "-c" || a == "--context" || a == "--env") && i+1 < len(args):
+			return args[i+1]
+		case strings.HasPrefix(a, "--context="):
+			return strings.TrimPrefix(a, "--context=")
+		case strings.HasPrefix(a, "-c="):
+			return strings.TrimPrefix(a, "-c=")
+		}
+	}
+	return ""
+}
+
+// Env is what else is known about where the command ran.
+type Env struct {
+	KubeContext string
+	OS          string
+}
+
+const llmPrompt = `An ods command failed. ods is the developer and operations CLI of Onyx, an
+open-source enterprise search and chat product deployed with Docker Compose
+locally and on Kubernetes (EKS) in the cloud.
+
+Command: %s
+Directory: %s
+ods version: %s, OS: %s, kubectl context: %s
+
+Error: %s
+
+End of its log output:
+%s
+%s
+Diagnose the most likely cause and what to run next. Reply with only a JSON
+object: {"cause": "...", "next": ["..."]} where cause is at most three
+sentences and next is up to three commands, preferring ods commands such as
+ods doctor, ods whoami, ods health, ods events, ods port-forwards --clean or
+a corrected form of the failed command.`
+
+// Prompt returns the LLM prompt for e, including any signatures that
+// matched.
+func Prompt(e *history.Entry, env Env, known []Diagnosis) string {
+	var hints string
+	if len(known) > 0 {
+		var b strings.Builder
+		b.WriteString("\nKnown error signatures matched:\n")
+		for _, d := range known {
+			fmt.Fprintf(&b, "- %s: %s\n", d.Source, d.Cause)
+		}
+		hints = b.String()
+	}
+	output := strings.TrimSpace(e.Output)
+	if output == "" {
+		output = "(none)"
+	}
+	return fmt.Sprintf(llmPrompt, e.Command(), orNone(e.Dir), orNone(e.Version), orNone(env.OS),
+		orNone(env.KubeContext), orNone(e.Error), output, hints)
+}
+
+// WithLLM asks chat to diagnose e.
+func WithLLM(chat func([]ask.Message) (string, error), e *history.Entry, env Env, known []Diagnosis) (*Diagnosis, error) {
+	reply, err := chat([]ask.Message{{Role: "user", Content: Prompt(e, env, known)}})
+	if err != nil {
+		return nil, err
+	}
+	start, end := strings.Index(reply, "{"), strings.LastIndex(reply, "}")
+	var d Diagnosis
+	if start < 0 || end < start || json.Unmarshal([]byte(reply[start:end+1]), &d) != nil || d.Cause == "" {
+		return nil, fmt.Errorf("LLM did not reply with a diagnosis: %q", truncate(reply, 200))
+	}
+	d.Source = SourceLLM
+	return &d, nil
+}
+
+func orNone(s string) string {
+	if s == "" {
+		return "(unknown)"
+	}
+	return s
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	return s[:n] + "…"
+}
@@ -0,0 +1,77 @@
+package explain
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/ask"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/history"
+)
+
+func TestHeuristics(t *testing.T) {
+	tests := []struct {
+		args   []string
+		error  string
+		source string
+		next   []string
+	}{
+		{[]string{"whois", "jane@acme.com", "-c", "prod"},
+			"Environment variable KUBE_CTX_PROD is not set.",
+			"kube-context-unset", []string{"ods whoami"}},
+		{[]string{"health", "-c", "prod"},
+			"Failed to ensure cluster context: aws eks update-kubeconfig failed: exit status 255\nError when retrieving token from sso: Token has expired and refresh failed",
+			"aws-sso-expired", []string{"aws sso login", "ods whoami"}},
+		{[]string{"usage", "tokens", "--context=staging"},
+			"Failed to find api-server pod: no ready pod found matching \"api-server\"",
+			"no-ready-pod", []string{"ods events -c staging", "ods health -c staging"}},
+		{[]string{"scale", "api-server", "3"},
+			"Refusing to scale without an audit record: a --ticket is required for actions on shared environments",
+			"ticket-required", []string{"ods scale api-server 3 --ticket <issue>"}},
+		{[]string{"curl", "/health"},
+			"Get \"http://127.0.0.1:8080/health\": dial tcp 127.0.0.1:8080: connect: connection refused",
+			"local-connection-refused", []string{"ods health", "ods port-forwards"}},
+		{[]string{"migrate", "status"},
+			"alembic failed: Can't locate revision identified by 'abc123'",
+			"migrations", []string{"ods migrate status"}},
+	}
+	for _, tt := range tests {
+		e := &history.Entry{Args: tt.args, Error: tt.error}
+		got := Heuristics(e)
+		if len(got) == 0 {
+			t.Errorf("%s: no diagnosis", tt.error)
+			continue
+		}
+		if got[0].Source != tt.source || !reflect.DeepEqual(got[0].Next, tt.next) {
+			t.Errorf("%s: got %s %q, want %s %q", tt.error, got[0].Source, got[0].Next, tt.source, tt.next)
+		}
+	}
+
+	if got := Heuristics(&history.Entry{Error: "something unheard of"}); len(got) != 0 {
+		t.Errorf("expected no diagnosis, got %+v", got)
+	}
+}
+
+func TestWithLLM(t *testing.T) {
+	e := &history.Entry{Args: []string{"reindex", "all", "-c", "prod"}, Error: "celery task failed: KeyError 'search_settings'"}
+	var prompt string
+	d, err := WithLLM(func(messages []ask.Message) (string, error) {
+		prompt = messages[0].Content
+		return "```json\n{\"cause\": \"No search settings exist.\", \"next\": [\"ods migrate status -c prod\"]}\n```", nil
+	}, e, Env{KubeContext: "prod", OS: "linux"}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if d.Source != SourceLLM || d.Cause != "No search settings exist." || len(d.Next) != 1 {
+		t.Errorf("unexpected diagnosis %+v", d)
+	}
+	for _, s := range []string{"Command: ods reindex all -c prod", "KeyError 'search_settings'", "kubectl context: prod"} {
+		if !strings.Contains(prompt, s) {
+			t.Errorf("prompt lacks %q:\n%s", s, prompt)
+		}
+	}
+
+	if _, err := WithLLM(func([]ask.Message) (string, error) { return "no idea", nil }, e, Env{}, nil); err == nil {
+		t.Error("expected a reply without JSON to fail")
+	}
+}
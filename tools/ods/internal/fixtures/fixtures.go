@@ -0,0 +1,261 @@
+// Package fixtures generates synthetic documents for load and relevance
+// testing. Generation is deterministic: document i of a seed is the same on
+// every run and independent of the others, so a run can be resumed, split
+// across processes or repeated to upsert the same documents.
+package fixtures
+
+import (
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"strings"
+	"time"
+)
+
+// SizeDists are the supported document size distributions.
+var SizeDists = []string{"lognormal", "uniform", "fixed"}
+
+// Options configures a Generator.
+type Options struct {
+	Seed uint64
+	// SizeDist is one of SizeDists. Lognormal gives the long tail of real
+	// corpora: most documents short, a few very long.
+	SizeDist string
+	// MeanWords is the mean document length in words.
+	MeanWords int
+	// Now anchors document timestamps, which spread over the preceding year.
+	Now time.Time
+}
+
+// Section is a section of a document, as the ingestion API takes it.
+type Section struct {
+	Text string `json:"text"`
+	Link string `json:"link,omitempty"`
+}
+
+// Owner is a document owner, as the ingestion API takes it.
+type Owner struct {
+	DisplayName string `json:"display_name"`
+	Email       string `json:"email"`
+}
+
+// Document is a synthetic document in the shape of the ingestion API's
+// DocumentBase.
+type Document struct {
+	ID                 string            `json:"id"`
+	SemanticIdentifier string            `json:"semantic_identifier"`
+	Title              string            `json:"title"`
+	Source             string            `json:"source"`
+	Sections           []Section         `json:"sections"`
+	Metadata           map[string]any    `json:"metadata"`
+	PrimaryOwners      []Owner           `json:"primary_owners"`
+	DocUpdatedAt       time.Time         `json:"doc_updated_at"`
+	DocCreatedAt       time.Time         `json:"doc_created_at"`
+	AdditionalInfo     map[string]string `json:"additional_info,omitempty"`
+}
+
+// Words counts the words in the document's sections.
+func (d Document) Words() int {
+	n := 0
+	for _, s := range d.Sections {
+		n += len(strings.Fields(s.Text))
+	}
+	return n
+}
+
+// Generator produces synthetic documents.
+type Generator struct {
+	opts Options
+}
+
+// NewGenerator returns a Generator, validating opts.
+func NewGenerator(opts Options) (*Generator, error) {
+	valid := false
+	for _, d := range SizeDists {
+		valid = valid || d == opts.SizeDist
+	}
+	if !valid {
+		return nil, fmt.Errorf("unknown size distribution %q (must be one of %s)", opts.SizeDist, strings.Join(SizeDists, ", "))
+	}
+	if opts.MeanWords < 1 {
+		return nil, fmt.Errorf("mean words must be positive")
+	}
+	if opts.Now.IsZero() {
+		opts.Now = time.Now()
+	}
+	return &Generator{opts: opts}, nil
+}
+
+// ID returns the document ID of document i, stable for a seed so reruns
+// upsert rather than duplicate.
+func (g *Generator) ID(i int) string {
+	return fmt.Sprintf("ods-fixture-%d-%d", g.opts.Seed, i)
+}
+
+// Doc generates document i.
+func (g *Generator) Doc(i int) Document {
+	r := rand.New(rand.NewPCG(g.opts.Seed, uint64(i)))
+	t := topics[r.IntN(len(topics))]
+	kind := kinds[r.IntN(len(kinds))]
+	team := teams[r.IntN(len(teams))]
+	owner := people[r.IntN(len(people))]
+	// A name unique to the document, so relevance tests can ask about a
+	// specific one and know which document should answer.
+	codename := fmt.Sprintf("%s-%s-%d", pick(r, codenameAdjectives), pick(r, codenameNouns), i)
+
+	title := fmt.Sprintf("%s: %s %s", kind.name, strings.ToUpper(t.name[:1])+t.name[1:], codename)
+	link := fmt.Sprintf("https://fixtures.onyx.local/%s/%s", kind.slug, g.ID(i))
+
+	words := g.size(r)
+	var sections []Section
+	sections = append(sections, Section{
+		Text: fmt.Sprintf("%s. Owned by %s (%s). Project %s covers %s for the %s team.",
+			title, owner.DisplayName, team, codename, t.name, team),
+		Link: link,
+	})
+	remaining := words - len(strings.Fields(sections[0].Text))
+	for s := 1; remaining > 0; s++ {
+		heading := fmt.Sprintf("%d. %s", s, headings[r.IntN(len(headings))])
+		n := max(1, min(remaining-len(strings.Fields(heading)), 80+r.IntN(320)))
+		sections = append(sections, Section{
+			Text: heading + "\n" + paragraph(r, t, n),
+			Link: fmt.Sprintf("%s#section-%d", link, s),
+		})
+		remaining -= n + len(strings.Fields(heading))
+	}
+
+	created := g.opts.Now.Add(-time.Duration(r.Int64N(int64(365 * 24 * time.Hour)))).UTC().Truncate(time.Second)
+	updated := created.Add(time.Duration(r.Int64N(int64(g.opts.Now.Sub(created)) + 1))).UTC().Truncate(time.Second)
+
+	tags := []string{t.name, kind.slug}
+	if r.IntN(3) == 0 {
+		tags = append(tags, pick(r, t.words))
+	}
+	return Document{
+		ID:                 g.ID(i),
+		SemanticIdentifier: title,
+		Title:              title,
+		Source:             "ingestion_api",
+		Sections:           sections,
+		Metadata: map[string]any{
+			"team":     team,
+			"type":     kind.slug,
+			"tags":     tags,
+			"fixture":  "true",
+			"codename": codename,
+		},
+		PrimaryOwners:  []Owner{owner},
+		DocUpdatedAt:   updated,
+		DocCreatedAt:   created,
+		AdditionalInfo: map[string]string{"ods_fixture_seed": fmt.Sprint(g.opts.Seed)},
+	}
+}
+
+// size draws a document length in words from the configured distribution.
+func (g *Generator) size(r *rand.Rand) int {
+	mean := float64(g.opts.MeanWords)
+	var n float64
+	switch g.opts.SizeDist {
+	case "lognormal":
+		// sigma 1 gives a median of ~0.6x the mean and a 99th percentile of
+		// ~6x; mu is set so the mean comes out at MeanWords.
+		const sigma = 1.0
+		mu := math.Log(mean) - sigma*sigma/2
+		n = math.Exp(mu + sigma*r.NormFloat64())
+	case "uniform":
+		n = mean * (0.5 + r.Float64())
+	default:
+		n = mean
+	}
+	return int(math.Max(20, math.Min(n, mean*50)))
+}
+
+// paragraph returns n words of sentences about topic t.
+func paragraph(r *rand.Rand, t topic, n int) string {
+	var b strings.Builder
+	for written := 0; written < n; {
+		length := min(n-written, 8+r.IntN(13))
+		for w := 0; w < length; w++ {
+			var word string
+			if r.IntN(3) == 0 {
+				word = pick(r, t.words)
+			} else {
+				word = pick(r, filler)
+			}
+			if w == 0 {
+				word = strings.ToUpper(word[:1]) + word[1:]
+				if written > 0 {
+					b.WriteByte(' ')
+				}
+			} else {
+				b.WriteByte(' ')
+			}
+			b.WriteString(word)
+		}
+		b.WriteByte('.')
+		written += length
+	}
+	return b.String()
+}
+
+func pick(r *rand.Rand, words []string) string {
+	return words[r.IntN(len(words))]
+}
+
+type topic struct {
+	name  string
+	words []string
+}
+
+type kind struct {
+	name string
+	slug string
+}
+
+var topics = []topic{
+	{"billing", []string{"invoice", "subscription", "refund", "seat", "proration", "stripe", "renewal", "discount", "plan", "overage"}},
+	{"onboarding", []string{"checklist", "laptop", "orientation", "buddy", "benefits", "payroll", "badge", "training", "handbook", "equipment"}},
+	{"incident response", []string{"outage", "pager", "severity", "postmortem", "mitigation", "rollback", "escalation", "timeline", "statuspage", "rca"}},
+	{"security", []string{"encryption", "audit", "vulnerability", "sso", "mfa", "pentest", "offboarding", "secrets", "compliance", "soc2"}},
+	{"infrastructure", []string{"kubernetes", "terraform", "autoscaling", "latency", "ingress", "postgres", "redis", "replica", "nodepool", "capacity"}},
+	{"sales", []string{"pipeline", "quota", "prospect", "demo", "contract", "procurement", "champion", "forecast", "territory", "renewal"}},
+	{"product roadmap", []string{"milestone", "launch", "beta", "feedback", "prioritization", "spec", "metric", "experiment", "release"}},
+	{"vacation policy", []string{"pto", "holiday", "accrual", "approval", "carryover", "leave", "calendar", "coverage", "blackout", "manager"}},
+	{"data pipeline", []string{"ingestion", "schema", "warehouse", "backfill", "airflow", "partition", "dedupe", "freshness", "lineage", "etl"}},
+	{"customer support", []string{"ticket", "sla", "macro", "escalation", "csat", "queue", "triage", "helpcenter", "refund", "chat"}},
+}
+
+var kinds = []kind{
+	{"Wiki", "wiki"},
+	{"Meeting notes", "meeting-notes"},
+	{"Runbook", "runbook"},
+	{"Design doc", "design-doc"},
+	{"Ticket", "ticket"},
+	{"FAQ", "faq"},
+}
+
+var teams = []string{"platform", "growth", "finance", "people", "security", "support", "sales", "data"}
+
+var people = []Owner{
+	{"Ada Park", "ada.park@example.com"},
+	{"Bo Nguyen", "bo.nguyen@example.com"},
+	{"Chidi Okafor", "chidi.okafor@example.com"},
+	{"Dana Levi", "dana.levi@example.com"},
+	{"Elif Demir", "elif.demir@example.com"},
+	{"Femi Adeyemi", "femi.adeyemi@example.com"},
+	{"Grace Lin", "grace.lin@example.com"},
+	{"Hugo Martin", "hugo.martin@example.com"},
+}
+
+var headings = []string{"Background", "Goals", "Details", "Open questions", "Decisions", "Next steps", "Owners", "Timeline", "Risks", "Appendix"}
+
+var codenameAdjectives = []string{"amber", "brisk", "cobalt", "dusty", "ember", "frosty", "golden", "hidden", "ivory", "jade", "lunar", "misty"}
+
+var codenameNouns = []string{"falcon", "harbor", "lantern", "meadow", "orchid", "pebble", "quartz", "river", "summit", "tundra", "willow", "zephyr"}
+
+var filler = []string{
+	"the", "team", "will", "should", "review", "update", "process", "before", "after", "each",
+	"quarter", "week", "customer", "request", "owner", "document", "we", "agreed", "to", "track",
+	"changes", "in", "this", "plan", "and", "confirm", "with", "stakeholders", "when", "needed",
+	"it", "is", "important", "that", "all", "steps", "are", "followed", "for", "consistency",
+}
@@ -0,0 +1,78 @@
+package fixtures
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+var testNow = time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func newTestGenerator(t *testing.T, seed uint64, dist string) *Generator {
+	t.Helper()
+	g, err := NewGenerator(Options{Seed: seed, SizeDist: dist, MeanWords: 400, Now: testNow})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return g
+}
+
+func TestDocIsDeterministic(t *testing.T) {
+	a, _ := json.Marshal(newTestGenerator(t, 7, "lognormal").Doc(42))
+	b, _ := json.Marshal(newTestGenerator(t, 7, "lognormal").Doc(42))
+	if string(a) != string(b) {
+		t.Error("the same seed and index produced different documents")
+	}
+	c, _ := json.Marshal(newTestGenerator(t, 8, "lognormal").Doc(42))
+	if string(a) == string(c) {
+		t.Error("different seeds produced the same document")
+	}
+}
+
+func TestDocShape(t *testing.T) {
+	g := newTestGenerator(t, 1, "fixed")
+	doc := g.Doc(3)
+	if doc.ID != "ods-fixture-1-3" || doc.ID != g.ID(3) {
+		t.Errorf("ID = %q", doc.ID)
+	}
+	if len(doc.Sections) < 2 || doc.Sections[0].Link == "" {
+		t.Errorf("unexpected sections %+v", doc.Sections)
+	}
+	if words := doc.Words(); words < 390 || words > 410 {
+		t.Errorf("fixed size gave %d words, want ~400", words)
+	}
+	if doc.DocCreatedAt.After(doc.DocUpdatedAt) || doc.DocUpdatedAt.After(testNow) {
+		t.Errorf("created %s, updated %s", doc.DocCreatedAt, doc.DocUpdatedAt)
+	}
+}
+
+func TestSizeDistributions(t *testing.T) {
+	for _, dist := range SizeDists {
+		g := newTestGenerator(t, 99, dist)
+		total, smallest, largest := 0, 1<<30, 0
+		const n = 2000
+		for i := range n {
+			w := g.Doc(i).Words()
+			total += w
+			smallest, largest = min(smallest, w), max(largest, w)
+		}
+		if mean := total / n; mean < 340 || mean > 460 {
+			t.Errorf("%s: mean %d words, want ~400", dist, mean)
+		}
+		if dist == "lognormal" && largest < 4*400 {
+			t.Errorf("lognormal: largest document %d words, want a long tail", largest)
+		}
+		if dist == "uniform" && (smallest < 190 || largest > 610) {
+			t.Errorf("uniform: sizes %d..%d outside [200, 600]", smallest, largest)
+		}
+	}
+}
+
+func TestNewGeneratorValidates(t *testing.T) {
+	if _, err := NewGenerator(Options{SizeDist: "pareto", MeanWords: 10}); err == nil {
+		t.Error("expected an error for an unknown distribution")
+	}
+	if _, err := NewGenerator(Options{SizeDist: "fixed"}); err == nil {
+		t.Error("expected an error for zero mean words")
+	}
+}
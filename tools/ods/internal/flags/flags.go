@@ -0,0 +1,110 @@
+// Package flags lists and toggles a tenant's boolean workspace settings,
+// which the backend uses as feature flags.
+package flags
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/kube"
+)
+
+//go:embed flags.py
+var flagsScript string
+
+// Flag is a boolean setting and where its value comes from: "stored" in the
+// tenant's settings, the model "default", or overridden by the deployment's
+// "env".
+type Flag struct {
+	Name   string `json:"name"`
+	Value  *bool  `json:"value"`
+	Source string `json:"source"`
+}
+
+// Bulk is the outcome of setting a flag on every tenant.
+type Bulk struct {
+	Changed   int               `json:"changed"`
+	Unchanged int               `json:"unchanged"`
+	Failed    map[string]string `json:"failed"`
+}
+
+// ParseValue reads an on/off flag value.
+func ParseValue(s string) (bool, error) {
+	switch strings.ToLower(s) {
+	case "on", "true", "yes", "1", "enable", "enabled":
+		return true, nil
+	case "off", "false", "no", "0", "disable", "disabled":
+		return false, nil
+	}
+	return false, fmt.Errorf("invalid flag value %q (expected on or off)", s)
+}
+
+// FormatValue renders a flag value as on, off or unset.
+func FormatValue(v *bool) string {
+	switch {
+	case v == nil:
+		return "unset"
+	case *v:
+		return "on"
+	default:
+		return "off"
+	}
+}
+
+// List returns the flags of schema ("" for the default schema of a
+// single-tenant deployment).
+func List(c *kube.Cluster, pod, schema string) ([]Flag, error) {
+	var r struct {
+		Flags []Flag `json:"flags"`
+	}
+	if err := run(c, pod, &r, "list", schema); err != nil {
+		return nil, err
+	}
+	return r.Flags, nil
+}
+
+// Set sets a flag in schema and returns its previous stored value.
+func Set(c *kube.Cluster, pod, schema, name string, value bool) (*bool, error) {
+	var r struct {
+		Previous *bool `json:"previous"`
+	}
+	if err := run(c, pod, &r, "set", schema, name, fmt.Sprint(value)); err != nil {
+		return nil, err
+	}
+	return r.Previous, nil
+}
+
+// SetAll sets a flag in every tenant schema.
+func SetAll(c *kube.Cluster, pod, name string, value bool) (*Bulk, error) {
+	var r Bulk
+	if err := run(c, pod, &r, "set-all", name, fmt.Sprint(value)); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+func run(c *kube.Cluster, pod string, out any, args ...string) error {
+	stdout, err := c.RunPython(pod, flagsScript, args...)
+	if err != nil {
+		return err
+	}
+	return parseResult(stdout, out)
+}
+
+func parseResult(stdout string, out any) error {
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	last := strings.TrimSpace(lines[len(lines)-1])
+	var r struct {
+		Status  string `json:"status"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal([]byte(last), &r); err != nil {
+		return fmt.Errorf("unexpected output from flags script: %q", last)
+	}
+	if r.Status != "success" {
+		return fmt.Errorf("%s", r.Message)
+	}
+	return json.Unmarshal([]byte(last), out)
+}
@@ -0,0 +1,158 @@
+"""List or toggle the boolean workspace settings of a tenant.
+
+Bundled with ods and piped into `python -` on an api-server pod by
+`ods flags`. Flags are the boolean fields of the backend's Settings model,
+stored per schema in the key-value store under "onyx_settings". Writes go
+through store_settings, like the admin settings page, so the Redis copy is
+updated along with Postgres.
+
+Usage:
+    python - list <schema>
+    python - set <schema> <name> <true|false>
+    python - set-all <name> <true|false>    # every tenant schema
+
+An empty <schema> means the default schema of a single-tenant deployment.
+
+Progress goes to stderr; the last line on stdout is a JSON object with
+"status" and "flags" (list), "previous" (set) or "changed"/"unchanged"/
+"failed" (set-all).
+"""
+
+from __future__ import annotations
+
+import json
+import sys
+from typing import Any
+
+
+def bool_fields() -> list[str]:
+    from onyx.server.settings.models import Settings
+
+    return sorted(
+        name
+        for name, field in Settings.model_fields.items()
+        if field.annotation in (bool, bool | None)
+    )
+
+
+def stored_settings() -> dict[str, Any]:
+    from onyx.configs.constants import KV_SETTINGS_KEY
+    from onyx.key_value_store.factory import get_kv_store
+    from onyx.key_value_store.interface import KvKeyNotFoundError
+
+    try:
+        stored = get_kv_store().load(KV_SETTINGS_KEY)
+    except KvKeyNotFoundError:
+        return {}
+    return dict(stored) if isinstance(stored, dict) else {}
+
+
+def use_schema(schema: str) -> str:
+    from onyx.db.engine.tenant_utils import validate_tenant_id
+    from shared_configs.configs import MULTI_TENANT
+    from shared_configs.configs import POSTGRES_DEFAULT_SCHEMA
+    from shared_configs.contextvars import CURRENT_TENANT_ID_CONTEXTVAR
+
+    if not schema:
+        if MULTI_TENANT:
+            raise ValueError("This deployment is multi-tenant; pass --tenant")
+        schema = POSTGRES_DEFAULT_SCHEMA
+    elif schema != POSTGRES_DEFAULT_SCHEMA and not validate_tenant_id(schema):
+        raise ValueError(f"Invalid schema {schema!r}")
+    CURRENT_TENANT_ID_CONTEXTVAR.set(schema)
+    return schema
+
+
+def list_flags(schema: str) -> dict[str, Any]:
+    from onyx.server.settings.models import Settings
+    from onyx.server.settings.store import load_settings
+
+    use_schema(schema)
+    stored = stored_settings()
+    configured = Settings.model_validate(stored)
+    effective = load_settings()
+    flags = []
+    for name in bool_fields():
+        value = getattr(effective, name)
+        if value != getattr(configured, name):
+            source = "env"
+        elif name in stored:
+            source = "stored"
+        else:
+            source = "default"
+        flags.append({"name": name, "value": value, "source": source})
+    return {"status": "success", "flags": flags}
+
+
+def set_flag(schema: str, name: str, value: bool) -> dict[str, Any]:
+    from onyx.server.settings.models import Settings
+    from onyx.server.settings.store import store_settings
+
+    if name not in bool_fields():
+        raise ValueError(f"Unknown flag {name!r}; see `ods flags list`")
+    use_schema(schema)
+    settings = Settings.model_validate(stored_settings())
+    previous = getattr(settings, name)
+    if previous != value:
+        setattr(settings, name, value)
+        store_settings(settings)
+    return {"status": "success", "previous": previous}
+
+
+def set_all(name: str, value: bool) -> dict[str, Any]:
+    from onyx.db.engine.tenant_utils import get_all_tenant_ids
+    from shared_configs.configs import MULTI_TENANT
+    from shared_configs.configs import TENANT_ID_PREFIX
+
+    if not MULTI_TENANT:
+        raise ValueError("This deployment is not multi-tenant; drop --all-tenants")
+    if name not in bool_fields():
+        raise ValueError(f"Unknown flag {name!r}; see `ods flags list`")
+
+    tenants = [t for t in get_all_tenant_ids() if t.startswith(TENANT_ID_PREFIX)]
+    changed, unchanged, failed = 0, 0, {}
+    for i, tenant_id in enumerate(tenants, 1):
+        if i % 100 == 0:
+            print(f"{i}/{len(tenants)} tenants...", file=sys.stderr)
+        try:
+            if set_flag(tenant_id, name, value)["previous"] == value:
+                unchanged += 1
+            else:
+                changed += 1
+        except Exception as e:
+            failed[tenant_id] = str(e)
+    return {
+        "status": "success",
+        "changed": changed,
+        "unchanged": unchanged,
+        "failed": failed,
+    }
+
+
+def main() -> None:
+    usage = "Usage: python - list <schema> | set <schema> <name> <value> | set-all <name> <value>"
+    args = sys.argv[1:]
+    arity = {"list": 2, "set": 4, "set-all": 3}
+    if not args or arity.get(args[0]) != len(args):
+        print(json.dumps({"status": "error", "message": usage}))
+        sys.exit(1)
+
+    from onyx.db.engine.sql_engine import SqlEngine
+
+    SqlEngine.init_engine(pool_size=5, max_overflow=2)
+
+    try:
+        if args[0] == "list":
+            result = list_flags(args[1])
+        elif args[0] == "set":
+            result = set_flag(args[1], args[2], args[3] == "true")
+        else:
+            result = set_all(args[1], args[2] == "true")
+    except Exception as e:
+        print(f"Error: {e}", file=sys.stderr)
+        result = {"status": "error", "message": str(e)}
+    print(json.dumps(result))
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,59 @@
+package flags
+
+import "testing"
+
+func TestParseValue(t *testing.T) {
+	for _, s := range []string{"on", "TRUE", "yes", "1", "enabled"} {
+		if v, err := ParseValue(s); err != nil || !v {
+			t.Errorf("ParseValue(%q) = %v, %v; want true", s, v, err)
+		}
+	}
+	for _, s := range []string{"off", "false", "No", "0", "disable"} {
+		if v, err := ParseValue(s); err != nil || v {
+			t.Errorf("ParseValue(%q) = %v, %v; want false", s, v, err)
+		}
+	}
+	if _, err := ParseValue("maybe"); err == nil {
+		t.Error("expected an error for an unknown value")
+	}
+}
+
+func TestFormatValue(t *testing.T) {
+	on, off := true, false
+	for _, tt := range []struct {
+		v    *bool
+		want string
+	}{{&on, "on"}, {&off, "off"}, {nil, "unset"}} {
+		if got := FormatValue(tt.v); got != tt.want {
+			t.Errorf("FormatValue(%v) = %q, want %q", tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestParseResult(t *testing.T) {
+	var list struct {
+		Flags []Flag `json:"flags"`
+	}
+	out := "Connecting...\n" + `{"status": "success", "flags": [{"name": "auto_scroll", "value": true, "source": "stored"}, {"name": "gpu_enabled", "value": null, "source": "default"}]}`
+	if err := parseResult(out, &list); err != nil {
+		t.Fatalf("parseResult() error: %v", err)
+	}
+	if len(list.Flags) != 2 || list.Flags[0].Name != "auto_scroll" || !*list.Flags[0].Value || list.Flags[1].Value != nil {
+		t.Errorf("unexpected flags %+v", list.Flags)
+	}
+
+	var bulk Bulk
+	if err := parseResult(`{"status": "success", "changed": 3, "unchanged": 1, "failed": {"tenant_x": "boom"}}`, &bulk); err != nil {
+		t.Fatalf("parseResult() error: %v", err)
+	}
+	if bulk.Changed != 3 || bulk.Unchanged != 1 || bulk.Failed["tenant_x"] != "boom" {
+		t.Errorf("unexpected bulk result %+v", bulk)
+	}
+
+	if err := parseResult(`{"status": "error", "message": "Unknown flag 'x'"}`, &bulk); err == nil || err.Error() != "Unknown flag 'x'" {
+		t.Errorf("expected the script's error, got %v", err)
+	}
+	if err := parseResult("Traceback", &bulk); err == nil {
+		t.Error("expected an error for unexpected output")
+	}
+}
@@ -0,0 +1,257 @@
+"""Collect or purge one user's personal data for a data subject request.
+
+Bundled with ods and piped into `python -` on an api-server pod by
+`ods gdpr`, so it runs against the tenant's real database, document index and
+file store using the backend's own models and deletion paths.
+
+Usage:
+    python - export <email> <tenant_id>
+    python - delete <email> <tenant_id>
+
+Progress goes to stderr; the last line on stdout is a JSON object with
+"status" ("success", "not_found" or "error") and, on success, "counts" and
+(for export) "data".
+"""
+
+from __future__ import annotations
+
+import json
+import sys
+from datetime import datetime
+from enum import Enum
+from typing import Any
+from uuid import UUID
+
+
+def _jsonable(value: Any) -> Any:
+    if isinstance(value, datetime):
+        return value.isoformat()
+    if isinstance(value, UUID):
+        return str(value)
+    if isinstance(value, Enum):
+        return value.value
+    return value
+
+
+def _row(obj: Any, fields: list[str]) -> dict[str, Any]:
+    return {f: _jsonable(getattr(obj, f, None)) for f in fields}
+
+
+def collect(db_session: Any, user: Any) -> tuple[dict[str, Any], dict[str, int]]:
+    from sqlalchemy import select
+
+    from onyx.db.models import ChatMessage
+    from onyx.db.models import ChatMessageFeedback
+    from onyx.db.models import ChatSession
+    from onyx.db.models import DocumentRetrievalFeedback
+    from onyx.db.models import Memory
+    from onyx.db.models import SearchQuery
+    from onyx.db.models import UserFile
+
+    sessions = db_session.scalars(
+        select(ChatSession).where(ChatSession.user_id == user.id)
+    ).all()
+    session_ids = [s.id for s in sessions]
+    messages = (
+        db_session.scalars(
+            select(ChatMessage)
+            .where(ChatMessage.chat_session_id.in_(session_ids))
+            .order_by(ChatMessage.time_sent)
+        ).all()
+        if session_ids
+        else []
+    )
+    message_ids = [m.id for m in messages]
+    chat_feedback = (
+        db_session.scalars(
+            select(ChatMessageFeedback).where(
+                ChatMessageFeedback.chat_message_id.in_(message_ids)
+            )
+        ).all()
+        if message_ids
+        else []
+    )
+    doc_feedback = (
+        db_session.scalars(
+            select(DocumentRetrievalFeedback).where(
+                DocumentRetrievalFeedback.chat_message_id.in_(message_ids)
+            )
+        ).all()
+        if message_ids
+        else []
+    )
+    memories = db_session.scalars(select(Memory).where(Memory.user_id == user.id)).all()
+    queries = db_session.scalars(
+        select(SearchQuery).where(SearchQuery.user_id == user.id)
+    ).all()
+    files = db_session.scalars(select(UserFile).where(UserFile.user_id == user.id)).all()
+
+    messages_by_session: dict[Any, list[dict[str, Any]]] = {}
+    for m in messages:
+        messages_by_session.setdefault(m.chat_session_id, []).append(
+            _row(m, ["id", "message_type", "message", "time_sent"])
+        )
+
+    data = {
+        "user": _row(
+            user,
+            ["id", "email", "role", "personal_name", "personal_role", "is_active"],
+        ),
+        "oauth_accounts": [
+            _row(a, ["oauth_name", "account_email"]) for a in user.oauth_accounts
+        ],
+        "chat_sessions": [
+            {
+                **_row(s, ["id", "description", "time_created", "deleted"]),
+                "messages": messages_by_session.get(s.id, []),
+            }
+            for s in sessions
+        ],
+        "chat_feedback": [
+            _row(
+                f,
+                [
+                    "chat_message_id",
+                    "is_positive",
+                    "feedback_text",
+                    "predefined_feedback",
+                ],
+            )
+            for f in chat_feedback
+        ],
+        "document_feedback": [
+            _row(f, ["chat_message_id", "document_id", "feedback", "clicked"])
+            for f in doc_feedback
+        ],
+        "memories": [_row(m, ["memory_text", "created_at"]) for m in memories],
+        "search_queries": [_row(q, ["query", "created_at"]) for q in queries],
+        "files": [
+            _row(f, ["id", "name", "file_id", "file_type", "created_at"]) for f in files
+        ],
+    }
+    counts = {
+        "chat_sessions": len(sessions),
+        "chat_messages": len(messages),
+        "chat_feedback": len(chat_feedback),
+        "document_feedback": len(doc_feedback),
+        "memories": len(memories),
+        "search_queries": len(queries),
+        "files": len(files),
+        "oauth_accounts": len(user.oauth_accounts),
+    }
+    return data, counts
+
+
+def purge(db_session: Any, user: Any, tenant_id: str) -> None:
+    from sqlalchemy import delete
+    from sqlalchemy import select
+
+    from onyx.background.celery.tasks.user_file_processing.tasks import (
+        delete_user_file_impl,
+    )
+    from onyx.db.chat import delete_all_chat_sessions_for_user
+    from onyx.db.engine.sql_engine import get_session_with_shared_schema
+    from onyx.db.models import ChatMessage
+    from onyx.db.models import ChatMessageFeedback
+    from onyx.db.models import ChatSession
+    from onyx.db.models import DocumentRetrievalFeedback
+    from onyx.db.models import SearchQuery
+    from onyx.db.models import UserFile
+    from onyx.db.models import UserTenantMapping
+    from onyx.db.users import delete_user_from_db
+
+    # Feedback rows only have their message FK nulled on delete, which would
+    # leave free-text feedback behind; remove them explicitly first.
+    message_ids = select(ChatMessage.id).join(
+        ChatSession, ChatMessage.chat_session_id == ChatSession.id
+    ).where(ChatSession.user_id == user.id)
+    db_session.execute(
+        delete(ChatMessageFeedback).where(
+            ChatMessageFeedback.chat_message_id.in_(message_ids)
+        )
+    )
+    db_session.execute(
+        delete(DocumentRetrievalFeedback).where(
+            DocumentRetrievalFeedback.chat_message_id.in_(message_ids)
+        )
+    )
+    db_session.execute(delete(SearchQuery).where(SearchQuery.user_id == user.id))
+    db_session.commit()
+
+    print("Deleting chat sessions and their files...", file=sys.stderr)
+    delete_all_chat_sessions_for_user(user, db_session, hard_delete=True)
+    # Slack-bot sessions are skipped by the helper above.
+    db_session.execute(delete(ChatSession).where(ChatSession.user_id == user.id))
+    db_session.commit()
+
+    file_ids = db_session.scalars(
+        select(UserFile.id).where(UserFile.user_id == user.id)
+    ).all()
+    for file_id in file_ids:
+        print(f"Deleting user file {file_id}...", file=sys.stderr)
+        delete_user_file_impl(
+            user_file_id=str(file_id), tenant_id=tenant_id, redis_locking=False
+        )
+
+    print("Deleting user...", file=sys.stderr)
+    email = user.email
+    db_session.expunge(user)
+    delete_user_from_db(user, db_session)
+
+    with get_session_with_shared_schema() as shared_session:
+        shared_session.execute(
+            delete(UserTenantMapping).where(
+                UserTenantMapping.email == email,
+                UserTenantMapping.tenant_id == tenant_id,
+            )
+        )
+        shared_session.commit()
+
+
+def run(action: str, email: str, tenant_id: str) -> dict[str, Any]:
+    from onyx.db.engine.sql_engine import get_session_with_tenant
+    from onyx.db.users import get_user_by_email
+    from shared_configs.contextvars import CURRENT_TENANT_ID_CONTEXTVAR
+
+    CURRENT_TENANT_ID_CONTEXTVAR.set(tenant_id)
+    with get_session_with_tenant(tenant_id=tenant_id) as db_session:
+        user = get_user_by_email(email, db_session)
+        if user is None:
+            return {"status": "not_found", "message": f"No user {email} in {tenant_id}"}
+
+        print(f"Collecting data for {email} in {tenant_id}...", file=sys.stderr)
+        data, counts = collect(db_session, user)
+        if action == "export":
+            return {"status": "success", "counts": counts, "data": data}
+
+        purge(db_session, user, tenant_id)
+        return {"status": "success", "counts": counts}
+
+
+def main() -> None:
+    if len(sys.argv) != 4 or sys.argv[1] not in ("export", "delete"):
+        print(
+            json.dumps(
+                {
+                    "status": "error",
+                    "message": "Usage: python - export|delete <email> <tenant_id>",
+                }
+            )
+        )
+        sys.exit(1)
+
+    from onyx.db.engine.sql_engine import SqlEngine
+
+    SqlEngine.init_engine(pool_size=5, max_overflow=2)
+
+    action, email, tenant_id = sys.argv[1:]
+    try:
+        result = run(action, email.lower(), tenant_id)
+    except Exception as e:
+        print(f"Error: {e}", file=sys.stderr)
+        result = {"status": "error", "message": str(e)}
+    print(json.dumps(result, default=str))
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,206 @@
+// Package gdpr runs data subject request (DSR) exports and deletions on an
+// api-server pod and produces signed reports of what was processed.
+package gdpr
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/sha256"
+	_ "embed"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/kube"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/paths"
+)
+
+//go:embed dsr.py
+var dsrScript string
+
+// SigningKeyEnv overrides the local signing key with a base64-encoded
+// ed25519 seed, so a team can sign with a shared key.
+const SigningKeyEnv = "ODS_DSR_SIGNING_KEY"
+
+// Action is the kind of data subject request.
+type Action string
+
+const (
+	ActionExport Action = "export"
+	ActionDelete Action = "delete"
+)
+
+// Result is what the on-pod script reports.
+type Result struct {
+	Status  string          `json:"status"`
+	Message string          `json:"message,omitempty"`
+	Counts  map[string]int  `json:"counts,omitempty"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+// Run executes the DSR script for email in tenantID on pod.
+func Run(c *kube.Cluster, pod string, action Action, email, tenantID string) (*Result, error) {
+	out, err := c.RunPython(pod, dsrScript, string(action), email, tenantID)
+	if err != nil {
+		return nil, err
+	}
+	return ParseResult(out)
+}
+
+// ParseResult parses the script's stdout, whose last non-empty line is the
+// JSON result (backend imports may log before it).
+func ParseResult(stdout string) (*Result, error) {
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	last := strings.TrimSpace(lines[len(lines)-1])
+	var r Result
+	if err := json.Unmarshal([]byte(last), &r); err != nil {
+		return nil, fmt.Errorf("unexpected output from DSR script: %q", last)
+	}
+	switch r.Status {
+	case "success":
+		return &r, nil
+	case "not_found":
+		return nil, fmt.Errorf("%s", r.Message)
+	default:
+		return nil, fmt.Errorf("DSR script failed: %s", r.Message)
+	}
+}
+
+// Report is the signed record of a processed request. Deletion reports carry
+// counts only, never the deleted data.
+type Report struct {
+	Action   Action          `json:"action"`
+	Email    string          `json:"email"`
+	TenantID string          `json:"tenant_id"`
+	Context  string          `json:"context"`
+	Reason   string          `json:"reason"`
+	Actor    string          `json:"actor"`
+	Time     time.Time       `json:"time"`
+	Counts   map[string]int  `json:"counts"`
+	Data     json.RawMessage `json:"data,omitempty"`
+
+	// Digest is the hex SHA-256 of the report with the three fields below
+	// empty; Signature is the base64 ed25519 signature of Digest by PublicKey.
+	Digest    string `json:"digest"`
+	Signature string `json:"signature"`
+	PublicKey string `json:"public_key"`
+}
+
+func (r *Report) digest() (string, error) {
+	unsigned := *r
+	unsigned.Digest, unsigned.Signature, unsigned.PublicKey = "", "", ""
+	data, err := json.Marshal(unsigned)
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Sign fills in the report's digest, signature and public key.
+func (r *Report) Sign(key ed25519.PrivateKey) error {
+	digest, err := r.digest()
+	if err != nil {
+		return fmt.Errorf("failed to digest report: %w", err)
+	}
+	r.Digest = digest
+	r.Signature = base64.StdEncoding.EncodeToString(ed25519.Sign(key, []byte(digest)))
+	r.PublicKey = base64.StdEncoding.EncodeToString(key.Public().(ed25519.PublicKey))
+	return nil
+}
+
+// Verify checks that the report is unmodified and signed by its public key.
+func (r *Report) Verify() error {
+	digest, err := r.digest()
+	if err != nil {
+		return fmt.Errorf("failed to digest report: %w", err)
+	}
+	if digest != r.Digest {
+		return errors.New("report contents do not match its digest")
+	}
+	pub, err := base64.StdEncoding.DecodeString(r.PublicKey)
+	if err != nil || len(pub) != ed25519.PublicKeySize {
+		return errors.New("report has an invalid public key")
+	}
+	sig, err := base64.StdEncoding.DecodeString(r.Signature)
+	if err != nil {
+		return errors.New("report has an invalid signature encoding")
+	}
+	if !ed25519.Verify(pub, []byte(r.Digest), sig) {
+		return errors.New("signature does not match")
+	}
+	return nil
+}
+
+// SigningKeyPath returns where the local signing key is kept.
+func SigningKeyPath() string {
+	return filepath.Join(paths.ConfigDir(), "dsr-signing.key")
+}
+
+// LoadSigningKey returns the key from $ODS_DSR_SIGNING_KEY, or the key at
+// path, generating it on first use.
+func LoadSigningKey(path string) (ed25519.PrivateKey, error) {
+	if v := os.Getenv(SigningKeyEnv); v != "" {
+		seed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(v))
+		if err != nil || len(seed) != ed25519.SeedSize {
+			return nil, fmt.Errorf("%s must be a base64-encoded %d-byte ed25519 seed", SigningKeyEnv, ed25519.SeedSize)
+		}
+		return ed25519.NewKeyFromSeed(seed), nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err == nil {
+		seed, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil || len(seed) != ed25519.SeedSize {
+			return nil, fmt.Errorf("signing key %s is corrupt", path)
+		}
+		return ed25519.NewKeyFromSeed(seed), nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read signing key %s: %w", path, err)
+	}
+
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate signing key: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create signing key directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(base64.StdEncoding.EncodeToString(key.Seed())+"\n"), 0600); err != nil {
+		return nil, fmt.Errorf("failed to write signing key %s: %w", path, err)
+	}
+	return key, nil
+}
+
+// ReadReport loads a report from a file.
+func ReadReport(path string) (*Report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read report %s: %w", path, err)
+	}
+	var r Report
+	if err := json.Unmarshal(data, &r); err != nil {
+		return nil, fmt.Errorf("failed to parse report %s: %w", path, err)
+	}
+	return &r, nil
+}
+
+// WriteReport writes a report to path, readable only by the current user
+// since exports contain personal data.
+func WriteReport(path string, r *Report) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal report: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0600); err != nil {
+		return fmt.Errorf("failed to write report %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package gdpr
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestParseResult(t *testing.T) {
+	out := "some import-time log line\n{\"status\": \"success\", \"counts\": {\"chat_sessions\": 2}}\n"
+	r, err := ParseResult(out)
+	if err != nil {
+		t.Fatalf("ParseResult() error: %v", err)
+	}
+	if r.Counts["chat_sessions"] != 2 {
+		t.Errorf("Counts = %v", r.Counts)
+	}
+
+	if _, err := ParseResult(`{"status": "not_found", "message": "No user x in t"}`); err == nil || err.Error() != "No user x in t" {
+		t.Errorf("expected not_found message as error, got %v", err)
+	}
+	if _, err := ParseResult(`{"status": "error", "message": "boom"}`); err == nil {
+		t.Error("expected error status to return an error")
+	}
+	if _, err := ParseResult("Traceback (most recent call last):"); err == nil {
+		t.Error("expected non-JSON output to return an error")
+	}
+}
+
+func TestReportSignVerify(t *testing.T) {
+	t.Setenv(SigningKeyEnv, "")
+	dir := t.TempDir()
+	key, err := LoadSigningKey(filepath.Join(dir, "k"))
+	if err != nil {
+		t.Fatalf("LoadSigningKey() error: %v", err)
+	}
+	again, err := LoadSigningKey(filepath.Join(dir, "k"))
+	if err != nil || !again.Equal(key) {
+		t.Fatalf("expected the generated key to be reused, err=%v", err)
+	}
+
+	r := &Report{
+		Action:   ActionExport,
+		Email:    "jane@customer.com",
+		TenantID: "tenant_1",
+		Time:     time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC),
+		Counts:   map[string]int{"chat_sessions": 1},
+		Data:     json.RawMessage(`{"user":{"email":"jane@customer.com"}}`),
+	}
+	if err := r.Sign(key); err != nil {
+		t.Fatalf("Sign() error: %v", err)
+	}
+
+	path := filepath.Join(dir, "report.json")
+	if err := WriteReport(path, r); err != nil {
+		t.Fatalf("WriteReport() error: %v", err)
+	}
+	loaded, err := ReadReport(path)
+	if err != nil {
+		t.Fatalf("ReadReport() error: %v", err)
+	}
+	if err := loaded.Verify(); err != nil {
+		t.Fatalf("Verify() of an untouched report failed: %v", err)
+	}
+
+	loaded.Counts["chat_sessions"] = 0
+	if err := loaded.Verify(); err == nil {
+		t.Error("expected Verify() to fail after tampering")
+	}
+}
+
+func TestLoadSigningKeyFromEnv(t *testing.T) {
+	// 32 zero bytes, base64-encoded.
+	t.Setenv(SigningKeyEnv, "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA=")
+	key, err := LoadSigningKey(filepath.Join(t.TempDir(), "unused"))
+	if err != nil {
+		t.Fatalf("LoadSigningKey() error: %v", err)
+	}
+	if len(key.Seed()) != 32 {
+		t.Errorf("unexpected seed length %d", len(key.Seed()))
+	}
+
+	t.Setenv(SigningKeyEnv, "not-a-key")
+	if _, err := LoadSigningKey(filepath.Join(t.TempDir(), "unused")); err == nil {
+		t.Error("expected an error for an invalid env key")
+	}
+}
@@ -0,0 +1,142 @@
+package gen
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// SourceCategories are the frontend's SourceCategory members a connector can
+// be listed under on the Add Connector page.
+var SourceCategories = []string{"Wiki", "Storage", "TicketingAndTaskManagement", "Messaging", "Sales", "CodeRepository", "Other"}
+
+// ConnectorOptions describes a connector to scaffold.
+type ConnectorOptions struct {
+	Name Name
+	// Description is the one-line summary shown for the source.
+	Description string
+	// Category is a member of SourceCategories.
+	Category string
+}
+
+// Files the connector generator edits.
+const (
+	constantsPath   = "backend/onyx/configs/constants.py"
+	registryPath    = "backend/onyx/connectors/registry.py"
+	typesPath       = "web/src/lib/types.ts"
+	sourcesPath     = "web/src/lib/sources.ts"
+	connectorsPath  = "web/src/lib/connectors/connectors.tsx"
+	credentialsPath = "web/src/lib/connectors/credentials.ts"
+)
+
+var (
+	documentSourceRE     = regexp.MustCompile(`^class DocumentSource\(`)
+	mockSourceRE         = regexp.MustCompile(`^    MOCK_CONNECTOR = `)
+	sourceDescriptionRE  = regexp.MustCompile(`^DocumentSourceDescription\b`)
+	closeBraceRE         = regexp.MustCompile(`^}`)
+	connectorMapRE       = regexp.MustCompile(`^CONNECTOR_CLASS_MAP = \{`)
+	mockMappingRE        = regexp.MustCompile(`^    DocumentSource\.MOCK_CONNECTOR:`)
+	validSourcesRE       = regexp.MustCompile(`^export enum ValidSources \{`)
+	craftSourcesRE       = regexp.MustCompile(`^  // Craft-specific sources`)
+	sourceMetadataRE     = regexp.MustCompile(`^export const SOURCE_METADATA_MAP\b`)
+	ingestionSourceRE    = regexp.MustCompile(`^  ingestion_api: \{`)
+	connectorConfigsRE   = regexp.MustCompile(`^export const connectorConfigs\b`)
+	credentialTemplateRE = regexp.MustCompile(`^export const credentialTemplates\b`)
+	credentialNamesRE    = regexp.MustCompile(`^export const credentialDisplayNames\b`)
+	objectEndRE          = regexp.MustCompile(`^};`)
+)
+
+// Connector plans a new connector: a backend module with a unit test,
+// registered as a DocumentSource and in the connector factory, plus the
+// frontend source, config form and credential form stubs.
+func Connector(root string, opts ConnectorOptions) (*Plan, error) {
+	n := opts.Name
+	if opts.Category == "" {
+		opts.Category = "Other"
+	}
+	if !slices.Contains(SourceCategories, opts.Category) {
+		return nil, fmt.Errorf("unknown category %q; choose from %s", opts.Category, strings.Join(SourceCategories, ", "))
+	}
+	if opts.Description == "" {
+		opts.Description = "Documents from " + n.Display
+	}
+
+	p := newPlan(root)
+	module := "backend/onyx/connectors/" + n.Snake
+	if _, err := os.Stat(p.abs(module)); err == nil {
+		return nil, fmt.Errorf("%s already exists", module)
+	}
+	data := struct {
+		ConnectorOptions
+		Name
+	}{opts, n}
+
+	steps := []error{
+		p.create(module+"/__init__.py", "", nil),
+		p.create(module+"/connector.py", "connector.py.tmpl", data),
+		p.create("backend/tests/unit/onyx/connectors/"+n.Snake+"/__init__.py", "", nil),
+		p.create("backend/tests/unit/onyx/connectors/"+n.Snake+"/test_"+n.Snake+"_connector.py", "connector_test.py.tmpl", data),
+
+		p.edit(constantsPath, func(s string) (string, error) {
+			if strings.Contains(s, fmt.Sprintf("    %s = ", n.Upper)) {
+				return "", fmt.Errorf("DocumentSource.%s already exists", n.Upper)
+			}
+			s, err := insertAbove(s, documentSourceRE, mockSourceRE, fmt.Sprintf("    %s = %q\n", n.Upper, n.Snake))
+			if err != nil {
+				return "", err
+			}
+			return insertAbove(s, sourceDescriptionRE, closeBraceRE, fmt.Sprintf("    DocumentSource.%s: %q,\n", n.Upper, opts.Description))
+		}),
+		p.edit(registryPath, func(s string) (string, error) {
+			return insertAbove(s, connectorMapRE, mockMappingRE, fmt.Sprintf(
+				"    DocumentSource.%s: ConnectorMapping(\n        module_path=\"onyx.connectors.%s.connector\",\n        class_name=\"%sConnector\",\n    ),\n",
+				n.Upper, n.Snake, n.Pascal))
+		}),
+
+		p.edit(typesPath, func(s string) (string, error) {
+			return insertAbove(s, validSourcesRE, craftSourcesRE, fmt.Sprintf("  %s = %q,\n", n.Pascal, n.Snake))
+		}),
+		p.edit(sourcesPath, func(s string) (string, error) {
+			return insertAbove(s, sourceMetadataRE, ingestionSourceRE, fmt.Sprintf(
+				"  %s: {\n    icon: SvgFileText,\n    displayName: %q,\n    category: SourceCategory.%s,\n  },\n",
+				n.Snake, n.Display, opts.Category))
+		}),
+		p.edit(connectorsPath, func(s string) (string, error) {
+			s, err := insertAbove(s, connectorConfigsRE, objectEndRE, fmt.Sprintf(
+				"  %s: {\n    description: %q,\n    values: [\n      {\n        type: \"text\",\n        label: \"Base URL\",\n        name: \"base_url\",\n        optional: false,\n        description: %q,\n      },\n    ],\n    advanced_values: [],\n  },\n",
+				n.Snake, "Configure "+n.Display+" connector", "The URL of your "+n.Display+" instance."))
+			if err != nil {
+				return "", err
+			}
+			return strings.TrimRight(s, "\n") + fmt.Sprintf("\n\nexport interface %sConfig {\n  base_url: string;\n}\n", n.Pascal), nil
+		}),
+		p.edit(credentialsPath, func(s string) (string, error) {
+			s, err := insertAbove(s, nil, credentialTemplateRE, fmt.Sprintf(
+				"\nexport interface %sCredentialJson {\n  %s_api_token: string;\n}\n", n.Pascal, n.Snake))
+			if err != nil {
+				return "", err
+			}
+			s, err = insertAbove(s, credentialTemplateRE, objectEndRE, fmt.Sprintf(
+				"  %s: { %s_api_token: \"\" } as %sCredentialJson,\n", n.Snake, n.Snake, n.Pascal))
+			if err != nil {
+				return "", err
+			}
+			return insertAbove(s, credentialNamesRE, objectEndRE, fmt.Sprintf(
+				"\n  // %s\n  %s_api_token: %q,\n", n.Display, n.Snake, n.Display+" API Token"))
+		}),
+	}
+	for _, err := range steps {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	p.Next = []string{
+		fmt.Sprintf("Fetch items from the %s API in %s/connector.py (_fetch_items)", n.Display, module),
+		fmt.Sprintf("Add an icon for %s to web/src/lib/sources.ts (it uses SvgFileText for now)", n.Display),
+		fmt.Sprintf("Run the unit test: pytest backend/tests/unit/onyx/connectors/%s", n.Snake),
+	}
+	return p, nil
+}
@@ -0,0 +1,97 @@
+package gen
+
+import (
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+const mainPath = "backend/onyx/main.py"
+
+var (
+	areaRE         = regexp.MustCompile(`^[a-z][a-z0-9_]*(/[a-z][a-z0-9_]*)*$`)
+	serverImportRE = regexp.MustCompile(`^from onyx\.server\.`)
+	getAppRE       = regexp.MustCompile(`^def get_application\(`)
+	patRouterRE    = regexp.MustCompile(`^    include_router_with_global_prefix_prepended\(application, pat_router\)`)
+)
+
+// EndpointOptions describes an API endpoint to scaffold.
+type EndpointOptions struct {
+	// Area is the package under backend/onyx/server, e.g. "retention" or
+	// "features/retention".
+	Area string
+	// Admin puts the router under /admin and requires full admin access;
+	// otherwise any signed-in user may call it.
+	Admin bool
+}
+
+// APIEndpoint plans a router under backend/onyx/server/<area> with one GET
+// endpoint, its response model and a unit test, included in the app by
+// backend/onyx/main.py.
+func APIEndpoint(root string, opts EndpointOptions) (*Plan, error) {
+	if !areaRE.MatchString(opts.Area) {
+		return nil, fmt.Errorf("invalid area %q (snake_case package names separated by /)", opts.Area)
+	}
+	segments := strings.Split(opts.Area, "/")
+	n, err := ParseName(segments[len(segments)-1])
+	if err != nil {
+		return nil, err
+	}
+
+	p := newPlan(root)
+	pkg := "backend/onyx/server/" + opts.Area
+	if _, err := os.Stat(p.abs(pkg)); err == nil {
+		return nil, fmt.Errorf("%s already exists", pkg)
+	}
+	router, alias, prefix := "router", n.Snake+"_router", "/"+strings.ReplaceAll(n.Snake, "_", "-")
+	if opts.Admin {
+		router, alias, prefix = "admin_router", n.Snake+"_admin_router", "/admin"+prefix
+	}
+	data := struct {
+		Name
+		Module string
+		Router string
+		Prefix string
+		Admin  bool
+	}{n, "onyx.server." + strings.ReplaceAll(opts.Area, "/", "."), router, prefix, opts.Admin}
+
+	// Every package above the new one already exists or gets an __init__.py.
+	var steps []error
+	for i := range segments {
+		dir := "backend/onyx/server/" + strings.Join(segments[:i+1], "/")
+		if _, err := os.Stat(p.abs(dir)); err != nil {
+			steps = append(steps, p.create(dir+"/__init__.py", "", nil))
+		}
+	}
+	tests := "backend/tests/unit/onyx/server/" + opts.Area
+	for i := range segments {
+		dir := "backend/tests/unit/onyx/server/" + strings.Join(segments[:i+1], "/")
+		if _, err := os.Stat(p.abs(dir)); err != nil {
+			steps = append(steps, p.create(dir+"/__init__.py", "", nil))
+		}
+	}
+	steps = append(steps,
+		p.create(pkg+"/api.py", "api.py.tmpl", data),
+		p.create(pkg+"/models.py", "models.py.tmpl", data),
+		p.create(tests+"/test_api.py", "api_test.py.tmpl", data),
+		p.edit(mainPath, func(s string) (string, error) {
+			s, err := insertSorted(s, serverImportRE, fmt.Sprintf("from %s.api import %s as %s", data.Module, router, alias))
+			if err != nil {
+				return "", err
+			}
+			return insertAbove(s, getAppRE, patRouterRE, fmt.Sprintf("    include_router_with_global_prefix_prepended(application, %s)\n", alias))
+		}),
+	)
+	for _, err := range steps {
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	p.Next = []string{
+		fmt.Sprintf("Fill in %s/api.py and the response model in %s/models.py", pkg, pkg),
+		"Run the unit test: pytest " + tests,
+	}
+	return p, nil
+}
@@ -0,0 +1,237 @@
+// Package gen scaffolds new code in the Onyx repository: connectors, Alembic
+// migrations and API endpoints, wired into the registries they need so the
+// result runs before any real logic is written.
+package gen
+
+import (
+	"bytes"
+	"embed"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"text/template"
+	"unicode"
+)
+
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+var templates = template.Must(template.ParseFS(templateFS, "templates/*.tmpl"))
+
+// Change is one file a generator creates or edits.
+type Change struct {
+	// Path is relative to the repository root, with forward slashes.
+	Path string
+	// Created is true for new files and false for edits to existing ones.
+	Created bool
+	content string
+}
+
+// Plan is the set of changes a generator makes. Nothing is written until
+// Apply, so a generator that fails part way leaves the tree untouched.
+type Plan struct {
+	Root    string
+	Changes []Change
+	// Next lists what is left to do by hand.
+	Next []string
+	// edits holds the pending content of files being edited, so several
+	// edits to one file stack.
+	edits map[string]int
+}
+
+func newPlan(root string) *Plan {
+	return &Plan{Root: root, edits: map[string]int{}}
+}
+
+// create adds a new file rendered from the named template, or an empty one
+// if tmpl is "".
+func (p *Plan) create(path, tmpl string, data any) error {
+	if _, err := os.Stat(p.abs(path)); err == nil {
+		return fmt.Errorf("%s already exists", path)
+	}
+	var buf bytes.Buffer
+	if tmpl != "" {
+		if err := templates.ExecuteTemplate(&buf, tmpl, data); err != nil {
+			return fmt.Errorf("rendering %s: %w", path, err)
+		}
+	}
+	p.Changes = append(p.Changes, Change{Path: path, Created: true, content: buf.String()})
+	return nil
+}
+
+// edit applies fn to the current content of an existing file.
+func (p *Plan) edit(path string, fn func(string) (string, error)) error {
+	i, ok := p.edits[path]
+	if !ok {
+		data, err := os.ReadFile(p.abs(path))
+		if err != nil {
+			return err
+		}
+		i = len(p.Changes)
+		p.edits[path] = i
+		p.Changes = append(p.Changes, Change{Path: path, content: string(data)})
+	}
+	content, err := fn(p.Changes[i].content)
+	if err != nil {
+		return fmt.Errorf("%s: %w", path, err)
+	}
+	p.Changes[i].content = content
+	return nil
+}
+
+func (p *Plan) abs(path string) string {
+	return filepath.Join(p.Root, filepath.FromSlash(path))
+}
+
+// Apply writes the plan's changes.
+func (p *Plan) Apply() error {
+	for _, c := range p.Changes {
+		path := p.abs(c.Path)
+		if c.Created {
+			if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+				return err
+			}
+		}
+		if err := os.WriteFile(path, []byte(c.content), 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Content returns the content a change writes.
+func (c Change) Content() string {
+	return c.content
+}
+
+// errAnchor reports that a file no longer looks the way a generator expects.
+var errAnchor = errors.New("anchor not found; the file's layout has changed, so update ods gen")
+
+// insertAbove inserts text into content above the first line matching anchor
+// that follows the first line matching start (or the top, if start is nil).
+// It first steps back over blank and comment lines, so the text joins the
+// entries above them rather than the section a comment introduces.
+func insertAbove(content string, start, anchor *regexp.Regexp, text string) (string, error) {
+	lines := strings.SplitAfter(content, "\n")
+	from := 0
+	if start != nil {
+		from = indexLine(lines, 0, start)
+		if from < 0 {
+			return "", fmt.Errorf("%w: %s", errAnchor, start)
+		}
+		from++
+	}
+	at := indexLine(lines, from, anchor)
+	if at < 0 {
+		return "", fmt.Errorf("%w: %s", errAnchor, anchor)
+	}
+	for at > from && isBlankOrComment(lines[at-1]) {
+		at--
+	}
+	return strings.Join(lines[:at], "") + text + strings.Join(lines[at:], ""), nil
+}
+
+// insertSorted inserts line among the lines matching group, before the first
+// one that sorts after it.
+func insertSorted(content string, group *regexp.Regexp, line string) (string, error) {
+	lines := strings.SplitAfter(content, "\n")
+	last := -1
+	for i, l := range lines {
+		if !group.MatchString(l) {
+			continue
+		}
+		if strings.TrimRight(l, "\n") > line {
+			return strings.Join(lines[:i], "") + line + "\n" + strings.Join(lines[i:], ""), nil
+		}
+		last = i
+	}
+	if last < 0 {
+		return "", fmt.Errorf("%w: %s", errAnchor, group)
+	}
+	return strings.Join(lines[:last+1], "") + line + "\n" + strings.Join(lines[last+1:], ""), nil
+}
+
+func indexLine(lines []string, from int, re *regexp.Regexp) int {
+	for i := from; i < len(lines); i++ {
+		if re.MatchString(strings.TrimRight(lines[i], "\n")) {
+			return i
+		}
+	}
+	return -1
+}
+
+func isBlankOrComment(line string) bool {
+	s := strings.TrimSpace(line)
+	return s == "" || strings.HasPrefix(s, "#") || strings.HasPrefix(s, "//")
+}
+
+// Name is an identifier in the forms the backend and frontend spell it.
+type Name struct {
+	Display string // Acme Wiki
+	Snake   string // acme_wiki
+	Upper   string // ACME_WIKI
+	Pascal  string // AcmeWiki
+}
+
+// ParseName reads a name written as words ("Acme Wiki"), in PascalCase
+// ("AcmeWiki") or in snake_case ("acme_wiki").
+func ParseName(s string) (Name, error) {
+	words := splitWords(s)
+	if len(words) == 0 {
+		return Name{}, fmt.Errorf("invalid name %q", s)
+	}
+	if !unicode.IsLetter(rune(words[0][0])) {
+		return Name{}, fmt.Errorf("invalid name %q: must start with a letter", s)
+	}
+	var n Name
+	display := make([]string, len(words))
+	lower := make([]string, len(words))
+	for i, w := range words {
+		lower[i] = strings.ToLower(w)
+		display[i] = strings.ToUpper(w[:1]) + w[1:]
+		n.Pascal += display[i]
+	}
+	n.Snake = strings.Join(lower, "_")
+	n.Upper = strings.ToUpper(n.Snake)
+	n.Display = strings.Join(display, " ")
+	if strings.ContainsRune(strings.TrimSpace(s), ' ') {
+		n.Display = strings.Join(strings.Fields(s), " ")
+	}
+	return n, nil
+}
+
+// splitWords splits s at spaces, underscores, dashes and case changes,
+// keeping acronyms together ("HTTPDocs" is HTTP and Docs). It returns nil if
+// s holds anything but ASCII letters, digits and separators.
+func splitWords(s string) []string {
+	var words []string
+	var cur []rune
+	flush := func() {
+		if len(cur) > 0 {
+			words = append(words, string(cur))
+			cur = nil
+		}
+	}
+	runes := []rune(strings.TrimSpace(s))
+	for i, r := range runes {
+		switch {
+		case r == ' ' || r == '_' || r == '-':
+			flush()
+			continue
+		case r > unicode.MaxASCII || !(unicode.IsLetter(r) || unicode.IsDigit(r)):
+			return nil
+		case unicode.IsUpper(r) && len(cur) > 0:
+			prev := cur[len(cur)-1]
+			nextLower := i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if !unicode.IsUpper(prev) || nextLower {
+				flush()
+			}
+		}
+		cur = append(cur, r)
+	}
+	flush()
+	return words
+}
@@ -0,0 +1,342 @@
+package gen
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeRepo holds trimmed copies of the files the generators edit.
+var fakeRepo = map[string]string{
+	constantsPath: `class DocumentSource(str, Enum):
+    SLACK = "slack"
+    LUMAPPS = "lumapps"
+
+    # Special case just for integration tests
+    MOCK_CONNECTOR = "mock_connector"
+
+
+DocumentSourceDescription: dict[DocumentSource, str] = {
+    DocumentSource.SLACK: "chat messages",
+    DocumentSource.LUMAPPS: "Intranet pages, news, and content",
+}
+`,
+	registryPath: `CONNECTOR_CLASS_MAP = {
+    DocumentSource.SLACK: ConnectorMapping(
+        module_path="onyx.connectors.slack.connector",
+        class_name="SlackConnector",
+    ),
+    # just for integration tests
+    DocumentSource.MOCK_CONNECTOR: ConnectorMapping(
+        module_path="onyx.connectors.mock_connector.connector",
+        class_name="MockConnector",
+    ),
+}
+`,
+	typesPath: `export enum ValidSources {
+  Slack = "slack",
+  Canvas = "canvas",
+
+  // Craft-specific sources
+  CraftFile = "craft_file",
+}
+`,
+	sourcesPath: `export const SOURCE_METADATA_MAP: SourceMap = {
+  slack: {
+    icon: SvgSlack,
+    displayName: "Slack",
+    category: SourceCategory.Messaging,
+  },
+
+  // Other
+  ingestion_api: {
+    icon: SvgGlobe,
+    displayName: "Ingestion",
+    category: SourceCategory.Other,
+  },
+} as SourceMap;
+`,
+	connectorsPath: `export const connectorConfigs: Record<ConfigurableSources, ConnectionConfiguration> = {
+  slack: {
+    description: "Configure Slack connector",
+    values: [],
+    advanced_values: [],
+  },
+};
+type ConnectorField = ConnectionConfiguration["values"][number];
+
+export interface ImapConfig {
+  host: string;
+}
+`,
+	credentialsPath: `export interface SlackCredentialJson {
+  slack_bot_token: string;
+}
+
+export const credentialTemplates: Record<ValidSources, any> = {
+  slack: { slack_bot_token: "" } as SlackCredentialJson,
+};
+
+export const credentialDisplayNames: Record<string, string> = {
+  // Slack
+  slack_bot_token: "Slack Bot Token",
+};
+`,
+	mainPath: `from onyx.server.auth.captcha_api import router as captcha_router
+from onyx.server.pat.api import router as pat_router
+from onyx.server.security.api import admin_router as security_admin_router
+
+
+def get_application() -> FastAPI:
+    include_router_with_global_prefix_prepended(application, security_admin_router)
+
+    include_router_with_global_prefix_prepended(application, pat_router)
+`,
+	"backend/alembic/versions/aaaaaaaaaaaa_first.py": `revision = "aaaaaaaaaaaa"
+down_revision = None
+`,
+	"backend/alembic/versions/bbbbbbbbbbbb_second.py": `revision: str = "bbbbbbbbbbbb"
+down_revision: Union[str, None] = "aaaaaaaaaaaa"
+`,
+}
+
+func newFakeRepo(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	for path, content := range fakeRepo {
+		abs := filepath.Join(root, filepath.FromSlash(path))
+		if err := os.MkdirAll(filepath.Dir(abs), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(abs, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return root
+}
+
+func read(t *testing.T, root, path string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(root, filepath.FromSlash(path)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestParseName(t *testing.T) {
+	tests := []struct {
+		in   string
+		want Name
+	}{
+		{"Acme Wiki", Name{Display: "Acme Wiki", Snake: "acme_wiki", Upper: "ACME_WIKI", Pascal: "AcmeWiki"}},
+		{"AcmeWiki", Name{Display: "Acme Wiki", Snake: "acme_wiki", Upper: "ACME_WIKI", Pascal: "AcmeWiki"}},
+		{"acme_wiki", Name{Display: "Acme Wiki", Snake: "acme_wiki", Upper: "ACME_WIKI", Pascal: "AcmeWiki"}},
+		{"HTTPDocs", Name{Display: "HTTP Docs", Snake: "http_docs", Upper: "HTTP_DOCS", Pascal: "HTTPDocs"}},
+		{"Notion2", Name{Display: "Notion2", Snake: "notion2", Upper: "NOTION2", Pascal: "Notion2"}},
+	}
+	for _, tt := range tests {
+		got, err := ParseName(tt.in)
+		if err != nil || got != tt.want {
+			t.Errorf("ParseName(%q) = %+v, %v; want %+v", tt.in, got, err, tt.want)
+		}
+	}
+	for _, bad := range []string{"", "  ", "2fa", "acme.wiki", "wiki!"} {
+		if _, err := ParseName(bad); err == nil {
+			t.Errorf("ParseName(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestConnector(t *testing.T) {
+	root := newFakeRepo(t)
+	name, _ := ParseName("Acme Wiki")
+	p, err := Connector(root, ConnectorOptions{Name: name, Category: "Wiki"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Apply(); err != nil {
+		t.Fatal(err)
+	}
+
+	checks := map[string][]string{
+		constantsPath: {
+			"    LUMAPPS = \"lumapps\"\n    ACME_WIKI = \"acme_wiki\"\n\n    # Special case",
+			"    DocumentSource.ACME_WIKI: \"Documents from Acme Wiki\",\n}",
+		},
+		registryPath: {"    ),\n    DocumentSource.ACME_WIKI: ConnectorMapping(\n        module_path=\"onyx.connectors.acme_wiki.connector\",\n        class_name=\"AcmeWikiConnector\",\n    ),\n    # just for"},
+		typesPath:    {"  Canvas = \"canvas\",\n  AcmeWiki = \"acme_wiki\",\n\n  // Craft"},
+		sourcesPath:  {"  },\n  acme_wiki: {\n    icon: SvgFileText,\n    displayName: \"Acme Wiki\",\n    category: SourceCategory.Wiki,\n  },\n\n  // Other"},
+		connectorsPath: {
+			"    advanced_values: [],\n  },\n  acme_wiki: {\n    description: \"Configure Acme Wiki connector\",",
+			"}\n\nexport interface AcmeWikiConfig {\n  base_url: string;\n}\n",
+		},
+		credentialsPath: {
+			"}\n\nexport interface AcmeWikiCredentialJson {\n  acme_wiki_api_token: string;\n}\n\nexport const credentialTemplates",
+			"  acme_wiki: { acme_wiki_api_token: \"\" } as AcmeWikiCredentialJson,\n};",
+			"\n\n  // Acme Wiki\n  acme_wiki_api_token: \"Acme Wiki API Token\",\n};",
+		},
+		"backend/onyx/connectors/acme_wiki/connector.py":                           {"class AcmeWikiConnector(LoadConnector, PollConnector):", "source=DocumentSource.ACME_WIKI,"},
+		"backend/tests/unit/onyx/connectors/acme_wiki/test_acme_wiki_connector.py": {"from onyx.connectors.acme_wiki.connector import AcmeWikiConnector"},
+	}
+	for path, wants := range checks {
+		got := read(t, root, path)
+		for _, want := range wants {
+			if !strings.Contains(got, want) {
+				t.Errorf("%s lacks %q:\n%s", path, want, got)
+			}
+		}
+	}
+	if read(t, root, "backend/onyx/connectors/acme_wiki/__init__.py") != "" {
+		t.Error("__init__.py is not empty")
+	}
+
+	if _, err := Connector(root, ConnectorOptions{Name: name}); err == nil || !strings.Contains(err.Error(), "already exists") {
+		t.Errorf("second Connector() error = %v", err)
+	}
+}
+
+func TestConnectorMissingAnchorWritesNothing(t *testing.T) {
+	root := newFakeRepo(t)
+	if err := os.WriteFile(filepath.Join(root, filepath.FromSlash(typesPath)), []byte("export enum ValidSources {\n}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	name, _ := ParseName("Acme")
+	if _, err := Connector(root, ConnectorOptions{Name: name}); err == nil || !strings.Contains(err.Error(), typesPath) {
+		t.Fatalf("Connector() error = %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(root, "backend/onyx/connectors/acme")); !os.IsNotExist(err) {
+		t.Error("connector module written despite the error")
+	}
+	if _, err := Connector(root, ConnectorOptions{Name: name, Category: "Gossip"}); err == nil {
+		t.Error("expected an error for an unknown category")
+	}
+}
+
+func TestMigration(t *testing.T) {
+	root := newFakeRepo(t)
+	now := time.Date(2026, 10, 15, 9, 30, 0, 0, time.UTC)
+	p, err := Migration(root, MigrationOptions{Message: "Add retention policy to chat sessions", Schema: "default", Now: now})
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := p.Changes[0]
+	if !strings.HasPrefix(c.Path, "backend/alembic/versions/") || !strings.HasSuffix(c.Path, "_add_retention_policy_to_chat_sessions.py") {
+		t.Errorf("path = %s", c.Path)
+	}
+	for _, want := range []string{"Revises: bbbbbbbbbbbb\nCreate Date: 2026-10-15 09:30:00.000000", `down_revision = "bbbbbbbbbbbb"`} {
+		if !strings.Contains(c.Content(), want) {
+			t.Errorf("migration lacks %q:\n%s", want, c.Content())
+		}
+	}
+	if err := p.Apply(); err != nil {
+		t.Fatal(err)
+	}
+	head, err := Head(filepath.Join(root, "backend/alembic/versions"))
+	if err != nil || !strings.HasPrefix(filepath.Base(c.Path), head+"_") {
+		t.Errorf("Head() = %s, %v after adding %s", head, err, c.Path)
+	}
+
+	if _, err := Migration(root, MigrationOptions{Message: "x", Schema: "tenants"}); err == nil {
+		t.Error("expected an error for an unknown schema")
+	}
+}
+
+func TestHeadRejectsBranches(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"a.py": "revision = 'a1'\ndown_revision = None\n",
+		"b.py": "revision = 'b1'\ndown_revision = 'a1'\n",
+		"c.py": "revision = 'c1'\ndown_revision = 'a1'\n",
+	}
+	for name, content := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := Head(dir); err == nil || !strings.Contains(err.Error(), "2 heads (b1, c1)") {
+		t.Errorf("Head() error = %v", err)
+	}
+
+	merge := "revision = 'm1'\ndown_revision = (\n    'b1',\n    'c1',\n)\n"
+	if err := os.WriteFile(filepath.Join(dir, "m.py"), []byte(merge), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if head, err := Head(dir); err != nil || head != "m1" {
+		t.Errorf("Head() = %s, %v", head, err)
+	}
+}
+
+func TestSlug(t *testing.T) {
+	tests := map[string]string{
+		"Add retention policy":                               "add_retention_policy",
+		"Drop user.email (unique)":                           "drop_user_email_unique",
+		"Add a column for the tenant's indexing pause state": "add_a_column_for_the_tenant_s_indexing_",
+	}
+	for in, want := range tests {
+		if got := Slug(in); got != want {
+			t.Errorf("Slug(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestAPIEndpoint(t *testing.T) {
+	root := newFakeRepo(t)
+	p, err := APIEndpoint(root, EndpointOptions{Area: "features/retention", Admin: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.Apply(); err != nil {
+		t.Fatal(err)
+	}
+
+	main := read(t, root, mainPath)
+	for _, want := range []string{
+		"from onyx.server.auth.captcha_api import router as captcha_router\nfrom onyx.server.features.retention.api import admin_router as retention_admin_router\nfrom onyx.server.pat.api",
+		"security_admin_router)\n    include_router_with_global_prefix_prepended(application, retention_admin_router)\n\n",
+	} {
+		if !strings.Contains(main, want) {
+			t.Errorf("main.py lacks %q:\n%s", want, main)
+		}
+	}
+	api := read(t, root, "backend/onyx/server/features/retention/api.py")
+	for _, want := range []string{
+		`admin_router = APIRouter(prefix="/admin/retention")`,
+		"from onyx.server.features.retention.models import RetentionResponse\n",
+		"Depends(require_permission(Permission.FULL_ADMIN_PANEL_ACCESS))",
+	} {
+		if !strings.Contains(api, want) {
+			t.Errorf("api.py lacks %q:\n%s", want, api)
+		}
+	}
+	for _, path := range []string{
+		"backend/onyx/server/features/__init__.py",
+		"backend/onyx/server/features/retention/__init__.py",
+		"backend/onyx/server/features/retention/models.py",
+		"backend/tests/unit/onyx/server/features/retention/__init__.py",
+		"backend/tests/unit/onyx/server/features/retention/test_api.py",
+	} {
+		read(t, root, path)
+	}
+
+	p, err = APIEndpoint(root, EndpointOptions{Area: "reading_list"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range p.Changes {
+		if c.Path == "backend/onyx/server/reading_list/api.py" {
+			api = c.Content()
+		}
+	}
+	if !strings.Contains(api, `router = APIRouter(prefix="/reading-list")`) || !strings.Contains(api, "Depends(current_user)") {
+		t.Errorf("user api.py:\n%s", api)
+	}
+	if _, err := APIEndpoint(root, EndpointOptions{Area: "features/retention"}); err == nil {
+		t.Error("expected an error for an existing area")
+	}
+	if _, err := APIEndpoint(root, EndpointOptions{Area: "../etc"}); err == nil {
+		t.Error("expected an error for an invalid area")
+	}
+}
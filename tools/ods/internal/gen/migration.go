@@ -0,0 +1,166 @@
+package gen
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MigrationDirs maps each Alembic schema to its versions directory.
+var MigrationDirs = map[string]string{
+	"default": "backend/alembic/versions",
+	"private": "backend/alembic_tenants/versions",
+}
+
+// slugLength matches Alembic's default truncate_slug_length.
+const slugLength = 40
+
+var (
+	revisionRE     = regexp.MustCompile(`^revision(?:\s*:[^=]+)?\s*=\s*["']([^"']+)["']`)
+	downRevisionRE = regexp.MustCompile(`^down_revision(?:\s*:[^=]+)?\s*=(.*)`)
+	quotedRE       = regexp.MustCompile(`["']([^"']+)["']`)
+	slugWordRE     = regexp.MustCompile(`\w+`)
+)
+
+// MigrationOptions describes a migration to scaffold.
+type MigrationOptions struct {
+	Message string
+	// Schema is a key of MigrationDirs.
+	Schema string
+	// Now stamps the migration's Create Date.
+	Now time.Time
+}
+
+// Migration plans an empty Alembic migration on top of the schema's single
+// head, named the way alembic revision names it.
+func Migration(root string, opts MigrationOptions) (*Plan, error) {
+	if strings.TrimSpace(opts.Message) == "" {
+		return nil, fmt.Errorf("a migration message is required")
+	}
+	dir, ok := MigrationDirs[opts.Schema]
+	if !ok {
+		return nil, fmt.Errorf("unknown schema %q; use default or private", opts.Schema)
+	}
+	head, err := Head(filepath.Join(root, filepath.FromSlash(dir)))
+	if err != nil {
+		return nil, err
+	}
+	revision, err := newRevision()
+	if err != nil {
+		return nil, err
+	}
+
+	p := newPlan(root)
+	data := map[string]string{
+		"Message":      strings.TrimSpace(opts.Message),
+		"Revision":     revision,
+		"DownRevision": head,
+		"CreateDate":   opts.Now.Format("2006-01-02 15:04:05.000000"),
+	}
+	path := fmt.Sprintf("%s/%s_%s.py", dir, revision, Slug(opts.Message))
+	if err := p.create(path, "migration.py.tmpl", data); err != nil {
+		return nil, err
+	}
+	p.Next = []string{
+		"Write upgrade() and downgrade() in " + path,
+		"Apply it: ods db upgrade --schema " + opts.Schema,
+	}
+	return p, nil
+}
+
+// Head returns the revision no migration in dir revises. It fails if there
+// is more than one, since a new migration would then need a merge first.
+func Head(dir string) (string, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.py"))
+	if err != nil {
+		return "", err
+	}
+	revisions := map[string]bool{}
+	revised := map[string]bool{}
+	for _, path := range paths {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		}
+		rev, downs := parseRevisions(string(data))
+		if rev == "" {
+			continue
+		}
+		revisions[rev] = true
+		for _, d := range downs {
+			revised[d] = true
+		}
+	}
+
+	var heads []string
+	for rev := range revisions {
+		if !revised[rev] {
+			heads = append(heads, rev)
+		}
+	}
+	sort.Strings(heads)
+	switch len(heads) {
+	case 0:
+		return "", fmt.Errorf("no migrations found in %s", dir)
+	case 1:
+		return heads[0], nil
+	}
+	return "", fmt.Errorf("%s has %d heads (%s); merge them with alembic merge first", dir, len(heads), strings.Join(heads, ", "))
+}
+
+// parseRevisions reads a migration's revision and the revisions it revises.
+// down_revision may be None, a string, or a tuple spanning several lines.
+func parseRevisions(src string) (string, []string) {
+	var rev string
+	var downs []string
+	lines := strings.Split(src, "\n")
+	for i := 0; i < len(lines); i++ {
+		if m := revisionRE.FindStringSubmatch(lines[i]); m != nil {
+			rev = m[1]
+			continue
+		}
+		m := downRevisionRE.FindStringSubmatch(lines[i])
+		if m == nil {
+			continue
+		}
+		value := m[1]
+		for strings.Contains(value, "(") && !strings.Contains(value, ")") && i+1 < len(lines) {
+			i++
+			value += lines[i]
+		}
+		for _, q := range quotedRE.FindAllStringSubmatch(value, -1) {
+			downs = append(downs, q[1])
+		}
+	}
+	return rev, downs
+}
+
+// Slug turns a migration message into a file name suffix the way Alembic
+// does: its words, lowercased and joined by underscores, cut at a word
+// boundary once longer than 40 characters.
+func Slug(message string) string {
+	slug := strings.ToLower(strings.Join(slugWordRE.FindAllString(message, -1), "_"))
+	if len(slug) > slugLength {
+		slug = slug[:slugLength]
+		if i := strings.LastIndex(slug, "_"); i > 0 {
+			slug = slug[:i]
+		}
+		slug += "_"
+	}
+	return slug
+}
+
+// newRevision returns a random 12-digit hex revision ID, like Alembic's.
+func newRevision() (string, error) {
+	b := make([]byte, 6)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
@@ -210,6 +210,62 @@ func GetGitDir() (string, error) {
 	return strings.TrimSpace(string(output)), nil
 }
 
+// Worktree is a working tree of the repository as git worktree list reports
+// it.
+type Worktree struct {
+	Path string
+	// Branch is the checked-out branch, empty when HEAD is detached.
+	Branch string
+}
+
+// ListWorktrees returns the repository's working trees, the main one first.
+func ListWorktrees() ([]Worktree, error) {
+	cmd := exec.Command("git", "worktree", "list", "--porcelain")
+	output, err := cmd.Output()
+	if err != nil {
+		return nil, fmt.Errorf("git worktree list failed: %w", err)
+	}
+	return parseWorktrees(string(output)), nil
+}
+
+func parseWorktrees(out string) []Worktree {
+	var worktrees []Worktree
+	for _, block := range strings.Split(strings.ReplaceAll(out, "\r\n", "\n"), "\n\n") {
+		var wt Worktree
+		for _, line := range strings.Split(block, "\n") {
+			if path, ok := strings.CutPrefix(line, "worktree "); ok {
+				wt.Path = filepath.FromSlash(path)
+			} else if ref, ok := strings.CutPrefix(line, "branch "); ok {
+				wt.Branch = strings.TrimPrefix(ref, "refs/heads/")
+			}
+		}
+		if wt.Path != "" {
+			worktrees = append(worktrees, wt)
+		}
+	}
+	return worktrees
+}
+
+// MainWorktreeRoot returns the root of the repository's main working tree,
+// the same from any of its worktrees.
+func MainWorktreeRoot() (string, error) {
+	worktrees, err := ListWorktrees()
+	if err != nil {
+		return "", err
+	}
+	if len(worktrees) == 0 {
+		return "", fmt.Errorf("git worktree list returned no working trees")
+	}
+	return worktrees[0].Path, nil
+}
+
+// RemoteBranchExists checks if origin has a branch of the given name, as of
+// the last fetch.
+func RemoteBranchExists(branchName string) bool {
+	cmd := exec.Command("git", "show-ref", "--verify", "--quiet", fmt.Sprintf("refs/remotes/origin/%s", branchName))
+	return cmd.Run() == nil
+}
+
 // IsCommitAppliedOnBranch checks if a commit (or its cherry-picked equivalent) exists on a branch.
 // First tries exact SHA match, then falls back to matching by commit subject line.
 func IsCommitAppliedOnBranch(commitSHA, branchName string) bool {
@@ -230,7 +286,7 @@ func IsCommitAppliedOnBranch(commitSHA, branchName string) bool {
 		return false
 	}
 	for _, line := range strings.Split(string(output), "\n") {
-		if line == subject {
+		if strings.TrimSuffix(line, "\r") == subject {
 			return true
 		}
 	}
@@ -182,3 +182,42 @@ func TestIsCommitAppliedOnBranch_NoFalsePositiveFromBody(t *testing.T) {
 		t.Error("should NOT match when subject only appears in body of another commit")
 	}
 }
+
+func TestListWorktrees(t *testing.T) {
+	repo := newTestRepo(t)
+
+	wtDir := filepath.Join(t.TempDir(), "onyx-feature-x")
+	repo.Git("worktree", "add", "-b", "feature/x", wtDir)
+	detached := filepath.Join(t.TempDir(), "detached")
+	repo.Git("worktree", "add", "--detach", detached)
+
+	worktrees, err := ListWorktrees()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(worktrees) != 3 {
+		t.Fatalf("expected 3 worktrees, got %+v", worktrees)
+	}
+	sameDir := func(a, b string) bool {
+		a, _ = filepath.EvalSymlinks(a)
+		b, _ = filepath.EvalSymlinks(b)
+		return a == b
+	}
+	if !sameDir(worktrees[0].Path, repo.Dir) || worktrees[0].Branch != "main" {
+		t.Errorf("expected the main worktree first, got %+v", worktrees[0])
+	}
+	if !sameDir(worktrees[1].Path, wtDir) || worktrees[1].Branch != "feature/x" {
+		t.Errorf("unexpected worktree %+v", worktrees[1])
+	}
+	if worktrees[2].Branch != "" {
+		t.Errorf("expected a detached worktree to have no branch, got %+v", worktrees[2])
+	}
+
+	root, err := MainWorktreeRoot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !sameDir(root, repo.Dir) {
+		t.Errorf("MainWorktreeRoot = %s, want %s", root, repo.Dir)
+	}
+}
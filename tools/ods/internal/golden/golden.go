@@ -0,0 +1,33 @@
+// Package golden compares test output with golden files. Run the tests
+// with UPDATE_GOLDEN=1 to rewrite the files after an intended change, and
+// review the diff: scripts rely on these formats.
+package golden
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// Assert checks got against testdata/<name>.golden in the package under
+// test.
+func Assert(t *testing.T, name string, got []byte) {
+	t.Helper()
+	path := filepath.Join("testdata", name+".golden")
+	if os.Getenv("UPDATE_GOLDEN") != "" {
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, got, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("%v (run with UPDATE_GOLDEN=1 to create it)", err)
+	}
+	if string(got) != string(want) {
+		t.Errorf("output differs from %s (run with UPDATE_GOLDEN=1 to accept it)\n--- got\n%s--- want\n%s", path, got, want)
+	}
+}
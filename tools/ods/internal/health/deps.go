@@ -0,0 +1,77 @@
+package health
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/docker"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/kube"
+)
+
+//go:embed probe.py
+var probeScript string
+
+// Result is the outcome of probing one dependency.
+type Result struct {
+	Name    string  `json:"name"`
+	OK      bool    `json:"ok"`
+	Skipped bool    `json:"skipped"`
+	Latency float64 `json:"latency_ms"`
+	Detail  string  `json:"detail"`
+}
+
+// ProbeCluster probes the API server, Postgres, Redis, Vespa, model servers
+// and Celery workers concurrently from pod, an api-server pod, with each
+// probe bounded by timeout.
+func ProbeCluster(c *kube.Cluster, pod string, timeout time.Duration) ([]Result, error) {
+	stdout, err := c.RunPython(pod, probeScript, timeoutArg(timeout))
+	if err != nil {
+		return nil, err
+	}
+	return parseResults(stdout)
+}
+
+// ProbeLocal is ProbeCluster for the local api_server container.
+func ProbeLocal(container string, timeout time.Duration) ([]Result, error) {
+	var stdout strings.Builder
+	if err := docker.RunPython(container, probeScript, nil, &stdout, timeoutArg(timeout)); err != nil {
+		return nil, err
+	}
+	return parseResults(stdout.String())
+}
+
+// Failed returns how many results are failures.
+func Failed(results []Result) int {
+	n := 0
+	for _, r := range results {
+		if !r.OK {
+			n++
+		}
+	}
+	return n
+}
+
+func timeoutArg(timeout time.Duration) string {
+	return strconv.FormatFloat(timeout.Seconds(), 'f', -1, 64)
+}
+
+func parseResults(stdout string) ([]Result, error) {
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	last := strings.TrimSpace(lines[len(lines)-1])
+	var r struct {
+		Status  string   `json:"status"`
+		Message string   `json:"message"`
+		Probes  []Result `json:"probes"`
+	}
+	if err := json.Unmarshal([]byte(last), &r); err != nil {
+		return nil, fmt.Errorf("unexpected output from probe script: %q", last)
+	}
+	if r.Status != "success" {
+		return nil, fmt.Errorf("%s", r.Message)
+	}
+	return r.Probes, nil
+}
@@ -0,0 +1,42 @@
+// Package health probes HTTP health endpoints of Onyx services.
+package health
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+)
+
+// DefaultTimeout bounds a single probe.
+const DefaultTimeout = 3 * time.Second
+
+// Probe issues a GET to url and returns nil if it answers with a 2xx status
+// within timeout.
+func Probe(url string, timeout time.Duration) error {
+	client := &http.Client{Timeout: timeout}
+	resp, err := client.Get(url)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("%s returned %s", url, resp.Status)
+	}
+	return nil
+}
+
+// WaitFor polls url every interval until it is healthy or timeout elapses,
+// returning the last probe error on timeout.
+func WaitFor(url string, timeout, interval time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		err := Probe(url, DefaultTimeout)
+		if err == nil {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("%s not healthy after %s: %w", url, timeout, err)
+		}
+		time.Sleep(interval)
+	}
+}
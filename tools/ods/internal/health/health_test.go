@@ -0,0 +1,76 @@
+package health
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestProbe(t *testing.T) {
+	ok := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer ok.Close()
+	if err := Probe(ok.URL, time.Second); err != nil {
+		t.Errorf("expected healthy, got %v", err)
+	}
+
+	bad := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer bad.Close()
+	if err := Probe(bad.URL, time.Second); err == nil {
+		t.Error("expected an error for a 503")
+	}
+}
+
+func TestWaitFor_becomesHealthy(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	if err := WaitFor(srv.URL, 5*time.Second, 10*time.Millisecond); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if calls.Load() < 3 {
+		t.Errorf("expected at least 3 probes, got %d", calls.Load())
+	}
+}
+
+func TestWaitFor_timesOut(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	if err := WaitFor(srv.URL, 50*time.Millisecond, 10*time.Millisecond); err == nil {
+		t.Error("expected a timeout error")
+	}
+}
+
+func TestParseResults(t *testing.T) {
+	stdout := "some warning\n" +
+		`{"status": "success", "probes": [{"name": "postgres", "ok": true, "skipped": false, "latency_ms": 12.5, "detail": "PostgreSQL 15.2"},` +
+		` {"name": "celery", "ok": false, "skipped": false, "latency_ms": 5000, "detail": "RuntimeError: no workers answered"}]}` + "\n"
+	results, err := parseResults(stdout)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(results) != 2 || results[0].Name != "postgres" || results[0].Latency != 12.5 || results[1].OK {
+		t.Errorf("parseResults = %+v", results)
+	}
+	if Failed(results) != 1 {
+		t.Errorf("Failed = %d, want 1", Failed(results))
+	}
+
+	if _, err := parseResults(`{"status": "error", "message": "boom"}`); err == nil || err.Error() != "boom" {
+		t.Errorf("parseResults(error) = %v, want boom", err)
+	}
+	if _, err := parseResults("Traceback ..."); err == nil {
+		t.Error("expected an error for non-JSON output")
+	}
+}
@@ -0,0 +1,171 @@
+"""Probe the services an Onyx deployment depends on, concurrently.
+
+Bundled with ods and piped into `python -` on an api-server pod (or the
+local api_server container) by `ods health`, so every probe runs with the
+backend's own configuration and network view.
+
+Usage:
+    python - <timeout seconds>
+
+Progress goes to stderr; the last line on stdout is a JSON object with
+"status" and "probes", one per dependency with "name", "ok", "skipped",
+"latency_ms" and "detail".
+"""
+
+from __future__ import annotations
+
+import json
+import os
+import sys
+import time
+from concurrent.futures import ThreadPoolExecutor
+from concurrent.futures import TimeoutError as FutureTimeout
+from typing import Any
+from typing import Callable
+
+
+class Skip(Exception):
+    """Raised by a probe whose dependency is not deployed."""
+
+
+def api_server(timeout: float) -> str:
+    import httpx
+
+    resp = httpx.get("http://localhost:8080/health", timeout=timeout)
+    resp.raise_for_status()
+    return f"HTTP {resp.status_code}"
+
+
+def postgres(timeout: float) -> str:
+    from sqlalchemy import text
+
+    from onyx.db.engine.sql_engine import SqlEngine
+    from onyx.db.engine.sql_engine import get_sqlalchemy_engine
+
+    SqlEngine.init_engine(pool_size=2, max_overflow=0)
+    with get_sqlalchemy_engine().connect() as conn:
+        conn.execute(text(f"SET statement_timeout = {int(timeout * 1000)}"))
+        version = conn.execute(text("SHOW server_version")).scalar()
+    return f"PostgreSQL {version}"
+
+
+def redis(timeout: float) -> str:
+    from onyx.redis.redis_pool import get_raw_redis_client
+
+    client = get_raw_redis_client()
+    client.ping()
+    return f"Redis {client.info('server').get('redis_version', '?')}"
+
+
+def vespa(timeout: float) -> str:
+    from onyx.document_index.vespa.shared_utils.utils import get_vespa_http_client
+    from onyx.document_index.vespa_constants import VESPA_APP_CONTAINER_URL
+
+    with get_vespa_http_client(timeout=int(timeout) or 1) as client:
+        resp = client.get(f"{VESPA_APP_CONTAINER_URL}/state/v1/health")
+    resp.raise_for_status()
+    status = resp.json().get("status", {}).get("code", "?")
+    if status != "up":
+        raise RuntimeError(f"Vespa reports {status}")
+    return status
+
+
+def model_server(host: str, port: int) -> Callable[[float], str]:
+    def probe(timeout: float) -> str:
+        import httpx
+
+        if host == "disabled":
+            raise Skip("model server disabled")
+        resp = httpx.get(f"http://{host}:{port}/api/health", timeout=timeout)
+        resp.raise_for_status()
+        return f"{host}:{port}"
+
+    return probe
+
+
+def celery(timeout: float) -> str:
+    from onyx.background.celery.versioned_apps.client import app
+
+    replies = app.control.ping(timeout=timeout)
+    workers = sorted(name for reply in replies for name in reply)
+    if not workers:
+        raise RuntimeError("no workers answered")
+    kinds = sorted({name.split("@")[0] for name in workers})
+    return f"{len(workers)} workers ({', '.join(kinds)})"
+
+
+def probes() -> dict[str, Callable[[float], str]]:
+    from shared_configs.configs import INDEXING_MODEL_SERVER_HOST
+    from shared_configs.configs import INDEXING_MODEL_SERVER_PORT
+    from shared_configs.configs import MODEL_SERVER_HOST
+    from shared_configs.configs import MODEL_SERVER_PORT
+
+    checks: dict[str, Callable[[float], str]] = {
+        "api_server": api_server,
+        "postgres": postgres,
+        "redis": redis,
+        "vespa": vespa,
+        "model_server": model_server(MODEL_SERVER_HOST, MODEL_SERVER_PORT),
+        "celery": celery,
+    }
+    if (INDEXING_MODEL_SERVER_HOST, INDEXING_MODEL_SERVER_PORT) != (
+        MODEL_SERVER_HOST,
+        MODEL_SERVER_PORT,
+    ):
+        checks["indexing_model_server"] = model_server(
+            INDEXING_MODEL_SERVER_HOST, INDEXING_MODEL_SERVER_PORT
+        )
+    return checks
+
+
+def timed(probe: Callable[[float], str], timeout: float) -> dict[str, Any]:
+    start = time.monotonic()
+    result: dict[str, Any] = {"ok": False, "skipped": False}
+    try:
+        result["detail"] = probe(timeout)
+        result["ok"] = True
+    except Skip as e:
+        result.update(ok=True, skipped=True, detail=str(e))
+    except Exception as e:
+        result["detail"] = f"{type(e).__name__}: {e}"
+    result["latency_ms"] = round((time.monotonic() - start) * 1000, 1)
+    return result
+
+
+def main() -> None:
+    if len(sys.argv) != 2:
+        print(json.dumps({"status": "error", "message": "Usage: python - <timeout seconds>"}))
+        sys.exit(1)
+    timeout = float(sys.argv[1])
+
+    try:
+        checks = probes()
+    except Exception as e:
+        print(json.dumps({"status": "error", "message": str(e)}))
+        return
+
+    results = []
+    # Hung probes are reported and abandoned rather than waited for.
+    pool = ThreadPoolExecutor(max_workers=len(checks))
+    futures = {name: pool.submit(timed, check, timeout) for name, check in checks.items()}
+    deadline = time.monotonic() + timeout + 1
+    for name, future in futures.items():
+        try:
+            result = future.result(timeout=max(deadline - time.monotonic(), 0))
+        except FutureTimeout:
+            result = {
+                "ok": False,
+                "skipped": False,
+                "latency_ms": timeout * 1000,
+                "detail": f"no answer within {timeout:g}s",
+            }
+        results.append({"name": name, **result})
+    pool.shutdown(wait=False, cancel_futures=True)
+
+    print(json.dumps({"status": "success", "probes": results}), flush=True)
+    # Exit without joining abandoned probe threads.
+    os._exit(0)
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,184 @@
+// Package history keeps a log of recent ods runs: the command line, how the
+// run ended and the tail of what it logged, so a failure can be explained
+// after the fact (ods explain --last). Entries are redacted before they are
+// written.
+package history
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/paths"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/redact"
+)
+
+// MaxEntries is how many runs the log keeps.
+const MaxEntries = 200
+
+// TailSize is how much of a run's log output is kept.
+const TailSize = 8 << 10
+
+// trimSize is the file size past which the log is trimmed to MaxEntries.
+const trimSize = 1 << 20
+
+// Entry is one ods run.
+type Entry struct {
+	Time time.Time `json:"time"`
+	// Args are the arguments after "ods".
+	Args       []string `json:"args"`
+	Dir        string   `json:"dir,omitempty"`
+	Version    string   `json:"version,omitempty"`
+	DurationMS int64    `json:"duration_ms"`
+	OK         bool     `json:"ok"`
+	// Error is the message the run failed with.
+	Error string `json:"error,omitempty"`
+	// Output is the end of what the run logged to stderr.
+	Output string `json:"output,omitempty"`
+}
+
+// Command returns the run's command line.
+func (e *Entry) Command() string {
+	return strings.TrimSpace("ods " + strings.Join(e.Args, " "))
+}
+
+// Record appends e to the history at paths.HistoryPath().
+func Record(e Entry) error {
+	return RecordTo(paths.HistoryPath(), e)
+}
+
+// RecordTo redacts e and appends it to the history at path, trimming the
+// file to the last MaxEntries runs once it grows large.
+func RecordTo(path string, e Entry) error {
+	if e.Time.IsZero() {
+		e.Time = time.Now().UTC()
+	}
+	args := make([]string, len(e.Args))
+	for i, a := range e.Args {
+		args[i] = redact.Line(a)
+	}
+	e.Args = args
+	e.Error = redact.String(e.Error)
+	e.Output = redact.String(e.Output)
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create history directory: %w", err)
+	}
+	data, err := json.Marshal(e)
+	if err != nil {
+		return fmt.Errorf("failed to marshal history entry: %w", err)
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return fmt.Errorf("failed to open history %s: %w", path, err)
+	}
+	_, err = f.Write(append(data, '\n'))
+	_ = f.Close()
+	if err != nil {
+		return fmt.Errorf("failed to write history %s: %w", path, err)
+	}
+
+	if info, err := os.Stat(path); err == nil && info.Size() > trimSize {
+		return trim(path)
+	}
+	return nil
+}
+
+// trim rewrites the history at path with only its last MaxEntries runs.
+func trim(path string) error {
+	entries, err := Read(path)
+	if err != nil {
+		return err
+	}
+	if len(entries) > MaxEntries {
+		entries = entries[len(entries)-MaxEntries:]
+	}
+	var buf strings.Builder
+	for _, e := range entries {
+		data, err := json.Marshal(e)
+		if err != nil {
+			return err
+		}
+		buf.Write(data)
+		buf.WriteByte('\n')
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, []byte(buf.String()), 0600); err != nil {
+		return fmt.Errorf("failed to write history %s: %w", tmp, err)
+	}
+	return os.Rename(tmp, path)
+}
+
+// Read returns the runs in the history at path, oldest first. A missing
+// file yields no entries.
+func Read(path string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to open history %s: %w", path, err)
+	}
+	defer func() { _ = f.Close() }()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64<<10), 1<<20)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			continue
+		}
+		var e Entry
+		if err := json.Unmarshal([]byte(line), &e); err != nil {
+			return nil, fmt.Errorf("failed to parse history %s: %w", path, err)
+		}
+		entries = append(entries, e)
+	}
+	return entries, scanner.Err()
+}
+
+// LastFailed returns the most recent run that failed, or nil.
+func LastFailed(entries []Entry) *Entry {
+	for i := len(entries) - 1; i >= 0; i-- {
+		if !entries[i].OK {
+			return &entries[i]
+		}
+	}
+	return nil
+}
+
+// Tail is a writer that keeps the last n bytes written to it.
+type Tail struct {
+	mu  sync.Mutex
+	buf []byte
+	n   int
+}
+
+// NewTail returns a Tail keeping n bytes.
+func NewTail(n int) *Tail {
+	return &Tail{n: n}
+}
+
+func (t *Tail) Write(p []byte) (int, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.buf = append(t.buf, p...)
+	if len(t.buf) > t.n {
+		t.buf = append(t.buf[:0], t.buf[len(t.buf)-t.n:]...)
+	}
+	return len(p), nil
+}
+
+// String returns what was kept.
+func (t *Tail) String() string {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return string(t.buf)
+}
@@ -0,0 +1,78 @@
+package history
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestRecordAndRead(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	if err := RecordTo(path, Entry{Args: []string{"health"}, OK: true}); err != nil {
+		t.Fatal(err)
+	}
+	err := RecordTo(path, Entry{
+		Args:   []string{"curl", "-H", "Authorization: Bearer abcdefghijklmnop"},
+		Error:  "request failed for jane@acme.com",
+		Output: "level=info msg=\"token=supersecretvalue\"\n",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := RecordTo(path, Entry{Args: []string{"whoami"}, OK: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	entries, err := Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 3 {
+		t.Fatalf("got %d entries, want 3", len(entries))
+	}
+	last := LastFailed(entries)
+	if last == nil || last.Args[0] != "curl" {
+		t.Fatalf("LastFailed = %+v", last)
+	}
+	for _, s := range []string{last.Command(), last.Error, last.Output} {
+		for _, secret := range []string{"abcdefghijklmnop", "jane@acme.com", "supersecretvalue"} {
+			if strings.Contains(s, secret) {
+				t.Errorf("%q was not redacted from %q", secret, s)
+			}
+		}
+	}
+
+	if entries, err := Read(filepath.Join(t.TempDir(), "missing")); err != nil || entries != nil {
+		t.Errorf("Read(missing) = %v, %v", entries, err)
+	}
+}
+
+func TestTrim(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "history.jsonl")
+	big := strings.Repeat("x", TailSize)
+	for i := 0; i < trimSize/TailSize+MaxEntries/2; i++ {
+		if err := RecordTo(path, Entry{Args: []string{"logs"}, Output: big}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries, err := Read(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) > MaxEntries {
+		t.Errorf("history kept %d entries, want at most %d", len(entries), MaxEntries)
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("temporary file left behind: %v", err)
+	}
+}
+
+func TestTail(t *testing.T) {
+	tail := NewTail(8)
+	_, _ = tail.Write([]byte("hello "))
+	_, _ = tail.Write([]byte("world"))
+	if got := tail.String(); got != "lo world" {
+		t.Errorf("Tail = %q, want %q", got, "lo world")
+	}
+}
@@ -0,0 +1,95 @@
+package hooks
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os/exec"
+	"slices"
+	"strings"
+)
+
+// DefaultBase is what a new branch's pushed changes are measured against.
+const DefaultBase = "origin/main"
+
+const zeroSHA = "0000000000000000000000000000000000000000"
+
+func git(root string, args ...string) (string, error) {
+	out, err := exec.Command("git", append([]string{"-C", root}, args...)...).Output()
+	if err != nil {
+		if ee, ok := err.(*exec.ExitError); ok && len(ee.Stderr) > 0 {
+			return "", fmt.Errorf("git %s: %s", strings.Join(args, " "), strings.TrimSpace(string(ee.Stderr)))
+		}
+		return "", fmt.Errorf("git %s: %w", strings.Join(args, " "), err)
+	}
+	return string(out), nil
+}
+
+// diffNames lists the files a git diff touches that still exist (deleted
+// files have nothing to lint).
+func diffNames(root string, args ...string) ([]string, error) {
+	out, err := git(root, append([]string{"diff", "--name-only", "--diff-filter=ACMR"}, args...)...)
+	if err != nil {
+		return nil, err
+	}
+	files := []string{}
+	for _, line := range strings.Split(out, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			files = append(files, line)
+		}
+	}
+	return files, nil
+}
+
+// StagedFiles lists the files staged for commit.
+func StagedFiles(root string) ([]string, error) {
+	return diffNames(root, "--cached")
+}
+
+// FilesSince lists the files changed between the merge base of ref and HEAD
+// and the working tree, so uncommitted edits count too.
+func FilesSince(root, ref string) ([]string, error) {
+	base, err := git(root, "merge-base", ref, "HEAD")
+	if err != nil {
+		return nil, err
+	}
+	return diffNames(root, strings.TrimSpace(base))
+}
+
+// PushedFiles lists the files changed by the refs git passes a pre-push
+// hook on stdin ("<local ref> <local sha> <remote ref> <remote sha>" per
+// line). A new branch is measured from where it left DefaultBase.
+func PushedFiles(root string, stdin io.Reader) ([]string, error) {
+	var files []string
+	scanner := bufio.NewScanner(stdin)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) != 4 || fields[1] == zeroSHA {
+			continue // malformed, or a branch deletion
+		}
+		local, remote := fields[1], fields[3]
+		base := remote
+		if remote == zeroSHA || !hasCommit(root, remote) {
+			mb, err := git(root, "merge-base", local, DefaultBase)
+			if err != nil {
+				return nil, err
+			}
+			base = strings.TrimSpace(mb)
+		}
+		changed, err := diffNames(root, base, local)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, changed...)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	slices.Sort(files)
+	return slices.Compact(append([]string{}, files...)), nil
+}
+
+func hasCommit(root, sha string) bool {
+	_, err := git(root, "cat-file", "-e", sha+"^{commit}")
+	return err == nil
+}
@@ -0,0 +1,238 @@
+// Package hooks runs the linters and tests for the parts of the repository a
+// change touches, and installs the git hooks that run them on commit and
+// push.
+package hooks
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+)
+
+// Step is one linter or test command for an area.
+type Step struct {
+	Name string
+	// Dir is where the command runs, relative to the repository root.
+	Dir string
+	// Args run the step over the whole area.
+	Args []string
+	// FileArgs, if set, run the step over just the changed files with one
+	// of Exts, appended relative to Dir; the step is skipped when none of
+	// them changed. Without FileArgs a touched area runs Args.
+	FileArgs []string
+	Exts     []string
+	// FailOnOutput treats any output as a failure, for tools such as
+	// gofmt -l that exit 0 when they find problems.
+	FailOnOutput bool
+}
+
+// Area is a part of the repository with its own toolchain.
+type Area struct {
+	Name string
+	// Prefix selects the area's files, relative to the repository root.
+	Prefix string
+	Lint   []Step
+	Test   []Step
+}
+
+var (
+	pyExts  = []string{".py"}
+	tsExts  = []string{".ts", ".tsx", ".js", ".jsx", ".mjs"}
+	fmtExts = []string{".ts", ".tsx", ".js", ".jsx", ".mjs", ".css", ".json", ".md"}
+	goExts  = []string{".go"}
+)
+
+// Areas are the areas lint and test know about, mirroring the checks in
+// .pre-commit-config.yaml and CI.
+var Areas = []Area{
+	{
+		Name:   "backend",
+		Prefix: "backend/",
+		Lint: []Step{
+			{Name: "ruff check", Dir: "backend", Args: []string{"uv", "run", "ruff", "check", "."}, FileArgs: []string{"uv", "run", "ruff", "check"}, Exts: pyExts},
+			{Name: "ruff format", Dir: "backend", Args: []string{"uv", "run", "ruff", "format", "--check", "."}, FileArgs: []string{"uv", "run", "ruff", "format", "--check"}, Exts: pyExts},
+		},
+		Test: []Step{
+			{Name: "pytest unit", Dir: "backend", Args: []string{"uv", "run", "pytest", "-q", "-x", "tests/unit"}},
+		},
+	},
+	{
+		Name:   "web",
+		Prefix: "web/",
+		Lint: []Step{
+			{Name: "oxlint", Dir: "web", Args: []string{"bunx", "oxlint"}, FileArgs: []string{"bunx", "oxlint"}, Exts: tsExts},
+			{Name: "oxfmt", Dir: "web", Args: []string{"bunx", "oxfmt", "--check", "src"}, FileArgs: []string{"bunx", "oxfmt", "--check"}, Exts: fmtExts},
+		},
+		Test: []Step{
+			{Name: "jest", Dir: "web", Args: []string{"bunx", "jest", "--bail"}, FileArgs: []string{"bunx", "jest", "--bail", "--passWithNoTests", "--findRelatedTests"}, Exts: tsExts},
+		},
+	},
+	{
+		Name:   "tools",
+		Prefix: "tools/ods/",
+		Lint: []Step{
+			{Name: "gofmt", Dir: "tools/ods", Args: []string{"gofmt", "-l", "."}, FileArgs: []string{"gofmt", "-l"}, Exts: goExts, FailOnOutput: true},
+			{Name: "go vet", Dir: "tools/ods", Args: []string{"go", "vet", "./..."}},
+		},
+		Test: []Step{
+			{Name: "go test", Dir: "tools/ods", Args: []string{"go", "test", "./..."}},
+		},
+	},
+}
+
+// AreaNames lists the areas' names.
+func AreaNames() []string {
+	names := make([]string, len(Areas))
+	for i, a := range Areas {
+		names[i] = a.Name
+	}
+	return names
+}
+
+// Select returns the named areas, or all of them if names is empty.
+func Select(names []string) ([]Area, error) {
+	if len(names) == 0 {
+		return Areas, nil
+	}
+	var areas []Area
+	for _, name := range names {
+		i := slices.IndexFunc(Areas, func(a Area) bool { return a.Name == name })
+		if i < 0 {
+			return nil, fmt.Errorf("unknown area %q; choose from %s", name, strings.Join(AreaNames(), ", "))
+		}
+		areas = append(areas, Areas[i])
+	}
+	return areas, nil
+}
+
+// Command is a step ready to run.
+type Command struct {
+	Area string
+	Step Step
+	Args []string
+}
+
+// Plan returns the commands to run for kind ("lint" or "test") in areas. If
+// changed is nil every step runs over its whole area; otherwise only areas
+// with changed files run, and file-aware steps get just those files. An
+// empty plan means nothing relevant changed.
+func Plan(areas []Area, kind string, changed []string) []Command {
+	var cmds []Command
+	for _, a := range areas {
+		steps := a.Lint
+		if kind == "test" {
+			steps = a.Test
+		}
+		if changed == nil {
+			for _, s := range steps {
+				cmds = append(cmds, Command{Area: a.Name, Step: s, Args: s.Args})
+			}
+			continue
+		}
+
+		var files []string
+		for _, f := range changed {
+			if strings.HasPrefix(f, a.Prefix) {
+				files = append(files, f)
+			}
+		}
+		if len(files) == 0 {
+			continue
+		}
+		for _, s := range steps {
+			if s.FileArgs == nil {
+				cmds = append(cmds, Command{Area: a.Name, Step: s, Args: s.Args})
+				continue
+			}
+			args := slices.Clone(s.FileArgs)
+			for _, f := range files {
+				if slices.Contains(s.Exts, filepath.Ext(f)) {
+					args = append(args, relTo(s.Dir, f))
+				}
+			}
+			if len(args) > len(s.FileArgs) {
+				cmds = append(cmds, Command{Area: a.Name, Step: s, Args: args})
+			}
+		}
+	}
+	return cmds
+}
+
+// relTo makes a repository-relative path relative to dir.
+func relTo(dir, path string) string {
+	if dir == "" {
+		return path
+	}
+	return strings.TrimPrefix(path, strings.TrimSuffix(dir, "/")+"/")
+}
+
+// Run runs the commands from root, streaming their output to out, and
+// returns an error naming the ones that failed. Every command runs, so one
+// failure does not hide the next.
+func Run(root string, cmds []Command, out io.Writer) error {
+	var failed []string
+	for _, c := range cmds {
+		start := time.Now()
+		fmt.Fprintf(out, "==> %s: %s\n", c.Area, c.Step.Name)
+		cmd := exec.Command(c.Args[0], c.Args[1:]...)
+		cmd.Dir = filepath.Join(root, filepath.FromSlash(c.Step.Dir))
+		var captured bytes.Buffer
+		cmd.Stdout = io.MultiWriter(out, &captured)
+		cmd.Stderr = io.MultiWriter(out, &captured)
+		cmd.Stdin = nil
+		err := cmd.Run()
+		if err == nil && c.Step.FailOnOutput && strings.TrimSpace(captured.String()) != "" {
+			err = fmt.Errorf("reported problems")
+		}
+		if err != nil {
+			fmt.Fprintf(out, "FAIL %s: %s (%v)\n", c.Area, c.Step.Name, err)
+			failed = append(failed, c.Area+": "+c.Step.Name)
+			continue
+		}
+		fmt.Fprintf(out, "ok   %s: %s (%s)\n", c.Area, c.Step.Name, time.Since(start).Round(100*time.Millisecond))
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("%d check(s) failed: %s", len(failed), strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// SkipEnv names the environment variable that bypasses the hooks: "1" (or
+// "all") skips them, and a comma-separated list of areas skips just those.
+const SkipEnv = "ODS_SKIP_HOOKS"
+
+// Skipped returns the areas SkipEnv's value skips, and whether it skips
+// everything.
+func Skipped(value string) (areas []string, all bool) {
+	for _, part := range strings.Split(value, ",") {
+		part = strings.TrimSpace(part)
+		switch part {
+		case "":
+		case "1", "all", "true":
+			return nil, true
+		default:
+			areas = append(areas, part)
+		}
+	}
+	return areas, false
+}
+
+// Without drops the named areas.
+func Without(areas []Area, skip []string) []Area {
+	return slices.DeleteFunc(slices.Clone(areas), func(a Area) bool { return slices.Contains(skip, a.Name) })
+}
+
+// SkipFromEnv applies SkipEnv to areas.
+func SkipFromEnv(areas []Area) []Area {
+	skip, all := Skipped(os.Getenv(SkipEnv))
+	if all {
+		return nil
+	}
+	return Without(areas, skip)
+}
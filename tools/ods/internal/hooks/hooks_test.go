@@ -0,0 +1,164 @@
+package hooks
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func planArgs(cmds []Command) []string {
+	var out []string
+	for _, c := range cmds {
+		out = append(out, c.Area+": "+strings.Join(c.Args, " "))
+	}
+	return out
+}
+
+func TestPlan(t *testing.T) {
+	changed := []string{"backend/onyx/main.py", "backend/README.md", "web/src/app/page.tsx", "web/src/app/globals.css", "docs/x.md"}
+	got := planArgs(Plan(Areas, "lint", changed))
+	want := []string{
+		"backend: uv run ruff check onyx/main.py",
+		"backend: uv run ruff format --check onyx/main.py",
+		"web: bunx oxlint src/app/page.tsx",
+		"web: bunx oxfmt --check src/app/page.tsx src/app/globals.css",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("lint plan = %q, want %q", got, want)
+	}
+
+	got = planArgs(Plan(Areas, "test", []string{"tools/ods/cmd/root.go", "backend/README.md"}))
+	want = []string{"backend: uv run pytest -q -x tests/unit", "tools: go test ./..."}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("test plan = %q, want %q", got, want)
+	}
+
+	if cmds := Plan(Areas, "lint", []string{"docs/x.md", ".github/workflows/ci.yml"}); len(cmds) != 0 {
+		t.Errorf("plan for unrelated changes = %q", planArgs(cmds))
+	}
+	if cmds := Plan(Areas, "lint", nil); len(cmds) != 6 {
+		t.Errorf("full lint plan has %d commands", len(cmds))
+	}
+}
+
+func TestSkipped(t *testing.T) {
+	if _, all := Skipped("1"); !all {
+		t.Error("1 should skip everything")
+	}
+	areas, all := Skipped(" web, backend ,")
+	if all || !reflect.DeepEqual(areas, []string{"web", "backend"}) {
+		t.Errorf("Skipped() = %v, %v", areas, all)
+	}
+	if left := Without(Areas, areas); len(left) != 1 || left[0].Name != "tools" {
+		t.Errorf("Without() = %v", left)
+	}
+	if _, err := Select([]string{"mobile"}); err == nil {
+		t.Error("expected an error for an unknown area")
+	}
+}
+
+func TestInstallChainsAndRestores(t *testing.T) {
+	dir := t.TempDir()
+	existing := "#!/bin/sh\npre-commit run\n"
+	if err := os.WriteFile(filepath.Join(dir, "pre-commit"), []byte(existing), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	chained, err := Install(dir, "pre-commit")
+	if err != nil || !chained {
+		t.Fatalf("Install() = %v, %v", chained, err)
+	}
+	if !IsOurs(filepath.Join(dir, "pre-commit")) {
+		t.Error("the installed hook is not marked")
+	}
+	// Reinstalling updates the hook without chaining it to itself.
+	if chained, err := Install(dir, "pre-commit"); err != nil || chained {
+		t.Fatalf("reinstall = %v, %v", chained, err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "pre-commit.pre-ods")); string(data) != existing {
+		t.Errorf("chained hook = %q", data)
+	}
+
+	if removed, err := Uninstall(dir, "pre-commit"); err != nil || !removed {
+		t.Fatalf("Uninstall() = %v, %v", removed, err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "pre-commit")); string(data) != existing {
+		t.Errorf("restored hook = %q", data)
+	}
+	if removed, _ := Uninstall(dir, "pre-commit"); removed {
+		t.Error("uninstall removed a hook ods did not write")
+	}
+}
+
+func TestScriptSkipsWithoutOds(t *testing.T) {
+	dir := t.TempDir()
+	if _, err := Install(dir, "pre-push"); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "pre-push.pre-ods"), []byte("#!/bin/sh\ncat > \"$0.stdin\"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	cmd := exec.Command(filepath.Join(dir, "pre-push"), "origin")
+	cmd.Env = []string{"PATH=/usr/bin:/bin"}
+	cmd.Stdin = strings.NewReader("refs/heads/a 1 refs/heads/a 2\n")
+	out, err := cmd.CombinedOutput()
+	if err != nil || !strings.Contains(string(out), "ods not found") {
+		t.Fatalf("hook = %s, %v", out, err)
+	}
+	if data, _ := os.ReadFile(filepath.Join(dir, "pre-push.pre-ods.stdin")); string(data) != "refs/heads/a 1 refs/heads/a 2\n" {
+		t.Errorf("chained hook stdin = %q", data)
+	}
+}
+
+func TestChangedFiles(t *testing.T) {
+	root := t.TempDir()
+	run := func(args ...string) string {
+		t.Helper()
+		out, err := git(root, args...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return strings.TrimSpace(out)
+	}
+	write := func(name, content string) {
+		t.Helper()
+		path := filepath.Join(root, name)
+		_ = os.MkdirAll(filepath.Dir(path), 0o755)
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	run("init", "-q", "-b", "main")
+	run("config", "user.email", "t@example.com")
+	run("config", "user.name", "t")
+	write("backend/a.py", "a\n")
+	write("web/gone.ts", "x\n")
+	run("add", ".")
+	run("commit", "-qm", "base")
+	base := run("rev-parse", "HEAD")
+
+	write("backend/a.py", "b\n")
+	write("tools/ods/main.go", "package main\n")
+	run("rm", "-q", "web/gone.ts")
+	run("add", "backend/a.py")
+
+	staged, err := StagedFiles(root)
+	if err != nil || !reflect.DeepEqual(staged, []string{"backend/a.py"}) {
+		t.Errorf("StagedFiles() = %v, %v", staged, err)
+	}
+	run("add", ".")
+	run("commit", "-qm", "change")
+	head := run("rev-parse", "HEAD")
+
+	pushed, err := PushedFiles(root, strings.NewReader("refs/heads/main "+head+" refs/heads/main "+base+"\n"))
+	if err != nil || !reflect.DeepEqual(pushed, []string{"backend/a.py", "tools/ods/main.go"}) {
+		t.Errorf("PushedFiles() = %v, %v", pushed, err)
+	}
+	since, err := FilesSince(root, base)
+	if err != nil || !reflect.DeepEqual(since, pushed) {
+		t.Errorf("FilesSince() = %v, %v", since, err)
+	}
+}
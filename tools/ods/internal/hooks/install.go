@@ -0,0 +1,115 @@
+package hooks
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Hooks maps the git hooks ods installs to the checks they run: lint on the
+// staged files before a commit, tests on the pushed changes before a push.
+var Hooks = map[string]string{
+	"pre-commit": "lint",
+	"pre-push":   "test",
+}
+
+// HookNames lists the hooks ods installs, in the order they fire.
+var HookNames = []string{"pre-commit", "pre-push"}
+
+// marker identifies a hook script ods wrote.
+const marker = "# Installed by `ods hooks install`"
+
+// chainSuffix is appended to a hook ods displaces; the ods hook runs it
+// first and uninstall puts it back.
+const chainSuffix = ".pre-ods"
+
+// Script returns the hook script for hook. It exits early without starting
+// ods when SkipEnv skips everything, and never blocks git when ods is not
+// on PATH.
+func Script(hook string) string {
+	var b strings.Builder
+	b.WriteString("#!/bin/sh\n")
+	b.WriteString(marker + "; `ods hooks uninstall` removes it.\n")
+	fmt.Fprintf(&b, "# Bypass with `git %s --no-verify`, or %s=1 (or a list of areas).\n", map[string]string{"pre-commit": "commit", "pre-push": "push"}[hook], SkipEnv)
+	if hook == "pre-push" {
+		// git writes the pushed refs to stdin; both hooks need them.
+		b.WriteString("input=$(cat)\n")
+		fmt.Fprintf(&b, "if [ -x \"$0%s\" ]; then printf '%%s\\n' \"$input\" | \"$0%s\" \"$@\" || exit $?; fi\n", chainSuffix, chainSuffix)
+	} else {
+		fmt.Fprintf(&b, "if [ -x \"$0%s\" ]; then \"$0%s\" \"$@\" || exit $?; fi\n", chainSuffix, chainSuffix)
+	}
+	fmt.Fprintf(&b, "case \"$%s\" in 1|all|true) exit 0 ;; esac\n", SkipEnv)
+	b.WriteString("if ! command -v ods >/dev/null 2>&1; then\n")
+	fmt.Fprintf(&b, "  echo \"ods not found on PATH; skipping the %s checks\" >&2\n", hook)
+	b.WriteString("  exit 0\nfi\n")
+	if hook == "pre-push" {
+		fmt.Fprintf(&b, "printf '%%s\\n' \"$input\" | exec ods hooks run %s \"$@\"\n", hook)
+	} else {
+		fmt.Fprintf(&b, "exec ods hooks run %s \"$@\"\n", hook)
+	}
+	return b.String()
+}
+
+// IsOurs reports whether the hook script at path was written by ods.
+func IsOurs(path string) bool {
+	data, err := os.ReadFile(path)
+	return err == nil && strings.Contains(string(data), marker)
+}
+
+// Install writes hook into dir. A hook ods did not write is kept as
+// <hook>.pre-ods and run first, so tools such as pre-commit keep working.
+// It reports whether an existing hook was chained.
+func Install(dir, hook string) (chained bool, err error) {
+	if _, ok := Hooks[hook]; !ok {
+		return false, fmt.Errorf("unknown hook %q", hook)
+	}
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return false, err
+	}
+	path := filepath.Join(dir, hook)
+	if _, err := os.Stat(path); err == nil && !IsOurs(path) {
+		if _, err := os.Stat(path + chainSuffix); err == nil {
+			return false, fmt.Errorf("%s exists and so does %s; move one aside first", path, hook+chainSuffix)
+		}
+		if err := os.Rename(path, path+chainSuffix); err != nil {
+			return false, err
+		}
+		chained = true
+	}
+	if err := os.WriteFile(path, []byte(Script(hook)), 0o755); err != nil {
+		return chained, err
+	}
+	return chained, nil
+}
+
+// Uninstall removes hook from dir if ods wrote it, restoring the hook it
+// chained. It reports whether anything was removed.
+func Uninstall(dir, hook string) (bool, error) {
+	path := filepath.Join(dir, hook)
+	if !IsOurs(path) {
+		return false, nil
+	}
+	if err := os.Remove(path); err != nil {
+		return false, err
+	}
+	if err := os.Rename(path+chainSuffix, path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return true, err
+	}
+	return true, nil
+}
+
+// Dir returns the hooks directory of the repository at root, honouring
+// core.hooksPath and worktrees.
+func Dir(root string) (string, error) {
+	out, err := git(root, "rev-parse", "--git-path", "hooks")
+	if err != nil {
+		return "", err
+	}
+	dir := strings.TrimSpace(out)
+	if !filepath.IsAbs(dir) {
+		dir = filepath.Join(root, dir)
+	}
+	return dir, nil
+}
@@ -0,0 +1,145 @@
+package i18n
+
+import (
+	"slices"
+	"sort"
+	"strings"
+)
+
+// Report is the drift between the code and the locale files.
+type Report struct {
+	Source string
+	// Undefined are keys the code uses that the source locale lacks.
+	Undefined []Usage
+	// Unused are source keys the code never uses.
+	Unused []string
+	// Missing are, per locale, source keys with no translation (absent or
+	// empty). The source locale is included: extract adds keys empty.
+	Missing map[string][]string
+	// Extra are, per locale, keys the source locale does not have.
+	Extra map[string][]string
+	// Placeholders are, per locale, keys whose translation interpolates
+	// different arguments than the source message.
+	Placeholders map[string][]string
+}
+
+// Problems counts the report's findings.
+func (r *Report) Problems() int {
+	n := len(r.Undefined) + len(r.Unused)
+	for _, m := range []map[string][]string{r.Missing, r.Extra, r.Placeholders} {
+		for _, keys := range m {
+			n += len(keys)
+		}
+	}
+	return n
+}
+
+// Check compares the code's keys with the locale files. locales must include
+// the source locale, possibly empty.
+func Check(scan *Scan, locales map[string]Catalog, source string) *Report {
+	r := &Report{
+		Source:       source,
+		Missing:      map[string][]string{},
+		Extra:        map[string][]string{},
+		Placeholders: map[string][]string{},
+	}
+	src := locales[source]
+
+	used := map[string]bool{}
+	for _, u := range scan.Usages {
+		used[u.Key] = true
+		if _, ok := src[u.Key]; !ok {
+			r.Undefined = append(r.Undefined, u)
+		}
+	}
+	for _, key := range src.Keys() {
+		if !used[key] && !underPrefix(key, scan.Prefixes) {
+			r.Unused = append(r.Unused, key)
+		}
+	}
+
+	for locale, c := range locales {
+		for _, key := range src.Keys() {
+			msg, ok := c[key]
+			if !ok || strings.TrimSpace(msg) == "" {
+				r.Missing[locale] = append(r.Missing[locale], key)
+				continue
+			}
+			if locale != source && src[key] != "" && !slices.Equal(placeholders(msg), placeholders(src[key])) {
+				r.Placeholders[locale] = append(r.Placeholders[locale], key)
+			}
+		}
+		if locale == source {
+			continue
+		}
+		for _, key := range c.Keys() {
+			if _, ok := src[key]; !ok {
+				r.Extra[locale] = append(r.Extra[locale], key)
+			}
+		}
+	}
+	return r
+}
+
+// Locales returns the report's locales with findings, in order.
+func (r *Report) Locales() []string {
+	seen := map[string]bool{}
+	for _, m := range []map[string][]string{r.Missing, r.Extra, r.Placeholders} {
+		for locale, keys := range m {
+			if len(keys) > 0 {
+				seen[locale] = true
+			}
+		}
+	}
+	locales := make([]string, 0, len(seen))
+	for l := range seen {
+		locales = append(locales, l)
+	}
+	sort.Strings(locales)
+	return locales
+}
+
+// Extract adds the keys the code uses but src lacks, with empty messages for
+// a writer to fill in, and with prune removes the keys the code never uses.
+// It returns the keys added and removed.
+func Extract(scan *Scan, src Catalog, prune bool) (added, removed []string) {
+	used := map[string]bool{}
+	for _, u := range scan.Usages {
+		used[u.Key] = true
+		if _, ok := src[u.Key]; !ok {
+			src[u.Key] = ""
+			added = append(added, u.Key)
+		}
+	}
+	if prune {
+		for _, key := range src.Keys() {
+			if !used[key] && !underPrefix(key, scan.Prefixes) {
+				delete(src, key)
+				removed = append(removed, key)
+			}
+		}
+	}
+	sort.Strings(added)
+	return added, removed
+}
+
+// Prune removes from c the keys src does not have, returning them.
+func Prune(c, src Catalog) []string {
+	var removed []string
+	for _, key := range c.Keys() {
+		if _, ok := src[key]; !ok {
+			delete(c, key)
+			removed = append(removed, key)
+		}
+	}
+	return removed
+}
+
+func underPrefix(key string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if p != "" && strings.HasPrefix(key, p) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,210 @@
+package i18n
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/config"
+)
+
+// CrowdinTokenEnv holds the Crowdin personal access token.
+const CrowdinTokenEnv = "CROWDIN_API_TOKEN"
+
+// DefaultCrowdinURL is the crowdin.com API; Crowdin Enterprise organizations
+// have their own (https://<org>.api.crowdin.com/api/v2).
+const DefaultCrowdinURL = "https://api.crowdin.com/api/v2"
+
+const requestTimeout = 60 * time.Second
+
+// Service is a translation service locale files are exchanged with.
+type Service interface {
+	// Push uploads the source locale file, so new and changed messages
+	// reach translators.
+	Push(name string, data []byte) error
+	// Pull downloads the file translated into locale.
+	Pull(name, locale string) ([]byte, error)
+}
+
+// ErrNotConfigured means no translation service is configured.
+var ErrNotConfigured = errors.New("no translation service configured (set i18n.provider in the ods config)")
+
+// NewService returns the configured translation service, reading credentials
+// from the environment.
+func NewService(cfg config.I18nConfig) (Service, error) {
+	switch cfg.Provider {
+	case "":
+		return nil, ErrNotConfigured
+	case "crowdin":
+		token := os.Getenv(CrowdinTokenEnv)
+		if cfg.CrowdinProjectID == 0 || token == "" {
+			return nil, fmt.Errorf("crowdin needs i18n.crowdin_project_id in the ods config and %s set", CrowdinTokenEnv)
+		}
+		base := cfg.CrowdinURL
+		if base == "" {
+			base = DefaultCrowdinURL
+		}
+		return &Crowdin{BaseURL: strings.TrimSuffix(base, "/"), Token: token, ProjectID: cfg.CrowdinProjectID}, nil
+	default:
+		return nil, fmt.Errorf("unknown translation provider %q (expected crowdin)", cfg.Provider)
+	}
+}
+
+// Crowdin exchanges files through the Crowdin API v2. Files are matched by
+// name in the project's root; locales are Crowdin language IDs (de, pt-BR).
+type Crowdin struct {
+	BaseURL   string
+	Token     string
+	ProjectID int
+}
+
+// Push uploads data as the project's file called name, adding the file if
+// the project does not have it yet.
+func (c *Crowdin) Push(name string, data []byte) error {
+	fileID, err := c.fileID(name)
+	if err != nil {
+		return err
+	}
+
+	req, err := c.request(http.MethodPost, "/storages", bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Crowdin-API-FileName", url.PathEscape(name))
+	req.Header.Set("Content-Type", "application/json")
+	var storage struct {
+		Data struct {
+			ID int `json:"id"`
+		} `json:"data"`
+	}
+	if err := c.do(req, &storage); err != nil {
+		return err
+	}
+
+	if fileID == 0 {
+		return c.doJSON(http.MethodPost, c.projectPath("/files"), map[string]any{"storageId": storage.Data.ID, "name": name}, nil)
+	}
+	return c.doJSON(http.MethodPut, c.projectPath(fmt.Sprintf("/files/%d", fileID)), map[string]any{"storageId": storage.Data.ID}, nil)
+}
+
+// Pull builds and downloads the project's file called name translated into
+// locale.
+func (c *Crowdin) Pull(name, locale string) ([]byte, error) {
+	fileID, err := c.fileID(name)
+	if err != nil {
+		return nil, err
+	}
+	if fileID == 0 {
+		return nil, fmt.Errorf("crowdin project %d has no file %s; push it first", c.ProjectID, name)
+	}
+	var build struct {
+		Data struct {
+			URL string `json:"url"`
+		} `json:"data"`
+	}
+	path := c.projectPath(fmt.Sprintf("/translations/builds/files/%d", fileID))
+	if err := c.doJSON(http.MethodPost, path, map[string]any{"targetLanguageId": locale}, &build); err != nil {
+		return nil, err
+	}
+	if build.Data.URL == "" {
+		return nil, fmt.Errorf("crowdin returned no download for %s (%s)", name, locale)
+	}
+
+	// The download URL is pre-signed; it must not carry the API token.
+	req, err := http.NewRequest(http.MethodGet, build.Data.URL, nil)
+	if err != nil {
+		return nil, err
+	}
+	return send(req)
+}
+
+// fileID returns the ID of the project file called name, or 0 if there is
+// none.
+func (c *Crowdin) fileID(name string) (int, error) {
+	var files struct {
+		Data []struct {
+			Data struct {
+				ID   int    `json:"id"`
+				Name string `json:"name"`
+				Path string `json:"path"`
+			} `json:"data"`
+		} `json:"data"`
+	}
+	req, err := c.request(http.MethodGet, c.projectPath("/files?limit=500"), nil)
+	if err != nil {
+		return 0, err
+	}
+	if err := c.do(req, &files); err != nil {
+		return 0, err
+	}
+	for _, f := range files.Data {
+		if f.Data.Path == "/"+name || (f.Data.Path == "" && f.Data.Name == name) {
+			return f.Data.ID, nil
+		}
+	}
+	return 0, nil
+}
+
+func (c *Crowdin) projectPath(path string) string {
+	return fmt.Sprintf("/projects/%d%s", c.ProjectID, path)
+}
+
+func (c *Crowdin) request(method, path string, body io.Reader) (*http.Request, error) {
+	req, err := http.NewRequest(method, c.BaseURL+path, body)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.Token)
+	return req, nil
+}
+
+func (c *Crowdin) doJSON(method, path string, body, out any) error {
+	payload, err := json.Marshal(body)
+	if err != nil {
+		return err
+	}
+	req, err := c.request(method, path, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	return c.do(req, out)
+}
+
+func (c *Crowdin) do(req *http.Request, out any) error {
+	data, err := send(req)
+	if err != nil {
+		return err
+	}
+	if out == nil {
+		return nil
+	}
+	if err := json.Unmarshal(data, out); err != nil {
+		return fmt.Errorf("unexpected crowdin response: %w", err)
+	}
+	return nil
+}
+
+func send(req *http.Request) ([]byte, error) {
+	resp, err := (&http.Client{Timeout: requestTimeout}).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s request failed: %w", req.URL.Host, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 32<<20))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg := strings.TrimSpace(string(data))
+		if len(msg) > 300 {
+			msg = msg[:300]
+		}
+		return nil, fmt.Errorf("%s returned %s: %s", req.URL.Host, resp.Status, msg)
+	}
+	return data, nil
+}
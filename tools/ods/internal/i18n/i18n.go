@@ -0,0 +1,168 @@
+// Package i18n keeps the web app's translations in step with its code: it
+// finds the message keys the code uses, compares them with the locale files,
+// and exchanges those files with a translation service.
+//
+// Locale files are JSON, one per locale (en.json, de.json, ...), holding
+// messages nested by key segment: t("settings.title") reads
+// {"settings": {"title": "..."}}.
+package i18n
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Defaults for the web app.
+const (
+	DefaultLocalesDir = "web/src/locales"
+	DefaultSourceDir  = "web/src"
+	DefaultSource     = "en"
+)
+
+// Catalog maps dotted message keys to messages.
+type Catalog map[string]string
+
+// LoadCatalog reads a locale file. A missing file is an empty catalog.
+func LoadCatalog(path string) (Catalog, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return Catalog{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return ParseCatalog(data)
+}
+
+// ParseCatalog reads a locale file's content.
+func ParseCatalog(data []byte) (Catalog, error) {
+	var tree map[string]any
+	if err := json.Unmarshal(data, &tree); err != nil {
+		return nil, err
+	}
+	c := Catalog{}
+	if err := c.flatten("", tree); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c Catalog) flatten(prefix string, tree map[string]any) error {
+	for k, v := range tree {
+		key := k
+		if prefix != "" {
+			key = prefix + "." + k
+		}
+		switch v := v.(type) {
+		case string:
+			c[key] = v
+		case map[string]any:
+			if err := c.flatten(key, v); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("%s: messages must be strings or objects, not %T", key, v)
+		}
+	}
+	return nil
+}
+
+// Marshal renders the catalog as a locale file, nested and sorted by key.
+func (c Catalog) Marshal() ([]byte, error) {
+	tree := map[string]any{}
+	for _, key := range c.Keys() {
+		node := tree
+		parts := strings.Split(key, ".")
+		for _, part := range parts[:len(parts)-1] {
+			child, ok := node[part].(map[string]any)
+			if !ok {
+				if _, isMessage := node[part]; isMessage {
+					return nil, fmt.Errorf("%s is both a message and a prefix of %s", strings.Join(parts[:len(parts)-1], "."), key)
+				}
+				child = map[string]any{}
+				node[part] = child
+			}
+			node = child
+		}
+		last := parts[len(parts)-1]
+		if _, ok := node[last].(map[string]any); ok {
+			return nil, fmt.Errorf("%s is both a message and a prefix of other keys", key)
+		}
+		node[last] = c[key]
+	}
+
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "  ")
+	if err := enc.Encode(tree); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Keys returns the catalog's keys in order.
+func (c Catalog) Keys() []string {
+	keys := make([]string, 0, len(c))
+	for k := range c {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// LoadLocales reads every locale file in dir, keyed by locale.
+func LoadLocales(dir string) (map[string]Catalog, error) {
+	paths, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	locales := map[string]Catalog{}
+	for _, path := range paths {
+		c, err := LoadCatalog(path)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", path, err)
+		}
+		locales[strings.TrimSuffix(filepath.Base(path), ".json")] = c
+	}
+	return locales, nil
+}
+
+// LocalePath is the file holding locale's messages.
+func LocalePath(dir, locale string) string {
+	return filepath.Join(dir, locale+".json")
+}
+
+// SaveCatalog writes c to path.
+func SaveCatalog(path string, c Catalog) error {
+	data, err := c.Marshal()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+var placeholderRE = regexp.MustCompile(`\{\s*(\w+)`)
+
+// placeholders returns the ICU argument names a message interpolates.
+func placeholders(msg string) []string {
+	seen := map[string]bool{}
+	var names []string
+	for _, m := range placeholderRE.FindAllStringSubmatch(msg, -1) {
+		if !seen[m[1]] {
+			seen[m[1]] = true
+			names = append(names, m[1])
+		}
+	}
+	sort.Strings(names)
+	return names
+}
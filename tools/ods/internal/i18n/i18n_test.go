@@ -0,0 +1,208 @@
+package i18n
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCatalogRoundTrip(t *testing.T) {
+	c, err := ParseCatalog([]byte(`{"settings": {"title": "Settings", "save": "Save <b>{name}</b>"}, "ok": "OK"}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := Catalog{"settings.title": "Settings", "settings.save": "Save <b>{name}</b>", "ok": "OK"}
+	if !reflect.DeepEqual(c, want) {
+		t.Errorf("ParseCatalog() = %v", c)
+	}
+	data, err := c.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != "{\n  \"ok\": \"OK\",\n  \"settings\": {\n    \"save\": \"Save <b>{name}</b>\",\n    \"title\": \"Settings\"\n  }\n}\n" {
+		t.Errorf("Marshal() = %s", data)
+	}
+
+	if _, err := (Catalog{"a": "x", "a.b": "y"}).Marshal(); err == nil {
+		t.Error("expected an error for a key that is also a prefix")
+	}
+	if _, err := ParseCatalog([]byte(`{"count": 3}`)); err == nil {
+		t.Error("expected an error for a non-string message")
+	}
+}
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestScanSource(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"app/page.tsx": `import { t } from "@/lib/i18n";
+export function Page(): Promise<void> {
+  const label = t("settings.title");
+  // t("commented.out")
+  return (
+    <div title="Workspace settings">
+      <Trans i18nKey="settings.intro" />
+      {t(` + "`connectors.${source}.name`" + `)}
+      <p>Save your changes</p>
+      <span>{count}</span>
+    </div>
+  );
+}
+`,
+		"app/page.test.tsx":     `t("only.in.tests")`,
+		"lib/util.ts":           `const x = format(t('errors.generic', { code }));`,
+		"node_modules/x/a.tsx":  `t("vendored")`,
+		"app/Page.stories.tsx":  `t("story.key")`,
+		"app/types.d.ts":        `t("types.key")`,
+		"app/client.tsx":        `const msg = i18n.t("errors.network");`,
+		"app/notTranslated.tsx": `const n = parseInt("10"); <b>OK</b>`,
+	})
+
+	s, err := ScanSource(dir, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for _, u := range s.Usages {
+		keys = append(keys, u.Key)
+	}
+	if want := []string{"errors.network", "settings.title", "settings.intro", "errors.generic"}; !reflect.DeepEqual(keys, want) {
+		t.Errorf("keys = %v, want %v", keys, want)
+	}
+	if s.Usages[1] != (Usage{Key: "settings.title", File: "app/page.tsx", Line: 3}) {
+		t.Errorf("usage = %+v", s.Usages[1])
+	}
+	if !reflect.DeepEqual(s.Prefixes, []string{"connectors."}) {
+		t.Errorf("prefixes = %v", s.Prefixes)
+	}
+	var literals []string
+	for _, l := range s.Literals {
+		literals = append(literals, l.Text)
+	}
+	if want := []string{"Workspace settings", "Save your changes"}; !reflect.DeepEqual(literals, want) {
+		t.Errorf("literals = %q, want %q", literals, want)
+	}
+}
+
+func TestCheckAndExtract(t *testing.T) {
+	scan := &Scan{
+		Usages:   []Usage{{Key: "a.title"}, {Key: "a.body"}, {Key: "a.title"}},
+		Prefixes: []string{"sources."},
+	}
+	locales := map[string]Catalog{
+		"en": {"a.title": "Hi {name}", "old": "Old", "sources.slack": "Slack"},
+		"de": {"a.title": "Hallo {user}", "old": "Alt", "stale": "x"},
+		"fr": {"a.title": "Salut {name}", "old": "", "sources.slack": "Slack"},
+	}
+
+	r := Check(scan, locales, "en")
+	if len(r.Undefined) != 1 || r.Undefined[0].Key != "a.body" {
+		t.Errorf("undefined = %+v", r.Undefined)
+	}
+	if !reflect.DeepEqual(r.Unused, []string{"old"}) {
+		t.Errorf("unused = %v", r.Unused)
+	}
+	if !reflect.DeepEqual(r.Missing, map[string][]string{"de": {"sources.slack"}, "fr": {"old"}}) {
+		t.Errorf("missing = %v", r.Missing)
+	}
+	if !reflect.DeepEqual(r.Extra, map[string][]string{"de": {"stale"}}) {
+		t.Errorf("extra = %v", r.Extra)
+	}
+	if !reflect.DeepEqual(r.Placeholders, map[string][]string{"de": {"a.title"}}) {
+		t.Errorf("placeholders = %v", r.Placeholders)
+	}
+	if r.Problems() != 6 || !reflect.DeepEqual(r.Locales(), []string{"de", "fr"}) {
+		t.Errorf("problems = %d, locales = %v", r.Problems(), r.Locales())
+	}
+
+	src := locales["en"]
+	added, removed := Extract(scan, src, true)
+	if !reflect.DeepEqual(added, []string{"a.body"}) || !reflect.DeepEqual(removed, []string{"old"}) {
+		t.Errorf("Extract() = %v, %v", added, removed)
+	}
+	if !reflect.DeepEqual(src, Catalog{"a.title": "Hi {name}", "a.body": "", "sources.slack": "Slack"}) {
+		t.Errorf("source after extract = %v", src)
+	}
+	if pruned := Prune(locales["de"], src); !reflect.DeepEqual(pruned, []string{"old", "stale"}) {
+		t.Errorf("Prune() = %v", pruned)
+	}
+
+	// The new key is missing from the source locale until it is written.
+	if r := Check(scan, locales, "en"); !reflect.DeepEqual(r.Missing["en"], []string{"a.body"}) {
+		t.Errorf("missing after extract = %v", r.Missing)
+	}
+}
+
+func TestCrowdin(t *testing.T) {
+	var uploaded, updated string
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/download/de.json" {
+			if r.Header.Get("Authorization") != "" {
+				t.Error("download carried the API token")
+			}
+			_, _ = io.WriteString(w, `{"a": {"title": "Hallo"}}`)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		switch r.Method + " " + r.URL.Path {
+		case "GET /projects/7/files":
+			_, _ = io.WriteString(w, `{"data": [{"data": {"id": 3, "name": "other.json", "path": "/other.json"}}, {"data": {"id": 12, "name": "en.json", "path": "/en.json"}}]}`)
+		case "POST /storages":
+			uploaded = r.Header.Get("Crowdin-API-FileName") + " " + string(body)
+			_, _ = io.WriteString(w, `{"data": {"id": 99}}`)
+		case "PUT /projects/7/files/12":
+			updated = string(body)
+			_, _ = io.WriteString(w, `{"data": {}}`)
+		case "POST /projects/7/translations/builds/files/12":
+			var req map[string]string
+			_ = json.Unmarshal(body, &req)
+			_, _ = io.WriteString(w, `{"data": {"url": "`+srv.URL+`/download/`+req["targetLanguageId"]+`.json"}}`)
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	c := &Crowdin{BaseURL: srv.URL, Token: "secret", ProjectID: 7}
+	if err := c.Push("en.json", []byte(`{"a": {"title": "Hi"}}`)); err != nil {
+		t.Fatal(err)
+	}
+	if uploaded != `en.json {"a": {"title": "Hi"}}` || updated != `{"storageId":99}` {
+		t.Errorf("uploaded %q, updated %q", uploaded, updated)
+	}
+	data, err := c.Pull("en.json", "de")
+	if err != nil || !strings.Contains(string(data), "Hallo") {
+		t.Errorf("Pull() = %s, %v", data, err)
+	}
+	if _, err := c.Pull("missing.json", "de"); err == nil || !strings.Contains(err.Error(), "push it first") {
+		t.Errorf("Pull(missing) error = %v", err)
+	}
+
+	c.Token = "wrong"
+	if err := c.Push("en.json", nil); err == nil || !strings.Contains(err.Error(), "401") {
+		t.Errorf("Push() with a bad token error = %v", err)
+	}
+}
@@ -0,0 +1,145 @@
+package i18n
+
+import (
+	"bufio"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Usage is a place the code uses a message key.
+type Usage struct {
+	Key  string
+	File string
+	Line int
+}
+
+// Literal is user-facing text written straight into the code.
+type Literal struct {
+	File string
+	Line int
+	Text string
+}
+
+// Scan is what a pass over the source found.
+type Scan struct {
+	Usages []Usage
+	// Prefixes are the fixed starts of keys built at run time, e.g.
+	// "connectors." from t(`connectors.${source}`). Keys under them count as
+	// used.
+	Prefixes []string
+	Literals []Literal
+}
+
+var (
+	keyCallRE    = regexp.MustCompile(`\bt\(\s*["'` + "`" + `]([A-Za-z0-9_-]+(?:\.[A-Za-z0-9_-]+)*)["'` + "`" + `]`)
+	keyPropRE    = regexp.MustCompile(`\bi18nKey=\{?["'` + "`" + `]([A-Za-z0-9_-]+(?:\.[A-Za-z0-9_-]+)*)["'` + "`" + `]`)
+	dynamicKeyRE = regexp.MustCompile(`\bt\(\s*` + "`" + `([A-Za-z0-9_.-]*)\$\{`)
+	jsxTextRE    = regexp.MustCompile(`(?:^|[^=])>([^<>{}]*[A-Za-z]{2}[^<>{}]*)<`)
+	textPropRE   = regexp.MustCompile(`\b(?:placeholder|title|aria-label|alt|label)="([^"]*[A-Za-z]{2}[^"]*)"`)
+)
+
+// sourceFile reports whether path is app code worth scanning: TypeScript,
+// and not a test or story.
+func sourceFile(path string) bool {
+	ext := filepath.Ext(path)
+	if ext != ".ts" && ext != ".tsx" {
+		return false
+	}
+	base := filepath.Base(path)
+	for _, skip := range []string{".test.", ".spec.", ".stories.", ".d.ts"} {
+		if strings.Contains(base, skip) {
+			return false
+		}
+	}
+	return true
+}
+
+// ScanSource walks the TypeScript under dir for message keys and, if
+// literals is set, for hardcoded text that should probably be translated.
+// File names are reported relative to dir.
+func ScanSource(dir string, literals bool) (*Scan, error) {
+	s := &Scan{}
+	prefixes := map[string]bool{}
+	err := filepath.WalkDir(dir, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() {
+			if d.Name() == "node_modules" || d.Name() == "locales" || strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !sourceFile(path) {
+			return nil
+		}
+		rel, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		return s.scanFile(path, filepath.ToSlash(rel), literals, prefixes)
+	})
+	if err != nil {
+		return nil, err
+	}
+	for p := range prefixes {
+		s.Prefixes = append(s.Prefixes, p)
+	}
+	sort.Strings(s.Prefixes)
+	return s, nil
+}
+
+func (s *Scan) scanFile(path, name string, literals bool, prefixes map[string]bool) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = f.Close() }()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1<<20)
+	tsx := filepath.Ext(path) == ".tsx"
+	for n := 1; scanner.Scan(); n++ {
+		line := scanner.Text()
+		trimmed := strings.TrimSpace(line)
+		if strings.HasPrefix(trimmed, "//") || strings.HasPrefix(trimmed, "*") || strings.HasPrefix(trimmed, "import ") {
+			continue
+		}
+		for _, re := range []*regexp.Regexp{keyCallRE, keyPropRE} {
+			for _, m := range re.FindAllStringSubmatch(line, -1) {
+				s.Usages = append(s.Usages, Usage{Key: m[1], File: name, Line: n})
+			}
+		}
+		for _, m := range dynamicKeyRE.FindAllStringSubmatch(line, -1) {
+			prefixes[m[1]] = true
+		}
+		if !literals || !tsx {
+			continue
+		}
+		for _, re := range []*regexp.Regexp{jsxTextRE, textPropRE} {
+			for _, m := range re.FindAllStringSubmatch(line, -1) {
+				if text := strings.TrimSpace(m[1]); looksLikeProse(text) {
+					s.Literals = append(s.Literals, Literal{File: name, Line: n, Text: text})
+				}
+			}
+		}
+	}
+	return scanner.Err()
+}
+
+// looksLikeProse filters out the code the literal patterns also match, such
+// as generics (Array<string>) and comparisons: prose has lowercase letters
+// and either spaces or a leading capital.
+func looksLikeProse(text string) bool {
+	if text == "" || strings.ContainsAny(text, "=;()&|") || strings.ToUpper(text) == text {
+		return false
+	}
+	if c := text[0]; !(c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z' || c >= '0' && c <= '9') {
+		return false
+	}
+	return strings.ContainsRune(text, ' ') || (text[0] >= 'A' && text[0] <= 'Z')
+}
@@ -0,0 +1,179 @@
+// Package impersonate mints and revokes short-lived Onyx sessions for another
+// user, for support debugging: login links through the control-plane API, and
+// session tokens through the cloud superuser impersonation endpoint of an API
+// server for tools that hold the session themselves.
+package impersonate
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/controlplane"
+)
+
+const (
+	// SessionCookieName is the cookie the API server reads the session token
+	// from (FASTAPI_USERS_AUTH_COOKIE_NAME in the backend).
+	SessionCookieName = "fastapiusersauth"
+
+	// impersonatePath is only mounted when the API server runs with
+	// IMPERSONATION_ENABLED.
+	impersonatePath = "/tenants/impersonate"
+	logoutPath      = "/auth/logout"
+
+	// loginPath and loginRevokePath are the control plane's impersonation
+	// endpoints, specified in control-plane-api.md.
+	loginPath       = "/impersonation/login"
+	loginRevokePath = "/impersonation/revoke"
+
+	// expirySlack allows for clock skew when checking the expiry the control
+	// plane set.
+	expirySlack = time.Minute
+
+	requestTimeout = 30 * time.Second
+)
+
+// Credentials authenticate the caller as a cloud superuser. The endpoint
+// requires both a logged-in superuser session and the super-cloud API key.
+type Credentials struct {
+	APIKey        string
+	SessionCookie string
+}
+
+// CredentialsFromEnv reads superuser credentials from SUPER_CLOUD_API_KEY and
+// ODS_SUPERUSER_SESSION (the value of your own fastapiusersauth cookie).
+func CredentialsFromEnv() (*Credentials, error) {
+	creds := &Credentials{
+		APIKey:        os.Getenv("SUPER_CLOUD_API_KEY"),
+		SessionCookie: os.Getenv("ODS_SUPERUSER_SESSION"),
+	}
+	var missing []string
+	if creds.APIKey == "" {
+		missing = append(missing, "SUPER_CLOUD_API_KEY")
+	}
+	if creds.SessionCookie == "" {
+		missing = append(missing, "ODS_SUPERUSER_SESSION")
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("missing superuser credentials: set %s", strings.Join(missing, " and "))
+	}
+	return creds, nil
+}
+
+// Mint asks the API server at baseURL for a session as email and returns the
+// session token.
+func Mint(baseURL, email string, creds *Credentials) (string, error) {
+	body, err := json.Marshal(map[string]string{"email": email})
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(baseURL, "/")+impersonatePath, bytes.NewReader(body))
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer "+creds.APIKey)
+	req.AddCookie(&http.Cookie{Name: SessionCookieName, Value: creds.SessionCookie})
+
+	resp, err := (&http.Client{Timeout: requestTimeout}).Do(req)
+	if err != nil {
+		return "", fmt.Errorf("impersonation request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode == http.StatusNotFound {
+		return "", fmt.Errorf("impersonation endpoint not found; is IMPERSONATION_ENABLED set on the API server?")
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return "", fmt.Errorf("impersonation request returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+
+	for _, c := range resp.Cookies() {
+		if c.Name == SessionCookieName && c.Value != "" {
+			return c.Value, nil
+		}
+	}
+	return "", fmt.Errorf("impersonation response did not set a %s cookie", SessionCookieName)
+}
+
+// Revoke logs the impersonated session out so the token can't be reused after
+// the debugging session ends.
+func Revoke(baseURL, token string) error {
+	req, err := http.NewRequest(http.MethodPost, strings.TrimSuffix(baseURL, "/")+logoutPath, nil)
+	if err != nil {
+		return err
+	}
+	req.AddCookie(&http.Cookie{Name: SessionCookieName, Value: token})
+
+	resp, err := (&http.Client{Timeout: requestTimeout}).Do(req)
+	if err != nil {
+		return fmt.Errorf("logout request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("logout returned %s", resp.Status)
+	}
+	return nil
+}
+
+// Login is an impersonation session minted by the control plane.
+type Login struct {
+	SessionID string    `json:"session_id"`
+	LoginURL  string    `json:"login_url"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// MintLogin asks the control plane for a session as email that the server
+// expires after ttl, and returns it with a one-time login URL. The reason and
+// actor are recorded by the control plane too. It fails when the control
+// plane does not honor ttl, so a session never outlives what was asked for.
+func MintLogin(client *controlplane.Client, email, reason, actor string, ttl time.Duration) (*Login, error) {
+	var login Login
+	body := map[string]any{
+		"email":           email,
+		"reason":          reason,
+		"actor":           actor,
+		"expires_in_secs": int(ttl.Seconds()),
+	}
+	if err := client.Post(loginPath, body, &login); err != nil {
+		return nil, fmt.Errorf("impersonation request failed: %w", err)
+	}
+	if login.SessionID == "" || login.LoginURL == "" {
+		return nil, fmt.Errorf("the control plane returned no session or login URL")
+	}
+	if login.ExpiresAt.IsZero() || login.ExpiresAt.After(time.Now().Add(ttl+expirySlack)) {
+		_ = RevokeLogin(client, login.SessionID)
+		return nil, fmt.Errorf("the control plane did not limit the session to %s (expires %v); revoked it", ttl, login.ExpiresAt)
+	}
+	return &login, nil
+}
+
+// RevokeLogin ends a session minted by MintLogin before it expires.
+func RevokeLogin(client *controlplane.Client, sessionID string) error {
+	if err := client.Post(loginRevokePath, map[string]string{"session_id": sessionID}, nil); err != nil {
+		return fmt.Errorf("revoke request failed: %w", err)
+	}
+	return nil
+}
+
+// Hold waits until the session expires or ctx is done, whichever comes first.
+// When ctx ends first, e.g. on Ctrl-C, it revokes the session. It reports
+// whether the session expired, and otherwise any error revoking it.
+func Hold(ctx context.Context, client *controlplane.Client, login *Login) (expired bool, err error) {
+	timer := time.NewTimer(time.Until(login.ExpiresAt))
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true, nil
+	case <-ctx.Done():
+		return false, RevokeLogin(client, login.SessionID)
+	}
+}
@@ -0,0 +1,256 @@
+package impersonate
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"os/signal"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/controlplane"
+)
+
+func TestMint(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != impersonatePath || r.Method != http.MethodPost {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer key" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if c, err := r.Cookie(SessionCookieName); err != nil || c.Value != "mine" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		var body struct {
+			Email string `json:"email"`
+		}
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		http.SetCookie(w, &http.Cookie{Name: SessionCookieName, Value: "token-for-" + body.Email})
+	}))
+	defer srv.Close()
+
+	token, err := Mint(srv.URL, "a@example.com", &Credentials{APIKey: "key", SessionCookie: "mine"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if token != "token-for-a@example.com" {
+		t.Errorf("unexpected token %q", token)
+	}
+
+	if _, err := Mint(srv.URL, "a@example.com", &Credentials{APIKey: "wrong", SessionCookie: "mine"}); err == nil {
+		t.Error("expected an error for a rejected request")
+	}
+}
+
+func TestMint_endpointDisabled(t *testing.T) {
+	srv := httptest.NewServer(http.NotFoundHandler())
+	defer srv.Close()
+
+	if _, err := Mint(srv.URL, "a@example.com", &Credentials{APIKey: "k", SessionCookie: "s"}); err == nil {
+		t.Error("expected an error when the endpoint is not mounted")
+	}
+}
+
+func TestRevoke(t *testing.T) {
+	var got string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if c, err := r.Cookie(SessionCookieName); err == nil && r.URL.Path == logoutPath {
+			got = c.Value
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	if err := Revoke(srv.URL, "tok"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if got != "tok" {
+		t.Errorf("expected the token to be sent to logout, got %q", got)
+	}
+}
+
+func TestCredentialsFromEnv(t *testing.T) {
+	t.Setenv("SUPER_CLOUD_API_KEY", "")
+	t.Setenv("ODS_SUPERUSER_SESSION", "s")
+	if _, err := CredentialsFromEnv(); err == nil {
+		t.Error("expected an error when the API key is missing")
+	}
+
+	t.Setenv("SUPER_CLOUD_API_KEY", "k")
+	creds, err := CredentialsFromEnv()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if creds.APIKey != "k" || creds.SessionCookie != "s" {
+		t.Errorf("unexpected credentials %+v", creds)
+	}
+}
+
+func TestMintLogin(t *testing.T) {
+	var revoked []string
+	var grant time.Duration
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case loginPath:
+			var body struct {
+				Email   string `json:"email"`
+				Reason  string `json:"reason"`
+				Expires int    `json:"expires_in_secs"`
+			}
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			if body.Email != "a@example.com" || body.Reason != "SUP-1" || body.Expires != 900 {
+				http.Error(w, "bad request", http.StatusBadRequest)
+				return
+			}
+			_ = json.NewEncoder(w).Encode(Login{
+				SessionID: "sess-1",
+				LoginURL:  "https://cloud.onyx.app/auth/impersonate?code=abc",
+				ExpiresAt: time.Now().Add(grant),
+			})
+		case loginRevokePath:
+			var body map[string]string
+			_ = json.NewDecoder(r.Body).Decode(&body)
+			revoked = append(revoked, body["session_id"])
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer srv.Close()
+	client := controlplane.NewClient(srv.URL, "secret")
+
+	grant = 15 * time.Minute
+	login, err := MintLogin(client, "a@example.com", "SUP-1", "me@onyx.app", 15*time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if login.SessionID != "sess-1" || login.LoginURL == "" {
+		t.Errorf("unexpected login %+v", login)
+	}
+	if len(revoked) != 0 {
+		t.Errorf("revoked %v, want nothing", revoked)
+	}
+
+	// A session the server would keep longer than asked is revoked at once.
+	grant = 24 * time.Hour
+	if _, err := MintLogin(client, "a@example.com", "SUP-1", "me@onyx.app", 15*time.Minute); err == nil {
+		t.Error("expected an error when the control plane ignores the ttl")
+	}
+	if len(revoked) != 1 || revoked[0] != "sess-1" {
+		t.Errorf("revoked %v, want [sess-1]", revoked)
+	}
+
+	if err := RevokeLogin(client, "sess-2"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if revoked[len(revoked)-1] != "sess-2" {
+		t.Errorf("revoked %v, want sess-2 last", revoked)
+	}
+}
+
+// controlPlaneCall is one request to the fake control plane.
+type controlPlaneCall struct {
+	path string
+	body map[string]any
+}
+
+// fakeControlPlane serves the endpoints in control-plane-api.md, granting
+// logins that expire after grant, and records every request.
+func fakeControlPlane(t *testing.T, grant time.Duration) (*controlplane.Client, func() []controlPlaneCall) {
+	t.Helper()
+	var mu sync.Mutex
+	var calls []controlPlaneCall
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost || !strings.HasPrefix(r.Header.Get("Authorization"), "Bearer ") {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+		var body map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&body)
+		mu.Lock()
+		calls = append(calls, controlPlaneCall{path: r.URL.Path, body: body})
+		mu.Unlock()
+
+		switch r.URL.Path {
+		case "/impersonation/login":
+			w.Header().Set("Content-Type", "application/json")
+			_, _ = fmt.Fprintf(w, `{"session_id":"sess-1","login_url":"https://tenant.example/auth/impersonate?code=abc","expires_at":%q}`,
+				time.Now().Add(grant).UTC().Format(time.RFC3339Nano))
+		case "/impersonation/revoke":
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return controlplane.NewClient(srv.URL, "secret"), func() []controlPlaneCall {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]controlPlaneCall(nil), calls...)
+	}
+}
+
+func TestLoginThenRevoke(t *testing.T) {
+	client, calls := fakeControlPlane(t, 15*time.Minute)
+
+	login, err := MintLogin(client, "a@example.com", "SUP-1", "me@onyx.app", 15*time.Minute)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if login.SessionID != "sess-1" || login.LoginURL != "https://tenant.example/auth/impersonate?code=abc" {
+		t.Errorf("unexpected login %+v", login)
+	}
+	if until := time.Until(login.ExpiresAt); until < 14*time.Minute || until > 15*time.Minute {
+		t.Errorf("expires_at was not parsed: %v", login.ExpiresAt)
+	}
+
+	// Ctrl-C while the session is held revokes it.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	self, err := os.FindProcess(os.Getpid())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := self.Signal(os.Interrupt); err != nil {
+		t.Skipf("cannot interrupt the test process here: %v", err)
+	}
+	expired, err := Hold(ctx, client, login)
+	if expired || err != nil {
+		t.Fatalf("Hold = %v, %v; want the session revoked", expired, err)
+	}
+
+	want := []controlPlaneCall{
+		{path: "/impersonation/login", body: map[string]any{
+			"email":           "a@example.com",
+			"reason":          "SUP-1",
+			"actor":           "me@onyx.app",
+			"expires_in_secs": float64(900),
+		}},
+		{path: "/impersonation/revoke", body: map[string]any{"session_id": "sess-1"}},
+	}
+	if got := calls(); !reflect.DeepEqual(got, want) {
+		t.Errorf("control-plane calls = %+v\nwant %+v", got, want)
+	}
+}
+
+func TestHoldUntilExpiry(t *testing.T) {
+	client, calls := fakeControlPlane(t, 0)
+
+	login := &Login{SessionID: "sess-1", LoginURL: "https://tenant.example", ExpiresAt: time.Now().Add(20 * time.Millisecond)}
+	expired, err := Hold(context.Background(), client, login)
+	if !expired || err != nil {
+		t.Errorf("Hold = %v, %v; want the session to expire", expired, err)
+	}
+	if got := calls(); len(got) != 0 {
+		t.Errorf("an expired session was revoked: %+v", got)
+	}
+}
@@ -0,0 +1,338 @@
+// Package jobs renders one-off Kubernetes Jobs from named templates with
+// parameters, for maintenance tasks run with ods run-job.
+package jobs
+
+import (
+	"embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+//go:embed templates/*.yaml
+var builtin embed.FS
+
+// Built-in parameters every template may reference.
+const (
+	ParamJobName   = "JOB_NAME"
+	ParamNamespace = "NAMESPACE"
+)
+
+// TTLSeconds is how long finished jobs are kept when ods cannot clean them
+// up itself, e.g. because it was killed while waiting.
+const TTLSeconds = 24 * 60 * 60
+
+// ManagedByLabel and TemplateLabel mark the jobs ods creates.
+const (
+	ManagedByLabel = "app.kubernetes.io/managed-by"
+	TemplateLabel  = "ods.onyx.app/job-template"
+)
+
+var (
+	nameRE  = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]*[a-z0-9])?$`)
+	paramRE = regexp.MustCompile(`^[A-Z][A-Z0-9_]*$`)
+	refRE   = regexp.MustCompile(`\$\{([A-Za-z0-9_]+)\}`)
+)
+
+// Template is a Job manifest with ${PARAM} placeholders.
+type Template struct {
+	Name        string  `yaml:"-"`
+	Source      string  `yaml:"-"`
+	Description string  `yaml:"description"`
+	Params      []Param `yaml:"params"`
+	// Base names a deployment whose first container's image, environment
+	// and volume mounts (and the pod's volumes and service account) are
+	// copied into the job's containers, so jobs run like that component.
+	Base string    `yaml:"base"`
+	Job  yaml.Node `yaml:"job"`
+}
+
+// Param is a template parameter.
+type Param struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	Default     string `yaml:"default"`
+	Required    bool   `yaml:"required"`
+}
+
+// Parse reads the template called name from data, checking that it only
+// references parameters it declares.
+func Parse(name, source string, data []byte) (*Template, error) {
+	if !nameRE.MatchString(name) {
+		return nil, fmt.Errorf("invalid template name %q (lowercase letters, digits and dashes only)", name)
+	}
+	var t Template
+	if err := yaml.Unmarshal(data, &t); err != nil {
+		return nil, fmt.Errorf("%s: %w", source, err)
+	}
+	t.Name, t.Source = name, source
+	if t.Job.Kind == 0 {
+		return nil, fmt.Errorf("%s: missing job", source)
+	}
+
+	declared := map[string]bool{ParamJobName: true, ParamNamespace: true}
+	for _, p := range t.Params {
+		if !paramRE.MatchString(p.Name) {
+			return nil, fmt.Errorf("%s: invalid parameter name %q (expected UPPER_SNAKE_CASE)", source, p.Name)
+		}
+		if declared[p.Name] {
+			return nil, fmt.Errorf("%s: parameter %s declared twice or shadows a built-in", source, p.Name)
+		}
+		declared[p.Name] = true
+	}
+	var undeclared []string
+	walkScalars(&t.Job, func(n *yaml.Node) {
+		for _, m := range refRE.FindAllStringSubmatch(n.Value, -1) {
+			if !declared[m[1]] {
+				undeclared = append(undeclared, m[1])
+			}
+		}
+	})
+	if len(undeclared) > 0 {
+		return nil, fmt.Errorf("%s: undeclared parameter(s) %s", source, strings.Join(undeclared, ", "))
+	}
+	return &t, nil
+}
+
+// Load finds the template called name, preferring userDir over the built-in
+// templates so a team can override them.
+func Load(name, userDir string) (*Template, error) {
+	path := filepath.Join(userDir, name+".yaml")
+	data, err := os.ReadFile(path)
+	if err == nil {
+		return Parse(name, path, data)
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	data, err = builtin.ReadFile("templates/" + name + ".yaml")
+	if err != nil {
+		return nil, fmt.Errorf("no job template %q (see ods run-job --list)", name)
+	}
+	return Parse(name, "built-in", data)
+}
+
+// List returns every available template, sorted by name.
+func List(userDir string) ([]*Template, error) {
+	names := map[string]bool{}
+	entries, _ := builtin.ReadDir("templates")
+	for _, e := range entries {
+		names[strings.TrimSuffix(e.Name(), ".yaml")] = true
+	}
+	user, err := filepath.Glob(filepath.Join(userDir, "*.yaml"))
+	if err != nil {
+		return nil, err
+	}
+	for _, f := range user {
+		names[strings.TrimSuffix(filepath.Base(f), ".yaml")] = true
+	}
+
+	var templates []*Template
+	for name := range names {
+		t, err := Load(name, userDir)
+		if err != nil {
+			return nil, err
+		}
+		templates = append(templates, t)
+	}
+	sort.Slice(templates, func(i, j int) bool { return templates[i].Name < templates[j].Name })
+	return templates, nil
+}
+
+// ParseAssignments reads PARAM=value arguments.
+func ParseAssignments(args []string) (map[string]string, error) {
+	values := map[string]string{}
+	for _, arg := range args {
+		name, value, ok := strings.Cut(arg, "=")
+		if !ok || name == "" {
+			return nil, fmt.Errorf("invalid parameter %q (expected NAME=value)", arg)
+		}
+		if _, dup := values[name]; dup {
+			return nil, fmt.Errorf("parameter %s given twice", name)
+		}
+		values[name] = value
+	}
+	return values, nil
+}
+
+// Resolve returns the value of every declared parameter: values, falling
+// back to defaults. It fails on missing required or unknown parameters.
+func (t *Template) Resolve(values map[string]string) (map[string]string, error) {
+	known := map[string]bool{}
+	resolved := map[string]string{}
+	var missing []string
+	for _, p := range t.Params {
+		known[p.Name] = true
+		v, ok := values[p.Name]
+		switch {
+		case ok:
+			resolved[p.Name] = v
+		case p.Required:
+			missing = append(missing, p.Name)
+		default:
+			resolved[p.Name] = p.Default
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("missing required parameter(s): %s", strings.Join(missing, ", "))
+	}
+	var unknown []string
+	for name := range values {
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("unknown parameter(s) for %s: %s", t.Name, strings.Join(unknown, ", "))
+	}
+	return resolved, nil
+}
+
+// JobName returns a name for a run of the template, unique per second and
+// short enough for the job-name label Kubernetes puts on its pods.
+func JobName(template, stamp string) string {
+	prefix := "ods-" + template
+	if max := 63 - len(stamp) - 1; len(prefix) > max {
+		prefix = strings.TrimRight(prefix[:max], "-")
+	}
+	return prefix + "-" + stamp
+}
+
+// Render builds the Job manifest, as JSON for kubectl create, from the
+// template and resolved params. basePodSpec is the pod spec of t.Base's
+// deployment, or nil when the template has no base.
+func (t *Template) Render(jobName, namespace string, params map[string]string, basePodSpec map[string]any) ([]byte, error) {
+	all := map[string]string{ParamJobName: jobName, ParamNamespace: namespace}
+	for k, v := range params {
+		all[k] = v
+	}
+
+	job := cloneNode(&t.Job)
+	walkScalars(job, func(n *yaml.Node) {
+		if !refRE.MatchString(n.Value) {
+			return
+		}
+		whole := refRE.FindString(n.Value) == n.Value
+		n.Value = refRE.ReplaceAllStringFunc(n.Value, func(ref string) string {
+			return all[refRE.FindStringSubmatch(ref)[1]]
+		})
+		// An unquoted scalar that is just a reference takes the type of its
+		// value, so "replicas: ${N}" renders as a number.
+		if whole && n.Style == 0 {
+			n.Tag = ""
+		}
+	})
+
+	var manifest map[string]any
+	if err := job.Decode(&manifest); err != nil {
+		return nil, fmt.Errorf("%s: job: %w", t.Source, err)
+	}
+	if manifest == nil {
+		manifest = map[string]any{}
+	}
+	manifest["apiVersion"] = "batch/v1"
+	manifest["kind"] = "Job"
+	metadata := child(manifest, "metadata")
+	metadata["name"] = jobName
+	delete(metadata, "generateName")
+	labels := child(metadata, "labels")
+	labels[ManagedByLabel] = "ods"
+	labels[TemplateLabel] = t.Name
+
+	spec := child(manifest, "spec")
+	if _, ok := spec["ttlSecondsAfterFinished"]; !ok {
+		spec["ttlSecondsAfterFinished"] = TTLSeconds
+	}
+	podSpec := child(child(spec, "template"), "spec")
+	if _, ok := podSpec["restartPolicy"]; !ok {
+		podSpec["restartPolicy"] = "Never"
+	}
+	if basePodSpec != nil {
+		if err := applyBase(podSpec, basePodSpec); err != nil {
+			return nil, fmt.Errorf("%s: %w", t.Source, err)
+		}
+	}
+	return json.MarshalIndent(manifest, "", "  ")
+}
+
+// applyBase copies what a job needs to run like the base deployment: its
+// first container's image, environment and mounts into every job container
+// (the job's own settings win), and the pod's volumes and identity.
+func applyBase(podSpec, base map[string]any) error {
+	baseContainers, _ := base["containers"].([]any)
+	if len(baseContainers) == 0 {
+		return errors.New("base deployment has no containers")
+	}
+	baseContainer, _ := baseContainers[0].(map[string]any)
+
+	containers, _ := podSpec["containers"].([]any)
+	if len(containers) == 0 {
+		return errors.New("job has no containers")
+	}
+	for _, c := range containers {
+		container, ok := c.(map[string]any)
+		if !ok {
+			return errors.New("job containers must be mappings")
+		}
+		for _, key := range []string{"image", "imagePullPolicy", "securityContext"} {
+			if _, ok := container[key]; !ok && baseContainer[key] != nil {
+				container[key] = baseContainer[key]
+			}
+		}
+		for _, key := range []string{"env", "envFrom", "volumeMounts"} {
+			own, _ := container[key].([]any)
+			inherited, _ := baseContainer[key].([]any)
+			if len(inherited) > 0 {
+				container[key] = append(append([]any{}, inherited...), own...)
+			}
+		}
+	}
+
+	for _, key := range []string{"serviceAccountName", "imagePullSecrets", "securityContext", "nodeSelector", "tolerations"} {
+		if _, ok := podSpec[key]; !ok && base[key] != nil {
+			podSpec[key] = base[key]
+		}
+	}
+	own, _ := podSpec["volumes"].([]any)
+	if inherited, _ := base["volumes"].([]any); len(inherited) > 0 {
+		podSpec["volumes"] = append(append([]any{}, inherited...), own...)
+	}
+	return nil
+}
+
+// child returns m[key] as a mapping, creating it when absent.
+func child(m map[string]any, key string) map[string]any {
+	if c, ok := m[key].(map[string]any); ok {
+		return c
+	}
+	c := map[string]any{}
+	m[key] = c
+	return c
+}
+
+func walkScalars(n *yaml.Node, fn func(*yaml.Node)) {
+	if n.Kind == yaml.ScalarNode {
+		fn(n)
+		return
+	}
+	for _, c := range n.Content {
+		walkScalars(c, fn)
+	}
+}
+
+func cloneNode(n *yaml.Node) *yaml.Node {
+	c := *n
+	c.Content = make([]*yaml.Node, len(n.Content))
+	for i, child := range n.Content {
+		c.Content[i] = cloneNode(child)
+	}
+	return &c
+}
@@ -0,0 +1,176 @@
+package jobs
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const testTemplate = `
+description: test
+params:
+  - name: ID
+    required: true
+  - name: LIMIT
+    default: "10"
+base: api-server
+job:
+  spec:
+    backoffLimit: ${LIMIT}
+    template:
+      spec:
+        containers:
+          - name: job
+            command: ["run", "${ID}", "--job=${JOB_NAME}"]
+            env:
+              - name: OWN
+                value: "1"
+`
+
+func TestParseRejectsUndeclared(t *testing.T) {
+	_, err := Parse("bad", "bad.yaml", []byte("job:\n  spec:\n    x: ${NOPE}\n"))
+	if err == nil || !strings.Contains(err.Error(), "NOPE") {
+		t.Errorf("expected an undeclared parameter error, got %v", err)
+	}
+	if _, err := Parse("Bad_Name", "x", []byte("job: {}")); err == nil {
+		t.Error("expected an invalid name error")
+	}
+	if _, err := Parse("dup", "x", []byte("params: [{name: JOB_NAME}]\njob: {}")); err == nil {
+		t.Error("expected an error for a parameter shadowing a built-in")
+	}
+}
+
+func TestBuiltinTemplatesParse(t *testing.T) {
+	templates, err := List(t.TempDir())
+	if err != nil {
+		t.Fatalf("List() error: %v", err)
+	}
+	if len(templates) == 0 {
+		t.Fatal("expected built-in templates")
+	}
+	for _, tmpl := range templates {
+		params := map[string]string{}
+		for _, p := range tmpl.Params {
+			params[p.Name] = "1"
+		}
+		if _, err := tmpl.Render("job", "onyx", params, map[string]any{"containers": []any{map[string]any{"image": "img"}}}); err != nil {
+			t.Errorf("%s: Render() error: %v", tmpl.Name, err)
+		}
+	}
+}
+
+func TestResolve(t *testing.T) {
+	tmpl, err := Parse("test", "test.yaml", []byte(testTemplate))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	if _, err := tmpl.Resolve(map[string]string{}); err == nil || !strings.Contains(err.Error(), "ID") {
+		t.Errorf("expected missing ID, got %v", err)
+	}
+	if _, err := tmpl.Resolve(map[string]string{"ID": "1", "OTHER": "x"}); err == nil || !strings.Contains(err.Error(), "OTHER") {
+		t.Errorf("expected unknown OTHER, got %v", err)
+	}
+	got, err := tmpl.Resolve(map[string]string{"ID": "42"})
+	if err != nil || got["ID"] != "42" || got["LIMIT"] != "10" {
+		t.Errorf("Resolve() = %v, %v", got, err)
+	}
+}
+
+func TestRender(t *testing.T) {
+	tmpl, err := Parse("test", "test.yaml", []byte(testTemplate))
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	base := map[string]any{
+		"serviceAccountName": "onyx",
+		"volumes":            []any{map[string]any{"name": "certs"}},
+		"containers": []any{map[string]any{
+			"image":   "onyxdotapp/onyx-backend:v1",
+			"env":     []any{map[string]any{"name": "POSTGRES_HOST", "value": "pg"}},
+			"envFrom": []any{map[string]any{"configMapRef": map[string]any{"name": "env-configmap"}}},
+		}},
+	}
+	data, err := tmpl.Render("ods-test-1", "onyx", map[string]string{"ID": "42", "LIMIT": "3"}, base)
+	if err != nil {
+		t.Fatalf("Render() error: %v", err)
+	}
+
+	var job struct {
+		Kind     string
+		Metadata struct {
+			Name   string
+			Labels map[string]string
+		}
+		Spec struct {
+			BackoffLimit            any
+			TTLSecondsAfterFinished int
+			Template                struct {
+				Spec struct {
+					RestartPolicy      string
+					ServiceAccountName string
+					Volumes            []any
+					Containers         []struct {
+						Image   string
+						Command []string
+						Env     []struct{ Name string }
+						EnvFrom []any
+					}
+				}
+			}
+		}
+	}
+	if err := json.Unmarshal(data, &job); err != nil {
+		t.Fatalf("unmarshal rendered job: %v\n%s", err, data)
+	}
+	if job.Kind != "Job" || job.Metadata.Name != "ods-test-1" || job.Metadata.Labels[TemplateLabel] != "test" {
+		t.Errorf("unexpected metadata %+v", job.Metadata)
+	}
+	if job.Spec.BackoffLimit != float64(3) {
+		t.Errorf("backoffLimit = %#v, want the number 3", job.Spec.BackoffLimit)
+	}
+	if job.Spec.TTLSecondsAfterFinished != TTLSeconds {
+		t.Errorf("ttlSecondsAfterFinished = %d", job.Spec.TTLSecondsAfterFinished)
+	}
+	pod := job.Spec.Template.Spec
+	if pod.RestartPolicy != "Never" || pod.ServiceAccountName != "onyx" || len(pod.Volumes) != 1 {
+		t.Errorf("unexpected pod spec %+v", pod)
+	}
+	c := pod.Containers[0]
+	if c.Image != "onyxdotapp/onyx-backend:v1" || len(c.EnvFrom) != 1 {
+		t.Errorf("base not applied: %+v", c)
+	}
+	if strings.Join(c.Command, " ") != "run 42 --job=ods-test-1" {
+		t.Errorf("command = %v", c.Command)
+	}
+	if len(c.Env) != 2 || c.Env[0].Name != "POSTGRES_HOST" || c.Env[1].Name != "OWN" {
+		t.Errorf("env = %+v, want the base's then the job's own", c.Env)
+	}
+
+	// The template itself is left untouched for the next render.
+	again, _ := tmpl.Render("ods-test-2", "onyx", map[string]string{"ID": "7", "LIMIT": "1"}, nil)
+	if !strings.Contains(string(again), `"7"`) || strings.Contains(string(again), "42") {
+		t.Errorf("second render reused the first's values:\n%s", again)
+	}
+}
+
+func TestParseAssignments(t *testing.T) {
+	got, err := ParseAssignments([]string{"A=1", "B=x=y", "C="})
+	if err != nil || got["A"] != "1" || got["B"] != "x=y" || got["C"] != "" {
+		t.Errorf("ParseAssignments() = %v, %v", got, err)
+	}
+	for _, bad := range [][]string{{"A"}, {"=1"}, {"A=1", "A=2"}} {
+		if _, err := ParseAssignments(bad); err == nil {
+			t.Errorf("ParseAssignments(%v) expected error", bad)
+		}
+	}
+}
+
+func TestJobName(t *testing.T) {
+	if got := JobName("shell", "20260102-030405"); got != "ods-shell-20260102-030405" {
+		t.Errorf("JobName() = %q", got)
+	}
+	long := JobName(strings.Repeat("a", 60), "20260102-030405")
+	if len(long) > 63 || !strings.HasSuffix(long, "-20260102-030405") {
+		t.Errorf("JobName() = %q (%d chars)", long, len(long))
+	}
+}
@@ -0,0 +1,119 @@
+package kube
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/runner"
+)
+
+// CallerIdentity is the AWS identity credentials resolve to.
+type CallerIdentity struct {
+	Account string `json:"Account"`
+	Arn     string `json:"Arn"`
+	UserID  string `json:"UserId"`
+}
+
+// GetCallerIdentity runs aws sts get-caller-identity with profile ("" for
+// the shell's default credentials).
+func GetCallerIdentity(profile string) (*CallerIdentity, error) {
+	env := os.Environ()
+	if profile != "" {
+		env = append(env, "AWS_PROFILE="+profile)
+	}
+	out, err := runner.Output(nil, runner.Cmd{Name: "aws", Args: []string{"sts", "get-caller-identity", "--output", "json"}, Env: env})
+	if err != nil {
+		return nil, err
+	}
+	var id CallerIdentity
+	if err := json.Unmarshal(out, &id); err != nil {
+		return nil, fmt.Errorf("failed to parse aws output: %w", err)
+	}
+	return &id, nil
+}
+
+// assumedRoles caches the credentials of roles assumed by AWSEnv, keyed by
+// profile and role, for the life of the process.
+var assumedRoles = struct {
+	sync.Mutex
+	env map[[2]string][]string
+}{env: map[[2]string][]string{}}
+
+// AWSEnv returns the environment for aws commands against this cluster's
+// account. When the cluster has a RoleARN, the role is assumed from its
+// profile and the temporary credentials are exported, which the CLI prefers
+// over any profile; otherwise only the profile is pinned.
+func (c *Cluster) AWSEnv() ([]string, error) {
+	if c.RoleARN == "" {
+		return c.env(), nil
+	}
+	assumedRoles.Lock()
+	defer assumedRoles.Unlock()
+	key := [2]string{c.Profile, c.RoleARN}
+	creds, ok := assumedRoles.env[key]
+	if !ok {
+		var err error
+		if creds, err = c.assumeRole(); err != nil {
+			return nil, err
+		}
+		assumedRoles.env[key] = creds
+	}
+	return append(c.env(), creds...), nil
+}
+
+// assumeRole runs aws sts assume-role for c.RoleARN and returns the
+// temporary credentials as environment variables.
+func (c *Cluster) assumeRole() ([]string, error) {
+	out, err := runner.Output(c.Runner, runner.Cmd{
+		Name: "aws",
+		Args: []string{"sts", "assume-role", "--role-arn", c.RoleARN, "--role-session-name", "ods", "--output", "json"},
+		Env:  c.env(),
+	})
+	if err != nil {
+		return nil, err
+	}
+	var resp struct {
+		Credentials struct {
+			AccessKeyID     string `json:"AccessKeyId"`
+			SecretAccessKey string `json:"SecretAccessKey"`
+			SessionToken    string `json:"SessionToken"`
+		} `json:"Credentials"`
+	}
+	if err := json.Unmarshal(out, &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse aws output: %w", err)
+	}
+	if resp.Credentials.AccessKeyID == "" {
+		return nil, fmt.Errorf("aws sts assume-role returned no credentials for %s", c.RoleARN)
+	}
+	return []string{
+		"AWS_ACCESS_KEY_ID=" + resp.Credentials.AccessKeyID,
+		"AWS_SECRET_ACCESS_KEY=" + resp.Credentials.SecretAccessKey,
+		"AWS_SESSION_TOKEN=" + resp.Credentials.SessionToken,
+	}, nil
+}
+
+// AWS runs aws in this cluster's region, with its profile or role, and
+// returns its stdout. On failure the error names the service and operation
+// and includes aws's stderr.
+func (c *Cluster) AWS(args ...string) ([]byte, error) {
+	env, err := c.AWSEnv()
+	if err != nil {
+		return nil, err
+	}
+	var stdout, stderr bytes.Buffer
+	cmd := runner.Cmd{
+		Name:   "aws",
+		Args:   append([]string{"--region", c.Region, "--output", "json"}, args...),
+		Env:    env,
+		Stdout: &stdout,
+		Stderr: &stderr,
+	}
+	if err := c.run(cmd); err != nil {
+		return nil, fmt.Errorf("aws %s failed: %w\n%s", strings.Join(args[:min(len(args), 2)], " "), err, stderr.String())
+	}
+	return stdout.Bytes(), nil
+}
@@ -0,0 +1,225 @@
+package kube
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Node is the subset of a Kubernetes Node ods prices.
+type Node struct {
+	Name         string
+	InstanceType string
+	// Spot is set for spot (or Karpenter spot) capacity.
+	Spot bool
+	// CPU (cores) and Memory (bytes) are the node's allocatable resources.
+	CPU    float64
+	Memory float64
+}
+
+// PodRequests is the resources a running pod reserves on its node.
+type PodRequests struct {
+	Pod string
+	// Workload is the deployment, statefulset or job that owns the pod, or
+	// the pod's own name.
+	Workload string
+	Node     string
+	CPU      float64 // cores
+	Memory   float64 // bytes
+}
+
+// PVC is a persistent volume claim and its requested size.
+type PVC struct {
+	Name         string
+	StorageClass string
+	Bytes        float64
+}
+
+var quantityPattern = regexp.MustCompile(`^([0-9.]+(?:[eE][-+]?[0-9]+)?)([a-zA-Z]*)$`)
+
+var quantitySuffixes = map[string]float64{
+	"":   1,
+	"m":  1e-3,
+	"k":  1e3,
+	"M":  1e6,
+	"G":  1e9,
+	"T":  1e12,
+	"Ki": 1 << 10,
+	"Mi": 1 << 20,
+	"Gi": 1 << 30,
+	"Ti": 1 << 40,
+}
+
+// ParseQuantity parses a Kubernetes resource quantity such as "500m", "2",
+// "512Mi" or "10G" into cores or bytes.
+func ParseQuantity(s string) (float64, error) {
+	m := quantityPattern.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return 0, fmt.Errorf("invalid quantity %q", s)
+	}
+	multiplier, ok := quantitySuffixes[m[2]]
+	if !ok {
+		return 0, fmt.Errorf("invalid quantity suffix in %q", s)
+	}
+	v, err := strconv.ParseFloat(m[1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid quantity %q: %w", s, err)
+	}
+	return v * multiplier, nil
+}
+
+// ListNodes returns the cluster's nodes, sorted by name.
+func (c *Cluster) ListNodes() ([]*Node, error) {
+	out, err := c.output("get", "nodes", "-o", "json")
+	if err != nil {
+		return nil, err
+	}
+	return parseNodeList(out)
+}
+
+func parseNodeList(data []byte) ([]*Node, error) {
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Name   string            `json:"name"`
+				Labels map[string]string `json:"labels"`
+			} `json:"metadata"`
+			Status struct {
+				Allocatable map[string]string `json:"allocatable"`
+			} `json:"status"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse kubectl output: %w", err)
+	}
+	nodes := make([]*Node, 0, len(list.Items))
+	for _, item := range list.Items {
+		labels := item.Metadata.Labels
+		n := &Node{
+			Name:         item.Metadata.Name,
+			InstanceType: labels["node.kubernetes.io/instance-type"],
+			Spot:         strings.EqualFold(labels["eks.amazonaws.com/capacityType"], "SPOT") || labels["karpenter.sh/capacity-type"] == "spot",
+		}
+		var err error
+		if n.CPU, err = ParseQuantity(item.Status.Allocatable["cpu"]); err != nil {
+			return nil, fmt.Errorf("node %s: %w", n.Name, err)
+		}
+		if n.Memory, err = ParseQuantity(item.Status.Allocatable["memory"]); err != nil {
+			return nil, fmt.Errorf("node %s: %w", n.Name, err)
+		}
+		nodes = append(nodes, n)
+	}
+	sort.Slice(nodes, func(i, j int) bool { return nodes[i].Name < nodes[j].Name })
+	return nodes, nil
+}
+
+// ListPodRequests returns the resource requests of every running pod in the
+// cluster's namespace, sorted by pod name.
+func (c *Cluster) ListPodRequests() ([]*PodRequests, error) {
+	out, err := c.output("get", "pods", "--field-selector", "status.phase=Running", "-o", "json")
+	if err != nil {
+		return nil, err
+	}
+	return parsePodRequests(out)
+}
+
+func parsePodRequests(data []byte) ([]*PodRequests, error) {
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Name            string `json:"name"`
+				OwnerReferences []struct {
+					Kind string `json:"kind"`
+					Name string `json:"name"`
+				} `json:"ownerReferences"`
+			} `json:"metadata"`
+			Spec struct {
+				NodeName   string `json:"nodeName"`
+				Containers []struct {
+					Resources struct {
+						Requests map[string]string `json:"requests"`
+					} `json:"resources"`
+				} `json:"containers"`
+			} `json:"spec"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse kubectl output: %w", err)
+	}
+	pods := make([]*PodRequests, 0, len(list.Items))
+	for _, item := range list.Items {
+		p := &PodRequests{Pod: item.Metadata.Name, Workload: item.Metadata.Name, Node: item.Spec.NodeName}
+		for _, owner := range item.Metadata.OwnerReferences {
+			p.Workload = owner.Name
+			// A ReplicaSet is named after its deployment plus a template hash.
+			if i := strings.LastIndex(owner.Name, "-"); owner.Kind == "ReplicaSet" && i > 0 {
+				p.Workload = owner.Name[:i]
+			}
+		}
+		for _, container := range item.Spec.Containers {
+			for resource, field := range map[string]*float64{"cpu": &p.CPU, "memory": &p.Memory} {
+				q, ok := container.Resources.Requests[resource]
+				if !ok {
+					continue
+				}
+				v, err := ParseQuantity(q)
+				if err != nil {
+					return nil, fmt.Errorf("pod %s: %w", p.Pod, err)
+				}
+				*field += v
+			}
+		}
+		pods = append(pods, p)
+	}
+	sort.Slice(pods, func(i, j int) bool { return pods[i].Pod < pods[j].Pod })
+	return pods, nil
+}
+
+// ListPVCs returns the persistent volume claims in the cluster's namespace,
+// sorted by name.
+func (c *Cluster) ListPVCs() ([]*PVC, error) {
+	out, err := c.output("get", "pvc", "-o", "json")
+	if err != nil {
+		return nil, err
+	}
+	return parsePVCList(out)
+}
+
+func parsePVCList(data []byte) ([]*PVC, error) {
+	var list struct {
+		Items []struct {
+			Metadata struct {
+				Name string `json:"name"`
+			} `json:"metadata"`
+			Spec struct {
+				StorageClassName string `json:"storageClassName"`
+				Resources        struct {
+					Requests map[string]string `json:"requests"`
+				} `json:"resources"`
+			} `json:"spec"`
+			Status struct {
+				Capacity map[string]string `json:"capacity"`
+			} `json:"status"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse kubectl output: %w", err)
+	}
+	pvcs := make([]*PVC, 0, len(list.Items))
+	for _, item := range list.Items {
+		size := item.Status.Capacity["storage"]
+		if size == "" {
+			size = item.Spec.Resources.Requests["storage"]
+		}
+		bytes, err := ParseQuantity(size)
+		if err != nil {
+			return nil, fmt.Errorf("pvc %s: %w", item.Metadata.Name, err)
+		}
+		pvcs = append(pvcs, &PVC{Name: item.Metadata.Name, StorageClass: item.Spec.StorageClassName, Bytes: bytes})
+	}
+	sort.Slice(pvcs, func(i, j int) bool { return pvcs[i].Name < pvcs[j].Name })
+	return pvcs, nil
+}
@@ -0,0 +1,88 @@
+package kube
+
+import "testing"
+
+func TestParseQuantity(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    float64
+		wantErr bool
+	}{
+		{"2", 2, false},
+		{"500m", 0.5, false},
+		{"1.5", 1.5, false},
+		{"512Mi", 512 << 20, false},
+		{"16Gi", 16 << 30, false},
+		{"10G", 10e9, false},
+		{"1e3", 1000, false},
+		{"", 0, true},
+		{"12Qi", 0, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseQuantity(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseQuantity(%q) = %v, %v; want %v, err=%v", tt.in, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestParseNodeList(t *testing.T) {
+	data := []byte(`{"items":[
+		{"metadata":{"name":"ip-2","labels":{"node.kubernetes.io/instance-type":"m5.xlarge","karpenter.sh/capacity-type":"spot"}},
+		 "status":{"allocatable":{"cpu":"3920m","memory":"15Gi"}}},
+		{"metadata":{"name":"ip-1","labels":{"node.kubernetes.io/instance-type":"r5.large","eks.amazonaws.com/capacityType":"ON_DEMAND"}},
+		 "status":{"allocatable":{"cpu":"2","memory":"15Gi"}}}
+	]}`)
+	nodes, err := parseNodeList(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(nodes) != 2 || nodes[0].Name != "ip-1" || nodes[0].Spot || nodes[0].CPU != 2 {
+		t.Fatalf("unexpected nodes %+v", nodes)
+	}
+	if n := nodes[1]; n.InstanceType != "m5.xlarge" || !n.Spot || n.CPU != 3.92 || n.Memory != 15<<30 {
+		t.Errorf("unexpected node %+v", n)
+	}
+}
+
+func TestParsePodRequests(t *testing.T) {
+	data := []byte(`{"items":[
+		{"metadata":{"name":"api-server-7d9f8-abcde","ownerReferences":[{"kind":"ReplicaSet","name":"api-server-7d9f8"}]},
+		 "spec":{"nodeName":"ip-1","containers":[
+		   {"resources":{"requests":{"cpu":"500m","memory":"1Gi"}}},
+		   {"resources":{"requests":{"cpu":"100m"}}}]}},
+		{"metadata":{"name":"vespa-0","ownerReferences":[{"kind":"StatefulSet","name":"vespa"}]},
+		 "spec":{"nodeName":"ip-2","containers":[{"resources":{}}]}}
+	]}`)
+	pods, err := parsePodRequests(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(pods) != 2 {
+		t.Fatalf("got %d pods, want 2", len(pods))
+	}
+	if p := pods[0]; p.Workload != "api-server" || p.Node != "ip-1" || p.CPU != 0.6 || p.Memory != 1<<30 {
+		t.Errorf("unexpected api-server pod %+v", p)
+	}
+	if p := pods[1]; p.Workload != "vespa" || p.CPU != 0 {
+		t.Errorf("unexpected vespa pod %+v", p)
+	}
+}
+
+func TestParsePVCList(t *testing.T) {
+	data := []byte(`{"items":[
+		{"metadata":{"name":"vespa-storage-vespa-0"},"spec":{"storageClassName":"gp3","resources":{"requests":{"storage":"100Gi"}}},
+		 "status":{"capacity":{"storage":"200Gi"}}},
+		{"metadata":{"name":"pending"},"spec":{"resources":{"requests":{"storage":"10Gi"}}},"status":{}}
+	]}`)
+	pvcs, err := parsePVCList(data)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(pvcs) != 2 || pvcs[0].Name != "pending" || pvcs[0].Bytes != 10<<30 {
+		t.Fatalf("unexpected pvcs %+v", pvcs)
+	}
+	if p := pvcs[1]; p.Bytes != 200<<30 || p.StorageClass != "gp3" {
+		t.Errorf("expected the bound capacity, got %+v", p)
+	}
+}
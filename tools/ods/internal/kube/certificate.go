@@ -0,0 +1,143 @@
+package kube
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Certificate is the subset of a cert-manager Certificate ods reports on.
+type Certificate struct {
+	Name       string
+	SecretName string
+	DNSNames   []string
+	Issuer     string
+	Ready      bool
+	// ReadyMessage is why the certificate is or is not ready.
+	ReadyMessage string
+	// Issuing is set while cert-manager is requesting a new certificate.
+	Issuing     bool
+	NotAfter    time.Time
+	RenewalTime time.Time
+	// FailedIssuanceAttempts counts the issuances that failed in a row.
+	FailedIssuanceAttempts int
+}
+
+type certificateJSON struct {
+	Metadata struct {
+		Name string `json:"name"`
+	} `json:"metadata"`
+	Spec struct {
+		SecretName string   `json:"secretName"`
+		DNSNames   []string `json:"dnsNames"`
+		IssuerRef  struct {
+			Name string `json:"name"`
+			Kind string `json:"kind"`
+		} `json:"issuerRef"`
+	} `json:"spec"`
+	Status struct {
+		Conditions             []certificateCondition `json:"conditions"`
+		NotAfter               time.Time              `json:"notAfter"`
+		RenewalTime            time.Time              `json:"renewalTime"`
+		FailedIssuanceAttempts int                    `json:"failedIssuanceAttempts"`
+	} `json:"status"`
+}
+
+type certificateCondition struct {
+	Type               string `json:"type"`
+	Status             string `json:"status"`
+	Reason             string `json:"reason,omitempty"`
+	Message            string `json:"message,omitempty"`
+	LastTransitionTime string `json:"lastTransitionTime,omitempty"`
+}
+
+func (c certificateJSON) toCertificate() *Certificate {
+	cert := &Certificate{
+		Name:                   c.Metadata.Name,
+		SecretName:             c.Spec.SecretName,
+		DNSNames:               c.Spec.DNSNames,
+		Issuer:                 c.Spec.IssuerRef.Name,
+		NotAfter:               c.Status.NotAfter,
+		RenewalTime:            c.Status.RenewalTime,
+		FailedIssuanceAttempts: c.Status.FailedIssuanceAttempts,
+	}
+	if c.Spec.IssuerRef.Kind != "" {
+		cert.Issuer = c.Spec.IssuerRef.Kind + "/" + cert.Issuer
+	}
+	for _, cond := range c.Status.Conditions {
+		switch cond.Type {
+		case "Ready":
+			cert.Ready = cond.Status == "True"
+			cert.ReadyMessage = cond.Message
+		case "Issuing":
+			cert.Issuing = cond.Status == "True"
+		}
+	}
+	return cert
+}
+
+// ListCertificates returns every cert-manager Certificate in the cluster's
+// namespace, sorted by name. It fails when cert-manager is not installed.
+func (c *Cluster) ListCertificates() ([]*Certificate, error) {
+	out, err := c.output("get", "certificates.cert-manager.io", "-o", "json")
+	if err != nil {
+		return nil, err
+	}
+	return parseCertificateList(out)
+}
+
+// RenewCertificate has cert-manager issue the certificate again now, the way
+// cmctl renew does: by setting its Issuing condition.
+func (c *Cluster) RenewCertificate(name string) error {
+	out, err := c.output("get", "certificates.cert-manager.io", name, "-o", "json")
+	if err != nil {
+		return err
+	}
+	var cert certificateJSON
+	if err := json.Unmarshal(out, &cert); err != nil {
+		return fmt.Errorf("failed to parse kubectl output: %w", err)
+	}
+	patch, err := json.Marshal(map[string]any{
+		"status": map[string]any{"conditions": renewConditions(cert.Status.Conditions, time.Now())},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to marshal patch: %w", err)
+	}
+	_, err = c.output("patch", "certificates.cert-manager.io", name, "--subresource=status", "--type", "merge", "-p", string(patch))
+	return err
+}
+
+// renewConditions returns conditions with Issuing set to True, which a merge
+// patch replaces the certificate's conditions with.
+func renewConditions(conditions []certificateCondition, now time.Time) []certificateCondition {
+	out := make([]certificateCondition, 0, len(conditions)+1)
+	for _, cond := range conditions {
+		if cond.Type != "Issuing" {
+			out = append(out, cond)
+		}
+	}
+	return append(out, certificateCondition{
+		Type:               "Issuing",
+		Status:             "True",
+		Reason:             "ManuallyTriggered",
+		Message:            "Certificate re-issuance manually triggered",
+		LastTransitionTime: now.UTC().Format(time.RFC3339),
+	})
+}
+
+func parseCertificateList(data []byte) ([]*Certificate, error) {
+	var list struct {
+		Items []certificateJSON `json:"items"`
+	}
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse kubectl output: %w", err)
+	}
+
+	certs := make([]*Certificate, 0, len(list.Items))
+	for _, item := range list.Items {
+		certs = append(certs, item.toCertificate())
+	}
+	sort.Slice(certs, func(i, j int) bool { return certs[i].Name < certs[j].Name })
+	return certs, nil
+}
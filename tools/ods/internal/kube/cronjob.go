@@ -0,0 +1,74 @@
+package kube
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// CronJob is the subset of a Kubernetes CronJob's state ods reports on.
+type CronJob struct {
+	Name     string
+	Schedule string
+	Suspend  bool
+	// Active counts the jobs of this cronjob currently running.
+	Active         int
+	LastSchedule   time.Time
+	LastSuccessful time.Time
+}
+
+type cronJobJSON struct {
+	Metadata struct {
+		Name string `json:"name"`
+	} `json:"metadata"`
+	Spec struct {
+		Schedule string `json:"schedule"`
+		Suspend  bool   `json:"suspend"`
+	} `json:"spec"`
+	Status struct {
+		Active             []json.RawMessage `json:"active"`
+		LastScheduleTime   time.Time         `json:"lastScheduleTime"`
+		LastSuccessfulTime time.Time         `json:"lastSuccessfulTime"`
+	} `json:"status"`
+}
+
+// ListCronJobs returns every cronjob in the cluster's namespace, sorted by
+// name.
+func (c *Cluster) ListCronJobs() ([]*CronJob, error) {
+	out, err := c.output("get", "cronjobs", "-o", "json")
+	if err != nil {
+		return nil, err
+	}
+	return parseCronJobList(out)
+}
+
+// TriggerCronJob starts a job named jobName from a cronjob's template now,
+// like kubectl create job --from.
+func (c *Cluster) TriggerCronJob(name, jobName string) error {
+	_, err := c.output("create", "job", jobName, "--from=cronjob/"+name)
+	return err
+}
+
+func parseCronJobList(data []byte) ([]*CronJob, error) {
+	var list struct {
+		Items []cronJobJSON `json:"items"`
+	}
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse kubectl output: %w", err)
+	}
+
+	cronJobs := make([]*CronJob, 0, len(list.Items))
+	for _, item := range list.Items {
+		cronJobs = append(cronJobs, &CronJob{
+			Name:           item.Metadata.Name,
+			Schedule:       item.Spec.Schedule,
+			Suspend:        item.Spec.Suspend,
+			Active:         len(item.Status.Active),
+			LastSchedule:   item.Status.LastScheduleTime,
+			LastSuccessful: item.Status.LastSuccessfulTime,
+		})
+	}
+	sort.Slice(cronJobs, func(i, j int) bool { return cronJobs[i].Name < cronJobs[j].Name })
+	return cronJobs, nil
+}
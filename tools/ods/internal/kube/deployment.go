@@ -0,0 +1,209 @@
+package kube
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// Deployment is the subset of a Kubernetes Deployment's state ods reports on.
+type Deployment struct {
+	Name string
+	// Replicas is the desired replica count from the spec.
+	Replicas int
+	// CurrentReplicas counts every pod of the deployment, old and new.
+	CurrentReplicas int
+	UpdatedReplicas int
+	ReadyReplicas   int
+
+	Generation         int64
+	ObservedGeneration int64
+
+	// Containers are the containers of the deployment's pod template.
+	Containers []Container
+}
+
+// Container is a container of a pod spec or pod template.
+type Container struct {
+	Name  string
+	Image string
+}
+
+// Image returns the image of the deployment's first container, which for
+// every Onyx deployment is the component itself.
+func (d *Deployment) Image() string {
+	if len(d.Containers) == 0 {
+		return ""
+	}
+	return d.Containers[0].Image
+}
+
+// RolloutComplete reports whether the latest spec has been fully rolled out:
+// every desired replica is updated and ready and no old pods remain.
+func (d *Deployment) RolloutComplete() bool {
+	return d.ObservedGeneration >= d.Generation &&
+		d.UpdatedReplicas == d.Replicas &&
+		d.ReadyReplicas == d.Replicas &&
+		d.CurrentReplicas == d.Replicas
+}
+
+type deploymentJSON struct {
+	Metadata struct {
+		Name       string `json:"name"`
+		Generation int64  `json:"generation"`
+	} `json:"metadata"`
+	Spec struct {
+		Replicas *int `json:"replicas"`
+		Template struct {
+			Spec podSpecJSON `json:"spec"`
+		} `json:"template"`
+	} `json:"spec"`
+	Status struct {
+		ObservedGeneration int64 `json:"observedGeneration"`
+		Replicas           int   `json:"replicas"`
+		UpdatedReplicas    int   `json:"updatedReplicas"`
+		ReadyReplicas      int   `json:"readyReplicas"`
+	} `json:"status"`
+}
+
+func (d deploymentJSON) toDeployment() *Deployment {
+	// Replicas defaults to 1 when omitted from the spec.
+	replicas := 1
+	if d.Spec.Replicas != nil {
+		replicas = *d.Spec.Replicas
+	}
+	return &Deployment{
+		Name:               d.Metadata.Name,
+		Replicas:           replicas,
+		CurrentReplicas:    d.Status.Replicas,
+		UpdatedReplicas:    d.Status.UpdatedReplicas,
+		ReadyReplicas:      d.Status.ReadyReplicas,
+		Generation:         d.Metadata.Generation,
+		ObservedGeneration: d.Status.ObservedGeneration,
+		Containers:         d.Spec.Template.Spec.containers(),
+	}
+}
+
+type podSpecJSON struct {
+	Containers []struct {
+		Name  string `json:"name"`
+		Image string `json:"image"`
+	} `json:"containers"`
+}
+
+func (s podSpecJSON) containers() []Container {
+	containers := make([]Container, 0, len(s.Containers))
+	for _, c := range s.Containers {
+		containers = append(containers, Container{Name: c.Name, Image: c.Image})
+	}
+	return containers
+}
+
+// ListDeployments returns every deployment in the cluster's namespace, sorted
+// by name.
+func (c *Cluster) ListDeployments() ([]*Deployment, error) {
+	out, err := c.output("get", "deployments", "-o", "json")
+	if err != nil {
+		return nil, err
+	}
+
+	return parseDeploymentList(out)
+}
+
+func parseDeploymentList(data []byte) ([]*Deployment, error) {
+	var list struct {
+		Items []deploymentJSON `json:"items"`
+	}
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse kubectl output: %w", err)
+	}
+
+	deployments := make([]*Deployment, 0, len(list.Items))
+	for _, item := range list.Items {
+		deployments = append(deployments, item.toDeployment())
+	}
+	sort.Slice(deployments, func(i, j int) bool { return deployments[i].Name < deployments[j].Name })
+	return deployments, nil
+}
+
+// GetDeployment fetches a single deployment by name.
+func (c *Cluster) GetDeployment(name string) (*Deployment, error) {
+	out, err := c.output("get", "deployment", name, "-o", "json")
+	if err != nil {
+		return nil, err
+	}
+	var d deploymentJSON
+	if err := json.Unmarshal(out, &d); err != nil {
+		return nil, fmt.Errorf("failed to parse kubectl output: %w", err)
+	}
+	return d.toDeployment(), nil
+}
+
+// DeploymentPodSpec returns the pod spec of a deployment's template as
+// generic JSON, for building other workloads that run like it.
+func (c *Cluster) DeploymentPodSpec(name string) (map[string]any, error) {
+	out, err := c.output("get", "deployment", name, "-o", "json")
+	if err != nil {
+		return nil, err
+	}
+	var d struct {
+		Spec struct {
+			Template struct {
+				Spec map[string]any `json:"spec"`
+			} `json:"template"`
+		} `json:"spec"`
+	}
+	if err := json.Unmarshal(out, &d); err != nil {
+		return nil, fmt.Errorf("failed to parse kubectl output: %w", err)
+	}
+	return d.Spec.Template.Spec, nil
+}
+
+// ScaleDeployment sets a deployment's replica count.
+func (c *Cluster) ScaleDeployment(name string, replicas int) error {
+	_, err := c.output("scale", "deployment/"+name, "--replicas="+strconv.Itoa(replicas))
+	return err
+}
+
+// RolloutRestart triggers a rolling restart of the named deployments.
+func (c *Cluster) RolloutRestart(names ...string) error {
+	args := []string{"rollout", "restart"}
+	for _, n := range names {
+		args = append(args, "deployment/"+n)
+	}
+	_, err := c.output(args...)
+	return err
+}
+
+// AutoscaledDeployments returns the names of deployments targeted by a
+// HorizontalPodAutoscaler (including the ones KEDA creates for ScaledObjects),
+// whose replica counts will drift from any manual scale.
+func (c *Cluster) AutoscaledDeployments() (map[string]bool, error) {
+	out, err := c.output("get", "hpa", "-o", "json")
+	if err != nil {
+		return nil, err
+	}
+
+	var list struct {
+		Items []struct {
+			Spec struct {
+				ScaleTargetRef struct {
+					Kind string `json:"kind"`
+					Name string `json:"name"`
+				} `json:"scaleTargetRef"`
+			} `json:"spec"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(out, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse kubectl output: %w", err)
+	}
+
+	targets := make(map[string]bool)
+	for _, item := range list.Items {
+		if item.Spec.ScaleTargetRef.Kind == "Deployment" {
+			targets[item.Spec.ScaleTargetRef.Name] = true
+		}
+	}
+	return targets, nil
+}
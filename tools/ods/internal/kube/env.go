@@ -0,0 +1,184 @@
+package kube
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// EnvVar is an environment variable of a container with its value resolved
+// from the pod template, ConfigMaps and Secrets.
+type EnvVar struct {
+	Name  string
+	Value string
+	// Secret is set when the value comes from a Secret. Callers are
+	// responsible for redacting it before display.
+	Secret bool
+	// Unresolved describes a value ods cannot resolve, such as a field
+	// reference or a missing key; Value is empty.
+	Unresolved string
+}
+
+// ContainerEnv is the environment of one container of a deployment.
+type ContainerEnv struct {
+	Deployment string
+	Container  string
+	Vars       []EnvVar // sorted by name
+}
+
+// DeploymentEnvs returns the resolved environment of every container of
+// every deployment in the cluster's namespace.
+func (c *Cluster) DeploymentEnvs() ([]ContainerEnv, error) {
+	deployments, err := c.output("get", "deployments", "-o", "json")
+	if err != nil {
+		return nil, err
+	}
+	configMaps, err := c.output("get", "configmaps", "-o", "json")
+	if err != nil {
+		return nil, err
+	}
+	secretList, err := c.ListSecrets()
+	if err != nil {
+		return nil, err
+	}
+	secrets := make(map[string]map[string]string, len(secretList))
+	for _, s := range secretList {
+		secrets[s.Name] = s.Data
+	}
+	return resolveEnvs(deployments, configMaps, secrets)
+}
+
+type keyRefJSON struct {
+	Name string `json:"name"`
+	Key  string `json:"key"`
+}
+
+type envContainerJSON struct {
+	Name string `json:"name"`
+	Env  []struct {
+		Name      string `json:"name"`
+		Value     string `json:"value"`
+		ValueFrom *struct {
+			SecretKeyRef    *keyRefJSON `json:"secretKeyRef"`
+			ConfigMapKeyRef *keyRefJSON `json:"configMapKeyRef"`
+			FieldRef        *struct {
+				FieldPath string `json:"fieldPath"`
+			} `json:"fieldRef"`
+			ResourceFieldRef *struct {
+				Resource string `json:"resource"`
+			} `json:"resourceFieldRef"`
+		} `json:"valueFrom"`
+	} `json:"env"`
+	EnvFrom []struct {
+		Prefix       string      `json:"prefix"`
+		SecretRef    *keyRefJSON `json:"secretRef"`
+		ConfigMapRef *keyRefJSON `json:"configMapRef"`
+	} `json:"envFrom"`
+}
+
+func resolveEnvs(deploymentsJSON, configMapsJSON []byte, secrets map[string]map[string]string) ([]ContainerEnv, error) {
+	var deployments struct {
+		Items []struct {
+			Metadata struct {
+				Name string `json:"name"`
+			} `json:"metadata"`
+			Spec struct {
+				Template struct {
+					Spec struct {
+						Containers []envContainerJSON `json:"containers"`
+					} `json:"spec"`
+				} `json:"template"`
+			} `json:"spec"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(deploymentsJSON, &deployments); err != nil {
+		return nil, fmt.Errorf("failed to parse kubectl output: %w", err)
+	}
+	var configMapList struct {
+		Items []struct {
+			Metadata struct {
+				Name string `json:"name"`
+			} `json:"metadata"`
+			Data map[string]string `json:"data"`
+		} `json:"items"`
+	}
+	if err := json.Unmarshal(configMapsJSON, &configMapList); err != nil {
+		return nil, fmt.Errorf("failed to parse kubectl output: %w", err)
+	}
+	configMaps := make(map[string]map[string]string, len(configMapList.Items))
+	for _, cm := range configMapList.Items {
+		configMaps[cm.Metadata.Name] = cm.Data
+	}
+
+	var envs []ContainerEnv
+	for _, d := range deployments.Items {
+		for _, container := range d.Spec.Template.Spec.Containers {
+			envs = append(envs, ContainerEnv{
+				Deployment: d.Metadata.Name,
+				Container:  container.Name,
+				Vars:       resolveContainerEnv(container, configMaps, secrets),
+			})
+		}
+	}
+	sort.Slice(envs, func(i, j int) bool {
+		if envs[i].Deployment != envs[j].Deployment {
+			return envs[i].Deployment < envs[j].Deployment
+		}
+		return envs[i].Container < envs[j].Container
+	})
+	return envs, nil
+}
+
+// resolveContainerEnv applies envFrom sources, then env entries, with later
+// definitions of a name winning as they do in Kubernetes.
+func resolveContainerEnv(container envContainerJSON, configMaps, secrets map[string]map[string]string) []EnvVar {
+	vars := map[string]EnvVar{}
+	for _, from := range container.EnvFrom {
+		switch {
+		case from.ConfigMapRef != nil:
+			for k, v := range configMaps[from.ConfigMapRef.Name] {
+				vars[from.Prefix+k] = EnvVar{Name: from.Prefix + k, Value: v}
+			}
+		case from.SecretRef != nil:
+			for k, v := range secrets[from.SecretRef.Name] {
+				vars[from.Prefix+k] = EnvVar{Name: from.Prefix + k, Value: v, Secret: true}
+			}
+		}
+	}
+	for _, e := range container.Env {
+		v := EnvVar{Name: e.Name, Value: e.Value}
+		if from := e.ValueFrom; from != nil {
+			switch {
+			case from.SecretKeyRef != nil:
+				v.Secret = true
+				v.Value, v.Unresolved = lookupKey(secrets, "secret", from.SecretKeyRef)
+			case from.ConfigMapKeyRef != nil:
+				v.Value, v.Unresolved = lookupKey(configMaps, "configmap", from.ConfigMapKeyRef)
+			case from.FieldRef != nil:
+				v.Unresolved = "field " + from.FieldRef.FieldPath
+			case from.ResourceFieldRef != nil:
+				v.Unresolved = "resource " + from.ResourceFieldRef.Resource
+			}
+		}
+		vars[e.Name] = v
+	}
+
+	out := make([]EnvVar, 0, len(vars))
+	for _, v := range vars {
+		out = append(out, v)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Name < out[j].Name })
+	return out
+}
+
+func lookupKey(sources map[string]map[string]string, kind string, ref *keyRefJSON) (value, unresolved string) {
+	data, ok := sources[ref.Name]
+	if !ok {
+		return "", fmt.Sprintf("missing %s %s", kind, ref.Name)
+	}
+	value, ok = data[ref.Key]
+	if !ok {
+		return "", fmt.Sprintf("missing key %s in %s %s", ref.Key, kind, ref.Name)
+	}
+	return value, ""
+}
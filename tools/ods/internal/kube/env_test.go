@@ -0,0 +1,49 @@
+package kube
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestResolveEnvs(t *testing.T) {
+	deployments := []byte(`{"items": [{
+		"metadata": {"name": "api-server"},
+		"spec": {"template": {"spec": {"containers": [{
+			"name": "api-server",
+			"envFrom": [
+				{"configMapRef": {"name": "env-configmap"}},
+				{"secretRef": {"name": "onyx-secrets"}, "prefix": "S_"}
+			],
+			"env": [
+				{"name": "LOG_LEVEL", "value": "debug"},
+				{"name": "POSTGRES_PASSWORD", "valueFrom": {"secretKeyRef": {"name": "pg", "key": "password"}}},
+				{"name": "REDIS_HOST", "valueFrom": {"configMapKeyRef": {"name": "env-configmap", "key": "MISSING"}}},
+				{"name": "POD_NAME", "valueFrom": {"fieldRef": {"fieldPath": "metadata.name"}}}
+			]
+		}]}}}
+	}]}`)
+	configMaps := []byte(`{"items": [{"metadata": {"name": "env-configmap"}, "data": {"LOG_LEVEL": "info", "AUTH_TYPE": "oidc"}}]}`)
+	secrets := map[string]map[string]string{
+		"onyx-secrets": {"TOKEN": "t0k"},
+		"pg":           {"password": "hunter2"},
+	}
+
+	envs, err := resolveEnvs(deployments, configMaps, secrets)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(envs) != 1 || envs[0].Deployment != "api-server" || envs[0].Container != "api-server" {
+		t.Fatalf("unexpected containers %+v", envs)
+	}
+	want := []EnvVar{
+		{Name: "AUTH_TYPE", Value: "oidc"},
+		{Name: "LOG_LEVEL", Value: "debug"},
+		{Name: "POD_NAME", Unresolved: "field metadata.name"},
+		{Name: "POSTGRES_PASSWORD", Value: "hunter2", Secret: true},
+		{Name: "REDIS_HOST", Unresolved: "missing key MISSING in configmap env-configmap"},
+		{Name: "S_TOKEN", Value: "t0k", Secret: true},
+	}
+	if !reflect.DeepEqual(envs[0].Vars, want) {
+		t.Errorf("got %+v\nwant %+v", envs[0].Vars, want)
+	}
+}
@@ -0,0 +1,92 @@
+package kube
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Event is a Kubernetes event about an object in the namespace.
+type Event struct {
+	Kind    string
+	Object  string
+	Type    string
+	Reason  string
+	Message string
+	Count   int
+	// LastSeen is when the event last fired.
+	LastSeen time.Time
+}
+
+type eventJSON struct {
+	InvolvedObject struct {
+		Kind string `json:"kind"`
+		Name string `json:"name"`
+	} `json:"involvedObject"`
+	Type           string    `json:"type"`
+	Reason         string    `json:"reason"`
+	Message        string    `json:"message"`
+	Count          int       `json:"count"`
+	FirstTimestamp time.Time `json:"firstTimestamp"`
+	LastTimestamp  time.Time `json:"lastTimestamp"`
+	EventTime      time.Time `json:"eventTime"`
+	Series         *struct {
+		Count            int       `json:"count"`
+		LastObservedTime time.Time `json:"lastObservedTime"`
+	} `json:"series"`
+}
+
+func (e eventJSON) toEvent() *Event {
+	ev := &Event{
+		Kind:    e.InvolvedObject.Kind,
+		Object:  e.InvolvedObject.Name,
+		Type:    e.Type,
+		Reason:  e.Reason,
+		Message: e.Message,
+		Count:   e.Count,
+	}
+	// Newer components report eventTime/series instead of the legacy
+	// timestamps and count.
+	switch {
+	case e.Series != nil && !e.Series.LastObservedTime.IsZero():
+		ev.LastSeen = e.Series.LastObservedTime
+		ev.Count = e.Series.Count
+	case !e.LastTimestamp.IsZero():
+		ev.LastSeen = e.LastTimestamp
+	case !e.EventTime.IsZero():
+		ev.LastSeen = e.EventTime
+	default:
+		ev.LastSeen = e.FirstTimestamp
+	}
+	if ev.Count == 0 {
+		ev.Count = 1
+	}
+	return ev
+}
+
+// ListWarningEvents returns the Warning events in the cluster's namespace,
+// most recent first.
+func (c *Cluster) ListWarningEvents() ([]*Event, error) {
+	out, err := c.output("get", "events", "--field-selector", "type=Warning", "-o", "json")
+	if err != nil {
+		return nil, err
+	}
+	return parseEventList(out)
+}
+
+func parseEventList(data []byte) ([]*Event, error) {
+	var list struct {
+		Items []eventJSON `json:"items"`
+	}
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse kubectl output: %w", err)
+	}
+
+	events := make([]*Event, 0, len(list.Items))
+	for _, item := range list.Items {
+		events = append(events, item.toEvent())
+	}
+	sort.SliceStable(events, func(i, j int) bool { return events[i].LastSeen.After(events[j].LastSeen) })
+	return events, nil
+}
@@ -0,0 +1,26 @@
+package kube
+
+import "testing"
+
+func TestParseEventList(t *testing.T) {
+	data := []byte(`{"items":[
+		{"involvedObject":{"kind":"Pod","name":"api-0"},"type":"Warning","reason":"BackOff","message":"Back-off restarting failed container",
+		 "count":5,"lastTimestamp":"2026-01-02T03:00:00Z"},
+		{"involvedObject":{"kind":"Pod","name":"web-0"},"type":"Warning","reason":"FailedScheduling","message":"0/3 nodes are available",
+		 "eventTime":"2026-01-02T04:00:00.000000Z","series":{"count":3,"lastObservedTime":"2026-01-02T05:00:00.000000Z"}}
+	]}`)
+
+	events, err := parseEventList(data)
+	if err != nil {
+		t.Fatalf("parseEventList() error: %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(events))
+	}
+	if events[0].Object != "web-0" || events[0].Count != 3 || events[0].LastSeen.Hour() != 5 {
+		t.Errorf("expected the series event first with its series count and time, got %+v", events[0])
+	}
+	if events[1].Object != "api-0" || events[1].Count != 5 {
+		t.Errorf("unexpected legacy event %+v", events[1])
+	}
+}
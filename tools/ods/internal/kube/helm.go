@@ -0,0 +1,60 @@
+package kube
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/runner"
+)
+
+// Helm returns a helm command targeting this cluster and namespace, to run
+// with c.Runner.
+func (c *Cluster) Helm(args ...string) runner.Cmd {
+	args = append([]string{"--kube-context", c.Name, "--namespace", c.Namespace}, args...)
+	log.Debugf("Running: helm %s", strings.Join(args, " "))
+	return runner.Cmd{Name: "helm", Args: args, Env: c.env()}
+}
+
+// EnsureNamespace creates the cluster's namespace if it does not exist and
+// sets labels on it.
+func (c *Cluster) EnsureNamespace(labels map[string]string) error {
+	if _, err := c.output("get", "namespace", c.Namespace, "-o", "name"); err != nil {
+		if _, err := c.output("create", "namespace", c.Namespace); err != nil {
+			return err
+		}
+	}
+	if len(labels) == 0 {
+		return nil
+	}
+	args := []string{"label", "namespace", c.Namespace, "--overwrite"}
+	keys := make([]string, 0, len(labels))
+	for k := range labels {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	for _, k := range keys {
+		args = append(args, fmt.Sprintf("%s=%s", k, labels[k]))
+	}
+	_, err := c.output(args...)
+	return err
+}
+
+// DeleteNamespace deletes the cluster's namespace and everything in it,
+// without waiting for the deletion to finish.
+func (c *Cluster) DeleteNamespace() error {
+	_, err := c.output("delete", "namespace", c.Namespace, "--ignore-not-found", "--wait=false")
+	return err
+}
+
+// ListNamespaces returns the names of the namespaces matching a label
+// selector.
+func (c *Cluster) ListNamespaces(selector string) ([]string, error) {
+	out, err := c.output("get", "namespaces", "-l", selector, "-o", "jsonpath={.items[*].metadata.name}")
+	if err != nil {
+		return nil, err
+	}
+	return strings.Fields(string(out)), nil
+}
@@ -0,0 +1,189 @@
+package kube
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+)
+
+// Ingress is the subset of a Kubernetes Ingress ods reports on.
+type Ingress struct {
+	Name   string
+	Class  string
+	Labels map[string]string
+	// Hosts are the hosts of the ingress's rules.
+	Hosts []string
+	// Backends are where the ingress's rules route requests, in rule order,
+	// followed by its default backend.
+	Backends []IngressBackend
+	TLS      []IngressTLS
+	// LoadBalancer holds the hostnames and IPs the ingress controller
+	// publishes for the ingress.
+	LoadBalancer []string
+}
+
+// IngressBackend is a service an ingress routes a host and path to. Host is
+// "" for a rule without one or the default backend, which match any host.
+type IngressBackend struct {
+	Host string
+	Path string
+	// Service is "" for a resource backend.
+	Service string
+	// Port is the service port's number or name.
+	Port string
+}
+
+// BackendsFor returns the backends that serve host.
+func (i *Ingress) BackendsFor(host string) []IngressBackend {
+	var out []IngressBackend
+	for _, b := range i.Backends {
+		if b.Host == host {
+			out = append(out, b)
+		}
+	}
+	if len(out) > 0 {
+		return out
+	}
+	for _, b := range i.Backends {
+		if b.Host == "" {
+			out = append(out, b)
+		}
+	}
+	return out
+}
+
+// IngressTLS is a TLS block of an ingress: the hosts served with the
+// certificate in SecretName.
+type IngressTLS struct {
+	Hosts      []string
+	SecretName string
+}
+
+type ingressJSON struct {
+	Metadata struct {
+		Name        string            `json:"name"`
+		Labels      map[string]string `json:"labels"`
+		Annotations map[string]string `json:"annotations"`
+	} `json:"metadata"`
+	Spec struct {
+		IngressClassName string              `json:"ingressClassName"`
+		DefaultBackend   *ingressBackendJSON `json:"defaultBackend"`
+		Rules            []struct {
+			Host string `json:"host"`
+			HTTP struct {
+				Paths []struct {
+					Path    string             `json:"path"`
+					Backend ingressBackendJSON `json:"backend"`
+				} `json:"paths"`
+			} `json:"http"`
+		} `json:"rules"`
+		TLS []struct {
+			Hosts      []string `json:"hosts"`
+			SecretName string   `json:"secretName"`
+		} `json:"tls"`
+	} `json:"spec"`
+	Status struct {
+		LoadBalancer struct {
+			Ingress []struct {
+				Hostname string `json:"hostname"`
+				IP       string `json:"ip"`
+			} `json:"ingress"`
+		} `json:"loadBalancer"`
+	} `json:"status"`
+}
+
+type ingressBackendJSON struct {
+	Service *struct {
+		Name string `json:"name"`
+		Port struct {
+			Number int    `json:"number"`
+			Name   string `json:"name"`
+		} `json:"port"`
+	} `json:"service"`
+}
+
+func (b ingressBackendJSON) toBackend(host, path string) IngressBackend {
+	backend := IngressBackend{Host: host, Path: path}
+	if b.Service != nil {
+		backend.Service = b.Service.Name
+		backend.Port = b.Service.Port.Name
+		if b.Service.Port.Number != 0 {
+			backend.Port = strconv.Itoa(b.Service.Port.Number)
+		}
+	}
+	return backend
+}
+
+func (i ingressJSON) toIngress() *Ingress {
+	ing := &Ingress{
+		Name:   i.Metadata.Name,
+		Class:  i.Spec.IngressClassName,
+		Labels: i.Metadata.Labels,
+	}
+	if ing.Class == "" {
+		ing.Class = i.Metadata.Annotations["kubernetes.io/ingress.class"]
+	}
+	for _, r := range i.Spec.Rules {
+		if r.Host != "" {
+			ing.Hosts = append(ing.Hosts, r.Host)
+		}
+		for _, p := range r.HTTP.Paths {
+			ing.Backends = append(ing.Backends, p.Backend.toBackend(r.Host, p.Path))
+		}
+	}
+	if b := i.Spec.DefaultBackend; b != nil {
+		ing.Backends = append(ing.Backends, b.toBackend("", ""))
+	}
+	for _, t := range i.Spec.TLS {
+		ing.TLS = append(ing.TLS, IngressTLS{Hosts: t.Hosts, SecretName: t.SecretName})
+	}
+	for _, lb := range i.Status.LoadBalancer.Ingress {
+		if lb.Hostname != "" {
+			ing.LoadBalancer = append(ing.LoadBalancer, lb.Hostname)
+		}
+		if lb.IP != "" {
+			ing.LoadBalancer = append(ing.LoadBalancer, lb.IP)
+		}
+	}
+	return ing
+}
+
+// TLSSecret returns the name of the secret the ingress serves host's
+// certificate from, or "" when host is served without TLS.
+func (i *Ingress) TLSSecret(host string) string {
+	for _, t := range i.TLS {
+		for _, h := range t.Hosts {
+			if h == host {
+				return t.SecretName
+			}
+		}
+	}
+	return ""
+}
+
+// ListIngresses returns every ingress in the cluster's namespace, sorted by
+// name.
+func (c *Cluster) ListIngresses() ([]*Ingress, error) {
+	out, err := c.output("get", "ingresses", "-o", "json")
+	if err != nil {
+		return nil, err
+	}
+	return parseIngressList(out)
+}
+
+func parseIngressList(data []byte) ([]*Ingress, error) {
+	var list struct {
+		Items []ingressJSON `json:"items"`
+	}
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse kubectl output: %w", err)
+	}
+
+	ingresses := make([]*Ingress, 0, len(list.Items))
+	for _, item := range list.Items {
+		ingresses = append(ingresses, item.toIngress())
+	}
+	sort.Slice(ingresses, func(i, j int) bool { return ingresses[i].Name < ingresses[j].Name })
+	return ingresses, nil
+}
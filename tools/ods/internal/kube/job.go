@@ -0,0 +1,167 @@
+package kube
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Job is the subset of a Kubernetes Job's state ods reports on.
+type Job struct {
+	Name    string
+	Created time.Time
+	// CronJob is the cronjob that created the job, if any.
+	CronJob string
+	// Completions is how many pods must succeed; 1 when omitted.
+	Completions int
+	Active      int
+	Succeeded   int
+	Failed      int
+	// Condition is "Complete" or "Failed" once the job has ended, and empty
+	// while it runs. Reason explains a failure, e.g. "BackoffLimitExceeded".
+	Condition  string
+	Reason     string
+	StartTime  time.Time
+	FinishTime time.Time
+	Containers []Container
+}
+
+// Finished reports whether the job has completed or failed for good.
+func (j *Job) Finished() bool {
+	return j.Condition != ""
+}
+
+// Status summarises the job as Running, Complete or Failed.
+func (j *Job) Status() string {
+	if j.Condition == "" {
+		return "Running"
+	}
+	return j.Condition
+}
+
+type jobJSON struct {
+	Metadata struct {
+		Name              string    `json:"name"`
+		CreationTimestamp time.Time `json:"creationTimestamp"`
+		OwnerReferences   []struct {
+			Kind string `json:"kind"`
+			Name string `json:"name"`
+		} `json:"ownerReferences"`
+	} `json:"metadata"`
+	Spec struct {
+		Completions *int `json:"completions"`
+		Template    struct {
+			Spec podSpecJSON `json:"spec"`
+		} `json:"template"`
+	} `json:"spec"`
+	Status struct {
+		Active         int       `json:"active"`
+		Succeeded      int       `json:"succeeded"`
+		Failed         int       `json:"failed"`
+		StartTime      time.Time `json:"startTime"`
+		CompletionTime time.Time `json:"completionTime"`
+		Conditions     []struct {
+			Type               string    `json:"type"`
+			Status             string    `json:"status"`
+			Reason             string    `json:"reason"`
+			LastTransitionTime time.Time `json:"lastTransitionTime"`
+		} `json:"conditions"`
+	} `json:"status"`
+}
+
+func (j jobJSON) toJob() *Job {
+	completions := 1
+	if j.Spec.Completions != nil {
+		completions = *j.Spec.Completions
+	}
+	job := &Job{
+		Name:        j.Metadata.Name,
+		Created:     j.Metadata.CreationTimestamp,
+		Completions: completions,
+		Active:      j.Status.Active,
+		Succeeded:   j.Status.Succeeded,
+		Failed:      j.Status.Failed,
+		StartTime:   j.Status.StartTime,
+		FinishTime:  j.Status.CompletionTime,
+		Containers:  j.Spec.Template.Spec.containers(),
+	}
+	for _, owner := range j.Metadata.OwnerReferences {
+		if owner.Kind == "CronJob" {
+			job.CronJob = owner.Name
+		}
+	}
+	for _, cond := range j.Status.Conditions {
+		if cond.Status != "True" {
+			continue
+		}
+		switch cond.Type {
+		case "Complete":
+			job.Condition = cond.Type
+		case "Failed":
+			job.Condition = cond.Type
+			job.Reason = cond.Reason
+			// Failed jobs have no completionTime.
+			job.FinishTime = cond.LastTransitionTime
+		}
+	}
+	return job
+}
+
+// ListJobs returns every job in the cluster's namespace, newest first.
+func (c *Cluster) ListJobs() ([]*Job, error) {
+	out, err := c.output("get", "jobs", "-o", "json")
+	if err != nil {
+		return nil, err
+	}
+	return parseJobList(out)
+}
+
+// GetJob fetches a single job by name.
+func (c *Cluster) GetJob(name string) (*Job, error) {
+	out, err := c.output("get", "job", name, "-o", "json")
+	if err != nil {
+		return nil, err
+	}
+	var j jobJSON
+	if err := json.Unmarshal(out, &j); err != nil {
+		return nil, fmt.Errorf("failed to parse kubectl output: %w", err)
+	}
+	return j.toJob(), nil
+}
+
+// CreateJob creates a job from its JSON (or YAML) manifest.
+func (c *Cluster) CreateJob(manifest []byte) error {
+	cmd := c.kubectlCmd("create", "-f", "-")
+	cmd.Stdin = bytes.NewReader(manifest)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := c.run(cmd); err != nil {
+		return fmt.Errorf("kubectl create failed: %w\n%s", err, stderr.String())
+	}
+	return nil
+}
+
+// DeleteJob deletes a job and its pods. Deleting a job that no longer exists
+// is not an error.
+func (c *Cluster) DeleteJob(name string) error {
+	_, err := c.output("delete", "job", name, "--ignore-not-found", "--cascade=background")
+	return err
+}
+
+func parseJobList(data []byte) ([]*Job, error) {
+	var list struct {
+		Items []jobJSON `json:"items"`
+	}
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse kubectl output: %w", err)
+	}
+
+	jobs := make([]*Job, 0, len(list.Items))
+	for _, item := range list.Items {
+		jobs = append(jobs, item.toJob())
+	}
+	sort.Slice(jobs, func(i, j int) bool { return jobs[i].Created.After(jobs[j].Created) })
+	return jobs, nil
+}
@@ -2,11 +2,20 @@ package kube
 
 import (
 	"bytes"
+	"encoding/json"
 	"fmt"
+	"io"
+	"os"
 	"os/exec"
+	"slices"
 	"strings"
+	"time"
 
 	log "github.com/sirupsen/logrus"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/paths"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/render"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/runner"
 )
 
 // Cluster holds the connection info for a Kubernetes cluster.
@@ -14,57 +23,204 @@ type Cluster struct {
 	Name      string
 	Region    string
 	Namespace string
+
+	// Profile is the AWS profile used to authenticate to the cluster. Empty
+	// means whatever the shell has exported.
+	Profile string
+	// RoleARN is an optional IAM role assumed (from Profile) when fetching
+	// cluster tokens and running aws.
+	RoleARN string
+
+	// Runner runs kubectl and aws; nil means runner.Default. Tests set a
+	// runner.Fake.
+	Runner runner.Runner
+}
+
+// ParseClusterSpec parses a space-separated cluster tuple:
+//
+//	<cluster> <region> <namespace> [<aws-profile> [<role-arn>]]
+//
+// A profile of "-" means the shell's default, so a role can be given without
+// pinning a profile.
+func ParseClusterSpec(spec string) (*Cluster, error) {
+	parts := strings.Fields(spec)
+	if len(parts) < 3 || len(parts) > 5 {
+		return nil, fmt.Errorf("expected 3 to 5 space-separated values (cluster region namespace [aws-profile [role-arn]]), got %d", len(parts))
+	}
+
+	c := &Cluster{Name: parts[0], Region: parts[1], Namespace: parts[2]}
+	if len(parts) > 3 && parts[3] != "-" {
+		c.Profile = parts[3]
+	}
+	if len(parts) > 4 {
+		if !strings.HasPrefix(parts[4], "arn:") {
+			return nil, fmt.Errorf("role %q is not an ARN", parts[4])
+		}
+		c.RoleARN = parts[4]
+	}
+	return c, nil
 }
 
 // EnsureContext makes sure the cluster exists in kubeconfig, calling
-// aws eks update-kubeconfig only if the context is missing.
+// aws eks update-kubeconfig only if the context is missing or was written
+// with a different AWS profile or role than this cluster is configured with.
 func (c *Cluster) EnsureContext() error {
 	// Check if context already exists in kubeconfig
-	cmd := exec.Command("kubectl", "config", "get-contexts", c.Name, "--no-headers")
-	if err := cmd.Run(); err == nil {
-		log.Debugf("Context %s already exists, skipping aws eks update-kubeconfig", c.Name)
-		return nil
+	out, err := runner.Output(c.Runner, runner.Cmd{Name: "kubectl", Args: []string{"config", "view", "--minify", "--context", c.Name, "-o", "json"}})
+	if err == nil {
+		if c.kubeconfigMatches(out) {
+			log.Debugf("Context %s already exists, skipping aws eks update-kubeconfig", c.Name)
+			return nil
+		}
+		log.Infof("Context %s uses different AWS credentials, refreshing kubeconfig from AWS...", c.Name)
+	} else {
+		log.Infof("Context %s not found, fetching kubeconfig from AWS...", c.Name)
 	}
 
-	log.Infof("Context %s not found, fetching kubeconfig from AWS...", c.Name)
-	cmd = exec.Command("aws", "eks", "update-kubeconfig", "--region", c.Region, "--name", c.Name, "--alias", c.Name)
-	if out, err := cmd.CombinedOutput(); err != nil {
+	args := []string{"eks", "update-kubeconfig", "--region", c.Region, "--name", c.Name, "--alias", c.Name}
+	if c.Profile != "" {
+		// Recorded in the kubeconfig's exec env, so kubectl keeps using it.
+		args = append(args, "--profile", c.Profile)
+	}
+	if c.RoleARN != "" {
+		args = append(args, "--role-arn", c.RoleARN)
+	}
+	if out, err := runner.CombinedOutput(c.Runner, runner.Cmd{Name: "aws", Args: args, Env: c.env()}); err != nil {
 		return fmt.Errorf("aws eks update-kubeconfig failed: %w\n%s", err, string(out))
 	}
 
 	return nil
 }
 
+// InKubeconfig reports whether the cluster's context exists in kubeconfig,
+// without fetching it from AWS as EnsureContext would.
+func (c *Cluster) InKubeconfig() bool {
+	return runner.Or(c.Runner).Run(runner.Cmd{Name: "kubectl", Args: []string{"config", "get-contexts", c.Name}}) == nil
+}
+
+// WhoAmI returns the user the API server authenticates this cluster's
+// credentials as, giving up after timeout.
+func (c *Cluster) WhoAmI(timeout time.Duration) (string, error) {
+	out, err := c.output("auth", "whoami", "--request-timeout", timeout.String(), "-o", "jsonpath={.status.userInfo.username}")
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// CurrentContext returns kubectl's current context, or "" if none is set.
+func CurrentContext() string {
+	out, err := runner.Output(nil, runner.Cmd{Name: "kubectl", Args: []string{"config", "current-context"}})
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// kubeconfigMatches reports whether a minified kubeconfig (as JSON) for this
+// context authenticates with the configured profile and role.
+func (c *Cluster) kubeconfigMatches(kubeconfig []byte) bool {
+	var cfg struct {
+		Users []struct {
+			User struct {
+				Exec *struct {
+					Args []string `json:"args"`
+					Env  []struct {
+						Name  string `json:"name"`
+						Value string `json:"value"`
+					} `json:"env"`
+				} `json:"exec"`
+			} `json:"user"`
+		} `json:"users"`
+	}
+	if err := json.Unmarshal(kubeconfig, &cfg); err != nil || len(cfg.Users) == 0 {
+		return false
+	}
+
+	auth := cfg.Users[0].User.Exec
+	if auth == nil {
+		// Not an aws-authenticated context; nothing we can check.
+		return c.Profile == "" && c.RoleARN == ""
+	}
+
+	profile := ""
+	for _, e := range auth.Env {
+		if e.Name == "AWS_PROFILE" {
+			profile = e.Value
+		}
+	}
+	if c.Profile != "" && profile != c.Profile {
+		return false
+	}
+
+	role := ""
+	if i := slices.Index(auth.Args, "--role-arn"); i >= 0 && i+1 < len(auth.Args) {
+		role = auth.Args[i+1]
+	}
+	return role == c.RoleARN
+}
+
+// env returns the environment for aws and kubectl subprocesses, pinning
+// AWS_PROFILE when the cluster has one configured.
+func (c *Cluster) env() []string {
+	env := os.Environ()
+	if c.Profile != "" {
+		env = append(env, "AWS_PROFILE="+c.Profile)
+	}
+	return env
+}
+
+// kubectl returns a kubectl process targeting this cluster, for the
+// long-running port-forward; everything else goes through run.
+func (c *Cluster) kubectl(args ...string) *exec.Cmd {
+	args = append(c.kubectlArgs(), args...)
+	log.Debugf("Running: kubectl %s", strings.Join(args, " "))
+
+	cmd := exec.Command(paths.Executable("kubectl"), args...)
+	cmd.Env = c.env()
+	return cmd
+}
+
 // kubectlArgs returns common kubectl flags to target this cluster without mutating global context.
 func (c *Cluster) kubectlArgs() []string {
 	return []string{"--context", c.Name, "--namespace", c.Namespace}
 }
 
+// kubectlCmd returns a kubectl command targeting this cluster.
+func (c *Cluster) kubectlCmd(args ...string) runner.Cmd {
+	args = append(c.kubectlArgs(), args...)
+	log.Debugf("Running: kubectl %s", strings.Join(args, " "))
+	return runner.Cmd{Name: "kubectl", Args: args, Env: c.env()}
+}
+
+// run runs a kubectl command with c.Runner.
+func (c *Cluster) run(cmd runner.Cmd) error {
+	return runner.Or(c.Runner).Run(cmd)
+}
+
+// output runs kubectl against this cluster and returns its stdout. On failure
+// the returned error includes kubectl's stderr.
+func (c *Cluster) output(args ...string) ([]byte, error) {
+	cmd := c.kubectlCmd(args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := c.run(cmd); err != nil {
+		return nil, fmt.Errorf("kubectl %s failed: %w\n%s", args[0], err, stderr.String())
+	}
+	return stdout.Bytes(), nil
+}
+
 // FindPod returns the name of the first Running/Ready pod matching the given substring.
 func (c *Cluster) FindPod(substring string) (string, error) {
-	args := append(c.kubectlArgs(), "get", "po",
-		"--field-selector", "status.phase=Running",
-		"--no-headers",
-		"-o", "custom-columns=NAME:.metadata.name,READY:.status.conditions[?(@.type=='Ready')].status",
-	)
-	cmd := exec.Command("kubectl", args...)
-	out, err := cmd.Output()
+	pods, err := c.ListPods()
 	if err != nil {
-		if exitErr, ok := err.(*exec.ExitError); ok {
-			return "", fmt.Errorf("kubectl get po failed: %w\n%s", err, string(exitErr.Stderr))
-		}
-		return "", fmt.Errorf("kubectl get po failed: %w", err)
+		return "", err
 	}
-
-	for _, line := range strings.Split(strings.TrimSpace(string(out)), "\n") {
-		fields := strings.Fields(line)
-		if len(fields) < 2 {
-			continue
-		}
-		name, ready := fields[0], fields[1]
-		if strings.Contains(name, substring) && ready == "True" {
-			log.Debugf("Found pod: %s", name)
-			return name, nil
+	for _, p := range pods {
+		if p.Phase == "Running" && p.Ready && strings.Contains(p.Name, substring) {
+			log.Debugf("Found pod: %s", p.Name)
+			return p.Name, nil
 		}
 	}
 
@@ -73,18 +229,137 @@ func (c *Cluster) FindPod(substring string) (string, error) {
 
 // ExecOnPod runs a command on a pod and returns its stdout.
 func (c *Cluster) ExecOnPod(pod string, command ...string) (string, error) {
-	args := append(c.kubectlArgs(), "exec", pod, "--")
-	args = append(args, command...)
-	log.Debugf("Running: kubectl %s", strings.Join(args, " "))
+	return c.ExecOnPodWithStdin(pod, nil, command...)
+}
+
+// ExecOnPodCombined runs a command on a pod and returns its stdout and
+// stderr interleaved, whether or not it fails, for commands such as nginx -t
+// that report on stderr.
+func (c *Cluster) ExecOnPodCombined(pod string, command ...string) (string, error) {
+	out, err := runner.CombinedOutput(c.Runner, c.kubectlCmd(append([]string{"exec", pod, "--"}, command...)...))
+	if err != nil {
+		return string(out), fmt.Errorf("kubectl exec failed: %w", err)
+	}
+	return string(out), nil
+}
 
-	cmd := exec.Command("kubectl", args...)
+// ExecOnPodWithStdin runs a command on a pod with stdin attached and returns
+// its stdout. A nil stdin behaves like ExecOnPod.
+func (c *Cluster) ExecOnPodWithStdin(pod string, stdin io.Reader, command ...string) (string, error) {
+	args := []string{"exec", pod}
+	if stdin != nil {
+		args = append(args, "-i")
+	}
+	cmd := c.kubectlCmd(append(append(args, "--"), command...)...)
+	cmd.Stdin = stdin
 	var stdout, stderr bytes.Buffer
 	cmd.Stdout = &stdout
 	cmd.Stderr = &stderr
 
-	if err := cmd.Run(); err != nil {
+	if err := c.run(cmd); err != nil {
 		return "", fmt.Errorf("kubectl exec failed: %w\n%s", err, stderr.String())
 	}
 
 	return stdout.String(), nil
 }
+
+// ExecOnPodStreaming runs a command on a pod, streaming its output to the
+// terminal. Use it for long-running commands whose progress matters.
+func (c *Cluster) ExecOnPodStreaming(pod string, command ...string) error {
+	cmd := c.kubectlCmd(append([]string{"exec", pod, "--"}, command...)...)
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := c.run(cmd); err != nil {
+		return fmt.Errorf("kubectl exec failed: %w", err)
+	}
+	return nil
+}
+
+// InteractiveOptions configure ExecInteractive.
+type InteractiveOptions struct {
+	// Container is the container to exec in; "" picks the pod's default.
+	Container string
+	// NoTTY keeps stdin attached but never allocates a TTY, for commands
+	// whose output is parsed even when run from a terminal.
+	NoTTY bool
+}
+
+// ExecInteractive runs command on pod attached to the caller's stdin, stdout
+// and stderr, for shells, psql and redis-cli. A TTY is allocated when stdin
+// and stdout are both terminals; kubectl then puts the local terminal in raw
+// mode and forwards window resizes until the command exits. With piped input
+// no TTY is allocated, so `echo 'select 1' | ...` works too. The remote
+// command's exit status is available through errors.As with *exec.ExitError.
+func (c *Cluster) ExecInteractive(pod string, opts InteractiveOptions, command ...string) error {
+	tty := !opts.NoTTY && render.IsTerminal(os.Stdin) && render.IsTerminal(os.Stdout)
+	cmd := c.kubectlCmd(interactiveExecArgs(pod, opts.Container, tty, command)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := c.run(cmd); err != nil {
+		return fmt.Errorf("kubectl exec failed: %w", err)
+	}
+	return nil
+}
+
+func interactiveExecArgs(pod, container string, tty bool, command []string) []string {
+	args := []string{"exec", "-i"}
+	if tty {
+		args = append(args, "-t")
+	}
+	args = append(args, pod)
+	if container != "" {
+		args = append(args, "--container", container)
+	}
+	return append(append(args, "--"), command...)
+}
+
+// CopyFromPod streams the file at path on pod into w. Unlike kubectl cp it
+// does not need tar in the container.
+func (c *Cluster) CopyFromPod(pod, path string, w io.Writer) error {
+	cmd := c.kubectlCmd("exec", pod, "--", "cat", path)
+	var stderr bytes.Buffer
+	cmd.Stdout = w
+	cmd.Stderr = &stderr
+	if err := c.run(cmd); err != nil {
+		return fmt.Errorf("copying %s from %s failed: %w\n%s", path, pod, err, stderr.String())
+	}
+	return nil
+}
+
+// RunPython pipes a Python script into `python -` on a pod (so it runs with
+// the backend's code and environment) and returns its stdout.
+func (c *Cluster) RunPython(pod, script string, args ...string) (string, error) {
+	return c.ExecOnPodWithStdin(pod, strings.NewReader(script), append([]string{"python", "-"}, args...)...)
+}
+
+// RunDebugContainer runs command in an ephemeral container of image attached
+// to pod, sharing the process namespace of the pod's first container. The
+// sysadmin profile grants the ptrace capability profilers need. Ephemeral
+// containers cannot be removed; they stay in the pod spec, terminated, until
+// the pod is replaced.
+func (c *Cluster) RunDebugContainer(pod, image string, env map[string]string, stdout io.Writer, command ...string) error {
+	p, err := c.GetPod(pod)
+	if err != nil {
+		return err
+	}
+	if len(p.Spec) == 0 {
+		return fmt.Errorf("pod %s has no containers", pod)
+	}
+
+	args := []string{"debug", pod, "--quiet", "-i", "--attach",
+		"--image", image,
+		"--target", p.Spec[0].Name,
+		"--profile", "sysadmin",
+	}
+	for k, v := range env {
+		args = append(args, "--env", k+"="+v)
+	}
+	cmd := c.kubectlCmd(append(append(args, "--"), command...)...)
+	cmd.Stdout = stdout
+	cmd.Stderr = os.Stderr
+	if err := c.run(cmd); err != nil {
+		return fmt.Errorf("kubectl debug failed: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,200 @@
+package kube
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/runner"
+)
+
+func TestParseClusterSpec(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    Cluster
+		wantErr bool
+	}{
+		{spec: "dp us-east-2 onyx", want: Cluster{Name: "dp", Region: "us-east-2", Namespace: "onyx"}},
+		{spec: "dp us-east-2 onyx prod", want: Cluster{Name: "dp", Region: "us-east-2", Namespace: "onyx", Profile: "prod"}},
+		{
+			spec: "dp us-east-2 onyx prod arn:aws:iam::123:role/eks-admin",
+			want: Cluster{Name: "dp", Region: "us-east-2", Namespace: "onyx", Profile: "prod", RoleARN: "arn:aws:iam::123:role/eks-admin"},
+		},
+		{
+			spec: "dp us-east-2 onyx - arn:aws:iam::123:role/eks-admin",
+			want: Cluster{Name: "dp", Region: "us-east-2", Namespace: "onyx", RoleARN: "arn:aws:iam::123:role/eks-admin"},
+		},
+		{spec: "dp us-east-2", wantErr: true},
+		{spec: "dp us-east-2 onyx prod eks-admin", wantErr: true},
+		{spec: "a b c d e f", wantErr: true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseClusterSpec(tt.spec)
+		if tt.wantErr {
+			if err == nil {
+				t.Errorf("ParseClusterSpec(%q) expected error, got %+v", tt.spec, got)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("ParseClusterSpec(%q) error: %v", tt.spec, err)
+			continue
+		}
+		if *got != tt.want {
+			t.Errorf("ParseClusterSpec(%q) = %+v, want %+v", tt.spec, *got, tt.want)
+		}
+	}
+}
+
+func TestKubeconfigMatches(t *testing.T) {
+	const withProfileAndRole = `{"users":[{"name":"u","user":{"exec":{
+		"args":["--region","us-east-2","eks","get-token","--cluster-name","dp","--role-arn","arn:aws:iam::123:role/eks-admin"],
+		"env":[{"name":"AWS_PROFILE","value":"prod"}]}}}]}`
+	const plain = `{"users":[{"name":"u","user":{"exec":{"args":["eks","get-token","--cluster-name","dp"]}}}]}`
+
+	tests := []struct {
+		name       string
+		cluster    Cluster
+		kubeconfig string
+		want       bool
+	}{
+		{"unpinned cluster accepts any profile", Cluster{}, plain, true},
+		{"profile and role match", Cluster{Profile: "prod", RoleARN: "arn:aws:iam::123:role/eks-admin"}, withProfileAndRole, true},
+		{"profile differs", Cluster{Profile: "staging", RoleARN: "arn:aws:iam::123:role/eks-admin"}, withProfileAndRole, false},
+		{"role missing from kubeconfig", Cluster{RoleARN: "arn:aws:iam::123:role/eks-admin"}, plain, false},
+		{"kubeconfig assumes a role we don't", Cluster{Profile: "prod"}, withProfileAndRole, false},
+		{"profile missing from kubeconfig", Cluster{Profile: "prod"}, plain, false},
+		{"invalid json", Cluster{}, "not json", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.cluster.kubeconfigMatches([]byte(tt.kubeconfig)); got != tt.want {
+				t.Errorf("kubeconfigMatches() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestInteractiveExecArgs(t *testing.T) {
+	tests := []struct {
+		container string
+		tty       bool
+		want      []string
+	}{
+		{want: []string{"exec", "-i", "api-0", "--", "psql"}},
+		{tty: true, want: []string{"exec", "-i", "-t", "api-0", "--", "psql"}},
+		{container: "api-server", tty: true, want: []string{"exec", "-i", "-t", "api-0", "--container", "api-server", "--", "psql"}},
+	}
+
+	for _, tt := range tests {
+		if got := interactiveExecArgs("api-0", tt.container, tt.tty, []string{"psql"}); !slices.Equal(got, tt.want) {
+			t.Errorf("interactiveExecArgs(%q, %t) = %v, want %v", tt.container, tt.tty, got, tt.want)
+		}
+	}
+}
+
+func TestRunnerArgs(t *testing.T) {
+	pods := `{"items": [
+		{"metadata": {"name": "api-server-1"}, "status": {"phase": "Pending"}},
+		{"metadata": {"name": "api-server-2"}, "status": {"phase": "Running", "conditions": [{"type": "Ready", "status": "True"}]}}
+	]}`
+	f := (&runner.Fake{}).
+		On("kubectl --context dp --namespace onyx get pods -o json", runner.Response{Stdout: pods}).
+		On("kubectl --context dp --namespace onyx exec api-server-2 -i -- python -", runner.Response{Stdout: `{"status": "success"}`})
+	c := &Cluster{Name: "dp", Region: "us-east-2", Namespace: "onyx", Profile: "prod", Runner: f}
+
+	pod, err := c.FindPod("api-server")
+	if err != nil || pod != "api-server-2" {
+		t.Fatalf("FindPod() = %q, %v", pod, err)
+	}
+	out, err := c.RunPython(pod, "print('hi')", "--all")
+	if err != nil || out != `{"status": "success"}` {
+		t.Fatalf("RunPython() = %q, %v", out, err)
+	}
+
+	calls := f.Calls()
+	if len(calls) != 2 {
+		t.Fatalf("expected 2 commands, got %q", f.Lines())
+	}
+	if got := calls[1].String(); got != "kubectl --context dp --namespace onyx exec api-server-2 -i -- python - --all" {
+		t.Errorf("unexpected command %q", got)
+	}
+	if calls[1].Input != "print('hi')" {
+		t.Errorf("expected the script on stdin, got %q", calls[1].Input)
+	}
+	if !slices.Contains(calls[1].Env, "AWS_PROFILE=prod") {
+		t.Error("expected the cluster's AWS profile in the environment")
+	}
+
+	if _, err := c.ListPodsWithSelector("app=web"); err == nil || !strings.Contains(err.Error(), "kubectl get failed") {
+		t.Errorf("expected an unanswered command to fail, got %v", err)
+	}
+}
+
+func TestAWSAssumesRole(t *testing.T) {
+	t.Cleanup(func() { assumedRoles.env = map[[2]string][]string{} })
+	const role = "arn:aws:iam::123:role/eks-admin"
+	f := (&runner.Fake{}).
+		On("aws sts assume-role --role-arn "+role, runner.Response{
+			Stdout: `{"Credentials": {"AccessKeyId": "ASIAEXAMPLE", "SecretAccessKey": "secret", "SessionToken": "token"}}`,
+		}).
+		On("aws --region us-east-2 --output json", runner.Response{Stdout: "{}"})
+	c := &Cluster{Name: "dp", Region: "us-east-2", Namespace: "onyx", Profile: "prod", RoleARN: role, Runner: f}
+
+	for range 2 {
+		if _, err := c.AWS("rds", "describe-db-instances"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	calls := f.Calls()
+	if len(calls) != 3 {
+		t.Fatalf("expected the role to be assumed once and reused, got %q", f.Lines())
+	}
+	if !slices.Contains(calls[0].Env, "AWS_PROFILE=prod") {
+		t.Error("expected the role to be assumed from the cluster's profile")
+	}
+	for _, call := range calls[1:] {
+		for _, want := range []string{"AWS_ACCESS_KEY_ID=ASIAEXAMPLE", "AWS_SECRET_ACCESS_KEY=secret", "AWS_SESSION_TOKEN=token"} {
+			if !slices.Contains(call.Env, want) {
+				t.Errorf("%s: expected %s in the environment", call, want)
+			}
+		}
+	}
+
+	denied := &Cluster{Region: "us-east-2", RoleARN: "arn:aws:iam::456:role/nope", Runner: (&runner.Fake{}).
+		On("aws sts assume-role", runner.Response{Stderr: "AccessDenied\n", Exit: 254})}
+	if _, err := denied.AWS("rds", "describe-db-instances"); err == nil || !strings.Contains(err.Error(), "AccessDenied") {
+		t.Errorf("expected a failed assume-role to fail the command, got %v", err)
+	}
+}
+
+func TestHelmAndAWSArgs(t *testing.T) {
+	f := (&runner.Fake{}).
+		On("helm --kube-context dp --namespace onyx list --short", runner.Response{Stdout: "onyx\n"}).
+		On("aws --region us-east-2 --output json rds describe-db-instances", runner.Response{Stdout: `{"DBInstances": []}`}).
+		On("aws --region us-east-2 --output json s3api", runner.Response{Stderr: "AccessDenied\n", Exit: 254})
+	c := &Cluster{Name: "dp", Region: "us-east-2", Namespace: "onyx", Profile: "prod", Runner: f}
+
+	out, err := runner.Output(c.Runner, c.Helm("list", "--short"))
+	if err != nil || string(out) != "onyx\n" {
+		t.Fatalf("helm list = %q, %v", out, err)
+	}
+	if out, err := c.AWS("rds", "describe-db-instances"); err != nil || string(out) != `{"DBInstances": []}` {
+		t.Fatalf("AWS() = %q, %v", out, err)
+	}
+	for _, call := range f.Calls() {
+		if !slices.Contains(call.Env, "AWS_PROFILE=prod") {
+			t.Errorf("%s: expected the cluster's AWS profile in the environment", call)
+		}
+	}
+
+	_, err = c.AWS("s3api", "get-bucket-versioning", "--bucket", "backups")
+	if err == nil || !strings.Contains(err.Error(), "aws s3api get-bucket-versioning failed") || !strings.Contains(err.Error(), "AccessDenied") {
+		t.Errorf("expected the operation and aws's stderr in the error, got %v", err)
+	}
+	if code, ok := runner.ExitCode(err); !ok || code != 254 {
+		t.Errorf("ExitCode() = %d, %v; want aws's exit code", code, ok)
+	}
+}
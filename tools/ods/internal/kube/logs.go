@@ -0,0 +1,146 @@
+package kube
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LogOptions selects which of a pod's logs Logs returns.
+type LogOptions struct {
+	// Container selects a container by name. Empty means the pod's default
+	// container, unless AllContainers is set.
+	Container     string
+	AllContainers bool
+	// Follow keeps streaming until the pod exits or the caller's process is
+	// interrupted.
+	Follow bool
+	// Since limits output to lines newer than this duration. SinceTime takes
+	// precedence when both are set.
+	Since     time.Duration
+	SinceTime time.Time
+	// Tail is the number of lines to show from the end; 0 means all of them.
+	Tail int
+	// Previous returns the logs of the previous, terminated instance of the
+	// container, e.g. to see why it crashed.
+	Previous   bool
+	Timestamps bool
+}
+
+// Logs streams the logs of pod into w as kubectl produces them, so it can be
+// used with Follow for long-running tails.
+func (c *Cluster) Logs(pod string, opts LogOptions, w io.Writer) error {
+	cmd := c.kubectlCmd(logsArgs(pod, opts)...)
+	var stderr bytes.Buffer
+	cmd.Stdout = w
+	cmd.Stderr = &stderr
+	if err := c.run(cmd); err != nil {
+		return fmt.Errorf("kubectl logs %s failed: %w\n%s", pod, err, stderr.String())
+	}
+	return nil
+}
+
+func logsArgs(pod string, opts LogOptions) []string {
+	args := []string{"logs", pod}
+	switch {
+	case opts.AllContainers:
+		args = append(args, "--all-containers")
+	case opts.Container != "":
+		args = append(args, "--container", opts.Container)
+	}
+	if opts.Follow {
+		args = append(args, "--follow")
+	}
+	switch {
+	case !opts.SinceTime.IsZero():
+		args = append(args, "--since-time", opts.SinceTime.UTC().Format(time.RFC3339))
+	case opts.Since > 0:
+		args = append(args, "--since", opts.Since.String())
+	}
+	if opts.Tail > 0 {
+		args = append(args, "--tail", strconv.Itoa(opts.Tail))
+	}
+	if opts.Previous {
+		args = append(args, "--previous")
+	}
+	if opts.Timestamps {
+		args = append(args, "--timestamps")
+	}
+	return args
+}
+
+// MatchPods returns the names of the pods whose name contains any of
+// substrings.
+func MatchPods(pods []*Pod, substrings []string) []string {
+	var matched []string
+	for _, p := range pods {
+		for _, s := range substrings {
+			if strings.Contains(p.Name, s) {
+				matched = append(matched, p.Name)
+				break
+			}
+		}
+	}
+	return matched
+}
+
+// LogMatch is a log line found by SearchLogs.
+type LogMatch struct {
+	Pod  string `json:"pod"`
+	Line string `json:"line"`
+}
+
+// SearchLogs returns the lines of the logs of pods that contain text,
+// ignoring case, in pod order. At most limit lines are returned per pod; 0
+// means all of them. opts.Follow is ignored.
+func (c *Cluster) SearchLogs(pods []string, text string, opts LogOptions, limit int) ([]LogMatch, error) {
+	opts.Follow = false
+	results := make([][]LogMatch, len(pods))
+	errs := make([]error, len(pods))
+	var wg sync.WaitGroup
+	for i, pod := range pods {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			var buf bytes.Buffer
+			if errs[i] = c.Logs(pod, opts, &buf); errs[i] == nil {
+				results[i] = filterLogLines(pod, &buf, text, limit)
+			}
+		}()
+	}
+	wg.Wait()
+
+	var matches []LogMatch
+	for i := range pods {
+		if errs[i] != nil {
+			return nil, errs[i]
+		}
+		matches = append(matches, results[i]...)
+	}
+	return matches, nil
+}
+
+// filterLogLines returns the lines of r containing text, ignoring case,
+// keeping the last limit of them if limit is positive.
+func filterLogLines(pod string, r io.Reader, text string, limit int) []LogMatch {
+	text = strings.ToLower(text)
+	var matches []LogMatch
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.Contains(strings.ToLower(line), text) {
+			continue
+		}
+		matches = append(matches, LogMatch{Pod: pod, Line: line})
+		if limit > 0 && len(matches) > limit {
+			matches = matches[1:]
+		}
+	}
+	return matches
+}
@@ -0,0 +1,64 @@
+package kube
+
+import (
+	"slices"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLogsArgs(t *testing.T) {
+	since := time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)
+	tests := []struct {
+		name string
+		opts LogOptions
+		want []string
+	}{
+		{name: "defaults", want: []string{"logs", "api-0"}},
+		{
+			name: "container and tail",
+			opts: LogOptions{Container: "api-server", Follow: true, Tail: 100},
+			want: []string{"logs", "api-0", "--container", "api-server", "--follow", "--tail", "100"},
+		},
+		{
+			name: "all containers wins over container",
+			opts: LogOptions{Container: "api-server", AllContainers: true},
+			want: []string{"logs", "api-0", "--all-containers"},
+		},
+		{
+			name: "since duration",
+			opts: LogOptions{Since: 10 * time.Minute, Previous: true},
+			want: []string{"logs", "api-0", "--since", "10m0s", "--previous"},
+		},
+		{
+			name: "since time wins over duration",
+			opts: LogOptions{Since: time.Hour, SinceTime: since, Timestamps: true},
+			want: []string{"logs", "api-0", "--since-time", "2026-01-02T03:04:05Z", "--timestamps"},
+		},
+	}
+
+	for _, tt := range tests {
+		if got := logsArgs("api-0", tt.opts); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: logsArgs() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestFilterLogLines(t *testing.T) {
+	logs := "INFO started\nERROR db timeout\nINFO ok\nerror: retry 1\nError: retry 2\n"
+	got := filterLogLines("api-0", strings.NewReader(logs), "error", 0)
+	if len(got) != 3 || got[0] != (LogMatch{Pod: "api-0", Line: "ERROR db timeout"}) {
+		t.Errorf("filterLogLines() = %+v", got)
+	}
+	got = filterLogLines("api-0", strings.NewReader(logs), "error", 2)
+	if len(got) != 2 || got[0].Line != "error: retry 1" || got[1].Line != "Error: retry 2" {
+		t.Errorf("filterLogLines() with limit = %+v", got)
+	}
+}
+
+func TestMatchPods(t *testing.T) {
+	pods := []*Pod{{Name: "api-server-1"}, {Name: "celery-worker-light-2"}, {Name: "web-server-3"}}
+	if got := MatchPods(pods, []string{"api", "celery"}); !slices.Equal(got, []string{"api-server-1", "celery-worker-light-2"}) {
+		t.Errorf("MatchPods() = %v", got)
+	}
+}
@@ -0,0 +1,184 @@
+package kube
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// Pod is the subset of a Kubernetes Pod's state ods reports on.
+type Pod struct {
+	Name    string
+	Phase   string
+	Created time.Time
+	// Ready is the pod's Ready condition: every container is ready and the
+	// pod is receiving traffic.
+	Ready      bool
+	Containers []ContainerStatus
+	// Spec lists the pod's containers in spec order, with their images.
+	Spec        []Container
+	Annotations map[string]string
+}
+
+// ContainerStatus summarises one container of a pod.
+type ContainerStatus struct {
+	Name         string
+	Ready        bool
+	RestartCount int
+	// WaitingReason is set while the container is not running, e.g.
+	// "CrashLoopBackOff" or "ImagePullBackOff".
+	WaitingReason string
+	// LastTerminationReason is why the previous instance exited, e.g.
+	// "OOMKilled" or "Error".
+	LastTerminationReason string
+}
+
+// failingWaitingReasons are container waiting reasons that won't resolve
+// without intervention.
+var failingWaitingReasons = map[string]bool{
+	"CrashLoopBackOff":           true,
+	"ImagePullBackOff":           true,
+	"ErrImagePull":               true,
+	"CreateContainerConfigError": true,
+	"CreateContainerError":       true,
+	"RunContainerError":          true,
+	"InvalidImageName":           true,
+}
+
+// FailureReason returns why the pod is stuck (e.g. "CrashLoopBackOff" for a
+// named container), or "" if it looks healthy or is still starting normally.
+func (p *Pod) FailureReason() string {
+	for _, cs := range p.Containers {
+		if failingWaitingReasons[cs.WaitingReason] {
+			reason := fmt.Sprintf("%s: %s", cs.Name, cs.WaitingReason)
+			if cs.LastTerminationReason != "" {
+				reason += fmt.Sprintf(" (last exit: %s)", cs.LastTerminationReason)
+			}
+			return reason
+		}
+	}
+	return ""
+}
+
+type podJSON struct {
+	Metadata struct {
+		Name              string            `json:"name"`
+		CreationTimestamp time.Time         `json:"creationTimestamp"`
+		Annotations       map[string]string `json:"annotations"`
+	} `json:"metadata"`
+	Spec   podSpecJSON `json:"spec"`
+	Status struct {
+		Phase      string `json:"phase"`
+		Conditions []struct {
+			Type   string `json:"type"`
+			Status string `json:"status"`
+		} `json:"conditions"`
+		ContainerStatuses []struct {
+			Name         string `json:"name"`
+			Ready        bool   `json:"ready"`
+			RestartCount int    `json:"restartCount"`
+			State        struct {
+				Waiting *struct {
+					Reason string `json:"reason"`
+				} `json:"waiting"`
+			} `json:"state"`
+			LastState struct {
+				Terminated *struct {
+					Reason string `json:"reason"`
+				} `json:"terminated"`
+			} `json:"lastState"`
+		} `json:"containerStatuses"`
+	} `json:"status"`
+}
+
+func (p podJSON) toPod() *Pod {
+	pod := &Pod{Name: p.Metadata.Name, Phase: p.Status.Phase, Created: p.Metadata.CreationTimestamp, Spec: p.Spec.containers(), Annotations: p.Metadata.Annotations}
+	for _, cond := range p.Status.Conditions {
+		if cond.Type == "Ready" {
+			pod.Ready = cond.Status == "True"
+		}
+	}
+	for _, cs := range p.Status.ContainerStatuses {
+		status := ContainerStatus{Name: cs.Name, Ready: cs.Ready, RestartCount: cs.RestartCount}
+		if cs.State.Waiting != nil {
+			status.WaitingReason = cs.State.Waiting.Reason
+		}
+		if cs.LastState.Terminated != nil {
+			status.LastTerminationReason = cs.LastState.Terminated.Reason
+		}
+		pod.Containers = append(pod.Containers, status)
+	}
+	return pod
+}
+
+// ListPods returns every pod in the cluster's namespace, sorted by name.
+func (c *Cluster) ListPods() ([]*Pod, error) {
+	out, err := c.output("get", "pods", "-o", "json")
+	if err != nil {
+		return nil, err
+	}
+	return parsePodList(out)
+}
+
+// ListPodsWithSelector returns the pods matching a label selector (e.g.
+// "job-name=migrate"), sorted by name.
+func (c *Cluster) ListPodsWithSelector(selector string) ([]*Pod, error) {
+	out, err := c.output("get", "pods", "--selector", selector, "-o", "json")
+	if err != nil {
+		return nil, err
+	}
+	return parsePodList(out)
+}
+
+// GetPod fetches a single pod by name.
+func (c *Cluster) GetPod(name string) (*Pod, error) {
+	out, err := c.output("get", "pod", name, "-o", "json")
+	if err != nil {
+		return nil, err
+	}
+	var p podJSON
+	if err := json.Unmarshal(out, &p); err != nil {
+		return nil, fmt.Errorf("failed to parse kubectl output: %w", err)
+	}
+	return p.toPod(), nil
+}
+
+// DeletePod deletes a pod, giving it its termination grace period. Pods of
+// a deployment are replaced by its ReplicaSet.
+func (c *Cluster) DeletePod(name string) error {
+	_, err := c.output("delete", "pod", name, "--ignore-not-found", "--wait=false")
+	return err
+}
+
+// KillPod deletes a pod without a grace period, as if its node died.
+func (c *Cluster) KillPod(name string) error {
+	_, err := c.output("delete", "pod", name, "--grace-period=0", "--force", "--wait=false")
+	return err
+}
+
+// AnnotatePod sets an annotation on a pod, or removes it when value is empty.
+func (c *Cluster) AnnotatePod(name, key, value string) error {
+	arg := key + "-"
+	if value != "" {
+		arg = key + "=" + value
+	}
+	_, err := c.output("annotate", "pod", name, arg, "--overwrite")
+	return err
+}
+
+func parsePodList(data []byte) ([]*Pod, error) {
+	var list struct {
+		Items []podJSON `json:"items"`
+	}
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse kubectl output: %w", err)
+	}
+
+	pods := make([]*Pod, 0, len(list.Items))
+	for _, item := range list.Items {
+		pods = append(pods, item.toPod())
+	}
+	sort.Slice(pods, func(i, j int) bool { return pods[i].Name < pods[j].Name })
+	return pods, nil
+}
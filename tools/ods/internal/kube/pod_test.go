@@ -0,0 +1,63 @@
+package kube
+
+import "testing"
+
+func TestParsePodListFailureReason(t *testing.T) {
+	data := []byte(`{"items":[
+		{"metadata":{"name":"api-1","creationTimestamp":"2026-01-02T03:04:05Z"},
+		 "spec":{"containers":[{"name":"api","image":"onyxdotapp/onyx-backend:v1"}]},
+		 "status":{"phase":"Running","conditions":[{"type":"Ready","status":"True"}],
+		   "containerStatuses":[{"name":"api","ready":true,"restartCount":0,"state":{"running":{}}}]}},
+		{"metadata":{"name":"api-0","creationTimestamp":"2026-01-02T03:04:05Z"},
+		 "status":{"phase":"Running","containerStatuses":[{"name":"api","ready":false,"restartCount":4,
+		   "state":{"waiting":{"reason":"CrashLoopBackOff"}},"lastState":{"terminated":{"reason":"OOMKilled"}}}]}},
+		{"metadata":{"name":"api-2","creationTimestamp":"2026-01-02T03:04:05Z"},
+		 "status":{"phase":"Pending","containerStatuses":[{"name":"api","state":{"waiting":{"reason":"ContainerCreating"}}}]}}
+	]}`)
+
+	pods, err := parsePodList(data)
+	if err != nil {
+		t.Fatalf("parsePodList() error: %v", err)
+	}
+	if len(pods) != 3 || pods[0].Name != "api-0" {
+		t.Fatalf("expected 3 pods sorted by name, got %+v", pods)
+	}
+
+	if got, want := pods[0].FailureReason(), "api: CrashLoopBackOff (last exit: OOMKilled)"; got != want {
+		t.Errorf("FailureReason() = %q, want %q", got, want)
+	}
+	if got := pods[1].FailureReason(); got != "" {
+		t.Errorf("healthy pod FailureReason() = %q", got)
+	}
+	if got := pods[2].FailureReason(); got != "" {
+		t.Errorf("starting pod FailureReason() = %q", got)
+	}
+	if !pods[1].Ready || pods[0].Ready {
+		t.Errorf("Ready = %v, %v; want only the healthy pod ready", pods[0].Ready, pods[1].Ready)
+	}
+	if len(pods[1].Spec) != 1 || pods[1].Spec[0].Image != "onyxdotapp/onyx-backend:v1" {
+		t.Errorf("Spec = %+v", pods[1].Spec)
+	}
+	if pods[0].Containers[0].RestartCount != 4 {
+		t.Errorf("RestartCount = %d, want 4", pods[0].Containers[0].RestartCount)
+	}
+}
+
+func TestDeploymentRolloutComplete(t *testing.T) {
+	done := Deployment{Replicas: 2, CurrentReplicas: 2, UpdatedReplicas: 2, ReadyReplicas: 2, Generation: 3, ObservedGeneration: 3}
+	if !done.RolloutComplete() {
+		t.Error("expected rollout to be complete")
+	}
+
+	oldPodsRemain := done
+	oldPodsRemain.CurrentReplicas = 3
+	if oldPodsRemain.RolloutComplete() {
+		t.Error("rollout with old pods remaining should not be complete")
+	}
+
+	notObserved := done
+	notObserved.Generation = 4
+	if notObserved.RolloutComplete() {
+		t.Error("rollout of an unobserved generation should not be complete")
+	}
+}
@@ -0,0 +1,101 @@
+package kube
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// portForwardReadyTimeout bounds how long we wait for kubectl to report that
+// the forward is listening.
+const portForwardReadyTimeout = 30 * time.Second
+
+// PortForward is a running `kubectl port-forward` process.
+type PortForward struct {
+	Target     string
+	LocalPort  int
+	RemotePort int
+
+	cmd  *exec.Cmd
+	done chan struct{}
+}
+
+// StartPortForward runs `kubectl port-forward <target> <local>:<remote>` in
+// the background and blocks until kubectl reports it is listening. target is
+// anything kubectl accepts, e.g. "pod/api-server-abc" or "svc/api-server".
+// The caller must call Stop.
+func (c *Cluster) StartPortForward(target string, localPort, remotePort int) (*PortForward, error) {
+	cmd := c.kubectl("port-forward", target, fmt.Sprintf("%d:%d", localPort, remotePort))
+	// An io.Pipe (rather than StdoutPipe) lets cmd.Wait run concurrently with
+	// the reader below; exec copies into it until the process exits.
+	stdout, stdoutW := io.Pipe()
+	cmd.Stdout = stdoutW
+	var stderr strings.Builder
+	cmd.Stderr = &stderr
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start kubectl port-forward: %w", err)
+	}
+
+	ready := make(chan struct{})
+	go func() {
+		scanner := bufio.NewScanner(stdout)
+		signalled := false
+		for scanner.Scan() {
+			line := scanner.Text()
+			log.Debugf("port-forward: %s", line)
+			if !signalled && strings.HasPrefix(line, "Forwarding from") {
+				close(ready)
+				signalled = true
+			}
+		}
+	}()
+
+	pf := &PortForward{Target: target, LocalPort: localPort, RemotePort: remotePort, cmd: cmd, done: make(chan struct{})}
+	var waitErr error
+	go func() {
+		waitErr = cmd.Wait()
+		_ = stdoutW.Close()
+		close(pf.done)
+	}()
+
+	// Commands report errors with log.Fatal, which skips deferred Stop calls;
+	// make sure the kubectl child doesn't outlive us in that case.
+	log.RegisterExitHandler(pf.Stop)
+	select {
+	case <-ready:
+		return pf, nil
+	case <-pf.done:
+		return nil, fmt.Errorf("kubectl port-forward exited: %v\n%s", waitErr, stderr.String())
+	case <-time.After(portForwardReadyTimeout):
+		_ = cmd.Process.Kill()
+		return nil, fmt.Errorf("kubectl port-forward to %s not ready after %s", target, portForwardReadyTimeout)
+	}
+}
+
+// Stop terminates the port-forward process.
+func (pf *PortForward) Stop() {
+	if pf == nil || pf.cmd == nil || pf.cmd.Process == nil {
+		return
+	}
+	_ = pf.cmd.Process.Kill()
+}
+
+// Done is closed when the kubectl process exits.
+func (pf *PortForward) Done() <-chan struct{} {
+	return pf.done
+}
+
+// PID returns the process ID of kubectl.
+func (pf *PortForward) PID() int {
+	return pf.cmd.Process.Pid
+}
+
+// URL returns the local http URL of the forwarded port.
+func (pf *PortForward) URL() string {
+	return fmt.Sprintf("http://localhost:%d", pf.LocalPort)
+}
@@ -0,0 +1,186 @@
+package kube
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseDeploymentListImages(t *testing.T) {
+	data := []byte(`{"items":[
+		{"metadata":{"name":"web-server","generation":2},
+		 "spec":{"replicas":2,"template":{"spec":{"containers":[{"name":"web-server","image":"onyxdotapp/onyx-web-server:v1.2.3"}]}}},
+		 "status":{"observedGeneration":2,"replicas":2,"updatedReplicas":2,"readyReplicas":2}},
+		{"metadata":{"name":"api-server","generation":1},
+		 "spec":{"template":{"spec":{"containers":[
+		   {"name":"api-server","image":"onyxdotapp/onyx-backend:v1.2.3"},
+		   {"name":"sidecar","image":"busybox"}]}}},
+		 "status":{}}
+	]}`)
+
+	deployments, err := parseDeploymentList(data)
+	if err != nil {
+		t.Fatalf("parseDeploymentList() error: %v", err)
+	}
+	if len(deployments) != 2 || deployments[0].Name != "api-server" {
+		t.Fatalf("expected 2 deployments sorted by name, got %+v", deployments)
+	}
+	if d := deployments[0]; d.Replicas != 1 || d.Image() != "onyxdotapp/onyx-backend:v1.2.3" || len(d.Containers) != 2 {
+		t.Errorf("unexpected api-server %+v", d)
+	}
+	if d := deployments[1]; d.Replicas != 2 || !d.RolloutComplete() {
+		t.Errorf("unexpected web-server %+v", d)
+	}
+}
+
+func TestParseServiceList(t *testing.T) {
+	data := []byte(`{"items":[
+		{"metadata":{"name":"vespa"},"spec":{"type":"ClusterIP","clusterIP":"None","selector":{"app":"vespa"},
+		 "ports":[{"name":"query","protocol":"TCP","port":8081,"targetPort":8081},{"name":"admin","protocol":"TCP","port":19071,"targetPort":"admin"}]}},
+		{"metadata":{"name":"api-server"},"spec":{"type":"NodePort","clusterIP":"10.0.0.1",
+		 "ports":[{"protocol":"TCP","port":80,"targetPort":8080,"nodePort":30080}]}}
+	]}`)
+
+	services, err := parseServiceList(data)
+	if err != nil {
+		t.Fatalf("parseServiceList() error: %v", err)
+	}
+	if len(services) != 2 || services[0].Name != "api-server" {
+		t.Fatalf("expected 2 services sorted by name, got %+v", services)
+	}
+	if p, ok := services[0].Port(""); !ok || p.Port != 80 || p.TargetPort != "8080" || p.NodePort != 30080 {
+		t.Errorf("api-server Port(\"\") = %+v, %v", p, ok)
+	}
+	if p, ok := services[1].Port("admin"); !ok || p.TargetPort != "admin" {
+		t.Errorf("vespa Port(admin) = %+v, %v", p, ok)
+	}
+	if _, ok := services[1].Port(""); ok {
+		t.Error("Port(\"\") should be ambiguous for a multi-port service")
+	}
+}
+
+func TestParseJobList(t *testing.T) {
+	data := []byte(`{"items":[
+		{"metadata":{"name":"migrate-1","creationTimestamp":"2026-01-01T00:00:00Z"},
+		 "spec":{"template":{"spec":{"containers":[{"name":"migrate","image":"onyxdotapp/onyx-backend:v1"}]}}},
+		 "status":{"succeeded":1,"startTime":"2026-01-01T00:00:01Z","completionTime":"2026-01-01T00:01:00Z",
+		  "conditions":[{"type":"Complete","status":"True"}]}},
+		{"metadata":{"name":"migrate-2","creationTimestamp":"2026-01-02T00:00:00Z","ownerReferences":[{"kind":"CronJob","name":"migrate"}]},
+		 "spec":{"completions":1},
+		 "status":{"failed":6,"conditions":[{"type":"Failed","status":"True","reason":"BackoffLimitExceeded","lastTransitionTime":"2026-01-02T00:05:00Z"}]}},
+		{"metadata":{"name":"migrate-3","creationTimestamp":"2026-01-03T00:00:00Z"},"status":{"active":1}}
+	]}`)
+
+	jobs, err := parseJobList(data)
+	if err != nil {
+		t.Fatalf("parseJobList() error: %v", err)
+	}
+	if len(jobs) != 3 || jobs[0].Name != "migrate-3" {
+		t.Fatalf("expected 3 jobs newest first, got %+v", jobs)
+	}
+	if j := jobs[0]; j.Finished() || j.Status() != "Running" || j.Active != 1 {
+		t.Errorf("unexpected running job %+v", j)
+	}
+	if j := jobs[1]; j.Status() != "Failed" || j.Reason != "BackoffLimitExceeded" || j.CronJob != "migrate" || j.FinishTime.Minute() != 5 {
+		t.Errorf("unexpected failed job %+v", j)
+	}
+	if j := jobs[2]; j.Status() != "Complete" || j.Containers[0].Image != "onyxdotapp/onyx-backend:v1" || j.FinishTime.Minute() != 1 {
+		t.Errorf("unexpected complete job %+v", j)
+	}
+}
+
+func TestParseCronJobList(t *testing.T) {
+	data := []byte(`{"items":[
+		{"metadata":{"name":"vespa-backup"},"spec":{"schedule":"0 3 * * *","suspend":true},"status":{}},
+		{"metadata":{"name":"cleanup"},"spec":{"schedule":"*/30 * * * *"},
+		 "status":{"active":[{"name":"cleanup-1"}],"lastScheduleTime":"2026-01-02T03:30:00Z","lastSuccessfulTime":"2026-01-02T03:00:00Z"}}
+	]}`)
+
+	cronJobs, err := parseCronJobList(data)
+	if err != nil {
+		t.Fatalf("parseCronJobList() error: %v", err)
+	}
+	if len(cronJobs) != 2 || cronJobs[0].Name != "cleanup" {
+		t.Fatalf("expected 2 cronjobs sorted by name, got %+v", cronJobs)
+	}
+	if cj := cronJobs[0]; cj.Active != 1 || cj.LastSchedule.Minute() != 30 || cj.LastSuccessful.Minute() != 0 || cj.Suspend {
+		t.Errorf("unexpected cleanup cronjob %+v", cj)
+	}
+	if cj := cronJobs[1]; !cj.Suspend || !cj.LastSchedule.IsZero() {
+		t.Errorf("unexpected vespa-backup cronjob %+v", cj)
+	}
+}
+
+func TestParseIngressList(t *testing.T) {
+	data := []byte(`{"items":[
+		{"metadata":{"name":"onyx-ingress-webserver","annotations":{"kubernetes.io/ingress.class":"nginx"}},
+		 "spec":{"rules":[{"host":"cloud.onyx.app","http":{"paths":[
+		   {"path":"/api","backend":{"service":{"name":"api-server","port":{"number":8080}}}},
+		   {"path":"/","backend":{"service":{"name":"web-server","port":{"name":"http"}}}}]}}],
+		  "tls":[{"hosts":["cloud.onyx.app"],"secretName":"webserver-tls"}]},
+		 "status":{"loadBalancer":{"ingress":[{"hostname":"abc.elb.amazonaws.com"}]}}},
+		{"metadata":{"name":"acme-domain","labels":{"onyx.app/tenant-id":"tenant_acme"}},
+		 "spec":{"ingressClassName":"nginx","rules":[{"host":"onyx.acme.com"},{}],
+		  "defaultBackend":{"service":{"name":"web-server","port":{"number":3000}}}},"status":{}}
+	]}`)
+
+	ingresses, err := parseIngressList(data)
+	if err != nil {
+		t.Fatalf("parseIngressList() error: %v", err)
+	}
+	if len(ingresses) != 2 || ingresses[0].Name != "acme-domain" {
+		t.Fatalf("expected 2 ingresses sorted by name, got %+v", ingresses)
+	}
+	if ing := ingresses[0]; ing.Class != "nginx" || len(ing.Hosts) != 1 || ing.TLSSecret("onyx.acme.com") != "" || ing.Labels["onyx.app/tenant-id"] != "tenant_acme" {
+		t.Errorf("unexpected acme-domain ingress %+v", ing)
+	}
+	if ing := ingresses[1]; ing.Class != "nginx" || ing.TLSSecret("cloud.onyx.app") != "webserver-tls" || len(ing.LoadBalancer) != 1 {
+		t.Errorf("unexpected webserver ingress %+v", ing)
+	}
+
+	wantBackends := []IngressBackend{
+		{Host: "cloud.onyx.app", Path: "/api", Service: "api-server", Port: "8080"},
+		{Host: "cloud.onyx.app", Path: "/", Service: "web-server", Port: "http"},
+	}
+	if got := ingresses[1].BackendsFor("cloud.onyx.app"); !reflect.DeepEqual(got, wantBackends) {
+		t.Errorf("BackendsFor(cloud.onyx.app) = %+v, want %+v", got, wantBackends)
+	}
+	if got := ingresses[0].BackendsFor("onyx.acme.com"); len(got) != 1 || got[0].Service != "web-server" || got[0].Port != "3000" {
+		t.Errorf("expected the default backend for onyx.acme.com, got %+v", got)
+	}
+}
+
+func TestParseCertificateList(t *testing.T) {
+	data := []byte(`{"items":[
+		{"metadata":{"name":"webserver-tls"},
+		 "spec":{"secretName":"webserver-tls","dnsNames":["cloud.onyx.app"],"issuerRef":{"name":"onyx-letsencrypt","kind":"ClusterIssuer"}},
+		 "status":{"conditions":[{"type":"Ready","status":"False","message":"Issuing certificate as Secret does not exist"},{"type":"Issuing","status":"True"}],
+		  "notAfter":"2026-12-01T00:00:00Z","renewalTime":"2026-11-01T00:00:00Z","failedIssuanceAttempts":2}}
+	]}`)
+
+	certs, err := parseCertificateList(data)
+	if err != nil {
+		t.Fatalf("parseCertificateList() error: %v", err)
+	}
+	if len(certs) != 1 {
+		t.Fatalf("expected 1 certificate, got %+v", certs)
+	}
+	c := certs[0]
+	if c.Ready || !c.Issuing || c.Issuer != "ClusterIssuer/onyx-letsencrypt" || c.NotAfter.Month() != 12 || c.FailedIssuanceAttempts != 2 || c.ReadyMessage == "" {
+		t.Errorf("unexpected certificate %+v", c)
+	}
+}
+
+func TestRenewConditions(t *testing.T) {
+	now := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	conditions := renewConditions([]certificateCondition{
+		{Type: "Ready", Status: "True"},
+		{Type: "Issuing", Status: "False"},
+	}, now)
+	if len(conditions) != 2 || conditions[0].Type != "Ready" {
+		t.Fatalf("unexpected conditions %+v", conditions)
+	}
+	if c := conditions[1]; c.Type != "Issuing" || c.Status != "True" || c.LastTransitionTime != "2026-10-15T12:00:00Z" {
+		t.Errorf("unexpected Issuing condition %+v", c)
+	}
+}
@@ -0,0 +1,162 @@
+package kube
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+)
+
+// Secret is a decoded Kubernetes Secret. Data values are plaintext; callers are
+// responsible for redacting them before display.
+type Secret struct {
+	Name string
+	Type string
+	Data map[string]string
+}
+
+// Keys returns the secret's data keys in sorted order.
+func (s *Secret) Keys() []string {
+	keys := make([]string, 0, len(s.Data))
+	for k := range s.Data {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// secretJSON is the subset of `kubectl get secret -o json` we care about.
+type secretJSON struct {
+	Metadata struct {
+		Name string `json:"name"`
+	} `json:"metadata"`
+	Type string            `json:"type"`
+	Data map[string]string `json:"data"`
+}
+
+func (s secretJSON) decode() (*Secret, error) {
+	out := &Secret{Name: s.Metadata.Name, Type: s.Type, Data: make(map[string]string, len(s.Data))}
+	for k, v := range s.Data {
+		raw, err := base64.StdEncoding.DecodeString(v)
+		if err != nil {
+			return nil, fmt.Errorf("secret %s key %s is not valid base64: %w", s.Metadata.Name, k, err)
+		}
+		out.Data[k] = string(raw)
+	}
+	return out, nil
+}
+
+// ListSecrets returns every secret in the cluster's namespace.
+func (c *Cluster) ListSecrets() ([]*Secret, error) {
+	out, err := c.output("get", "secrets", "-o", "json")
+	if err != nil {
+		return nil, err
+	}
+
+	var list struct {
+		Items []secretJSON `json:"items"`
+	}
+	if err := json.Unmarshal(out, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse kubectl output: %w", err)
+	}
+
+	secrets := make([]*Secret, 0, len(list.Items))
+	for _, item := range list.Items {
+		s, err := item.decode()
+		if err != nil {
+			return nil, err
+		}
+		secrets = append(secrets, s)
+	}
+	sort.Slice(secrets, func(i, j int) bool { return secrets[i].Name < secrets[j].Name })
+	return secrets, nil
+}
+
+// GetSecret fetches and decodes a single secret by name.
+func (c *Cluster) GetSecret(name string) (*Secret, error) {
+	out, err := c.output("get", "secret", name, "-o", "json")
+	if err != nil {
+		return nil, err
+	}
+
+	var raw secretJSON
+	if err := json.Unmarshal(out, &raw); err != nil {
+		return nil, fmt.Errorf("failed to parse kubectl output: %w", err)
+	}
+	return raw.decode()
+}
+
+// PatchSecret merges the given plaintext values into an existing secret. Keys
+// not present in values are left untouched. The patch goes to kubectl in a
+// private temporary file, so the values never appear on a command line where
+// ps or shell auditing would see them.
+func (c *Cluster) PatchSecret(name string, values map[string]string) error {
+	encoded := make(map[string]string, len(values))
+	for k, v := range values {
+		encoded[k] = base64.StdEncoding.EncodeToString([]byte(v))
+	}
+	patch, err := json.Marshal(map[string]any{"data": encoded})
+	if err != nil {
+		return fmt.Errorf("failed to marshal patch: %w", err)
+	}
+
+	// CreateTemp makes the file readable by its owner only.
+	f, err := os.CreateTemp("", "ods-secret-patch-*.json")
+	if err != nil {
+		return fmt.Errorf("failed to create patch file: %w", err)
+	}
+	defer func() { _ = os.Remove(f.Name()) }()
+	if _, err := f.Write(patch); err != nil {
+		_ = f.Close()
+		return fmt.Errorf("failed to write patch file: %w", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write patch file: %w", err)
+	}
+
+	_, err = c.output("patch", "secret", name, "--type", "merge", "--patch-file", f.Name())
+	return err
+}
+
+// SecretChange describes how a single key differs between two versions of a
+// secret.
+type SecretChange struct {
+	Key string
+	Old string
+	New string
+	// Op is one of "add", "change" or "remove".
+	Op string
+}
+
+// DiffSecretData returns the changes needed to go from old to new, sorted by
+// key. Keys whose values are identical are omitted.
+func DiffSecretData(old, new map[string]string) []SecretChange {
+	var changes []SecretChange
+	for k, nv := range new {
+		ov, ok := old[k]
+		switch {
+		case !ok:
+			changes = append(changes, SecretChange{Key: k, New: nv, Op: "add"})
+		case ov != nv:
+			changes = append(changes, SecretChange{Key: k, Old: ov, New: nv, Op: "change"})
+		}
+	}
+	for k, ov := range old {
+		if _, ok := new[k]; !ok {
+			changes = append(changes, SecretChange{Key: k, Old: ov, Op: "remove"})
+		}
+	}
+	sort.Slice(changes, func(i, j int) bool { return changes[i].Key < changes[j].Key })
+	return changes
+}
+
+// RedactValue masks a secret value for display, keeping only its length so
+// that empty or obviously-truncated values are still noticeable.
+func RedactValue(v string) string {
+	if v == "" {
+		return "(empty)"
+	}
+	return fmt.Sprintf("%s (%d bytes)", strings.Repeat("*", 8), len(v))
+}
@@ -0,0 +1,110 @@
+package kube
+
+import (
+	"encoding/json"
+	"os"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/runner"
+)
+
+// runnerFunc is a Runner for checks that must happen while a command runs.
+type runnerFunc func(c runner.Cmd) error
+
+func (f runnerFunc) Run(c runner.Cmd) error { return f(c) }
+
+func TestPatchSecret(t *testing.T) {
+	var patchFile string
+	var patch map[string]map[string]string
+	c := &Cluster{Name: "dp", Namespace: "onyx", Runner: runnerFunc(func(cmd runner.Cmd) error {
+		line := cmd.String()
+		if strings.Contains(line, "hunter2") || strings.Contains(line, "aHVudGVyMg") {
+			t.Errorf("secret value on the command line: %s", line)
+		}
+		for i, arg := range cmd.Args {
+			if arg == "--patch-file" && i+1 < len(cmd.Args) {
+				patchFile = cmd.Args[i+1]
+			}
+		}
+		info, err := os.Stat(patchFile)
+		if err != nil {
+			return err
+		}
+		if perm := info.Mode().Perm(); runtime.GOOS != "windows" && perm&0o077 != 0 {
+			t.Errorf("patch file mode %v is readable by others", perm)
+		}
+		data, err := os.ReadFile(patchFile)
+		if err != nil {
+			return err
+		}
+		return json.Unmarshal(data, &patch)
+	})}
+
+	if err := c.PatchSecret("onyx-postgres", map[string]string{"password": "hunter2"}); err != nil {
+		t.Fatalf("PatchSecret() = %v", err)
+	}
+	if patch["data"]["password"] != "aHVudGVyMg==" {
+		t.Errorf("unexpected patch %v", patch)
+	}
+	if _, err := os.Stat(patchFile); !os.IsNotExist(err) {
+		t.Errorf("patch file %s was not removed", patchFile)
+	}
+}
+
+func TestDiffSecretData(t *testing.T) {
+	old := map[string]string{"A": "1", "B": "2", "C": "3"}
+	new := map[string]string{"A": "1", "B": "two", "D": "4"}
+
+	got := DiffSecretData(old, new)
+	want := []SecretChange{
+		{Key: "B", Old: "2", New: "two", Op: "change"},
+		{Key: "C", Old: "3", Op: "remove"},
+		{Key: "D", New: "4", Op: "add"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DiffSecretData() = %+v, want %+v", got, want)
+	}
+}
+
+func TestDiffSecretData_noChanges(t *testing.T) {
+	data := map[string]string{"A": "1"}
+	if got := DiffSecretData(data, data); len(got) != 0 {
+		t.Errorf("expected no changes, got %+v", got)
+	}
+}
+
+func TestRedactValue(t *testing.T) {
+	if got := RedactValue(""); got != "(empty)" {
+		t.Errorf("RedactValue(\"\") = %q", got)
+	}
+	got := RedactValue("hunter2")
+	if strings.Contains(got, "hunter2") {
+		t.Errorf("RedactValue leaked the value: %q", got)
+	}
+	if !strings.Contains(got, "7 bytes") {
+		t.Errorf("RedactValue should report the length, got %q", got)
+	}
+}
+
+func TestSecretJSONDecode(t *testing.T) {
+	var raw secretJSON
+	raw.Metadata.Name = "db"
+	raw.Type = "Opaque"
+	raw.Data = map[string]string{"PASSWORD": "aHVudGVyMg=="}
+
+	s, err := raw.decode()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if s.Data["PASSWORD"] != "hunter2" {
+		t.Errorf("expected decoded value hunter2, got %q", s.Data["PASSWORD"])
+	}
+
+	raw.Data["BAD"] = "not base64!"
+	if _, err := raw.decode(); err == nil {
+		t.Error("expected an error for invalid base64")
+	}
+}
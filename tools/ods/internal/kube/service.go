@@ -0,0 +1,127 @@
+package kube
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+)
+
+// Service is the subset of a Kubernetes Service ods reports on.
+type Service struct {
+	Name      string
+	Type      string
+	ClusterIP string
+	Ports     []ServicePort
+	// Selector is the pod label selector traffic is routed to.
+	Selector map[string]string
+}
+
+// ServicePort is one port a service exposes.
+type ServicePort struct {
+	Name     string
+	Protocol string
+	Port     int
+	// TargetPort is the container port, as a number or a named port.
+	TargetPort string
+	NodePort   int
+}
+
+// Port returns the service port with the given name, or the only port when
+// name is empty and the service has exactly one.
+func (s *Service) Port(name string) (ServicePort, bool) {
+	if name == "" && len(s.Ports) == 1 {
+		return s.Ports[0], true
+	}
+	for _, p := range s.Ports {
+		if p.Name == name {
+			return p, true
+		}
+	}
+	return ServicePort{}, false
+}
+
+type serviceJSON struct {
+	Metadata struct {
+		Name string `json:"name"`
+	} `json:"metadata"`
+	Spec struct {
+		Type      string            `json:"type"`
+		ClusterIP string            `json:"clusterIP"`
+		Selector  map[string]string `json:"selector"`
+		Ports     []struct {
+			Name       string          `json:"name"`
+			Protocol   string          `json:"protocol"`
+			Port       int             `json:"port"`
+			TargetPort json.RawMessage `json:"targetPort"`
+			NodePort   int             `json:"nodePort"`
+		} `json:"ports"`
+	} `json:"spec"`
+}
+
+func (s serviceJSON) toService() *Service {
+	svc := &Service{
+		Name:      s.Metadata.Name,
+		Type:      s.Spec.Type,
+		ClusterIP: s.Spec.ClusterIP,
+		Selector:  s.Spec.Selector,
+	}
+	for _, p := range s.Spec.Ports {
+		svc.Ports = append(svc.Ports, ServicePort{
+			Name:       p.Name,
+			Protocol:   p.Protocol,
+			Port:       p.Port,
+			TargetPort: intOrString(p.TargetPort),
+			NodePort:   p.NodePort,
+		})
+	}
+	return svc
+}
+
+// intOrString renders a Kubernetes IntOrString field, which is either a JSON
+// number or a JSON string.
+func intOrString(raw json.RawMessage) string {
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s
+	}
+	return string(raw)
+}
+
+// ListServices returns every service in the cluster's namespace, sorted by
+// name.
+func (c *Cluster) ListServices() ([]*Service, error) {
+	out, err := c.output("get", "services", "-o", "json")
+	if err != nil {
+		return nil, err
+	}
+	return parseServiceList(out)
+}
+
+// GetService fetches a single service by name.
+func (c *Cluster) GetService(name string) (*Service, error) {
+	out, err := c.output("get", "service", name, "-o", "json")
+	if err != nil {
+		return nil, err
+	}
+	var s serviceJSON
+	if err := json.Unmarshal(out, &s); err != nil {
+		return nil, fmt.Errorf("failed to parse kubectl output: %w", err)
+	}
+	return s.toService(), nil
+}
+
+func parseServiceList(data []byte) ([]*Service, error) {
+	var list struct {
+		Items []serviceJSON `json:"items"`
+	}
+	if err := json.Unmarshal(data, &list); err != nil {
+		return nil, fmt.Errorf("failed to parse kubectl output: %w", err)
+	}
+
+	services := make([]*Service, 0, len(list.Items))
+	for _, item := range list.Items {
+		services = append(services, item.toService())
+	}
+	sort.Slice(services, func(i, j int) bool { return services[i].Name < services[j].Name })
+	return services, nil
+}
@@ -0,0 +1,181 @@
+// Package lookupcache stores the results of cluster lookups (whois and
+// friends) in a local file encrypted with AES-256-GCM, so repeated lookups are
+// instant and can still be answered once cluster access is gone.
+//
+// The key lives in the config directory and the ciphertext in the data
+// directory, so a copy of either alone (a backup, a synced folder) does not
+// expose customer emails. It does not protect against someone who can read
+// both as you.
+package lookupcache
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/paths"
+)
+
+const keySize = 32
+
+// Entry is one cached lookup result.
+type Entry struct {
+	Rows    []string  `json:"rows"`
+	Fetched time.Time `json:"fetched"`
+}
+
+// Age returns how long ago the entry was fetched.
+func (e *Entry) Age() time.Duration {
+	return time.Since(e.Fetched)
+}
+
+// Cache is an encrypted key/value store of lookup results.
+type Cache struct {
+	path    string
+	keyPath string
+	key     []byte
+	entries map[string]*Entry
+}
+
+// DefaultPaths returns the cache and key file locations.
+func DefaultPaths() (cachePath, keyPath string) {
+	return filepath.Join(paths.DataDir(), "lookup-cache.bin"), filepath.Join(paths.ConfigDir(), "lookup-cache.key")
+}
+
+// Open loads the cache at path, decrypting it with the key at keyPath. A
+// missing key is generated; a missing cache file yields an empty cache.
+func Open(path, keyPath string) (*Cache, error) {
+	key, err := loadOrCreateKey(keyPath)
+	if err != nil {
+		return nil, err
+	}
+	c := &Cache{path: path, keyPath: keyPath, key: key, entries: make(map[string]*Entry)}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return c, nil
+		}
+		return nil, fmt.Errorf("failed to read lookup cache %s: %w", path, err)
+	}
+	plain, err := c.decrypt(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt lookup cache %s (clear it to start over): %w", path, err)
+	}
+	if err := json.Unmarshal(plain, &c.entries); err != nil {
+		return nil, fmt.Errorf("failed to parse lookup cache %s: %w", path, err)
+	}
+	return c, nil
+}
+
+// Key builds a cache key from its parts, e.g. Key("data_plane", "email", "chris").
+func Key(parts ...string) string {
+	return strings.Join(parts, "\x00")
+}
+
+// Get returns the cached entry for key.
+func (c *Cache) Get(key string) (*Entry, bool) {
+	e, ok := c.entries[key]
+	return e, ok
+}
+
+// Put stores rows under key and writes the cache to disk.
+func (c *Cache) Put(key string, rows []string) error {
+	c.entries[key] = &Entry{Rows: rows, Fetched: time.Now().UTC()}
+	return c.save()
+}
+
+// Clear deletes the cache file. The key is kept.
+func (c *Cache) Clear() error {
+	c.entries = make(map[string]*Entry)
+	if err := os.Remove(c.path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove lookup cache %s: %w", c.path, err)
+	}
+	return nil
+}
+
+func (c *Cache) save() error {
+	plain, err := json.Marshal(c.entries)
+	if err != nil {
+		return fmt.Errorf("failed to marshal lookup cache: %w", err)
+	}
+	data, err := c.encrypt(plain)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0700); err != nil {
+		return fmt.Errorf("failed to create lookup cache directory: %w", err)
+	}
+	if err := os.WriteFile(c.path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write lookup cache %s: %w", c.path, err)
+	}
+	return nil
+}
+
+func (c *Cache) aead() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(c.key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// encrypt returns nonce || ciphertext.
+func (c *Cache) encrypt(plain []byte) ([]byte, error) {
+	gcm, err := c.aead()
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return nil, fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	return gcm.Seal(nonce, nonce, plain, nil), nil
+}
+
+func (c *Cache) decrypt(data []byte) ([]byte, error) {
+	gcm, err := c.aead()
+	if err != nil {
+		return nil, err
+	}
+	if len(data) < gcm.NonceSize() {
+		return nil, errors.New("ciphertext too short")
+	}
+	nonce, ciphertext := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, nil)
+}
+
+func loadOrCreateKey(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		key, err := hex.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil || len(key) != keySize {
+			return nil, fmt.Errorf("lookup cache key %s is corrupt", path)
+		}
+		return key, nil
+	}
+	if !errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("failed to read lookup cache key %s: %w", path, err)
+	}
+
+	key := make([]byte, keySize)
+	if _, err := io.ReadFull(rand.Reader, key); err != nil {
+		return nil, fmt.Errorf("failed to generate lookup cache key: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("failed to create lookup cache key directory: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(hex.EncodeToString(key)+"\n"), 0600); err != nil {
+		return nil, fmt.Errorf("failed to write lookup cache key %s: %w", path, err)
+	}
+	return key, nil
+}
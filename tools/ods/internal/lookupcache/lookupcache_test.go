@@ -0,0 +1,108 @@
+package lookupcache
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestPutGetRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+	cachePath, keyPath := filepath.Join(dir, "data", "cache.bin"), filepath.Join(dir, "config", "cache.key")
+
+	c, err := Open(cachePath, keyPath)
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	key := Key("data_plane", "email", "chris")
+	rows := []string{"chris@example.com\ttenant_1\tt"}
+	if err := c.Put(key, rows); err != nil {
+		t.Fatalf("Put() error: %v", err)
+	}
+
+	reopened, err := Open(cachePath, keyPath)
+	if err != nil {
+		t.Fatalf("reopen error: %v", err)
+	}
+	e, ok := reopened.Get(key)
+	if !ok {
+		t.Fatal("expected cached entry after reopen")
+	}
+	if !reflect.DeepEqual(e.Rows, rows) {
+		t.Errorf("Rows = %v, want %v", e.Rows, rows)
+	}
+	if e.Fetched.IsZero() {
+		t.Error("expected Fetched to be set")
+	}
+}
+
+func TestCacheIsEncryptedAtRest(t *testing.T) {
+	dir := t.TempDir()
+	cachePath, keyPath := filepath.Join(dir, "cache.bin"), filepath.Join(dir, "cache.key")
+
+	c, err := Open(cachePath, keyPath)
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	if err := c.Put(Key("q"), []string{"secret@customer.com"}); err != nil {
+		t.Fatalf("Put() error: %v", err)
+	}
+
+	data, err := os.ReadFile(cachePath)
+	if err != nil {
+		t.Fatalf("ReadFile() error: %v", err)
+	}
+	if bytes.Contains(data, []byte("secret@customer.com")) {
+		t.Error("cache file contains plaintext")
+	}
+	for _, p := range []string{cachePath, keyPath} {
+		info, err := os.Stat(p)
+		if err != nil {
+			t.Fatalf("Stat(%s) error: %v", p, err)
+		}
+		if info.Mode().Perm() != 0600 {
+			t.Errorf("%s mode = %v, want 0600", p, info.Mode().Perm())
+		}
+	}
+}
+
+func TestOpenWithWrongKeyFails(t *testing.T) {
+	dir := t.TempDir()
+	cachePath := filepath.Join(dir, "cache.bin")
+
+	c, err := Open(cachePath, filepath.Join(dir, "a.key"))
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	if err := c.Put(Key("q"), []string{"row"}); err != nil {
+		t.Fatalf("Put() error: %v", err)
+	}
+
+	if _, err := Open(cachePath, filepath.Join(dir, "b.key")); err == nil {
+		t.Error("expected an error decrypting with a different key")
+	}
+}
+
+func TestClear(t *testing.T) {
+	dir := t.TempDir()
+	cachePath := filepath.Join(dir, "cache.bin")
+
+	c, err := Open(cachePath, filepath.Join(dir, "cache.key"))
+	if err != nil {
+		t.Fatalf("Open() error: %v", err)
+	}
+	if err := c.Put(Key("q"), []string{"row"}); err != nil {
+		t.Fatalf("Put() error: %v", err)
+	}
+	if err := c.Clear(); err != nil {
+		t.Fatalf("Clear() error: %v", err)
+	}
+	if _, ok := c.Get(Key("q")); ok {
+		t.Error("expected entry to be gone after Clear")
+	}
+	if _, err := os.Stat(cachePath); !os.IsNotExist(err) {
+		t.Errorf("expected cache file to be removed, stat err = %v", err)
+	}
+}
@@ -0,0 +1,235 @@
+// Package mcp serves tools over the Model Context Protocol: JSON-RPC 2.0
+// messages, one per line, on stdin and stdout.
+package mcp
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"slices"
+	"sync"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// ProtocolVersion is the MCP revision the server implements.
+const ProtocolVersion = "2025-06-18"
+
+// supportedVersions are the revisions a client may negotiate; the tools
+// subset used here is the same in all of them.
+var supportedVersions = []string{"2024-11-05", "2025-03-26", ProtocolVersion}
+
+// JSON-RPC error codes.
+const (
+	codeParseError     = -32700
+	codeInvalidRequest = -32600
+	codeMethodNotFound = -32601
+	codeInvalidParams  = -32602
+)
+
+// maxMessageSize bounds one request line.
+const maxMessageSize = 4 << 20
+
+// Tool is a tool the server exposes.
+type Tool struct {
+	Name        string
+	Description string
+	// InputSchema is the JSON schema of the tool's arguments.
+	InputSchema map[string]any
+	// Call runs the tool. Its result is returned to the client as JSON.
+	Call func(args json.RawMessage) (any, error)
+}
+
+// Server answers MCP requests with its tools.
+type Server struct {
+	name    string
+	version string
+	tools   []Tool
+	// Audit, if set, records each tool call before it runs; a call whose
+	// record fails is refused. client is the name the client gave.
+	Audit func(tool string, args json.RawMessage, client string) error
+
+	mu     sync.Mutex
+	client string
+}
+
+// NewServer returns a server that identifies itself as name and version.
+func NewServer(name, version string, tools []Tool) *Server {
+	return &Server{name: name, version: version, tools: tools}
+}
+
+type request struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method"`
+	Params  json.RawMessage `json:"params,omitempty"`
+}
+
+type response struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  any             `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+// Serve answers requests read from r on w until r is exhausted or ctx is
+// done. Requests are answered concurrently, so a slow tool call does not
+// hold up pings.
+func (s *Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	var wmu sync.Mutex
+	send := func(resp response) {
+		data, err := json.Marshal(resp)
+		if err != nil {
+			log.Errorf("Failed to encode MCP response: %v", err)
+			return
+		}
+		wmu.Lock()
+		defer wmu.Unlock()
+		_, _ = w.Write(append(data, '\n'))
+	}
+
+	var wg sync.WaitGroup
+	defer wg.Wait()
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxMessageSize)
+	for scanner.Scan() {
+		if ctx.Err() != nil {
+			return nil
+		}
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var req request
+		if err := json.Unmarshal(line, &req); err != nil {
+			send(response{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &rpcError{codeParseError, "parse error: " + err.Error()}})
+			continue
+		}
+		if req.ID == nil {
+			// A notification, e.g. notifications/initialized; nothing to answer.
+			log.Debugf("MCP notification %s", req.Method)
+			continue
+		}
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			result, rerr := s.handle(req)
+			resp := response{JSONRPC: "2.0", ID: req.ID, Result: result, Error: rerr}
+			if rerr == nil && result == nil {
+				resp.Result = struct{}{}
+			}
+			send(resp)
+		}()
+	}
+	return scanner.Err()
+}
+
+func (s *Server) handle(req request) (any, *rpcError) {
+	if req.JSONRPC != "2.0" {
+		return nil, &rpcError{codeInvalidRequest, `jsonrpc must be "2.0"`}
+	}
+	switch req.Method {
+	case "initialize":
+		return s.initialize(req.Params)
+	case "ping":
+		return nil, nil
+	case "tools/list":
+		return s.listTools(), nil
+	case "tools/call":
+		return s.callTool(req.Params)
+	}
+	return nil, &rpcError{codeMethodNotFound, fmt.Sprintf("method %q not found", req.Method)}
+}
+
+func (s *Server) initialize(params json.RawMessage) (any, *rpcError) {
+	var p struct {
+		ProtocolVersion string `json:"protocolVersion"`
+		ClientInfo      struct {
+			Name    string `json:"name"`
+			Version string `json:"version"`
+		} `json:"clientInfo"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, &rpcError{codeInvalidParams, err.Error()}
+	}
+	s.mu.Lock()
+	s.client = p.ClientInfo.Name
+	s.mu.Unlock()
+	log.Infof("MCP client connected: %s %s", p.ClientInfo.Name, p.ClientInfo.Version)
+
+	version := ProtocolVersion
+	if slices.Contains(supportedVersions, p.ProtocolVersion) {
+		version = p.ProtocolVersion
+	}
+	return map[string]any{
+		"protocolVersion": version,
+		"capabilities":    map[string]any{"tools": map[string]any{"listChanged": false}},
+		"serverInfo":      map[string]any{"name": s.name, "version": s.version},
+	}, nil
+}
+
+func (s *Server) listTools() any {
+	tools := make([]map[string]any, len(s.tools))
+	for i, t := range s.tools {
+		tools[i] = map[string]any{
+			"name":        t.Name,
+			"description": t.Description,
+			"inputSchema": t.InputSchema,
+			"annotations": map[string]any{"readOnlyHint": true, "openWorldHint": false},
+		}
+	}
+	return map[string]any{"tools": tools}
+}
+
+func (s *Server) callTool(params json.RawMessage) (any, *rpcError) {
+	var p struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return nil, &rpcError{codeInvalidParams, err.Error()}
+	}
+	i := slices.IndexFunc(s.tools, func(t Tool) bool { return t.Name == p.Name })
+	if i < 0 {
+		return nil, &rpcError{codeInvalidParams, fmt.Sprintf("unknown or disallowed tool %q", p.Name)}
+	}
+	if len(p.Arguments) == 0 || string(p.Arguments) == "null" {
+		p.Arguments = json.RawMessage("{}")
+	}
+
+	if s.Audit != nil {
+		s.mu.Lock()
+		client := s.client
+		s.mu.Unlock()
+		if err := s.Audit(p.Name, p.Arguments, client); err != nil {
+			return toolError(fmt.Errorf("refusing to run an unaudited tool call: %w", err)), nil
+		}
+	}
+	out, err := s.tools[i].Call(p.Arguments)
+	if err != nil {
+		return toolError(err), nil
+	}
+	data, err := json.Marshal(out)
+	if err != nil {
+		return toolError(err), nil
+	}
+	return map[string]any{
+		"content":           []map[string]any{{"type": "text", "text": string(data)}},
+		"structuredContent": out,
+	}, nil
+}
+
+// toolError reports a failed call as a result, so the model sees it.
+func toolError(err error) any {
+	return map[string]any{
+		"content": []map[string]any{{"type": "text", "text": err.Error()}},
+		"isError": true,
+	}
+}
@@ -0,0 +1,172 @@
+package mcp
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/health"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/kube"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/report"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/whois"
+)
+
+type fakeBackend struct {
+	since time.Duration
+}
+
+func (b *fakeBackend) Whois(query string) (*whois.Result, error) {
+	if whois.KindOf(query) == whois.KindTenant {
+		return &whois.Result{Kind: whois.KindTenant, Rows: []string{"admin@acme.com"}}, nil
+	}
+	return &whois.Result{Kind: whois.KindEmail, Rows: []string{"chris@acme.com\ttenant_a\tt"}}, nil
+}
+
+func (b *fakeBackend) Health(time.Duration) ([]health.Result, error) {
+	return []health.Result{{Name: "postgres", OK: true}}, nil
+}
+
+func (b *fakeBackend) Stats(kind report.Kind, p report.Period) (*report.Report, error) {
+	return kind.Build(report.Data{Context: "prod", Period: p, Generated: p.To}), nil
+}
+
+func (b *fakeBackend) SearchLogs(pods []string, text string, since time.Duration, limit int) ([]kube.LogMatch, error) {
+	b.since = since
+	return nil, errors.New("no pods match " + pods[0])
+}
+
+func (b *fakeBackend) TenantStats(tenantID string, p report.Period) (*report.TenantStats, error) {
+	return &report.TenantStats{Tenant: tenantID, Users: 12}, nil
+}
+
+// exchange sends each request line to s and returns the responses by ID.
+func exchange(t *testing.T, s *Server, lines ...string) map[string]map[string]any {
+	t.Helper()
+	var out bytes.Buffer
+	if err := s.Serve(context.Background(), strings.NewReader(strings.Join(lines, "\n")+"\n"), &out); err != nil {
+		t.Fatal(err)
+	}
+	responses := map[string]map[string]any{}
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		var resp map[string]any
+		if err := json.Unmarshal([]byte(line), &resp); err != nil {
+			t.Fatalf("invalid response %q: %v", line, err)
+		}
+		id, _ := json.Marshal(resp["id"])
+		responses[string(id)] = resp
+	}
+	return responses
+}
+
+func result(t *testing.T, resp map[string]any) map[string]any {
+	t.Helper()
+	r, ok := resp["result"].(map[string]any)
+	if !ok {
+		t.Fatalf("no result in %v", resp)
+	}
+	return r
+}
+
+func toolText(t *testing.T, resp map[string]any) (string, bool) {
+	t.Helper()
+	r := result(t, resp)
+	content := r["content"].([]any)
+	return content[0].(map[string]any)["text"].(string), r["isError"] == true
+}
+
+func TestServe(t *testing.T) {
+	backend := &fakeBackend{}
+	tools, err := OpsTools(backend, []string{"stats", "whois", "tenant_info", "search_logs"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := NewServer("ods", "v1.2.3", tools)
+	var mu sync.Mutex
+	var audited []string
+	s.Audit = func(tool string, args json.RawMessage, client string) error {
+		mu.Lock()
+		defer mu.Unlock()
+		audited = append(audited, client+" "+tool+" "+string(args))
+		return nil
+	}
+
+	responses := exchange(t, s,
+		`{"jsonrpc":"2.0","id":1,"method":"initialize","params":{"protocolVersion":"2025-03-26","clientInfo":{"name":"claude","version":"1"}}}`,
+		`{"jsonrpc":"2.0","method":"notifications/initialized"}`,
+	)
+	init := result(t, responses["1"])
+	if init["protocolVersion"] != "2025-03-26" || init["serverInfo"].(map[string]any)["version"] != "v1.2.3" {
+		t.Errorf("initialize = %v", init)
+	}
+	if len(responses) != 1 {
+		t.Errorf("notification was answered: %v", responses)
+	}
+
+	responses = exchange(t, s,
+		`{"jsonrpc":"2.0","id":2,"method":"tools/list"}`,
+		`{"jsonrpc":"2.0","id":3,"method":"tools/call","params":{"name":"whois","arguments":{"query":"chris"}}}`,
+		`{"jsonrpc":"2.0","id":4,"method":"tools/call","params":{"name":"tenant_info","arguments":{"tenant_id":"tenant_a"}}}`,
+		`{"jsonrpc":"2.0","id":5,"method":"tools/call","params":{"name":"search_logs","arguments":{"pod":"api","text":"x","since":"30m"}}}`,
+		`{"jsonrpc":"2.0","id":6,"method":"tools/call","params":{"name":"health","arguments":{}}}`,
+		`{"jsonrpc":"2.0","id":7,"method":"tools/call","params":{"name":"whois","arguments":{"query":"chris","drop":true}}}`,
+		`{"jsonrpc":"2.0","id":"eight","method":"resources/list"}`,
+		`{not json`,
+	)
+
+	var names []string
+	for _, tool := range result(t, responses["2"])["tools"].([]any) {
+		names = append(names, tool.(map[string]any)["name"].(string))
+	}
+	if strings.Join(names, ",") != "whois,tenant_info,search_logs,stats" {
+		t.Errorf("tools/list names = %v", names)
+	}
+	if text, isErr := toolText(t, responses["3"]); isErr || text != `{"matches":[{"email":"chris@acme.com","tenant_id":"tenant_a","active":true}]}` {
+		t.Errorf("whois = %s", text)
+	}
+	if text, _ := toolText(t, responses["4"]); !strings.Contains(text, `"admins":["admin@acme.com"]`) || !strings.Contains(text, `"users":12`) {
+		t.Errorf("tenant_info = %s", text)
+	}
+	if text, isErr := toolText(t, responses["5"]); !isErr || text != "no pods match api" || backend.since != 30*time.Minute {
+		t.Errorf("search_logs = %s (isError %v)", text, isErr)
+	}
+	if responses["6"]["error"].(map[string]any)["code"] != float64(codeInvalidParams) {
+		t.Errorf("disallowed tool = %v", responses["6"])
+	}
+	if text, isErr := toolText(t, responses["7"]); !isErr || !strings.Contains(text, `unknown field "drop"`) {
+		t.Errorf("unknown argument = %s", text)
+	}
+	if responses[`"eight"`]["error"].(map[string]any)["code"] != float64(codeMethodNotFound) {
+		t.Errorf("unknown method = %v", responses[`"eight"`])
+	}
+	if responses["null"]["error"].(map[string]any)["code"] != float64(codeParseError) {
+		t.Errorf("bad JSON = %v", responses["null"])
+	}
+	if len(audited) != 4 || !slices.Contains(audited, `claude whois {"query":"chris"}`) {
+		t.Errorf("audited = %q", audited)
+	}
+}
+
+func TestAuditFailureRefusesCall(t *testing.T) {
+	tools, _ := OpsTools(&fakeBackend{}, []string{"health"})
+	s := NewServer("ods", "dev", tools)
+	s.Audit = func(string, json.RawMessage, string) error { return errors.New("disk full") }
+	responses := exchange(t, s, `{"jsonrpc":"2.0","id":1,"method":"tools/call","params":{"name":"health"}}`)
+	if text, isErr := toolText(t, responses["1"]); !isErr || !strings.Contains(text, "unaudited") {
+		t.Errorf("health = %s", text)
+	}
+}
+
+func TestOpsToolsAllowlist(t *testing.T) {
+	if _, err := OpsTools(&fakeBackend{}, nil); err == nil {
+		t.Error("expected an error for an empty allowlist")
+	}
+	if _, err := OpsTools(&fakeBackend{}, []string{"whois", "delete_tenant"}); err == nil || !strings.Contains(err.Error(), "delete_tenant") {
+		t.Errorf("OpsTools() error = %v", err)
+	}
+}
@@ -0,0 +1,225 @@
+package mcp
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/health"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/report"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/serve"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/whois"
+)
+
+// Backend answers the operations tools. It is the ods serve backend plus
+// per-tenant statistics.
+type Backend interface {
+	serve.Backend
+	TenantStats(tenantID string, p report.Period) (*report.TenantStats, error)
+}
+
+// tenantInfoPeriod is the activity window tenant_info reports on.
+const tenantInfoPeriod = 30 * 24 * time.Hour
+
+// ToolNames lists the operations tools, in the order they are offered.
+var ToolNames = []string{"whois", "tenant_info", "health", "search_logs", "stats"}
+
+// OpsTools returns the read-only operations tools named in allow, answered
+// by b.
+func OpsTools(b Backend, allow []string) ([]Tool, error) {
+	all := map[string]Tool{
+		"whois": {
+			Name:        "whois",
+			Description: "Find users by email fragment (returns email, tenant ID and whether active), or list a tenant's admin emails when given a tenant ID (tenant_...).",
+			InputSchema: objectSchema(map[string]any{
+				"query": stringProp("An email fragment such as \"chris@acme\", or a tenant ID such as \"tenant_1234...\""),
+			}, "query"),
+			Call: func(raw json.RawMessage) (any, error) {
+				var args struct{ Query string }
+				if err := decodeArgs(raw, &args); err != nil {
+					return nil, err
+				}
+				if strings.TrimSpace(args.Query) == "" {
+					return nil, fmt.Errorf("query is required")
+				}
+				r, err := b.Whois(args.Query)
+				if err != nil {
+					return nil, err
+				}
+				if r.Kind == whois.KindTenant {
+					return map[string]any{"tenant_id": args.Query, "admins": nonNil(r.Rows)}, nil
+				}
+				return map[string]any{"matches": nonNil(r.Matches())}, nil
+			},
+		},
+		"tenant_info": {
+			Name:        "tenant_info",
+			Description: "Summarize a tenant: its admins and, over the last 30 days, user counts, chat activity, documents, indexing successes and failures, and connectors in a repeated-error state.",
+			InputSchema: objectSchema(map[string]any{
+				"tenant_id": stringProp("The tenant ID, e.g. \"tenant_1234...\""),
+			}, "tenant_id"),
+			Call: func(raw json.RawMessage) (any, error) {
+				var args struct {
+					TenantID string `json:"tenant_id"`
+				}
+				if err := decodeArgs(raw, &args); err != nil {
+					return nil, err
+				}
+				if whois.KindOf(args.TenantID) != whois.KindTenant {
+					return nil, fmt.Errorf("tenant_id must start with tenant_")
+				}
+				if err := whois.ValidateTenantID(args.TenantID); err != nil {
+					return nil, err
+				}
+				admins, err := b.Whois(args.TenantID)
+				if err != nil {
+					return nil, err
+				}
+				p := report.Last(tenantInfoPeriod, time.Now())
+				stats, err := b.TenantStats(args.TenantID, p)
+				if err != nil {
+					return nil, err
+				}
+				return map[string]any{"tenant_id": args.TenantID, "admins": nonNil(admins.Rows), "period": p, "stats": stats}, nil
+			},
+		},
+		"health": {
+			Name:        "health",
+			Description: "Probe the API server, Postgres, Redis, Vespa, model servers and Celery workers from an api-server pod and report which are failing.",
+			InputSchema: objectSchema(map[string]any{}),
+			Call: func(raw json.RawMessage) (any, error) {
+				if err := decodeArgs(raw, &struct{}{}); err != nil {
+					return nil, err
+				}
+				results, err := b.Health(serve.DefaultHealthTimeout)
+				if err != nil {
+					return nil, err
+				}
+				failed := health.Failed(results)
+				return map[string]any{"ok": failed == 0, "failed": failed, "results": nonNil(results)}, nil
+			},
+		},
+		"search_logs": {
+			Name:        "search_logs",
+			Description: "Search recent pod logs for lines containing text (case-insensitive). Returns the latest matching lines of each pod whose name contains pod.",
+			InputSchema: objectSchema(map[string]any{
+				"pod":   stringProp("Pod name substring, e.g. \"api-server\" or \"celery-worker-docfetching\""),
+				"text":  stringProp("Text to look for, e.g. an error message or tenant ID"),
+				"since": stringProp(fmt.Sprintf("How far back to search, e.g. \"30m\" (default %s, at most %s)", serve.DefaultLogSince, serve.MaxLogSince)),
+				"limit": map[string]any{"type": "integer", "description": fmt.Sprintf("Most lines to return per pod (default %d)", serve.DefaultLogLimit), "minimum": 1, "maximum": serve.MaxLogLimit},
+			}, "pod", "text"),
+			Call: func(raw json.RawMessage) (any, error) {
+				var args struct {
+					Pod   string
+					Text  string
+					Since string
+					Limit int
+				}
+				if err := decodeArgs(raw, &args); err != nil {
+					return nil, err
+				}
+				if args.Pod == "" || args.Text == "" {
+					return nil, fmt.Errorf("pod and text are required")
+				}
+				since, err := lookback(args.Since, serve.DefaultLogSince, serve.MaxLogSince)
+				if err != nil {
+					return nil, err
+				}
+				if args.Limit == 0 {
+					args.Limit = serve.DefaultLogLimit
+				}
+				if args.Limit < 0 || args.Limit > serve.MaxLogLimit {
+					return nil, fmt.Errorf("limit must be between 1 and %d", serve.MaxLogLimit)
+				}
+				matches, err := b.SearchLogs([]string{args.Pod}, args.Text, since, args.Limit)
+				if err != nil {
+					return nil, err
+				}
+				return map[string]any{"matches": nonNil(matches)}, nil
+			},
+		},
+		"stats": {
+			Name:        "stats",
+			Description: "Build an operations report across tenants: " + strings.Join(report.KindNames(), ", ") + ". Returns headline numbers with their change from the previous period, and tables.",
+			InputSchema: objectSchema(map[string]any{
+				"report": map[string]any{"type": "string", "enum": report.KindNames(), "description": "Which report to build"},
+				"since":  stringProp("Period the report covers, e.g. \"7d\" (default: the report's own)"),
+			}, "report"),
+			Call: func(raw json.RawMessage) (any, error) {
+				var args struct{ Report, Since string }
+				if err := decodeArgs(raw, &args); err != nil {
+					return nil, err
+				}
+				kind, err := report.LookupKind(args.Report)
+				if err != nil {
+					return nil, err
+				}
+				d, err := lookback(args.Since, kind.DefaultPeriod, serve.MaxStatsPeriod)
+				if err != nil {
+					return nil, err
+				}
+				return b.Stats(kind, report.Last(d, time.Now()))
+			},
+		},
+	}
+
+	if len(allow) == 0 {
+		return nil, fmt.Errorf("no tools allowed; choose from %s", strings.Join(ToolNames, ", "))
+	}
+	var tools []Tool
+	for _, name := range ToolNames {
+		if slices.Contains(allow, name) {
+			tools = append(tools, all[name])
+		}
+	}
+	for _, name := range allow {
+		if _, ok := all[name]; !ok {
+			return nil, fmt.Errorf("unknown tool %q; choose from %s", name, strings.Join(ToolNames, ", "))
+		}
+	}
+	return tools, nil
+}
+
+func objectSchema(props map[string]any, required ...string) map[string]any {
+	s := map[string]any{"type": "object", "properties": props, "additionalProperties": false}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s
+}
+
+func stringProp(description string) map[string]any {
+	return map[string]any{"type": "string", "description": description}
+}
+
+func decodeArgs(raw json.RawMessage, v any) error {
+	dec := json.NewDecoder(strings.NewReader(string(raw)))
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(v); err != nil {
+		return fmt.Errorf("invalid arguments: %v", err)
+	}
+	return nil
+}
+
+func lookback(s string, def, limit time.Duration) (time.Duration, error) {
+	if s == "" {
+		return def, nil
+	}
+	d, err := report.ParseLookback(s)
+	if err != nil {
+		return 0, err
+	}
+	if d > limit {
+		return 0, fmt.Errorf("%s is longer than the %s allowed", s, limit)
+	}
+	return d, nil
+}
+
+func nonNil[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
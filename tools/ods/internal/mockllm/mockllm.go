@@ -0,0 +1,286 @@
+// Package mockllm is an OpenAI-compatible stub server for local development.
+// It answers chat completions with canned responses, optionally after a
+// delay or with an injected failure, so the stack can run without real
+// provider keys or spend.
+package mockllm
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand/v2"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"gopkg.in/yaml.v3"
+)
+
+// DefaultModel is the model listed by /v1/models. Any model name is accepted
+// on requests.
+const DefaultModel = "mock-gpt"
+
+// Rule answers requests whose last user message matches Match with Reply.
+type Rule struct {
+	Match string `yaml:"match"`
+	Reply string `yaml:"reply"`
+
+	re *regexp.Regexp
+}
+
+// Config configures a Server.
+type Config struct {
+	// Latency is waited before responding (and before the first streamed
+	// chunk).
+	Latency time.Duration
+	// ChunkDelay is waited between streamed chunks.
+	ChunkDelay time.Duration
+	// FailureRate is the fraction of completions answered with a 500 or 429.
+	FailureRate float64
+	// Rules are tried in order; the first match wins. Without a match the
+	// reply echoes the user's message.
+	Rules []Rule
+}
+
+// LoadRules reads a YAML list of {match, reply} rules. Match is a regular
+// expression tried against the last user message.
+func LoadRules(path string) ([]Rule, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var rules []Rule
+	if err := yaml.Unmarshal(data, &rules); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return rules, nil
+}
+
+// Server is the stub's HTTP handler.
+type Server struct {
+	cfg   Config
+	mux   *http.ServeMux
+	count atomic.Int64
+	// roll returns a number in [0, 1) for failure injection.
+	roll func() float64
+	// sleep waits for latency; replaced in tests.
+	sleep func(time.Duration)
+}
+
+// New compiles the rules in cfg and returns a Server.
+func New(cfg Config) (*Server, error) {
+	for i := range cfg.Rules {
+		re, err := regexp.Compile(cfg.Rules[i].Match)
+		if err != nil {
+			return nil, fmt.Errorf("invalid match %q: %w", cfg.Rules[i].Match, err)
+		}
+		cfg.Rules[i].re = re
+	}
+	if cfg.FailureRate < 0 || cfg.FailureRate > 1 {
+		return nil, fmt.Errorf("failure rate must be between 0 and 1, got %v", cfg.FailureRate)
+	}
+
+	s := &Server{cfg: cfg, mux: http.NewServeMux(), roll: rand.Float64, sleep: time.Sleep}
+	s.mux.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	s.mux.HandleFunc("GET /v1/models", s.models)
+	s.mux.HandleFunc("POST /v1/chat/completions", s.chatCompletions)
+	return s, nil
+}
+
+// Requests returns the number of completions served so far.
+func (s *Server) Requests() int64 { return s.count.Load() }
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) models(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]any{
+		"object": "list",
+		"data": []map[string]any{
+			{"id": DefaultModel, "object": "model", "created": 0, "owned_by": "ods"},
+		},
+	})
+}
+
+type chatRequest struct {
+	Model    string `json:"model"`
+	Stream   bool   `json:"stream"`
+	Messages []struct {
+		Role    string          `json:"role"`
+		Content json.RawMessage `json:"content"`
+	} `json:"messages"`
+	StreamOptions *struct {
+		IncludeUsage bool `json:"include_usage"`
+	} `json:"stream_options"`
+}
+
+// lastUserMessage returns the text of the last user message. Content may be a
+// string or a list of typed parts.
+func (req *chatRequest) lastUserMessage() string {
+	for i := len(req.Messages) - 1; i >= 0; i-- {
+		m := req.Messages[i]
+		if m.Role != "user" {
+			continue
+		}
+		var text string
+		if json.Unmarshal(m.Content, &text) == nil {
+			return text
+		}
+		var parts []struct {
+			Type string `json:"type"`
+			Text string `json:"text"`
+		}
+		if json.Unmarshal(m.Content, &parts) == nil {
+			var b strings.Builder
+			for _, p := range parts {
+				if p.Type == "text" {
+					b.WriteString(p.Text)
+				}
+			}
+			return b.String()
+		}
+	}
+	return ""
+}
+
+// Reply returns the canned response for a user message.
+func (s *Server) Reply(message string) string {
+	for _, rule := range s.cfg.Rules {
+		if rule.re.MatchString(message) {
+			return rule.Reply
+		}
+	}
+	if len(message) > 200 {
+		message = message[:200] + "..."
+	}
+	return "This is a mock response to: " + message
+}
+
+func (s *Server) chatCompletions(w http.ResponseWriter, r *http.Request) {
+	var req chatRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeError(w, http.StatusBadRequest, "invalid_request_error", "invalid JSON body: "+err.Error())
+		return
+	}
+	n := s.count.Add(1)
+	id := fmt.Sprintf("chatcmpl-mock-%d", n)
+	model := req.Model
+	if model == "" {
+		model = DefaultModel
+	}
+
+	s.sleep(s.cfg.Latency)
+	if s.cfg.FailureRate > 0 && s.roll() < s.cfg.FailureRate {
+		// Alternate between the two failures clients must retry on.
+		if n%2 == 0 {
+			writeError(w, http.StatusTooManyRequests, "rate_limit_exceeded", "mock-llm injected rate limit")
+		} else {
+			writeError(w, http.StatusInternalServerError, "server_error", "mock-llm injected failure")
+		}
+		return
+	}
+
+	prompt := req.lastUserMessage()
+	reply := s.Reply(prompt)
+	usage := map[string]int{
+		"prompt_tokens":     countTokens(prompt),
+		"completion_tokens": countTokens(reply),
+	}
+	usage["total_tokens"] = usage["prompt_tokens"] + usage["completion_tokens"]
+
+	if !req.Stream {
+		writeJSON(w, http.StatusOK, map[string]any{
+			"id":      id,
+			"object":  "chat.completion",
+			"created": time.Now().Unix(),
+			"model":   model,
+			"choices": []map[string]any{{
+				"index":         0,
+				"message":       map[string]any{"role": "assistant", "content": reply},
+				"finish_reason": "stop",
+			}},
+			"usage": usage,
+		})
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/event-stream")
+	w.Header().Set("Cache-Control", "no-cache")
+	w.WriteHeader(http.StatusOK)
+	flusher, _ := w.(http.Flusher)
+	created := time.Now().Unix()
+	send := func(delta map[string]any, finish any, extra map[string]any) {
+		chunk := map[string]any{
+			"id":      id,
+			"object":  "chat.completion.chunk",
+			"created": created,
+			"model":   model,
+			"choices": []map[string]any{{"index": 0, "delta": delta, "finish_reason": finish}},
+		}
+		for k, v := range extra {
+			chunk[k] = v
+		}
+		data, _ := json.Marshal(chunk)
+		_, _ = fmt.Fprintf(w, "data: %s\n\n", data)
+		if flusher != nil {
+			flusher.Flush()
+		}
+	}
+
+	send(map[string]any{"role": "assistant", "content": ""}, nil, nil)
+	for i, piece := range splitChunks(reply) {
+		if i > 0 {
+			s.sleep(s.cfg.ChunkDelay)
+		}
+		send(map[string]any{"content": piece}, nil, nil)
+	}
+	var extra map[string]any
+	if req.StreamOptions != nil && req.StreamOptions.IncludeUsage {
+		extra = map[string]any{"usage": usage}
+	}
+	send(map[string]any{}, "stop", extra)
+	_, _ = fmt.Fprint(w, "data: [DONE]\n\n")
+	if flusher != nil {
+		flusher.Flush()
+	}
+}
+
+// splitChunks splits a reply into word-sized stream chunks, keeping the
+// whitespace so the chunks concatenate back to the reply.
+func splitChunks(reply string) []string {
+	var chunks []string
+	start := 0
+	for i := 1; i < len(reply); i++ {
+		if reply[i] == ' ' || reply[i] == '\n' {
+			chunks = append(chunks, reply[start:i])
+			start = i
+		}
+	}
+	if start < len(reply) {
+		chunks = append(chunks, reply[start:])
+	}
+	return chunks
+}
+
+// countTokens approximates a token count as one per four bytes, which is
+// close enough for usage accounting against a stub.
+func countTokens(s string) int {
+	return (len(s) + 3) / 4
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, code, message string) {
+	writeJSON(w, status, map[string]any{
+		"error": map[string]any{"message": message, "type": code, "code": code},
+	})
+}
@@ -0,0 +1,148 @@
+package mockllm
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestServer(t *testing.T, cfg Config) *Server {
+	t.Helper()
+	s, err := New(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.sleep = func(time.Duration) {}
+	return s
+}
+
+func post(s *Server, body string) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	s.ServeHTTP(w, httptest.NewRequest(http.MethodPost, "/v1/chat/completions", strings.NewReader(body)))
+	return w
+}
+
+func TestChatCompletion(t *testing.T) {
+	s := newTestServer(t, Config{Rules: []Rule{{Match: `(?i)pto`, Reply: "You get 25 days."}}})
+
+	w := post(s, `{"model": "gpt-4o-mini", "messages": [{"role": "system", "content": "Be brief"}, {"role": "user", "content": [{"type": "text", "text": "What is our PTO policy?"}]}]}`)
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	var resp struct {
+		Model   string `json:"model"`
+		Choices []struct {
+			Message struct {
+				Content string `json:"content"`
+			} `json:"message"`
+		} `json:"choices"`
+		Usage struct {
+			TotalTokens int `json:"total_tokens"`
+		} `json:"usage"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil {
+		t.Fatal(err)
+	}
+	if resp.Model != "gpt-4o-mini" || resp.Choices[0].Message.Content != "You get 25 days." {
+		t.Errorf("unexpected response %+v", resp)
+	}
+	if resp.Usage.TotalTokens == 0 {
+		t.Error("expected usage")
+	}
+
+	w = post(s, `{"messages": [{"role": "user", "content": "hello"}]}`)
+	if !strings.Contains(w.Body.String(), "This is a mock response to: hello") {
+		t.Errorf("expected the echo reply, got %s", w.Body)
+	}
+}
+
+func TestChatCompletionStream(t *testing.T) {
+	s := newTestServer(t, Config{Rules: []Rule{{Match: ".", Reply: "one two\nthree"}}})
+
+	w := post(s, `{"stream": true, "stream_options": {"include_usage": true}, "messages": [{"role": "user", "content": "hi"}]}`)
+	if ct := w.Header().Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("unexpected content type %q", ct)
+	}
+	var text strings.Builder
+	var sawUsage, sawDone bool
+	for _, line := range strings.Split(w.Body.String(), "\n") {
+		data, ok := strings.CutPrefix(line, "data: ")
+		if !ok {
+			continue
+		}
+		if data == "[DONE]" {
+			sawDone = true
+			continue
+		}
+		var chunk struct {
+			Choices []struct {
+				Delta struct {
+					Content string `json:"content"`
+				} `json:"delta"`
+			} `json:"choices"`
+			Usage *struct{} `json:"usage"`
+		}
+		if err := json.Unmarshal([]byte(data), &chunk); err != nil {
+			t.Fatalf("bad chunk %q: %v", data, err)
+		}
+		text.WriteString(chunk.Choices[0].Delta.Content)
+		sawUsage = sawUsage || chunk.Usage != nil
+	}
+	if text.String() != "one two\nthree" {
+		t.Errorf("chunks concatenate to %q", text.String())
+	}
+	if !sawUsage || !sawDone {
+		t.Errorf("usage=%v done=%v", sawUsage, sawDone)
+	}
+}
+
+func TestFailureInjection(t *testing.T) {
+	s := newTestServer(t, Config{FailureRate: 0.5})
+	rolls := []float64{0.1, 0.9, 0.2}
+	s.roll = func() float64 {
+		r := rolls[0]
+		rolls = rolls[1:]
+		return r
+	}
+
+	var codes []int
+	for range 3 {
+		codes = append(codes, post(s, `{"messages": [{"role": "user", "content": "hi"}]}`).Code)
+	}
+	want := []int{http.StatusInternalServerError, http.StatusOK, http.StatusInternalServerError}
+	if codes[0] != want[0] || codes[1] != want[1] || codes[2] != want[2] {
+		t.Errorf("codes = %v, want %v", codes, want)
+	}
+	if s.Requests() != 3 {
+		t.Errorf("Requests() = %d", s.Requests())
+	}
+}
+
+func TestNewValidates(t *testing.T) {
+	if _, err := New(Config{FailureRate: 1.5}); err == nil {
+		t.Error("expected an error for a failure rate above 1")
+	}
+	if _, err := New(Config{Rules: []Rule{{Match: "("}}}); err == nil {
+		t.Error("expected an error for an invalid pattern")
+	}
+}
+
+func TestLoadRules(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "responses.yml")
+	data := "- match: (?i)hello\n  reply: Hi there!\n- match: .\n  reply: |\n    Line one\n    Line two\n"
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	rules, err := LoadRules(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rules) != 2 || rules[0].Reply != "Hi there!" || rules[1].Reply != "Line one\nLine two\n" {
+		t.Errorf("unexpected rules %+v", rules)
+	}
+}
@@ -0,0 +1,420 @@
+// Package mockoauth is a minimal OpenID Connect provider for exercising SSO
+// login flows locally. It signs in whoever the developer picks on its login
+// page, without passwords, and implements just enough of OIDC (discovery,
+// authorization code with PKCE, refresh, userinfo and JWKS) for Onyx's OIDC
+// login.
+package mockoauth
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"math/big"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	codeTTL  = 5 * time.Minute
+	tokenTTL = time.Hour
+	keyID    = "ods-mock-oauth"
+)
+
+// Config configures a Provider.
+type Config struct {
+	ClientID     string
+	ClientSecret string
+	// PublicURL is the provider's URL as the browser reaches it. The
+	// authorization endpoint is advertised under it; the other endpoints use
+	// whatever host the discovery document was fetched from, so a backend in
+	// a container can reach them via host.docker.internal.
+	PublicURL string
+	// Users are offered on the login page; any other email can be typed in.
+	Users []string
+}
+
+type grant struct {
+	email       string
+	nonce       string
+	redirectURI string
+	challenge   string
+	method      string
+	scope       string
+	expires     time.Time
+	issuer      string
+}
+
+// Provider is the mock identity provider's HTTP handler.
+type Provider struct {
+	cfg Config
+	key *rsa.PrivateKey
+	mux *http.ServeMux
+	now func() time.Time
+
+	mu            sync.Mutex
+	codes         map[string]*grant
+	accessTokens  map[string]*grant
+	refreshTokens map[string]*grant
+}
+
+// New generates a signing key and returns a Provider.
+func New(cfg Config) (*Provider, error) {
+	if cfg.ClientID == "" || cfg.ClientSecret == "" {
+		return nil, fmt.Errorf("client ID and secret are required")
+	}
+	if _, err := url.Parse(cfg.PublicURL); err != nil || cfg.PublicURL == "" {
+		return nil, fmt.Errorf("invalid public URL %q", cfg.PublicURL)
+	}
+	cfg.PublicURL = strings.TrimRight(cfg.PublicURL, "/")
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate signing key: %w", err)
+	}
+
+	p := &Provider{
+		cfg:           cfg,
+		key:           key,
+		mux:           http.NewServeMux(),
+		now:           time.Now,
+		codes:         map[string]*grant{},
+		accessTokens:  map[string]*grant{},
+		refreshTokens: map[string]*grant{},
+	}
+	p.mux.HandleFunc("GET /.well-known/openid-configuration", p.discovery)
+	p.mux.HandleFunc("GET /authorize", p.authorizePage)
+	p.mux.HandleFunc("POST /authorize", p.authorize)
+	p.mux.HandleFunc("POST /token", p.token)
+	p.mux.HandleFunc("GET /userinfo", p.userinfo)
+	p.mux.HandleFunc("GET /jwks", p.jwks)
+	return p, nil
+}
+
+// ConfigURL returns the discovery URL under base, e.g. the provider's URL as
+// the backend reaches it.
+func ConfigURL(base string) string {
+	return strings.TrimRight(base, "/") + "/.well-known/openid-configuration"
+}
+
+func (p *Provider) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.mux.ServeHTTP(w, r)
+}
+
+// issuer is the origin the request was made to. Onyx requires the issuer to
+// own the discovery URL it was configured with, so it must follow the host
+// the backend uses rather than a fixed value.
+func issuer(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	return scheme + "://" + r.Host
+}
+
+func (p *Provider) discovery(w http.ResponseWriter, r *http.Request) {
+	iss := issuer(r)
+	writeJSON(w, http.StatusOK, map[string]any{
+		"issuer":                                iss,
+		"authorization_endpoint":                p.cfg.PublicURL + "/authorize",
+		"token_endpoint":                        iss + "/token",
+		"userinfo_endpoint":                     iss + "/userinfo",
+		"jwks_uri":                              iss + "/jwks",
+		"response_types_supported":              []string{"code"},
+		"grant_types_supported":                 []string{"authorization_code", "refresh_token"},
+		"subject_types_supported":               []string{"public"},
+		"id_token_signing_alg_values_supported": []string{"RS256"},
+		"scopes_supported":                      []string{"openid", "email", "profile", "offline_access"},
+		"token_endpoint_auth_methods_supported": []string{"client_secret_basic", "client_secret_post"},
+		"code_challenge_methods_supported":      []string{"S256", "plain"},
+		"claims_supported":                      []string{"sub", "email", "email_verified", "name", "iss", "aud", "exp", "iat", "nonce"},
+	})
+}
+
+var loginPage = template.Must(template.New("login").Parse(`<!doctype html>
+<html><head><title>ods mock-oauth</title>
+<style>body{font-family:sans-serif;max-width:28rem;margin:4rem auto}button{display:block;width:100%;margin:.4rem 0;padding:.6rem}input{width:100%;padding:.5rem;box-sizing:border-box}</style>
+</head><body>
+<h2>Sign in to the mock identity provider</h2>
+<p>Client <code>{{.ClientID}}</code> wants to sign you in.</p>
+{{define "params"}}{{range $k, $v := .}}<input type="hidden" name="{{$k}}" value="{{$v}}">{{end}}{{end}}
+{{if .Users}}<form method="post" action="authorize">{{template "params" .Params}}
+{{range .Users}}<button name="email" value="{{.}}">Continue as {{.}}</button>
+{{end}}</form>
+<p>Or any email:</p>{{end}}
+<form method="post" action="authorize">{{template "params" .Params}}
+<input name="email" type="email" placeholder="someone@example.com" required>
+<button>Continue</button>
+</form>
+</body></html>
+`))
+
+// authParams are the authorization request parameters carried from the
+// login page to the form post.
+var authParams = []string{"client_id", "redirect_uri", "response_type", "scope", "state", "nonce", "code_challenge", "code_challenge_method"}
+
+func (p *Provider) checkAuthRequest(q url.Values) (string, error) {
+	if q.Get("client_id") != p.cfg.ClientID {
+		return "", fmt.Errorf("unknown client_id %q", q.Get("client_id"))
+	}
+	if q.Get("response_type") != "code" {
+		return "", fmt.Errorf("unsupported response_type %q", q.Get("response_type"))
+	}
+	redirect, err := url.Parse(q.Get("redirect_uri"))
+	if err != nil || !redirect.IsAbs() {
+		return "", fmt.Errorf("invalid redirect_uri %q", q.Get("redirect_uri"))
+	}
+	switch q.Get("code_challenge_method") {
+	case "", "plain", "S256":
+	default:
+		return "", fmt.Errorf("unsupported code_challenge_method %q", q.Get("code_challenge_method"))
+	}
+	return redirect.String(), nil
+}
+
+func (p *Provider) authorizePage(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	if _, err := p.checkAuthRequest(q); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	params := map[string]string{}
+	for _, k := range authParams {
+		if v := q.Get(k); v != "" {
+			params[k] = v
+		}
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	_ = loginPage.Execute(w, map[string]any{"ClientID": p.cfg.ClientID, "Params": params, "Users": p.cfg.Users})
+}
+
+func (p *Provider) authorize(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	redirectURI, err := p.checkAuthRequest(r.PostForm)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	email := strings.TrimSpace(r.PostForm.Get("email"))
+	if !strings.Contains(email, "@") {
+		http.Error(w, "an email address is required", http.StatusBadRequest)
+		return
+	}
+
+	code := randomToken()
+	method := r.PostForm.Get("code_challenge_method")
+	if method == "" && r.PostForm.Get("code_challenge") != "" {
+		method = "plain"
+	}
+	p.mu.Lock()
+	p.codes[code] = &grant{
+		email:       strings.ToLower(email),
+		nonce:       r.PostForm.Get("nonce"),
+		redirectURI: redirectURI,
+		challenge:   r.PostForm.Get("code_challenge"),
+		method:      method,
+		scope:       r.PostForm.Get("scope"),
+		expires:     p.now().Add(codeTTL),
+	}
+	p.mu.Unlock()
+
+	target, _ := url.Parse(redirectURI)
+	q := target.Query()
+	q.Set("code", code)
+	if state := r.PostForm.Get("state"); state != "" {
+		q.Set("state", state)
+	}
+	target.RawQuery = q.Encode()
+	http.Redirect(w, r, target.String(), http.StatusFound)
+}
+
+func (p *Provider) clientAuthenticated(r *http.Request) bool {
+	id, secret, ok := r.BasicAuth()
+	if !ok {
+		id, secret = r.PostForm.Get("client_id"), r.PostForm.Get("client_secret")
+	}
+	// Basic auth credentials are form-encoded per RFC 6749 2.3.1.
+	if unescaped, err := url.QueryUnescape(id); err == nil {
+		id = unescaped
+	}
+	if unescaped, err := url.QueryUnescape(secret); err == nil {
+		secret = unescaped
+	}
+	return id == p.cfg.ClientID &&
+		subtle.ConstantTimeCompare([]byte(secret), []byte(p.cfg.ClientSecret)) == 1
+}
+
+func (p *Provider) token(w http.ResponseWriter, r *http.Request) {
+	if err := r.ParseForm(); err != nil {
+		tokenError(w, http.StatusBadRequest, "invalid_request", err.Error())
+		return
+	}
+	if !p.clientAuthenticated(r) {
+		tokenError(w, http.StatusUnauthorized, "invalid_client", "bad client credentials")
+		return
+	}
+
+	var g *grant
+	switch r.PostForm.Get("grant_type") {
+	case "authorization_code":
+		p.mu.Lock()
+		g = p.codes[r.PostForm.Get("code")]
+		delete(p.codes, r.PostForm.Get("code"))
+		p.mu.Unlock()
+		if g == nil || p.now().After(g.expires) {
+			tokenError(w, http.StatusBadRequest, "invalid_grant", "unknown, used or expired code")
+			return
+		}
+		if r.PostForm.Get("redirect_uri") != g.redirectURI {
+			tokenError(w, http.StatusBadRequest, "invalid_grant", "redirect_uri does not match the authorization request")
+			return
+		}
+		if !verifyPKCE(g.challenge, g.method, r.PostForm.Get("code_verifier")) {
+			tokenError(w, http.StatusBadRequest, "invalid_grant", "code_verifier does not match code_challenge")
+			return
+		}
+		g.issuer = issuer(r)
+	case "refresh_token":
+		p.mu.Lock()
+		g = p.refreshTokens[r.PostForm.Get("refresh_token")]
+		delete(p.refreshTokens, r.PostForm.Get("refresh_token"))
+		p.mu.Unlock()
+		if g == nil {
+			tokenError(w, http.StatusBadRequest, "invalid_grant", "unknown or used refresh token")
+			return
+		}
+		// A refreshed ID token carries no nonce.
+		g = &grant{email: g.email, scope: g.scope, issuer: issuer(r)}
+	default:
+		tokenError(w, http.StatusBadRequest, "unsupported_grant_type", r.PostForm.Get("grant_type"))
+		return
+	}
+
+	idToken, err := p.idToken(g)
+	if err != nil {
+		tokenError(w, http.StatusInternalServerError, "server_error", err.Error())
+		return
+	}
+	access, refresh := randomToken(), randomToken()
+	g.expires = p.now().Add(tokenTTL)
+	p.mu.Lock()
+	p.accessTokens[access] = g
+	p.refreshTokens[refresh] = g
+	p.mu.Unlock()
+
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, map[string]any{
+		"access_token":  access,
+		"token_type":    "Bearer",
+		"expires_in":    int(tokenTTL.Seconds()),
+		"refresh_token": refresh,
+		"id_token":      idToken,
+		"scope":         g.scope,
+	})
+}
+
+func (p *Provider) userinfo(w http.ResponseWriter, r *http.Request) {
+	token, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+	p.mu.Lock()
+	g := p.accessTokens[token]
+	p.mu.Unlock()
+	if !ok || g == nil || p.now().After(g.expires) {
+		w.Header().Set("WWW-Authenticate", `Bearer error="invalid_token"`)
+		writeJSON(w, http.StatusUnauthorized, map[string]string{"error": "invalid_token"})
+		return
+	}
+	writeJSON(w, http.StatusOK, claims(g.email))
+}
+
+func (p *Provider) jwks(w http.ResponseWriter, r *http.Request) {
+	pub := p.key.PublicKey
+	writeJSON(w, http.StatusOK, map[string]any{
+		"keys": []map[string]string{{
+			"kty": "RSA",
+			"use": "sig",
+			"alg": "RS256",
+			"kid": keyID,
+			"n":   b64(pub.N.Bytes()),
+			"e":   b64(big.NewInt(int64(pub.E)).Bytes()),
+		}},
+	})
+}
+
+// claims are the identity claims for an email: a stable subject derived
+// from it, the email marked verified, and a display name from its local
+// part.
+func claims(email string) map[string]any {
+	sum := sha256.Sum256([]byte(email))
+	local, _, _ := strings.Cut(email, "@")
+	return map[string]any{
+		"sub":            fmt.Sprintf("mock|%x", sum[:8]),
+		"email":          email,
+		"email_verified": true,
+		"name":           local,
+	}
+}
+
+func (p *Provider) idToken(g *grant) (string, error) {
+	now := p.now()
+	c := claims(g.email)
+	c["iss"] = g.issuer
+	c["aud"] = p.cfg.ClientID
+	c["iat"] = now.Unix()
+	c["exp"] = now.Add(tokenTTL).Unix()
+	if g.nonce != "" {
+		c["nonce"] = g.nonce
+	}
+	header, _ := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT", "kid": keyID})
+	payload, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	signingInput := b64(header) + "." + b64(payload)
+	digest := sha256.Sum256([]byte(signingInput))
+	sig, err := rsa.SignPKCS1v15(rand.Reader, p.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+	return signingInput + "." + b64(sig), nil
+}
+
+func verifyPKCE(challenge, method, verifier string) bool {
+	if challenge == "" {
+		return true
+	}
+	if method == "S256" {
+		sum := sha256.Sum256([]byte(verifier))
+		verifier = b64(sum[:])
+	}
+	return subtle.ConstantTimeCompare([]byte(challenge), []byte(verifier)) == 1
+}
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func randomToken() string {
+	b := make([]byte, 24)
+	_, _ = rand.Read(b)
+	return b64(b)
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func tokenError(w http.ResponseWriter, status int, code, description string) {
+	writeJSON(w, status, map[string]string{"error": code, "error_description": description})
+}
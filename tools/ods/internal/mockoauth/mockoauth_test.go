@@ -0,0 +1,209 @@
+package mockoauth
+
+import (
+	"crypto"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func newTestProvider(t *testing.T) (*Provider, *httptest.Server) {
+	t.Helper()
+	p, err := New(Config{
+		ClientID:     "onyx",
+		ClientSecret: "s3cret",
+		PublicURL:    "http://localhost:8002/",
+		Users:        []string{"admin@example.com"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	srv := httptest.NewServer(p)
+	t.Cleanup(srv.Close)
+	return p, srv
+}
+
+func getJSON(t *testing.T, req *http.Request, out any) int {
+	t.Helper()
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+		t.Fatal(err)
+	}
+	return resp.StatusCode
+}
+
+func TestDiscovery(t *testing.T) {
+	_, srv := newTestProvider(t)
+	var doc map[string]any
+	req, _ := http.NewRequest(http.MethodGet, ConfigURL(srv.URL), nil)
+	getJSON(t, req, &doc)
+	if doc["issuer"] != srv.URL {
+		t.Errorf("issuer = %v, want the requested origin %s", doc["issuer"], srv.URL)
+	}
+	if doc["authorization_endpoint"] != "http://localhost:8002/authorize" {
+		t.Errorf("authorization_endpoint = %v", doc["authorization_endpoint"])
+	}
+	if doc["token_endpoint"] != srv.URL+"/token" {
+		t.Errorf("token_endpoint = %v", doc["token_endpoint"])
+	}
+}
+
+func TestLoginPage(t *testing.T) {
+	_, srv := newTestProvider(t)
+	resp, err := http.Get(srv.URL + "/authorize?client_id=onyx&response_type=code&redirect_uri=http://localhost:3000/cb&state=xyz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	page, _ := io.ReadAll(resp.Body)
+	if !strings.Contains(string(page), `value="admin@example.com"`) || !strings.Contains(string(page), `name="state" value="xyz"`) {
+		t.Errorf("login page missing the user or the state:\n%s", page)
+	}
+
+	resp, err = http.Get(srv.URL + "/authorize?client_id=other&response_type=code&redirect_uri=http://localhost:3000/cb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("unknown client got %d", resp.StatusCode)
+	}
+}
+
+func TestCodeFlow(t *testing.T) {
+	p, srv := newTestProvider(t)
+	noRedirect := &http.Client{CheckRedirect: func(*http.Request, []*http.Request) error { return http.ErrUseLastResponse }}
+
+	verifier := "a-long-enough-code-verifier-for-the-test-0123456789"
+	sum := sha256.Sum256([]byte(verifier))
+	form := url.Values{
+		"client_id":             {"onyx"},
+		"response_type":         {"code"},
+		"redirect_uri":          {"http://localhost:3000/auth/oidc/callback"},
+		"scope":                 {"openid email"},
+		"state":                 {"st"},
+		"nonce":                 {"n0"},
+		"code_challenge":        {base64.RawURLEncoding.EncodeToString(sum[:])},
+		"code_challenge_method": {"S256"},
+		"email":                 {"Ann@Example.com"},
+	}
+	resp, err := noRedirect.PostForm(srv.URL+"/authorize", form)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	loc, err := url.Parse(resp.Header.Get("Location"))
+	if err != nil || resp.StatusCode != http.StatusFound {
+		t.Fatalf("expected a redirect, got %d %q", resp.StatusCode, resp.Header.Get("Location"))
+	}
+	if loc.Query().Get("state") != "st" || loc.Query().Get("code") == "" {
+		t.Fatalf("unexpected redirect %s", loc)
+	}
+	code := loc.Query().Get("code")
+
+	exchange := func(values url.Values) (int, map[string]any) {
+		req, _ := http.NewRequest(http.MethodPost, srv.URL+"/token", strings.NewReader(values.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetBasicAuth("onyx", "s3cret")
+		var out map[string]any
+		return getJSON(t, req, &out), out
+	}
+
+	status, out := exchange(url.Values{"grant_type": {"authorization_code"}, "code": {code}, "redirect_uri": {"http://localhost:3000/auth/oidc/callback"}, "code_verifier": {"wrong"}})
+	if status != http.StatusBadRequest || out["error"] != "invalid_grant" {
+		t.Fatalf("a bad verifier got %d %v", status, out)
+	}
+
+	// The failed exchange used up the code; authorize again.
+	resp, err = noRedirect.PostForm(srv.URL+"/authorize", form)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = resp.Body.Close()
+	loc, _ = url.Parse(resp.Header.Get("Location"))
+	status, out = exchange(url.Values{"grant_type": {"authorization_code"}, "code": {loc.Query().Get("code")}, "redirect_uri": {"http://localhost:3000/auth/oidc/callback"}, "code_verifier": {verifier}})
+	if status != http.StatusOK {
+		t.Fatalf("exchange got %d %v", status, out)
+	}
+
+	claims := verifyIDToken(t, p, out["id_token"].(string))
+	if claims["email"] != "ann@example.com" || claims["nonce"] != "n0" || claims["aud"] != "onyx" || claims["iss"] != srv.URL {
+		t.Errorf("unexpected id_token claims %v", claims)
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, srv.URL+"/userinfo", nil)
+	req.Header.Set("Authorization", "Bearer "+out["access_token"].(string))
+	var info map[string]any
+	if status := getJSON(t, req, &info); status != http.StatusOK {
+		t.Fatalf("userinfo got %d", status)
+	}
+	if info["email"] != "ann@example.com" || info["email_verified"] != true || info["sub"] != claims["sub"] {
+		t.Errorf("unexpected userinfo %v", info)
+	}
+
+	status, refreshed := exchange(url.Values{"grant_type": {"refresh_token"}, "refresh_token": {out["refresh_token"].(string)}})
+	if status != http.StatusOK || refreshed["access_token"] == out["access_token"] {
+		t.Fatalf("refresh got %d %v", status, refreshed)
+	}
+	status, _ = exchange(url.Values{"grant_type": {"refresh_token"}, "refresh_token": {out["refresh_token"].(string)}})
+	if status != http.StatusBadRequest {
+		t.Errorf("reusing a refresh token got %d", status)
+	}
+}
+
+func TestTokenRejectsBadClient(t *testing.T) {
+	_, srv := newTestProvider(t)
+	req, _ := http.NewRequest(http.MethodPost, srv.URL+"/token", strings.NewReader("grant_type=authorization_code&code=x"))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetBasicAuth("onyx", "wrong")
+	var out map[string]any
+	if status := getJSON(t, req, &out); status != http.StatusUnauthorized || out["error"] != "invalid_client" {
+		t.Errorf("got %d %v", status, out)
+	}
+}
+
+// verifyIDToken checks the token's signature against the provider's JWKS
+// and returns its claims.
+func verifyIDToken(t *testing.T, p *Provider, token string) map[string]any {
+	t.Helper()
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		t.Fatalf("malformed token %q", token)
+	}
+
+	rec := httptest.NewRecorder()
+	p.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/jwks", nil))
+	var set struct {
+		Keys []struct{ N, E string } `json:"keys"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &set); err != nil || len(set.Keys) != 1 {
+		t.Fatalf("bad JWKS %s", rec.Body)
+	}
+	n, _ := base64.RawURLEncoding.DecodeString(set.Keys[0].N)
+	e, _ := base64.RawURLEncoding.DecodeString(set.Keys[0].E)
+	pub := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+
+	sig, _ := base64.RawURLEncoding.DecodeString(parts[2])
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(pub, crypto.SHA256, digest[:], sig); err != nil {
+		t.Fatalf("id_token signature does not verify: %v", err)
+	}
+	payload, _ := base64.RawURLEncoding.DecodeString(parts[1])
+	var claims map[string]any
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		t.Fatal(err)
+	}
+	return claims
+}
@@ -0,0 +1,329 @@
+// Package nginx reads the reverse proxy's effective configuration and logs:
+// the directives that most often explain a failing request, and filters for
+// its access and error log lines.
+package nginx
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SummaryDirectives are the directives Summarize reports: request size
+// limits, proxy timeouts and buffering, and where requests are routed.
+var SummaryDirectives = map[string]bool{
+	"listen":                  true,
+	"server_name":             true,
+	"client_max_body_size":    true,
+	"client_body_timeout":     true,
+	"keepalive_timeout":       true,
+	"send_timeout":            true,
+	"proxy_connect_timeout":   true,
+	"proxy_send_timeout":      true,
+	"proxy_read_timeout":      true,
+	"proxy_buffering":         true,
+	"proxy_request_buffering": true,
+	"proxy_pass":              true,
+	"ssl_certificate":         true,
+}
+
+// Directive is one directive of the effective configuration.
+type Directive struct {
+	Name  string
+	Value string
+	File  string
+	Line  int
+	// Context is the enclosing blocks within File, outermost first, e.g.
+	// ["server :80", "location /api"].
+	Context []string
+}
+
+func (d Directive) String() string {
+	return fmt.Sprintf("%s:%d  %s  %s %s", d.File, d.Line, strings.Join(d.Context, " > "), d.Name, d.Value)
+}
+
+// configFileHeader starts each file in the output of `nginx -T`.
+var configFileHeader = regexp.MustCompile(`^# configuration file (.+):$`)
+
+// Summarize returns the SummaryDirectives in the output of `nginx -T`, in
+// the order nginx reads them, and the upstream servers each upstream block
+// routes to. The dump lists included files after the file including them,
+// so a directive's context starts at the top of its own file.
+func Summarize(dump string) []Directive {
+	var out []Directive
+	var stack []string
+	file := ""
+	line := 0
+	var stmt strings.Builder
+	stmtLine := 0
+
+	scanner := bufio.NewScanner(strings.NewReader(dump))
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for scanner.Scan() {
+		text := scanner.Text()
+		if m := configFileHeader.FindStringSubmatch(text); m != nil {
+			file, line, stack = m[1], 0, nil
+			stmt.Reset()
+			continue
+		}
+		line++
+		if file == "" {
+			continue // nginx -T's own "syntax is ok" lines
+		}
+		for _, r := range stripComment(text) {
+			switch r {
+			case ';', '{', '}':
+				fields := strings.Fields(stmt.String())
+				stmt.Reset()
+				switch {
+				case r == '{':
+					stack = append(stack, strings.Join(fields, " "))
+				case r == '}':
+					if len(stack) > 0 {
+						stack = stack[:len(stack)-1]
+					}
+				case len(fields) > 0:
+					name, value := fields[0], strings.Join(fields[1:], " ")
+					if name == "listen" && len(stack) > 0 && stack[len(stack)-1] == "server" && len(fields) > 1 {
+						stack[len(stack)-1] = "server :" + strings.TrimPrefix(fields[1], "0.0.0.0:")
+					}
+					inUpstream := len(stack) > 0 && strings.HasPrefix(stack[len(stack)-1], "upstream ")
+					if SummaryDirectives[name] || (inUpstream && name == "server") {
+						out = append(out, Directive{
+							Name:    name,
+							Value:   value,
+							File:    file,
+							Line:    stmtLine,
+							Context: append([]string(nil), stack...),
+						})
+					}
+				}
+			default:
+				if stmt.Len() == 0 {
+					if r == ' ' || r == '\t' {
+						continue
+					}
+					stmtLine = line
+				}
+				stmt.WriteRune(r)
+			}
+		}
+		if stmt.Len() > 0 {
+			stmt.WriteByte(' ')
+		}
+	}
+	return out
+}
+
+// stripComment drops a trailing # comment outside quotes.
+func stripComment(line string) string {
+	var quote rune
+	for i, r := range line {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '"' || r == '\'':
+			quote = r
+		case r == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// StatusMatch matches a response status exactly (404) or by class (5xx).
+type StatusMatch struct {
+	Code  int
+	Class int
+}
+
+func (s StatusMatch) matches(status int) bool {
+	if s.Class != 0 {
+		return status/100 == s.Class
+	}
+	return status == s.Code
+}
+
+// ParseStatuses parses a comma-separated list of statuses and classes, e.g.
+// "5xx,404,429".
+func ParseStatuses(s string) ([]StatusMatch, error) {
+	var out []StatusMatch
+	for _, part := range strings.Split(s, ",") {
+		part = strings.ToLower(strings.TrimSpace(part))
+		if part == "" {
+			continue
+		}
+		if len(part) == 3 && strings.HasSuffix(part, "xx") && part[0] >= '1' && part[0] <= '5' {
+			out = append(out, StatusMatch{Class: int(part[0] - '0')})
+			continue
+		}
+		code, err := strconv.Atoi(part)
+		if err != nil || code < 100 || code > 599 {
+			return nil, fmt.Errorf("invalid status %q: expected a code such as 404 or a class such as 5xx", part)
+		}
+		out = append(out, StatusMatch{Code: code})
+	}
+	return out, nil
+}
+
+// AccessEntry is the part of an access log line the filters look at.
+type AccessEntry struct {
+	Method string
+	Path   string
+	Status int
+	// Duration is the request time, when the log format records it.
+	Duration    time.Duration
+	HasDuration bool
+}
+
+var (
+	// accessPattern matches the request and status of the combined format
+	// and the formats derived from it (the compose stack's custom_main and
+	// ingress-nginx's upstreaminfo).
+	accessPattern = regexp.MustCompile(`"([A-Z]+) (\S+)[^"]*" (\d{3}) `)
+	// customMainTime is custom_main's trailing rt=$request_time.
+	customMainTime = regexp.MustCompile(`\brt=([0-9.]+)`)
+	// upstreamInfoTime is ingress-nginx's $request_length $request_time
+	// after the user agent.
+	upstreamInfoTime = regexp.MustCompile(`" \d+ ([0-9.]+) \[`)
+	// errorPattern matches the start of an error log line.
+	errorPattern = regexp.MustCompile(`^\d{4}/\d{2}/\d{2} \d{2}:\d{2}:\d{2} \[(\w+)\]`)
+)
+
+// ParseAccess parses an access log line, reporting false for other lines.
+func ParseAccess(line string) (AccessEntry, bool) {
+	m := accessPattern.FindStringSubmatch(line)
+	if m == nil {
+		return AccessEntry{}, false
+	}
+	status, _ := strconv.Atoi(m[3])
+	e := AccessEntry{Method: m[1], Path: m[2], Status: status}
+	t := customMainTime.FindStringSubmatch(line)
+	if t == nil {
+		t = upstreamInfoTime.FindStringSubmatch(line)
+	}
+	if t != nil {
+		if secs, err := strconv.ParseFloat(t[1], 64); err == nil {
+			e.Duration = time.Duration(secs * float64(time.Second))
+			e.HasDuration = true
+		}
+	}
+	return e, true
+}
+
+// ErrorLevel returns the severity of an error log line (e.g. "error",
+// "warn"), or "" for other lines.
+func ErrorLevel(line string) string {
+	if m := errorPattern.FindStringSubmatch(line); m != nil {
+		return m[1]
+	}
+	return ""
+}
+
+// errorLevels are the error log severities Filter.Errors keeps.
+var errorLevels = map[string]bool{
+	"warn": true, "error": true, "crit": true, "alert": true, "emerg": true,
+}
+
+// Filter selects log lines. The zero Filter keeps every line; access filters
+// keep only the access lines that pass all of them.
+type Filter struct {
+	Statuses []StatusMatch
+	// Path keeps requests whose path contains it.
+	Path string
+	// SlowerThan keeps requests that took longer; lines without a request
+	// time never pass it.
+	SlowerThan time.Duration
+	// Errors keeps error log lines of warn severity and above; with access
+	// filters as well, lines passing either are kept.
+	Errors bool
+}
+
+func (f Filter) hasAccessFilters() bool {
+	return len(f.Statuses) > 0 || f.Path != "" || f.SlowerThan > 0
+}
+
+// Match reports whether the filter keeps line.
+func (f Filter) Match(line string) bool {
+	if !f.Errors && !f.hasAccessFilters() {
+		return true
+	}
+	if f.Errors && errorLevels[ErrorLevel(line)] {
+		return true
+	}
+	if !f.hasAccessFilters() {
+		return false
+	}
+	e, ok := ParseAccess(line)
+	if !ok {
+		return false
+	}
+	if len(f.Statuses) > 0 {
+		matched := false
+		for _, s := range f.Statuses {
+			matched = matched || s.matches(e.Status)
+		}
+		if !matched {
+			return false
+		}
+	}
+	if f.Path != "" && !strings.Contains(e.Path, f.Path) {
+		return false
+	}
+	if f.SlowerThan > 0 && (!e.HasDuration || e.Duration <= f.SlowerThan) {
+		return false
+	}
+	return true
+}
+
+// FilterWriter writes the lines that pass a filter to an underlying writer,
+// for streaming logs through it.
+type FilterWriter struct {
+	w      io.Writer
+	filter Filter
+	buf    []byte
+}
+
+// NewFilterWriter returns a FilterWriter writing to w.
+func NewFilterWriter(w io.Writer, filter Filter) *FilterWriter {
+	return &FilterWriter{w: w, filter: filter}
+}
+
+func (f *FilterWriter) Write(p []byte) (int, error) {
+	f.buf = append(f.buf, p...)
+	for {
+		i := bytes.IndexByte(f.buf, '\n')
+		if i < 0 {
+			return len(p), nil
+		}
+		if err := f.writeLine(f.buf[:i+1]); err != nil {
+			return len(p), err
+		}
+		f.buf = f.buf[i+1:]
+	}
+}
+
+// Flush writes a trailing partial line if it passes the filter.
+func (f *FilterWriter) Flush() error {
+	if len(f.buf) == 0 {
+		return nil
+	}
+	line := append(f.buf, '\n')
+	f.buf = nil
+	return f.writeLine(line)
+}
+
+func (f *FilterWriter) writeLine(line []byte) error {
+	if !f.filter.Match(string(bytes.TrimRight(line, "\r\n"))) {
+		return nil
+	}
+	_, err := f.w.Write(line)
+	return err
+}
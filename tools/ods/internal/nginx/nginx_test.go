@@ -0,0 +1,108 @@
+package nginx
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSummarize(t *testing.T) {
+	dump := `nginx: the configuration file /etc/nginx/nginx.conf syntax is ok
+nginx: configuration file /etc/nginx/nginx.conf test is successful
+# configuration file /etc/nginx/nginx.conf:
+events { worker_connections 1024; }
+http {
+    include /etc/nginx/conf.d/*.conf;
+}
+
+# configuration file /etc/nginx/conf.d/app.conf:
+upstream api_server {
+    # fail_timeout=0 means we always retry an upstream even if it failed
+    server api_server:8080 fail_timeout=0;
+}
+
+server {
+    listen 80 default_server;
+    client_max_body_size 5G;    # Maximum upload size
+
+    location ~ ^/(api|openapi.json)(/.*)?$ {
+        proxy_read_timeout
+            300s;
+        proxy_pass http://api_server;
+    }
+}
+`
+	got := Summarize(dump)
+	var lines []string
+	for _, d := range got {
+		lines = append(lines, d.String())
+	}
+	want := []string{
+		"/etc/nginx/conf.d/app.conf:3  upstream api_server  server api_server:8080 fail_timeout=0",
+		"/etc/nginx/conf.d/app.conf:7  server :80  listen 80 default_server",
+		"/etc/nginx/conf.d/app.conf:8  server :80  client_max_body_size 5G",
+		"/etc/nginx/conf.d/app.conf:11  server :80 > location ~ ^/(api|openapi.json)(/.*)?$  proxy_read_timeout 300s",
+		"/etc/nginx/conf.d/app.conf:13  server :80 > location ~ ^/(api|openapi.json)(/.*)?$  proxy_pass http://api_server",
+	}
+	if strings.Join(lines, "\n") != strings.Join(want, "\n") {
+		t.Errorf("Summarize() =\n%s\nwant\n%s", strings.Join(lines, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestParseStatuses(t *testing.T) {
+	got, err := ParseStatuses("5xx, 404")
+	if err != nil || len(got) != 2 || got[0] != (StatusMatch{Class: 5}) || got[1] != (StatusMatch{Code: 404}) {
+		t.Errorf("ParseStatuses() = %v, %v", got, err)
+	}
+	for _, bad := range []string{"6xx", "abc", "99"} {
+		if _, err := ParseStatuses(bad); err == nil {
+			t.Errorf("ParseStatuses(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestFilter(t *testing.T) {
+	compose502 := `172.18.0.1 - - [15/Oct/2026:10:00:00 +0000] "POST /api/chat/send-message HTTP/1.1" 502 157 "-" "curl/8.5" "-" rt=60.002`
+	compose200 := `172.18.0.1 - - [15/Oct/2026:10:00:01 +0000] "GET /api/me HTTP/1.1" 200 512 "-" "curl/8.5" "-" rt=0.012`
+	ingress404 := `10.0.0.1 - - [15/Oct/2026:10:00:02 +0000] "GET /missing HTTP/2.0" 404 19 "-" "Mozilla/5.0" 33 0.004 [onyx-web-server-3000] [] 10.1.2.3:3000 19 0.004 404 abc`
+	errorLine := `2026/10/15 10:00:00 [error] 29#29: *1 upstream timed out (110: Connection timed out) while reading response header from upstream`
+	noticeLine := `2026/10/15 10:00:00 [notice] 1#1: signal process started`
+
+	e, ok := ParseAccess(ingress404)
+	if !ok || e.Method != "GET" || e.Path != "/missing" || e.Status != 404 || e.Duration != 4*time.Millisecond {
+		t.Errorf("ParseAccess(ingress) = %+v, %v", e, ok)
+	}
+
+	statuses, _ := ParseStatuses("5xx")
+	for _, tc := range []struct {
+		name   string
+		filter Filter
+		want   []string
+	}{
+		{"none", Filter{}, []string{compose502, compose200, ingress404, errorLine, noticeLine}},
+		{"status", Filter{Statuses: statuses}, []string{compose502}},
+		{"path", Filter{Path: "/api/"}, []string{compose502, compose200}},
+		{"slow", Filter{SlowerThan: time.Second}, []string{compose502}},
+		{"errors", Filter{Errors: true}, []string{errorLine}},
+		{"status or errors", Filter{Statuses: statuses, Errors: true}, []string{compose502, errorLine}},
+	} {
+		var kept []string
+		for _, line := range []string{compose502, compose200, ingress404, errorLine, noticeLine} {
+			if tc.filter.Match(line) {
+				kept = append(kept, line)
+			}
+		}
+		if strings.Join(kept, "\n") != strings.Join(tc.want, "\n") {
+			t.Errorf("%s: kept\n%s", tc.name, strings.Join(kept, "\n"))
+		}
+	}
+
+	var b strings.Builder
+	w := NewFilterWriter(&b, Filter{Statuses: statuses})
+	_, _ = w.Write([]byte(compose200 + "\n" + compose502[:20]))
+	_, _ = w.Write([]byte(compose502[20:]))
+	_ = w.Flush()
+	if b.String() != compose502+"\n" {
+		t.Errorf("FilterWriter wrote %q", b.String())
+	}
+}
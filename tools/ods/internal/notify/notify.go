@@ -0,0 +1,120 @@
+// Package notify posts the outcome of long-running ods commands to chat.
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/config"
+)
+
+// WebhookEnv overrides the configured default Slack webhook URL.
+const WebhookEnv = "ODS_SLACK_WEBHOOK_URL"
+
+const requestTimeout = 10 * time.Second
+
+// maxSummaryLen keeps messages readable; Slack truncates long ones anyway.
+const maxSummaryLen = 2500
+
+// Target is where a notification goes.
+type Target struct {
+	Service string // only "slack" for now
+	Channel string // e.g. "#oncall"; empty uses the webhook's own channel
+}
+
+func (t Target) String() string {
+	if t.Channel == "" {
+		return t.Service
+	}
+	return t.Service + ":" + t.Channel
+}
+
+// ParseTarget parses "slack" or "slack:#channel".
+func ParseTarget(spec string) (Target, error) {
+	service, channel, _ := strings.Cut(strings.TrimSpace(spec), ":")
+	if service != "slack" {
+		return Target{}, fmt.Errorf("unsupported notification target %q (expected slack or slack:#channel)", spec)
+	}
+	if channel != "" && !strings.HasPrefix(channel, "#") && !strings.HasPrefix(channel, "@") {
+		channel = "#" + channel
+	}
+	return Target{Service: service, Channel: channel}, nil
+}
+
+// WebhookURL returns the webhook for target: the channel's own webhook from
+// cfg, then $ODS_SLACK_WEBHOOK_URL, then cfg's "default" webhook.
+func WebhookURL(t Target, cfg config.NotifyConfig) (string, error) {
+	if url := cfg.SlackWebhooks[t.Channel]; t.Channel != "" && url != "" {
+		return url, nil
+	}
+	if url := os.Getenv(WebhookEnv); url != "" {
+		return url, nil
+	}
+	if url := cfg.SlackWebhooks["default"]; url != "" {
+		return url, nil
+	}
+	return "", fmt.Errorf("no Slack webhook for %s; set %s or notify.slack_webhooks in the ods config", t, WebhookEnv)
+}
+
+// Message is the outcome of a command run.
+type Message struct {
+	Command  string
+	OK       bool
+	Duration time.Duration
+	Summary  string
+	Actor    string
+}
+
+// Text renders the message as Slack mrkdwn.
+func (m Message) Text() string {
+	icon, outcome := ":white_check_mark:", "finished"
+	if !m.OK {
+		icon, outcome = ":x:", "failed"
+	}
+	text := fmt.Sprintf("%s `%s` %s after %s", icon, m.Command, outcome, m.Duration.Round(time.Second))
+	if m.Actor != "" {
+		text += " (" + m.Actor + ")"
+	}
+	if summary := strings.TrimSpace(m.Summary); summary != "" {
+		if len(summary) > maxSummaryLen {
+			summary = summary[:maxSummaryLen] + "\n…"
+		}
+		text += "\n```" + summary + "```"
+	}
+	return text
+}
+
+// Send posts m to the webhook at url. The channel override is honoured by
+// legacy webhooks and ignored by app webhooks, which are bound to a channel.
+func Send(url string, t Target, m Message) error {
+	return SendText(url, t, m.Text())
+}
+
+// SendText posts text, in Slack mrkdwn, to the webhook at url.
+func SendText(url string, t Target, text string) error {
+	payload := map[string]string{"text": text}
+	if t.Channel != "" {
+		payload["channel"] = t.Channel
+	}
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return err
+	}
+
+	resp, err := (&http.Client{Timeout: requestTimeout}).Post(url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("slack webhook request failed: %w", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("slack webhook returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
@@ -0,0 +1,89 @@
+package notify
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/config"
+)
+
+func TestParseTarget(t *testing.T) {
+	tests := []struct {
+		spec    string
+		want    Target
+		wantErr bool
+	}{
+		{"slack", Target{Service: "slack"}, false},
+		{"slack:#oncall", Target{Service: "slack", Channel: "#oncall"}, false},
+		{"slack:oncall", Target{Service: "slack", Channel: "#oncall"}, false},
+		{"slack:@jane", Target{Service: "slack", Channel: "@jane"}, false},
+		{"email:me@example.com", Target{}, true},
+	}
+	for _, tt := range tests {
+		got, err := ParseTarget(tt.spec)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseTarget(%q) = %+v, %v; want %+v, err=%v", tt.spec, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+func TestWebhookURL(t *testing.T) {
+	cfg := config.NotifyConfig{SlackWebhooks: map[string]string{
+		"#oncall": "https://hooks.example/oncall",
+		"default": "https://hooks.example/default",
+	}}
+
+	t.Setenv(WebhookEnv, "")
+	if url, _ := WebhookURL(Target{Service: "slack", Channel: "#oncall"}, cfg); url != "https://hooks.example/oncall" {
+		t.Errorf("channel webhook = %q", url)
+	}
+	if url, _ := WebhookURL(Target{Service: "slack", Channel: "#other"}, cfg); url != "https://hooks.example/default" {
+		t.Errorf("default webhook = %q", url)
+	}
+
+	t.Setenv(WebhookEnv, "https://hooks.example/env")
+	if url, _ := WebhookURL(Target{Service: "slack", Channel: "#other"}, cfg); url != "https://hooks.example/env" {
+		t.Errorf("env webhook = %q", url)
+	}
+
+	t.Setenv(WebhookEnv, "")
+	if _, err := WebhookURL(Target{Service: "slack"}, config.NotifyConfig{}); err == nil {
+		t.Error("expected an error with no webhook configured")
+	}
+}
+
+func TestSend(t *testing.T) {
+	var got map[string]string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Errorf("bad payload: %v", err)
+		}
+		_, _ = w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	msg := Message{Command: "ods restart api", OK: false, Duration: 90 * time.Second, Summary: "rollout timed out", Actor: "jane"}
+	if err := Send(srv.URL, Target{Service: "slack", Channel: "#oncall"}, msg); err != nil {
+		t.Fatalf("Send() error: %v", err)
+	}
+	if got["channel"] != "#oncall" {
+		t.Errorf("channel = %q", got["channel"])
+	}
+	for _, want := range []string{":x:", "`ods restart api` failed after 1m30s", "(jane)", "rollout timed out"} {
+		if !strings.Contains(got["text"], want) {
+			t.Errorf("text %q missing %q", got["text"], want)
+		}
+	}
+
+	failing := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "invalid_token", http.StatusForbidden)
+	}))
+	defer failing.Close()
+	if err := Send(failing.URL, Target{Service: "slack"}, msg); err == nil || !strings.Contains(err.Error(), "invalid_token") {
+		t.Errorf("expected webhook error, got %v", err)
+	}
+}
@@ -0,0 +1,328 @@
+"""Describe and probe the OAuth apps a deployment is configured with.
+
+Bundled with ods and piped into `python -` on an api-server pod by `ods
+oauth check`, so the probes leave from the deployment's network and the
+client secrets never leave the pod. Covered are the Google login app
+(OAUTH_CLIENT_ID), the Linear connector app (LINEAR_CLIENT_ID) and every
+built-in external app, whether its client comes from the EXT_APP_* settings
+or from an app row an admin configured. For each it reports the non-secret
+config, the redirect URI the backend sends, and:
+
+  - the token endpoint's answer to exchanging a made-up authorization code.
+    Providers authenticate the client (and most check the redirect URI)
+    before the code, so the error tells a wrong client ID, secret or
+    redirect URI apart from a working app.
+  - the authorize endpoint's answer to the request a login would make, which
+    for Google, Slack and others shows an unregistered redirect URI, an
+    unknown client or an unknown scope before anyone signs in.
+
+Usage:
+    python - <schema> [<provider>]
+
+Progress goes to stderr; the last line on stdout is a JSON object with
+"status", "web_domain" and "apps".
+"""
+
+from __future__ import annotations
+
+import json
+import re
+import sys
+from typing import Any
+from urllib.parse import urlencode
+
+TIMEOUT = 10
+PROBE_CODE = "ods-oauth-check-invalid-code"
+
+GOOGLE_AUTHORIZE_URL = "https://accounts.google.com/o/oauth2/v2/auth"
+GOOGLE_TOKEN_URL = "https://oauth2.googleapis.com/token"
+
+# Error codes providers put in authorize pages and redirects.
+AUTHORIZE_MARKERS = [
+    "redirect_uri_mismatch",
+    "bad_redirect_uri",
+    "invalid_redirect_uri",
+    "redirect_uri did not match",
+    "invalid_client",
+    "deleted_client",
+    "invalid_client_id",
+    "unauthorized_client",
+    "invalid_scope",
+    "invalid_team_for_non_distributed_app",
+]
+
+
+def use_schema(schema: str) -> str:
+    from onyx.db.engine.tenant_utils import validate_tenant_id
+    from shared_configs.configs import MULTI_TENANT
+    from shared_configs.configs import POSTGRES_DEFAULT_SCHEMA
+    from shared_configs.contextvars import CURRENT_TENANT_ID_CONTEXTVAR
+
+    if not schema:
+        if MULTI_TENANT:
+            raise ValueError("This deployment is multi-tenant; pass --tenant")
+        schema = POSTGRES_DEFAULT_SCHEMA
+    elif schema != POSTGRES_DEFAULT_SCHEMA and not validate_tenant_id(schema):
+        raise ValueError(f"Invalid schema {schema!r}")
+    CURRENT_TENANT_ID_CONTEXTVAR.set(schema)
+    return schema
+
+
+def probe_token(
+    url: str, headers: dict[str, str], body: dict[str, str], as_json: bool
+) -> dict[str, Any]:
+    import requests
+
+    print(f"Probing {url}...", file=sys.stderr)
+    try:
+        if as_json:
+            resp = requests.post(url, headers=headers, json=body, timeout=TIMEOUT)
+        else:
+            resp = requests.post(url, headers=headers, data=body, timeout=TIMEOUT)
+    except Exception as e:
+        return {"request_error": f"{type(e).__name__}: {e}"}
+    out: dict[str, Any] = {"status": resp.status_code}
+    try:
+        data = resp.json()
+        error = data.get("error", "")
+        # Some providers nest the error object.
+        if isinstance(error, dict):
+            error = error.get("code") or error.get("message") or ""
+        out["error"] = str(error)
+        out["error_description"] = str(data.get("error_description", ""))
+    except ValueError:
+        pass
+    return out
+
+
+def probe_authorize(url: str, params: dict[str, str]) -> dict[str, Any]:
+    import requests
+
+    full = f"{url}?{urlencode(params)}"
+    try:
+        resp = requests.get(full, timeout=TIMEOUT, allow_redirects=False)
+    except Exception as e:
+        return {"request_error": f"{type(e).__name__}: {e}"}
+    location = resp.headers.get("Location", "")
+    haystack = (location + " " + resp.text[:200_000]).lower()
+    markers = [m for m in AUTHORIZE_MARKERS if m in haystack]
+    # An error redirect back to our own redirect URI carries ?error=...
+    if m := re.search(r"[?&]error=([\w-]+)", location):
+        markers.append(m.group(1))
+    return {
+        "status": resp.status_code,
+        "location": location[:300],
+        "markers": sorted(set(markers)),
+    }
+
+
+def describe(
+    provider: str,
+    label: str,
+    source: str,
+    client_id: str,
+    client_secret: str,
+    redirect_uri: str,
+    authorize_url: str,
+    token_url: str,
+    scope: str,
+    scope_param: str = "scope",
+    token_request: Any = None,
+) -> dict[str, Any]:
+    app: dict[str, Any] = {
+        "provider": provider,
+        "label": label,
+        "source": source,
+        "client_id": client_id,
+        "has_client_secret": bool(client_secret),
+        "redirect_uri": redirect_uri,
+        "authorize_url": authorize_url,
+        "token_url": token_url,
+        "scope": scope,
+    }
+    if not client_id:
+        return app
+
+    params = {
+        "client_id": client_id,
+        "redirect_uri": redirect_uri,
+        "response_type": "code",
+        "state": "ods-oauth-check",
+    }
+    if scope:
+        params[scope_param] = scope
+    app["authorize_probe"] = probe_authorize(authorize_url, params)
+
+    if client_secret:
+        if token_request is None:
+            headers = {
+                "Content-Type": "application/x-www-form-urlencoded",
+                "Accept": "application/json",
+            }
+            body = {
+                "grant_type": "authorization_code",
+                "client_id": client_id,
+                "client_secret": client_secret,
+                "code": PROBE_CODE,
+                "redirect_uri": redirect_uri,
+            }
+            app["token_probe"] = probe_token(token_url, headers, body, False)
+        else:
+            app["token_probe"] = probe_token(
+                token_url,
+                token_request.headers,
+                token_request.body,
+                token_request.json_encoded,
+            )
+    return app
+
+
+def google_login() -> list[dict[str, Any]]:
+    from onyx.configs.app_configs import GOOGLE_LOGIN_BASE_SCOPES
+    from onyx.configs.app_configs import GOOGLE_OAUTH_SCOPE_OVERRIDE
+    from onyx.configs.app_configs import OAUTH_CLIENT_ID
+    from onyx.configs.app_configs import OAUTH_CLIENT_SECRET
+    from onyx.configs.app_configs import WEB_DOMAIN
+
+    scopes = GOOGLE_OAUTH_SCOPE_OVERRIDE or GOOGLE_LOGIN_BASE_SCOPES
+    return [
+        describe(
+            "google-login",
+            "Google login",
+            "env OAUTH_CLIENT_ID",
+            OAUTH_CLIENT_ID,
+            OAUTH_CLIENT_SECRET,
+            f"{WEB_DOMAIN}/auth/oauth/callback",
+            GOOGLE_AUTHORIZE_URL,
+            GOOGLE_TOKEN_URL,
+            " ".join(scopes),
+        )
+    ]
+
+
+def linear_connector() -> list[dict[str, Any]]:
+    from onyx.configs.app_configs import LINEAR_CLIENT_ID
+    from onyx.configs.app_configs import LINEAR_CLIENT_SECRET
+    from onyx.configs.app_configs import WEB_DOMAIN
+    from onyx.configs.constants import DocumentSource
+    from onyx.connectors.cross_connector_utils.miscellaneous_utils import (
+        get_oauth_callback_uri,
+    )
+
+    return [
+        describe(
+            "linear-connector",
+            "Linear connector",
+            "env LINEAR_CLIENT_ID",
+            LINEAR_CLIENT_ID or "",
+            LINEAR_CLIENT_SECRET or "",
+            get_oauth_callback_uri(WEB_DOMAIN, DocumentSource.LINEAR.value),
+            "https://linear.app/oauth/authorize",
+            "https://api.linear.app/oauth/token",
+            "read",
+        )
+    ]
+
+
+def external_apps(schema: str, only: str) -> list[dict[str, Any]]:
+    from sqlalchemy import select
+
+    from onyx.configs.app_configs import WEB_DOMAIN
+    from onyx.db.engine.sql_engine import get_session_with_tenant
+    from onyx.db.models import ExternalApp
+    from onyx.external_apps.providers.base import OAuthExternalAppProvider
+    from onyx.external_apps.providers.base import OnyxManagedExtApp
+    from onyx.external_apps.providers.registry import PROVIDERS
+
+    redirect_uri = f"{WEB_DOMAIN}/craft/v1/apps/oauth/callback"
+    apps = []
+    with get_session_with_tenant(tenant_id=schema) as db_session:
+        rows = db_session.scalars(select(ExternalApp).order_by(ExternalApp.id)).all()
+        for app_type, provider in PROVIDERS.items():
+            name = app_type.value.lower()
+            if only and only != name:
+                continue
+            if not isinstance(provider, OAuthExternalAppProvider):
+                continue
+            spec = provider.spec
+            flow = spec.oauth
+
+            clients: list[tuple[str, str, str]] = []
+            for row in rows:
+                if row.app_type != app_type:
+                    continue
+                creds = row.organization_credentials.get_value(apply_mask=False) or {}
+                state = "" if row.enabled else ", disabled"
+                clients.append(
+                    (
+                        f"external app {row.name!r} (id {row.id}{state})",
+                        creds.get("client_id") or "",
+                        creds.get("client_secret") or "",
+                    )
+                )
+            if isinstance(provider, OnyxManagedExtApp):
+                managed = type(provider).managed_org_credentials
+                if managed.get("client_id") or not clients:
+                    clients.append(
+                        (
+                            f"env EXT_APP_{app_type.value}_CLIENT_ID",
+                            managed.get("client_id") or "",
+                            managed.get("client_secret") or "",
+                        )
+                    )
+            for source, client_id, client_secret in clients:
+                token_request = None
+                if client_id and client_secret:
+                    token_request = provider.build_token_exchange_request(
+                        PROBE_CODE, client_id, client_secret, redirect_uri
+                    )
+                apps.append(
+                    describe(
+                        name,
+                        spec.app_name,
+                        source,
+                        client_id,
+                        client_secret,
+                        redirect_uri,
+                        flow.authorize_url,
+                        flow.token_url,
+                        flow.scope,
+                        flow.scope_param,
+                        token_request,
+                    )
+                )
+    return apps
+
+
+def main() -> None:
+    args = sys.argv[1:]
+    if len(args) not in (1, 2):
+        usage = "Usage: python - <schema> [<provider>]"
+        print(json.dumps({"status": "error", "message": usage}))
+        sys.exit(1)
+
+    from onyx.db.engine.sql_engine import SqlEngine
+
+    SqlEngine.init_engine(pool_size=5, max_overflow=2)
+
+    only = args[1] if len(args) == 2 else ""
+    try:
+        from onyx.configs.app_configs import WEB_DOMAIN
+
+        schema = use_schema(args[0])
+        apps = []
+        if only in ("", "google-login"):
+            apps += google_login()
+        if only in ("", "linear-connector"):
+            apps += linear_connector()
+        if only not in ("google-login", "linear-connector"):
+            apps += external_apps(schema, only)
+        result = {"status": "success", "web_domain": WEB_DOMAIN, "apps": apps}
+    except Exception as e:
+        print(f"Error: {e}", file=sys.stderr)
+        result = {"status": "error", "message": str(e)}
+    print(json.dumps(result))
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,239 @@
+// Package oauthcheck inspects the OAuth apps a deployment is configured
+// with, and asks their providers whether the client and redirect URI are
+// registered, without anyone going through a login.
+package oauthcheck
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/kube"
+)
+
+//go:embed oauth_check.py
+var checkScript string
+
+// Providers whose client IDs have a known format.
+var clientIDFormats = map[string]struct {
+	re      *regexp.Regexp
+	example string
+}{
+	"google-login":    {googleClientID, "1234-abc.apps.googleusercontent.com"},
+	"google_drive":    {googleClientID, "1234-abc.apps.googleusercontent.com"},
+	"gmail":           {googleClientID, "1234-abc.apps.googleusercontent.com"},
+	"google_calendar": {googleClientID, "1234-abc.apps.googleusercontent.com"},
+	"slack":           {regexp.MustCompile(`^\d+\.\d+$`), "1234567890.1234567890"},
+}
+
+var googleClientID = regexp.MustCompile(`^\d+-[a-z0-9]+\.apps\.googleusercontent\.com$`)
+
+// App is an OAuth client the deployment uses, with the outcome of probing
+// its provider. The client secret is reduced to whether it is set.
+type App struct {
+	// Provider is "google-login", "linear-connector" or an external app
+	// type such as "slack".
+	Provider        string          `json:"provider"`
+	Label           string          `json:"label"`
+	Source          string          `json:"source"`
+	ClientID        string          `json:"client_id"`
+	HasClientSecret bool            `json:"has_client_secret"`
+	RedirectURI     string          `json:"redirect_uri"`
+	AuthorizeURL    string          `json:"authorize_url"`
+	TokenURL        string          `json:"token_url"`
+	Scope           string          `json:"scope"`
+	AuthorizeProbe  *AuthorizeProbe `json:"authorize_probe"`
+	TokenProbe      *TokenProbe     `json:"token_probe"`
+}
+
+// AuthorizeProbe is what the authorize endpoint answered to the request a
+// login would make. Markers are the OAuth error codes found in the page or
+// redirect.
+type AuthorizeProbe struct {
+	Status       int      `json:"status"`
+	Location     string   `json:"location"`
+	Markers      []string `json:"markers"`
+	RequestError string   `json:"request_error"`
+}
+
+// TokenProbe is what the token endpoint answered to exchanging a made-up
+// authorization code.
+type TokenProbe struct {
+	Status           int    `json:"status"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+	RequestError     string `json:"request_error"`
+}
+
+// Report is the OAuth apps of a deployment.
+type Report struct {
+	WebDomain string `json:"web_domain"`
+	Apps      []App  `json:"apps"`
+}
+
+// Inspect describes and probes the OAuth apps of schema ("" for the default
+// schema of a single-tenant deployment) on pod, or only those of provider.
+func Inspect(c *kube.Cluster, pod, schema, provider string) (*Report, error) {
+	args := []string{schema}
+	if provider != "" {
+		args = append(args, provider)
+	}
+	out, err := c.RunPython(pod, checkScript, args...)
+	if err != nil {
+		return nil, err
+	}
+	return parseReport(out)
+}
+
+func parseReport(stdout string) (*Report, error) {
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	last := strings.TrimSpace(lines[len(lines)-1])
+	var r struct {
+		Status  string `json:"status"`
+		Message string `json:"message"`
+		Report
+	}
+	if err := json.Unmarshal([]byte(last), &r); err != nil {
+		return nil, fmt.Errorf("unexpected output from oauth check script: %q", last)
+	}
+	if r.Status != "success" {
+		return nil, fmt.Errorf("%s", r.Message)
+	}
+	return &r.Report, nil
+}
+
+// DomainFindings lists what looks wrong with WEB_DOMAIN, which every
+// redirect URI is built from.
+func (r *Report) DomainFindings() []string {
+	if r.WebDomain == "" {
+		return []string{"WEB_DOMAIN is not set, so redirect URIs are relative and every provider will reject them"}
+	}
+	u, err := url.Parse(r.WebDomain)
+	if err != nil || u.Host == "" {
+		return []string{fmt.Sprintf("WEB_DOMAIN %q is not an absolute URL", r.WebDomain)}
+	}
+	var findings []string
+	switch {
+	case isLocalhost(u.Hostname()):
+		findings = append(findings, fmt.Sprintf("WEB_DOMAIN is %s, so providers send users back to their own machine", r.WebDomain))
+	case u.Scheme != "https":
+		findings = append(findings, fmt.Sprintf("WEB_DOMAIN %s is not https; Google, Slack and most providers only accept http redirect URIs for localhost", r.WebDomain))
+	}
+	if strings.HasSuffix(r.WebDomain, "/") {
+		findings = append(findings, "WEB_DOMAIN ends with a slash, so redirect URIs get a double slash that will not match the registered ones")
+	}
+	return findings
+}
+
+// Findings lists what looks wrong with the app's configuration or what its
+// provider answered, given the deployment's WEB_DOMAIN.
+func (a *App) Findings(webDomain string) []string {
+	if a.ClientID == "" {
+		return []string{"No client ID is configured, so this app cannot be used"}
+	}
+	var findings []string
+	if strings.TrimSpace(a.ClientID) != a.ClientID {
+		findings = append(findings, "The client ID has leading or trailing whitespace, which providers do not ignore")
+	}
+	if f, ok := clientIDFormats[a.Provider]; ok && !f.re.MatchString(strings.TrimSpace(a.ClientID)) {
+		findings = append(findings, fmt.Sprintf("The client ID does not look like a %s client ID (%s)", a.Label, f.example))
+	}
+	if !a.HasClientSecret {
+		findings = append(findings, "No client secret is configured, so the code exchange after consent will fail")
+	}
+	if webDomain != "" && !strings.HasPrefix(a.RedirectURI, strings.TrimRight(webDomain, "/")+"/") {
+		findings = append(findings, fmt.Sprintf("The redirect URI %s is not under WEB_DOMAIN %s, so the callback lands on another host", a.RedirectURI, webDomain))
+	}
+	if v := a.AuthorizeVerdict(); v != "" && !strings.HasPrefix(v, "ok") {
+		findings = append(findings, v)
+	}
+	if v := a.TokenVerdict(); v != "" && !strings.HasPrefix(v, "ok") {
+		findings = append(findings, v)
+	}
+	return findings
+}
+
+// AuthorizeVerdict interprets the authorize probe. Providers check the
+// client, redirect URI and scope before showing a login, so an error in the
+// page or in the redirect back points at the registration. It returns ""
+// when no probe was made, and a verdict starting with "ok" when nothing was
+// refused.
+func (a *App) AuthorizeVerdict() string {
+	p := a.AuthorizeProbe
+	switch {
+	case p == nil:
+		return ""
+	case p.RequestError != "":
+		return "The authorize endpoint could not be reached from the api-server: " + p.RequestError
+	}
+	for _, m := range p.Markers {
+		switch m {
+		case "redirect_uri_mismatch", "bad_redirect_uri", "invalid_redirect_uri", "redirect_uri did not match":
+			return fmt.Sprintf("The provider does not have %s registered as a redirect URI for this client", a.RedirectURI)
+		case "invalid_client", "deleted_client", "invalid_client_id", "unauthorized_client":
+			return fmt.Sprintf("The provider does not recognise the client ID (%s); it may have been deleted or belong to another account", m)
+		case "invalid_scope":
+			return fmt.Sprintf("The provider refused the requested scope %q", a.Scope)
+		case "invalid_team_for_non_distributed_app":
+			return "The Slack app is not distributed, so only its own workspace can install it"
+		}
+	}
+	switch {
+	case len(p.Markers) > 0:
+		return fmt.Sprintf("The authorize endpoint answered with %s", strings.Join(p.Markers, ", "))
+	case p.Status >= 500:
+		return fmt.Sprintf("The authorize endpoint failed with HTTP %d", p.Status)
+	case p.Status >= 400:
+		return fmt.Sprintf("The authorize endpoint answered HTTP %d; check the client ID and redirect URI", p.Status)
+	}
+	return "ok: the provider showed its login or consent page"
+}
+
+// TokenVerdict interprets the token probe: providers authenticate the
+// client, and most compare the redirect URI, before looking at the code, so
+// an error about the code shows the rest is right. It returns "" when no
+// probe was made, and a verdict starting with "ok" when the client was
+// accepted.
+func (a *App) TokenVerdict() string {
+	p := a.TokenProbe
+	switch {
+	case p == nil:
+		return ""
+	case p.RequestError != "":
+		return "The token endpoint could not be reached from the api-server: " + p.RequestError
+	case p.Status == 0:
+		return ""
+	}
+	detail := p.ErrorDescription
+	if detail == "" {
+		detail = p.Error
+	}
+	if detail == "" {
+		detail = fmt.Sprintf("HTTP %d", p.Status)
+	}
+	switch p.Error {
+	case "invalid_client", "unauthorized_client", "invalid_client_id", "bad_client_secret", "incorrect_client_credentials", "invalid_client_secret":
+		return "The provider rejected the client ID or secret (" + detail + "); a rotated or mistyped secret is the usual cause"
+	case "redirect_uri_mismatch", "bad_redirect_uri", "invalid_redirect_uri":
+		return fmt.Sprintf("The provider rejected the redirect URI %s (%s)", a.RedirectURI, detail)
+	case "":
+	default:
+		return fmt.Sprintf("ok: the provider accepted the client and refused only the made-up code (%s)", p.Error)
+	}
+	switch {
+	case p.Status == 401:
+		return "The provider rejected the client ID or secret (" + detail + ")"
+	case p.Status >= 500:
+		return fmt.Sprintf("The token endpoint failed with HTTP %d", p.Status)
+	case p.Status < 300:
+		return fmt.Sprintf("The token endpoint answered HTTP %d to a made-up code; check the token URL", p.Status)
+	}
+	return fmt.Sprintf("The token endpoint answered HTTP %d without an OAuth error; check the token URL", p.Status)
+}
+
+func isLocalhost(host string) bool {
+	return host == "localhost" || host == "127.0.0.1" || host == "::1" || strings.HasSuffix(host, ".localhost")
+}
@@ -0,0 +1,138 @@
+package oauthcheck
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseReport(t *testing.T) {
+	out := "Probing https://oauth2.googleapis.com/token...\n" + `{"status": "success", "web_domain": "https://onyx.example.com", "apps": [{"provider": "google-login", "label": "Google login", "client_id": "1-a.apps.googleusercontent.com", "has_client_secret": true, "token_probe": {"status": 400, "error": "invalid_grant"}}]}`
+	r, err := parseReport(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.WebDomain != "https://onyx.example.com" || len(r.Apps) != 1 || r.Apps[0].TokenProbe == nil || r.Apps[0].TokenProbe.Error != "invalid_grant" {
+		t.Errorf("unexpected report: %+v", r)
+	}
+
+	if _, err := parseReport(`{"status": "error", "message": "This deployment is multi-tenant; pass --tenant"}`); err == nil || err.Error() != "This deployment is multi-tenant; pass --tenant" {
+		t.Errorf("expected the script's error, got %v", err)
+	}
+	if _, err := parseReport("Traceback"); err == nil {
+		t.Error("expected an error for unparseable output")
+	}
+}
+
+func TestTokenVerdict(t *testing.T) {
+	tests := []struct {
+		probe *TokenProbe
+		want  string
+	}{
+		{nil, ""},
+		{&TokenProbe{RequestError: "ConnectTimeout"}, "could not be reached"},
+		{&TokenProbe{Status: 400, Error: "invalid_grant"}, "ok: "},
+		{&TokenProbe{Status: 200, Error: "invalid_code"}, "ok: "},
+		{&TokenProbe{Status: 200, Error: "bad_verification_code"}, "ok: "},
+		{&TokenProbe{Status: 401, Error: "invalid_client", ErrorDescription: "Unauthorized"}, "rejected the client ID or secret (Unauthorized)"},
+		{&TokenProbe{Status: 200, Error: "bad_client_secret"}, "rejected the client ID or secret"},
+		{&TokenProbe{Status: 400, Error: "redirect_uri_mismatch"}, "rejected the redirect URI https://onyx.example.com/cb"},
+		{&TokenProbe{Status: 401}, "rejected the client ID or secret (HTTP 401)"},
+		{&TokenProbe{Status: 502}, "failed with HTTP 502"},
+		{&TokenProbe{Status: 404}, "HTTP 404 without an OAuth error"},
+	}
+	for _, tt := range tests {
+		a := &App{RedirectURI: "https://onyx.example.com/cb", TokenProbe: tt.probe}
+		got := a.TokenVerdict()
+		if (tt.want == "") != (got == "") || !strings.Contains(got, tt.want) {
+			t.Errorf("TokenVerdict(%+v) = %q, want it to contain %q", tt.probe, got, tt.want)
+		}
+	}
+}
+
+func TestAuthorizeVerdict(t *testing.T) {
+	tests := []struct {
+		probe *AuthorizeProbe
+		want  string
+	}{
+		{nil, ""},
+		{&AuthorizeProbe{Status: 302, Location: "https://accounts.google.com/signin"}, "ok: "},
+		{&AuthorizeProbe{Status: 400, Markers: []string{"redirect_uri_mismatch"}}, "does not have https://onyx.example.com/cb registered"},
+		{&AuthorizeProbe{Status: 401, Markers: []string{"deleted_client"}}, "does not recognise the client ID (deleted_client)"},
+		{&AuthorizeProbe{Status: 302, Markers: []string{"invalid_scope"}}, `refused the requested scope "read"`},
+		{&AuthorizeProbe{Status: 302, Markers: []string{"access_denied"}}, "answered with access_denied"},
+		{&AuthorizeProbe{Status: 404}, "answered HTTP 404"},
+	}
+	for _, tt := range tests {
+		a := &App{RedirectURI: "https://onyx.example.com/cb", Scope: "read", AuthorizeProbe: tt.probe}
+		got := a.AuthorizeVerdict()
+		if (tt.want == "") != (got == "") || !strings.Contains(got, tt.want) {
+			t.Errorf("AuthorizeVerdict(%+v) = %q, want it to contain %q", tt.probe, got, tt.want)
+		}
+	}
+}
+
+func TestFindings(t *testing.T) {
+	ok := App{
+		Provider:        "google-login",
+		Label:           "Google login",
+		ClientID:        "1234-abc.apps.googleusercontent.com",
+		HasClientSecret: true,
+		RedirectURI:     "https://onyx.example.com/auth/oauth/callback",
+		AuthorizeProbe:  &AuthorizeProbe{Status: 302},
+		TokenProbe:      &TokenProbe{Status: 400, Error: "invalid_grant"},
+	}
+	if f := ok.Findings("https://onyx.example.com"); len(f) != 0 {
+		t.Errorf("expected no findings, got %v", f)
+	}
+
+	if f := (&App{Provider: "slack"}).Findings("https://onyx.example.com"); len(f) != 1 || !strings.Contains(f[0], "No client ID") {
+		t.Errorf("expected only the missing client ID, got %v", f)
+	}
+
+	bad := ok
+	bad.ClientID = "1234-abc.apps.googleusercontent.com "
+	bad.HasClientSecret = false
+	bad.RedirectURI = "http://localhost:3000/auth/oauth/callback"
+	bad.TokenProbe = nil
+	want := []string{"whitespace", "No client secret", "not under WEB_DOMAIN"}
+	f := bad.Findings("https://onyx.example.com")
+	if len(f) != len(want) {
+		t.Fatalf("expected %d findings, got %v", len(want), f)
+	}
+	for i, w := range want {
+		if !strings.Contains(f[i], w) {
+			t.Errorf("finding %d = %q, want it to contain %q", i, f[i], w)
+		}
+	}
+
+	slack := App{Provider: "slack", Label: "Slack", ClientID: "abc", HasClientSecret: true, RedirectURI: "https://onyx.example.com/craft/v1/apps/oauth/callback"}
+	if f := slack.Findings("https://onyx.example.com"); len(f) != 1 || !strings.Contains(f[0], "does not look like a Slack client ID") {
+		t.Errorf("expected a client ID format finding, got %v", f)
+	}
+}
+
+func TestDomainFindings(t *testing.T) {
+	tests := []struct {
+		domain string
+		want   string
+	}{
+		{"https://onyx.example.com", ""},
+		{"", "not set"},
+		{"onyx.example.com", "not an absolute URL"},
+		{"http://localhost:3000", "their own machine"},
+		{"http://onyx.example.com", "not https"},
+		{"https://onyx.example.com/", "double slash"},
+	}
+	for _, tt := range tests {
+		f := (&Report{WebDomain: tt.domain}).DomainFindings()
+		if tt.want == "" {
+			if len(f) != 0 {
+				t.Errorf("DomainFindings(%q) = %v, want none", tt.domain, f)
+			}
+			continue
+		}
+		if len(f) != 1 || !strings.Contains(f[0], tt.want) {
+			t.Errorf("DomainFindings(%q) = %v, want one containing %q", tt.domain, f, tt.want)
+		}
+	}
+}
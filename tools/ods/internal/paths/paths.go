@@ -17,7 +17,8 @@ func GitRoot() (string, error) {
 	if err != nil {
 		return "", err
 	}
-	return strings.TrimSpace(string(output)), nil
+	// git prints forward slashes (C:/src/onyx) even on Windows.
+	return filepath.FromSlash(strings.TrimSpace(string(output))), nil
 }
 
 // DataDir returns the data directory for onyx-dev tools.
@@ -84,6 +85,12 @@ func EnsureConfigDir() error {
 	return os.MkdirAll(ConfigDir(), 0755)
 }
 
+// JobTemplatesDir returns the directory of user job templates for
+// ods run-job, which take precedence over the built-in ones.
+func JobTemplatesDir() string {
+	return filepath.Join(ConfigDir(), "jobs")
+}
+
 // SnapshotsDir returns the directory for database snapshots.
 func SnapshotsDir() string {
 	return filepath.Join(DataDir(), "snapshots")
@@ -94,6 +101,70 @@ func EnsureSnapshotsDir() error {
 	return os.MkdirAll(SnapshotsDir(), 0755)
 }
 
+// TenantArchivesDir returns the directory for schema snapshots taken before
+// tenants are deleted.
+func TenantArchivesDir() string {
+	return filepath.Join(DataDir(), "tenant-archives")
+}
+
+// TenantMigrationsDir returns the directory for the state and artifacts of
+// tenant migrations between data planes.
+func TenantMigrationsDir() string {
+	return filepath.Join(DataDir(), "tenant-migrations")
+}
+
+// EvalRunsDir returns the directory of saved ods eval run reports.
+func EvalRunsDir() string {
+	return filepath.Join(DataDir(), "eval-runs")
+}
+
+// ReindexRunsDir returns the directory of ods reindex all checkpoints.
+func ReindexRunsDir() string {
+	return filepath.Join(DataDir(), "reindex-runs")
+}
+
+// HistoryPath returns the path to the log of recent ods runs and how they
+// ended, which ods explain reads.
+func HistoryPath() string {
+	return filepath.Join(DataDir(), "history.jsonl")
+}
+
+// ChaosStatePath returns the path to the record of faults ods chaos left in
+// place in the local stack.
+func ChaosStatePath() string {
+	return filepath.Join(DataDir(), "chaos.json")
+}
+
+// ComposeProjectsPath returns the path to the registry of compose projects
+// ods compose started and the host ports each was given.
+func ComposeProjectsPath() string {
+	return filepath.Join(DataDir(), "compose-projects.json")
+}
+
+// WorkspacesPath returns the path to the record of branch workspaces made by
+// ods workspace create.
+func WorkspacesPath() string {
+	return filepath.Join(DataDir(), "workspaces.json")
+}
+
+// AuditLogPath returns the path to the local audit log of actions ods has
+// taken against shared environments.
+func AuditLogPath() string {
+	return filepath.Join(DataDir(), "audit.log")
+}
+
+// ProdSessionsPath returns the path to the state of active time-boxed
+// production access sessions.
+func ProdSessionsPath() string {
+	return filepath.Join(DataDir(), "prod-sessions.json")
+}
+
+// TLSDir returns the directory of the local CA and dev stack certificates
+// made by ods tls setup.
+func TLSDir() string {
+	return filepath.Join(DataDir(), "tls")
+}
+
 // BackendDir returns the backend directory relative to the git root.
 func BackendDir() (string, error) {
 	root, err := GitRoot()
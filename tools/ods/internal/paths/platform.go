@@ -0,0 +1,57 @@
+package paths
+
+import (
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+)
+
+// Executable returns the path of the named tool (docker, npm, kubectl, ...).
+// On Windows it also tries the .exe and .cmd shims npm and friends install,
+// and under WSL it falls back to the Windows .exe when only the Windows
+// build is on PATH (Docker Desktop without WSL integration). When nothing is
+// found it returns name unchanged so exec reports the usual "not found".
+func Executable(name string) string {
+	return executable(name, runtime.GOOS, IsWSL(), exec.LookPath)
+}
+
+func executable(name, goos string, wsl bool, lookPath func(string) (string, error)) string {
+	candidates := []string{name}
+	switch {
+	case goos == "windows":
+		candidates = append(candidates, name+".exe", name+".cmd")
+	case wsl:
+		candidates = append(candidates, name+".exe")
+	}
+	for _, c := range candidates {
+		if p, err := lookPath(c); err == nil {
+			return p
+		}
+	}
+	return name
+}
+
+// IsWSL reports whether ods is running under the Windows Subsystem for Linux.
+func IsWSL() bool {
+	if runtime.GOOS != "linux" {
+		return false
+	}
+	if os.Getenv("WSL_DISTRO_NAME") != "" {
+		return true
+	}
+	release, err := os.ReadFile("/proc/sys/kernel/osrelease")
+	return err == nil && strings.Contains(strings.ToLower(string(release)), "microsoft")
+}
+
+// OnWindowsDrive reports whether path is a Windows drive mounted into WSL
+// (e.g. /mnt/c/src/onyx). Bind mounts and file watching from there are slow
+// and unreliable in Docker.
+func OnWindowsDrive(path string) bool {
+	rest, ok := strings.CutPrefix(path, "/mnt/")
+	if !ok || len(rest) == 0 {
+		return false
+	}
+	drive, _, _ := strings.Cut(rest, "/")
+	return len(drive) == 1 && drive[0] >= 'a' && drive[0] <= 'z'
+}
@@ -0,0 +1,56 @@
+package paths
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestExecutable(t *testing.T) {
+	onPath := func(names ...string) func(string) (string, error) {
+		return func(name string) (string, error) {
+			for _, n := range names {
+				if n == name {
+					return "/bin/" + name, nil
+				}
+			}
+			return "", errors.New("not found")
+		}
+	}
+
+	tests := []struct {
+		name string
+		goos string
+		wsl  bool
+		path []string
+		want string
+	}{
+		{"docker", "linux", false, []string{"docker"}, "/bin/docker"},
+		{"npm", "windows", false, []string{"npm.cmd"}, "/bin/npm.cmd"},
+		{"kubectl", "windows", false, []string{"kubectl.exe", "kubectl.cmd"}, "/bin/kubectl.exe"},
+		{"docker", "linux", true, []string{"docker.exe"}, "/bin/docker.exe"},
+		{"docker", "linux", true, []string{"docker", "docker.exe"}, "/bin/docker"},
+		{"npm", "linux", false, []string{"npm.cmd"}, "npm"},
+		{"docker", "darwin", false, nil, "docker"},
+	}
+	for _, tt := range tests {
+		if got := executable(tt.name, tt.goos, tt.wsl, onPath(tt.path...)); got != tt.want {
+			t.Errorf("executable(%q, %s, wsl=%v) = %q, want %q", tt.name, tt.goos, tt.wsl, got, tt.want)
+		}
+	}
+}
+
+func TestOnWindowsDrive(t *testing.T) {
+	tests := map[string]bool{
+		"/mnt/c/src/onyx": true,
+		"/mnt/d":          true,
+		"/mnt/wsl/shared": false,
+		"/home/jane/onyx": false,
+		"/mnt/":           false,
+		"C:\\src\\onyx":   false,
+	}
+	for path, want := range tests {
+		if got := OnWindowsDrive(path); got != want {
+			t.Errorf("OnWindowsDrive(%q) = %v, want %v", path, got, want)
+		}
+	}
+}
@@ -0,0 +1,267 @@
+"""Pause or resume the indexing of tenants by pausing their connectors.
+
+Bundled with ods and piped into `python -` on an api-server pod by `ods
+pause-tenant-indexing` and `ods resume-tenant-indexing`. A tenant is paused
+the way the admin UI pauses a connector, by setting its connector/credential
+pairs to PAUSED: the indexing beat schedules no new attempts for them, and a
+running attempt stops at its next batch, keeping its checkpoint. Unlike the
+UI, running attempts are not revoked, so no work in flight is thrown away.
+
+The pairs paused, with their previous status, are recorded in the tenant's
+Redis under RECORD_KEY, so resuming restores exactly those pairs and leaves
+connectors an admin paused alone.
+
+Usage:
+    python - pause <reason> <actor> <schema> [<schema> ...]
+    python - pause-all <reason> <actor>
+    python - resume <schema> [<schema> ...]
+    python - resume-all
+    python - status <schema> [<schema> ...]
+    python - status-all
+
+An empty <schema> means the default schema of a single-tenant deployment;
+the -all forms cover every tenant, and resume-all and status-all only those
+paused by ods.
+
+Progress goes to stderr; the last line on stdout is a JSON object with
+"status" and "tenants", one per tenant with "tenant", "paused" (pairs
+recorded as paused by ods), "changed" (pairs whose status this run changed),
+"running" (index attempts of recorded pairs still in progress), "reason",
+"actor", "paused_at" and "error".
+"""
+
+from __future__ import annotations
+
+import json
+import sys
+from datetime import datetime
+from datetime import timezone
+from typing import Any
+
+RECORD_KEY = "ods_indexing_paused"
+
+
+def use_schema(schema: str) -> str:
+    from onyx.db.engine.tenant_utils import validate_tenant_id
+    from shared_configs.configs import MULTI_TENANT
+    from shared_configs.configs import POSTGRES_DEFAULT_SCHEMA
+    from shared_configs.contextvars import CURRENT_TENANT_ID_CONTEXTVAR
+
+    if not schema:
+        if MULTI_TENANT:
+            raise ValueError("This deployment is multi-tenant; name the tenants")
+        schema = POSTGRES_DEFAULT_SCHEMA
+    elif schema != POSTGRES_DEFAULT_SCHEMA and not validate_tenant_id(schema):
+        raise ValueError(f"Invalid schema {schema!r}")
+    CURRENT_TENANT_ID_CONTEXTVAR.set(schema)
+    return schema
+
+
+def load_record(schema: str) -> dict[str, Any] | None:
+    from onyx.redis.redis_pool import get_redis_client
+
+    raw = get_redis_client(tenant_id=schema).get(RECORD_KEY)
+    return json.loads(raw) if raw else None
+
+
+def save_record(schema: str, record: dict[str, Any] | None) -> None:
+    from onyx.redis.redis_pool import get_redis_client
+
+    r = get_redis_client(tenant_id=schema)
+    if record is None:
+        r.delete(RECORD_KEY)
+    else:
+        r.set(RECORD_KEY, json.dumps(record))
+
+
+def running_attempts(db_session: Any, pair_ids: list[int]) -> int:
+    from sqlalchemy import func
+    from sqlalchemy import select
+
+    from onyx.db.enums import IndexingStatus
+    from onyx.db.models import IndexAttempt
+
+    if not pair_ids:
+        return 0
+    return db_session.scalar(
+        select(func.count())
+        .select_from(IndexAttempt)
+        .where(
+            IndexAttempt.connector_credential_pair_id.in_(pair_ids),
+            IndexAttempt.status == IndexingStatus.IN_PROGRESS,
+        )
+    )
+
+
+def summary(schema: str, record: dict[str, Any] | None, db_session: Any) -> dict:
+    record = record or {}
+    pairs = [int(p) for p in record.get("pairs", {})]
+    return {
+        "tenant": schema,
+        "paused": len(pairs),
+        "changed": 0,
+        "running": running_attempts(db_session, pairs),
+        "reason": record.get("reason", ""),
+        "actor": record.get("actor", ""),
+        "paused_at": record.get("paused_at"),
+        "error": "",
+    }
+
+
+def pause(schema: str, reason: str, actor: str) -> dict[str, Any]:
+    from sqlalchemy import select
+
+    from onyx.db.connector_credential_pair import (
+        update_connector_credential_pair_from_id,
+    )
+    from onyx.db.engine.sql_engine import get_session_with_tenant
+    from onyx.db.enums import ConnectorCredentialPairStatus
+    from onyx.db.models import ConnectorCredentialPair
+
+    schema = use_schema(schema)
+    record = load_record(schema) or {
+        "pairs": {},
+        "reason": reason,
+        "actor": actor,
+        "paused_at": datetime.now(timezone.utc).isoformat(),
+    }
+    changed = 0
+    with get_session_with_tenant(tenant_id=schema) as db_session:
+        pairs = db_session.scalars(
+            select(ConnectorCredentialPair).where(
+                ConnectorCredentialPair.status.in_(
+                    ConnectorCredentialPairStatus.active_statuses()
+                )
+            )
+        ).all()
+        for pair in pairs:
+            record["pairs"][str(pair.id)] = pair.status.value
+            update_connector_credential_pair_from_id(
+                db_session=db_session,
+                cc_pair_id=pair.id,
+                status=ConnectorCredentialPairStatus.PAUSED,
+            )
+            changed += 1
+        # Record before committing, so a pair is never paused unrecorded.
+        save_record(schema, record)
+        db_session.commit()
+        result = summary(schema, record, db_session)
+    result["changed"] = changed
+    return result
+
+
+def resume(schema: str) -> dict[str, Any]:
+    from onyx.db.connector_credential_pair import get_connector_credential_pair_from_id
+    from onyx.db.connector_credential_pair import (
+        update_connector_credential_pair_from_id,
+    )
+    from onyx.db.engine.sql_engine import get_session_with_tenant
+    from onyx.db.enums import ConnectorCredentialPairStatus
+    from onyx.redis.redis_connector import RedisConnector
+
+    schema = use_schema(schema)
+    record = load_record(schema)
+    changed = 0
+    with get_session_with_tenant(tenant_id=schema) as db_session:
+        result = summary(schema, record, db_session)
+        for pair_id, previous in (record or {}).get("pairs", {}).items():
+            pair = get_connector_credential_pair_from_id(
+                db_session=db_session, cc_pair_id=int(pair_id)
+            )
+            # Leave pairs that were deleted or changed by someone since.
+            if pair is None or pair.status != ConnectorCredentialPairStatus.PAUSED:
+                continue
+            update_connector_credential_pair_from_id(
+                db_session=db_session,
+                cc_pair_id=pair.id,
+                status=ConnectorCredentialPairStatus(previous),
+            )
+            RedisConnector(schema, pair.id).stop.set_fence(False)
+            changed += 1
+        db_session.commit()
+    save_record(schema, None)
+    result["changed"] = changed
+    return result
+
+
+def status(schema: str) -> dict[str, Any]:
+    from onyx.db.engine.sql_engine import get_session_with_tenant
+
+    schema = use_schema(schema)
+    with get_session_with_tenant(tenant_id=schema) as db_session:
+        return summary(schema, load_record(schema), db_session)
+
+
+def all_tenants(only_paused: bool) -> list[str]:
+    from onyx.db.engine.tenant_utils import get_all_tenant_ids
+    from shared_configs.configs import MULTI_TENANT
+    from shared_configs.configs import TENANT_ID_PREFIX
+
+    if not MULTI_TENANT:
+        raise ValueError("This deployment is not multi-tenant; drop --all-tenants")
+    tenants = [t for t in get_all_tenant_ids() if t.startswith(TENANT_ID_PREFIX)]
+    if only_paused:
+        tenants = [t for t in tenants if load_record(t)]
+    return tenants
+
+
+def each(schemas: list[str], fn: Any, *args: str) -> list[dict[str, Any]]:
+    results = []
+    for i, schema in enumerate(schemas, 1):
+        if i % 50 == 0:
+            print(f"{i}/{len(schemas)} tenants...", file=sys.stderr)
+        try:
+            results.append(fn(schema, *args))
+        except Exception as e:
+            results.append({"tenant": schema, "error": str(e)})
+    return results
+
+
+def main() -> None:
+    usage = (
+        "Usage: python - pause <reason> <actor> <schema>... | pause-all <reason> "
+        "<actor> | resume <schema>... | resume-all | status <schema>... | status-all"
+    )
+    args = sys.argv[1:]
+    minimum = {
+        "pause": 4,
+        "pause-all": 3,
+        "resume": 2,
+        "resume-all": 1,
+        "status": 2,
+        "status-all": 1,
+    }
+    if not args or args[0] not in minimum or len(args) < minimum[args[0]]:
+        print(json.dumps({"status": "error", "message": usage}))
+        sys.exit(1)
+    if args[0].endswith("-all") and len(args) != minimum[args[0]]:
+        print(json.dumps({"status": "error", "message": usage}))
+        sys.exit(1)
+
+    from onyx.db.engine.sql_engine import SqlEngine
+
+    SqlEngine.init_engine(pool_size=5, max_overflow=2)
+
+    action = args[0]
+    try:
+        if action == "pause":
+            tenants = each(args[3:], pause, args[1], args[2])
+        elif action == "pause-all":
+            tenants = each(all_tenants(False), pause, args[1], args[2])
+        elif action == "resume":
+            tenants = each(args[1:], resume)
+        elif action == "resume-all":
+            tenants = each(all_tenants(True), resume)
+        elif action == "status":
+            tenants = each(args[1:], status)
+        else:
+            tenants = each(all_tenants(True), status)
+        result = {"status": "success", "tenants": tenants}
+    except Exception as e:
+        print(f"Error: {e}", file=sys.stderr)
+        result = {"status": "error", "message": str(e)}
+    print(json.dumps(result))
+
+
+if __name__ == "__main__":
+    main()
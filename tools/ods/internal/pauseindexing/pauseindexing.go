@@ -0,0 +1,105 @@
+// Package pauseindexing pauses and resumes the indexing of tenants by
+// pausing their connectors, and reports which paused tenants still have
+// indexing attempts running.
+package pauseindexing
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/kube"
+)
+
+//go:embed pause_indexing.py
+var pauseScript string
+
+// Tenant is the pause state of one tenant schema.
+type Tenant struct {
+	Tenant string `json:"tenant"`
+	// Paused is the number of connectors paused by ods.
+	Paused int `json:"paused"`
+	// Changed is the number of connectors whose status this run changed.
+	Changed int `json:"changed"`
+	// Running is the number of index attempts of the paused connectors
+	// still in progress; they stop at their next batch.
+	Running  int     `json:"running"`
+	Reason   string  `json:"reason"`
+	Actor    string  `json:"actor"`
+	PausedAt *string `json:"paused_at"`
+	Error    string  `json:"error"`
+}
+
+// Pause pauses the connectors of schemas ("" for the default schema of a
+// single-tenant deployment), or of every tenant when all is set, and records
+// them as paused by actor for reason.
+func Pause(c *kube.Cluster, pod, reason, actor string, schemas []string, all bool) ([]Tenant, error) {
+	if all {
+		return run(c, pod, "pause-all", reason, actor)
+	}
+	return run(c, pod, append([]string{"pause", reason, actor}, schemas...)...)
+}
+
+// Resume restores the connectors ods paused in schemas, or in every paused
+// tenant when all is set.
+func Resume(c *kube.Cluster, pod string, schemas []string, all bool) ([]Tenant, error) {
+	if all {
+		return run(c, pod, "resume-all")
+	}
+	return run(c, pod, append([]string{"resume"}, schemas...)...)
+}
+
+// Status reports the pause state of schemas, or of every paused tenant when
+// all is set.
+func Status(c *kube.Cluster, pod string, schemas []string, all bool) ([]Tenant, error) {
+	if all {
+		return run(c, pod, "status-all")
+	}
+	return run(c, pod, append([]string{"status"}, schemas...)...)
+}
+
+// Running returns the index attempts still in progress across tenants.
+func Running(tenants []Tenant) int {
+	n := 0
+	for _, t := range tenants {
+		n += t.Running
+	}
+	return n
+}
+
+// Failed returns the tenants the script failed on.
+func Failed(tenants []Tenant) []Tenant {
+	var failed []Tenant
+	for _, t := range tenants {
+		if t.Error != "" {
+			failed = append(failed, t)
+		}
+	}
+	return failed
+}
+
+func run(c *kube.Cluster, pod string, args ...string) ([]Tenant, error) {
+	stdout, err := c.RunPython(pod, pauseScript, args...)
+	if err != nil {
+		return nil, err
+	}
+	return parseResult(stdout)
+}
+
+func parseResult(stdout string) ([]Tenant, error) {
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	last := strings.TrimSpace(lines[len(lines)-1])
+	var r struct {
+		Status  string   `json:"status"`
+		Message string   `json:"message"`
+		Tenants []Tenant `json:"tenants"`
+	}
+	if err := json.Unmarshal([]byte(last), &r); err != nil {
+		return nil, fmt.Errorf("unexpected output from pause script: %q", last)
+	}
+	if r.Status != "success" {
+		return nil, fmt.Errorf("%s", r.Message)
+	}
+	return r.Tenants, nil
+}
@@ -0,0 +1,27 @@
+package pauseindexing
+
+import "testing"
+
+func TestParseResult(t *testing.T) {
+	out := "20 tenants...\n" + `{"status": "success", "tenants": [{"tenant": "tenant_a", "paused": 3, "changed": 3, "running": 1, "reason": "db maintenance", "actor": "alice", "paused_at": "2026-10-15T12:00:00+00:00", "error": ""}, {"tenant": "tenant_b", "error": "Invalid schema 'tenant_b'"}]}`
+	tenants, err := parseResult(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tenants) != 2 || tenants[0].Paused != 3 || tenants[0].PausedAt == nil || tenants[1].PausedAt != nil {
+		t.Fatalf("unexpected tenants: %+v", tenants)
+	}
+	if n := Running(tenants); n != 1 {
+		t.Errorf("Running = %d, want 1", n)
+	}
+	if failed := Failed(tenants); len(failed) != 1 || failed[0].Tenant != "tenant_b" {
+		t.Errorf("unexpected failures: %+v", failed)
+	}
+
+	if _, err := parseResult(`{"status": "error", "message": "This deployment is not multi-tenant; drop --all-tenants"}`); err == nil || err.Error() != "This deployment is not multi-tenant; drop --all-tenants" {
+		t.Errorf("expected the script's error, got %v", err)
+	}
+	if _, err := parseResult("Traceback"); err == nil {
+		t.Error("expected an error for unparseable output")
+	}
+}
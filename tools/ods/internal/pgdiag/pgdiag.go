@@ -0,0 +1,195 @@
+// Package pgdiag holds the curated Postgres diagnostic queries behind
+// `ods pg` and the thresholds used to flag their results.
+package pgdiag
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Thresholds above which rows are flagged.
+const (
+	IdleInTransactionLimit = 5 * time.Minute
+	LockWaitLimit          = 30 * time.Second
+	SlowQueryLimit         = time.Minute
+	ConnectionUsageLimit   = 0.8
+	BloatMinDeadTuples     = 10000
+	BloatDeadRatioLimit    = 0.2
+)
+
+// ConnectionSummarySQL returns the number of connections and the server's
+// max_connections.
+const ConnectionSummarySQL = `SELECT count(*), current_setting('max_connections') FROM pg_stat_activity;`
+
+// ConnectionsSQL groups this database's connections by state, user and
+// application, with the longest time spent in that state.
+const ConnectionsSQL = `SELECT coalesce(state, 'background'), coalesce(usename, ''), coalesce(nullif(application_name, ''), '-'), count(*), ` +
+	`coalesce(extract(epoch FROM max(now() - state_change))::int, 0) ` +
+	`FROM pg_stat_activity WHERE datname = current_database() GROUP BY 1, 2, 3 ORDER BY 4 DESC;`
+
+// LocksSQL lists sessions waiting on a lock with the sessions blocking them.
+const LocksSQL = `SELECT pid, array_to_string(pg_blocking_pids(pid), ','), ` +
+	`coalesce(extract(epoch FROM now() - query_start)::int, 0), coalesce(wait_event, ''), ` +
+	`left(regexp_replace(query, '\s+', ' ', 'g'), 80) ` +
+	`FROM pg_stat_activity WHERE cardinality(pg_blocking_pids(pid)) > 0 ORDER BY 3 DESC;`
+
+// BloatSQL lists the tables with the most dead tuples.
+const BloatSQL = `SELECT schemaname, relname, n_live_tup, n_dead_tup, ` +
+	`coalesce(to_char(greatest(last_vacuum, last_autovacuum), 'YYYY-MM-DD HH24:MI'), 'never') ` +
+	`FROM pg_stat_user_tables WHERE n_dead_tup > 0 %s ORDER BY n_dead_tup DESC LIMIT 25;`
+
+// SlowQueriesSQL lists active queries running for longer than minAge.
+func SlowQueriesSQL(minAge time.Duration) string {
+	return fmt.Sprintf(`SELECT pid, coalesce(usename, ''), state, extract(epoch FROM now() - query_start)::int, `+
+		`left(regexp_replace(query, '\s+', ' ', 'g'), 100) `+
+		`FROM pg_stat_activity WHERE state <> 'idle' AND pid <> pg_backend_pid() `+
+		`AND query_start < now() - interval '%d seconds' ORDER BY 4 DESC LIMIT 50;`, int(minAge.Seconds()))
+}
+
+// TableBloatSQL returns BloatSQL restricted to one table. schema and table
+// must already be validated identifiers.
+func TableBloatSQL(schema, table string) string {
+	return fmt.Sprintf(BloatSQL, fmt.Sprintf("AND schemaname = '%s' AND relname = '%s'", schema, table))
+}
+
+// AllBloatSQL returns BloatSQL across all tables.
+func AllBloatSQL() string {
+	return fmt.Sprintf(BloatSQL, "")
+}
+
+// VacuumSQL vacuums and analyzes a table. schema and table must already be
+// validated identifiers.
+func VacuumSQL(schema, table string) string {
+	return fmt.Sprintf(`VACUUM (ANALYZE) "%s"."%s";`, schema, table)
+}
+
+// Row is one result row, flagged when it crosses a threshold.
+type Row struct {
+	Cells []string
+	Flag  bool
+}
+
+// ConnectionUsage parses ConnectionSummarySQL's row.
+func ConnectionUsage(rows []string) (used, limit int, err error) {
+	f, err := split(rows, 2)
+	if err != nil || len(f) == 0 {
+		return 0, 0, fmt.Errorf("unexpected connection summary %q", rows)
+	}
+	if used, err = strconv.Atoi(f[0][0]); err != nil {
+		return 0, 0, fmt.Errorf("unexpected connection summary %q", rows)
+	}
+	if limit, err = strconv.Atoi(f[0][1]); err != nil {
+		return 0, 0, fmt.Errorf("unexpected connection summary %q", rows)
+	}
+	return used, limit, nil
+}
+
+// Connections formats ConnectionsSQL rows, flagging long idle transactions.
+func Connections(rows []string) ([]Row, error) {
+	fields, err := split(rows, 5)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Row, 0, len(fields))
+	for _, f := range fields {
+		age, err := seconds(f[4])
+		if err != nil {
+			return nil, err
+		}
+		out = append(out, Row{
+			Cells: []string{f[0], f[1], f[2], f[3], FormatDuration(age)},
+			Flag:  f[0] == "idle in transaction" && age > IdleInTransactionLimit,
+		})
+	}
+	return out, nil
+}
+
+// Locks formats LocksSQL rows, flagging long waits.
+func Locks(rows []string) ([]Row, error) {
+	return durationRows(rows, 2, LockWaitLimit)
+}
+
+// SlowQueries formats SlowQueriesSQL rows, flagging very long queries.
+func SlowQueries(rows []string) ([]Row, error) {
+	return durationRows(rows, 3, SlowQueryLimit)
+}
+
+// Bloat formats BloatSQL rows with a dead-tuple percentage, flagging tables
+// with many dead tuples relative to live ones.
+func Bloat(rows []string) ([]Row, error) {
+	fields, err := split(rows, 5)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Row, 0, len(fields))
+	for _, f := range fields {
+		live, err1 := strconv.Atoi(f[2])
+		dead, err2 := strconv.Atoi(f[3])
+		if err1 != nil || err2 != nil {
+			return nil, fmt.Errorf("unexpected tuple counts %q/%q", f[2], f[3])
+		}
+		ratio := 0.0
+		if live+dead > 0 {
+			ratio = float64(dead) / float64(live+dead)
+		}
+		out = append(out, Row{
+			Cells: []string{f[0] + "." + f[1], f[2], f[3], fmt.Sprintf("%.0f%%", ratio*100), f[4]},
+			Flag:  dead >= BloatMinDeadTuples && ratio > BloatDeadRatioLimit,
+		})
+	}
+	return out, nil
+}
+
+// durationRows formats rows whose column col is a number of seconds.
+func durationRows(rows []string, col int, limit time.Duration) ([]Row, error) {
+	fields, err := split(rows, 5)
+	if err != nil {
+		return nil, err
+	}
+	out := make([]Row, 0, len(fields))
+	for _, f := range fields {
+		d, err := seconds(f[col])
+		if err != nil {
+			return nil, err
+		}
+		cells := append([]string(nil), f...)
+		cells[col] = FormatDuration(d)
+		out = append(out, Row{Cells: cells, Flag: d > limit})
+	}
+	return out, nil
+}
+
+// FormatDuration renders a duration compactly (e.g. "2h05m", "45s").
+func FormatDuration(d time.Duration) string {
+	switch {
+	case d >= time.Hour:
+		return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+	case d >= time.Minute:
+		return fmt.Sprintf("%dm%02ds", int(d.Minutes()), int(d.Seconds())%60)
+	default:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	}
+}
+
+func seconds(s string) (time.Duration, error) {
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("unexpected duration %q", s)
+	}
+	return time.Duration(n) * time.Second, nil
+}
+
+// split splits tab-separated rows, requiring n fields each.
+func split(rows []string, n int) ([][]string, error) {
+	out := make([][]string, 0, len(rows))
+	for _, row := range rows {
+		f := strings.Split(row, "\t")
+		if len(f) != n {
+			return nil, fmt.Errorf("unexpected row %q (want %d columns)", row, n)
+		}
+		out = append(out, f)
+	}
+	return out, nil
+}
@@ -0,0 +1,87 @@
+package pgdiag
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestConnectionUsage(t *testing.T) {
+	used, limit, err := ConnectionUsage([]string{"180\t200"})
+	if err != nil || used != 180 || limit != 200 {
+		t.Errorf("ConnectionUsage() = %d, %d, %v", used, limit, err)
+	}
+	if _, _, err := ConnectionUsage(nil); err == nil {
+		t.Error("expected an error for no rows")
+	}
+}
+
+func TestConnections(t *testing.T) {
+	rows, err := Connections([]string{
+		"idle in transaction\tonyx\tcelery\t3\t900",
+		"idle in transaction\tonyx\tapi\t1\t20",
+		"active\tonyx\tapi\t12\t4000",
+	})
+	if err != nil {
+		t.Fatalf("Connections() error: %v", err)
+	}
+	if !rows[0].Flag || rows[1].Flag || rows[2].Flag {
+		t.Errorf("unexpected flags %v %v %v", rows[0].Flag, rows[1].Flag, rows[2].Flag)
+	}
+	if rows[0].Cells[4] != "15m00s" || rows[2].Cells[4] != "1h06m" {
+		t.Errorf("unexpected durations %q %q", rows[0].Cells[4], rows[2].Cells[4])
+	}
+}
+
+func TestLocksAndSlowQueries(t *testing.T) {
+	locks, err := Locks([]string{"101\t99,100\t45\trelation\tUPDATE document SET ..."})
+	if err != nil {
+		t.Fatalf("Locks() error: %v", err)
+	}
+	if !locks[0].Flag || locks[0].Cells[2] != "45s" || locks[0].Cells[1] != "99,100" {
+		t.Errorf("unexpected lock row %+v", locks[0])
+	}
+
+	slow, err := SlowQueries([]string{"7\tonyx\tactive\t30\tSELECT 1"})
+	if err != nil {
+		t.Fatalf("SlowQueries() error: %v", err)
+	}
+	if slow[0].Flag {
+		t.Error("a 30s query should not be flagged")
+	}
+
+	if _, err := Locks([]string{"101\t99"}); err == nil {
+		t.Error("expected an error for a short row")
+	}
+}
+
+func TestBloat(t *testing.T) {
+	rows, err := Bloat([]string{
+		"public\tdocument\t30000\t20000\tnever",
+		"public\tuser\t10\t90\t2026-01-02 03:04",
+	})
+	if err != nil {
+		t.Fatalf("Bloat() error: %v", err)
+	}
+	if !rows[0].Flag || rows[0].Cells[0] != "public.document" || rows[0].Cells[3] != "40%" {
+		t.Errorf("unexpected bloat row %+v", rows[0])
+	}
+	if rows[1].Flag {
+		t.Error("a table with few dead tuples should not be flagged")
+	}
+}
+
+func TestSQLBuilders(t *testing.T) {
+	if s := SlowQueriesSQL(10 * time.Second); !strings.Contains(s, "interval '10 seconds'") {
+		t.Errorf("SlowQueriesSQL() = %q", s)
+	}
+	if s := TableBloatSQL("tenant_1", "document"); !strings.Contains(s, "schemaname = 'tenant_1' AND relname = 'document'") {
+		t.Errorf("TableBloatSQL() = %q", s)
+	}
+	if s := AllBloatSQL(); strings.Contains(s, "%") {
+		t.Errorf("AllBloatSQL() left a format verb: %q", s)
+	}
+	if s := VacuumSQL("tenant_1", "document"); s != `VACUUM (ANALYZE) "tenant_1"."document";` {
+		t.Errorf("VacuumSQL() = %q", s)
+	}
+}
@@ -0,0 +1,316 @@
+// Package playbook runs runbooks written as YAML: a sequence of ods command
+// lines with parameters, conditions on the exit codes of earlier steps and
+// confirmation before destructive ones, for ods run -f.
+package playbook
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/alias"
+)
+
+// Step states.
+const (
+	StateOK       = "ok"
+	StateFailed   = "failed"
+	StateSkipped  = "skipped"
+	StateDeclined = "declined"
+)
+
+var (
+	stepNameRE = regexp.MustCompile(`^[a-z][a-z0-9-]*$`)
+	paramRE    = regexp.MustCompile(`^[A-Z][A-Z0-9_]*$`)
+	refRE      = regexp.MustCompile(`\$\{([A-Za-z0-9_]+)\}`)
+	// condRE matches <step>.ok, <step>.failed, <step>.skipped and
+	// <step>.exit == N or != N.
+	condRE = regexp.MustCompile(`^([a-z][a-z0-9-]*)\.(?:(ok|failed|skipped)|exit\s*(==|!=)\s*(\d+))$`)
+)
+
+// Playbook is a parsed playbook file.
+type Playbook struct {
+	Source      string  `yaml:"-"`
+	Description string  `yaml:"description"`
+	Params      []Param `yaml:"params"`
+	Steps       []Step  `yaml:"steps"`
+}
+
+// Param is a playbook parameter, referenced in steps as ${NAME}.
+type Param struct {
+	Name        string `yaml:"name"`
+	Description string `yaml:"description"`
+	Default     string `yaml:"default"`
+	Required    bool   `yaml:"required"`
+}
+
+// Step is one ods command line of a playbook.
+type Step struct {
+	Name string `yaml:"name"`
+	// Run is the command line without the leading "ods".
+	Run string `yaml:"run"`
+	// If is a condition on an earlier step; the step is skipped when it
+	// does not hold.
+	If string `yaml:"if"`
+	// Confirm asks before running the step, for destructive actions.
+	Confirm bool `yaml:"confirm"`
+	// AllowFailure lets the playbook go on when the step fails, so later
+	// steps can react to it.
+	AllowFailure bool `yaml:"allow_failure"`
+
+	words []string
+	cond  *condition
+}
+
+// condition is a parsed If.
+type condition struct {
+	step string
+	// state is ok, failed or skipped; "" compares the exit code.
+	state string
+	op    string
+	exit  int
+}
+
+// Load reads and parses the playbook at path.
+func Load(path string) (*Playbook, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	return Parse(path, data)
+}
+
+// Parse parses a playbook, checking that its steps are valid command lines
+// that only reference declared parameters and earlier steps.
+func Parse(source string, data []byte) (*Playbook, error) {
+	var p Playbook
+	dec := yaml.NewDecoder(bytes.NewReader(data))
+	dec.KnownFields(true)
+	if err := dec.Decode(&p); err != nil && !errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%s: %w", source, err)
+	}
+	p.Source = source
+	if len(p.Steps) == 0 {
+		return nil, fmt.Errorf("%s: no steps", source)
+	}
+
+	declared := map[string]bool{}
+	for _, param := range p.Params {
+		if !paramRE.MatchString(param.Name) {
+			return nil, fmt.Errorf("%s: invalid parameter name %q (expected UPPER_SNAKE_CASE)", source, param.Name)
+		}
+		if declared[param.Name] {
+			return nil, fmt.Errorf("%s: parameter %s declared twice", source, param.Name)
+		}
+		declared[param.Name] = true
+	}
+
+	seen := map[string]bool{}
+	for i := range p.Steps {
+		s := &p.Steps[i]
+		if !stepNameRE.MatchString(s.Name) {
+			return nil, fmt.Errorf("%s: step %d: invalid name %q (lowercase letters, digits and dashes)", source, i+1, s.Name)
+		}
+		if seen[s.Name] {
+			return nil, fmt.Errorf("%s: step %s defined twice", source, s.Name)
+		}
+		words, err := alias.Split(s.Run)
+		if err != nil {
+			return nil, fmt.Errorf("%s: step %s: %w", source, s.Name, err)
+		}
+		if len(words) == 0 {
+			return nil, fmt.Errorf("%s: step %s: empty run", source, s.Name)
+		}
+		if words[0] == "ods" {
+			return nil, fmt.Errorf("%s: step %s: run is an ods command line without the leading \"ods\"", source, s.Name)
+		}
+		for _, m := range refRE.FindAllStringSubmatch(s.Run, -1) {
+			if !declared[m[1]] {
+				return nil, fmt.Errorf("%s: step %s: undeclared parameter %s", source, s.Name, m[1])
+			}
+		}
+		s.words = words
+		if s.If != "" {
+			c, err := parseCondition(s.If)
+			if err != nil {
+				return nil, fmt.Errorf("%s: step %s: %w", source, s.Name, err)
+			}
+			if !seen[c.step] {
+				return nil, fmt.Errorf("%s: step %s: if refers to %s, which is not an earlier step", source, s.Name, c.step)
+			}
+			s.cond = c
+		}
+		seen[s.Name] = true
+	}
+	return &p, nil
+}
+
+func parseCondition(s string) (*condition, error) {
+	m := condRE.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return nil, fmt.Errorf("invalid if %q (expected <step>.ok, <step>.failed, <step>.skipped or <step>.exit == N)", s)
+	}
+	c := &condition{step: m[1], state: m[2], op: m[3]}
+	if m[4] != "" {
+		c.exit, _ = strconv.Atoi(m[4])
+	}
+	return c, nil
+}
+
+// holds reports whether c holds given the results of earlier steps.
+func (c *condition) holds(results map[string]Result) bool {
+	r, ok := results[c.step]
+	if !ok {
+		return false
+	}
+	switch c.state {
+	case StateOK, StateFailed, StateSkipped:
+		return r.State == c.state
+	}
+	if r.State != StateOK && r.State != StateFailed {
+		return false
+	}
+	return (r.Exit == c.exit) == (c.op == "==")
+}
+
+// Resolve returns the value of every declared parameter: values, falling
+// back to defaults. It fails on missing required or unknown parameters.
+func (p *Playbook) Resolve(values map[string]string) (map[string]string, error) {
+	known := map[string]bool{}
+	resolved := map[string]string{}
+	var missing []string
+	for _, param := range p.Params {
+		known[param.Name] = true
+		v, ok := values[param.Name]
+		switch {
+		case ok:
+			resolved[param.Name] = v
+		case param.Required:
+			missing = append(missing, param.Name)
+		default:
+			resolved[param.Name] = param.Default
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("missing required parameter(s): %s", strings.Join(missing, ", "))
+	}
+	var unknown []string
+	for name := range values {
+		if !known[name] {
+			unknown = append(unknown, name)
+		}
+	}
+	if len(unknown) > 0 {
+		sort.Strings(unknown)
+		return nil, fmt.Errorf("unknown parameter(s): %s", strings.Join(unknown, ", "))
+	}
+	return resolved, nil
+}
+
+// Argv returns the step's arguments with params substituted. A parameter
+// never splits or joins words, so values with spaces stay one argument.
+func (s *Step) Argv(params map[string]string) []string {
+	argv := make([]string, len(s.words))
+	for i, w := range s.words {
+		argv[i] = refRE.ReplaceAllStringFunc(w, func(ref string) string {
+			return params[refRE.FindStringSubmatch(ref)[1]]
+		})
+	}
+	return argv
+}
+
+// Result is how a step ended.
+type Result struct {
+	Step    string   `json:"step"`
+	Command []string `json:"command"`
+	State   string   `json:"state"`
+	// Exit is the step's exit code; it is only meaningful for steps that
+	// ran.
+	Exit     int           `json:"exit"`
+	Duration time.Duration `json:"duration"`
+}
+
+// Options configure Execute.
+type Options struct {
+	// Params are the resolved parameters.
+	Params map[string]string
+	// Run runs an ods command line and returns its exit code; err is for
+	// commands that could not be run at all.
+	Run func(argv []string) (int, error)
+	// Confirm asks whether to run a step marked confirm; nil confirms
+	// every step.
+	Confirm func(s *Step, argv []string) bool
+	// OnStep, if set, is called before each step runs.
+	OnStep func(i int, s *Step, argv []string)
+}
+
+// FailedError is returned by Execute when a step stops the playbook.
+type FailedError struct {
+	Step string
+	// Exit is the failed step's exit code, or 0 when it was declined.
+	Exit     int
+	Declined bool
+}
+
+func (e *FailedError) Error() string {
+	if e.Declined {
+		return fmt.Sprintf("step %s was declined", e.Step)
+	}
+	return fmt.Sprintf("step %s failed with exit code %d", e.Step, e.Exit)
+}
+
+// Execute runs the playbook's steps in order. Steps whose condition does
+// not hold are skipped. It stops at the first step that fails without
+// allow_failure or is declined, returning a *FailedError, and returns the
+// result of every step it reached.
+func Execute(p *Playbook, opts Options) ([]Result, error) {
+	byStep := map[string]Result{}
+	var results []Result
+	for i := range p.Steps {
+		s := &p.Steps[i]
+		argv := s.Argv(opts.Params)
+		r := Result{Step: s.Name, Command: argv}
+
+		switch {
+		case s.cond != nil && !s.cond.holds(byStep):
+			r.State = StateSkipped
+		case s.Confirm && opts.Confirm != nil && !opts.Confirm(s, argv):
+			r.State = StateDeclined
+		default:
+			if opts.OnStep != nil {
+				opts.OnStep(i, s, argv)
+			}
+			start := time.Now()
+			exit, err := opts.Run(argv)
+			r.Duration = time.Since(start)
+			if err != nil {
+				results = append(results, r)
+				return results, fmt.Errorf("step %s: %w", s.Name, err)
+			}
+			r.Exit = exit
+			r.State = StateOK
+			if exit != 0 {
+				r.State = StateFailed
+			}
+		}
+		results = append(results, r)
+		byStep[s.Name] = r
+
+		switch {
+		case r.State == StateDeclined:
+			return results, &FailedError{Step: s.Name, Declined: true}
+		case r.State == StateFailed && !s.AllowFailure:
+			return results, &FailedError{Step: s.Name, Exit: r.Exit}
+		}
+	}
+	return results, nil
+}
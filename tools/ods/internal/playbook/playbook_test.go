@@ -0,0 +1,145 @@
+package playbook
+
+import (
+	"errors"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const sitedown = `
+description: Triage a site that is down
+params:
+  - name: HOST
+    required: true
+  - name: CONTEXT
+    default: data_plane
+steps:
+  - name: trace
+    run: dns ${HOST} -c ${CONTEXT}
+    allow_failure: true
+  - name: events
+    run: events -c ${CONTEXT} --since 30m
+    if: trace.failed
+  - name: restart
+    run: restart web -c ${CONTEXT} --yes
+    if: trace.exit == 1
+    confirm: true
+  - name: verify
+    run: health -c ${CONTEXT}
+    if: restart.ok
+`
+
+func TestParse(t *testing.T) {
+	p, err := Parse("site-down.yaml", []byte(sitedown))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(p.Steps) != 4 || p.Description != "Triage a site that is down" {
+		t.Fatalf("unexpected playbook %+v", p)
+	}
+
+	invalid := map[string]string{
+		"no steps":            "description: x\n",
+		"unknown field":       "steps:\n  - name: a\n    run: health\n    retries: 3\n",
+		"leading ods":         "steps:\n  - name: a\n    run: ods health\n",
+		"undeclared param":    "steps:\n  - name: a\n    run: health -c ${CONTEXT}\n",
+		"later step in if":    "steps:\n  - name: a\n    run: health\n    if: b.ok\n  - name: b\n    run: health\n",
+		"bad condition":       "steps:\n  - name: a\n    run: health\n  - name: b\n    run: health\n    if: a.exit > 1\n",
+		"duplicate step":      "steps:\n  - name: a\n    run: health\n  - name: a\n    run: health\n",
+		"bad step name":       "steps:\n  - name: Check Health\n    run: health\n",
+		"unterminated quote":  "steps:\n  - name: a\n    run: whois 'x\n",
+		"lowercase parameter": "params:\n  - name: host\nsteps:\n  - name: a\n    run: health\n",
+	}
+	for name, data := range invalid {
+		if _, err := Parse("x.yaml", []byte(data)); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestResolveAndArgv(t *testing.T) {
+	p, err := Parse("x.yaml", []byte(sitedown))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := p.Resolve(map[string]string{}); err == nil || !strings.Contains(err.Error(), "HOST") {
+		t.Errorf("expected HOST to be required, got %v", err)
+	}
+	if _, err := p.Resolve(map[string]string{"HOST": "x", "TENANT": "y"}); err == nil {
+		t.Error("expected an unknown parameter to be refused")
+	}
+	params, err := p.Resolve(map[string]string{"HOST": "onyx.acme.com; rm -rf /"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"dns", "onyx.acme.com; rm -rf /", "-c", "data_plane"}
+	if got := p.Steps[0].Argv(params); !reflect.DeepEqual(got, want) {
+		t.Errorf("Argv = %q, want %q", got, want)
+	}
+}
+
+func TestExecute(t *testing.T) {
+	p, err := Parse("x.yaml", []byte(sitedown))
+	if err != nil {
+		t.Fatal(err)
+	}
+	params, _ := p.Resolve(map[string]string{"HOST": "onyx.acme.com"})
+
+	run := func(exits map[string]int, confirm bool) ([]Result, []string, error) {
+		var ran []string
+		results, err := Execute(p, Options{
+			Params: params,
+			Run: func(argv []string) (int, error) {
+				ran = append(ran, argv[0])
+				return exits[argv[0]], nil
+			},
+			Confirm: func(s *Step, argv []string) bool { return confirm },
+		})
+		return results, ran, err
+	}
+	states := func(results []Result) string {
+		var s []string
+		for _, r := range results {
+			s = append(s, r.Step+"="+r.State)
+		}
+		return strings.Join(s, " ")
+	}
+
+	results, ran, err := run(map[string]int{}, true)
+	if err != nil || strings.Join(ran, " ") != "dns" || states(results) != "trace=ok events=skipped restart=skipped verify=skipped" {
+		t.Errorf("healthy: ran %q, results %s, err %v", ran, states(results), err)
+	}
+
+	results, ran, err = run(map[string]int{"dns": 1}, true)
+	if err != nil || strings.Join(ran, " ") != "dns events restart health" || states(results) != "trace=failed events=ok restart=ok verify=ok" {
+		t.Errorf("broken: ran %q, results %s, err %v", ran, states(results), err)
+	}
+
+	results, ran, err = run(map[string]int{"dns": 1}, false)
+	var failed *FailedError
+	if !errors.As(err, &failed) || !failed.Declined || failed.Step != "restart" || strings.Join(ran, " ") != "dns events" {
+		t.Errorf("declined: ran %q, results %s, err %v", ran, states(results), err)
+	}
+
+	results, _, err = run(map[string]int{"dns": 1, "events": 2}, true)
+	if !errors.As(err, &failed) || failed.Step != "events" || failed.Exit != 2 || len(results) != 2 {
+		t.Errorf("failing step: results %s, err %v", states(results), err)
+	}
+}
+
+func TestShippedPlaybooks(t *testing.T) {
+	files, err := filepath.Glob("../../playbooks/*.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) == 0 {
+		t.Fatal("no playbooks found")
+	}
+	for _, f := range files {
+		if _, err := Load(f); err != nil {
+			t.Errorf("%v", err)
+		}
+	}
+}
@@ -0,0 +1,223 @@
+// Package portforward runs kubectl port-forwards as managed child
+// processes. A forward is health-checked and restarted when kubectl exits
+// or stops accepting connections, e.g. after its pod is replaced; it is
+// recorded in a registry file while it runs, so forwards leaked by an ods
+// run that crashed can be listed and cleaned up; and it is stopped when ods
+// exits, including through log.Fatal.
+package portforward
+
+import (
+	"fmt"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/kube"
+)
+
+const (
+	// healthInterval is how often a forward's local port is probed.
+	healthInterval = 10 * time.Second
+	// healthTimeout bounds one probe.
+	healthTimeout = 2 * time.Second
+	// maxRestartFailures is how many restarts in a row may fail before a
+	// forward is given up on.
+	maxRestartFailures = 5
+	// maxBackoff caps the wait between restarts.
+	maxBackoff = 30 * time.Second
+)
+
+// Spec describes a port-forward.
+type Spec struct {
+	// Name labels the forward in logs and the registry, e.g. "api-server".
+	Name string
+	// Target is what kubectl forwards to, e.g. "pod/api-server-abc" or
+	// "svc/api-server".
+	Target string
+	// Resolve, if set, returns the target again before each restart, so a
+	// forward to a pod follows it when the pod is replaced.
+	Resolve    func() (string, error)
+	LocalPort  int
+	RemotePort int
+}
+
+// Forward is a managed port-forward. Call Stop when done with it.
+type Forward struct {
+	Spec
+
+	c        *kube.Cluster
+	mu       sync.Mutex
+	pf       *kube.PortForward
+	stopping chan struct{}
+	stopped  chan struct{}
+	once     sync.Once
+}
+
+// Start starts a port-forward on c and blocks until it is listening.
+func Start(c *kube.Cluster, spec Spec) (*Forward, error) {
+	if spec.Name == "" {
+		spec.Name = spec.Target
+	}
+	f := &Forward{Spec: spec, c: c, stopping: make(chan struct{}), stopped: make(chan struct{})}
+	if f.Target == "" {
+		if err := f.resolve(); err != nil {
+			return nil, err
+		}
+	}
+	if err := f.start(); err != nil {
+		return nil, err
+	}
+	// Commands report errors with log.Fatal, which skips deferred Stop calls.
+	log.RegisterExitHandler(f.Stop)
+	go f.supervise()
+	return f, nil
+}
+
+// URL returns the local http URL of the forwarded port.
+func (f *Forward) URL() string {
+	return fmt.Sprintf("http://localhost:%d", f.LocalPort)
+}
+
+// Addr returns the local address of the forwarded port.
+func (f *Forward) Addr() string {
+	return fmt.Sprintf("localhost:%d", f.LocalPort)
+}
+
+// Stop stops the forward and removes it from the registry. It is safe to
+// call more than once.
+func (f *Forward) Stop() {
+	if f == nil {
+		return
+	}
+	f.once.Do(func() {
+		close(f.stopping)
+		<-f.stopped
+		f.mu.Lock()
+		defer f.mu.Unlock()
+		f.kill()
+	})
+}
+
+func (f *Forward) resolve() error {
+	if f.Resolve == nil {
+		return nil
+	}
+	target, err := f.Resolve()
+	if err != nil {
+		return fmt.Errorf("failed to resolve the target of %s: %w", f.Name, err)
+	}
+	f.Target = target
+	return nil
+}
+
+// start starts kubectl and registers it. The caller holds mu, or is Start.
+func (f *Forward) start() error {
+	pf, err := f.c.StartPortForward(f.Target, f.LocalPort, f.RemotePort)
+	if err != nil {
+		return err
+	}
+	f.pf = pf
+	if err := register(Entry{
+		Owner:      os.Getpid(),
+		PID:        pf.PID(),
+		Name:       f.Name,
+		Context:    f.c.Name,
+		Namespace:  f.c.Namespace,
+		Target:     f.Target,
+		LocalPort:  f.LocalPort,
+		RemotePort: f.RemotePort,
+		StartedAt:  time.Now().UTC(),
+	}); err != nil {
+		log.Debugf("Failed to record port-forward %s: %v", f.Name, err)
+	}
+	return nil
+}
+
+// kill stops kubectl and unregisters it. The caller holds mu.
+func (f *Forward) kill() {
+	if f.pf == nil {
+		return
+	}
+	f.pf.Stop()
+	if err := unregister(f.pf.PID()); err != nil {
+		log.Debugf("Failed to unrecord port-forward %s: %v", f.Name, err)
+	}
+	f.pf = nil
+}
+
+// supervise restarts the forward when kubectl exits or two probes in a row
+// fail, until Stop is called or restarting keeps failing.
+func (f *Forward) supervise() {
+	defer close(f.stopped)
+	ticker := time.NewTicker(healthInterval)
+	defer ticker.Stop()
+	unhealthy := 0
+	for {
+		f.mu.Lock()
+		done := f.pf.Done()
+		f.mu.Unlock()
+
+		select {
+		case <-f.stopping:
+			return
+		case <-done:
+			log.Warnf("Port-forward %s to %s exited; restarting", f.Name, f.Target)
+		case <-ticker.C:
+			if healthy(f.Addr()) {
+				unhealthy = 0
+				continue
+			}
+			if unhealthy++; unhealthy < 2 {
+				continue
+			}
+			log.Warnf("Port-forward %s to %s is not accepting connections; restarting", f.Name, f.Target)
+		}
+		unhealthy = 0
+		if !f.restart() {
+			return
+		}
+	}
+}
+
+// restart replaces kubectl, backing off between failed attempts. It
+// returns false when stopped or out of attempts.
+func (f *Forward) restart() bool {
+	backoff := time.Second
+	for attempt := 1; ; attempt++ {
+		f.mu.Lock()
+		f.kill()
+		err := f.resolve()
+		if err == nil {
+			err = f.start()
+		}
+		f.mu.Unlock()
+		if err == nil {
+			log.Infof("Port-forward %s restarted to %s", f.Name, f.Target)
+			return true
+		}
+		if attempt == maxRestartFailures {
+			log.Errorf("Giving up on port-forward %s after %d failed restarts: %v", f.Name, attempt, err)
+			return false
+		}
+		log.Warnf("Failed to restart port-forward %s (retrying in %s): %v", f.Name, backoff, err)
+		select {
+		case <-f.stopping:
+			return false
+		case <-time.After(backoff):
+		}
+		backoff = min(backoff*2, maxBackoff)
+	}
+}
+
+// healthy reports whether something accepts connections on addr.
+func healthy(addr string) bool {
+	conn, err := net.DialTimeout("tcp", addr, healthTimeout)
+	if err != nil {
+		return false
+	}
+	_ = conn.Close()
+	return true
+}
@@ -0,0 +1,94 @@
+package portforward
+
+import (
+	"net"
+	"os"
+	"os/exec"
+	"runtime"
+	"testing"
+	"time"
+)
+
+// exitedPID returns the process ID of a process that has exited.
+func exitedPID(t *testing.T) int {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], "-test.run=^$")
+	if err := cmd.Run(); err != nil {
+		t.Fatal(err)
+	}
+	return cmd.Process.Pid
+}
+
+func TestRegistry(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("needs /bin/sh")
+	}
+	t.Setenv("XDG_DATA_HOME", t.TempDir())
+
+	// A stand-in for kubectl whose command line names port-forward.
+	forward := exec.Command("/bin/sh", "-c", "sleep 30; :", "port-forward")
+	if err := forward.Start(); err != nil {
+		t.Fatal(err)
+	}
+	defer func() { _ = forward.Process.Kill() }()
+	exited := make(chan struct{})
+	go func() { _ = forward.Wait(); close(exited) }()
+
+	mine := Entry{Owner: os.Getpid(), PID: forward.Process.Pid, Name: "api-server", LocalPort: 18080}
+	gone := Entry{Owner: os.Getpid(), PID: exitedPID(t), Name: "control-plane", LocalPort: 18082}
+	for _, e := range []Entry{mine, gone} {
+		if err := register(e); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name != "api-server" || entries[0].Orphaned() {
+		t.Fatalf("expected only the running forward, got %+v", entries)
+	}
+	if cleaned, err := Clean(); err != nil || len(cleaned) != 0 {
+		t.Fatalf("expected nothing to clean while the owner runs, got %+v %v", cleaned, err)
+	}
+
+	if err := unregister(mine.PID); err != nil {
+		t.Fatal(err)
+	}
+	orphan := mine
+	orphan.Owner = exitedPID(t)
+	if err := register(orphan); err != nil {
+		t.Fatal(err)
+	}
+	if !orphan.Orphaned() {
+		t.Fatal("expected the forward to be orphaned")
+	}
+	cleaned, err := Clean()
+	if err != nil || len(cleaned) != 1 {
+		t.Fatalf("expected the orphan to be cleaned, got %+v %v", cleaned, err)
+	}
+	select {
+	case <-exited:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the orphaned forward to be killed")
+	}
+	if entries, err := List(); err != nil || len(entries) != 0 {
+		t.Fatalf("expected an empty registry, got %+v %v", entries, err)
+	}
+}
+
+func TestHealthy(t *testing.T) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr := l.Addr().String()
+	if !healthy(addr) {
+		t.Errorf("expected %s to be healthy", addr)
+	}
+	_ = l.Close()
+	if healthy(addr) {
+		t.Errorf("expected %s to be unhealthy once closed", addr)
+	}
+}
@@ -0,0 +1,168 @@
+package portforward
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/paths"
+)
+
+// Entry is a port-forward recorded in the registry.
+type Entry struct {
+	// Owner is the process ID of the ods run that started the forward.
+	Owner int `json:"owner"`
+	// PID is the process ID of kubectl.
+	PID        int       `json:"pid"`
+	Name       string    `json:"name"`
+	Context    string    `json:"context"`
+	Namespace  string    `json:"namespace"`
+	Target     string    `json:"target"`
+	LocalPort  int       `json:"local_port"`
+	RemotePort int       `json:"remote_port"`
+	StartedAt  time.Time `json:"started_at"`
+}
+
+// Orphaned reports whether the ods run that started the forward is gone
+// while its kubectl process is still running.
+func (e Entry) Orphaned() bool {
+	return !alive(e.Owner) && isPortForward(e.PID)
+}
+
+// RegistryPath returns the path of the registry file.
+func RegistryPath() string {
+	return filepath.Join(paths.DataDir(), "port-forwards.json")
+}
+
+// List returns the forwards in the registry whose kubectl process is still
+// running, dropping the others from the file.
+func List() ([]Entry, error) {
+	var live []Entry
+	err := update(func(entries []Entry) []Entry {
+		live = nil
+		for _, e := range entries {
+			if isPortForward(e.PID) {
+				live = append(live, e)
+			}
+		}
+		return live
+	})
+	return live, err
+}
+
+// Clean stops the orphaned forwards of ods runs that exited without
+// stopping them, and returns them.
+func Clean() ([]Entry, error) {
+	var cleaned []Entry
+	err := update(func(entries []Entry) []Entry {
+		cleaned = nil
+		var kept []Entry
+		for _, e := range entries {
+			switch {
+			case !isPortForward(e.PID):
+			case !alive(e.Owner):
+				if p, err := os.FindProcess(e.PID); err == nil {
+					_ = p.Kill()
+				}
+				cleaned = append(cleaned, e)
+			default:
+				kept = append(kept, e)
+			}
+		}
+		return kept
+	})
+	return cleaned, err
+}
+
+func register(e Entry) error {
+	return update(func(entries []Entry) []Entry {
+		return append(entries, e)
+	})
+}
+
+func unregister(pid int) error {
+	return update(func(entries []Entry) []Entry {
+		var kept []Entry
+		for _, e := range entries {
+			if e.PID != pid {
+				kept = append(kept, e)
+			}
+		}
+		return kept
+	})
+}
+
+// update rewrites the registry with fn applied to its entries. The file is
+// replaced atomically, so a concurrent ods run never reads it half-written.
+func update(fn func([]Entry) []Entry) error {
+	path := RegistryPath()
+	var entries []Entry
+	data, err := os.ReadFile(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+	case err != nil:
+		return fmt.Errorf("failed to read %s: %w", path, err)
+	default:
+		if err := json.Unmarshal(data, &entries); err != nil {
+			// A corrupt registry only loses track of old forwards.
+			entries = nil
+		}
+	}
+
+	entries = fn(entries)
+	if entries == nil {
+		entries = []Entry{}
+	}
+	data, err = json.MarshalIndent(entries, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create %s: %w", filepath.Dir(path), err)
+	}
+	tmp := fmt.Sprintf("%s.%d.tmp", path, os.Getpid())
+	if err := os.WriteFile(tmp, data, 0644); err != nil {
+		return fmt.Errorf("failed to write %s: %w", tmp, err)
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		_ = os.Remove(tmp)
+		return fmt.Errorf("failed to write %s: %w", path, err)
+	}
+	return nil
+}
+
+// alive reports whether a process is running. On Windows FindProcess
+// itself fails for a missing process.
+func alive(pid int) bool {
+	if pid <= 0 {
+		return false
+	}
+	p, err := os.FindProcess(pid)
+	if err != nil {
+		return false
+	}
+	if runtime.GOOS == "windows" {
+		return true
+	}
+	return p.Signal(syscall.Signal(0)) == nil
+}
+
+// isPortForward reports whether pid is a running kubectl port-forward.
+// Where /proc is available the command line is checked as well, so a
+// recycled process ID is not mistaken for the forward.
+func isPortForward(pid int) bool {
+	if !alive(pid) {
+		return false
+	}
+	cmdline, err := os.ReadFile(fmt.Sprintf("/proc/%d/cmdline", pid))
+	if err != nil {
+		return true
+	}
+	return strings.Contains(string(cmdline), "port-forward")
+}
@@ -0,0 +1,175 @@
+// Package preview deploys pull requests as ephemeral Onyx stacks, either to
+// a namespace in the dev cluster or to an isolated local compose project.
+package preview
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/runner"
+)
+
+// LabelKey labels a preview's namespace with its PR number.
+const LabelKey = "onyx.app/preview-pr"
+
+// Runner runs gh and git; nil means runner.Default. Tests set a
+// runner.Fake.
+var Runner runner.Runner
+
+// PR is the part of a pull request a preview is built from.
+type PR struct {
+	Number  int    `json:"number"`
+	Title   string `json:"title"`
+	State   string `json:"state"`
+	HeadRef string `json:"headRefName"`
+	HeadSHA string `json:"headRefOid"`
+}
+
+// LookupPR fetches a pull request with gh.
+func LookupPR(number int) (*PR, error) {
+	out, err := runner.Output(Runner, runner.Cmd{Name: "gh", Args: []string{"pr", "view", strconv.Itoa(number), "--json", "number,title,state,headRefName,headRefOid"}})
+	if err != nil {
+		return nil, err
+	}
+	var pr PR
+	if err := json.Unmarshal(out, &pr); err != nil {
+		return nil, fmt.Errorf("unexpected gh output: %w", err)
+	}
+	if pr.HeadSHA == "" {
+		return nil, fmt.Errorf("PR #%d has no head commit", number)
+	}
+	return &pr, nil
+}
+
+// Name is the compose project, helm release and namespace of a PR's
+// preview.
+func Name(number int) string {
+	return fmt.Sprintf("onyx-pr-%d", number)
+}
+
+// Tag is the image tag a PR's head commit is built as, so a redeploy after
+// new commits pulls fresh images.
+func (pr *PR) Tag() string {
+	sha := pr.HeadSHA
+	if len(sha) > 7 {
+		sha = sha[:7]
+	}
+	return fmt.Sprintf("pr-%d-%s", pr.Number, sha)
+}
+
+// Image is an image a preview builds from the PR's source; the rest (model
+// servers, Postgres, ...) use released images.
+type Image struct {
+	// Repository is the image name without a registry, e.g. onyx-backend.
+	Repository string
+	// Context and Dockerfile are relative to the repository root.
+	Context    string
+	Dockerfile string
+}
+
+// Images are the images built for a preview.
+var Images = []Image{
+	{Repository: "onyx-backend", Context: "backend", Dockerfile: "Dockerfile"},
+	{Repository: "onyx-web-server", Context: "web", Dockerfile: "Dockerfile"},
+}
+
+// Ref returns the image's full reference under registry (e.g.
+// onyxdotapp for local builds) at tag.
+func (i Image) Ref(registry, tag string) string {
+	return fmt.Sprintf("%s/%s:%s", strings.TrimSuffix(registry, "/"), i.Repository, tag)
+}
+
+// BuildArgs returns the docker arguments that build the image from the
+// checkout at dir. push builds for the cluster's platform and pushes the
+// result to the registry.
+func (i Image) BuildArgs(dir, ref string, push bool) []string {
+	args := []string{"build"}
+	if push {
+		args = []string{"buildx", "build", "--platform", "linux/amd64", "--push"}
+	}
+	return append(args,
+		"-t", ref,
+		"-f", filepath.Join(dir, i.Context, i.Dockerfile),
+		filepath.Join(dir, i.Context),
+	)
+}
+
+// backendImageValues are the chart sections running the backend image.
+var backendImageValues = []string{"api", "celery_shared", "slackbot", "discordbot", "mcpServer"}
+
+// HelmSetArgs returns the --set flags that point the chart at a preview's
+// images and serve it at host.
+func HelmSetArgs(registry, tag, host, ingressClass string) []string {
+	set := func(key, value string) []string { return []string{"--set", key + "=" + value} }
+	registry = strings.TrimSuffix(registry, "/")
+
+	var args []string
+	for _, section := range backendImageValues {
+		args = append(args, set(section+".image.repository", registry+"/onyx-backend")...)
+		args = append(args, set(section+".image.tag", tag)...)
+	}
+	args = append(args, set("webserver.image.repository", registry+"/onyx-web-server")...)
+	args = append(args, set("webserver.image.tag", tag)...)
+	args = append(args, set("global.pullPolicy", "Always")...)
+
+	args = append(args, set("ingress.enabled", "true")...)
+	args = append(args, set("ingress.api.host", host)...)
+	args = append(args, set("ingress.webserver.host", host)...)
+	if ingressClass != "" {
+		args = append(args, set("ingress.className", ingressClass)...)
+	}
+	return args
+}
+
+// Host is where a PR's cluster preview is served.
+func Host(number int, domain string) string {
+	return Name(number) + "." + strings.TrimPrefix(domain, ".")
+}
+
+// LocalRegistry is the image namespace local previews are tagged under,
+// matching the compose file's defaults; the tag keeps them apart from
+// released images.
+const LocalRegistry = "onyxdotapp"
+
+// ComposeEnv returns the variables that point the compose file at a
+// preview's images and publish nginx on port.
+func ComposeEnv(tag string, port, port80 int) []string {
+	return []string{
+		"ONYX_BACKEND_IMAGE=" + Images[0].Ref(LocalRegistry, tag),
+		"ONYX_WEB_SERVER_IMAGE=" + Images[1].Ref(LocalRegistry, tag),
+		fmt.Sprintf("HOST_PORT=%d", port),
+		fmt.Sprintf("HOST_PORT_80=%d", port80),
+	}
+}
+
+// Checkout adds a detached worktree of the PR's head commit in a temporary
+// directory and returns it with a function that removes it. The caller's
+// checkout is left alone.
+func Checkout(root string, pr *PR) (string, func(), error) {
+	ref := fmt.Sprintf("pull/%d/head", pr.Number)
+	if out, err := git(root, "fetch", "--quiet", "origin", ref); err != nil {
+		return "", nil, fmt.Errorf("git fetch origin %s failed: %w\n%s", ref, err, out)
+	}
+	dir, err := os.MkdirTemp("", Name(pr.Number)+"-")
+	if err != nil {
+		return "", nil, err
+	}
+	if out, err := git(root, "worktree", "add", "--detach", "--force", dir, pr.HeadSHA); err != nil {
+		_ = os.RemoveAll(dir)
+		return "", nil, fmt.Errorf("git worktree add failed: %w\n%s", err, out)
+	}
+	cleanup := func() {
+		_, _ = git(root, "worktree", "remove", "--force", dir)
+		_ = os.RemoveAll(dir)
+	}
+	return dir, cleanup, nil
+}
+
+// git runs git in the repository at root and returns its combined output.
+func git(root string, args ...string) ([]byte, error) {
+	return runner.CombinedOutput(Runner, runner.Cmd{Name: "git", Args: append([]string{"-C", root}, args...)})
+}
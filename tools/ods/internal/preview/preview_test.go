@@ -0,0 +1,107 @@
+package preview
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/runner"
+)
+
+func useFake(t *testing.T) *runner.Fake {
+	t.Helper()
+	f := &runner.Fake{}
+	Runner = f
+	t.Cleanup(func() { Runner = nil })
+	return f
+}
+
+func TestNamesAndTags(t *testing.T) {
+	pr := &PR{Number: 4821, HeadSHA: "0123456789abcdef"}
+	if got := pr.Tag(); got != "pr-4821-0123456" {
+		t.Errorf("Tag() = %q", got)
+	}
+	if got := Host(4821, ".preview.onyx.app"); got != "onyx-pr-4821.preview.onyx.app" {
+		t.Errorf("Host() = %q", got)
+	}
+	if got := Images[0].Ref("123.dkr.ecr.us-east-2.amazonaws.com/onyx-preview/", pr.Tag()); got != "123.dkr.ecr.us-east-2.amazonaws.com/onyx-preview/onyx-backend:pr-4821-0123456" {
+		t.Errorf("Ref() = %q", got)
+	}
+	args := Images[1].BuildArgs("/tmp/wt", "r/onyx-web-server:t", true)
+	if strings.Join(args, " ") != "buildx build --platform linux/amd64 --push -t r/onyx-web-server:t -f /tmp/wt/web/Dockerfile /tmp/wt/web" {
+		t.Errorf("BuildArgs() = %q", args)
+	}
+}
+
+func TestHelmSetArgs(t *testing.T) {
+	args := HelmSetArgs("reg/", "pr-1-abc", "onyx-pr-1.example.com", "alb")
+	values := map[string]string{}
+	for i := 0; i < len(args); i += 2 {
+		if args[i] != "--set" {
+			t.Fatalf("args[%d] = %q", i, args[i])
+		}
+		k, v, _ := strings.Cut(args[i+1], "=")
+		values[k] = v
+	}
+	for _, section := range []string{"api", "celery_shared", "slackbot", "discordbot", "mcpServer"} {
+		if values[section+".image.repository"] != "reg/onyx-backend" || values[section+".image.tag"] != "pr-1-abc" {
+			t.Errorf("%s image = %s:%s", section, values[section+".image.repository"], values[section+".image.tag"])
+		}
+	}
+	if values["webserver.image.repository"] != "reg/onyx-web-server" || values["ingress.webserver.host"] != "onyx-pr-1.example.com" || values["ingress.className"] != "alb" {
+		t.Errorf("values = %v", values)
+	}
+	if _, ok := values["inferenceCapability.image.tag"]; ok {
+		t.Error("model servers should use released images")
+	}
+}
+
+func TestComposeEnv(t *testing.T) {
+	got := ComposeEnv("pr-1-abc", 3100, 8100)
+	want := []string{
+		"ONYX_BACKEND_IMAGE=onyxdotapp/onyx-backend:pr-1-abc",
+		"ONYX_WEB_SERVER_IMAGE=onyxdotapp/onyx-web-server:pr-1-abc",
+		"HOST_PORT=3100",
+		"HOST_PORT_80=8100",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("ComposeEnv() = %q", got)
+	}
+}
+
+func TestLookupPR(t *testing.T) {
+	useFake(t).
+		On("gh pr view 4821 --json number,title,state,headRefName,headRefOid", runner.Response{Stdout: `{"number": 4821, "title": "Faster search", "state": "OPEN", "headRefName": "faster-search", "headRefOid": "0123456789abcdef"}`}).
+		On("gh pr view 9", runner.Response{Stderr: "no pull requests found\n", Err: errors.New("exit status 1")})
+
+	pr, err := LookupPR(4821)
+	if err != nil || pr.HeadRef != "faster-search" || pr.Tag() != "pr-4821-0123456" {
+		t.Fatalf("LookupPR() = %+v, %v", pr, err)
+	}
+	if _, err := LookupPR(9); err == nil || !strings.Contains(err.Error(), "no pull requests found") {
+		t.Errorf("expected gh's stderr in the error, got %v", err)
+	}
+}
+
+func TestCheckout(t *testing.T) {
+	f := useFake(t).On("git -C /src/onyx", runner.Response{})
+	dir, cleanup, err := Checkout("/src/onyx", &PR{Number: 4821, HeadSHA: "0123456789abcdef"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	cleanup()
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("expected cleanup to remove %s", dir)
+	}
+
+	want := []string{
+		"git -C /src/onyx fetch --quiet origin pull/4821/head",
+		"git -C /src/onyx worktree add --detach --force " + dir + " 0123456789abcdef",
+		"git -C /src/onyx worktree remove --force " + dir,
+	}
+	if got := f.Lines(); !reflect.DeepEqual(got, want) {
+		t.Errorf("commands = %q, want %q", got, want)
+	}
+}
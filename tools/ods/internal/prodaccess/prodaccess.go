@@ -0,0 +1,130 @@
+// Package prodaccess keeps time-boxed production access sessions: ods refuses
+// to run commands against a production context unless a session for it,
+// started with a reason, has not yet expired. Sessions add just-in-time
+// semantics on top of credentials that are otherwise always valid.
+package prodaccess
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+// DefaultMaxTTL caps session length when the ods config sets no limit.
+const DefaultMaxTTL = 4 * time.Hour
+
+// Session unlocks one cluster context until Expires.
+type Session struct {
+	Context string    `json:"context"`
+	Actor   string    `json:"actor"`
+	Reason  string    `json:"reason"`
+	Started time.Time `json:"started"`
+	Expires time.Time `json:"expires"`
+}
+
+// Active reports whether s has not expired at now.
+func (s Session) Active(now time.Time) bool {
+	return now.Before(s.Expires)
+}
+
+// Remaining returns how long s stays active after now, rounded to seconds.
+func (s Session) Remaining(now time.Time) time.Duration {
+	return max(s.Expires.Sub(now), 0).Round(time.Second)
+}
+
+// Load returns the sessions recorded at path, active or not, ordered by
+// context. A missing file yields no sessions.
+func Load(path string) ([]Session, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read sessions %s: %w", path, err)
+	}
+	var sessions []Session
+	if err := json.Unmarshal(data, &sessions); err != nil {
+		return nil, fmt.Errorf("failed to parse sessions %s: %w", path, err)
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].Context < sessions[j].Context })
+	return sessions, nil
+}
+
+// Save replaces the sessions recorded at path. Expired sessions are dropped.
+func Save(path string, sessions []Session, now time.Time) error {
+	var keep []Session
+	for _, s := range sessions {
+		if s.Active(now) {
+			keep = append(keep, s)
+		}
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create sessions directory: %w", err)
+	}
+	data, err := json.MarshalIndent(keep, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to marshal sessions: %w", err)
+	}
+	if err := os.WriteFile(path, data, 0600); err != nil {
+		return fmt.Errorf("failed to write sessions %s: %w", path, err)
+	}
+	return nil
+}
+
+// Start returns sessions with a session for s.Context replaced by s.
+func Start(sessions []Session, s Session) []Session {
+	out := []Session{s}
+	for _, existing := range sessions {
+		if existing.Context != s.Context {
+			out = append(out, existing)
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Context < out[j].Context })
+	return out
+}
+
+// End returns sessions without the one for context ("" ends all), and the
+// sessions it ended.
+func End(sessions []Session, context string) (kept, ended []Session) {
+	for _, s := range sessions {
+		if context == "" || s.Context == context {
+			ended = append(ended, s)
+		} else {
+			kept = append(kept, s)
+		}
+	}
+	return kept, ended
+}
+
+// ErrNoSession is returned by Check when no session covers a context.
+var ErrNoSession = errors.New("no active production access session")
+
+// Check returns the active session for context, ErrNoSession when there is
+// none, or an error describing a session that has expired.
+func Check(sessions []Session, context string, now time.Time) (*Session, error) {
+	for _, s := range sessions {
+		if s.Context != context {
+			continue
+		}
+		if !s.Active(now) {
+			return nil, fmt.Errorf("the production access session for %s expired at %s", context, s.Expires.Local().Format(time.Kitchen))
+		}
+		return &s, nil
+	}
+	return nil, ErrNoSession
+}
+
+// ValidateTTL checks a requested session length against maxTTL.
+func ValidateTTL(ttl, maxTTL time.Duration) error {
+	if ttl <= 0 {
+		return fmt.Errorf("--ttl must be positive")
+	}
+	if ttl > maxTTL {
+		return fmt.Errorf("--ttl %s exceeds the maximum of %s", ttl, maxTTL)
+	}
+	return nil
+}
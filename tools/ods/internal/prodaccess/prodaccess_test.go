@@ -0,0 +1,84 @@
+package prodaccess
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+var now = time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+
+func session(context string, expiresIn time.Duration) Session {
+	return Session{Context: context, Actor: "a@example.com", Reason: "OPS-1", Started: now.Add(-time.Minute), Expires: now.Add(expiresIn)}
+}
+
+func TestSaveAndLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "nested", "sessions.json")
+
+	if got, err := Load(path); err != nil || got != nil {
+		t.Fatalf("Load(missing) = %v, %v; want no sessions", got, err)
+	}
+	if err := Save(path, []Session{session("prod", time.Hour), session("old", -time.Second)}, now); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	got, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(got) != 1 || got[0].Context != "prod" || !got[0].Expires.Equal(now.Add(time.Hour)) {
+		t.Errorf("Load = %+v, want only the active prod session", got)
+	}
+}
+
+func TestStartAndEnd(t *testing.T) {
+	sessions := Start(nil, session("prod", time.Hour))
+	sessions = Start(sessions, session("control_plane", time.Hour))
+	sessions = Start(sessions, session("prod", 2*time.Hour))
+	if len(sessions) != 2 || sessions[0].Context != "control_plane" || !sessions[1].Expires.Equal(now.Add(2*time.Hour)) {
+		t.Fatalf("Start = %+v, want control_plane and the restarted prod session", sessions)
+	}
+
+	kept, ended := End(sessions, "prod")
+	if len(kept) != 1 || kept[0].Context != "control_plane" || len(ended) != 1 || ended[0].Context != "prod" {
+		t.Errorf("End(prod) = %+v, %+v", kept, ended)
+	}
+	kept, ended = End(sessions, "")
+	if len(kept) != 0 || len(ended) != 2 {
+		t.Errorf("End(all) = %+v, %+v", kept, ended)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	sessions := []Session{session("prod", time.Hour), session("control_plane", -time.Minute)}
+
+	s, err := Check(sessions, "prod", now)
+	if err != nil || s.Context != "prod" {
+		t.Errorf("Check(prod) = %+v, %v; want the prod session", s, err)
+	}
+	if s.Remaining(now) != time.Hour {
+		t.Errorf("Remaining = %s, want 1h", s.Remaining(now))
+	}
+	if _, err := Check(sessions, "control_plane", now); err == nil || errors.Is(err, ErrNoSession) {
+		t.Errorf("Check(expired) = %v, want an expiry error", err)
+	}
+	if _, err := Check(sessions, "data_plane", now); !errors.Is(err, ErrNoSession) {
+		t.Errorf("Check(none) = %v, want ErrNoSession", err)
+	}
+}
+
+func TestValidateTTL(t *testing.T) {
+	for _, tt := range []struct {
+		ttl     time.Duration
+		wantErr bool
+	}{
+		{time.Hour, false},
+		{DefaultMaxTTL, false},
+		{0, true},
+		{DefaultMaxTTL + time.Minute, true},
+	} {
+		if err := ValidateTTL(tt.ttl, DefaultMaxTTL); (err != nil) != tt.wantErr {
+			t.Errorf("ValidateTTL(%s) = %v, wantErr %v", tt.ttl, err, tt.wantErr)
+		}
+	}
+}
@@ -0,0 +1,131 @@
+// Package profile builds py-spy invocations that run in a sidecar container
+// sharing a backend container's process namespace.
+package profile
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultImage is the sidecar image py-spy runs in; it matches the backend's
+// Python base image so pip can install a compatible wheel.
+const DefaultImage = "python:3.13-slim"
+
+// FindPIDEnv holds the Python snippet that picks the process to profile. It
+// is passed as an environment variable to avoid nested shell quoting.
+const FindPIDEnv = "ODS_FIND_PID"
+
+// findPID prints the lowest PID (other than itself and its shell) running a
+// Python entrypoint. supervisord and the celery/uvicorn parents come first,
+// so --subprocesses then covers their workers.
+const findPID = `import os
+me = {os.getpid(), os.getppid()}
+for pid in sorted(int(p) for p in os.listdir("/proc") if p.isdigit()):
+    if pid in me:
+        continue
+    try:
+        with open(f"/proc/{pid}/cmdline", "rb") as f:
+            cmd = f.read().replace(b"\0", b" ").decode(errors="replace")
+    except OSError:
+        continue
+    if any(k in cmd for k in ("python", "uvicorn", "celery", "supervisord")):
+        print(pid)
+        break
+`
+
+// Type is the kind of profile to capture.
+type Type string
+
+const (
+	// TypeCPU samples stacks for a duration (py-spy record).
+	TypeCPU Type = "cpu"
+	// TypeDump prints every thread's current stack once (py-spy dump).
+	TypeDump Type = "dump"
+)
+
+// Formats are the py-spy record output formats, keyed by name.
+var Formats = map[string]string{
+	"flamegraph": "svg",
+	"speedscope": "json",
+	"raw":        "txt",
+}
+
+// Options describe a capture.
+type Options struct {
+	Type     Type
+	Format   string
+	Duration time.Duration
+	Rate     int
+	// Native includes native (C extension) frames.
+	Native bool
+}
+
+// Validate checks the options are consistent.
+func (o Options) Validate() error {
+	switch o.Type {
+	case TypeCPU:
+		if _, ok := Formats[o.Format]; !ok {
+			return fmt.Errorf("unknown format %q (expected flamegraph, speedscope or raw)", o.Format)
+		}
+		if o.Duration < time.Second || o.Duration > 10*time.Minute {
+			return fmt.Errorf("duration must be between 1s and 10m, got %s", o.Duration)
+		}
+		if o.Rate < 1 || o.Rate > 1000 {
+			return fmt.Errorf("rate must be between 1 and 1000 samples/s, got %d", o.Rate)
+		}
+	case TypeDump:
+	default:
+		return fmt.Errorf("unknown profile type %q (expected cpu or dump)", o.Type)
+	}
+	return nil
+}
+
+// Extension returns the file extension of the capture's output.
+func (o Options) Extension() string {
+	if o.Type == TypeDump {
+		return "txt"
+	}
+	return Formats[o.Format]
+}
+
+// Env returns the environment the sidecar needs.
+func (o Options) Env() map[string]string {
+	return map[string]string{FindPIDEnv: findPID}
+}
+
+// Script returns the sidecar's shell script. It installs py-spy if the image
+// lacks it, finds the target process, and writes only the profile to stdout;
+// everything else goes to stderr.
+func (o Options) Script() string {
+	var spy []string
+	if o.Type == TypeDump {
+		spy = []string{"py-spy", "dump", "--pid", `"$pid"`, "--subprocesses"}
+	} else {
+		spy = []string{
+			"py-spy", "record", "--pid", `"$pid"`, "--subprocesses",
+			"--duration", strconv.Itoa(int(o.Duration.Seconds())),
+			"--rate", strconv.Itoa(o.Rate),
+			"--format", o.Format,
+			"--output", "/tmp/ods-profile",
+		}
+	}
+	if o.Native {
+		spy = append(spy, "--native")
+	}
+
+	lines := []string{
+		"set -e",
+		"command -v py-spy >/dev/null || pip install --quiet --disable-pip-version-check py-spy >&2",
+		`pid=$(python -c "$` + FindPIDEnv + `")`,
+		`[ -n "$pid" ] || { echo "no Python process found to profile" >&2; exit 1; }`,
+		`echo "Profiling PID $pid" >&2`,
+	}
+	if o.Type == TypeDump {
+		lines = append(lines, strings.Join(spy, " "))
+	} else {
+		lines = append(lines, strings.Join(spy, " ")+" >&2", "cat /tmp/ods-profile")
+	}
+	return strings.Join(lines, "\n")
+}
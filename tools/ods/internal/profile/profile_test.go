@@ -0,0 +1,54 @@
+package profile
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    Options
+		wantErr bool
+	}{
+		{"cpu", Options{Type: TypeCPU, Format: "flamegraph", Duration: 30 * time.Second, Rate: 100}, false},
+		{"dump ignores cpu options", Options{Type: TypeDump}, false},
+		{"unknown type", Options{Type: "memory"}, true},
+		{"unknown format", Options{Type: TypeCPU, Format: "pprof", Duration: time.Minute, Rate: 100}, true},
+		{"too short", Options{Type: TypeCPU, Format: "raw", Duration: 500 * time.Millisecond, Rate: 100}, true},
+		{"too fast", Options{Type: TypeCPU, Format: "raw", Duration: time.Minute, Rate: 5000}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.opts.Validate(); (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestScript(t *testing.T) {
+	cpu := Options{Type: TypeCPU, Format: "speedscope", Duration: 45 * time.Second, Rate: 50, Native: true}
+	script := cpu.Script()
+	for _, want := range []string{
+		"py-spy record --pid \"$pid\" --subprocesses --duration 45 --rate 50 --format speedscope --output /tmp/ods-profile --native >&2",
+		"cat /tmp/ods-profile",
+		"$" + FindPIDEnv,
+	} {
+		if !strings.Contains(script, want) {
+			t.Errorf("cpu script missing %q:\n%s", want, script)
+		}
+	}
+	if cpu.Extension() != "json" {
+		t.Errorf("Extension() = %q, want json", cpu.Extension())
+	}
+
+	dump := Options{Type: TypeDump}
+	if s := dump.Script(); !strings.Contains(s, "py-spy dump --pid \"$pid\" --subprocesses") || strings.Contains(s, "cat /tmp") {
+		t.Errorf("unexpected dump script:\n%s", s)
+	}
+	if dump.Extension() != "txt" {
+		t.Errorf("Extension() = %q, want txt", dump.Extension())
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"strings"
 
 	log "github.com/sirupsen/logrus"
+	"golang.org/x/term"
 )
 
 // reader is the input reader, can be replaced for testing
@@ -49,3 +50,19 @@ func Confirm(prompt string) bool {
 		fmt.Println("Please enter 'yes' or 'no'")
 	}
 }
+
+// Secret prompts on stderr for a value without echoing it, so it stays out of
+// the terminal's scrollback. It fails when stdin is not a terminal.
+func Secret(prompt string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", fmt.Errorf("stdin is not a terminal")
+	}
+	fmt.Fprint(os.Stderr, prompt)
+	value, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", fmt.Errorf("failed to read input: %w", err)
+	}
+	return string(value), nil
+}
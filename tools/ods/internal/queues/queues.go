@@ -0,0 +1,242 @@
+// Package queues samples the depth of the Celery queues and their oldest
+// tasks from the broker, and raises alarms when a backlog builds up.
+package queues
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/docker"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/kube"
+)
+
+//go:embed queues.py
+var sampleScript string
+
+// Task states.
+const (
+	// StateQueued is a task waiting in a queue's list.
+	StateQueued = "queued"
+	// StateUnacked is a task a worker has taken but not acknowledged: it
+	// is prefetched, waiting for its ETA, or running with acks_late.
+	StateUnacked = "unacked"
+)
+
+// Queue is the state of one Celery queue.
+type Queue struct {
+	Name    string `json:"name"`
+	Depth   int    `json:"depth"`
+	Unacked int    `json:"unacked"`
+	// OldestUnackedSeconds is the age of the oldest unacknowledged task,
+	// nil when there is none.
+	OldestUnackedSeconds *int `json:"oldest_unacked_seconds"`
+}
+
+// Task is one of the oldest tasks of a queue.
+type Task struct {
+	Queue    string  `json:"queue"`
+	State    string  `json:"state"`
+	Priority int     `json:"priority"`
+	ID       string  `json:"id"`
+	Name     string  `json:"task"`
+	Args     string  `json:"args"`
+	Kwargs   string  `json:"kwargs"`
+	ETA      *string `json:"eta"`
+	Retries  int     `json:"retries"`
+	// AgeSeconds is known for unacked tasks, from when they were delivered.
+	AgeSeconds *int `json:"age_seconds"`
+}
+
+// Sample is the state of every queue at one moment.
+type Sample struct {
+	Queues []Queue `json:"queues"`
+	Tasks  []Task  `json:"tasks"`
+}
+
+// Runner runs the sampling script with args and returns its stdout.
+type Runner func(script string, args ...string) (string, error)
+
+// PodRunner runs the script on a pod.
+func PodRunner(c *kube.Cluster, pod string) Runner {
+	return func(script string, args ...string) (string, error) {
+		return c.RunPython(pod, script, args...)
+	}
+}
+
+// ContainerRunner runs the script in a local container.
+func ContainerRunner(container string) Runner {
+	return func(script string, args ...string) (string, error) {
+		var stdout strings.Builder
+		err := docker.RunPython(container, script, nil, &stdout, args...)
+		return stdout.String(), err
+	}
+}
+
+// Take samples the queues, with up to oldest tasks of each.
+func Take(run Runner, oldest int) (*Sample, error) {
+	out, err := run(sampleScript, strconv.Itoa(oldest))
+	if err != nil {
+		return nil, err
+	}
+	return parseSample(out)
+}
+
+func parseSample(stdout string) (*Sample, error) {
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	last := strings.TrimSpace(lines[len(lines)-1])
+	var r struct {
+		Status  string `json:"status"`
+		Message string `json:"message"`
+		Sample
+	}
+	if err := json.Unmarshal([]byte(last), &r); err != nil {
+		return nil, fmt.Errorf("unexpected output from queue sampling script: %q", last)
+	}
+	if r.Status != "success" {
+		return nil, fmt.Errorf("%s", r.Message)
+	}
+	return &r.Sample, nil
+}
+
+// Thresholds are the limits past which a queue is alarmed on. A zero
+// threshold is off.
+type Thresholds struct {
+	// Depth is the number of waiting tasks.
+	Depth int
+	// Age is how long the oldest task has waited or been held.
+	Age time.Duration
+}
+
+// Alarm is a queue past a threshold.
+type Alarm struct {
+	Queue   string
+	Message string
+}
+
+// Tracker follows samples over time. Queued tasks carry no enqueue time, so
+// their age is how long the tracker has seen them waiting, a lower bound.
+type Tracker struct {
+	Thresholds Thresholds
+
+	firstSeen map[string]time.Time
+	active    map[string]bool
+}
+
+// NewTracker returns a tracker alarming past thresholds.
+func NewTracker(thresholds Thresholds) *Tracker {
+	return &Tracker{Thresholds: thresholds, firstSeen: map[string]time.Time{}, active: map[string]bool{}}
+}
+
+// Observe records s as taken at now, fills in the age of the queued tasks
+// seen before, and returns the queues past a threshold along with those of
+// them that were not in the previous sample.
+func (t *Tracker) Observe(s *Sample, now time.Time) (alarms, raised []Alarm) {
+	seen := map[string]time.Time{}
+	for i := range s.Tasks {
+		task := &s.Tasks[i]
+		if task.State != StateQueued || task.ID == "" {
+			continue
+		}
+		first, ok := t.firstSeen[task.ID]
+		if !ok {
+			first = now
+		}
+		seen[task.ID] = first
+		age := int(now.Sub(first).Seconds())
+		task.AgeSeconds = &age
+	}
+	t.firstSeen = seen
+
+	active := map[string]bool{}
+	for _, q := range s.Queues {
+		msg := t.check(q, s.Tasks)
+		if msg == "" {
+			continue
+		}
+		a := Alarm{Queue: q.Name, Message: msg}
+		alarms = append(alarms, a)
+		active[q.Name] = true
+		if !t.active[q.Name] {
+			raised = append(raised, a)
+		}
+	}
+	t.active = active
+	return alarms, raised
+}
+
+func (t *Tracker) check(q Queue, tasks []Task) string {
+	var reasons []string
+	if t.Thresholds.Depth > 0 && q.Depth >= t.Thresholds.Depth {
+		reasons = append(reasons, fmt.Sprintf("%d tasks waiting (threshold %d)", q.Depth, t.Thresholds.Depth))
+	}
+	if t.Thresholds.Age > 0 {
+		if age := OldestAge(q, tasks); age >= t.Thresholds.Age {
+			reasons = append(reasons, fmt.Sprintf("oldest task %s old (threshold %s)", FormatAge(age), FormatAge(t.Thresholds.Age)))
+		}
+	}
+	return strings.Join(reasons, ", ")
+}
+
+// OldestAge returns the age of q's oldest known task, unacked or queued.
+func OldestAge(q Queue, tasks []Task) time.Duration {
+	var oldest time.Duration
+	if q.OldestUnackedSeconds != nil {
+		oldest = time.Duration(*q.OldestUnackedSeconds) * time.Second
+	}
+	for _, task := range tasks {
+		if task.Queue == q.Name && task.AgeSeconds != nil {
+			if age := time.Duration(*task.AgeSeconds) * time.Second; age > oldest {
+				oldest = age
+			}
+		}
+	}
+	return oldest
+}
+
+// Busy returns the queues with waiting or unacked tasks, deepest first.
+func (s *Sample) Busy() []Queue {
+	var busy []Queue
+	for _, q := range s.Queues {
+		if q.Depth > 0 || q.Unacked > 0 {
+			busy = append(busy, q)
+		}
+	}
+	sort.SliceStable(busy, func(i, j int) bool { return busy[i].Depth > busy[j].Depth })
+	return busy
+}
+
+// Stuck returns the tasks of the given queues, oldest first.
+func (s *Sample) Stuck(queues map[string]bool) []Task {
+	var tasks []Task
+	for _, t := range s.Tasks {
+		if queues[t.Queue] {
+			tasks = append(tasks, t)
+		}
+	}
+	sort.SliceStable(tasks, func(i, j int) bool { return ageOf(tasks[i]) > ageOf(tasks[j]) })
+	return tasks
+}
+
+func ageOf(t Task) int {
+	if t.AgeSeconds == nil {
+		return -1
+	}
+	return *t.AgeSeconds
+}
+
+// FormatAge formats d compactly, e.g. 45s, 12m or 3h05m.
+func FormatAge(d time.Duration) string {
+	d = d.Round(time.Second)
+	switch {
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	}
+	return fmt.Sprintf("%dh%02dm", int(d.Hours()), int(d.Minutes())%60)
+}
@@ -0,0 +1,132 @@
+"""Report the depth of every Celery queue and its oldest tasks.
+
+Bundled with ods and piped into `python -` on an api-server pod (or the
+local api_server container) by `ods celery watch`. The broker is read
+directly, the way the monitoring tasks do: each queue is a Redis list per
+priority, pushed at the head and consumed from the tail, so the oldest
+queued tasks are at the tail. Tasks a worker has taken but not acknowledged
+("prefetched" or running with acks_late) sit in the "unacked" hash, and
+"unacked_index" holds when each was delivered, which gives their age.
+
+Usage:
+    python - <oldest tasks per queue>
+
+Progress goes to stderr; the last line on stdout is a JSON object with
+"status", "queues" (name, depth, unacked, oldest_unacked_seconds) and
+"tasks", the oldest of each queue with their arguments.
+"""
+
+from __future__ import annotations
+
+import json
+import sys
+import time
+from typing import Any
+
+MAX_REPR = 300
+
+
+def queue_names() -> list[str]:
+    from onyx.configs.constants import OnyxCeleryQueues
+
+    names = {
+        value
+        for key, value in vars(OnyxCeleryQueues).items()
+        if not key.startswith("_") and isinstance(value, str)
+    }
+    return sorted(names)
+
+
+def describe(message: Any, queue: str, state: str, priority: int) -> dict[str, Any]:
+    headers = message.get("headers") if isinstance(message, dict) else None
+    if not isinstance(headers, dict):
+        return {"queue": queue, "state": state, "id": "", "task": "(unreadable)"}
+    return {
+        "queue": queue,
+        "state": state,
+        "priority": priority,
+        "id": headers.get("id", ""),
+        "task": headers.get("task", ""),
+        "args": str(headers.get("argsrepr", ""))[:MAX_REPR],
+        "kwargs": str(headers.get("kwargsrepr", ""))[:MAX_REPR],
+        "eta": headers.get("eta"),
+        "retries": headers.get("retries", 0),
+    }
+
+
+def sample(oldest: int) -> dict[str, Any]:
+    from onyx.background.celery.celery_redis import celery_get_broker_client
+    from onyx.background.celery.configs.base import CELERY_SEPARATOR
+    from onyx.background.celery.versioned_apps.client import app
+    from onyx.configs.constants import OnyxCeleryPriority
+
+    r = celery_get_broker_client(app)
+    now = time.time()
+    names = queue_names()
+    queues: dict[str, dict[str, Any]] = {
+        name: {"name": name, "depth": 0, "unacked": 0, "oldest_unacked_seconds": None}
+        for name in names
+    }
+    tasks: list[dict[str, Any]] = []
+
+    for name in names:
+        queued: list[dict[str, Any]] = []
+        for priority in range(len(OnyxCeleryPriority)):
+            key = f"{name}{CELERY_SEPARATOR}{priority}" if priority > 0 else name
+            queues[name]["depth"] += r.llen(key)
+            if oldest > 0:
+                # The tail is the next to be consumed, i.e. the oldest.
+                raw = r.lrange(key, -oldest, -1)
+                for m in reversed(raw):
+                    try:
+                        message = json.loads(m)
+                    except ValueError:
+                        message = None
+                    queued.append(describe(message, name, "queued", priority))
+        tasks += queued[:oldest]
+
+    delivered = {
+        (k.decode() if isinstance(k, bytes) else k): score
+        for k, score in r.zrange("unacked_index", 0, -1, withscores=True)
+    }
+    unacked: dict[str, list[dict[str, Any]]] = {}
+    for tag, raw in r.hscan_iter("unacked"):
+        tag = tag.decode() if isinstance(tag, bytes) else tag
+        try:
+            message, _exchange, routing_key = json.loads(raw)
+        except ValueError:
+            continue
+        if routing_key not in queues:
+            continue
+        queues[routing_key]["unacked"] += 1
+        task = describe(message, routing_key, "unacked", 0)
+        if tag in delivered:
+            task["age_seconds"] = int(now - delivered[tag])
+        unacked.setdefault(routing_key, []).append(task)
+
+    for name, entries in unacked.items():
+        entries.sort(key=lambda t: -t.get("age_seconds", 0))
+        if entries and "age_seconds" in entries[0]:
+            queues[name]["oldest_unacked_seconds"] = entries[0]["age_seconds"]
+        tasks += entries[:oldest]
+
+    return {"queues": list(queues.values()), "tasks": tasks}
+
+
+def main() -> None:
+    args = sys.argv[1:]
+    if len(args) != 1 or not args[0].isdigit():
+        usage = "Usage: python - <oldest tasks per queue>"
+        print(json.dumps({"status": "error", "message": usage}))
+        sys.exit(1)
+
+    try:
+        result = {"status": "success", **sample(int(args[0]))}
+    except Exception as e:
+        print(f"Error: {e}", file=sys.stderr)
+        result = {"status": "error", "message": f"{type(e).__name__}: {e}"}
+    print(json.dumps(result))
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,92 @@
+package queues
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseSample(t *testing.T) {
+	out := "loading...\n" + `{"status": "success", "queues": [{"name": "docprocessing", "depth": 1200, "unacked": 2, "oldest_unacked_seconds": 900}, {"name": "celery", "depth": 0, "unacked": 0, "oldest_unacked_seconds": null}], "tasks": [{"queue": "docprocessing", "state": "unacked", "id": "a", "task": "docprocessing_task", "kwargs": "{'tenant_id': 'tenant_x'}", "eta": null, "age_seconds": 900}]}`
+	s, err := parseSample(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(s.Queues) != 2 || s.Queues[0].OldestUnackedSeconds == nil || *s.Queues[0].OldestUnackedSeconds != 900 || s.Queues[1].OldestUnackedSeconds != nil {
+		t.Errorf("unexpected queues: %+v", s.Queues)
+	}
+	if len(s.Tasks) != 1 || s.Tasks[0].Name != "docprocessing_task" || *s.Tasks[0].AgeSeconds != 900 {
+		t.Errorf("unexpected tasks: %+v", s.Tasks)
+	}
+
+	if _, err := parseSample(`{"status": "error", "message": "ConnectionError: redis down"}`); err == nil || err.Error() != "ConnectionError: redis down" {
+		t.Errorf("expected the script's error, got %v", err)
+	}
+	if _, err := parseSample("Traceback"); err == nil {
+		t.Error("expected an error for unparseable output")
+	}
+}
+
+func TestTracker(t *testing.T) {
+	start := time.Date(2026, 10, 15, 12, 0, 0, 0, time.UTC)
+	tr := NewTracker(Thresholds{Depth: 1000, Age: 10 * time.Minute})
+	sample := func(depth int, ids ...string) *Sample {
+		s := &Sample{Queues: []Queue{{Name: "docprocessing", Depth: depth}, {Name: "celery"}}}
+		for _, id := range ids {
+			s.Tasks = append(s.Tasks, Task{Queue: "docprocessing", State: StateQueued, ID: id})
+		}
+		return s
+	}
+
+	alarms, raised := tr.Observe(sample(10, "a", "b"), start)
+	if len(alarms) != 0 || len(raised) != 0 {
+		t.Fatalf("expected no alarms, got %v %v", alarms, raised)
+	}
+
+	// Task a is still waiting 11 minutes later; b was consumed.
+	s := sample(10, "a", "c")
+	alarms, raised = tr.Observe(s, start.Add(11*time.Minute))
+	if len(alarms) != 1 || len(raised) != 1 || alarms[0].Message != "oldest task 11m old (threshold 10m)" {
+		t.Fatalf("expected an age alarm, got %v %v", alarms, raised)
+	}
+	if *s.Tasks[0].AgeSeconds != 660 || *s.Tasks[1].AgeSeconds != 0 {
+		t.Errorf("unexpected ages: %d %d", *s.Tasks[0].AgeSeconds, *s.Tasks[1].AgeSeconds)
+	}
+	if stuck := s.Stuck(map[string]bool{"docprocessing": true}); len(stuck) != 2 || stuck[0].ID != "a" {
+		t.Errorf("expected a first, got %+v", stuck)
+	}
+
+	// Still alarmed, now also on depth, but not raised again.
+	alarms, raised = tr.Observe(sample(1500, "a"), start.Add(12*time.Minute))
+	if len(alarms) != 1 || len(raised) != 0 || alarms[0].Message != "1500 tasks waiting (threshold 1000), oldest task 12m old (threshold 10m)" {
+		t.Fatalf("expected a continuing alarm, got %v %v", alarms, raised)
+	}
+
+	// Cleared, then raised again.
+	if alarms, _ := tr.Observe(sample(0), start.Add(13*time.Minute)); len(alarms) != 0 {
+		t.Fatalf("expected the alarm to clear, got %v", alarms)
+	}
+	if _, raised := tr.Observe(sample(2000), start.Add(14*time.Minute)); len(raised) != 1 {
+		t.Fatalf("expected the alarm to be raised again, got %v", raised)
+	}
+}
+
+func TestBusy(t *testing.T) {
+	s := &Sample{Queues: []Queue{{Name: "celery", Unacked: 1}, {Name: "light"}, {Name: "docprocessing", Depth: 5}}}
+	busy := s.Busy()
+	if len(busy) != 2 || busy[0].Name != "docprocessing" || busy[1].Name != "celery" {
+		t.Errorf("unexpected busy queues: %+v", busy)
+	}
+}
+
+func TestFormatAge(t *testing.T) {
+	for d, want := range map[time.Duration]string{
+		45 * time.Second:                "45s",
+		12*time.Minute + 30*time.Second: "12m",
+		3*time.Hour + 5*time.Minute:     "3h05m",
+		26*time.Hour + 59*time.Minute:   "26h59m",
+	} {
+		if got := FormatAge(d); got != want {
+			t.Errorf("FormatAge(%s) = %q, want %q", d, got, want)
+		}
+	}
+}
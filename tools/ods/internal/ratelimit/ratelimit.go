@@ -0,0 +1,141 @@
+// Package ratelimit reports a tenant's rate limits, the usage counted against
+// them, and the Redis buckets the request rate limiters keep, and clears a
+// stuck bucket.
+package ratelimit
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/kube"
+)
+
+//go:embed ratelimit.py
+var ratelimitScript string
+
+// Settings are the request rate-limit settings of the api-server.
+type Settings struct {
+	AuthEnabled           bool `json:"auth_enabled"`
+	AuthMaxRequests       *int `json:"auth_max_requests"`
+	AuthWindowSeconds     *int `json:"auth_window_seconds"`
+	FeedbackEnabled       bool `json:"feedback_enabled"`
+	FeedbackMaxRequests   int  `json:"feedback_max_requests"`
+	FeedbackWindowSeconds int  `json:"feedback_window_seconds"`
+}
+
+// TokenLimit is a tenant-wide token and/or cost budget with the usage in its
+// current window. TokenBudget is in tokens (not the thousands the admin page
+// takes); a nil budget is not enforced.
+type TokenLimit struct {
+	ID               int       `json:"id"`
+	Enabled          bool      `json:"enabled"`
+	PeriodHours      int       `json:"period_hours"`
+	TokenBudget      *int64    `json:"token_budget"`
+	TokensUsed       int64     `json:"tokens_used"`
+	TokenWindowStart time.Time `json:"token_window_start"`
+	CostBudgetCents  *float64  `json:"cost_budget_cents"`
+	CostUsedCents    float64   `json:"cost_used_cents"`
+	CostWindowStart  time.Time `json:"cost_window_start"`
+}
+
+// Exceeded reports whether an enabled limit blocks requests now.
+func (l TokenLimit) Exceeded() bool {
+	if !l.Enabled {
+		return false
+	}
+	if l.TokenBudget != nil && *l.TokenBudget > 0 && l.TokensUsed >= *l.TokenBudget {
+		return true
+	}
+	return l.CostBudgetCents != nil && l.CostUsedCents >= *l.CostBudgetCents
+}
+
+// Bucket is a rate-limit counter in Redis. Shared buckets are not in the
+// tenant's namespace: they count requests per client IP or user across the
+// whole deployment.
+type Bucket struct {
+	Key        string  `json:"key"`
+	Value      *string `json:"value"`
+	TTLSeconds int64   `json:"ttl_seconds"`
+	Shared     bool    `json:"shared"`
+}
+
+// Limiter names what a bucket limits, from its key.
+func (b Bucket) Limiter() string {
+	switch {
+	case strings.HasPrefix(b.Key, "ratelimit:invite_put:"):
+		return "invite"
+	case strings.HasPrefix(b.Key, "ratelimit:invite_remove:"):
+		return "invite removal"
+	case strings.HasPrefix(b.Key, "signup_rate:"):
+		return "signup"
+	case strings.HasPrefix(b.Key, "fastapi-limiter:"):
+		if strings.Contains(b.Key, "feedback") {
+			return "feedback"
+		}
+		return "auth"
+	}
+	return "other"
+}
+
+// TTL renders the bucket's time to expiry; Redis reports -1 for a key
+// without one, which never resets on its own.
+func (b Bucket) TTL() string {
+	switch {
+	case b.TTLSeconds == -1:
+		return "none"
+	case b.TTLSeconds < 0:
+		return "-"
+	}
+	return (time.Duration(b.TTLSeconds) * time.Second).String()
+}
+
+// Report is the rate-limit state of a tenant.
+type Report struct {
+	Settings    Settings     `json:"settings"`
+	TokenLimits []TokenLimit `json:"token_limits"`
+	Buckets     []Bucket     `json:"buckets"`
+}
+
+// Show reports the rate-limit state of schema ("" for the default schema of
+// a single-tenant deployment).
+func Show(c *kube.Cluster, pod, schema string) (*Report, error) {
+	var r Report
+	if err := run(c, pod, &r, "show", schema); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// Reset deletes the bucket key, as listed by Show. Only rate-limit keys are
+// accepted.
+func Reset(c *kube.Cluster, pod, schema, key string) error {
+	var r struct{}
+	return run(c, pod, &r, "reset", schema, key)
+}
+
+func run(c *kube.Cluster, pod string, out any, args ...string) error {
+	stdout, err := c.RunPython(pod, ratelimitScript, args...)
+	if err != nil {
+		return err
+	}
+	return parseResult(stdout, out)
+}
+
+func parseResult(stdout string, out any) error {
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	last := strings.TrimSpace(lines[len(lines)-1])
+	var r struct {
+		Status  string `json:"status"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal([]byte(last), &r); err != nil {
+		return fmt.Errorf("unexpected output from ratelimit script: %q", last)
+	}
+	if r.Status != "success" {
+		return fmt.Errorf("%s", r.Message)
+	}
+	return json.Unmarshal([]byte(last), out)
+}
@@ -0,0 +1,218 @@
+"""Show a tenant's rate limits and usage, or clear one stuck Redis bucket.
+
+Bundled with ods and piped into `python -` on an api-server pod by
+`ods ratelimit`. `show` reports the request rate-limit settings of the
+deployment, the tenant's global token/cost budgets with the usage in their
+current window, and the Redis counters the rate limiters keep: the tenant's
+invite counters and the deployment-wide auth, feedback and signup buckets.
+`reset` deletes one of those counters.
+
+Usage:
+    python - show <schema>
+    python - reset <schema> <key>
+
+An empty <schema> means the default schema of a single-tenant deployment.
+
+Progress goes to stderr; the last line on stdout is a JSON object with
+"status" and "settings", "token_limits" and "buckets" (show) or "deleted"
+(reset).
+"""
+
+from __future__ import annotations
+
+import json
+import sys
+from datetime import datetime
+from datetime import timezone
+from typing import Any
+
+# Counters in the tenant's Redis namespace (onyx.server.manage.invite_rate_limit).
+TENANT_PATTERNS = ["ratelimit:*"]
+# Counters shared by every tenant: fastapi-limiter's auth and feedback limits
+# (keyed by client IP or user) and the signup limit (keyed by IP).
+SHARED_PATTERNS = ["fastapi-limiter:*", "signup_rate:*"]
+
+# Budgets are entered in thousands of tokens
+# (onyx.server.query_and_chat.token_limit.TOKEN_BUDGET_UNIT).
+TOKEN_BUDGET_UNIT = 1000
+
+MAX_SHARED_BUCKETS = 200
+
+
+def iso(dt: datetime | None) -> str | None:
+    return dt.isoformat() if dt is not None else None
+
+
+def use_schema(schema: str) -> str:
+    from onyx.db.engine.tenant_utils import validate_tenant_id
+    from shared_configs.configs import MULTI_TENANT
+    from shared_configs.configs import POSTGRES_DEFAULT_SCHEMA
+    from shared_configs.contextvars import CURRENT_TENANT_ID_CONTEXTVAR
+
+    if not schema:
+        if MULTI_TENANT:
+            raise ValueError("This deployment is multi-tenant; pass --tenant")
+        schema = POSTGRES_DEFAULT_SCHEMA
+    elif schema != POSTGRES_DEFAULT_SCHEMA and not validate_tenant_id(schema):
+        raise ValueError(f"Invalid schema {schema!r}")
+    CURRENT_TENANT_ID_CONTEXTVAR.set(schema)
+    return schema
+
+
+def settings() -> dict[str, Any]:
+    from onyx.configs import app_configs
+
+    return {
+        "auth_enabled": bool(app_configs.AUTH_RATE_LIMITING_ENABLED),
+        "auth_max_requests": app_configs.RATE_LIMIT_MAX_REQUESTS,
+        "auth_window_seconds": app_configs.RATE_LIMIT_WINDOW_SECONDS,
+        "feedback_enabled": bool(app_configs.FEEDBACK_RATE_LIMITING_ENABLED),
+        "feedback_max_requests": app_configs.FEEDBACK_RATE_LIMIT_MAX_REQUESTS,
+        "feedback_window_seconds": app_configs.FEEDBACK_RATE_LIMIT_WINDOW_SECONDS,
+    }
+
+
+def token_limits() -> list[dict[str, Any]]:
+    from onyx.db.engine.sql_engine import get_session_with_current_tenant
+    from onyx.db.token_limit import fetch_all_global_token_rate_limits
+    from onyx.db.user_usage import get_cost_window_start
+    from onyx.db.user_usage import get_token_window_start
+    from onyx.db.user_usage import get_total_cost_cents_buckets_since
+    from onyx.db.user_usage import get_total_token_buckets_since
+
+    now = datetime.now(timezone.utc)
+    with get_session_with_current_tenant() as db_session:
+        limits = fetch_all_global_token_rate_limits(db_session)
+        if not limits:
+            return []
+        earliest = min(
+            min(
+                get_token_window_start(now, rl.period_hours),
+                get_cost_window_start(now, rl.period_hours),
+            )
+            for rl in limits
+        )
+        token_buckets = get_total_token_buckets_since(db_session, earliest)
+        cost_buckets = get_total_cost_cents_buckets_since(db_session, earliest)
+
+        result = []
+        for rl in limits:
+            token_start = get_token_window_start(now, rl.period_hours)
+            cost_start = get_cost_window_start(now, rl.period_hours)
+            result.append(
+                {
+                    "id": rl.id,
+                    "enabled": rl.enabled,
+                    "period_hours": rl.period_hours,
+                    "token_budget": (
+                        rl.token_budget * TOKEN_BUDGET_UNIT
+                        if rl.token_budget
+                        else None
+                    ),
+                    "tokens_used": sum(
+                        b.tokens for b in token_buckets if b.window_start >= token_start
+                    ),
+                    "token_window_start": iso(token_start),
+                    "cost_budget_cents": rl.cost_budget_cents,
+                    "cost_used_cents": sum(
+                        cents for start, cents in cost_buckets if start >= cost_start
+                    ),
+                    "cost_window_start": iso(cost_start),
+                }
+            )
+        return result
+
+
+def bucket(r: Any, key: bytes, shared: bool) -> dict[str, Any]:
+    value = r.get(key)
+    return {
+        "key": key.decode(),
+        "value": value.decode() if value is not None else None,
+        "ttl_seconds": r.ttl(key),
+        "shared": shared,
+    }
+
+
+def buckets(schema: str) -> list[dict[str, Any]]:
+    from onyx.redis.redis_pool import get_raw_redis_client
+    from onyx.redis.redis_pool import get_redis_client
+
+    tenant_redis = get_redis_client(tenant_id=schema)
+    raw_redis = get_raw_redis_client()
+
+    result = []
+    for pattern in TENANT_PATTERNS:
+        for key in tenant_redis.scan_iter(match=pattern, count=500):
+            result.append(bucket(tenant_redis, key, shared=False))
+    shared_keys = [
+        key
+        for pattern in SHARED_PATTERNS
+        for key in raw_redis.scan_iter(match=pattern, count=500)
+    ]
+    if len(shared_keys) > MAX_SHARED_BUCKETS:
+        print(
+            f"{len(shared_keys)} shared buckets; showing the first {MAX_SHARED_BUCKETS}",
+            file=sys.stderr,
+        )
+    for key in sorted(shared_keys)[:MAX_SHARED_BUCKETS]:
+        result.append(bucket(raw_redis, key, shared=True))
+    return sorted(result, key=lambda b: (b["shared"], b["key"]))
+
+
+def show(schema: str) -> dict[str, Any]:
+    schema = use_schema(schema)
+    return {
+        "status": "success",
+        "settings": settings(),
+        "token_limits": token_limits(),
+        "buckets": buckets(schema),
+    }
+
+
+def reset(schema: str, key: str) -> dict[str, Any]:
+    from fnmatch import fnmatchcase
+
+    from onyx.redis.redis_pool import get_raw_redis_client
+    from onyx.redis.redis_pool import get_redis_client
+
+    schema = use_schema(schema)
+    # Only rate-limit counters may be deleted, never arbitrary keys.
+    if any(fnmatchcase(key, p) for p in TENANT_PATTERNS):
+        r = get_redis_client(tenant_id=schema)
+    elif any(fnmatchcase(key, p) for p in SHARED_PATTERNS):
+        r = get_raw_redis_client()
+    else:
+        patterns = ", ".join(TENANT_PATTERNS + SHARED_PATTERNS)
+        raise ValueError(f"{key!r} is not a rate-limit bucket (expected {patterns})")
+    deleted = r.delete(key)
+    if not deleted:
+        raise ValueError(f"Bucket {key!r} does not exist")
+    print(f"Deleted {key}", file=sys.stderr)
+    return {"status": "success", "deleted": key}
+
+
+def main() -> None:
+    usage = "Usage: python - show <schema> | reset <schema> <key>"
+    args = sys.argv[1:]
+    arity = {"show": 2, "reset": 3}
+    if not args or arity.get(args[0]) != len(args):
+        print(json.dumps({"status": "error", "message": usage}))
+        sys.exit(1)
+
+    from onyx.db.engine.sql_engine import SqlEngine
+
+    SqlEngine.init_engine(pool_size=5, max_overflow=2)
+
+    try:
+        if args[0] == "show":
+            result = show(args[1])
+        else:
+            result = reset(args[1], args[2])
+    except Exception as e:
+        print(f"Error: {e}", file=sys.stderr)
+        result = {"status": "error", "message": str(e)}
+    print(json.dumps(result))
+
+
+if __name__ == "__main__":
+    main()
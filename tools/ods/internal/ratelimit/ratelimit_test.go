@@ -0,0 +1,66 @@
+package ratelimit
+
+import "testing"
+
+func TestExceeded(t *testing.T) {
+	budget := int64(100_000)
+	cents := 500.0
+	tests := []struct {
+		name  string
+		limit TokenLimit
+		want  bool
+	}{
+		{"under budget", TokenLimit{Enabled: true, TokenBudget: &budget, TokensUsed: 99_999}, false},
+		{"tokens at budget", TokenLimit{Enabled: true, TokenBudget: &budget, TokensUsed: 100_000}, true},
+		{"disabled", TokenLimit{TokenBudget: &budget, TokensUsed: 200_000}, false},
+		{"cost over budget", TokenLimit{Enabled: true, CostBudgetCents: &cents, CostUsedCents: 501}, true},
+		{"no budgets", TokenLimit{Enabled: true, TokensUsed: 1 << 40}, false},
+	}
+	for _, tt := range tests {
+		if got := tt.limit.Exceeded(); got != tt.want {
+			t.Errorf("%s: Exceeded() = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestBucketLimiter(t *testing.T) {
+	tests := map[string]string{
+		"ratelimit:invite_put:admin:42:min":                              "invite",
+		"ratelimit:invite_remove:admin:42:day":                           "invite removal",
+		"signup_rate:10.0.0.1:493021":                                    "signup",
+		"fastapi-limiter:user-42:/chat/create-chat-message-feedback:0:0": "feedback",
+		"fastapi-limiter:10.0.0.1-curl:/auth/login:0:0":                  "auth",
+	}
+	for key, want := range tests {
+		if got := (Bucket{Key: key}).Limiter(); got != want {
+			t.Errorf("Limiter(%q) = %q, want %q", key, got, want)
+		}
+	}
+}
+
+func TestBucketTTL(t *testing.T) {
+	for ttl, want := range map[int64]string{90: "1m30s", -1: "none", -2: "-"} {
+		if got := (Bucket{TTLSeconds: ttl}).TTL(); got != want {
+			t.Errorf("TTL(%d) = %q, want %q", ttl, got, want)
+		}
+	}
+}
+
+func TestParseResult(t *testing.T) {
+	var r Report
+	stdout := "loading\n" + `{"status": "success", "settings": {"auth_enabled": false, "auth_max_requests": null, "feedback_max_requests": 100}, "token_limits": [], "buckets": [{"key": "signup_rate:1.2.3.4:1", "value": "3", "ttl_seconds": 60, "shared": true}]}`
+	if err := parseResult(stdout, &r); err != nil {
+		t.Fatal(err)
+	}
+	if r.Settings.AuthMaxRequests != nil || r.Settings.FeedbackMaxRequests != 100 {
+		t.Errorf("unexpected settings %+v", r.Settings)
+	}
+	if len(r.Buckets) != 1 || *r.Buckets[0].Value != "3" || !r.Buckets[0].Shared {
+		t.Errorf("unexpected buckets %+v", r.Buckets)
+	}
+
+	err := parseResult(`{"status": "error", "message": "'foo' is not a rate-limit bucket"}`, &r)
+	if err == nil || err.Error() != "'foo' is not a rate-limit bucket" {
+		t.Errorf("expected the script's error, got %v", err)
+	}
+}
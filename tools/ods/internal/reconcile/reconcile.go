@@ -0,0 +1,121 @@
+// Package reconcile compares a tenant's documents and chunks in Postgres
+// with what Vespa holds for it, to find index drift.
+package reconcile
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/kube"
+)
+
+//go:embed reconcile.py
+var reconcileScript string
+
+// Connector is one connector/credential pair's documents in each store.
+type Connector struct {
+	CCPairID       int    `json:"cc_pair_id"`
+	ConnectorID    int    `json:"connector_id"`
+	Name           string `json:"name"`
+	Source         string `json:"source"`
+	Status         string `json:"status"`
+	PostgresDocs   int    `json:"postgres_docs"`
+	PostgresChunks int    `json:"postgres_chunks"`
+	VespaDocs      int    `json:"vespa_docs"`
+	VespaChunks    int    `json:"vespa_chunks"`
+	// Missing counts documents indexed according to Postgres with no chunks
+	// in Vespa.
+	Missing       int      `json:"missing"`
+	MissingSample []string `json:"missing_sample"`
+	// ChunkMismatch counts documents whose chunks in Vespa differ in number
+	// from the document's chunk_count.
+	ChunkMismatch       int      `json:"chunk_mismatch"`
+	ChunkMismatchSample []string `json:"chunk_mismatch_sample"`
+}
+
+// Drifted reports whether the stores disagree about the pair's documents.
+func (c *Connector) Drifted() bool {
+	return c.Missing > 0 || c.ChunkMismatch > 0
+}
+
+// Orphans are documents one store has and the other does not account for.
+type Orphans struct {
+	Count  int      `json:"count"`
+	Sample []string `json:"sample"`
+}
+
+// Report is the outcome of reconciling a tenant.
+type Report struct {
+	Schema      string      `json:"schema"`
+	IndexName   string      `json:"index_name"`
+	VespaDocs   int         `json:"vespa_docs"`
+	VespaChunks int         `json:"vespa_chunks"`
+	Connectors  []Connector `json:"connectors"`
+	// VespaOrphans are documents in Vespa with no row in Postgres; search
+	// can return them but nothing will update or delete them.
+	VespaOrphans Orphans `json:"vespa_orphans"`
+	// PostgresOrphans are documents no connector has indexed, left for
+	// pruning to delete.
+	PostgresOrphans Orphans `json:"postgres_orphans"`
+}
+
+// Run reconciles schema ("" for the default schema of a single-tenant
+// deployment) on pod, listing up to sample document IDs per kind of drift.
+func Run(c *kube.Cluster, pod, schema string, sample int) (*Report, error) {
+	out, err := c.RunPython(pod, reconcileScript, schema, strconv.Itoa(sample))
+	if err != nil {
+		return nil, err
+	}
+	return parseReport(out)
+}
+
+func parseReport(stdout string) (*Report, error) {
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	last := strings.TrimSpace(lines[len(lines)-1])
+	var r struct {
+		Status  string `json:"status"`
+		Message string `json:"message"`
+		Report
+	}
+	if err := json.Unmarshal([]byte(last), &r); err != nil {
+		return nil, fmt.Errorf("unexpected output from reconcile script: %q", last)
+	}
+	if r.Status != "success" {
+		return nil, fmt.Errorf("%s", r.Message)
+	}
+	return &r.Report, nil
+}
+
+// Drifted returns the connector/credential pairs whose documents are missing
+// from Vespa or have the wrong number of chunks there.
+func (r *Report) Drifted() []Connector {
+	var out []Connector
+	for _, c := range r.Connectors {
+		if c.Drifted() {
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+// Clean reports whether nothing drifted and neither store has orphans.
+func (r *Report) Clean() bool {
+	return len(r.Drifted()) == 0 && r.VespaOrphans.Count == 0 && r.PostgresOrphans.Count == 0
+}
+
+// ResyncConnectors returns the connector IDs to reindex to repair drift,
+// in order and without repeats.
+func (r *Report) ResyncConnectors() []int {
+	seen := map[int]bool{}
+	var ids []int
+	for _, c := range r.Drifted() {
+		if !seen[c.ConnectorID] {
+			seen[c.ConnectorID] = true
+			ids = append(ids, c.ConnectorID)
+		}
+	}
+	return ids
+}
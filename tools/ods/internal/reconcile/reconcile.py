@@ -0,0 +1,210 @@
+"""Compare a tenant's documents and chunks in Postgres with Vespa.
+
+Bundled with ods and piped into `python -` on an api-server pod by
+`ods reconcile`. Visits every chunk Vespa holds for the tenant (document ID
+and chunk ID only, skipping large chunks) and compares them with the
+documents each connector has indexed according to Postgres.
+
+Usage:
+    python - <schema> <sample>
+
+An empty <schema> means the default schema of a single-tenant deployment.
+<sample> caps how many document IDs are listed per kind of drift.
+
+Progress goes to stderr; the last line on stdout is a JSON object with
+"status", "schema", "connectors" (per connector/credential pair: documents
+and chunks in each store, documents missing from Vespa and documents whose
+chunk count differs, with samples), "vespa_orphans" (documents Vespa has but
+Postgres does not) and "postgres_orphans" (documents no connector indexes any
+more), each with a count and a sample.
+"""
+
+from __future__ import annotations
+
+import json
+import sys
+from collections import Counter
+from typing import Any
+
+
+def use_schema(schema: str) -> str:
+    from onyx.db.engine.tenant_utils import validate_tenant_id
+    from shared_configs.configs import MULTI_TENANT
+    from shared_configs.configs import POSTGRES_DEFAULT_SCHEMA
+    from shared_configs.contextvars import CURRENT_TENANT_ID_CONTEXTVAR
+
+    if not schema:
+        if MULTI_TENANT:
+            raise ValueError("This deployment is multi-tenant; pass --tenant")
+        schema = POSTGRES_DEFAULT_SCHEMA
+    elif schema != POSTGRES_DEFAULT_SCHEMA and not validate_tenant_id(schema):
+        raise ValueError(f"Invalid schema {schema!r}")
+    CURRENT_TENANT_ID_CONTEXTVAR.set(schema)
+    return schema
+
+
+def vespa_chunk_counts(index_name: str, schema: str) -> Counter[str]:
+    from onyx.configs.app_configs import ONYX_DISABLE_VESPA
+    from onyx.document_index.vespa.shared_utils.utils import get_vespa_http_client
+    from onyx.document_index.vespa_constants import DOCUMENT_ID_ENDPOINT
+    from shared_configs.configs import MULTI_TENANT
+
+    if ONYX_DISABLE_VESPA:
+        raise RuntimeError("Vespa is disabled in this deployment")
+    selection = f"{index_name}.large_chunk_reference_ids == null"
+    if MULTI_TENANT:
+        selection += f" and {index_name}.tenant_id=='{schema}'"
+    params: dict[str, Any] = {
+        "selection": selection,
+        "wantedDocumentCount": 1_000,
+        "fieldSet": f"{index_name}:document_id,chunk_id",
+    }
+
+    counts: Counter[str] = Counter()
+    chunks = reported = 0
+    with get_vespa_http_client(no_timeout=True) as client:
+        while True:
+            response = client.get(
+                DOCUMENT_ID_ENDPOINT.format(index_name=index_name), params=params
+            )
+            response.raise_for_status()
+            data = response.json()
+            for doc in data.get("documents", []):
+                doc_id = doc.get("fields", {}).get("document_id")
+                if doc_id is not None:
+                    counts[doc_id] += 1
+                    chunks += 1
+            if chunks - reported >= 100_000:
+                print(f"Visited {chunks} chunks...", file=sys.stderr)
+                reported = chunks
+            if not data.get("continuation"):
+                return counts
+            params["continuation"] = data["continuation"]
+
+
+def reconcile(schema: str, sample: int) -> dict[str, Any]:
+    from sqlalchemy import and_
+    from sqlalchemy import select
+
+    from onyx.db.engine.sql_engine import get_session_with_current_tenant
+    from onyx.db.models import Connector
+    from onyx.db.models import ConnectorCredentialPair
+    from onyx.db.models import Document
+    from onyx.db.models import DocumentByConnectorCredentialPair as DocByCC
+    from onyx.db.search_settings import get_current_search_settings
+
+    schema = use_schema(schema)
+    with get_session_with_current_tenant() as db_session:
+        index_name = get_current_search_settings(db_session).index_name
+        print(f"Visiting chunks of {schema} in {index_name}...", file=sys.stderr)
+        in_vespa = vespa_chunk_counts(index_name, schema)
+
+        print("Reading indexed documents from Postgres...", file=sys.stderr)
+        pairs: dict[int, dict[str, Any]] = {}
+        for cc_pair, connector in db_session.execute(
+            select(ConnectorCredentialPair, Connector).join(
+                Connector, Connector.id == ConnectorCredentialPair.connector_id
+            )
+        ):
+            pairs[cc_pair.id] = {
+                "cc_pair_id": cc_pair.id,
+                "connector_id": connector.id,
+                "name": cc_pair.name,
+                "source": connector.source.value,
+                "status": cc_pair.status.value,
+                "postgres_docs": 0,
+                "postgres_chunks": 0,
+                "vespa_docs": 0,
+                "vespa_chunks": 0,
+                "missing": 0,
+                "missing_sample": [],
+                "chunk_mismatch": 0,
+                "chunk_mismatch_sample": [],
+            }
+
+        indexed: set[str] = set()
+        rows = db_session.execute(
+            select(DocByCC.id, Document.chunk_count, ConnectorCredentialPair.id)
+            .join(Document, Document.id == DocByCC.id)
+            .join(
+                ConnectorCredentialPair,
+                and_(
+                    ConnectorCredentialPair.connector_id == DocByCC.connector_id,
+                    ConnectorCredentialPair.credential_id == DocByCC.credential_id,
+                ),
+            )
+            .where(DocByCC.has_been_indexed.is_(True))
+            .execution_options(yield_per=10_000)
+        )
+        for doc_id, chunk_count, cc_pair_id in rows:
+            indexed.add(doc_id)
+            p = pairs[cc_pair_id]
+            p["postgres_docs"] += 1
+            p["postgres_chunks"] += chunk_count or 0
+            chunks = in_vespa.get(doc_id, 0)
+            if chunks == 0:
+                p["missing"] += 1
+                if len(p["missing_sample"]) < sample:
+                    p["missing_sample"].append(doc_id)
+                continue
+            p["vespa_docs"] += 1
+            p["vespa_chunks"] += chunks
+            if chunk_count is not None and chunk_count != chunks:
+                p["chunk_mismatch"] += 1
+                if len(p["chunk_mismatch_sample"]) < sample:
+                    p["chunk_mismatch_sample"].append(doc_id)
+
+        print("Looking for orphans...", file=sys.stderr)
+        known: set[str] = set()
+        postgres_orphans: list[str] = []
+        orphan_count = 0
+        for doc_id, from_api in db_session.execute(
+            select(Document.id, Document.from_ingestion_api).execution_options(
+                yield_per=10_000
+            )
+        ):
+            known.add(doc_id)
+            if doc_id not in indexed and not from_api:
+                orphan_count += 1
+                if len(postgres_orphans) < sample:
+                    postgres_orphans.append(doc_id)
+        vespa_orphans = sorted(d for d in in_vespa if d not in known)
+
+    return {
+        "status": "success",
+        "schema": schema,
+        "index_name": index_name,
+        "vespa_docs": len(in_vespa),
+        "vespa_chunks": sum(in_vespa.values()),
+        "connectors": sorted(pairs.values(), key=lambda p: p["cc_pair_id"]),
+        "vespa_orphans": {
+            "count": len(vespa_orphans),
+            "sample": vespa_orphans[:sample],
+        },
+        "postgres_orphans": {"count": orphan_count, "sample": postgres_orphans},
+    }
+
+
+def main() -> None:
+    if len(sys.argv) != 3:
+        print(
+            json.dumps(
+                {"status": "error", "message": "Usage: python - <schema> <sample>"}
+            )
+        )
+        sys.exit(1)
+
+    from onyx.db.engine.sql_engine import SqlEngine
+
+    SqlEngine.init_engine(pool_size=5, max_overflow=2)
+
+    try:
+        result = reconcile(sys.argv[1], int(sys.argv[2]))
+    except Exception as e:
+        print(f"Error: {e}", file=sys.stderr)
+        result = {"status": "error", "message": str(e)}
+    print(json.dumps(result))
+
+
+if __name__ == "__main__":
+    main()
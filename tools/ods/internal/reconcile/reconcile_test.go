@@ -0,0 +1,27 @@
+package reconcile
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestParseReport(t *testing.T) {
+	out := "Visiting chunks...\n" + `{"status": "success", "schema": "tenant_a", "index_name": "danswer_chunk", "vespa_docs": 3, "vespa_chunks": 9, "connectors": [{"cc_pair_id": 1, "connector_id": 10, "name": "Docs", "postgres_docs": 2, "postgres_chunks": 6, "vespa_docs": 2, "vespa_chunks": 6}, {"cc_pair_id": 2, "connector_id": 11, "name": "Drive", "postgres_docs": 3, "vespa_docs": 1, "missing": 2, "missing_sample": ["d1", "d2"]}, {"cc_pair_id": 3, "connector_id": 11, "name": "Drive 2", "chunk_mismatch": 1}], "vespa_orphans": {"count": 1, "sample": ["gone"]}, "postgres_orphans": {"count": 0, "sample": []}}`
+	r, err := parseReport(out)
+	if err != nil {
+		t.Fatalf("parseReport() error: %v", err)
+	}
+	if drifted := r.Drifted(); len(drifted) != 2 || drifted[0].Name != "Drive" {
+		t.Errorf("Drifted() = %+v", drifted)
+	}
+	if ids := r.ResyncConnectors(); !slices.Equal(ids, []int{11}) {
+		t.Errorf("ResyncConnectors() = %v", ids)
+	}
+	if r.Clean() || r.VespaOrphans.Sample[0] != "gone" {
+		t.Errorf("unexpected report %+v", r)
+	}
+
+	if _, err := parseReport(`{"status": "error", "message": "Vespa is disabled in this deployment"}`); err == nil || err.Error() != "Vespa is disabled in this deployment" {
+		t.Errorf("expected the script's message as error, got %v", err)
+	}
+}
@@ -0,0 +1,182 @@
+package reindex
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"time"
+)
+
+// Tenant states in a fleet reindex.
+const (
+	StatePending = "pending"
+	StateRunning = "running"
+	StateDone    = "done"
+	StateFailed  = "failed"
+	// StateSkipped is a tenant with no active connectors.
+	StateSkipped = "skipped"
+)
+
+// TenantRun is one tenant's part in a fleet reindex.
+type TenantRun struct {
+	Tenant string `json:"tenant"`
+	State  string `json:"state"`
+	// CCPairIDs and StartedAt come from the trigger, so that progress can
+	// still be followed after a restart.
+	CCPairIDs   []int     `json:"cc_pair_ids,omitempty"`
+	StartedAt   string    `json:"started_at,omitempty"`
+	DocsIndexed int       `json:"docs_indexed,omitempty"`
+	Running     int       `json:"running_attempts,omitempty"`
+	FinishedAt  time.Time `json:"finished_at,omitzero"`
+	Error       string    `json:"error,omitempty"`
+}
+
+// Checkpoint is the persisted state of a fleet reindex, rewritten after
+// every change so an interrupted run can resume.
+type Checkpoint struct {
+	Context string       `json:"context"`
+	Created time.Time    `json:"created"`
+	Tenants []*TenantRun `json:"tenants"`
+
+	path string
+}
+
+// NewCheckpoint starts a fleet reindex of tenants, saved to path.
+func NewCheckpoint(path, context string, tenants []string) *Checkpoint {
+	cp := &Checkpoint{Context: context, Created: time.Now().UTC(), path: path}
+	for _, t := range tenants {
+		cp.Tenants = append(cp.Tenants, &TenantRun{Tenant: t, State: StatePending})
+	}
+	return cp
+}
+
+// LoadCheckpoint reads the checkpoint at path. It returns nil and no error
+// when there is none.
+func LoadCheckpoint(path string) (*Checkpoint, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var cp Checkpoint
+	if err := json.Unmarshal(data, &cp); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	cp.path = path
+	return &cp, nil
+}
+
+// Path is where the checkpoint is saved.
+func (cp *Checkpoint) Path() string {
+	return cp.path
+}
+
+// Save writes the checkpoint atomically.
+func (cp *Checkpoint) Save() error {
+	if err := os.MkdirAll(filepath.Dir(cp.path), 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(cp, "", "  ")
+	if err != nil {
+		return err
+	}
+	tmp := cp.path + ".tmp"
+	if err := os.WriteFile(tmp, append(data, '\n'), 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, cp.path)
+}
+
+// InState returns the tenants in state, in checkpoint order.
+func (cp *Checkpoint) InState(state string) []*TenantRun {
+	var out []*TenantRun
+	for _, t := range cp.Tenants {
+		if t.State == state {
+			out = append(out, t)
+		}
+	}
+	return out
+}
+
+// Counts returns the number of tenants in each state.
+func (cp *Checkpoint) Counts() map[string]int {
+	counts := map[string]int{}
+	for _, t := range cp.Tenants {
+		counts[t.State]++
+	}
+	return counts
+}
+
+// RetryFailed moves failed tenants back to pending.
+func (cp *Checkpoint) RetryFailed() int {
+	n := 0
+	for _, t := range cp.Tenants {
+		if t.State == StateFailed {
+			*t = TenantRun{Tenant: t.Tenant, State: StatePending}
+			n++
+		}
+	}
+	return n
+}
+
+// Budget limits how hard a fleet reindex pushes the data plane.
+type Budget struct {
+	// MaxTenants caps tenants reindexing at once.
+	MaxTenants int
+	// MaxAttempts caps running index attempts across tenants; 0 is no cap.
+	MaxAttempts int
+	// MaxDocsPerMinute caps the combined indexing rate; 0 is no cap.
+	MaxDocsPerMinute float64
+}
+
+// CanStart reports whether another tenant may start given the running
+// tenants and the current combined indexing rate, and if not, why.
+func (b Budget) CanStart(running []*TenantRun, docsPerMinute float64) (bool, string) {
+	if len(running) >= b.MaxTenants {
+		return false, fmt.Sprintf("%d tenant(s) running", len(running))
+	}
+	if b.MaxAttempts > 0 {
+		attempts := 0
+		for _, t := range running {
+			attempts += t.Running
+		}
+		if attempts >= b.MaxAttempts {
+			return false, fmt.Sprintf("%d index attempts running (budget %d)", attempts, b.MaxAttempts)
+		}
+	}
+	if b.MaxDocsPerMinute > 0 && docsPerMinute >= b.MaxDocsPerMinute {
+		return false, fmt.Sprintf("indexing %.0f docs/min (budget %.0f)", docsPerMinute, b.MaxDocsPerMinute)
+	}
+	return true, ""
+}
+
+// Finish records the outcome of a running tenant from its final progress.
+func (t *TenantRun) Finish(p *Progress, now time.Time) {
+	t.DocsIndexed, t.Running = p.DocsIndexed, 0
+	t.FinishedAt = now.UTC()
+	if p.Failed() > 0 {
+		t.State = StateFailed
+		t.Error = fmt.Sprintf("%d of %d index attempt(s) failed", p.Failed(), p.Total())
+		return
+	}
+	t.State = StateDone
+}
+
+// AddTenants appends the tenants the checkpoint does not cover yet, as
+// pending, and returns how many were added.
+func (cp *Checkpoint) AddTenants(tenants []string) int {
+	n := 0
+	for _, name := range tenants {
+		if !slices.ContainsFunc(cp.Tenants, func(t *TenantRun) bool { return t.Tenant == name }) {
+			cp.Tenants = append(cp.Tenants, &TenantRun{Tenant: name, State: StatePending})
+			n++
+		}
+	}
+	return n
+}
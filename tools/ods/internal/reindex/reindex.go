@@ -0,0 +1,150 @@
+// Package reindex triggers from-scratch reindexing of a tenant's connectors
+// and tracks the resulting index attempts.
+package reindex
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/kube"
+)
+
+//go:embed trigger_reindex.py
+var triggerScript string
+
+// Trigger is the outcome of triggering a reindex.
+type Trigger struct {
+	Triggered int    `json:"triggered"`
+	CCPairIDs []int  `json:"cc_pair_ids"`
+	StartedAt string `json:"started_at"`
+}
+
+// Start marks the tenant's active connector/credential pairs (or only those
+// of connectorID, when non-zero) for reindexing on pod.
+func Start(c *kube.Cluster, pod, tenantID string, connectorID int) (*Trigger, error) {
+	args := []string{tenantID}
+	if connectorID != 0 {
+		args = append(args, strconv.Itoa(connectorID))
+	}
+	out, err := c.RunPython(pod, triggerScript, args...)
+	if err != nil {
+		return nil, err
+	}
+	return parseTrigger(out)
+}
+
+// NoConnectorsError reports a tenant without active connectors to reindex.
+type NoConnectorsError struct {
+	Message string
+}
+
+func (e *NoConnectorsError) Error() string {
+	return e.Message
+}
+
+func parseTrigger(stdout string) (*Trigger, error) {
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	last := strings.TrimSpace(lines[len(lines)-1])
+	var r struct {
+		Status  string `json:"status"`
+		Message string `json:"message"`
+		Trigger
+	}
+	if err := json.Unmarshal([]byte(last), &r); err != nil {
+		return nil, fmt.Errorf("unexpected output from reindex script: %q", last)
+	}
+	switch r.Status {
+	case "success":
+		return &r.Trigger, nil
+	case "not_found":
+		return nil, &NoConnectorsError{Message: r.Message}
+	default:
+		return nil, fmt.Errorf("%s", r.Message)
+	}
+}
+
+// Progress summarises the index attempts created by a reindex.
+type Progress struct {
+	// Attempts counts index attempts by lower-cased status.
+	Attempts        map[string]int
+	DocsIndexed     int
+	BatchesDone     int
+	BatchesExpected int
+}
+
+// Total returns the number of index attempts.
+func (p *Progress) Total() int {
+	n := 0
+	for _, v := range p.Attempts {
+		n += v
+	}
+	return n
+}
+
+// Running returns the number of attempts that have not finished.
+func (p *Progress) Running() int {
+	return p.Attempts["not_started"] + p.Attempts["in_progress"]
+}
+
+// Failed returns the number of attempts that ended in failure.
+func (p *Progress) Failed() int {
+	return p.Attempts["failed"] + p.Attempts["canceled"]
+}
+
+// ProgressSQL returns the query summarising index attempts for the given
+// pairs created at or after startedAt. tenantID must already be validated as
+// a safe identifier.
+func ProgressSQL(tenantID string, ccPairIDs []int, startedAt string) string {
+	ids := make([]string, len(ccPairIDs))
+	for i, id := range ccPairIDs {
+		ids[i] = strconv.Itoa(id)
+	}
+	return fmt.Sprintf(
+		`SELECT status, count(*), coalesce(sum(total_docs_indexed), 0), coalesce(sum(completed_batches), 0), coalesce(sum(total_batches), 0) `+
+			`FROM "%s".index_attempt WHERE connector_credential_pair_id IN (%s) AND time_created >= '%s' GROUP BY status;`,
+		tenantID, strings.Join(ids, ", "), startedAt,
+	)
+}
+
+// ParseProgress parses the tab-separated rows returned by ProgressSQL.
+func ParseProgress(rows []string) (*Progress, error) {
+	p := &Progress{Attempts: make(map[string]int)}
+	for _, row := range rows {
+		fields := strings.Split(row, "\t")
+		if len(fields) != 5 {
+			return nil, fmt.Errorf("unexpected progress row %q", row)
+		}
+		nums := make([]int, 4)
+		for i, f := range fields[1:] {
+			n, err := strconv.Atoi(strings.TrimSpace(f))
+			if err != nil {
+				return nil, fmt.Errorf("unexpected progress row %q", row)
+			}
+			nums[i] = n
+		}
+		p.Attempts[strings.ToLower(strings.TrimSpace(fields[0]))] += nums[0]
+		p.DocsIndexed += nums[1]
+		p.BatchesDone += nums[2]
+		p.BatchesExpected += nums[3]
+	}
+	return p, nil
+}
+
+// Throughput returns documents indexed per minute between two samples.
+func Throughput(prevDocs, docs int, elapsed time.Duration) float64 {
+	if elapsed <= 0 || docs < prevDocs {
+		return 0
+	}
+	return float64(docs-prevDocs) / elapsed.Minutes()
+}
+
+// ValidStartedAt reports whether s looks like the ISO-8601 timestamp the
+// trigger script returns, so it can be interpolated into SQL.
+func ValidStartedAt(s string) bool {
+	_, err := time.Parse(time.RFC3339Nano, s)
+	return err == nil
+}
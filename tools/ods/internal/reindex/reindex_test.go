@@ -0,0 +1,119 @@
+package reindex
+
+import (
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseTrigger(t *testing.T) {
+	out := "INFO some import log\n{\"status\": \"success\", \"triggered\": 2, \"cc_pair_ids\": [3, 4], \"started_at\": \"2026-01-02T03:04:05.123456+00:00\"}\n"
+	tr, err := parseTrigger(out)
+	if err != nil {
+		t.Fatalf("parseTrigger() error: %v", err)
+	}
+	if tr.Triggered != 2 || len(tr.CCPairIDs) != 2 || !ValidStartedAt(tr.StartedAt) {
+		t.Errorf("unexpected trigger %+v", tr)
+	}
+
+	_, err = parseTrigger(`{"status": "not_found", "message": "No active connectors to reindex"}`)
+	var none *NoConnectorsError
+	if !errors.As(err, &none) || err.Error() != "No active connectors to reindex" {
+		t.Errorf("expected not_found message as NoConnectorsError, got %v", err)
+	}
+	if _, err := parseTrigger("Traceback (most recent call last):"); err == nil {
+		t.Error("expected non-JSON output to return an error")
+	}
+}
+
+func TestProgressSQL(t *testing.T) {
+	sql := ProgressSQL("tenant_1", []int{3, 4}, "2026-01-02T03:04:05+00:00")
+	for _, want := range []string{`"tenant_1".index_attempt`, "IN (3, 4)", "time_created >= '2026-01-02T03:04:05+00:00'"} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("ProgressSQL() = %q, missing %q", sql, want)
+		}
+	}
+}
+
+func TestParseProgress(t *testing.T) {
+	p, err := ParseProgress([]string{
+		"IN_PROGRESS\t2\t150\t3\t10",
+		"SUCCESS\t1\t500\t5\t5",
+		"failed\t1\t0\t0\t0",
+	})
+	if err != nil {
+		t.Fatalf("ParseProgress() error: %v", err)
+	}
+	if p.Total() != 4 || p.Running() != 2 || p.Failed() != 1 {
+		t.Errorf("Total/Running/Failed = %d/%d/%d", p.Total(), p.Running(), p.Failed())
+	}
+	if p.DocsIndexed != 650 || p.BatchesDone != 8 || p.BatchesExpected != 15 {
+		t.Errorf("unexpected totals %+v", p)
+	}
+
+	if _, err := ParseProgress([]string{"garbage"}); err == nil {
+		t.Error("expected an error for a malformed row")
+	}
+}
+
+func TestThroughput(t *testing.T) {
+	if got := Throughput(100, 400, 30*time.Second); got != 600 {
+		t.Errorf("Throughput() = %v, want 600", got)
+	}
+	if got := Throughput(100, 50, time.Minute); got != 0 {
+		t.Errorf("Throughput() with fewer docs = %v, want 0", got)
+	}
+}
+
+func TestValidStartedAt(t *testing.T) {
+	if ValidStartedAt("2026-01-02'; DROP TABLE x; --") {
+		t.Error("expected an injected timestamp to be rejected")
+	}
+}
+
+func TestCheckpoint(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "reindex-runs", "prod.json")
+	if cp, err := LoadCheckpoint(path); err != nil || cp != nil {
+		t.Fatalf("LoadCheckpoint() of a missing file = %v, %v", cp, err)
+	}
+
+	cp := NewCheckpoint(path, "prod", []string{"tenant_a", "tenant_b", "tenant_c"})
+	cp.Tenants[0].State, cp.Tenants[0].CCPairIDs, cp.Tenants[0].StartedAt = StateRunning, []int{3}, "2026-01-02T03:04:05+00:00"
+	cp.Tenants[1].Finish(&Progress{Attempts: map[string]int{"success": 1, "failed": 1}, DocsIndexed: 10}, time.Now())
+	if err := cp.Save(); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+
+	got, err := LoadCheckpoint(path)
+	if err != nil {
+		t.Fatalf("LoadCheckpoint() error: %v", err)
+	}
+	if got.Path() != path || got.Tenants[0].CCPairIDs[0] != 3 || got.Tenants[1].State != StateFailed || got.Tenants[1].Error != "1 of 2 index attempt(s) failed" {
+		t.Errorf("unexpected checkpoint %+v", got.Tenants)
+	}
+	if n := got.AddTenants([]string{"tenant_c", "tenant_d", "tenant_a"}); n != 1 || got.Tenants[3].Tenant != "tenant_d" {
+		t.Errorf("AddTenants() = %d, tenants %+v", n, got.Tenants)
+	}
+	if n := got.RetryFailed(); n != 1 || got.Counts()[StatePending] != 3 || got.Tenants[1].Error != "" {
+		t.Errorf("RetryFailed() = %d, counts %v", n, got.Counts())
+	}
+}
+
+func TestBudget(t *testing.T) {
+	b := Budget{MaxTenants: 2, MaxAttempts: 5, MaxDocsPerMinute: 1000}
+	running := []*TenantRun{{Running: 3}}
+	if ok, why := b.CanStart(running, 500); !ok {
+		t.Errorf("CanStart() = false (%s), want true", why)
+	}
+	if ok, _ := b.CanStart(append(running, &TenantRun{}), 0); ok {
+		t.Error("CanStart() allowed more than MaxTenants")
+	}
+	if ok, why := b.CanStart([]*TenantRun{{Running: 5}}, 0); ok || !strings.Contains(why, "attempts") {
+		t.Errorf("CanStart() = %v (%s), want the attempt budget to block", ok, why)
+	}
+	if ok, why := b.CanStart(running, 1200); ok || !strings.Contains(why, "docs/min") {
+		t.Errorf("CanStart() = %v (%s), want the rate budget to block", ok, why)
+	}
+}
@@ -0,0 +1,99 @@
+"""Trigger a from-scratch reindex of a tenant's connectors.
+
+Bundled with ods and piped into `python -` on an api-server pod by
+`ods vespa reindex`. Marks each matching connector/credential pair with a
+REINDEX indexing trigger (the same path as the admin "re-index" button) and
+kicks the indexing check task so workers pick it up immediately.
+
+Usage:
+    python - <tenant_id> [<connector_id>]
+
+Progress goes to stderr; the last line on stdout is a JSON object with
+"status", "cc_pair_ids" and "started_at" (database time, for polling index
+attempts created after the trigger).
+"""
+
+from __future__ import annotations
+
+import json
+import sys
+from typing import Any
+
+
+def trigger(tenant_id: str, connector_id: int | None) -> dict[str, Any]:
+    from sqlalchemy import func
+    from sqlalchemy import select
+
+    from onyx.db.engine.sql_engine import get_session_with_tenant
+    from onyx.db.enums import ConnectorCredentialPairStatus
+    from onyx.db.models import ConnectorCredentialPair
+    from onyx.server.documents.connector import trigger_indexing_for_cc_pair
+    from shared_configs.contextvars import CURRENT_TENANT_ID_CONTEXTVAR
+
+    CURRENT_TENANT_ID_CONTEXTVAR.set(tenant_id)
+    with get_session_with_tenant(tenant_id=tenant_id) as db_session:
+        started_at = db_session.scalar(select(func.now()))
+
+        stmt = select(ConnectorCredentialPair).where(
+            ConnectorCredentialPair.status.in_(
+                ConnectorCredentialPairStatus.active_statuses()
+            )
+        )
+        if connector_id is not None:
+            stmt = stmt.where(ConnectorCredentialPair.connector_id == connector_id)
+        cc_pairs = db_session.scalars(stmt).all()
+        if not cc_pairs:
+            return {
+                "status": "not_found",
+                "message": "No active connectors to reindex",
+            }
+
+        by_connector: dict[int, list[int]] = {}
+        for cc_pair in cc_pairs:
+            by_connector.setdefault(cc_pair.connector_id, []).append(
+                cc_pair.credential_id
+            )
+
+        triggered = 0
+        for cid, credential_ids in by_connector.items():
+            print(f"Triggering reindex of connector {cid}...", file=sys.stderr)
+            triggered += trigger_indexing_for_cc_pair(
+                credential_ids, cid, True, tenant_id, db_session
+            )
+
+        return {
+            "status": "success",
+            "triggered": triggered,
+            "cc_pair_ids": [cc_pair.id for cc_pair in cc_pairs],
+            "started_at": started_at.isoformat(),
+        }
+
+
+def main() -> None:
+    if len(sys.argv) not in (2, 3):
+        print(
+            json.dumps(
+                {
+                    "status": "error",
+                    "message": "Usage: python - <tenant_id> [<connector_id>]",
+                }
+            )
+        )
+        sys.exit(1)
+
+    from onyx.db.engine.sql_engine import SqlEngine
+
+    SqlEngine.init_engine(pool_size=5, max_overflow=2)
+
+    tenant_id = sys.argv[1]
+    connector_id = int(sys.argv[2]) if len(sys.argv) == 3 else None
+    try:
+        result = trigger(tenant_id, connector_id)
+    except Exception as e:
+        print(f"Error: {e}", file=sys.stderr)
+        result = {"status": "error", "message": str(e)}
+    print(json.dumps(result))
+
+
+if __name__ == "__main__":
+    main()
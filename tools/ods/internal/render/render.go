@@ -0,0 +1,125 @@
+// Package render formats what commands print: aligned tables, indented JSON
+// and ANSI colors.
+//
+// Scripts parse ods output, so these formats are a contract: tables are
+// columns separated by at least two spaces under an upper-case header, JSON
+// is indented by two spaces with a trailing newline, and colors are only
+// written to a terminal, never when NO_COLOR or --no-color is set. Golden
+// files under testdata pin the formats of the commands that use them.
+package render
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+)
+
+// ANSI styles for Paint.
+const (
+	Reset  = "\033[0m"
+	Red    = "\033[31m"
+	Green  = "\033[32m"
+	Yellow = "\033[33m"
+	Bold   = "\033[1m"
+	// Reverse swaps the foreground and background, as watch(1) -d does.
+	Reverse = "\033[7m"
+)
+
+// Table is a table of aligned columns.
+type Table struct {
+	header []string
+	rows   [][]string
+	// Underline adds a row of dashes under the header.
+	Underline bool
+	// Indent prefixes every line.
+	Indent string
+}
+
+// NewTable returns a table with the given column headers; none omits the
+// header line.
+func NewTable(header ...string) *Table {
+	return &Table{header: header}
+}
+
+// Row appends a row, formatting each cell with fmt.Sprint.
+func (t *Table) Row(cells ...any) {
+	row := make([]string, len(cells))
+	for i, c := range cells {
+		row[i] = fmt.Sprint(c)
+	}
+	t.rows = append(t.rows, row)
+}
+
+// Len returns the number of rows.
+func (t *Table) Len() int {
+	return len(t.rows)
+}
+
+// Write writes the table to w.
+func (t *Table) Write(w io.Writer) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	line := func(cells []string) {
+		_, _ = fmt.Fprintln(tw, t.Indent+strings.Join(cells, "\t"))
+	}
+	if len(t.header) > 0 {
+		line(t.header)
+		if t.Underline {
+			dashes := make([]string, len(t.header))
+			for i, h := range t.header {
+				dashes[i] = strings.Repeat("-", len(h))
+			}
+			line(dashes)
+		}
+	}
+	for _, r := range t.rows {
+		line(r)
+	}
+	return tw.Flush()
+}
+
+// JSON writes v to w indented by two spaces, with a trailing newline.
+func JSON(w io.Writer, v any) error {
+	out, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(append(out, '\n'))
+	return err
+}
+
+// noColor is set by --no-color.
+var noColor bool
+
+// SetNoColor turns colors off for the rest of the run, as --no-color does.
+func SetNoColor(off bool) {
+	noColor = off
+}
+
+// NoColor reports whether colors are turned off by --no-color or a
+// non-empty NO_COLOR (https://no-color.org).
+func NoColor() bool {
+	return noColor || os.Getenv("NO_COLOR") != ""
+}
+
+// ColorEnabled reports whether to color output written to f: colors are on
+// and f is a terminal.
+func ColorEnabled(f *os.File) bool {
+	return !NoColor() && IsTerminal(f)
+}
+
+// IsTerminal reports whether f is a terminal rather than a pipe or file.
+func IsTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
+
+// Paint wraps s in style when color is true.
+func Paint(color bool, style, s string) string {
+	if !color {
+		return s
+	}
+	return style + s + Reset
+}
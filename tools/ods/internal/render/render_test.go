@@ -0,0 +1,63 @@
+package render
+
+import (
+	"bytes"
+	"os"
+	"testing"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/golden"
+)
+
+func TestTable(t *testing.T) {
+	var buf bytes.Buffer
+	table := NewTable("NAME", "READY", "AGE")
+	table.Underline = true
+	table.Row("api-server-7d9f", "1/1", "3d")
+	table.Row("background-worker-5c6b8", "0/1", "12m")
+	if err := table.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+
+	noHeader := NewTable()
+	noHeader.Indent = "  "
+	noHeader.Row("a1b2c3", 12)
+	noHeader.Row("(none)", 3)
+	if err := noHeader.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	golden.Assert(t, "table", buf.Bytes())
+}
+
+func TestJSON(t *testing.T) {
+	var buf bytes.Buffer
+	v := map[string]any{"status": "ok", "tenants": []string{"tenant_a", "tenant_b"}, "count": 2}
+	if err := JSON(&buf, v); err != nil {
+		t.Fatal(err)
+	}
+	golden.Assert(t, "json", buf.Bytes())
+}
+
+func TestNoColor(t *testing.T) {
+	t.Setenv("NO_COLOR", "")
+	SetNoColor(false)
+	if NoColor() {
+		t.Error("expected colors on by default")
+	}
+	t.Setenv("NO_COLOR", "1")
+	if !NoColor() || ColorEnabled(os.Stdout) {
+		t.Error("expected NO_COLOR to turn colors off")
+	}
+	t.Setenv("NO_COLOR", "")
+	SetNoColor(true)
+	defer SetNoColor(false)
+	if !NoColor() {
+		t.Error("expected --no-color to turn colors off")
+	}
+
+	if got := Paint(false, Red, "x"); got != "x" {
+		t.Errorf("Paint without color = %q", got)
+	}
+	if got := Paint(true, Red, "x"); got != Red+"x"+Reset {
+		t.Errorf("Paint with color = %q", got)
+	}
+}
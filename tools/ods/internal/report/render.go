@@ -0,0 +1,138 @@
+package report
+
+import (
+	"fmt"
+	"html/template"
+	"io"
+	"strings"
+)
+
+// Output formats.
+const (
+	FormatMarkdown = "md"
+	FormatHTML     = "html"
+)
+
+// Render writes r to w in format.
+func Render(w io.Writer, r *Report, format string) error {
+	switch format {
+	case FormatMarkdown:
+		return Markdown(w, r)
+	case FormatHTML:
+		return HTML(w, r)
+	}
+	return fmt.Errorf("unknown format %q (must be %s or %s)", format, FormatMarkdown, FormatHTML)
+}
+
+func (r *Report) subtitle() string {
+	return fmt.Sprintf("%s · %s · generated %s", r.Context, r.Period, r.Generated.UTC().Format("2006-01-02 15:04 MST"))
+}
+
+// Markdown writes r as GitHub-flavored Markdown.
+func Markdown(w io.Writer, r *Report) error {
+	var b strings.Builder
+	fmt.Fprintf(&b, "# %s\n\n_%s_\n\n", r.Title, r.subtitle())
+
+	b.WriteString("| Metric | Value | Change |\n|---|--:|--:|\n")
+	for _, f := range r.Summary {
+		fmt.Fprintf(&b, "| %s | %s | %s |\n", markdownCell(f.Label), markdownCell(f.Value), markdownCell(f.Change))
+	}
+
+	for _, s := range r.Sections {
+		fmt.Fprintf(&b, "\n## %s\n\n", s.Heading)
+		if s.Intro != "" {
+			fmt.Fprintf(&b, "%s\n\n", s.Intro)
+		}
+		if len(s.Rows) == 0 {
+			fmt.Fprintf(&b, "%s\n", s.Empty)
+			continue
+		}
+		cells := make([]string, len(s.Columns))
+		for i, c := range s.Columns {
+			cells[i] = markdownCell(c)
+		}
+		fmt.Fprintf(&b, "| %s |\n|%s\n", strings.Join(cells, " | "), strings.Repeat("---|", len(s.Columns)))
+		for _, row := range s.Rows {
+			for i, c := range row {
+				cells[i] = markdownCell(c)
+			}
+			fmt.Fprintf(&b, "| %s |\n", strings.Join(cells, " | "))
+		}
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+func markdownCell(s string) string {
+	return strings.NewReplacer("|", `\|`, "\n", " ").Replace(s)
+}
+
+var htmlTemplate = template.Must(template.New("report").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}} – {{.Context}}</title>
+<style>
+  body { font-family: -apple-system, BlinkMacSystemFont, "Segoe UI", Helvetica, Arial, sans-serif; color: #1f2328; margin: 2rem auto; max-width: 72rem; padding: 0 1.5rem; }
+  h1 { margin-bottom: 0.25rem; }
+  .subtitle { color: #59636e; margin-top: 0; }
+  .facts { display: flex; flex-wrap: wrap; gap: 0.75rem; margin: 1.5rem 0; }
+  .fact { border: 1px solid #d1d9e0; border-radius: 6px; padding: 0.75rem 1rem; min-width: 10rem; }
+  .fact .label { color: #59636e; font-size: 0.85rem; }
+  .fact .value { font-size: 1.5rem; font-weight: 600; }
+  .fact .change { color: #59636e; font-size: 0.85rem; }
+  table { border-collapse: collapse; width: 100%; font-size: 0.9rem; }
+  th, td { border-bottom: 1px solid #d1d9e0; padding: 0.4rem 0.6rem; text-align: left; vertical-align: top; }
+  th { background: #f6f8fa; }
+  .empty { color: #59636e; font-style: italic; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<p class="subtitle">{{.Subtitle}}</p>
+<div class="facts">
+{{- range .Summary}}
+  <div class="fact"><div class="label">{{.Label}}</div><div class="value">{{.Value}}</div>{{if .Change}}<div class="change">{{.Change}}</div>{{end}}</div>
+{{- end}}
+</div>
+{{- range .Sections}}
+<h2>{{.Heading}}</h2>
+{{- if .Intro}}
+<p>{{.Intro}}</p>
+{{- end}}
+{{- if .Rows}}
+<table>
+  <tr>{{range .Columns}}<th>{{.}}</th>{{end}}</tr>
+{{- range .Rows}}
+  <tr>{{range .}}<td>{{.}}</td>{{end}}</tr>
+{{- end}}
+</table>
+{{- else}}
+<p class="empty">{{.Empty}}</p>
+{{- end}}
+{{- end}}
+</body>
+</html>
+`))
+
+// HTML writes r as a self-contained HTML page.
+func HTML(w io.Writer, r *Report) error {
+	return htmlTemplate.Execute(w, struct {
+		*Report
+		Subtitle string
+	}{r, r.subtitle()})
+}
+
+// SlackSummary renders r's headline numbers as Slack mrkdwn.
+func SlackSummary(r *Report) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "*%s* (%s)\n", r.Title, r.subtitle())
+	for _, f := range r.Summary {
+		fmt.Fprintf(&b, "• %s: *%s*", f.Label, f.Value)
+		if f.Change != "" && f.Change != "-" {
+			fmt.Fprintf(&b, " (%s)", f.Change)
+		}
+		b.WriteByte('\n')
+	}
+	return strings.TrimSuffix(b.String(), "\n")
+}
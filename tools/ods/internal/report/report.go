@@ -0,0 +1,448 @@
+// Package report builds the periodic operations reports ods generates from
+// data plane statistics, and renders them as Markdown or HTML.
+package report
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Period is the time range a report covers.
+type Period struct {
+	From time.Time `json:"from"`
+	To   time.Time `json:"to"`
+}
+
+// Last returns the period of length d ending at now.
+func Last(d time.Duration, now time.Time) Period {
+	return Period{From: now.Add(-d), To: now}
+}
+
+// Previous returns the period of the same length just before p.
+func (p Period) Previous() Period {
+	return Period{From: p.From.Add(-p.To.Sub(p.From)), To: p.From}
+}
+
+func (p Period) String() string {
+	return p.From.UTC().Format("2006-01-02") + " to " + p.To.UTC().Format("2006-01-02")
+}
+
+// ParseLookback parses a Go duration, also accepting a whole number of days
+// such as "30d".
+func ParseLookback(s string) (time.Duration, error) {
+	var d time.Duration
+	if days, ok := strings.CutSuffix(s, "d"); ok {
+		n, err := strconv.Atoi(days)
+		if err != nil {
+			return 0, fmt.Errorf("%q is not a number of days", s)
+		}
+		d = time.Duration(n) * 24 * time.Hour
+	} else {
+		var err error
+		if d, err = time.ParseDuration(s); err != nil {
+			return 0, err
+		}
+	}
+	if d <= 0 {
+		return 0, fmt.Errorf("%q must be positive", s)
+	}
+	return d, nil
+}
+
+// Data is what reports are built from. Tenants and Connectors are only
+// filled in for the kinds that need them.
+type Data struct {
+	Context    string
+	Period     Period
+	Generated  time.Time
+	Tenants    []TenantStats
+	Connectors []ConnectorStats
+}
+
+// Report is a built report, ready to render.
+type Report struct {
+	Kind      string    `json:"kind"`
+	Title     string    `json:"title"`
+	Context   string    `json:"context"`
+	Period    Period    `json:"period"`
+	Generated time.Time `json:"generated"`
+	Summary   []Fact    `json:"summary"`
+	Sections  []Section `json:"sections"`
+}
+
+// Fact is a headline number, with its change from the previous period when
+// there is one.
+type Fact struct {
+	Label  string `json:"label"`
+	Value  string `json:"value"`
+	Change string `json:"change,omitempty"`
+}
+
+// Section is a titled table.
+type Section struct {
+	Heading string     `json:"heading"`
+	Intro   string     `json:"intro,omitempty"`
+	Columns []string   `json:"columns"`
+	Rows    [][]string `json:"rows"`
+	// Empty is shown instead of the table when there are no rows.
+	Empty string `json:"empty,omitempty"`
+}
+
+// Kind is a type of report.
+type Kind struct {
+	Name  string
+	Title string
+	// DefaultPeriod is the period covered unless another is asked for.
+	DefaultPeriod time.Duration
+	// Tenants and Connectors say which statistics the report needs.
+	Tenants    bool
+	Connectors bool
+	build      func(d Data) *Report
+}
+
+// Kinds lists the available reports.
+var Kinds = []Kind{
+	{Name: "weekly-ops", Title: "Weekly operations", DefaultPeriod: 7 * 24 * time.Hour, Tenants: true, Connectors: true, build: weeklyOps},
+	{Name: "tenant-growth", Title: "Tenant growth", DefaultPeriod: 30 * 24 * time.Hour, Tenants: true, build: tenantGrowth},
+	{Name: "connector-health", Title: "Connector health", DefaultPeriod: 7 * 24 * time.Hour, Connectors: true, build: connectorHealth},
+}
+
+// KindNames returns the names of Kinds.
+func KindNames() []string {
+	names := make([]string, len(Kinds))
+	for i, k := range Kinds {
+		names[i] = k.Name
+	}
+	return names
+}
+
+// LookupKind returns the kind called name.
+func LookupKind(name string) (Kind, error) {
+	for _, k := range Kinds {
+		if k.Name == name {
+			return k, nil
+		}
+	}
+	return Kind{}, fmt.Errorf("unknown report %q (must be one of %s)", name, strings.Join(KindNames(), ", "))
+}
+
+// Build builds the report from d.
+func (k Kind) Build(d Data) *Report {
+	r := k.build(d)
+	r.Kind, r.Title, r.Context, r.Period, r.Generated = k.Name, k.Title, d.Context, d.Period, d.Generated
+	return r
+}
+
+// topRows is how many rows the ranked tables show.
+const topRows = 15
+
+func weeklyOps(d Data) *Report {
+	var t TenantStats
+	activeTenants, prevActiveTenants := 0, 0
+	for _, s := range d.Tenants {
+		t.ActiveUsers += s.ActiveUsers
+		t.ChatSessions += s.ChatSessions
+		t.Messages += s.Messages
+		t.PrevMessages += s.PrevMessages
+		t.NewUsers += s.NewUsers
+		t.DocsIndexed += s.DocsIndexed
+		t.IndexSuccesses += s.IndexSuccesses
+		t.IndexFailures += s.IndexFailures
+		t.ErroredConnectors += s.ErroredConnectors
+		if s.Messages > 0 {
+			activeTenants++
+		}
+		if s.PrevMessages > 0 {
+			prevActiveTenants++
+		}
+	}
+
+	r := &Report{Summary: []Fact{
+		{Label: "Active tenants", Value: formatInt(int64(activeTenants)), Change: change(int64(activeTenants), int64(prevActiveTenants))},
+		{Label: "Active users", Value: formatInt(t.ActiveUsers)},
+		{Label: "Chat sessions", Value: formatInt(t.ChatSessions)},
+		{Label: "User messages", Value: formatInt(t.Messages), Change: change(t.Messages, t.PrevMessages)},
+		{Label: "New users", Value: formatInt(t.NewUsers)},
+		{Label: "Documents indexed", Value: formatInt(t.DocsIndexed)},
+		{Label: "Failed index attempts", Value: fmt.Sprintf("%s of %s", formatInt(t.IndexFailures), formatInt(t.IndexFailures+t.IndexSuccesses))},
+		{Label: "Connectors in repeated error", Value: formatInt(t.ErroredConnectors)},
+	}}
+
+	active := sortedTenants(d.Tenants, func(s TenantStats) int64 { return s.Messages })
+	section := Section{
+		Heading: "Most active tenants",
+		Columns: []string{"Tenant", "Active users", "Chat sessions", "Messages", "Change"},
+		Empty:   "No chat activity.",
+	}
+	for _, s := range top(active, func(s TenantStats) bool { return s.Messages > 0 }) {
+		section.Rows = append(section.Rows, []string{
+			s.Tenant, formatInt(s.ActiveUsers), formatInt(s.ChatSessions), formatInt(s.Messages), change(s.Messages, s.PrevMessages),
+		})
+	}
+	r.Sections = append(r.Sections, section)
+
+	failing := sortedTenants(d.Tenants, func(s TenantStats) int64 { return s.IndexFailures })
+	section = Section{
+		Heading: "Indexing failures",
+		Columns: []string{"Tenant", "Failed", "Succeeded", "Documents indexed", "Connectors in error"},
+		Empty:   "No failed index attempts.",
+	}
+	for _, s := range top(failing, func(s TenantStats) bool { return s.IndexFailures > 0 }) {
+		section.Rows = append(section.Rows, []string{
+			s.Tenant, formatInt(s.IndexFailures), formatInt(s.IndexSuccesses), formatInt(s.DocsIndexed), formatInt(s.ErroredConnectors),
+		})
+	}
+	r.Sections = append(r.Sections, section)
+
+	r.Sections = append(r.Sections, attentionSection(d, "Failing connectors", func(h string) bool { return h == HealthFailing }))
+	return r
+}
+
+func tenantGrowth(d Data) *Report {
+	var users, newUsers, messages, prevMessages, documents int64
+	active, prevActive := 0, 0
+	for _, s := range d.Tenants {
+		users += s.Users
+		newUsers += s.NewUsers
+		messages += s.Messages
+		prevMessages += s.PrevMessages
+		documents += s.Documents
+		if s.Messages > 0 {
+			active++
+		}
+		if s.PrevMessages > 0 {
+			prevActive++
+		}
+	}
+
+	r := &Report{Summary: []Fact{
+		{Label: "Tenants", Value: formatInt(int64(len(d.Tenants)))},
+		{Label: "Active tenants", Value: formatInt(int64(active)), Change: change(int64(active), int64(prevActive))},
+		{Label: "Users", Value: formatInt(users), Change: fmt.Sprintf("+%s new", formatInt(newUsers))},
+		{Label: "User messages", Value: formatInt(messages), Change: change(messages, prevMessages)},
+		{Label: "Documents", Value: formatInt(documents)},
+	}}
+
+	columns := []string{"Tenant", "Users", "New users", "Messages", "Previous period", "Change", "Documents"}
+	row := func(s TenantStats) []string {
+		return []string{
+			s.Tenant, formatInt(s.Users), formatInt(s.NewUsers), formatInt(s.Messages), formatInt(s.PrevMessages),
+			change(s.Messages, s.PrevMessages), formatInt(s.Documents),
+		}
+	}
+	delta := func(s TenantStats) int64 { return s.Messages - s.PrevMessages }
+
+	growing := Section{Heading: "Fastest growing", Intro: "By the increase in user messages over the previous period.", Columns: columns, Empty: "No tenant grew."}
+	for _, s := range top(sortedTenants(d.Tenants, delta), func(s TenantStats) bool { return delta(s) > 0 }) {
+		growing.Rows = append(growing.Rows, row(s))
+	}
+	declining := Section{Heading: "Declining", Intro: "By the drop in user messages from the previous period.", Columns: columns, Empty: "No tenant declined."}
+	shrink := func(s TenantStats) int64 { return -delta(s) }
+	for _, s := range top(sortedTenants(d.Tenants, shrink), func(s TenantStats) bool { return delta(s) < 0 }) {
+		declining.Rows = append(declining.Rows, row(s))
+	}
+	r.Sections = []Section{growing, declining}
+	return r
+}
+
+func connectorHealth(d Data) *Report {
+	counts := map[string]int64{}
+	var failures int64
+	type sourceStats struct{ connectors, unhealthy, failures int64 }
+	sources := map[string]*sourceStats{}
+	for _, c := range d.Connectors {
+		h := c.Health()
+		counts[h]++
+		failures += c.Failures
+		s := sources[c.Source]
+		if s == nil {
+			s = &sourceStats{}
+			sources[c.Source] = s
+		}
+		s.connectors++
+		s.failures += c.Failures
+		if needsAttention(h) {
+			s.unhealthy++
+		}
+	}
+
+	r := &Report{Summary: []Fact{{Label: "Connectors", Value: formatInt(int64(len(d.Connectors)))}}}
+	for _, h := range healthOrder {
+		if counts[h] > 0 || h == HealthFailing || h == HealthHealthy {
+			r.Summary = append(r.Summary, Fact{Label: strings.ToUpper(h[:1]) + h[1:], Value: formatInt(counts[h])})
+		}
+	}
+	r.Summary = append(r.Summary, Fact{Label: "Failed index attempts", Value: formatInt(failures)})
+
+	r.Sections = append(r.Sections, attentionSection(d, "Needs attention", needsAttention))
+
+	names := make([]string, 0, len(sources))
+	for name := range sources {
+		names = append(names, name)
+	}
+	sort.Slice(names, func(i, j int) bool {
+		a, b := sources[names[i]], sources[names[j]]
+		if a.unhealthy != b.unhealthy {
+			return a.unhealthy > b.unhealthy
+		}
+		if a.connectors != b.connectors {
+			return a.connectors > b.connectors
+		}
+		return names[i] < names[j]
+	})
+	bySource := Section{Heading: "By source", Columns: []string{"Source", "Connectors", "Needing attention", "Failed attempts"}, Empty: "No connectors."}
+	for _, name := range names {
+		s := sources[name]
+		bySource.Rows = append(bySource.Rows, []string{strings.ToLower(name), formatInt(s.connectors), formatInt(s.unhealthy), formatInt(s.failures)})
+	}
+	r.Sections = append(r.Sections, bySource)
+	return r
+}
+
+// attentionSection lists the connectors whose health include accepts, the
+// worst first.
+func attentionSection(d Data, heading string, include func(health string) bool) Section {
+	var conns []ConnectorStats
+	for _, c := range d.Connectors {
+		if include(c.Health()) {
+			conns = append(conns, c)
+		}
+	}
+	sort.SliceStable(conns, func(i, j int) bool {
+		hi, hj := healthRank(conns[i].Health()), healthRank(conns[j].Health())
+		if hi != hj {
+			return hi < hj
+		}
+		if conns[i].Failures != conns[j].Failures {
+			return conns[i].Failures > conns[j].Failures
+		}
+		return conns[i].Tenant < conns[j].Tenant
+	})
+
+	section := Section{
+		Heading: heading,
+		Columns: []string{"Tenant", "Connector", "Source", "Health", "Last success", "Failed / succeeded", "Last error"},
+		Empty:   "None.",
+	}
+	for _, c := range conns {
+		lastSuccess := "never"
+		if !c.LastSuccess.IsZero() {
+			lastSuccess = c.LastSuccess.UTC().Format("2006-01-02")
+		}
+		section.Rows = append(section.Rows, []string{
+			c.Tenant, fmt.Sprintf("%s (#%d)", c.Name, c.CCPairID), strings.ToLower(c.Source), c.Health(), lastSuccess,
+			fmt.Sprintf("%d / %d", c.Failures, c.Successes), c.LastError,
+		})
+	}
+	return section
+}
+
+// Connector health, from worst to best.
+const (
+	HealthFailing  = "failing"
+	HealthInvalid  = "invalid"
+	HealthStale    = "stale"
+	HealthFlaky    = "flaky"
+	HealthIndexing = "indexing"
+	HealthPaused   = "paused"
+	HealthDeleting = "deleting"
+	HealthHealthy  = "healthy"
+)
+
+var healthOrder = []string{HealthFailing, HealthInvalid, HealthStale, HealthFlaky, HealthIndexing, HealthPaused, HealthDeleting, HealthHealthy}
+
+func healthRank(h string) int {
+	for i, o := range healthOrder {
+		if o == h {
+			return i
+		}
+	}
+	return len(healthOrder)
+}
+
+func needsAttention(h string) bool {
+	return h == HealthFailing || h == HealthInvalid || h == HealthStale || h == HealthFlaky
+}
+
+// Health classifies the connector's indexing in the period: failing when it
+// is in a repeated error state or never succeeded, flaky when some attempts
+// failed, and stale when it ran nothing at all.
+func (c ConnectorStats) Health() string {
+	switch c.Status {
+	case "PAUSED":
+		return HealthPaused
+	case "DELETING":
+		return HealthDeleting
+	case "INVALID":
+		return HealthInvalid
+	}
+	switch {
+	case c.RepeatedErrors || (c.Failures > 0 && c.Successes == 0):
+		return HealthFailing
+	case c.Failures > 0:
+		return HealthFlaky
+	case c.Status == "INITIAL_INDEXING":
+		return HealthIndexing
+	case c.Successes == 0:
+		return HealthStale
+	}
+	return HealthHealthy
+}
+
+// sortedTenants returns a copy of stats sorted by key, highest first.
+func sortedTenants(stats []TenantStats, key func(TenantStats) int64) []TenantStats {
+	sorted := append([]TenantStats(nil), stats...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if key(sorted[i]) != key(sorted[j]) {
+			return key(sorted[i]) > key(sorted[j])
+		}
+		return sorted[i].Tenant < sorted[j].Tenant
+	})
+	return sorted
+}
+
+// top returns the first topRows of stats that keep accepts.
+func top(stats []TenantStats, keep func(TenantStats) bool) []TenantStats {
+	var kept []TenantStats
+	for _, s := range stats {
+		if len(kept) == topRows {
+			break
+		}
+		if keep(s) {
+			kept = append(kept, s)
+		}
+	}
+	return kept
+}
+
+// change formats the relative change from prev to cur.
+func change(cur, prev int64) string {
+	switch {
+	case prev == 0 && cur == 0:
+		return "-"
+	case prev == 0:
+		return "new"
+	}
+	pct := float64(cur-prev) / float64(prev) * 100
+	return fmt.Sprintf("%+.1f%%", pct)
+}
+
+// formatInt formats n with thousands separators.
+func formatInt(n int64) string {
+	s := strconv.FormatInt(n, 10)
+	sign := ""
+	if n < 0 {
+		sign, s = "-", s[1:]
+	}
+	var b strings.Builder
+	for i, r := range s {
+		if i > 0 && (len(s)-i)%3 == 0 {
+			b.WriteByte(',')
+		}
+		b.WriteRune(r)
+	}
+	return sign + b.String()
+}
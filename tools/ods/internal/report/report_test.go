@@ -0,0 +1,179 @@
+package report
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+var testPeriod = Last(7*24*time.Hour, time.Date(2026, 10, 12, 9, 0, 0, 0, time.UTC))
+
+func TestParseLookback(t *testing.T) {
+	for in, want := range map[string]time.Duration{"30d": 30 * 24 * time.Hour, "90m": 90 * time.Minute} {
+		if got, err := ParseLookback(in); err != nil || got != want {
+			t.Errorf("ParseLookback(%q) = %v, %v, want %v", in, got, err, want)
+		}
+	}
+	for _, in := range []string{"", "xd", "0d", "-1h"} {
+		if _, err := ParseLookback(in); err == nil {
+			t.Errorf("ParseLookback(%q) succeeded, want an error", in)
+		}
+	}
+}
+
+func TestStatsSQL(t *testing.T) {
+	sql := TenantStatsSQL([]string{"tenant_a", "tenant_b"}, testPeriod)
+	if strings.Count(sql, "UNION ALL") != 1 || !strings.Contains(sql, `FROM "tenant_b".document`) ||
+		!strings.Contains(sql, "time_sent >= '2026-09-28T09:00:00Z' AND time_sent < '2026-10-05T09:00:00Z'") {
+		t.Errorf("unexpected tenant stats SQL:\n%s", sql)
+	}
+	sql = ConnectorStatsSQL([]string{"tenant_a"}, testPeriod)
+	if !strings.Contains(sql, `JOIN "tenant_a".connector c`) || !strings.Contains(sql, "a.time_created >= '2026-10-05T09:00:00Z'") {
+		t.Errorf("unexpected connector stats SQL:\n%s", sql)
+	}
+}
+
+func TestParseStats(t *testing.T) {
+	tenants, err := ParseTenantStats([]string{"tenant_a\t10\t2\t5\t7\t40\t20\t1000\t12\t3\t150\t1"})
+	if err != nil {
+		t.Fatalf("ParseTenantStats() error: %v", err)
+	}
+	want := TenantStats{
+		Tenant: "tenant_a", Users: 10, NewUsers: 2, ActiveUsers: 5, ChatSessions: 7, Messages: 40, PrevMessages: 20,
+		Documents: 1000, IndexSuccesses: 12, IndexFailures: 3, DocsIndexed: 150, ErroredConnectors: 1,
+	}
+	if len(tenants) != 1 || tenants[0] != want {
+		t.Errorf("ParseTenantStats() = %+v", tenants)
+	}
+	if _, err := ParseTenantStats([]string{"tenant_a\t10"}); err == nil {
+		t.Error("expected an error for a short row")
+	}
+
+	conns, err := ParseConnectorStats([]string{
+		"tenant_a\t3\tWiki\tCONFLUENCE\tACTIVE\tt\t2026-10-01T08:00:00Z\t4\t0\tauth failed",
+		"tenant_a\t4\tDrive\tGOOGLE_DRIVE\tACTIVE\tf\t\t0\t2\t",
+	})
+	if err != nil {
+		t.Fatalf("ParseConnectorStats() error: %v", err)
+	}
+	if c := conns[0]; c.CCPairID != 3 || !c.RepeatedErrors || c.LastSuccess.Day() != 1 || c.Failures != 4 || c.LastError != "auth failed" {
+		t.Errorf("first connector = %+v", c)
+	}
+	if c := conns[1]; !c.LastSuccess.IsZero() || c.Successes != 2 {
+		t.Errorf("second connector = %+v", c)
+	}
+}
+
+func TestConnectorHealth(t *testing.T) {
+	for _, tc := range []struct {
+		c    ConnectorStats
+		want string
+	}{
+		{ConnectorStats{Status: "ACTIVE", Successes: 3}, HealthHealthy},
+		{ConnectorStats{Status: "ACTIVE", RepeatedErrors: true, Successes: 3}, HealthFailing},
+		{ConnectorStats{Status: "ACTIVE", Failures: 2}, HealthFailing},
+		{ConnectorStats{Status: "ACTIVE", Failures: 1, Successes: 5}, HealthFlaky},
+		{ConnectorStats{Status: "ACTIVE"}, HealthStale},
+		{ConnectorStats{Status: "INITIAL_INDEXING"}, HealthIndexing},
+		{ConnectorStats{Status: "PAUSED", Failures: 3}, HealthPaused},
+		{ConnectorStats{Status: "INVALID"}, HealthInvalid},
+	} {
+		if got := tc.c.Health(); got != tc.want {
+			t.Errorf("Health(%+v) = %s, want %s", tc.c, got, tc.want)
+		}
+	}
+}
+
+func testData() Data {
+	return Data{
+		Context:   "prod",
+		Period:    testPeriod,
+		Generated: testPeriod.To,
+		Tenants: []TenantStats{
+			{Tenant: "tenant_a", Users: 10, NewUsers: 2, ActiveUsers: 5, Messages: 1500, PrevMessages: 1000, IndexFailures: 2, IndexSuccesses: 8},
+			{Tenant: "tenant_b", Users: 4, Messages: 10, PrevMessages: 50},
+			{Tenant: "tenant_c", Users: 1},
+		},
+		Connectors: []ConnectorStats{
+			{Tenant: "tenant_a", CCPairID: 1, Name: "Wiki", Source: "CONFLUENCE", Status: "ACTIVE", Failures: 2, LastError: "a | b"},
+			{Tenant: "tenant_a", CCPairID: 2, Name: "Drive", Source: "GOOGLE_DRIVE", Status: "ACTIVE", Successes: 8},
+		},
+	}
+}
+
+func TestBuildReports(t *testing.T) {
+	ops, err := LookupKind("weekly-ops")
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := ops.Build(testData())
+	if r.Title != "Weekly operations" || r.Context != "prod" {
+		t.Errorf("report = %+v", r)
+	}
+	if f := r.Summary[0]; f.Value != "2" || f.Change != "+0.0%" {
+		t.Errorf("active tenants = %+v", f)
+	}
+	if f := r.Summary[3]; f.Value != "1,510" || f.Change != "+43.8%" {
+		t.Errorf("user messages = %+v", f)
+	}
+	if active := r.Sections[0]; len(active.Rows) != 2 || active.Rows[0][0] != "tenant_a" {
+		t.Errorf("most active = %+v", active.Rows)
+	}
+	if failing := r.Sections[2]; len(failing.Rows) != 1 || failing.Rows[0][1] != "Wiki (#1)" || failing.Rows[0][4] != "never" {
+		t.Errorf("failing connectors = %+v", failing.Rows)
+	}
+
+	growth, _ := LookupKind("tenant-growth")
+	r = growth.Build(testData())
+	if len(r.Sections[0].Rows) != 1 || r.Sections[0].Rows[0][0] != "tenant_a" {
+		t.Errorf("growing = %+v", r.Sections[0].Rows)
+	}
+	if len(r.Sections[1].Rows) != 1 || r.Sections[1].Rows[0][0] != "tenant_b" || r.Sections[1].Rows[0][5] != "-80.0%" {
+		t.Errorf("declining = %+v", r.Sections[1].Rows)
+	}
+
+	health, _ := LookupKind("connector-health")
+	r = health.Build(testData())
+	if len(r.Sections[0].Rows) != 1 || r.Sections[1].Rows[0][0] != "confluence" {
+		t.Errorf("connector health = %+v", r.Sections)
+	}
+
+	if _, err := LookupKind("daily"); err == nil {
+		t.Error("expected an error for an unknown report")
+	}
+}
+
+func TestRender(t *testing.T) {
+	health, _ := LookupKind("connector-health")
+	r := health.Build(testData())
+
+	var md strings.Builder
+	if err := Render(&md, r, FormatMarkdown); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		"# Connector health\n\n_prod · 2026-10-05 to 2026-10-12 · generated 2026-10-12 09:00 UTC_",
+		"| Failing | 1 |  |",
+		`| tenant_a | Wiki (#1) | confluence | failing | never | 2 / 0 | a \| b |`,
+	} {
+		if !strings.Contains(md.String(), want) {
+			t.Errorf("missing %q in:\n%s", want, md.String())
+		}
+	}
+
+	var html strings.Builder
+	r.Sections[0].Rows[0][6] = "<script>"
+	if err := Render(&html, r, FormatHTML); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(html.String(), "<td>&lt;script&gt;</td>") || !strings.Contains(html.String(), "<h2>By source</h2>") {
+		t.Errorf("unexpected HTML:\n%s", html.String())
+	}
+
+	if err := Render(&html, r, "pdf"); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+	if s := SlackSummary(r); !strings.HasPrefix(s, "*Connector health* (prod") || !strings.Contains(s, "• Failing: *1*") {
+		t.Errorf("SlackSummary() = %q", s)
+	}
+}
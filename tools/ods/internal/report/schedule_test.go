@@ -0,0 +1,46 @@
+package report
+
+import (
+	"testing"
+	"time"
+)
+
+func TestScheduleNext(t *testing.T) {
+	// Wednesday.
+	from := time.Date(2026, 10, 14, 9, 30, 0, 0, time.UTC)
+	for _, tc := range []struct {
+		expr string
+		want time.Time
+	}{
+		{"*/15 * * * *", time.Date(2026, 10, 14, 9, 45, 0, 0, time.UTC)},
+		{"0 9 * * MON", time.Date(2026, 10, 19, 9, 0, 0, 0, time.UTC)},
+		{"0 9 * * 1-5", time.Date(2026, 10, 15, 9, 0, 0, 0, time.UTC)},
+		{"30 9 * * *", time.Date(2026, 10, 15, 9, 30, 0, 0, time.UTC)},
+		{"0 0 1 * *", time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)},
+		{"0 8 1 jan *", time.Date(2027, 1, 1, 8, 0, 0, 0, time.UTC)},
+		{"0 12 * * 7", time.Date(2026, 10, 18, 12, 0, 0, 0, time.UTC)},
+		// Both day fields restricted: either matches.
+		{"0 0 20 * 4", time.Date(2026, 10, 15, 0, 0, 0, 0, time.UTC)},
+		{"0 6,18 * * *", time.Date(2026, 10, 14, 18, 0, 0, 0, time.UTC)},
+	} {
+		s, err := ParseSchedule(tc.expr)
+		if err != nil {
+			t.Fatalf("ParseSchedule(%q) error: %v", tc.expr, err)
+		}
+		if got := s.Next(from); !got.Equal(tc.want) {
+			t.Errorf("Next(%q) = %v, want %v", tc.expr, got, tc.want)
+		}
+	}
+}
+
+func TestParseScheduleErrors(t *testing.T) {
+	for _, expr := range []string{"", "* * * *", "60 * * * *", "* * * * FUNDAY", "5-1 * * * *", "*/0 * * * *"} {
+		if _, err := ParseSchedule(expr); err == nil {
+			t.Errorf("ParseSchedule(%q) succeeded, want an error", expr)
+		}
+	}
+	s, _ := ParseSchedule("0 0 30 2 *")
+	if next := s.Next(time.Now()); !next.IsZero() {
+		t.Errorf("a schedule that never fires returned %v", next)
+	}
+}
@@ -0,0 +1,145 @@
+package report
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// failedStatuses are the index attempt statuses counted as failures.
+const failedStatuses = `'FAILED', 'COMPLETED_WITH_ERRORS'`
+
+// TenantStats is one tenant's activity in a period and the period before it.
+type TenantStats struct {
+	Tenant       string `json:"tenant"`
+	Users        int64  `json:"users"`
+	NewUsers     int64  `json:"new_users"`
+	ActiveUsers  int64  `json:"active_users"`
+	ChatSessions int64  `json:"chat_sessions"`
+	// Messages and PrevMessages count user messages in the period and in
+	// the period before it.
+	Messages       int64 `json:"messages"`
+	PrevMessages   int64 `json:"prev_messages"`
+	Documents      int64 `json:"documents"`
+	IndexSuccesses int64 `json:"index_successes"`
+	IndexFailures  int64 `json:"index_failures"`
+	DocsIndexed    int64 `json:"docs_indexed"`
+	// ErroredConnectors counts connectors in a repeated error state.
+	ErroredConnectors int64 `json:"errored_connectors"`
+}
+
+// ConnectorStats is one connector's indexing in a period.
+type ConnectorStats struct {
+	Tenant   string
+	CCPairID int
+	Name     string
+	Source   string
+	Status   string
+	// RepeatedErrors is set once the connector keeps failing.
+	RepeatedErrors bool
+	// LastSuccess is the last successful index; zero if there was none.
+	LastSuccess time.Time
+	Failures    int64
+	Successes   int64
+	LastError   string
+}
+
+func sqlTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339)
+}
+
+// TenantStatsSQL returns the TenantStats of each schema for p.
+func TenantStatsSQL(schemas []string, p Period) string {
+	parts := make([]string, len(schemas))
+	for i, s := range schemas {
+		parts[i] = fmt.Sprintf(`SELECT '%[1]s',
+  (SELECT count(*) FROM "%[1]s"."user"),
+  (SELECT count(*) FROM "%[1]s"."user" WHERE created_at >= '%[2]s'),
+  (SELECT count(DISTINCT s.user_id) FROM "%[1]s".chat_message m JOIN "%[1]s".chat_session s ON s.id = m.chat_session_id
+    WHERE m.time_sent >= '%[2]s' AND m.message_type = 'USER'),
+  (SELECT count(DISTINCT chat_session_id) FROM "%[1]s".chat_message WHERE time_sent >= '%[2]s' AND message_type = 'USER'),
+  (SELECT count(*) FROM "%[1]s".chat_message WHERE time_sent >= '%[2]s' AND message_type = 'USER'),
+  (SELECT count(*) FROM "%[1]s".chat_message WHERE time_sent >= '%[3]s' AND time_sent < '%[2]s' AND message_type = 'USER'),
+  (SELECT count(*) FROM "%[1]s".document),
+  (SELECT count(*) FROM "%[1]s".index_attempt WHERE time_created >= '%[2]s' AND status = 'SUCCESS'),
+  (SELECT count(*) FROM "%[1]s".index_attempt WHERE time_created >= '%[2]s' AND status IN (%[4]s)),
+  (SELECT COALESCE(sum(new_docs_indexed), 0) FROM "%[1]s".index_attempt WHERE time_created >= '%[2]s'),
+  (SELECT count(*) FROM "%[1]s".connector_credential_pair WHERE in_repeated_error_state)`,
+			s, sqlTime(p.From), sqlTime(p.Previous().From), failedStatuses)
+	}
+	return strings.Join(parts, "\nUNION ALL\n")
+}
+
+// ConnectorStatsSQL returns the ConnectorStats of every connector in each
+// schema for p.
+func ConnectorStatsSQL(schemas []string, p Period) string {
+	parts := make([]string, len(schemas))
+	for i, s := range schemas {
+		parts[i] = fmt.Sprintf(`SELECT '%[1]s', p.id, replace(p.name, E'\t', ' '), c.source, p.status, p.in_repeated_error_state,
+  COALESCE(to_char(p.last_successful_index_time AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS"Z"'), ''),
+  count(a.id) FILTER (WHERE a.status IN (%[3]s)),
+  count(a.id) FILTER (WHERE a.status = 'SUCCESS'),
+  COALESCE((SELECT left(regexp_replace(e.error_msg, '\s+', ' ', 'g'), 200) FROM "%[1]s".index_attempt e
+    WHERE e.connector_credential_pair_id = p.id AND e.error_msg IS NOT NULL ORDER BY e.time_created DESC LIMIT 1), '')
+FROM "%[1]s".connector_credential_pair p
+JOIN "%[1]s".connector c ON c.id = p.connector_id
+LEFT JOIN "%[1]s".index_attempt a ON a.connector_credential_pair_id = p.id AND a.time_created >= '%[2]s'
+GROUP BY p.id, c.id`, s, sqlTime(p.From), failedStatuses)
+	}
+	return strings.Join(parts, "\nUNION ALL\n")
+}
+
+// ParseTenantStats reads the output of TenantStatsSQL.
+func ParseTenantStats(lines []string) ([]TenantStats, error) {
+	stats := make([]TenantStats, 0, len(lines))
+	for _, line := range lines {
+		f := strings.Split(line, "\t")
+		if len(f) != 12 {
+			return nil, fmt.Errorf("unexpected tenant stats row: %q", line)
+		}
+		s := TenantStats{Tenant: f[0]}
+		fields := []*int64{
+			&s.Users, &s.NewUsers, &s.ActiveUsers, &s.ChatSessions, &s.Messages, &s.PrevMessages,
+			&s.Documents, &s.IndexSuccesses, &s.IndexFailures, &s.DocsIndexed, &s.ErroredConnectors,
+		}
+		for i, field := range fields {
+			v, err := strconv.ParseInt(f[i+1], 10, 64)
+			if err != nil {
+				return nil, fmt.Errorf("unexpected tenant stats row: %q", line)
+			}
+			*field = v
+		}
+		stats = append(stats, s)
+	}
+	return stats, nil
+}
+
+// ParseConnectorStats reads the output of ConnectorStatsSQL.
+func ParseConnectorStats(lines []string) ([]ConnectorStats, error) {
+	stats := make([]ConnectorStats, 0, len(lines))
+	for _, line := range lines {
+		f := strings.Split(line, "\t")
+		if len(f) != 10 {
+			return nil, fmt.Errorf("unexpected connector stats row: %q", line)
+		}
+		s := ConnectorStats{Tenant: f[0], Name: f[2], Source: f[3], Status: f[4], RepeatedErrors: f[5] == "t", LastError: f[9]}
+		var err error
+		if s.CCPairID, err = strconv.Atoi(f[1]); err != nil {
+			return nil, fmt.Errorf("unexpected connector stats row: %q", line)
+		}
+		if f[6] != "" {
+			if s.LastSuccess, err = time.Parse(time.RFC3339, f[6]); err != nil {
+				return nil, fmt.Errorf("unexpected connector stats row: %q", line)
+			}
+		}
+		if s.Failures, err = strconv.ParseInt(f[7], 10, 64); err != nil {
+			return nil, fmt.Errorf("unexpected connector stats row: %q", line)
+		}
+		if s.Successes, err = strconv.ParseInt(f[8], 10, 64); err != nil {
+			return nil, fmt.Errorf("unexpected connector stats row: %q", line)
+		}
+		stats = append(stats, s)
+	}
+	return stats, nil
+}
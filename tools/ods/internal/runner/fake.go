@@ -0,0 +1,108 @@
+package runner
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+)
+
+// Response is what a Fake command prints and returns.
+type Response struct {
+	Stdout string
+	Stderr string
+	Err    error
+	// Exit, when not 0 and Err is nil, fails the command with this exit
+	// code, as ExitCode reports it.
+	Exit int
+}
+
+// exitError is the error of a Fake command that exits non-zero.
+type exitError int
+
+func (e exitError) Error() string { return fmt.Sprintf("exit status %d", int(e)) }
+
+func (e exitError) ExitCode() int { return int(e) }
+
+// Call is a command a Fake was asked to run.
+type Call struct {
+	Cmd
+	// Input is everything the command read from Stdin.
+	Input string
+}
+
+type fakeResponse struct {
+	prefix string
+	resp   Response
+}
+
+// Fake is a Runner for tests. It records every command and answers it with
+// the response registered for the longest matching prefix of its command
+// line; a command nothing matches fails.
+type Fake struct {
+	mu        sync.Mutex
+	calls     []Call
+	responses []fakeResponse
+}
+
+// On answers commands whose command line (see Cmd.String) starts with
+// prefix with resp.
+func (f *Fake) On(prefix string, resp Response) *Fake {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.responses = append(f.responses, fakeResponse{prefix: prefix, resp: resp})
+	return f
+}
+
+// Run records c and plays back its response.
+func (f *Fake) Run(c Cmd) error {
+	call := Call{Cmd: c}
+	if c.Stdin != nil {
+		in, err := io.ReadAll(c.Stdin)
+		if err != nil {
+			return err
+		}
+		call.Input = string(in)
+	}
+
+	f.mu.Lock()
+	f.calls = append(f.calls, call)
+	line := c.String()
+	var match *fakeResponse
+	for i, r := range f.responses {
+		if strings.HasPrefix(line, r.prefix) && (match == nil || len(r.prefix) > len(match.prefix)) {
+			match = &f.responses[i]
+		}
+	}
+	f.mu.Unlock()
+
+	if match == nil {
+		return fmt.Errorf("runner.Fake: unexpected command: %s", line)
+	}
+	if c.Stdout != nil {
+		_, _ = io.WriteString(c.Stdout, match.resp.Stdout)
+	}
+	if c.Stderr != nil {
+		_, _ = io.WriteString(c.Stderr, match.resp.Stderr)
+	}
+	if match.resp.Err == nil && match.resp.Exit != 0 {
+		return exitError(match.resp.Exit)
+	}
+	return match.resp.Err
+}
+
+// Calls returns the commands run so far.
+func (f *Fake) Calls() []Call {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Call(nil), f.calls...)
+}
+
+// Lines returns the command lines run so far.
+func (f *Fake) Lines() []string {
+	var lines []string
+	for _, c := range f.Calls() {
+		lines = append(lines, c.String())
+	}
+	return lines
+}
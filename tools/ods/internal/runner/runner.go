@@ -0,0 +1,108 @@
+// Package runner runs the external tools ods drives (docker, kubectl, aws,
+// git, npm, ...) behind an interface, so code that builds their arguments
+// and parses their output can be unit tested against a Fake instead of the
+// real binaries.
+package runner
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"os/exec"
+	"strings"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/paths"
+)
+
+// Cmd is one run of a tool.
+type Cmd struct {
+	// Name is the tool, e.g. "kubectl"; it is looked up with
+	// paths.Executable.
+	Name string
+	Args []string
+	// Env is the full environment; nil inherits ods's own.
+	Env []string
+	// Dir is the working directory; "" is the current one.
+	Dir string
+
+	Stdin  io.Reader
+	Stdout io.Writer
+	Stderr io.Writer
+}
+
+// String returns the command line, for logs and errors.
+func (c Cmd) String() string {
+	return strings.Join(append([]string{c.Name}, c.Args...), " ")
+}
+
+// Runner runs commands to completion. The error of a command that ran but
+// failed wraps *exec.ExitError, as with os/exec.
+type Runner interface {
+	Run(c Cmd) error
+}
+
+// Exec runs commands as child processes.
+type Exec struct{}
+
+// Run runs c with os/exec.
+func (Exec) Run(c Cmd) error {
+	cmd := exec.Command(paths.Executable(c.Name), c.Args...)
+	cmd.Env = c.Env
+	cmd.Dir = c.Dir
+	cmd.Stdin = c.Stdin
+	cmd.Stdout = c.Stdout
+	cmd.Stderr = c.Stderr
+	return cmd.Run()
+}
+
+// Default is the runner used where none is injected.
+var Default Runner = Exec{}
+
+// Or returns r, or Default when r is nil, so a zero-value field can hold a
+// runner.
+func Or(r Runner) Runner {
+	if r == nil {
+		return Default
+	}
+	return r
+}
+
+// Output runs c and returns its stdout. On failure the error includes the
+// command's stderr.
+func Output(r Runner, c Cmd) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	c.Stdout = &stdout
+	c.Stderr = &stderr
+	if err := Or(r).Run(c); err != nil {
+		return nil, fmt.Errorf("%s %s failed: %w\n%s", c.Name, firstArg(c.Args), err, stderr.String())
+	}
+	return stdout.Bytes(), nil
+}
+
+// CombinedOutput runs c and returns its stdout and stderr interleaved,
+// whether or not it fails.
+func CombinedOutput(r Runner, c Cmd) ([]byte, error) {
+	var out bytes.Buffer
+	c.Stdout = &out
+	c.Stderr = &out
+	err := Or(r).Run(c)
+	return out.Bytes(), err
+}
+
+// ExitCode returns the exit code of a command that ran but failed, from the
+// error Run returned; ok is false for commands that could not be run.
+func ExitCode(err error) (code int, ok bool) {
+	var exit interface{ ExitCode() int }
+	if errors.As(err, &exit) {
+		return exit.ExitCode(), true
+	}
+	return 0, false
+}
+
+func firstArg(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+	return args[0]
+}
@@ -0,0 +1,62 @@
+package runner
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestFake(t *testing.T) {
+	f := (&Fake{}).
+		On("kubectl get", Response{Stdout: "generic"}).
+		On("kubectl get pods", Response{Stdout: "pods"}).
+		On("kubectl delete", Response{Stderr: "forbidden", Err: errors.New("exit status 1")})
+
+	out, err := Output(f, Cmd{Name: "kubectl", Args: []string{"get", "pods", "-o", "json"}})
+	if err != nil || string(out) != "pods" {
+		t.Errorf("expected the longest prefix to answer, got %q %v", out, err)
+	}
+	if out, _ := Output(f, Cmd{Name: "kubectl", Args: []string{"get", "jobs"}}); string(out) != "generic" {
+		t.Errorf("expected the shorter prefix to answer, got %q", out)
+	}
+	_, err = Output(f, Cmd{Name: "kubectl", Args: []string{"delete", "job", "x"}})
+	if err == nil || !strings.Contains(err.Error(), "kubectl delete failed") || !strings.Contains(err.Error(), "forbidden") {
+		t.Errorf("expected the failure with stderr, got %v", err)
+	}
+	if err := f.Run(Cmd{Name: "docker", Args: []string{"ps"}, Stdin: strings.NewReader("input")}); err == nil {
+		t.Error("expected an unmatched command to fail")
+	}
+
+	calls := f.Calls()
+	if len(calls) != 4 || calls[3].Input != "input" {
+		t.Fatalf("unexpected calls: %+v", calls)
+	}
+	want := []string{"kubectl get pods -o json", "kubectl get jobs", "kubectl delete job x", "docker ps"}
+	if got := f.Lines(); strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("Lines() = %q, want %q", got, want)
+	}
+}
+
+func TestExitCode(t *testing.T) {
+	f := (&Fake{}).
+		On("false", Response{Exit: 3}).
+		On("missing", Response{Err: errors.New("executable file not found")})
+
+	if code, ok := ExitCode(f.Run(Cmd{Name: "false"})); !ok || code != 3 {
+		t.Errorf("ExitCode() = %d, %v; want 3, true", code, ok)
+	}
+	if _, ok := ExitCode(f.Run(Cmd{Name: "missing"})); ok {
+		t.Error("expected no exit code for a command that could not run")
+	}
+	if _, ok := ExitCode(nil); ok {
+		t.Error("expected no exit code without an error")
+	}
+}
+
+func TestCombinedOutput(t *testing.T) {
+	f := (&Fake{}).On("nginx -t", Response{Stdout: "out\n", Stderr: "err\n", Err: errors.New("exit status 1")})
+	out, err := CombinedOutput(f, Cmd{Name: "nginx", Args: []string{"-t"}})
+	if err == nil || string(out) != "out\nerr\n" {
+		t.Errorf("CombinedOutput() = %q, %v", out, err)
+	}
+}
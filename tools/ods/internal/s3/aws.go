@@ -0,0 +1,19 @@
+package s3
+
+import (
+	"os"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/runner"
+)
+
+// Runner runs the AWS CLI; nil means runner.Default. Tests set a
+// runner.Fake.
+var Runner runner.Runner
+
+// runAWS runs aws with args in env (nil for ours). The CLI's transfer
+// progress ("Completed X/Y ... with N file(s) remaining") goes to stderr, not
+// stdout: callers like `ods audit ... --format=sarif` redirect our stdout into
+// a report file, and stray progress lines corrupt it.
+func runAWS(env []string, args ...string) error {
+	return runner.Or(Runner).Run(runner.Cmd{Name: "aws", Args: args, Env: env, Stdout: os.Stderr, Stderr: os.Stderr})
+}
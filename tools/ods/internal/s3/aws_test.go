@@ -0,0 +1,78 @@
+package s3
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/runner"
+)
+
+func useFake(t *testing.T) *runner.Fake {
+	t.Helper()
+	f := &runner.Fake{}
+	Runner = f
+	t.Cleanup(func() { Runner = nil })
+	return f
+}
+
+func TestAWSArgs(t *testing.T) {
+	f := useFake(t)
+	f.On("aws s3", runner.Response{})
+	dir := t.TempDir()
+
+	if err := PutFile("dist/SHA256SUMS", "s3://releases/ods/0.4.1/SHA256SUMS"); err != nil {
+		t.Fatal(err)
+	}
+	if err := SyncUp("screenshots", "s3://baselines/main", true); err != nil {
+		t.Fatal(err)
+	}
+	if err := SyncDown("s3://baselines/main", dir); err != nil {
+		t.Fatal(err)
+	}
+	if err := SyncBuckets([]string{"AWS_PROFILE=dp-b"}, "s3://a/tenant_1/", "s3://b/tenant_1/"); err != nil {
+		t.Fatal(err)
+	}
+
+	want := []string{
+		"aws s3 cp dist/SHA256SUMS s3://releases/ods/0.4.1/SHA256SUMS",
+		"aws s3 sync screenshots s3://baselines/main --delete",
+		"aws s3 sync s3://baselines/main " + dir,
+		"aws s3 sync s3://a/tenant_1/ s3://b/tenant_1/",
+	}
+	got := f.Lines()
+	if len(got) != len(want) {
+		t.Fatalf("commands = %q, want %q", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("command %d = %q, want %q", i, got[i], want[i])
+		}
+	}
+	calls := f.Calls()
+	for _, c := range calls {
+		if c.Stdout != os.Stderr {
+			t.Errorf("%s: transfer progress should go to stderr", c)
+		}
+	}
+	if env := calls[3].Env; !slices.Equal(env, []string{"AWS_PROFILE=dp-b"}) {
+		t.Errorf("SyncBuckets env = %q, want the caller's AWS environment", env)
+	}
+}
+
+func TestFetchWithAWSCLIRemovesPartialFile(t *testing.T) {
+	dest := filepath.Join(t.TempDir(), "snapshot.dump")
+	if err := os.WriteFile(dest, []byte("partial"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	useFake(t).On("aws s3 cp", runner.Response{Err: errors.New("exit status 1")})
+
+	if err := fetchWithAWSCLI("s3://snapshots/seeded.dump", dest); err == nil {
+		t.Fatal("expected the failed copy to return an error")
+	}
+	if _, err := os.Stat(dest); !os.IsNotExist(err) {
+		t.Error("expected the partial file to be removed")
+	}
+}
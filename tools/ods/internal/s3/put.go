@@ -2,9 +2,6 @@ package s3
 
 import (
 	"fmt"
-	"os"
-	"os/exec"
-
 	log "github.com/sirupsen/logrus"
 )
 
@@ -19,13 +16,7 @@ func PutFile(srcPath string, s3url string) error {
 	}
 
 	log.Infof("Uploading %s to %s ...", srcPath, s3url)
-	cmd := exec.Command("aws", "s3", "cp", srcPath, s3url)
-	// Keep transfer progress off stdout so it can't corrupt a report a caller is
-	// capturing from our stdout (see fetch.go).
-	cmd.Stdout = os.Stderr
-	cmd.Stderr = os.Stderr
-
-	if err := cmd.Run(); err != nil {
+	if err := runAWS(nil, "s3", "cp", srcPath, s3url); err != nil {
 		return fmt.Errorf("aws s3 cp failed: %w\n\nTo authenticate, run:\n  aws sso login\n\nOr configure AWS credentials with:\n  aws configure sso", err)
 	}
 
@@ -3,7 +3,6 @@ package s3
 import (
 	"fmt"
 	"os"
-	"os/exec"
 
 	log "github.com/sirupsen/logrus"
 )
@@ -16,13 +15,7 @@ func SyncDown(s3url string, destDir string) error {
 	}
 
 	log.Infof("Downloading from %s to %s ...", s3url, destDir)
-	cmd := exec.Command("aws", "s3", "sync", s3url, destDir)
-	// Keep transfer progress off stdout so it can't corrupt a report a caller is
-	// capturing from our stdout (see fetch.go).
-	cmd.Stdout = os.Stderr
-	cmd.Stderr = os.Stderr
-
-	if err := cmd.Run(); err != nil {
+	if err := runAWS(nil, "s3", "sync", s3url, destDir); err != nil {
 		return fmt.Errorf("aws s3 sync failed: %w\n\nTo authenticate, run:\n  aws sso login\n\nOr configure AWS credentials with:\n  aws configure sso", err)
 	}
 
@@ -39,13 +32,19 @@ func SyncUp(srcDir string, s3url string, delete bool) error {
 	}
 
 	log.Infof("Uploading from %s to %s ...", srcDir, s3url)
-	cmd := exec.Command("aws", args...)
-	// Keep transfer progress off stdout so it can't corrupt a report a caller is
-	// capturing from our stdout (see fetch.go).
-	cmd.Stdout = os.Stderr
-	cmd.Stderr = os.Stderr
+	if err := runAWS(nil, args...); err != nil {
+		return fmt.Errorf("aws s3 sync failed: %w\n\nTo authenticate, run:\n  aws sso login\n\nOr configure AWS credentials with:\n  aws configure sso", err)
+	}
+
+	return nil
+}
 
-	if err := cmd.Run(); err != nil {
+// SyncBuckets copies one S3 prefix to another without a local copy, running
+// aws in env (see kube.Cluster.AWSEnv; nil for the shell's credentials).
+// This is equivalent to: aws s3 sync <srcURL> <dstURL>
+func SyncBuckets(env []string, srcURL string, dstURL string) error {
+	log.Infof("Copying from %s to %s ...", srcURL, dstURL)
+	if err := runAWS(env, "s3", "sync", srcURL, dstURL); err != nil {
 		return fmt.Errorf("aws s3 sync failed: %w\n\nTo authenticate, run:\n  aws sso login\n\nOr configure AWS credentials with:\n  aws configure sso", err)
 	}
 
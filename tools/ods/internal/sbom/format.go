@@ -0,0 +1,228 @@
+package sbom
+
+import (
+	"crypto/rand"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// Formats are the SBOM formats Write supports.
+var Formats = []string{"cyclonedx", "spdx"}
+
+// Document describes the product the bill of materials is for.
+type Document struct {
+	// Name and Version are the product's, e.g. "onyx" and "v2.10.4".
+	Name    string
+	Version string
+	// Tool is the generating tool's version.
+	Tool    string
+	Created time.Time
+}
+
+// Write renders components as a CycloneDX 1.5 or SPDX 2.3 JSON document.
+func Write(w io.Writer, format string, doc Document, components []Component) error {
+	var v any
+	switch format {
+	case "cyclonedx":
+		v = cycloneDX(doc, components)
+	case "spdx":
+		v = spdx(doc, components)
+	default:
+		return fmt.Errorf("unknown SBOM format %q (want %s)", format, strings.Join(Formats, " or "))
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(v)
+}
+
+// Minimal CycloneDX 1.5 JSON types (https://cyclonedx.org/docs/1.5/json/),
+// covering only the fields ods emits.
+type cdxBOM struct {
+	BOMFormat    string         `json:"bomFormat"`
+	SpecVersion  string         `json:"specVersion"`
+	SerialNumber string         `json:"serialNumber"`
+	Version      int            `json:"version"`
+	Metadata     cdxMetadata    `json:"metadata"`
+	Components   []cdxComponent `json:"components"`
+}
+
+type cdxMetadata struct {
+	Timestamp string       `json:"timestamp"`
+	Tools     cdxTools     `json:"tools"`
+	Component cdxComponent `json:"component"`
+}
+
+type cdxTools struct {
+	Components []cdxComponent `json:"components"`
+}
+
+type cdxComponent struct {
+	Type       string        `json:"type"`
+	BOMRef     string        `json:"bom-ref,omitempty"`
+	Name       string        `json:"name"`
+	Version    string        `json:"version,omitempty"`
+	PURL       string        `json:"purl,omitempty"`
+	Licenses   []cdxLicense  `json:"licenses,omitempty"`
+	Properties []cdxProperty `json:"properties,omitempty"`
+}
+
+// cdxLicense is either a single license ID or an SPDX expression.
+type cdxLicense struct {
+	License    *cdxLicenseID `json:"license,omitempty"`
+	Expression string        `json:"expression,omitempty"`
+}
+
+type cdxLicenseID struct {
+	ID string `json:"id"`
+}
+
+type cdxProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+func cycloneDX(doc Document, components []Component) cdxBOM {
+	bom := cdxBOM{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  "1.5",
+		SerialNumber: "urn:uuid:" + newUUID(),
+		Version:      1,
+		Metadata: cdxMetadata{
+			Timestamp: doc.Created.UTC().Format(time.RFC3339),
+			Tools:     cdxTools{Components: []cdxComponent{{Type: "application", Name: "ods", Version: doc.Tool}}},
+			Component: cdxComponent{Type: "application", Name: doc.Name, Version: doc.Version},
+		},
+		Components: make([]cdxComponent, 0, len(components)),
+	}
+	for _, c := range components {
+		cc := cdxComponent{Type: "library", BOMRef: c.PURL, Name: c.Name, Version: c.Version, PURL: c.PURL}
+		if expr := LicenseExpression(c.Licenses); expr != "" {
+			if len(c.Licenses) == 1 && !strings.ContainsAny(expr, " ()") {
+				cc.Licenses = []cdxLicense{{License: &cdxLicenseID{ID: expr}}}
+			} else {
+				cc.Licenses = []cdxLicense{{Expression: expr}}
+			}
+		}
+		for _, s := range c.Sources {
+			cc.Properties = append(cc.Properties, cdxProperty{Name: "onyx:source", Value: s})
+		}
+		if v := c.Verdict(); v != VerdictNotChecked {
+			cc.Properties = append(cc.Properties, cdxProperty{Name: "onyx:license-policy", Value: string(v)})
+		}
+		bom.Components = append(bom.Components, cc)
+	}
+	return bom
+}
+
+// Minimal SPDX 2.3 JSON types (https://spdx.github.io/spdx-spec/v2.3/),
+// covering only the fields ods emits.
+type spdxDocument struct {
+	SPDXVersion       string             `json:"spdxVersion"`
+	DataLicense       string             `json:"dataLicense"`
+	SPDXID            string             `json:"SPDXID"`
+	Name              string             `json:"name"`
+	DocumentNamespace string             `json:"documentNamespace"`
+	CreationInfo      spdxCreationInfo   `json:"creationInfo"`
+	Packages          []spdxPackage      `json:"packages"`
+	Relationships     []spdxRelationship `json:"relationships"`
+}
+
+type spdxCreationInfo struct {
+	Created  string   `json:"created"`
+	Creators []string `json:"creators"`
+}
+
+type spdxPackage struct {
+	SPDXID           string            `json:"SPDXID"`
+	Name             string            `json:"name"`
+	VersionInfo      string            `json:"versionInfo,omitempty"`
+	DownloadLocation string            `json:"downloadLocation"`
+	FilesAnalyzed    bool              `json:"filesAnalyzed"`
+	LicenseConcluded string            `json:"licenseConcluded"`
+	LicenseDeclared  string            `json:"licenseDeclared"`
+	Comment          string            `json:"comment,omitempty"`
+	ExternalRefs     []spdxExternalRef `json:"externalRefs,omitempty"`
+}
+
+type spdxExternalRef struct {
+	ReferenceCategory string `json:"referenceCategory"`
+	ReferenceType     string `json:"referenceType"`
+	ReferenceLocator  string `json:"referenceLocator"`
+}
+
+type spdxRelationship struct {
+	SPDXElementID      string `json:"spdxElementId"`
+	RelationshipType   string `json:"relationshipType"`
+	RelatedSPDXElement string `json:"relatedSpdxElement"`
+}
+
+func spdx(doc Document, components []Component) spdxDocument {
+	const rootID = "SPDXRef-Product"
+	d := spdxDocument{
+		SPDXVersion:       "SPDX-2.3",
+		DataLicense:       "CC0-1.0",
+		SPDXID:            "SPDXRef-DOCUMENT",
+		Name:              doc.Name + "-" + doc.Version,
+		DocumentNamespace: fmt.Sprintf("https://onyx.app/spdx/%s-%s-%s", doc.Name, doc.Version, newUUID()),
+		CreationInfo: spdxCreationInfo{
+			Created:  doc.Created.UTC().Format(time.RFC3339),
+			Creators: []string{"Organization: Onyx", "Tool: ods-" + doc.Tool},
+		},
+		Packages: []spdxPackage{{
+			SPDXID:           rootID,
+			Name:             doc.Name,
+			VersionInfo:      doc.Version,
+			DownloadLocation: "NOASSERTION",
+			LicenseConcluded: "NOASSERTION",
+			LicenseDeclared:  "NOASSERTION",
+		}},
+		Relationships: []spdxRelationship{{SPDXElementID: "SPDXRef-DOCUMENT", RelationshipType: "DESCRIBES", RelatedSPDXElement: rootID}},
+	}
+	for i, c := range components {
+		id := fmt.Sprintf("SPDXRef-Package-%d", i+1)
+		declared := LicenseExpression(c.Licenses)
+		if declared == "" {
+			declared = "NOASSERTION"
+		}
+		d.Packages = append(d.Packages, spdxPackage{
+			SPDXID:           id,
+			Name:             c.Name,
+			VersionInfo:      c.Version,
+			DownloadLocation: "NOASSERTION",
+			LicenseConcluded: "NOASSERTION",
+			LicenseDeclared:  declared,
+			Comment:          "Found in " + strings.Join(c.Sources, ", "),
+			ExternalRefs:     []spdxExternalRef{{ReferenceCategory: "PACKAGE-MANAGER", ReferenceType: "purl", ReferenceLocator: c.PURL}},
+		})
+		d.Relationships = append(d.Relationships, spdxRelationship{SPDXElementID: rootID, RelationshipType: "DEPENDS_ON", RelatedSPDXElement: id})
+	}
+	return d
+}
+
+// LicenseExpression joins a package's declared licenses into one SPDX
+// expression, "" when there are none.
+func LicenseExpression(licenses []string) string {
+	if len(licenses) == 1 {
+		return licenses[0]
+	}
+	parts := make([]string, len(licenses))
+	for i, l := range licenses {
+		if strings.Contains(l, " ") {
+			l = "(" + l + ")"
+		}
+		parts[i] = l
+	}
+	return strings.Join(parts, " AND ")
+}
+
+// newUUID returns a random (version 4) UUID.
+func newUUID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:16])
+}
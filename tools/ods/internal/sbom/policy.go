@@ -0,0 +1,153 @@
+package sbom
+
+import (
+	"strings"
+)
+
+// Verdict is the license policy's verdict on a package.
+type Verdict string
+
+const (
+	VerdictAllowed Verdict = "allowed"
+	// VerdictReview licenses are fine for how Onyx uses most packages (e.g.
+	// LGPL, dynamically linked) but need a look before shipping.
+	VerdictReview Verdict = "review"
+	// VerdictIncompatible licenses cannot ship in Onyx.
+	VerdictIncompatible Verdict = "incompatible"
+	// VerdictUnknown means no license was found for the package.
+	VerdictUnknown    Verdict = "unknown"
+	VerdictNotChecked Verdict = "not-checked"
+)
+
+// rank orders verdicts from best to worst.
+func (v Verdict) rank() int {
+	switch v {
+	case VerdictAllowed:
+		return 0
+	case VerdictReview:
+		return 1
+	case VerdictUnknown:
+		return 2
+	case VerdictIncompatible:
+		return 3
+	default:
+		return -1
+	}
+}
+
+// allowedLicenses are the permissive licenses Onyx ships dependencies under.
+var allowedLicenses = map[string]bool{
+	"0BSD": true, "Apache-2.0": true, "Artistic-2.0": true, "BlueOak-1.0.0": true,
+	"BSD-1-Clause": true, "BSD-2-Clause": true, "BSD-3-Clause": true, "BSD-3-Clause-Clear": true,
+	"BSL-1.0": true, "CC-BY-3.0": true, "CC-BY-4.0": true, "CC0-1.0": true, "HPND": true,
+	"ISC": true, "MIT": true, "MIT-0": true, "MPL-2.0": true, "PSF-2.0": true,
+	"Python-2.0": true, "Unicode-3.0": true, "Unicode-DFS-2016": true, "Unlicense": true,
+	"WTFPL": true, "X11": true, "Zlib": true,
+}
+
+// incompatibleLicensePrefixes are strong-copyleft and non-commercial
+// license families.
+var incompatibleLicensePrefixes = []string{"GPL-", "AGPL-", "SSPL-", "BUSL-", "CC-BY-NC", "Commons-Clause", "EUPL-", "OSL-"}
+
+// Evaluate returns the policy's verdict on one SPDX license expression. Of
+// "A OR B" the better license may be chosen; "A AND B" needs both. An
+// identifier the policy does not list needs review.
+func Evaluate(expr string) Verdict {
+	expr = strings.TrimSpace(expr)
+	for strings.HasPrefix(expr, "(") && strings.HasSuffix(expr, ")") && balanced(expr[1:len(expr)-1]) {
+		expr = strings.TrimSpace(expr[1 : len(expr)-1])
+	}
+	if expr == "" || strings.EqualFold(expr, "NOASSERTION") || strings.EqualFold(expr, "non-standard") {
+		return VerdictUnknown
+	}
+	if parts := splitTopLevel(expr, " OR "); len(parts) > 1 {
+		best := VerdictIncompatible
+		for _, p := range parts {
+			if v := Evaluate(p); v.rank() < best.rank() {
+				best = v
+			}
+		}
+		return best
+	}
+	if parts := splitTopLevel(expr, " AND "); len(parts) > 1 {
+		return worst(parts)
+	}
+
+	id, _, _ := strings.Cut(expr, " WITH ")
+	id = strings.TrimSuffix(strings.TrimSpace(id), "+")
+	switch {
+	case allowedLicenses[id]:
+		return VerdictAllowed
+	case hasAnyPrefix(id, incompatibleLicensePrefixes):
+		return VerdictIncompatible
+	default:
+		// Weak copyleft (LGPL, EPL, CDDL, ...) and licenses the policy
+		// does not know alike need a person to look.
+		return VerdictReview
+	}
+}
+
+// EvaluateAll returns the verdict on a package declaring all of licenses.
+func EvaluateAll(licenses []string) Verdict {
+	if len(licenses) == 0 {
+		return VerdictUnknown
+	}
+	return worst(licenses)
+}
+
+func worst(exprs []string) Verdict {
+	w := VerdictAllowed
+	for _, e := range exprs {
+		if v := Evaluate(e); v.rank() > w.rank() {
+			w = v
+		}
+	}
+	return w
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(s, p) {
+			return true
+		}
+	}
+	return false
+}
+
+// splitTopLevel splits expr on sep outside parentheses.
+func splitTopLevel(expr, sep string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i := 0; i < len(expr); i++ {
+		switch expr[i] {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		}
+		if depth == 0 && strings.HasPrefix(expr[i:], sep) {
+			parts = append(parts, expr[start:i])
+			start = i + len(sep)
+			i += len(sep) - 1
+		}
+	}
+	return append(parts, expr[start:])
+}
+
+// balanced reports whether the parentheses in s pair up, so stripping the
+// ones around it keeps the expression's meaning.
+func balanced(s string) bool {
+	depth := 0
+	for _, r := range s {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+			if depth < 0 {
+				return false
+			}
+		}
+	}
+	return depth == 0
+}
@@ -0,0 +1,199 @@
+// Package sbom builds a software bill of materials for Onyx from its
+// lockfiles and built images, as CycloneDX or SPDX, and checks the licenses
+// in it against the license policy.
+package sbom
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/google/osv-scanner/v2/pkg/models"
+	"github.com/google/osv-scanner/v2/pkg/osvscanner"
+)
+
+// Component is one package in the bill of materials.
+type Component struct {
+	Name      string `json:"name"`
+	Version   string `json:"version"`
+	Ecosystem string `json:"ecosystem"`
+	PURL      string `json:"purl"`
+	// Licenses are the package's declared licenses, as SPDX expressions,
+	// from deps.dev. Empty when unknown.
+	Licenses []string `json:"licenses,omitempty"`
+	// Sources are the lockfiles and images the package was found in.
+	Sources []string `json:"sources"`
+}
+
+// Verdict returns the license policy's verdict on the component, or
+// VerdictNotChecked for OS packages.
+func (c Component) Verdict() Verdict {
+	if !languageEcosystems[c.Ecosystem] {
+		return VerdictNotChecked
+	}
+	return EvaluateAll(c.Licenses)
+}
+
+// languageEcosystems are the ecosystems deps.dev knows licenses for. OS
+// packages in images are listed but not checked: their licenses cover the
+// distribution, not Onyx.
+var languageEcosystems = map[string]bool{
+	"PyPI": true, "npm": true, "Go": true, "Maven": true, "crates.io": true,
+	"RubyGems": true, "NuGet": true, "Packagist": true,
+}
+
+// ScanLockfiles lists every package in the given lockfiles with its
+// licenses.
+func ScanLockfiles(lockfiles []string, sources map[string]string) ([]Component, error) {
+	if len(lockfiles) == 0 {
+		return nil, nil
+	}
+	res, err := osvscanner.DoScan(osvscanner.ScannerActions{
+		LockfilePaths:       lockfiles,
+		ShowAllPackages:     true,
+		ScanLicensesSummary: true,
+	})
+	if err := scanErr(err); err != nil {
+		return nil, err
+	}
+	return componentsFromResults(res, func(path string) string {
+		if s, ok := sources[path]; ok {
+			return s
+		}
+		return path
+	}), nil
+}
+
+// ScanImage lists every package in a container image, OS packages
+// included. ref may be a remote image, pulled with the ambient Docker
+// credentials.
+func ScanImage(ref string) ([]Component, error) {
+	res, err := osvscanner.DoContainerScan(osvscanner.ScannerActions{
+		Image:               ref,
+		ShowAllPackages:     true,
+		ScanLicensesSummary: true,
+	})
+	if err := scanErr(err); err != nil {
+		return nil, fmt.Errorf("%s: %w", ref, err)
+	}
+	return componentsFromResults(res, func(string) string { return ref }), nil
+}
+
+// scanErr drops the errors osv-scanner returns on a successful scan.
+func scanErr(err error) error {
+	if err == nil || errors.Is(err, osvscanner.ErrVulnerabilitiesFound) || errors.Is(err, osvscanner.ErrNoPackagesFound) {
+		return nil
+	}
+	return err
+}
+
+// componentsFromResults maps osv-scanner's results to Components, naming
+// each result's source with sourceName. Pure, so it can be unit tested.
+func componentsFromResults(res models.VulnerabilityResults, sourceName func(path string) string) []Component {
+	var components []Component
+	for _, src := range res.Results {
+		source := sourceName(src.Source.Path)
+		for _, pkg := range src.Packages {
+			c := Component{
+				Name:      pkg.Package.Name,
+				Version:   pkg.Package.Version,
+				Ecosystem: pkg.Package.Ecosystem,
+				Sources:   []string{source},
+			}
+			for _, l := range pkg.Licenses {
+				// deps.dev's placeholders are not SPDX and say nothing.
+				if s := string(l); s != "" && !strings.EqualFold(s, "UNKNOWN") && !strings.EqualFold(s, "non-standard") {
+					c.Licenses = append(c.Licenses, s)
+				}
+			}
+			c.PURL = PURL(c.Ecosystem, c.Name, c.Version)
+			components = append(components, c)
+		}
+	}
+	return components
+}
+
+// Merge combines the components found in several sources into one list,
+// one entry per package version, sorted by PURL.
+func Merge(lists ...[]Component) []Component {
+	byPURL := map[string]*Component{}
+	var order []string
+	for _, list := range lists {
+		for _, c := range list {
+			existing, ok := byPURL[c.PURL]
+			if !ok {
+				c := c
+				c.Sources = append([]string(nil), c.Sources...)
+				byPURL[c.PURL] = &c
+				order = append(order, c.PURL)
+				continue
+			}
+			existing.Sources = appendMissing(existing.Sources, c.Sources...)
+			existing.Licenses = appendMissing(existing.Licenses, c.Licenses...)
+		}
+	}
+	sort.Strings(order)
+	merged := make([]Component, 0, len(order))
+	for _, purl := range order {
+		merged = append(merged, *byPURL[purl])
+	}
+	return merged
+}
+
+func appendMissing(list []string, values ...string) []string {
+	for _, v := range values {
+		if !slices.Contains(list, v) {
+			list = append(list, v)
+		}
+	}
+	return list
+}
+
+// purlTypes maps OSV ecosystems (without their release suffix, e.g.
+// "Debian" of "Debian:12") to package URL types and namespaces.
+var purlTypes = map[string]string{
+	"PyPI":      "pypi",
+	"npm":       "npm",
+	"Go":        "golang",
+	"Maven":     "maven",
+	"crates.io": "cargo",
+	"RubyGems":  "gem",
+	"NuGet":     "nuget",
+	"Packagist": "composer",
+	"Debian":    "deb/debian",
+	"Ubuntu":    "deb/ubuntu",
+	"Alpine":    "apk/alpine",
+	"Wolfi":     "apk/wolfi",
+}
+
+// PURL returns the package URL (https://github.com/package-url/purl-spec)
+// of a package, e.g. pkg:npm/%40types/node@20.1.0.
+func PURL(ecosystem, name, version string) string {
+	base, _, _ := strings.Cut(ecosystem, ":")
+	typ, ok := purlTypes[base]
+	if !ok {
+		typ = "generic"
+	}
+	if typ == "pypi" {
+		// PyPI names are case-insensitive, with - _ . interchangeable.
+		name = strings.ReplaceAll(strings.ToLower(name), "_", "-")
+	}
+	segments := strings.Split(name, "/")
+	for i, s := range segments {
+		// PathEscape keeps "@", which separates the version in a PURL.
+		segments[i] = strings.ReplaceAll(url.PathEscape(s), "@", "%40")
+	}
+	if typ == "maven" {
+		name = strings.Replace(strings.Join(segments, "/"), ":", "/", 1)
+	} else {
+		name = strings.Join(segments, "/")
+	}
+	purl := "pkg:" + typ + "/" + name
+	if version != "" {
+		purl += "@" + url.PathEscape(version)
+	}
+	return purl
+}
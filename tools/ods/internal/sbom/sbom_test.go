@@ -0,0 +1,130 @@
+package sbom
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/google/osv-scanner/v2/pkg/models"
+)
+
+func TestPURL(t *testing.T) {
+	tests := []struct{ ecosystem, name, version, want string }{
+		{"PyPI", "Python_Multipart", "0.0.9", "pkg:pypi/python-multipart@0.0.9"},
+		{"npm", "@types/node", "20.1.0", "pkg:npm/%40types/node@20.1.0"},
+		{"Go", "github.com/spf13/cobra", "v1.8.1", "pkg:golang/github.com/spf13/cobra@v1.8.1"},
+		{"Debian:12", "libssl3", "3.0.11-1~deb12u2", "pkg:deb/debian/libssl3@3.0.11-1~deb12u2"},
+		{"Maven", "org.slf4j:slf4j-api", "2.0.9", "pkg:maven/org.slf4j/slf4j-api@2.0.9"},
+		{"Hackage", "aeson", "2.1", "pkg:generic/aeson@2.1"},
+	}
+	for _, tt := range tests {
+		if got := PURL(tt.ecosystem, tt.name, tt.version); got != tt.want {
+			t.Errorf("PURL(%q, %q, %q) = %q, want %q", tt.ecosystem, tt.name, tt.version, got, tt.want)
+		}
+	}
+}
+
+func TestEvaluate(t *testing.T) {
+	tests := map[string]Verdict{
+		"MIT":                                VerdictAllowed,
+		"Apache-2.0 WITH LLVM-exception":     VerdictAllowed,
+		"MIT OR GPL-3.0-only":                VerdictAllowed,
+		"(MIT AND GPL-2.0-or-later)":         VerdictIncompatible,
+		"AGPL-3.0-only":                      VerdictIncompatible,
+		"LGPL-2.1-or-later":                  VerdictReview,
+		"GPL-2.0+ OR LGPL-3.0":               VerdictReview,
+		"(BSD-3-Clause OR MIT) AND ISC":      VerdictAllowed,
+		"(MIT) AND (Apache-2.0 OR SSPL-1.0)": VerdictAllowed,
+		"SomeCustomLicense":                  VerdictReview,
+		"NOASSERTION":                        VerdictUnknown,
+	}
+	for expr, want := range tests {
+		if got := Evaluate(expr); got != want {
+			t.Errorf("Evaluate(%q) = %s, want %s", expr, got, want)
+		}
+	}
+	if got := EvaluateAll(nil); got != VerdictUnknown {
+		t.Errorf("EvaluateAll(nil) = %s", got)
+	}
+	if got := EvaluateAll([]string{"MIT", "GPL-3.0-only"}); got != VerdictIncompatible {
+		t.Errorf("EvaluateAll(MIT, GPL) = %s", got)
+	}
+}
+
+func TestComponentsFromResultsAndMerge(t *testing.T) {
+	res := models.VulnerabilityResults{Results: []models.PackageSource{{
+		Source: models.SourceInfo{Path: "/src/onyx/uv.lock"},
+		Packages: []models.PackageVulns{
+			{Package: models.PackageInfo{Name: "requests", Version: "2.32.3", Ecosystem: "PyPI"}, Licenses: []models.License{"Apache-2.0"}},
+			{Package: models.PackageInfo{Name: "mystery", Version: "1.0", Ecosystem: "PyPI"}, Licenses: []models.License{"UNKNOWN"}},
+		},
+	}}}
+	backend := componentsFromResults(res, func(string) string { return "uv.lock" })
+	if len(backend) != 2 || backend[0].PURL != "pkg:pypi/requests@2.32.3" || len(backend[1].Licenses) != 0 {
+		t.Fatalf("componentsFromResults() = %+v", backend)
+	}
+	if backend[1].Verdict() != VerdictUnknown {
+		t.Errorf("expected an unknown verdict, got %s", backend[1].Verdict())
+	}
+
+	image := []Component{
+		{Name: "requests", Version: "2.32.3", Ecosystem: "PyPI", PURL: "pkg:pypi/requests@2.32.3", Licenses: []string{"Apache-2.0"}, Sources: []string{"onyx-backend:v1"}},
+		{Name: "bash", Version: "5.2", Ecosystem: "Debian:12", PURL: "pkg:deb/debian/bash@5.2", Sources: []string{"onyx-backend:v1"}},
+	}
+	merged := Merge(backend, image)
+	if len(merged) != 3 {
+		t.Fatalf("Merge() = %+v", merged)
+	}
+	if merged[0].Name != "bash" || merged[0].Verdict() != VerdictNotChecked {
+		t.Errorf("expected the OS package first and unchecked, got %+v", merged[0])
+	}
+	if req := merged[2]; req.Name != "requests" || len(req.Sources) != 2 || len(req.Licenses) != 1 {
+		t.Errorf("expected requests merged across sources, got %+v", req)
+	}
+}
+
+func TestWrite(t *testing.T) {
+	doc := Document{Name: "onyx", Version: "v2.10.4", Tool: "0.5.0", Created: time.Date(2026, 10, 1, 0, 0, 0, 0, time.UTC)}
+	components := []Component{
+		{Name: "requests", Version: "2.32.3", Ecosystem: "PyPI", PURL: "pkg:pypi/requests@2.32.3", Licenses: []string{"Apache-2.0"}, Sources: []string{"uv.lock"}},
+		{Name: "dual", Version: "1.0.0", Ecosystem: "npm", PURL: "pkg:npm/dual@1.0.0", Licenses: []string{"MIT OR Apache-2.0", "ISC"}, Sources: []string{"web/bun.lock"}},
+	}
+
+	var buf bytes.Buffer
+	if err := Write(&buf, "cyclonedx", doc, components); err != nil {
+		t.Fatal(err)
+	}
+	var bom cdxBOM
+	if err := json.Unmarshal(buf.Bytes(), &bom); err != nil {
+		t.Fatal(err)
+	}
+	if bom.BOMFormat != "CycloneDX" || len(bom.Components) != 2 || bom.Metadata.Component.Version != "v2.10.4" {
+		t.Fatalf("unexpected CycloneDX document %+v", bom)
+	}
+	if l := bom.Components[0].Licenses; len(l) != 1 || l[0].License == nil || l[0].License.ID != "Apache-2.0" {
+		t.Errorf("expected a license ID, got %+v", l)
+	}
+	if l := bom.Components[1].Licenses; len(l) != 1 || l[0].Expression != "(MIT OR Apache-2.0) AND ISC" {
+		t.Errorf("expected a license expression, got %+v", l)
+	}
+
+	buf.Reset()
+	if err := Write(&buf, "spdx", doc, components); err != nil {
+		t.Fatal(err)
+	}
+	var d spdxDocument
+	if err := json.Unmarshal(buf.Bytes(), &d); err != nil {
+		t.Fatal(err)
+	}
+	if d.SPDXVersion != "SPDX-2.3" || len(d.Packages) != 3 || len(d.Relationships) != 3 {
+		t.Fatalf("unexpected SPDX document %+v", d)
+	}
+	if p := d.Packages[1]; p.LicenseDeclared != "Apache-2.0" || p.ExternalRefs[0].ReferenceLocator != "pkg:pypi/requests@2.32.3" {
+		t.Errorf("unexpected SPDX package %+v", p)
+	}
+
+	if err := Write(&buf, "xml", doc, components); err == nil {
+		t.Error("expected an error for an unknown format")
+	}
+}
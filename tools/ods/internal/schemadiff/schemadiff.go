@@ -0,0 +1,148 @@
+// Package schemadiff compares the tables, columns and indexes of two Postgres
+// schemas, e.g. the public schema of two environments or a tenant schema
+// against one at the migration head.
+package schemadiff
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// validSchema matches the schema names Onyx creates (public, tenant_<id>).
+var validSchema = regexp.MustCompile(`^[a-zA-Z0-9_\-]+$`)
+
+// ValidSchema reports whether name is safe to interpolate into SQL.
+func ValidSchema(name string) bool {
+	return validSchema.MatchString(name)
+}
+
+// SnapshotSQL lists schema's objects as kind, table, name and definition.
+// Callers must check ValidSchema first.
+func SnapshotSQL(schema string) string {
+	return fmt.Sprintf(`SELECT 'table', c.relname, '', ''
+FROM pg_class c JOIN pg_namespace n ON n.oid = c.relnamespace
+WHERE n.nspname = '%[1]s' AND c.relkind IN ('r', 'p')
+UNION ALL
+SELECT 'column', c.relname, a.attname,
+       format_type(a.atttypid, a.atttypmod) || CASE WHEN a.attnotnull THEN ' not null' ELSE '' END
+FROM pg_attribute a
+JOIN pg_class c ON c.oid = a.attrelid
+JOIN pg_namespace n ON n.oid = c.relnamespace
+WHERE n.nspname = '%[1]s' AND c.relkind IN ('r', 'p') AND a.attnum > 0 AND NOT a.attisdropped
+UNION ALL
+SELECT 'index', tablename, indexname, indexdef FROM pg_indexes WHERE schemaname = '%[1]s'`, schema)
+}
+
+// RevisionSQL returns schema's Alembic revision(s). Only run it when the
+// snapshot contains an alembic_version table.
+func RevisionSQL(schema string) string {
+	return fmt.Sprintf(`SELECT string_agg(version_num, ',' ORDER BY version_num) FROM "%s".alembic_version`, schema)
+}
+
+// Object is a table, column or index.
+type Object struct {
+	Kind  string // "table", "column" or "index"
+	Table string
+	Name  string // column or index name; empty for tables
+}
+
+func (o Object) String() string {
+	if o.Name == "" {
+		return o.Table
+	}
+	return o.Table + "." + o.Name
+}
+
+// Snapshot maps each object in a schema to its definition: the column type
+// and nullability, or the index definition.
+type Snapshot struct {
+	Objects map[Object]string
+}
+
+// HasTable reports whether the snapshot contains table.
+func (s Snapshot) HasTable(table string) bool {
+	_, ok := s.Objects[Object{Kind: "table", Table: table}]
+	return ok
+}
+
+// Parse reads SnapshotSQL output. Schema qualifiers are stripped from
+// definitions so snapshots of differently named schemas compare equal.
+func Parse(schema string, lines []string) (Snapshot, error) {
+	s := Snapshot{Objects: map[Object]string{}}
+	for _, line := range lines {
+		fields := strings.Split(line, "\t")
+		for len(fields) < 4 {
+			fields = append(fields, "")
+		}
+		kind := fields[0]
+		if kind != "table" && kind != "column" && kind != "index" {
+			return Snapshot{}, fmt.Errorf("unexpected schema row %q", line)
+		}
+		def := strings.ReplaceAll(fields[3], `"`+schema+`".`, "")
+		def = strings.ReplaceAll(def, schema+".", "")
+		s.Objects[Object{Kind: kind, Table: fields[1], Name: fields[2]}] = def
+	}
+	return s, nil
+}
+
+// Change is one difference between two snapshots. A or B is empty when the
+// object only exists on the other side.
+type Change struct {
+	Object
+	A, B string
+}
+
+// Status describes the change from the point of view of the two sides.
+func (c Change) Status(a, b string) string {
+	switch {
+	case c.A == "missing":
+		return "missing in " + a
+	case c.B == "missing":
+		return "missing in " + b
+	default:
+		return "differs"
+	}
+}
+
+// Diff returns the differences between a and b, sorted by table, kind and
+// name. Columns and indexes of a table missing on one side are folded into
+// the table's own change.
+func Diff(a, b Snapshot) []Change {
+	var changes []Change
+	for obj, def := range a.Objects {
+		other, ok := b.Objects[obj]
+		switch {
+		case !ok && (obj.Kind == "table" || b.HasTable(obj.Table)):
+			changes = append(changes, Change{Object: obj, A: present(def), B: "missing"})
+		case ok && other != def:
+			changes = append(changes, Change{Object: obj, A: def, B: other})
+		}
+	}
+	for obj, def := range b.Objects {
+		if _, ok := a.Objects[obj]; !ok && (obj.Kind == "table" || a.HasTable(obj.Table)) {
+			changes = append(changes, Change{Object: obj, A: "missing", B: present(def)})
+		}
+	}
+
+	order := map[string]int{"table": 0, "column": 1, "index": 2}
+	sort.Slice(changes, func(i, j int) bool {
+		x, y := changes[i], changes[j]
+		if x.Table != y.Table {
+			return x.Table < y.Table
+		}
+		if x.Kind != y.Kind {
+			return order[x.Kind] < order[y.Kind]
+		}
+		return x.Name < y.Name
+	})
+	return changes
+}
+
+func present(def string) string {
+	if def == "" {
+		return "present"
+	}
+	return def
+}
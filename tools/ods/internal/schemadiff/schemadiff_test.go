@@ -0,0 +1,81 @@
+package schemadiff
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestValidSchema(t *testing.T) {
+	for name, want := range map[string]bool{
+		"public":          true,
+		"tenant_abcd-123": true,
+		"public; drop":    false,
+		`x"y`:             false,
+		"":                false,
+	} {
+		if got := ValidSchema(name); got != want {
+			t.Errorf("ValidSchema(%q) = %v, want %v", name, got, want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	s, err := Parse("tenant_a", []string{
+		"table\tdocument",
+		"column\tdocument\tid\tcharacter varying not null",
+		"index\tdocument\tix_document_link\tCREATE INDEX ix_document_link ON tenant_a.document USING btree (link)",
+	})
+	if err != nil {
+		t.Fatalf("Parse() error: %v", err)
+	}
+	want := map[Object]string{
+		{Kind: "table", Table: "document"}:                           "",
+		{Kind: "column", Table: "document", Name: "id"}:              "character varying not null",
+		{Kind: "index", Table: "document", Name: "ix_document_link"}: "CREATE INDEX ix_document_link ON document USING btree (link)",
+	}
+	if !reflect.DeepEqual(s.Objects, want) {
+		t.Errorf("Parse() = %v, want %v", s.Objects, want)
+	}
+
+	if _, err := Parse("public", []string{"Connecting to db"}); err == nil {
+		t.Error("expected an error for an unexpected row")
+	}
+}
+
+func TestDiff(t *testing.T) {
+	a := Snapshot{Objects: map[Object]string{
+		{Kind: "table", Table: "document"}:                   "",
+		{Kind: "column", Table: "document", Name: "id"}:      "integer not null",
+		{Kind: "column", Table: "document", Name: "boost"}:   "integer",
+		{Kind: "table", Table: "persona"}:                    "",
+		{Kind: "column", Table: "persona", Name: "name"}:     "text",
+		{Kind: "index", Table: "document", Name: "ix_boost"}: "CREATE INDEX ix_boost ON document USING btree (boost)",
+	}}
+	b := Snapshot{Objects: map[Object]string{
+		{Kind: "table", Table: "document"}:                "",
+		{Kind: "column", Table: "document", Name: "id"}:   "bigint not null",
+		{Kind: "column", Table: "document", Name: "link"}: "text",
+		{Kind: "table", Table: "user"}:                    "",
+		{Kind: "column", Table: "user", Name: "email"}:    "text",
+	}}
+
+	got := Diff(a, b)
+	want := []Change{
+		{Object: Object{Kind: "column", Table: "document", Name: "boost"}, A: "integer", B: "missing"},
+		{Object: Object{Kind: "column", Table: "document", Name: "id"}, A: "integer not null", B: "bigint not null"},
+		{Object: Object{Kind: "column", Table: "document", Name: "link"}, A: "missing", B: "text"},
+		{Object: Object{Kind: "index", Table: "document", Name: "ix_boost"}, A: "CREATE INDEX ix_boost ON document USING btree (boost)", B: "missing"},
+		{Object: Object{Kind: "table", Table: "persona"}, A: "present", B: "missing"},
+		{Object: Object{Kind: "table", Table: "user"}, A: "missing", B: "present"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff() =\n%v\nwant\n%v", got, want)
+	}
+
+	if got[0].Status("prod", "staging") != "missing in staging" || got[1].Status("prod", "staging") != "differs" {
+		t.Errorf("unexpected statuses %q, %q", got[0].Status("prod", "staging"), got[1].Status("prod", "staging"))
+	}
+	if len(Diff(a, a)) != 0 {
+		t.Error("expected no changes between identical snapshots")
+	}
+}
@@ -0,0 +1,289 @@
+// Package serve exposes ods lookups (whois, health, stats and log search)
+// over a local HTTP API, so dashboards and chatops bots can call them
+// without shelling out to ods.
+//
+// Every endpoint except /healthz requires "Authorization: Bearer <token>".
+// Responses are JSON; errors are {"error": "..."}.
+package serve
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/health"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/kube"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/paths"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/report"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/whois"
+)
+
+// Limits on what one request may ask for.
+const (
+	DefaultHealthTimeout = 5 * time.Second
+	MaxHealthTimeout     = 30 * time.Second
+	DefaultLogSince      = time.Hour
+	MaxLogSince          = 24 * time.Hour
+	DefaultLogLimit      = 200
+	MaxLogLimit          = 2000
+	MaxStatsPeriod       = 366 * 24 * time.Hour
+)
+
+// Backend answers the API's requests, typically against a cluster.
+type Backend interface {
+	Whois(query string) (*whois.Result, error)
+	Health(timeout time.Duration) ([]health.Result, error)
+	Stats(kind report.Kind, p report.Period) (*report.Report, error)
+	// SearchLogs returns the log lines, newer than since, of the pods whose
+	// name contains any of pods that contain text.
+	SearchLogs(pods []string, text string, since time.Duration, limit int) ([]kube.LogMatch, error)
+}
+
+// Server is the API's HTTP handler.
+type Server struct {
+	token   string
+	backend Backend
+	mux     *http.ServeMux
+	now     func() time.Time
+}
+
+// New returns a Server that accepts requests bearing token.
+func New(token string, backend Backend) (*Server, error) {
+	if len(token) < 16 {
+		return nil, fmt.Errorf("the API token must be at least 16 characters")
+	}
+	s := &Server{token: token, backend: backend, mux: http.NewServeMux(), now: time.Now}
+	s.mux.HandleFunc("GET /healthz", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusOK, map[string]string{"status": "ok"})
+	})
+	s.mux.Handle("GET /v1/whois", s.authenticated(s.whois))
+	s.mux.Handle("GET /v1/health", s.authenticated(s.health))
+	s.mux.Handle("GET /v1/stats/{report}", s.authenticated(s.stats))
+	s.mux.Handle("GET /v1/logs/search", s.authenticated(s.searchLogs))
+	return s, nil
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	s.mux.ServeHTTP(w, r)
+}
+
+func (s *Server) authenticated(h http.HandlerFunc) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, ok := strings.CutPrefix(r.Header.Get("Authorization"), "Bearer ")
+		if !ok || subtle.ConstantTimeCompare([]byte(got), []byte(s.token)) != 1 {
+			w.Header().Set("WWW-Authenticate", `Bearer realm="ods"`)
+			writeError(w, http.StatusUnauthorized, "missing or invalid bearer token")
+			return
+		}
+		h(w, r)
+	})
+}
+
+func (s *Server) whois(w http.ResponseWriter, r *http.Request) {
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	if query == "" {
+		writeError(w, http.StatusBadRequest, "q is required: an email fragment or tenant ID")
+		return
+	}
+	if whois.KindOf(query) == whois.KindTenant {
+		if err := whois.ValidateTenantID(query); err != nil {
+			writeError(w, http.StatusBadRequest, err.Error())
+			return
+		}
+	}
+	result, err := s.backend.Whois(query)
+	if err != nil {
+		s.fail(w, r, err)
+		return
+	}
+	if result.Kind == whois.KindTenant {
+		writeJSON(w, http.StatusOK, map[string]any{"kind": result.Kind, "tenant_id": query, "admins": nonNil(result.Rows)})
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"kind": result.Kind, "matches": nonNil(result.Matches())})
+}
+
+func (s *Server) health(w http.ResponseWriter, r *http.Request) {
+	timeout, err := durationParam(r, "timeout", DefaultHealthTimeout, MaxHealthTimeout)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	results, err := s.backend.Health(timeout)
+	if err != nil {
+		s.fail(w, r, err)
+		return
+	}
+	failed := health.Failed(results)
+	writeJSON(w, http.StatusOK, map[string]any{"ok": failed == 0, "failed": failed, "results": nonNil(results)})
+}
+
+func (s *Server) stats(w http.ResponseWriter, r *http.Request) {
+	kind, err := report.LookupKind(r.PathValue("report"))
+	if err != nil {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	format := r.URL.Query().Get("format")
+	if format != "" && format != "json" && format != report.FormatMarkdown && format != report.FormatHTML {
+		writeError(w, http.StatusBadRequest, fmt.Sprintf("format must be json, %s or %s", report.FormatMarkdown, report.FormatHTML))
+		return
+	}
+	d, err := durationParam(r, "since", kind.DefaultPeriod, MaxStatsPeriod)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	rep, err := s.backend.Stats(kind, report.Last(d, s.now()))
+	if err != nil {
+		s.fail(w, r, err)
+		return
+	}
+	switch format {
+	case report.FormatMarkdown:
+		w.Header().Set("Content-Type", "text/markdown; charset=utf-8")
+		_ = report.Render(w, rep, format)
+	case report.FormatHTML:
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		_ = report.Render(w, rep, format)
+	default:
+		writeJSON(w, http.StatusOK, rep)
+	}
+}
+
+func (s *Server) searchLogs(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	pods := q["pod"]
+	text := q.Get("q")
+	if len(pods) == 0 || text == "" {
+		writeError(w, http.StatusBadRequest, "pod (repeatable pod name substring) and q are required")
+		return
+	}
+	since, err := durationParam(r, "since", DefaultLogSince, MaxLogSince)
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	limit := DefaultLogLimit
+	if v := q.Get("limit"); v != "" {
+		if limit, err = strconv.Atoi(v); err != nil || limit <= 0 || limit > MaxLogLimit {
+			writeError(w, http.StatusBadRequest, fmt.Sprintf("limit must be between 1 and %d", MaxLogLimit))
+			return
+		}
+	}
+	matches, err := s.backend.SearchLogs(pods, text, since, limit)
+	if err != nil {
+		s.fail(w, r, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, map[string]any{"matches": nonNil(matches)})
+}
+
+// NotFoundError reports that what a request asked about does not exist.
+type NotFoundError struct{ Message string }
+
+func (e *NotFoundError) Error() string { return e.Message }
+
+// ForbiddenError reports that the backend may no longer answer, e.g.
+// because the production access session it ran under has ended.
+type ForbiddenError struct{ Message string }
+
+func (e *ForbiddenError) Error() string { return e.Message }
+
+// fail reports a backend error: a 404 for a NotFoundError, a 403 for a
+// ForbiddenError, and a 502 for anything else, since those come from the
+// cluster rather than the request.
+func (s *Server) fail(w http.ResponseWriter, r *http.Request, err error) {
+	var notFound *NotFoundError
+	if errors.As(err, &notFound) {
+		writeError(w, http.StatusNotFound, err.Error())
+		return
+	}
+	var forbidden *ForbiddenError
+	if errors.As(err, &forbidden) {
+		writeError(w, http.StatusForbidden, err.Error())
+		return
+	}
+	log.Errorf("%s %s: %v", r.Method, r.URL.Path, err)
+	writeError(w, http.StatusBadGateway, err.Error())
+}
+
+// durationParam parses the query parameter name as a lookback such as "10m"
+// or "7d", returning def when it is absent.
+func durationParam(r *http.Request, name string, def, limit time.Duration) (time.Duration, error) {
+	v := r.URL.Query().Get(name)
+	if v == "" {
+		return def, nil
+	}
+	d, err := report.ParseLookback(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s: %v", name, err)
+	}
+	if d > limit {
+		return 0, fmt.Errorf("%s must be at most %s", name, limit)
+	}
+	return d, nil
+}
+
+func nonNil[T any](s []T) []T {
+	if s == nil {
+		return []T{}
+	}
+	return s
+}
+
+func writeJSON(w http.ResponseWriter, status int, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	_ = json.NewEncoder(w).Encode(v)
+}
+
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
+
+// DefaultTokenPath is where the API token is kept unless another file is
+// given.
+func DefaultTokenPath() string {
+	return filepath.Join(paths.DataDir(), "serve-token")
+}
+
+// LoadOrCreateToken reads the API token from path, generating a random one
+// readable only by the current user if the file does not exist.
+func LoadOrCreateToken(path string) (token string, created bool, err error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		token = strings.TrimSpace(string(data))
+		if token == "" {
+			return "", false, fmt.Errorf("%s is empty", path)
+		}
+		return token, false, nil
+	}
+	if !os.IsNotExist(err) {
+		return "", false, err
+	}
+
+	buf := make([]byte, 32)
+	if _, err := rand.Read(buf); err != nil {
+		return "", false, err
+	}
+	token = hex.EncodeToString(buf)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return "", false, err
+	}
+	if err := os.WriteFile(path, []byte(token+"\n"), 0600); err != nil {
+		return "", false, err
+	}
+	return token, true, nil
+}
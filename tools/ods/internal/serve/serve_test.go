@@ -0,0 +1,166 @@
+package serve
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/health"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/kube"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/report"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/whois"
+)
+
+const testToken = "0123456789abcdef0123"
+
+type fakeBackend struct {
+	since time.Duration
+	limit int
+	err   error
+}
+
+func (b *fakeBackend) Whois(query string) (*whois.Result, error) {
+	if whois.KindOf(query) == whois.KindTenant {
+		return &whois.Result{Kind: whois.KindTenant, Rows: []string{"admin@acme.com"}}, b.err
+	}
+	return &whois.Result{Kind: whois.KindEmail, Rows: []string{"chris@acme.com\ttenant_a\tt"}}, b.err
+}
+
+func (b *fakeBackend) Health(timeout time.Duration) ([]health.Result, error) {
+	return []health.Result{{Name: "postgres", OK: true}, {Name: "redis", Detail: "timeout"}}, b.err
+}
+
+func (b *fakeBackend) Stats(kind report.Kind, p report.Period) (*report.Report, error) {
+	return kind.Build(report.Data{Context: "prod", Period: p, Generated: p.To}), b.err
+}
+
+func (b *fakeBackend) SearchLogs(pods []string, text string, since time.Duration, limit int) ([]kube.LogMatch, error) {
+	b.since, b.limit = since, limit
+	if b.err != nil {
+		return nil, b.err
+	}
+	return []kube.LogMatch{{Pod: pods[0] + "-0", Line: "ERROR " + text}}, nil
+}
+
+func get(t *testing.T, s *Server, path, token string) (*httptest.ResponseRecorder, map[string]any) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	rec := httptest.NewRecorder()
+	s.ServeHTTP(rec, req)
+	var body map[string]any
+	if strings.HasPrefix(rec.Header().Get("Content-Type"), "application/json") {
+		if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+			t.Fatalf("%s: invalid JSON %q: %v", path, rec.Body.String(), err)
+		}
+	}
+	return rec, body
+}
+
+func TestAuthentication(t *testing.T) {
+	if _, err := New("short", &fakeBackend{}); err == nil {
+		t.Error("expected an error for a short token")
+	}
+	s, err := New(testToken, &fakeBackend{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, token := range []string{"", "wrong-token-0123456789"} {
+		if rec, _ := get(t, s, "/v1/health", token); rec.Code != http.StatusUnauthorized {
+			t.Errorf("token %q: status %d, want 401", token, rec.Code)
+		}
+	}
+	if rec, _ := get(t, s, "/healthz", ""); rec.Code != http.StatusOK {
+		t.Errorf("/healthz status %d, want 200", rec.Code)
+	}
+}
+
+func TestEndpoints(t *testing.T) {
+	backend := &fakeBackend{}
+	s, _ := New(testToken, backend)
+	s.now = func() time.Time { return time.Date(2026, 10, 12, 9, 0, 0, 0, time.UTC) }
+
+	rec, body := get(t, s, "/v1/whois?q=chris", testToken)
+	matches, _ := body["matches"].([]any)
+	if rec.Code != http.StatusOK || len(matches) != 1 || matches[0].(map[string]any)["active"] != true {
+		t.Errorf("whois email: %d %v", rec.Code, body)
+	}
+	rec, body = get(t, s, "/v1/whois?q=tenant_a", testToken)
+	if rec.Code != http.StatusOK || body["tenant_id"] != "tenant_a" || len(body["admins"].([]any)) != 1 {
+		t.Errorf("whois tenant: %d %v", rec.Code, body)
+	}
+	if rec, _ = get(t, s, "/v1/whois?q=tenant_a;drop", testToken); rec.Code != http.StatusBadRequest {
+		t.Errorf("unsafe tenant ID: status %d, want 400", rec.Code)
+	}
+
+	rec, body = get(t, s, "/v1/health?timeout=2s", testToken)
+	if rec.Code != http.StatusOK || body["ok"] != false || body["failed"] != 1.0 {
+		t.Errorf("health: %d %v", rec.Code, body)
+	}
+	if rec, _ = get(t, s, "/v1/health?timeout=1h", testToken); rec.Code != http.StatusBadRequest {
+		t.Errorf("health with a long timeout: status %d, want 400", rec.Code)
+	}
+
+	rec, body = get(t, s, "/v1/stats/weekly-ops?since=14d", testToken)
+	period, _ := body["period"].(map[string]any)
+	if rec.Code != http.StatusOK || body["title"] != "Weekly operations" || period["from"] != "2026-09-28T09:00:00Z" {
+		t.Errorf("stats: %d %v", rec.Code, body)
+	}
+	rec, _ = get(t, s, "/v1/stats/connector-health?format=md", testToken)
+	if rec.Code != http.StatusOK || !strings.HasPrefix(rec.Body.String(), "# Connector health") {
+		t.Errorf("stats markdown: %d %q", rec.Code, rec.Body.String())
+	}
+	if rec, _ = get(t, s, "/v1/stats/daily", testToken); rec.Code != http.StatusNotFound {
+		t.Errorf("unknown report: status %d, want 404", rec.Code)
+	}
+
+	rec, body = get(t, s, "/v1/logs/search?pod=api-server&q=timeout&since=30m", testToken)
+	if rec.Code != http.StatusOK || len(body["matches"].([]any)) != 1 || backend.since != 30*time.Minute || backend.limit != DefaultLogLimit {
+		t.Errorf("logs search: %d %v (since %s, limit %d)", rec.Code, body, backend.since, backend.limit)
+	}
+	if rec, _ = get(t, s, "/v1/logs/search?q=timeout", testToken); rec.Code != http.StatusBadRequest {
+		t.Errorf("logs search without pod: status %d, want 400", rec.Code)
+	}
+	if rec, _ = get(t, s, "/v1/logs/search?pod=api&q=x&limit=0", testToken); rec.Code != http.StatusBadRequest {
+		t.Errorf("logs search with limit 0: status %d, want 400", rec.Code)
+	}
+}
+
+func TestBackendErrors(t *testing.T) {
+	backend := &fakeBackend{err: errors.New("kubectl exec failed")}
+	s, _ := New(testToken, backend)
+	if rec, body := get(t, s, "/v1/logs/search?pod=api&q=x", testToken); rec.Code != http.StatusBadGateway || body["error"] != "kubectl exec failed" {
+		t.Errorf("backend error: %d %v", rec.Code, body)
+	}
+	backend.err = &NotFoundError{Message: "no pods match api"}
+	if rec, _ := get(t, s, "/v1/logs/search?pod=api&q=x", testToken); rec.Code != http.StatusNotFound {
+		t.Errorf("not found error: status %d, want 404", rec.Code)
+	}
+	backend.err = &ForbiddenError{Message: "the production access session for prod has ended"}
+	if rec, _ := get(t, s, "/v1/logs/search?pod=api&q=x", testToken); rec.Code != http.StatusForbidden {
+		t.Errorf("forbidden error: status %d, want 403", rec.Code)
+	}
+}
+
+func TestLoadOrCreateToken(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ods", "serve-token")
+	token, created, err := LoadOrCreateToken(path)
+	if err != nil || !created || len(token) != 64 {
+		t.Fatalf("LoadOrCreateToken() = %q, %v, %v", token, created, err)
+	}
+	if info, err := os.Stat(path); err != nil || info.Mode().Perm() != 0600 {
+		t.Errorf("token file mode = %v, %v", info.Mode().Perm(), err)
+	}
+	again, created, err := LoadOrCreateToken(path)
+	if err != nil || created || again != token {
+		t.Errorf("second LoadOrCreateToken() = %q, %v, %v", again, created, err)
+	}
+}
@@ -0,0 +1,160 @@
+package slack
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeSlack serves apps.connections.open, a Socket Mode WebSocket that
+// delivers one slash command, and that command's response URL.
+type fakeSlack struct {
+	t       *testing.T
+	server  *httptest.Server
+	acks    chan map[string]any
+	replies chan map[string]any
+}
+
+func newFakeSlack(t *testing.T) *fakeSlack {
+	f := &fakeSlack{t: t, acks: make(chan map[string]any, 4), replies: make(chan map[string]any, 1)}
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /api/apps.connections.open", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer xapp-test" {
+			_ = json.NewEncoder(w).Encode(map[string]any{"ok": false, "error": "invalid_auth"})
+			return
+		}
+		_ = json.NewEncoder(w).Encode(map[string]any{"ok": true, "url": "ws" + strings.TrimPrefix(f.server.URL, "http") + "/link"})
+	})
+	mux.HandleFunc("GET /link", f.socket)
+	mux.HandleFunc("POST /respond", func(w http.ResponseWriter, r *http.Request) {
+		var reply map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&reply)
+		f.replies <- reply
+	})
+	f.server = httptest.NewServer(mux)
+	t.Cleanup(f.server.Close)
+	return f
+}
+
+func (f *fakeSlack) socket(w http.ResponseWriter, r *http.Request) {
+	key := r.Header.Get("Sec-WebSocket-Key")
+	conn, rw, err := http.NewResponseController(w).Hijack()
+	if err != nil {
+		f.t.Error(err)
+		return
+	}
+	defer func() { _ = conn.Close() }()
+	_, _ = rw.WriteString("HTTP/1.1 101 Switching Protocols\r\nUpgrade: websocket\r\nConnection: Upgrade\r\nSec-WebSocket-Accept: " + acceptKey(key) + "\r\n\r\n")
+	_ = rw.Flush()
+
+	send := func(op byte, v any) {
+		data, _ := json.Marshal(v)
+		_ = writeFrame(conn, op, data, false)
+	}
+	send(opText, map[string]any{"type": "hello"})
+	_ = writeFrame(conn, opPing, []byte("hi"), false)
+	send(opText, map[string]any{
+		"envelope_id": "env-1",
+		"type":        "slash_commands",
+		"payload": map[string]any{
+			"command": "/ods", "text": "whois chris", "user_id": "U1", "user_name": "sam",
+			"channel_id": "C1", "response_url": f.server.URL + "/respond",
+		},
+	})
+
+	br := bufio.NewReader(rw)
+	for {
+		fr, err := readFrame(br)
+		if err != nil {
+			return
+		}
+		switch fr.op {
+		case opPong:
+			if string(fr.payload) != "hi" {
+				f.t.Errorf("pong payload = %q", fr.payload)
+			}
+		case opText:
+			var ack map[string]any
+			_ = json.Unmarshal(fr.payload, &ack)
+			f.acks <- ack
+		}
+	}
+}
+
+func TestClientAnswersSlashCommands(t *testing.T) {
+	f := newFakeSlack(t)
+	c, err := NewClient("xapp-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c.APIURL = f.server.URL + "/api"
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	got := make(chan SlashCommand, 1)
+	done := make(chan error, 1)
+	go func() {
+		done <- c.Run(ctx, func(ctx context.Context, cmd SlashCommand) Reply {
+			got <- cmd
+			return Reply{Text: "chris@acme.com"}
+		})
+	}()
+
+	select {
+	case ack := <-f.acks:
+		payload, _ := ack["payload"].(map[string]any)
+		if ack["envelope_id"] != "env-1" || payload["response_type"] != "in_channel" {
+			t.Errorf("ack = %v", ack)
+		}
+	case <-ctx.Done():
+		t.Fatal("no acknowledgement")
+	}
+	if cmd := <-got; cmd.Text != "whois chris" || cmd.UserName != "sam" {
+		t.Errorf("handler got %+v", cmd)
+	}
+	select {
+	case reply := <-f.replies:
+		if reply["text"] != "chris@acme.com" || reply["response_type"] != "in_channel" {
+			t.Errorf("reply = %v", reply)
+		}
+	case <-ctx.Done():
+		t.Fatal("no reply")
+	}
+
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("Run() = %v", err)
+	}
+}
+
+func TestClientStopsOnBadToken(t *testing.T) {
+	f := newFakeSlack(t)
+	if _, err := NewClient("xoxb-bot-token"); err == nil {
+		t.Error("expected an error for a bot token")
+	}
+	c, _ := NewClient("xapp-wrong")
+	c.APIURL = f.server.URL + "/api"
+	err := c.Run(context.Background(), nil)
+	if err == nil || !strings.Contains(err.Error(), "invalid_auth") {
+		t.Errorf("Run() = %v, want invalid_auth", err)
+	}
+}
+
+func TestFrameRoundTrip(t *testing.T) {
+	for _, size := range []int{0, 125, 126, 70000} {
+		payload := []byte(strings.Repeat("x", size))
+		var buf strings.Builder
+		if err := writeFrame(&buf, opText, payload, true); err != nil {
+			t.Fatal(err)
+		}
+		f, err := readFrame(bufio.NewReader(strings.NewReader(buf.String())))
+		if err != nil || !f.fin || f.op != opText || string(f.payload) != string(payload) {
+			t.Errorf("size %d: round trip = %v, %v", size, len(f.payload), err)
+		}
+	}
+}
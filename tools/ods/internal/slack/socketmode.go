@@ -0,0 +1,248 @@
+// Package slack is a Slack Socket Mode client for answering slash commands
+// without exposing a public HTTP endpoint.
+package slack
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+)
+
+// DefaultAPIURL is Slack's Web API.
+const DefaultAPIURL = "https://slack.com/api"
+
+const requestTimeout = 10 * time.Second
+
+// SlashCommand is a slash command invocation, e.g. "/ods whois chris".
+type SlashCommand struct {
+	Command     string `json:"command"`
+	Text        string `json:"text"`
+	UserID      string `json:"user_id"`
+	UserName    string `json:"user_name"`
+	ChannelID   string `json:"channel_id"`
+	ChannelName string `json:"channel_name"`
+	TeamID      string `json:"team_id"`
+	ResponseURL string `json:"response_url"`
+}
+
+// Reply is the answer to a slash command. Ephemeral replies are only shown
+// to the person who ran the command.
+type Reply struct {
+	Text      string
+	Ephemeral bool
+}
+
+// Handler answers a slash command. It runs on its own goroutine after the
+// command has been acknowledged, so it may take longer than Slack's
+// three-second acknowledgement deadline.
+type Handler func(ctx context.Context, cmd SlashCommand) Reply
+
+// Client connects to Slack in Socket Mode with an app-level token (xapp-…).
+type Client struct {
+	AppToken string
+	APIURL   string
+	HTTP     *http.Client
+}
+
+// NewClient returns a Client for appToken.
+func NewClient(appToken string) (*Client, error) {
+	if !strings.HasPrefix(appToken, "xapp-") {
+		return nil, fmt.Errorf("a Slack app-level token (xapp-…) with the connections:write scope is required")
+	}
+	return &Client{AppToken: appToken, APIURL: DefaultAPIURL, HTTP: &http.Client{Timeout: requestTimeout}}, nil
+}
+
+// errFatal marks errors that reconnecting will not fix.
+type errFatal struct{ err error }
+
+func (e *errFatal) Error() string { return e.err.Error() }
+func (e *errFatal) Unwrap() error { return e.err }
+
+// Run answers slash commands with h until ctx is done, reconnecting with
+// backoff whenever Slack drops or refreshes the connection.
+func (c *Client) Run(ctx context.Context, h Handler) error {
+	backoff := time.Second
+	for {
+		start := time.Now()
+		err := c.serve(ctx, h)
+		if ctx.Err() != nil {
+			return nil
+		}
+		var fatal *errFatal
+		if errors.As(err, &fatal) {
+			return fatal.err
+		}
+		if time.Since(start) > time.Minute {
+			backoff = time.Second
+		}
+		if err != nil {
+			log.Warnf("Slack connection lost: %v; reconnecting in %s", err, backoff)
+		} else {
+			log.Debug("Slack asked to reconnect")
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(backoff):
+		}
+		backoff = min(2*backoff, 30*time.Second)
+	}
+}
+
+// envelope is a Socket Mode message from Slack.
+type envelope struct {
+	EnvelopeID string          `json:"envelope_id"`
+	Type       string          `json:"type"`
+	Reason     string          `json:"reason"`
+	Payload    json.RawMessage `json:"payload"`
+}
+
+// serve handles one connection until it fails, Slack asks to reconnect
+// (returning nil) or ctx is done.
+func (c *Client) serve(ctx context.Context, h Handler) error {
+	wsURL, err := c.openConnection(ctx)
+	if err != nil {
+		return err
+	}
+	conn, err := dialWebSocket(ctx, wsURL)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = conn.Close() }()
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	defer stop()
+
+	for {
+		data, err := conn.ReadMessage()
+		if err != nil {
+			if ctx.Err() != nil || errors.Is(err, io.EOF) {
+				return nil
+			}
+			return err
+		}
+		var env envelope
+		if err := json.Unmarshal(data, &env); err != nil {
+			log.Warnf("Ignoring malformed Slack message: %v", err)
+			continue
+		}
+
+		switch env.Type {
+		case "hello":
+			log.Info("Connected to Slack")
+			continue
+		case "disconnect":
+			log.Debugf("Slack is closing the connection (%s)", env.Reason)
+			return nil
+		}
+		if env.EnvelopeID == "" {
+			continue
+		}
+		if env.Type != "slash_commands" {
+			// Acknowledge anything else so Slack does not redeliver it.
+			if err := c.ack(conn, env.EnvelopeID, nil); err != nil {
+				return err
+			}
+			continue
+		}
+
+		var cmd SlashCommand
+		if err := json.Unmarshal(env.Payload, &cmd); err != nil {
+			log.Warnf("Ignoring malformed slash command: %v", err)
+			_ = c.ack(conn, env.EnvelopeID, nil)
+			continue
+		}
+		ack := map[string]any{
+			"response_type": "in_channel",
+			"text":          fmt.Sprintf("_<@%s> ran_ `%s %s`", cmd.UserID, cmd.Command, cmd.Text),
+		}
+		if err := c.ack(conn, env.EnvelopeID, ack); err != nil {
+			return err
+		}
+		go func() {
+			reply := h(ctx, cmd)
+			if err := c.Respond(ctx, cmd.ResponseURL, reply); err != nil {
+				log.Warnf("Failed to answer %s %s: %v", cmd.Command, cmd.Text, err)
+			}
+		}()
+	}
+}
+
+func (c *Client) ack(conn *wsConn, envelopeID string, payload any) error {
+	msg := map[string]any{"envelope_id": envelopeID}
+	if payload != nil {
+		msg["payload"] = payload
+	}
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return conn.WriteText(data)
+}
+
+// openConnection asks Slack for a Socket Mode WebSocket URL.
+func (c *Client) openConnection(ctx context.Context) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, strings.TrimRight(c.APIURL, "/")+"/apps.connections.open", nil)
+	if err != nil {
+		return "", err
+	}
+	req.Header.Set("Authorization", "Bearer "+c.AppToken)
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return "", err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	var out struct {
+		OK    bool   `json:"ok"`
+		URL   string `json:"url"`
+		Error string `json:"error"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&out); err != nil {
+		return "", fmt.Errorf("apps.connections.open: %s: %w", resp.Status, err)
+	}
+	if !out.OK {
+		err := fmt.Errorf("apps.connections.open: %s", out.Error)
+		switch out.Error {
+		case "invalid_auth", "not_authed", "account_inactive", "token_revoked", "not_allowed_token_type", "missing_scope":
+			return "", &errFatal{err}
+		}
+		return "", err
+	}
+	return out.URL, nil
+}
+
+// Respond posts reply to a slash command's response URL.
+func (c *Client) Respond(ctx context.Context, responseURL string, reply Reply) error {
+	responseType := "in_channel"
+	if reply.Ephemeral {
+		responseType = "ephemeral"
+	}
+	body, err := json.Marshal(map[string]any{"response_type": responseType, "text": reply.Text})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, responseURL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	resp, err := c.HTTP.Do(req)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode >= 300 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 512))
+		return fmt.Errorf("response URL returned %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
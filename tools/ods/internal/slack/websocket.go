@@ -0,0 +1,253 @@
+package slack
+
+import (
+	"bufio"
+	"context"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/tls"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sync"
+	"time"
+)
+
+// WebSocket opcodes (RFC 6455 section 5.2).
+const (
+	opContinuation = 0x0
+	opText         = 0x1
+	opBinary       = 0x2
+	opClose        = 0x8
+	opPing         = 0x9
+	opPong         = 0xa
+)
+
+// maxMessageSize bounds a message; Socket Mode envelopes are a few KiB.
+const maxMessageSize = 16 << 20
+
+const webSocketGUID = "258EAFA5-E914-47DA-95CA-C5AB0DC85B11"
+
+// wsConn is a minimal WebSocket client connection: enough for Socket Mode,
+// which only exchanges JSON text messages.
+type wsConn struct {
+	conn net.Conn
+	br   *bufio.Reader
+	wmu  sync.Mutex
+}
+
+// dialWebSocket opens a WebSocket connection to a ws:// or wss:// URL.
+func dialWebSocket(ctx context.Context, rawURL string) (*wsConn, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, err
+	}
+	host := u.Host
+	dialer := &net.Dialer{Timeout: 15 * time.Second}
+	var conn net.Conn
+	switch u.Scheme {
+	case "wss":
+		if u.Port() == "" {
+			host = net.JoinHostPort(u.Hostname(), "443")
+		}
+		tlsDialer := &tls.Dialer{NetDialer: dialer, Config: &tls.Config{ServerName: u.Hostname(), MinVersion: tls.VersionTLS12}}
+		conn, err = tlsDialer.DialContext(ctx, "tcp", host)
+	case "ws":
+		if u.Port() == "" {
+			host = net.JoinHostPort(u.Hostname(), "80")
+		}
+		conn, err = dialer.DialContext(ctx, "tcp", host)
+	default:
+		return nil, fmt.Errorf("unsupported WebSocket URL scheme %q", u.Scheme)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	ws, err := handshake(conn, u)
+	if err != nil {
+		_ = conn.Close()
+		return nil, err
+	}
+	return ws, nil
+}
+
+func handshake(conn net.Conn, u *url.URL) (*wsConn, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	key := base64.StdEncoding.EncodeToString(nonce)
+
+	req := &http.Request{
+		Method: http.MethodGet,
+		URL:    u,
+		Host:   u.Host,
+		Header: http.Header{
+			"Upgrade":               {"websocket"},
+			"Connection":            {"Upgrade"},
+			"Sec-WebSocket-Key":     {key},
+			"Sec-WebSocket-Version": {"13"},
+		},
+	}
+	_ = conn.SetDeadline(time.Now().Add(15 * time.Second))
+	if err := req.Write(conn); err != nil {
+		return nil, fmt.Errorf("failed to send WebSocket handshake: %w", err)
+	}
+	br := bufio.NewReader(conn)
+	resp, err := http.ReadResponse(br, req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read WebSocket handshake: %w", err)
+	}
+	_ = resp.Body.Close()
+	if resp.StatusCode != http.StatusSwitchingProtocols {
+		return nil, fmt.Errorf("WebSocket handshake failed: %s", resp.Status)
+	}
+	if resp.Header.Get("Sec-WebSocket-Accept") != acceptKey(key) {
+		return nil, fmt.Errorf("WebSocket handshake failed: bad Sec-WebSocket-Accept")
+	}
+	_ = conn.SetDeadline(time.Time{})
+	return &wsConn{conn: conn, br: br}, nil
+}
+
+func acceptKey(key string) string {
+	sum := sha1.Sum([]byte(key + webSocketGUID))
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
+
+// ReadMessage returns the next text or binary message, answering pings on
+// the way. It returns io.EOF once the server closes the connection.
+func (c *wsConn) ReadMessage() ([]byte, error) {
+	var msg []byte
+	for {
+		f, err := readFrame(c.br)
+		if err != nil {
+			return nil, err
+		}
+		switch f.op {
+		case opPing:
+			if err := c.write(opPong, f.payload); err != nil {
+				return nil, err
+			}
+		case opPong:
+		case opClose:
+			_ = c.write(opClose, f.payload[:min(2, len(f.payload))])
+			return nil, io.EOF
+		case opText, opBinary, opContinuation:
+			if len(msg)+len(f.payload) > maxMessageSize {
+				return nil, fmt.Errorf("WebSocket message exceeds %d bytes", maxMessageSize)
+			}
+			msg = append(msg, f.payload...)
+			if f.fin {
+				return msg, nil
+			}
+		default:
+			return nil, fmt.Errorf("unexpected WebSocket opcode %#x", f.op)
+		}
+	}
+}
+
+// WriteText sends data as a text message. It is safe for concurrent use.
+func (c *wsConn) WriteText(data []byte) error {
+	return c.write(opText, data)
+}
+
+func (c *wsConn) write(op byte, payload []byte) error {
+	c.wmu.Lock()
+	defer c.wmu.Unlock()
+	return writeFrame(c.conn, op, payload, true)
+}
+
+// Close closes the connection without a closing handshake.
+func (c *wsConn) Close() error {
+	return c.conn.Close()
+}
+
+type frame struct {
+	fin     bool
+	op      byte
+	payload []byte
+}
+
+func readFrame(r *bufio.Reader) (frame, error) {
+	var h [2]byte
+	if _, err := io.ReadFull(r, h[:]); err != nil {
+		return frame{}, err
+	}
+	f := frame{fin: h[0]&0x80 != 0, op: h[0] & 0x0f}
+	masked := h[1]&0x80 != 0
+	n := uint64(h[1] & 0x7f)
+	switch n {
+	case 126:
+		var ext [2]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return frame{}, err
+		}
+		n = uint64(binary.BigEndian.Uint16(ext[:]))
+	case 127:
+		var ext [8]byte
+		if _, err := io.ReadFull(r, ext[:]); err != nil {
+			return frame{}, err
+		}
+		n = binary.BigEndian.Uint64(ext[:])
+	}
+	if n > maxMessageSize {
+		return frame{}, errors.New("WebSocket frame too large")
+	}
+	var mask [4]byte
+	if masked {
+		if _, err := io.ReadFull(r, mask[:]); err != nil {
+			return frame{}, err
+		}
+	}
+	f.payload = make([]byte, n)
+	if _, err := io.ReadFull(r, f.payload); err != nil {
+		return frame{}, err
+	}
+	if masked {
+		for i := range f.payload {
+			f.payload[i] ^= mask[i%4]
+		}
+	}
+	return f, nil
+}
+
+// writeFrame writes payload as a single, final frame. Clients must mask the
+// frames they send; servers must not.
+func writeFrame(w io.Writer, op byte, payload []byte, mask bool) error {
+	buf := make([]byte, 0, 14+len(payload))
+	buf = append(buf, 0x80|op)
+	var maskBit byte
+	if mask {
+		maskBit = 0x80
+	}
+	switch n := len(payload); {
+	case n < 126:
+		buf = append(buf, maskBit|byte(n))
+	case n <= 0xffff:
+		buf = append(buf, maskBit|126)
+		buf = binary.BigEndian.AppendUint16(buf, uint16(n))
+	default:
+		buf = append(buf, maskBit|127)
+		buf = binary.BigEndian.AppendUint64(buf, uint64(n))
+	}
+	if !mask {
+		_, err := w.Write(append(buf, payload...))
+		return err
+	}
+	var key [4]byte
+	if _, err := rand.Read(key[:]); err != nil {
+		return err
+	}
+	buf = append(buf, key[:]...)
+	for i, b := range payload {
+		buf = append(buf, b^key[i%4])
+	}
+	_, err := w.Write(buf)
+	return err
+}
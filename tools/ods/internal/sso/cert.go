@@ -0,0 +1,35 @@
+package sso
+
+import (
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"strings"
+)
+
+// ParseCertificate parses an X.509 certificate given as PEM or, as SAML
+// metadata carries it, bare base64 DER.
+func ParseCertificate(raw string) (*x509.Certificate, error) {
+	raw = strings.TrimSpace(raw)
+	if block, _ := pem.Decode([]byte(raw)); block != nil {
+		return x509.ParseCertificate(block.Bytes)
+	}
+	der, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(raw), ""))
+	if err != nil {
+		return nil, fmt.Errorf("neither PEM nor base64")
+	}
+	return x509.ParseCertificate(der)
+}
+
+// Fingerprint returns the certificate's SHA-256 fingerprint in the
+// colon-separated form IdP consoles show.
+func Fingerprint(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.Raw)
+	parts := make([]string, len(sum))
+	for i, b := range sum {
+		parts[i] = fmt.Sprintf("%02X", b)
+	}
+	return strings.Join(parts, ":")
+}
@@ -0,0 +1,46 @@
+package sso
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/token"
+)
+
+// CheckIDToken lists where an ID token from the IdP disagrees with the OIDC
+// or Google provider p, as of now.
+func CheckIDToken(t *token.JWT, p *Provider, now time.Time) []string {
+	var findings []string
+	if p.OIDC != nil && p.OIDC.Issuer != "" {
+		if iss := t.String("iss"); iss != p.OIDC.Issuer {
+			findings = append(findings, fmt.Sprintf("The token issuer %q is not the discovery document's issuer %q; the discovery URL may point at another tenant of the IdP", iss, p.OIDC.Issuer))
+		}
+	}
+	if aud := t.Audiences(); p.ClientID != "" && !slices.Contains(aud, p.ClientID) {
+		findings = append(findings, fmt.Sprintf("The token audience %s does not include the client ID %q; the token was issued to another app", strings.Join(aud, ", "), p.ClientID))
+	}
+	if exp, ok := t.Time("exp"); ok && now.After(exp) {
+		findings = append(findings, fmt.Sprintf("The token expired at %s (%s ago)", exp.UTC().Format(time.RFC3339), now.Sub(exp).Truncate(time.Second)))
+	}
+	if nbf, ok := t.Time("nbf"); ok && now.Before(nbf) {
+		findings = append(findings, fmt.Sprintf("The token is not valid before %s; check the clocks", nbf.UTC().Format(time.RFC3339)))
+	}
+
+	email := t.String("email")
+	if email == "" {
+		return append(findings, "The token has no email claim; request the email scope and make sure the IdP releases it")
+	}
+	verified, present := t.Claims["email_verified"]
+	switch {
+	case present && verified != true && verified != "true":
+		findings = append(findings, fmt.Sprintf("email_verified is %v, so the login is refused", verified))
+	case !present && p.RequireVerifiedEmail:
+		findings = append(findings, "The token has no email_verified claim and the provider requires verified emails, so the login is refused")
+	}
+	if f := domainFinding(email, p.AllowedEmailDomains); f != "" {
+		findings = append(findings, f)
+	}
+	return findings
+}
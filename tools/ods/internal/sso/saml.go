@@ -0,0 +1,296 @@
+package sso
+
+import (
+	"encoding/base64"
+	"encoding/xml"
+	"fmt"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+)
+
+// emailAttributes are the attributes the SAML callback reads the email from
+// when the provider names none, compared case-insensitively.
+var emailAttributes = []string{
+	"email",
+	"emailaddress",
+	"mail",
+	"http://schemas.xmlsoap.org/ws/2005/05/identity/claims/emailaddress",
+	"http://schemas.xmlsoap.org/ws/2005/05/identity/claims/mail",
+	"http://schemas.microsoft.com/identity/claims/emailaddress",
+}
+
+const samlSuccess = "urn:oasis:names:tc:SAML:2.0:status:Success"
+
+// SAMLResponse is the part of a SAML response that login depends on.
+type SAMLResponse struct {
+	Destination   string
+	IssueInstant  string
+	Issuer        string
+	Status        string
+	SubStatus     string
+	StatusMessage string
+	// Signed reports a signature on the response itself.
+	Signed bool
+	// SigningCert is the certificate embedded in the first signature, if any.
+	SigningCert string
+	// Encrypted is set when the assertion is encrypted and so not decoded.
+	Encrypted bool
+	Assertion *SAMLAssertion
+}
+
+// SAMLAssertion is the assertion of a SAML response.
+type SAMLAssertion struct {
+	Issuer       string
+	Signed       bool
+	NameID       string
+	NameIDFormat string
+	Recipient    string
+	NotBefore    string
+	NotOnOrAfter string
+	Audiences    []string
+	// Attributes maps attribute names to their values, in document order
+	// of first appearance in AttributeNames.
+	Attributes     map[string][]string
+	AttributeNames []string
+}
+
+type xmlSignature struct {
+	Certificate string `xml:"KeyInfo>X509Data>X509Certificate"`
+}
+
+type xmlResponse struct {
+	Destination  string `xml:"Destination,attr"`
+	IssueInstant string `xml:"IssueInstant,attr"`
+	Issuer       string `xml:"Issuer"`
+	Status       struct {
+		StatusCode struct {
+			Value      string `xml:"Value,attr"`
+			StatusCode struct {
+				Value string `xml:"Value,attr"`
+			} `xml:"StatusCode"`
+		} `xml:"StatusCode"`
+		StatusMessage string `xml:"StatusMessage"`
+	} `xml:"Status"`
+	Signature          *xmlSignature `xml:"Signature"`
+	Assertion          *xmlAssertion `xml:"Assertion"`
+	EncryptedAssertion *struct{}     `xml:"EncryptedAssertion"`
+}
+
+type xmlAssertion struct {
+	Issuer    string        `xml:"Issuer"`
+	Signature *xmlSignature `xml:"Signature"`
+	Subject   struct {
+		NameID struct {
+			Format string `xml:"Format,attr"`
+			Value  string `xml:",chardata"`
+		} `xml:"NameID"`
+		Confirmation struct {
+			Recipient string `xml:"Recipient,attr"`
+		} `xml:"SubjectConfirmation>SubjectConfirmationData"`
+	} `xml:"Subject"`
+	Conditions struct {
+		NotBefore    string   `xml:"NotBefore,attr"`
+		NotOnOrAfter string   `xml:"NotOnOrAfter,attr"`
+		Audiences    []string `xml:"AudienceRestriction>Audience"`
+	} `xml:"Conditions"`
+	Attributes []struct {
+		Name   string   `xml:"Name,attr"`
+		Values []string `xml:"AttributeValue"`
+	} `xml:"AttributeStatement>Attribute"`
+}
+
+// DecodeSAMLResponse decodes a SAML response as copied from a browser: the
+// base64 SAMLResponse form value (URL-encoded or not, with or without the
+// "SAMLResponse=" prefix) or the XML itself.
+func DecodeSAMLResponse(input string) (*SAMLResponse, error) {
+	input = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(input), "SAMLResponse="))
+	if strings.Contains(input, "%") {
+		if unescaped, err := url.QueryUnescape(input); err == nil {
+			input = unescaped
+		}
+	}
+	data := []byte(input)
+	if !strings.HasPrefix(input, "<") {
+		decoded, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(input), ""))
+		if err != nil {
+			return nil, fmt.Errorf("not base64 or XML: %w", err)
+		}
+		data = decoded
+	}
+
+	var x xmlResponse
+	if err := xml.Unmarshal(data, &x); err != nil {
+		return nil, fmt.Errorf("not a SAML response: %w", err)
+	}
+	r := &SAMLResponse{
+		Destination:   x.Destination,
+		IssueInstant:  x.IssueInstant,
+		Issuer:        strings.TrimSpace(x.Issuer),
+		Status:        x.Status.StatusCode.Value,
+		SubStatus:     x.Status.StatusCode.StatusCode.Value,
+		StatusMessage: strings.TrimSpace(x.Status.StatusMessage),
+		Signed:        x.Signature != nil,
+		Encrypted:     x.EncryptedAssertion != nil,
+	}
+	if x.Signature != nil {
+		r.SigningCert = x.Signature.Certificate
+	}
+	if a := x.Assertion; a != nil {
+		r.Assertion = &SAMLAssertion{
+			Issuer:       strings.TrimSpace(a.Issuer),
+			Signed:       a.Signature != nil,
+			NameID:       strings.TrimSpace(a.Subject.NameID.Value),
+			NameIDFormat: a.Subject.NameID.Format,
+			Recipient:    a.Subject.Confirmation.Recipient,
+			NotBefore:    a.Conditions.NotBefore,
+			NotOnOrAfter: a.Conditions.NotOnOrAfter,
+			Attributes:   make(map[string][]string),
+		}
+		for _, aud := range a.Conditions.Audiences {
+			r.Assertion.Audiences = append(r.Assertion.Audiences, strings.TrimSpace(aud))
+		}
+		for _, attr := range a.Attributes {
+			if _, ok := r.Assertion.Attributes[attr.Name]; !ok {
+				r.Assertion.AttributeNames = append(r.Assertion.AttributeNames, attr.Name)
+			}
+			for _, v := range attr.Values {
+				r.Assertion.Attributes[attr.Name] = append(r.Assertion.Attributes[attr.Name], strings.TrimSpace(v))
+			}
+		}
+		if r.SigningCert == "" && a.Signature != nil {
+			r.SigningCert = a.Signature.Certificate
+		}
+	}
+	return r, nil
+}
+
+// Email returns the email the SAML callback would read from the assertion
+// for an email attribute of configured (may be empty), and the attribute it
+// came from.
+func (a *SAMLAssertion) Email(configured string) (email, attribute string) {
+	keys := emailAttributes
+	if configured != "" {
+		keys = append([]string{configured}, keys...)
+	}
+	for _, key := range keys {
+		for _, name := range a.AttributeNames {
+			if strings.EqualFold(name, key) && len(a.Attributes[name]) > 0 && a.Attributes[name][0] != "" {
+				return a.Attributes[name][0], name
+			}
+		}
+	}
+	return "", ""
+}
+
+// Check lists where the response disagrees with the provider p.
+func (r *SAMLResponse) Check(p *Provider) []string {
+	var findings []string
+	if r.Status != samlSuccess {
+		msg := fmt.Sprintf("The IdP reported a failed login: %s", lastURNPart(r.Status))
+		if r.SubStatus != "" {
+			msg += " / " + lastURNPart(r.SubStatus)
+		}
+		if r.StatusMessage != "" {
+			msg += fmt.Sprintf(" (%s)", r.StatusMessage)
+		}
+		findings = append(findings, msg)
+	}
+	if r.Issuer != "" && r.Issuer != p.IdPEntityID {
+		findings = append(findings, fmt.Sprintf("The response issuer %q is not the configured IdP entity ID %q; Onyx finds the provider by issuer, so the login is refused", r.Issuer, p.IdPEntityID))
+	}
+	if r.Destination != "" && p.CallbackURL != "" && r.Destination != p.CallbackURL {
+		findings = append(findings, fmt.Sprintf("The response is addressed to %s, not the ACS URL %s; fix the ACS URL in the IdP", r.Destination, p.CallbackURL))
+	}
+	if r.SigningCert != "" && p.IdPCert != "" {
+		findings = append(findings, signingCertFindings(r.SigningCert, p.IdPCert)...)
+	}
+
+	if r.Encrypted {
+		if !p.HasSPPrivateKey {
+			findings = append(findings, "The assertion is encrypted but no SP private key is configured to decrypt it; turn off assertion encryption in the IdP or configure the SP key")
+		}
+		return findings
+	}
+	a := r.Assertion
+	if a == nil {
+		if r.Status == samlSuccess {
+			findings = append(findings, "The response carries no assertion")
+		}
+		return findings
+	}
+	if !r.Signed && !a.Signed {
+		findings = append(findings, "Neither the response nor the assertion is signed; the IdP must sign at least one")
+	}
+	if a.Issuer != "" && a.Issuer != p.IdPEntityID && a.Issuer != r.Issuer {
+		findings = append(findings, fmt.Sprintf("The assertion issuer %q is not the configured IdP entity ID %q", a.Issuer, p.IdPEntityID))
+	}
+	if p.SPEntityID != "" && len(a.Audiences) > 0 && !slices.Contains(a.Audiences, p.SPEntityID) {
+		findings = append(findings, fmt.Sprintf("The assertion's audience %s does not include the SP entity ID %q; fix the entity ID (audience URI) in the IdP", strings.Join(a.Audiences, ", "), p.SPEntityID))
+	}
+	if a.Recipient != "" && p.CallbackURL != "" && a.Recipient != p.CallbackURL {
+		findings = append(findings, fmt.Sprintf("The assertion's recipient %s is not the ACS URL %s", a.Recipient, p.CallbackURL))
+	}
+	if before, after, err := parseSAMLWindow(a); err == nil && !after.After(before) {
+		findings = append(findings, fmt.Sprintf("The assertion is never valid: NotOnOrAfter %s is not after NotBefore %s", a.NotOnOrAfter, a.NotBefore))
+	}
+
+	email, _ := a.Email(p.EmailAttribute)
+	if email == "" {
+		want := "email, mail or the emailaddress claim URIs"
+		if p.EmailAttribute != "" {
+			want = fmt.Sprintf("%q (configured) or the usual email attributes", p.EmailAttribute)
+		}
+		got := "none"
+		if len(a.AttributeNames) > 0 {
+			got = strings.Join(a.AttributeNames, ", ")
+		}
+		findings = append(findings, fmt.Sprintf("No email attribute: Onyx reads %s, the IdP sent %s; map the email attribute in the IdP or set the provider's email attribute", want, got))
+	} else if f := domainFinding(email, p.AllowedEmailDomains); f != "" {
+		findings = append(findings, f)
+	}
+	return findings
+}
+
+func parseSAMLWindow(a *SAMLAssertion) (time.Time, time.Time, error) {
+	before, err := time.Parse(time.RFC3339, a.NotBefore)
+	if err != nil {
+		return time.Time{}, time.Time{}, err
+	}
+	after, err := time.Parse(time.RFC3339, a.NotOnOrAfter)
+	return before, after, err
+}
+
+func signingCertFindings(signing, configured string) []string {
+	sc, err := ParseCertificate(signing)
+	if err != nil {
+		return []string{fmt.Sprintf("The response's signing certificate cannot be parsed: %v", err)}
+	}
+	cc, err := ParseCertificate(configured)
+	if err != nil {
+		return nil
+	}
+	if !sc.Equal(cc) {
+		return []string{fmt.Sprintf("The response is signed with a certificate (SHA-256 %s, expires %s) other than the configured IdP certificate (SHA-256 %s); the IdP has likely rotated its certificate",
+			Fingerprint(sc), sc.NotAfter.Format(time.DateOnly), Fingerprint(cc))}
+	}
+	return nil
+}
+
+// domainFinding reports an email outside the provider's allowed domains; an
+// empty list admits every domain.
+func domainFinding(email string, allowed []string) string {
+	if len(allowed) == 0 {
+		return ""
+	}
+	_, domain, _ := strings.Cut(email, "@")
+	if slices.Contains(allowed, strings.ToLower(strings.TrimSpace(domain))) {
+		return ""
+	}
+	return fmt.Sprintf("The email %s is outside the provider's allowed domains (%s), so the login is refused", email, strings.Join(allowed, ", "))
+}
+
+func lastURNPart(urn string) string {
+	return urn[strings.LastIndex(urn, ":")+1:]
+}
@@ -0,0 +1,250 @@
+// Package sso inspects a tenant's SSO providers and decodes the SAML
+// responses and ID tokens their IdPs send, pointing out where they disagree.
+package sso
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/kube"
+)
+
+//go:embed sso_debug.py
+var debugScript string
+
+// Provider types, as stored in sso_provider.provider_type.
+const (
+	TypeGoogle = "GOOGLE_OAUTH"
+	TypeOIDC   = "OIDC"
+	TypeSAML   = "SAML"
+)
+
+// certWarning is how close to expiry a certificate gets flagged.
+const certWarning = 30 * 24 * time.Hour
+
+// Provider is an SSO provider row with the outcome of probing its IdP.
+// Secrets are reduced to whether they are set.
+type Provider struct {
+	Name                string   `json:"name"`
+	DisplayName         string   `json:"display_name"`
+	Type                string   `json:"type"`
+	Enabled             bool     `json:"enabled"`
+	AllowedEmailDomains []string `json:"allowed_email_domains"`
+	CallbackURL         string   `json:"callback_url"`
+	Updated             string   `json:"updated"`
+
+	// OIDC and Google.
+	ClientID             string     `json:"client_id"`
+	HasClientSecret      bool       `json:"has_client_secret"`
+	RequireVerifiedEmail bool       `json:"require_verified_email"`
+	OIDC                 *OIDCProbe `json:"oidc"`
+
+	// SAML.
+	IdPEntityID     string    `json:"idp_entity_id"`
+	IdPSSOURL       string    `json:"idp_sso_url"`
+	SPEntityID      string    `json:"sp_entity_id"`
+	EmailAttribute  string    `json:"email_attribute"`
+	IdPCert         string    `json:"idp_x509_cert"`
+	SPCert          string    `json:"sp_x509_cert"`
+	HasSPPrivateKey bool      `json:"has_sp_private_key"`
+	IdPSSOProbe     *URLProbe `json:"idp_sso_probe"`
+}
+
+// OIDCProbe is what the IdP's discovery document, JWKS and token endpoint
+// returned.
+type OIDCProbe struct {
+	DiscoveryURL          string `json:"discovery_url"`
+	DiscoveryError        string `json:"discovery_error"`
+	Issuer                string `json:"issuer"`
+	AuthorizationEndpoint string `json:"authorization_endpoint"`
+	TokenEndpoint         string `json:"token_endpoint"`
+	JWKSURI               string `json:"jwks_uri"`
+	JWKSKeys              int    `json:"jwks_keys"`
+	JWKSError             string `json:"jwks_error"`
+	// TokenStatus is the HTTP status of the client_credentials request, 0
+	// when it was not made.
+	TokenStatus           int    `json:"token_status"`
+	TokenError            string `json:"token_error"`
+	TokenErrorDescription string `json:"token_error_description"`
+	TokenRequestError     string `json:"token_request_error"`
+}
+
+// URLProbe is the outcome of requesting a URL.
+type URLProbe struct {
+	Status int    `json:"status"`
+	Error  string `json:"error"`
+}
+
+// Report is a tenant's SSO providers.
+type Report struct {
+	WebDomain string     `json:"web_domain"`
+	Providers []Provider `json:"providers"`
+}
+
+// Inspect fetches and probes the SSO providers of schema ("" for the
+// default schema of a single-tenant deployment) on pod, or only the one
+// named name.
+func Inspect(c *kube.Cluster, pod, schema, name string) (*Report, error) {
+	args := []string{schema}
+	if name != "" {
+		args = append(args, name)
+	}
+	out, err := c.RunPython(pod, debugScript, args...)
+	if err != nil {
+		return nil, err
+	}
+	return parseReport(out)
+}
+
+func parseReport(stdout string) (*Report, error) {
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	last := strings.TrimSpace(lines[len(lines)-1])
+	var r struct {
+		Status  string `json:"status"`
+		Message string `json:"message"`
+		Report
+	}
+	if err := json.Unmarshal([]byte(last), &r); err != nil {
+		return nil, fmt.Errorf("unexpected output from sso-debug script: %q", last)
+	}
+	if r.Status != "success" {
+		return nil, fmt.Errorf("%s", r.Message)
+	}
+	return &r.Report, nil
+}
+
+// Findings lists what looks wrong with the provider's configuration or its
+// IdP, as of now.
+func (p *Provider) Findings(now time.Time) []string {
+	var findings []string
+	if !p.Enabled {
+		findings = append(findings, "The provider is disabled, so its login button is hidden and its logins are refused")
+	}
+	switch p.Type {
+	case TypeSAML:
+		findings = append(findings, p.samlFindings(now)...)
+	default:
+		findings = append(findings, p.oidcFindings()...)
+	}
+	return findings
+}
+
+func (p *Provider) oidcFindings() []string {
+	var findings []string
+	if p.ClientID == "" {
+		findings = append(findings, "No client ID is configured")
+	}
+	if !p.HasClientSecret {
+		findings = append(findings, "No client secret is configured, so the code exchange after login will fail")
+	}
+	o := p.OIDC
+	if o == nil {
+		return findings
+	}
+	if o.DiscoveryError != "" {
+		return append(findings, fmt.Sprintf("The discovery document %s could not be fetched from the api-server: %s", o.DiscoveryURL, o.DiscoveryError))
+	}
+	for _, f := range []struct{ name, value string }{
+		{"issuer", o.Issuer},
+		{"authorization_endpoint", o.AuthorizationEndpoint},
+		{"token_endpoint", o.TokenEndpoint},
+		{"jwks_uri", o.JWKSURI},
+	} {
+		if f.value == "" {
+			findings = append(findings, fmt.Sprintf("The discovery document has no %s", f.name))
+		}
+	}
+	switch {
+	case o.JWKSError != "":
+		findings = append(findings, "The JWKS could not be fetched, so ID tokens cannot be verified: "+o.JWKSError)
+	case o.JWKSURI != "" && o.JWKSKeys == 0:
+		findings = append(findings, "The JWKS has no keys, so ID tokens cannot be verified")
+	}
+	if v := TokenVerdict(o); v != "" && !strings.HasPrefix(v, "ok") {
+		findings = append(findings, v)
+	}
+	return findings
+}
+
+// TokenVerdict interprets the client_credentials probe: IdPs authenticate
+// the client before looking at the grant, so an error other than
+// invalid_client still shows the client ID and secret are right. It returns
+// "" when no probe was made, and a verdict starting with "ok" when the
+// client was accepted.
+func TokenVerdict(o *OIDCProbe) string {
+	switch {
+	case o == nil:
+		return ""
+	case o.TokenRequestError != "":
+		return "The token endpoint could not be reached from the api-server: " + o.TokenRequestError
+	case o.TokenStatus == 0:
+		return ""
+	case o.TokenStatus == 200:
+		return "ok: the IdP issued a token for the client credentials"
+	case o.TokenError == "invalid_client" || (o.TokenStatus == 401 && o.TokenError == ""):
+		detail := o.TokenErrorDescription
+		if detail == "" {
+			detail = fmt.Sprintf("HTTP %d", o.TokenStatus)
+		}
+		return "The IdP rejected the client ID or secret (" + detail + "); a rotated or expired secret is the usual cause"
+	case o.TokenError != "":
+		return fmt.Sprintf("ok: the IdP accepted the client and refused only the probe's grant (%s)", o.TokenError)
+	case o.TokenStatus >= 500:
+		return fmt.Sprintf("The token endpoint failed with HTTP %d", o.TokenStatus)
+	default:
+		return fmt.Sprintf("The token endpoint answered HTTP %d without an OAuth error; check the discovery URL points at the right IdP", o.TokenStatus)
+	}
+}
+
+func (p *Provider) samlFindings(now time.Time) []string {
+	var findings []string
+	for _, f := range []struct{ name, value string }{
+		{"IdP entity ID", p.IdPEntityID},
+		{"IdP SSO URL", p.IdPSSOURL},
+		{"SP entity ID", p.SPEntityID},
+	} {
+		if f.value == "" {
+			findings = append(findings, fmt.Sprintf("No %s is configured", f.name))
+		}
+	}
+	if probe := p.IdPSSOProbe; probe != nil {
+		switch {
+		case probe.Error != "":
+			findings = append(findings, "The IdP SSO URL could not be reached from the api-server: "+probe.Error)
+		case probe.Status == 404 || probe.Status >= 500:
+			findings = append(findings, fmt.Sprintf("The IdP SSO URL answered HTTP %d", probe.Status))
+		}
+	}
+
+	if p.IdPCert == "" {
+		findings = append(findings, "No IdP certificate is configured, so no SAML response can be verified")
+	} else {
+		findings = append(findings, certFindings("IdP", p.IdPCert, now)...)
+	}
+	if p.SPCert != "" {
+		findings = append(findings, certFindings("SP", p.SPCert, now)...)
+		if !p.HasSPPrivateKey {
+			findings = append(findings, "An SP certificate is configured without its private key")
+		}
+	}
+	return findings
+}
+
+func certFindings(which, raw string, now time.Time) []string {
+	cert, err := ParseCertificate(raw)
+	if err != nil {
+		return []string{fmt.Sprintf("The %s certificate cannot be parsed: %v", which, err)}
+	}
+	switch {
+	case now.After(cert.NotAfter):
+		return []string{fmt.Sprintf("The %s certificate expired on %s; every login fails signature validation until the IdP's new certificate is configured", which, cert.NotAfter.Format(time.DateOnly))}
+	case now.Before(cert.NotBefore):
+		return []string{fmt.Sprintf("The %s certificate is not valid until %s", which, cert.NotBefore.Format(time.DateOnly))}
+	case cert.NotAfter.Sub(now) < certWarning:
+		return []string{fmt.Sprintf("The %s certificate expires on %s; ask the customer for the IdP's next certificate", which, cert.NotAfter.Format(time.DateOnly))}
+	}
+	return nil
+}
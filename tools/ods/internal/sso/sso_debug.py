@@ -0,0 +1,188 @@
+"""Describe and probe a tenant's SSO providers.
+
+Bundled with ods and piped into `python -` on an api-server pod by `ods
+sso-debug`, so the probes leave from the same network as real logins and the
+client secrets never leave the pod. For every SSO provider row (or only the
+named one) it reports the non-secret config and:
+
+  - OIDC and Google: fetches the discovery document and its JWKS, then asks
+    the token endpoint for a client_credentials token. Most IdPs refuse that
+    grant for a login client, but they check the client first, so the error
+    tells a wrong client ID or secret apart from a working one.
+  - SAML: requests the IdP's SSO URL. The certificates are returned for ods
+    to check.
+
+Usage:
+    python - <schema> [<provider name>]
+
+Progress goes to stderr; the last line on stdout is a JSON object with
+"status" and "providers".
+"""
+
+from __future__ import annotations
+
+import json
+import sys
+from typing import Any
+
+TIMEOUT = 10
+GOOGLE_DISCOVERY_URL = "https://accounts.google.com/.well-known/openid-configuration"
+
+
+def use_schema(schema: str) -> str:
+    from onyx.db.engine.tenant_utils import validate_tenant_id
+    from shared_configs.configs import MULTI_TENANT
+    from shared_configs.configs import POSTGRES_DEFAULT_SCHEMA
+    from shared_configs.contextvars import CURRENT_TENANT_ID_CONTEXTVAR
+
+    if not schema:
+        if MULTI_TENANT:
+            raise ValueError("This deployment is multi-tenant; pass --tenant")
+        schema = POSTGRES_DEFAULT_SCHEMA
+    elif schema != POSTGRES_DEFAULT_SCHEMA and not validate_tenant_id(schema):
+        raise ValueError(f"Invalid schema {schema!r}")
+    CURRENT_TENANT_ID_CONTEXTVAR.set(schema)
+    return schema
+
+
+def probe_oidc(discovery_url: str, client_id: str, client_secret: str) -> dict[str, Any]:
+    import requests
+
+    out: dict[str, Any] = {"discovery_url": discovery_url}
+    try:
+        resp = requests.get(discovery_url, timeout=TIMEOUT)
+        resp.raise_for_status()
+        doc = resp.json()
+    except Exception as e:
+        out["discovery_error"] = f"{type(e).__name__}: {e}"
+        return out
+    for key in ("issuer", "authorization_endpoint", "token_endpoint", "jwks_uri"):
+        out[key] = doc.get(key, "")
+
+    if out["jwks_uri"]:
+        try:
+            resp = requests.get(out["jwks_uri"], timeout=TIMEOUT)
+            resp.raise_for_status()
+            out["jwks_keys"] = len(resp.json().get("keys", []))
+        except Exception as e:
+            out["jwks_error"] = f"{type(e).__name__}: {e}"
+
+    if out["token_endpoint"] and client_secret:
+        print(f"Probing {out['token_endpoint']}...", file=sys.stderr)
+        try:
+            resp = requests.post(
+                out["token_endpoint"],
+                data={"grant_type": "client_credentials"},
+                auth=(client_id, client_secret),
+                timeout=TIMEOUT,
+            )
+            out["token_status"] = resp.status_code
+            try:
+                body = resp.json()
+                out["token_error"] = body.get("error", "")
+                out["token_error_description"] = body.get("error_description", "")
+            except ValueError:
+                pass
+        except Exception as e:
+            out["token_request_error"] = f"{type(e).__name__}: {e}"
+    return out
+
+
+def probe_url(url: str) -> dict[str, Any]:
+    import requests
+
+    try:
+        resp = requests.get(url, timeout=TIMEOUT, allow_redirects=False)
+        return {"status": resp.status_code}
+    except Exception as e:
+        return {"error": f"{type(e).__name__}: {e}"}
+
+
+def describe(provider: Any, web_domain: str) -> dict[str, Any]:
+    from onyx.db.enums import SSOProviderType
+    from onyx.db.sso_provider import sso_login_callback_uri
+
+    config = provider.config.get_value(apply_mask=False) if provider.config else {}
+    ptype = provider.provider_type
+    out: dict[str, Any] = {
+        "name": provider.name,
+        "display_name": provider.display_name,
+        "type": ptype.value,
+        "enabled": provider.enabled,
+        "allowed_email_domains": list(provider.allowed_email_domains or []),
+        "callback_url": sso_login_callback_uri(provider, config, web_domain),
+        "updated": provider.time_updated.isoformat(),
+    }
+    print(f"Checking {provider.name} ({ptype.value})...", file=sys.stderr)
+
+    if ptype is SSOProviderType.SAML:
+        for key in ("idp_entity_id", "idp_sso_url", "sp_entity_id", "email_attribute"):
+            out[key] = config.get(key) or ""
+        out["idp_x509_cert"] = config.get("idp_x509_cert") or ""
+        out["sp_x509_cert"] = config.get("sp_x509_cert") or ""
+        out["has_sp_private_key"] = bool(config.get("sp_private_key"))
+        if out["idp_sso_url"]:
+            out["idp_sso_probe"] = probe_url(out["idp_sso_url"])
+        return out
+
+    out["client_id"] = config.get("client_id") or ""
+    out["has_client_secret"] = bool(config.get("client_secret"))
+    out["require_verified_email"] = bool(config.get("require_verified_email"))
+    discovery_url = config.get("openid_config_url") or GOOGLE_DISCOVERY_URL
+    out["oidc"] = probe_oidc(
+        discovery_url, out["client_id"], config.get("client_secret") or ""
+    )
+    return out
+
+
+def inspect(schema: str, name: str | None) -> dict[str, Any]:
+    from onyx.configs.app_configs import WEB_DOMAIN
+    from onyx.db.engine.sql_engine import get_session_with_current_tenant
+    from onyx.db.sso_provider import fetch_sso_provider_by_name
+    from onyx.db.sso_provider import fetch_sso_providers
+
+    use_schema(schema)
+    with get_session_with_current_tenant() as db_session:
+        if name:
+            provider = fetch_sso_provider_by_name(db_session, name)
+            if provider is None:
+                return {
+                    "status": "not_found",
+                    "message": f"No SSO provider named {name!r}",
+                }
+            providers = [provider]
+        else:
+            providers = fetch_sso_providers(db_session)
+        return {
+            "status": "success",
+            "web_domain": WEB_DOMAIN,
+            "providers": [describe(p, WEB_DOMAIN) for p in providers],
+        }
+
+
+def main() -> None:
+    if len(sys.argv) not in (2, 3):
+        print(
+            json.dumps(
+                {
+                    "status": "error",
+                    "message": "Usage: python - <schema> [<provider name>]",
+                }
+            )
+        )
+        sys.exit(1)
+
+    from onyx.db.engine.sql_engine import SqlEngine
+
+    SqlEngine.init_engine(pool_size=5, max_overflow=2)
+
+    try:
+        result = inspect(sys.argv[1], sys.argv[2] if len(sys.argv) == 3 else None)
+    except Exception as e:
+        print(f"Error: {e}", file=sys.stderr)
+        result = {"status": "error", "message": str(e)}
+    print(json.dumps(result))
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,161 @@
+package sso
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/token"
+)
+
+var now = time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+
+func testCert(t *testing.T, notAfter time.Time) string {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: "idp"},
+		NotBefore:    notAfter.AddDate(-1, 0, 0),
+		NotAfter:     notAfter,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return base64.StdEncoding.EncodeToString(der)
+}
+
+func joined(findings []string) string {
+	return strings.Join(findings, "\n")
+}
+
+func TestParseReport(t *testing.T) {
+	out := "Checking okta (OIDC)...\n" + `{"status": "success", "web_domain": "https://acme.onyx.app", "providers": [{"name": "okta", "type": "OIDC", "enabled": true, "client_id": "abc", "has_client_secret": true, "oidc": {"issuer": "https://acme.okta.com", "token_endpoint": "https://acme.okta.com/token", "token_status": 401, "token_error": "invalid_client"}}]}`
+	r, err := parseReport(out)
+	if err != nil {
+		t.Fatalf("parseReport() error: %v", err)
+	}
+	if len(r.Providers) != 1 || r.Providers[0].OIDC.TokenError != "invalid_client" {
+		t.Fatalf("unexpected report %+v", r)
+	}
+	if f := joined(r.Providers[0].Findings(now)); !strings.Contains(f, "rejected the client ID or secret") || !strings.Contains(f, "no jwks_uri") {
+		t.Errorf("unexpected findings:\n%s", f)
+	}
+
+	if _, err := parseReport(`{"status": "not_found", "message": "No SSO provider named 'x'"}`); err == nil || !strings.Contains(err.Error(), "No SSO provider") {
+		t.Errorf("expected the script's message as error, got %v", err)
+	}
+}
+
+func TestTokenVerdict(t *testing.T) {
+	for _, tc := range []struct {
+		probe *OIDCProbe
+		want  string
+	}{
+		{&OIDCProbe{}, ""},
+		{&OIDCProbe{TokenStatus: 400, TokenError: "unauthorized_client"}, "ok: the IdP accepted the client"},
+		{&OIDCProbe{TokenStatus: 401}, "rejected the client ID or secret (HTTP 401)"},
+		{&OIDCProbe{TokenRequestError: "timeout"}, "could not be reached"},
+	} {
+		got := TokenVerdict(tc.probe)
+		if (tc.want == "" && got != "") || !strings.Contains(got, tc.want) {
+			t.Errorf("TokenVerdict(%+v) = %q, want %q", tc.probe, got, tc.want)
+		}
+	}
+}
+
+func TestSAMLFindings(t *testing.T) {
+	p := &Provider{Type: TypeSAML, Enabled: true, IdPEntityID: "idp", IdPSSOURL: "https://idp/sso", SPEntityID: "sp",
+		IdPCert: testCert(t, now.AddDate(0, 0, 10)), IdPSSOProbe: &URLProbe{Status: 302}}
+	if f := joined(p.Findings(now)); !strings.Contains(f, "IdP certificate expires on 2026-06-11") {
+		t.Errorf("expected an expiry warning, got:\n%s", f)
+	}
+	p.IdPCert = testCert(t, now.AddDate(-1, 0, 0))
+	if f := joined(p.Findings(now)); !strings.Contains(f, "IdP certificate expired") {
+		t.Errorf("expected an expired certificate, got:\n%s", f)
+	}
+	p.IdPCert = testCert(t, now.AddDate(1, 0, 0))
+	if f := p.Findings(now); len(f) != 0 {
+		t.Errorf("expected no findings, got:\n%s", joined(f))
+	}
+}
+
+func TestSAMLResponse(t *testing.T) {
+	configured := testCert(t, now.AddDate(1, 0, 0))
+	rotated := testCert(t, now.AddDate(2, 0, 0))
+	xml := `<samlp:Response xmlns:samlp="urn:oasis:names:tc:SAML:2.0:protocol" xmlns:saml="urn:oasis:names:tc:SAML:2.0:assertion" Destination="https://acme.onyx.app/auth/saml/callback">
+  <saml:Issuer>idp</saml:Issuer>
+  <samlp:Status><samlp:StatusCode Value="urn:oasis:names:tc:SAML:2.0:status:Success"/></samlp:Status>
+  <saml:Assertion>
+    <saml:Issuer>idp</saml:Issuer>
+    <ds:Signature xmlns:ds="http://www.w3.org/2000/09/xmldsig#"><ds:KeyInfo><ds:X509Data><ds:X509Certificate>` + rotated + `</ds:X509Certificate></ds:X509Data></ds:KeyInfo></ds:Signature>
+    <saml:Subject><saml:NameID Format="urn:oasis:names:tc:SAML:1.1:nameid-format:emailAddress">jane@acme.com</saml:NameID></saml:Subject>
+    <saml:Conditions NotBefore="2026-06-01T11:59:00Z" NotOnOrAfter="2026-06-01T12:04:00Z">
+      <saml:AudienceRestriction><saml:Audience>https://wrong.example</saml:Audience></saml:AudienceRestriction>
+    </saml:Conditions>
+    <saml:AttributeStatement>
+      <saml:Attribute Name="firstName"><saml:AttributeValue>Jane</saml:AttributeValue></saml:Attribute>
+    </saml:AttributeStatement>
+  </saml:Assertion>
+</samlp:Response>`
+
+	r, err := DecodeSAMLResponse("SAMLResponse=" + base64.StdEncoding.EncodeToString([]byte(xml)))
+	if err != nil {
+		t.Fatalf("DecodeSAMLResponse() error: %v", err)
+	}
+	if r.Issuer != "idp" || r.Assertion == nil || r.Assertion.NameID != "jane@acme.com" || r.Assertion.Attributes["firstName"][0] != "Jane" {
+		t.Fatalf("unexpected response %+v", r)
+	}
+
+	p := &Provider{Type: TypeSAML, IdPEntityID: "idp", SPEntityID: "sp", IdPCert: configured, CallbackURL: "https://acme.onyx.app/auth/saml/callback"}
+	f := joined(r.Check(p))
+	for _, want := range []string{"other than the configured IdP certificate", "does not include the SP entity ID", "No email attribute", "firstName"} {
+		if !strings.Contains(f, want) {
+			t.Errorf("Check() missing %q:\n%s", want, f)
+		}
+	}
+
+	p.EmailAttribute = "firstName"
+	p.AllowedEmailDomains = []string{"acme.com"}
+	if f := joined(r.Check(p)); !strings.Contains(f, "outside the provider's allowed domains") {
+		t.Errorf("expected the configured attribute to be read, got:\n%s", f)
+	}
+
+	if _, err := DecodeSAMLResponse("not a response"); err == nil {
+		t.Error("expected garbage to be rejected")
+	}
+}
+
+func TestCheckIDToken(t *testing.T) {
+	enc := func(v any) string {
+		data, _ := json.Marshal(v)
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	raw := enc(map[string]any{"alg": "RS256"}) + "." + enc(map[string]any{
+		"iss": "https://login.example/other", "aud": []string{"someone-else"},
+		"exp": now.Add(-time.Hour).Unix(), "email": "jane@acme.com", "email_verified": false,
+	}) + ".c2ln"
+	tok, err := token.Decode(raw)
+	if err != nil {
+		t.Fatalf("token.Decode() error: %v", err)
+	}
+	p := &Provider{Type: TypeOIDC, ClientID: "onyx", OIDC: &OIDCProbe{Issuer: "https://login.example/acme"}}
+	f := joined(CheckIDToken(tok, p, now))
+	for _, want := range []string{"not the discovery document's issuer", "does not include the client ID", "expired", "email_verified is false"} {
+		if !strings.Contains(f, want) {
+			t.Errorf("CheckIDToken() missing %q:\n%s", want, f)
+		}
+	}
+}
@@ -0,0 +1,112 @@
+// Package supportbundle writes the tarball `ods support-bundle` collects a
+// deployment's diagnostics into, redacting every file on the way in (unless
+// told not to) so the bundle can be attached to a public issue.
+package supportbundle
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/redact"
+)
+
+// Bundle writes files into a gzipped tarball under a top-level directory.
+// Collection is best effort: a step that fails is recorded in errors.txt and
+// the rest of the bundle is still written.
+type Bundle struct {
+	dir string
+	// Raw turns redaction off.
+	Raw    bool
+	tw     *tar.Writer
+	gz     *gzip.Writer
+	now    time.Time
+	files  []string
+	errors []string
+}
+
+// New starts a bundle written to w, with its files under dir.
+func New(w io.Writer, dir string) *Bundle {
+	gz := gzip.NewWriter(w)
+	return &Bundle{dir: dir, gz: gz, tw: tar.NewWriter(gz), now: time.Now()}
+}
+
+// Add redacts data and writes it to the bundle as name.
+func (b *Bundle) Add(name string, data []byte) error {
+	return b.addRaw(name, []byte(b.redact(string(data))))
+}
+
+func (b *Bundle) redact(s string) string {
+	if b.Raw {
+		return s
+	}
+	return redact.String(s)
+}
+
+func (b *Bundle) addRaw(name string, data []byte) error {
+	hdr := &tar.Header{
+		Name:    path.Join(b.dir, name),
+		Mode:    0o644,
+		Size:    int64(len(data)),
+		ModTime: b.now,
+	}
+	if err := b.tw.WriteHeader(hdr); err != nil {
+		return err
+	}
+	if _, err := b.tw.Write(data); err != nil {
+		return err
+	}
+	b.files = append(b.files, name)
+	return nil
+}
+
+// Fail records that step could not be collected.
+func (b *Bundle) Fail(step string, err error) {
+	b.errors = append(b.errors, fmt.Sprintf("%s: %s", step, b.redact(err.Error())))
+}
+
+// Errors returns the failed steps.
+func (b *Bundle) Errors() []string {
+	return b.errors
+}
+
+// Close writes the README and errors.txt and finishes the tarball.
+func (b *Bundle) Close(summary string) error {
+	if len(b.errors) > 0 {
+		if err := b.addRaw("errors.txt", []byte(strings.Join(b.errors, "\n")+"\n")); err != nil {
+			return err
+		}
+	}
+	files := append([]string(nil), b.files...)
+	sort.Strings(files)
+	redaction := `Credentials (values of keys such as *_PASSWORD, *_SECRET, *_API_KEY and
+*_TOKEN, API keys, tokens and URL passwords) were replaced with
+` + redact.Placeholder + `, and email addresses, card numbers and SSNs with
+placeholders. Review the files before sharing: logs can still contain
+document text and other data from your deployment.`
+	if b.Raw {
+		redaction = "This bundle was collected WITHOUT redaction. Do not share it publicly."
+	}
+	readme := fmt.Sprintf(`Onyx support bundle
+Collected %s by ods support-bundle.
+
+%s
+
+%s
+
+Files:
+  %s
+`, b.now.UTC().Format(time.RFC3339), summary, redaction, strings.Join(files, "\n  "))
+	if err := b.addRaw("README.txt", []byte(readme)); err != nil {
+		return err
+	}
+	if err := b.tw.Close(); err != nil {
+		return err
+	}
+	return b.gz.Close()
+}
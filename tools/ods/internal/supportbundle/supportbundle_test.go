@@ -0,0 +1,52 @@
+package supportbundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/redact"
+)
+
+func TestBundle(t *testing.T) {
+	var buf bytes.Buffer
+	b := New(&buf, "onyx-support-x")
+	if err := b.Add("config/env", []byte("SECRET=abc\nLOG_LEVEL=debug\n")); err != nil {
+		t.Fatal(err)
+	}
+	b.Fail("health.json", errors.New("dial postgresql://u:p@db failed"))
+	if err := b.Close("Deployment: test."); err != nil {
+		t.Fatal(err)
+	}
+
+	gz, err := gzip.NewReader(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tr := tar.NewReader(gz)
+	files := map[string]string{}
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, _ := io.ReadAll(tr)
+		files[hdr.Name] = string(data)
+	}
+	if got := files["onyx-support-x/config/env"]; got != "SECRET="+redact.Placeholder+"\nLOG_LEVEL=debug\n" {
+		t.Errorf("config/env = %q", got)
+	}
+	if got := files["onyx-support-x/errors.txt"]; got != "health.json: dial postgresql://u:"+redact.Placeholder+"@db failed\n" {
+		t.Errorf("errors.txt = %q", got)
+	}
+	if !strings.Contains(files["onyx-support-x/README.txt"], "Deployment: test.") {
+		t.Errorf("README.txt = %q", files["onyx-support-x/README.txt"])
+	}
+}
@@ -0,0 +1,146 @@
+// Package telemetry sends anonymous, opt-in usage events for ods itself:
+// which commands run, how long they take and, when they fail, a coarse
+// category of why. Arguments, paths, messages and anything identifying the
+// user are never collected.
+package telemetry
+
+import (
+	"bytes"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/config"
+)
+
+// sendTimeout bounds how long a command's exit may wait on the sink.
+const sendTimeout = 2 * time.Second
+
+// defaultEndpoint receives events when the config names no endpoint. It is
+// empty unless the release build sets it with -ldflags "-X
+// github.com/onyx-dot-app/onyx/tools/ods/internal/telemetry.defaultEndpoint=<url>",
+// so binaries built from source cannot send telemetry.
+var defaultEndpoint string
+
+// Event is one ods run.
+type Event struct {
+	InstallID string `json:"install_id"`
+	// Command is the command path without "ods" or any arguments, e.g.
+	// "compose list-projects".
+	Command    string    `json:"command"`
+	DurationMS int64     `json:"duration_ms"`
+	OK         bool      `json:"ok"`
+	Error      string    `json:"error_category,omitempty"`
+	Version    string    `json:"version"`
+	OS         string    `json:"os"`
+	Arch       string    `json:"arch"`
+	Time       time.Time `json:"time"`
+}
+
+// Sink receives events.
+type Sink interface {
+	Send(e Event) error
+}
+
+// ErrDisabled means telemetry is off: not opted into, turned off by the
+// environment, or not built into this binary.
+var ErrDisabled = errors.New("telemetry is disabled")
+
+// Available reports whether this build can send telemetry at all. Only
+// release builds have a default endpoint.
+func Available() bool {
+	return defaultEndpoint != ""
+}
+
+// Endpoint returns where events go: the configured override, else the
+// build's default.
+func Endpoint(cfg config.TelemetryConfig) string {
+	if !Available() {
+		return ""
+	}
+	if cfg.Endpoint != "" {
+		return cfg.Endpoint
+	}
+	return defaultEndpoint
+}
+
+// EnvDisabled reports whether the environment turns telemetry off
+// regardless of the opt-in: DO_NOT_TRACK=1 or ODS_TELEMETRY=0.
+func EnvDisabled() bool {
+	return os.Getenv("DO_NOT_TRACK") == "1" || os.Getenv("ODS_TELEMETRY") == "0"
+}
+
+// NewSink returns the sink for the configuration, or ErrDisabled.
+func NewSink(cfg config.TelemetryConfig) (Sink, error) {
+	endpoint := Endpoint(cfg)
+	if !cfg.Enabled || cfg.InstallID == "" || endpoint == "" || EnvDisabled() {
+		return nil, ErrDisabled
+	}
+	return &HTTPSink{URL: endpoint}, nil
+}
+
+// NewInstallID returns a random installation ID.
+func NewInstallID() string {
+	var b [16]byte
+	_, _ = rand.Read(b[:])
+	return hex.EncodeToString(b[:])
+}
+
+// HTTPSink posts each event as JSON.
+type HTTPSink struct {
+	URL string
+}
+
+// Send posts e, giving up after sendTimeout.
+func (s *HTTPSink) Send(e Event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+	client := &http.Client{Timeout: sendTimeout}
+	resp, err := client.Post(s.URL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	defer func() { _ = resp.Body.Close() }()
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("telemetry endpoint returned %s", resp.Status)
+	}
+	return nil
+}
+
+// errorCategories map substrings of a failure message, checked in order, to
+// the category sent in its place. The message itself is never sent.
+var errorCategories = []struct {
+	category string
+	needles  []string
+}{
+	{"timeout", []string{"timeout", "timed out", "deadline exceeded"}},
+	{"auth", []string{"unauthorized", "forbidden", "401", "403", "credential", "sso", "token", "expired"}},
+	{"permission", []string{"permission denied", "operation not permitted"}},
+	{"network", []string{"connection refused", "no such host", "network", "dial tcp", "connection reset", "tls"}},
+	{"not-found", []string{"not found", "no such file", "does not exist", "no running"}},
+	{"docker", []string{"docker", "container", "compose"}},
+	{"kubernetes", []string{"kubectl", "pod", "cluster", "context"}},
+	{"git", []string{"git "}},
+	{"invalid-input", []string{"invalid", "unknown", "expected", "must be", "required"}},
+}
+
+// Categorize reduces a failure message to a coarse category.
+func Categorize(message string) string {
+	m := strings.ToLower(message)
+	for _, c := range errorCategories {
+		for _, n := range c.needles {
+			if strings.Contains(m, n) {
+				return c.category
+			}
+		}
+	}
+	return "other"
+}
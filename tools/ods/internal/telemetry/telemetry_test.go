@@ -0,0 +1,70 @@
+package telemetry
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/config"
+)
+
+func TestCategorize(t *testing.T) {
+	tests := map[string]string{
+		"Failed to reach api: dial tcp 10.0.0.1:443: connection refused": "network",
+		"kubectl exec failed: context deadline exceeded":                 "timeout",
+		"AWS SSO session expired, run aws sso login":                     "auth",
+		"open /root/.env: no such file or directory":                     "not-found",
+		"Invalid --fail-on \"urgent\"":                                   "invalid-input",
+		"something else went wrong":                                      "other",
+	}
+	for msg, want := range tests {
+		if got := Categorize(msg); got != want {
+			t.Errorf("Categorize(%q) = %q, want %q", msg, got, want)
+		}
+	}
+}
+
+func TestNewSink(t *testing.T) {
+	t.Setenv("DO_NOT_TRACK", "")
+	t.Setenv("ODS_TELEMETRY", "")
+	if _, err := NewSink(config.TelemetryConfig{}); err != ErrDisabled {
+		t.Errorf("not opted in: err = %v, want ErrDisabled", err)
+	}
+	if Available() {
+		t.Error("expected a build without -ldflags to have no endpoint")
+	}
+	cfg := config.TelemetryConfig{Enabled: true, InstallID: "abc", Endpoint: "http://localhost"}
+	if _, err := NewSink(cfg); err != ErrDisabled {
+		t.Errorf("override without a built-in endpoint: err = %v, want ErrDisabled", err)
+	}
+
+	defaultEndpoint = "http://telemetry.example"
+	t.Cleanup(func() { defaultEndpoint = "" })
+	if _, err := NewSink(cfg); err != nil {
+		t.Errorf("opted in: err = %v", err)
+	}
+	t.Setenv("DO_NOT_TRACK", "1")
+	if _, err := NewSink(cfg); err != ErrDisabled {
+		t.Errorf("DO_NOT_TRACK: err = %v, want ErrDisabled", err)
+	}
+}
+
+func TestHTTPSink(t *testing.T) {
+	var got Event
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if err := json.NewDecoder(r.Body).Decode(&got); err != nil {
+			t.Error(err)
+		}
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer srv.Close()
+
+	sink := &HTTPSink{URL: srv.URL}
+	if err := sink.Send(Event{InstallID: "abc", Command: "compose", DurationMS: 1200, OK: true}); err != nil {
+		t.Fatal(err)
+	}
+	if got.Command != "compose" || got.DurationMS != 1200 || !got.OK {
+		t.Errorf("received %+v", got)
+	}
+}
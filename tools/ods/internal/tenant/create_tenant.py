@@ -0,0 +1,124 @@
+"""Provision a tenant with a verified admin user.
+
+Bundled with ods and piped into `python -` on an api-server pod by
+`ods tenant create`. Goes through UserManager.create, the same path as web
+signup, so the tenant is provisioned (or taken from the pre-provisioned
+pool), migrated and seeded exactly as for a real customer. The admin gets a
+random password; the user is created verified so it can log in straight away.
+
+Usage:
+    python - <admin_email>
+
+Progress goes to stderr; the last line on stdout is a JSON object with
+"status" and, on success, "tenant_id", "email", "password" and "web_domain".
+"""
+
+from __future__ import annotations
+
+import asyncio
+import contextlib
+import json
+import secrets
+import string
+import sys
+from typing import Any
+
+
+def random_password() -> str:
+    # Satisfies the default password policy: upper, lower, digit, special.
+    specials = "!@#$%^&*-_=+"
+    chars = [
+        secrets.choice(string.ascii_uppercase),
+        secrets.choice(string.ascii_lowercase),
+        secrets.choice(string.digits),
+        secrets.choice(specials),
+    ]
+    alphabet = string.ascii_letters + string.digits + specials
+    chars += [secrets.choice(alphabet) for _ in range(16)]
+    secrets.SystemRandom().shuffle(chars)
+    return "".join(chars)
+
+
+def existing_tenant(email: str) -> str | None:
+    from onyx.utils.variable_functionality import fetch_ee_implementation_or_noop
+
+    try:
+        return fetch_ee_implementation_or_noop(
+            "onyx.server.tenants.user_mapping", "get_tenant_id_for_email", None
+        )(email)
+    except Exception:
+        return None
+
+
+async def create(email: str) -> dict[str, Any]:
+    from onyx.auth.schemas import UserCreate
+    from onyx.auth.users import get_user_manager
+    from onyx.configs.app_configs import WEB_DOMAIN
+    from onyx.db.auth import get_user_db
+    from onyx.db.engine.async_sql_engine import get_async_session_context_manager
+    from shared_configs.configs import MULTI_TENANT
+
+    if not MULTI_TENANT:
+        return {
+            "status": "error",
+            "message": "This deployment is not multi-tenant",
+        }
+    if tenant_id := existing_tenant(email):
+        return {
+            "status": "exists",
+            "message": f"{email} already belongs to {tenant_id}",
+        }
+
+    password = random_password()
+    get_user_db_context = contextlib.asynccontextmanager(get_user_db)
+    get_user_manager_context = contextlib.asynccontextmanager(get_user_manager)
+
+    print(f"Provisioning a tenant for {email}...", file=sys.stderr)
+    async with get_async_session_context_manager() as session:
+        async with get_user_db_context(session) as user_db:
+            async with get_user_manager_context(user_db) as user_manager:
+                user = await user_manager.create(
+                    UserCreate(email=email, password=password, is_verified=True),
+                    safe=False,
+                    request=None,
+                )
+
+    tenant_id = existing_tenant(email)
+    if not tenant_id:
+        return {
+            "status": "error",
+            "message": f"Created {email} but found no tenant mapping for it",
+        }
+    return {
+        "status": "success",
+        "tenant_id": tenant_id,
+        "email": email,
+        "role": str(user.role.value),
+        "password": password,
+        "web_domain": WEB_DOMAIN,
+    }
+
+
+def main() -> None:
+    if len(sys.argv) != 2:
+        print(
+            json.dumps(
+                {"status": "error", "message": "Usage: python - <admin_email>"}
+            )
+        )
+        sys.exit(1)
+
+    from onyx.db.engine.sql_engine import SqlEngine
+
+    SqlEngine.init_engine(pool_size=5, max_overflow=2)
+
+    try:
+        result = asyncio.run(create(sys.argv[1]))
+    except Exception as e:
+        print(f"Error: {e}", file=sys.stderr)
+        result = {"status": "error", "message": str(e)}
+    print(json.dumps(result))
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,131 @@
+package tenant
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Usage is what a tenant still has in the database, used to decide whether
+// it is safe to delete.
+type Usage struct {
+	SchemaExists bool
+	HasChats     bool
+	Users        int
+	ActiveUsers  int
+	// LastActivity is the newest chat message; zero when there are none.
+	LastActivity time.Time
+	// PublicRows counts the tenant's rows in each public table with a
+	// tenant_id column.
+	PublicRows map[string]int
+}
+
+// UsageSQL returns whether the schema exists, whether it has chat messages
+// to check for activity, and the tenant's total and active user mappings.
+func UsageSQL(tenantID string) string {
+	return fmt.Sprintf(`SELECT
+  (SELECT count(*) FROM information_schema.schemata WHERE schema_name = '%[1]s'),
+  to_regclass('"%[1]s".chat_message') IS NOT NULL,
+  (SELECT count(*) FROM public.user_tenant_mapping WHERE tenant_id = '%[1]s'),
+  (SELECT count(*) FROM public.user_tenant_mapping WHERE tenant_id = '%[1]s' AND active)`, tenantID)
+}
+
+// LastActivitySQL returns the time of the tenant's newest chat message.
+func LastActivitySQL(tenantID string) string {
+	return fmt.Sprintf(`SELECT COALESCE(to_char(max(time_sent) AT TIME ZONE 'UTC', 'YYYY-MM-DD"T"HH24:MI:SS"Z"'), '') FROM "%s".chat_message`, tenantID)
+}
+
+// PublicTablesSQL lists the public tables keyed by tenant_id.
+const PublicTablesSQL = `SELECT table_name FROM information_schema.columns
+WHERE table_schema = 'public' AND column_name = 'tenant_id' ORDER BY table_name`
+
+// PublicRowsSQL counts the tenant's rows in each of tables.
+func PublicRowsSQL(tenantID string, tables []string) string {
+	parts := make([]string, len(tables))
+	for i, t := range tables {
+		parts[i] = fmt.Sprintf(`SELECT '%[1]s', count(*) FROM public."%[1]s" WHERE tenant_id = '%[2]s'`, t, tenantID)
+	}
+	return strings.Join(parts, "\nUNION ALL\n")
+}
+
+// ParseUsage reads the output of UsageSQL. LastActivity and PublicRows are
+// filled in from ParseLastActivity and ParsePublicRows.
+func ParseUsage(usage []string) (*Usage, error) {
+	if len(usage) != 1 {
+		return nil, fmt.Errorf("unexpected tenant usage output: %q", usage)
+	}
+	f := strings.Split(usage[0], "\t")
+	if len(f) != 4 {
+		return nil, fmt.Errorf("unexpected tenant usage row: %q", usage[0])
+	}
+	u := &Usage{
+		SchemaExists: f[0] == "1",
+		HasChats:     f[1] == "t",
+		PublicRows:   map[string]int{},
+	}
+	var err error
+	if u.Users, err = strconv.Atoi(f[2]); err != nil {
+		return nil, fmt.Errorf("unexpected tenant usage row: %q", usage[0])
+	}
+	if u.ActiveUsers, err = strconv.Atoi(f[3]); err != nil {
+		return nil, fmt.Errorf("unexpected tenant usage row: %q", usage[0])
+	}
+	return u, nil
+}
+
+// ParseLastActivity reads the output of LastActivitySQL, returning the zero
+// time when the tenant has no chat messages.
+func ParseLastActivity(lines []string) (time.Time, error) {
+	if len(lines) == 0 || strings.TrimSpace(lines[0]) == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, strings.TrimSpace(lines[0]))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("unexpected last activity %q", lines[0])
+	}
+	return t, nil
+}
+
+// ParsePublicRows reads the output of PublicRowsSQL.
+func ParsePublicRows(lines []string) (map[string]int, error) {
+	rows := make(map[string]int, len(lines))
+	for _, line := range lines {
+		table, count, ok := strings.Cut(line, "\t")
+		n, err := strconv.Atoi(count)
+		if !ok || err != nil {
+			return nil, fmt.Errorf("unexpected row count %q", line)
+		}
+		rows[table] = n
+	}
+	return rows, nil
+}
+
+// Inactive reports whether the tenant can be deleted: no active users and no
+// chat activity within idle of now. Otherwise it returns why not.
+func (u *Usage) Inactive(idle time.Duration, now time.Time) (bool, string) {
+	if u.ActiveUsers > 0 {
+		return false, fmt.Sprintf("%d active user(s)", u.ActiveUsers)
+	}
+	if !u.LastActivity.IsZero() && now.Sub(u.LastActivity) < idle {
+		return false, "chat activity at " + u.LastActivity.Format(time.RFC3339)
+	}
+	return true, ""
+}
+
+// DeleteSQL drops the tenant's schema and its rows in the public tables in
+// one transaction.
+func DeleteSQL(tenantID string, tables []string) string {
+	var b strings.Builder
+	b.WriteString("BEGIN;\n")
+	for _, t := range tables {
+		fmt.Fprintf(&b, "DELETE FROM public.\"%s\" WHERE tenant_id = '%s';\n", t, tenantID)
+	}
+	fmt.Fprintf(&b, "DROP SCHEMA IF EXISTS \"%s\" CASCADE;\nCOMMIT;", tenantID)
+	return b.String()
+}
+
+// ArchiveName is the file name of a tenant's schema snapshot taken at t.
+func ArchiveName(tenantID string, t time.Time) string {
+	return tenantID + "-" + t.UTC().Format("20060102T150405Z") + ".dump"
+}
@@ -0,0 +1,97 @@
+package tenant
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseUsage(t *testing.T) {
+	u, err := ParseUsage([]string{"1\tt\t3\t0"})
+	if err != nil {
+		t.Fatalf("ParseUsage() error: %v", err)
+	}
+	if !u.SchemaExists || !u.HasChats || u.Users != 3 || u.ActiveUsers != 0 {
+		t.Errorf("unexpected usage %+v", u)
+	}
+
+	u, err = ParseUsage([]string{"0\tf\t0\t0"})
+	if err != nil || u.SchemaExists || u.HasChats {
+		t.Errorf("ParseUsage() = %+v, %v", u, err)
+	}
+
+	if _, err := ParseUsage([]string{"1\tt"}); err == nil {
+		t.Error("expected an error for a short row")
+	}
+}
+
+func TestParseLastActivity(t *testing.T) {
+	got, err := ParseLastActivity([]string{"2026-01-02T03:04:05Z"})
+	if err != nil || !got.Equal(time.Date(2026, 1, 2, 3, 4, 5, 0, time.UTC)) {
+		t.Errorf("ParseLastActivity() = %v, %v", got, err)
+	}
+	if got, err := ParseLastActivity([]string{""}); err != nil || !got.IsZero() {
+		t.Errorf("expected zero time for no messages, got %v, %v", got, err)
+	}
+	if _, err := ParseLastActivity([]string{"yesterday"}); err == nil {
+		t.Error("expected an error for a bad timestamp")
+	}
+}
+
+func TestParsePublicRows(t *testing.T) {
+	rows, err := ParsePublicRows([]string{"user_tenant_mapping\t3", "tenant_invite_counter\t0"})
+	if err != nil || rows["user_tenant_mapping"] != 3 || rows["tenant_invite_counter"] != 0 {
+		t.Errorf("ParsePublicRows() = %v, %v", rows, err)
+	}
+	if _, err := ParsePublicRows([]string{"user_tenant_mapping"}); err == nil {
+		t.Error("expected an error for a row without a count")
+	}
+}
+
+func TestInactive(t *testing.T) {
+	now := time.Date(2026, 3, 1, 0, 0, 0, 0, time.UTC)
+	idle := 30 * 24 * time.Hour
+	tests := []struct {
+		name string
+		u    Usage
+		want bool
+	}{
+		{"no users or chats", Usage{}, true},
+		{"active user", Usage{ActiveUsers: 1}, false},
+		{"recent chat", Usage{LastActivity: now.Add(-time.Hour)}, false},
+		{"old chat", Usage{LastActivity: now.Add(-60 * 24 * time.Hour)}, true},
+	}
+	for _, tt := range tests {
+		got, reason := tt.u.Inactive(idle, now)
+		if got != tt.want {
+			t.Errorf("%s: Inactive() = %v (%s), want %v", tt.name, got, reason, tt.want)
+		}
+		if !got && reason == "" {
+			t.Errorf("%s: expected a reason", tt.name)
+		}
+	}
+}
+
+func TestDeleteSQL(t *testing.T) {
+	sql := DeleteSQL("tenant_abc", []string{"user_tenant_mapping"})
+	for _, want := range []string{
+		"BEGIN;",
+		`DELETE FROM public."user_tenant_mapping" WHERE tenant_id = 'tenant_abc';`,
+		`DROP SCHEMA IF EXISTS "tenant_abc" CASCADE;`,
+		"COMMIT;",
+	} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("DeleteSQL missing %q:\n%s", want, sql)
+		}
+	}
+	if strings.Index(sql, "DELETE") > strings.Index(sql, "DROP") {
+		t.Error("mapping rows should be deleted before the schema is dropped")
+	}
+}
+
+func TestArchiveName(t *testing.T) {
+	got := ArchiveName("tenant_abc", time.Date(2026, 3, 1, 12, 30, 0, 0, time.UTC))
+	if got != "tenant_abc-20260301T123000Z.dump" {
+		t.Errorf("ArchiveName() = %q", got)
+	}
+}
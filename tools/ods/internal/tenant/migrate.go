@@ -0,0 +1,250 @@
+package tenant
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/kube"
+)
+
+//go:embed route_tenant.py
+var routeScript string
+
+// MigrationSteps are the steps of a tenant migration, in order. A migration
+// that fails resumes from the first step not yet done.
+var MigrationSteps = []string{
+	"dump",         // pg_dump the tenant schema on the source
+	"export",       // copy the tenant's rows out of the source's public tables
+	"restore",      // restore the schema on the target
+	"import",       // copy the public rows into the target
+	"files",        // copy file-store objects between buckets
+	"file-records", // point file_record rows at the target bucket
+	"validate",     // compare row counts
+	"route",        // switch the control plane to the target data plane
+}
+
+// Migration is the persisted progress of a tenant migration.
+type Migration struct {
+	TenantID  string          `json:"tenant_id"`
+	From      string          `json:"from"`
+	To        string          `json:"to"`
+	StartedAt time.Time       `json:"started_at"`
+	Done      map[string]bool `json:"done"`
+	// PublicTables are the public tables the tenant had rows in.
+	PublicTables []string `json:"public_tables,omitempty"`
+
+	dir string
+}
+
+// MigrationDir is where a migration's state and artifacts live under base.
+func MigrationDir(base, tenantID string) string {
+	return filepath.Join(base, tenantID)
+}
+
+// LoadMigration returns the migration of tenantID recorded under dir, or a
+// new one. A recorded migration between different clusters is an error.
+func LoadMigration(dir, tenantID, from, to string) (*Migration, error) {
+	m := &Migration{TenantID: tenantID, From: from, To: to, StartedAt: time.Now().UTC(), Done: map[string]bool{}, dir: dir}
+	data, err := os.ReadFile(m.statePath())
+	if errors.Is(err, os.ErrNotExist) {
+		return m, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, m); err != nil {
+		return nil, fmt.Errorf("corrupt migration state %s: %w", m.statePath(), err)
+	}
+	if m.From != from || m.To != to {
+		return nil, fmt.Errorf("%s has an unfinished migration from %s to %s; finish it or remove %s", tenantID, m.From, m.To, dir)
+	}
+	if m.Done == nil {
+		m.Done = map[string]bool{}
+	}
+	return m, nil
+}
+
+// Resuming reports whether any step has already completed.
+func (m *Migration) Resuming() bool {
+	return len(m.Done) > 0
+}
+
+// Finished reports whether every step has completed.
+func (m *Migration) Finished() bool {
+	for _, s := range MigrationSteps {
+		if !m.Done[s] {
+			return false
+		}
+	}
+	return true
+}
+
+// Path returns the path of an artifact of this migration.
+func (m *Migration) Path(name string) string {
+	return filepath.Join(m.dir, name)
+}
+
+// MarkDone records step as completed and saves the state.
+func (m *Migration) MarkDone(step string) error {
+	m.Done[step] = true
+	return m.Save()
+}
+
+// Save writes the migration state.
+func (m *Migration) Save() error {
+	if err := os.MkdirAll(m.dir, 0700); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(m.statePath(), data, 0600)
+}
+
+func (m *Migration) statePath() string {
+	return filepath.Join(m.dir, "state.json")
+}
+
+// TablesSQL lists the tables of schema.
+func TablesSQL(schema string) string {
+	return fmt.Sprintf(`SELECT tablename FROM pg_tables WHERE schemaname = '%s' ORDER BY tablename`, schema)
+}
+
+// CountsSQL counts the rows of each table in schema.
+func CountsSQL(schema string, tables []string) string {
+	parts := make([]string, len(tables))
+	for i, t := range tables {
+		parts[i] = fmt.Sprintf(`SELECT '%[2]s', count(*) FROM "%[1]s"."%[2]s"`, schema, t)
+	}
+	return strings.Join(parts, "\nUNION ALL\n")
+}
+
+// ParseCounts reads the output of CountsSQL or PublicRowsSQL.
+func ParseCounts(lines []string) (map[string]int, error) {
+	return ParsePublicRows(lines)
+}
+
+// CountMismatch is a table whose row count differs between source and target.
+type CountMismatch struct {
+	Table          string
+	Source, Target int
+}
+
+// CompareCounts returns the tables whose counts differ, sorted by name. A
+// table missing on one side counts as -1 there.
+func CompareCounts(source, target map[string]int) []CountMismatch {
+	var out []CountMismatch
+	for t, n := range source {
+		m, ok := target[t]
+		if !ok {
+			m = -1
+		}
+		if m != n {
+			out = append(out, CountMismatch{Table: t, Source: n, Target: m})
+		}
+	}
+	for t, m := range target {
+		if _, ok := source[t]; !ok {
+			out = append(out, CountMismatch{Table: t, Source: -1, Target: m})
+		}
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Table < out[j].Table })
+	return out
+}
+
+// CopyOutSQL exports the tenant's rows of a public table as CSV with a header.
+func CopyOutSQL(table, tenantID string) string {
+	return fmt.Sprintf(`COPY (SELECT * FROM public."%s" WHERE tenant_id = '%s') TO STDOUT WITH (FORMAT csv, HEADER true)`, table, tenantID)
+}
+
+// ClearPublicSQL deletes the tenant's rows of a public table, so an import
+// that failed part-way can be re-run.
+func ClearPublicSQL(table, tenantID string) string {
+	return fmt.Sprintf(`DELETE FROM public."%s" WHERE tenant_id = '%s'`, table, tenantID)
+}
+
+// CopyInSQL imports CSV produced by CopyOutSQL, whose first line is header.
+func CopyInSQL(table, header string) (string, error) {
+	cols := strings.Split(strings.TrimSpace(header), ",")
+	for i, c := range cols {
+		if !validColumn(c) {
+			return "", fmt.Errorf("unexpected column %q in %s export", c, table)
+		}
+		cols[i] = `"` + c + `"`
+	}
+	return fmt.Sprintf(`COPY public."%s" (%s) FROM STDIN WITH (FORMAT csv, HEADER true)`, table, strings.Join(cols, ", ")), nil
+}
+
+func validColumn(c string) bool {
+	if c == "" {
+		return false
+	}
+	for _, r := range c {
+		if !(r == '_' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+			return false
+		}
+	}
+	return true
+}
+
+// FileStore is where a data plane keeps tenant files: s3://Bucket/Prefix/<tenant>/.
+type FileStore struct {
+	Bucket string
+	Prefix string
+}
+
+// TenantURL returns the S3 URL of the tenant's files.
+func (f FileStore) TenantURL(tenantID string) string {
+	return "s3://" + f.Bucket + "/" + strings.Trim(f.Prefix, "/") + "/" + tenantID + "/"
+}
+
+// FileRecordsSQL repoints the tenant's file_record rows from one file store
+// to another. It returns "" when both are the same.
+func FileRecordsSQL(tenantID string, from, to FileStore) string {
+	if from == to {
+		return ""
+	}
+	fromPrefix := strings.Trim(from.Prefix, "/") + "/"
+	toPrefix := strings.Trim(to.Prefix, "/") + "/"
+	return fmt.Sprintf(`UPDATE "%[1]s".file_record
+SET bucket_name = '%[3]s', object_key = '%[5]s' || substr(object_key, %[6]s)
+WHERE bucket_name = '%[2]s' AND starts_with(object_key, '%[4]s')`,
+		tenantID, from.Bucket, to.Bucket, fromPrefix, toPrefix, strconv.Itoa(len(fromPrefix)+1))
+}
+
+// Route sets column of the tenant's row in the control plane's tenant table to
+// value, by running a script on a control-plane pod, and returns the previous
+// value.
+func Route(c *kube.Cluster, pod, tenantID, column, value string) (string, error) {
+	out, err := c.RunPython(pod, routeScript, tenantID, column, value)
+	if err != nil {
+		return "", err
+	}
+	return parseRouted(out)
+}
+
+func parseRouted(stdout string) (string, error) {
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	last := strings.TrimSpace(lines[len(lines)-1])
+	var r struct {
+		Status   string `json:"status"`
+		Message  string `json:"message"`
+		Previous string `json:"previous"`
+	}
+	if err := json.Unmarshal([]byte(last), &r); err != nil {
+		return "", fmt.Errorf("unexpected output from route script: %q", last)
+	}
+	if r.Status != "success" {
+		return "", fmt.Errorf("%s", r.Message)
+	}
+	return r.Previous, nil
+}
@@ -0,0 +1,139 @@
+package tenant
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoadMigration(t *testing.T) {
+	dir := MigrationDir(t.TempDir(), "tenant_abc")
+
+	m, err := LoadMigration(dir, "tenant_abc", "us", "eu")
+	if err != nil {
+		t.Fatalf("LoadMigration() error: %v", err)
+	}
+	if m.Resuming() {
+		t.Error("a new migration should not be resuming")
+	}
+	if err := m.MarkDone("dump"); err != nil {
+		t.Fatalf("MarkDone() error: %v", err)
+	}
+
+	m, err = LoadMigration(dir, "tenant_abc", "us", "eu")
+	if err != nil {
+		t.Fatalf("LoadMigration() error: %v", err)
+	}
+	if !m.Resuming() || !m.Done["dump"] || m.Done["restore"] {
+		t.Errorf("unexpected resumed state %+v", m.Done)
+	}
+	if m.Finished() {
+		t.Error("migration with one step done should not be finished")
+	}
+
+	if _, err := LoadMigration(dir, "tenant_abc", "us", "ap"); err == nil {
+		t.Error("expected an error resuming towards a different target")
+	}
+}
+
+func TestFinished(t *testing.T) {
+	m := &Migration{Done: map[string]bool{}}
+	for _, s := range MigrationSteps {
+		m.Done[s] = true
+	}
+	if !m.Finished() {
+		t.Error("expected all steps done to be finished")
+	}
+}
+
+func TestCountsSQL(t *testing.T) {
+	sql := CountsSQL("tenant_abc", []string{"chat_message", "user"})
+	for _, want := range []string{
+		`SELECT 'chat_message', count(*) FROM "tenant_abc"."chat_message"`,
+		"UNION ALL",
+		`SELECT 'user', count(*) FROM "tenant_abc"."user"`,
+	} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("CountsSQL missing %q:\n%s", want, sql)
+		}
+	}
+}
+
+func TestCompareCounts(t *testing.T) {
+	got := CompareCounts(
+		map[string]int{"user": 3, "chat_message": 10, "document": 5},
+		map[string]int{"user": 3, "chat_message": 9, "extra": 1},
+	)
+	want := []CountMismatch{
+		{"chat_message", 10, 9},
+		{"document", 5, -1},
+		{"extra", -1, 1},
+	}
+	if len(got) != len(want) {
+		t.Fatalf("CompareCounts() = %+v, want %+v", got, want)
+	}
+	for i := range want {
+		if got[i] != want[i] {
+			t.Errorf("CompareCounts()[%d] = %+v, want %+v", i, got[i], want[i])
+		}
+	}
+}
+
+func TestCopyInSQL(t *testing.T) {
+	sql, err := CopyInSQL("user_tenant_mapping", "email,tenant_id,active\r\n")
+	if err != nil {
+		t.Fatalf("CopyInSQL() error: %v", err)
+	}
+	want := `COPY public."user_tenant_mapping" ("email", "tenant_id", "active") FROM STDIN WITH (FORMAT csv, HEADER true)`
+	if sql != want {
+		t.Errorf("CopyInSQL() = %q, want %q", sql, want)
+	}
+	if _, err := CopyInSQL("t", `email,"x"); DROP TABLE t; --`); err == nil {
+		t.Error("expected an error for an unexpected column name")
+	}
+}
+
+func TestClearPublicSQL(t *testing.T) {
+	want := `DELETE FROM public."user_tenant_mapping" WHERE tenant_id = 'tenant_abc'`
+	if got := ClearPublicSQL("user_tenant_mapping", "tenant_abc"); got != want {
+		t.Errorf("ClearPublicSQL() = %q, want %q", got, want)
+	}
+}
+
+func TestFileStoreTenantURL(t *testing.T) {
+	f := FileStore{Bucket: "onyx-eu", Prefix: "/onyx-files/"}
+	if got := f.TenantURL("tenant_abc"); got != "s3://onyx-eu/onyx-files/tenant_abc/" {
+		t.Errorf("TenantURL() = %q", got)
+	}
+}
+
+func TestFileRecordsSQL(t *testing.T) {
+	us := FileStore{Bucket: "onyx-us", Prefix: "onyx-files"}
+	if sql := FileRecordsSQL("tenant_abc", us, us); sql != "" {
+		t.Errorf("expected no update between identical stores, got %q", sql)
+	}
+
+	sql := FileRecordsSQL("tenant_abc", us, FileStore{Bucket: "onyx-eu", Prefix: "files"})
+	for _, want := range []string{
+		`UPDATE "tenant_abc".file_record`,
+		"bucket_name = 'onyx-eu'",
+		"object_key = 'files/' || substr(object_key, 12)",
+		"WHERE bucket_name = 'onyx-us' AND starts_with(object_key, 'onyx-files/')",
+	} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("FileRecordsSQL missing %q:\n%s", want, sql)
+		}
+	}
+}
+
+func TestParseRouted(t *testing.T) {
+	prev, err := parseRouted("Connecting...\n{\"status\": \"success\", \"previous\": \"us\"}\n")
+	if err != nil || prev != "us" {
+		t.Errorf("parseRouted() = %q, %v", prev, err)
+	}
+	if _, err := parseRouted(`{"status": "error", "message": "tenant not found"}`); err == nil || err.Error() != "tenant not found" {
+		t.Errorf("expected the script's error, got %v", err)
+	}
+	if _, err := parseRouted("Traceback"); err == nil {
+		t.Error("expected an error for unexpected output")
+	}
+}
@@ -0,0 +1,92 @@
+package tenant
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// restoreTimeLayouts are the accepted forms of a point-in-time restore
+// target. Layouts without a zone are UTC.
+var restoreTimeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02T15:04:05",
+	"2006-01-02T15:04",
+}
+
+// ParseRestoreTime parses the time to restore a tenant to.
+func ParseRestoreTime(s string) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	for _, layout := range restoreTimeLayouts {
+		if t, err := time.ParseInLocation(layout, s, time.UTC); err == nil {
+			return t.UTC(), nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q (expected e.g. \"2026-05-01 12:00\" in UTC, or RFC 3339)", s)
+}
+
+// maxIdentifier is Postgres's identifier length limit.
+const maxIdentifier = 63
+
+// suffixedSchema appends suffix to schema, shortening schema so the result
+// is a valid Postgres identifier.
+func suffixedSchema(schema, suffix string) string {
+	if max := maxIdentifier - len(suffix); len(schema) > max {
+		schema = schema[:max]
+	}
+	return schema + suffix
+}
+
+// ScratchSchema names the schema a tenant restored to the point in time at
+// is copied into next to the live one.
+func ScratchSchema(tenantID string, at time.Time) string {
+	return suffixedSchema(tenantID, "_pitr_"+at.UTC().Format("0601021504"))
+}
+
+// IsScratchSchema reports whether schema is named like a restore of
+// tenantID made by ScratchSchema, the only schemas a restore may promote.
+func IsScratchSchema(tenantID, schema string) bool {
+	stamp := len("0601021504")
+	prefix := ScratchSchema(tenantID, time.Time{})
+	prefix = prefix[:len(prefix)-stamp]
+	if len(schema) != len(prefix)+stamp || !strings.HasPrefix(schema, prefix) {
+		return false
+	}
+	for _, r := range schema[len(prefix):] {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// ReplacedSchema names the schema the live tenant is kept in after a
+// restored one is promoted at now.
+func ReplacedSchema(tenantID string, now time.Time) string {
+	return suffixedSchema(tenantID, "_pre_"+now.UTC().Format("0601021504"))
+}
+
+// SchemaExistsSQL returns 1 if schema exists, else 0.
+func SchemaExistsSQL(schema string) string {
+	return fmt.Sprintf(`SELECT count(*) FROM information_schema.schemata WHERE schema_name = '%s'`, schema)
+}
+
+// RenameSchemaSQL renames a schema.
+func RenameSchemaSQL(from, to string) string {
+	return fmt.Sprintf(`ALTER SCHEMA "%s" RENAME TO "%s"`, from, to)
+}
+
+// PromoteSQL swaps a restored schema in for the tenant's in one transaction,
+// keeping the live one as replaced. With no live schema (the tenant was
+// dropped) the restored one is only renamed.
+func PromoteSQL(tenantID, restored, replaced string, liveExists bool) string {
+	var b strings.Builder
+	b.WriteString("BEGIN;\n")
+	if liveExists {
+		fmt.Fprintf(&b, "%s;\n", RenameSchemaSQL(tenantID, replaced))
+	}
+	fmt.Fprintf(&b, "%s;\nCOMMIT;", RenameSchemaSQL(restored, tenantID))
+	return b.String()
+}
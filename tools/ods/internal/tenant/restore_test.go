@@ -0,0 +1,65 @@
+package tenant
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseRestoreTime(t *testing.T) {
+	want := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	for _, s := range []string{"2026-05-01 12:00", "2026-05-01T12:00:00", " 2026-05-01 12:00:00 ", "2026-05-01T14:00:00+02:00"} {
+		if got, err := ParseRestoreTime(s); err != nil || !got.Equal(want) {
+			t.Errorf("ParseRestoreTime(%q) = %v, %v", s, got, err)
+		}
+	}
+	if _, err := ParseRestoreTime("yesterday"); err == nil {
+		t.Error("expected an error for a bad time")
+	}
+}
+
+func TestRestoreSchemaNames(t *testing.T) {
+	at := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	id := "tenant_0b9e7c1a-4f5d-4e2b-9a61-3c2f8d7e6a10"
+	if got := ScratchSchema(id, at); got != id+"_pitr_2605011200" {
+		t.Errorf("ScratchSchema() = %q", got)
+	}
+	long := "tenant_" + strings.Repeat("x", 60)
+	if got := ReplacedSchema(long, at); len(got) != 63 || !strings.HasSuffix(got, "_pre_2605011200") {
+		t.Errorf("ReplacedSchema(long) = %q", got)
+	}
+}
+
+func TestIsScratchSchema(t *testing.T) {
+	at := time.Date(2026, 5, 1, 12, 0, 0, 0, time.UTC)
+	id := "tenant_abcd"
+	long := "tenant_" + strings.Repeat("x", 60)
+	tests := []struct {
+		tenant, schema string
+		want           bool
+	}{
+		{id, ScratchSchema(id, at), true},
+		{long, ScratchSchema(long, at), true},
+		{id, "tenant_abcd_pitr_26050112", false},
+		{id, "tenant_abcd_pitr_260501120x", false},
+		{id, "tenant_abcd_pre_2605011200", false},
+		{id, "tenant_other_pitr_2605011200", false},
+		{id, "tenant_abcd", false},
+	}
+	for _, tt := range tests {
+		if got := IsScratchSchema(tt.tenant, tt.schema); got != tt.want {
+			t.Errorf("IsScratchSchema(%q, %q) = %v, want %v", tt.tenant, tt.schema, got, tt.want)
+		}
+	}
+}
+
+func TestPromoteSQL(t *testing.T) {
+	got := PromoteSQL("tenant_a", "tenant_a_pitr_1", "tenant_a_pre_2", true)
+	want := "BEGIN;\nALTER SCHEMA \"tenant_a\" RENAME TO \"tenant_a_pre_2\";\nALTER SCHEMA \"tenant_a_pitr_1\" RENAME TO \"tenant_a\";\nCOMMIT;"
+	if got != want {
+		t.Errorf("PromoteSQL() =\n%s", got)
+	}
+	if got := PromoteSQL("tenant_a", "tenant_a_pitr_1", "tenant_a_pre_2", false); strings.Contains(got, "tenant_a_pre_2") {
+		t.Errorf("PromoteSQL(no live) renamed the missing live schema:\n%s", got)
+	}
+}
@@ -0,0 +1,62 @@
+"""Point a tenant at another data plane in the control-plane database.
+
+Bundled with ods and piped into `python -` on a control-plane pod by
+`ods tenant migrate`. Connects with the pod's POSTGRES_* environment, as the
+tenant cleanup scripts do, and sets one column of the tenant's row in the
+control plane's tenant table.
+
+Usage:
+    python - <tenant_id> <column> <value>
+
+The last line on stdout is a JSON object with "status" and, on success,
+"previous" (the column's old value).
+"""
+
+from __future__ import annotations
+
+import json
+import os
+import re
+import sys
+
+from sqlalchemy import create_engine, text
+
+
+def route(tenant_id: str, column: str, value: str) -> dict[str, str]:
+    if not re.fullmatch(r"[a-z_][a-z0-9_]*", column):
+        raise ValueError(f"invalid column name {column!r}")
+
+    url = "postgresql://{user}:{password}@{host}:{port}/{db}".format(
+        user=os.environ.get("POSTGRES_USER", "postgres"),
+        password=os.environ.get("POSTGRES_PASSWORD", ""),
+        host=os.environ["POSTGRES_HOST"],
+        port=os.environ.get("POSTGRES_PORT", "5432"),
+        db=os.environ.get("POSTGRES_DB", "danswer"),
+    )
+    engine = create_engine(url)
+    with engine.begin() as conn:
+        row = conn.execute(
+            text(f"SELECT {column} FROM tenant WHERE tenant_id = :tenant_id FOR UPDATE"),
+            {"tenant_id": tenant_id},
+        ).first()
+        if row is None:
+            raise ValueError(f"tenant {tenant_id} not found in the control plane")
+        conn.execute(
+            text(f"UPDATE tenant SET {column} = :value WHERE tenant_id = :tenant_id"),
+            {"value": value, "tenant_id": tenant_id},
+        )
+    previous = row[0]
+    return {"status": "success", "previous": "" if previous is None else str(previous)}
+
+
+def main() -> None:
+    try:
+        result = route(*sys.argv[1:4])
+    except Exception as e:
+        print(f"Error: {e}", file=sys.stderr)
+        result = {"status": "error", "message": str(e)}
+    print(json.dumps(result))
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,72 @@
+// Package tenant provisions and manages tenants of a multi-tenant data plane.
+package tenant
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/kube"
+)
+
+//go:embed create_tenant.py
+var createScript string
+
+var emailPattern = regexp.MustCompile(`^[^@\s'"]+@[^@\s'"]+\.[^@\s'"]+$`)
+
+// ValidEmail reports whether email looks like an address signup would accept.
+func ValidEmail(email string) bool {
+	return emailPattern.MatchString(email)
+}
+
+// Created is a newly provisioned tenant and its admin's login details.
+type Created struct {
+	TenantID  string `json:"tenant_id"`
+	Email     string `json:"email"`
+	Role      string `json:"role"`
+	Password  string `json:"password"`
+	WebDomain string `json:"web_domain"`
+}
+
+// LoginURL returns the tenant's login page.
+func (c *Created) LoginURL() string {
+	return strings.TrimSuffix(c.WebDomain, "/") + "/auth/login"
+}
+
+// Create provisions a tenant whose first user is email, by running the
+// signup flow on pod.
+func Create(c *kube.Cluster, pod, email string) (*Created, error) {
+	out, err := c.RunPython(pod, createScript, email)
+	if err != nil {
+		return nil, err
+	}
+	return parseCreated(out)
+}
+
+func parseCreated(stdout string) (*Created, error) {
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	last := strings.TrimSpace(lines[len(lines)-1])
+	var r struct {
+		Status  string `json:"status"`
+		Message string `json:"message"`
+		Created
+	}
+	if err := json.Unmarshal([]byte(last), &r); err != nil {
+		return nil, fmt.Errorf("unexpected output from tenant script: %q", last)
+	}
+	if r.Status != "success" {
+		return nil, fmt.Errorf("%s", r.Message)
+	}
+	if r.TenantID == "" || r.Password == "" {
+		return nil, fmt.Errorf("tenant script returned incomplete details: %q", last)
+	}
+	return &r.Created, nil
+}
+
+// SchemasSQL lists the schemas holding tenant data: every tenant schema in a
+// multi-tenant data plane, or public in a single-tenant deployment.
+const SchemasSQL = `SELECT table_schema FROM information_schema.tables
+WHERE table_name = 'chat_message' AND table_schema NOT IN ('pg_catalog', 'information_schema')
+ORDER BY table_schema`
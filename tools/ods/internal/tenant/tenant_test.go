@@ -0,0 +1,43 @@
+package tenant
+
+import "testing"
+
+func TestValidEmail(t *testing.T) {
+	tests := map[string]bool{
+		"qa+1@onyx.app":      true,
+		"jane@example.co.uk": true,
+		"jane@localhost":     false,
+		"jane":               false,
+		"a b@example.com":    false,
+		"x'@example.com":     false,
+	}
+	for email, want := range tests {
+		if got := ValidEmail(email); got != want {
+			t.Errorf("ValidEmail(%q) = %v, want %v", email, got, want)
+		}
+	}
+}
+
+func TestParseCreated(t *testing.T) {
+	out := "Provisioning...\n" + `{"status": "success", "tenant_id": "tenant_abc", "email": "qa@onyx.app", "role": "admin", "password": "Xy1!", "web_domain": "https://cloud.onyx.app/"}`
+	c, err := parseCreated(out)
+	if err != nil {
+		t.Fatalf("parseCreated() error: %v", err)
+	}
+	if c.TenantID != "tenant_abc" || c.Password != "Xy1!" || c.Role != "admin" {
+		t.Errorf("unexpected result %+v", c)
+	}
+	if got := c.LoginURL(); got != "https://cloud.onyx.app/auth/login" {
+		t.Errorf("LoginURL() = %q", got)
+	}
+
+	for _, bad := range []string{
+		`{"status": "exists", "message": "qa@onyx.app already belongs to tenant_abc"}`,
+		`{"status": "success", "email": "qa@onyx.app"}`,
+		"Traceback (most recent call last):",
+	} {
+		if _, err := parseCreated(bad); err == nil {
+			t.Errorf("parseCreated(%q) expected an error", bad)
+		}
+	}
+}
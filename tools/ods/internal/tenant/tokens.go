@@ -0,0 +1,114 @@
+package tenant
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ModelTokens is a tenant's token consumption for one model, as recorded
+// in the user_usage rollup.
+type ModelTokens struct {
+	Model    string
+	Provider string
+	// Kind is "embedding", "rerank" or "llm", from the usage flow.
+	Kind            string
+	InputTokens     int64
+	OutputTokens    int64
+	CacheReadTokens int64
+	CostCents       float64
+	Users           int
+}
+
+// AssistantTokens is a tenant's chat activity with one assistant.
+type AssistantTokens struct {
+	Assistant    string
+	ChatSessions int
+	Messages     int
+	// InputTokens and OutputTokens sum the token counts of stored user and
+	// assistant messages, without retrieved context or prompts.
+	InputTokens  int64
+	OutputTokens int64
+}
+
+// ModelTokensSQL returns token usage since since, grouped by model. The
+// rollup's windows are daily, so since is rounded down to the start of its day.
+func ModelTokensSQL(tenantID string, since time.Time) string {
+	return fmt.Sprintf(`SELECT model, provider,
+  CASE WHEN flow IN ('embed_query', 'embed_passage') THEN 'embedding' WHEN flow = 'rerank' THEN 'rerank' ELSE 'llm' END AS kind,
+  sum(input_tokens), sum(output_tokens), sum(cache_read_tokens), round(sum(cost_cents)::numeric, 4), count(DISTINCT user_id)
+FROM "%s".user_usage WHERE window_start >= '%s'
+GROUP BY 1, 2, 3 ORDER BY sum(input_tokens) + sum(output_tokens) DESC, 1`, tenantID, since.UTC().Truncate(24*time.Hour).Format(time.RFC3339))
+}
+
+// AssistantTokensSQL returns chat sessions, user messages and message
+// tokens since since, grouped by assistant.
+func AssistantTokensSQL(tenantID string, since time.Time) string {
+	return fmt.Sprintf(`SELECT COALESCE(replace(p.name, E'\t', ' '), '(none)'), count(DISTINCT s.id),
+  count(*) FILTER (WHERE m.message_type = 'USER'),
+  COALESCE(sum(m.token_count) FILTER (WHERE m.message_type <> 'ASSISTANT'), 0),
+  COALESCE(sum(m.token_count) FILTER (WHERE m.message_type = 'ASSISTANT'), 0)
+FROM "%[1]s".chat_message m
+JOIN "%[1]s".chat_session s ON s.id = m.chat_session_id
+LEFT JOIN "%[1]s".persona p ON p.id = s.persona_id
+WHERE m.time_sent >= '%[2]s'
+GROUP BY p.id, p.name ORDER BY count(DISTINCT s.id) DESC, 1`, tenantID, since.UTC().Format(time.RFC3339))
+}
+
+// ParseModelTokens reads the output of ModelTokensSQL.
+func ParseModelTokens(lines []string) ([]ModelTokens, error) {
+	rows := make([]ModelTokens, 0, len(lines))
+	for _, line := range lines {
+		f := strings.Split(line, "\t")
+		if len(f) != 8 {
+			return nil, fmt.Errorf("unexpected model usage row: %q", line)
+		}
+		r := ModelTokens{Model: f[0], Provider: f[1], Kind: f[2]}
+		var err error
+		if r.InputTokens, err = strconv.ParseInt(f[3], 10, 64); err != nil {
+			return nil, fmt.Errorf("unexpected model usage row: %q", line)
+		}
+		if r.OutputTokens, err = strconv.ParseInt(f[4], 10, 64); err != nil {
+			return nil, fmt.Errorf("unexpected model usage row: %q", line)
+		}
+		if r.CacheReadTokens, err = strconv.ParseInt(f[5], 10, 64); err != nil {
+			return nil, fmt.Errorf("unexpected model usage row: %q", line)
+		}
+		if r.CostCents, err = strconv.ParseFloat(f[6], 64); err != nil {
+			return nil, fmt.Errorf("unexpected model usage row: %q", line)
+		}
+		if r.Users, err = strconv.Atoi(f[7]); err != nil {
+			return nil, fmt.Errorf("unexpected model usage row: %q", line)
+		}
+		rows = append(rows, r)
+	}
+	return rows, nil
+}
+
+// ParseAssistantTokens reads the output of AssistantTokensSQL.
+func ParseAssistantTokens(lines []string) ([]AssistantTokens, error) {
+	rows := make([]AssistantTokens, 0, len(lines))
+	for _, line := range lines {
+		f := strings.Split(line, "\t")
+		if len(f) != 5 {
+			return nil, fmt.Errorf("unexpected assistant usage row: %q", line)
+		}
+		r := AssistantTokens{Assistant: f[0]}
+		var err error
+		if r.ChatSessions, err = strconv.Atoi(f[1]); err != nil {
+			return nil, fmt.Errorf("unexpected assistant usage row: %q", line)
+		}
+		if r.Messages, err = strconv.Atoi(f[2]); err != nil {
+			return nil, fmt.Errorf("unexpected assistant usage row: %q", line)
+		}
+		if r.InputTokens, err = strconv.ParseInt(f[3], 10, 64); err != nil {
+			return nil, fmt.Errorf("unexpected assistant usage row: %q", line)
+		}
+		if r.OutputTokens, err = strconv.ParseInt(f[4], 10, 64); err != nil {
+			return nil, fmt.Errorf("unexpected assistant usage row: %q", line)
+		}
+		rows = append(rows, r)
+	}
+	return rows, nil
+}
@@ -0,0 +1,45 @@
+package tenant
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTokensSQL(t *testing.T) {
+	since := time.Date(2026, 9, 1, 0, 0, 0, 0, time.UTC)
+	if sql := ModelTokensSQL("tenant_a", since); !strings.Contains(sql, `FROM "tenant_a".user_usage WHERE window_start >= '2026-09-01T00:00:00Z'`) {
+		t.Errorf("unexpected model SQL:\n%s", sql)
+	}
+	if sql := AssistantTokensSQL("tenant_a", since); !strings.Contains(sql, `LEFT JOIN "tenant_a".persona p`) ||
+		!strings.Contains(sql, "m.time_sent >= '2026-09-01T00:00:00Z'") {
+		t.Errorf("unexpected assistant SQL:\n%s", sql)
+	}
+}
+
+func TestParseModelTokens(t *testing.T) {
+	rows, err := ParseModelTokens([]string{"gpt-4o\topenai\tllm\t1200\t300\t100\t1.2500\t4"})
+	if err != nil {
+		t.Fatalf("ParseModelTokens() error: %v", err)
+	}
+	want := ModelTokens{Model: "gpt-4o", Provider: "openai", Kind: "llm", InputTokens: 1200, OutputTokens: 300, CacheReadTokens: 100, CostCents: 1.25, Users: 4}
+	if len(rows) != 1 || rows[0] != want {
+		t.Errorf("ParseModelTokens() = %+v", rows)
+	}
+	if _, err := ParseModelTokens([]string{"gpt-4o\topenai\tllm\tx\t0\t0\t0\t0"}); err == nil {
+		t.Error("expected an error for a bad token count")
+	}
+}
+
+func TestParseAssistantTokens(t *testing.T) {
+	rows, err := ParseAssistantTokens([]string{"Search\t10\t25\t900\t4000", "(none)\t1\t1\t5\t20"})
+	if err != nil {
+		t.Fatalf("ParseAssistantTokens() error: %v", err)
+	}
+	if len(rows) != 2 || rows[0] != (AssistantTokens{Assistant: "Search", ChatSessions: 10, Messages: 25, InputTokens: 900, OutputTokens: 4000}) {
+		t.Errorf("ParseAssistantTokens() = %+v", rows)
+	}
+	if _, err := ParseAssistantTokens([]string{"Search\t10"}); err == nil {
+		t.Error("expected an error for a short row")
+	}
+}
@@ -0,0 +1,204 @@
+// Package tickets comments on Jira or Linear issues to tie actions on shared
+// environments to the ticket that justified them.
+package tickets
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/auditlog"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/config"
+)
+
+const requestTimeout = 15 * time.Second
+
+// LinearAPIURL is Linear's GraphQL endpoint.
+var LinearAPIURL = "https://api.linear.app/graphql"
+
+// keyPattern matches issue keys shared by Jira and Linear, e.g. OPS-123.
+var keyPattern = regexp.MustCompile(`^[A-Z][A-Z0-9]+-[0-9]+$`)
+
+// ValidKey reports whether key looks like a Jira or Linear issue key.
+func ValidKey(key string) bool {
+	return keyPattern.MatchString(key)
+}
+
+// Commenter posts comments on issues.
+type Commenter interface {
+	Comment(key, body string) error
+}
+
+// ErrNotConfigured means no provider is configured, so tickets are only
+// recorded in the audit log.
+var ErrNotConfigured = errors.New("no ticket provider configured")
+
+// NewCommenter returns the commenter for the configured provider, reading
+// credentials from the environment.
+func NewCommenter(cfg config.TicketsConfig) (Commenter, error) {
+	switch cfg.Provider {
+	case "":
+		return nil, ErrNotConfigured
+	case "jira":
+		email, token := os.Getenv("JIRA_EMAIL"), os.Getenv("JIRA_API_TOKEN")
+		if cfg.JiraURL == "" || email == "" || token == "" {
+			return nil, errors.New("jira needs tickets.jira_url in the ods config and JIRA_EMAIL/JIRA_API_TOKEN set")
+		}
+		return &Jira{BaseURL: strings.TrimSuffix(cfg.JiraURL, "/"), Email: email, Token: token}, nil
+	case "linear":
+		key := os.Getenv("LINEAR_API_KEY")
+		if key == "" {
+			return nil, errors.New("linear needs LINEAR_API_KEY set")
+		}
+		return &Linear{APIKey: key}, nil
+	default:
+		return nil, fmt.Errorf("unknown ticket provider %q (expected jira or linear)", cfg.Provider)
+	}
+}
+
+// Jira comments through the Jira Cloud REST API.
+type Jira struct {
+	BaseURL string
+	Email   string
+	Token   string
+}
+
+// Comment adds a plain-text comment to the issue.
+func (j *Jira) Comment(key, body string) error {
+	payload, err := json.Marshal(map[string]string{"body": "{noformat}\n" + body + "\n{noformat}"})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, j.BaseURL+"/rest/api/2/issue/"+key+"/comment", bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.SetBasicAuth(j.Email, j.Token)
+	req.Header.Set("Content-Type", "application/json")
+	_, err = do(req)
+	return err
+}
+
+// Linear comments through Linear's GraphQL API.
+type Linear struct {
+	APIKey string
+}
+
+// Comment adds a markdown comment to the issue.
+func (l *Linear) Comment(key, body string) error {
+	var issue struct {
+		Issue struct {
+			ID string `json:"id"`
+		} `json:"issue"`
+	}
+	if err := l.query(`query($id: String!) { issue(id: $id) { id } }`, map[string]any{"id": key}, &issue); err != nil {
+		return err
+	}
+	if issue.Issue.ID == "" {
+		return fmt.Errorf("linear issue %s not found", key)
+	}
+
+	var created struct {
+		CommentCreate struct {
+			Success bool `json:"success"`
+		} `json:"commentCreate"`
+	}
+	mutation := `mutation($issueId: String!, $body: String!) { commentCreate(input: {issueId: $issueId, body: $body}) { success } }`
+	if err := l.query(mutation, map[string]any{"issueId": issue.Issue.ID, "body": "```\n" + body + "\n```"}, &created); err != nil {
+		return err
+	}
+	if !created.CommentCreate.Success {
+		return fmt.Errorf("linear did not create the comment on %s", key)
+	}
+	return nil
+}
+
+func (l *Linear) query(query string, vars map[string]any, out any) error {
+	payload, err := json.Marshal(map[string]any{"query": query, "variables": vars})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, LinearAPIURL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Authorization", l.APIKey)
+	req.Header.Set("Content-Type", "application/json")
+	data, err := do(req)
+	if err != nil {
+		return err
+	}
+
+	var resp struct {
+		Data   json.RawMessage `json:"data"`
+		Errors []struct {
+			Message string `json:"message"`
+		} `json:"errors"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return fmt.Errorf("unexpected linear response: %w", err)
+	}
+	if len(resp.Errors) > 0 {
+		return fmt.Errorf("linear: %s", resp.Errors[0].Message)
+	}
+	return json.Unmarshal(resp.Data, out)
+}
+
+func do(req *http.Request) ([]byte, error) {
+	resp, err := (&http.Client{Timeout: requestTimeout}).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s request failed: %w", req.URL.Host, err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+	data, _ := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		msg := strings.TrimSpace(string(data))
+		if len(msg) > 300 {
+			msg = msg[:300]
+		}
+		return nil, fmt.Errorf("%s returned %s: %s", req.URL.Host, resp.Status, msg)
+	}
+	return data, nil
+}
+
+// Report is what a ticket comment describes: the command, who ran it, how it
+// ended and the audited actions it took.
+type Report struct {
+	Command string
+	Actor   string
+	OK      bool
+	Summary string
+	Actions []auditlog.Entry
+}
+
+// Body renders the report as a plain-text comment.
+func (r Report) Body() string {
+	var b strings.Builder
+	outcome := "succeeded"
+	if !r.OK {
+		outcome = "failed"
+	}
+	fmt.Fprintf(&b, "$ %s\n", r.Command)
+	fmt.Fprintf(&b, "Run by %s: %s", r.Actor, outcome)
+	if summary := strings.TrimSpace(r.Summary); summary != "" {
+		fmt.Fprintf(&b, " (%s)", summary)
+	}
+	b.WriteString("\n")
+	for _, e := range r.Actions {
+		line := e.Time.Format(time.RFC3339) + " " + e.Action
+		for _, part := range []string{e.Context, e.Target, e.Detail} {
+			if part != "" {
+				line += " " + part
+			}
+		}
+		b.WriteString("\n" + line)
+	}
+	return b.String()
+}
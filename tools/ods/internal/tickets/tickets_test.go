@@ -0,0 +1,130 @@
+package tickets
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/auditlog"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/config"
+)
+
+func TestValidKey(t *testing.T) {
+	tests := map[string]bool{
+		"OPS-123":   true,
+		"ENG2-7":    true,
+		"ops-123":   false,
+		"OPS123":    false,
+		"OPS-":      false,
+		"O-1; rm":   false,
+		"../OPS-12": false,
+	}
+	for key, want := range tests {
+		if got := ValidKey(key); got != want {
+			t.Errorf("ValidKey(%q) = %v, want %v", key, got, want)
+		}
+	}
+}
+
+func TestNewCommenter(t *testing.T) {
+	t.Setenv("JIRA_EMAIL", "")
+	t.Setenv("JIRA_API_TOKEN", "")
+	t.Setenv("LINEAR_API_KEY", "")
+
+	if _, err := NewCommenter(config.TicketsConfig{}); err != ErrNotConfigured {
+		t.Errorf("no provider: err = %v, want ErrNotConfigured", err)
+	}
+	if _, err := NewCommenter(config.TicketsConfig{Provider: "jira", JiraURL: "https://x.atlassian.net"}); err == nil {
+		t.Error("jira without credentials: expected an error")
+	}
+	if _, err := NewCommenter(config.TicketsConfig{Provider: "github"}); err == nil {
+		t.Error("unknown provider: expected an error")
+	}
+
+	t.Setenv("LINEAR_API_KEY", "lin_api_x")
+	if c, err := NewCommenter(config.TicketsConfig{Provider: "linear"}); err != nil || c.(*Linear).APIKey != "lin_api_x" {
+		t.Errorf("linear: %v, %v", c, err)
+	}
+}
+
+func TestJiraComment(t *testing.T) {
+	var path, user, body string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		user, _, _ = r.BasicAuth()
+		var payload map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&payload)
+		body = payload["body"]
+		w.WriteHeader(http.StatusCreated)
+	}))
+	defer srv.Close()
+
+	j := &Jira{BaseURL: srv.URL, Email: "jane@example.com", Token: "t"}
+	if err := j.Comment("OPS-1", "hello"); err != nil {
+		t.Fatalf("Comment() error: %v", err)
+	}
+	if path != "/rest/api/2/issue/OPS-1/comment" || user != "jane@example.com" || !strings.Contains(body, "hello") {
+		t.Errorf("path=%q user=%q body=%q", path, user, body)
+	}
+}
+
+func TestLinearComment(t *testing.T) {
+	var calls []map[string]any
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "key" {
+			t.Errorf("missing API key")
+		}
+		var req map[string]any
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		calls = append(calls, req)
+		if strings.HasPrefix(req["query"].(string), "query") {
+			_, _ = w.Write([]byte(`{"data":{"issue":{"id":"uuid-1"}}}`))
+			return
+		}
+		_, _ = w.Write([]byte(`{"data":{"commentCreate":{"success":true}}}`))
+	}))
+	defer srv.Close()
+
+	old := LinearAPIURL
+	LinearAPIURL = srv.URL
+	defer func() { LinearAPIURL = old }()
+
+	if err := (&Linear{APIKey: "key"}).Comment("ENG-9", "hello"); err != nil {
+		t.Fatalf("Comment() error: %v", err)
+	}
+	if len(calls) != 2 {
+		t.Fatalf("expected 2 requests, got %d", len(calls))
+	}
+	vars := calls[1]["variables"].(map[string]any)
+	if vars["issueId"] != "uuid-1" || !strings.Contains(vars["body"].(string), "hello") {
+		t.Errorf("mutation variables = %v", vars)
+	}
+}
+
+func TestReportBody(t *testing.T) {
+	r := Report{
+		Command: "ods restart api-server -c prod",
+		Actor:   "jane",
+		OK:      false,
+		Summary: "rollout timed out",
+		Actions: []auditlog.Entry{{
+			Time:    time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC),
+			Action:  "restart",
+			Context: "prod/onyx",
+			Target:  "api-server",
+		}},
+	}
+	body := r.Body()
+	for _, want := range []string{
+		"$ ods restart api-server -c prod",
+		"Run by jane: failed (rollout timed out)",
+		"2026-03-01T12:00:00Z restart prod/onyx api-server",
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("body %q missing %q", body, want)
+		}
+	}
+}
@@ -0,0 +1,66 @@
+package token
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strings"
+	"testing"
+	"time"
+)
+
+func encode(t *testing.T, header, claims map[string]any) string {
+	t.Helper()
+	seg := func(v any) string {
+		data, err := json.Marshal(v)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return base64.RawURLEncoding.EncodeToString(data)
+	}
+	return seg(header) + "." + seg(claims) + ".c2ln"
+}
+
+func TestDecode(t *testing.T) {
+	now := time.Date(2026, 6, 1, 12, 0, 0, 0, time.UTC)
+	raw := encode(t, map[string]any{"alg": "HS256", "kid": "k1"}, map[string]any{
+		"sub": "3f0c", "aud": []string{AudienceSession}, "iat": now.Add(-2 * time.Hour).Unix(), "exp": now.Add(-time.Hour).Unix(),
+	})
+	tok, err := Decode("Bearer " + raw + "\n")
+	if err != nil {
+		t.Fatalf("Decode() error: %v", err)
+	}
+	if tok.Algorithm() != "HS256" || tok.KeyID() != "k1" || tok.String("sub") != "3f0c" || string(tok.Signature) != "sig" {
+		t.Fatalf("unexpected token %+v", tok)
+	}
+	if tok.Kind() != "Onyx session (JWT auth backend)" {
+		t.Errorf("Kind() = %q", tok.Kind())
+	}
+	if exp, ok := tok.Time("exp"); !ok || !exp.Equal(now.Add(-time.Hour)) {
+		t.Errorf("Time(exp) = %v, %v", exp, ok)
+	}
+	if f := strings.Join(tok.Findings(now), "\n"); !strings.Contains(f, "expired at 2026-06-01T11:00:00Z (1h0m0s ago)") {
+		t.Errorf("unexpected findings:\n%s", f)
+	}
+	if f := tok.Findings(now.Add(-90 * time.Minute)); len(f) != 0 {
+		t.Errorf("expected no findings before expiry, got %v", f)
+	}
+
+	for _, bad := range []string{"abc", "a.b", "!!.e30.c2ln"} {
+		if _, err := Decode(bad); err == nil {
+			t.Errorf("Decode(%q) succeeded", bad)
+		}
+	}
+}
+
+func TestParseVerification(t *testing.T) {
+	v, err := parseVerification("noise\n" + `{"status": "success", "key": "USER_AUTH_SECRET", "valid": false, "error": "Signature verification failed"}`)
+	if err != nil {
+		t.Fatalf("parseVerification() error: %v", err)
+	}
+	if v.Key != "USER_AUTH_SECRET" || v.Valid || v.Error != "Signature verification failed" {
+		t.Errorf("unexpected verification %+v", v)
+	}
+	if _, err := parseVerification(`{"status": "error", "message": "boom"}`); err == nil || err.Error() != "boom" {
+		t.Errorf("expected the script's message as error, got %v", err)
+	}
+}
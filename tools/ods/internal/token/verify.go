@@ -0,0 +1,97 @@
+package token
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/kube"
+)
+
+//go:embed verify_token.py
+var verifyScript string
+
+// Audiences fastapi-users puts on the tokens it signs with USER_AUTH_SECRET.
+const (
+	AudienceSession = "fastapi-users:auth"
+	AudienceReset   = "fastapi-users:reset"
+	AudienceVerify  = "fastapi-users:verify"
+)
+
+// Verification is the outcome of checking a token's signature with the
+// deployment's keys.
+type Verification struct {
+	// Key names the key tried, "" when the deployment has none for the
+	// token's algorithm.
+	Key   string `json:"key"`
+	Valid bool   `json:"valid"`
+	Error string `json:"error"`
+}
+
+// Verify checks raw's signature on pod, where the deployment's keys are.
+func Verify(c *kube.Cluster, pod, raw string) (*Verification, error) {
+	out, err := c.RunPython(pod, verifyScript, strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(raw), "Bearer ")))
+	if err != nil {
+		return nil, err
+	}
+	return parseVerification(out)
+}
+
+func parseVerification(stdout string) (*Verification, error) {
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	last := strings.TrimSpace(lines[len(lines)-1])
+	var r struct {
+		Status  string `json:"status"`
+		Message string `json:"message"`
+		Verification
+	}
+	if err := json.Unmarshal([]byte(last), &r); err != nil {
+		return nil, fmt.Errorf("unexpected output from verify script: %q", last)
+	}
+	if r.Status != "success" {
+		return nil, fmt.Errorf("%s", r.Message)
+	}
+	return &r.Verification, nil
+}
+
+// Kind names what issued the token, judging by its audience.
+func (t *JWT) Kind() string {
+	aud := t.Audiences()
+	switch {
+	case slices.Contains(aud, AudienceSession):
+		return "Onyx session (JWT auth backend)"
+	case slices.Contains(aud, AudienceReset):
+		return "Onyx password reset"
+	case slices.Contains(aud, AudienceVerify):
+		return "Onyx email verification"
+	case t.String("iss") != "":
+		return "issued by " + t.String("iss")
+	}
+	return "unknown"
+}
+
+// Findings lists why the token would be refused as of now, going by its
+// claims alone.
+func (t *JWT) Findings(now time.Time) []string {
+	var findings []string
+	if alg := t.Algorithm(); alg == "" || strings.EqualFold(alg, "none") {
+		findings = append(findings, "The token is unsigned")
+	}
+	exp, ok := t.Time("exp")
+	switch {
+	case !ok:
+		findings = append(findings, "The token has no exp claim")
+	case !now.Before(exp):
+		findings = append(findings, fmt.Sprintf("The token expired at %s (%s ago)", exp.UTC().Format(time.RFC3339), now.Sub(exp).Truncate(time.Second)))
+	}
+	if nbf, ok := t.Time("nbf"); ok && now.Before(nbf) {
+		findings = append(findings, fmt.Sprintf("The token is not valid before %s; check the clocks", nbf.UTC().Format(time.RFC3339)))
+	}
+	if iat, ok := t.Time("iat"); ok && iat.After(now.Add(time.Minute)) {
+		findings = append(findings, fmt.Sprintf("The token was issued in the future (%s); check the clocks", iat.UTC().Format(time.RFC3339)))
+	}
+	return findings
+}
@@ -0,0 +1,89 @@
+"""Verify a JWT's signature with the deployment's keys.
+
+Bundled with ods and piped into `python -` on an api-server pod by `ods token
+decode --verify`, so the signing secret never leaves the pod. HS* tokens are
+checked against USER_AUTH_SECRET (session JWTs, password-reset and
+verification tokens), RS256 tokens against the key published at
+JWT_PUBLIC_KEY_URL (tokens from an external identity service). Expiry and
+audience are left to ods, which reports them from the decoded claims.
+
+Usage:
+    python - <token>
+
+The last line on stdout is a JSON object with "status", "key" (which key was
+tried, "" when none applies), "valid" and "error".
+"""
+
+from __future__ import annotations
+
+import json
+import sys
+from typing import Any
+
+CHECKS = {"verify_aud": False, "verify_exp": False, "verify_nbf": False, "verify_iat": False}
+
+
+def verify(token: str) -> dict[str, Any]:
+    import jwt
+
+    from onyx.configs.app_configs import JWT_PUBLIC_KEY_URL
+    from onyx.configs.app_configs import USER_AUTH_SECRET
+
+    alg = jwt.get_unverified_header(token).get("alg", "")
+    if alg.startswith("HS"):
+        key_name, key = "USER_AUTH_SECRET", USER_AUTH_SECRET
+    elif alg == "RS256":
+        if not JWT_PUBLIC_KEY_URL:
+            return {
+                "status": "success",
+                "key": "",
+                "valid": False,
+                "error": "JWT_PUBLIC_KEY_URL is not set, so no RS256 key is trusted",
+            }
+        from onyx.auth.jwt import get_public_key
+
+        key_name, key = "JWT_PUBLIC_KEY_URL", get_public_key(token)
+        if key is None:
+            return {
+                "status": "success",
+                "key": key_name,
+                "valid": False,
+                "error": f"No key at {JWT_PUBLIC_KEY_URL} matches the token",
+            }
+    else:
+        return {
+            "status": "success",
+            "key": "",
+            "valid": False,
+            "error": f"The deployment trusts no {alg or 'unsigned'} key",
+        }
+
+    if not key:
+        return {
+            "status": "success",
+            "key": key_name,
+            "valid": False,
+            "error": f"{key_name} is not set",
+        }
+    try:
+        jwt.decode(token, key, algorithms=[alg], options=CHECKS)
+    except jwt.PyJWTError as e:
+        return {"status": "success", "key": key_name, "valid": False, "error": str(e)}
+    return {"status": "success", "key": key_name, "valid": True, "error": ""}
+
+
+def main() -> None:
+    if len(sys.argv) != 2:
+        print(json.dumps({"status": "error", "message": "Usage: python - <token>"}))
+        sys.exit(1)
+
+    try:
+        result = verify(sys.argv[1])
+    except Exception as e:
+        print(f"Error: {e}", file=sys.stderr)
+        result = {"status": "error", "message": str(e)}
+    print(json.dumps(result))
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,280 @@
+// Package trace follows the path a request for a host takes into a
+// deployment: DNS, the load balancer, the ingress rule, the services it
+// routes to and their pods, and finally an HTTP request end to end. Each
+// hop is judged on what was observed, so the first broken one is where to
+// look.
+package trace
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/domains"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/kube"
+)
+
+// Hop states.
+const (
+	StateOK   = "ok"
+	StateWarn = "warn"
+	StateFail = "fail"
+	// StateSkipped is a hop that could not be checked because an earlier
+	// one failed.
+	StateSkipped = "skipped"
+)
+
+// Hop is one step of the request path.
+type Hop struct {
+	// Name is dns, load-balancer, ingress, service, pods or http.
+	Name   string `json:"name"`
+	Target string `json:"target"`
+	State  string `json:"state"`
+	Detail string `json:"detail"`
+}
+
+// Trace is the request path of one host.
+type Trace struct {
+	Host   string `json:"host"`
+	Tenant string `json:"tenant,omitempty"`
+	Hops   []Hop  `json:"hops"`
+}
+
+// Break returns the first hop that failed, or nil when none did.
+func (t *Trace) Break() *Hop {
+	for i := range t.Hops {
+		if t.Hops[i].State == StateFail {
+			return &t.Hops[i]
+		}
+	}
+	return nil
+}
+
+// NormalizeHost turns a host, host:port or URL into a lowercase host name.
+func NormalizeHost(s string) string {
+	s = strings.TrimSpace(s)
+	if strings.Contains(s, "://") {
+		if u, err := url.Parse(s); err == nil {
+			s = u.Host
+		}
+	}
+	s, _, _ = strings.Cut(s, "/")
+	if h, _, ok := strings.Cut(s, ":"); ok {
+		s = h
+	}
+	return strings.ToLower(strings.TrimSuffix(s, "."))
+}
+
+// FindIngress returns the ingress with a rule for host, or nil.
+func FindIngress(ingresses []*kube.Ingress, host string) *kube.Ingress {
+	for _, ing := range ingresses {
+		for _, h := range ing.Hosts {
+			if strings.EqualFold(h, host) {
+				return ing
+			}
+		}
+	}
+	return nil
+}
+
+// DNSHop judges what host resolved to against the load balancer's
+// addresses.
+func DNSHop(host string, dns *domains.DNS) Hop {
+	hop := Hop{Name: "dns", Target: host}
+	if dns.Error != "" {
+		hop.State = StateFail
+		hop.Detail = fmt.Sprintf("does not resolve: %s", dns.Error)
+		return hop
+	}
+	hop.Detail = strings.Join(dns.Addresses, ", ")
+	if dns.CNAME != "" {
+		hop.Detail = dns.CNAME + " -> " + hop.Detail
+	}
+	hop.State = StateOK
+	if len(dns.LoadBalancerAddresses) == 0 {
+		return hop
+	}
+	lb := map[string]bool{}
+	for _, a := range dns.LoadBalancerAddresses {
+		lb[a] = true
+	}
+	for _, a := range dns.Addresses {
+		if lb[a] {
+			return hop
+		}
+	}
+	hop.State = StateFail
+	hop.Detail += fmt.Sprintf(", not the ingress load balancer (%s)", strings.Join(dns.LoadBalancerAddresses, ", "))
+	return hop
+}
+
+// LoadBalancerHop judges a TCP connection to the ingress's load balancer on
+// port; lb is nil when the ingress controller published none.
+func LoadBalancerHop(lb []string, port string, dialErr error) Hop {
+	hop := Hop{Name: "load-balancer", Target: strings.Join(lb, ", ")}
+	switch {
+	case len(lb) == 0:
+		hop.Target = "-"
+		hop.State = StateFail
+		hop.Detail = "the ingress controller has published no load balancer for the ingress"
+	case dialErr != nil:
+		hop.State = StateFail
+		hop.Detail = fmt.Sprintf("port %s: %v", port, dialErr)
+	default:
+		hop.State = StateOK
+		hop.Detail = fmt.Sprintf("accepts connections on port %s", port)
+	}
+	return hop
+}
+
+// IngressHop judges the ingress serving host and the backends it routes
+// it to.
+func IngressHop(ing *kube.Ingress, host string) Hop {
+	if ing == nil {
+		return Hop{Name: "ingress", Target: "-", State: StateFail, Detail: fmt.Sprintf("no ingress has a rule for %s", host)}
+	}
+	hop := Hop{Name: "ingress", Target: ing.Name, State: StateOK}
+	backends := ing.BackendsFor(host)
+	var routes []string
+	for _, b := range backends {
+		path := b.Path
+		if path == "" {
+			path = "*"
+		}
+		to := b.Service + ":" + b.Port
+		if b.Service == "" {
+			to = "(resource backend)"
+		}
+		routes = append(routes, path+" -> "+to)
+	}
+	if len(backends) == 0 {
+		hop.State = StateFail
+		hop.Detail = "the rule for the host routes no paths"
+		return hop
+	}
+	hop.Detail = strings.Join(routes, ", ")
+	if ing.Class != "" {
+		hop.Detail = "class " + ing.Class + "; " + hop.Detail
+	}
+	if ing.TLSSecret(host) == "" {
+		hop.State = StateWarn
+		hop.Detail += "; served without TLS"
+	}
+	return hop
+}
+
+// ServicePort returns the port of svc an ingress backend port (a number or
+// a name) refers to.
+func ServicePort(svc *kube.Service, port string) (kube.ServicePort, bool) {
+	if n, err := strconv.Atoi(port); err == nil {
+		for _, p := range svc.Ports {
+			if p.Port == n {
+				return p, true
+			}
+		}
+		return kube.ServicePort{}, false
+	}
+	return svc.Port(port)
+}
+
+// ServiceHop judges the service an ingress backend routes to; svc is nil
+// when it could not be fetched, with err saying why.
+func ServiceHop(b kube.IngressBackend, svc *kube.Service, err error) Hop {
+	hop := Hop{Name: "service", Target: b.Service + ":" + b.Port}
+	if svc == nil {
+		hop.State = StateFail
+		hop.Detail = fmt.Sprintf("service %s not found", b.Service)
+		if err != nil {
+			hop.Detail += ": " + err.Error()
+		}
+		return hop
+	}
+	p, ok := ServicePort(svc, b.Port)
+	if !ok {
+		var ports []string
+		for _, sp := range svc.Ports {
+			ports = append(ports, strconv.Itoa(sp.Port))
+		}
+		hop.State = StateFail
+		hop.Detail = fmt.Sprintf("has no port %s (ports: %s)", b.Port, strings.Join(ports, ", "))
+		return hop
+	}
+	hop.State = StateOK
+	hop.Detail = fmt.Sprintf("%s, port %d -> target port %s", svc.Type, p.Port, p.TargetPort)
+	if len(svc.Selector) == 0 {
+		hop.State = StateWarn
+		hop.Detail += "; no pod selector, so its endpoints are managed by hand"
+	}
+	return hop
+}
+
+// Selector renders a service's pod selector as a label selector.
+func Selector(svc *kube.Service) string {
+	keys := make([]string, 0, len(svc.Selector))
+	for k := range svc.Selector {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		parts[i] = k + "=" + svc.Selector[k]
+	}
+	return strings.Join(parts, ",")
+}
+
+// PodsHop judges the pods a service selects.
+func PodsHop(service, selector string, pods []*kube.Pod) Hop {
+	hop := Hop{Name: "pods", Target: selector}
+	ready := 0
+	var failing []string
+	for _, p := range pods {
+		if p.Ready {
+			ready++
+		} else if reason := p.FailureReason(); reason != "" {
+			failing = append(failing, p.Name+" ("+reason+")")
+		}
+	}
+	hop.Detail = fmt.Sprintf("%d/%d ready", ready, len(pods))
+	if len(failing) > 0 {
+		hop.Detail += "; " + strings.Join(failing, ", ")
+	}
+	switch {
+	case len(pods) == 0:
+		hop.State = StateFail
+		hop.Detail = fmt.Sprintf("service %s selects no pods", service)
+	case ready == 0:
+		hop.State = StateFail
+	case ready < len(pods):
+		hop.State = StateWarn
+	default:
+		hop.State = StateOK
+	}
+	return hop
+}
+
+// HTTPHop judges an end-to-end request to target.
+func HTTPHop(target string, status int, err error) Hop {
+	hop := Hop{Name: "http", Target: target}
+	switch {
+	case err != nil:
+		hop.State = StateFail
+		hop.Detail = err.Error()
+	case status == 502 || status == 503 || status == 504:
+		hop.State = StateFail
+		hop.Detail = fmt.Sprintf("%d: the ingress controller could not get an answer from the backend", status)
+	case status >= 500:
+		hop.State = StateFail
+		hop.Detail = fmt.Sprintf("%d: the application failed", status)
+	default:
+		hop.State = StateOK
+		hop.Detail = strconv.Itoa(status)
+	}
+	return hop
+}
+
+// Skipped is a hop that was not checked because of an earlier failure.
+func Skipped(name, target, reason string) Hop {
+	return Hop{Name: name, Target: target, State: StateSkipped, Detail: reason}
+}
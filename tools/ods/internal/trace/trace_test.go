@@ -0,0 +1,110 @@
+package trace
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/domains"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/kube"
+)
+
+func TestNormalizeHost(t *testing.T) {
+	for in, want := range map[string]string{
+		"cloud.onyx.app":                    "cloud.onyx.app",
+		"Onyx.Acme.com.":                    "onyx.acme.com",
+		"https://onyx.acme.com/chat?x=1":    "onyx.acme.com",
+		"onyx.acme.com:443":                 "onyx.acme.com",
+		"http://onyx.acme.com:8080/api/x/y": "onyx.acme.com",
+	} {
+		if got := NormalizeHost(in); got != want {
+			t.Errorf("NormalizeHost(%q) = %q, want %q", in, got, want)
+		}
+	}
+}
+
+func TestDNSHop(t *testing.T) {
+	ok := DNSHop("onyx.acme.com", &domains.DNS{CNAME: "abc.elb.amazonaws.com", Addresses: []string{"203.0.113.7"}, LoadBalancerAddresses: []string{"203.0.113.7"}})
+	if ok.State != StateOK {
+		t.Errorf("expected ok, got %+v", ok)
+	}
+	elsewhere := DNSHop("onyx.acme.com", &domains.DNS{Addresses: []string{"198.51.100.1"}, LoadBalancerAddresses: []string{"203.0.113.7"}})
+	if elsewhere.State != StateFail {
+		t.Errorf("expected a record pointing elsewhere to fail, got %+v", elsewhere)
+	}
+	if h := DNSHop("onyx.acme.com", &domains.DNS{Error: "no such host"}); h.State != StateFail {
+		t.Errorf("expected a failed lookup to fail, got %+v", h)
+	}
+}
+
+func TestIngressAndServiceHops(t *testing.T) {
+	ing := &kube.Ingress{
+		Name:  "onyx",
+		Hosts: []string{"cloud.onyx.app"},
+		Backends: []kube.IngressBackend{
+			{Host: "cloud.onyx.app", Path: "/api", Service: "api-server", Port: "8080"},
+			{Host: "cloud.onyx.app", Path: "/", Service: "web-server", Port: "http"},
+		},
+		TLS: []kube.IngressTLS{{Hosts: []string{"cloud.onyx.app"}, SecretName: "onyx-tls"}},
+	}
+	if FindIngress([]*kube.Ingress{ing}, "CLOUD.onyx.app") != ing {
+		t.Error("FindIngress should match hosts case-insensitively")
+	}
+	if h := IngressHop(ing, "cloud.onyx.app"); h.State != StateOK || h.Detail != "/api -> api-server:8080, / -> web-server:http" {
+		t.Errorf("unexpected ingress hop %+v", h)
+	}
+	if h := IngressHop(nil, "cloud.onyx.app"); h.State != StateFail {
+		t.Errorf("expected a missing ingress to fail, got %+v", h)
+	}
+
+	svc := &kube.Service{
+		Name:     "web-server",
+		Type:     "ClusterIP",
+		Ports:    []kube.ServicePort{{Name: "http", Port: 3000, TargetPort: "3000"}},
+		Selector: map[string]string{"app": "web-server", "tier": "frontend"},
+	}
+	if h := ServiceHop(ing.Backends[1], svc, nil); h.State != StateOK {
+		t.Errorf("expected the named port to match, got %+v", h)
+	}
+	if h := ServiceHop(kube.IngressBackend{Service: "web-server", Port: "8080"}, svc, nil); h.State != StateFail {
+		t.Errorf("expected a missing port to fail, got %+v", h)
+	}
+	if h := ServiceHop(ing.Backends[0], nil, errors.New("NotFound")); h.State != StateFail {
+		t.Errorf("expected a missing service to fail, got %+v", h)
+	}
+	if got := Selector(svc); got != "app=web-server,tier=frontend" {
+		t.Errorf("Selector = %q", got)
+	}
+}
+
+func TestPodsHop(t *testing.T) {
+	crashing := &kube.Pod{Name: "web-1", Containers: []kube.ContainerStatus{{Name: "web", WaitingReason: "CrashLoopBackOff"}}}
+	ready := &kube.Pod{Name: "web-2", Ready: true}
+	tests := []struct {
+		pods []*kube.Pod
+		want string
+	}{
+		{nil, StateFail},
+		{[]*kube.Pod{crashing}, StateFail},
+		{[]*kube.Pod{crashing, ready}, StateWarn},
+		{[]*kube.Pod{ready}, StateOK},
+	}
+	for _, tt := range tests {
+		if h := PodsHop("web-server", "app=web", tt.pods); h.State != tt.want {
+			t.Errorf("PodsHop(%d pods) = %+v, want %s", len(tt.pods), h, tt.want)
+		}
+	}
+}
+
+func TestBreak(t *testing.T) {
+	tr := &Trace{Hops: []Hop{
+		HTTPHop("https://x/", 302, nil),
+		LoadBalancerHop([]string{"abc.elb.amazonaws.com"}, "443", errors.New("i/o timeout")),
+		HTTPHop("https://x/", 503, nil),
+	}}
+	if b := tr.Break(); b == nil || b.Name != "load-balancer" {
+		t.Errorf("Break() = %+v, want the load balancer", b)
+	}
+	if (&Trace{Hops: []Hop{HTTPHop("https://x/", 200, nil)}}).Break() != nil {
+		t.Error("expected no break")
+	}
+}
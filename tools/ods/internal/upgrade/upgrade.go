@@ -0,0 +1,303 @@
+// Package upgrade reads the upgrade metadata packaged with each Onyx release
+// and checks a self-hosted instance against the release it is moving to.
+package upgrade
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/runner"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/version"
+)
+
+// Runner runs git; nil means runner.Default. Tests set a runner.Fake.
+var Runner runner.Runner
+
+// git runs git in the checkout at root and returns its stdout.
+func git(root string, args ...string) ([]byte, error) {
+	return runner.Output(Runner, runner.Cmd{Name: "git", Args: append([]string{"-C", root}, args...)})
+}
+
+// Repo-relative paths read at the target release.
+const (
+	MetadataPath   = "deployment/upgrade/upgrades.yaml"
+	MigrationsPath = "backend/alembic/versions"
+)
+
+// baseMigrationTime is what a migration without a cost entry is assumed to
+// take: schema changes on a self-hosted database are near-instant.
+const baseMigrationTime = 2 * time.Second
+
+// Metadata is the contents of MetadataPath.
+type Metadata struct {
+	Releases   []Release       `yaml:"releases" json:"releases,omitempty"`
+	Migrations []MigrationCost `yaml:"migrations" json:"migrations,omitempty"`
+}
+
+// Release holds what an operator must know when upgrading past a version.
+type Release struct {
+	Version  string      `yaml:"version" json:"version"`
+	Breaking []string    `yaml:"breaking" json:"breaking,omitempty"`
+	Env      []EnvChange `yaml:"env" json:"env,omitempty"`
+}
+
+// EnvChange is an environment variable a release adds or renames.
+type EnvChange struct {
+	Name string `yaml:"name" json:"name"`
+	// RenamedFrom is the variable's name before this release, if renamed.
+	RenamedFrom string `yaml:"renamed_from" json:"renamed_from,omitempty"`
+	// Required variables have no default; the release will not start
+	// without them.
+	Required bool   `yaml:"required" json:"required,omitempty"`
+	Note     string `yaml:"note" json:"note,omitempty"`
+}
+
+// MigrationCost describes a migration whose run time grows with a table,
+// typically a data backfill.
+type MigrationCost struct {
+	Revision              string  `yaml:"revision" json:"revision"`
+	Table                 string  `yaml:"table" json:"table,omitempty"`
+	SecondsPerMillionRows float64 `yaml:"seconds_per_million_rows" json:"seconds_per_million_rows,omitempty"`
+	Note                  string  `yaml:"note" json:"note,omitempty"`
+}
+
+// ParseMetadata parses the contents of MetadataPath.
+func ParseMetadata(data []byte) (*Metadata, error) {
+	var m Metadata
+	if err := yaml.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", MetadataPath, err)
+	}
+	return &m, nil
+}
+
+// Between returns the releases after from up to and including to, oldest
+// first. An empty from means the current version is unknown, and only the
+// target release itself is returned.
+func (m *Metadata) Between(from, to string) []Release {
+	var out []Release
+	for _, r := range m.Releases {
+		if version.Compare(r.Version, to) > 0 {
+			continue
+		}
+		if from == "" && version.Compare(r.Version, to) != 0 {
+			continue
+		}
+		if from != "" && version.Compare(r.Version, from) <= 0 {
+			continue
+		}
+		out = append(out, r)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return version.Compare(out[i].Version, out[j].Version) < 0 })
+	return out
+}
+
+// ResolveRef makes sure the release tag is available in the checkout at
+// root, fetching it from origin if needed, and returns it.
+func ResolveRef(root, tag string) (string, error) {
+	if _, err := git(root, "rev-parse", "--verify", "--quiet", tag+"^{commit}"); err == nil {
+		return tag, nil
+	}
+	out, err := runner.CombinedOutput(Runner, runner.Cmd{Name: "git", Args: []string{"-C", root, "fetch", "--quiet", "--no-tags", "origin", "refs/tags/" + tag + ":refs/tags/" + tag}})
+	if err != nil {
+		return "", fmt.Errorf("release %s not found locally or on origin: %s", tag, strings.TrimSpace(string(out)))
+	}
+	return tag, nil
+}
+
+// LoadMetadata reads MetadataPath at ref. Releases that predate the file
+// have no metadata, which is reported as nil, nil.
+func LoadMetadata(root, ref string) (*Metadata, error) {
+	out, err := git(root, "show", ref+":"+MetadataPath)
+	if err != nil {
+		return nil, nil
+	}
+	return ParseMetadata(out)
+}
+
+// Graph maps each Alembic revision to its down revisions.
+type Graph map[string][]string
+
+// LoadRevisions reads the Alembic revision graph at ref.
+func LoadRevisions(root, ref string) (Graph, error) {
+	out, err := git(root, "grep", "-E", `^(down_)?revision\b`, ref, "--", MigrationsPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the migrations at %s: %w", ref, err)
+	}
+	return parseRevisions(string(out), ref), nil
+}
+
+var (
+	revisionLine = regexp.MustCompile(`^(down_)?revision\b[^=]*=(.*)$`)
+	revisionID   = regexp.MustCompile(`["']([0-9A-Za-z_]+)["']`)
+)
+
+// parseRevisions builds the graph from git grep output at ref, whose lines
+// read "<ref>:<path>:<line>".
+func parseRevisions(out, ref string) Graph {
+	type file struct {
+		rev  string
+		down []string
+	}
+	files := map[string]*file{}
+	for _, line := range strings.Split(out, "\n") {
+		line, _ = strings.CutPrefix(line, ref+":")
+		path, src, ok := strings.Cut(line, ":")
+		if !ok {
+			continue
+		}
+		m := revisionLine.FindStringSubmatch(strings.TrimSpace(src))
+		if m == nil {
+			continue
+		}
+		// Drop trailing comments so they cannot contribute IDs.
+		value, _, _ := strings.Cut(m[2], "#")
+		var ids []string
+		for _, id := range revisionID.FindAllStringSubmatch(value, -1) {
+			ids = append(ids, id[1])
+		}
+		f := files[path]
+		if f == nil {
+			f = &file{}
+			files[path] = f
+		}
+		if m[1] == "" && len(ids) > 0 {
+			f.rev = ids[0]
+		} else {
+			f.down = ids
+		}
+	}
+	g := Graph{}
+	for _, f := range files {
+		if f.rev != "" {
+			g[f.rev] = f.down
+		}
+	}
+	return g
+}
+
+// Heads returns the revisions nothing else revises, sorted.
+func (g Graph) Heads() []string {
+	revised := map[string]bool{}
+	for _, downs := range g {
+		for _, d := range downs {
+			revised[d] = true
+		}
+	}
+	var heads []string
+	for rev := range g {
+		if !revised[rev] {
+			heads = append(heads, rev)
+		}
+	}
+	sort.Strings(heads)
+	return heads
+}
+
+// Pending returns the revisions upgrading from the database's current
+// revisions to the heads applies, sorted. No current revisions is an
+// unmigrated database.
+func (g Graph) Pending(current ...string) ([]string, error) {
+	applied := map[string]bool{}
+	for _, rev := range current {
+		if _, ok := g[rev]; !ok {
+			return nil, fmt.Errorf("the database is at revision %s, which the target release does not have (is the instance newer than the target?)", rev)
+		}
+		g.walk(rev, applied)
+	}
+	all := map[string]bool{}
+	for _, head := range g.Heads() {
+		g.walk(head, all)
+	}
+	var pending []string
+	for rev := range all {
+		if !applied[rev] {
+			pending = append(pending, rev)
+		}
+	}
+	sort.Strings(pending)
+	return pending, nil
+}
+
+// walk marks rev and its ancestors in seen.
+func (g Graph) walk(rev string, seen map[string]bool) {
+	stack := []string{rev}
+	for len(stack) > 0 {
+		r := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if seen[r] {
+			continue
+		}
+		seen[r] = true
+		stack = append(stack, g[r]...)
+	}
+}
+
+// HeavyMigration is a pending migration whose cost depends on the data.
+type HeavyMigration struct {
+	MigrationCost
+	Rows     int64         `json:"rows"`
+	Estimate time.Duration `json:"estimate_ns"`
+}
+
+// Estimate returns how long the pending migrations should take on a
+// database with the given row counts per table, and the migrations that
+// account for most of it, slowest first.
+func Estimate(pending []string, costs []MigrationCost, rows map[string]int64) (time.Duration, []HeavyMigration) {
+	byRevision := map[string]MigrationCost{}
+	for _, c := range costs {
+		byRevision[c.Revision] = c
+	}
+	var total time.Duration
+	var heavy []HeavyMigration
+	for _, rev := range pending {
+		total += baseMigrationTime
+		c, ok := byRevision[rev]
+		if !ok {
+			continue
+		}
+		h := HeavyMigration{MigrationCost: c, Rows: rows[c.Table]}
+		h.Estimate = time.Duration(float64(h.Rows) / 1e6 * c.SecondsPerMillionRows * float64(time.Second))
+		total += h.Estimate
+		heavy = append(heavy, h)
+	}
+	sort.SliceStable(heavy, func(i, j int) bool { return heavy[i].Estimate > heavy[j].Estimate })
+	return total, heavy
+}
+
+// EnvIssue is an environment change the instance's configuration has not
+// caught up with.
+type EnvIssue struct {
+	Release string `json:"release"`
+	EnvChange
+	// Action is what to do, e.g. "rename OLD to NEW"; empty for an optional
+	// variable that is only worth knowing about.
+	Action string `json:"action,omitempty"`
+}
+
+// CheckEnv compares the env changes of releases against the instance's
+// environment. Optional additions are returned with an empty Action.
+func CheckEnv(releases []Release, env map[string]string) []EnvIssue {
+	var issues []EnvIssue
+	for _, r := range releases {
+		for _, c := range r.Env {
+			_, has := env[c.Name]
+			_, hasOld := env[c.RenamedFrom]
+			issue := EnvIssue{Release: r.Version, EnvChange: c}
+			switch {
+			case has:
+				continue
+			case c.RenamedFrom != "" && hasOld:
+				issue.Action = fmt.Sprintf("rename %s to %s", c.RenamedFrom, c.Name)
+			case c.Required:
+				issue.Action = "set " + c.Name
+			}
+			issues = append(issues, issue)
+		}
+	}
+	return issues
+}
@@ -0,0 +1,146 @@
+package upgrade
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/runner"
+)
+
+func useFake(t *testing.T) *runner.Fake {
+	t.Helper()
+	f := &runner.Fake{}
+	Runner = f
+	t.Cleanup(func() { Runner = nil })
+	return f
+}
+
+func TestParseRevisions(t *testing.T) {
+	out := `v2.10.4:backend/alembic/versions/a_first.py:revision = "aaa"
+v2.10.4:backend/alembic/versions/a_first.py:down_revision = None
+v2.10.4:backend/alembic/versions/b_second.py:revision: str = "bbb"
+v2.10.4:backend/alembic/versions/b_second.py:down_revision: Union[str, None] = "aaa"
+v2.10.4:backend/alembic/versions/c_branch.py:revision = "ccc"  # Generate a new unique ID
+v2.10.4:backend/alembic/versions/c_branch.py:down_revision = "aaa"
+v2.10.4:backend/alembic/versions/d_merge.py:revision = "ddd"
+v2.10.4:backend/alembic/versions/d_merge.py:down_revision = ("bbb", "ccc")
+`
+	g := parseRevisions(out, "v2.10.4")
+	want := Graph{"aaa": nil, "bbb": {"aaa"}, "ccc": {"aaa"}, "ddd": {"bbb", "ccc"}}
+	if !reflect.DeepEqual(g, want) {
+		t.Fatalf("parseRevisions() = %v, want %v", g, want)
+	}
+	if heads := g.Heads(); !reflect.DeepEqual(heads, []string{"ddd"}) {
+		t.Errorf("Heads() = %v", heads)
+	}
+}
+
+func TestPending(t *testing.T) {
+	g := Graph{"aaa": nil, "bbb": {"aaa"}, "ccc": {"aaa"}, "ddd": {"bbb", "ccc"}}
+	tests := []struct {
+		current []string
+		want    []string
+	}{
+		{nil, []string{"aaa", "bbb", "ccc", "ddd"}},
+		{[]string{"bbb"}, []string{"ccc", "ddd"}},
+		{[]string{"bbb", "ccc"}, []string{"ddd"}},
+		{[]string{"ddd"}, nil},
+	}
+	for _, tt := range tests {
+		got, err := g.Pending(tt.current...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Pending(%v) = %v, want %v", tt.current, got, tt.want)
+		}
+	}
+	if _, err := g.Pending("zzz"); err == nil {
+		t.Error("Pending of an unknown revision should fail")
+	}
+}
+
+func TestBetween(t *testing.T) {
+	m := &Metadata{Releases: []Release{{Version: "v2.11.0"}, {Version: "v2.9.0"}, {Version: "v2.10.0"}, {Version: "v2.8.0"}}}
+	versions := func(rs []Release) []string {
+		var out []string
+		for _, r := range rs {
+			out = append(out, r.Version)
+		}
+		return out
+	}
+	if got := versions(m.Between("v2.8.0", "v2.10.0")); !reflect.DeepEqual(got, []string{"v2.9.0", "v2.10.0"}) {
+		t.Errorf("Between(v2.8.0, v2.10.0) = %v", got)
+	}
+	if got := versions(m.Between("", "v2.10.0")); !reflect.DeepEqual(got, []string{"v2.10.0"}) {
+		t.Errorf("Between(\"\", v2.10.0) = %v", got)
+	}
+}
+
+func TestCheckEnv(t *testing.T) {
+	releases := []Release{{Version: "v2.10.0", Env: []EnvChange{
+		{Name: "NEW_NAME", RenamedFrom: "OLD_NAME"},
+		{Name: "REQUIRED", Required: true},
+		{Name: "ALREADY_SET", Required: true},
+		{Name: "OPTIONAL"},
+	}}}
+	issues := CheckEnv(releases, map[string]string{"OLD_NAME": "x", "ALREADY_SET": "y"})
+	var actions []string
+	for _, i := range issues {
+		actions = append(actions, i.Name+":"+i.Action)
+	}
+	want := []string{"NEW_NAME:rename OLD_NAME to NEW_NAME", "REQUIRED:set REQUIRED", "OPTIONAL:"}
+	if !reflect.DeepEqual(actions, want) {
+		t.Errorf("CheckEnv() = %v, want %v", actions, want)
+	}
+}
+
+func TestEstimate(t *testing.T) {
+	costs := []MigrationCost{
+		{Revision: "bbb", Table: "document", SecondsPerMillionRows: 60},
+		{Revision: "zzz", Table: "chat_message", SecondsPerMillionRows: 600},
+	}
+	total, heavy := Estimate([]string{"aaa", "bbb"}, costs, map[string]int64{"document": 2_000_000})
+	if want := 2*baseMigrationTime + 2*time.Minute; total != want {
+		t.Errorf("total = %s, want %s", total, want)
+	}
+	if len(heavy) != 1 || heavy[0].Revision != "bbb" || heavy[0].Estimate != 2*time.Minute {
+		t.Errorf("heavy = %+v", heavy)
+	}
+}
+
+func TestResolveRef(t *testing.T) {
+	f := useFake(t).
+		On("git -C /src/onyx rev-parse --verify --quiet v2.10.4^{commit}", runner.Response{Stdout: "0123abcd\n"}).
+		On("git -C /src/onyx rev-parse", runner.Response{Err: errors.New("exit status 1")}).
+		On("git -C /src/onyx fetch --quiet --no-tags origin refs/tags/v2.11.0:refs/tags/v2.11.0", runner.Response{}).
+		On("git -C /src/onyx fetch", runner.Response{Stderr: "fatal: couldn't find remote ref\n", Err: errors.New("exit status 128")})
+
+	if ref, err := ResolveRef("/src/onyx", "v2.10.4"); err != nil || ref != "v2.10.4" {
+		t.Errorf("ResolveRef(local tag) = %q, %v", ref, err)
+	}
+	if ref, err := ResolveRef("/src/onyx", "v2.11.0"); err != nil || ref != "v2.11.0" {
+		t.Errorf("ResolveRef(remote tag) = %q, %v", ref, err)
+	}
+	if _, err := ResolveRef("/src/onyx", "v9.9.9"); err == nil || !strings.Contains(err.Error(), "couldn't find remote ref") {
+		t.Errorf("expected git's output in the error, got %v", err)
+	}
+	if n := len(f.Lines()); n != 5 {
+		t.Errorf("expected 5 git commands, got %q", f.Lines())
+	}
+}
+
+func TestLoadRevisions(t *testing.T) {
+	useFake(t).On("git -C /src/onyx grep -E ^(down_)?revision\\b v2.10.4 -- backend/alembic/versions",
+		runner.Response{Stdout: "v2.10.4:backend/alembic/versions/a.py:revision = \"aaa\"\nv2.10.4:backend/alembic/versions/a.py:down_revision = None\n"})
+	g, err := LoadRevisions("/src/onyx", "v2.10.4")
+	if err != nil || !reflect.DeepEqual(g, Graph{"aaa": nil}) {
+		t.Errorf("LoadRevisions() = %v, %v", g, err)
+	}
+	if meta, err := LoadMetadata("/src/onyx", "v2.10.4"); meta != nil || err != nil {
+		t.Errorf("LoadMetadata() without the file = %v, %v; want nil, nil", meta, err)
+	}
+}
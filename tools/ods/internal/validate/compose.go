@@ -0,0 +1,236 @@
+package validate
+
+import (
+	"fmt"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// HostVars are variables compose files may take from the user's shell rather
+// than an env template.
+var HostVars = []string{"HOME", "USER", "UID", "GID", "PWD", "HOSTNAME", "XDG_RUNTIME_DIR"}
+
+// ComposeFile is a parsed docker compose file.
+type ComposeFile struct {
+	File string
+	root *yaml.Node
+}
+
+// ParseCompose parses a compose file, reporting it as a syntax issue if it is
+// not valid YAML.
+func ParseCompose(file string, data []byte) (*ComposeFile, []Issue) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, []Issue{{File: file, Kind: KindSyntax, Message: err.Error()}}
+	}
+	root := &doc
+	if doc.Kind == yaml.DocumentNode && len(doc.Content) > 0 {
+		root = doc.Content[0]
+	}
+	return &ComposeFile{File: file, root: root}, nil
+}
+
+// UndefinedVars reports variable references with no default that are not in
+// defined. A variable is reported once per file, at its first use.
+func (c *ComposeFile) UndefinedVars(defined map[string]bool) []Issue {
+	var issues []Issue
+	seen := map[string]bool{}
+	walkScalars(c.root, func(n *yaml.Node) {
+		for _, ref := range ParseVarRefs(n.Value) {
+			if ref.HasDefault || defined[ref.Name] || seen[ref.Name] {
+				continue
+			}
+			seen[ref.Name] = true
+			msg := fmt.Sprintf("${%s} has no default and is not in any env template", ref.Name)
+			if ref.Required {
+				msg = fmt.Sprintf("${%s} is required but not in any env template", ref.Name)
+			}
+			issues = append(issues, Issue{File: c.File, Line: n.Line, Kind: KindUndefined, Message: msg})
+		}
+	})
+	return issues
+}
+
+// Images returns the images of services that are pulled rather than built,
+// with variables resolved to their defaults. Images that cannot be resolved
+// that way are left out.
+func (c *ComposeFile) Images() []Image {
+	var images []Image
+	for _, svc := range c.services() {
+		if mapValue(svc.node, "build") != nil {
+			continue
+		}
+		img := mapValue(svc.node, "image")
+		if img == nil || img.Kind != yaml.ScalarNode {
+			continue
+		}
+		if ref, ok := Interpolate(img.Value); ok && ref != "" {
+			images = append(images, Image{Ref: ref, File: c.File, Line: img.Line})
+		}
+	}
+	return images
+}
+
+// PortConflicts reports host ports published by more than one service when
+// files are combined, as with docker compose -f a.yml -f b.yml.
+func PortConflicts(files ...*ComposeFile) []Issue {
+	type binding struct {
+		service string
+		file    string
+		line    int
+	}
+	byPort := map[string][]binding{}
+	for _, f := range files {
+		for _, svc := range f.services() {
+			ports := mapValue(svc.node, "ports")
+			if ports == nil || ports.Kind != yaml.SequenceNode {
+				continue
+			}
+			for _, p := range ports.Content {
+				key, ok := hostPort(p)
+				if !ok {
+					continue
+				}
+				dup := false
+				for _, b := range byPort[key] {
+					if b.service == svc.name {
+						dup = true
+					}
+				}
+				if !dup {
+					byPort[key] = append(byPort[key], binding{svc.name, f.File, p.Line})
+				}
+			}
+		}
+	}
+
+	names := make([]string, 0, len(files))
+	for _, f := range files {
+		names = append(names, filepath.Base(f.File))
+	}
+	keys := make([]string, 0, len(byPort))
+	for k := range byPort {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var issues []Issue
+	for _, k := range keys {
+		bs := byPort[k]
+		if len(bs) < 2 {
+			continue
+		}
+		services := make([]string, len(bs))
+		for i, b := range bs {
+			services[i] = b.service
+		}
+		msg := fmt.Sprintf("host port %s is published by %s", k, strings.Join(services, ", "))
+		if len(files) > 1 {
+			msg += " (with " + strings.Join(names, " + ") + ")"
+		}
+		issues = append(issues, Issue{File: bs[1].file, Line: bs[1].line, Kind: KindPortConflict, Message: msg})
+	}
+	return issues
+}
+
+// hostPort returns the host side of a ports entry as [ip:]port[/proto], with
+// variables resolved to their defaults. ok is false for entries that publish
+// no fixed host port.
+func hostPort(n *yaml.Node) (string, bool) {
+	var ip, host, proto string
+	switch n.Kind {
+	case yaml.ScalarNode:
+		spec, ok := Interpolate(n.Value)
+		if !ok {
+			return "", false
+		}
+		spec, proto, _ = strings.Cut(spec, "/")
+		parts := strings.Split(spec, ":")
+		switch len(parts) {
+		case 2:
+			host = parts[0]
+		case 3:
+			ip, host = parts[0], parts[1]
+		default:
+			return "", false
+		}
+	case yaml.MappingNode:
+		if v := mapValue(n, "published"); v != nil {
+			host, _ = Interpolate(v.Value)
+		}
+		if v := mapValue(n, "host_ip"); v != nil {
+			ip, _ = Interpolate(v.Value)
+		}
+		if v := mapValue(n, "protocol"); v != nil {
+			proto = v.Value
+		}
+	default:
+		return "", false
+	}
+	if host == "" || strings.Contains(host, "-") {
+		return "", false
+	}
+	if ip == "0.0.0.0" {
+		ip = ""
+	}
+	if proto == "tcp" {
+		proto = ""
+	}
+	key := host
+	if ip != "" {
+		key = ip + ":" + key
+	}
+	if proto != "" {
+		key += "/" + proto
+	}
+	return key, true
+}
+
+type composeService struct {
+	name string
+	node *yaml.Node
+}
+
+func (c *ComposeFile) services() []composeService {
+	services := mapValue(c.root, "services")
+	if services == nil || services.Kind != yaml.MappingNode {
+		return nil
+	}
+	var out []composeService
+	for i := 0; i+1 < len(services.Content); i += 2 {
+		if services.Content[i+1].Kind == yaml.MappingNode {
+			out = append(out, composeService{services.Content[i].Value, services.Content[i+1]})
+		}
+	}
+	return out
+}
+
+// mapValue returns the value of key in a mapping node, or nil.
+func mapValue(n *yaml.Node, key string) *yaml.Node {
+	if n == nil || n.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(n.Content); i += 2 {
+		if n.Content[i].Value == key {
+			return n.Content[i+1]
+		}
+	}
+	return nil
+}
+
+// walkScalars calls fn for every scalar node under n.
+func walkScalars(n *yaml.Node, fn func(*yaml.Node)) {
+	if n == nil {
+		return
+	}
+	if n.Kind == yaml.ScalarNode {
+		fn(n)
+		return
+	}
+	for _, c := range n.Content {
+		walkScalars(c, fn)
+	}
+}
@@ -0,0 +1,103 @@
+package validate
+
+import (
+	"strings"
+	"testing"
+)
+
+const baseCompose = `
+services:
+  api_server:
+    image: ${ONYX_BACKEND_IMAGE:-onyxdotapp/onyx-backend:${IMAGE_TAG:-latest}}
+    environment:
+      - AUTH_TYPE=${AUTH_TYPE:-basic}
+      - USE_IAM_AUTH=${USE_IAM_AUTH}
+      - SECRET=${UNDOCUMENTED}
+      - PASSWORD=${MINIO_ROOT_PASSWORD:?set it}
+  web_server:
+    build:
+      context: ../../web
+    image: onyxdotapp/onyx-web-server:dev
+  nginx:
+    image: nginx:1.25.5-alpine
+    ulimits: !reset null
+    ports:
+      - "${HOST_PORT_80:-80}:80"
+      - "${HOST_PORT:-3000}:80"
+  # cache:
+  #   ports:
+  #     - "${COMMENTED}:6379"
+`
+
+func parseCompose(t *testing.T, file, data string) *ComposeFile {
+	t.Helper()
+	c, issues := ParseCompose(file, []byte(data))
+	if len(issues) > 0 {
+		t.Fatalf("ParseCompose(%s) issues: %+v", file, issues)
+	}
+	return c
+}
+
+func TestUndefinedVars(t *testing.T) {
+	c := parseCompose(t, "docker-compose.yml", baseCompose)
+	issues := c.UndefinedVars(map[string]bool{"USE_IAM_AUTH": true})
+	var names []string
+	for _, i := range issues {
+		names = append(names, i.Message)
+	}
+	got := strings.Join(names, "\n")
+	if len(issues) != 2 || !strings.Contains(got, "${UNDOCUMENTED}") || !strings.Contains(got, "${MINIO_ROOT_PASSWORD} is required") {
+		t.Errorf("UndefinedVars() = %+v", issues)
+	}
+	if strings.Contains(got, "COMMENTED") {
+		t.Error("commented-out references should be ignored")
+	}
+}
+
+func TestComposeImages(t *testing.T) {
+	c := parseCompose(t, "docker-compose.yml", baseCompose)
+	var refs []string
+	for _, img := range c.Images() {
+		refs = append(refs, img.Ref)
+	}
+	want := "onyxdotapp/onyx-backend:latest nginx:1.25.5-alpine"
+	if strings.Join(refs, " ") != want {
+		t.Errorf("Images() = %v, want %s (built images are skipped)", refs, want)
+	}
+}
+
+func TestPortConflicts(t *testing.T) {
+	base := parseCompose(t, "docker-compose.yml", baseCompose)
+	if issues := PortConflicts(base); len(issues) != 0 {
+		t.Errorf("expected no conflicts in the base file, got %+v", issues)
+	}
+
+	dev := parseCompose(t, "docker-compose.dev.yml", `
+services:
+  api_server:
+    ports:
+      - "8080:8080"
+  model_server:
+    ports:
+      - target: 9000
+        published: "${MODEL_PORT:-3000}"
+  minio:
+    ports:
+      - "127.0.0.1:3000:9000"
+      - "3000:9000/udp"
+      - "9000"
+`)
+	issues := PortConflicts(base, dev)
+	if len(issues) != 1 {
+		t.Fatalf("PortConflicts() = %+v, want one conflict", issues)
+	}
+	if msg := issues[0].Message; !strings.Contains(msg, "host port 3000 is published by nginx, model_server") || !strings.Contains(msg, "docker-compose.yml + docker-compose.dev.yml") {
+		t.Errorf("unexpected message %q", msg)
+	}
+}
+
+func TestParseComposeSyntaxError(t *testing.T) {
+	if _, issues := ParseCompose("bad.yml", []byte("services: [")); len(issues) != 1 || issues[0].Kind != KindSyntax {
+		t.Errorf("expected a syntax issue, got %+v", issues)
+	}
+}
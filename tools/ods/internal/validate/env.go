@@ -0,0 +1,77 @@
+package validate
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	envLine          = regexp.MustCompile(`^(?:export\s+)?([A-Za-z_][A-Za-z0-9_]*)=(.*)$`)
+	commentedEnvLine = regexp.MustCompile(`^#+\s*([A-Z_][A-Z0-9_]*)=`)
+)
+
+// EnvTemplate is an env template such as deployment/docker_compose/env.template.
+type EnvTemplate struct {
+	File string
+	// Keys maps every documented variable, set or commented out, to the line
+	// it first appears on.
+	Keys map[string]int
+}
+
+// ParseEnvTemplate reads an env template and reports malformed lines,
+// variables set more than once and values referencing variables the template
+// does not document.
+func ParseEnvTemplate(file string, data []byte) (*EnvTemplate, []Issue) {
+	t := &EnvTemplate{File: file, Keys: map[string]int{}}
+	set := map[string]int{}
+	type pending struct {
+		line int
+		key  string
+		refs []VarRef
+	}
+	var values []pending
+	var issues []Issue
+
+	for i, raw := range strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n") {
+		line := strings.TrimSpace(raw)
+		n := i + 1
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			if m := commentedEnvLine.FindStringSubmatch(line); m != nil {
+				if _, ok := t.Keys[m[1]]; !ok {
+					t.Keys[m[1]] = n
+				}
+			}
+			continue
+		}
+		m := envLine.FindStringSubmatch(line)
+		if m == nil {
+			issues = append(issues, Issue{File: file, Line: n, Kind: KindSyntax, Message: fmt.Sprintf("not KEY=VALUE: %q", line)})
+			continue
+		}
+		key := m[1]
+		if first, ok := set[key]; ok {
+			issues = append(issues, Issue{File: file, Line: n, Kind: KindDuplicate, Message: fmt.Sprintf("%s is already set on line %d", key, first)})
+		} else {
+			set[key] = n
+		}
+		if first, ok := t.Keys[key]; !ok || first > n {
+			t.Keys[key] = n
+		}
+		if !strings.HasPrefix(strings.TrimSpace(m[2]), "'") {
+			values = append(values, pending{line: n, key: key, refs: ParseVarRefs(m[2])})
+		}
+	}
+
+	for _, v := range values {
+		for _, ref := range v.refs {
+			if _, ok := t.Keys[ref.Name]; !ok && !ref.HasDefault {
+				issues = append(issues, Issue{File: file, Line: v.line, Kind: KindUndefined, Message: fmt.Sprintf("%s references ${%s}, which is not in this template", v.key, ref.Name)})
+			}
+		}
+	}
+	return t, issues
+}
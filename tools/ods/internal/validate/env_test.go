@@ -0,0 +1,39 @@
+package validate
+
+import "testing"
+
+func TestParseEnvTemplate(t *testing.T) {
+	data := []byte(`# Comment
+IMAGE_TAG=latest
+# AUTH_TYPE=basic
+WEB_DOMAIN=http://localhost:${HOST_PORT}
+API_URL=${SCHEME:-http}://api
+IMAGE_TAG=stable
+not a variable
+QUOTED='${LITERAL}'
+`)
+	tmpl, issues := ParseEnvTemplate("env.template", data)
+
+	for _, key := range []string{"IMAGE_TAG", "AUTH_TYPE", "WEB_DOMAIN", "API_URL", "QUOTED"} {
+		if _, ok := tmpl.Keys[key]; !ok {
+			t.Errorf("expected %s to be documented", key)
+		}
+	}
+	if tmpl.Keys["IMAGE_TAG"] != 2 {
+		t.Errorf("IMAGE_TAG first line = %d, want 2", tmpl.Keys["IMAGE_TAG"])
+	}
+
+	kinds := map[string]int{}
+	for _, i := range issues {
+		kinds[i.Kind]++
+	}
+	want := map[string]int{KindDuplicate: 1, KindSyntax: 1, KindUndefined: 1}
+	if len(kinds) != len(want) {
+		t.Fatalf("issues = %+v", issues)
+	}
+	for k, n := range want {
+		if kinds[k] != n {
+			t.Errorf("got %d %s issue(s), want %d: %+v", kinds[k], k, n, issues)
+		}
+	}
+}
@@ -0,0 +1,187 @@
+package validate
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+var (
+	valuesRef = regexp.MustCompile(`\$?\.Values((?:\.[A-Za-z_][A-Za-z0-9_]*)+)`)
+	action    = regexp.MustCompile(`{{-?(.*?)-?}}`)
+	// guard matches template functions and actions that tolerate a missing
+	// value, so references inside them are not reported.
+	guard = regexp.MustCompile(`\b(default|if|with|range|hasKey|include|and|or|not|empty|coalesce|required)\b`)
+)
+
+// HelmChart is a chart's metadata, default values and templates.
+type HelmChart struct {
+	ValuesFile string
+	AppVersion string
+	// Subcharts are the names (or aliases) of the chart's dependencies, whose
+	// values are defined by the subchart rather than this chart.
+	Subcharts map[string]bool
+	values    *yaml.Node
+	// freeform are .Values paths templates range over, whose keys are the
+	// user's to choose.
+	freeform map[string]bool
+}
+
+// ParseHelmChart parses Chart.yaml and the default values file.
+func ParseHelmChart(chartFile string, chart []byte, valuesFile string, values []byte) (*HelmChart, []Issue) {
+	var meta struct {
+		AppVersion   string `yaml:"appVersion"`
+		Dependencies []struct {
+			Name  string `yaml:"name"`
+			Alias string `yaml:"alias"`
+		} `yaml:"dependencies"`
+	}
+	if err := yaml.Unmarshal(chart, &meta); err != nil {
+		return nil, []Issue{{File: chartFile, Kind: KindSyntax, Message: err.Error()}}
+	}
+	root, issues := parseValues(valuesFile, values)
+	if root == nil {
+		return nil, issues
+	}
+	h := &HelmChart{ValuesFile: valuesFile, AppVersion: meta.AppVersion, Subcharts: map[string]bool{"global": true}, values: root, freeform: map[string]bool{}}
+	for _, d := range meta.Dependencies {
+		if d.Alias != "" {
+			h.Subcharts[d.Alias] = true
+		} else {
+			h.Subcharts[d.Name] = true
+		}
+	}
+	return h, nil
+}
+
+func parseValues(file string, data []byte) (*yaml.Node, []Issue) {
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, []Issue{{File: file, Kind: KindSyntax, Message: err.Error()}}
+	}
+	if doc.Kind != yaml.DocumentNode || len(doc.Content) == 0 {
+		return &yaml.Node{Kind: yaml.MappingNode}, nil
+	}
+	return doc.Content[0], nil
+}
+
+// CheckTemplate reports .Values paths a template renders that the default
+// values do not define. References guarded by default, if, with and the like
+// are skipped, as are paths into subchart values or below a value that is not
+// a map (e.g. an empty one). It also records the paths the template ranges
+// over, for UnknownOverrides.
+func (h *HelmChart) CheckTemplate(file string, template []byte) []Issue {
+	var issues []Issue
+	seen := map[string]bool{}
+	for i, line := range strings.Split(string(template), "\n") {
+		for _, a := range action.FindAllStringSubmatch(line, -1) {
+			body := a[1]
+			guarded := guard.MatchString(body)
+			for _, m := range valuesRef.FindAllStringSubmatch(body, -1) {
+				ref := strings.TrimPrefix(m[1], ".")
+				if strings.Contains(body, "range") {
+					h.freeform[ref] = true
+				}
+				path := strings.Split(ref, ".")
+				if guarded || h.Subcharts[path[0]] {
+					continue
+				}
+				missing := h.missing(path)
+				if missing == "" || seen[missing] {
+					continue
+				}
+				seen[missing] = true
+				issues = append(issues, Issue{File: file, Line: i + 1, Kind: KindUndefined, Message: fmt.Sprintf(".Values.%s is not defined in %s", missing, filepath.Base(h.ValuesFile))})
+			}
+		}
+	}
+	return issues
+}
+
+// missing returns the prefix of path that ends at the first key absent from
+// the default values, or "".
+func (h *HelmChart) missing(path []string) string {
+	n := h.values
+	for i, key := range path {
+		if n.Kind != yaml.MappingNode {
+			return ""
+		}
+		v := mapValue(n, key)
+		if v == nil {
+			return strings.Join(path[:i+1], ".")
+		}
+		n = v
+	}
+	return ""
+}
+
+// UnknownOverrides reports keys in an override values file (such as
+// values-lite.yaml) that the default values do not define, which helm would
+// silently ignore. Call it after CheckTemplate has seen every template, so
+// maps the templates range over are known to be free-form.
+func (h *HelmChart) UnknownOverrides(file string, data []byte) []Issue {
+	root, issues := parseValues(file, data)
+	if root == nil {
+		return issues
+	}
+	var walk func(override, base *yaml.Node, path []string)
+	walk = func(override, base *yaml.Node, path []string) {
+		if override.Kind != yaml.MappingNode || base == nil || base.Kind != yaml.MappingNode {
+			return
+		}
+		for i := 0; i+1 < len(override.Content); i += 2 {
+			key := override.Content[i]
+			p := append(append([]string{}, path...), key.Value)
+			if len(path) == 0 && h.Subcharts[key.Value] || h.freeform[strings.Join(path, ".")] {
+				continue
+			}
+			// An empty map in the defaults is a placeholder for free-form
+			// entries (e.g. annotations).
+			if len(base.Content) == 0 {
+				return
+			}
+			b := mapValue(base, key.Value)
+			if b == nil {
+				issues = append(issues, Issue{File: file, Line: key.Line, Kind: KindUndefined, Message: fmt.Sprintf("%s is not defined in %s", strings.Join(p, "."), filepath.Base(h.ValuesFile))})
+				continue
+			}
+			walk(override.Content[i+1], b, p)
+		}
+	}
+	walk(root, h.values, nil)
+	return issues
+}
+
+// Images returns the images configured in the default values: every map
+// with a repository, combined with its registry and tag (the chart's
+// appVersion when the tag is empty).
+func (h *HelmChart) Images() []Image {
+	var images []Image
+	var walk func(n *yaml.Node)
+	walk = func(n *yaml.Node) {
+		if n.Kind == yaml.MappingNode {
+			if repo := mapValue(n, "repository"); repo != nil && repo.Kind == yaml.ScalarNode && repo.Value != "" {
+				ref := repo.Value
+				if reg := mapValue(n, "registry"); reg != nil && reg.Value != "" {
+					ref = reg.Value + "/" + ref
+				}
+				tag := h.AppVersion
+				if t := mapValue(n, "tag"); t != nil && t.Value != "" {
+					tag = t.Value
+				}
+				if tag != "" {
+					ref += ":" + tag
+				}
+				images = append(images, Image{Ref: ref, File: h.ValuesFile, Line: repo.Line})
+			}
+		}
+		for _, c := range n.Content {
+			walk(c)
+		}
+	}
+	walk(h.values)
+	return images
+}
@@ -0,0 +1,101 @@
+package validate
+
+import (
+	"strings"
+	"testing"
+)
+
+const chartYAML = `
+appVersion: v1.2.3
+dependencies:
+  - name: cloudnative-pg
+    alias: postgresql
+`
+
+const valuesYAML = `
+api:
+  replicaCount: 1
+  image:
+    repository: onyxdotapp/onyx-backend
+    tag: ""
+  annotations: {}
+  resources:
+configMap:
+  AUTH_TYPE: basic
+opensearch:
+  image:
+    registry: docker.io
+    repository: opensearchproject/opensearch
+    tag: "3.4.0"
+`
+
+func parseChart(t *testing.T) *HelmChart {
+	t.Helper()
+	h, issues := ParseHelmChart("Chart.yaml", []byte(chartYAML), "values.yaml", []byte(valuesYAML))
+	if len(issues) > 0 {
+		t.Fatalf("ParseHelmChart() issues: %+v", issues)
+	}
+	return h
+}
+
+func TestCheckTemplate(t *testing.T) {
+	h := parseChart(t)
+	tmpl := []byte(`replicas: {{ .Values.api.replicaCount }}
+idle: {{ .Values.api.idleReplicaCount }}
+again: {{ $.Values.api.idleReplicaCount }}
+{{- if .Values.api.autoscaling }}
+port: {{ .Values.api.port | default 8080 }}
+pg: {{ .Values.postgresql.cluster.name }}
+limits: {{ .Values.api.resources.limits }}
+{{- range $k, $v := .Values.configMap }}
+`)
+	issues := h.CheckTemplate("api.yaml", tmpl)
+	if len(issues) != 1 || issues[0].Line != 2 || !strings.Contains(issues[0].Message, ".Values.api.idleReplicaCount") {
+		t.Errorf("CheckTemplate() = %+v, want one issue for idleReplicaCount on line 2", issues)
+	}
+}
+
+func TestUnknownOverrides(t *testing.T) {
+	h := parseChart(t)
+	h.CheckTemplate("configmap.yaml", []byte(`{{- range $key, $value := .Values.configMap }}`))
+
+	issues := h.UnknownOverrides("values-lite.yaml", []byte(`
+api:
+  replicaCount: 2
+  replicas: 3
+  annotations:
+    anything: goes
+configMap:
+  NEW_SETTING: "true"
+postgresql:
+  enabled: false
+webserver:
+  replicaCount: 1
+`))
+	var got []string
+	for _, i := range issues {
+		got = append(got, strings.SplitN(i.Message, " ", 2)[0])
+	}
+	if strings.Join(got, ",") != "api.replicas,webserver" {
+		t.Errorf("UnknownOverrides() = %+v", issues)
+	}
+}
+
+func TestHelmImages(t *testing.T) {
+	h := parseChart(t)
+	var refs []string
+	for _, img := range h.Images() {
+		refs = append(refs, img.Ref)
+	}
+	want := "onyxdotapp/onyx-backend:v1.2.3 docker.io/opensearchproject/opensearch:3.4.0"
+	if strings.Join(refs, " ") != want {
+		t.Errorf("Images() = %v, want %s", refs, want)
+	}
+}
+
+func TestUniqueImages(t *testing.T) {
+	got := UniqueImages([]Image{{Ref: "b", File: "1"}, {Ref: "a"}, {Ref: "b", File: "2"}})
+	if len(got) != 2 || got[0].Ref != "a" || got[1].File != "1" {
+		t.Errorf("UniqueImages() = %+v", got)
+	}
+}
@@ -0,0 +1,30 @@
+package validate
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/google/go-containerregistry/pkg/authn"
+	"github.com/google/go-containerregistry/pkg/name"
+	"github.com/google/go-containerregistry/pkg/v1/remote"
+	"github.com/google/go-containerregistry/pkg/v1/remote/transport"
+)
+
+// ImageExists reports whether ref resolves in its registry, using docker's
+// credentials when it has any. Only a definite "not found" returns false
+// without an error.
+func ImageExists(ref string) (bool, error) {
+	r, err := name.ParseReference(ref)
+	if err != nil {
+		return false, err
+	}
+	_, err = remote.Head(r, remote.WithAuthFromKeychain(authn.DefaultKeychain))
+	if err == nil {
+		return true, nil
+	}
+	var terr *transport.Error
+	if errors.As(err, &terr) && terr.StatusCode == http.StatusNotFound {
+		return false, nil
+	}
+	return false, err
+}
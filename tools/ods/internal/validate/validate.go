@@ -0,0 +1,70 @@
+// Package validate statically checks the repo's deployment configuration:
+// docker compose files, helm values and env templates.
+package validate
+
+import (
+	"fmt"
+	"sort"
+)
+
+// Issue kinds.
+const (
+	KindSyntax       = "syntax"
+	KindUndefined    = "undefined"
+	KindDuplicate    = "duplicate"
+	KindPortConflict = "port-conflict"
+	KindMissingImage = "missing-image"
+)
+
+// Issue is a problem found in a file.
+type Issue struct {
+	File    string
+	Line    int
+	Kind    string
+	Message string
+}
+
+func (i Issue) String() string {
+	loc := i.File
+	if i.Line > 0 {
+		loc = fmt.Sprintf("%s:%d", i.File, i.Line)
+	}
+	return fmt.Sprintf("%s: %s: %s", loc, i.Kind, i.Message)
+}
+
+// Sort orders issues by file, line and message.
+func Sort(issues []Issue) {
+	sort.Slice(issues, func(a, b int) bool {
+		x, y := issues[a], issues[b]
+		if x.File != y.File {
+			return x.File < y.File
+		}
+		if x.Line != y.Line {
+			return x.Line < y.Line
+		}
+		return x.Message < y.Message
+	})
+}
+
+// Image is a container image referenced by a file.
+type Image struct {
+	Ref  string
+	File string
+	Line int
+}
+
+// UniqueImages returns images with duplicate references removed, keeping the
+// first place each is referenced, sorted by reference.
+func UniqueImages(images []Image) []Image {
+	seen := map[string]bool{}
+	var out []Image
+	for _, img := range images {
+		if seen[img.Ref] {
+			continue
+		}
+		seen[img.Ref] = true
+		out = append(out, img)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].Ref < out[j].Ref })
+	return out
+}
@@ -0,0 +1,152 @@
+package validate
+
+import "strings"
+
+// VarRef is a variable reference in compose interpolation syntax.
+type VarRef struct {
+	Name string
+	// Default is set when the reference supplies a fallback (${X:-y},
+	// ${X-y}) or only uses the variable when set (${X:+y}, ${X+y}).
+	HasDefault bool
+	// Required is set for ${X:?msg} and ${X?msg}.
+	Required bool
+}
+
+// ParseVarRefs returns the variable references in s, including those nested
+// in defaults. $$ escapes a literal dollar sign.
+func ParseVarRefs(s string) []VarRef {
+	var refs []VarRef
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' || i+1 >= len(s) {
+			continue
+		}
+		switch next := s[i+1]; {
+		case next == '$':
+			i++
+		case next == '{':
+			end := matchingBrace(s, i+1)
+			if end < 0 {
+				return refs
+			}
+			refs = append(refs, parseBraced(s[i+2:end])...)
+			i = end
+		case isNameStart(next):
+			j := i + 1
+			for j < len(s) && isNameChar(s[j]) {
+				j++
+			}
+			refs = append(refs, VarRef{Name: s[i+1 : j]})
+			i = j - 1
+		}
+	}
+	return refs
+}
+
+// parseBraced parses the inside of ${...}.
+func parseBraced(body string) []VarRef {
+	j := 0
+	for j < len(body) && isNameChar(body[j]) {
+		j++
+	}
+	ref := VarRef{Name: body[:j]}
+	if ref.Name == "" {
+		return nil
+	}
+	rest := body[j:]
+	switch {
+	case strings.HasPrefix(rest, ":-"), strings.HasPrefix(rest, ":+"):
+		ref.HasDefault = true
+		rest = rest[2:]
+	case strings.HasPrefix(rest, "-"), strings.HasPrefix(rest, "+"):
+		ref.HasDefault = true
+		rest = rest[1:]
+	case strings.HasPrefix(rest, ":?"):
+		ref.Required = true
+		rest = ""
+	case strings.HasPrefix(rest, "?"):
+		ref.Required = true
+		rest = ""
+	}
+	return append([]VarRef{ref}, ParseVarRefs(rest)...)
+}
+
+// Interpolate resolves references using only their defaults, the way compose
+// would with none of the variables set. ok is false if any reference has no
+// default.
+func Interpolate(s string) (string, bool) {
+	var b strings.Builder
+	ok := true
+	for i := 0; i < len(s); i++ {
+		if s[i] != '$' || i+1 >= len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		switch next := s[i+1]; {
+		case next == '$':
+			b.WriteByte('$')
+			i++
+		case next == '{':
+			end := matchingBrace(s, i+1)
+			if end < 0 {
+				b.WriteString(s[i:])
+				return b.String(), false
+			}
+			body := s[i+2 : end]
+			j := 0
+			for j < len(body) && isNameChar(body[j]) {
+				j++
+			}
+			rest := body[j:]
+			switch {
+			case strings.HasPrefix(rest, ":-"):
+				v, vok := Interpolate(rest[2:])
+				b.WriteString(v)
+				ok = ok && vok
+			case strings.HasPrefix(rest, "-"):
+				v, vok := Interpolate(rest[1:])
+				b.WriteString(v)
+				ok = ok && vok
+			case strings.HasPrefix(rest, ":+"), strings.HasPrefix(rest, "+"):
+				// Unset variable: the alternative is not used.
+			default:
+				ok = false
+			}
+			i = end
+		case isNameStart(next):
+			j := i + 1
+			for j < len(s) && isNameChar(s[j]) {
+				j++
+			}
+			ok = false
+			i = j - 1
+		default:
+			b.WriteByte('$')
+		}
+	}
+	return b.String(), ok
+}
+
+// matchingBrace returns the index of the } closing the { at open.
+func matchingBrace(s string, open int) int {
+	depth := 0
+	for k := open; k < len(s); k++ {
+		switch s[k] {
+		case '{':
+			depth++
+		case '}':
+			depth--
+			if depth == 0 {
+				return k
+			}
+		}
+	}
+	return -1
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z'
+}
+
+func isNameChar(c byte) bool {
+	return isNameStart(c) || c >= '0' && c <= '9'
+}
@@ -0,0 +1,51 @@
+package validate
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseVarRefs(t *testing.T) {
+	tests := []struct {
+		in   string
+		want []VarRef
+	}{
+		{"plain", nil},
+		{"$$ESCAPED and $$", nil},
+		{"$HOME/x", []VarRef{{Name: "HOME"}}},
+		{"${A}", []VarRef{{Name: "A"}}},
+		{"${A:-x}", []VarRef{{Name: "A", HasDefault: true}}},
+		{"${A-x}", []VarRef{{Name: "A", HasDefault: true}}},
+		{"${A:+x}", []VarRef{{Name: "A", HasDefault: true}}},
+		{"${A:?set it}", []VarRef{{Name: "A", Required: true}}},
+		{"img:${TAG:-${IMAGE_TAG:-latest}}", []VarRef{{Name: "TAG", HasDefault: true}, {Name: "IMAGE_TAG", HasDefault: true}}},
+		{"${A:-${B}}", []VarRef{{Name: "A", HasDefault: true}, {Name: "B"}}},
+	}
+	for _, tt := range tests {
+		if got := ParseVarRefs(tt.in); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseVarRefs(%q) = %+v, want %+v", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestInterpolate(t *testing.T) {
+	tests := []struct {
+		in     string
+		want   string
+		wantOK bool
+	}{
+		{"onyxdotapp/onyx-backend:${IMAGE_TAG:-latest}", "onyxdotapp/onyx-backend:latest", true},
+		{"${IMG:-onyxdotapp/onyx-backend:${IMAGE_TAG:-latest}}", "onyxdotapp/onyx-backend:latest", true},
+		{"${HOST_PORT:-80}:80", "80:80", true},
+		{"x${A:+y}z", "xz", true},
+		{"$$literal", "$literal", true},
+		{"${A}", "", false},
+		{"$A:80", ":80", false},
+	}
+	for _, tt := range tests {
+		got, ok := Interpolate(tt.in)
+		if got != tt.want || ok != tt.wantOK {
+			t.Errorf("Interpolate(%q) = %q, %v; want %q, %v", tt.in, got, ok, tt.want, tt.wantOK)
+		}
+	}
+}
@@ -3,6 +3,8 @@
 package version
 
 import (
+	"fmt"
+	"strconv"
 	"strings"
 
 	"github.com/google/osv-scalibr/semantic"
@@ -32,3 +34,138 @@ func Compare(a, b string) int {
 	c, _ := semantic.ParseSemverVersion(Normalize(a)).CompareStr(Normalize(b))
 	return c
 }
+
+// Satisfies reports whether v matches an npm-style range constraint such as
+// ">=20.0.0", "^22.11", "20.x || 22.x" or a bare (possibly partial) version
+// like "22" as found in .nvmrc files. Floating aliases ("*", "lts/*", "node")
+// match anything.
+func Satisfies(v, constraint string) (bool, error) {
+	have, ok := parseVersionParts(Normalize(v))
+	if !ok || have.n != 3 {
+		return false, fmt.Errorf("invalid version %q", v)
+	}
+
+	constraint = strings.TrimSpace(constraint)
+	if constraint == "" || constraint == "*" || constraint == "node" || strings.HasPrefix(constraint, "lts/") {
+		return true, nil
+	}
+
+	for _, alt := range strings.Split(constraint, "||") {
+		matched := true
+		fields := strings.Fields(alt)
+		if len(fields) == 0 {
+			continue
+		}
+		for _, comparator := range fields {
+			m, err := matchComparator(have, comparator)
+			if err != nil {
+				return false, err
+			}
+			if !m {
+				matched = false
+				break
+			}
+		}
+		if matched {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// versionParts is a parsed major.minor.patch where n records how many
+// components were actually given (so "22" is distinguishable from "22.0.0").
+type versionParts struct {
+	v [3]int
+	n int
+}
+
+func parseVersionParts(s string) (versionParts, bool) {
+	var p versionParts
+	if i := strings.IndexAny(s, "-+"); i >= 0 {
+		s = s[:i]
+	}
+	for _, part := range strings.Split(s, ".") {
+		if part == "x" || part == "X" || part == "*" {
+			break
+		}
+		if p.n == 3 {
+			return p, false
+		}
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return p, false
+		}
+		p.v[p.n] = n
+		p.n++
+	}
+	return p, p.n > 0
+}
+
+func comparePartsTo(a, b versionParts) int {
+	for i := 0; i < 3; i++ {
+		switch {
+		case a.v[i] < b.v[i]:
+			return -1
+		case a.v[i] > b.v[i]:
+			return 1
+		}
+	}
+	return 0
+}
+
+// upperBound returns the exclusive upper bound of a partial version, bumping
+// the component at index i (e.g. 22.11 bumped at 0 -> 23.0.0).
+func upperBound(p versionParts, i int) versionParts {
+	out := versionParts{n: 3}
+	copy(out.v[:i], p.v[:i])
+	out.v[i] = p.v[i] + 1
+	return out
+}
+
+func matchComparator(have versionParts, comparator string) (bool, error) {
+	op := ""
+	for _, candidate := range []string{">=", "<=", ">", "<", "=", "^", "~"} {
+		if strings.HasPrefix(comparator, candidate) {
+			op = candidate
+			break
+		}
+	}
+	want, ok := parseVersionParts(Normalize(strings.TrimPrefix(comparator, op)))
+	if !ok {
+		return false, fmt.Errorf("invalid version constraint %q", comparator)
+	}
+
+	cmp := comparePartsTo(have, want)
+	switch op {
+	case ">=":
+		return cmp >= 0, nil
+	case ">":
+		if want.n < 3 {
+			return comparePartsTo(have, upperBound(want, want.n-1)) >= 0, nil
+		}
+		return cmp > 0, nil
+	case "<=":
+		if want.n < 3 {
+			return comparePartsTo(have, upperBound(want, want.n-1)) < 0, nil
+		}
+		return cmp <= 0, nil
+	case "<":
+		return cmp < 0, nil
+	case "^":
+		return cmp >= 0 && comparePartsTo(have, upperBound(want, 0)) < 0, nil
+	case "~":
+		idx := 1
+		if want.n == 1 {
+			idx = 0
+		}
+		return cmp >= 0 && comparePartsTo(have, upperBound(want, idx)) < 0, nil
+	default:
+		// Bare or "=" versions match exactly when fully specified and act as
+		// x-ranges when partial ("22" == "22.x").
+		if want.n == 3 {
+			return cmp == 0, nil
+		}
+		return cmp >= 0 && comparePartsTo(have, upperBound(want, want.n-1)) < 0, nil
+	}
+}
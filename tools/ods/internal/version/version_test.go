@@ -44,3 +44,48 @@ func TestCompare(t *testing.T) {
 		}
 	}
 }
+
+func TestSatisfies(t *testing.T) {
+	cases := []struct {
+		v, constraint string
+		want          bool
+	}{
+		{"v22.11.0", "22", true},
+		{"v22.11.0", "v22.11.0", true},
+		{"v22.11.1", "22.11.0", false},
+		{"v20.5.0", "22", false},
+		{"v22.11.0", "22.x", true},
+		{"v22.11.0", ">=20.0.0", true},
+		{"v18.0.0", ">=20.0.0", false},
+		{"v22.11.0", ">=20 <23", true},
+		{"v23.0.0", ">=20 <23", false},
+		{"v22.11.0", "^22.1.0", true},
+		{"v23.1.0", "^22.1.0", false},
+		{"v22.11.5", "~22.11.0", true},
+		{"v22.12.0", "~22.11.0", false},
+		{"v20.1.0", "18.x || 20.x", true},
+		{"v21.1.0", "18.x || 20.x", false},
+		{"v21.1.0", "lts/*", true},
+		{"v21.1.0", "", true},
+		{"v22.1.0", ">21", true},
+		{"v21.9.0", ">21", false},
+		{"v21.9.0", "<=21", true},
+	}
+	for _, c := range cases {
+		got, err := Satisfies(c.v, c.constraint)
+		if err != nil {
+			t.Errorf("Satisfies(%q, %q) unexpected error: %v", c.v, c.constraint, err)
+			continue
+		}
+		if got != c.want {
+			t.Errorf("Satisfies(%q, %q) = %v, want %v", c.v, c.constraint, got, c.want)
+		}
+	}
+
+	if _, err := Satisfies("v22.1.0", ">=abc"); err == nil {
+		t.Error("expected an error for an invalid constraint")
+	}
+	if _, err := Satisfies("not-a-version", "22"); err == nil {
+		t.Error("expected an error for an invalid version")
+	}
+}
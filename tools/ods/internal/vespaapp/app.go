@@ -0,0 +1,232 @@
+// Package vespaapp renders the Vespa application package from the repo's
+// templates, compares it with the package deployed in an environment, and
+// deploys it.
+package vespaapp
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// AppConfigPath is the repo-relative directory of the package templates.
+const AppConfigPath = "backend/onyx/document_index/vespa/app_config"
+
+const (
+	schemaTemplate    = "schemas/danswer_chunk.sd.jinja"
+	servicesTemplate  = "services.xml.jinja"
+	overridesTemplate = "validation-overrides.xml.jinja"
+
+	// OverridesFile is the package's validation-overrides.xml. Its allow
+	// dates move daily, so it is left out of diffs.
+	OverridesFile = "validation-overrides.xml"
+)
+
+// Package maps the files of an application package, e.g. "services.xml"
+// or "schemas/danswer_chunk_nomic_ai_nomic_embed_text_v1.sd", to their
+// contents.
+type Package map[string]string
+
+// Schemas returns the names of the package's schemas, sorted.
+func (p Package) Schemas() []string {
+	var names []string
+	for path := range p {
+		if name, ok := schemaName(path); ok {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+	return names
+}
+
+func schemaName(path string) (string, bool) {
+	if !strings.HasPrefix(path, "schemas/") || !strings.HasSuffix(path, ".sd") {
+		return "", false
+	}
+	return strings.TrimSuffix(strings.TrimPrefix(path, "schemas/"), ".sd"), true
+}
+
+// SchemaParams are the values the schema template is rendered with for one
+// index, as the backend's deploy_vespa_schemas passes them.
+type SchemaParams struct {
+	Name        string
+	Dim         string
+	Precision   string
+	MultiTenant bool
+	// NGrams adds trigram matching to title and content, as the backend
+	// does when a reindex with ngrams is pending.
+	NGrams bool
+}
+
+// RenderOptions are the values the package templates are rendered with.
+type RenderOptions struct {
+	Schemas       []SchemaParams
+	SearchThreads string
+	// OverridesUntil is the date the template's validation overrides
+	// expire, a week out in the backend.
+	OverridesUntil time.Time
+}
+
+var (
+	embeddingsField = regexp.MustCompile(`field embeddings type tensor<(\w+)>\(t\{\},\s*x\[(\d+)\]\)`)
+	perSearch       = regexp.MustCompile(`<persearch>\s*(\d+)\s*</persearch>`)
+)
+
+// InferOptions reads the render values back out of a deployed package, so
+// that rendering the repo's templates with them differs from it only where
+// the templates changed.
+func InferOptions(deployed Package) (RenderOptions, error) {
+	opts := RenderOptions{OverridesUntil: time.Now().AddDate(0, 0, 7)}
+	for _, name := range deployed.Schemas() {
+		src := deployed["schemas/"+name+".sd"]
+		m := embeddingsField.FindStringSubmatch(src)
+		if m == nil {
+			return RenderOptions{}, fmt.Errorf("schema %s has no embeddings field to read its dimension from", name)
+		}
+		opts.Schemas = append(opts.Schemas, SchemaParams{
+			Name:        name,
+			Precision:   m[1],
+			Dim:         m[2],
+			MultiTenant: strings.Contains(src, "field tenant_id "),
+			NGrams:      strings.Contains(src, "gram-size"),
+		})
+	}
+	if len(opts.Schemas) == 0 {
+		return RenderOptions{}, fmt.Errorf("the deployed package has no schemas")
+	}
+	if m := perSearch.FindStringSubmatch(deployed["services.xml"]); m != nil {
+		opts.SearchThreads = m[1]
+	} else {
+		return RenderOptions{}, fmt.Errorf("the deployed services.xml has no <persearch> thread count")
+	}
+	return opts, nil
+}
+
+// Render renders the package templates under dir (AppConfigPath in a
+// checkout) the way the backend does.
+func Render(dir string, opts RenderOptions) (Package, error) {
+	read := func(name string) (string, error) {
+		data, err := os.ReadFile(filepath.Join(dir, name))
+		return string(data), err
+	}
+	schemaTmpl, err := read(schemaTemplate)
+	if err != nil {
+		return nil, err
+	}
+	servicesTmpl, err := read(servicesTemplate)
+	if err != nil {
+		return nil, err
+	}
+	overridesTmpl, err := read(overridesTemplate)
+	if err != nil {
+		return nil, err
+	}
+
+	pkg := Package{}
+	var docLines []string
+	for _, s := range opts.Schemas {
+		schema, err := renderTemplate(schemaTmpl, map[string]string{
+			"schema_name":         s.Name,
+			"dim":                 s.Dim,
+			"embedding_precision": s.Precision,
+		}, map[string]bool{"multi_tenant": s.MultiTenant})
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", schemaTemplate, err)
+		}
+		if s.NGrams {
+			schema = addNGrams(schema)
+		}
+		pkg["schemas/"+s.Name+".sd"] = schema
+		docLines = append(docLines, fmt.Sprintf(`<document type="%s" mode="index" />`, s.Name))
+	}
+
+	if pkg["services.xml"], err = renderTemplate(servicesTmpl, map[string]string{
+		"document_elements":  strings.Join(docLines, "\n"),
+		"num_search_threads": opts.SearchThreads,
+	}, nil); err != nil {
+		return nil, fmt.Errorf("%s: %w", servicesTemplate, err)
+	}
+	if pkg[OverridesFile], err = renderTemplate(overridesTmpl, map[string]string{
+		"until_date": opts.OverridesUntil.Format(time.DateOnly),
+	}, nil); err != nil {
+		return nil, fmt.Errorf("%s: %w", overridesTemplate, err)
+	}
+	return pkg, nil
+}
+
+var templateTag = regexp.MustCompile(`\{\{\s*(\w+)\s*\}\}|\{%\s*(if|else|endif)\s*(\w*)\s*%\}`)
+
+// renderTemplate renders the Jinja subset the package templates use:
+// {{ var }} and {% if flag %}...{% else %}...{% endif %}.
+func renderTemplate(tmpl string, vars map[string]string, flags map[string]bool) (string, error) {
+	var b strings.Builder
+	// emitting holds, per open if, whether its current branch is output.
+	var emitting []bool
+	on := func() bool {
+		for _, e := range emitting {
+			if !e {
+				return false
+			}
+		}
+		return true
+	}
+	last := 0
+	for _, m := range templateTag.FindAllStringSubmatchIndex(tmpl, -1) {
+		if on() {
+			b.WriteString(tmpl[last:m[0]])
+		}
+		last = m[1]
+		if m[2] >= 0 {
+			name := tmpl[m[2]:m[3]]
+			v, ok := vars[name]
+			if !ok {
+				return "", fmt.Errorf("undefined variable %q", name)
+			}
+			if on() {
+				b.WriteString(v)
+			}
+			continue
+		}
+		switch tmpl[m[4]:m[5]] {
+		case "if":
+			name := tmpl[m[6]:m[7]]
+			if _, ok := flags[name]; !ok {
+				return "", fmt.Errorf("undefined flag %q", name)
+			}
+			emitting = append(emitting, flags[name])
+		case "else":
+			if len(emitting) == 0 {
+				return "", fmt.Errorf("else outside if")
+			}
+			emitting[len(emitting)-1] = !emitting[len(emitting)-1]
+		case "endif":
+			if len(emitting) == 0 {
+				return "", fmt.Errorf("endif outside if")
+			}
+			emitting = emitting[:len(emitting)-1]
+		}
+	}
+	if len(emitting) > 0 {
+		return "", fmt.Errorf("unclosed if")
+	}
+	b.WriteString(tmpl[last:])
+	// Like Jinja, drop a single trailing newline.
+	return strings.TrimSuffix(b.String(), "\n"), nil
+}
+
+var (
+	titleIndexing   = regexp.MustCompile(`(field title type string \{[^}]*indexing: summary \| index \| attribute)`)
+	contentIndexing = regexp.MustCompile(`(field content type string \{[^}]*indexing: summary \| index)`)
+)
+
+const ngramMatch = "${1}\n            match {\n                gram\n                gram-size: 3\n            }"
+
+// addNGrams mirrors the backend's _add_ngrams_to_schema.
+func addNGrams(schema string) string {
+	schema = titleIndexing.ReplaceAllString(schema, ngramMatch)
+	return contentIndexing.ReplaceAllString(schema, ngramMatch)
+}
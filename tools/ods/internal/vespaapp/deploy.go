@@ -0,0 +1,175 @@
+package vespaapp
+
+import (
+	"archive/zip"
+	"bytes"
+	_ "embed"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"html"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/docker"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/kube"
+)
+
+//go:embed vespa_app.py
+var appScript string
+
+// Runner runs a python script with the backend's environment and returns
+// its stdout.
+type Runner func(script string, args ...string) (string, error)
+
+// PodRunner runs scripts on pod, an api-server pod.
+func PodRunner(c *kube.Cluster, pod string) Runner {
+	return func(script string, args ...string) (string, error) {
+		return c.RunPython(pod, script, args...)
+	}
+}
+
+// ContainerRunner runs scripts in the local api_server container.
+func ContainerRunner(container string) Runner {
+	return func(script string, args ...string) (string, error) {
+		var stdout strings.Builder
+		err := docker.RunPython(container, script, nil, &stdout, args...)
+		return stdout.String(), err
+	}
+}
+
+// Fetch downloads the application package the config server has active.
+func Fetch(run Runner) (Package, error) {
+	var r struct {
+		Files Package `json:"files"`
+	}
+	if err := runScript(run, appScript, "fetch", &r); err != nil {
+		return nil, err
+	}
+	if len(r.Files) == 0 {
+		return nil, fmt.Errorf("the config server has no application package deployed")
+	}
+	return r.Files, nil
+}
+
+// DeployResult is the config server's answer to a deploy.
+type DeployResult struct {
+	Code int    `json:"code"`
+	Body string `json:"body"`
+}
+
+// OK reports whether the package was prepared and activated.
+func (r DeployResult) OK() bool {
+	return r.Code == 200
+}
+
+// Message is the config server's message, from its JSON body if it has
+// one.
+func (r DeployResult) Message() string {
+	var body struct {
+		Message string `json:"message"`
+	}
+	if json.Unmarshal([]byte(r.Body), &body) == nil && body.Message != "" {
+		return body.Message
+	}
+	return strings.TrimSpace(r.Body)
+}
+
+// Deploy prepares and activates pkg.
+func Deploy(run Runner, pkg Package) (DeployResult, error) {
+	data, err := Zip(pkg)
+	if err != nil {
+		return DeployResult{}, err
+	}
+	script := strings.Replace(appScript, `PACKAGE_B64 = ""`, fmt.Sprintf("PACKAGE_B64 = %q", base64.StdEncoding.EncodeToString(data)), 1)
+	var r DeployResult
+	err = runScript(run, script, "deploy", &r)
+	return r, err
+}
+
+func runScript(run Runner, script, action string, result any) error {
+	stdout, err := run(script, action)
+	if err != nil {
+		return err
+	}
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	last := strings.TrimSpace(lines[len(lines)-1])
+	var status struct {
+		Status  string `json:"status"`
+		Message string `json:"message"`
+	}
+	if err := json.Unmarshal([]byte(last), &status); err != nil {
+		return fmt.Errorf("unexpected output from vespa script: %q", last)
+	}
+	if status.Status != "success" {
+		return fmt.Errorf("%s", status.Message)
+	}
+	return json.Unmarshal([]byte(last), result)
+}
+
+// Zip packs pkg as the config server expects it.
+func Zip(pkg Package) ([]byte, error) {
+	paths := make([]string, 0, len(pkg))
+	for path := range pkg {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, path := range paths {
+		w, err := zw.Create(path)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := w.Write([]byte(pkg[path])); err != nil {
+			return nil, err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// requiredOverride matches the fix Vespa suggests for a change that needs
+// a validation override, e.g. "To allow this add <allow
+// until='yyyy-mm-dd'>content-type-removal</allow> to validation-overrides.xml".
+var requiredOverride = regexp.MustCompile(`<allow until='[^']*'>([\w-]+)</allow>`)
+
+// RequiredOverrides returns the validation override IDs a failed deploy's
+// message asks for, in order and without repeats.
+func RequiredOverrides(message string) []string {
+	var ids []string
+	for _, m := range requiredOverride.FindAllStringSubmatch(message, -1) {
+		if !slices.Contains(ids, m[1]) {
+			ids = append(ids, m[1])
+		}
+	}
+	return ids
+}
+
+// WithOverrides returns pkg with validation-overrides.xml allowing ids
+// until the given date. Vespa rejects dates more than 30 days out.
+func WithOverrides(pkg Package, ids []string, until time.Time, comment string) Package {
+	var allows strings.Builder
+	for _, id := range ids {
+		fmt.Fprintf(&allows, "    <allow\n        until=%q\n        comment=%q>%s</allow>\n",
+			until.Format(time.DateOnly), html.EscapeString(comment), id)
+	}
+
+	out := Package{}
+	for path, content := range pkg {
+		out[path] = content
+	}
+	overrides, ok := out[OverridesFile]
+	if i := strings.LastIndex(overrides, "</validation-overrides>"); ok && i >= 0 {
+		out[OverridesFile] = overrides[:i] + allows.String() + overrides[i:]
+	} else {
+		out[OverridesFile] = "<validation-overrides>\n" + allows.String() + "</validation-overrides>"
+	}
+	return out
+}
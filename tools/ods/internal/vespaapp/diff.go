@@ -0,0 +1,211 @@
+package vespaapp
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Change kinds besides the schema block kinds ("field", "rank-profile",
+// "fieldset", "document-summary").
+const (
+	// KindFile is a whole file: one only in one package, or a non-schema
+	// file that differs.
+	KindFile = "file"
+	// KindSchema is a schema's definitions outside any block, such as its
+	// document and inheritance lines.
+	KindSchema = "schema"
+)
+
+// Change is one difference between the deployed package and the repo's.
+type Change struct {
+	File string `json:"file"`
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+	// InDeployed and InRepo say which packages have it; both for a
+	// changed definition.
+	InDeployed bool `json:"in_deployed"`
+	InRepo     bool `json:"in_repo"`
+	// Lines is the line diff of a changed definition: "- " lines are
+	// only deployed, "+ " lines only in the repo.
+	Lines []string `json:"lines,omitempty"`
+}
+
+// Block is a named definition in a schema.
+type Block struct {
+	Kind string
+	Name string
+	Body string
+}
+
+var blockHead = regexp.MustCompile(`(?m)^[ \t]*(field|rank-profile|fieldset|document-summary)[ \t]+([\w.-]+)[^{\n]*\{`)
+
+// ParseSchema splits a schema into its field, rank-profile, fieldset and
+// document-summary blocks, and what is left outside them.
+func ParseSchema(src string) (blocks []Block, rest string) {
+	var b strings.Builder
+	pos := 0
+	for pos < len(src) {
+		loc := blockHead.FindStringSubmatchIndex(src[pos:])
+		if loc == nil {
+			break
+		}
+		start, open := pos+loc[0], pos+loc[1]-1
+		end := matchBrace(src, open)
+		b.WriteString(src[pos:start])
+		blocks = append(blocks, Block{
+			Kind: src[pos+loc[2] : pos+loc[3]],
+			Name: src[pos+loc[4] : pos+loc[5]],
+			Body: src[start:end],
+		})
+		pos = end
+	}
+	b.WriteString(src[pos:])
+	return blocks, b.String()
+}
+
+// matchBrace returns the offset just past the brace closing the one at
+// open, ignoring braces in # comments, or len(src) if it is unclosed.
+func matchBrace(src string, open int) int {
+	depth := 0
+	comment := false
+	for i := open; i < len(src); i++ {
+		switch c := src[i]; {
+		case comment:
+			comment = c != '\n'
+		case c == '#':
+			comment = true
+		case c == '{':
+			depth++
+		case c == '}':
+			depth--
+			if depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return len(src)
+}
+
+// Diff compares the deployed package with the repo's, schema blocks by
+// block and other files whole. validation-overrides.xml is skipped.
+// Comments, indentation and blank lines are not differences.
+func Diff(deployed, repo Package) []Change {
+	var paths []string
+	for path := range deployed {
+		paths = append(paths, path)
+	}
+	for path := range repo {
+		if _, ok := deployed[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	var changes []Change
+	for _, path := range paths {
+		if path == OverridesFile {
+			continue
+		}
+		d, inDeployed := deployed[path]
+		r, inRepo := repo[path]
+		switch {
+		case !inDeployed || !inRepo:
+			changes = append(changes, Change{File: path, Kind: KindFile, Name: path, InDeployed: inDeployed, InRepo: inRepo})
+		case strings.HasSuffix(path, ".sd"):
+			changes = append(changes, diffSchema(path, d, r)...)
+		default:
+			if lines := lineDiff(normalize(d), normalize(r)); lines != nil {
+				changes = append(changes, Change{File: path, Kind: KindFile, Name: path, InDeployed: true, InRepo: true, Lines: lines})
+			}
+		}
+	}
+	return changes
+}
+
+func diffSchema(path, deployed, repo string) []Change {
+	type key struct{ kind, name string }
+	dBlocks, dRest := ParseSchema(deployed)
+	rBlocks, rRest := ParseSchema(repo)
+	dByKey := map[key]string{}
+	for _, b := range dBlocks {
+		dByKey[key{b.Kind, b.Name}] = b.Body
+	}
+	rByKey := map[key]string{}
+	for _, b := range rBlocks {
+		rByKey[key{b.Kind, b.Name}] = b.Body
+	}
+
+	var changes []Change
+	if lines := lineDiff(normalize(dRest), normalize(rRest)); lines != nil {
+		name, _ := schemaName(path)
+		changes = append(changes, Change{File: path, Kind: KindSchema, Name: name, InDeployed: true, InRepo: true, Lines: lines})
+	}
+	// Deployed blocks in their order, then blocks new in the repo in theirs.
+	for _, b := range dBlocks {
+		k := key{b.Kind, b.Name}
+		r, ok := rByKey[k]
+		if !ok {
+			changes = append(changes, Change{File: path, Kind: b.Kind, Name: b.Name, InDeployed: true})
+			continue
+		}
+		if lines := lineDiff(normalize(b.Body), normalize(r)); lines != nil {
+			changes = append(changes, Change{File: path, Kind: b.Kind, Name: b.Name, InDeployed: true, InRepo: true, Lines: lines})
+		}
+	}
+	for _, b := range rBlocks {
+		if _, ok := dByKey[key{b.Kind, b.Name}]; !ok {
+			changes = append(changes, Change{File: path, Kind: b.Kind, Name: b.Name, InRepo: true})
+		}
+	}
+	return changes
+}
+
+// normalize returns src's lines trimmed, without blank lines and comments.
+func normalize(src string) []string {
+	var out []string
+	for _, line := range strings.Split(src, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "<!--") {
+			continue
+		}
+		out = append(out, line)
+	}
+	return out
+}
+
+// lineDiff returns the lines removed from a ("- ") and added in b ("+ ")
+// along a longest common subsequence, or nil if they are equal.
+func lineDiff(a, b []string) []string {
+	// lcs[i][j] is the LCS length of a[i:] and b[j:].
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+
+	var out []string
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		case i < len(a) && j < len(b) && a[i] == b[j]:
+			i++
+			j++
+		case j == len(b) || (i < len(a) && lcs[i+1][j] >= lcs[i][j+1]):
+			out = append(out, "- "+a[i])
+			i++
+		default:
+			out = append(out, "+ "+b[j])
+			j++
+		}
+	}
+	return out
+}
@@ -0,0 +1,90 @@
+"""Fetch or deploy the Vespa application package.
+
+Bundled with ods and piped into `python -` on an api-server pod (or the
+local api_server container) by `ods diff-schema-vespa`, so the config server
+is reached with the backend's own configuration and network view.
+
+Usage:
+    python - fetch
+    python - deploy
+
+For deploy, ods fills in PACKAGE_B64 below with the zipped package.
+
+Progress goes to stderr; the last line on stdout is a JSON object with
+"status" and, for fetch, "files" (package path to contents) or, for deploy,
+"code" and "body" of the config server's response.
+"""
+
+from __future__ import annotations
+
+import base64
+import json
+import sys
+from typing import Any
+
+PACKAGE_B64 = ""
+
+# Self-hosted Vespa has a single application in the prod environment.
+CONTENT_PATH = (
+    "/tenant/default/application/default/environment/prod"
+    "/region/default/instance/default/content/"
+)
+
+
+def fetch() -> dict[str, Any]:
+    import requests
+
+    from onyx.document_index.vespa_constants import VESPA_APPLICATION_ENDPOINT
+
+    base = f"{VESPA_APPLICATION_ENDPOINT}{CONTENT_PATH}"
+    resp = requests.get(base, params={"recursive": "true"}, timeout=30)
+    resp.raise_for_status()
+
+    files = {}
+    for url in resp.json():
+        # Listed URLs carry the config server's own hostname, which need not
+        # resolve from here, so only their path is used.
+        path = url.split(CONTENT_PATH, 1)[-1]
+        if not path or path.endswith("/"):
+            continue
+        file_resp = requests.get(base + path, timeout=30)
+        file_resp.raise_for_status()
+        files[path] = file_resp.text
+    print(f"Fetched {len(files)} file(s) from {base}", file=sys.stderr)
+    return {"status": "success", "files": files}
+
+
+def deploy() -> dict[str, Any]:
+    import requests
+
+    from onyx.document_index.vespa_constants import VESPA_APPLICATION_ENDPOINT
+
+    if not PACKAGE_B64:
+        return {"status": "error", "message": "no package to deploy"}
+    url = f"{VESPA_APPLICATION_ENDPOINT}/tenant/default/prepareandactivate"
+    print(f"Deploying to {url}", file=sys.stderr)
+    resp = requests.post(
+        url,
+        headers={"Content-Type": "application/zip"},
+        data=base64.b64decode(PACKAGE_B64),
+        timeout=300,
+    )
+    return {"status": "success", "code": resp.status_code, "body": resp.text}
+
+
+def main() -> None:
+    actions = {"fetch": fetch, "deploy": deploy}
+    if len(sys.argv) != 2 or sys.argv[1] not in actions:
+        print(json.dumps({"status": "error", "message": "Usage: python - fetch|deploy"}))
+        sys.exit(1)
+
+    try:
+        result = actions[sys.argv[1]]()
+    except Exception as e:
+        print(f"Error: {e}", file=sys.stderr)
+        result = {"status": "error", "message": str(e)}
+    print(json.dumps(result))
+
+
+if __name__ == "__main__":
+    main()
@@ -0,0 +1,203 @@
+package vespaapp
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderTemplate(t *testing.T) {
+	tmpl := "schema {{ name }} {\n{% if mt %}  tenant\n{% else %}  single\n{% endif %}}\n"
+	got, err := renderTemplate(tmpl, map[string]string{"name": "x"}, map[string]bool{"mt": true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "schema x {\n  tenant\n}"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+	got, _ = renderTemplate(tmpl, map[string]string{"name": "x"}, map[string]bool{"mt": false})
+	if want := "schema x {\n  single\n}"; got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	for _, bad := range []string{"{{ missing }}", "{% if missing %}{% endif %}", "{% if mt %}", "{% endif %}"} {
+		if _, err := renderTemplate(bad, nil, map[string]bool{"mt": true}); err == nil {
+			t.Errorf("%q: expected an error", bad)
+		}
+	}
+}
+
+func TestRenderRepoTemplatesRoundTrip(t *testing.T) {
+	dir := filepath.Join("..", "..", "..", "..", AppConfigPath)
+	if _, err := os.Stat(dir); err != nil {
+		t.Skipf("templates not found: %v", err)
+	}
+	opts := RenderOptions{
+		Schemas: []SchemaParams{
+			{Name: "danswer_chunk_a", Dim: "768", Precision: "float", MultiTenant: true},
+			{Name: "danswer_chunk_b", Dim: "1024", Precision: "bfloat16", NGrams: true},
+		},
+		SearchThreads:  "4",
+		OverridesUntil: time.Date(2026, 1, 8, 0, 0, 0, 0, time.UTC),
+	}
+	pkg, err := Render(dir, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(pkg[OverridesFile], `until="2026-01-08"`) {
+		t.Errorf("overrides not rendered with the date:\n%s", pkg[OverridesFile])
+	}
+	if !strings.Contains(pkg["services.xml"], `<document type="danswer_chunk_b" mode="index" />`) {
+		t.Errorf("services.xml lacks the documents:\n%s", pkg["services.xml"])
+	}
+
+	inferred, err := InferOptions(pkg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(inferred.Schemas, opts.Schemas) || inferred.SearchThreads != opts.SearchThreads {
+		t.Errorf("inferred %+v, want %+v", inferred, opts)
+	}
+	again, err := Render(dir, inferred)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if changes := Diff(pkg, again); len(changes) != 0 {
+		t.Errorf("unexpected differences: %+v", changes)
+	}
+}
+
+const deployedSchema = `schema danswer_chunk_a {
+    document danswer_chunk_a {
+        field title type string {
+            indexing: summary | index
+        }
+        # old comment
+        field gone type int {
+            indexing: attribute
+        }
+    }
+    rank-profile keyword inherits default {
+        first-phase {
+            expression: bm25(content)
+        }
+    }
+}`
+
+const repoSchema = `schema danswer_chunk_a {
+    document danswer_chunk_a {
+        field title type string {
+            # { comments with braces are ignored }
+            indexing: summary | index | attribute
+        }
+    }
+    field added type string {
+        indexing: input title | attribute
+    }
+    rank-profile keyword inherits default {
+
+        first-phase {
+            expression: bm25(content)
+        }
+    }
+}`
+
+func TestParseSchema(t *testing.T) {
+	blocks, rest := ParseSchema(repoSchema)
+	var names []string
+	for _, b := range blocks {
+		names = append(names, b.Kind+" "+b.Name)
+	}
+	want := []string{"field title", "field added", "rank-profile keyword"}
+	if !reflect.DeepEqual(names, want) {
+		t.Errorf("got %v, want %v", names, want)
+	}
+	if !strings.HasSuffix(blocks[0].Body, "attribute\n        }") {
+		t.Errorf("title block ends wrong: %q", blocks[0].Body)
+	}
+	if strings.Contains(rest, "field") || !strings.Contains(rest, "document danswer_chunk_a {") {
+		t.Errorf("unexpected rest: %q", rest)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	deployed := Package{
+		"schemas/danswer_chunk_a.sd": deployedSchema,
+		"services.xml":               "<services>\n  <a/>\n</services>",
+		OverridesFile:                "<validation-overrides>old</validation-overrides>",
+		"hosts.xml":                  "<hosts/>",
+	}
+	repo := Package{
+		"schemas/danswer_chunk_a.sd": repoSchema,
+		"services.xml":               "<services>\n    <a/>\n    <!-- note -->\n</services>\n",
+		OverridesFile:                "<validation-overrides>new</validation-overrides>",
+	}
+	changes := Diff(deployed, repo)
+
+	var got []string
+	for _, c := range changes {
+		got = append(got, c.File+" "+c.Kind+" "+c.Name)
+	}
+	want := []string{
+		"hosts.xml file hosts.xml",
+		"schemas/danswer_chunk_a.sd field title",
+		"schemas/danswer_chunk_a.sd field gone",
+		"schemas/danswer_chunk_a.sd field added",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("got %v, want %v", got, want)
+	}
+	if c := changes[0]; !c.InDeployed || c.InRepo {
+		t.Errorf("hosts.xml should be only deployed: %+v", c)
+	}
+	if c := changes[1]; !reflect.DeepEqual(c.Lines, []string{"- indexing: summary | index", "+ indexing: summary | index | attribute"}) {
+		t.Errorf("title lines: %q", c.Lines)
+	}
+	if c := changes[3]; c.InDeployed || !c.InRepo {
+		t.Errorf("added should be only in the repo: %+v", c)
+	}
+}
+
+func TestLineDiff(t *testing.T) {
+	if got := lineDiff([]string{"a", "b"}, []string{"a", "b"}); got != nil {
+		t.Errorf("equal: got %q", got)
+	}
+	got := lineDiff([]string{"a", "b", "c"}, []string{"a", "x", "c", "d"})
+	want := []string{"- b", "+ x", "+ d"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %q, want %q", got, want)
+	}
+}
+
+func TestRequiredOverrides(t *testing.T) {
+	msg := `Invalid application: indexing-change: Document type 'a': Field 'title' changed: add attribute aspect. ` +
+		`To allow this add <allow until='yyyy-mm-dd'>indexing-change</allow> to validation-overrides.xml; ` +
+		`field-type-change: Field 'x' changed. To allow this add <allow until='yyyy-mm-dd'>field-type-change</allow> ` +
+		`to validation-overrides.xml. Also <allow until='yyyy-mm-dd'>indexing-change</allow>`
+	got := RequiredOverrides(msg)
+	if want := []string{"indexing-change", "field-type-change"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+	if got := RequiredOverrides("Invalid application: syntax error"); got != nil {
+		t.Errorf("got %v for a message without overrides", got)
+	}
+}
+
+func TestWithOverrides(t *testing.T) {
+	pkg := Package{OverridesFile: "<validation-overrides>\n    <allow until=\"2026-01-08\">schema-removal</allow>\n</validation-overrides>"}
+	out := WithOverrides(pkg, []string{"content-type-removal"}, time.Date(2026, 1, 2, 0, 0, 0, 0, time.UTC), "a & b")
+
+	got := out[OverridesFile]
+	if !strings.Contains(got, "schema-removal") || !strings.HasSuffix(got, "</validation-overrides>") {
+		t.Errorf("existing overrides lost:\n%s", got)
+	}
+	if !strings.Contains(got, `until="2026-01-02"`) || !strings.Contains(got, `comment="a &amp; b">content-type-removal</allow>`) {
+		t.Errorf("override not added:\n%s", got)
+	}
+	if strings.Contains(pkg[OverridesFile], "content-type-removal") {
+		t.Error("the original package was modified")
+	}
+}
@@ -0,0 +1,175 @@
+// Package warm replays a tenant's most frequent recent queries against an
+// API server, so that caches are warm and errors show up before users hit
+// them after a deploy or reindex.
+package warm
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/api"
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/eval"
+)
+
+// maxQueryChars skips long messages, which are pastes rather than queries
+// and unlikely to repeat.
+const maxQueryChars = 500
+
+// Query is a distinct user message from the query history.
+type Query struct {
+	Text  string
+	Count int
+}
+
+// TopQueriesSQL returns the limit most frequent user chat messages in
+// schema since since, whitespace-normalized and compared case-insensitively.
+func TopQueriesSQL(schema string, since time.Time, limit int) string {
+	return fmt.Sprintf(`SELECT min(q), count(*) FROM (
+  SELECT regexp_replace(trim(message), '\s+', ' ', 'g') AS q
+  FROM "%[1]s".chat_message
+  WHERE message_type = 'USER' AND time_sent >= '%[2]s' AND length(message) BETWEEN 3 AND %[3]d
+) m
+GROUP BY lower(q) ORDER BY count(*) DESC, max(q) LIMIT %[4]d`, schema, since.UTC().Format(time.RFC3339), maxQueryChars, limit)
+}
+
+// ParseQueries reads the output of TopQueriesSQL.
+func ParseQueries(lines []string) ([]Query, error) {
+	queries := make([]Query, 0, len(lines))
+	for _, line := range lines {
+		i := strings.LastIndex(line, "\t")
+		if i < 0 {
+			return nil, fmt.Errorf("unexpected query history row: %q", line)
+		}
+		n, err := strconv.Atoi(line[i+1:])
+		if err != nil {
+			return nil, fmt.Errorf("unexpected query history row: %q", line)
+		}
+		queries = append(queries, Query{Text: line[:i], Count: n})
+	}
+	return queries, nil
+}
+
+// Options configures a replay.
+type Options struct {
+	// Chat also asks each query in chat, which warms the LLM path at the
+	// cost of tokens.
+	Chat     bool
+	Persona  int
+	Parallel int
+	// OnResult, if set, is called as each query finishes.
+	OnResult func(Result)
+}
+
+// Result is the outcome of replaying one query.
+type Result struct {
+	Index    int
+	Query    Query
+	Docs     int
+	SearchMS int64
+	ChatMS   int64
+	Error    string
+}
+
+// Run replays queries through client's search (and chat).
+func Run(client *api.Client, queries []Query, opts Options) []Result {
+	if opts.Parallel < 1 {
+		opts.Parallel = 1
+	}
+	results := make([]Result, len(queries))
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, opts.Parallel)
+	for i, q := range queries {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer func() { <-sem; wg.Done() }()
+			res := replay(client, q, opts)
+			res.Index = i + 1
+			results[i] = res
+			if opts.OnResult != nil {
+				mu.Lock()
+				opts.OnResult(res)
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+func replay(client *api.Client, q Query, opts Options) Result {
+	res := Result{Query: q}
+	var problems []string
+	start := time.Now()
+	docs, err := eval.Search(client, q.Text)
+	res.SearchMS = time.Since(start).Milliseconds()
+	res.Docs = len(docs)
+	if err != nil {
+		problems = append(problems, "search: "+err.Error())
+	}
+	if opts.Chat {
+		start := time.Now()
+		_, err := eval.Chat(client, q.Text, opts.Persona)
+		res.ChatMS = time.Since(start).Milliseconds()
+		if err != nil {
+			problems = append(problems, "chat: "+err.Error())
+		}
+	}
+	res.Error = strings.Join(problems, "; ")
+	return res
+}
+
+// Summary aggregates a replay.
+type Summary struct {
+	Queries int
+	Failed  int
+	// Empty counts searches that succeeded without results, which after a
+	// reindex often means missing documents.
+	Empty                int
+	SearchP50, SearchP95 int64
+	ChatP50, ChatP95     int64
+	// Slowest are up to three results with the slowest searches.
+	Slowest []Result
+}
+
+// Summarize aggregates results.
+func Summarize(results []Result) Summary {
+	s := Summary{Queries: len(results)}
+	var search, chat []int64
+	for _, r := range results {
+		switch {
+		case r.Error != "":
+			s.Failed++
+		case r.Docs == 0:
+			s.Empty++
+		}
+		search = append(search, r.SearchMS)
+		if r.ChatMS > 0 {
+			chat = append(chat, r.ChatMS)
+		}
+	}
+	s.SearchP50, s.SearchP95 = percentiles(search)
+	s.ChatP50, s.ChatP95 = percentiles(chat)
+
+	slowest := slices.Clone(results)
+	slices.SortStableFunc(slowest, func(a, b Result) int { return int(b.SearchMS - a.SearchMS) })
+	s.Slowest = slowest[:min(3, len(slowest))]
+	return s
+}
+
+func percentiles(ms []int64) (p50, p95 int64) {
+	if len(ms) == 0 {
+		return 0, 0
+	}
+	slices.Sort(ms)
+	at := func(p float64) int64 {
+		return ms[min(len(ms)-1, int(p*float64(len(ms))))]
+	}
+	return at(0.5), at(0.95)
+}
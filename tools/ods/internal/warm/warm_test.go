@@ -0,0 +1,47 @@
+package warm
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTopQueriesSQL(t *testing.T) {
+	sql := TopQueriesSQL("tenant_a", time.Date(2026, 10, 8, 0, 0, 0, 0, time.UTC), 50)
+	for _, want := range []string{`FROM "tenant_a".chat_message`, "time_sent >= '2026-10-08T00:00:00Z'", "GROUP BY lower(q)", "LIMIT 50"} {
+		if !strings.Contains(sql, want) {
+			t.Errorf("TopQueriesSQL() missing %q:\n%s", want, sql)
+		}
+	}
+}
+
+func TestParseQueries(t *testing.T) {
+	queries, err := ParseQueries([]string{"how do I reset my password\t12", "vpn\tsetup\t3"})
+	if err != nil {
+		t.Fatalf("ParseQueries() error: %v", err)
+	}
+	if len(queries) != 2 || queries[0].Count != 12 || queries[1].Text != "vpn\tsetup" {
+		t.Errorf("unexpected queries %+v", queries)
+	}
+	if _, err := ParseQueries([]string{"no count"}); err == nil {
+		t.Error("expected an error for a row without a count")
+	}
+}
+
+func TestSummarize(t *testing.T) {
+	s := Summarize([]Result{
+		{Index: 1, Docs: 5, SearchMS: 100},
+		{Index: 2, Docs: 0, SearchMS: 900},
+		{Index: 3, SearchMS: 50, Error: "search: 502"},
+		{Index: 4, Docs: 3, SearchMS: 200},
+	})
+	if s.Queries != 4 || s.Failed != 1 || s.Empty != 1 {
+		t.Errorf("unexpected summary %+v", s)
+	}
+	if s.SearchP50 != 200 || s.SearchP95 != 900 || s.ChatP50 != 0 {
+		t.Errorf("unexpected latencies p50=%d p95=%d", s.SearchP50, s.SearchP95)
+	}
+	if len(s.Slowest) != 3 || s.Slowest[0].Index != 2 || s.Slowest[1].Index != 4 {
+		t.Errorf("unexpected slowest %+v", s.Slowest)
+	}
+}
@@ -0,0 +1,110 @@
+// Package watch re-runs a command's output on an interval and redraws it in
+// place, highlighting what changed, as watch(1) -d does for shell commands.
+package watch
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	log "github.com/sirupsen/logrus"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/render"
+)
+
+const (
+	clearScreen = "\x1b[H\x1b[2J"
+	hideCursor  = "\x1b[?25l"
+	showCursor  = "\x1b[?25h"
+)
+
+// Run calls frame every interval until interrupted, showing what it writes
+// to stdout under a title line. On a terminal each frame replaces the last
+// and lines that differ from the previous frame are highlighted unless colors
+// are off (--no-color, NO_COLOR); otherwise
+// frames are appended, for logs. An error from frame is shown in the frame
+// instead of ending the watch.
+func Run(interval time.Duration, title string, frame func() error) error {
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	out := os.Stdout
+	tty := render.IsTerminal(out)
+	color := render.ColorEnabled(out)
+	if tty {
+		_, _ = fmt.Fprint(out, hideCursor)
+		defer func() { _, _ = fmt.Fprint(out, showCursor) }()
+	}
+
+	var prev []string
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		text, err := Capture(frame)
+		if err != nil {
+			text += fmt.Sprintf("\nerror: %v\n", err)
+		}
+		lines := strings.Split(strings.TrimRight(text, "\n"), "\n")
+		header := fmt.Sprintf("Every %s: %s    %s", interval, title, time.Now().Format("15:04:05"))
+		if tty {
+			_, _ = fmt.Fprint(out, clearScreen+header+"\n\n"+Render(lines, prev, color))
+		} else {
+			_, _ = fmt.Fprint(out, header+"\n\n"+strings.Join(lines, "\n")+"\n\n")
+		}
+		prev = lines
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// Render joins lines, highlighting those that differ from the line at the
+// same position in prev when color is true. The first frame (no prev) has no
+// highlights.
+func Render(lines, prev []string, color bool) string {
+	var b strings.Builder
+	for i, line := range lines {
+		if prev != nil && (i >= len(prev) || prev[i] != line) && line != "" {
+			b.WriteString(render.Paint(color, render.Reverse, line) + "\n")
+		} else {
+			b.WriteString(line + "\n")
+		}
+	}
+	return b.String()
+}
+
+// Capture runs fn with os.Stdout and log output redirected, and returns
+// what it wrote, so a frame's warnings are part of it rather than
+// scrolling past.
+func Capture(fn func() error) (string, error) {
+	r, w, err := os.Pipe()
+	if err != nil {
+		return "", err
+	}
+	var buf bytes.Buffer
+	done := make(chan struct{})
+	go func() {
+		_, _ = io.Copy(&buf, r)
+		close(done)
+	}()
+
+	stdout, logOut := os.Stdout, log.StandardLogger().Out
+	os.Stdout = w
+	log.SetOutput(w)
+	ferr := fn()
+	os.Stdout = stdout
+	log.SetOutput(logOut)
+	_ = w.Close()
+	<-done
+	_ = r.Close()
+	return buf.String(), ferr
+}
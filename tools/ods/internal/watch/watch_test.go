@@ -0,0 +1,35 @@
+package watch
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/onyx-dot-app/onyx/tools/ods/internal/render"
+)
+
+func TestRender(t *testing.T) {
+	prev := []string{"CHECK  STATUS", "redis  PASS", "vespa  PASS"}
+	lines := []string{"CHECK  STATUS", "redis  FAIL", "vespa  PASS", "celery PASS"}
+	want := "CHECK  STATUS\n" + render.Reverse + "redis  FAIL" + render.Reset + "\nvespa  PASS\n" + render.Reverse + "celery PASS" + render.Reset + "\n"
+	if got := Render(lines, prev, true); got != want {
+		t.Errorf("Render() = %q, want %q", got, want)
+	}
+	plain := "CHECK  STATUS\nredis  FAIL\nvespa  PASS\ncelery PASS\n"
+	if got := Render(lines, nil, true); got != plain {
+		t.Errorf("first frame should have no highlights, got %q", got)
+	}
+	if got := Render(lines, prev, false); got != plain {
+		t.Errorf("highlights should be off without color, got %q", got)
+	}
+}
+
+func TestCapture(t *testing.T) {
+	out, err := Capture(func() error {
+		fmt.Println("hello")
+		return errors.New("boom")
+	})
+	if out != "hello\n" || err == nil || err.Error() != "boom" {
+		t.Errorf("Capture() = %q, %v", out, err)
+	}
+}
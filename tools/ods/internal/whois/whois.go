@@ -0,0 +1,117 @@
+// Package whois looks up data plane users and tenants by email fragment or
+// tenant ID.
+package whois
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Lookup kinds.
+const (
+	KindEmail  = "email"
+	KindTenant = "tenant"
+)
+
+var safeIdentifier = regexp.MustCompile(`^[a-zA-Z0-9_\-]+$`)
+
+// Query runs sql against the data plane database and returns its output as
+// tab-separated rows.
+type Query func(sql string) ([]string, error)
+
+// Result is the answer to a lookup. Rows are "email\ttenant\tactive" for an
+// email lookup and admin emails for a tenant lookup.
+type Result struct {
+	Kind string
+	Rows []string
+}
+
+// Match is a user whose email matched an email lookup.
+type Match struct {
+	Email    string `json:"email"`
+	TenantID string `json:"tenant_id"`
+	Active   bool   `json:"active"`
+}
+
+// KindOf returns what query looks up: tenant IDs start with "tenant_", and
+// anything else is an email fragment.
+func KindOf(query string) string {
+	if strings.HasPrefix(query, "tenant_") {
+		return KindTenant
+	}
+	return KindEmail
+}
+
+// ValidateTenantID returns an error if tenantID could not be safely
+// interpolated into a schema-qualified SQL identifier.
+func ValidateTenantID(tenantID string) error {
+	if !safeIdentifier.MatchString(tenantID) {
+		return fmt.Errorf("invalid tenant ID: %q (must be alphanumeric, hyphens, underscores only)", tenantID)
+	}
+	return nil
+}
+
+// Lookup answers query through q: the users whose email contains an email
+// fragment, or the admins of a tenant.
+func Lookup(q Query, query string) (*Result, error) {
+	kind := KindOf(query)
+	var sql string
+	if kind == KindTenant {
+		var err error
+		if sql, err = AdminsSQL(query); err != nil {
+			return nil, err
+		}
+	} else {
+		if strings.TrimSpace(query) == "" {
+			return nil, fmt.Errorf("empty email fragment")
+		}
+		sql = EmailSQL(query)
+	}
+	rows, err := q(sql)
+	if err != nil {
+		return nil, err
+	}
+	return &Result{Kind: kind, Rows: rows}, nil
+}
+
+// EscapeFragment strips quoting characters from an email fragment and escapes
+// LIKE wildcards so it matches literally.
+func EscapeFragment(fragment string) string {
+	return strings.NewReplacer("'", "", `"`, "", `;`, "", `\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(fragment)
+}
+
+// EmailSQL selects the users whose email contains fragment.
+func EmailSQL(fragment string) string {
+	return fmt.Sprintf(
+		`SELECT email, tenant_id, active FROM public.user_tenant_mapping WHERE email LIKE '%%%s%%' ORDER BY email;`,
+		EscapeFragment(fragment),
+	)
+}
+
+// AdminsSQL selects the active, non-API-key admin emails of a tenant.
+func AdminsSQL(tenantID string) (string, error) {
+	if err := ValidateTenantID(tenantID); err != nil {
+		return "", err
+	}
+	return fmt.Sprintf(
+		`SELECT email FROM "%s"."user" WHERE role = 'ADMIN' AND is_active = true AND email NOT LIKE 'api_key__%%' ORDER BY email;`,
+		tenantID,
+	), nil
+}
+
+// Matches parses the rows of an email lookup, skipping malformed ones.
+func (r *Result) Matches() []Match {
+	if r.Kind != KindEmail {
+		return nil
+	}
+	var matches []Match
+	for _, row := range r.Rows {
+		cols := strings.Split(row, "\t")
+		if len(cols) != 3 {
+			continue
+		}
+		matches = append(matches, Match{Email: cols[0], TenantID: cols[1], Active: cols[2] == "t"})
+	}
+	return matches
+}
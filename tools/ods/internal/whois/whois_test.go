@@ -0,0 +1,39 @@
+package whois
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLookup(t *testing.T) {
+	var gotSQL string
+	q := func(sql string) ([]string, error) {
+		gotSQL = sql
+		return []string{"chris@acme.com\ttenant_a\tt", "chris@beta.io\ttenant_b\tf", "malformed"}, nil
+	}
+
+	r, err := Lookup(q, "chris_o'%")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(gotSQL, `LIKE '%chris\_o\%%'`) {
+		t.Errorf("unexpected email SQL: %s", gotSQL)
+	}
+	matches := r.Matches()
+	if r.Kind != KindEmail || len(matches) != 2 || matches[0] != (Match{Email: "chris@acme.com", TenantID: "tenant_a", Active: true}) || matches[1].Active {
+		t.Errorf("Matches() = %+v", matches)
+	}
+
+	if r, err = Lookup(q, "tenant_abc-123"); err != nil || r.Kind != KindTenant || r.Matches() != nil {
+		t.Errorf("tenant Lookup() = %+v, %v", r, err)
+	}
+	if !strings.Contains(gotSQL, `FROM "tenant_abc-123"."user" WHERE role = 'ADMIN'`) {
+		t.Errorf("unexpected admins SQL: %s", gotSQL)
+	}
+
+	for _, query := range []string{`tenant_a"; DROP TABLE x; --`, "  "} {
+		if _, err := Lookup(q, query); err == nil {
+			t.Errorf("Lookup(%q) succeeded, want an error", query)
+		}
+	}
+}
@@ -0,0 +1,120 @@
+// Package workspace keeps track of branch workspaces: git worktrees that
+// each run their own compose project, and so their own database.
+package workspace
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// Workspace is a git worktree of one branch with its own compose project.
+type Workspace struct {
+	Name   string `json:"name"`
+	Branch string `json:"branch"`
+	// Dir is the worktree's root.
+	Dir string `json:"dir"`
+	// Project is the compose project of the worktree; its volumes hold the
+	// workspace's database.
+	Project string    `json:"project"`
+	Created time.Time `json:"created"`
+}
+
+// Store is the set of workspaces ods workspace create has made.
+type Store struct {
+	Workspaces []Workspace `json:"workspaces"`
+	path       string
+}
+
+// LoadStore reads the store at path; a missing file is an empty store.
+func LoadStore(path string) (*Store, error) {
+	s := &Store{path: path}
+	data, err := os.ReadFile(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, s); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Find returns the workspace named, or checked out on the branch, ref; nil
+// if there is none.
+func (s *Store) Find(ref string) *Workspace {
+	for i := range s.Workspaces {
+		if s.Workspaces[i].Name == ref {
+			return &s.Workspaces[i]
+		}
+	}
+	for i := range s.Workspaces {
+		if s.Workspaces[i].Branch == ref {
+			return &s.Workspaces[i]
+		}
+	}
+	return nil
+}
+
+// Put adds or replaces the workspace of ws.Name.
+func (s *Store) Put(ws Workspace) {
+	for i := range s.Workspaces {
+		if s.Workspaces[i].Name == ws.Name {
+			s.Workspaces[i] = ws
+			return
+		}
+	}
+	s.Workspaces = append(s.Workspaces, ws)
+	slices.SortFunc(s.Workspaces, func(a, b Workspace) int { return strings.Compare(a.Name, b.Name) })
+}
+
+// Remove forgets the named workspace.
+func (s *Store) Remove(name string) {
+	s.Workspaces = slices.DeleteFunc(s.Workspaces, func(ws Workspace) bool { return ws.Name == name })
+}
+
+// Save writes the store back to its file.
+func (s *Store) Save() error {
+	if err := os.MkdirAll(filepath.Dir(s.path), 0755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(s.path, append(data, '\n'), 0644)
+}
+
+// NameForBranch returns the workspace name of a branch: lowercase, with
+// path separators and other punctuation turned into hyphens, e.g.
+// "feature-sso-fix" for "Feature/SSO_fix".
+func NameForBranch(branch string) string {
+	var b strings.Builder
+	hyphen := false
+	for _, r := range strings.ToLower(branch) {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			b.WriteRune(r)
+			hyphen = false
+		} else if !hyphen && b.Len() > 0 {
+			b.WriteRune('-')
+			hyphen = true
+		}
+	}
+	return strings.TrimSuffix(b.String(), "-")
+}
+
+// DefaultDir returns where the named workspace's worktree goes: beside the
+// main checkout, named after it, e.g. ~/src/onyx-feature-x for ~/src/onyx.
+// The compose project, named after the directory, follows.
+func DefaultDir(mainRoot, name string) string {
+	return filepath.Join(filepath.Dir(mainRoot), filepath.Base(mainRoot)+"-"+name)
+}
@@ -0,0 +1,60 @@
+package workspace
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestNameForBranch(t *testing.T) {
+	tests := map[string]string{
+		"feature-x":          "feature-x",
+		"Feature/SSO_fix":    "feature-sso-fix",
+		"jane/fix--login//":  "jane-fix-login",
+		"release/v2.10":      "release-v2-10",
+		"dependabot/npm/a.b": "dependabot-npm-a-b",
+	}
+	for branch, want := range tests {
+		if got := NameForBranch(branch); got != want {
+			t.Errorf("NameForBranch(%q) = %q, want %q", branch, got, want)
+		}
+	}
+}
+
+func TestDefaultDir(t *testing.T) {
+	got := DefaultDir(filepath.Join("src", "onyx"), "feature-x")
+	if want := filepath.Join("src", "onyx-feature-x"); got != want {
+		t.Errorf("DefaultDir() = %q, want %q", got, want)
+	}
+}
+
+func TestStore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "workspaces.json")
+	s, err := LoadStore(path)
+	if err != nil {
+		t.Fatalf("LoadStore() error: %v", err)
+	}
+	s.Put(Workspace{Name: "jane-sso", Branch: "jane/sso", Project: "onyx-jane-sso"})
+	s.Put(Workspace{Name: "feature-x", Branch: "feature-x", Project: "onyx-feature-x"})
+	s.Put(Workspace{Name: "jane-sso", Branch: "jane/sso", Project: "onyx-jane-sso", Dir: "/src/onyx-jane-sso"})
+	if err := s.Save(); err != nil {
+		t.Fatalf("Save() error: %v", err)
+	}
+
+	s, err = LoadStore(path)
+	if err != nil {
+		t.Fatalf("LoadStore() error: %v", err)
+	}
+	if len(s.Workspaces) != 2 || s.Workspaces[0].Name != "feature-x" {
+		t.Fatalf("unexpected workspaces %+v", s.Workspaces)
+	}
+	if ws := s.Find("jane/sso"); ws == nil || ws.Dir != "/src/onyx-jane-sso" {
+		t.Errorf("Find(branch) = %+v", ws)
+	}
+	if ws := s.Find("jane-sso"); ws == nil || ws.Name != "jane-sso" {
+		t.Errorf("Find(name) = %+v", ws)
+	}
+	s.Remove("feature-x")
+	if s.Find("feature-x") != nil || len(s.Workspaces) != 1 {
+		t.Errorf("Remove() left %+v", s.Workspaces)
+	}
+}